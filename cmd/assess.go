@@ -0,0 +1,137 @@
+/* Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.*/
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
+	"github.com/google/subcommands"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AssessCmd struct with flags.
+type AssessCmd struct {
+	source         string
+	sourceProfile  string
+	target         string
+	targetProfile  string
+	filePrefix     string // TODO: move filePrefix to global flags
+	logLevel       string
+	suppressIssues string
+}
+
+// Name returns the name of operation.
+func (cmd *AssessCmd) Name() string {
+	return "assess"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *AssessCmd) Synopsis() string {
+	return "assess the complexity of migrating the source db schema to the target db"
+}
+
+// Usage returns usage info of the command.
+func (cmd *AssessCmd) Usage() string {
+	return fmt.Sprintf(`%v assess -source=[source] -source-profile="key1=value1,key2=value2" ...
+
+Analyze the schema of the source db specified by source and source-profile and
+produce a migration complexity score per table and overall, without creating a
+Spanner database. The score is based on unsupported types, auto-increment
+columns, missing primary keys, foreign key cycles, table sizes, triggers,
+procedures and views. The assess flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *AssessCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.source, "source", "", sourceFlagUsage(false))
+	f.StringVar(&cmd.sourceProfile, "source-profile", "", "Flag for specifying connection profile for source database e.g., \"file=<path>,format=dump\"")
+	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`)")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"dialect=postgresql\"")
+	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
+	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+	f.StringVar(&cmd.suppressIssues, "suppress-issues", "", "JSON file with rules for suppressing schema issues from the report, e.g. [{\"issue\": \"Widened\", \"table\": \"t1\"}]")
+}
+
+func (cmd *AssessCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	// Cleanup hb tmp data directory in case residuals remain from prev runs.
+	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
+	var err error
+	defer func() {
+		if err != nil {
+			logger.Log.Fatal("FATAL error", zap.Error(err))
+		}
+	}()
+	err = logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+
+	var suppressions []internal.IssueSuppression
+	if suppressions, err = readIssueSuppressions(cmd.suppressIssues); err != nil {
+		return subcommands.ExitUsageError
+	}
+
+	sourceProfile, targetProfile, ioHelper, dbName, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
+	if err != nil {
+		err = fmt.Errorf("error while preparing prerequisites for migration: %v", err)
+		return subcommands.ExitUsageError
+	}
+
+	// If filePrefix not explicitly set, use generated dbName.
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = dbName + "."
+	}
+
+	schemaConversionStartTime := time.Now()
+	var conv *internal.Conv
+	conv, err = conversion.SchemaConv(sourceProfile, targetProfile, &ioHelper)
+	if err != nil {
+		return subcommands.ExitFailure
+	}
+	conv.SetIssueSuppressions(suppressions)
+	internal.AddHotspotIssues(conv, internal.DetectHotspots(conv))
+	if err = conversion.SetSourceRowStats(sourceProfile, targetProfile, conv); err != nil {
+		err = fmt.Errorf("can't get row counts of source tables: %v", err)
+		return subcommands.ExitFailure
+	}
+
+	// Populate migration request id and migration type in conv object.
+	conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
+	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_ONLY.Enum()
+	conv.Audit.DryRun = true
+	conv.Audit.SchemaConversionDuration = time.Since(schemaConversionStartTime)
+
+	banner := utils.GetBanner(schemaConversionStartTime, dbName)
+	conversion.Report(sourceProfile.Driver, nil, ioHelper.BytesRead, banner, conv, cmd.filePrefix+reportFile, ioHelper.Out)
+	conversion.WriteAssessment(conv, cmd.filePrefix+assessFile, ioHelper.Out)
+	// Cleanup hb tmp data directory.
+	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
+	return subcommands.ExitSuccess
+}
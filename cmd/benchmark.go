@@ -0,0 +1,249 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/performance"
+	"github.com/google/subcommands"
+	"go.uber.org/zap"
+)
+
+// BenchmarkCmd struct with flags.
+type BenchmarkCmd struct {
+	shapes        string
+	sizes         string
+	writeLimits   string
+	target        string
+	targetProfile string
+	filePrefix    string
+	dryRun        bool
+	keepDatabases bool
+	logLevel      string
+}
+
+// Name returns the name of operation.
+func (cmd *BenchmarkCmd) Name() string {
+	return "benchmark"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *BenchmarkCmd) Synopsis() string {
+	return "benchmark migrations of synthetic datasets with different write parallelism"
+}
+
+// Usage returns usage info of the command.
+func (cmd *BenchmarkCmd) Usage() string {
+	return fmt.Sprintf(`%v benchmark -shapes=single,multi -sizes=10000 -write-limits=20,40 -target-profile="instance=my-instance"...
+
+Generate synthetic datasets of the given schema shapes and sizes as mysqldump
+files, migrate each of them to a new Spanner database with each of the given
+write limits, and write a report comparing rows/sec, mutations/sec and CPU use
+of the data migrations. The databases are dropped after each run. With
+-dry-run, the data is converted without writing it to Spanner, to benchmark
+conversion alone. The benchmark flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *BenchmarkCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.shapes, "shapes", strings.Join(performance.ShapeNames(), ","), "Comma-separated schema shapes of the datasets (accepted values: "+strings.Join(performance.ShapeNames(), ", ")+")")
+	f.StringVar(&cmd.sizes, "sizes", "10000", "Comma-separated sizes of the datasets, in rows of their main table")
+	f.StringVar(&cmd.writeLimits, "write-limits", "20,40,80", "Comma-separated write limits to migrate each dataset with")
+	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`, `emulator`). The emulator target uses the Cloud Spanner emulator at SPANNER_EMULATOR_HOST (default localhost:9010)")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"instance=my-instance\"")
+	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
+	f.BoolVar(&cmd.dryRun, "dry-run", false, "Flag for converting the data without writing it to Spanner")
+	f.BoolVar(&cmd.keepDatabases, "keep-databases", false, "Flag for keeping the Spanner databases of the runs instead of dropping them")
+	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+}
+
+func (cmd *BenchmarkCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
+	var err error
+	defer func() {
+		if err != nil {
+			logger.Log.Fatal("FATAL error", zap.Error(err))
+		}
+	}()
+	err = logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+
+	shapes := strings.Split(cmd.shapes, ",")
+	for _, shape := range shapes {
+		if _, ok := performance.Shapes[shape]; !ok {
+			err = fmt.Errorf("unknown shape %q (accepted values: %s)", shape, strings.Join(performance.ShapeNames(), ", "))
+			return subcommands.ExitUsageError
+		}
+	}
+	var sizes, writeLimits []int64
+	if sizes, err = parsePositiveInts(cmd.sizes, "sizes"); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if writeLimits, err = parsePositiveInts(cmd.writeLimits, "write-limits"); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = "benchmark."
+	}
+
+	var results []performance.Result
+	for _, shape := range shapes {
+		for _, size := range sizes {
+			var dump string
+			if dump, err = writeBenchmarkDump(shape, size); err != nil {
+				return subcommands.ExitFailure
+			}
+			for _, writeLimit := range writeLimits {
+				fmt.Printf("Benchmarking shape %s of size %d with write limit %d\n", shape, size, writeLimit)
+				r := cmd.run(ctx, dump, writeLimit)
+				r.Shape, r.Size = shape, size
+				if r.Err != nil {
+					fmt.Printf("Run failed: %v\n", r.Err)
+				}
+				results = append(results, r)
+			}
+			os.Remove(dump)
+		}
+	}
+
+	performance.WriteComparison(os.Stdout, results)
+	var out *os.File
+	if out, err = os.Create(cmd.filePrefix + benchmarkFile); err != nil {
+		err = fmt.Errorf("can't create benchmark report file %s: %v", cmd.filePrefix+benchmarkFile, err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	performance.WriteComparison(out, results)
+	fmt.Printf("Wrote benchmark report to file '%s'.\n", cmd.filePrefix+benchmarkFile)
+	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
+	return subcommands.ExitSuccess
+}
+
+// run migrates the data of mysqldump file dump with writeLimit, and returns
+// the measured throughput.
+func (cmd *BenchmarkCmd) run(ctx context.Context, dump string, writeLimit int64) performance.Result {
+	result := performance.Result{WriteLimit: writeLimit}
+	sourceProfile, targetProfile, ioHelper, _, err := PrepareMigrationPrerequisites("file="+dump, cmd.targetProfile, constants.MYSQL)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer ioHelper.In.Close()
+	if result.Err = migration.ConfigureTarget(cmd.target, &targetProfile); result.Err != nil {
+		return result
+	}
+	conv, err := conversion.SchemaConv(sourceProfile, targetProfile, &ioHelper)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if cmd.dryRun {
+		conv.Audit.DryRun = true
+		start, cpu := time.Now(), performance.CPUTime()
+		_, result.Err = conversion.DataConv(ctx, sourceProfile, targetProfile, &ioHelper, nil, conv, true, writeLimit)
+		result.Duration, result.CPU = time.Since(start), performance.CPUTime()-cpu
+		result.Rows, result.Mutations = benchmarkCounts(conv, nil)
+		return result
+	}
+	adminClient, client, dbURI, err := CreateDatabaseClient(ctx, targetProfile, sourceProfile.Driver, ioHelper)
+	if err != nil {
+		result.Err = fmt.Errorf("can't create database client: %v", err)
+		return result
+	}
+	defer adminClient.Close()
+	defer client.Close()
+	if err = conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, sourceProfile.Driver, targetProfile.TargetDb, conv, ioHelper.Out); err != nil {
+		result.Err = fmt.Errorf("can't create database: %v", err)
+		return result
+	}
+	if !cmd.keepDatabases {
+		defer adminClient.DropDatabase(ctx, &adminpb.DropDatabaseRequest{Database: dbURI})
+	}
+	start, cpu := time.Now(), performance.CPUTime()
+	bw, err := conversion.DataConv(ctx, sourceProfile, targetProfile, &ioHelper, client, conv, true, writeLimit)
+	result.Duration, result.CPU = time.Since(start), performance.CPUTime()-cpu
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Rows, result.Mutations = benchmarkCounts(conv, bw.DroppedRowsByTable())
+	return result
+}
+
+// benchmarkCounts returns the number of rows migrated and of column values
+// written, excluding the rows in dropped, by Spanner table.
+func benchmarkCounts(conv *internal.Conv, dropped map[string]int64) (rows, mutations int64) {
+	for srcTable, n := range conv.Stats.GoodRows {
+		spTable, err := internal.GetSpannerTable(conv, srcTable)
+		if err != nil {
+			continue
+		}
+		n -= dropped[spTable]
+		rows += n
+		mutations += n * int64(len(conv.SpSchema[spTable].ColNames))
+	}
+	return rows, mutations
+}
+
+// writeBenchmarkDump writes a dataset of shape and size to a temporary
+// mysqldump file, and returns its name.
+func writeBenchmarkDump(shape string, size int64) (string, error) {
+	f, err := ioutil.TempFile("", "harbourbridge.benchmark.*.sql")
+	if err != nil {
+		return "", fmt.Errorf("can't create dump file: %v", err)
+	}
+	defer f.Close()
+	if err := performance.WriteDump(f, shape, size); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("can't write dump file %s: %v", f.Name(), err)
+	}
+	return f.Name(), nil
+}
+
+// parsePositiveInts parses a comma-separated list of positive integers,
+// the value of flag name.
+func parsePositiveInts(s, name string) ([]int64, error) {
+	var l []int64
+	for _, x := range strings.Split(s, ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid value %q in %s: must be a positive integer", x, name)
+		}
+		l = append(l, n)
+	}
+	return l, nil
+}
@@ -28,14 +28,18 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/profiles"
 	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
 	"github.com/google/uuid"
 )
 
 var (
-	badDataFile = "dropped.txt"
-	reportFile  = "report.txt"
-	schemaFile  = "schema.txt"
-	sessionFile = "session.json"
+	badDataFile   = "dropped.txt"
+	reportFile    = "report.txt"
+	schemaFile    = "schema.txt"
+	sessionFile   = "session.json"
+	assessFile    = "assessment.txt"
+	cutoverFile   = "cutover.json"
+	benchmarkFile = "benchmark.txt"
 )
 
 const defaultWritersLimit = 40
@@ -50,8 +54,8 @@ func CommandLine(ctx context.Context, driver, targetDb, dbURI string, dataOnly,
 	// Cleanup hb tmp data directory in case residuals remain from prev runs.
 	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
 	// Legacy mode is only supported for MySQL, PostgreSQL and DynamoDB
-	if driver != "" && utils.IsValidDriver(driver) && !utils.IsLegacyModeSupportedDriver(driver) {
-		return fmt.Errorf("legacy mode is not supported for drivers other than %s", strings.Join(utils.GetLegacyModeSupportedDrivers(), ", "))
+	if d, found := registry.Get(driver); found && !d.Info().LegacyMode {
+		return fmt.Errorf("legacy mode is not supported for drivers other than %s", strings.Join(registry.LegacyModeDrivers(), ", "))
 	}
 
 	var conv *internal.Conv
@@ -0,0 +1,115 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+	"github.com/google/subcommands"
+)
+
+// CompletionCmd struct with flags.
+type CompletionCmd struct{}
+
+// Name returns the name of operation.
+func (cmd *CompletionCmd) Name() string {
+	return "completion"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *CompletionCmd) Synopsis() string {
+	return "print a bash or zsh completion script for subcommands and their flags"
+}
+
+// Usage returns usage info of the command.
+func (cmd *CompletionCmd) Usage() string {
+	return fmt.Sprintf(`%v completion bash|zsh
+
+Print a script completing subcommands, their flags and the values of -source
+and -target for the given shell. To enable completion, source the script from
+your shell's startup file, e.g. for bash:
+
+  source <(%v completion bash)
+
+and for zsh:
+
+  source <(%v completion zsh)
+`, path.Base(os.Args[0]), path.Base(os.Args[0]), path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *CompletionCmd) SetFlags(f *flag.FlagSet) {}
+
+func (cmd *CompletionCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 || (f.Arg(0) != "bash" && f.Arg(0) != "zsh") {
+		fmt.Fprint(os.Stderr, cmd.Usage())
+		return subcommands.ExitUsageError
+	}
+	commands := make(map[string][]string)
+	subcommands.DefaultCommander.VisitCommands(func(_ *subcommands.CommandGroup, c subcommands.Command) {
+		fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+		c.SetFlags(fs)
+		var flags []string
+		fs.VisitAll(func(fl *flag.Flag) {
+			flags = append(flags, "-"+fl.Name)
+		})
+		commands[c.Name()] = flags
+	})
+	writeCompletion(os.Stdout, f.Arg(0), path.Base(os.Args[0]), commands, registry.Sources())
+	return subcommands.ExitSuccess
+}
+
+// writeCompletion writes the completion script for shell (bash or zsh) of
+// program prog to w. commands maps each subcommand to its flags, and sources
+// are the values of the -source flag.
+func writeCompletion(w io.Writer, shell, prog string, commands map[string][]string, sources []string) {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(prog)
+	if shell == "zsh" {
+		// zsh runs the bash completion function through bashcompinit.
+		fmt.Fprintf(w, "autoload -U +X bashcompinit && bashcompinit\n")
+	}
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "\tif [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintf(w, "\t\treturn\n\tfi\n")
+	fmt.Fprintf(w, "\tcase \"$prev\" in\n")
+	fmt.Fprintf(w, "\t-source)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn;;\n", strings.Join(sources, " "))
+	fmt.Fprintf(w, "\t-target)\n\t\tCOMPREPLY=($(compgen -W \"Spanner emulator\" -- \"$cur\"))\n\t\treturn;;\n")
+	fmt.Fprintf(w, "\t-session|-write-tuning|-fixups|-suppress-issues|-bad-data-dir)\n\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\treturn;;\n")
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "\tcase \"${COMP_WORDS[1]}\" in\n")
+	for _, name := range names {
+		if len(commands[name]) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\t%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"));;\n", name, strings.Join(commands[name], " "))
+	}
+	fmt.Fprintf(w, "\tesac\n}\n")
+	fmt.Fprintf(w, "complete -F %s %s\n", fn, prog)
+}
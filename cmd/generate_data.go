@@ -0,0 +1,216 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/performance"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/google/subcommands"
+	"go.uber.org/zap"
+)
+
+// GenerateDataCmd struct with flags.
+type GenerateDataCmd struct {
+	sessionJSON     string
+	target          string
+	targetProfile   string
+	rows            int64
+	tableRows       string
+	seed            int64
+	writeLimit      int64
+	dryRun          bool
+	skipForeignKeys bool
+	logLevel        string
+}
+
+// Name returns the name of operation.
+func (cmd *GenerateDataCmd) Name() string {
+	return "generate-data"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *GenerateDataCmd) Synopsis() string {
+	return "write synthetic data for a converted schema to Spanner, for load testing"
+}
+
+// Usage returns usage info of the command.
+func (cmd *GenerateDataCmd) Usage() string {
+	return fmt.Sprintf(`%v generate-data -session=[session_file] -target-profile="instance=my-instance,dbName=my-db" -rows=100000...
+
+Generate synthetic data for the Spanner schema of a session file and write it
+to the database of the target profile, so that the schema can be load tested
+before migrating real data. The database is created with the schema if it
+doesn't exist; otherwise its schema must match the session file and its tables
+must be empty. Primary keys are unique, foreign keys and interleaved tables
+reference existing rows, and other columns get random values of their type.
+The generate-data flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *GenerateDataCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.sessionJSON, "session", "", "Specifies the session file with the Spanner schema to generate data for")
+	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`, `emulator`). The emulator target uses the Cloud Spanner emulator at SPANNER_EMULATOR_HOST (default localhost:9010)")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"instance=my-instance,dbName=my-db\"")
+	f.Int64Var(&cmd.rows, "rows", 1000, "Number of rows to generate for each table")
+	f.StringVar(&cmd.tableRows, "table-rows", "", "Comma-separated numbers of rows of some tables, overriding -rows, e.g. \"orders=50000,order_items=200000\"")
+	f.Int64Var(&cmd.seed, "seed", 1, "Seed of the random values generated")
+	f.Int64Var(&cmd.writeLimit, "write-limit", defaultWritersLimit, "Write limit for writes to spanner")
+	f.BoolVar(&cmd.dryRun, "dry-run", false, "Flag for generating the data without writing it to Spanner")
+	f.BoolVar(&cmd.skipForeignKeys, "skip-foreign-keys", false, "Skip creating foreign keys after generating the data")
+	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+}
+
+func (cmd *GenerateDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	var err error
+	defer func() {
+		if err != nil {
+			logger.Log.Fatal("FATAL error", zap.Error(err))
+		}
+	}()
+	err = logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+
+	if cmd.sessionJSON == "" {
+		err = fmt.Errorf("please specify the session file with -session")
+		return subcommands.ExitUsageError
+	}
+	if cmd.rows < 0 {
+		err = fmt.Errorf("rows can't be negative, found %d", cmd.rows)
+		return subcommands.ExitUsageError
+	}
+	var tableRows map[string]int64
+	if tableRows, err = parseTableRows(cmd.tableRows); err != nil {
+		return subcommands.ExitUsageError
+	}
+	targetProfile, err := profiles.NewTargetProfile(cmd.targetProfile)
+	if err != nil {
+		return subcommands.ExitUsageError
+	}
+	targetProfile.TargetDb = targetProfile.ToLegacyTargetDb()
+	if err = migration.ConfigureTarget(cmd.target, &targetProfile); err != nil {
+		return subcommands.ExitUsageError
+	}
+	conv := internal.MakeConv()
+	if err = conversion.ReadSessionFile(conv, cmd.sessionJSON); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if targetProfile.TargetDb != "" && conv.TargetDb != targetProfile.TargetDb {
+		err = fmt.Errorf("generating data for Spanner dialect: %v, whereas the schema is for dialect: %v", targetProfile.TargetDb, conv.TargetDb)
+		return subcommands.ExitUsageError
+	}
+	g, err := performance.NewGenerator(conv, cmd.rows, tableRows, cmd.seed)
+	if err != nil {
+		return subcommands.ExitUsageError
+	}
+	conv.WriteOptions = targetProfile.WriteOptions()
+
+	if cmd.dryRun {
+		conv.Audit.DryRun = true
+		conversion.SyntheticDataConv(ctx, conv, nil, cmd.writeLimit, g.Total(), g.Generate)
+		writeGeneratedRows(conv, nil)
+		return subcommands.ExitSuccess
+	}
+	if targetProfile.Conn.Sp.Dbname == "" {
+		err = fmt.Errorf("please specify the database to write to with dbName in -target-profile")
+		return subcommands.ExitUsageError
+	}
+	adminClient, client, dbURI, err := migration.CreateDatabaseClient(ctx, targetProfile, "", os.Stdout)
+	if err != nil {
+		err = fmt.Errorf("can't create database client: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer adminClient.Close()
+	defer client.Close()
+	dbExists, err := conversion.CheckExistingDb(ctx, adminClient, dbURI)
+	if err != nil {
+		err = fmt.Errorf("can't verify target database: %v", err)
+		return subcommands.ExitFailure
+	}
+	if dbExists {
+		if err = migration.ValidateDatabase(ctx, conv.TargetDb, dbURI, adminClient, client, conv); err != nil {
+			err = fmt.Errorf("error while validating existing database: %v", err)
+			return subcommands.ExitFailure
+		}
+	} else if err = conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, "", conv.TargetDb, conv, os.Stdout); err != nil {
+		err = fmt.Errorf("can't create database: %v", err)
+		return subcommands.ExitFailure
+	}
+	bw := conversion.SyntheticDataConv(ctx, conv, client, cmd.writeLimit, g.Total(), g.Generate)
+	if !cmd.skipForeignKeys {
+		if err = conversion.UpdateDDLForeignKeys(ctx, adminClient, dbURI, conv, os.Stdout); err != nil {
+			err = fmt.Errorf("can't perform update schema on db %s with foreign keys: %v", dbURI, err)
+			return subcommands.ExitFailure
+		}
+	}
+	writeGeneratedRows(conv, bw.DroppedRowsByTable())
+	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
+	return subcommands.ExitSuccess
+}
+
+// writeGeneratedRows prints the number of rows generated for each table, and
+// of those that couldn't be written.
+func writeGeneratedRows(conv *internal.Conv, dropped map[string]int64) {
+	var tables []string
+	for t := range conv.SpSchema {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		if n := dropped[t]; n > 0 {
+			fmt.Printf("Table %s: generated %d rows, %d of which couldn't be written\n", t, conv.Stats.GoodRows[t], n)
+		} else {
+			fmt.Printf("Table %s: generated %d rows\n", t, conv.Stats.GoodRows[t])
+		}
+	}
+}
+
+// parseTableRows parses a comma-separated list of table=rows pairs.
+func parseTableRows(s string) (map[string]int64, error) {
+	m := make(map[string]int64)
+	if s == "" {
+		return m, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid table rows %q: expected table=rows", pair)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid number of rows %q of table %s", kv[1], kv[0])
+		}
+		m[strings.TrimSpace(kv[0])] = n
+	}
+	return m, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
+	"github.com/google/subcommands"
+)
+
+// ManifestCmd struct with flags.
+type ManifestCmd struct {
+	manifest    string
+	target      string
+	concurrency int
+	writeLimit  int64
+	dryRun      bool
+	force       bool
+	logDir      string
+	reportOut   string
+	logLevel    string
+}
+
+// Name returns the name of operation.
+func (cmd *ManifestCmd) Name() string {
+	return "manifest"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *ManifestCmd) Synopsis() string {
+	return "migrate several source dbs to several Spanner databases, as listed in a manifest"
+}
+
+// Usage returns usage info of the command.
+func (cmd *ManifestCmd) Usage() string {
+	return fmt.Sprintf(`%v manifest -manifest=[manifest_file] ...
+
+Run the migrations listed in a JSON manifest file, each from a source db to
+its own Spanner database, a few at a time: each converts the schema, creates
+the database and migrates the data (or only the schema, if schemaOnly is set).
+The output of each migration is written to <name>.log in the log directory,
+and a consolidated report of all migrations is printed once they're done.
+A migration that fails doesn't stop the others; the subcommand exits with
+status 1 if any failed. The manifest flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *ManifestCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.manifest, "manifest", "", "JSON file listing the migrations, e.g. {\"concurrency\": 2, \"migrations\": [{\"name\": \"orders\", \"source\": \"mysql\", \"sourceProfile\": \"host=orders-db,user=hb,dbName=orders\", \"targetProfile\": \"instance=my-instance,dbName=orders\"}]}")
+	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`, `emulator`). The emulator target uses the Cloud Spanner emulator at SPANNER_EMULATOR_HOST (default localhost:9010)")
+	f.IntVar(&cmd.concurrency, "concurrency", 0, "Number of migrations run at the same time, overriding the concurrency of the manifest (defaults to 4)")
+	f.Int64Var(&cmd.writeLimit, "write-limit", defaultWritersLimit, "Write limit for writes to spanner of each migration, unless set in the manifest")
+	f.BoolVar(&cmd.dryRun, "dry-run", false, "Flag for converting the schemas and data without creating spanner databases")
+	f.BoolVar(&cmd.force, "force", false, "Migrate even if other migrations hold leases on the tables of the target databases, e.g. because they crashed, taking over their leases")
+	f.StringVar(&cmd.logDir, "log-dir", "manifest_logs", "Directory the output of each migration is written to, in file <name>.log")
+	f.StringVar(&cmd.reportOut, "report-out", "", "File to write the consolidated report of the migrations to, in addition to stdout")
+	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+}
+
+func (cmd *ManifestCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if err := logger.InitializeLogger(cmd.logLevel); err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+	if cmd.manifest == "" {
+		fmt.Fprintln(os.Stderr, "Please specify the manifest file with -manifest")
+		return subcommands.ExitUsageError
+	}
+	if cmd.concurrency < 0 {
+		fmt.Fprintln(os.Stderr, "Invalid -concurrency: can't be negative")
+		return subcommands.ExitUsageError
+	}
+	m, err := migration.ReadManifest(cmd.manifest)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return subcommands.ExitUsageError
+	}
+	if cmd.concurrency > 0 {
+		m.Concurrency = cmd.concurrency
+	}
+	fmt.Printf("Running %d migration(s), writing their output to %s ...\n", len(m.Migrations), cmd.logDir)
+	r, err := migration.RunManifest(ctx, m, migration.Config{Target: cmd.target, WriteLimit: cmd.writeLimit, DryRun: cmd.dryRun, Force: cmd.force}, cmd.logDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return subcommands.ExitFailure
+	}
+	r.Write(os.Stdout)
+	if cmd.reportOut != "" {
+		out, err := os.Create(cmd.reportOut)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't write report: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		r.Write(out)
+		out.Close()
+		fmt.Printf("Wrote report to file '%s'.\n", cmd.reportOut)
+	}
+	if r.Failed() > 0 {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
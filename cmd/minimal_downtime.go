@@ -0,0 +1,379 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/monitoring"
+	"github.com/cloudspannerecosystem/harbourbridge/common/notify"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	migrationpb "github.com/cloudspannerecosystem/harbourbridge/proto/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+	"github.com/google/subcommands"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// MinimalDowntimeCmd struct with flags.
+type MinimalDowntimeCmd struct {
+	source             string
+	sourceProfile      string
+	target             string
+	targetProfile      string
+	skipForeignKeys    bool
+	deferIndexes       bool
+	filePrefix         string
+	writeLimit         int64
+	logLevel           string
+	badDataSampleSize  int
+	badDataDir         string
+	oversizedValues    string
+	oversizedSpillURI  string
+	reportFormat       string
+	chaos              string
+	migrationCatalog   bool
+	force              bool
+	notifyWebhook      string
+	notifyFormat       string
+	notifyEvents       string
+	monitoringProject  string
+	checkPermissions   bool
+	summaryOut         string
+	failOnSchemaIssues bool
+	maxBadRowRate      float64
+}
+
+// Name returns the name of operation.
+func (cmd *MinimalDowntimeCmd) Name() string {
+	return "stream"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *MinimalDowntimeCmd) Synopsis() string {
+	return "minimal downtime migration from source db to target db, streaming changes made during the migration"
+}
+
+// Usage returns usage info of the command.
+func (cmd *MinimalDowntimeCmd) Usage() string {
+	return fmt.Sprintf(`%v stream -source=[source] -source-profile="..." -target-profile="instance=my-instance"...
+
+Migrate schema and data from a source db that supports change data capture
+(MySQL and Oracle using Datastream, DynamoDB using DynamoDB Streams) while it
+continues to serve writes. The steps are: enable change data capture, bulk
+load, validation of row counts, catch-up streaming of the changes made since
+change data capture was enabled, a cutover signal and the final report. The
+source profile must enable streaming (streamingCfg for MySQL and Oracle,
+enableStreaming=true for DynamoDB). The stream flags (also accepted by its
+former name, minimal-downtime) are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *MinimalDowntimeCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.source, "source", "", sourceFlagUsage(true))
+	f.StringVar(&cmd.sourceProfile, "source-profile", "", "Flag for specifying connection profile for source database e.g., \"host=localhost,streamingCfg=streaming.json\"")
+	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`, `emulator`). The emulator target uses the Cloud Spanner emulator at SPANNER_EMULATOR_HOST (default localhost:9010)")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"dialect=postgresql\"")
+	f.BoolVar(&cmd.skipForeignKeys, "skip-foreign-keys", false, "Skip creating foreign keys after data migration is complete (ddl statements for foreign keys can still be found in the downloaded schema.ddl.txt file and the same can be applied separately)")
+	f.BoolVar(&cmd.deferIndexes, "defer-indexes", false, "Create secondary indexes after the bulk load instead of before it, which speeds up bulk loading")
+	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
+	f.Int64Var(&cmd.writeLimit, "write-limit", defaultWritersLimit, "Write limit for writes to spanner")
+	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+	f.StringVar(&cmd.reportFormat, "report-format", constants.ReportFormatText, "Format of the report, in addition to the text report (accepted values: `text`, `html`, `json`)")
+	f.IntVar(&cmd.badDataSampleSize, "bad-data-sample-size", internal.DefaultBadDataSampleSize, "Number of bad rows of each kind to write to the bad data file")
+	f.StringVar(&cmd.badDataDir, "bad-data-dir", "", "Directory to write all bad rows to, partitioned by table and error type as JSON lines files (default: only a sample of bad rows is written to the bad data file)")
+	f.StringVar(&cmd.oversizedValues, "oversized-values", internal.OversizeDrop, "How to handle STRING and BYTES values larger than Spanner's 10MB limit (accepted values: `drop`, `truncate`, `spill`): drop the row to the bad data, truncate the value, or spill it to oversized-values-spill-uri and write its URI instead")
+	f.StringVar(&cmd.oversizedSpillURI, "oversized-values-spill-uri", "", "GCS location (gs://bucket/path) to spill oversized values to, with -oversized-values=spill")
+	f.StringVar(&cmd.chaos, "chaos", "", "Test mode injecting faults at the given rates (0 to 1) to check retries before a production run, e.g. \"spanner-errors=0.05,throttling=0.1,timeouts=0.01,seed=1\": transient errors of writes to Spanner, throttling of reads from AWS, and timeouts of both")
+	f.BoolVar(&cmd.force, "force", false, "Migrate even if another migration holds a lease on the tables of the target database, e.g. because it crashed, taking over its lease")
+	f.BoolVar(&cmd.migrationCatalog, "migration-catalog", false, "Record the migration run, its source, schema versions, row counts and row count validation in tables harbourbridge_migration and harbourbridge_migration_tables of the target database")
+	f.StringVar(&cmd.notifyWebhook, "notify-webhook", "", "Webhook URL (e.g. a Slack or Microsoft Teams incoming webhook) to post notifications of the milestones and failures of the migration to")
+	f.StringVar(&cmd.notifyFormat, "notify-format", "", "Format of the notifications (accepted values: `slack`, `teams`, `json`), defaults to the format of the webhook's host, or json")
+	f.StringVar(&cmd.notifyEvents, "notify-events", "", "Comma separated list of events to notify, defaults to all events: "+strings.Join(notify.Events, ", "))
+	f.StringVar(&cmd.monitoringProject, "monitoring-project", "", "Project to write the Cloud Monitoring custom metrics replication_lag and cutover_ready to, so that alerting policies can page when the cutover window opens")
+	f.BoolVar(&cmd.checkPermissions, "check-permissions", false, checkPermissionsUsage)
+	f.StringVar(&cmd.summaryOut, "summary-out", "", "File to write a JSON summary of the run to, with its status, exit code and row and schema issue counts, for automation")
+	f.BoolVar(&cmd.failOnSchemaIssues, "fail-on-schema-issues", false, "Exit with status 3 if the converted schema has warnings, other than those suppressed with -suppress-issues")
+	f.Float64Var(&cmd.maxBadRowRate, "max-bad-row-rate", 1, "Exit with status 4 if the fraction of rows that couldn't be converted or written exceeds this rate (0 to 1, e.g. 0 to fail on any bad row)")
+}
+
+func (cmd *MinimalDowntimeCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	// Cleanup hb tmp data directory in case residuals remain from prev runs.
+	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
+	var err error
+	defer func() {
+		if err != nil {
+			logger.Log.Fatal("FATAL error", zap.Error(err))
+		}
+	}()
+	start := time.Now()
+	summary := internal.NewRunSummary(cmd.Name())
+	defer func() {
+		finishRun(cmd.summaryOut, summary, start, err)
+	}()
+	err = logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+
+	if err = validateReportFormat(cmd.reportFormat); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if err = configureNotifications(cmd.notifyWebhook, cmd.notifyFormat, cmd.notifyEvents, cmd.Name()); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if err = validateMaxBadRowRate(cmd.maxBadRowRate); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if err = configureChaos(cmd.chaos); err != nil {
+		err = fmt.Errorf("can't configure chaos mode: %v", err)
+		return subcommands.ExitUsageError
+	}
+
+	sourceProfile, targetProfile, ioHelper, dbName, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
+	if err != nil {
+		err = fmt.Errorf("error while preparing prerequisites for migration: %v", err)
+		return subcommands.ExitUsageError
+	}
+	if err = migration.ConfigureTarget(cmd.target, &targetProfile); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if cmd.checkPermissions {
+		var spillURI string
+		if cmd.oversizedValues == internal.OversizeSpill {
+			spillURI = cmd.oversizedSpillURI
+		}
+		if err = checkPermissions(ctx, sourceProfile, &targetProfile, spillURI, cmd.monitoringProject); err != nil {
+			return subcommands.ExitFailure
+		}
+	}
+	if err = checkMinimalDowntimeSource(sourceProfile); err != nil {
+		return subcommands.ExitUsageError
+	}
+	schemaConversionStartTime := time.Now()
+
+	// If filePrefix not explicitly set, use dbName as prefix.
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = dbName + "."
+	}
+
+	conv, err := conversion.SchemaConv(sourceProfile, targetProfile, &ioHelper)
+	if err != nil {
+		err = fmt.Errorf("can't convert schema: %v", err)
+		return subcommands.ExitFailure
+	}
+	closeBadData, err := ConfigureBadData(conv, cmd.badDataSampleSize, cmd.badDataDir, ioHelper.Out)
+	if err != nil {
+		err = fmt.Errorf("can't configure bad data: %v", err)
+		return subcommands.ExitUsageError
+	}
+	defer closeBadData()
+	closeSpill, err := ConfigureOversizedValues(ctx, conv, cmd.oversizedValues, cmd.oversizedSpillURI)
+	if err != nil {
+		err = fmt.Errorf("can't configure oversized values: %v", err)
+		return subcommands.ExitUsageError
+	}
+	defer closeSpill()
+
+	// Populate migration request id and migration type in conv object.
+	conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
+	conv.Audit.MigrationType = migrationpb.MigrationData_SCHEMA_AND_DATA.Enum()
+
+	conversion.WriteSchemaFile(conv, schemaConversionStartTime, cmd.filePrefix+schemaFile, ioHelper.Out)
+	conversion.WriteSessionFile(conv, cmd.filePrefix+sessionFile, ioHelper.Out)
+	conversion.Report(sourceProfile.Driver, nil, ioHelper.BytesRead, "", conv, cmd.filePrefix+reportFile, ioHelper.Out)
+
+	adminClient, client, dbURI, err := CreateDatabaseClient(ctx, targetProfile, sourceProfile.Driver, ioHelper)
+	if err != nil {
+		err = fmt.Errorf("can't create database client: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer adminClient.Close()
+	defer client.Close()
+	if err = monitoring.Configure(ctx, monitoring.Config{Project: cmd.monitoringProject, Database: dbURI}); err != nil {
+		return subcommands.ExitFailure
+	}
+
+	conv.DeferIndexes = cmd.deferIndexes
+	conv.ForceLock = cmd.force
+	conv.WriteOptions = targetProfile.WriteOptions()
+	err = conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, sourceProfile.Driver, targetProfile.TargetDb, conv, ioHelper.Out)
+	if err != nil {
+		err = fmt.Errorf("can't create/update database: %v", err)
+		return subcommands.ExitFailure
+	}
+	var release func()
+	if release, err = conversion.AcquireMigrationLock(ctx, client, conv, ioHelper.Out); err != nil {
+		return subcommands.ExitFailure
+	}
+	defer release()
+	notify.Sendf(notify.SchemaConverted, "schema conversion done: %d tables for database %s.", len(conv.SpSchema), dbURI)
+	schemaCoversionEndTime := time.Now()
+	conv.Audit.SchemaConversionDuration = schemaCoversionEndTime.Sub(schemaConversionStartTime)
+
+	fmt.Fprintf(ioHelper.Out, "\nStep 1 of 6: enabling change data capture\n")
+	infoSchema, err := conversion.GetInfoSchema(sourceProfile, targetProfile)
+	if err != nil {
+		err = fmt.Errorf("can't connect to source database: %v", err)
+		return subcommands.ExitFailure
+	}
+	streamInfo, err := infoSchema.StartChangeDataCapture(ctx, conv)
+	if err != nil {
+		err = fmt.Errorf("can't enable change data capture: %v", err)
+		return subcommands.ExitFailure
+	}
+
+	fmt.Fprintf(ioHelper.Out, "\nStep 2 of 6: bulk loading data\n")
+	bw, err := conversion.SnapshotMigration(ctx, conv, client, infoSchema, cmd.writeLimit)
+	if err != nil {
+		err = fmt.Errorf("can't finish bulk load for db %s: %v", dbURI, err)
+		return subcommands.ExitFailure
+	}
+	notify.Send(notify.BulkLoadDone, bulkLoadMessage(conv, bw.DroppedRowsByTable(), dbURI))
+	if cmd.deferIndexes {
+		if err = conversion.VerifyUniqueIndexes(ctx, client, conv, ioHelper.Out); err != nil {
+			err = fmt.Errorf("can't verify unique indexes of db %s: %v", dbURI, err)
+			return subcommands.ExitFailure
+		}
+		if err = conversion.CreateIndexes(ctx, adminClient, dbURI, conv, ioHelper.Out); err != nil {
+			err = fmt.Errorf("can't perform update schema on db %s with secondary indexes: %v", dbURI, err)
+			return subcommands.ExitFailure
+		}
+	}
+
+	fmt.Fprintf(ioHelper.Out, "\nStep 3 of 6: validating row counts\n")
+	mismatches, err := conversion.ValidateRowCounts(ctx, client, conv, bw.DroppedRowsByTable(), ioHelper.Out)
+	if err != nil {
+		err = fmt.Errorf("can't validate db %s: %v", dbURI, err)
+		return subcommands.ExitFailure
+	}
+	if len(mismatches) == 0 {
+		fmt.Fprintf(ioHelper.Out, "Row counts of all tables match the rows migrated.\n")
+	}
+	addValidation(summary, mismatches, true)
+
+	fmt.Fprintf(ioHelper.Out, "\nStep 4 of 6: streaming changes made since change data capture was enabled\n")
+	if err = infoSchema.StartStreamingMigration(ctx, client, conv, streamInfo); err != nil {
+		err = fmt.Errorf("can't stream changes to db %s: %v", dbURI, err)
+		return subcommands.ExitFailure
+	}
+	if !cmd.skipForeignKeys {
+		if err = conversion.UpdateDDLForeignKeys(ctx, adminClient, dbURI, conv, ioHelper.Out); err != nil {
+			err = fmt.Errorf("can't perform update schema on db %s with foreign keys: %v", dbURI, err)
+			return subcommands.ExitFailure
+		}
+	}
+	if cmd.migrationCatalog {
+		recordMigration(ctx, adminClient, client, dbURI, conv, sourceProfile, schemaConversionStartTime, bw.DroppedRowsByTable(), mismatches, true, ioHelper.Out)
+	}
+	dataCoversionEndTime := time.Now()
+	conv.Audit.DataConversionDuration = dataCoversionEndTime.Sub(schemaCoversionEndTime)
+
+	fmt.Fprintf(ioHelper.Out, "\nStep 5 of 6: signalling cutover\n")
+	signal := newCutoverSignal(sourceProfile, dbURI, mismatches, time.Now())
+	writeCutoverSignal(signal, cmd.filePrefix+cutoverFile, ioHelper.Out)
+	monitoring.ReportCutoverReady(ctx, signal.Ready)
+	if signal.Ready {
+		notify.Sendf(notify.CutoverRecommended, "%s is ready for cutover. %s", dbURI, signal.Instructions)
+	}
+
+	fmt.Fprintf(ioHelper.Out, "\nStep 6 of 6: writing the final report\n")
+	banner := utils.GetBanner(schemaConversionStartTime, dbURI)
+	conversion.Report(sourceProfile.Driver, bw.DroppedRowsByTable(), ioHelper.BytesRead, banner, conv, cmd.filePrefix+reportFile, ioHelper.Out)
+	writeFormattedReport(cmd.reportFormat, sourceProfile.Driver, bw.DroppedRowsByTable(), banner, conv, cmd.filePrefix, true, ioHelper.Out)
+	conversion.WriteBadData(bw, conv, banner, cmd.filePrefix+badDataFile, ioHelper.Out)
+	reportChaos(ioHelper.Out)
+
+	// Cleanup hb tmp data directory.
+	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
+	summary.Driver, summary.Database, summary.Report = sourceProfile.Driver, dbURI, cmd.filePrefix+reportFile
+	summary.AddStats(conv, bw.DroppedRowsByTable())
+	return runStatus(summary, cmd.failOnSchemaIssues, cmd.maxBadRowRate)
+}
+
+// checkMinimalDowntimeSource returns an error if the source can't be migrated
+// with minimal downtime, i.e. if it isn't a direct connection to a database
+// whose changes can be captured, or if streaming wasn't enabled for it.
+func checkMinimalDowntimeSource(sourceProfile profiles.SourceProfile) error {
+	if sourceProfile.Ty != profiles.SourceProfileTypeConnection {
+		return fmt.Errorf("minimal downtime migration requires a direct connection to the source database")
+	}
+	d, found := registry.Get(sourceProfile.Driver)
+	if !found || !d.Info().Streaming {
+		return fmt.Errorf("minimal downtime migration is not supported for source %s: change data capture is only available for %s", sourceProfile.Driver, strings.Join(streamingSources(), ", "))
+	}
+	if !sourceProfile.Conn.Streaming {
+		if sourceProfile.Driver == constants.DYNAMODB {
+			return fmt.Errorf("minimal downtime migration requires DynamoDB Streams: please specify enableStreaming=true in the source profile")
+		}
+		return fmt.Errorf("minimal downtime migration requires a streaming config: please specify streamingCfg in the source profile")
+	}
+	return nil
+}
+
+// cutoverSignal records whether the Spanner database is ready to take over
+// from the source database, and what remains to be done before switching.
+type cutoverSignal struct {
+	Database     string
+	Time         time.Time
+	Ready        bool
+	Mismatches   []conversion.RowCountMismatch
+	Instructions string
+}
+
+func newCutoverSignal(sourceProfile profiles.SourceProfile, dbURI string, mismatches []conversion.RowCountMismatch, now time.Time) cutoverSignal {
+	s := cutoverSignal{Database: dbURI, Time: now, Ready: len(mismatches) == 0, Mismatches: mismatches}
+	switch {
+	case !s.Ready:
+		s.Instructions = "Row counts of some tables don't match the rows migrated. Investigate the mismatches before switching to Spanner."
+	case sourceProfile.Driver == constants.DYNAMODB:
+		s.Instructions = "Streaming has stopped. Stop writes to the source database, then switch the application to Spanner."
+	default:
+		s.Instructions = "Changes are being replicated by the Dataflow job. Stop writes to the source database, wait for the Dataflow job to apply the remaining changes, then switch the application to Spanner."
+	}
+	return s
+}
+
+// writeCutoverSignal writes the cutover signal as JSON to the file name, so
+// that scripts can wait for it, and prints its instructions.
+func writeCutoverSignal(s cutoverSignal, name string, out *os.File) {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(name, b, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "Can't write cutover signal to file %s: %v\n", name, err)
+	} else {
+		fmt.Fprintf(out, "Wrote cutover signal to file '%s'.\n", name)
+	}
+	fmt.Fprintf(out, "Ready for cutover: %t. %s\n", s.Ready, s.Instructions)
+}
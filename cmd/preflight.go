@@ -0,0 +1,92 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
+	"github.com/google/subcommands"
+)
+
+// PreflightCmd struct with flags.
+type PreflightCmd struct {
+	source        string
+	sourceProfile string
+	target        string
+	targetProfile string
+	logLevel      string
+}
+
+// Name returns the name of operation.
+func (cmd *PreflightCmd) Name() string {
+	return "preflight"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *PreflightCmd) Synopsis() string {
+	return "check that a migration can run, without migrating anything"
+}
+
+// Usage returns usage info of the command.
+func (cmd *PreflightCmd) Usage() string {
+	return fmt.Sprintf(`%v preflight -source=[source] -source-profile="key1=value1,key2=value2" -target-profile="instance=my-instance,dbName=my-db" ...
+
+Check, without writing to the source db or to Spanner, what a migration with
+the same flags needs: that the source db can be reached and its tables read
+(and their changes streamed, for streaming migrations), that the dump file can
+be read and there's enough disk space, that the Cloud Spanner API is enabled,
+the instance is ready, the caller has the IAM permissions of the migration and
+the instance has room for a new database. Prints a checklist of the checks and
+exits with status 1 if any failed. The preflight flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *PreflightCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.source, "source", "", sourceFlagUsage(false))
+	f.StringVar(&cmd.sourceProfile, "source-profile", "", "Flag for specifying connection profile for source database e.g., \"file=<path>,format=dump\"")
+	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`, `emulator`). The emulator target uses the Cloud Spanner emulator at SPANNER_EMULATOR_HOST (default localhost:9010)")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"dialect=postgresql\"")
+	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+}
+
+func (cmd *PreflightCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if err := logger.InitializeLogger(cmd.logLevel); err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+	checks, err := migration.Preflight(ctx, migration.Config{
+		Source:        cmd.source,
+		SourceProfile: cmd.sourceProfile,
+		Target:        cmd.target,
+		TargetProfile: cmd.targetProfile,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid flags: %v\n", err)
+		return subcommands.ExitUsageError
+	}
+	checks.Write(os.Stdout)
+	if !checks.Passed() {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -20,15 +20,18 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	database "cloud.google.com/go/spanner/admin/database/apiv1"
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/notify"
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
 	"github.com/cloudspannerecosystem/harbourbridge/conversion"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
-	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
+	migrationpb "github.com/cloudspannerecosystem/harbourbridge/proto/migration"
 	"github.com/google/subcommands"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -36,13 +39,25 @@ import (
 
 // SchemaCmd struct with flags.
 type SchemaCmd struct {
-	source        string
-	sourceProfile string
-	target        string
-	targetProfile string
-	filePrefix    string // TODO: move filePrefix to global flags
-	logLevel      string
-	dryRun        bool
+	source             string
+	sourceProfile      string
+	target             string
+	targetProfile      string
+	filePrefix         string // TODO: move filePrefix to global flags
+	logLevel           string
+	dryRun             bool
+	reportFormat       string
+	suppressIssues     string
+	hotspotRemediation string
+	syntheticKeyName   string
+	syntheticKeyType   string
+	unsignedOverflow   string
+	notifyWebhook      string
+	notifyFormat       string
+	notifyEvents       string
+	summaryOut         string
+	failOnSchemaIssues bool
+	checkPermissions   bool
 }
 
 // Name returns the name of operation.
@@ -68,13 +83,25 @@ specified by setting appropriate params in source-profile. The schema flags are:
 
 // SetFlags sets the flags.
 func (cmd *SchemaCmd) SetFlags(f *flag.FlagSet) {
-	f.StringVar(&cmd.source, "source", "", "Flag for specifying source DB, (e.g., `PostgreSQL`, `MySQL`, `DynamoDB`)")
+	f.StringVar(&cmd.source, "source", "", sourceFlagUsage(false))
 	f.StringVar(&cmd.sourceProfile, "source-profile", "", "Flag for specifying connection profile for source database e.g., \"file=<path>,format=dump\"")
-	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`)")
+	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`, `emulator`). The emulator target uses the Cloud Spanner emulator at SPANNER_EMULATOR_HOST (default localhost:9010)")
 	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"dialect=postgresql\"")
 	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
 	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+	f.StringVar(&cmd.reportFormat, "report-format", constants.ReportFormatText, "Format of the report, in addition to the text report (accepted values: `text`, `html`, `json`)")
+	f.StringVar(&cmd.suppressIssues, "suppress-issues", "", "JSON file with rules for suppressing schema issues from the report, e.g. [{\"issue\": \"Widened\", \"table\": \"t1\"}]")
+	f.StringVar(&cmd.hotspotRemediation, "hotspot-remediation", "", "Remediation for tables whose primary key starts with a timestamp or auto-increment column, which cause write hotspots (accepted values: `bit_reverse`, `hash_prefix`)")
+	f.StringVar(&cmd.syntheticKeyName, "synthetic-key-name", "", "Name of the primary key column added to tables without a primary key, defaults to synth_id")
+	f.StringVar(&cmd.syntheticKeyType, "synthetic-key-type", "", "Type of the primary key added to tables without a primary key (accepted values: `sequence`, `uuid`, `ulid`), defaults to sequence")
+	f.StringVar(&cmd.unsignedOverflow, "unsigned-overflow", "", "Spanner type of unsigned bigint columns, whose values can be larger than the maximum INT64 value (accepted values: `fail`, `numeric`, `string`): INT64, dropping rows with larger values as bad rows, NUMERIC or STRING. Defaults to fail")
 	f.BoolVar(&cmd.dryRun, "dry-run", false, "Flag for generating DDL and schema conversion report without creating a spanner database")
+	f.StringVar(&cmd.notifyWebhook, "notify-webhook", "", "Webhook URL (e.g. a Slack or Microsoft Teams incoming webhook) to post notifications of the milestones and failures of the migration to")
+	f.StringVar(&cmd.notifyFormat, "notify-format", "", "Format of the notifications (accepted values: `slack`, `teams`, `json`), defaults to the format of the webhook's host, or json")
+	f.StringVar(&cmd.notifyEvents, "notify-events", "", "Comma separated list of events to notify, defaults to all events: "+strings.Join(notify.Events, ", "))
+	f.StringVar(&cmd.summaryOut, "summary-out", "", "File to write a JSON summary of the run to, with its status, exit code and row and schema issue counts, for automation")
+	f.BoolVar(&cmd.failOnSchemaIssues, "fail-on-schema-issues", false, "Exit with status 3 if the converted schema has warnings, other than those suppressed with -suppress-issues")
+	f.BoolVar(&cmd.checkPermissions, "check-permissions", false, checkPermissionsUsage)
 }
 
 func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
@@ -86,6 +113,11 @@ func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interfa
 			logger.Log.Fatal("FATAL error", zap.Error(err))
 		}
 	}()
+	start := time.Now()
+	summary := internal.NewRunSummary(cmd.Name())
+	defer func() {
+		finishRun(cmd.summaryOut, summary, start, err)
+	}()
 	err = logger.InitializeLogger(cmd.logLevel)
 	if err != nil {
 		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
@@ -93,11 +125,35 @@ func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interfa
 	}
 	defer logger.Log.Sync()
 
+	if err = validateReportFormat(cmd.reportFormat); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if err = configureNotifications(cmd.notifyWebhook, cmd.notifyFormat, cmd.notifyEvents, cmd.Name()); err != nil {
+		return subcommands.ExitUsageError
+	}
+
+	if err = validateHotspotRemediation(cmd.hotspotRemediation); err != nil {
+		return subcommands.ExitUsageError
+	}
+
+	var suppressions []internal.IssueSuppression
+	if suppressions, err = readIssueSuppressions(cmd.suppressIssues); err != nil {
+		return subcommands.ExitUsageError
+	}
+
 	sourceProfile, targetProfile, ioHelper, dbName, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
 	if err != nil {
 		err = fmt.Errorf("error while preparing prerequisites for migration: %v", err)
 		return subcommands.ExitUsageError
 	}
+	if err = migration.ConfigureTarget(cmd.target, &targetProfile); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if cmd.checkPermissions && !cmd.dryRun {
+		if err = checkPermissions(ctx, sourceProfile, &targetProfile, "", ""); err != nil {
+			return subcommands.ExitFailure
+		}
+	}
 
 	// If filePrefix not explicitly set, use generated dbName.
 	if cmd.filePrefix == "" {
@@ -110,13 +166,26 @@ func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interfa
 	if err != nil {
 		return subcommands.ExitFailure
 	}
+	conv.SetIssueSuppressions(suppressions)
+	if err = conv.SetSyntheticKeys(cmd.syntheticKeyName, cmd.syntheticKeyType); err != nil {
+		err = fmt.Errorf("can't configure synthetic primary keys: %v", err)
+		return subcommands.ExitUsageError
+	}
+	if cmd.unsignedOverflow != "" {
+		if err = conv.SetUnsignedOverflow(cmd.unsignedOverflow); err != nil {
+			return subcommands.ExitUsageError
+		}
+	}
+	if err = handleHotspots(conv, cmd.hotspotRemediation); err != nil {
+		return subcommands.ExitFailure
+	}
 
 	conversion.WriteSchemaFile(conv, schemaConversionStartTime, cmd.filePrefix+schemaFile, ioHelper.Out)
 	conversion.WriteSessionFile(conv, cmd.filePrefix+sessionFile, ioHelper.Out)
 
 	// Populate migration request id and migration type in conv object.
 	conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
-	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_ONLY.Enum()
+	conv.Audit.MigrationType = migrationpb.MigrationData_SCHEMA_ONLY.Enum()
 
 	var (
 		adminClient *database.DatabaseAdminClient
@@ -138,11 +207,18 @@ func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interfa
 		}
 	}
 
+	notify.Sendf(notify.SchemaConverted, "schema conversion done: %d tables for database %s.", len(conv.SpSchema), dbName)
 	schemaCoversionEndTime := time.Now()
 	conv.Audit.SchemaConversionDuration = schemaCoversionEndTime.Sub(schemaConversionStartTime)
 	banner := utils.GetBanner(schemaConversionStartTime, dbName)
 	conversion.Report(sourceProfile.Driver, nil, ioHelper.BytesRead, banner, conv, cmd.filePrefix+reportFile, ioHelper.Out)
+	writeFormattedReport(cmd.reportFormat, sourceProfile.Driver, nil, banner, conv, cmd.filePrefix, true, ioHelper.Out)
 	// Cleanup hb tmp data directory.
 	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
-	return subcommands.ExitSuccess
+	summary.Driver, summary.Database, summary.DryRun, summary.Report = sourceProfile.Driver, dbName, cmd.dryRun, cmd.filePrefix+reportFile
+	if dbURI != "" {
+		summary.Database = dbURI
+	}
+	summary.AddStats(conv, nil)
+	return runStatus(summary, cmd.failOnSchemaIssues, 1)
 }
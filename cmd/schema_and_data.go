@@ -20,16 +20,19 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	sp "cloud.google.com/go/spanner"
 	database "cloud.google.com/go/spanner/admin/database/apiv1"
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/notify"
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
 	"github.com/cloudspannerecosystem/harbourbridge/conversion"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
-	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
+	migrationpb "github.com/cloudspannerecosystem/harbourbridge/proto/migration"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/writer"
 	"github.com/google/subcommands"
 	"github.com/google/uuid"
@@ -38,15 +41,48 @@ import (
 
 // SchemaAndDataCmd struct with flags.
 type SchemaAndDataCmd struct {
-	source          string
-	sourceProfile   string
-	target          string
-	targetProfile   string
-	skipForeignKeys bool
-	filePrefix      string // TODO: move filePrefix to global flags
-	writeLimit      int64
-	dryRun          bool
-	logLevel        string
+	source              string
+	sourceProfile       string
+	target              string
+	targetProfile       string
+	skipForeignKeys     bool
+	deferIndexes        bool
+	verifyForeignKeys   bool
+	filePrefix          string // TODO: move filePrefix to global flags
+	writeLimit          int64
+	dryRun              bool
+	logLevel            string
+	badDataSampleSize   int
+	badDataDir          string
+	oversizedValues     string
+	oversizedSpillURI   string
+	checkPermissions    bool
+	invalidDates        string
+	invalidDateSentinel string
+	datetimeZone        string
+	reportFormat        string
+	suppressIssues      string
+	hotspotRemediation  string
+	syntheticKeyName    string
+	syntheticKeyType    string
+	unsignedOverflow    string
+	fixups              string
+	writeTuning         string
+	maxBatchBytes       int64
+	chaos               string
+	migrationCatalog    bool
+	force               bool
+	maxBadRows          string
+	maxDroppedRows      string
+	errorBudgetAction   string
+	windows             string
+	windowsTimezone     string
+	notifyWebhook       string
+	notifyFormat        string
+	notifyEvents        string
+	summaryOut          string
+	failOnSchemaIssues  bool
+	maxBadRowRate       float64
 }
 
 // Name returns the name of operation.
@@ -72,15 +108,48 @@ by setting appropriate params in source-profile. The schema-and-data flags are:
 
 // SetFlags sets the flags.
 func (cmd *SchemaAndDataCmd) SetFlags(f *flag.FlagSet) {
-	f.StringVar(&cmd.source, "source", "", "Flag for specifying source DB, (e.g., `PostgreSQL`, `MySQL`, `DynamoDB`)")
+	f.StringVar(&cmd.source, "source", "", sourceFlagUsage(false))
 	f.StringVar(&cmd.sourceProfile, "source-profile", "", "Flag for specifying connection profile for source database e.g., \"file=<path>,format=dump\"")
-	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`)")
+	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`, `emulator`). The emulator target uses the Cloud Spanner emulator at SPANNER_EMULATOR_HOST (default localhost:9010)")
 	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"dialect=postgresql\"")
 	f.BoolVar(&cmd.skipForeignKeys, "skip-foreign-keys", false, "Skip creating foreign keys after data migration is complete (ddl statements for foreign keys can still be found in the downloaded schema.ddl.txt file and the same can be applied separately)")
+	f.BoolVar(&cmd.verifyForeignKeys, "verify-foreign-keys", false, "Check that the migrated data satisfies each foreign key before creating it, and skip (and report) foreign keys that are violated")
+	f.BoolVar(&cmd.deferIndexes, "defer-indexes", false, "Create secondary indexes after data migration is complete instead of before it, which speeds up bulk loading")
 	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
 	f.Int64Var(&cmd.writeLimit, "write-limit", defaultWritersLimit, "Write limit for writes to spanner")
 	f.BoolVar(&cmd.dryRun, "dry-run", false, "Flag for generating DDL and schema conversion report without creating a spanner database")
 	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+	f.StringVar(&cmd.reportFormat, "report-format", constants.ReportFormatText, "Format of the report, in addition to the text report (accepted values: `text`, `html`, `json`)")
+	f.StringVar(&cmd.hotspotRemediation, "hotspot-remediation", "", "Remediation for tables whose primary key starts with a timestamp or auto-increment column, which cause write hotspots (accepted values: `bit_reverse`, `hash_prefix`)")
+	f.StringVar(&cmd.syntheticKeyName, "synthetic-key-name", "", "Name of the primary key column added to tables without a primary key, defaults to synth_id")
+	f.StringVar(&cmd.syntheticKeyType, "synthetic-key-type", "", "Type of the primary key added to tables without a primary key (accepted values: `sequence`, `uuid`, `ulid`), defaults to sequence")
+	f.StringVar(&cmd.unsignedOverflow, "unsigned-overflow", "", "Spanner type of unsigned bigint columns, whose values can be larger than the maximum INT64 value (accepted values: `fail`, `numeric`, `string`): INT64, dropping rows with larger values as bad rows, NUMERIC or STRING. Defaults to fail")
+	f.StringVar(&cmd.suppressIssues, "suppress-issues", "", "JSON file with rules for suppressing schema issues from the report, e.g. [{\"issue\": \"Widened\", \"table\": \"t1\"}]")
+	f.IntVar(&cmd.badDataSampleSize, "bad-data-sample-size", internal.DefaultBadDataSampleSize, "Number of bad rows of each kind to write to the bad data file")
+	f.StringVar(&cmd.badDataDir, "bad-data-dir", "", "Directory to write all bad rows to, partitioned by table and error type as JSON lines files (default: only a sample of bad rows is written to the bad data file)")
+	f.StringVar(&cmd.oversizedValues, "oversized-values", internal.OversizeDrop, "How to handle STRING and BYTES values larger than Spanner's 10MB limit (accepted values: `drop`, `truncate`, `spill`): drop the row to the bad data, truncate the value, or spill it to oversized-values-spill-uri and write its URI instead")
+	f.StringVar(&cmd.oversizedSpillURI, "oversized-values-spill-uri", "", "GCS location (gs://bucket/path) to spill oversized values to, with -oversized-values=spill")
+	f.BoolVar(&cmd.checkPermissions, "check-permissions", false, checkPermissionsUsage)
+	f.StringVar(&cmd.invalidDates, "invalid-dates", internal.InvalidDateDrop, "How to handle source dates and datetimes that aren't valid Spanner values, e.g. MySQL's '0000-00-00' (accepted values: `drop`, `null`, `sentinel`): drop the row to the bad data, write NULL, or write invalid-date-sentinel")
+	f.StringVar(&cmd.invalidDateSentinel, "invalid-date-sentinel", internal.DefaultDateSentinel, "Date (YYYY-MM-DD) written instead of invalid dates with -invalid-dates=sentinel; timestamp columns get midnight UTC of this date")
+	f.StringVar(&cmd.writeTuning, "write-tuning", "", "JSON file with the parallelism of the writes of some tables and whether to auto-tune parallelism, e.g. {\"autoTune\": true, \"tables\": {\"t1\": {\"writeLimit\": 4, \"batchRows\": 500}}}")
+	f.Int64Var(&cmd.maxBatchBytes, "max-batch-bytes", 0, "Limit on the bytes of each write to Spanner and of the rows buffered for writing, to bound memory use for very wide rows (0 for Spanner's limits only)")
+	f.StringVar(&cmd.chaos, "chaos", "", "Test mode injecting faults at the given rates (0 to 1) to check retries before a production run, e.g. \"spanner-errors=0.05,throttling=0.1,timeouts=0.01,seed=1\": transient errors of writes to Spanner, throttling of reads from AWS, and timeouts of both")
+	f.BoolVar(&cmd.migrationCatalog, "migration-catalog", false, "Record the migration run, its source, schema versions, row counts and row count validation in tables harbourbridge_migration and harbourbridge_migration_tables of the target database")
+	f.BoolVar(&cmd.force, "force", false, "Migrate even if another migration holds a lease on the tables of the target database, e.g. because it crashed, taking over its lease")
+	f.StringVar(&cmd.fixups, "fixups", "", "File with DML statements separated by semicolons, e.g. to backfill columns or normalize values, that are run as Partitioned DML after data migration and listed in the report")
+	f.StringVar(&cmd.datetimeZone, "datetime-timezone", "", "IANA time zone (e.g. America/New_York) that source datetimes without time zone, such as MySQL DATETIME, are assumed to be in when converted to Spanner TIMESTAMP, defaults to UTC. Per-column overrides can be set in the web UI")
+	f.StringVar(&cmd.maxBadRows, "max-bad-rows", "", "Limits on the rows that can't be converted, as a count or a percentage of rows, over all tables and per source table, e.g. \"0.1%,orders=0\"")
+	f.StringVar(&cmd.maxDroppedRows, "max-dropped-rows", "", "Limits on the rows that can't be written to Spanner, as a count or a percentage of rows, over all tables and per source table, e.g. \"100,orders=0\"")
+	f.StringVar(&cmd.errorBudgetAction, "error-budget-action", internal.ErrorBudgetFail, "What to do when -max-bad-rows or -max-dropped-rows is exceeded (accepted values: `fail`, `abort`): complete the migration and exit with status 4, or also stop the data migration as soon as a count limit is exceeded")
+	f.StringVar(&cmd.windows, "windows", "", "Comma separated daily time windows (HH:MM-HH:MM) data migration is restricted to, e.g. \"00:00-06:00\": outside them, data migration pauses at its next checkpoint (a table, or a chunk of rows for sources read in chunks) and resumes from it once a window opens")
+	f.StringVar(&cmd.windowsTimezone, "windows-timezone", "", "Time zone of -windows as an IANA name, e.g. America/New_York, normally the source database's, defaults to the local time zone")
+	f.StringVar(&cmd.notifyWebhook, "notify-webhook", "", "Webhook URL (e.g. a Slack or Microsoft Teams incoming webhook) to post notifications of the milestones and failures of the migration to")
+	f.StringVar(&cmd.notifyFormat, "notify-format", "", "Format of the notifications (accepted values: `slack`, `teams`, `json`), defaults to the format of the webhook's host, or json")
+	f.StringVar(&cmd.notifyEvents, "notify-events", "", "Comma separated list of events to notify, defaults to all events: "+strings.Join(notify.Events, ", "))
+	f.StringVar(&cmd.summaryOut, "summary-out", "", "File to write a JSON summary of the run to, with its status, exit code and row and schema issue counts, for automation")
+	f.BoolVar(&cmd.failOnSchemaIssues, "fail-on-schema-issues", false, "Exit with status 3 if the converted schema has warnings, other than those suppressed with -suppress-issues")
+	f.Float64Var(&cmd.maxBadRowRate, "max-bad-row-rate", 1, "Exit with status 4 if the fraction of rows that couldn't be converted or written exceeds this rate (0 to 1, e.g. 0 to fail on any bad row)")
 }
 
 func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
@@ -92,6 +161,11 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 			logger.Log.Fatal("FATAL error", zap.Error(err))
 		}
 	}()
+	start := time.Now()
+	summary := internal.NewRunSummary(cmd.Name())
+	defer func() {
+		finishRun(cmd.summaryOut, summary, start, err)
+	}()
 	err = logger.InitializeLogger(cmd.logLevel)
 	if err != nil {
 		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
@@ -99,11 +173,58 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 	}
 	defer logger.Log.Sync()
 
+	if err = validateReportFormat(cmd.reportFormat); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if err = configureNotifications(cmd.notifyWebhook, cmd.notifyFormat, cmd.notifyEvents, cmd.Name()); err != nil {
+		return subcommands.ExitUsageError
+	}
+
+	if err = validateHotspotRemediation(cmd.hotspotRemediation); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if err = validateMaxBadRowRate(cmd.maxBadRowRate); err != nil {
+		return subcommands.ExitUsageError
+	}
+
+	var suppressions []internal.IssueSuppression
+	if suppressions, err = readIssueSuppressions(cmd.suppressIssues); err != nil {
+		return subcommands.ExitUsageError
+	}
+	var fixups []string
+	if fixups, err = readFixups(cmd.fixups); err != nil {
+		return subcommands.ExitUsageError
+	}
+	var writeTuning internal.WriteTuning
+	if writeTuning, err = readWriteTuning(cmd.writeTuning); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if cmd.maxBatchBytes < 0 {
+		err = fmt.Errorf("max-batch-bytes can't be negative, found %d", cmd.maxBatchBytes)
+		return subcommands.ExitUsageError
+	}
+	if err = configureChaos(cmd.chaos); err != nil {
+		err = fmt.Errorf("can't configure chaos mode: %v", err)
+		return subcommands.ExitUsageError
+	}
+
 	sourceProfile, targetProfile, ioHelper, dbName, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
 	if err != nil {
 		err = fmt.Errorf("error while preparing prerequisites for migration: %v", err)
 		return subcommands.ExitUsageError
 	}
+	if err = migration.ConfigureTarget(cmd.target, &targetProfile); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if cmd.checkPermissions && !cmd.dryRun {
+		var spillURI string
+		if cmd.oversizedValues == internal.OversizeSpill {
+			spillURI = cmd.oversizedSpillURI
+		}
+		if err = checkPermissions(ctx, sourceProfile, &targetProfile, spillURI, ""); err != nil {
+			return subcommands.ExitFailure
+		}
+	}
 	schemaConversionStartTime := time.Now()
 
 	// If filePrefix not explicitly set, use dbName as prefix.
@@ -123,12 +244,61 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 	if err != nil {
 		panic(err)
 	}
+	conv.SetIssueSuppressions(suppressions)
+	for _, f := range fixups {
+		conv.AddFixup(f, internal.FixupUser)
+	}
+	conv.WriteTuning = writeTuning
+	if cmd.maxBatchBytes > 0 {
+		conv.WriteTuning.MaxBatchBytes = cmd.maxBatchBytes
+	}
+	if err = configureErrorBudget(conv, cmd.maxBadRows, cmd.maxDroppedRows, cmd.errorBudgetAction); err != nil {
+		return subcommands.ExitUsageError
+	}
+	if conv.Schedule, err = internal.ParseSchedule(cmd.windows, cmd.windowsTimezone); err != nil {
+		err = fmt.Errorf("invalid windows: %v", err)
+		return subcommands.ExitUsageError
+	}
+	if err = conv.SetSyntheticKeys(cmd.syntheticKeyName, cmd.syntheticKeyType); err != nil {
+		err = fmt.Errorf("can't configure synthetic primary keys: %v", err)
+		return subcommands.ExitUsageError
+	}
+	if cmd.unsignedOverflow != "" {
+		if err = conv.SetUnsignedOverflow(cmd.unsignedOverflow); err != nil {
+			return subcommands.ExitUsageError
+		}
+	}
+	if err = handleHotspots(conv, cmd.hotspotRemediation); err != nil {
+		return subcommands.ExitFailure
+	}
 	schemaCoversionEndTime := time.Now()
 	conv.Audit.SchemaConversionDuration = schemaCoversionEndTime.Sub(schemaConversionStartTime)
+	closeBadData, err := ConfigureBadData(conv, cmd.badDataSampleSize, cmd.badDataDir, ioHelper.Out)
+	if err != nil {
+		err = fmt.Errorf("can't configure bad data: %v", err)
+		return subcommands.ExitUsageError
+	}
+	defer closeBadData()
+	closeSpill, err := ConfigureOversizedValues(ctx, conv, cmd.oversizedValues, cmd.oversizedSpillURI)
+	if err != nil {
+		err = fmt.Errorf("can't configure oversized values: %v", err)
+		return subcommands.ExitUsageError
+	}
+	defer closeSpill()
+	if err = conv.SetInvalidDatePolicy(cmd.invalidDates, cmd.invalidDateSentinel); err != nil {
+		err = fmt.Errorf("can't configure invalid dates: %v", err)
+		return subcommands.ExitUsageError
+	}
+	if cmd.datetimeZone != "" {
+		if err = conv.SetDatetimeZone("", "", cmd.datetimeZone); err != nil {
+			err = fmt.Errorf("can't configure datetime time zone: %v", err)
+			return subcommands.ExitUsageError
+		}
+	}
 
 	// Populate migration request id and migration type in conv object.
 	conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
-	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_AND_DATA.Enum()
+	conv.Audit.MigrationType = migrationpb.MigrationData_SCHEMA_AND_DATA.Enum()
 
 	conversion.WriteSchemaFile(conv, schemaConversionStartTime, cmd.filePrefix+schemaFile, ioHelper.Out)
 	conversion.WriteSessionFile(conv, cmd.filePrefix+sessionFile, ioHelper.Out)
@@ -143,11 +313,14 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 		defer adminClient.Close()
 		defer client.Close()
 
+		conv.DeferIndexes = cmd.deferIndexes
+		conv.ForceLock = cmd.force
 		err = conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, sourceProfile.Driver, targetProfile.TargetDb, conv, ioHelper.Out)
 		if err != nil {
 			err = fmt.Errorf("can't create/update database: %v", err)
 			return subcommands.ExitFailure
 		}
+		notify.Sendf(notify.SchemaConverted, "schema conversion done: %d tables for database %s.", len(conv.SpSchema), dbURI)
 		schemaCoversionEndTime := time.Now()
 		conv.Audit.SchemaConversionDuration = schemaCoversionEndTime.Sub(schemaConversionStartTime)
 
@@ -156,14 +329,49 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 			err = fmt.Errorf("can't finish data conversion for db %s: %v", dbURI, err)
 			return subcommands.ExitFailure
 		}
-		if !cmd.skipForeignKeys {
-			if err = conversion.UpdateDDLForeignKeys(ctx, adminClient, dbURI, conv, ioHelper.Out); err != nil {
-				err = fmt.Errorf("can't perform update schema on db %s with foreign keys: %v", dbURI, err)
-				return subcommands.ExitFailure
+		notify.Send(notify.BulkLoadDone, bulkLoadMessage(conv, bw.DroppedRowsByTable(), dbURI))
+		// Row counts are validated before fixups, which may change them.
+		var (
+			mismatches []conversion.RowCountMismatch
+			validated  bool
+		)
+		if reason := conv.ErrorBudget.Aborted(); reason != "" {
+			fmt.Fprintf(ioHelper.Out, "Data migration aborted: %s. Skipping validation, fix-ups, indexes and foreign keys.\n", reason)
+			notify.Sendf(notify.ErrorsAboveThreshold, "data migration to %s aborted: %s.", dbURI, reason)
+		} else {
+			if cmd.migrationCatalog {
+				mismatches, validated = validateRowCounts(ctx, client, conv, bw.DroppedRowsByTable(), ioHelper.Out)
+			}
+			addValidation(summary, mismatches, validated)
+			conversion.RunFixups(ctx, client, conv, ioHelper.Out)
+			if cmd.deferIndexes {
+				if err = conversion.VerifyUniqueIndexes(ctx, client, conv, ioHelper.Out); err != nil {
+					err = fmt.Errorf("can't verify unique indexes of db %s: %v", dbURI, err)
+					return subcommands.ExitFailure
+				}
+				if err = conversion.CreateIndexes(ctx, adminClient, dbURI, conv, ioHelper.Out); err != nil {
+					err = fmt.Errorf("can't perform update schema on db %s with secondary indexes: %v", dbURI, err)
+					return subcommands.ExitFailure
+				}
+			}
+			if !cmd.skipForeignKeys {
+				if cmd.verifyForeignKeys {
+					if err = conversion.VerifyForeignKeys(ctx, client, conv, ioHelper.Out); err != nil {
+						err = fmt.Errorf("can't verify foreign keys of db %s: %v", dbURI, err)
+						return subcommands.ExitFailure
+					}
+				}
+				if err = conversion.UpdateDDLForeignKeys(ctx, adminClient, dbURI, conv, ioHelper.Out); err != nil {
+					err = fmt.Errorf("can't perform update schema on db %s with foreign keys: %v", dbURI, err)
+					return subcommands.ExitFailure
+				}
 			}
 		}
 		dataCoversionEndTime := time.Now()
 		conv.Audit.DataConversionDuration = dataCoversionEndTime.Sub(schemaCoversionEndTime)
+		if cmd.migrationCatalog {
+			recordMigration(ctx, adminClient, client, dbURI, conv, sourceProfile, schemaConversionStartTime, bw.DroppedRowsByTable(), mismatches, validated, ioHelper.Out)
+		}
 		banner = utils.GetBanner(schemaConversionStartTime, dbURI)
 
 	} else {
@@ -180,9 +388,17 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 		banner = utils.GetBanner(schemaConversionStartTime, dbName)
 	}
 	conversion.Report(sourceProfile.Driver, bw.DroppedRowsByTable(), ioHelper.BytesRead, banner, conv, cmd.filePrefix+reportFile, ioHelper.Out)
+	writeFormattedReport(cmd.reportFormat, sourceProfile.Driver, bw.DroppedRowsByTable(), banner, conv, cmd.filePrefix, true, ioHelper.Out)
 	conversion.WriteBadData(bw, conv, banner, cmd.filePrefix+badDataFile, ioHelper.Out)
+	reportChaos(ioHelper.Out)
 
 	// Cleanup hb tmp data directory.
 	os.RemoveAll(os.TempDir() + constants.HB_TMP_DIR)
-	return subcommands.ExitSuccess
+	summary.Driver, summary.Database, summary.DryRun, summary.Report = sourceProfile.Driver, dbName, cmd.dryRun, cmd.filePrefix+reportFile
+	if dbURI != "" {
+		summary.Database = dbURI
+	}
+	summary.AddStats(conv, bw.DroppedRowsByTable())
+	checkErrorBudget(conv, bw.DroppedRowsByTable(), summary, ioHelper.Out)
+	return runStatus(summary, cmd.failOnSchemaIssues, cmd.maxBadRowRate)
 }
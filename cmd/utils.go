@@ -17,36 +17,30 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	sp "cloud.google.com/go/spanner"
 	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"github.com/cloudspannerecosystem/harbourbridge/common/chaos"
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/notify"
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
 	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+	"github.com/google/subcommands"
 )
 
 // CreateDatabaseClient creates new database client and admin client.
 func CreateDatabaseClient(ctx context.Context, targetProfile profiles.TargetProfile, driver string, ioHelper utils.IOStreams) (*database.DatabaseAdminClient, *sp.Client, string, error) {
-	project, instance, dbName, err := targetProfile.GetResourceIds(ctx, time.Now(), driver, ioHelper.Out)
-	if err != nil {
-		return nil, nil, "", err
-	}
-	fmt.Println("Using Google Cloud project:", project)
-	fmt.Println("Using Cloud Spanner instance:", instance)
-	utils.PrintPermissionsWarning(driver, ioHelper.Out)
-
-	dbURI := fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, dbName)
-	adminClient, err := utils.NewDatabaseAdminClient(ctx)
-	if err != nil {
-		err = fmt.Errorf("can't create admin client: %v", utils.AnalyzeError(err, dbURI))
-		return nil, nil, dbURI, err
-	}
-	client, err := utils.GetClient(ctx, dbURI)
-	if err != nil {
-		err = fmt.Errorf("can't create client for db %s: %v", dbURI, err)
-		return adminClient, nil, dbURI, err
-	}
-	return adminClient, client, dbURI, nil
+	return migration.CreateDatabaseClient(ctx, targetProfile, driver, ioHelper.Out)
 }
 
 // PrepareMigrationPrerequisites creates source and target profiles, opens a new IOStream and generates the database name.
@@ -55,10 +49,14 @@ func PrepareMigrationPrerequisites(sourceProfileString, targetProfileString, sou
 	if err != nil {
 		return profiles.SourceProfile{}, profiles.TargetProfile{}, utils.IOStreams{}, "", err
 	}
-	sourceProfile.Driver, err = sourceProfile.ToLegacyDriver(source)
+	sourceProfile.Driver, err = migration.SourceDriver(sourceProfile, source)
 	if err != nil {
 		return profiles.SourceProfile{}, profiles.TargetProfile{}, utils.IOStreams{}, "", err
 	}
+	// Tunnels to the source databases stay open until the process exits.
+	if _, err = sourceProfile.OpenTunnels(); err != nil {
+		return profiles.SourceProfile{}, profiles.TargetProfile{}, utils.IOStreams{}, "", err
+	}
 
 	targetProfile, err := profiles.NewTargetProfile(targetProfileString)
 	if err != nil {
@@ -82,3 +80,361 @@ func PrepareMigrationPrerequisites(sourceProfileString, targetProfileString, sou
 	}
 	return sourceProfile, targetProfile, ioHelper, dbName, nil
 }
+
+// checkPermissionsUsage is the usage of the -check-permissions flag.
+const checkPermissionsUsage = "Test the IAM permissions the migration needs on Spanner, GCS and Cloud Monitoring before starting it, and fail listing the missing ones, the roles that grant them and the gcloud commands to grant these roles"
+
+// checkPermissions tests the IAM permissions that the migration of
+// sourceProfile to the database of targetProfile needs up front, spilling
+// oversized values to spillURI and writing metrics to monitoringProject if
+// they are set. It prints how to grant the missing permissions, if any.
+func checkPermissions(ctx context.Context, sourceProfile profiles.SourceProfile, targetProfile *profiles.TargetProfile, spillURI, monitoringProject string) error {
+	access := migration.Access{MonitoringProject: monitoringProject}
+	if sourceProfile.Ty == profiles.SourceProfileTypeFile && strings.HasPrefix(sourceProfile.File.Path, "gs://") {
+		access.ReadGCS = append(access.ReadGCS, sourceProfile.File.Path)
+	}
+	if spillURI != "" {
+		access.WriteGCS = append(access.WriteGCS, spillURI)
+	}
+	missing, err := migration.CheckPermissions(ctx, targetProfile, sourceProfile.Driver, access)
+	if err != nil {
+		return fmt.Errorf("can't check IAM permissions: %v", err)
+	}
+	if len(missing) > 0 {
+		fmt.Println(utils.DiagnoseMissingPermissions(missing))
+		return fmt.Errorf("missing %d IAM permission(s) for the migration", len(missing))
+	}
+	fmt.Println("All the IAM permissions of the migration are granted.")
+	return nil
+}
+
+// sourceFlagUsage returns the usage of the -source flag, listing the sources
+// of the registered drivers, or only those that support streaming.
+func sourceFlagUsage(streaming bool) string {
+	sources := registry.Sources()
+	if streaming {
+		sources = streamingSources()
+	}
+	return fmt.Sprintf("Flag for specifying source DB (one of %s)", strings.Join(sources, ", "))
+}
+
+// streamingSources returns the sorted sources of the registered drivers that
+// support change data capture.
+func streamingSources() []string {
+	var sources []string
+	for _, d := range registry.List() {
+		if d.Info().Streaming {
+			sources = append(sources, d.Info().Source)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// ConfigureBadData sets the number of bad rows of each kind written to the bad data
+// file and, if badDataDir is not empty, configures conv to write all bad rows to
+// badDataDir. It returns a function to be called once data migration is complete,
+// which flushes the rows written to badDataDir.
+func ConfigureBadData(conv *internal.Conv, sampleSize int, badDataDir string, out *os.File) (func(), error) {
+	if sampleSize <= 0 {
+		return nil, fmt.Errorf("bad-data-sample-size must be positive, got %d", sampleSize)
+	}
+	conv.SetBadDataSampleSize(sampleSize)
+	if badDataDir == "" {
+		return func() {}, nil
+	}
+	bdw, err := internal.NewBadDataWriter(badDataDir)
+	if err != nil {
+		return nil, err
+	}
+	conv.SetBadDataWriter(bdw)
+	return func() {
+		if err := bdw.Close(); err != nil {
+			fmt.Fprintf(out, "%v\n", err)
+		}
+	}, nil
+}
+
+// ConfigureOversizedValues configures how conv handles converted values
+// larger than Spanner's limit, with policy one of internal.OversizePolicies.
+// Values are spilled to GCS location spillURI with the spill policy. It
+// returns a function to be called once data migration is complete.
+func ConfigureOversizedValues(ctx context.Context, conv *internal.Conv, policy, spillURI string) (func(), error) {
+	if policy != internal.OversizeSpill {
+		if spillURI != "" {
+			return nil, fmt.Errorf("oversized-values-spill-uri is only used with oversized-values=%s", internal.OversizeSpill)
+		}
+		return func() {}, conv.SetOversizePolicy(policy, nil)
+	}
+	if spillURI == "" {
+		return nil, fmt.Errorf("oversized-values=%s requires oversized-values-spill-uri", internal.OversizeSpill)
+	}
+	spill, closeSpill, err := utils.NewGCSSpiller(ctx, spillURI)
+	if err != nil {
+		return nil, err
+	}
+	return closeSpill, conv.SetOversizePolicy(policy, spill)
+}
+
+// reportFormats lists the accepted values of the report-format flag.
+var reportFormats = []string{constants.ReportFormatText, constants.ReportFormatHTML, constants.ReportFormatJSON}
+
+func validateReportFormat(format string) error {
+	for _, f := range reportFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid report-format %s, accepted values are: %s", format, strings.Join(reportFormats, ", "))
+}
+
+// writeFormattedReport writes the report in reportFormat alongside the text report,
+// e.g. <prefix>report.html for the html format. If withDDL is set, the report links
+// to the DDL file generated by conversion.WriteSchemaFile.
+func writeFormattedReport(reportFormat, driver string, badWrites map[string]int64, banner string, conv *internal.Conv, filePrefix string, withDDL bool, out *os.File) {
+	if reportFormat == constants.ReportFormatText {
+		return
+	}
+	name := filePrefix + strings.TrimSuffix(reportFile, filepath.Ext(reportFile)) + "." + reportFormat
+	ddlFile := ""
+	if withDDL {
+		ddlFile = filepath.Base(filePrefix + strings.TrimSuffix(schemaFile, filepath.Ext(schemaFile)) + ".ddl" + filepath.Ext(schemaFile))
+	}
+	conversion.WriteFormattedReport(reportFormat, driver, badWrites, banner, conv, name, ddlFile, out)
+}
+
+// readIssueSuppressions reads the rules for suppressing schema issues from
+// file 'name', if set.
+func readIssueSuppressions(name string) ([]internal.IssueSuppression, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return internal.ReadIssueSuppressions(name)
+}
+
+// readFixups reads the fix-up statements in file 'name', if set: DML
+// statements separated by semicolons.
+func readFixups(name string) ([]string, error) {
+	if name == "" {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't read fix-up statements: %v", err)
+	}
+	return internal.SplitStatements(string(b)), nil
+}
+
+// readWriteTuning reads the tuning of the writes of data migration from
+// file 'name', if set.
+func readWriteTuning(name string) (internal.WriteTuning, error) {
+	if name == "" {
+		return internal.WriteTuning{}, nil
+	}
+	return internal.ReadWriteTuning(name)
+}
+
+// configureChaos enables chaos mode with the configuration of the chaos flag,
+// if set. It must be called before the Spanner and source clients are
+// created.
+func configureChaos(config string) error {
+	c, err := chaos.Parse(config)
+	if err != nil {
+		return err
+	}
+	chaos.Configure(c)
+	return nil
+}
+
+// reportChaos writes the number of faults injected by chaos mode to out, if
+// it is enabled.
+func reportChaos(out *os.File) {
+	if s := chaos.Summary(); s != "" {
+		fmt.Fprintf(out, "%s\n", s)
+	}
+}
+
+// validateRowCounts validates the row counts of the tables of the database of
+// client for the migration catalog. It returns the mismatches found, and
+// false if row counts couldn't be validated, which is reported to out.
+func validateRowCounts(ctx context.Context, client *sp.Client, conv *internal.Conv, droppedRows map[string]int64, out *os.File) ([]conversion.RowCountMismatch, bool) {
+	mismatches, err := conversion.ValidateRowCounts(ctx, client, conv, droppedRows, out)
+	if err != nil {
+		fmt.Fprintf(out, "Can't validate row counts for the migration catalog: %v\n", err)
+		return nil, false
+	}
+	return mismatches, true
+}
+
+// recordMigration records the migration run of conv in the migration catalog
+// of database dbURI. Failures are reported to out, rather than failing a
+// migration whose data has been written already.
+func recordMigration(ctx context.Context, adminClient *database.DatabaseAdminClient, client *sp.Client, dbURI string, conv *internal.Conv, sourceProfile profiles.SourceProfile, startedAt time.Time, droppedRows map[string]int64, mismatches []conversion.RowCountMismatch, validated bool, out *os.File) {
+	if err := conversion.RecordMigration(ctx, adminClient, client, dbURI, conv, sourceProfile, startedAt, droppedRows, mismatches, validated, out); err != nil {
+		fmt.Fprintf(out, "Warning: %v\n", err)
+	}
+}
+
+// validateHotspotRemediation checks that remediation is a valid value for the
+// hotspot-remediation flag.
+func validateHotspotRemediation(remediation string) error {
+	switch remediation {
+	case internal.HotspotRemediationNone, internal.HotspotRemediationBitReverse, internal.HotspotRemediationHashPrefix:
+		return nil
+	}
+	return fmt.Errorf("invalid hotspot-remediation %s, accepted values are: %s, %s", remediation, internal.HotspotRemediationBitReverse, internal.HotspotRemediationHashPrefix)
+}
+
+// handleHotspots applies remediation to the tables in conv whose primary keys
+// would cause write hotspots, and records the remaining hotspots as schema
+// issues so that they are shown in the report.
+func handleHotspots(conv *internal.Conv, remediation string) error {
+	if err := internal.RemediateHotspots(conv, internal.DetectHotspots(conv), remediation); err != nil {
+		return err
+	}
+	internal.AddHotspotIssues(conv, internal.DetectHotspots(conv))
+	return nil
+}
+
+// Exit statuses of migration subcommands that completed with problems.
+// Fatal errors exit with subcommands.ExitFailure.
+const (
+	ExitSchemaIssues     subcommands.ExitStatus = 3
+	ExitDataErrors       subcommands.ExitStatus = 4
+	ExitValidationFailed subcommands.ExitStatus = 5
+)
+
+// runStatus sets the status of the completed run summarized by s, and returns
+// its exit status. Failed validations take precedence over data errors, which
+// take precedence over schema issues.
+func runStatus(s *internal.RunSummary, failOnSchemaIssues bool, maxBadRowRate float64) subcommands.ExitStatus {
+	status, name := subcommands.ExitSuccess, internal.RunSucceeded
+	switch {
+	case len(s.RowCountMismatches) > 0:
+		status, name = ExitValidationFailed, internal.RunValidationFailed
+	case s.BadRowRate() > maxBadRowRate || len(s.BudgetViolations) > 0:
+		status, name = ExitDataErrors, internal.RunDataErrors
+	case failOnSchemaIssues && s.SchemaWarnings > 0:
+		status, name = ExitSchemaIssues, internal.RunSchemaIssues
+	}
+	s.Status, s.ExitCode = name, int(status)
+	return status
+}
+
+// addValidation records the row count mismatches found by validating a
+// migration in s, if row counts were validated.
+func addValidation(s *internal.RunSummary, mismatches []conversion.RowCountMismatch, validated bool) {
+	s.Validated = validated
+	for _, m := range mismatches {
+		s.RowCountMismatches = append(s.RowCountMismatches, m.Table)
+	}
+}
+
+// validateMaxBadRowRate checks that rate is a valid value for the
+// max-bad-row-rate flag.
+func validateMaxBadRowRate(rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("max-bad-row-rate must be between 0 and 1, found %v", rate)
+	}
+	return nil
+}
+
+// finishRun completes the summary s of a run that started at start, which
+// is summarized as failed if it was stopped by err, writes it to the file
+// name, if set, and sends the notifications of the end of the run.
+func finishRun(name string, s *internal.RunSummary, start time.Time, err error) {
+	s.DurationSeconds = time.Since(start).Seconds()
+	if err != nil || s.Status == "" {
+		s.Status, s.ExitCode = internal.RunFailed, int(subcommands.ExitFailure)
+	}
+	if err != nil {
+		s.Error = err.Error()
+		if d := utils.PermissionDiagnosis(err, ""); d != "" && !strings.Contains(s.Error, d) {
+			fmt.Fprintln(os.Stderr, d)
+		}
+	}
+	notifyRunEnd(s)
+	writeRunSummary(name, s)
+}
+
+// notifyRunEnd sends the notifications of the end of the run summarized by s.
+func notifyRunEnd(s *internal.RunSummary) {
+	if s.Status == internal.RunFailed {
+		notify.Sendf(notify.RunFailed, "failed: %s", s.Error)
+		return
+	}
+	if s.Status == internal.RunDataErrors {
+		notify.Sendf(notify.ErrorsAboveThreshold, "%d of %d rows couldn't be migrated to %s. %s", s.BadRows, s.Rows, s.Database, strings.Join(s.BudgetViolations, ". "))
+	}
+	notify.Sendf(notify.RunComplete, "completed with status %s (exit code %d) for %s in %s: %d tables, %d rows, %d bad rows.", s.Status, s.ExitCode, s.Database, time.Duration(s.DurationSeconds*float64(time.Second)).Round(time.Second), s.Tables, s.Rows, s.BadRows)
+}
+
+// writeRunSummary writes the summary s of a run to the file name, if set.
+func writeRunSummary(name string, s *internal.RunSummary) {
+	if name == "" {
+		return
+	}
+	f, err := os.Create(name)
+	if err == nil {
+		err = internal.WriteRunSummary(s, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Printf("Can't write run summary to file %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("Wrote run summary to file '%s'.\n", name)
+}
+
+// configureErrorBudget sets the error budget of conv from the values of the
+// max-bad-rows, max-dropped-rows and error-budget-action flags.
+func configureErrorBudget(conv *internal.Conv, maxBadRows, maxDroppedRows, action string) error {
+	if action != internal.ErrorBudgetFail && action != internal.ErrorBudgetAbort {
+		return fmt.Errorf("invalid error-budget-action %s, accepted values are: %s, %s", action, internal.ErrorBudgetFail, internal.ErrorBudgetAbort)
+	}
+	if maxBadRows == "" && maxDroppedRows == "" {
+		return nil
+	}
+	bad, err := internal.ParseRowLimits(maxBadRows)
+	if err != nil {
+		return fmt.Errorf("invalid max-bad-rows: %v", err)
+	}
+	dropped, err := internal.ParseRowLimits(maxDroppedRows)
+	if err != nil {
+		return fmt.Errorf("invalid max-dropped-rows: %v", err)
+	}
+	conv.ErrorBudget = &internal.ErrorBudget{BadRows: bad, DroppedRows: dropped, Abort: action == internal.ErrorBudgetAbort}
+	return nil
+}
+
+// checkErrorBudget records the limits of the error budget of conv exceeded by
+// data migration in s, and reports them to out.
+func checkErrorBudget(conv *internal.Conv, droppedRows map[string]int64, s *internal.RunSummary, out *os.File) {
+	for _, v := range conv.ErrorBudget.Violations(conv, droppedRows) {
+		fmt.Fprintf(out, "Error budget exceeded: %s\n", v)
+		s.BudgetViolations = append(s.BudgetViolations, v)
+	}
+}
+
+// configureNotifications enables notifications of the events in the
+// comma separated list events (all events if empty) of the run of
+// subcommand to webhook, if set, in format.
+func configureNotifications(webhook, format, events, subcommand string) error {
+	e, err := notify.ParseEvents(events)
+	if err != nil {
+		return err
+	}
+	return notify.Configure(notify.Config{Webhook: webhook, Format: format, Events: e, Run: "harbourbridge " + subcommand})
+}
+
+// bulkLoadMessage describes the rows of conv migrated to db, with droppedRows
+// the rows that couldn't be written.
+func bulkLoadMessage(conv *internal.Conv, droppedRows map[string]int64, db string) string {
+	var dropped int64
+	for _, n := range droppedRows {
+		dropped += n
+	}
+	return fmt.Sprintf("bulk load to %s done: %d of %d rows migrated.", db, conv.Rows()-conv.BadRows()-dropped, conv.Rows())
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
+	"github.com/google/subcommands"
+	"go.uber.org/zap"
+)
+
+// ValidateCmd struct with flags.
+type ValidateCmd struct {
+	source        string
+	sourceProfile string
+	target        string
+	targetProfile string
+	sessionJSON   string
+	logLevel      string
+	summaryOut    string
+}
+
+// Name returns the name of operation.
+func (cmd *ValidateCmd) Name() string {
+	return "validate"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *ValidateCmd) Synopsis() string {
+	return "validate the row counts of a migrated Spanner database against the source db"
+}
+
+// Usage returns usage info of the command.
+func (cmd *ValidateCmd) Usage() string {
+	return fmt.Sprintf(`%v validate -session=[session_file] -source=[source] -source-profile="key1=value1,key2=value2" -target-profile="instance=my-instance,dbName=my-db" ...
+
+Compare the row count of each table of the Spanner database specified by
+target-profile with the row count of its source table in the source db, using
+the schema mapping of the session file of the migration. The source db must be
+read directly (not from a dump file) and shouldn't be written to while
+validating. Exits with status 5 if any row count differs. The validate flags
+are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *ValidateCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.source, "source", "", sourceFlagUsage(false))
+	f.StringVar(&cmd.sourceProfile, "source-profile", "", "Flag for specifying connection profile for source database e.g., \"host=localhost,user=admin,dbName=mydb\"")
+	f.StringVar(&cmd.sessionJSON, "session", "", "Specifies the session file of the migration, holding its schema mapping")
+	f.StringVar(&cmd.target, "target", "Spanner", "Specifies the target DB, defaults to Spanner (accepted values: `Spanner`, `emulator`). The emulator target uses the Cloud Spanner emulator at SPANNER_EMULATOR_HOST (default localhost:9010)")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"instance=my-instance,dbName=my-db\"")
+	f.StringVar(&cmd.logLevel, "log-level", "INFO", "Configure the logging level for the command (INFO, DEBUG), defaults to INFO")
+	f.StringVar(&cmd.summaryOut, "summary-out", "", "File to write a JSON summary of the run to, with its status, exit code and row and schema issue counts, for automation")
+}
+
+func (cmd *ValidateCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	var err error
+	defer func() {
+		if err != nil {
+			logger.Log.Fatal("FATAL error", zap.Error(err))
+		}
+	}()
+	start := time.Now()
+	summary := internal.NewRunSummary(cmd.Name())
+	defer func() {
+		finishRun(cmd.summaryOut, summary, start, err)
+	}()
+	err = logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+
+	if cmd.sessionJSON == "" {
+		err = fmt.Errorf("please specify the session file of the migration with -session")
+		return subcommands.ExitUsageError
+	}
+	sourceProfile, targetProfile, ioHelper, _, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
+	if err != nil {
+		err = fmt.Errorf("error while preparing prerequisites for migration: %v", err)
+		return subcommands.ExitUsageError
+	}
+	if targetProfile.Conn.Sp.Dbname == "" {
+		err = fmt.Errorf("please specify the migrated database with dbName in -target-profile")
+		return subcommands.ExitUsageError
+	}
+	if err = migration.ConfigureTarget(cmd.target, &targetProfile); err != nil {
+		return subcommands.ExitUsageError
+	}
+	conv := internal.MakeConv()
+	if err = conversion.ReadSessionFile(conv, cmd.sessionJSON); err != nil {
+		return subcommands.ExitUsageError
+	}
+	adminClient, client, dbURI, err := CreateDatabaseClient(ctx, targetProfile, sourceProfile.Driver, ioHelper)
+	if err != nil {
+		err = fmt.Errorf("can't create database client: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer adminClient.Close()
+	defer client.Close()
+	mismatches, err := conversion.ValidateSourceRowCounts(ctx, sourceProfile, targetProfile, client, conv, ioHelper.Out)
+	if err != nil {
+		err = fmt.Errorf("can't validate row counts of db %s: %v", dbURI, err)
+		return subcommands.ExitFailure
+	}
+	summary.Driver, summary.Database, summary.Tables = sourceProfile.Driver, dbURI, len(conv.SpSchema)
+	addValidation(summary, mismatches, true)
+	if len(mismatches) > 0 {
+		fmt.Fprintf(ioHelper.Out, "Row counts of %d table(s) of db %s don't match the source db.\n", len(mismatches), dbURI)
+	} else {
+		fmt.Fprintf(ioHelper.Out, "Row counts of all %d table(s) of db %s match the source db.\n", len(conv.SpSchema), dbURI)
+	}
+	return runStatus(summary, false, 1)
+}
@@ -0,0 +1,323 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+	"github.com/google/subcommands"
+)
+
+// wizardSources are the sources that the wizard can connect to, whose source
+// profiles all take host, port, user, dbName and password params. Other
+// sources (e.g. DynamoDB and CSV files) are set up with flags.
+var wizardSources = []string{"mysql", "mariadb", "postgres", "sqlserver", "oracle"}
+
+// WizardCmd struct with flags.
+type WizardCmd struct {
+	run bool
+}
+
+// Name returns the name of operation.
+func (cmd *WizardCmd) Name() string {
+	return "wizard"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *WizardCmd) Synopsis() string {
+	return "set up a migration interactively, step by step"
+}
+
+// Usage returns usage info of the command.
+func (cmd *WizardCmd) Usage() string {
+	return fmt.Sprintf(`%v wizard
+
+Guide you through setting up a migration by prompting for the source database
+and its connection params, testing the connection, picking the tables to
+migrate and configuring the target Spanner database. The wizard prints the
+equivalent command, which can be reused in scripts, and offers to run it.
+The wizard flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *WizardCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&cmd.run, "run", false, "Run the command without asking for confirmation")
+}
+
+func (cmd *WizardCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if err := logger.InitializeLogger("INFO"); err != nil {
+		fmt.Println("Error initialising logger", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+	w := &wizard{in: bufio.NewReader(os.Stdin), out: os.Stdout, password: utils.GetPassword}
+	args, err := w.setup()
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "\n%v\n", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Fprintf(os.Stdout, "\nThe equivalent command is:\n\n  %s %s\n\n", path.Base(os.Args[0]), shellJoin(args))
+	if !cmd.run && !w.confirm("Run it now?") {
+		return subcommands.ExitSuccess
+	}
+	fmt.Fprintf(os.Stdout, "You will be asked for the password of the source database again.\n\n")
+	prog, err := os.Executable()
+	if err != nil {
+		prog = os.Args[0]
+	}
+	c := exec.CommandContext(ctx, prog, args...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		fmt.Fprintf(os.Stdout, "\n%v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// wizard prompts for the settings of a migration on out, reading answers
+// from in.
+type wizard struct {
+	in       *bufio.Reader
+	out      io.Writer
+	password func() string
+}
+
+// setup runs the steps of the wizard and returns the arguments of the
+// subcommand that runs the migration set up.
+func (w *wizard) setup() ([]string, error) {
+	fmt.Fprintf(w.out, "This wizard sets up a migration to Cloud Spanner. Press Enter to accept [defaults].\n")
+
+	// Source database.
+	var descriptions []string
+	for _, s := range wizardSources {
+		d, err := registry.Database(s)
+		if err != nil {
+			return nil, err
+		}
+		descriptions = append(descriptions, d.Info().Description)
+	}
+	source := wizardSources[w.choose("\nWhich database do you want to migrate?", descriptions, 0)]
+
+	// Connection params and connection test.
+	var (
+		params []string
+		tables []string
+		names  []string
+	)
+	for {
+		params = []string{
+			"host=" + w.ask("Host", "localhost"),
+			"port=" + w.ask("Port (empty for the default port)", ""),
+			"user=" + w.ask("User", ""),
+			"dbName=" + w.ask("Database name", ""),
+		}
+		pwd := w.password()
+		fmt.Fprintf(w.out, "Testing the connection...\n")
+		var err error
+		tables, names, err = wizardTables(source, profileString(append(params, "password="+pwd)))
+		if err == nil {
+			fmt.Fprintf(w.out, "Connected. Found %d table(s).\n", len(tables))
+			break
+		}
+		fmt.Fprintf(w.out, "Can't connect: %v\n", err)
+		if !w.confirm("Try again with different connection params?") {
+			return nil, fmt.Errorf("couldn't connect to the source database")
+		}
+	}
+
+	// Tables.
+	if len(tables) > 0 {
+		for i, t := range names {
+			fmt.Fprintf(w.out, " %d) %s\n", i+1, t)
+		}
+		for {
+			answer := w.ask("Tables to migrate, as numbers or ranges (e.g. 1,3-5), or empty for all tables", "")
+			if answer == "" {
+				break
+			}
+			picked, err := parseSelection(answer, len(names))
+			if err == nil {
+				var selected []string
+				for _, i := range picked {
+					selected = append(selected, names[i])
+				}
+				params = append(params, "tables="+strings.Join(selected, ","))
+				break
+			}
+			fmt.Fprintf(w.out, "%v\n", err)
+		}
+	}
+
+	// Target database.
+	project, _ := utils.GetProject()
+	project = w.ask("\nGoogle Cloud project", project)
+	instance := ""
+	for instance == "" {
+		instance = w.ask("Spanner instance", "")
+	}
+	dbName, _ := utils.GetDatabaseName(source, time.Now())
+	target := []string{"project=" + project, "instance=" + instance, "dbName=" + w.ask("Spanner database (created if it doesn't exist)", dbName)}
+	if source == "postgres" && w.choose("Which Spanner dialect?", []string{"GoogleSQL", "PostgreSQL"}, 0) == 1 {
+		target = append(target, "dialect=postgresql")
+	}
+
+	// Migration.
+	subcommand := []string{"schema", "schema-and-data"}[w.choose("\nWhat do you want to migrate?", []string{
+		"Schema only, to review it (e.g. in the web UI) before migrating data with the data subcommand",
+		"Schema and data",
+	}, 0)]
+	return []string{subcommand, "-source=" + source, "-source-profile=" + profileString(params), "-target-profile=" + profileString(target)}, nil
+}
+
+// wizardTables connects to the source database of source profile profile and
+// returns its tables, as schema and name, and as named in source profiles.
+func wizardTables(source, profile string) ([]string, []string, error) {
+	sourceProfile, err := profiles.NewSourceProfile(profile, source)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sourceProfile.Driver, err = migration.SourceDriver(sourceProfile, source); err != nil {
+		return nil, nil, err
+	}
+	closeTunnels, err := sourceProfile.OpenTunnels()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeTunnels()
+	infoSchema, err := conversion.GetInfoSchema(sourceProfile, profiles.TargetProfile{})
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := infoSchema.GetTables()
+	if err != nil {
+		return nil, nil, err
+	}
+	var tables, names []string
+	for _, t := range l {
+		tables = append(tables, t.Schema+"."+t.Name)
+		names = append(names, infoSchema.GetTableName(t.Schema, t.Name))
+	}
+	return tables, names, nil
+}
+
+// ask prints question with default answer def and returns the answer.
+func (w *wizard) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	answer, _ := w.in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+	return answer
+}
+
+// choose prints question and numbered options, and returns the index of the
+// option picked, def by default.
+func (w *wizard) choose(question string, options []string, def int) int {
+	fmt.Fprintf(w.out, "%s\n", question)
+	for i, o := range options {
+		fmt.Fprintf(w.out, " %d) %s\n", i+1, o)
+	}
+	for {
+		n, err := strconv.Atoi(w.ask("Choice", strconv.Itoa(def+1)))
+		if err == nil && n >= 1 && n <= len(options) {
+			return n - 1
+		}
+		fmt.Fprintf(w.out, "Please enter a number from 1 to %d.\n", len(options))
+	}
+}
+
+// confirm asks a yes/no question, whose answer is no by default.
+func (w *wizard) confirm(question string) bool {
+	answer := strings.ToLower(w.ask(question+" (y/N)", ""))
+	return answer == "y" || answer == "yes"
+}
+
+// parseSelection parses a selection of items numbered from 1 to n, as a
+// comma separated list of numbers and ranges, e.g. "1,3-5", and returns the
+// indexes of the items selected, in order.
+func parseSelection(s string, n int) ([]int, error) {
+	selected := make([]bool, n)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi := part, part
+		if i := strings.Index(part, "-"); i > 0 {
+			lo, hi = part[:i], part[i+1:]
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		to, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || from < 1 || to > n || from > to {
+			return nil, fmt.Errorf("invalid selection '%s': please use numbers from 1 to %d", part, n)
+		}
+		for i := from; i <= to; i++ {
+			selected[i-1] = true
+		}
+	}
+	var l []int
+	for i, ok := range selected {
+		if ok {
+			l = append(l, i)
+		}
+	}
+	return l, nil
+}
+
+// profileString returns the source or target profile of params, key=value
+// pairs, quoting the pairs whose value holds a comma.
+func profileString(params []string) string {
+	var l []string
+	for _, p := range params {
+		if strings.HasSuffix(p, "=") {
+			continue
+		}
+		if strings.ContainsAny(p, ",\"") {
+			p = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+		}
+		l = append(l, p)
+	}
+	return strings.Join(l, ",")
+}
+
+// shellJoin returns args quoted for a POSIX shell.
+func shellJoin(args []string) string {
+	var l []string
+	for _, a := range args {
+		if strings.ContainsAny(a, " \t\"'$`\\,;&|<>()*?!#") {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		l = append(l, a)
+	}
+	return strings.Join(l, " ")
+}
@@ -0,0 +1,248 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chaos injects faults into HarbourBridge's calls to Cloud Spanner
+// and AWS, to test how a migration copes with them before a production run.
+// Chaos mode is off unless configured, e.g. with the -chaos flag:
+//
+//	-chaos="spanner-errors=0.05,throttling=0.1,timeouts=0.01"
+//
+// Each rate is the probability that a call fails with that kind of fault:
+//   - spanner-errors: writes to Spanner fail with a transient error (ABORTED
+//     or UNAVAILABLE), which the Spanner client retries.
+//   - throttling: reads from AWS (DynamoDB, DynamoDB Streams and S3) fail
+//     with ProvisionedThroughputExceededException, which the AWS SDK retries
+//     with backoff.
+//   - timeouts: writes to Spanner fail with DEADLINE_EXCEEDED, and reads from
+//     AWS with a request timeout.
+//
+// Faults are injected below the retries of the clients, so that they
+// exercise the same retry paths as real faults do.
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Config is the configuration of chaos mode: the rates of each kind of
+// fault, between 0 and 1.
+type Config struct {
+	SpannerErrors float64
+	Throttling    float64
+	Timeouts      float64
+	Seed          int64 // Seed of the faults; 0 for a random seed.
+}
+
+// Enabled returns true if c injects any fault.
+func (c Config) Enabled() bool {
+	return c.SpannerErrors > 0 || c.Throttling > 0 || c.Timeouts > 0
+}
+
+// Parse parses a chaos configuration of the form
+// "spanner-errors=0.05,throttling=0.1,timeouts=0.01,seed=1". Unset rates
+// are 0, and an empty string is a configuration without faults.
+func Parse(s string) (Config, error) {
+	var c Config
+	if strings.TrimSpace(s) == "" {
+		return c, nil
+	}
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return Config{}, fmt.Errorf("invalid chaos setting %q, expected key=value", kv)
+		}
+		key, val := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if key == "seed" {
+			seed, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid chaos seed %q: %v", val, err)
+			}
+			c.Seed = seed
+			continue
+		}
+		rate, err := strconv.ParseFloat(val, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return Config{}, fmt.Errorf("invalid chaos rate %q for %s, expected a number between 0 and 1", val, key)
+		}
+		switch key {
+		case "spanner-errors":
+			c.SpannerErrors = rate
+		case "throttling":
+			c.Throttling = rate
+		case "timeouts":
+			c.Timeouts = rate
+		default:
+			return Config{}, fmt.Errorf("unknown chaos setting %q (accepted settings: spanner-errors, throttling, timeouts, seed)", key)
+		}
+	}
+	return c, nil
+}
+
+// injector draws the faults of a configuration and counts them.
+type injector struct {
+	config   Config
+	lock     sync.Mutex
+	rand     *rand.Rand
+	injected map[string]int64 // Number of injected faults, by kind.
+}
+
+// Kinds of faults, as reported by Injected.
+const (
+	SpannerError = "Spanner error"
+	Throttling   = "AWS throttling"
+	Timeout      = "timeout"
+)
+
+var current *injector
+
+// Configure enables chaos mode with configuration c, or disables it if c
+// injects no fault. It must be called before the clients whose calls get
+// faults are created.
+func Configure(c Config) {
+	if !c.Enabled() {
+		current = nil
+		return
+	}
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	current = &injector{config: c, rand: rand.New(rand.NewSource(seed)), injected: make(map[string]int64)}
+}
+
+// Enabled returns true if chaos mode is enabled.
+func Enabled() bool {
+	return current != nil
+}
+
+// Injected returns the number of faults injected so far, by kind.
+func Injected() map[string]int64 {
+	if current == nil {
+		return nil
+	}
+	current.lock.Lock()
+	defer current.lock.Unlock()
+	m := make(map[string]int64)
+	for k, n := range current.injected {
+		m[k] = n
+	}
+	return m
+}
+
+// Summary returns a one-line description of the faults injected so far,
+// or "" if chaos mode isn't enabled.
+func Summary() string {
+	m := Injected()
+	if m == nil {
+		return ""
+	}
+	return fmt.Sprintf("Chaos mode injected %d Spanner errors, %d AWS throttling errors and %d timeouts.", m[SpannerError], m[Throttling], m[Timeout])
+}
+
+// draw returns the kind of fault to inject into a call, among the kinds
+// with the given rates, along with the number of faults of that kind
+// injected so far, or "" if the call shouldn't fail.
+func (in *injector) draw(rates map[string]float64, kinds ...string) (string, int64) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+	p := in.rand.Float64()
+	for _, k := range kinds {
+		if p < rates[k] {
+			in.injected[k]++
+			return k, in.injected[k]
+		}
+		p -= rates[k]
+	}
+	return "", 0
+}
+
+// spannerWrites are the Spanner RPCs that faults are injected into.
+var spannerWrites = map[string]bool{
+	"/google.spanner.v1.Spanner/Commit":          true,
+	"/google.spanner.v1.Spanner/ExecuteBatchDml": true,
+}
+
+// UnaryClientInterceptor returns a gRPC interceptor that makes writes to
+// Spanner fail at the configured rates, or nil if chaos mode isn't enabled.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	in := current
+	if in == nil {
+		return nil
+	}
+	rates := map[string]float64{SpannerError: in.config.SpannerErrors, Timeout: in.config.Timeouts}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if spannerWrites[method] {
+			switch kind, n := in.draw(rates, SpannerError, Timeout); kind {
+			case SpannerError:
+				// Alternate between the transient errors retried by the
+				// transaction (ABORTED) and by the RPC (UNAVAILABLE).
+				if n%2 == 0 {
+					return status.Error(codes.Unavailable, "chaos: injected unavailable error")
+				}
+				return status.Error(codes.Aborted, "chaos: injected transaction abort")
+			case Timeout:
+				return status.Error(codes.DeadlineExceeded, "chaos: injected timeout")
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// InstrumentAWS adds a handler to the handlers of an AWS client that makes
+// its requests fail at the configured rates, if chaos mode is enabled. The
+// handler runs in place of sending the request, so failed requests go
+// through the SDK's retries like real throttling and timeouts do.
+func InstrumentAWS(h *request.Handlers) {
+	in := current
+	if in == nil {
+		return
+	}
+	rates := map[string]float64{Throttling: in.config.Throttling, Timeout: in.config.Timeouts}
+	// Don't send requests that already failed.
+	h.Send.AfterEachFn = request.HandlerListStopOnError
+	h.Send.PushFrontNamed(request.NamedHandler{
+		Name: "harbourbridge.chaos",
+		Fn: func(r *request.Request) {
+			switch kind, _ := in.draw(rates, Throttling, Timeout); kind {
+			case Throttling:
+				r.Error = awserr.New("ProvisionedThroughputExceededException", "chaos: injected throttling", nil)
+				r.HTTPResponse = dummyResponse(http.StatusBadRequest)
+			case Timeout:
+				r.Error = awserr.New(request.ErrCodeResponseTimeout, "chaos: injected timeout", nil)
+				r.HTTPResponse = dummyResponse(0)
+			}
+		},
+	})
+}
+
+// dummyResponse returns an empty HTTP response with the given status code,
+// for the SDK handlers that inspect the response of failed requests.
+func dummyResponse(code int) *http.Response {
+	return &http.Response{StatusCode: code, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader(nil))}
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chaos
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse("spanner-errors=0.05, throttling=0.1,timeouts=0,seed=7")
+	assert.Nil(t, err)
+	assert.Equal(t, Config{SpannerErrors: 0.05, Throttling: 0.1, Seed: 7}, c)
+	assert.True(t, c.Enabled())
+
+	c, err = Parse("")
+	assert.Nil(t, err)
+	assert.False(t, c.Enabled())
+
+	for _, s := range []string{"spanner-errors", "throttling=2", "timeouts=-0.1", "timeouts=x", "seed=x", "latency=0.1"} {
+		_, err = Parse(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(Config{})
+	Configure(Config{})
+	assert.False(t, Enabled())
+	assert.Nil(t, UnaryClientInterceptor())
+	assert.Equal(t, "", Summary())
+
+	Configure(Config{Timeouts: 0.5})
+	assert.True(t, Enabled())
+	assert.Equal(t, "Chaos mode injected 0 Spanner errors, 0 AWS throttling errors and 0 timeouts.", Summary())
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	defer Configure(Config{})
+	var calls int
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		return nil
+	}
+	call := func(method string) error {
+		return UnaryClientInterceptor()(context.Background(), method, nil, nil, nil, invoker)
+	}
+
+	Configure(Config{SpannerErrors: 1})
+	err := call("/google.spanner.v1.Spanner/Commit")
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	err = call("/google.spanner.v1.Spanner/Commit")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	// Only writes fail.
+	assert.Nil(t, call("/google.spanner.v1.Spanner/ExecuteStreamingSql"))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, map[string]int64{SpannerError: 2}, Injected())
+
+	Configure(Config{Timeouts: 1})
+	err = call("/google.spanner.v1.Spanner/ExecuteBatchDml")
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Equal(t, map[string]int64{Timeout: 1}, Injected())
+
+	// Faults are drawn from the seed.
+	draws := func() []error {
+		Configure(Config{SpannerErrors: 0.3, Timeouts: 0.3, Seed: 42})
+		var errs []error
+		for i := 0; i < 20; i++ {
+			errs = append(errs, call("/google.spanner.v1.Spanner/Commit"))
+		}
+		return errs
+	}
+	assert.Equal(t, draws(), draws())
+}
+
+func TestInstrumentAWS(t *testing.T) {
+	defer Configure(Config{})
+	client := func() *dynamodb.DynamoDB {
+		sess := session.Must(session.NewSession(&aws.Config{
+			Region:      aws.String("us-west-2"),
+			Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+			Endpoint:    aws.String("http://localhost:1"),
+			MaxRetries:  aws.Int(2),
+		}))
+		c := dynamodb.New(sess)
+		InstrumentAWS(&c.Handlers)
+		return c
+	}
+
+	// Requests fail without being sent, and are retried by the SDK.
+	Configure(Config{Timeouts: 1})
+	_, err := client().ListTables(&dynamodb.ListTablesInput{})
+	assert.Equal(t, "ResponseTimeout", err.(awserr.Error).Code())
+	assert.Equal(t, map[string]int64{Timeout: 3}, Injected())
+
+	Configure(Config{Throttling: 1})
+	req, _ := client().ListTablesRequest(&dynamodb.ListTablesInput{})
+	req.Handlers.Send.Run(req)
+	assert.Equal(t, "ProvisionedThroughputExceededException", req.Error.(awserr.Error).Code())
+	assert.True(t, req.IsErrorThrottle())
+}
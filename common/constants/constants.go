@@ -30,6 +30,13 @@ const (
 	// MYSQL is the driver name for MySQL.
 	MYSQL string = "mysql"
 
+	// MARIADBDUMP is the driver name for mysqldump (or mariadb-dump) files
+	// of MariaDB databases.
+	MARIADBDUMP string = "mariadbdump"
+
+	// MARIADB is the driver name for MariaDB.
+	MARIADB string = "mariadb"
+
 	// SQLSERVER is the driver name for sqlserver.
 	SQLSERVER string = "sqlserver"
 
@@ -37,6 +44,13 @@ const (
 	// This is an experimental driver; implementation in progress.
 	DYNAMODB string = "dynamodb"
 
+	// BIGQUERY is the driver name for BigQuery datasets.
+	BIGQUERY string = "bigquery"
+
+	// FILES is the driver name for CSV and Parquet files listed in a
+	// manifest, with a schema defined in the manifest or inferred.
+	FILES string = "files"
+
 	// CSV is the driver name when loading data using csv.
 	CSV string = "csv"
 
@@ -44,6 +58,11 @@ const (
 	// This is an experimental driver; implementation in progress.
 	ORACLE string = "oracle"
 
+	// SPANNER is the driver name for the schema of an existing Spanner
+	// database or of Spanner DDL statements, loaded in the web app to be
+	// edited.
+	SPANNER string = "spanner"
+
 	// Target db for which schema is being generated.
 	TargetSpanner              string = "spanner"
 	TargetExperimentalPostgres string = "experimental_postgres"
@@ -61,4 +80,9 @@ const (
 
 	// Information passed in metadata while using Cloud Spanner client.
 	MigrationMetadataKey string = "cloud-spanner-migration-metadata"
+
+	// Formats in which the conversion report can be written.
+	ReportFormatText string = "text"
+	ReportFormatHTML string = "html"
+	ReportFormatJSON string = "json"
 )
@@ -0,0 +1,161 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package errs defines the kinds of errors that HarbourBridge handles
+// specially, e.g. by retrying. Errors returned by sources and writers are
+// classified with these kinds, so that callers (including programs using
+// HarbourBridge as a library) can check them with errors.Is instead of
+// matching error messages:
+//
+//	if errors.Is(err, errs.ErrParentRowMissing) {
+//		// Retry once the parent row is written.
+//	}
+//
+// Classified errors still wrap the original error, so errors.As can be used
+// to get e.g. the *spanner.Error.
+package errs
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	sp "cloud.google.com/go/spanner"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Kinds of errors.
+var (
+	// ErrCancelled is the kind of errors of operations stopped because their
+	// context was cancelled or its deadline passed.
+	ErrCancelled = errors.New("cancelled")
+	// ErrParentRowMissing is the kind of errors of writes to interleaved
+	// tables whose parent row doesn't exist (yet).
+	ErrParentRowMissing = errors.New("parent row missing")
+	// ErrUniqueViolation is the kind of errors of writes that violate a
+	// unique index.
+	ErrUniqueViolation = errors.New("unique index violation")
+	// ErrAlreadyExists is the kind of errors of writes of rows, or creations
+	// of schema objects, that already exist.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrTrimmedData is the kind of errors of reads of change records that
+	// expired before they were read, e.g. from a DynamoDB Stream.
+	ErrTrimmedData = errors.New("change records trimmed")
+	// ErrSchemaChanged is the kind of errors of data migrations whose source
+	// schema changed since schema conversion ran.
+	ErrSchemaChanged = errors.New("source schema changed")
+	// ErrMigrationLocked is the kind of errors of migrations to tables that
+	// another migration is writing to.
+	ErrMigrationLocked = errors.New("tables locked by another migration")
+	// ErrQuotaExceeded is the kind of errors of requests rejected because a
+	// quota, e.g. of admin operations, is exceeded. They can be retried
+	// later.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// Error is an error of kind Kind. It wraps the original error Err.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of e, so that errors.Is(err, kind)
+// is true for errors of that kind.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Wrap returns err classified as kind. It returns nil if err is nil.
+func Wrap(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+var (
+	parentRowMissingRegexp = regexp.MustCompile(`(?i)parent row .* is missing`)
+	uniqueViolationRegexp  = regexp.MustCompile(`(?i)unique index violation on index ([^\s,.]+)`)
+	duplicateNameRegexp    = regexp.MustCompile(`(?i)duplicate name in schema`)
+)
+
+// Spanner classifies err, returned by Cloud Spanner, by its error code.
+// Some kinds share their code with other errors: they are told apart by the
+// error description, which is the only place Cloud Spanner reports them.
+// Errors that are already classified, or don't match any kind, are returned
+// as is.
+func Spanner(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return Wrap(ErrCancelled, err)
+	}
+	code, desc := spannerCode(err)
+	switch {
+	case code == codes.Canceled || code == codes.DeadlineExceeded:
+		return Wrap(ErrCancelled, err)
+	case code == codes.NotFound && parentRowMissingRegexp.MatchString(desc):
+		return Wrap(ErrParentRowMissing, err)
+	case code == codes.AlreadyExists && uniqueViolationRegexp.MatchString(desc):
+		return Wrap(ErrUniqueViolation, err)
+	case code == codes.AlreadyExists:
+		return Wrap(ErrAlreadyExists, err)
+	case code == codes.FailedPrecondition && duplicateNameRegexp.MatchString(desc):
+		return Wrap(ErrAlreadyExists, err)
+	case code == codes.ResourceExhausted:
+		return Wrap(ErrQuotaExceeded, err)
+	}
+	return err
+}
+
+// UniqueViolationIndex returns the name of the index violated by err, if err
+// is of kind ErrUniqueViolation.
+func UniqueViolationIndex(err error) (string, bool) {
+	if !errors.Is(err, ErrUniqueViolation) {
+		return "", false
+	}
+	_, desc := spannerCode(err)
+	m := uniqueViolationRegexp.FindStringSubmatch(desc)
+	if m == nil {
+		return "", false
+	}
+	return strings.Trim(m[1], "`\""), true
+}
+
+// spannerCode returns the code and description of Cloud Spanner error err.
+func spannerCode(err error) (codes.Code, string) {
+	var se *sp.Error
+	if errors.As(err, &se) {
+		return se.Code, se.Desc
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code(), s.Message()
+	}
+	return codes.Unknown, err.Error()
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSpanner(t *testing.T) {
+	spannerErr := func(code codes.Code, desc string) error {
+		return sp.ToSpannerError(status.Error(code, desc))
+	}
+	tc := []struct {
+		name string
+		err  error
+		kind error // nil if err isn't classified.
+	}{
+		{"Parent row missing", spannerErr(codes.NotFound, "Parent row for row [1,2] in table child is missing. Row cannot be written."), ErrParentRowMissing},
+		{"Wrapped parent row missing", fmt.Errorf("can't write: %w", spannerErr(codes.NotFound, "Parent row for row [1] in table child is missing.")), ErrParentRowMissing},
+		{"Table not found", spannerErr(codes.NotFound, "Table not found: t"), nil},
+		{"Unique violation", spannerErr(codes.AlreadyExists, "Unique index violation on index idx at index key [1]"), ErrUniqueViolation},
+		{"Row exists", spannerErr(codes.AlreadyExists, "Row [1] in table t already exists"), ErrAlreadyExists},
+		{"Duplicate name", spannerErr(codes.FailedPrecondition, "Duplicate name in schema: t."), ErrAlreadyExists},
+		{"Quota exceeded", spannerErr(codes.ResourceExhausted, "Quota exceeded for quota metric 'Administrative requests'"), ErrQuotaExceeded},
+		{"Cancelled", spannerErr(codes.Canceled, "context canceled"), ErrCancelled},
+		{"Context cancelled", fmt.Errorf("can't write: %w", context.Canceled), ErrCancelled},
+		{"Deadline exceeded", context.DeadlineExceeded, ErrCancelled},
+		{"Other", errors.New("Parent row for row [1] in table child is missing"), nil},
+	}
+	kinds := []error{ErrCancelled, ErrParentRowMissing, ErrUniqueViolation, ErrAlreadyExists, ErrTrimmedData, ErrQuotaExceeded}
+	for _, tc := range tc {
+		err := Spanner(tc.err)
+		assert.True(t, errors.Is(err, tc.err), tc.name)
+		assert.Equal(t, tc.err.Error(), err.Error(), tc.name)
+		for _, kind := range kinds {
+			assert.Equal(t, kind == tc.kind, errors.Is(err, kind), fmt.Sprintf("%s: %v", tc.name, kind))
+		}
+	}
+	assert.Nil(t, Spanner(nil))
+	var se *sp.Error
+	assert.True(t, errors.As(Spanner(spannerErr(codes.NotFound, "Parent row for row [1] in table child is missing.")), &se))
+	assert.Equal(t, codes.NotFound, se.Code)
+}
+
+func TestUniqueViolationIndex(t *testing.T) {
+	index, ok := UniqueViolationIndex(Spanner(sp.ToSpannerError(status.Error(codes.AlreadyExists, "Unique index violation on index `idx_name` at index key [1,a]. It conflicts with row [2] in table t."))))
+	assert.True(t, ok)
+	assert.Equal(t, "idx_name", index)
+	_, ok = UniqueViolationIndex(errors.New("Unique index violation on index idx_name"))
+	assert.False(t, ok)
+}
+
+func TestWrap(t *testing.T) {
+	assert.Nil(t, Wrap(ErrTrimmedData, nil))
+	err := fmt.Errorf("can't read records: %w", Wrap(ErrTrimmedData, errors.New("TrimmedDataAccessException")))
+	assert.True(t, errors.Is(err, ErrTrimmedData))
+	assert.False(t, errors.Is(err, ErrCancelled))
+	// Classified errors aren't classified again.
+	assert.Equal(t, err, Spanner(err))
+}
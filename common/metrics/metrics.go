@@ -84,11 +84,11 @@ func getMigrationDataSourceDetails(driver string, migrationData *migration.Migra
 	switch driver {
 	case constants.PGDUMP:
 		return migration.MigrationData_DB_DUMP.Enum(), migration.MigrationData_POSTGRESQL.Enum()
-	case constants.MYSQLDUMP:
+	case constants.MYSQLDUMP, constants.MARIADBDUMP:
 		return migration.MigrationData_DB_DUMP.Enum(), migration.MigrationData_MYSQL.Enum()
 	case constants.POSTGRES:
 		return migration.MigrationData_DIRECT_CONNECTION.Enum(), migration.MigrationData_POSTGRESQL.Enum()
-	case constants.MYSQL:
+	case constants.MYSQL, constants.MARIADB:
 		return migration.MigrationData_DIRECT_CONNECTION.Enum(), migration.MigrationData_MYSQL.Enum()
 	case constants.DYNAMODB:
 		return migration.MigrationData_DIRECT_CONNECTION.Enum(), migration.MigrationData_DYNAMODB.Enum()
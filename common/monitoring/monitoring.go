@@ -0,0 +1,141 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package monitoring writes Cloud Monitoring custom metrics about the
+// replication of changes to Spanner, so that users can set alerting
+// policies (e.g. with an email notification channel) and get paged when the
+// optimal cutover window opens, instead of watching the terminal. Metrics
+// are off unless configured, e.g. with the -monitoring-project flag.
+//
+// Failures to write metrics are printed, but never fail the migration.
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	monitoring "google.golang.org/api/monitoring/v3"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+)
+
+// Custom metrics written, labelled with the Spanner database.
+const (
+	ReplicationLagMetric = "custom.googleapis.com/harbourbridge/replication_lag" // Seconds between a change to the source database and its replication to Spanner.
+	CutoverReadyMetric   = "custom.googleapis.com/harbourbridge/cutover_ready"   // True when now is a good time to switch the application to Spanner.
+)
+
+// Config is the configuration of metrics.
+type Config struct {
+	Project  string // Project to write metrics to.
+	Database string // Spanner database URI, the value of the database label of metrics.
+}
+
+// reporter writes metrics with a configuration.
+type reporter struct {
+	config Config
+	create func(ctx context.Context, project string, ts []*monitoring.TimeSeries) error // Writes time series to project.
+	out    io.Writer                                                                    // Where failures to write are printed.
+	mu     sync.Mutex
+	failed bool // Whether a failure has already been printed.
+}
+
+var current *reporter
+
+// Configure enables metrics with configuration c, or disables them if c has
+// no project.
+func Configure(ctx context.Context, c Config) error {
+	if c.Project == "" {
+		current = nil
+		return nil
+	}
+	svc, err := monitoring.NewService(ctx)
+	if err != nil {
+		return fmt.Errorf("can't create Cloud Monitoring client: %v", err)
+	}
+	create := func(ctx context.Context, project string, ts []*monitoring.TimeSeries) error {
+		_, err := svc.Projects.TimeSeries.Create("projects/"+project, &monitoring.CreateTimeSeriesRequest{TimeSeries: ts}).Context(ctx).Do()
+		return err
+	}
+	current = &reporter{config: c, create: create, out: os.Stdout}
+	return nil
+}
+
+// Enabled returns true if metrics are enabled.
+func Enabled() bool {
+	return current != nil
+}
+
+// ReportReplication writes the current replication lag, and whether now is
+// a good time for cutover, if metrics are enabled.
+func ReportReplication(ctx context.Context, lag time.Duration, ready bool) {
+	if current != nil {
+		current.write(ctx, current.timeSeries(time.Now(), &lag, ready))
+	}
+}
+
+// ReportCutoverReady writes whether now is a good time for cutover, if
+// metrics are enabled.
+func ReportCutoverReady(ctx context.Context, ready bool) {
+	if current != nil {
+		current.write(ctx, current.timeSeries(time.Now(), nil, ready))
+	}
+}
+
+// write writes ts, printing the first failure only: metrics are written
+// every minute while streaming.
+func (r *reporter) write(ctx context.Context, ts []*monitoring.TimeSeries) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := r.create(ctx, r.config.Project, ts); err != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if !r.failed {
+			r.failed = true
+			fmt.Fprintf(r.out, "Warning: can't write Cloud Monitoring metrics to project %s: %v\n", r.config.Project, err)
+			if d := utils.PermissionDiagnosis(err, "projects/"+r.config.Project); d != "" {
+				fmt.Fprintln(r.out, d)
+			}
+		}
+	}
+}
+
+// timeSeries returns the points at now of the replication lag, if set, and
+// of cutover readiness.
+func (r *reporter) timeSeries(now time.Time, lag *time.Duration, ready bool) []*monitoring.TimeSeries {
+	var ts []*monitoring.TimeSeries
+	if lag != nil {
+		seconds := lag.Seconds()
+		ts = append(ts, r.gauge(ReplicationLagMetric, "DOUBLE", "s", now, &monitoring.TypedValue{DoubleValue: &seconds}))
+	}
+	return append(ts, r.gauge(CutoverReadyMetric, "BOOL", "", now, &monitoring.TypedValue{BoolValue: &ready}))
+}
+
+func (r *reporter) gauge(metric, valueType, unit string, now time.Time, v *monitoring.TypedValue) *monitoring.TimeSeries {
+	return &monitoring.TimeSeries{
+		Metric:     &monitoring.Metric{Type: metric, Labels: map[string]string{"database": r.config.Database}},
+		Resource:   &monitoring.MonitoredResource{Type: "global", Labels: map[string]string{"project_id": r.config.Project}},
+		MetricKind: "GAUGE",
+		ValueType:  valueType,
+		Unit:       unit,
+		Points: []*monitoring.Point{{
+			Interval: &monitoring.TimeInterval{EndTime: now.UTC().Format(time.RFC3339Nano)},
+			Value:    v,
+		}},
+	}
+}
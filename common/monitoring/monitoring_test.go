@@ -0,0 +1,85 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	monitoring "google.golang.org/api/monitoring/v3"
+)
+
+func TestTimeSeries(t *testing.T) {
+	r := &reporter{config: Config{Project: "p", Database: "projects/p/instances/i/databases/d"}}
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	lag := 90 * time.Second
+	ts := r.timeSeries(now, &lag, false)
+	assert.Equal(t, 2, len(ts))
+	assert.Equal(t, ReplicationLagMetric, ts[0].Metric.Type)
+	assert.Equal(t, "DOUBLE", ts[0].ValueType)
+	assert.Equal(t, 90.0, *ts[0].Points[0].Value.DoubleValue)
+	assert.Equal(t, CutoverReadyMetric, ts[1].Metric.Type)
+	assert.Equal(t, "BOOL", ts[1].ValueType)
+	assert.False(t, *ts[1].Points[0].Value.BoolValue)
+	for _, s := range ts {
+		assert.Equal(t, "GAUGE", s.MetricKind)
+		assert.Equal(t, map[string]string{"database": "projects/p/instances/i/databases/d"}, s.Metric.Labels)
+		assert.Equal(t, map[string]string{"project_id": "p"}, s.Resource.Labels)
+		assert.Equal(t, "2022-05-01T10:00:00Z", s.Points[0].Interval.EndTime)
+	}
+
+	ts = r.timeSeries(now, nil, true)
+	assert.Equal(t, 1, len(ts))
+	assert.Equal(t, CutoverReadyMetric, ts[0].Metric.Type)
+	assert.True(t, *ts[0].Points[0].Value.BoolValue)
+}
+
+func TestReport(t *testing.T) {
+	defer func() { current = nil }()
+	ctx := context.Background()
+	assert.Nil(t, Configure(ctx, Config{}))
+	assert.False(t, Enabled())
+	ReportCutoverReady(ctx, true)
+
+	var (
+		written []*monitoring.TimeSeries
+		err     error
+		out     bytes.Buffer
+	)
+	current = &reporter{
+		config: Config{Project: "p", Database: "d"},
+		create: func(ctx context.Context, project string, ts []*monitoring.TimeSeries) error {
+			assert.Equal(t, "p", project)
+			written = append(written, ts...)
+			return err
+		},
+		out: &out,
+	}
+	assert.True(t, Enabled())
+	ReportReplication(ctx, time.Minute, false)
+	ReportCutoverReady(ctx, true)
+	assert.Equal(t, 3, len(written))
+	assert.Equal(t, "", out.String())
+
+	// Only the first failure is printed.
+	err = errors.New("permission denied")
+	ReportReplication(ctx, time.Minute, false)
+	ReportReplication(ctx, time.Minute, true)
+	assert.Equal(t, "Warning: can't write Cloud Monitoring metrics to project p: permission denied\n", out.String())
+}
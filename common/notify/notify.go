@@ -0,0 +1,210 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notify posts notifications about the milestones and failures of a
+// migration to a webhook: a Slack or Microsoft Teams incoming webhook, or any
+// HTTP endpoint accepting JSON. Notifications are off unless configured, e.g.
+// with the -notify-webhook flag:
+//
+//	-notify-webhook=https://hooks.slack.com/services/T000/B000/XXXX -notify-events=bulk-load-done,run-complete
+//
+// Failures to post a notification are printed, but never fail the
+// migration.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Events that notifications are sent on.
+const (
+	SchemaConverted      = "schema-converted"       // Schema conversion is done, and the Spanner database created or updated.
+	BulkLoadDone         = "bulk-load-done"         // The current contents of the source database have been migrated.
+	StreamingCaughtUp    = "streaming-caught-up"    // Streaming has processed the backlog of changes made during the bulk load.
+	CutoverRecommended   = "cutover-recommended"    // Now is a good time to switch the application to Spanner.
+	ErrorsAboveThreshold = "errors-above-threshold" // Rows lost exceed -max-bad-row-rate or an error budget.
+	RunComplete          = "run-complete"           // The subcommand completed, successfully or not.
+	RunFailed            = "run-failed"             // A fatal error stopped the subcommand.
+)
+
+// Events lists all events, in the order they typically happen.
+var Events = []string{SchemaConverted, BulkLoadDone, StreamingCaughtUp, CutoverRecommended, ErrorsAboveThreshold, RunComplete, RunFailed}
+
+// Formats of the notifications posted to webhooks.
+const (
+	FormatSlack = "slack" // Slack incoming webhook message.
+	FormatTeams = "teams" // Microsoft Teams incoming webhook message card.
+	FormatJSON  = "json"  // JSON object with the event, run, message and time.
+)
+
+// Config is the configuration of notifications.
+type Config struct {
+	Webhook string          // URL to post notifications to.
+	Format  string          // One of the Format* constants; "" to choose by the host of Webhook.
+	Events  map[string]bool // Events to notify; nil for all events.
+	Run     string          // Description of the run, e.g. the subcommand, prefixed to messages.
+	Timeout time.Duration   // Timeout of each post; 0 for 10 seconds.
+}
+
+// ParseEvents parses a comma separated list of events. An empty string is
+// all events, returned as nil.
+func ParseEvents(s string) (map[string]bool, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	known := make(map[string]bool)
+	for _, e := range Events {
+		known[e] = true
+	}
+	events := make(map[string]bool)
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		if !known[e] {
+			return nil, fmt.Errorf("unknown notification event %q (accepted events: %s)", e, strings.Join(Events, ", "))
+		}
+		events[e] = true
+	}
+	return events, nil
+}
+
+// notifier posts notifications with a configuration.
+type notifier struct {
+	config Config
+	client *http.Client
+	out    io.Writer // Where failures to post are printed.
+}
+
+var current *notifier
+
+// Configure enables notifications with configuration c, or disables them if
+// c has no webhook.
+func Configure(c Config) error {
+	if c.Webhook == "" {
+		current = nil
+		return nil
+	}
+	u, err := url.Parse(c.Webhook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid notification webhook %q, expected an http(s) URL", c.Webhook)
+	}
+	if c.Format == "" {
+		c.Format = formatOf(u)
+	}
+	switch c.Format {
+	case FormatSlack, FormatTeams, FormatJSON:
+	default:
+		return fmt.Errorf("invalid notification format %s, accepted values are: %s, %s, %s", c.Format, FormatSlack, FormatTeams, FormatJSON)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	current = &notifier{config: c, client: &http.Client{Timeout: c.Timeout}, out: os.Stdout}
+	return nil
+}
+
+// formatOf returns the format of notifications posted to webhook u, based on
+// its host.
+func formatOf(u *url.URL) string {
+	switch host := strings.ToLower(u.Hostname()); {
+	case host == "hooks.slack.com":
+		return FormatSlack
+	case strings.HasSuffix(host, ".office.com") || strings.HasSuffix(host, ".logic.azure.com"):
+		return FormatTeams
+	}
+	return FormatJSON
+}
+
+// Enabled returns true if notifications are enabled for event.
+func Enabled(event string) bool {
+	return current != nil && (current.config.Events == nil || current.config.Events[event])
+}
+
+// Send posts a notification of event with message, if notifications are
+// enabled for event.
+func Send(event, message string) {
+	if !Enabled(event) {
+		return
+	}
+	n := current
+	if err := n.post(event, message, time.Now()); err != nil {
+		fmt.Fprintf(n.out, "Warning: can't send %s notification: %v\n", event, err)
+	}
+}
+
+// Sendf is like Send, with the message formatted as by fmt.Sprintf.
+func Sendf(event, format string, a ...interface{}) {
+	if Enabled(event) {
+		Send(event, fmt.Sprintf(format, a...))
+	}
+}
+
+func (n *notifier) post(event, message string, now time.Time) error {
+	b, err := json.Marshal(n.payload(event, message, now))
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(n.config.Webhook, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// payload returns the body of the notification of event with message, in
+// the format of n.
+func (n *notifier) payload(event, message string, now time.Time) interface{} {
+	text := message
+	if n.config.Run != "" {
+		text = n.config.Run + ": " + message
+	}
+	switch n.config.Format {
+	case FormatSlack:
+		return map[string]string{"text": fmt.Sprintf("*HarbourBridge %s* %s", event, text)}
+	case FormatTeams:
+		return map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "http://schema.org/extensions",
+			"summary":    "HarbourBridge " + event,
+			"title":      "HarbourBridge " + event,
+			"text":       text,
+			"themeColor": themeColor(event),
+		}
+	}
+	return map[string]string{"event": event, "run": n.config.Run, "message": message, "time": now.UTC().Format(time.RFC3339)}
+}
+
+// themeColor returns the color of Microsoft Teams cards for event.
+func themeColor(event string) string {
+	switch event {
+	case ErrorsAboveThreshold, RunFailed:
+		return "D93025"
+	case CutoverRecommended:
+		return "1E8E3E"
+	}
+	return "1A73E8"
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents("")
+	assert.Nil(t, err)
+	assert.Nil(t, events)
+	events, err = ParseEvents("bulk-load-done, run-failed")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{BulkLoadDone: true, RunFailed: true}, events)
+	_, err = ParseEvents("bulk-load-done,done")
+	assert.NotNil(t, err)
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(Config{})
+	assert.Nil(t, Configure(Config{}))
+	assert.False(t, Enabled(RunComplete))
+	assert.NotNil(t, Configure(Config{Webhook: "hooks.slack.com/services/x"}))
+	assert.NotNil(t, Configure(Config{Webhook: "https://example.com/hook", Format: "xml"}))
+
+	assert.Nil(t, Configure(Config{Webhook: "https://example.com/hook", Events: map[string]bool{RunFailed: true}}))
+	assert.True(t, Enabled(RunFailed))
+	assert.False(t, Enabled(RunComplete))
+	assert.Equal(t, FormatJSON, current.config.Format)
+
+	for webhook, format := range map[string]string{
+		"https://hooks.slack.com/services/T0/B0/X":       FormatSlack,
+		"https://contoso.webhook.office.com/webhookb2/x": FormatTeams,
+		"http://localhost:8080/notify":                   FormatJSON,
+	} {
+		u, _ := url.Parse(webhook)
+		assert.Equal(t, format, formatOf(u), webhook)
+	}
+}
+
+func TestPayload(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	n := &notifier{config: Config{Format: FormatSlack, Run: "schema-and-data"}}
+	assert.Equal(t, map[string]string{"text": "*HarbourBridge bulk-load-done* schema-and-data: 10 rows"}, n.payload(BulkLoadDone, "10 rows", now))
+	n.config.Format = FormatTeams
+	p := n.payload(RunFailed, "boom", now).(map[string]string)
+	assert.Equal(t, "MessageCard", p["@type"])
+	assert.Equal(t, "schema-and-data: boom", p["text"])
+	assert.Equal(t, "D93025", p["themeColor"])
+	n.config.Format = FormatJSON
+	assert.Equal(t, map[string]string{"event": RunComplete, "run": "schema-and-data", "message": "done", "time": "2022-05-01T10:00:00Z"}, n.payload(RunComplete, "done", now))
+}
+
+func TestSend(t *testing.T) {
+	defer Configure(Config{})
+	var got []map[string]string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		var m map[string]string
+		assert.Nil(t, json.Unmarshal(b, &m))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		got = append(got, m)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	assert.Nil(t, Configure(Config{Webhook: srv.URL, Events: map[string]bool{BulkLoadDone: true, RunFailed: true}}))
+	out := new(bytes.Buffer)
+	current.out = out
+	Sendf(BulkLoadDone, "%d rows", 10)
+	Send(RunComplete, "not sent")
+	assert.Len(t, got, 1)
+	assert.Equal(t, BulkLoadDone, got[0]["event"])
+	assert.Equal(t, "10 rows", got[0]["message"])
+	assert.Empty(t, out.String())
+
+	// Failures are printed, and don't stop the migration.
+	status = http.StatusInternalServerError
+	Send(RunFailed, "boom")
+	assert.Len(t, got, 2)
+	assert.Contains(t, out.String(), "Warning: can't send run-failed notification: webhook returned 500")
+}
@@ -0,0 +1,302 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tunnel reaches source databases behind bastions or proxies: it
+// forwards connections made to a local port to the database, through an SSH
+// tunnel, a SOCKS5 or HTTP proxy, or both (the proxy is then used to reach
+// the SSH server). Drivers connect to the local end of the tunnel as if it
+// were the database, so that users don't have to set up tunnels themselves.
+package tunnel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/net/proxy"
+)
+
+// Config is the configuration of the route to a source database.
+type Config struct {
+	SSHHost       string // host or host:port of the SSH server (bastion), if any.
+	SSHUser       string
+	SSHKeyFile    string // Private key file authenticating to the SSH server.
+	SSHPassword   string
+	SSHKnownHosts string // known_hosts file the SSH server's key is checked against; "" for ~/.ssh/known_hosts.
+	Proxy         string // URL of a SOCKS5 (socks5://) or HTTP (http://) proxy, if any.
+}
+
+// Enabled returns true if c routes connections through a tunnel or proxy.
+func (c Config) Enabled() bool {
+	return c.SSHHost != "" || c.Proxy != ""
+}
+
+// dialer dials connections to an address.
+type dialer interface {
+	Dial(network, addr string) (net.Conn, error)
+}
+
+// Tunnel forwards the connections made to a local address to a remote one.
+type Tunnel struct {
+	listener net.Listener
+	target   string
+	dial     dialer
+	closers  []io.Closer
+	wg       sync.WaitGroup
+}
+
+// Open opens a tunnel to target (host:port) routed as configured by c,
+// listening on a local port. The route is checked by connecting once.
+func Open(c Config, target string) (*Tunnel, error) {
+	d, closers, err := c.dialer()
+	if err != nil {
+		return nil, err
+	}
+	t := &Tunnel{target: target, dial: d, closers: closers}
+	conn, err := d.Dial("tcp", target)
+	if err != nil {
+		t.close()
+		return nil, fmt.Errorf("can't reach %s: %v", target, err)
+	}
+	conn.Close()
+	if t.listener, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.close()
+		return nil, err
+	}
+	t.wg.Add(1)
+	go t.serve()
+	return t, nil
+}
+
+// Addr returns the host and port of the local end of t.
+func (t *Tunnel) Addr() (string, string) {
+	host, port, _ := net.SplitHostPort(t.listener.Addr().String())
+	return host, port
+}
+
+// Close stops accepting connections and closes the route of t.
+func (t *Tunnel) Close() error {
+	err := t.listener.Close()
+	t.wg.Wait()
+	t.close()
+	return err
+}
+
+func (t *Tunnel) close() {
+	for i := len(t.closers) - 1; i >= 0; i-- {
+		t.closers[i].Close()
+	}
+}
+
+func (t *Tunnel) serve() {
+	defer t.wg.Done()
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		go t.forward(local)
+	}
+}
+
+// forward copies data between local and a new connection to the target of t
+// until either side closes.
+func (t *Tunnel) forward(local net.Conn) {
+	defer local.Close()
+	remote, err := t.dial.Dial("tcp", t.target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: can't forward connection to %s: %v\n", t.target, err)
+		return
+	}
+	defer remote.Close()
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// dialer returns the dialer routing connections as configured by c, and
+// what must be closed once it's no longer used.
+func (c Config) dialer() (dialer, []io.Closer, error) {
+	var d dialer = &net.Dialer{Timeout: 30 * time.Second}
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid proxy %s: %v", c.Proxy, err)
+		}
+		switch u.Scheme {
+		case "socks5", "socks5h":
+			if d, err = proxy.FromURL(u, d.(*net.Dialer)); err != nil {
+				return nil, nil, fmt.Errorf("invalid proxy %s: %v", c.Proxy, err)
+			}
+		case "http":
+			if u.Port() == "" {
+				u.Host = net.JoinHostPort(u.Hostname(), "80")
+			}
+			d = &httpProxy{proxy: u, forward: d}
+		default:
+			return nil, nil, fmt.Errorf("invalid proxy %s: expected a socks5:// or http:// URL", c.Proxy)
+		}
+	}
+	if c.SSHHost == "" {
+		return d, nil, nil
+	}
+	config, closers, err := c.sshConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	addr := c.SSHHost
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	conn, err := d.Dial("tcp", addr)
+	if err != nil {
+		closeAll(closers)
+		return nil, nil, fmt.Errorf("can't reach SSH server %s: %v", addr, err)
+	}
+	sc, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		closeAll(closers)
+		return nil, nil, fmt.Errorf("can't connect to SSH server %s: %v", addr, err)
+	}
+	client := ssh.NewClient(sc, chans, reqs)
+	return client, append(closers, client), nil
+}
+
+// sshConfig returns the configuration of connections to the SSH server of
+// c, and what must be closed once it's no longer used.
+func (c Config) sshConfig() (*ssh.ClientConfig, []io.Closer, error) {
+	if c.SSHUser == "" {
+		return nil, nil, fmt.Errorf("please specify the user of SSH server %s", c.SSHHost)
+	}
+	knownHosts := c.SSHKnownHosts
+	if knownHosts == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil, err
+		}
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKey, err := knownhosts.New(knownHosts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read known hosts of SSH servers, add the key of %s to %s (e.g. with ssh-keyscan): %v", c.SSHHost, knownHosts, err)
+	}
+	var (
+		auth    []ssh.AuthMethod
+		closers []io.Closer
+	)
+	if c.SSHKeyFile != "" {
+		b, err := ioutil.ReadFile(c.SSHKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't read SSH key: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(b)
+		if err != nil {
+			if _, ok := err.(*ssh.PassphraseMissingError); ok {
+				return nil, nil, fmt.Errorf("SSH key %s is protected by a passphrase: add it to ssh-agent instead", c.SSHKeyFile)
+			}
+			return nil, nil, fmt.Errorf("can't parse SSH key %s: %v", c.SSHKeyFile, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closers = append(closers, conn)
+		}
+	}
+	if c.SSHPassword != "" {
+		auth = append(auth, ssh.Password(c.SSHPassword))
+	}
+	if len(auth) == 0 {
+		return nil, nil, fmt.Errorf("please specify an SSH key file or password, or run ssh-agent, to authenticate to SSH server %s", c.SSHHost)
+	}
+	return &ssh.ClientConfig{User: c.SSHUser, Auth: auth, HostKeyCallback: hostKey, Timeout: 30 * time.Second}, closers, nil
+}
+
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+// httpProxy dials connections through an HTTP proxy, with CONNECT.
+type httpProxy struct {
+	proxy   *url.URL
+	forward dialer
+}
+
+func (p *httpProxy) Dial(network, addr string) (net.Conn, error) {
+	conn, err := p.forward.Dial(network, p.proxy.Host)
+	if err != nil {
+		return nil, err
+	}
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: addr}, Host: addr, Header: make(http.Header)}
+	if u := p.proxy.User; u != nil {
+		password, _ := u.Password()
+		req.SetBasicAuth(u.Username(), password)
+		req.Header.Set("Proxy-Authorization", req.Header.Get("Authorization"))
+		req.Header.Del("Authorization")
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", p.proxy.Host, addr, strings.TrimSpace(resp.Status))
+	}
+	if r.Buffered() > 0 {
+		// Servers such as MySQL speak first: keep what was read after the
+		// response.
+		return &bufferedConn{Conn: conn, r: r}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection whose first bytes were read into r.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
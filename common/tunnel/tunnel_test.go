@@ -0,0 +1,134 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serve accepts connections on a local port, greets them with hello and
+// then echoes what they send, like a database speaking first.
+func serve(t *testing.T, hello string) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				io.WriteString(c, hello)
+				io.Copy(c, c)
+			}()
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+// connectProxy is an HTTP proxy accepting CONNECT requests.
+func connectProxy(t *testing.T) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				req, err := http.ReadRequest(bufio.NewReader(c))
+				if err != nil || req.Method != http.MethodConnect {
+					return
+				}
+				remote, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(c, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					return
+				}
+				defer remote.Close()
+				io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go io.Copy(remote, c)
+				io.Copy(c, remote)
+			}()
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+// roundTrip connects to host:port, and returns the greeting and the echo of
+// a message.
+func roundTrip(t *testing.T, host, port string) string {
+	c, err := net.Dial("tcp", net.JoinHostPort(host, port))
+	assert.Nil(t, err)
+	defer c.Close()
+	_, err = io.WriteString(c, "ping")
+	assert.Nil(t, err)
+	b := make([]byte, 9)
+	_, err = io.ReadFull(c, b)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func TestOpen_HTTPProxy(t *testing.T) {
+	db, stopDb := serve(t, "hello")
+	defer stopDb()
+	p, stopProxy := connectProxy(t)
+	defer stopProxy()
+
+	tun, err := Open(Config{Proxy: "http://" + p}, db)
+	assert.Nil(t, err)
+	host, port := tun.Addr()
+	assert.Equal(t, "127.0.0.1", host)
+	assert.Equal(t, "helloping", roundTrip(t, host, port))
+	assert.Equal(t, "helloping", roundTrip(t, host, port))
+	assert.Nil(t, tun.Close())
+
+	// The route is checked when the tunnel is opened.
+	_, err = Open(Config{Proxy: "http://" + p}, "127.0.0.1:1")
+	assert.NotNil(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	assert.False(t, Config{}.Enabled())
+	assert.True(t, Config{Proxy: "socks5://localhost:1080"}.Enabled())
+	assert.True(t, Config{SSHHost: "bastion"}.Enabled())
+
+	_, _, err := Config{Proxy: "ftp://localhost"}.dialer()
+	assert.NotNil(t, err)
+	_, _, err = Config{SSHHost: "bastion"}.sshConfig()
+	assert.NotNil(t, err)
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	assert.Nil(t, ioutil.WriteFile(knownHosts, nil, 0600))
+	t.Setenv("SSH_AUTH_SOCK", "")
+	_, _, err = Config{SSHHost: "bastion", SSHUser: "me", SSHKnownHosts: knownHosts}.sshConfig()
+	assert.NotNil(t, err)
+	config, _, err := Config{SSHHost: "bastion", SSHUser: "me", SSHPassword: "secret", SSHKnownHosts: knownHosts}.sshConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "me", config.User)
+	_, _, err = Config{SSHHost: "bastion", SSHUser: "me", SSHPassword: "secret", SSHKnownHosts: filepath.Join(t.TempDir(), "missing")}.sshConfig()
+	assert.NotNil(t, err)
+}
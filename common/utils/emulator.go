@@ -0,0 +1,89 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	instancepb "google.golang.org/genproto/googleapis/spanner/admin/instance/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults used when migrating to the Cloud Spanner emulator. The emulator
+// accepts any project and instance name, and doesn't need credentials.
+const (
+	DefaultEmulatorHost     = "localhost:9010"
+	DefaultEmulatorProject  = "emulator-project"
+	DefaultEmulatorInstance = "emulator-instance"
+	emulatorInstanceConfig  = "emulator-config"
+)
+
+// UsingEmulator returns true if Spanner clients connect to the Cloud Spanner
+// emulator, i.e. if SPANNER_EMULATOR_HOST is set.
+func UsingEmulator() bool {
+	return os.Getenv("SPANNER_EMULATOR_HOST") != ""
+}
+
+// ConfigureEmulator makes Spanner clients connect to the Cloud Spanner
+// emulator, using DefaultEmulatorHost if SPANNER_EMULATOR_HOST isn't already
+// set. It returns the emulator host.
+func ConfigureEmulator() string {
+	if host := os.Getenv("SPANNER_EMULATOR_HOST"); host != "" {
+		return host
+	}
+	os.Setenv("SPANNER_EMULATOR_HOST", DefaultEmulatorHost)
+	return DefaultEmulatorHost
+}
+
+// CreateEmulatorInstance creates Spanner instance instanceID in project on
+// the emulator, unless it already exists. A freshly started emulator has no
+// instances.
+func CreateEmulatorInstance(ctx context.Context, project, instanceID string) error {
+	if !UsingEmulator() {
+		return fmt.Errorf("SPANNER_EMULATOR_HOST is not set")
+	}
+	instanceClient, err := NewInstanceAdminClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer instanceClient.Close()
+	name := fmt.Sprintf("projects/%s/instances/%s", project, instanceID)
+	_, err = instanceClient.GetInstance(ctx, &instancepb.GetInstanceRequest{Name: name})
+	if err == nil {
+		return nil
+	}
+	if status.Code(err) != codes.NotFound {
+		return fmt.Errorf("can't get instance %s: %v", name, err)
+	}
+	op, err := instanceClient.CreateInstance(ctx, &instancepb.CreateInstanceRequest{
+		Parent:     fmt.Sprintf("projects/%s", project),
+		InstanceId: instanceID,
+		Instance: &instancepb.Instance{
+			Config:      fmt.Sprintf("projects/%s/instanceConfigs/%s", project, emulatorInstanceConfig),
+			DisplayName: instanceID,
+			NodeCount:   1,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("can't create instance %s: %v", name, err)
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return fmt.Errorf("can't create instance %s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// MissingPermission is an IAM permission the caller lacks on a resource.
+type MissingPermission struct {
+	Permission string // e.g. spanner.databases.create.
+	Resource   string // e.g. projects/my-project/instances/my-instance, or gs://my-bucket.
+}
+
+// leastPrivilegeRoles maps IAM permissions used by migrations to the
+// predefined role with the least privileges that grants them.
+var leastPrivilegeRoles = map[string]string{
+	"spanner.databases.create":                              "roles/spanner.databaseAdmin",
+	"spanner.databases.drop":                                "roles/spanner.databaseAdmin",
+	"spanner.databases.get":                                 "roles/spanner.databaseAdmin",
+	"spanner.databases.list":                                "roles/spanner.viewer",
+	"spanner.instances.get":                                 "roles/spanner.viewer",
+	"spanner.instances.list":                                "roles/spanner.viewer",
+	"spanner.instanceConfigs.get":                           "roles/spanner.viewer",
+	"spanner.databases.getDdl":                              "roles/spanner.databaseReader",
+	"spanner.databases.read":                                "roles/spanner.databaseReader",
+	"spanner.databases.select":                              "roles/spanner.databaseReader",
+	"spanner.databases.beginReadOnlyTransaction":            "roles/spanner.databaseReader",
+	"spanner.sessions.create":                               "roles/spanner.databaseReader",
+	"spanner.sessions.delete":                               "roles/spanner.databaseReader",
+	"spanner.sessions.get":                                  "roles/spanner.databaseReader",
+	"spanner.databases.write":                               "roles/spanner.databaseUser",
+	"spanner.databases.updateDdl":                           "roles/spanner.databaseUser",
+	"spanner.databases.beginOrRollbackReadWriteTransaction": "roles/spanner.databaseUser",
+	"spanner.databaseOperations.get":                        "roles/spanner.databaseUser",
+	"storage.objects.get":                                   "roles/storage.objectViewer",
+	"storage.objects.list":                                  "roles/storage.objectViewer",
+	"storage.objects.create":                                "roles/storage.objectCreator",
+	"monitoring.timeSeries.create":                          "roles/monitoring.metricWriter",
+	"datastream.streams.create":                             "roles/datastream.admin",
+	"dataflow.jobs.create":                                  "roles/dataflow.developer",
+}
+
+// LeastPrivilegeRole returns the predefined IAM role with the least
+// privileges that grants permission, or "" if it isn't known.
+func LeastPrivilegeRole(permission string) string {
+	return leastPrivilegeRoles[permission]
+}
+
+// Messages of permission errors of Google Cloud APIs, naming the permission
+// and possibly the resource, e.g. of Spanner ("Caller is missing IAM
+// permission spanner.databases.create on resource projects/..."), Cloud
+// Storage ("... does not have storage.objects.get access to ...") and Cloud
+// Monitoring ("Permission monitoring.timeSeries.create denied ...").
+var (
+	missingPermissionRegexp = regexp.MustCompile(`missing IAM permission ([\w.]+)(?: on resource ([^\s,]+[^\s,.]))?`)
+	noAccessRegexp          = regexp.MustCompile(`does not have ([a-z]+\.[\w.]+) access`)
+	permissionDeniedRegexp  = regexp.MustCompile(`Permission '?([a-z]+\.[\w.]+)'? denied`)
+)
+
+// ParseMissingPermission returns the permission missing for err, if err is
+// a permission error of a Google Cloud API that names it. Its resource is the
+// one named by err, or resource if err doesn't name it.
+func ParseMissingPermission(err error, resource string) (MissingPermission, bool) {
+	if err == nil {
+		return MissingPermission{}, false
+	}
+	msg := err.Error()
+	if m := missingPermissionRegexp.FindStringSubmatch(msg); m != nil {
+		if m[2] != "" {
+			resource = m[2]
+		}
+		return MissingPermission{Permission: m[1], Resource: resource}, true
+	}
+	for _, re := range []*regexp.Regexp{noAccessRegexp, permissionDeniedRegexp} {
+		if m := re.FindStringSubmatch(msg); m != nil {
+			return MissingPermission{Permission: m[1], Resource: resource}, true
+		}
+	}
+	return MissingPermission{}, false
+}
+
+// PermissionDiagnosis returns how to fix err if it's a permission error of a
+// Google Cloud API that names the missing permission: the predefined role
+// with the least privileges that grants it, and a gcloud command granting
+// this role on resource (or on the resource named by err) to the account
+// running HarbourBridge. It returns "" for other errors.
+func PermissionDiagnosis(err error, resource string) string {
+	p, ok := ParseMissingPermission(err, resource)
+	if !ok {
+		return ""
+	}
+	return DiagnoseMissingPermissions([]MissingPermission{p})
+}
+
+// DiagnoseMissingPermissions returns how to grant the missing permissions,
+// with one gcloud command per role and resource.
+func DiagnoseMissingPermissions(missing []MissingPermission) string {
+	type grant struct{ role, resource string }
+	var (
+		b      strings.Builder
+		grants []grant
+	)
+	seen := map[grant]bool{}
+	member := currentMember()
+	for _, p := range missing {
+		role := LeastPrivilegeRole(p.Permission)
+		resource := p.Resource
+		if resource == "" {
+			resource = "(unknown resource)"
+		}
+		if role == "" {
+			fmt.Fprintf(&b, "Missing IAM permission %s on %s: grant a role that includes it.\n", p.Permission, resource)
+			continue
+		}
+		fmt.Fprintf(&b, "Missing IAM permission %s on %s, granted by role %s.\n", p.Permission, resource, role)
+		g := grant{role, p.Resource}
+		if !seen[g] && p.Resource != "" {
+			seen[g] = true
+			grants = append(grants, g)
+		}
+	}
+	if len(grants) > 0 {
+		b.WriteString("Grant the missing roles to the account running HarbourBridge with:\n")
+		for _, g := range grants {
+			fmt.Fprintf(&b, "\n  %s\n", GrantCommand(g.role, g.resource, member))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+var (
+	databaseResourceRegexp = regexp.MustCompile(`^projects/([^/]+)/instances/([^/]+)/databases/([^/]+)$`)
+	instanceResourceRegexp = regexp.MustCompile(`^projects/([^/]+)/instances/([^/]+)$`)
+	projectResourceRegexp  = regexp.MustCompile(`^projects/([^/]+)$`)
+)
+
+// GrantCommand returns the gcloud command granting role on resource, a
+// Spanner database or instance, a project or a GCS bucket (gs://bucket), to
+// member, e.g. user:me@example.com.
+func GrantCommand(role, resource, member string) string {
+	args := fmt.Sprintf("--member=%s --role=%s", member, role)
+	if m := databaseResourceRegexp.FindStringSubmatch(resource); m != nil {
+		return fmt.Sprintf("gcloud spanner databases add-iam-policy-binding %s --instance=%s --project=%s %s", m[3], m[2], m[1], args)
+	}
+	if m := instanceResourceRegexp.FindStringSubmatch(resource); m != nil {
+		return fmt.Sprintf("gcloud spanner instances add-iam-policy-binding %s --project=%s %s", m[2], m[1], args)
+	}
+	if m := projectResourceRegexp.FindStringSubmatch(resource); m != nil {
+		return fmt.Sprintf("gcloud projects add-iam-policy-binding %s %s", m[1], args)
+	}
+	if strings.HasPrefix(resource, "gs://") {
+		bucket := strings.SplitN(strings.TrimPrefix(resource, "gs://"), "/", 2)[0]
+		return fmt.Sprintf("gcloud storage buckets add-iam-policy-binding gs://%s %s", bucket, args)
+	}
+	return fmt.Sprintf("gcloud projects add-iam-policy-binding PROJECT %s", args)
+}
+
+// currentMember returns the IAM member of the account HarbourBridge runs as:
+// the service account of GOOGLE_APPLICATION_CREDENTIALS, or the account of
+// gcloud, or a placeholder if neither is known.
+func currentMember() string {
+	if f := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); f != "" {
+		var key struct {
+			ClientEmail string `json:"client_email"`
+		}
+		if b, err := ioutil.ReadFile(f); err == nil && json.Unmarshal(b, &key) == nil && key.ClientEmail != "" {
+			return "serviceAccount:" + key.ClientEmail
+		}
+	}
+	out, err := exec.Command("gcloud", "config", "list", "--format", "value(core.account)").Output()
+	if account := strings.TrimSpace(string(out)); err == nil && account != "" {
+		if strings.HasSuffix(account, ".gserviceaccount.com") {
+			return "serviceAccount:" + account
+		}
+		return "user:" + account
+	}
+	return "user:YOUR_ACCOUNT"
+}
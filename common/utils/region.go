@@ -0,0 +1,104 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/compute/metadata"
+	databasepb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	instancepb "google.golang.org/genproto/googleapis/spanner/admin/instance/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WorkerRegion returns the Google Cloud region HarbourBridge runs in, as
+// reported by the metadata server of Compute Engine, GKE or Cloud Run, or ""
+// if it doesn't run on Google Cloud.
+func WorkerRegion() string {
+	if !metadata.OnGCE() {
+		return ""
+	}
+	zone, err := metadata.Zone()
+	if err != nil {
+		return ""
+	}
+	return zoneRegion(zone)
+}
+
+// zoneRegion returns the region of zone, e.g. us-central1 for us-central1-a
+// or projects/123/zones/us-central1-1.
+func zoneRegion(zone string) string {
+	zone = zone[strings.LastIndex(zone, "/")+1:]
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
+
+// LeaderRegion returns the region of the leader replicas of database dbURI:
+// its default leader, or the default leader location of the configuration of
+// its instance if the database doesn't exist yet or has no default leader.
+func LeaderRegion(ctx context.Context, dbURI string) (string, error) {
+	adminClient, err := NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer adminClient.Close()
+	db, err := adminClient.GetDatabase(ctx, &databasepb.GetDatabaseRequest{Name: dbURI})
+	if err == nil && db.DefaultLeader != "" {
+		return db.DefaultLeader, nil
+	}
+	if err != nil && status.Code(err) != codes.NotFound {
+		return "", err
+	}
+	instanceClient, err := NewInstanceAdminClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer instanceClient.Close()
+	i := strings.Index(dbURI, "/databases/")
+	if i < 0 {
+		return "", fmt.Errorf("invalid database %s", dbURI)
+	}
+	instance, err := instanceClient.GetInstance(ctx, &instancepb.GetInstanceRequest{Name: dbURI[:i]})
+	if err != nil {
+		return "", err
+	}
+	config, err := instanceClient.GetInstanceConfig(ctx, &instancepb.GetInstanceConfigRequest{Name: instance.Config})
+	if err != nil {
+		return "", err
+	}
+	return defaultLeaderLocation(config), nil
+}
+
+// defaultLeaderLocation returns the location of the default leader replicas
+// of instance configuration config, or "" if it has none.
+func defaultLeaderLocation(config *instancepb.InstanceConfig) string {
+	for _, r := range config.Replicas {
+		if r.DefaultLeaderLocation {
+			return r.Location
+		}
+	}
+	// Regional configurations have all their replicas in one region.
+	for _, r := range config.Replicas {
+		if r.Type == instancepb.ReplicaInfo_READ_WRITE {
+			return r.Location
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+// NewGCSSpiller returns an internal.Spiller that writes oversized values to
+// objects under gcsURI (gs://bucket/path), named by Spanner table, column and
+// the SHA-256 of the value, so that equal values are written once. It also
+// returns a function to close the GCS client once data migration is complete.
+func NewGCSSpiller(ctx context.Context, gcsURI string) (internal.Spiller, func(), error) {
+	u, err := url.Parse(gcsURI)
+	if err != nil || u.Scheme != "gs" || u.Host == "" {
+		return nil, nil, fmt.Errorf("invalid GCS location %s, expected gs://bucket/path", gcsURI)
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't create GCS client: %v", err)
+	}
+	bucket := client.Bucket(u.Host)
+	prefix := strings.Trim(u.Path, "/")
+	spill := func(table, col string, val []byte) (string, error) {
+		name := path.Join(prefix, table, col, fmt.Sprintf("%x", sha256.Sum256(val)))
+		w := bucket.Object(name).NewWriter(ctx)
+		if _, err := w.Write(val); err != nil {
+			w.Close()
+			return "", err
+		}
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("gs://%s/%s", u.Host, name), nil
+	}
+	return spill, func() { client.Close() }, nil
+}
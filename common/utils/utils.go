@@ -37,6 +37,7 @@ import (
 	database "cloud.google.com/go/spanner/admin/database/apiv1"
 	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
 	"cloud.google.com/go/storage"
+	"github.com/cloudspannerecosystem/harbourbridge/common/chaos"
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
@@ -45,6 +46,9 @@ import (
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	instancepb "google.golang.org/genproto/googleapis/spanner/admin/instance/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
 )
 
 // IOStreams is a struct that contains the file descriptor for dumpFile.
@@ -69,7 +73,7 @@ func NewIOStreams(driver string, dumpFile string) IOStreams {
 		fmt.Printf("parseFilePath: unable parse file path for dumpfile %s", dumpFile)
 		log.Fatal(err)
 	}
-	if (driver == constants.PGDUMP || driver == constants.MYSQLDUMP) && dumpFile != "" {
+	if (driver == constants.PGDUMP || driver == constants.MYSQLDUMP || driver == constants.MARIADBDUMP) && dumpFile != "" {
 		fmt.Printf("\nLoading dump file from path: %s\n", dumpFile)
 		var f *os.File
 		var err error
@@ -104,6 +108,9 @@ func DownloadFromGCS(bucketName, filePath, tmpFile string) (*os.File, error) {
 	rc, err := bucket.Object(filePath).NewReader(ctx)
 	if err != nil {
 		fmt.Printf("readFile: unable to open file from bucket %q, file %q: %v", bucketName, filePath, err)
+		if d := PermissionDiagnosis(err, "gs://"+bucketName); d != "" {
+			fmt.Println(d)
+		}
 		log.Fatal(err)
 		return nil, err
 	}
@@ -321,6 +328,9 @@ Possible cause: Spanner instance specified via instance option does not exist.
 Please check that '%s' is correct and that it is a valid Spanner
 instance for project %s`, err, instance, project)
 	}
+	if d := PermissionDiagnosis(err, URI); d != "" {
+		return fmt.Errorf("%w.\n%s", err, d)
+	}
 	return err
 }
 
@@ -400,13 +410,87 @@ func PrintSeekError(driver string, err error, out *os.File) {
 	fmt.Fprintf(out, "  harbourbridge < tmpfile\n")
 }
 
+// ClientConfig is the configuration of the session pool and gRPC channels of
+// a Spanner client, e.g. to migrate over high-latency links or with many
+// parallel writes. Zero values keep the defaults of the client library.
+type ClientConfig struct {
+	MinSessions uint64        // Sessions opened when the client is created.
+	MaxSessions uint64        // Limit on open sessions: writes beyond it wait for a session.
+	Channels    int           // Number of gRPC channels.
+	Keepalive   time.Duration // Interval of keepalive pings on idle gRPC channels.
+
+	// RouteToLeader asks Spanner to route requests to the leader region of
+	// the database, instead of to the nearest replica, saving a hop between
+	// regions for the read-write transactions of the migration.
+	RouteToLeader bool
+}
+
+// routeToLeaderHeader is the request header that asks Spanner to route
+// requests to the leader region.
+const routeToLeaderHeader = "x-goog-spanner-route-to-leader"
+
+// keepaliveTimeout is how long a keepalive ping waits for an acknowledgement
+// before the channel is closed.
+const keepaliveTimeout = 20 * time.Second
+
 // NewSpannerClient returns a new Spanner client.
-// It respects SPANNER_API_ENDPOINT.
+// It respects SPANNER_API_ENDPOINT, and injects faults into writes if chaos
+// mode is enabled.
 func NewSpannerClient(ctx context.Context, db string) (*sp.Client, error) {
+	return NewSpannerClientWithConfig(ctx, db, ClientConfig{})
+}
+
+// NewSpannerClientWithConfig returns a new Spanner client whose session pool
+// and gRPC channels are configured by c. Like NewSpannerClient, it respects
+// SPANNER_API_ENDPOINT and chaos mode.
+func NewSpannerClientWithConfig(ctx context.Context, db string, c ClientConfig) (*sp.Client, error) {
+	var opts []option.ClientOption
 	if endpoint := os.Getenv("SPANNER_API_ENDPOINT"); endpoint != "" {
-		return sp.NewClient(ctx, db, option.WithEndpoint(endpoint))
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	if chaos.Enabled() {
+		opts = append(opts, option.WithGRPCDialOption(grpc.WithUnaryInterceptor(chaos.UnaryClientInterceptor())))
+	}
+	if c.Keepalive > 0 {
+		opts = append(opts, option.WithGRPCDialOption(grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                c.Keepalive,
+			Timeout:             keepaliveTimeout,
+			PermitWithoutStream: true,
+		})))
+	}
+	if c.RouteToLeader {
+		opts = append(opts,
+			option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(routeToLeaderUnary)),
+			option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(routeToLeaderStream)))
 	}
-	return sp.NewClient(ctx, db)
+	return sp.NewClientWithConfig(ctx, db, c.spannerConfig(), opts...)
+}
+
+func routeToLeaderUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(metadata.AppendToOutgoingContext(ctx, routeToLeaderHeader, "true"), method, req, reply, cc, opts...)
+}
+
+func routeToLeaderStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(metadata.AppendToOutgoingContext(ctx, routeToLeaderHeader, "true"), desc, cc, method, opts...)
+}
+
+// spannerConfig returns the Spanner client library configuration of c.
+func (c ClientConfig) spannerConfig() sp.ClientConfig {
+	pool := sp.DefaultSessionPoolConfig
+	if c.MinSessions > 0 {
+		pool.MinOpened = c.MinSessions
+	}
+	if c.Channels > 0 {
+		// Let the client library scale the session limit with the channels.
+		pool.MaxOpened = 0
+	}
+	if c.MaxSessions > 0 {
+		pool.MaxOpened = c.MaxSessions
+		if pool.MinOpened > pool.MaxOpened {
+			pool.MinOpened = pool.MaxOpened
+		}
+	}
+	return sp.ClientConfig{NumChannels: c.Channels, SessionPoolConfig: pool}
 }
 
 // GetClient returns a new Spanner client.  It uses the background context.
@@ -445,44 +529,6 @@ func GetBanner(now time.Time, db string) string {
 	return fmt.Sprintf("Generated at %s for db %s\n\n", now.Format("2006-01-02 15:04:05"), db)
 }
 
-func IsValidDriver(driver string) bool {
-	d := strings.ToLower(driver)
-	for _, vd := range GetValidDrivers() {
-		if d == vd {
-			return true
-		}
-	}
-	return false
-}
-
-func GetValidDrivers() []string {
-	//First 5 drivers support legacy mode. Rest dont.
-	return []string{
-		constants.POSTGRES,
-		constants.PGDUMP,
-		constants.MYSQL,
-		constants.MYSQLDUMP,
-		constants.DYNAMODB,
-
-		constants.SQLSERVER,
-	}
-}
-
-func IsLegacyModeSupportedDriver(driver string) bool {
-	d := strings.ToLower(driver)
-	lds := GetLegacyModeSupportedDrivers()
-	for _, ld := range lds {
-		if d == ld {
-			return true
-		}
-	}
-	return false
-}
-
-func GetLegacyModeSupportedDrivers() []string {
-	return GetValidDrivers()[:5]
-}
-
 // ReadSpannerSchema fills conv by querying Spanner infoschema treating Spanner as both the source and dest.
 func ReadSpannerSchema(ctx context.Context, conv *internal.Conv, client *sp.Client) error {
 	infoSchema := spanner.InfoSchemaImpl{Client: client, Ctx: ctx, TargetDb: conv.TargetDb}
@@ -497,13 +543,38 @@ func ReadSpannerSchema(ctx context.Context, conv *internal.Conv, client *sp.Clie
 		// In the emulator, the interleave_type column in not supported hence the query fails.
 		conv.Unexpected(fmt.Sprintf("error trying to fetch interleave table info from schema: %v", err))
 	}
-	// Assign parents if any.
+	assignParents(conv, parentTables)
+	return nil
+}
+
+// ReadSpannerDDL reads the schema described by Spanner DDL statements (e.g.
+// the contents of a .ddl file) into conv, so that it can be edited like a
+// converted schema. Statements other than those creating tables, indexes and
+// foreign keys are ignored, and reported as unexpected conditions.
+func ReadSpannerDDL(conv *internal.Conv, text string) error {
+	infoSchema, err := spanner.NewDDLInfoSchema(text)
+	if err != nil {
+		return err
+	}
+	if err = common.ProcessSchema(conv, infoSchema); err != nil {
+		return fmt.Errorf("error trying to read and convert spanner DDL: %v", err)
+	}
+	infoSchema.UpdateSpannerSchema(conv)
+	parentTables, _ := infoSchema.GetInterleaveTables()
+	assignParents(conv, parentTables)
+	for _, stmt := range infoSchema.Skipped {
+		conv.Unexpected(fmt.Sprintf("Ignored DDL statement: %s", stmt))
+	}
+	return nil
+}
+
+// assignParents sets the parent of interleaved tables.
+func assignParents(conv *internal.Conv, parentTables map[string]string) {
 	for table, parent := range parentTables {
 		spTable := conv.SpSchema[table]
 		spTable.Parent = parent
 		conv.SpSchema[table] = spTable
 	}
-	return nil
 }
 
 // CompareSchema compares the spanner schema of two conv objects and returns specific error if they don't match
@@ -0,0 +1,124 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+
+	sp "cloud.google.com/go/spanner"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// maxTagLength is the maximum length of Spanner request and transaction tags.
+const maxTagLength = 50
+
+var priorities = map[string]sppb.RequestOptions_Priority{
+	"low":    sppb.RequestOptions_PRIORITY_LOW,
+	"medium": sppb.RequestOptions_PRIORITY_MEDIUM,
+	"high":   sppb.RequestOptions_PRIORITY_HIGH,
+}
+
+// ValidPriority returns true if priority is a valid priority of
+// internal.WriteOptions.
+func ValidPriority(priority string) bool {
+	_, ok := priorities[priority]
+	return ok
+}
+
+// ValidateTag checks that tag is a valid Spanner request and transaction tag:
+// at most 50 printable ASCII characters.
+func ValidateTag(tag string) error {
+	if len(tag) > maxTagLength {
+		return fmt.Errorf("invalid tag %s: tags can't be longer than %d characters", tag, maxTagLength)
+	}
+	for _, c := range tag {
+		if c < ' ' || c > '~' {
+			return fmt.Errorf("invalid tag %s: tags can only contain printable ASCII characters", tag)
+		}
+	}
+	return nil
+}
+
+// ApplyOptions returns the options of client.Apply calls that write with
+// opts.
+func ApplyOptions(opts internal.WriteOptions) []sp.ApplyOption {
+	var o []sp.ApplyOption
+	if p, ok := priorities[opts.Priority]; ok {
+		o = append(o, sp.Priority(p))
+	}
+	if opts.Tag != "" {
+		o = append(o, sp.TransactionTag(opts.Tag))
+	}
+	return o
+}
+
+// TransactionOptions returns the options of read-write transactions that
+// write with opts. Their reads should use ReadOptions(opts).
+func TransactionOptions(opts internal.WriteOptions) sp.TransactionOptions {
+	return sp.TransactionOptions{TransactionTag: opts.Tag, CommitPriority: priorities[opts.Priority]}
+}
+
+// ReadOptions returns the options of the reads of read-write transactions
+// that write with opts.
+func ReadOptions(opts internal.WriteOptions) *sp.ReadOptions {
+	return &sp.ReadOptions{RequestTag: opts.Tag, Priority: priorities[opts.Priority]}
+}
+
+// QueryOptions returns the options of the DML statements of read-write
+// transactions that write with opts.
+func QueryOptions(opts internal.WriteOptions) sp.QueryOptions {
+	return sp.QueryOptions{Priority: priorities[opts.Priority], RequestTag: opts.Tag}
+}
+
+// CommitTimestamps returns the columns cols of table and their values vals,
+// with the values of columns that allow commit timestamps set to
+// spanner.CommitTimestamp, so that they are the time of the write. Such
+// columns are added if they are missing from cols. cols and vals aren't
+// modified.
+func CommitTimestamps(table ddl.CreateTable, cols []string, vals []interface{}) ([]string, []interface{}) {
+	var commitCols []string
+	for _, col := range table.ColNames {
+		if table.ColDefs[col].AllowCommitTimestamp {
+			commitCols = append(commitCols, col)
+		}
+	}
+	if len(commitCols) == 0 {
+		return cols, vals
+	}
+	c := append([]string{}, cols...)
+	v := append([]interface{}{}, vals...)
+	for _, col := range commitCols {
+		i := indexOf(c, col)
+		if i < 0 {
+			c = append(c, col)
+			v = append(v, sp.CommitTimestamp)
+		} else if i < len(v) {
+			v[i] = sp.CommitTimestamp
+		}
+	}
+	return c, v
+}
+
+func indexOf(l []string, s string) int {
+	for i, x := range l {
+		if x == s {
+			return i
+		}
+	}
+	return -1
+}
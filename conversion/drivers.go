@@ -0,0 +1,28 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+// The source drivers register themselves with the registry of package
+// sources/registry when their package is imported. New drivers only need
+// to be added here to be available in the command line tool and web app.
+import (
+	_ "github.com/cloudspannerecosystem/harbourbridge/sources/bigquery"
+	_ "github.com/cloudspannerecosystem/harbourbridge/sources/dynamodb"
+	_ "github.com/cloudspannerecosystem/harbourbridge/sources/files"
+	_ "github.com/cloudspannerecosystem/harbourbridge/sources/mysql"
+	_ "github.com/cloudspannerecosystem/harbourbridge/sources/oracle"
+	_ "github.com/cloudspannerecosystem/harbourbridge/sources/postgres"
+	_ "github.com/cloudspannerecosystem/harbourbridge/sources/sqlserver"
+)
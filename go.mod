@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	cloud.google.com/go v0.100.2
+	cloud.google.com/go/compute v1.5.0
 	cloud.google.com/go/dataflow v0.4.0
 	cloud.google.com/go/datastream v0.4.0
 	cloud.google.com/go/iam v0.3.0 // indirect
@@ -11,6 +12,7 @@ require (
 	cloud.google.com/go/storage v1.21.0
 	github.com/BurntSushi/toml v0.4.1 // indirect
 	github.com/DATA-DOG/go-sqlmock v1.5.0
+	github.com/amzn/ion-go v1.1.3
 	github.com/aws/aws-sdk-go v1.35.3
 	github.com/basgys/goxml2json v1.1.0
 	github.com/bitly/go-simplejson v0.5.0 // indirect
@@ -26,12 +28,17 @@ require (
 	github.com/google/uuid v1.1.2
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.0
+	github.com/jackc/pgconn v1.11.0
+	github.com/jackc/pgproto3/v2 v2.1.1
 	github.com/lib/pq v1.9.0
+	github.com/linkedin/goavro/v2 v2.11.1
 	github.com/pganalyze/pg_query_go/v2 v2.0.5
 	github.com/pingcap/tidb v1.1.0-beta.0.20220411093434-32b9c14779c2
 	github.com/pingcap/tidb/parser v0.0.0-20220411093434-32b9c14779c2
 	github.com/sijms/go-ora/v2 v2.2.17
 	github.com/stretchr/testify v1.7.0
+	github.com/xitongsys/parquet-go v1.5.5-0.20201110004701-b09c49d6d457
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
 	go.uber.org/zap v1.21.0
 	golang.org/x/crypto v0.0.0-20220214200702-86341886e292
 	golang.org/x/net v0.0.0-20220325170049-de3da57026de
@@ -0,0 +1,220 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+)
+
+// Complexity levels of a migration assessment.
+const (
+	ComplexityLow    = "LOW"
+	ComplexityMedium = "MEDIUM"
+	ComplexityHigh   = "HIGH"
+)
+
+// Points contributed by each complexity factor. The weights are rough
+// estimates of the relative manual effort needed to resolve each factor.
+const (
+	unsupportedTypePoints = 5  // Per column whose type has no good Spanner mapping.
+	autoIncrementPoints   = 3  // Per auto-increment/serial column that needs a key strategy.
+	defaultValuePoints    = 1  // Per column with a default value.
+	missingPKeyPoints     = 5  // Per table without a primary key.
+	fkCyclePoints         = 10 // Per table that is part of a foreign key cycle.
+	triggerPoints         = 5  // Per trigger, which must be reimplemented in the application.
+	procedurePoints       = 5  // Per stored procedure or function.
+	viewPoints            = 2  // Per view.
+)
+
+// TableAssessment is the migration complexity assessment of a table.
+type TableAssessment struct {
+	SrcTable   string
+	Rows       int64 // Zero if the number of rows is unknown.
+	Score      int64
+	Complexity string
+	Factors    []string // Human readable explanation of the score.
+}
+
+// Assessment is the migration complexity assessment of a database.
+type Assessment struct {
+	Tables     []TableAssessment
+	Score      int64
+	Complexity string
+	Factors    []string // Factors that apply to the database rather than a table.
+}
+
+// AssessMigration computes a migration complexity score for each table in
+// conv and for the database as a whole, based on the schema issues found
+// during schema conversion, foreign key cycles, table sizes (if available in
+// conv.Stats.Rows) and the triggers, procedures and views seen in the source.
+func AssessMigration(conv *Conv) Assessment {
+	var a Assessment
+	cycles := FkCycleTables(conv)
+	var tables []string
+	for t := range conv.SrcSchema {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		ta := assessTable(conv, t, cycles[t])
+		a.Score += ta.Score
+		a.Tables = append(a.Tables, ta)
+	}
+	for _, f := range []struct {
+		stmts  []string
+		name   string
+		points int64
+	}{
+		{[]string{"CreateTrigStmt"}, "trigger", triggerPoints},
+		{[]string{"CreateFunctionStmt", "CreatePLangStmt", "CreateProcedureStmt"}, "stored procedure/function", procedurePoints},
+		{[]string{"ViewStmt", "CreateViewStmt"}, "view", viewPoints},
+	} {
+		n := int64(0)
+		for _, s := range f.stmts {
+			if x, ok := conv.Stats.Statement[s]; ok {
+				n += x.Schema + x.Data + x.Skip + x.Error
+			}
+		}
+		if n > 0 {
+			a.Score += n * f.points
+			a.Factors = append(a.Factors, fmt.Sprintf("%d %s(s) must be reimplemented outside Spanner (+%d)", n, f.name, n*f.points))
+		}
+	}
+	a.Complexity = ComplexityLow
+	switch {
+	case a.Score >= 100:
+		a.Complexity = ComplexityHigh
+	case a.Score >= 20:
+		a.Complexity = ComplexityMedium
+	}
+	for _, t := range a.Tables {
+		if t.Complexity == ComplexityHigh {
+			a.Complexity = ComplexityHigh
+		}
+	}
+	return a
+}
+
+func assessTable(conv *Conv, srcTable string, inCycle bool) TableAssessment {
+	ta := TableAssessment{SrcTable: srcTable, Rows: conv.Stats.Rows[srcTable]}
+	add := func(points int64, format string, args ...interface{}) {
+		ta.Score += points
+		ta.Factors = append(ta.Factors, fmt.Sprintf(format+" (+%d)", append(args, points)...))
+	}
+	issues := conv.activeIssues(srcTable)
+	var cols []string
+	for c := range issues {
+		cols = append(cols, c)
+	}
+	sort.Strings(cols)
+	for _, c := range cols {
+		for _, i := range issues[c] {
+			switch i {
+			case NoGoodType, Numeric, Decimal, MultiDimensionalArray, StringOverflow, UnsignedOverflow:
+				add(unsupportedTypePoints, "Column '%s': %s", c, IssueDB[i].Brief)
+			case Serial, AutoIncrement:
+				add(autoIncrementPoints, "Column '%s': %s", c, IssueDB[i].Brief)
+			case DefaultValue:
+				add(defaultValuePoints, "Column '%s': %s", c, IssueDB[i].Brief)
+			}
+		}
+	}
+	if spTable, err := GetSpannerTable(conv, srcTable); err == nil {
+		if _, ok := conv.SyntheticPKeys[spTable]; ok {
+			add(missingPKeyPoints, "Table has no primary key")
+		}
+	}
+	if inCycle {
+		add(fkCyclePoints, "Table is part of a foreign key cycle")
+	}
+	switch {
+	case ta.Rows >= 1000*1000*1000:
+		add(20, "Table has %d rows", ta.Rows)
+	case ta.Rows >= 100*1000*1000:
+		add(10, "Table has %d rows", ta.Rows)
+	case ta.Rows >= 10*1000*1000:
+		add(5, "Table has %d rows", ta.Rows)
+	case ta.Rows >= 1000*1000:
+		add(2, "Table has %d rows", ta.Rows)
+	}
+	ta.Complexity = ComplexityLow
+	switch {
+	case ta.Score >= 20:
+		ta.Complexity = ComplexityHigh
+	case ta.Score >= 5:
+		ta.Complexity = ComplexityMedium
+	}
+	return ta
+}
+
+// FkCycleTables returns the set of source tables that are part of a cycle of
+// foreign keys. A table whose foreign keys only reference itself is not
+// considered to be part of a cycle.
+func FkCycleTables(conv *Conv) map[string]bool {
+	var tables []string
+	for t := range conv.SrcSchema {
+		tables = append(tables, t)
+	}
+	cycles := make(map[string]bool)
+	for _, scc := range fkCycleComponents(tables, func(t string) []string {
+		var refs []string
+		for _, fk := range conv.SrcSchema[t].ForeignKeys {
+			if _, ok := conv.SrcSchema[fk.ReferTable]; ok {
+				refs = append(refs, fk.ReferTable)
+			}
+		}
+		return refs
+	}) {
+		for _, t := range scc {
+			cycles[t] = true
+		}
+	}
+	return cycles
+}
+
+// WriteAssessment writes a human readable version of assessment a to w.
+func WriteAssessment(a Assessment, w *bufio.Writer) {
+	writeHeading(w, "Migration Assessment")
+	w.WriteString(fmt.Sprintf("Overall complexity: %s (score %d)\n", a.Complexity, a.Score))
+	w.WriteString("\n")
+	w.WriteString("Scores are the sum of points for each factor that requires manual effort\n")
+	w.WriteString("when migrating to Spanner. Table sizes are only included when connecting\n")
+	w.WriteString("directly to the source database, and triggers, procedures and views are\n")
+	w.WriteString("only counted for dump files.\n\n")
+	for _, f := range a.Factors {
+		w.WriteString(fmt.Sprintf("  - %s\n", f))
+	}
+	if len(a.Factors) > 0 {
+		w.WriteString("\n")
+	}
+	// Show the most complex tables first.
+	tables := append([]TableAssessment{}, a.Tables...)
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].Score > tables[j].Score })
+	w.WriteString(fmt.Sprintf("%-10s %-8s %s\n", "Score", "Level", "Table"))
+	for _, t := range tables {
+		w.WriteString(fmt.Sprintf("%-10d %-8s %s\n", t.Score, t.Complexity, t.SrcTable))
+	}
+	for _, t := range tables {
+		if len(t.Factors) == 0 {
+			continue
+		}
+		writeHeading(w, fmt.Sprintf("Table %s: %s complexity (score %d)", t.SrcTable, t.Complexity, t.Score))
+		for _, f := range t.Factors {
+			w.WriteString(fmt.Sprintf("  - %s\n", f))
+		}
+	}
+}
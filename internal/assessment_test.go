@@ -0,0 +1,84 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+func TestFkCycleTables(t *testing.T) {
+	conv := MakeConv()
+	fk := func(refer string) []schema.ForeignKey { return []schema.ForeignKey{{ReferTable: refer}} }
+	conv.SrcSchema = map[string]schema.Table{
+		"a":    {Name: "a", ForeignKeys: fk("b")},
+		"b":    {Name: "b", ForeignKeys: fk("c")},
+		"c":    {Name: "c", ForeignKeys: fk("a")},
+		"d":    {Name: "d", ForeignKeys: fk("a")},
+		"self": {Name: "self", ForeignKeys: fk("self")},
+		"e":    {Name: "e", ForeignKeys: fk("missing")},
+	}
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, FkCycleTables(conv))
+}
+
+func TestAssessMigration(t *testing.T) {
+	conv := buildReportConv()
+	conv.SrcSchema["t2"] = schema.Table{Name: "t2", ColNames: []string{"x"}, ColDefs: map[string]schema.Column{"x": {Name: "x"}}}
+	conv.ToSpanner["t2"] = NameAndCols{Name: "t2", Cols: map[string]string{"x": "x"}}
+	conv.ToSource["t2"] = NameAndCols{Name: "t2", Cols: map[string]string{"x": "x"}}
+	conv.SyntheticPKeys["t2"] = SyntheticPKey{Col: "synth_id"}
+	conv.Issues["t2"] = map[string][]SchemaIssue{"x": {AutoIncrement, Timestamp}}
+	conv.Stats.Rows["t2"] = 20 * 1000 * 1000
+	conv.SetSchemaMode()
+	conv.SchemaStatement("CreateTrigStmt")
+	conv.SchemaStatement("CreateTrigStmt")
+
+	a := AssessMigration(conv)
+	assert.Equal(t, []TableAssessment{
+		{
+			SrcTable:   "t1",
+			Rows:       100,
+			Score:      5,
+			Complexity: ComplexityMedium,
+			Factors:    []string{"Column 'b': No appropriate Spanner type (+5)"},
+		},
+		{
+			SrcTable:   "t2",
+			Rows:       20 * 1000 * 1000,
+			Score:      13,
+			Complexity: ComplexityMedium,
+			Factors: []string{
+				"Column 'x': Spanner does not support auto_increment attribute (+3)",
+				"Table has no primary key (+5)",
+				"Table has 20000000 rows (+5)",
+			},
+		},
+	}, a.Tables)
+	assert.Equal(t, int64(28), a.Score)
+	assert.Equal(t, ComplexityMedium, a.Complexity)
+	assert.Equal(t, []string{"2 trigger(s) must be reimplemented outside Spanner (+10)"}, a.Factors)
+
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	WriteAssessment(a, w)
+	w.Flush()
+	assert.Contains(t, buf.String(), "Overall complexity: MEDIUM (score 28)")
+	assert.Contains(t, buf.String(), "Table t2: MEDIUM complexity (score 13)")
+}
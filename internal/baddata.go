@@ -0,0 +1,143 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultBadDataSampleSize is the default number of bad rows of each kind
+// that are written to the bad data file.
+const DefaultBadDataSampleSize = 100
+
+// Error types used to partition the rows written by BadDataWriter.
+const (
+	ConversionError = "conversion" // Row could not be converted to Spanner data.
+	WriteError      = "write"      // Row was converted but could not be written to Spanner.
+)
+
+// BadDataRecord is the machine-readable representation of a row that
+// generated an error during data migration.
+type BadDataRecord struct {
+	Table      string        `json:"table"`
+	RecordType string        `json:"recordType,omitempty"` // Type of streaming record i.e. INSERT, MODIFY & REMOVE.
+	Cols       []string      `json:"cols"`
+	Vals       []interface{} `json:"vals"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// BadDataWriter writes every row that generated an error during data migration
+// to a directory, in contrast to the sample of bad rows kept in memory for the
+// bad data file. Rows are partitioned by table and error type: rows of table t
+// with error type e are written to <dir>/<t>/<e>.jsonl, one JSON encoded
+// BadDataRecord per line. BadDataWriter is threadsafe.
+type BadDataWriter struct {
+	dir   string
+	lock  sync.Mutex
+	files map[string]*os.File
+	err   error // First error encountered while writing.
+}
+
+// NewBadDataWriter returns a BadDataWriter that writes to directory dir,
+// creating it if needed.
+func NewBadDataWriter(dir string) (*BadDataWriter, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("can't create bad data directory %s: %v", dir, err)
+	}
+	return &BadDataWriter{dir: dir, files: make(map[string]*os.File)}, nil
+}
+
+// Dir returns the directory bw writes to.
+func (bw *BadDataWriter) Dir() string {
+	return bw.dir
+}
+
+// Write appends r to the file for table r.Table and error type errorType.
+// Errors are recorded and returned by Close, so that a failure to write bad
+// data doesn't interrupt the migration.
+func (bw *BadDataWriter) Write(errorType string, r BadDataRecord) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		b, _ = json.Marshal(BadDataRecord{Table: r.Table, RecordType: r.RecordType, Cols: r.Cols, Vals: stringifyVals(r.Vals), Error: r.Error})
+	}
+	bw.lock.Lock()
+	defer bw.lock.Unlock()
+	path := filepath.Join(bw.dir, pathComponent(r.Table), errorType+".jsonl")
+	f, ok := bw.files[path]
+	if !ok {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			bw.setErr(err)
+			return
+		}
+		f, err = os.Create(path)
+		if err != nil {
+			bw.setErr(err)
+			return
+		}
+		bw.files[path] = f
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		bw.setErr(err)
+	}
+}
+
+// Close closes all files written by bw and returns the first error
+// encountered while writing, if any.
+func (bw *BadDataWriter) Close() error {
+	bw.lock.Lock()
+	defer bw.lock.Unlock()
+	for _, f := range bw.files {
+		if err := f.Close(); err != nil {
+			bw.setErr(err)
+		}
+	}
+	bw.files = make(map[string]*os.File)
+	return bw.err
+}
+
+func (bw *BadDataWriter) setErr(err error) {
+	if bw.err == nil {
+		bw.err = fmt.Errorf("can't write bad data to %s: %v", bw.dir, err)
+	}
+}
+
+// pathComponent maps a table name to a string that is safe to use as a
+// directory name.
+func pathComponent(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, s)
+	if s == "" || s == "." || s == ".." {
+		s = "_" + s
+	}
+	return s
+}
+
+func stringifyVals(vals []interface{}) []interface{} {
+	var l []interface{}
+	for _, v := range vals {
+		l = append(l, fmt.Sprintf("%v", v))
+	}
+	return l
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBadDataWriter(t *testing.T) {
+	dir := t.TempDir()
+	bw, err := NewBadDataWriter(dir)
+	assert.Nil(t, err)
+
+	conv := MakeConv()
+	conv.SetBadDataWriter(bw)
+	conv.CollectBadRow("t1", []string{"a", "b"}, []string{"1", "x"})
+	bw.Write(WriteError, BadDataRecord{Table: "t1", RecordType: "INSERT", Cols: []string{"a"}, Vals: []interface{}{int64(2)}, Error: "already exists"})
+	bw.Write(WriteError, BadDataRecord{Table: "t1", Cols: []string{"a"}, Vals: []interface{}{int64(3)}, Error: "already exists"})
+	bw.Write(ConversionError, BadDataRecord{Table: "../t2", Cols: []string{"c"}, Vals: []interface{}{"y"}})
+	assert.Nil(t, bw.Close())
+
+	b, err := os.ReadFile(filepath.Join(dir, "t1", "conversion.jsonl"))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"table":"t1","cols":["a","b"],"vals":["1","x"]}`+"\n", string(b))
+	b, err = os.ReadFile(filepath.Join(dir, "t1", "write.jsonl"))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"table":"t1","recordType":"INSERT","cols":["a"],"vals":[2],"error":"already exists"}`+"\n"+
+		`{"table":"t1","cols":["a"],"vals":[3],"error":"already exists"}`+"\n", string(b))
+	b, err = os.ReadFile(filepath.Join(dir, ".._t2", "conversion.jsonl"))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"table":"../t2","cols":["c"],"vals":["y"]}`+"\n", string(b))
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// MigrationCatalogTable is the table of the target database in which each
+// migration run is recorded, so that migrations can be audited after the fact.
+const MigrationCatalogTable = "harbourbridge_migration"
+
+// MigrationCatalogTablesTable is the table of the target database in which
+// the row counts and validation results of each table of a migration run are
+// recorded. It is interleaved in MigrationCatalogTable.
+const MigrationCatalogTablesTable = "harbourbridge_migration_tables"
+
+// Validation results of migration runs recorded in the migration catalog.
+const (
+	ValidationNotRun = "NOT_RUN"
+	ValidationPassed = "PASSED"
+	ValidationFailed = "FAILED"
+)
+
+// MigrationCatalogCols are the columns of MigrationCatalogTable written by a
+// MigrationRecord, in the order of MigrationRecord.Values. The commit
+// timestamp of the run's record is written to column CompletedAt.
+var MigrationCatalogCols = []string{"MigrationRequestId", "MigrationType", "SourceDriver", "Source", "SourceSchemaFingerprint", "SpannerSchemaFingerprint", "StartedAt", "SourceRows", "MigratedRows", "BadRows", "Validation"}
+
+// MigrationCatalogTablesCols are the columns of MigrationCatalogTablesTable
+// written by a TableRecord, in the order of TableRecord.Values.
+var MigrationCatalogTablesCols = []string{"MigrationRequestId", "TableName", "SourceTable", "SourceRows", "GoodRows", "BadRows", "DroppedRows", "ExpectedRows", "ActualRows"}
+
+// IsMigrationCatalogTable returns true if table is one of the tables of the
+// migration catalog, or the table of migration locks, which aren't part of
+// the migrated schema.
+func IsMigrationCatalogTable(table string) bool {
+	return table == MigrationCatalogTable || table == MigrationCatalogTablesTable || table == MigrationLockTable
+}
+
+// MigrationCatalogDDL returns the CREATE TABLE statements of the migration
+// catalog for target database targetDb.
+func MigrationCatalogDDL(targetDb string) []string {
+	if targetDb == constants.TargetExperimentalPostgres {
+		return []string{
+			fmt.Sprintf(`CREATE TABLE "%s" (
+	"MigrationRequestId" VARCHAR(2621440) NOT NULL,
+	"MigrationType" VARCHAR(2621440),
+	"SourceDriver" VARCHAR(2621440),
+	"Source" VARCHAR(2621440),
+	"SourceSchemaFingerprint" VARCHAR(2621440),
+	"SpannerSchemaFingerprint" VARCHAR(2621440),
+	"StartedAt" TIMESTAMPTZ,
+	"CompletedAt" SPANNER.COMMIT_TIMESTAMP NOT NULL,
+	"SourceRows" INT8,
+	"MigratedRows" INT8,
+	"BadRows" INT8,
+	"Validation" VARCHAR(2621440),
+	PRIMARY KEY ("MigrationRequestId")
+)`, MigrationCatalogTable),
+			fmt.Sprintf(`CREATE TABLE "%s" (
+	"MigrationRequestId" VARCHAR(2621440) NOT NULL,
+	"TableName" VARCHAR(2621440) NOT NULL,
+	"SourceTable" VARCHAR(2621440),
+	"SourceRows" INT8,
+	"GoodRows" INT8,
+	"BadRows" INT8,
+	"DroppedRows" INT8,
+	"ExpectedRows" INT8,
+	"ActualRows" INT8,
+	PRIMARY KEY ("MigrationRequestId", "TableName")
+) INTERLEAVE IN PARENT "%s" ON DELETE CASCADE`, MigrationCatalogTablesTable, MigrationCatalogTable),
+		}
+	}
+	return []string{
+		fmt.Sprintf("CREATE TABLE `%s` (\n"+
+			"\t`MigrationRequestId` STRING(MAX) NOT NULL,\n"+
+			"\t`MigrationType` STRING(MAX),\n"+
+			"\t`SourceDriver` STRING(MAX),\n"+
+			"\t`Source` STRING(MAX),\n"+
+			"\t`SourceSchemaFingerprint` STRING(MAX),\n"+
+			"\t`SpannerSchemaFingerprint` STRING(MAX),\n"+
+			"\t`StartedAt` TIMESTAMP,\n"+
+			"\t`CompletedAt` TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true),\n"+
+			"\t`SourceRows` INT64,\n"+
+			"\t`MigratedRows` INT64,\n"+
+			"\t`BadRows` INT64,\n"+
+			"\t`Validation` STRING(MAX),\n"+
+			") PRIMARY KEY (`MigrationRequestId`)", MigrationCatalogTable),
+		fmt.Sprintf("CREATE TABLE `%s` (\n"+
+			"\t`MigrationRequestId` STRING(MAX) NOT NULL,\n"+
+			"\t`TableName` STRING(MAX) NOT NULL,\n"+
+			"\t`SourceTable` STRING(MAX),\n"+
+			"\t`SourceRows` INT64,\n"+
+			"\t`GoodRows` INT64,\n"+
+			"\t`BadRows` INT64,\n"+
+			"\t`DroppedRows` INT64,\n"+
+			"\t`ExpectedRows` INT64,\n"+
+			"\t`ActualRows` INT64,\n"+
+			") PRIMARY KEY (`MigrationRequestId`, `TableName`),\n"+
+			"INTERLEAVE IN PARENT `%s` ON DELETE CASCADE", MigrationCatalogTablesTable, MigrationCatalogTable),
+	}
+}
+
+// MigrationRecord is the record of a migration run in the migration catalog.
+type MigrationRecord struct {
+	MigrationRequestId       string
+	MigrationType            string
+	SourceDriver             string
+	Source                   string // Identifies the source database, e.g. host, port and database name, or dump file.
+	SourceSchemaFingerprint  string
+	SpannerSchemaFingerprint string
+	StartedAt                time.Time
+	SourceRows               int64
+	MigratedRows             int64
+	BadRows                  int64
+	Validation               string // One of ValidationNotRun, ValidationPassed and ValidationFailed.
+	Tables                   []TableRecord
+}
+
+// TableRecord is the record of a table of a migration run in the migration
+// catalog.
+type TableRecord struct {
+	TableName    string
+	SourceTable  string
+	SourceRows   int64
+	GoodRows     int64
+	BadRows      int64
+	DroppedRows  int64 // Rows converted but not written to Spanner.
+	ExpectedRows int64
+	ActualRows   *int64 // Rows of the Spanner table, or nil if row counts weren't validated.
+}
+
+// Values returns the values of the columns MigrationCatalogCols of r.
+func (r MigrationRecord) Values() []interface{} {
+	return []interface{}{r.MigrationRequestId, r.MigrationType, r.SourceDriver, r.Source, r.SourceSchemaFingerprint, r.SpannerSchemaFingerprint, r.StartedAt, r.SourceRows, r.MigratedRows, r.BadRows, r.Validation}
+}
+
+// Values returns the values of the columns MigrationCatalogTablesCols of
+// table t of migration run r.
+func (t TableRecord) Values(r MigrationRecord) []interface{} {
+	var actual interface{}
+	if t.ActualRows != nil {
+		actual = *t.ActualRows
+	}
+	return []interface{}{r.MigrationRequestId, t.TableName, t.SourceTable, t.SourceRows, t.GoodRows, t.BadRows, t.DroppedRows, t.ExpectedRows, actual}
+}
+
+// MigrationRecord returns the record of the migration run of conv, which
+// started at startedAt and read the source database identified by source.
+// droppedRows counts the rows that couldn't be written, by Spanner table.
+// actualRows holds the row counts of the Spanner tables whose row count was
+// validated, or is nil if row counts weren't validated: a table whose row
+// count isn't in actualRows is assumed to have the rows expected.
+func (conv *Conv) MigrationRecord(driver, source string, startedAt time.Time, droppedRows, actualRows map[string]int64) MigrationRecord {
+	r := MigrationRecord{
+		MigrationRequestId:       conv.Audit.MigrationRequestId,
+		SourceDriver:             driver,
+		Source:                   source,
+		SourceSchemaFingerprint:  conv.SrcFingerprint,
+		SpannerSchemaFingerprint: SpannerSchemaFingerprint(conv),
+		StartedAt:                startedAt,
+		Validation:               ValidationNotRun,
+	}
+	if conv.Audit.MigrationType != nil {
+		r.MigrationType = conv.Audit.MigrationType.String()
+	}
+	if actualRows != nil {
+		r.Validation = ValidationPassed
+	}
+	for _, spTable := range ddl.OrderTables(conv.SpSchema) {
+		srcTable, err := GetSourceTable(conv, spTable)
+		if err != nil {
+			continue
+		}
+		t := TableRecord{
+			TableName:   spTable,
+			SourceTable: srcTable,
+			SourceRows:  conv.Stats.Rows[srcTable],
+			GoodRows:    conv.Stats.GoodRows[srcTable],
+			BadRows:     conv.Stats.BadRows[srcTable],
+			DroppedRows: droppedRows[spTable],
+		}
+		t.ExpectedRows = t.GoodRows - t.DroppedRows
+		if actualRows != nil {
+			actual, ok := actualRows[spTable]
+			if !ok {
+				actual = t.ExpectedRows
+			}
+			if actual != t.ExpectedRows {
+				r.Validation = ValidationFailed
+			}
+			t.ActualRows = &actual
+		}
+		r.SourceRows += t.SourceRows
+		r.MigratedRows += t.ExpectedRows
+		r.BadRows += t.BadRows + t.DroppedRows
+		r.Tables = append(r.Tables, t)
+	}
+	return r
+}
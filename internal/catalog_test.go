@@ -0,0 +1,94 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	migrationpb "github.com/cloudspannerecosystem/harbourbridge/proto/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func catalogConv() *Conv {
+	conv := MakeConv()
+	conv.SpSchema["orders"] = ddl.CreateTable{Name: "orders", ColNames: []string{"id"}, ColDefs: map[string]ddl.ColumnDef{"id": {Name: "id", T: ddl.Type{Name: ddl.Int64}}}, Pks: []ddl.IndexKey{{Col: "id"}}}
+	conv.SpSchema["users"] = ddl.CreateTable{Name: "users", ColNames: []string{"id"}, ColDefs: map[string]ddl.ColumnDef{"id": {Name: "id", T: ddl.Type{Name: ddl.Int64}}}, Pks: []ddl.IndexKey{{Col: "id"}}}
+	conv.ToSource["orders"] = NameAndCols{Name: "Orders"}
+	conv.ToSource["users"] = NameAndCols{Name: "Users"}
+	conv.Stats.Rows = map[string]int64{"Orders": 10, "Users": 5}
+	conv.Stats.GoodRows = map[string]int64{"Orders": 9, "Users": 5}
+	conv.Stats.BadRows = map[string]int64{"Orders": 1}
+	conv.Audit.MigrationRequestId = "HB-1"
+	conv.Audit.MigrationType = migrationpb.MigrationData_SCHEMA_AND_DATA.Enum()
+	conv.SrcFingerprint = "abc"
+	return conv
+}
+
+func TestMigrationRecord(t *testing.T) {
+	conv := catalogConv()
+	startedAt := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	r := conv.MigrationRecord(constants.MYSQLDUMP, "dump.sql", startedAt, map[string]int64{"orders": 2}, nil)
+	assert.Equal(t, "HB-1", r.MigrationRequestId)
+	assert.Equal(t, "SCHEMA_AND_DATA", r.MigrationType)
+	assert.Equal(t, "abc", r.SourceSchemaFingerprint)
+	assert.Equal(t, SpannerSchemaFingerprint(conv), r.SpannerSchemaFingerprint)
+	assert.Equal(t, ValidationNotRun, r.Validation)
+	assert.Equal(t, int64(15), r.SourceRows)
+	assert.Equal(t, int64(12), r.MigratedRows)
+	assert.Equal(t, int64(3), r.BadRows)
+	assert.Equal(t, []TableRecord{
+		{TableName: "orders", SourceTable: "Orders", SourceRows: 10, GoodRows: 9, BadRows: 1, DroppedRows: 2, ExpectedRows: 7},
+		{TableName: "users", SourceTable: "Users", SourceRows: 5, GoodRows: 5, ExpectedRows: 5},
+	}, r.Tables)
+	assert.Equal(t, len(MigrationCatalogCols), len(r.Values()))
+	assert.Equal(t, []interface{}{"HB-1", "orders", "Orders", int64(10), int64(9), int64(1), int64(2), int64(7), nil}, r.Tables[0].Values(r))
+
+	r = conv.MigrationRecord(constants.MYSQLDUMP, "dump.sql", startedAt, nil, map[string]int64{})
+	assert.Equal(t, ValidationPassed, r.Validation)
+	assert.Equal(t, int64(9), *r.Tables[0].ActualRows)
+
+	r = conv.MigrationRecord(constants.MYSQLDUMP, "dump.sql", startedAt, nil, map[string]int64{"users": 4})
+	assert.Equal(t, ValidationFailed, r.Validation)
+	assert.Equal(t, int64(4), r.Tables[1].Values(r)[8])
+}
+
+func TestSpannerSchemaFingerprint(t *testing.T) {
+	conv := catalogConv()
+	fp := SpannerSchemaFingerprint(conv)
+	assert.Equal(t, fp, SpannerSchemaFingerprint(catalogConv()))
+	conv.SpSchema["users"] = ddl.CreateTable{Name: "users", ColNames: []string{"id"}, ColDefs: map[string]ddl.ColumnDef{"id": {Name: "id", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}}}, Pks: []ddl.IndexKey{{Col: "id"}}}
+	assert.NotEqual(t, fp, SpannerSchemaFingerprint(conv))
+}
+
+func TestMigrationCatalogDDL(t *testing.T) {
+	assert.True(t, IsMigrationCatalogTable(MigrationCatalogTablesTable))
+	assert.False(t, IsMigrationCatalogTable("orders"))
+	for _, targetDb := range []string{constants.TargetSpanner, constants.TargetExperimentalPostgres} {
+		stmts := MigrationCatalogDDL(targetDb)
+		assert.Equal(t, 2, len(stmts))
+		for _, col := range append(MigrationCatalogCols, "CompletedAt") {
+			assert.Contains(t, stmts[0], col)
+		}
+		for _, col := range MigrationCatalogTablesCols {
+			assert.Contains(t, stmts[1], col)
+		}
+		assert.True(t, strings.Contains(stmts[1], "INTERLEAVE IN PARENT"))
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"sort"
+)
+
+// AttributeCensus is the frequency of the attributes of the items of a
+// schemaless source table, such as a DynamoDB table, as found by schema
+// inference.
+type AttributeCensus struct {
+	Items      int64            // Number of items read.
+	Full       bool             // Whether all the items of the table were read, rather than a sample.
+	Attributes map[string]int64 // Number of items having each attribute.
+	CatchAll   string           // Name of the column holding the attributes without a column of their own, if any.
+	// Numbers is the precision of the number values of each attribute
+	// having some.
+	Numbers map[string]NumberPrecision
+}
+
+// NumberPrecision is the precision of the number values of an attribute, as
+// found by schema inference.
+type NumberPrecision struct {
+	Values        int64 // Number of values read.
+	IntegerDigits int   // Largest number of digits before the decimal point.
+	Scale         int   // Largest number of digits after the decimal point.
+	Exceeding     int64 // Number of values that don't fit Spanner's NUMERIC type.
+}
+
+// Merge returns the precision of the values of both p and q.
+func (p NumberPrecision) Merge(q NumberPrecision) NumberPrecision {
+	p.Values += q.Values
+	p.Exceeding += q.Exceeding
+	if q.IntegerDigits > p.IntegerDigits {
+		p.IntegerDigits = q.IntegerDigits
+	}
+	if q.Scale > p.Scale {
+		p.Scale = q.Scale
+	}
+	return p
+}
+
+// AddAttributeCensus records the attribute census of source table srcTable.
+func (conv *Conv) AddAttributeCensus(srcTable string, census AttributeCensus) {
+	if conv.AttributeCensus == nil {
+		conv.AttributeCensus = make(map[string]AttributeCensus)
+	}
+	conv.AttributeCensus[srcTable] = census
+}
+
+// Frequency returns the fraction of items having attribute attr.
+func (c AttributeCensus) Frequency(attr string) float64 {
+	if c.Items == 0 {
+		return 0
+	}
+	return float64(c.Attributes[attr]) / float64(c.Items)
+}
+
+// SortedAttributes returns the attributes of c, most frequent first and
+// then by name.
+func (c AttributeCensus) SortedAttributes() []string {
+	var attrs []string
+	for a := range c.Attributes {
+		attrs = append(attrs, a)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		if c.Attributes[attrs[i]] != c.Attributes[attrs[j]] {
+			return c.Attributes[attrs[i]] > c.Attributes[attrs[j]]
+		}
+		return attrs[i] < attrs[j]
+	})
+	return attrs
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// InsensitiveCollation returns true if source collation collation compares
+// strings case- or accent-insensitively, e.g. MySQL's utf8mb4_0900_ai_ci or
+// SQL Server's SQL_Latin1_General_CP1_CI_AS. Spanner always compares and
+// orders strings by their binary value.
+func InsensitiveCollation(collation string) bool {
+	c := strings.ToLower(collation)
+	for _, s := range []string{"_ci", "_ai"} {
+		if strings.HasSuffix(c, s) || strings.Contains(c, s+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// CollationImpact describes a Spanner primary key or secondary index whose
+// key includes STRING columns with an insensitive source collation (see
+// InsensitiveCollation). Its order, and for unique keys its uniqueness,
+// differ from the source database.
+type CollationImpact struct {
+	SpTable string
+	Index   string   // Name of the Spanner index, empty for the primary key.
+	Unique  bool     // Whether the key is unique, i.e. a primary key or unique index.
+	SpCols  []string // Key columns with an insensitive source collation.
+}
+
+// DetectCollationImpacts returns the primary keys and indexes affected by
+// insensitive source collations, sorted by table and index name.
+func DetectCollationImpacts(conv *Conv) []CollationImpact {
+	var impacts []CollationImpact
+	for spTable, ct := range conv.SpSchema {
+		if cols := conv.insensitiveKeyCols(spTable, ct.Pks); len(cols) > 0 {
+			impacts = append(impacts, CollationImpact{SpTable: spTable, Unique: true, SpCols: cols})
+		}
+		for _, idx := range ct.Indexes {
+			if cols := conv.insensitiveKeyCols(spTable, idx.Keys); len(cols) > 0 {
+				impacts = append(impacts, CollationImpact{SpTable: spTable, Index: idx.Name, Unique: idx.Unique, SpCols: cols})
+			}
+		}
+	}
+	sort.Slice(impacts, func(i, j int) bool {
+		if impacts[i].SpTable != impacts[j].SpTable {
+			return impacts[i].SpTable < impacts[j].SpTable
+		}
+		return impacts[i].Index < impacts[j].Index
+	})
+	return impacts
+}
+
+// insensitiveKeyCols returns the STRING columns of keys of Spanner table
+// spTable whose source collation is insensitive.
+func (conv *Conv) insensitiveKeyCols(spTable string, keys []ddl.IndexKey) []string {
+	var cols []string
+	for _, k := range keys {
+		cd := conv.SpSchema[spTable].ColDefs[k.Col]
+		if cd.T.Name != ddl.String || cd.T.IsArray {
+			continue
+		}
+		if InsensitiveCollation(conv.sourceCollation(spTable, k.Col)) {
+			cols = append(cols, k.Col)
+		}
+	}
+	return cols
+}
+
+// sourceCollation returns the collation of the source column of Spanner
+// column spCol of spTable, or "" if unknown.
+func (conv *Conv) sourceCollation(spTable, spCol string) string {
+	srcTable, err := GetSourceTable(conv, spTable)
+	if err != nil {
+		return ""
+	}
+	srcCol, ok := conv.ToSource[spTable].Cols[spCol]
+	if !ok {
+		return ""
+	}
+	return conv.SrcSchema[srcTable].ColDefs[srcCol].Collation
+}
+
+// AddCollationIssues records a schema issue for the columns of each key in
+// impacts, so that they are shown in reports: CollationUnique for columns of
+// unique keys, whose values may no longer be duplicates in Spanner, and
+// CollationOrder for columns of other indexes. CollationUnique subsumes
+// CollationOrder.
+func AddCollationIssues(conv *Conv, impacts []CollationImpact) {
+	for _, ci := range impacts {
+		srcTable, err := GetSourceTable(conv, ci.SpTable)
+		if err != nil {
+			continue
+		}
+		for _, spCol := range ci.SpCols {
+			srcCol, ok := conv.ToSource[ci.SpTable].Cols[spCol]
+			if !ok {
+				continue
+			}
+			if conv.Issues[srcTable] == nil {
+				conv.Issues[srcTable] = make(map[string][]SchemaIssue)
+			}
+			var issues []SchemaIssue
+			unique, order := false, false
+			for _, i := range conv.Issues[srcTable][srcCol] {
+				unique = unique || i == CollationUnique
+				order = order || i == CollationOrder
+				if i != CollationOrder || !ci.Unique {
+					issues = append(issues, i)
+				}
+			}
+			switch {
+			case unique:
+			case ci.Unique:
+				issues = append(issues, CollationUnique)
+			case !order:
+				issues = append(issues, CollationOrder)
+			}
+			conv.Issues[srcTable][srcCol] = issues
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsensitiveCollation(t *testing.T) {
+	for c, expected := range map[string]bool{
+		"utf8mb4_0900_ai_ci":           true,
+		"latin1_swedish_ci":            true,
+		"SQL_Latin1_General_CP1_CI_AS": true,
+		"Latin1_General_CS_AI":         true,
+		"utf8mb4_0900_as_cs":           false,
+		"utf8mb4_bin":                  false,
+		"Latin1_General_BIN2":          false,
+		"":                             false,
+	} {
+		assert.Equal(t, expected, InsensitiveCollation(c), c)
+	}
+}
+
+func TestDetectCollationImpacts(t *testing.T) {
+	conv := MakeConv()
+	conv.SrcSchema["users"] = schema.Table{
+		Name:     "users",
+		ColNames: []string{"email", "name", "code", "n"},
+		ColDefs: map[string]schema.Column{
+			"email": {Name: "email", Collation: "utf8mb4_0900_ai_ci"},
+			"name":  {Name: "name", Collation: "utf8mb4_0900_ai_ci"},
+			"code":  {Name: "code", Collation: "utf8mb4_bin"},
+			"n":     {Name: "n"},
+		},
+	}
+	str := ddl.Type{Name: ddl.String, Len: 100}
+	conv.SpSchema["users"] = ddl.CreateTable{
+		Name:     "users",
+		ColNames: []string{"email", "name", "code", "n"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"email": {Name: "email", T: str},
+			"name":  {Name: "name", T: str},
+			"code":  {Name: "code", T: str},
+			"n":     {Name: "n", T: ddl.Type{Name: ddl.Int64}},
+		},
+		Pks: []ddl.IndexKey{{Col: "email"}},
+		Indexes: []ddl.CreateIndex{
+			{Name: "by_name", Table: "users", Keys: []ddl.IndexKey{{Col: "name"}, {Col: "n"}}},
+			{Name: "by_name_email", Table: "users", Unique: true, Keys: []ddl.IndexKey{{Col: "name"}, {Col: "email"}}},
+			{Name: "by_code", Table: "users", Unique: true, Keys: []ddl.IndexKey{{Col: "code"}}},
+		},
+	}
+	cols := map[string]string{"email": "email", "name": "name", "code": "code", "n": "n"}
+	conv.ToSource["users"] = NameAndCols{Name: "users", Cols: cols}
+	conv.ToSpanner["users"] = NameAndCols{Name: "users", Cols: cols}
+	impacts := DetectCollationImpacts(conv)
+	assert.Equal(t, []CollationImpact{
+		{SpTable: "users", Unique: true, SpCols: []string{"email"}},
+		{SpTable: "users", Index: "by_name", SpCols: []string{"name"}},
+		{SpTable: "users", Index: "by_name_email", Unique: true, SpCols: []string{"name", "email"}},
+	}, impacts)
+	AddCollationIssues(conv, impacts)
+	AddCollationIssues(conv, impacts)
+	assert.Equal(t, map[string][]SchemaIssue{
+		"email": {CollationUnique},
+		"name":  {CollationUnique},
+	}, conv.Issues["users"])
+
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeCollationImpacts(impacts, w)
+	w.Flush()
+	expected := `----------------------------
+Collations
+----------------------------
+Spanner orders and compares strings by their binary value, but the following keys
+have columns with a case- or accent-insensitive source collation. Their order
+differs from the source database, and unique keys accept values that differ only
+in case or accents, which were duplicates in the source database. To keep the
+source semantics, add a normalized shadow column (e.g. a generated column holding
+LOWER of the column) and use it in the key instead, or normalize values in the
+application.
+
+  Table users, primary key: columns email
+  Table users, index by_name: columns name
+  Table users, unique index by_name_email: columns name, email
+
+`
+	assert.Equal(t, expected, buf.String())
+}
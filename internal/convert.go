@@ -27,24 +27,70 @@ import (
 
 // Conv contains all schema and data conversion state.
 type Conv struct {
-	mode           mode                                // Schema mode or data mode.
-	SpSchema       ddl.Schema                          // Maps Spanner table name to Spanner schema.
-	SyntheticPKeys map[string]SyntheticPKey            // Maps Spanner table name to synthetic primary key (if needed).
-	SrcSchema      map[string]schema.Table             // Maps source-DB table name to schema information.
-	Issues         map[string]map[string][]SchemaIssue // Maps source-DB table/col to list of schema conversion issues.
-	ToSpanner      map[string]NameAndCols              // Maps from source-DB table name to Spanner name and column mapping.
-	ToSource       map[string]NameAndCols              // Maps from Spanner table name to source-DB table name and column mapping.
-	UsedNames      map[string]bool                     // Map storing the names that are already assigned to tables, indices or foreign key contraints.
-	dataSink       func(table string, cols []string, values []interface{})
-	DataFlush      func()         `json:"-"` // Data flush is used to flush out remaining writes and wait for them to complete.
-	Location       *time.Location // Timezone (for timestamp conversion).
-	sampleBadRows  rowSamples     // Rows that generated errors during conversion.
-	Stats          stats
-	TimezoneOffset string              // Timezone offset for timestamp conversion.
-	TargetDb       string              // The target database to which HarbourBridge is writing.
-	UniquePKey     map[string][]string // Maps Spanner table name to unique column name being used as primary key (if needed).
-	Audit          Audit               // Stores the audit information for the database conversion
-}
+	mode              mode                                // Schema mode or data mode.
+	SpSchema          ddl.Schema                          // Maps Spanner table name to Spanner schema.
+	SyntheticPKeys    map[string]SyntheticPKey            // Maps Spanner table name to synthetic primary key (if needed).
+	SrcSchema         map[string]schema.Table             // Maps source-DB table name to schema information.
+	Issues            map[string]map[string][]SchemaIssue // Maps source-DB table/col to list of schema conversion issues.
+	ToSpanner         map[string]NameAndCols              // Maps from source-DB table name to Spanner name and column mapping.
+	ToSource          map[string]NameAndCols              // Maps from Spanner table name to source-DB table name and column mapping.
+	UsedNames         map[string]bool                     // Map storing the names that are already assigned to tables, indices or foreign key contraints.
+	dataSink          func(table string, cols []string, values []interface{})
+	DataFlush         func()                       `json:"-"` // Data flush is used to flush out remaining writes and wait for them to complete.
+	Location          *time.Location               // Timezone (for timestamp conversion).
+	sampleBadRows     rowSamples                   // Rows that generated errors during conversion.
+	badDataSampleSize int                          // Number of bad rows of each kind written to the bad data file.
+	badData           *BadDataWriter               // If set, all rows that generate errors are written to it.
+	issueSuppressions []IssueSuppression           // Rules for suppressing schema issues from reports.
+	keyRewrites       map[string]map[string]string // Cache of columns re-keyed by KeyStrategyUUID (see uuidKeyRewrites).
+	oversize          oversizeConfig               // Handling of values larger than MaxValueBytes.
+	invalidDates      invalidDateConfig            // Handling of invalid source dates and datetimes.
+	mergeMembers      map[string]int               // Maps merged source table name to the index of the table whose data is being migrated.
+	Stats             stats
+	TimezoneOffset    string                      // Timezone offset for timestamp conversion.
+	DatetimeZones     DatetimeZones               // Time zones of source datetimes without time zone.
+	TargetDb          string                      // The target database to which HarbourBridge is writing.
+	UniquePKey        map[string][]string         // Maps Spanner table name to unique column name being used as primary key (if needed).
+	Audit             Audit                       // Stores the audit information for the database conversion
+	KeyStrategies     map[string]KeyStrategy      // Maps Spanner table name to the strategy used to replace its auto-increment key (if any).
+	TableSplits       map[string]TableSplit       // Maps Spanner table name to its vertical split into tables sharing its primary key (if any).
+	NestedTables      map[string][]NestedTable    // Maps Spanner table name to the tables its List and Map columns are nested into (if any).
+	TableMerges       map[string]TableMerge       // Maps source-DB table name to the source tables merged into it (if any).
+	MergeRules        []MergeRule                 `json:"-"` // Rules merging source tables during schema conversion.
+	DeferIndexes      bool                        `json:"-"` // If true, secondary indexes are created after data migration instead of with their tables.
+	SelectedTables    map[string]bool             `json:"-"` // Source-DB tables to convert, or nil to convert all tables.
+	ErrorBudget       *ErrorBudget                `json:"-"` // Limits on the rows data migration may lose, if set.
+	Schedule          *Schedule                   `json:"-"` // Daily windows data migration is restricted to, if set.
+	ForceLock         bool                        `json:"-"` // If true, data migration takes over the leases other migrations hold on its tables (see MigrationLock).
+	Shards            *Shards                     // Source databases merged into the Spanner database, if there are several.
+	IssueReviews      map[string]IssueReview      // Maps ReviewKey of a schema issue to its review (if reviewed).
+	DdlEdits          map[string]DdlEdit          // Maps Spanner table name to manual edits of its DDL statements (if edited).
+	WriteOptions      WriteOptions                `json:"-"` // Priority, tag and mode of the Spanner writes of data migration.
+	WriteTuning       WriteTuning                 `json:"-"` // Per-table parallelism and auto-tuning of the Spanner writes of bulk data migration.
+	WriteStats        *WriteStats                 `json:"-"` // Throughput and latency of the Spanner writes of bulk data migration, if recorded.
+	Timings           *Timings                    `json:"-"` // Time spent in each stage of the migration.
+	MaterializedViews map[string]MaterializedView // Maps source-DB materialized view name to its definition.
+	AttributeCensus   map[string]AttributeCensus  // Maps source-DB table name to the frequency of its attributes, for schemaless sources.
+	Fixups            []Fixup                     // Statements run as Partitioned DML after data migration, in order.
+	Grants            []Grant                     // Grants of privileges on source tables, migrated as database roles.
+	SrcFingerprint    string                      // SchemaFingerprint of the source schema when schema conversion ran, checked before data migration.
+	Dialect           string                      // Dialect of the source dump (e.g. DialectRedshift), if detected.
+}
+
+// WriteOptions are the options of the Spanner writes of bulk and streaming
+// data migration, which distinguish migration traffic from serving traffic
+// on a shared instance.
+type WriteOptions struct {
+	Priority string // Request priority: "low", "medium" or "high" (Spanner's default if empty).
+	Tag      string // Request and transaction tag (untagged if empty).
+	Mode     string // How bulk data migration writes rows: WriteModeMutation (if empty) or WriteModeDML.
+}
+
+// Modes of the writes of bulk data migration.
+const (
+	WriteModeMutation = "mutation" // Rows are written with mutations.
+	WriteModeDML      = "dml"      // Rows are written with batched DML INSERT statements.
+)
 
 type mode int
 
@@ -59,6 +105,7 @@ const (
 type SyntheticPKey struct {
 	Col      string
 	Sequence int64
+	Type     string // Type of key, one of SyntheticKeySequence (if empty), SyntheticKeyUUID or SyntheticKeyULID.
 }
 
 // SchemaIssue specifies a schema conversion issue.
@@ -90,8 +137,57 @@ const (
 	InterleavedOrder
 	InterleavedAddColumn
 	IllegalName
+	UnsignedOverflow
+	Domain
+	DomainCheck
+	Composite
+	SourceSequence
+	CollationUnique
+	CollationOrder
 )
 
+var schemaIssueNames = map[SchemaIssue]string{
+	DefaultValue:          "DefaultValue",
+	ForeignKey:            "ForeignKey",
+	MissingPrimaryKey:     "MissingPrimaryKey",
+	MultiDimensionalArray: "MultiDimensionalArray",
+	NoGoodType:            "NoGoodType",
+	Numeric:               "Numeric",
+	NumericThatFits:       "NumericThatFits",
+	Decimal:               "Decimal",
+	DecimalThatFits:       "DecimalThatFits",
+	Serial:                "Serial",
+	AutoIncrement:         "AutoIncrement",
+	Timestamp:             "Timestamp",
+	Datetime:              "Datetime",
+	Widened:               "Widened",
+	Time:                  "Time",
+	StringOverflow:        "StringOverflow",
+	HotspotTimestamp:      "HotspotTimestamp",
+	HotspotAutoIncrement:  "HotspotAutoIncrement",
+	InterleavedNotInOrder: "InterleavedNotInOrder",
+	InterleavedOrder:      "InterleavedOrder",
+	InterleavedAddColumn:  "InterleavedAddColumn",
+	IllegalName:           "IllegalName",
+	UnsignedOverflow:      "UnsignedOverflow",
+	Domain:                "Domain",
+	DomainCheck:           "DomainCheck",
+	Composite:             "Composite",
+	SourceSequence:        "SourceSequence",
+	CollationUnique:       "CollationUnique",
+	CollationOrder:        "CollationOrder",
+}
+
+// Name returns the name of the schema issue e.g. "NoGoodType". Unlike the
+// numeric value of a SchemaIssue, names are stable across releases and are
+// used to identify issues in machine-readable reports.
+func (i SchemaIssue) Name() string {
+	if n, ok := schemaIssueNames[i]; ok {
+		return n
+	}
+	return fmt.Sprintf("SchemaIssue(%d)", int(i))
+}
+
 // NameAndCols contains the name of a table and its columns.
 // Used to map between source DB and Spanner table and column names.
 type NameAndCols struct {
@@ -125,12 +221,25 @@ type row struct {
 // c) successfully converted, but an error occurs when writing the row to Spanner.
 // d) unsuccessfully converted (we won't try to write such rows to Spanner).
 type stats struct {
-	Rows       map[string]int64          // Count of rows encountered during processing (a + b + c + d), broken down by source table.
-	GoodRows   map[string]int64          // Count of rows successfully converted (b + c), broken down by source table.
-	BadRows    map[string]int64          // Count of rows where conversion failed (d), broken down by source table.
-	Statement  map[string]*statementStat // Count of processed statements, broken down by statement type.
-	Unexpected map[string]int64          // Count of unexpected conditions, broken down by condition description.
-	Reparsed   int64                     // Count of times we re-parse dump data looking for end-of-statement.
+	Rows             map[string]int64            // Count of rows encountered during processing (a + b + c + d), broken down by source table.
+	GoodRows         map[string]int64            // Count of rows successfully converted (b + c), broken down by source table.
+	BadRows          map[string]int64            // Count of rows where conversion failed (d), broken down by source table.
+	Statement        map[string]*statementStat   // Count of processed statements, broken down by statement type.
+	Unexpected       map[string]int64            // Count of unexpected conditions, broken down by condition description.
+	Reparsed         int64                       // Count of times we re-parse dump data looking for end-of-statement.
+	UniqueViolations map[string]int64            // Count of rows not written because of a unique index violation, broken down by Spanner index name.
+	FkViolations     map[string]int64            // Count of rows violating a foreign key found before creating it, broken down by Spanner foreign key name.
+	DuplicateKeys    map[string][]DuplicateKey   // Keys held by more than one row found before creating a unique index, broken down by Spanner index name.
+	Oversized        map[string]int64            // Count of values larger than MaxValueBytes, broken down by Spanner table name.
+	UnsignedOverflow map[string]int64            // Count of unsigned integer values larger than math.MaxInt64, broken down by source table.
+	InvalidDates     map[string]map[string]int64 // Count of invalid dates and datetimes, broken down by source table and column.
+}
+
+// DuplicateKey is a key of a unique index held by more than one row of the
+// migrated data.
+type DuplicateKey struct {
+	Key  string `json:"key"`  // Values of the key columns, formatted for the report.
+	Rows int64  `json:"rows"` // Count of rows with the key.
 }
 
 type statementStat struct {
@@ -155,35 +264,48 @@ type Audit struct {
 
 // Stores information related to the streaming migration process.
 type streamingStats struct {
-	Streaming        bool                        // Flag for confirmation of streaming migration.
-	TotalRecords     map[string]map[string]int64 // Tablewise count of records received for processing, broken down by record type i.e. INSERT, MODIFY & REMOVE.
-	BadRecords       map[string]map[string]int64 // Tablewise count of records not converted successfully, broken down by record type.
-	DroppedRecords   map[string]map[string]int64 // Tablewise count of records successfully converted but failed to written on Spanner, broken down by record type.
-	SampleBadRecords []string                    // Records that generated errors during conversion.
-	SampleBadWrites  []string                    // Records that faced errors while writing to Cloud Spanner.
+	Streaming         bool                        // Flag for confirmation of streaming migration.
+	TotalRecords      map[string]map[string]int64 // Tablewise count of records received for processing, broken down by record type i.e. INSERT, MODIFY & REMOVE.
+	BadRecords        map[string]map[string]int64 // Tablewise count of records not converted successfully, broken down by record type.
+	DroppedRecords    map[string]map[string]int64 // Tablewise count of records successfully converted but failed to written on Spanner, broken down by record type.
+	StaleRecords      map[string]map[string]int64 // Tablewise count of records skipped since the record or a newer record for the same key was already written, broken down by record type.
+	UnknownAttributes map[string]map[string]int64 // Tablewise count of records containing attributes missing from the schema, broken down by attribute name.
+	AddedColumns      map[string][]string         // Tablewise list of attributes for which a nullable column was added to the schema during streaming.
+	SampleBadRecords  []string                    // Records that generated errors during conversion.
+	SampleBadWrites   []string                    // Records that faced errors while writing to Cloud Spanner.
 }
 
 // MakeConv returns a default-configured Conv.
 func MakeConv() *Conv {
 	return &Conv{
-		SpSchema:       ddl.NewSchema(),
-		SyntheticPKeys: make(map[string]SyntheticPKey),
-		SrcSchema:      make(map[string]schema.Table),
-		Issues:         make(map[string]map[string][]SchemaIssue),
-		ToSpanner:      make(map[string]NameAndCols),
-		ToSource:       make(map[string]NameAndCols),
-		UsedNames:      make(map[string]bool),
-		Location:       time.Local, // By default, use go's local time, which uses $TZ (when set).
-		sampleBadRows:  rowSamples{bytesLimit: 10 * 1000 * 1000},
+		SpSchema:          ddl.NewSchema(),
+		SyntheticPKeys:    make(map[string]SyntheticPKey),
+		SrcSchema:         make(map[string]schema.Table),
+		Issues:            make(map[string]map[string][]SchemaIssue),
+		ToSpanner:         make(map[string]NameAndCols),
+		ToSource:          make(map[string]NameAndCols),
+		UsedNames:         make(map[string]bool),
+		Location:          time.Local, // By default, use go's local time, which uses $TZ (when set).
+		sampleBadRows:     rowSamples{bytesLimit: 10 * 1000 * 1000},
+		badDataSampleSize: DefaultBadDataSampleSize,
 		Stats: stats{
-			Rows:       make(map[string]int64),
-			GoodRows:   make(map[string]int64),
-			BadRows:    make(map[string]int64),
-			Statement:  make(map[string]*statementStat),
-			Unexpected: make(map[string]int64),
+			Rows:             make(map[string]int64),
+			GoodRows:         make(map[string]int64),
+			BadRows:          make(map[string]int64),
+			Statement:        make(map[string]*statementStat),
+			Unexpected:       make(map[string]int64),
+			UniqueViolations: make(map[string]int64),
+			FkViolations:     make(map[string]int64),
+			DuplicateKeys:    make(map[string][]DuplicateKey),
+			Oversized:        make(map[string]int64),
+			UnsignedOverflow: make(map[string]int64),
+			InvalidDates:     make(map[string]map[string]int64),
 		},
 		TimezoneOffset: "+00:00", // By default, use +00:00 offset which is equal to UTC timezone
 		UniquePKey:     make(map[string][]string),
+		KeyStrategies:  make(map[string]KeyStrategy),
+		TableSplits:    make(map[string]TableSplit),
+		Timings:        NewTimings(),
 		Audit: Audit{
 			ToSpannerFkIdx: make(map[string]FkeyAndIdxs),
 			ToSourceFkIdx:  make(map[string]FkeyAndIdxs),
@@ -198,6 +320,69 @@ func (conv *Conv) SetDataSink(ds func(table string, cols []string, values []inte
 	conv.dataSink = ds
 }
 
+// SampleConv returns a copy of conv for converting sample data, e.g. for
+// previewing data conversion, without changing conv. Rows aren't written
+// anywhere until a data sink is set.
+func (conv *Conv) SampleConv() *Conv {
+	sc := conv.dataConv()
+	sc.dataSink = nil
+	sc.DataFlush = nil
+	sc.badData = nil
+	sc.WriteStats = nil
+	sc.Timings = nil
+	return sc
+}
+
+// dataConv returns a copy of conv whose data conversion state, such as stats
+// and synthetic key sequences, is separate from conv. Schema state is shared
+// and must not be modified.
+func (conv *Conv) dataConv() *Conv {
+	sc := *conv
+	sc.SyntheticPKeys = make(map[string]SyntheticPKey)
+	for t, pk := range conv.SyntheticPKeys {
+		sc.SyntheticPKeys[t] = pk
+	}
+	sc.keyRewrites = nil
+	sc.sampleBadRows = rowSamples{bytesLimit: conv.sampleBadRows.bytesLimit}
+	sc.Stats = stats{
+		Rows:             make(map[string]int64),
+		GoodRows:         make(map[string]int64),
+		BadRows:          make(map[string]int64),
+		Statement:        make(map[string]*statementStat),
+		Unexpected:       make(map[string]int64),
+		UniqueViolations: make(map[string]int64),
+		FkViolations:     make(map[string]int64),
+		DuplicateKeys:    make(map[string][]DuplicateKey),
+		Oversized:        make(map[string]int64),
+		UnsignedOverflow: make(map[string]int64),
+		InvalidDates:     make(map[string]map[string]int64),
+	}
+	return &sc
+}
+
+// SetBadDataSampleSize configures the number of bad rows of each kind that are
+// written to the bad data file.
+func (conv *Conv) SetBadDataSampleSize(n int) {
+	conv.badDataSampleSize = n
+}
+
+// BadDataSampleSize returns the number of bad rows of each kind that are
+// written to the bad data file.
+func (conv *Conv) BadDataSampleSize() int {
+	return conv.badDataSampleSize
+}
+
+// SetBadDataWriter configures conv to write all rows that generate errors to bw.
+func (conv *Conv) SetBadDataWriter(bw *BadDataWriter) {
+	conv.badData = bw
+}
+
+// BadDataWriter returns the writer used to write all rows that generate errors,
+// or nil if writing all bad rows isn't configured.
+func (conv *Conv) BadDataWriter() *BadDataWriter {
+	return conv.badData
+}
+
 // Note on modes.
 // We process the dump output twice. In the first pass (schema mode) we
 // build the schema, and the second pass (data mode) we write data to
@@ -222,6 +407,10 @@ func (conv *Conv) SetDataMode() {
 
 // WriteRow calls dataSink and updates row stats.
 func (conv *Conv) WriteRow(srcTable, spTable string, spCols []string, spVals []interface{}) {
+	spVals, ok := conv.checkValueSizes(srcTable, spTable, spCols, spVals)
+	if !ok {
+		return
+	}
 	if conv.Audit.DryRun {
 		conv.statsAddGoodRow(srcTable, conv.DataMode())
 	} else if conv.dataSink == nil {
@@ -231,8 +420,35 @@ func (conv *Conv) WriteRow(srcTable, spTable string, spCols []string, spVals []i
 
 		conv.Unexpected(msg)
 		conv.StatsAddBadRow(srcTable, conv.DataMode())
+	} else if conv.ErrorBudget.Aborted() != "" {
+		// Rows read after data migration was aborted are neither written nor counted.
+		return
 	} else {
-		conv.dataSink(spTable, spCols, spVals)
+		spCols, spVals = conv.rekeyRow(spTable, spCols, spVals)
+		spCols, spVals = conv.mergeRow(srcTable, spCols, spVals)
+		spCols, spVals = conv.shardRow(spCols, spVals)
+		var nested []NestedRows
+		if _, ok := conv.NestedTables[spTable]; ok {
+			var err error
+			spCols, spVals, nested, err = conv.NestRow(spTable, spCols, spVals)
+			if err != nil {
+				conv.Unexpected(err.Error())
+				conv.StatsAddBadRow(srcTable, conv.DataMode())
+				return
+			}
+		}
+		start := time.Now()
+		if split, ok := conv.TableSplits[spTable]; ok {
+			conv.splitRow(spTable, split, spCols, spVals, conv.dataSink)
+		} else {
+			conv.dataSink(spTable, spCols, spVals)
+		}
+		for _, rows := range nested {
+			for _, vals := range rows.Vals {
+				conv.dataSink(rows.Table, rows.Cols, vals)
+			}
+		}
+		conv.Timings.TimeTable(StageWriteWait, srcTable, start)
 		conv.statsAddGoodRow(srcTable, conv.DataMode())
 	}
 }
@@ -283,7 +499,19 @@ func (conv *Conv) Unexpecteds() int64 {
 // CollectBadRow updates the list of bad rows, while respecting
 // the byte limit for bad rows.
 func (conv *Conv) CollectBadRow(srcTable string, srcCols, vals []string) {
-	r := &row{table: srcTable, cols: srcCols, vals: vals}
+	if conv.badData != nil {
+		var l []interface{}
+		for _, v := range vals {
+			l = append(l, v)
+		}
+		conv.badData.Write(ConversionError, BadDataRecord{Table: srcTable, Cols: srcCols, Vals: l})
+	}
+	conv.sampleBadRow(&row{table: srcTable, cols: srcCols, vals: vals})
+}
+
+// sampleBadRow adds r to the sample of bad rows, while respecting the byte
+// limit for bad rows.
+func (conv *Conv) sampleBadRow(r *row) {
 	bytes := byteSize(r)
 	// Cap storage used by badRows. Keep at least one bad row.
 	if len(conv.sampleBadRows.rows) == 0 || bytes+conv.sampleBadRows.bytes < conv.sampleBadRows.bytesLimit {
@@ -331,7 +559,7 @@ func (conv *Conv) AddPrimaryKeys() {
 				ct.ColNames = append(ct.ColNames, k)
 				ct.ColDefs[k] = ddl.ColumnDef{Name: k, T: ddl.Type{Name: ddl.Int64}}
 				ct.Pks = []ddl.IndexKey{{Col: k}}
-				conv.SyntheticPKeys[t] = SyntheticPKey{k, 0}
+				conv.SyntheticPKeys[t] = SyntheticPKey{Col: k}
 			}
 			conv.SpSchema[t] = ct
 		}
@@ -403,6 +631,7 @@ func (conv *Conv) statsAddGoodRow(srcTable string, b bool) {
 func (conv *Conv) StatsAddBadRow(srcTable string, b bool) {
 	if b {
 		conv.Stats.BadRows[srcTable]++
+		conv.ErrorBudget.addBadRow(srcTable)
 	}
 }
 
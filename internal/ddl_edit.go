@@ -0,0 +1,114 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// DdlEdit is a manual edit of the DDL statements of a Spanner table, which
+// replaces the statements generated from conv.SpSchema.
+type DdlEdit struct {
+	Generated []string // Statements generated for the table when it was edited (see TableDDL).
+	Edited    []string // Statements that replace the generated ones.
+}
+
+// TableDDL returns the statements generated for Spanner table table: the
+// CREATE SEQUENCE statements of its columns, its CREATE TABLE statement and
+// the CREATE INDEX statements of its secondary indexes. Statements are legal
+// Cloud Spanner DDL i.e. without comments and with protected names. Tables
+// are those of the schema created in Spanner (see TargetSchema).
+func (conv *Conv) TableDDL(table string) []string {
+	return tableDDL(conv.TargetSchema()[table], ddl.Config{ProtectIds: true, Tables: true, TargetDb: conv.TargetDb})
+}
+
+func tableDDL(ct ddl.CreateTable, c ddl.Config) []string {
+	var stmts []string
+	for _, cn := range ct.ColNames {
+		if sq := ct.ColDefs[cn].Sequence; sq != nil {
+			stmts = append(stmts, sq.PrintCreateSequence(c))
+		}
+	}
+	stmts = append(stmts, ct.PrintCreateTable(c))
+	if !c.SkipIndexes {
+		for _, index := range ct.Indexes {
+			stmts = append(stmts, index.PrintCreateIndex(c))
+		}
+	}
+	return stmts
+}
+
+// EditTableDDL replaces the generated DDL statements of Spanner table table
+// with stmts. Edits are kept when the schema is changed, and are reported by
+// StaleDdlEdits until they are edited again. Empty stmts, or stmts that are
+// the same as the generated ones, remove the edit.
+func (conv *Conv) EditTableDDL(table string, stmts []string) error {
+	if _, ok := conv.SpSchema[table]; !ok {
+		return fmt.Errorf("table %s not found", table)
+	}
+	generated := conv.TableDDL(table)
+	if len(stmts) == 0 || reflect.DeepEqual(stmts, generated) {
+		delete(conv.DdlEdits, table)
+		return nil
+	}
+	if conv.DdlEdits == nil {
+		conv.DdlEdits = make(map[string]DdlEdit)
+	}
+	conv.DdlEdits[table] = DdlEdit{Generated: generated, Edited: stmts}
+	return nil
+}
+
+// StaleDdlEdits returns the Spanner tables whose schema was changed, or
+// which were dropped, after their DDL was edited, in sorted order.
+func (conv *Conv) StaleDdlEdits() []string {
+	var l []string
+	for t, e := range conv.DdlEdits {
+		if _, ok := conv.SpSchema[t]; !ok || !reflect.DeepEqual(e.Generated, conv.TableDDL(t)) {
+			l = append(l, t)
+		}
+	}
+	sort.Strings(l)
+	return l
+}
+
+// SchemaDDL is like conv.TargetSchema().GetDDL, except that the statements of
+// tables with a DdlEdit are replaced by the edited statements, and that the
+// database roles of conv.Grants (see RoleDDL) are printed along with the
+// tables. Edited statements are used as they are, so they include secondary
+// indexes even if c.SkipIndexes is set. Edits of dropped tables are ignored.
+func (conv *Conv) SchemaDDL(c ddl.Config) []string {
+	schema := conv.TargetSchema()
+	if !c.Tables {
+		return schema.GetDDL(c)
+	}
+	if len(conv.DdlEdits) == 0 {
+		return append(schema.GetDDL(c), conv.RoleDDL(c)...)
+	}
+	var stmts []string
+	for _, t := range ddl.OrderTables(schema) {
+		if e, ok := conv.DdlEdits[t]; ok {
+			stmts = append(stmts, e.Edited...)
+			continue
+		}
+		stmts = append(stmts, tableDDL(schema[t], c)...)
+	}
+	c.Tables = false
+	stmts = append(stmts, schema.GetDDL(c)...)
+	return append(stmts, conv.RoleDDL(c)...)
+}
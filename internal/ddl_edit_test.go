@@ -0,0 +1,67 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDdlEdits(t *testing.T) {
+	conv := MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"a", "b"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a": {Name: "a", T: ddl.Type{Name: ddl.Int64}},
+			"b": {Name: "b", T: ddl.Type{Name: ddl.Int64}},
+		},
+		Pks:     []ddl.IndexKey{{Col: "a"}},
+		Fks:     []ddl.Foreignkey{{Name: "fk", Columns: []string{"b"}, ReferTable: "t2", ReferColumns: []string{"c"}}},
+		Indexes: []ddl.CreateIndex{{Name: "i", Table: "t1", Keys: []ddl.IndexKey{{Col: "b"}}}},
+	}
+	conv.SpSchema["t2"] = ddl.CreateTable{
+		Name:     "t2",
+		ColNames: []string{"c"},
+		ColDefs:  map[string]ddl.ColumnDef{"c": {Name: "c", T: ddl.Type{Name: ddl.Int64}}},
+		Pks:      []ddl.IndexKey{{Col: "c"}},
+	}
+	c := ddl.Config{ProtectIds: true, Tables: true, ForeignKeys: true}
+	generated := conv.SchemaDDL(c)
+	assert.Equal(t, conv.SpSchema.GetDDL(c), generated)
+	assert.Equal(t, generated[:2], conv.TableDDL("t1"))
+
+	edited := []string{"CREATE TABLE t1 (a INT64, b INT64 NOT NULL) PRIMARY KEY (a)", "CREATE INDEX i ON t1 (b DESC)"}
+	assert.NotNil(t, conv.EditTableDDL("t3", edited))
+	assert.Nil(t, conv.EditTableDDL("t1", edited))
+	assert.Equal(t, append(append([]string{}, edited...), generated[2:]...), conv.SchemaDDL(c))
+	assert.Nil(t, conv.StaleDdlEdits())
+
+	// Edits are kept when the schema changes, but are stale.
+	t1 := conv.SpSchema["t1"]
+	t1.Indexes = nil
+	conv.SpSchema["t1"] = t1
+	assert.Equal(t, edited, conv.SchemaDDL(c)[:2])
+	assert.Equal(t, []string{"t1"}, conv.StaleDdlEdits())
+
+	// Editing back to the generated statements removes the edit.
+	assert.Nil(t, conv.EditTableDDL("t1", conv.TableDDL("t1")))
+	assert.Equal(t, 0, len(conv.DdlEdits))
+	assert.Nil(t, conv.EditTableDDL("t1", edited))
+	assert.Nil(t, conv.EditTableDDL("t1", nil))
+	assert.Equal(t, conv.SpSchema.GetDDL(c), conv.SchemaDDL(c))
+}
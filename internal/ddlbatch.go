@@ -0,0 +1,150 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limits of the DDL statements sent in a single UpdateDatabaseDdl (or
+// CreateDatabase) call. Spanner applies the statements of a call as one
+// schema change, validating them together, so very large calls are slow to
+// validate and exceed the limits on the size of schema changes.
+const (
+	DDLBatchStatements = 50
+	DDLBatchBytes      = 1 << 20
+)
+
+// BatchDDL splits stmts, in order, into batches of at most maxStmts
+// statements and maxBytes bytes each (no limit if 0). A statement larger than
+// maxBytes is a batch of its own.
+func BatchDDL(stmts []string, maxStmts, maxBytes int) [][]string {
+	var (
+		batches [][]string
+		batch   []string
+		size    int
+	)
+	for _, s := range stmts {
+		if len(batch) > 0 && ((maxStmts > 0 && len(batch) >= maxStmts) || (maxBytes > 0 && size+len(s) > maxBytes)) {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, s)
+		size += len(s)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// maxAdminInterval is the longest interval between admin operations that
+// AdminPacer slows down to when quotas are exceeded.
+const maxAdminInterval = time.Minute
+
+// AdminPacer paces the admin operations of a migration, e.g. the schema
+// changes of its tables, foreign keys and indexes, so that they stay under
+// Spanner's admin API quotas (see
+// https://cloud.google.com/spanner/quotas#administrative_limits). Operations
+// start at most at the configured rate, and the rate is halved each time an
+// operation fails because a quota is exceeded. It is safe for concurrent use.
+type AdminPacer struct {
+	lock     sync.Mutex
+	base     time.Duration // Interval between operations at the configured rate.
+	interval time.Duration // Current interval between operations.
+	next     time.Time     // Earliest start of the next operation.
+	now      func() time.Time
+}
+
+// NewAdminPacer returns an AdminPacer starting at most perMinute operations
+// per minute (no limit if 0, until quota errors are observed).
+func NewAdminPacer(perMinute int) *AdminPacer {
+	var base time.Duration
+	if perMinute > 0 {
+		base = time.Minute / time.Duration(perMinute)
+	}
+	return &AdminPacer{base: base, interval: base, now: time.Now}
+}
+
+// Wait waits until the next operation can start, or ctx is done.
+func (p *AdminPacer) Wait(ctx context.Context) error {
+	d := p.reserve()
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// reserve reserves the start of an operation and returns how long to wait
+// for it.
+func (p *AdminPacer) reserve() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := p.now()
+	start := p.next
+	if start.Before(now) {
+		start = now
+	}
+	p.next = start.Add(p.interval)
+	return start.Sub(now)
+}
+
+// Throttled records that an operation failed because a quota was exceeded:
+// it halves the rate of the following operations, and returns how long to
+// wait before retrying the operation.
+func (p *AdminPacer) Throttled() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.interval == 0 {
+		p.interval = time.Second
+	} else {
+		p.interval *= 2
+	}
+	if p.interval > maxAdminInterval {
+		p.interval = maxAdminInterval
+	}
+	p.next = p.now().Add(p.interval)
+	return p.interval
+}
+
+// Succeeded records that an operation succeeded: the rate recovers towards
+// the configured one.
+func (p *AdminPacer) Succeeded() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.interval <= p.base {
+		return
+	}
+	p.interval -= p.interval / 4
+	if p.interval < p.base {
+		p.interval = p.base
+	}
+}
+
+// Interval returns the current interval between operations.
+func (p *AdminPacer) Interval() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.interval
+}
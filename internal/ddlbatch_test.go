@@ -0,0 +1,71 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchDDL(t *testing.T) {
+	stmts := []string{"aaaa", "bb", "cccccc", "d", "eeeeeeeeee", "f"}
+	tests := []struct {
+		name     string
+		maxStmts int
+		maxBytes int
+		want     [][]string
+	}{
+		{"no limits", 0, 0, [][]string{stmts}},
+		{"statements", 4, 0, [][]string{{"aaaa", "bb", "cccccc", "d"}, {"eeeeeeeeee", "f"}}},
+		{"bytes", 0, 7, [][]string{{"aaaa", "bb"}, {"cccccc", "d"}, {"eeeeeeeeee"}, {"f"}}},
+		{"both", 1, 100, [][]string{{"aaaa"}, {"bb"}, {"cccccc"}, {"d"}, {"eeeeeeeeee"}, {"f"}}},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, BatchDDL(stmts, tc.maxStmts, tc.maxBytes), tc.name)
+	}
+	assert.Nil(t, BatchDDL(nil, 10, 10))
+}
+
+func TestAdminPacer(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := NewAdminPacer(30)
+	p.now = func() time.Time { return now }
+	assert.Equal(t, time.Duration(0), p.reserve())
+	assert.Equal(t, 2*time.Second, p.reserve())
+	assert.Equal(t, 4*time.Second, p.reserve())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, 4*time.Second, p.Throttled())
+	assert.Equal(t, 4*time.Second, p.reserve())
+	for i := 0; i < 5; i++ {
+		p.Throttled()
+	}
+	assert.Equal(t, time.Minute, p.Interval())
+	for i := 0; i < 20; i++ {
+		p.Succeeded()
+	}
+	assert.Equal(t, 2*time.Second, p.Interval())
+
+	unpaced := NewAdminPacer(0)
+	assert.NoError(t, unpaced.Wait(context.Background()))
+	assert.NoError(t, unpaced.Wait(context.Background()))
+	assert.Equal(t, time.Second, unpaced.Throttled())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, unpaced.Wait(ctx))
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import "fmt"
+
+// Dialects of source dumps written by databases compatible with, but not
+// identical to, the source they are read as. Dialect-specific syntax is
+// ignored or converted, and reported as unexpected conditions.
+const (
+	DialectAuroraMySQL    = "Aurora MySQL"
+	DialectAuroraPostgres = "Aurora PostgreSQL"
+	DialectMariaDB        = "MariaDB"
+	DialectRedshift       = "Redshift"
+)
+
+// DetectDialect records that the source dump is in dialect d, and prints it
+// unless a dialect was already detected.
+func (conv *Conv) DetectDialect(d string) {
+	if conv.Dialect != "" {
+		return
+	}
+	conv.Dialect = d
+	fmt.Printf("Detected a %s dump: %s-specific syntax will be ignored or converted, see the unexpected conditions of the report.\n", d, d)
+}
+
+// DialectWarning records a construct of the dialect of the source dump that
+// was ignored or converted, on the first pass only.
+func (conv *Conv) DialectWarning(msg string) {
+	if conv.SchemaMode() {
+		conv.Unexpected(fmt.Sprintf("%s: %s", conv.Dialect, msg))
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// Conversions of enumeration source columns, such as MySQL ENUM and SET,
+// whose source type lists their allowed values.
+const (
+	EnumString = "string" // STRING, e.g. a comma-separated list of the values of a set. This is the default for enums.
+	EnumCheck  = "check"  // STRING, with a CHECK constraint enumerating the allowed values. Only for enums.
+	EnumArray  = "array"  // ARRAY<STRING> of the values of a set. This is the default for sets.
+)
+
+// SetEnumConversion sets the conversion of column spCol of Spanner table
+// spTable, whose source column is an enumeration, to conversion.
+func (conv *Conv) SetEnumConversion(spTable, spCol, conversion string) error {
+	ct, ok := conv.SpSchema[spTable]
+	if !ok {
+		return fmt.Errorf("table %s not found", spTable)
+	}
+	cd, ok := ct.ColDefs[spCol]
+	if !ok {
+		return fmt.Errorf("column %s not found in table %s", spCol, spTable)
+	}
+	srcTable := conv.ToSource[spTable].Name
+	srcCol := conv.SrcSchema[srcTable].ColDefs[conv.ToSource[spTable].Cols[spCol]]
+	if len(srcCol.Type.Values) == 0 {
+		return fmt.Errorf("column %s of table %s is not an enumeration", spCol, spTable)
+	}
+	if cd.T.Name != ddl.String {
+		return fmt.Errorf("column %s of table %s must be of type %s", spCol, spTable, ddl.String)
+	}
+	isSet := len(srcCol.Type.ArrayBounds) == 1
+	switch conversion {
+	case EnumString:
+		cd.T.IsArray = false
+		cd.AllowedValues = nil
+	case EnumCheck:
+		if isSet {
+			return fmt.Errorf("column %s of table %s holds sets of values, which a CHECK constraint can't enumerate", spCol, spTable)
+		}
+		cd.T.IsArray = false
+		cd.AllowedValues = srcCol.Type.Values
+	case EnumArray:
+		if !isSet {
+			return fmt.Errorf("column %s of table %s holds single values, only sets can be converted to arrays", spCol, spTable)
+		}
+		if conv.TargetDb == constants.TargetExperimentalPostgres {
+			return fmt.Errorf("PostgreSQL dialect databases don't support arrays")
+		}
+		cd.T.IsArray = true
+		cd.AllowedValues = nil
+	default:
+		return fmt.Errorf("invalid enum conversion %s, accepted values are: string, check, array", conversion)
+	}
+	ct.ColDefs[spCol] = cd
+	conv.SpSchema[spTable] = ct
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func TestSetEnumConversion(t *testing.T) {
+	conv := MakeConv()
+	values := []string{"a", "b"}
+	conv.SrcSchema["t1"] = schema.Table{
+		Name:     "t1",
+		ColNames: []string{"e", "s", "n"},
+		ColDefs: map[string]schema.Column{
+			"e": {Name: "e", Type: schema.Type{Name: "enum", Values: values}},
+			"s": {Name: "s", Type: schema.Type{Name: "set", ArrayBounds: []int64{-1}, Values: values}},
+			"n": {Name: "n", Type: schema.Type{Name: "varchar"}},
+		},
+	}
+	str := ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"e", "s", "n"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"e": {Name: "e", T: str},
+			"s": {Name: "s", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}},
+			"n": {Name: "n", T: str},
+		},
+	}
+	conv.ToSource["t1"] = NameAndCols{Name: "t1", Cols: map[string]string{"e": "e", "s": "s", "n": "n"}}
+
+	assert.NotNil(t, conv.SetEnumConversion("t2", "e", EnumCheck))
+	assert.NotNil(t, conv.SetEnumConversion("t1", "x", EnumCheck))
+	assert.NotNil(t, conv.SetEnumConversion("t1", "n", EnumCheck))
+	assert.NotNil(t, conv.SetEnumConversion("t1", "e", EnumArray))
+	assert.NotNil(t, conv.SetEnumConversion("t1", "s", EnumCheck))
+	assert.NotNil(t, conv.SetEnumConversion("t1", "e", "bitmask"))
+
+	assert.Nil(t, conv.SetEnumConversion("t1", "e", EnumCheck))
+	assert.Equal(t, ddl.ColumnDef{Name: "e", T: str, AllowedValues: values}, conv.SpSchema["t1"].ColDefs["e"])
+	assert.Nil(t, conv.SetEnumConversion("t1", "e", EnumString))
+	assert.Equal(t, ddl.ColumnDef{Name: "e", T: str}, conv.SpSchema["t1"].ColDefs["e"])
+
+	assert.Nil(t, conv.SetEnumConversion("t1", "s", EnumString))
+	assert.Equal(t, ddl.ColumnDef{Name: "s", T: str}, conv.SpSchema["t1"].ColDefs["s"])
+	conv.TargetDb = constants.TargetExperimentalPostgres
+	assert.NotNil(t, conv.SetEnumConversion("t1", "s", EnumArray))
+	conv.TargetDb = constants.TargetSpanner
+	assert.Nil(t, conv.SetEnumConversion("t1", "s", EnumArray))
+	assert.True(t, conv.SpSchema["t1"].ColDefs["s"].T.IsArray)
+}
@@ -0,0 +1,235 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Actions taken once an error budget is exceeded.
+const (
+	ErrorBudgetFail  = "fail"  // Complete the migration, and exit with a failure.
+	ErrorBudgetAbort = "abort" // Stop the data migration as soon as a count limit is exceeded.
+)
+
+// RowLimit is a limit on a count of rows, either absolute or as a percentage
+// of the rows read.
+type RowLimit struct {
+	Value   float64
+	Percent bool
+}
+
+// exceeded returns true if n rows out of rows exceed l.
+func (l RowLimit) exceeded(n, rows int64) bool {
+	if l.Percent {
+		return n > 0 && float64(n)*100 > l.Value*float64(rows)
+	}
+	return float64(n) > l.Value
+}
+
+func (l RowLimit) String() string {
+	if l.Percent {
+		return strconv.FormatFloat(l.Value, 'f', -1, 64) + "%"
+	}
+	return strconv.FormatFloat(l.Value, 'f', -1, 64)
+}
+
+// RowLimits are limits on a count of rows, over all tables and per table.
+type RowLimits struct {
+	Global *RowLimit
+	Tables map[string]RowLimit // Keyed by source table name.
+}
+
+// empty returns true if l sets no limit.
+func (l RowLimits) empty() bool {
+	return l.Global == nil && len(l.Tables) == 0
+}
+
+// ParseRowLimits parses limits on a count of rows, specified as a comma
+// separated list of limits, each either a count or a percentage of the rows
+// read, e.g. "1000" or "0.5%". Limits prefixed with a source table name apply
+// to that table only, e.g. "1%,orders=100,items=0".
+func ParseRowLimits(s string) (RowLimits, error) {
+	var limits RowLimits
+	if s == "" {
+		return limits, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		table, value := "", entry
+		if i := strings.LastIndex(entry, "="); i >= 0 {
+			table, value = strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+			if table == "" {
+				return RowLimits{}, fmt.Errorf("missing table name in row limit '%s'", entry)
+			}
+		}
+		var l RowLimit
+		l.Percent = strings.HasSuffix(value, "%")
+		v, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || v < 0 || (l.Percent && v > 100) {
+			return RowLimits{}, fmt.Errorf("invalid row limit '%s': expected a count or a percentage, e.g. 1000 or 0.5%%", entry)
+		}
+		l.Value = v
+		switch {
+		case table == "" && limits.Global != nil:
+			return RowLimits{}, fmt.Errorf("found several row limits for all tables in '%s'", s)
+		case table == "":
+			limits.Global = &l
+		default:
+			if limits.Tables == nil {
+				limits.Tables = make(map[string]RowLimit)
+			}
+			limits.Tables[table] = l
+		}
+	}
+	return limits, nil
+}
+
+// ErrorBudget limits the rows that a data migration may lose: bad rows,
+// which couldn't be converted, and dropped rows, which couldn't be written
+// to Spanner. If Abort is set, data migration stops as soon as a count limit
+// is exceeded; percentages are only checked once all rows have been read.
+type ErrorBudget struct {
+	BadRows     RowLimits
+	DroppedRows RowLimits
+	Abort       bool
+
+	mu      sync.Mutex
+	bad     map[string]int64 // Bad rows so far, by source table.
+	dropped map[string]int64 // Dropped rows so far, by source table.
+	reason  string           // Set once data migration is aborted.
+}
+
+// addBadRow counts a bad row of srcTable, aborting data migration if it
+// exceeds a limit.
+func (b *ErrorBudget) addBadRow(srcTable string) {
+	if b == nil || !b.Abort || b.BadRows.empty() {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.bad == nil {
+		b.bad = make(map[string]int64)
+	}
+	b.bad[srcTable]++
+	b.checkLocked("bad rows", b.BadRows, b.bad, srcTable)
+}
+
+// AddDroppedRow counts a dropped row of srcTable, aborting data migration if
+// it exceeds a limit.
+func (b *ErrorBudget) AddDroppedRow(srcTable string) {
+	if b == nil || !b.Abort || b.DroppedRows.empty() {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.dropped == nil {
+		b.dropped = make(map[string]int64)
+	}
+	b.dropped[srcTable]++
+	b.checkLocked("dropped rows", b.DroppedRows, b.dropped, srcTable)
+}
+
+// checkLocked aborts data migration if the count of rows of srcTable, or the
+// total count, exceeds a count limit. b.mu must be held.
+func (b *ErrorBudget) checkLocked(kind string, limits RowLimits, counts map[string]int64, srcTable string) {
+	if b.reason != "" {
+		return
+	}
+	if l, ok := limits.Tables[srcTable]; ok && !l.Percent && l.exceeded(counts[srcTable], 0) {
+		b.reason = fmt.Sprintf("%s of table %s exceed the limit of %s", kind, srcTable, l)
+		return
+	}
+	if l := limits.Global; l != nil && !l.Percent {
+		var total int64
+		for _, n := range counts {
+			total += n
+		}
+		if l.exceeded(total, 0) {
+			b.reason = fmt.Sprintf("%s exceed the limit of %s", kind, l)
+		}
+	}
+}
+
+// Aborted returns why data migration was aborted, or "" if it wasn't.
+func (b *ErrorBudget) Aborted() string {
+	if b == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.reason
+}
+
+// Violations returns a description of each limit of b exceeded by the bad
+// rows of conv and droppedRows (keyed by Spanner table), once data migration
+// is done.
+func (b *ErrorBudget) Violations(conv *Conv, droppedRows map[string]int64) []string {
+	if b == nil {
+		return nil
+	}
+	dropped := make(map[string]int64)
+	for spTable, n := range droppedRows {
+		srcTable, err := GetSourceTable(conv, spTable)
+		if err != nil {
+			srcTable = spTable
+		}
+		dropped[srcTable] += n
+	}
+	var l []string
+	l = append(l, violations("bad rows", b.BadRows, conv.Stats.BadRows, conv.Stats.Rows)...)
+	l = append(l, violations("dropped rows", b.DroppedRows, dropped, conv.Stats.Rows)...)
+	return l
+}
+
+// violations returns a description of each of limits exceeded by counts,
+// out of rows, both keyed by source table.
+func violations(kind string, limits RowLimits, counts, rows map[string]int64) []string {
+	var l []string
+	var tables []string
+	for t := range limits.Tables {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		if limit := limits.Tables[t]; limit.exceeded(counts[t], rows[t]) {
+			l = append(l, fmt.Sprintf("%s of table %s: %s exceed the limit of %s", kind, t, describeRows(counts[t], rows[t]), limit))
+		}
+	}
+	if limit := limits.Global; limit != nil {
+		var n, total int64
+		for _, c := range counts {
+			n += c
+		}
+		for _, c := range rows {
+			total += c
+		}
+		if limit.exceeded(n, total) {
+			l = append(l, fmt.Sprintf("%s: %s exceed the limit of %s", kind, describeRows(n, total), limit))
+		}
+	}
+	return l
+}
+
+func describeRows(n, rows int64) string {
+	if rows == 0 {
+		return strconv.FormatInt(n, 10)
+	}
+	return fmt.Sprintf("%d (%.2f%% of %d rows)", n, float64(n)*100/float64(rows), rows)
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRowLimits(t *testing.T) {
+	l, err := ParseRowLimits("")
+	assert.Nil(t, err)
+	assert.Equal(t, RowLimits{}, l)
+
+	l, err = ParseRowLimits("1%, orders=100,items=0")
+	assert.Nil(t, err)
+	assert.Equal(t, RowLimits{
+		Global: &RowLimit{Value: 1, Percent: true},
+		Tables: map[string]RowLimit{"orders": {Value: 100}, "items": {Value: 0}},
+	}, l)
+	assert.Equal(t, "1%", l.Global.String())
+
+	for _, s := range []string{"x", "-1", "101%", "=5", "1,2", "orders=1%%"} {
+		_, err := ParseRowLimits(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestErrorBudgetViolations(t *testing.T) {
+	conv := buildReportConv() // 100 rows of t1, of which 10 bad.
+	bad, _ := ParseRowLimits("5%,t1=20")
+	dropped, _ := ParseRowLimits("t1=3")
+	b := &ErrorBudget{BadRows: bad, DroppedRows: dropped}
+	assert.Equal(t, []string{
+		"bad rows: 10 (10.00% of 100 rows) exceed the limit of 5%",
+		"dropped rows of table t1: 4 (4.00% of 100 rows) exceed the limit of 3",
+	}, b.Violations(conv, map[string]int64{"t1": 4}))
+	assert.Empty(t, b.Violations(conv, map[string]int64{"t1": 3})[1:])
+
+	var none *ErrorBudget
+	assert.Nil(t, none.Violations(conv, nil))
+	assert.Equal(t, "", none.Aborted())
+}
+
+func TestErrorBudgetAbort(t *testing.T) {
+	conv := buildReportConv()
+	var written int
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) { written++ })
+	bad, _ := ParseRowLimits("50%,t1=2")
+	conv.ErrorBudget = &ErrorBudget{BadRows: bad, Abort: true}
+
+	// Percentages aren't checked during data migration.
+	conv.StatsAddBadRow("t1", true)
+	conv.StatsAddBadRow("t1", true)
+	assert.Equal(t, "", conv.ErrorBudget.Aborted())
+	conv.WriteRow("t1", "t1", []string{"a"}, []interface{}{int64(1)})
+	assert.Equal(t, 1, written)
+
+	conv.StatsAddBadRow("t1", true)
+	assert.Equal(t, "bad rows of table t1 exceed the limit of 2", conv.ErrorBudget.Aborted())
+	conv.WriteRow("t1", "t1", []string{"a"}, []interface{}{int64(2)})
+	assert.Equal(t, 1, written)
+
+	dropped, _ := ParseRowLimits("1")
+	b := &ErrorBudget{DroppedRows: dropped, Abort: true}
+	b.AddDroppedRow("t1")
+	b.AddDroppedRow("t2")
+	assert.Equal(t, "dropped rows exceed the limit of 1", b.Aborted())
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// SchemaFingerprint returns a hash of the parts of source schema srcSchema
+// that data migration depends on: the tables, the order, types and
+// nullability of their columns, and their primary keys. Generated ids,
+// indexes and foreign keys aren't part of the fingerprint.
+func SchemaFingerprint(srcSchema map[string]schema.Table) string {
+	h := sha256.New()
+	for _, name := range sortedTableNames(srcSchema) {
+		fmt.Fprintf(h, "%s\n", describeTable(srcSchema[name]))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SpannerSchemaFingerprint returns a hash of the DDL statements of the
+// Spanner schema of conv, which identifies the version of the schema that
+// data was migrated to.
+func SpannerSchemaFingerprint(conv *Conv) string {
+	h := sha256.New()
+	for _, stmt := range conv.SchemaDDL(ddl.Config{ProtectIds: true, Tables: true, ForeignKeys: true, TargetDb: conv.TargetDb}) {
+		fmt.Fprintf(h, "%s\n", stmt)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DiffSchemas returns a description of the changes from source schema old to
+// source schema new that make their fingerprints differ, one per line.
+func DiffSchemas(old, new map[string]schema.Table) []string {
+	var diffs []string
+	for _, name := range sortedTableNames(old) {
+		if _, ok := new[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("table %s was dropped", name))
+		}
+	}
+	for _, name := range sortedTableNames(new) {
+		o, ok := old[name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("table %s was added", name))
+			continue
+		}
+		diffs = append(diffs, diffTable(o, new[name])...)
+	}
+	return diffs
+}
+
+func diffTable(old, new schema.Table) []string {
+	var diffs []string
+	for _, c := range old.ColNames {
+		if _, ok := new.ColDefs[c]; !ok {
+			diffs = append(diffs, fmt.Sprintf("table %s: column %s was dropped", old.Name, c))
+		}
+	}
+	var common []string
+	for _, c := range new.ColNames {
+		oc, ok := old.ColDefs[c]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("table %s: column %s was added", old.Name, c))
+			continue
+		}
+		common = append(common, c)
+		nc := new.ColDefs[c]
+		if o, n := oc.Type.Print(), nc.Type.Print(); o != n {
+			diffs = append(diffs, fmt.Sprintf("table %s: type of column %s changed from %s to %s", old.Name, c, o, n))
+		}
+		if o, n := strings.Join(oc.Type.Values, ","), strings.Join(nc.Type.Values, ","); o != n {
+			diffs = append(diffs, fmt.Sprintf("table %s: values of column %s changed from (%s) to (%s)", old.Name, c, o, n))
+		}
+		if oc.NotNull != nc.NotNull {
+			diffs = append(diffs, fmt.Sprintf("table %s: column %s changed from %s to %s", old.Name, c, nullability(oc.NotNull), nullability(nc.NotNull)))
+		}
+	}
+	var oldCommon []string
+	for _, c := range old.ColNames {
+		if _, ok := new.ColDefs[c]; ok {
+			oldCommon = append(oldCommon, c)
+		}
+	}
+	if strings.Join(oldCommon, ",") != strings.Join(common, ",") {
+		diffs = append(diffs, fmt.Sprintf("table %s: column order changed from (%s) to (%s)", old.Name, strings.Join(oldCommon, ", "), strings.Join(common, ", ")))
+	}
+	if o, n := describeKeys(old.PrimaryKeys), describeKeys(new.PrimaryKeys); o != n {
+		diffs = append(diffs, fmt.Sprintf("table %s: primary key changed from (%s) to (%s)", old.Name, o, n))
+	}
+	return diffs
+}
+
+// describeTable returns a canonical description of the parts of table t
+// that are part of its fingerprint.
+func describeTable(t schema.Table) string {
+	var cols []string
+	for _, c := range t.ColNames {
+		cd := t.ColDefs[c]
+		cols = append(cols, fmt.Sprintf("%s %s %s %q", c, cd.Type.Print(), nullability(cd.NotNull), cd.Type.Values))
+	}
+	return fmt.Sprintf("%s.%s (%s) PRIMARY KEY (%s)", t.Schema, t.Name, strings.Join(cols, ", "), describeKeys(t.PrimaryKeys))
+}
+
+func describeKeys(keys []schema.Key) string {
+	var s []string
+	for _, k := range keys {
+		if k.Desc {
+			s = append(s, k.Column+" DESC")
+		} else {
+			s = append(s, k.Column)
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+func nullability(notNull bool) string {
+	if notNull {
+		return "NOT NULL"
+	}
+	return "NULL"
+}
+
+func sortedTableNames(srcSchema map[string]schema.Table) []string {
+	var names []string
+	for name := range srcSchema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+func fingerprintSchema() map[string]schema.Table {
+	return map[string]schema.Table{
+		"t1": {
+			Name:     "t1",
+			ColNames: []string{"a", "b", "c"},
+			ColDefs: map[string]schema.Column{
+				"a": {Name: "a", Type: schema.Type{Name: "bigint"}, NotNull: true, Id: "c1"},
+				"b": {Name: "b", Type: schema.Type{Name: "varchar", Mods: []int64{10}}, Id: "c2"},
+				"c": {Name: "c", Type: schema.Type{Name: "enum", Values: []string{"x", "y"}}, Id: "c3"},
+			},
+			PrimaryKeys: []schema.Key{{Column: "a"}},
+			Id:          "t1",
+		},
+		"t2": {
+			Name:     "t2",
+			ColNames: []string{"k"},
+			ColDefs:  map[string]schema.Column{"k": {Name: "k", Type: schema.Type{Name: "text"}}},
+		},
+	}
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	fp := SchemaFingerprint(fingerprintSchema())
+	assert.Len(t, fp, 64)
+
+	// Ids and indexes aren't part of the fingerprint.
+	s := fingerprintSchema()
+	t1 := s["t1"]
+	t1.Id = "other"
+	t1.Indexes = []schema.Index{{Name: "idx", Keys: []schema.Key{{Column: "b"}}}}
+	s["t1"] = t1
+	assert.Equal(t, fp, SchemaFingerprint(s))
+
+	t1.ColNames = []string{"a", "c", "b"}
+	s["t1"] = t1
+	assert.NotEqual(t, fp, SchemaFingerprint(s))
+}
+
+func TestDiffSchemas(t *testing.T) {
+	assert.Empty(t, DiffSchemas(fingerprintSchema(), fingerprintSchema()))
+
+	s := fingerprintSchema()
+	delete(s, "t2")
+	s["t3"] = schema.Table{Name: "t3"}
+	t1 := s["t1"]
+	t1.ColNames = []string{"d", "c", "a"}
+	t1.ColDefs = map[string]schema.Column{
+		"a": {Name: "a", Type: schema.Type{Name: "bigint"}},
+		"c": {Name: "c", Type: schema.Type{Name: "enum", Values: []string{"x", "y", "z"}}},
+		"d": {Name: "d", Type: schema.Type{Name: "text"}},
+	}
+	t1.PrimaryKeys = []schema.Key{{Column: "a"}, {Column: "d", Desc: true}}
+	s["t1"] = t1
+	assert.Equal(t, []string{
+		"table t2 was dropped",
+		"table t1: column b was dropped",
+		"table t1: column d was added",
+		"table t1: values of column c changed from (x,y) to (x,y,z)",
+		"table t1: column a changed from NOT NULL to NULL",
+		"table t1: column order changed from (a, c) to (c, a)",
+		"table t1: primary key changed from (a) to (a, d DESC)",
+		"table t3 was added",
+	}, DiffSchemas(fingerprintSchema(), s))
+}
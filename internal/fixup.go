@@ -0,0 +1,102 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"strings"
+)
+
+// Origins of fix-up statements.
+const (
+	FixupUser = "user" // Supplied by the user, e.g. with the -fixups flag.
+)
+
+// Fixup is a statement run as Partitioned DML after data migration, e.g. to
+// backfill a column or normalize values, along with its outcome.
+type Fixup struct {
+	SQL    string // The DML statement, in the dialect of the Spanner database.
+	Origin string // FixupUser, or a description of why the statement was generated.
+	Done   bool   // Whether the statement was run successfully.
+	Rows   int64  // Lower bound on the number of rows modified, if Done.
+	Error  string // Error of the statement, if it failed.
+}
+
+// AddFixup records fix-up statement sql to be run after data migration.
+// origin is FixupUser for statements supplied by the user, and otherwise
+// describes why the statement was generated.
+func (conv *Conv) AddFixup(sql, origin string) {
+	conv.Fixups = append(conv.Fixups, Fixup{SQL: sql, Origin: origin})
+}
+
+// SplitStatements splits sql into its statements, which are separated by
+// semicolons. Semicolons in quoted strings or identifiers and in comments
+// don't separate statements. Comments and empty statements are dropped.
+func SplitStatements(sql string) []string {
+	var stmts []string
+	var b strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			stmts = append(stmts, s)
+		}
+		b.Reset()
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == ';':
+			flush()
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			// Skip the comment, up to the end of the line.
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			b.WriteByte('\n')
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 3
+			}
+			b.WriteByte(' ')
+		case c == '\'' || c == '"' || c == '`':
+			// Copy the quoted string or identifier. A backslash escapes the
+			// next character, as does doubling the quote.
+			j := i + 1
+			for ; j < len(sql); j++ {
+				if sql[j] == '\\' {
+					j++
+					continue
+				}
+				if sql[j] == c {
+					if j+1 < len(sql) && sql[j+1] == c {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			if j >= len(sql) {
+				j = len(sql) - 1
+			}
+			b.WriteString(sql[i : j+1])
+			i = j
+		default:
+			b.WriteByte(c)
+		}
+	}
+	flush()
+	return stmts
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	sql := `-- Backfill the shard id; it was added after the export.
+UPDATE orders SET shard_id = 'a;b' WHERE shard_id IS NULL;
+UPDATE "users" SET name = 'it''s; fine' /* not; a separator */ WHERE true;;
+UPDATE t SET s = "x\";y" WHERE true
+`
+	assert.Equal(t, []string{
+		"UPDATE orders SET shard_id = 'a;b' WHERE shard_id IS NULL",
+		`UPDATE "users" SET name = 'it''s; fine'   WHERE true`,
+		`UPDATE t SET s = "x\";y" WHERE true`,
+	}, SplitStatements(sql))
+	assert.Empty(t, SplitStatements(" -- nothing\n;\n"))
+}
+
+func TestWriteFixups(t *testing.T) {
+	conv := MakeConv()
+	conv.AddFixup("UPDATE t SET a = LOWER(a)\nWHERE true", FixupUser)
+	conv.AddFixup("UPDATE u SET b = 1 WHERE true", FixupUser)
+	conv.AddFixup("UPDATE v SET c = 1 WHERE true", FixupUser)
+	conv.Fixups[0].Done, conv.Fixups[0].Rows = true, 42
+	conv.Fixups[1].Error = "table u not found"
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeFixups(conv, w)
+	w.Flush()
+	expected := `----------------------------
+Fix-up Statements
+----------------------------
+The following statements were run as Partitioned DML after data migration.
+Partitioned DML can run a statement more than once on some rows, and reports a
+lower bound of the rows modified.
+
+  1. (user) done, at least 42 rows modified
+    UPDATE t SET a = LOWER(a)
+    WHERE true
+  2. (user) failed: table u not found
+    UPDATE u SET b = 1 WHERE true
+  3. (user) not run
+    UPDATE v SET c = 1 WHERE true
+
+`
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,224 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Resolutions of a foreign key that is part of a cycle.
+const (
+	// FkResolutionNone leaves the foreign key unchanged.
+	FkResolutionNone = ""
+	// FkResolutionDrop removes the foreign key from the Spanner schema.
+	FkResolutionDrop = "drop"
+	// FkResolutionDefer keeps the foreign key, but ignores it when ordering
+	// table loads. It is created after data migration, like all foreign keys.
+	FkResolutionDefer = "defer"
+	// FkResolutionApplication marks the foreign key as enforced by the
+	// application, so it isn't created in Spanner.
+	FkResolutionApplication = "application"
+)
+
+// FkCycleEdge is a foreign key between two tables of a cycle.
+type FkCycleEdge struct {
+	Table      string
+	Fk         string
+	ReferTable string
+	Resolution string // FkResolutionNone, FkResolutionDefer or FkResolutionApplication.
+}
+
+// FkCycle is a set of Spanner tables whose foreign keys form one or more
+// cycles (a strongly connected component of the foreign key graph).
+type FkCycle struct {
+	Tables   []string // Sorted by name.
+	Fks      []FkCycleEdge
+	Resolved bool // True if the resolutions of Fks break all cycles.
+}
+
+// FkCycles returns the foreign key cycles of the Spanner schema, sorted by
+// their first table. Dropped foreign keys no longer exist, so cycles that
+// were resolved by dropping a foreign key aren't returned.
+func FkCycles(conv *Conv) []FkCycle {
+	var tables []string
+	for t := range conv.SpSchema {
+		tables = append(tables, t)
+	}
+	refs := func(active bool) func(t string) []string {
+		return func(t string) []string {
+			var l []string
+			for _, fk := range conv.SpSchema[t].Fks {
+				if active && (fk.AppEnforced || fk.Deferred) {
+					continue
+				}
+				if _, ok := conv.SpSchema[fk.ReferTable]; ok {
+					l = append(l, fk.ReferTable)
+				}
+			}
+			return l
+		}
+	}
+	unresolved := make(map[string]bool)
+	for _, scc := range fkCycleComponents(tables, refs(true)) {
+		for _, t := range scc {
+			unresolved[t] = true
+		}
+	}
+	var cycles []FkCycle
+	for _, scc := range fkCycleComponents(tables, refs(false)) {
+		c := FkCycle{Tables: scc, Resolved: true}
+		in := make(map[string]bool)
+		for _, t := range scc {
+			in[t] = true
+			c.Resolved = c.Resolved && !unresolved[t]
+		}
+		for _, t := range scc {
+			for _, fk := range conv.SpSchema[t].Fks {
+				if !in[fk.ReferTable] || fk.ReferTable == t {
+					continue
+				}
+				e := FkCycleEdge{Table: t, Fk: fk.Name, ReferTable: fk.ReferTable}
+				switch {
+				case fk.AppEnforced:
+					e.Resolution = FkResolutionApplication
+				case fk.Deferred:
+					e.Resolution = FkResolutionDefer
+				}
+				c.Fks = append(c.Fks, e)
+			}
+		}
+		cycles = append(cycles, c)
+	}
+	return cycles
+}
+
+// ResolveFkCycle applies resolution to foreign key fkName of Spanner table
+// spTable, which must be part of a foreign key cycle. FkResolutionNone undoes
+// a previous FkResolutionDefer or FkResolutionApplication.
+func (conv *Conv) ResolveFkCycle(spTable, fkName, resolution string) error {
+	switch resolution {
+	case FkResolutionNone, FkResolutionDrop, FkResolutionDefer, FkResolutionApplication:
+	default:
+		return fmt.Errorf("unknown foreign key cycle resolution '%s': must be one of %s, %s or %s", resolution, FkResolutionDrop, FkResolutionDefer, FkResolutionApplication)
+	}
+	found := false
+	for _, c := range FkCycles(conv) {
+		for _, e := range c.Fks {
+			found = found || (e.Table == spTable && e.Fk == fkName)
+		}
+	}
+	if !found {
+		return fmt.Errorf("foreign key %s of table %s is not part of a foreign key cycle", fkName, spTable)
+	}
+	ct := conv.SpSchema[spTable]
+	for i, fk := range ct.Fks {
+		if fk.Name != fkName {
+			continue
+		}
+		if resolution == FkResolutionDrop {
+			ct.Fks = append(ct.Fks[:i], ct.Fks[i+1:]...)
+			break
+		}
+		ct.Fks[i].Deferred = resolution == FkResolutionDefer
+		ct.Fks[i].AppEnforced = resolution == FkResolutionApplication
+		break
+	}
+	conv.SpSchema[spTable] = ct
+	return nil
+}
+
+// fkCycleComponents returns the strongly connected components of the graph
+// with nodes tables and edges refs that contain more than one table, i.e. the
+// sets of tables that are part of a cycle. Self references are not cycles.
+// Components are sorted, and ordered by their first table.
+func fkCycleComponents(tables []string, refs func(t string) []string) [][]string {
+	// Tarjan's strongly connected components algorithm.
+	sort.Strings(tables)
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+	var visit func(t string)
+	visit = func(t string) {
+		index[t] = len(index)
+		low[t] = index[t]
+		stack = append(stack, t)
+		onStack[t] = true
+		for _, r := range refs(t) {
+			if _, ok := index[r]; !ok {
+				visit(r)
+				if low[r] < low[t] {
+					low[t] = low[r]
+				}
+			} else if onStack[r] && index[r] < low[t] {
+				low[t] = index[r]
+			}
+		}
+		if low[t] == index[t] {
+			var scc []string
+			for {
+				x := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[x] = false
+				scc = append(scc, x)
+				if x == t {
+					break
+				}
+			}
+			if len(scc) > 1 {
+				sort.Strings(scc)
+				sccs = append(sccs, scc)
+			}
+		}
+	}
+	for _, t := range tables {
+		if _, ok := index[t]; !ok {
+			visit(t)
+		}
+	}
+	sort.Slice(sccs, func(i, j int) bool { return sccs[i][0] < sccs[j][0] })
+	return sccs
+}
+
+// writeFkCycles reports the foreign key cycles of the Spanner schema and how
+// they have been resolved.
+func writeFkCycles(cycles []FkCycle, w *bufio.Writer) {
+	writeHeading(w, "Foreign Key Cycles")
+	justifyLines(w, "The foreign keys of the following tables form cycles. Tables on "+
+		"a cycle can't be loaded in an order that satisfies all foreign keys, and "+
+		"rows that reference each other can't be inserted or deleted one at a time. "+
+		"Resolve each cycle by dropping one of its foreign keys, deferring it (it is "+
+		"ignored when ordering table loads), or enforcing it in the application.", 80, 0)
+	w.WriteString("\n\n")
+	for _, c := range cycles {
+		status := "unresolved"
+		if c.Resolved {
+			status = "resolved"
+		}
+		w.WriteString(fmt.Sprintf("  Tables %s (%s):\n", strings.Join(c.Tables, ", "), status))
+		for _, e := range c.Fks {
+			r := e.Resolution
+			if r == FkResolutionNone {
+				r = "-"
+			}
+			w.WriteString(fmt.Sprintf("    %s.%s -> %s: %s\n", e.Table, e.Fk, e.ReferTable, r))
+		}
+	}
+	w.WriteString("\n")
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func buildFkCycleConv() *Conv {
+	conv := MakeConv()
+	fk := func(name, refer string) []ddl.Foreignkey {
+		return []ddl.Foreignkey{{Name: name, Columns: []string{"x"}, ReferTable: refer, ReferColumns: []string{"x"}}}
+	}
+	conv.SpSchema = ddl.Schema{
+		"a":    {Name: "a", Fks: fk("fk_a", "b")},
+		"b":    {Name: "b", Fks: append(fk("fk_b", "a"), fk("fk_b_self", "b")...)},
+		"c":    {Name: "c", Fks: fk("fk_c", "a")},
+		"self": {Name: "self", Fks: fk("fk_self", "self")},
+	}
+	return conv
+}
+
+func TestFkCycles(t *testing.T) {
+	conv := buildFkCycleConv()
+	assert.Equal(t, []FkCycle{{
+		Tables: []string{"a", "b"},
+		Fks: []FkCycleEdge{
+			{Table: "a", Fk: "fk_a", ReferTable: "b"},
+			{Table: "b", Fk: "fk_b", ReferTable: "a"},
+		},
+	}}, FkCycles(conv))
+}
+
+func TestResolveFkCycle(t *testing.T) {
+	conv := buildFkCycleConv()
+	assert.NotNil(t, conv.ResolveFkCycle("c", "fk_c", FkResolutionDefer))
+	assert.NotNil(t, conv.ResolveFkCycle("a", "fk_a", "ignore"))
+
+	assert.Nil(t, conv.ResolveFkCycle("a", "fk_a", FkResolutionDefer))
+	assert.True(t, conv.SpSchema["a"].Fks[0].Deferred)
+	cycles := FkCycles(conv)
+	assert.True(t, cycles[0].Resolved)
+	assert.Equal(t, FkResolutionDefer, cycles[0].Fks[0].Resolution)
+
+	assert.Nil(t, conv.ResolveFkCycle("a", "fk_a", FkResolutionApplication))
+	assert.False(t, conv.SpSchema["a"].Fks[0].Deferred)
+	assert.True(t, conv.SpSchema["a"].Fks[0].AppEnforced)
+
+	assert.Nil(t, conv.ResolveFkCycle("a", "fk_a", FkResolutionNone))
+	assert.False(t, FkCycles(conv)[0].Resolved)
+
+	assert.Nil(t, conv.ResolveFkCycle("b", "fk_b", FkResolutionDrop))
+	assert.Equal(t, []ddl.Foreignkey{{Name: "fk_b_self", Columns: []string{"x"}, ReferTable: "b", ReferColumns: []string{"x"}}}, conv.SpSchema["b"].Fks)
+	assert.Nil(t, FkCycles(conv))
+}
+
+func TestWriteFkCycles(t *testing.T) {
+	conv := buildFkCycleConv()
+	conv.ResolveFkCycle("a", "fk_a", FkResolutionApplication)
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeFkCycles(FkCycles(conv), w)
+	w.Flush()
+	expected := `----------------------------
+Foreign Key Cycles
+----------------------------
+The foreign keys of the following tables form cycles. Tables on a cycle can't be
+loaded in an order that satisfies all foreign keys, and rows that reference each
+other can't be inserted or deleted one at a time. Resolve each cycle by dropping
+one of its foreign keys, deferring it (it is ignored when ordering table loads),
+or enforcing it in the application.
+
+  Tables a, b (resolved):
+    a.fk_a -> b: application
+    b.fk_b -> a: -
+
+`
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// Grant is a grant of privileges on a source table to a source user or role.
+// Grants are migrated as Spanner database roles with the same privileges on
+// the converted tables, for fine-grained access control.
+type Grant struct {
+	Table      string   // Source-DB table name.
+	Columns    []string // Source-DB columns the privileges are limited to, or all columns if empty.
+	Grantee    string   // Source-DB user or role, e.g. reader or 'app'@'%'.
+	Privileges []string // Source privileges, e.g. SELECT or ALL.
+}
+
+// spannerPrivileges are the privileges on tables that Spanner database roles
+// can be granted, in the order they are printed.
+var spannerPrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE"}
+
+// AddGrant records a grant of privileges on source table table (limited to
+// columns, if any) to source user or role grantee.
+func (conv *Conv) AddGrant(table, grantee string, privileges, columns []string) {
+	conv.Grants = append(conv.Grants, Grant{Table: table, Columns: columns, Grantee: grantee, Privileges: privileges})
+}
+
+// RoleName returns the name of the Spanner database role that source user
+// or role grantee is migrated to. MySQL accounts are named after their user
+// name, so all the hosts of a user share a role.
+func RoleName(grantee string) string {
+	name := grantee
+	if i := strings.Index(name, "@"); i > 0 && strings.HasPrefix(name, "'") {
+		name = name[:i]
+	}
+	name = strings.Trim(name, "'`\"")
+	var b strings.Builder
+	for _, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	name = b.String()
+	// Role names must start with a letter, and names starting with spanner_
+	// and the name public are reserved for system roles.
+	lower := strings.ToLower(name)
+	if name == "" || !((name[0] >= 'a' && name[0] <= 'z') || (name[0] >= 'A' && name[0] <= 'Z')) || strings.HasPrefix(lower, "spanner_") || lower == "public" {
+		name = "role_" + name
+	}
+	if len(name) > 128 {
+		name = name[:128]
+	}
+	return name
+}
+
+// RoleDDL returns the CREATE ROLE and GRANT statements that migrate
+// conv.Grants to Spanner database roles. Privileges granted on the same
+// table to the same role are merged into one statement, and so are
+// privileges on the same columns. Source privileges without a Spanner
+// equivalent (e.g. TRIGGER or REFERENCES), and grants on tables and columns
+// that aren't migrated, are skipped. Column-level grants of DELETE are
+// skipped too, since Spanner only grants DELETE on whole tables.
+func (conv *Conv) RoleDDL(c ddl.Config) []string {
+	type roleTable struct{ role, table string }
+	// Columns that each privilege is granted on, by role and table. Table
+	// level privileges are granted on "".
+	privs := make(map[roleTable]map[string]map[string]bool)
+	for _, g := range conv.Grants {
+		sp, ok := conv.ToSpanner[g.Table]
+		if !ok {
+			continue
+		}
+		if _, ok := conv.SpSchema[sp.Name]; !ok {
+			continue
+		}
+		cols := []string{""}
+		if len(g.Columns) > 0 {
+			cols = nil
+			for _, col := range g.Columns {
+				if spCol, ok := sp.Cols[col]; ok {
+					cols = append(cols, spCol)
+				}
+			}
+		}
+		k := roleTable{RoleName(g.Grantee), sp.Name}
+		for _, p := range g.Privileges {
+			p = strings.ToUpper(strings.TrimSpace(p))
+			for _, priv := range spannerPrivileges {
+				if p != priv && p != "ALL" && p != "ALL PRIVILEGES" {
+					continue
+				}
+				for _, col := range cols {
+					if priv == "DELETE" && col != "" {
+						continue
+					}
+					if privs[k] == nil {
+						privs[k] = make(map[string]map[string]bool)
+					}
+					if privs[k][priv] == nil {
+						privs[k][priv] = make(map[string]bool)
+					}
+					privs[k][priv][col] = true
+				}
+			}
+		}
+	}
+	var keys []roleTable
+	roles := make(map[string]bool)
+	for k := range privs {
+		keys = append(keys, k)
+		roles[k.role] = true
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].role != keys[j].role {
+			return keys[i].role < keys[j].role
+		}
+		return keys[i].table < keys[j].table
+	})
+	var sortedRoles []string
+	for r := range roles {
+		sortedRoles = append(sortedRoles, r)
+	}
+	sort.Strings(sortedRoles)
+	var stmts []string
+	for _, r := range sortedRoles {
+		stmts = append(stmts, ddl.PrintCreateRole(r, c))
+	}
+	for _, k := range keys {
+		// Privileges on whole tables first, then privileges on columns,
+		// grouped by their columns.
+		var grants []ddl.Grant
+		for _, priv := range spannerPrivileges {
+			colSet := privs[k][priv]
+			if colSet[""] {
+				grants = addPrivilege(grants, priv, nil, k.table, k.role)
+			}
+			var cols []string
+			for col := range colSet {
+				if col != "" && !colSet[""] {
+					cols = append(cols, col)
+				}
+			}
+			if len(cols) > 0 {
+				sort.Strings(cols)
+				grants = addPrivilege(grants, priv, cols, k.table, k.role)
+			}
+		}
+		for _, g := range grants {
+			stmts = append(stmts, g.PrintGrant(c))
+		}
+	}
+	return stmts
+}
+
+// addPrivilege adds privilege priv on cols of table to the grant of grants
+// on the same columns, or to a new grant.
+func addPrivilege(grants []ddl.Grant, priv string, cols []string, table, role string) []ddl.Grant {
+	for i, g := range grants {
+		if strings.Join(g.Columns, ",") == strings.Join(cols, ",") {
+			grants[i].Privileges = append(grants[i].Privileges, priv)
+			return grants
+		}
+	}
+	return append(grants, ddl.Grant{Privileges: []string{priv}, Columns: cols, Table: table, Role: role})
+}
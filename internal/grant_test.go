@@ -0,0 +1,68 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func TestRoleName(t *testing.T) {
+	for grantee, role := range map[string]string{
+		"reader":             "reader",
+		"'app'@'%'":          "app",
+		"'app'@'10.0.0.%'":   "app",
+		"report-writer":      "report_writer",
+		"1st":                "role_1st",
+		"spanner_info_admin": "role_spanner_info_admin",
+		"PUBLIC":             "role_PUBLIC",
+	} {
+		assert.Equal(t, role, RoleName(grantee), grantee)
+	}
+}
+
+func TestRoleDDL(t *testing.T) {
+	conv := MakeConv()
+	conv.SpSchema["orders"] = ddl.CreateTable{Name: "orders", ColNames: []string{"id", "qty", "note"}}
+	conv.ToSpanner["Orders"] = NameAndCols{Name: "orders", Cols: map[string]string{"Id": "id", "Qty": "qty", "Note": "note"}}
+	conv.ToSpanner["Dropped"] = NameAndCols{Name: "dropped", Cols: map[string]string{}}
+	assert.Nil(t, conv.RoleDDL(ddl.Config{}))
+
+	conv.AddGrant("Orders", "'app'@'%'", []string{"select", "insert"}, nil)
+	conv.AddGrant("Orders", "'app'@'localhost'", []string{"DELETE", "TRIGGER"}, nil)
+	conv.AddGrant("Orders", "'app'@'%'", []string{"UPDATE"}, []string{"Qty"})
+	conv.AddGrant("Orders", "'app'@'%'", []string{"UPDATE"}, []string{"Note", "Missing"})
+	conv.AddGrant("Orders", "'app'@'%'", []string{"INSERT", "DELETE"}, []string{"Qty"})
+	conv.AddGrant("Orders", "analyst", []string{"ALL PRIVILEGES"}, nil)
+	conv.AddGrant("Orders", "auditor", []string{"SELECT"}, []string{"Missing"})
+	conv.AddGrant("Dropped", "analyst", []string{"SELECT"}, nil)
+	assert.Equal(t, []string{
+		"CREATE ROLE analyst",
+		"CREATE ROLE app",
+		"GRANT SELECT, INSERT, UPDATE, DELETE ON TABLE orders TO ROLE analyst",
+		"GRANT SELECT, INSERT, DELETE ON TABLE orders TO ROLE app",
+		"GRANT UPDATE(note, qty) ON TABLE orders TO ROLE app",
+	}, conv.RoleDDL(ddl.Config{}))
+	assert.Equal(t, `CREATE ROLE "analyst"`, conv.RoleDDL(ddl.Config{ProtectIds: true, TargetDb: constants.TargetExperimentalPostgres})[0])
+
+	// Roles are created along with the tables.
+	stmts := conv.SchemaDDL(ddl.Config{Tables: true})
+	assert.Equal(t, "GRANT UPDATE(note, qty) ON TABLE orders TO ROLE app", stmts[len(stmts)-1])
+	assert.Empty(t, conv.SchemaDDL(ddl.Config{ForeignKeys: true}))
+}
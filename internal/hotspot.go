@@ -0,0 +1,133 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// Hotspot remediations, applied to all tables found by DetectHotspots.
+const (
+	HotspotRemediationNone       = ""
+	HotspotRemediationBitReverse = KeyStrategyBitReverse
+	HotspotRemediationHashPrefix = KeyStrategyHashPrefix
+)
+
+// Hotspot describes a Spanner table whose primary key starts with a column
+// whose values typically increase monotonically. Inserts into such tables all
+// go to the end of the key space, and hence to a single Spanner split.
+type Hotspot struct {
+	SpTable string
+	SpCol   string
+	Issue   SchemaIssue // HotspotTimestamp or HotspotAutoIncrement.
+}
+
+// DetectHotspots returns the tables whose first primary key column is a
+// timestamp or an auto-increment (or SERIAL) integer, sorted by table name.
+// Tables with a key strategy are skipped since their keys have already been
+// remediated.
+func DetectHotspots(conv *Conv) []Hotspot {
+	var hotspots []Hotspot
+	for spTable, ct := range conv.SpSchema {
+		if _, ok := conv.KeyStrategies[spTable]; ok || len(ct.Pks) == 0 {
+			continue
+		}
+		spCol := ct.Pks[0].Col
+		cd := ct.ColDefs[spCol]
+		if cd.T.IsArray {
+			continue
+		}
+		switch {
+		case cd.T.Name == ddl.Timestamp:
+			hotspots = append(hotspots, Hotspot{SpTable: spTable, SpCol: spCol, Issue: HotspotTimestamp})
+		case cd.T.Name == ddl.Int64 && conv.isAutoIncrement(spTable, spCol):
+			hotspots = append(hotspots, Hotspot{SpTable: spTable, SpCol: spCol, Issue: HotspotAutoIncrement})
+		}
+	}
+	sort.Slice(hotspots, func(i, j int) bool { return hotspots[i].SpTable < hotspots[j].SpTable })
+	return hotspots
+}
+
+// isAutoIncrement returns true if Spanner column spCol of spTable maps to a
+// source column that is auto-increment, SERIAL or generated by a sequence.
+func (conv *Conv) isAutoIncrement(spTable, spCol string) bool {
+	srcTable, err := GetSourceTable(conv, spTable)
+	if err != nil {
+		return false
+	}
+	srcCol, ok := conv.ToSource[spTable].Cols[spCol]
+	if !ok {
+		return false
+	}
+	if sc := conv.SrcSchema[srcTable].ColDefs[srcCol]; sc.Ignored.AutoIncrement || sc.Sequence != nil {
+		return true
+	}
+	for _, i := range conv.Issues[srcTable][srcCol] {
+		if i == Serial || i == AutoIncrement {
+			return true
+		}
+	}
+	return false
+}
+
+// RemediateHotspots applies remediation to the tables in hotspots by setting
+// their key strategy. HotspotRemediationBitReverse can only be applied to
+// integer keys, so timestamp hotspots are left unchanged by it.
+func RemediateHotspots(conv *Conv, hotspots []Hotspot, remediation string) error {
+	switch remediation {
+	case HotspotRemediationNone:
+		return nil
+	case HotspotRemediationBitReverse, HotspotRemediationHashPrefix:
+	default:
+		return fmt.Errorf("unknown hotspot remediation '%s': must be one of %s or %s", remediation, HotspotRemediationBitReverse, HotspotRemediationHashPrefix)
+	}
+	for _, h := range hotspots {
+		if remediation == HotspotRemediationBitReverse && h.Issue != HotspotAutoIncrement {
+			continue
+		}
+		if err := conv.SetKeyStrategy(h.SpTable, remediation, 0); err != nil {
+			return fmt.Errorf("can't remediate hotspot of table %s: %v", h.SpTable, err)
+		}
+	}
+	return nil
+}
+
+// AddHotspotIssues records a schema issue for the key column of each table in
+// hotspots, so that they are shown in reports.
+func AddHotspotIssues(conv *Conv, hotspots []Hotspot) {
+	for _, h := range hotspots {
+		srcTable, err := GetSourceTable(conv, h.SpTable)
+		if err != nil {
+			continue
+		}
+		srcCol, ok := conv.ToSource[h.SpTable].Cols[h.SpCol]
+		if !ok {
+			continue
+		}
+		if conv.Issues[srcTable] == nil {
+			conv.Issues[srcTable] = make(map[string][]SchemaIssue)
+		}
+		found := false
+		for _, i := range conv.Issues[srcTable][srcCol] {
+			found = found || i == h.Issue
+		}
+		if !found {
+			conv.Issues[srcTable][srcCol] = append(conv.Issues[srcTable][srcCol], h.Issue)
+		}
+	}
+}
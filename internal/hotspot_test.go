@@ -0,0 +1,82 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildHotspotConv() *Conv {
+	conv := MakeConv()
+	for _, t := range []struct {
+		name    string
+		spType  ddl.Type
+		autoInc bool
+	}{
+		{"events", ddl.Type{Name: ddl.Timestamp}, false},
+		{"orders", ddl.Type{Name: ddl.Int64}, true},
+		{"users", ddl.Type{Name: ddl.Int64}, false},
+	} {
+		conv.SrcSchema[t.name] = schema.Table{
+			Name:     t.name,
+			ColNames: []string{"id"},
+			ColDefs:  map[string]schema.Column{"id": {Name: "id", Ignored: schema.Ignored{AutoIncrement: t.autoInc}}},
+		}
+		conv.SpSchema[t.name] = ddl.CreateTable{
+			Name:     t.name,
+			ColNames: []string{"id"},
+			ColDefs:  map[string]ddl.ColumnDef{"id": {Name: "id", T: t.spType, NotNull: true}},
+			Pks:      []ddl.IndexKey{{Col: "id"}},
+		}
+		conv.ToSource[t.name] = NameAndCols{Name: t.name, Cols: map[string]string{"id": "id"}}
+		conv.ToSpanner[t.name] = NameAndCols{Name: t.name, Cols: map[string]string{"id": "id"}}
+	}
+	return conv
+}
+
+func TestDetectHotspots(t *testing.T) {
+	conv := buildHotspotConv()
+	hotspots := DetectHotspots(conv)
+	assert.Equal(t, []Hotspot{
+		{SpTable: "events", SpCol: "id", Issue: HotspotTimestamp},
+		{SpTable: "orders", SpCol: "id", Issue: HotspotAutoIncrement},
+	}, hotspots)
+	AddHotspotIssues(conv, hotspots)
+	AddHotspotIssues(conv, hotspots)
+	assert.Equal(t, []SchemaIssue{HotspotTimestamp}, conv.Issues["events"]["id"])
+	assert.Equal(t, []SchemaIssue{HotspotAutoIncrement}, conv.Issues["orders"]["id"])
+	assert.Nil(t, conv.Issues["users"])
+}
+
+func TestRemediateHotspots(t *testing.T) {
+	conv := buildHotspotConv()
+	assert.NotNil(t, RemediateHotspots(conv, DetectHotspots(conv), "reverse"))
+
+	// Bit reversal only applies to integer keys.
+	assert.Nil(t, RemediateHotspots(conv, DetectHotspots(conv), HotspotRemediationBitReverse))
+	assert.Equal(t, KeyStrategyBitReverse, conv.KeyStrategies["orders"].Strategy)
+	assert.Equal(t, []Hotspot{{SpTable: "events", SpCol: "id", Issue: HotspotTimestamp}}, DetectHotspots(conv))
+	_, vals := conv.rekeyRow("orders", []string{"id"}, []interface{}{int64(1)})
+	assert.Equal(t, int64(bits.Reverse64(1)), vals[0])
+
+	assert.Nil(t, RemediateHotspots(conv, DetectHotspots(conv), HotspotRemediationHashPrefix))
+	assert.Equal(t, []ddl.IndexKey{{Col: "id_shard"}, {Col: "id"}}, conv.SpSchema["events"].Pks)
+	assert.Empty(t, DetectHotspots(conv))
+}
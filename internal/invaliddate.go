@@ -0,0 +1,114 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/civil"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// Policies for source dates and datetimes that aren't valid Spanner DATE or
+// TIMESTAMP values, such as MySQL's zero date '0000-00-00' and dates
+// with a zero month or day.
+const (
+	InvalidDateDrop     = "drop"     // Drop the row, and write it to the bad data. This is the default.
+	InvalidDateNull     = "null"     // Write NULL instead.
+	InvalidDateSentinel = "sentinel" // Write a sentinel date instead.
+)
+
+// DefaultDateSentinel is the default sentinel date, the earliest Spanner
+// DATE.
+const DefaultDateSentinel = "0001-01-01"
+
+type invalidDateConfig struct {
+	policy   string
+	sentinel civil.Date
+}
+
+// SetInvalidDatePolicy configures how conv handles invalid source dates and
+// datetimes: policy is one of InvalidDateDrop, InvalidDateNull or
+// InvalidDateSentinel, and sentinel (YYYY-MM-DD) is the date written with
+// InvalidDateSentinel, DefaultDateSentinel if empty. Timestamp columns get
+// midnight UTC of the sentinel date.
+func (conv *Conv) SetInvalidDatePolicy(policy, sentinel string) error {
+	switch policy {
+	case InvalidDateDrop, InvalidDateNull, InvalidDateSentinel:
+	default:
+		return fmt.Errorf("invalid invalid-date policy %s, accepted values are: drop, null, sentinel", policy)
+	}
+	if sentinel == "" {
+		sentinel = DefaultDateSentinel
+	}
+	d, err := civil.ParseDate(sentinel)
+	if err != nil || !DateInRange(d) {
+		return fmt.Errorf("invalid sentinel date %s, expected a date between 0001-01-01 and 9999-12-31", sentinel)
+	}
+	conv.invalidDates = invalidDateConfig{policy: policy, sentinel: d}
+	return nil
+}
+
+// InvalidDatePolicy returns the policy for invalid source dates and
+// datetimes.
+func (conv *Conv) InvalidDatePolicy() string {
+	if conv.invalidDates.policy == "" {
+		return InvalidDateDrop
+	}
+	return conv.invalidDates.policy
+}
+
+// DateInRange returns true if x, a civil.Date or time.Time, is in the range
+// of Spanner DATE and TIMESTAMP values (years 1 to 9999). Other values are
+// in range.
+func DateInRange(x interface{}) bool {
+	var year int
+	switch d := x.(type) {
+	case civil.Date:
+		year = d.Year
+	case time.Time:
+		year = d.UTC().Year()
+	default:
+		return true
+	}
+	return year >= 1 && year <= 9999
+}
+
+// SanitizeInvalidDate applies the invalid date policy to invalid value val
+// of column srcCol of source table srcTable, which is converted to Spanner
+// type spType (DATE or TIMESTAMP). It returns the value to write, nil for
+// NULL, or an error if the row must be dropped.
+func (conv *Conv) SanitizeInvalidDate(srcTable, srcCol, spType, val string) (interface{}, error) {
+	if conv.Stats.InvalidDates == nil {
+		conv.Stats.InvalidDates = make(map[string]map[string]int64)
+	}
+	if conv.Stats.InvalidDates[srcTable] == nil {
+		conv.Stats.InvalidDates[srcTable] = make(map[string]int64)
+	}
+	conv.Stats.InvalidDates[srcTable][srcCol]++
+	switch conv.InvalidDatePolicy() {
+	case InvalidDateNull:
+		return nil, nil
+	case InvalidDateSentinel:
+		d := conv.invalidDates.sentinel
+		if spType == ddl.Timestamp {
+			return d.In(time.UTC), nil
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("invalid date %q in column %s", val, srcCol)
+}
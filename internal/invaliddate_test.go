@@ -0,0 +1,66 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func TestSetInvalidDatePolicy(t *testing.T) {
+	conv := MakeConv()
+	assert.Equal(t, InvalidDateDrop, conv.InvalidDatePolicy())
+	assert.NotNil(t, conv.SetInvalidDatePolicy("zero", ""))
+	assert.NotNil(t, conv.SetInvalidDatePolicy(InvalidDateSentinel, "0000-00-00"))
+	assert.NotNil(t, conv.SetInvalidDatePolicy(InvalidDateSentinel, "1970-13-01"))
+	assert.Nil(t, conv.SetInvalidDatePolicy(InvalidDateNull, ""))
+	assert.Equal(t, InvalidDateNull, conv.InvalidDatePolicy())
+}
+
+func TestDateInRange(t *testing.T) {
+	assert.True(t, DateInRange(civil.Date{Year: 1, Month: 1, Day: 1}))
+	assert.False(t, DateInRange(civil.Date{Year: 0, Month: 1, Day: 1}))
+	assert.True(t, DateInRange(time.Date(9999, 12, 31, 23, 0, 0, 0, time.UTC)))
+	assert.False(t, DateInRange(time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, DateInRange("0000-00-00"))
+}
+
+func TestSanitizeInvalidDate(t *testing.T) {
+	tc := []struct {
+		name   string
+		policy string
+		spType string
+		want   interface{}
+		err    bool
+	}{
+		{"Drop", InvalidDateDrop, ddl.Date, nil, true},
+		{"Null", InvalidDateNull, ddl.Timestamp, nil, false},
+		{"Sentinel date", InvalidDateSentinel, ddl.Date, civil.Date{Year: 1970, Month: 1, Day: 1}, false},
+		{"Sentinel timestamp", InvalidDateSentinel, ddl.Timestamp, time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tc := range tc {
+		conv := MakeConv()
+		assert.Nil(t, conv.SetInvalidDatePolicy(tc.policy, "1970-01-01"), tc.name)
+		got, err := conv.SanitizeInvalidDate("t1", "d", tc.spType, "0000-00-00")
+		assert.Equal(t, tc.err, err != nil, tc.name)
+		assert.Equal(t, tc.want, got, tc.name)
+		assert.Equal(t, map[string]map[string]int64{"t1": {"d": 1}}, conv.Stats.InvalidDates, tc.name)
+	}
+}
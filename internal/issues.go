@@ -0,0 +1,204 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Severity levels used to group schema issues in reports. Notes and
+// suggestions in IssueDB are both reported at the info level.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Severity returns the severity level of the issue: one of SeverityError,
+// SeverityWarning and SeverityInfo.
+func (i SchemaIssue) Severity() string {
+	switch IssueDB[i].severity {
+	case errors:
+		return SeverityError
+	case warning:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// IssueSuppression is a rule for suppressing schema issues from reports,
+// e.g. issues that have been reviewed and accepted. Empty fields match
+// anything, so {"issue": "Widened"} suppresses all Widened issues and
+// {"table": "logs"} suppresses all issues of table logs.
+type IssueSuppression struct {
+	Issue  string `json:"issue"`  // Issue name, as returned by SchemaIssue.Name.
+	Table  string `json:"table"`  // Source table name.
+	Column string `json:"column"` // Source column name.
+}
+
+func (s IssueSuppression) matches(srcTable, srcCol string, i SchemaIssue) bool {
+	return (s.Issue == "" || s.Issue == i.Name()) &&
+		(s.Table == "" || s.Table == srcTable) &&
+		(s.Column == "" || s.Column == srcCol)
+}
+
+// ReadIssueSuppressions reads a JSON list of IssueSuppression rules from file
+// 'name'.
+func ReadIssueSuppressions(name string) ([]IssueSuppression, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't read issue suppression file %s: %v", name, err)
+	}
+	var l []IssueSuppression
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, fmt.Errorf("can't parse issue suppression file %s: %v", name, err)
+	}
+	names := make(map[string]bool)
+	for _, n := range schemaIssueNames {
+		names[n] = true
+	}
+	for _, s := range l {
+		if s.Issue != "" && !names[s.Issue] {
+			return nil, fmt.Errorf("unknown issue %s in issue suppression file %s", s.Issue, name)
+		}
+		if s.Issue == "" && s.Table == "" && s.Column == "" {
+			return nil, fmt.Errorf("empty rule in issue suppression file %s: rules must set at least one of issue, table and column", name)
+		}
+	}
+	return l, nil
+}
+
+// SetIssueSuppressions sets the rules used to suppress schema issues from
+// reports.
+func (conv *Conv) SetIssueSuppressions(l []IssueSuppression) {
+	conv.issueSuppressions = l
+}
+
+// IsSuppressed returns true if issue i of column srcCol of table srcTable
+// matches one of the suppression rules.
+func (conv *Conv) IsSuppressed(srcTable, srcCol string, i SchemaIssue) bool {
+	for _, s := range conv.issueSuppressions {
+		if s.matches(srcTable, srcCol, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// activeIssues returns the issues of table srcTable that are not suppressed,
+// keyed by source column.
+func (conv *Conv) activeIssues(srcTable string) map[string][]SchemaIssue {
+	m := make(map[string][]SchemaIssue)
+	for c, l := range conv.Issues[srcTable] {
+		for _, i := range l {
+			if !conv.IsSuppressed(srcTable, c, i) {
+				m[c] = append(m[c], i)
+			}
+		}
+	}
+	return m
+}
+
+// suppressedIssues returns the number of schema issues suppressed by the
+// suppression rules.
+func (conv *Conv) suppressedIssues() int64 {
+	n := int64(0)
+	for t, cols := range conv.Issues {
+		for c, l := range cols {
+			for _, i := range l {
+				if conv.IsSuppressed(t, c, i) {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
+
+// maxIssueLocations is the number of table.column locations listed for each
+// issue in the severity summary. The rest are elided to keep reports of large
+// schemas readable.
+const maxIssueLocations = 5
+
+// writeIssuesBySeverity writes a summary of the schema issues of all tables,
+// grouped by severity and then by issue, most severe first.
+func writeIssuesBySeverity(conv *Conv, w *bufio.Writer) {
+	// Maps severity to issue to list of table.column locations.
+	locations := make(map[string]map[SchemaIssue][]string)
+	var tables []string
+	for t := range conv.Issues {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		issues := conv.activeIssues(t)
+		var cols []string
+		for c := range issues {
+			cols = append(cols, c)
+		}
+		sort.Strings(cols)
+		for _, c := range cols {
+			for _, i := range issues[c] {
+				s := i.Severity()
+				if locations[s] == nil {
+					locations[s] = make(map[SchemaIssue][]string)
+				}
+				locations[s][i] = append(locations[s][i], t+"."+c)
+			}
+		}
+	}
+	writeHeading(w, "Schema Issues by Severity")
+	for _, s := range []struct {
+		severity string
+		heading  string
+	}{
+		{SeverityError, "Errors"},
+		{SeverityWarning, "Warnings"},
+		{SeverityInfo, "Info"},
+	} {
+		m := locations[s.severity]
+		n := 0
+		var issues []SchemaIssue
+		for i, l := range m {
+			n += len(l)
+			issues = append(issues, i)
+		}
+		if n == 0 {
+			w.WriteString(fmt.Sprintf("%s: none\n", s.heading))
+			continue
+		}
+		w.WriteString(fmt.Sprintf("%s: %d\n", s.heading, n))
+		sort.Slice(issues, func(i, j int) bool { return issues[i].Name() < issues[j].Name() })
+		for _, i := range issues {
+			l := m[i]
+			more := ""
+			if len(l) > maxIssueLocations {
+				more = fmt.Sprintf(" and %d more", len(l)-maxIssueLocations)
+				l = l[:maxIssueLocations]
+			}
+			justifyLines(w, fmt.Sprintf("  %s (%d): %s%s\n", i.Name(), len(m[i]), strings.Join(l, ", "), more), 80, 4)
+		}
+	}
+	if len(conv.issueSuppressions) > 0 {
+		w.WriteString(fmt.Sprintf("Suppressed: %d\n", conv.suppressedIssues()))
+	}
+	w.WriteString("\n")
+}
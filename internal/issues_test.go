@@ -0,0 +1,86 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadIssueSuppressions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(s string) string {
+		name := filepath.Join(dir, "rules.json")
+		assert.Nil(t, os.WriteFile(name, []byte(s), 0644))
+		return name
+	}
+	l, err := ReadIssueSuppressions(write(`[{"issue": "Widened"}, {"table": "t1", "column": "b"}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, []IssueSuppression{{Issue: "Widened"}, {Table: "t1", Column: "b"}}, l)
+
+	_, err = ReadIssueSuppressions(write(`[{"issue": "NoSuchIssue"}]`))
+	assert.NotNil(t, err)
+	_, err = ReadIssueSuppressions(write(`[{}]`))
+	assert.NotNil(t, err)
+	_, err = ReadIssueSuppressions(write(`{`))
+	assert.NotNil(t, err)
+}
+
+func TestIssueSuppression(t *testing.T) {
+	conv := MakeConv()
+	conv.Issues = map[string]map[string][]SchemaIssue{
+		"t1": {"a": {Widened, NoGoodType}, "b": {DefaultValue}},
+		"t2": {"a": {Widened}},
+	}
+	conv.SetIssueSuppressions([]IssueSuppression{{Issue: "Widened", Table: "t1"}, {Table: "t1", Column: "b"}})
+	assert.True(t, conv.IsSuppressed("t1", "a", Widened))
+	assert.False(t, conv.IsSuppressed("t2", "a", Widened))
+	assert.False(t, conv.IsSuppressed("t1", "a", NoGoodType))
+	assert.True(t, conv.IsSuppressed("t1", "b", DefaultValue))
+	assert.Equal(t, map[string][]SchemaIssue{"a": {NoGoodType}}, conv.activeIssues("t1"))
+	assert.Equal(t, int64(2), conv.suppressedIssues())
+}
+
+func TestWriteIssuesBySeverity(t *testing.T) {
+	conv := MakeConv()
+	conv.Issues = map[string]map[string][]SchemaIssue{
+		"t1": {"a": {Widened, NoGoodType}, "b": {DefaultValue}},
+		"t2": {"a": {Widened}, "b": {Widened}, "c": {Widened}, "d": {Widened}, "e": {Widened}},
+	}
+	conv.SetIssueSuppressions([]IssueSuppression{{Table: "t1", Column: "b"}})
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeIssuesBySeverity(conv, w)
+	w.Flush()
+	expected := `----------------------------
+Schema Issues by Severity
+----------------------------
+Errors: none
+Warnings: 1
+  NoGoodType (1): t1.a
+Info: 6
+  Widened (6): t1.a, t2.a, t2.b, t2.c, t2.d and 1 more
+Suppressed: 1
+
+`
+	assert.Equal(t, expected, buf.String())
+	assert.Equal(t, SeverityWarning, NoGoodType.Severity())
+	assert.Equal(t, SeverityInfo, Widened.Severity())
+}
@@ -0,0 +1,369 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/google/uuid"
+)
+
+// Key strategies for tables whose primary key is a single auto-increment
+// (or SERIAL) or timestamp column. Monotonically increasing keys cause hotspots
+// in Spanner, so each strategy replaces or spreads the key values.
+const (
+	// KeyStrategySequence keeps migrated key values and generates new ones
+	// using a bit-reversed Spanner sequence.
+	KeyStrategySequence = "sequence"
+	// KeyStrategyUUID replaces key values with UUIDs, rewriting all foreign
+	// keys (and interleaved child keys) that reference them.
+	KeyStrategyUUID = "uuid"
+	// KeyStrategyHashPrefix adds a shard column, computed from a hash of the
+	// key, as the first column of the primary key.
+	KeyStrategyHashPrefix = "hash_prefix"
+	// KeyStrategyBitReverse reverses the bits of key values during data
+	// migration, rewriting all foreign keys (and interleaved child keys) that
+	// reference them.
+	KeyStrategyBitReverse = "bit_reverse"
+)
+
+const (
+	// SequenceSkipRangeMax is the upper bound of the range of key values
+	// reserved for migrated rows when using KeyStrategySequence. Sequences
+	// never generate values in [1, SequenceSkipRangeMax].
+	SequenceSkipRangeMax = 1 << 40
+	// DefaultKeyShards is the number of shards used by KeyStrategyHashPrefix
+	// if none is specified.
+	DefaultKeyShards = 16
+	maxKeyShards     = 4096
+	uuidLength       = 36
+)
+
+// KeyStrategy describes how the primary key of a Spanner table is changed to
+// avoid hotspots, and how key values are re-keyed during data migration.
+type KeyStrategy struct {
+	Strategy string
+	Col      string // Spanner key column the strategy applies to.
+	ShardCol string // Shard column added by KeyStrategyHashPrefix.
+	Shards   int64  // Number of shards used by KeyStrategyHashPrefix.
+	// Types are the original types of the columns holding the key, changed
+	// to STRING by KeyStrategyUUID, by table and column.
+	Types map[string]map[string]ddl.Type `json:",omitempty"`
+}
+
+// SetKeyStrategy applies key strategy 'strategy' to Spanner table spTable,
+// updating the Spanner schema accordingly. Any existing key strategy of the
+// table is cleared first. The table must have a single primary key column,
+// which must have type INT64 for all strategies except KeyStrategyHashPrefix.
+// Shards is only used by KeyStrategyHashPrefix, and defaults to
+// DefaultKeyShards if zero.
+func (conv *Conv) SetKeyStrategy(spTable, strategy string, shards int64) error {
+	if _, ok := conv.SpSchema[spTable]; !ok {
+		return fmt.Errorf("table %s not found", spTable)
+	}
+	switch strategy {
+	case KeyStrategySequence, KeyStrategyUUID, KeyStrategyHashPrefix, KeyStrategyBitReverse:
+	default:
+		return fmt.Errorf("unknown key strategy '%s': must be one of %s, %s, %s or %s", strategy, KeyStrategySequence, KeyStrategyUUID, KeyStrategyHashPrefix, KeyStrategyBitReverse)
+	}
+	conv.ClearKeyStrategy(spTable)
+	ct := conv.SpSchema[spTable]
+	if len(ct.Pks) != 1 {
+		return fmt.Errorf("table %s must have a single primary key column to use a key strategy", spTable)
+	}
+	col := ct.Pks[0].Col
+	cd := ct.ColDefs[col]
+	if cd.T.IsArray || (cd.T.Name != ddl.Int64 && strategy != KeyStrategyHashPrefix) {
+		return fmt.Errorf("primary key column %s of table %s must have type INT64 to use key strategy %s", col, spTable, strategy)
+	}
+	if strategy == KeyStrategyUUID && cd.Sequence != nil && conv.hasSourceSequence(spTable, col) {
+		return fmt.Errorf("values of primary key column %s of table %s are generated by a sequence, and can't be replaced by UUIDs", col, spTable)
+	}
+	ks := KeyStrategy{Strategy: strategy, Col: col}
+	if conv.KeyStrategies == nil {
+		conv.KeyStrategies = make(map[string]KeyStrategy)
+	}
+	switch strategy {
+	case KeyStrategySequence:
+		// Keys generated by a source sequence already have a Spanner
+		// sequence that skips the migrated values.
+		if cd.Sequence == nil || !conv.hasSourceSequence(spTable, col) {
+			cd.Sequence = &ddl.Sequence{Name: getSpannerID(conv, spTable+"_"+col+"_seq"), SkipRangeMin: 1, SkipRangeMax: SequenceSkipRangeMax}
+		}
+		ct.ColDefs[col] = cd
+	case KeyStrategyUUID:
+		conv.KeyStrategies[spTable] = ks
+		conv.keyRewrites = nil
+		ks.Types = make(map[string]map[string]ddl.Type)
+		for t, cols := range conv.keyRewriteCols() {
+			for c, refTable := range cols {
+				if refTable != spTable {
+					continue
+				}
+				if cd, ok := conv.SpSchema[t].ColDefs[c]; ok {
+					if _, ok := ks.Types[t]; !ok {
+						ks.Types[t] = make(map[string]ddl.Type)
+					}
+					ks.Types[t][c] = cd.T
+				}
+				conv.setColType(t, c, ddl.Type{Name: ddl.String, Len: uuidLength})
+			}
+		}
+		conv.KeyStrategies[spTable] = ks
+		return nil
+	case KeyStrategyHashPrefix:
+		if shards == 0 {
+			shards = DefaultKeyShards
+		}
+		if shards < 2 || shards > maxKeyShards {
+			return fmt.Errorf("number of shards must be between 2 and %d", maxKeyShards)
+		}
+		if ct.Parent != "" {
+			return fmt.Errorf("table %s is interleaved, and its primary key can't be prefixed", spTable)
+		}
+		for _, t := range conv.SpSchema {
+			if t.Parent == spTable {
+				return fmt.Errorf("table %s has interleaved child table %s, and its primary key can't be prefixed", spTable, t.Name)
+			}
+		}
+		ks.Shards = shards
+		ks.ShardCol = col + "_shard"
+		for i := 0; ; i++ {
+			if _, ok := ct.ColDefs[ks.ShardCol]; !ok {
+				break
+			}
+			ks.ShardCol = fmt.Sprintf("%s_shard%d", col, i)
+		}
+		ct.ColNames = append([]string{ks.ShardCol}, ct.ColNames...)
+		ct.ColDefs[ks.ShardCol] = ddl.ColumnDef{Name: ks.ShardCol, T: ddl.Type{Name: ddl.Int64}, NotNull: true}
+		pk := ct.Pks[0]
+		shardKey := ddl.IndexKey{Col: ks.ShardCol}
+		if pk.Order != 0 {
+			// Keep the key order used by the web UI consistent.
+			shardKey.Order = 1
+			pk.Order = 2
+		}
+		ct.Pks = []ddl.IndexKey{shardKey, pk}
+	}
+	conv.SpSchema[spTable] = ct
+	conv.KeyStrategies[spTable] = ks
+	conv.keyRewrites = nil
+	return nil
+}
+
+// ClearKeyStrategy removes the key strategy of Spanner table spTable (if
+// any), restoring its original primary key.
+func (conv *Conv) ClearKeyStrategy(spTable string) {
+	ks, ok := conv.KeyStrategies[spTable]
+	if !ok {
+		return
+	}
+	switch ks.Strategy {
+	case KeyStrategySequence:
+		if cd, ok := conv.SpSchema[spTable].ColDefs[ks.Col]; ok && !conv.hasSourceSequence(spTable, ks.Col) {
+			cd.Sequence = nil
+			conv.SpSchema[spTable].ColDefs[ks.Col] = cd
+		}
+	case KeyStrategyUUID:
+		for t, cols := range conv.keyRewriteCols() {
+			for c, refTable := range cols {
+				if refTable != spTable {
+					continue
+				}
+				// Strategies set before the original types were recorded
+				// only apply to INT64 keys.
+				typ, ok := ks.Types[t][c]
+				if !ok {
+					typ = ddl.Type{Name: ddl.Int64}
+				}
+				conv.setColType(t, c, typ)
+			}
+		}
+	case KeyStrategyHashPrefix:
+		ct := conv.SpSchema[spTable]
+		var colNames []string
+		for _, c := range ct.ColNames {
+			if c != ks.ShardCol {
+				colNames = append(colNames, c)
+			}
+		}
+		ct.ColNames = colNames
+		delete(ct.ColDefs, ks.ShardCol)
+		var pks []ddl.IndexKey
+		for _, pk := range ct.Pks {
+			if pk.Col != ks.ShardCol {
+				if pk.Order > 1 {
+					pk.Order--
+				}
+				pks = append(pks, pk)
+			}
+		}
+		ct.Pks = pks
+		conv.SpSchema[spTable] = ct
+	}
+	delete(conv.KeyStrategies, spTable)
+	conv.keyRewrites = nil
+}
+
+func (conv *Conv) setColType(spTable, col string, t ddl.Type) {
+	if cd, ok := conv.SpSchema[spTable].ColDefs[col]; ok {
+		cd.T = t
+		conv.SpSchema[spTable].ColDefs[col] = cd
+	}
+}
+
+// keyRewriteCols returns the columns whose values must be rewritten during
+// data migration, as a map from Spanner table to column to the table whose
+// key the column holds. This includes the keys of tables using KeyStrategyUUID
+// or KeyStrategyBitReverse, the corresponding key columns of the tables
+// interleaved in them, at any depth, and foreign keys referencing any of
+// these columns.
+func (conv *Conv) keyRewriteCols() map[string]map[string]string {
+	if conv.keyRewrites != nil {
+		return conv.keyRewrites
+	}
+	m := make(map[string]map[string]string)
+	add := func(t, c, refTable string) {
+		if _, ok := m[t]; !ok {
+			m[t] = make(map[string]string)
+		}
+		m[t][c] = refTable
+	}
+	for spTable, ks := range conv.KeyStrategies {
+		if ks.Strategy != KeyStrategyUUID && ks.Strategy != KeyStrategyBitReverse {
+			continue
+		}
+		// The primary key of an interleaved child table starts with the
+		// primary key of its parent, so the key is the first key column of
+		// the tables interleaved in the table, their children and so on.
+		keyCols := map[string]string{spTable: ks.Col}
+		for parents := []string{spTable}; len(parents) > 0; {
+			var children []string
+			for t, ct := range conv.SpSchema {
+				if _, done := keyCols[t]; done || len(ct.Pks) == 0 {
+					continue
+				}
+				for _, p := range parents {
+					if ct.Parent == p {
+						keyCols[t] = ct.Pks[0].Col
+						children = append(children, t)
+						break
+					}
+				}
+			}
+			parents = children
+		}
+		for t, c := range keyCols {
+			add(t, c, spTable)
+		}
+		for t, ct := range conv.SpSchema {
+			for _, fk := range ct.Fks {
+				keyCol, ok := keyCols[fk.ReferTable]
+				if !ok {
+					continue
+				}
+				for i, c := range fk.ReferColumns {
+					if c == keyCol && i < len(fk.Columns) {
+						add(t, fk.Columns[i], spTable)
+					}
+				}
+			}
+		}
+	}
+	conv.keyRewrites = m
+	return m
+}
+
+// rekeyRow applies the key strategies to a row of Spanner table spTable,
+// returning the updated columns and values.
+func (conv *Conv) rekeyRow(spTable string, cols []string, vals []interface{}) ([]string, []interface{}) {
+	if len(conv.KeyStrategies) == 0 {
+		return cols, vals
+	}
+	if rewrites, ok := conv.keyRewriteCols()[spTable]; ok {
+		vals = append([]interface{}{}, vals...)
+		for i, c := range cols {
+			refTable, ok := rewrites[c]
+			if !ok || vals[i] == nil {
+				continue
+			}
+			switch conv.KeyStrategies[refTable].Strategy {
+			case KeyStrategyUUID:
+				vals[i] = keyUUID(refTable, vals[i])
+			case KeyStrategyBitReverse:
+				if v, ok := vals[i].(int64); ok {
+					vals[i] = int64(bits.Reverse64(uint64(v)))
+				}
+			}
+		}
+	}
+	ks, ok := conv.KeyStrategies[spTable]
+	if !ok {
+		return cols, vals
+	}
+	for i, c := range cols {
+		if c != ks.Col {
+			continue
+		}
+		switch ks.Strategy {
+		case KeyStrategySequence:
+			if v, ok := vals[i].(int64); ok && (v < 1 || v > SequenceSkipRangeMax) {
+				conv.Unexpected(fmt.Sprintf("Key of table %s is outside the sequence skip range and may collide with generated values", spTable))
+			}
+		case KeyStrategyHashPrefix:
+			cols = append(append([]string{}, cols...), ks.ShardCol)
+			vals = append(append([]interface{}{}, vals...), keyShard(vals[i], ks.Shards))
+		}
+		break
+	}
+	return cols, vals
+}
+
+// keyUUID returns the UUID that replaces key value v of Spanner table
+// spTable. UUIDs are derived deterministically from the table and value, so
+// that foreign keys can be rewritten independently of the referenced rows.
+func keyUUID(spTable string, v interface{}) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s/%v", spTable, v))).String()
+}
+
+// keyShard returns the shard of key value v for KeyStrategyHashPrefix.
+func keyShard(v interface{}, shards int64) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(fmt.Sprintf("%v", v)))
+	return int64(h.Sum32()) % shards
+}
+
+// keyStrategyNote returns a human readable description of the key strategy
+// of Spanner table spTable, or "" if it has none.
+func (conv *Conv) keyStrategyNote(spTable string) string {
+	ks, ok := conv.KeyStrategies[spTable]
+	if !ok {
+		return ""
+	}
+	switch ks.Strategy {
+	case KeyStrategySequence:
+		return fmt.Sprintf("New values of key column '%s' are generated by a bit-reversed sequence; migrated values are kept and reserved by the sequence skip range", ks.Col)
+	case KeyStrategyUUID:
+		return fmt.Sprintf("Values of key column '%s' (and foreign keys referencing it) are replaced by UUIDs during data migration", ks.Col)
+	case KeyStrategyBitReverse:
+		return fmt.Sprintf("Values of key column '%s' (and foreign keys referencing it) are bit-reversed during data migration", ks.Col)
+	case KeyStrategyHashPrefix:
+		return fmt.Sprintf("Column '%s' was added as the first primary key column to spread key column '%s' across %d shards", ks.ShardCol, ks.Col, ks.Shards)
+	}
+	return ""
+}
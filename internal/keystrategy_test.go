@@ -0,0 +1,190 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildKeyStrategyConv() *Conv {
+	conv := MakeConv()
+	conv.SpSchema = map[string]ddl.CreateTable{
+		"parent": {
+			Name:     "parent",
+			ColNames: []string{"id", "a"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id": {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"a":  {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			Pks: []ddl.IndexKey{{Col: "id"}},
+		},
+		"child": {
+			Name:     "child",
+			ColNames: []string{"id", "parent_id"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":        {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"parent_id": {Name: "parent_id", T: ddl.Type{Name: ddl.Int64}},
+			},
+			Pks: []ddl.IndexKey{{Col: "id"}},
+			Fks: []ddl.Foreignkey{{Name: "fk", Columns: []string{"parent_id"}, ReferTable: "parent", ReferColumns: []string{"id"}}},
+		},
+	}
+	return conv
+}
+
+func TestSetKeyStrategy(t *testing.T) {
+	conv := buildKeyStrategyConv()
+	assert.NotNil(t, conv.SetKeyStrategy("parent", "autoincrement", 0))
+	assert.NotNil(t, conv.SetKeyStrategy("missing", KeyStrategyUUID, 0))
+	assert.NotNil(t, conv.SetKeyStrategy("parent", KeyStrategyHashPrefix, 1))
+
+	assert.Nil(t, conv.SetKeyStrategy("parent", KeyStrategySequence, 0))
+	assert.Equal(t, &ddl.Sequence{Name: "parent_id_seq", SkipRangeMin: 1, SkipRangeMax: SequenceSkipRangeMax}, conv.SpSchema["parent"].ColDefs["id"].Sequence)
+
+	// Setting a new strategy replaces the old one.
+	assert.Nil(t, conv.SetKeyStrategy("parent", KeyStrategyUUID, 0))
+	assert.Nil(t, conv.SpSchema["parent"].ColDefs["id"].Sequence)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 36}, conv.SpSchema["parent"].ColDefs["id"].T)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 36}, conv.SpSchema["child"].ColDefs["parent_id"].T)
+
+	assert.Nil(t, conv.SetKeyStrategy("parent", KeyStrategyHashPrefix, 0))
+	assert.Equal(t, ddl.Type{Name: ddl.Int64}, conv.SpSchema["child"].ColDefs["parent_id"].T)
+	assert.Equal(t, []string{"id_shard", "id", "a"}, conv.SpSchema["parent"].ColNames)
+	assert.Equal(t, []ddl.IndexKey{{Col: "id_shard"}, {Col: "id"}}, conv.SpSchema["parent"].Pks)
+	assert.Equal(t, KeyStrategy{Strategy: KeyStrategyHashPrefix, Col: "id", ShardCol: "id_shard", Shards: DefaultKeyShards}, conv.KeyStrategies["parent"])
+
+	conv.ClearKeyStrategy("parent")
+	assert.Equal(t, buildKeyStrategyConv().SpSchema, conv.SpSchema)
+	assert.Empty(t, conv.KeyStrategies)
+}
+
+func TestSetKeyStrategySourceSequence(t *testing.T) {
+	conv := buildKeyStrategyConv()
+	conv.SrcSchema["parent"] = schema.Table{Name: "parent", ColNames: []string{"id", "a"}, ColDefs: map[string]schema.Column{
+		"id": {Name: "id", Type: schema.Type{Name: "bigint"}, Sequence: &schema.Sequence{Name: "parent_id_seq", LastValue: 10}},
+		"a":  {Name: "a", Type: schema.Type{Name: "text"}},
+	}}
+	conv.ToSpanner["parent"] = NameAndCols{Name: "parent", Cols: map[string]string{"id": "id", "a": "a"}}
+	conv.ToSource["parent"] = NameAndCols{Name: "parent", Cols: map[string]string{"id": "id", "a": "a"}}
+	sq := &ddl.Sequence{Name: "parent_id_seq", SkipRangeMin: 1, SkipRangeMax: 10, StartWithCounter: 11}
+	conv.SpSchema["parent"].ColDefs["id"] = ddl.ColumnDef{Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true, Sequence: sq}
+
+	// The sequence converted from the source is kept.
+	assert.Nil(t, conv.SetKeyStrategy("parent", KeyStrategySequence, 0))
+	assert.Equal(t, sq, conv.SpSchema["parent"].ColDefs["id"].Sequence)
+	conv.ClearKeyStrategy("parent")
+	assert.Equal(t, sq, conv.SpSchema["parent"].ColDefs["id"].Sequence)
+
+	assert.NotNil(t, conv.SetKeyStrategy("parent", KeyStrategyUUID, 0))
+	assert.Nil(t, conv.SetKeyStrategy("parent", KeyStrategyBitReverse, 0))
+	assert.Equal(t, sq, conv.SpSchema["parent"].ColDefs["id"].Sequence)
+}
+
+func TestSetKeyStrategyInterleaved(t *testing.T) {
+	conv := buildKeyStrategyConv()
+	int64Type := ddl.Type{Name: ddl.Int64}
+	conv.SpSchema["orders"] = ddl.CreateTable{
+		Name:     "orders",
+		ColNames: []string{"parent_id", "id"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"parent_id": {Name: "parent_id", T: int64Type, NotNull: true},
+			"id":        {Name: "id", T: int64Type, NotNull: true},
+		},
+		Pks:    []ddl.IndexKey{{Col: "parent_id"}, {Col: "id"}},
+		Parent: "parent",
+	}
+	conv.SpSchema["lines"] = ddl.CreateTable{
+		Name:     "lines",
+		ColNames: []string{"owner", "order_id", "id"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"owner":    {Name: "owner", T: int64Type, NotNull: true},
+			"order_id": {Name: "order_id", T: int64Type, NotNull: true},
+			"id":       {Name: "id", T: int64Type, NotNull: true},
+		},
+		Pks:    []ddl.IndexKey{{Col: "owner"}, {Col: "order_id"}, {Col: "id"}},
+		Parent: "orders",
+	}
+	// A foreign key referencing the key of a grandchild, with a type other
+	// than INT64.
+	conv.SpSchema["notes"] = ddl.CreateTable{
+		Name:     "notes",
+		ColNames: []string{"id", "line_owner"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"id":         {Name: "id", T: int64Type, NotNull: true},
+			"line_owner": {Name: "line_owner", T: ddl.Type{Name: ddl.Numeric}},
+		},
+		Pks: []ddl.IndexKey{{Col: "id"}},
+		Fks: []ddl.Foreignkey{{Name: "notes_fk", Columns: []string{"line_owner"}, ReferTable: "lines", ReferColumns: []string{"owner"}}},
+	}
+	original := make(ddl.Schema)
+	for t, ct := range conv.SpSchema {
+		colDefs := make(map[string]ddl.ColumnDef)
+		for c, cd := range ct.ColDefs {
+			colDefs[c] = cd
+		}
+		ct.ColDefs = colDefs
+		original[t] = ct
+	}
+
+	assert.Nil(t, conv.SetKeyStrategy("parent", KeyStrategyUUID, 0))
+	uuidType := ddl.Type{Name: ddl.String, Len: 36}
+	assert.Equal(t, uuidType, conv.SpSchema["orders"].ColDefs["parent_id"].T)
+	assert.Equal(t, uuidType, conv.SpSchema["lines"].ColDefs["owner"].T)
+	assert.Equal(t, uuidType, conv.SpSchema["notes"].ColDefs["line_owner"].T)
+	assert.Equal(t, int64Type, conv.SpSchema["lines"].ColDefs["order_id"].T)
+	assert.Equal(t, map[string]string{"owner": "parent"}, conv.keyRewriteCols()["lines"])
+
+	_, pv := conv.rekeyRow("parent", []string{"id", "a"}, []interface{}{int64(7), "x"})
+	_, lv := conv.rekeyRow("lines", []string{"owner", "order_id", "id"}, []interface{}{int64(7), int64(1), int64(2)})
+	_, nv := conv.rekeyRow("notes", []string{"id", "line_owner"}, []interface{}{int64(3), int64(7)})
+	assert.Equal(t, pv[0], lv[0])
+	assert.Equal(t, pv[0], nv[1])
+	assert.Equal(t, int64(1), lv[1])
+
+	// Renamed columns keep their original types.
+	assert.Nil(t, conv.RenameColumn("notes", "line_owner", "owner_id"))
+	conv.ClearKeyStrategy("parent")
+	renamed := original["notes"]
+	renamed.ColNames = []string{"id", "owner_id"}
+	renamed.ColDefs = map[string]ddl.ColumnDef{"id": renamed.ColDefs["id"], "owner_id": {Name: "owner_id", T: ddl.Type{Name: ddl.Numeric}}}
+	renamed.Fks = []ddl.Foreignkey{{Name: "notes_fk", Columns: []string{"owner_id"}, ReferTable: "lines", ReferColumns: []string{"owner"}}}
+	original["notes"] = renamed
+	assert.Equal(t, original, conv.SpSchema)
+}
+
+func TestRekeyRow(t *testing.T) {
+	conv := buildKeyStrategyConv()
+	assert.Nil(t, conv.SetKeyStrategy("parent", KeyStrategyUUID, 0))
+	_, pv := conv.rekeyRow("parent", []string{"id", "a"}, []interface{}{"7", "x"})
+	_, cv := conv.rekeyRow("child", []string{"id", "parent_id"}, []interface{}{int64(1), "7"})
+	assert.Len(t, pv[0], 36)
+	assert.Equal(t, pv[0], cv[1])
+	assert.Equal(t, "x", pv[1])
+	assert.Equal(t, int64(1), cv[0])
+
+	assert.Nil(t, conv.SetKeyStrategy("parent", KeyStrategyHashPrefix, 4))
+	cols, vals := conv.rekeyRow("parent", []string{"id", "a"}, []interface{}{int64(7), "x"})
+	assert.Equal(t, []string{"id", "a", "id_shard"}, cols)
+	assert.Equal(t, keyShard(int64(7), 4), vals[2])
+	assert.True(t, vals[2].(int64) >= 0 && vals[2].(int64) < 4)
+
+	assert.Nil(t, conv.SetKeyStrategy("parent", KeyStrategySequence, 0))
+	conv.rekeyRow("parent", []string{"id"}, []interface{}{int64(SequenceSkipRangeMax + 1)})
+	assert.Equal(t, int64(1), conv.Unexpecteds())
+}
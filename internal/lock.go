@@ -0,0 +1,161 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+)
+
+// MigrationLockTable is the table of the target database holding the leases
+// of the tables being migrated, which keep two migrations from writing to the
+// same tables at the same time.
+const MigrationLockTable = "harbourbridge_migration_locks"
+
+// MigrationLeaseDuration is how long a lease on a table lasts unless it is
+// renewed. A migration renews its leases every third of it, so that the
+// leases of a migration that crashed expire soon after.
+const MigrationLeaseDuration = 5 * time.Minute
+
+// MigrationLockCols are the columns of MigrationLockTable, in the order of
+// MigrationLock.Values.
+var MigrationLockCols = []string{"TableName", "MigrationRequestId", "Host", "Pid", "AcquiredAt", "ExpiresAt"}
+
+// MigrationLockDDL returns the CREATE TABLE statement of MigrationLockTable
+// for target database targetDb.
+func MigrationLockDDL(targetDb string) string {
+	if targetDb == constants.TargetExperimentalPostgres {
+		return fmt.Sprintf(`CREATE TABLE "%s" (
+	"TableName" VARCHAR(2621440) NOT NULL,
+	"MigrationRequestId" VARCHAR(2621440) NOT NULL,
+	"Host" VARCHAR(2621440),
+	"Pid" INT8,
+	"AcquiredAt" TIMESTAMPTZ NOT NULL,
+	"ExpiresAt" TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY ("TableName")
+)`, MigrationLockTable)
+	}
+	return fmt.Sprintf("CREATE TABLE `%s` (\n"+
+		"\t`TableName` STRING(MAX) NOT NULL,\n"+
+		"\t`MigrationRequestId` STRING(MAX) NOT NULL,\n"+
+		"\t`Host` STRING(MAX),\n"+
+		"\t`Pid` INT64,\n"+
+		"\t`AcquiredAt` TIMESTAMP NOT NULL,\n"+
+		"\t`ExpiresAt` TIMESTAMP NOT NULL,\n"+
+		") PRIMARY KEY (`TableName`)", MigrationLockTable)
+}
+
+// MigrationLock is the lease of migration MigrationRequestId, run by process
+// Pid of host Host, on Spanner table TableName.
+type MigrationLock struct {
+	TableName          string
+	MigrationRequestId string
+	Host               string
+	Pid                int64
+	AcquiredAt         time.Time
+	ExpiresAt          time.Time
+}
+
+// Values returns the values of the columns MigrationLockCols of l.
+func (l MigrationLock) Values() []interface{} {
+	return []interface{}{l.TableName, l.MigrationRequestId, l.Host, l.Pid, l.AcquiredAt, l.ExpiresAt}
+}
+
+// LockConflicts returns the leases of locks held by migrations other than
+// requestId that haven't expired at time now, sorted by table.
+func LockConflicts(locks []MigrationLock, requestId string, now time.Time) []MigrationLock {
+	var conflicts []MigrationLock
+	for _, l := range locks {
+		if l.MigrationRequestId != requestId && l.ExpiresAt.After(now) {
+			conflicts = append(conflicts, l)
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].TableName < conflicts[j].TableName })
+	return conflicts
+}
+
+// DescribeLockConflicts returns a description of the migrations holding the
+// leases conflicts, one line per migration.
+func DescribeLockConflicts(conflicts []MigrationLock) string {
+	var ids []string
+	byId := make(map[string][]MigrationLock)
+	for _, l := range conflicts {
+		if _, ok := byId[l.MigrationRequestId]; !ok {
+			ids = append(ids, l.MigrationRequestId)
+		}
+		byId[l.MigrationRequestId] = append(byId[l.MigrationRequestId], l)
+	}
+	var lines []string
+	for _, id := range ids {
+		l := byId[id]
+		var tables []string
+		for _, t := range l {
+			tables = append(tables, t.TableName)
+		}
+		lines = append(lines, fmt.Sprintf("migration %s (host %s, pid %d) has been migrating table(s) %s since %s, and its lease expires at %s",
+			id, l[0].Host, l[0].Pid, strings.Join(tables, ", "), l[0].AcquiredAt.Format(time.RFC3339), l[0].ExpiresAt.Format(time.RFC3339)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// migrationLeases are the leases held by the migrations of this process, by
+// migration request id.
+var migrationLeases = struct {
+	sync.Mutex
+	held map[string]*migrationLease
+}{held: make(map[string]*migrationLease)}
+
+// migrationLease is a lease shared by the steps of a migration that hold it.
+type migrationLease struct {
+	holders int
+	release func()
+}
+
+// HoldMigrationLease returns a function releasing the hold of a step of
+// migration requestId on its leases. The leases are taken with acquire when
+// no other step of the migration holds them, e.g. when data migration runs
+// within a minimal downtime migration, and are released with the function
+// returned by acquire once the last step releases them.
+func HoldMigrationLease(requestId string, acquire func() (func(), error)) (func(), error) {
+	migrationLeases.Lock()
+	defer migrationLeases.Unlock()
+	l, ok := migrationLeases.held[requestId]
+	if !ok {
+		release, err := acquire()
+		if err != nil {
+			return nil, err
+		}
+		l = &migrationLease{release: release}
+		migrationLeases.held[requestId] = l
+	}
+	l.holders++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			migrationLeases.Lock()
+			defer migrationLeases.Unlock()
+			l.holders--
+			if l.holders == 0 {
+				delete(migrationLeases.held, requestId)
+				l.release()
+			}
+		})
+	}, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+)
+
+func TestLockConflicts(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-time.Hour)
+	locks := []MigrationLock{
+		{TableName: "users", MigrationRequestId: "HB-2", Host: "h2", Pid: 7, AcquiredAt: since, ExpiresAt: now.Add(time.Minute)},
+		{TableName: "orders", MigrationRequestId: "HB-2", Host: "h2", Pid: 7, AcquiredAt: since, ExpiresAt: now.Add(time.Minute)},
+		{TableName: "items", MigrationRequestId: "HB-1", ExpiresAt: now.Add(time.Minute)},
+		{TableName: "carts", MigrationRequestId: "HB-3", ExpiresAt: now.Add(-time.Second)},
+	}
+	conflicts := LockConflicts(locks, "HB-1", now)
+	assert.Equal(t, []MigrationLock{locks[1], locks[0]}, conflicts)
+	assert.Equal(t, "migration HB-2 (host h2, pid 7) has been migrating table(s) orders, users since 2022-06-01T11:00:00Z, and its lease expires at 2022-06-01T12:01:00Z", DescribeLockConflicts(conflicts))
+	assert.Empty(t, LockConflicts(locks, "HB-2", now.Add(time.Minute)))
+
+	assert.True(t, IsMigrationCatalogTable(MigrationLockTable))
+	assert.Contains(t, MigrationLockDDL(constants.TargetExperimentalPostgres), `PRIMARY KEY ("TableName")`)
+	assert.Equal(t, len(MigrationLockCols), len(locks[0].Values()))
+}
+
+func TestHoldMigrationLease(t *testing.T) {
+	acquired, released := 0, 0
+	acquire := func() (func(), error) {
+		acquired++
+		return func() { released++ }, nil
+	}
+	release1, err := HoldMigrationLease("HB-1", acquire)
+	assert.Nil(t, err)
+	// Nested steps of the same migration share its leases.
+	release2, err := HoldMigrationLease("HB-1", acquire)
+	assert.Nil(t, err)
+	release2()
+	release2()
+	assert.Equal(t, 1, acquired)
+	assert.Equal(t, 0, released)
+	release1()
+	assert.Equal(t, 1, released)
+
+	// Other migrations take their own leases.
+	release3, err := HoldMigrationLease("HB-1", acquire)
+	assert.Nil(t, err)
+	_, err = HoldMigrationLease("HB-2", func() (func(), error) { return nil, fmt.Errorf("locked") })
+	assert.EqualError(t, err, "locked")
+	release3()
+	assert.Equal(t, 2, acquired)
+	assert.Equal(t, 2, released)
+}
@@ -44,7 +44,7 @@ func GetSpannerTable(conv *Conv, srcTable string) (string, error) {
 	if sp, found := conv.ToSpanner[srcTable]; found {
 		return sp.Name, nil
 	}
-	spTable := getSpannerID(conv, srcTable)
+	spTable := getSpannerID(conv, conv.ShardSpannerName(srcTable, srcTable))
 	if spTable != srcTable {
 		VerbosePrintf("Mapping source DB table %s to Spanner table %s\n", srcTable, spTable)
 		logger.Log.Debug(fmt.Sprintf("Mapping source DB table %s to Spanner table %s\n", srcTable, spTable))
@@ -57,11 +57,18 @@ func GetSpannerTable(conv *Conv, srcTable string) (string, error) {
 }
 
 // GetSourceTable maps a spanner table name into a legal source DB table
-// name.
+// name. Tables of the parts of a split table, and nested tables, map to the
+// source table of the split table or of the table they're nested in.
 func GetSourceTable(conv *Conv, spTable string) (string, error) {
 	if spTable == "" {
 		return "", fmt.Errorf("bad parameter: table string is empty")
 	}
+	if t, ok := conv.SplitOf(spTable); ok {
+		spTable = t
+	}
+	if t, ok := conv.NestedOf(spTable); ok {
+		spTable = t
+	}
 
 	if srcTable, found := conv.ToSource[spTable]; found {
 		return srcTable.Name, nil
@@ -0,0 +1,52 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+)
+
+// Handling of source materialized views, which Spanner doesn't support.
+const (
+	MaterializedViewSkip  = "skip"  // Report the view, but don't migrate it. This is the default.
+	MaterializedViewTable = "table" // Convert the view into a table, populated once during data migration.
+)
+
+// MaterializedView is a materialized view of the source database.
+type MaterializedView struct {
+	Name       string // Source-DB name, as used for source tables.
+	Definition string // Query of the view, in the source DB's SQL dialect.
+	Table      bool   // Whether the view was converted into a table.
+}
+
+// ValidateMaterializedViews checks that policy is a valid handling of
+// materialized views.
+func ValidateMaterializedViews(policy string) error {
+	switch policy {
+	case "", MaterializedViewSkip, MaterializedViewTable:
+		return nil
+	}
+	return fmt.Errorf("invalid materialized view handling %s, accepted values are: skip, table", policy)
+}
+
+// AddMaterializedView records materialized view name of the source database
+// with query definition. table is true if the view was converted into a
+// table.
+func (conv *Conv) AddMaterializedView(name, definition string, table bool) {
+	if conv.MaterializedViews == nil {
+		conv.MaterializedViews = make(map[string]MaterializedView)
+	}
+	conv.MaterializedViews[name] = MaterializedView{Name: name, Definition: definition, Table: table}
+}
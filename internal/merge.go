@@ -0,0 +1,204 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// DefaultMergeColumn is the default name of the column recording the source
+// table of each row of a merged table.
+const DefaultMergeColumn = "merge_key"
+
+// MergeRule merges the structurally identical source tables whose names
+// match Pattern, such as per-tenant tables t_0001 ... t_0999, into a single
+// table. Pattern contains a single '*', which matches any part of a name:
+// the matched part (e.g. 0001) is the value of column Column of the rows of
+// the table, which is added as the first primary key column so that rows
+// with the same key in different tables don't collide. The merged table is
+// named after the pattern without the '*', and separators next to it, e.g.
+// "t_*" merges tables into table t.
+type MergeRule struct {
+	Pattern string
+	Column  string
+}
+
+// NewMergeRule returns the rule merging the source tables matching pattern,
+// recording their names in column (DefaultMergeColumn if empty).
+func NewMergeRule(pattern, column string) (MergeRule, error) {
+	if column == "" {
+		column = DefaultMergeColumn
+	}
+	r := MergeRule{Pattern: pattern, Column: column}
+	if strings.Count(pattern, "*") != 1 {
+		return r, fmt.Errorf("table merge pattern '%s' must contain exactly one '*'", pattern)
+	}
+	if r.Table() == "" {
+		return r, fmt.Errorf("table merge pattern '%s' must contain a table name besides '*'", pattern)
+	}
+	if fixed, _ := FixName(column); fixed != column {
+		return r, fmt.Errorf("'%s' is not a valid Spanner column name", column)
+	}
+	return r, nil
+}
+
+// Table returns the name of the source table that r merges tables into.
+func (r MergeRule) Table() string {
+	i := strings.Index(r.Pattern, "*")
+	prefix := strings.TrimRight(r.Pattern[:i], "_-.")
+	suffix := strings.TrimLeft(r.Pattern[i+1:], "_-.")
+	if prefix != "" && suffix != "" {
+		return prefix + "_" + suffix
+	}
+	return prefix + suffix
+}
+
+// Match returns the part of source table name matched by the '*' of the
+// pattern of r, and false if name doesn't match it.
+func (r MergeRule) Match(name string) (string, bool) {
+	i := strings.Index(r.Pattern, "*")
+	prefix, suffix := r.Pattern[:i], r.Pattern[i+1:]
+	if len(name) <= len(prefix)+len(suffix) || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return "", false
+	}
+	return name[len(prefix) : len(name)-len(suffix)], true
+}
+
+// TableMerge describes the source tables merged into a source table of
+// conv.SrcSchema by a MergeRule. conv.SrcSchema only holds the merged table,
+// whose schema is that of the first table.
+type TableMerge struct {
+	Pattern string
+	Column  string        // Name of the Spanner column recording the source table of each row.
+	Members []MergeMember // Tables merged, in the order their data is migrated.
+}
+
+// MergeMember is a source table merged into another.
+type MergeMember struct {
+	Name   string // Name of the table, as named in conv.SrcSchema if it weren't merged.
+	Schema string // Schema of the table in the source database.
+	Table  string // Name of the table in the source database.
+	Key    string // Value of the merge column for the rows of the table.
+}
+
+// MergeTable returns the rule of conv.MergeRules that merges source table
+// name, and the value of its merge column. It returns false if name isn't
+// merged. Rules are tried in order.
+func (conv *Conv) MergeTable(name string) (MergeRule, string, bool) {
+	for _, r := range conv.MergeRules {
+		if key, ok := r.Match(name); ok {
+			return r, key, true
+		}
+	}
+	return MergeRule{}, "", false
+}
+
+// AddMergeMember records that source table m.Name is merged into source table
+// table by rule r.
+func (conv *Conv) AddMergeMember(table string, r MergeRule, m MergeMember) {
+	if conv.TableMerges == nil {
+		conv.TableMerges = make(map[string]TableMerge)
+	}
+	tm := conv.TableMerges[table]
+	tm.Pattern, tm.Column = r.Pattern, r.Column
+	tm.Members = append(tm.Members, m)
+	sort.Slice(tm.Members, func(i, j int) bool { return tm.Members[i].Name < tm.Members[j].Name })
+	conv.TableMerges[table] = tm
+}
+
+// MergedTable returns the name in conv.SrcSchema of source table name: the
+// table it is merged into, if any, and name otherwise.
+func (conv *Conv) MergedTable(name string) string {
+	for t, tm := range conv.TableMerges {
+		for _, m := range tm.Members {
+			if m.Name == name {
+				return t
+			}
+		}
+	}
+	return name
+}
+
+// AddMergeColumns adds the merge column of every merged table to its Spanner
+// table, as the first primary key column. Like the shard id column (see
+// AddShardIdColumn), the column is also added to unique indexes, and to both
+// sides of foreign keys between merged tables with the same merge column.
+func (conv *Conv) AddMergeColumns() error {
+	spColumn := make(map[string]string)
+	for srcTable, tm := range conv.TableMerges {
+		ct, ok := conv.SpSchema[conv.ToSpanner[srcTable].Name]
+		if !ok {
+			continue
+		}
+		for c := range ct.ColDefs {
+			if strings.EqualFold(c, tm.Column) {
+				return fmt.Errorf("merged table %s already has a column named %s", srcTable, c)
+			}
+		}
+		spColumn[ct.Name] = tm.Column
+	}
+	for t, col := range spColumn {
+		ct := conv.SpSchema[t]
+		ct.ColNames = append([]string{col}, ct.ColNames...)
+		ct.ColDefs[col] = ddl.ColumnDef{Name: col, T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, NotNull: true}
+		ct.Pks = append([]ddl.IndexKey{{Col: col}}, ct.Pks...)
+		for i, idx := range ct.Indexes {
+			if idx.Unique {
+				ct.Indexes[i].Keys = append([]ddl.IndexKey{{Col: col}}, idx.Keys...)
+			}
+		}
+		for i, fk := range ct.Fks {
+			if spColumn[fk.ReferTable] == col {
+				ct.Fks[i].Columns = append([]string{col}, fk.Columns...)
+				ct.Fks[i].ReferColumns = append([]string{col}, fk.ReferColumns...)
+			}
+		}
+		conv.SpSchema[t] = ct
+	}
+	return nil
+}
+
+// SetMergeMember configures conv to migrate the data of member i of merged
+// source table srcTable.
+func (conv *Conv) SetMergeMember(srcTable string, i int) {
+	if conv.mergeMembers == nil {
+		conv.mergeMembers = make(map[string]int)
+	}
+	conv.mergeMembers[srcTable] = i
+}
+
+// MergeMember returns the table being migrated of merged source table
+// srcTable, and false if srcTable isn't merged.
+func (conv *Conv) MergeMember(srcTable string) (MergeMember, bool) {
+	tm, ok := conv.TableMerges[srcTable]
+	if !ok || len(tm.Members) == 0 {
+		return MergeMember{}, false
+	}
+	return tm.Members[conv.mergeMembers[srcTable]], true
+}
+
+// mergeRow adds the merge column of merged source table srcTable, set to the
+// key of the table being migrated, to a row.
+func (conv *Conv) mergeRow(srcTable string, cols []string, vals []interface{}) ([]string, []interface{}) {
+	m, ok := conv.MergeMember(srcTable)
+	if !ok {
+		return cols, vals
+	}
+	return append([]string{conv.TableMerges[srcTable].Column}, cols...), append([]interface{}{m.Key}, vals...)
+}
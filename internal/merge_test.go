@@ -0,0 +1,137 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeRule(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		table   string
+		name    string
+		key     string
+		ok      bool
+	}{
+		{pattern: "t_*", table: "t", name: "t_0001", key: "0001", ok: true},
+		{pattern: "t_*", table: "t", name: "t_", ok: false},
+		{pattern: "t_*", table: "t", name: "u_0001", ok: false},
+		{pattern: "*_orders", table: "orders", name: "eu_orders", key: "eu", ok: true},
+		{pattern: "tenant*_orders", table: "tenant_orders", name: "tenant7_orders", key: "7", ok: true},
+		{pattern: "sales.t_*", table: "sales.t", name: "sales.t_1", key: "1", ok: true},
+	} {
+		r, err := NewMergeRule(tc.pattern, "")
+		assert.Nil(t, err, tc.pattern)
+		assert.Equal(t, DefaultMergeColumn, r.Column)
+		assert.Equal(t, tc.table, r.Table(), tc.pattern)
+		key, ok := r.Match(tc.name)
+		assert.Equal(t, tc.ok, ok, tc.name)
+		assert.Equal(t, tc.key, key, tc.name)
+	}
+	for _, tc := range []struct{ pattern, column string }{{"t_0001", ""}, {"t_*_*", ""}, {"_*", ""}, {"t_*", "1tenant"}} {
+		_, err := NewMergeRule(tc.pattern, tc.column)
+		assert.NotNil(t, err, tc.pattern)
+	}
+}
+
+func buildMergeConv() *Conv {
+	conv := MakeConv()
+	conv.ToSpanner = map[string]NameAndCols{"t": {Name: "t"}, "u": {Name: "u"}, "v": {Name: "v"}}
+	conv.ToSource = map[string]NameAndCols{"t": {Name: "t"}, "u": {Name: "u"}, "v": {Name: "v"}}
+	conv.SpSchema = map[string]ddl.CreateTable{
+		"t": {
+			Name:     "t",
+			ColNames: []string{"id", "u_id", "v_id"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":   {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"u_id": {Name: "u_id", T: ddl.Type{Name: ddl.Int64}},
+				"v_id": {Name: "v_id", T: ddl.Type{Name: ddl.Int64}},
+			},
+			Pks:     []ddl.IndexKey{{Col: "id"}},
+			Indexes: []ddl.CreateIndex{{Name: "t_u", Table: "t", Unique: true, Keys: []ddl.IndexKey{{Col: "u_id"}}}, {Name: "t_v", Table: "t", Keys: []ddl.IndexKey{{Col: "v_id"}}}},
+			Fks: []ddl.Foreignkey{
+				{Name: "t_u_fk", Columns: []string{"u_id"}, ReferTable: "u", ReferColumns: []string{"id"}},
+				{Name: "t_v_fk", Columns: []string{"v_id"}, ReferTable: "v", ReferColumns: []string{"id"}},
+			},
+		},
+		"u": {
+			Name:     "u",
+			ColNames: []string{"id"},
+			ColDefs:  map[string]ddl.ColumnDef{"id": {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true}},
+			Pks:      []ddl.IndexKey{{Col: "id"}},
+		},
+		"v": {
+			Name:     "v",
+			ColNames: []string{"id"},
+			ColDefs:  map[string]ddl.ColumnDef{"id": {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true}},
+			Pks:      []ddl.IndexKey{{Col: "id"}},
+		},
+	}
+	t := MergeRule{Pattern: "t_*", Column: "tenant"}
+	u := MergeRule{Pattern: "u_*", Column: "tenant"}
+	conv.AddMergeMember("t", t, MergeMember{Name: "t_2", Table: "t_2", Key: "2"})
+	conv.AddMergeMember("t", t, MergeMember{Name: "t_1", Table: "t_1", Key: "1"})
+	conv.AddMergeMember("u", u, MergeMember{Name: "u_1", Table: "u_1", Key: "1"})
+	return conv
+}
+
+func TestAddMergeColumns(t *testing.T) {
+	conv := buildMergeConv()
+	assert.Equal(t, []MergeMember{{Name: "t_1", Table: "t_1", Key: "1"}, {Name: "t_2", Table: "t_2", Key: "2"}}, conv.TableMerges["t"].Members)
+	assert.Equal(t, "t", conv.MergedTable("t_2"))
+	assert.Equal(t, "v", conv.MergedTable("v"))
+
+	assert.Nil(t, conv.AddMergeColumns())
+	ct := conv.SpSchema["t"]
+	assert.Equal(t, []string{"tenant", "id", "u_id", "v_id"}, ct.ColNames)
+	assert.Equal(t, ddl.ColumnDef{Name: "tenant", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, NotNull: true}, ct.ColDefs["tenant"])
+	assert.Equal(t, []ddl.IndexKey{{Col: "tenant"}, {Col: "id"}}, ct.Pks)
+	assert.Equal(t, []ddl.IndexKey{{Col: "tenant"}, {Col: "u_id"}}, ct.Indexes[0].Keys)
+	assert.Equal(t, []ddl.IndexKey{{Col: "v_id"}}, ct.Indexes[1].Keys)
+	// Only references to tables merged with the same column get the column.
+	assert.Equal(t, ddl.Foreignkey{Name: "t_u_fk", Columns: []string{"tenant", "u_id"}, ReferTable: "u", ReferColumns: []string{"tenant", "id"}}, ct.Fks[0])
+	assert.Equal(t, ddl.Foreignkey{Name: "t_v_fk", Columns: []string{"v_id"}, ReferTable: "v", ReferColumns: []string{"id"}}, ct.Fks[1])
+	assert.Equal(t, []string{"id"}, conv.SpSchema["v"].ColNames)
+
+	// The merge column can't collide with columns of the table.
+	conv = buildMergeConv()
+	tm := conv.TableMerges["t"]
+	tm.Column = "u_id"
+	conv.TableMerges["t"] = tm
+	assert.NotNil(t, conv.AddMergeColumns())
+}
+
+func TestWriteRowMerged(t *testing.T) {
+	conv := buildMergeConv()
+	var rows [][]interface{}
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		assert.Equal(t, []string{"tenant", "id"}, cols)
+		rows = append(rows, vals)
+	})
+	conv.SetDataMode()
+	m, ok := conv.MergeMember("t")
+	assert.True(t, ok)
+	assert.Equal(t, "t_1", m.Table)
+	conv.WriteRow("t", "t", []string{"id"}, []interface{}{int64(1)})
+	conv.SetMergeMember("t", 1)
+	conv.WriteRow("t", "t", []string{"id"}, []interface{}{int64(1)})
+	assert.Equal(t, [][]interface{}{{"1", int64(1)}, {"2", int64(1)}}, rows)
+	_, ok = conv.MergeMember("v")
+	assert.False(t, ok)
+}
@@ -0,0 +1,366 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// Source types of the columns that can be nested: the List and Map types of
+// DynamoDB, whose values are converted to JSON strings.
+const (
+	nestedList = "List"
+	nestedMap  = "Map"
+)
+
+// NestedTable moves the elements of a List or Map column of a Spanner table
+// (e.g. a List or Map attribute of a DynamoDB table) to a child table
+// interleaved in the table, with a row for each element, so that elements
+// can be queried and indexed. The primary key of the child table is the
+// primary key of the table followed by the position (List) or key (Map) of
+// elements.
+//
+// Like for TableSplit, conv.SpSchema keeps the column, which data conversion
+// reads: nested tables are added to the schema created in Spanner, without
+// the column in the table (see TargetSchema), and the elements of the column
+// are written to them (see NestRow).
+type NestedTable struct {
+	Table    string // Name of the Spanner child table.
+	Col      string // Column of the table holding the List or Map.
+	KeyCol   string // Column of the child table with the position (INT64) or key (STRING) of elements.
+	ValueCol string // Column of the child table with the elements, as JSON strings.
+	Map      bool   // If true, Col holds a Map rather than a List.
+}
+
+// NestedRows are the rows of a nested table for a row of its table.
+type NestedRows struct {
+	Table string
+	Key   []interface{} // Primary key of the row of the table, which the keys of the rows start with.
+	Cols  []string
+	Vals  [][]interface{}
+}
+
+// NestColumns moves the elements of List and Map columns of Spanner table
+// spTable to the nested tables of nested. Any existing nested tables of the
+// table are replaced, and empty nested removes them. KeyCol and ValueCol
+// default to "key" and "value". Nested tables need legal, unused names, and
+// their columns can't be key columns or be used by secondary indexes and
+// foreign keys.
+func (conv *Conv) NestColumns(spTable string, nested []NestedTable) error {
+	ct, ok := conv.SpSchema[spTable]
+	if !ok {
+		return fmt.Errorf("table %s not found", spTable)
+	}
+	if _, ok := conv.DdlEdits[spTable]; ok {
+		return fmt.Errorf("table %s has manual DDL edits: remove them before nesting its columns", spTable)
+	}
+	if _, ok := conv.TableSplits[spTable]; ok && len(nested) > 0 {
+		return fmt.Errorf("table %s is split: remove the split before nesting its columns", spTable)
+	}
+	old := conv.NestedTables[spTable]
+	conv.ClearNestedTables(spTable)
+	if len(nested) == 0 {
+		return nil
+	}
+	var tables []NestedTable
+	err := func() error {
+		names := make(map[string]bool)
+		cols := make(map[string]bool)
+		for _, n := range nested {
+			if n.KeyCol == "" {
+				n.KeyCol = "key"
+			}
+			if n.ValueCol == "" {
+				n.ValueCol = "value"
+			}
+			if err := conv.checkNestedTable(ct, n, names); err != nil {
+				return err
+			}
+			if cols[n.Col] {
+				return fmt.Errorf("column %s of table %s is nested twice", n.Col, spTable)
+			}
+			cols[n.Col] = true
+			n.Map = conv.nestedType(spTable, n.Col) == nestedMap
+			tables = append(tables, n)
+		}
+		return nil
+	}()
+	if err != nil {
+		if len(old) > 0 {
+			conv.addNestedTables(spTable, old)
+		}
+		return err
+	}
+	conv.addNestedTables(spTable, tables)
+	return nil
+}
+
+// nestedType returns the source type of column col of Spanner table spTable.
+func (conv *Conv) nestedType(spTable, col string) string {
+	srcTable := conv.ToSource[spTable]
+	return conv.SrcSchema[srcTable.Name].ColDefs[srcTable.Cols[col]].Type.Name
+}
+
+// checkNestedTable checks that nested table n of table ct is legal. names
+// are the lower case names of the other nested tables of ct.
+func (conv *Conv) checkNestedTable(ct ddl.CreateTable, n NestedTable, names map[string]bool) error {
+	if fixed, _ := FixName(n.Table); fixed != n.Table {
+		return fmt.Errorf("invalid name %s for a nested table of table %s: expected letters, digits and '_', starting with a letter", n.Table, ct.Name)
+	}
+	name := strings.ToLower(n.Table)
+	if _, ok := conv.SpSchema[n.Table]; ok || conv.UsedNames[name] || names[name] {
+		return fmt.Errorf("name %s of a nested table of table %s is already used", n.Table, ct.Name)
+	}
+	names[name] = true
+	cd, ok := ct.ColDefs[n.Col]
+	if !ok {
+		return fmt.Errorf("column %s not found in table %s", n.Col, ct.Name)
+	}
+	if t := conv.nestedType(ct.Name, n.Col); (t != nestedList && t != nestedMap) || cd.T.Name != ddl.String || cd.T.IsArray {
+		return fmt.Errorf("column %s of table %s can't be nested: only List and Map columns converted to STRING can", n.Col, ct.Name)
+	}
+	keys := make(map[string]bool)
+	for _, pk := range ct.Pks {
+		if pk.Col == n.Col {
+			return fmt.Errorf("column %s is part of the primary key of table %s", n.Col, ct.Name)
+		}
+		keys[strings.ToLower(pk.Col)] = true
+	}
+	for _, c := range []string{n.KeyCol, n.ValueCol} {
+		if fixed, _ := FixName(c); fixed != c {
+			return fmt.Errorf("invalid column name %s for nested table %s: expected letters, digits and '_', starting with a letter", c, n.Table)
+		}
+		if keys[strings.ToLower(c)] {
+			return fmt.Errorf("column %s of nested table %s is a key column of table %s", c, n.Table, ct.Name)
+		}
+	}
+	if strings.EqualFold(n.KeyCol, n.ValueCol) {
+		return fmt.Errorf("nested table %s has two columns %s", n.Table, n.KeyCol)
+	}
+	for _, index := range ct.Indexes {
+		cols := append([]string{}, index.StoredColumns...)
+		for _, k := range index.Keys {
+			cols = append(cols, k.Col)
+		}
+		for _, c := range cols {
+			if c == n.Col {
+				return fmt.Errorf("column %s of table %s is used by index %s", n.Col, ct.Name, index.Name)
+			}
+		}
+	}
+	for _, fk := range ct.Fks {
+		for _, c := range fk.Columns {
+			if c == n.Col {
+				return fmt.Errorf("column %s of table %s is used by foreign key %s", n.Col, ct.Name, fk.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// addNestedTables records nested as the nested tables of spTable, reserving
+// their names.
+func (conv *Conv) addNestedTables(spTable string, nested []NestedTable) {
+	for _, n := range nested {
+		conv.UsedNames[strings.ToLower(n.Table)] = true
+	}
+	if conv.NestedTables == nil {
+		conv.NestedTables = make(map[string][]NestedTable)
+	}
+	conv.NestedTables[spTable] = nested
+}
+
+// ClearNestedTables removes the nested tables of Spanner table spTable (if
+// any), moving their columns back to the table.
+func (conv *Conv) ClearNestedTables(spTable string) {
+	for _, n := range conv.NestedTables[spTable] {
+		delete(conv.UsedNames, strings.ToLower(n.Table))
+	}
+	delete(conv.NestedTables, spTable)
+}
+
+// NestedOf returns the table that nested table spTable is nested in, if it is
+// a nested table.
+func (conv *Conv) NestedOf(spTable string) (string, bool) {
+	for t, nested := range conv.NestedTables {
+		for _, n := range nested {
+			if n.Table == spTable {
+				return t, true
+			}
+		}
+	}
+	return "", false
+}
+
+// CheckNestedTables checks that the nested tables of conv still apply to the
+// Spanner schema, which may have changed since the columns were nested (see
+// NestColumns).
+func (conv *Conv) CheckNestedTables() error {
+	for t, nested := range conv.NestedTables {
+		ct, ok := conv.SpSchema[t]
+		if !ok {
+			return fmt.Errorf("table %s of nested tables not found", t)
+		}
+		names := make(map[string]bool)
+		for _, n := range nested {
+			// Names of nested tables are reserved.
+			delete(conv.UsedNames, strings.ToLower(n.Table))
+			err := conv.checkNestedTable(ct, n, names)
+			conv.UsedNames[strings.ToLower(n.Table)] = true
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addNestedTablesTo replaces the tables of schema s with nested tables by
+// the tables without their nested columns, and adds the nested tables.
+func (conv *Conv) addNestedTablesTo(s ddl.Schema) {
+	for t, nested := range conv.NestedTables {
+		ct, ok := s[t]
+		if !ok {
+			continue
+		}
+		moved := make(map[string]bool)
+		for _, n := range nested {
+			moved[n.Col] = true
+		}
+		trimmed := ct
+		trimmed.ColNames, trimmed.ColDefs = nil, make(map[string]ddl.ColumnDef)
+		for _, c := range ct.ColNames {
+			if !moved[c] {
+				trimmed.ColNames = append(trimmed.ColNames, c)
+				trimmed.ColDefs[c] = ct.ColDefs[c]
+			}
+		}
+		s[t] = trimmed
+		for _, n := range nested {
+			nt := ddl.CreateTable{Name: n.Table, ColDefs: make(map[string]ddl.ColumnDef), Parent: t, Comment: fmt.Sprintf("Elements of column %s of table %s", n.Col, t)}
+			for _, pk := range ct.Pks {
+				cd := ct.ColDefs[pk.Col]
+				// Key values of nested tables are those of the table.
+				cd.Sequence = nil
+				nt.ColNames = append(nt.ColNames, pk.Col)
+				nt.ColDefs[pk.Col] = cd
+				nt.Pks = append(nt.Pks, ddl.IndexKey{Col: pk.Col, Desc: pk.Desc})
+			}
+			keyType := ddl.Type{Name: ddl.Int64}
+			if n.Map {
+				keyType = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
+			}
+			nt.ColNames = append(nt.ColNames, n.KeyCol, n.ValueCol)
+			nt.ColDefs[n.KeyCol] = ddl.ColumnDef{Name: n.KeyCol, T: keyType, NotNull: true}
+			nt.ColDefs[n.ValueCol] = ddl.ColumnDef{Name: n.ValueCol, T: ct.ColDefs[n.Col].T}
+			nt.Pks = append(nt.Pks, ddl.IndexKey{Col: n.KeyCol})
+			s[n.Table] = nt
+		}
+	}
+}
+
+// NestRow splits a row of Spanner table spTable into the row of the table,
+// without its nested columns, and the rows of its nested tables: one per
+// element of the List or Map of each nested column, the elements of Maps
+// being sorted by key. It fails if the value of a nested column isn't the
+// JSON encoding of a List or Map, or if the primary key of the row is
+// missing, in which case no row should be written.
+func (conv *Conv) NestRow(spTable string, cols []string, vals []interface{}) ([]string, []interface{}, []NestedRows, error) {
+	nested := conv.NestedTables[spTable]
+	if len(nested) == 0 {
+		return cols, vals, nil, nil
+	}
+	ct := conv.SpSchema[spTable]
+	index := make(map[string]int)
+	for i, c := range cols {
+		index[c] = i
+	}
+	var key []interface{}
+	var keyCols []string
+	for _, pk := range ct.Pks {
+		i, ok := index[pk.Col]
+		if !ok || vals[i] == nil {
+			return nil, nil, nil, fmt.Errorf("value for primary key column %s of table %s missing", pk.Col, spTable)
+		}
+		key = append(key, vals[i])
+		keyCols = append(keyCols, pk.Col)
+	}
+	moved := make(map[string]bool)
+	var rows []NestedRows
+	for _, n := range nested {
+		moved[n.Col] = true
+		r := NestedRows{Table: n.Table, Key: key, Cols: append(append([]string{}, keyCols...), n.KeyCol, n.ValueCol)}
+		i, ok := index[n.Col]
+		if !ok || vals[i] == nil {
+			rows = append(rows, r)
+			continue
+		}
+		s, ok := vals[i].(string)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("value of nested column %s of table %s isn't a string", n.Col, spTable)
+		}
+		elems, err := nestedElements(s, n.Map)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("can't nest column %s of table %s: %v", n.Col, spTable, err)
+		}
+		for _, e := range elems {
+			r.Vals = append(r.Vals, append(append([]interface{}{}, key...), e...))
+		}
+		rows = append(rows, r)
+	}
+	var tableCols []string
+	var tableVals []interface{}
+	for i, c := range cols {
+		if !moved[c] {
+			tableCols = append(tableCols, c)
+			tableVals = append(tableVals, vals[i])
+		}
+	}
+	return tableCols, tableVals, rows, nil
+}
+
+// nestedElements returns the position (List) or key (Map), and the JSON
+// encoding, of the elements of the List or Map encoded in JSON string s.
+func nestedElements(s string, isMap bool) ([][]interface{}, error) {
+	var elems [][]interface{}
+	if isMap {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			return nil, fmt.Errorf("expected a JSON object: %v", err)
+		}
+		var keys []string
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			elems = append(elems, []interface{}{k, string(m[k])})
+		}
+		return elems, nil
+	}
+	var l []json.RawMessage
+	if err := json.Unmarshal([]byte(s), &l); err != nil {
+		return nil, fmt.Errorf("expected a JSON array: %v", err)
+	}
+	for i, e := range l {
+		elems = append(elems, []interface{}{int64(i), string(e)})
+	}
+	return elems, nil
+}
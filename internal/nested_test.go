@@ -0,0 +1,175 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildNestedConv() *Conv {
+	conv := MakeConv()
+	str := ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
+	conv.SpSchema = map[string]ddl.CreateTable{
+		"orders": {
+			Name:     "orders",
+			ColNames: []string{"id", "items", "tags", "note"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":    {Name: "id", T: str, NotNull: true},
+				"items": {Name: "items", T: str},
+				"tags":  {Name: "tags", T: str},
+				"note":  {Name: "note", T: str},
+			},
+			Pks: []ddl.IndexKey{{Col: "id"}},
+		},
+	}
+	conv.SrcSchema = map[string]schema.Table{
+		"Orders": {
+			Name:     "Orders",
+			ColNames: []string{"id", "items", "tags", "note"},
+			ColDefs: map[string]schema.Column{
+				"id":    {Name: "id", Type: schema.Type{Name: "String"}},
+				"items": {Name: "items", Type: schema.Type{Name: "List"}},
+				"tags":  {Name: "tags", Type: schema.Type{Name: "Map"}},
+				"note":  {Name: "note", Type: schema.Type{Name: "String"}},
+			},
+			PrimaryKeys: []schema.Key{{Column: "id"}},
+		},
+	}
+	conv.ToSource["orders"] = NameAndCols{Name: "Orders", Cols: map[string]string{"id": "id", "items": "items", "tags": "tags", "note": "note"}}
+	conv.ToSpanner["Orders"] = NameAndCols{Name: "orders", Cols: map[string]string{"id": "id", "items": "items", "tags": "tags", "note": "note"}}
+	conv.UsedNames = map[string]bool{"orders": true}
+	return conv
+}
+
+func TestNestColumns(t *testing.T) {
+	conv := buildNestedConv()
+	assert.NotNil(t, conv.NestColumns("missing", []NestedTable{{Table: "n", Col: "items"}}))
+	assert.NotNil(t, conv.NestColumns("orders", []NestedTable{{Table: "orders", Col: "items"}}))
+	assert.NotNil(t, conv.NestColumns("orders", []NestedTable{{Table: "1items", Col: "items"}}))
+	assert.NotNil(t, conv.NestColumns("orders", []NestedTable{{Table: "order_items", Col: "note"}}))
+	assert.NotNil(t, conv.NestColumns("orders", []NestedTable{{Table: "order_items", Col: "missing"}}))
+	assert.NotNil(t, conv.NestColumns("orders", []NestedTable{{Table: "order_items", Col: "items", KeyCol: "id"}}))
+	assert.NotNil(t, conv.NestColumns("orders", []NestedTable{{Table: "order_items", Col: "items", KeyCol: "v", ValueCol: "v"}}))
+	assert.NotNil(t, conv.NestColumns("orders", []NestedTable{{Table: "n1", Col: "items"}, {Table: "n2", Col: "items"}}))
+	assert.Empty(t, conv.NestedTables)
+
+	assert.Nil(t, conv.NestColumns("orders", []NestedTable{{Table: "order_items", Col: "items"}, {Table: "order_tags", Col: "tags", KeyCol: "tag"}}))
+	assert.Equal(t, []NestedTable{
+		{Table: "order_items", Col: "items", KeyCol: "key", ValueCol: "value"},
+		{Table: "order_tags", Col: "tags", KeyCol: "tag", ValueCol: "value", Map: true},
+	}, conv.NestedTables["orders"])
+	assert.True(t, conv.UsedNames["order_items"])
+	srcTable, err := GetSourceTable(conv, "order_tags")
+	assert.Nil(t, err)
+	assert.Equal(t, "Orders", srcTable)
+	assert.NotNil(t, conv.SplitTable("orders", []SplitPart{{Table: "orders_note", Cols: []string{"note"}}}, true))
+	assert.NotNil(t, conv.RenameTable("orders", "order_items"))
+
+	// A failed change keeps the nested tables.
+	assert.NotNil(t, conv.NestColumns("orders", []NestedTable{{Table: "order_items", Col: "note"}}))
+	assert.Len(t, conv.NestedTables["orders"], 2)
+	assert.True(t, conv.UsedNames["order_tags"])
+
+	assert.Nil(t, conv.RenameColumn("orders", "items", "lines"))
+	assert.Nil(t, conv.RenameTable("orders", "purchases"))
+	assert.Equal(t, "lines", conv.NestedTables["purchases"][0].Col)
+	assert.Nil(t, conv.CheckNestedTables())
+
+	assert.Nil(t, conv.NestColumns("purchases", nil))
+	assert.Empty(t, conv.NestedTables)
+	assert.False(t, conv.UsedNames["order_items"])
+	assert.False(t, conv.UsedNames["order_tags"])
+}
+
+func TestTargetSchemaNested(t *testing.T) {
+	conv := buildNestedConv()
+	assert.Nil(t, conv.NestColumns("orders", []NestedTable{{Table: "order_items", Col: "items", KeyCol: "position"}, {Table: "order_tags", Col: "tags"}}))
+	s := conv.TargetSchema()
+	assert.Equal(t, []string{"id", "note"}, s["orders"].ColNames)
+	assert.Len(t, s["orders"].ColDefs, 2)
+	str := ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
+	assert.Equal(t, ddl.CreateTable{
+		Name:     "order_items",
+		ColNames: []string{"id", "position", "value"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"id":       {Name: "id", T: str, NotNull: true},
+			"position": {Name: "position", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+			"value":    {Name: "value", T: str},
+		},
+		Pks:     []ddl.IndexKey{{Col: "id"}, {Col: "position"}},
+		Parent:  "orders",
+		Comment: "Elements of column items of table orders",
+	}, s["order_items"])
+	assert.Equal(t, str, s["order_tags"].ColDefs["key"].T)
+	assert.Equal(t, []string{"orders", "order_items", "order_tags"}, ddl.OrderTables(s))
+	// conv.SpSchema is unchanged.
+	assert.Equal(t, buildNestedConv().SpSchema, conv.SpSchema)
+}
+
+func TestNestRow(t *testing.T) {
+	conv := buildNestedConv()
+	assert.Nil(t, conv.NestColumns("orders", []NestedTable{{Table: "order_items", Col: "items"}, {Table: "order_tags", Col: "tags"}}))
+	cols := []string{"id", "items", "tags", "note"}
+	tableCols, tableVals, nested, err := conv.NestRow("orders", cols, []interface{}{"o1", `[{"sku":"a"},"b"]`, `{"z":1,"a":true}`, "n"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "note"}, tableCols)
+	assert.Equal(t, []interface{}{"o1", "n"}, tableVals)
+	assert.Equal(t, []NestedRows{
+		{Table: "order_items", Key: []interface{}{"o1"}, Cols: []string{"id", "key", "value"}, Vals: [][]interface{}{{"o1", int64(0), `{"sku":"a"}`}, {"o1", int64(1), `"b"`}}},
+		{Table: "order_tags", Key: []interface{}{"o1"}, Cols: []string{"id", "key", "value"}, Vals: [][]interface{}{{"o1", "a", "true"}, {"o1", "z", "1"}}},
+	}, nested)
+
+	// Missing Lists and Maps have no elements.
+	_, _, nested, err = conv.NestRow("orders", cols, []interface{}{"o1", nil, nil, nil})
+	assert.Nil(t, err)
+	assert.Empty(t, nested[0].Vals)
+	assert.Empty(t, nested[1].Vals)
+
+	_, _, _, err = conv.NestRow("orders", cols, []interface{}{"o1", `{"a":1}`, nil, nil})
+	assert.NotNil(t, err)
+	_, _, _, err = conv.NestRow("orders", cols, []interface{}{nil, nil, nil, nil})
+	assert.NotNil(t, err)
+}
+
+func TestWriteRowNested(t *testing.T) {
+	conv := buildNestedConv()
+	assert.Nil(t, conv.NestColumns("orders", []NestedTable{{Table: "order_items", Col: "items"}}))
+	type row struct {
+		table string
+		cols  []string
+		vals  []interface{}
+	}
+	var rows []row
+	conv.SetDataSink(func(table string, c []string, v []interface{}) {
+		rows = append(rows, row{table, c, v})
+	})
+	conv.SetDataMode()
+	cols := []string{"id", "items", "tags", "note"}
+	conv.WriteRow("Orders", "orders", cols, []interface{}{"o1", `["a","b"]`, `{}`, "n"})
+	assert.Equal(t, []row{
+		{"orders", []string{"id", "tags", "note"}, []interface{}{"o1", "{}", "n"}},
+		{"order_items", []string{"id", "key", "value"}, []interface{}{"o1", int64(0), `"a"`}},
+		{"order_items", []string{"id", "key", "value"}, []interface{}{"o1", int64(1), `"b"`}},
+	}, rows)
+	assert.Equal(t, int64(1), conv.Stats.GoodRows["Orders"])
+
+	conv.WriteRow("Orders", "orders", cols, []interface{}{"o2", `"a"`, `{}`, "n"})
+	assert.Len(t, rows, 3)
+	assert.Equal(t, int64(1), conv.Stats.BadRows["Orders"])
+}
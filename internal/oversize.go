@@ -0,0 +1,167 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// MaxValueBytes is the maximum size of a Spanner STRING or BYTES value.
+const MaxValueBytes = 10 * 1024 * 1024
+
+// Policies for converted STRING and BYTES values larger than MaxValueBytes,
+// which Spanner rejects. They are common from e.g. MySQL LONGBLOB columns and
+// DynamoDB binary attributes.
+const (
+	OversizeDrop     = "drop"     // Drop the row, and write it to the bad data.
+	OversizeTruncate = "truncate" // Truncate the value to MaxValueBytes.
+	OversizeSpill    = "spill"    // Write the value elsewhere (e.g. GCS) and replace it with its URI.
+)
+
+// OversizePolicies lists the accepted oversize policies.
+var OversizePolicies = []string{OversizeDrop, OversizeTruncate, OversizeSpill}
+
+// Spiller writes oversized value val of column col of Spanner table table
+// outside of Spanner, and returns the URI it was written to.
+type Spiller func(table, col string, val []byte) (string, error)
+
+type oversizeConfig struct {
+	policy string
+	spill  Spiller
+}
+
+// SetOversizePolicy configures how conv handles converted values larger than
+// MaxValueBytes: policy is one of OversizePolicies, and spill must be set for
+// OversizeSpill. By default, rows with such values are dropped.
+func (conv *Conv) SetOversizePolicy(policy string, spill Spiller) error {
+	switch policy {
+	case OversizeDrop, OversizeTruncate:
+	case OversizeSpill:
+		if spill == nil {
+			return fmt.Errorf("oversize policy %s requires a location to spill values to", policy)
+		}
+	default:
+		return fmt.Errorf("invalid oversize policy %s, accepted values are: drop, truncate, spill", policy)
+	}
+	conv.oversize = oversizeConfig{policy: policy, spill: spill}
+	return nil
+}
+
+// OversizePolicy returns the policy for converted values larger than
+// MaxValueBytes.
+func (conv *Conv) OversizePolicy() string {
+	if conv.oversize.policy == "" {
+		return OversizeDrop
+	}
+	return conv.oversize.policy
+}
+
+// checkValueSizes applies the oversize policy to the values vals of columns
+// cols of a row of Spanner table spTable. It returns the values to write, or
+// false if the row must be dropped. vals isn't modified.
+func (conv *Conv) checkValueSizes(srcTable, spTable string, cols []string, vals []interface{}) ([]interface{}, bool) {
+	var v []interface{}
+	for i, val := range vals {
+		var b []byte
+		switch x := val.(type) {
+		case string:
+			if len(x) <= MaxValueBytes {
+				continue
+			}
+			b = []byte(x)
+		case []byte:
+			if len(x) <= MaxValueBytes {
+				continue
+			}
+			b = x
+		default:
+			continue
+		}
+		if v == nil {
+			v = append([]interface{}{}, vals...)
+		}
+		if conv.Stats.Oversized == nil {
+			conv.Stats.Oversized = make(map[string]int64)
+		}
+		conv.Stats.Oversized[spTable]++
+		switch conv.OversizePolicy() {
+		case OversizeTruncate:
+			b = truncateValue(b, val)
+		case OversizeSpill:
+			if conv.Audit.DryRun {
+				continue
+			}
+			uri, err := conv.oversize.spill(spTable, cols[i], b)
+			if err != nil {
+				VerbosePrintf("Can't spill oversized value of column %s of table %s: %v\n", cols[i], spTable, err)
+				conv.Unexpected(fmt.Sprintf("Can't spill oversized values of table %s", spTable))
+				conv.dropOversizedRow(srcTable, spTable, cols, vals)
+				return nil, false
+			}
+			b = []byte(uri)
+		default:
+			conv.dropOversizedRow(srcTable, spTable, cols, vals)
+			return nil, false
+		}
+		if _, ok := val.(string); ok {
+			v[i] = string(b)
+		} else {
+			v[i] = b
+		}
+	}
+	if v == nil {
+		return vals, true
+	}
+	return v, true
+}
+
+// truncateValue truncates b, the bytes of value val, to MaxValueBytes. Strings
+// are truncated at a character boundary.
+func truncateValue(b []byte, val interface{}) []byte {
+	n := MaxValueBytes
+	if _, ok := val.(string); ok {
+		for n > 0 && !utf8.RuneStart(b[n]) {
+			n--
+		}
+	}
+	return b[:n]
+}
+
+// dropOversizedRow reports a row dropped because of an oversized value as a
+// bad row. Oversized values are summarized, to keep the bad data small.
+func (conv *Conv) dropOversizedRow(srcTable, spTable string, cols []string, vals []interface{}) {
+	conv.StatsAddBadRow(srcTable, conv.DataMode())
+	var l []interface{}
+	var s []string
+	for _, val := range vals {
+		switch x := val.(type) {
+		case string:
+			if len(x) > MaxValueBytes {
+				val = fmt.Sprintf("<oversized value of %d bytes>", len(x))
+			}
+		case []byte:
+			if len(x) > MaxValueBytes {
+				val = fmt.Sprintf("<oversized value of %d bytes>", len(x))
+			}
+		}
+		l = append(l, val)
+		s = append(s, fmt.Sprintf("%v", val))
+	}
+	if conv.badData != nil {
+		conv.badData.Write(ConversionError, BadDataRecord{Table: spTable, Cols: cols, Vals: l, Error: fmt.Sprintf("value larger than %d bytes", MaxValueBytes)})
+	}
+	conv.sampleBadRow(&row{table: srcTable, cols: cols, vals: s})
+}
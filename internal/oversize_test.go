@@ -0,0 +1,106 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOversizePolicy(t *testing.T) {
+	conv := MakeConv()
+	assert.Equal(t, OversizeDrop, conv.OversizePolicy())
+	assert.Nil(t, conv.SetOversizePolicy(OversizeTruncate, nil))
+	assert.Equal(t, OversizeTruncate, conv.OversizePolicy())
+	assert.NotNil(t, conv.SetOversizePolicy(OversizeSpill, nil))
+	assert.NotNil(t, conv.SetOversizePolicy("compress", nil))
+	assert.Equal(t, OversizeTruncate, conv.OversizePolicy())
+}
+
+func TestWriteRowOversized(t *testing.T) {
+	big := strings.Repeat("a", MaxValueBytes-1) + "é" // Multi-byte character across the limit.
+	bigBytes := make([]byte, MaxValueBytes+1)
+	cols := []string{"id", "s", "b"}
+	spill := func(table, col string, val []byte) (string, error) {
+		if col == "b" {
+			return "", fmt.Errorf("bucket not found")
+		}
+		return fmt.Sprintf("gs://bucket/%s/%s/%d", table, col, len(val)), nil
+	}
+
+	tc := []struct {
+		name    string
+		policy  string
+		vals    []interface{}
+		want    []interface{} // nil if the row is dropped.
+		badRows int64
+	}{
+		{"Small values", OversizeDrop, []interface{}{int64(1), "x", []byte("y")}, []interface{}{int64(1), "x", []byte("y")}, 0},
+		{"Drop", OversizeDrop, []interface{}{int64(1), big, nil}, nil, 1},
+		{"Truncate string", OversizeTruncate, []interface{}{int64(1), big, nil}, []interface{}{int64(1), big[:MaxValueBytes-1], nil}, 0},
+		{"Truncate bytes", OversizeTruncate, []interface{}{int64(1), nil, bigBytes}, []interface{}{int64(1), nil, bigBytes[:MaxValueBytes]}, 0},
+		{"Spill", OversizeSpill, []interface{}{int64(1), big, nil}, []interface{}{int64(1), fmt.Sprintf("gs://bucket/t1/s/%d", len(big)), nil}, 0},
+		{"Spill error", OversizeSpill, []interface{}{int64(1), "x", bigBytes}, nil, 1},
+	}
+	for _, tc := range tc {
+		conv := MakeConv()
+		conv.SetDataMode()
+		dir := t.TempDir()
+		bw, err := NewBadDataWriter(dir)
+		assert.Nil(t, err)
+		conv.SetBadDataWriter(bw)
+		assert.Nil(t, conv.SetOversizePolicy(tc.policy, spill), tc.name)
+		var got []interface{}
+		conv.SetDataSink(func(table string, cols []string, vals []interface{}) { got = vals })
+		vals := append([]interface{}{}, tc.vals...)
+		conv.WriteRow("t1", "t1", cols, vals)
+		assert.Equal(t, tc.vals, vals, tc.name) // Values aren't modified.
+		assert.Equal(t, tc.want, got, tc.name)
+		assert.Equal(t, tc.badRows, conv.BadRows(), tc.name)
+		if got != nil {
+			if s, ok := got[1].(string); ok {
+				assert.True(t, utf8.ValidString(s), tc.name)
+			}
+		}
+		if tc.badRows > 0 {
+			assert.Nil(t, bw.Close())
+			b, err := os.ReadFile(filepath.Join(dir, "t1", "conversion.jsonl"))
+			assert.Nil(t, err, tc.name)
+			assert.Contains(t, string(b), "oversized value of", tc.name)
+			assert.Less(t, len(b), 1000, tc.name)
+		}
+	}
+}
+
+func TestWriteRowOversizedDryRun(t *testing.T) {
+	conv := MakeConv()
+	conv.SetDataMode()
+	conv.Audit.DryRun = true
+	spilled := false
+	assert.Nil(t, conv.SetOversizePolicy(OversizeSpill, func(table, col string, val []byte) (string, error) {
+		spilled = true
+		return "", nil
+	}))
+	conv.WriteRow("t1", "t1", []string{"id", "s"}, []interface{}{int64(1), strings.Repeat("a", MaxValueBytes+1)})
+	assert.False(t, spilled)
+	assert.Equal(t, map[string]int64{"t1": 1}, conv.Stats.Oversized)
+	assert.Equal(t, int64(0), conv.BadRows())
+}
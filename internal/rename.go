@@ -0,0 +1,248 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Spanner tables and columns have ids (see ddl.CreateTable.Id and
+// ddl.ColumnDef.Id) that don't change when they are renamed. The functions
+// below look up the current names of tables and columns from their ids, and
+// rename tables and columns while keeping all conversion state keyed by
+// Spanner names (mappings to the source schema, synthetic and unique keys,
+// key strategies, manual DDL edits, indexes and foreign keys) consistent.
+// State keyed by source names (e.g. Issues and IssueReviews) isn't affected
+// by renames.
+
+// SpTableName returns the name of the Spanner table with id tableId.
+func (conv *Conv) SpTableName(tableId string) (string, bool) {
+	if tableId == "" {
+		return "", false
+	}
+	for name, t := range conv.SpSchema {
+		if t.Id == tableId {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// SpColName returns the name of the column with id colId of the Spanner
+// table with id tableId, and the name of the table.
+func (conv *Conv) SpColName(tableId, colId string) (string, string, bool) {
+	table, found := conv.SpTableName(tableId)
+	if !found || colId == "" {
+		return "", "", false
+	}
+	for name, c := range conv.SpSchema[table].ColDefs {
+		if c.Id == colId {
+			return table, name, true
+		}
+	}
+	return "", "", false
+}
+
+// RenameTable renames Spanner table table to newName. The caller is
+// responsible for checking that newName is a legal Spanner name.
+func (conv *Conv) RenameTable(table, newName string) error {
+	sp, found := conv.SpSchema[table]
+	if !found {
+		return fmt.Errorf("table %s not found", table)
+	}
+	if newName == table {
+		return nil
+	}
+	if _, found := conv.SpSchema[newName]; found {
+		return fmt.Errorf("new name %s is used by another table", newName)
+	}
+	if _, found := conv.SplitOf(newName); found {
+		return fmt.Errorf("new name %s is used by a part of a split table", newName)
+	}
+	if _, found := conv.NestedOf(newName); found {
+		return fmt.Errorf("new name %s is used by a nested table", newName)
+	}
+	sp.Name = newName
+	for i := range sp.Indexes {
+		sp.Indexes[i].Table = newName
+	}
+	delete(conv.SpSchema, table)
+	conv.SpSchema[newName] = sp
+	for name, t := range conv.SpSchema {
+		changed := false
+		if t.Parent == table {
+			t.Parent, changed = newName, true
+		}
+		for i, fk := range t.Fks {
+			if fk.ReferTable == table {
+				t.Fks[i].ReferTable, changed = newName, true
+			}
+		}
+		if changed {
+			conv.SpSchema[name] = t
+		}
+	}
+
+	if nc, found := conv.ToSource[table]; found {
+		delete(conv.ToSource, table)
+		conv.ToSource[newName] = nc
+		if src, found := conv.ToSpanner[nc.Name]; found {
+			src.Name = newName
+			conv.ToSpanner[nc.Name] = src
+		}
+	}
+	if fi, found := conv.Audit.ToSourceFkIdx[table]; found {
+		delete(conv.Audit.ToSourceFkIdx, table)
+		conv.Audit.ToSourceFkIdx[newName] = fi
+		if src, found := conv.Audit.ToSpannerFkIdx[fi.Name]; found {
+			src.Name = newName
+			conv.Audit.ToSpannerFkIdx[fi.Name] = src
+		}
+	}
+	if conv.UsedNames != nil {
+		delete(conv.UsedNames, strings.ToLower(table))
+		conv.UsedNames[strings.ToLower(newName)] = true
+	}
+	if k, found := conv.SyntheticPKeys[table]; found {
+		delete(conv.SyntheticPKeys, table)
+		conv.SyntheticPKeys[newName] = k
+	}
+	if k, found := conv.UniquePKey[table]; found {
+		delete(conv.UniquePKey, table)
+		conv.UniquePKey[newName] = k
+	}
+	if s, found := conv.KeyStrategies[table]; found {
+		delete(conv.KeyStrategies, table)
+		conv.KeyStrategies[newName] = s
+	}
+	// Original types of the columns holding keys of other tables.
+	for _, s := range conv.KeyStrategies {
+		if types, found := s.Types[table]; found {
+			delete(s.Types, table)
+			s.Types[newName] = types
+		}
+	}
+	if s, found := conv.TableSplits[table]; found {
+		delete(conv.TableSplits, table)
+		conv.TableSplits[newName] = s
+	}
+	if n, found := conv.NestedTables[table]; found {
+		delete(conv.NestedTables, table)
+		conv.NestedTables[newName] = n
+	}
+	// Manual edits keep their statements, which now refer to the old name:
+	// they are reported as stale since the generated DDL changed.
+	if e, found := conv.DdlEdits[table]; found {
+		delete(conv.DdlEdits, table)
+		conv.DdlEdits[newName] = e
+	}
+	conv.keyRewrites = nil
+	return nil
+}
+
+// RenameColumn renames column col of Spanner table table to newName. The
+// caller is responsible for checking that newName is a legal Spanner name.
+func (conv *Conv) RenameColumn(table, col, newName string) error {
+	sp, found := conv.SpSchema[table]
+	if !found {
+		return fmt.Errorf("table %s not found", table)
+	}
+	cd, found := sp.ColDefs[col]
+	if !found {
+		return fmt.Errorf("column %s not found in table %s", col, table)
+	}
+	if newName == col {
+		return nil
+	}
+	if _, found := sp.ColDefs[newName]; found {
+		return fmt.Errorf("new name %s is used by another column of table %s", newName, table)
+	}
+	rename := func(s []string) {
+		for i := range s {
+			if s[i] == col {
+				s[i] = newName
+			}
+		}
+	}
+	rename(sp.ColNames)
+	cd.Name = newName
+	delete(sp.ColDefs, col)
+	sp.ColDefs[newName] = cd
+	for i := range sp.Pks {
+		if sp.Pks[i].Col == col {
+			sp.Pks[i].Col = newName
+		}
+	}
+	for _, index := range sp.Indexes {
+		for i := range index.Keys {
+			if index.Keys[i].Col == col {
+				index.Keys[i].Col = newName
+			}
+		}
+		rename(index.StoredColumns)
+	}
+	for _, fk := range sp.Fks {
+		rename(fk.Columns)
+	}
+	conv.SpSchema[table] = sp
+	for _, t := range conv.SpSchema {
+		for _, fk := range t.Fks {
+			if fk.ReferTable == table {
+				rename(fk.ReferColumns)
+			}
+		}
+	}
+
+	if nc, found := conv.ToSource[table]; found {
+		if srcCol, found := nc.Cols[col]; found {
+			delete(nc.Cols, col)
+			nc.Cols[newName] = srcCol
+			if src, found := conv.ToSpanner[nc.Name]; found {
+				src.Cols[srcCol] = newName
+			}
+		}
+	}
+	if k, found := conv.SyntheticPKeys[table]; found && k.Col == col {
+		k.Col = newName
+		conv.SyntheticPKeys[table] = k
+	}
+	rename(conv.UniquePKey[table])
+	if s, found := conv.KeyStrategies[table]; found {
+		if s.Col == col {
+			s.Col = newName
+		}
+		if s.ShardCol == col {
+			s.ShardCol = newName
+		}
+		conv.KeyStrategies[table] = s
+	}
+	for _, s := range conv.KeyStrategies {
+		if typ, found := s.Types[table][col]; found {
+			delete(s.Types[table], col)
+			s.Types[table][newName] = typ
+		}
+	}
+	for _, p := range conv.TableSplits[table].Parts {
+		rename(p.Cols)
+	}
+	for i, n := range conv.NestedTables[table] {
+		if n.Col == col {
+			conv.NestedTables[table][i].Col = newName
+		}
+	}
+	conv.keyRewrites = nil
+	return nil
+}
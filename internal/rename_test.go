@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func renameTestConv() *Conv {
+	conv := MakeConv()
+	conv.SpSchema["users"] = ddl.CreateTable{
+		Name:     "users",
+		Id:       "t1",
+		ColNames: []string{"id", "name"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"id":   {Name: "id", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+			"name": {Name: "name", Id: "c2", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		},
+		Pks:     []ddl.IndexKey{{Col: "id"}},
+		Indexes: []ddl.CreateIndex{{Name: "users_by_name", Table: "users", Keys: []ddl.IndexKey{{Col: "name"}}, StoredColumns: []string{"id"}}},
+	}
+	conv.SpSchema["orders"] = ddl.CreateTable{
+		Name:     "orders",
+		Id:       "t2",
+		ColNames: []string{"id", "user_id"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"id":      {Name: "id", Id: "c3", T: ddl.Type{Name: ddl.Int64}},
+			"user_id": {Name: "user_id", Id: "c4", T: ddl.Type{Name: ddl.Int64}},
+		},
+		Pks:    []ddl.IndexKey{{Col: "user_id"}, {Col: "id"}},
+		Fks:    []ddl.Foreignkey{{Name: "fk_users", Columns: []string{"user_id"}, ReferTable: "users", ReferColumns: []string{"id"}}},
+		Parent: "users",
+	}
+	conv.SrcSchema["src_users"] = schema.Table{Name: "src_users", ColNames: []string{"uid", "name"}}
+	conv.ToSource["users"] = NameAndCols{Name: "src_users", Cols: map[string]string{"id": "uid", "name": "name"}}
+	conv.ToSpanner["src_users"] = NameAndCols{Name: "users", Cols: map[string]string{"uid": "id", "name": "name"}}
+	conv.UsedNames["users"] = true
+	conv.KeyStrategies["users"] = KeyStrategy{Strategy: KeyStrategyUUID, Col: "id"}
+	conv.DdlEdits = map[string]DdlEdit{"users": {Generated: []string{"a"}, Edited: []string{"b"}}}
+	return conv
+}
+
+func TestRenameTable(t *testing.T) {
+	conv := renameTestConv()
+	assert.NotNil(t, conv.RenameTable("unknown", "customers"))
+	assert.NotNil(t, conv.RenameTable("users", "orders"))
+	assert.Nil(t, conv.RenameTable("users", "customers"))
+
+	_, found := conv.SpSchema["users"]
+	assert.False(t, found)
+	customers := conv.SpSchema["customers"]
+	assert.Equal(t, "customers", customers.Name)
+	assert.Equal(t, "customers", customers.Indexes[0].Table)
+	assert.Equal(t, "customers", conv.SpSchema["orders"].Parent)
+	assert.Equal(t, "customers", conv.SpSchema["orders"].Fks[0].ReferTable)
+	assert.Equal(t, "src_users", conv.ToSource["customers"].Name)
+	assert.Equal(t, "customers", conv.ToSpanner["src_users"].Name)
+	assert.Equal(t, map[string]bool{"customers": true}, conv.UsedNames)
+	assert.Equal(t, "id", conv.KeyStrategies["customers"].Col)
+	assert.Equal(t, []string{"b"}, conv.DdlEdits["customers"].Edited)
+
+	name, found := conv.SpTableName("t1")
+	assert.True(t, found)
+	assert.Equal(t, "customers", name)
+	_, found = conv.SpTableName("t9")
+	assert.False(t, found)
+}
+
+func TestRenameColumn(t *testing.T) {
+	conv := renameTestConv()
+	assert.NotNil(t, conv.RenameColumn("users", "unknown", "uid"))
+	assert.NotNil(t, conv.RenameColumn("users", "id", "name"))
+	assert.Nil(t, conv.RenameColumn("users", "id", "user_id"))
+
+	users := conv.SpSchema["users"]
+	assert.Equal(t, []string{"user_id", "name"}, users.ColNames)
+	assert.Equal(t, ddl.ColumnDef{Name: "user_id", Id: "c1", T: ddl.Type{Name: ddl.Int64}}, users.ColDefs["user_id"])
+	assert.Equal(t, []ddl.IndexKey{{Col: "user_id"}}, users.Pks)
+	assert.Equal(t, []string{"user_id"}, users.Indexes[0].StoredColumns)
+	assert.Equal(t, []string{"user_id"}, conv.SpSchema["orders"].Fks[0].ReferColumns)
+	assert.Equal(t, map[string]string{"user_id": "uid", "name": "name"}, conv.ToSource["users"].Cols)
+	assert.Equal(t, "user_id", conv.ToSpanner["src_users"].Cols["uid"])
+	assert.Equal(t, "user_id", conv.KeyStrategies["users"].Col)
+
+	table, col, found := conv.SpColName("t1", "c1")
+	assert.True(t, found)
+	assert.Equal(t, "users", table)
+	assert.Equal(t, "user_id", col)
+	_, _, found = conv.SpColName("t2", "c1")
+	assert.False(t, found)
+}
@@ -20,6 +20,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
@@ -37,6 +38,12 @@ func GenerateReport(driverName string, conv *Conv, w *bufio.Writer, badWrites ma
 	ignored := IgnoredStatements(conv)
 	w.WriteString("\n")
 	w.WriteString(conversionDuration(conv, w))
+	if conv.Dialect != "" {
+		justifyLines(w, fmt.Sprintf("The source dump was detected as a %s dump: "+
+			"%s-specific syntax was ignored or converted, as listed in the "+
+			"unexpected conditions below.", conv.Dialect, conv.Dialect), 80, 0)
+		w.WriteString("\n\n")
+	}
 	if len(ignored) > 0 {
 		justifyLines(w, fmt.Sprintf("Note that the following source DB statements "+
 			"were detected but ignored: %s.",
@@ -71,6 +78,9 @@ func GenerateReport(driverName string, conv *Conv, w *bufio.Writer, badWrites ma
 	reportNameChanges(conv, w)
 
 	if printTableReports {
+		if *conv.Audit.MigrationType != migration.MigrationData_DATA_ONLY {
+			writeIssuesBySeverity(conv, w)
+		}
 		for _, t := range reports {
 			h := fmt.Sprintf("Table %s", t.SrcTable)
 			if t.SrcTable != t.SpTable {
@@ -89,6 +99,58 @@ func GenerateReport(driverName string, conv *Conv, w *bufio.Writer, badWrites ma
 		}
 	}
 
+	if conv.Shards != nil && len(conv.Shards.Stats) > 0 {
+		writeShards(conv, w)
+	}
+
+	if len(conv.TableMerges) > 0 {
+		writeTableMerges(conv, w)
+	}
+
+	if cycles := FkCycles(conv); len(cycles) > 0 {
+		writeFkCycles(cycles, w)
+	}
+
+	if len(conv.Stats.UniqueViolations) > 0 {
+		writeUniqueViolations(conv, w)
+	}
+
+	if len(conv.Stats.DuplicateKeys) > 0 {
+		writeDuplicateKeys(conv, w)
+	}
+
+	if len(conv.Stats.FkViolations) > 0 {
+		writeFkViolations(conv, w)
+	}
+
+	if len(conv.Stats.Oversized) > 0 {
+		writeOversized(conv, w)
+	}
+
+	if len(conv.Stats.UnsignedOverflow) > 0 {
+		writeUnsignedOverflow(conv, w)
+	}
+
+	if len(conv.Stats.InvalidDates) > 0 {
+		writeInvalidDates(conv, w)
+	}
+
+	if len(conv.MaterializedViews) > 0 {
+		writeMaterializedViews(conv, w)
+	}
+
+	if impacts := DetectCollationImpacts(conv); len(impacts) > 0 {
+		writeCollationImpacts(impacts, w)
+	}
+
+	if len(conv.AttributeCensus) > 0 {
+		writeAttributeCensus(conv, w)
+	}
+
+	if len(conv.Fixups) > 0 {
+		writeFixups(conv, w)
+	}
+
 	if printUnexpecteds {
 		writeUnexpectedConditions(driverName, conv, w)
 	}
@@ -96,6 +158,14 @@ func GenerateReport(driverName string, conv *Conv, w *bufio.Writer, badWrites ma
 	if conv.Audit.StreamingStats.Streaming {
 		writeStreamingMigrationReport(driverName, conv, w)
 	}
+
+	if len(conv.Timings.Stages()) > 0 || len(conv.Timings.TableStages()) > 0 {
+		writeStageTimings(conv, w)
+	}
+
+	if perf := conv.WriteStats.Tables(); len(perf) > 0 {
+		writeWritePerformance(perf, w)
+	}
 	return summary
 }
 
@@ -148,8 +218,14 @@ func writeStreamingMigrationReport(driverName string, conv *Conv, w *bufio.Write
 	totalReadRecords := sumNestedMapValues(stats.TotalRecords)
 	w.WriteString(fmt.Sprintf("\nCount of records read from %s: %s\n", streamName, strconv.FormatInt(totalReadRecords, 10)))
 
-	totalWrittenRecords := totalReadRecords - sumNestedMapValues(stats.BadRecords) - sumNestedMapValues(stats.DroppedRecords)
+	totalStaleRecords := sumNestedMapValues(stats.StaleRecords)
+	totalWrittenRecords := totalReadRecords - sumNestedMapValues(stats.BadRecords) - sumNestedMapValues(stats.DroppedRecords) - totalStaleRecords
 	w.WriteString(fmt.Sprintf("Count of records written to Cloud Spanner successfully: %s\n", strconv.FormatInt(totalWrittenRecords, 10)))
+	if totalStaleRecords > 0 {
+		w.WriteString(fmt.Sprintf("Count of stale records skipped (a newer record for the same key was already written): %s\n", strconv.FormatInt(totalStaleRecords, 10)))
+	}
+
+	writeSchemaDrift(stats.UnknownAttributes, stats.AddedColumns, w)
 
 	recordTypes := getRecordTypes(driverName)
 
@@ -170,7 +246,7 @@ func writeStreamingMigrationReport(driverName string, conv *Conv, w *bufio.Write
 		colWidth[change] = len(change)
 		for srcTable, records := range stats.TotalRecords {
 			total := records[change]
-			success := total - stats.BadRecords[srcTable][change] - stats.DroppedRecords[srcTable][change]
+			success := total - stats.BadRecords[srcTable][change] - stats.DroppedRecords[srcTable][change] - stats.StaleRecords[srcTable][change]
 
 			progress := fmt.Sprintf("%s/%s", strconv.FormatInt(success, 10), strconv.FormatInt(total, 10))
 			if len(progress) > colWidth[change] {
@@ -206,7 +282,7 @@ func writeStreamingMigrationReport(driverName string, conv *Conv, w *bufio.Write
 				progress = srcTable
 			} else {
 				total := records[colName]
-				success := total - stats.BadRecords[srcTable][colName] - stats.DroppedRecords[srcTable][colName]
+				success := total - stats.BadRecords[srcTable][colName] - stats.DroppedRecords[srcTable][colName] - stats.StaleRecords[srcTable][colName]
 				progress = fmt.Sprintf("%s/%s", strconv.FormatInt(success, 10), strconv.FormatInt(total, 10))
 			}
 
@@ -220,6 +296,37 @@ func writeStreamingMigrationReport(driverName string, conv *Conv, w *bufio.Write
 	}
 }
 
+// writeSchemaDrift writes details of attributes found in stream records which were
+// missing from the schema, and the columns added to the Spanner schema for them.
+func writeSchemaDrift(unknownAttributes map[string]map[string]int64, addedColumns map[string][]string, w *bufio.Writer) {
+	var unknown []string
+	for srcTable, attrs := range unknownAttributes {
+		for attr, count := range attrs {
+			unknown = append(unknown, fmt.Sprintf("  %s.%s: %d records\n", srcTable, attr, count))
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		w.WriteString("\nAttributes found in records but missing from the schema (data for these attributes was not migrated):\n")
+		for _, l := range unknown {
+			w.WriteString(l)
+		}
+	}
+	var added []string
+	for srcTable, attrs := range addedColumns {
+		for _, attr := range attrs {
+			added = append(added, fmt.Sprintf("  %s.%s\n", srcTable, attr))
+		}
+	}
+	if len(added) > 0 {
+		sort.Strings(added)
+		w.WriteString("\nColumns added to the Spanner schema during streaming (rows written before a column was added have no value for it):\n")
+		for _, l := range added {
+			w.WriteString(l)
+		}
+	}
+}
+
 type tableReport struct {
 	SrcTable      string
 	SpTable       string
@@ -317,6 +424,9 @@ func buildTableReportBody(conv *Conv, srcTable string, issues map[string][]Schem
 		}
 
 		if p.severity == note {
+			if n := conv.keyStrategyNote(spSchema.Name); n != "" {
+				l = append(l, n)
+			}
 			for srcKeyName, spKeyName := range conv.Audit.ToSpannerFkIdx[srcTable].ForeignKey {
 				if srcKeyName != spKeyName {
 					l = append(l, fmt.Sprintf("%s, Foreign Key '%s' is mapped to '%s'", IssueDB[IllegalName].Brief, srcKeyName, spKeyName))
@@ -372,7 +482,19 @@ func buildTableReportBody(conv *Conv, srcTable string, issues map[string][]Schem
 					// Avoid the confusing "timestamp is mapped to timestamp" message.
 					l = append(l, fmt.Sprintf("Some columns have source DB type 'timestamp without timezone' which is mapped to Spanner type timestamp e.g. column '%s'. %s", srcCol, IssueDB[i].Brief))
 				case Datetime:
-					l = append(l, fmt.Sprintf("Some columns have source DB type 'datetime' which is mapped to Spanner type timestamp e.g. column '%s'. %s", srcCol, IssueDB[i].Brief))
+					l = append(l, fmt.Sprintf("Some columns have source DB type 'datetime' which is mapped to Spanner type timestamp e.g. column '%s'. %s. Datetimes are converted assuming time zone %s", srcCol, IssueDB[i].Brief, conv.DatetimeZone(srcTable, srcCol)))
+					var overrides []string
+					for c := range conv.DatetimeZones.Columns[srcTable] {
+						if c != srcCol {
+							overrides = append(overrides, c)
+						}
+					}
+					sort.Strings(overrides)
+					for _, c := range overrides {
+						l = append(l, fmt.Sprintf("Datetimes of column '%s' are converted assuming time zone %s", c, conv.DatetimeZone(srcTable, c)))
+					}
+				case CollationUnique, CollationOrder:
+					l = append(l, fmt.Sprintf("Column '%s' has collation %s. %s", srcCol, srcSchema.ColDefs[srcCol].Collation, IssueDB[i].Brief))
 				case Widened:
 					l = append(l, fmt.Sprintf("%s e.g. for column '%s', source DB type %s is mapped to Spanner type %s", IssueDB[i].Brief, srcCol, srcType, spType))
 				case HotspotTimestamp:
@@ -477,6 +599,13 @@ var IssueDB = map[SchemaIssue]struct {
 	InterleavedOrder:      {Brief: "Can be converted to Interleaved Table", severity: note},
 	InterleavedAddColumn:  {Brief: "Candidate for Interleaved Table", severity: note},
 	IllegalName:           {Brief: "Names must adhere to the spanner regular expression {a-z|A-Z}[{a-z|A-Z|0-9|_}+]", severity: note},
+	UnsignedOverflow:      {Brief: "Unsigned values larger than the maximum INT64 value can't be converted, consider mapping to NUMERIC or STRING", severity: warning},
+	Domain:                {Brief: "Spanner does not support domains, which are mapped to their base type and CHECK constraints", severity: note},
+	DomainCheck:           {Brief: "Some CHECK constraints of the domain can't be converted to Spanner and were dropped", severity: warning},
+	Composite:             {Brief: "Spanner does not support composite types, which are mapped to JSON objects of their fields", severity: warning},
+	SourceSequence:        {Brief: "Spanner does not support autoincrementing columns, new values are generated by a bit-reversed sequence that skips the migrated values", severity: note},
+	CollationUnique:       {Brief: "Spanner compares strings by their binary value, so unique key values that differ only in case or accents are no longer duplicates", severity: warning},
+	CollationOrder:        {Brief: "Spanner orders and compares strings by their binary value, so index order and lookups differ for values that differ only in case or accents", severity: note},
 }
 
 type severity int
@@ -499,7 +628,7 @@ func AnalyzeCols(conv *Conv, srcTable, spTable string) (map[string][]SchemaIssue
 	// per column and/or multiple warnings per table.
 	// non-batched warnings: count at most one warning per column.
 	// batched warnings: count at most one warning per table.
-	for c, l := range conv.Issues[srcTable] {
+	for c, l := range conv.activeIssues(srcTable) {
 		colWarning := false
 		m[c] = l
 		for _, i := range l {
@@ -707,7 +836,7 @@ func writeStmtStats(driverName string, conv *Conv, w *bufio.Writer) {
 		w.WriteString("See github.com/pganalyze/pg_query_go for definitions of statement types\n")
 		w.WriteString("(pganalyze/pg_query_go is the library we use for parsing pg_dump output).\n")
 		w.WriteString("\n")
-	} else if driverName == constants.MYSQLDUMP {
+	} else if driverName == constants.MYSQLDUMP || driverName == constants.MARIADBDUMP {
 		w.WriteString("See https://github.com/pingcap/parser for definitions of statement types\n")
 		w.WriteString("(pingcap/tidb/parser is the library we use for parsing mysqldump output).\n")
 		w.WriteString("\n")
@@ -727,7 +856,7 @@ func writeUnexpectedConditions(driverName string, conv *Conv, w *bufio.Writer) {
 		return
 	}
 	switch driverName {
-	case constants.MYSQLDUMP:
+	case constants.MYSQLDUMP, constants.MARIADBDUMP:
 		w.WriteString("For debugging only. This section provides details of unexpected conditions\n")
 		w.WriteString("encountered as we processed the mysqldump data. In particular, the AST node\n")
 		w.WriteString("representation used by the pingcap/tidb/parser library used for parsing\n")
@@ -756,6 +885,346 @@ func writeUnexpectedConditions(driverName string, conv *Conv, w *bufio.Writer) {
 	reparseInfo()
 }
 
+// writeUniqueViolations reports the rows that were not written because of
+// unique index violations.
+func writeUniqueViolations(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Unique Index Violations")
+	justifyLines(w, "The following unique indexes rejected rows during data migration. "+
+		"Since the source database enforces the same uniqueness constraint, this "+
+		"indicates that distinct source values were converted to equal Spanner values, "+
+		"for example because of loss of precision in numeric or timestamp conversions, "+
+		"or collations that ignore trailing spaces. Rejected rows are reported as bad "+
+		"rows in the bad data file.", 80, 0)
+	w.WriteString("\n\n")
+	var indexes []string
+	for idx := range conv.Stats.UniqueViolations {
+		indexes = append(indexes, idx)
+	}
+	sort.Strings(indexes)
+	w.WriteString("  --------------------------------------\n")
+	w.WriteString(fmt.Sprintf("  %6s  %s\n", "rows", "index"))
+	w.WriteString("  --------------------------------------\n")
+	for _, idx := range indexes {
+		w.WriteString(fmt.Sprintf("  %6d  %s\n", conv.Stats.UniqueViolations[idx], idx))
+	}
+	w.WriteString("\n")
+}
+
+// writeOversized reports the values that were larger than Spanner's limit,
+// and how they were handled.
+func writeOversized(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Oversized Values")
+	var handling string
+	switch conv.OversizePolicy() {
+	case OversizeTruncate:
+		handling = "They were truncated, so the migrated values are incomplete."
+	case OversizeSpill:
+		handling = "They were written outside of Spanner, and replaced by the URI they were written to."
+	default:
+		handling = "The rows containing them were dropped, and are reported as bad rows in the bad data file."
+	}
+	justifyLines(w, fmt.Sprintf("The following tables had STRING or BYTES values larger than "+
+		"the Spanner limit of %d bytes. %s", MaxValueBytes, handling), 80, 0)
+	w.WriteString("\n\n")
+	var tables []string
+	for t := range conv.Stats.Oversized {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	w.WriteString("  --------------------------------------\n")
+	w.WriteString(fmt.Sprintf("  %6s  %s\n", "values", "table"))
+	w.WriteString("  --------------------------------------\n")
+	for _, t := range tables {
+		w.WriteString(fmt.Sprintf("  %6d  %s\n", conv.Stats.Oversized[t], t))
+	}
+	w.WriteString("\n")
+}
+
+// writeUnsignedOverflow reports the unsigned integer values that were larger
+// than the maximum INT64 value.
+func writeUnsignedOverflow(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Unsigned Integer Overflow")
+	justifyLines(w, "The following tables had unsigned integer values larger than "+
+		"the maximum INT64 value. Rows with such values in columns mapped to INT64 "+
+		"were dropped, and are reported as bad rows; values in columns mapped to "+
+		"NUMERIC or STRING were migrated as is.", 80, 0)
+	w.WriteString("\n\n")
+	var tables []string
+	for t := range conv.Stats.UnsignedOverflow {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	w.WriteString("  --------------------------------------\n")
+	w.WriteString(fmt.Sprintf("  %6s  %s\n", "values", "table"))
+	w.WriteString("  --------------------------------------\n")
+	for _, t := range tables {
+		w.WriteString(fmt.Sprintf("  %6d  %s\n", conv.Stats.UnsignedOverflow[t], t))
+	}
+	w.WriteString("\n")
+}
+
+// writeInvalidDates reports the source dates and datetimes that weren't valid
+// Spanner values, e.g. MySQL zero dates, and how they were handled.
+func writeInvalidDates(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Invalid Dates")
+	var handling string
+	switch conv.InvalidDatePolicy() {
+	case InvalidDateNull:
+		handling = "They were migrated as NULL."
+	case InvalidDateSentinel:
+		handling = fmt.Sprintf("They were replaced by the sentinel date %s.", conv.invalidDates.sentinel)
+	default:
+		handling = "The rows containing them were dropped, and are reported as bad rows in the bad data file."
+	}
+	justifyLines(w, "The following columns had dates or datetimes that aren't valid "+
+		"Spanner values, such as zero dates and dates outside years 1 to 9999. "+handling, 80, 0)
+	w.WriteString("\n\n")
+	var tables []string
+	for t := range conv.Stats.InvalidDates {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	w.WriteString("  --------------------------------------\n")
+	w.WriteString(fmt.Sprintf("  %6s  %s\n", "values", "table.column"))
+	w.WriteString("  --------------------------------------\n")
+	for _, t := range tables {
+		var cols []string
+		for c := range conv.Stats.InvalidDates[t] {
+			cols = append(cols, c)
+		}
+		sort.Strings(cols)
+		for _, c := range cols {
+			w.WriteString(fmt.Sprintf("  %6d  %s.%s\n", conv.Stats.InvalidDates[t][c], t, c))
+		}
+	}
+	w.WriteString("\n")
+}
+
+// writeMaterializedViews reports the materialized views of the source
+// database, which Spanner doesn't support, and their definitions.
+func writeMaterializedViews(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Materialized Views")
+	justifyLines(w, "Spanner doesn't support materialized views. Views converted into "+
+		"tables are populated once during data migration, and must be refreshed by the "+
+		"application e.g. by re-running the view's query and writing its results. Other "+
+		"views were not migrated.", 80, 0)
+	w.WriteString("\n\n")
+	var names []string
+	for n := range conv.MaterializedViews {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		v := conv.MaterializedViews[n]
+		if spTable, ok := conv.ToSpanner[n]; ok && v.Table {
+			w.WriteString(fmt.Sprintf("  %s: converted into table %s\n", n, spTable.Name))
+		} else {
+			w.WriteString(fmt.Sprintf("  %s: not migrated\n", n))
+		}
+		for _, l := range strings.Split(strings.TrimSpace(v.Definition), "\n") {
+			w.WriteString("    " + l + "\n")
+		}
+	}
+	w.WriteString("\n")
+}
+
+// writeAttributeCensus reports the frequency of the attributes of the
+// tables of schemaless sources.
+func writeAttributeCensus(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Attribute Frequency")
+	justifyLines(w, "Schema inference read the following items of each table, and found "+
+		"these attributes. Attributes missing from a sample don't get a column, so "+
+		"read all the items to find rare attributes. Sparse attributes may be better "+
+		"kept in a catch-all JSON column than as columns of their own. Numbers with "+
+		"more than 29 integer or 9 fractional digits exceed NUMERIC, and are better "+
+		"mapped to FLOAT64 (losing precision) or STRING.", 80, 0)
+	w.WriteString("\n\n")
+	var tables []string
+	for t := range conv.AttributeCensus {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		c := conv.AttributeCensus[t]
+		read := "sample"
+		if c.Full {
+			read = "all items"
+		}
+		w.WriteString(fmt.Sprintf("  Table %s: %d items read (%s)\n", t, c.Items, read))
+		for _, a := range c.SortedAttributes() {
+			note := ""
+			_, ok := conv.SrcSchema[t].ColDefs[a]
+			switch {
+			case ok:
+			case c.CatchAll != "":
+				note = fmt.Sprintf(" (in %s)", c.CatchAll)
+			default:
+				note = " (no column)"
+			}
+			w.WriteString(fmt.Sprintf("    %-30s %6.2f%%%s\n", a, 100*c.Frequency(a), note))
+			if p, ok := c.Numbers[a]; ok {
+				w.WriteString(fmt.Sprintf("      numbers: up to %d integer and %d fractional digits", p.IntegerDigits, p.Scale))
+				if p.Exceeding > 0 {
+					w.WriteString(fmt.Sprintf(", %d of %d values exceed NUMERIC", p.Exceeding, p.Values))
+				}
+				w.WriteString("\n")
+			}
+		}
+	}
+	w.WriteString("\n")
+}
+
+// writeFixups reports the fix-up statements run after data migration and
+// their outcome.
+func writeFixups(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Fix-up Statements")
+	justifyLines(w, "The following statements were run as Partitioned DML after "+
+		"data migration. Partitioned DML can run a statement more than once on some "+
+		"rows, and reports a lower bound of the rows modified.", 80, 0)
+	w.WriteString("\n\n")
+	for i, f := range conv.Fixups {
+		var status string
+		switch {
+		case f.Done:
+			status = fmt.Sprintf("done, at least %d rows modified", f.Rows)
+		case f.Error != "":
+			status = "failed: " + f.Error
+		default:
+			status = "not run"
+		}
+		w.WriteString(fmt.Sprintf("  %d. (%s) %s\n", i+1, f.Origin, status))
+		w.WriteString(fmt.Sprintf("    %s\n", strings.ReplaceAll(f.SQL, "\n", "\n    ")))
+	}
+	w.WriteString("\n")
+}
+
+// writeCollationImpacts reports the primary keys and indexes whose key
+// columns have a case- or accent-insensitive source collation.
+func writeCollationImpacts(impacts []CollationImpact, w *bufio.Writer) {
+	writeHeading(w, "Collations")
+	justifyLines(w, "Spanner orders and compares strings by their binary value, but "+
+		"the following keys have columns with a case- or accent-insensitive source "+
+		"collation. Their order differs from the source database, and unique keys "+
+		"accept values that differ only in case or accents, which were duplicates in "+
+		"the source database. To keep the source semantics, add a normalized shadow "+
+		"column (e.g. a generated column holding LOWER of the column) and use it in "+
+		"the key instead, or normalize values in the application.", 80, 0)
+	w.WriteString("\n\n")
+	for _, ci := range impacts {
+		key := "primary key"
+		switch {
+		case ci.Index != "" && ci.Unique:
+			key = "unique index " + ci.Index
+		case ci.Index != "":
+			key = "index " + ci.Index
+		}
+		w.WriteString(fmt.Sprintf("  Table %s, %s: columns %s\n", ci.SpTable, key, strings.Join(ci.SpCols, ", ")))
+	}
+	w.WriteString("\n")
+}
+
+func writeShards(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Source Databases")
+	justifyLines(w, fmt.Sprintf("The following source databases were merged into "+
+		"the Spanner database. The rows of each database are identified by the "+
+		"value of the %s column, which is the first primary key column of "+
+		"every table.", conv.Shards.IdColumn), 80, 0)
+	w.WriteString("\n\n")
+	w.WriteString("  --------------------------------------\n")
+	w.WriteString(fmt.Sprintf("  %8s  %8s  %s\n", "rows", "bad rows", "database"))
+	w.WriteString("  --------------------------------------\n")
+	for _, id := range conv.Shards.Ids {
+		st := conv.Shards.Stats[id]
+		w.WriteString(fmt.Sprintf("  %8d  %8d  %s\n", st.Rows, st.BadRows, id))
+	}
+	w.WriteString("\n")
+}
+
+func writeTableMerges(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Merged Tables")
+	justifyLines(w, "The following source tables were merged into single "+
+		"tables. The rows of each source table are identified by the value of "+
+		"the merge column, which is the first primary key column of the table.", 80, 0)
+	w.WriteString("\n\n")
+	var tables []string
+	for t := range conv.TableMerges {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		tm := conv.TableMerges[t]
+		w.WriteString(fmt.Sprintf("  Table %s (column %s): %d tables matching %s\n", conv.ToSpanner[t].Name, tm.Column, len(tm.Members), tm.Pattern))
+	}
+	w.WriteString("\n")
+}
+
+// writeStageTimings writes the time spent in each stage of the migration,
+// and in the data migration stages of each table.
+func writeStageTimings(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Timing by Stage")
+	justifyLines(w, "Time spent in each stage of the migration. For each table, "+
+		"read is the time spent reading rows from the source, convert the time "+
+		"spent converting them, and write wait the time spent waiting for Spanner "+
+		"writes to take more rows, while write spans the table's Spanner writes. "+
+		"A long write wait means that Spanner writes are the bottleneck, and a long "+
+		"read that the source is.", 80, 0)
+	w.WriteString("\n\n")
+	if stages := conv.Timings.Stages(); len(stages) > 0 {
+		w.WriteString("  --------------------------------\n")
+		w.WriteString(fmt.Sprintf("  %10s  %s\n", "duration", "stage"))
+		w.WriteString("  --------------------------------\n")
+		for _, s := range stages {
+			w.WriteString(fmt.Sprintf("  %10s  %s\n", formatDuration(s.Duration), s.Stage))
+		}
+		w.WriteString("\n")
+	}
+	if tables := conv.Timings.TableStages(); len(tables) > 0 {
+		writes := make(map[string]time.Duration)
+		for _, p := range conv.WriteStats.Tables() {
+			writes[p.Table] = p.Duration
+		}
+		w.WriteString("  ------------------------------------------------------------\n")
+		w.WriteString(fmt.Sprintf("  %10s  %10s  %10s  %10s  %s\n", "read", "convert", "write wait", "write", "table"))
+		w.WriteString("  ------------------------------------------------------------\n")
+		for _, t := range tables {
+			write := writes[conv.ToSpanner[t.Table].Name]
+			w.WriteString(fmt.Sprintf("  %10s  %10s  %10s  %10s  %s\n", formatDuration(t.Read), formatDuration(t.Convert),
+				formatDuration(t.WriteWait), formatDuration(write), t.Table))
+		}
+		w.WriteString("\n")
+	}
+}
+
+// formatDuration formats d rounded to the millisecond.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// writeWritePerformance writes the performance appendix: the throughput and
+// commit latency of the Spanner writes of each table.
+func writeWritePerformance(perf []TableWritePerformance, w *bufio.Writer) {
+	writeHeading(w, "Appendix: Write Performance")
+	justifyLines(w, "Throughput and commit latency percentiles of the Spanner "+
+		"writes of each table. Tables with high p99 latencies, retries or "+
+		"aborted writes are contended: give them fewer parallel writes "+
+		"(writeLimit in the -write-tuning file) or smaller writes (batchRows). "+
+		"Tables with low latencies may take more parallel writes (-write-limit).", 80, 0)
+	w.WriteString("\n\n")
+	w.WriteString("  ----------------------------------------------------------------------------\n")
+	w.WriteString(fmt.Sprintf("  %10s  %8s  %8s  %8s  %8s  %7s  %7s  %s\n", "rows/s", "writes", "p50", "p95", "p99", "retries", "aborted", "table"))
+	w.WriteString("  ----------------------------------------------------------------------------\n")
+	for _, p := range perf {
+		w.WriteString(fmt.Sprintf("  %10.0f  %8d  %8s  %8s  %8s  %7d  %7d  %s\n", p.RowsPerSecond, p.Writes,
+			formatLatency(p.LatencyP50), formatLatency(p.LatencyP95), formatLatency(p.LatencyP99), p.Retries, p.Aborted, p.Table))
+	}
+	w.WriteString("\n")
+}
+
+// formatLatency formats latency d in milliseconds.
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
 // justifyLines writes s out to w, adding newlines between words
 // to keep line length under 'limit'. Newlines are indented
 // 'indent' spaces.
@@ -817,3 +1286,53 @@ func conversionDuration(conv *Conv, w *bufio.Writer) string {
 	}
 	return res
 }
+
+// writeDuplicateKeys reports the unique indexes that were not created because
+// the migrated data has duplicate keys, along with a sample of these keys.
+func writeDuplicateKeys(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Duplicate Unique Index Keys")
+	justifyLines(w, "The following unique indexes were not created because some keys "+
+		"are held by more than one row of the migrated data. This typically "+
+		"happens when distinct source values were converted to equal Spanner "+
+		"values, or when the source database doesn't enforce the constraint. Fix "+
+		"the data and add the indexes using the statements in the schema file.", 80, 0)
+	w.WriteString("\n\n")
+	var indexes []string
+	for idx := range conv.Stats.DuplicateKeys {
+		indexes = append(indexes, idx)
+	}
+	sort.Strings(indexes)
+	for _, idx := range indexes {
+		w.WriteString(fmt.Sprintf("  %s\n", idx))
+		w.WriteString("  --------------------------------------\n")
+		w.WriteString(fmt.Sprintf("  %6s  %s\n", "rows", "key"))
+		w.WriteString("  --------------------------------------\n")
+		for _, k := range conv.Stats.DuplicateKeys[idx] {
+			w.WriteString(fmt.Sprintf("  %6d  %s\n", k.Rows, k.Key))
+		}
+		w.WriteString("\n")
+	}
+}
+
+// writeFkViolations reports the foreign keys that were not created because
+// the migrated data violates them.
+func writeFkViolations(conv *Conv, w *bufio.Writer) {
+	writeHeading(w, "Foreign Key Violations")
+	justifyLines(w, "The following foreign keys were not created because some rows "+
+		"reference rows that don't exist in the referenced table. This typically "+
+		"happens when the source database doesn't enforce the constraint. Fix the "+
+		"data and add the constraints using the statements in the schema file.", 80, 0)
+	w.WriteString("\n\n")
+	var fks []string
+	for fk := range conv.Stats.FkViolations {
+		fks = append(fks, fk)
+	}
+	sort.Strings(fks)
+	w.WriteString("  --------------------------------------\n")
+	w.WriteString(fmt.Sprintf("  %6s  %s\n", "rows", "foreign key"))
+	w.WriteString("  --------------------------------------\n")
+	for _, fk := range fks {
+		w.WriteString(fmt.Sprintf("  %6d  %s\n", conv.Stats.FkViolations[fk], fk))
+	}
+	w.WriteString("\n")
+}
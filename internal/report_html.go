@@ -0,0 +1,220 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+type htmlReport struct {
+	Banner     string
+	Summary    []string
+	Ignored    []string
+	DDLFile    string
+	SchemaMode bool
+	Tables     []htmlTableReport
+	Unexpected []htmlCount
+	Streaming  *htmlStreamingReport
+}
+
+type htmlTableReport struct {
+	SrcTable      string
+	SpTable       string
+	Rating        []string
+	Rows          int64
+	BadConversion int64
+	BadWrites     int64
+	BadPct        float64 // Bad rows as a percentage of the maximum bad rows of any table, for the chart.
+	SyntheticPKey string
+	Issues        []tableReportBody
+	IssueCount    int
+}
+
+type htmlStreamingReport struct {
+	Tables []htmlStreamingTable
+}
+
+type htmlStreamingTable struct {
+	SrcTable string
+	Records  int64
+	Bad      int64
+	Dropped  int64
+	Stale    int64
+	BadPct   float64 // Bad and dropped records as a percentage of the maximum of any table, for the chart.
+}
+
+type htmlCount struct {
+	Name  string
+	Count int64
+}
+
+// GenerateHTMLReport writes a HTML version of the report to w. It contains the
+// same information as the text report generated by GenerateReport, organized
+// as expandable per-table sections with charts of bad rows. ddlFile is the
+// name of the generated DDL file to link to; it is omitted if empty.
+func GenerateHTMLReport(driverName string, conv *Conv, w io.Writer, badWrites map[string]int64, banner, ddlFile string) error {
+	reports := AnalyzeTables(conv, badWrites)
+	r := htmlReport{
+		Banner:     banner,
+		Summary:    splitLines(GenerateSummary(conv, reports, badWrites)),
+		Ignored:    IgnoredStatements(conv),
+		DDLFile:    ddlFile,
+		SchemaMode: conv.SchemaMode(),
+	}
+	maxBad := int64(0)
+	for _, t := range reports {
+		tr := htmlTableReport{
+			SrcTable:      t.SrcTable,
+			SpTable:       t.SpTable,
+			Rating:        splitLines(rateConversion(t.rows, t.badRows, t.Cols, t.Warnings, t.SyntheticPKey != "", false, conv.SchemaMode(), *conv.Audit.MigrationType, conv.Audit.DryRun)),
+			Rows:          t.rows,
+			BadConversion: conv.Stats.BadRows[t.SrcTable],
+			BadWrites:     badWrites[t.SrcTable],
+			SyntheticPKey: t.SyntheticPKey,
+			Issues:        t.Body,
+		}
+		for _, b := range t.Body {
+			tr.IssueCount += len(b.Lines)
+		}
+		if t.badRows > maxBad {
+			maxBad = t.badRows
+		}
+		r.Tables = append(r.Tables, tr)
+	}
+	for i := range r.Tables {
+		if maxBad > 0 {
+			r.Tables[i].BadPct = 100 * float64(r.Tables[i].BadConversion+r.Tables[i].BadWrites) / float64(maxBad)
+		}
+	}
+	for s, n := range conv.Stats.Unexpected {
+		r.Unexpected = append(r.Unexpected, htmlCount{Name: s, Count: n})
+	}
+	sort.Slice(r.Unexpected, func(i, j int) bool { return r.Unexpected[i].Name < r.Unexpected[j].Name })
+	if conv.Audit.StreamingStats.Streaming {
+		r.Streaming = buildHTMLStreamingReport(conv)
+	}
+	return htmlReportTemplate.Execute(w, r)
+}
+
+func buildHTMLStreamingReport(conv *Conv) *htmlStreamingReport {
+	stats := conv.Audit.StreamingStats
+	var tables []string
+	for t := range stats.TotalRecords {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	sr := &htmlStreamingReport{}
+	maxBad := int64(0)
+	for _, t := range tables {
+		st := htmlStreamingTable{SrcTable: t}
+		for _, n := range stats.TotalRecords[t] {
+			st.Records += n
+		}
+		for _, n := range stats.BadRecords[t] {
+			st.Bad += n
+		}
+		for _, n := range stats.DroppedRecords[t] {
+			st.Dropped += n
+		}
+		for _, n := range stats.StaleRecords[t] {
+			st.Stale += n
+		}
+		if st.Bad+st.Dropped > maxBad {
+			maxBad = st.Bad + st.Dropped
+		}
+		sr.Tables = append(sr.Tables, st)
+	}
+	for i := range sr.Tables {
+		if maxBad > 0 {
+			sr.Tables[i].BadPct = 100 * float64(sr.Tables[i].Bad+sr.Tables[i].Dropped) / float64(maxBad)
+		}
+	}
+	return sr
+}
+
+func splitLines(s string) []string {
+	var l []string
+	for _, x := range strings.Split(s, "\n") {
+		if x != "" {
+			l = append(l, x)
+		}
+	}
+	return l
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"lower": strings.ToLower,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>HarbourBridge migration report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #202124; }
+h1, h2 { font-weight: normal; }
+pre.banner { color: #5f6368; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 4px 12px; border-bottom: 1px solid #dadce0; }
+details { margin: 8px 0; border: 1px solid #dadce0; border-radius: 4px; padding: 8px; }
+summary { cursor: pointer; font-weight: bold; }
+.bar { background: #d93025; height: 12px; }
+.chart td.barcell { width: 300px; }
+.issue-warning, .issue-warnings, .issue-error, .issue-errors { color: #d93025; }
+.issue-note, .issue-notes, .issue-suggestion, .issue-suggestions { color: #1a73e8; }
+</style>
+</head>
+<body>
+<h1>HarbourBridge migration report</h1>
+{{if .Banner}}<pre class="banner">{{.Banner}}</pre>{{end}}
+<h2>Summary of conversion</h2>
+<ul>{{range .Summary}}<li>{{.}}</li>{{end}}</ul>
+{{if .Ignored}}<p>The following source DB statements were detected but ignored: {{range $i, $s := .Ignored}}{{if $i}}, {{end}}{{$s}}{{end}}.</p>{{end}}
+{{if .DDLFile}}<p>Generated Spanner DDL: <a href="{{.DDLFile}}">{{.DDLFile}}</a></p>{{end}}
+{{if not .SchemaMode}}
+<h2>Bad rows by table</h2>
+<table class="chart">
+<tr><th>Table</th><th>Rows</th><th>Conversion errors</th><th>Write errors</th><th></th></tr>
+{{range .Tables}}<tr><td><a href="#table-{{.SrcTable}}">{{.SrcTable}}</a></td><td>{{.Rows}}</td><td>{{.BadConversion}}</td><td>{{.BadWrites}}</td><td class="barcell"><div class="bar" style="width: {{printf "%.1f" .BadPct}}%"></div></td></tr>
+{{end}}</table>
+{{end}}
+<h2>Tables</h2>
+{{range .Tables}}<details id="table-{{.SrcTable}}">
+<summary>{{.SrcTable}}{{if ne .SrcTable .SpTable}} (mapped to Spanner table {{.SpTable}}){{end}} &mdash; {{.IssueCount}} issue(s)</summary>
+<ul>{{range .Rating}}<li>{{.}}</li>{{end}}</ul>
+{{if .SyntheticPKey}}<p>Synthetic primary key column: {{.SyntheticPKey}}</p>{{end}}
+{{range .Issues}}<details class="issue-{{lower .Heading}}" open>
+<summary>{{.Heading}} ({{len .Lines}})</summary>
+<ol>{{range .Lines}}<li>{{.}}</li>{{end}}</ol>
+</details>
+{{end}}</details>
+{{end}}
+{{with .Streaming}}
+<h2>Streaming migration</h2>
+<table class="chart">
+<tr><th>Table</th><th>Records</th><th>Bad</th><th>Dropped</th><th>Stale</th><th></th></tr>
+{{range .Tables}}<tr><td>{{.SrcTable}}</td><td>{{.Records}}</td><td>{{.Bad}}</td><td>{{.Dropped}}</td><td>{{.Stale}}</td><td class="barcell"><div class="bar" style="width: {{printf "%.1f" .BadPct}}%"></div></td></tr>
+{{end}}</table>
+{{end}}
+<h2>Unexpected conditions</h2>
+{{if .Unexpected}}<table>
+<tr><th>Count</th><th>Condition</th></tr>
+{{range .Unexpected}}<tr><td>{{.Count}}</td><td>{{.Name}}</td></tr>
+{{end}}</table>{{else}}<p>There were no unexpected conditions encountered during processing.</p>{{end}}
+</body>
+</html>
+`))
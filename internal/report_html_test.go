@@ -0,0 +1,72 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func buildReportConv() *Conv {
+	conv := MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Name:     "t1",
+		ColNames: []string{"a", "b"},
+		ColDefs: map[string]schema.Column{
+			"a": {Name: "a", Type: schema.Type{Name: "bigint"}},
+			"b": {Name: "b", Type: schema.Type{Name: "float"}},
+		},
+		PrimaryKeys: []schema.Key{{Column: "a"}},
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"a", "b"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a": {Name: "a", T: ddl.Type{Name: ddl.Int64}},
+			"b": {Name: "b", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		},
+		Pks: []ddl.IndexKey{{Col: "a"}},
+	}
+	conv.ToSpanner["t1"] = NameAndCols{Name: "t1", Cols: map[string]string{"a": "a", "b": "b"}}
+	conv.ToSource["t1"] = NameAndCols{Name: "t1", Cols: map[string]string{"a": "a", "b": "b"}}
+	conv.Issues["t1"] = map[string][]SchemaIssue{"b": {NoGoodType}}
+	conv.SetDataMode()
+	conv.Stats.Rows["t1"] = 100
+	conv.Stats.GoodRows["t1"] = 90
+	conv.Stats.BadRows["t1"] = 10
+	conv.Stats.Unexpected["<unexpected>"] = 2
+	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_AND_DATA.Enum()
+	return conv
+}
+
+func TestGenerateHTMLReport(t *testing.T) {
+	conv := buildReportConv()
+	buf := new(bytes.Buffer)
+	err := GenerateHTMLReport("mysql", conv, buf, map[string]int64{"t1": 5}, "banner", "db.schema.ddl.txt")
+	assert.Nil(t, err)
+	s := buf.String()
+	assert.Contains(t, s, `<details id="table-t1">`)
+	assert.Contains(t, s, "Column &#39;b&#39;: type float is mapped to string(max). No appropriate Spanner type")
+	assert.Contains(t, s, `<td>100</td><td>10</td><td>5</td>`)
+	assert.Contains(t, s, `style="width: 100.0%"`)
+	assert.Contains(t, s, `<a href="db.schema.ddl.txt">`)
+	assert.Contains(t, s, "&lt;unexpected&gt;")
+}
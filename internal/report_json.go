@@ -0,0 +1,284 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONReportSchemaVersion is the version of the JSON report schema. It is
+// bumped whenever a field is removed or its meaning changes; new fields may
+// be added without changing the version.
+const JSONReportSchemaVersion = "1.0"
+
+// JSONReport is the machine-readable version of the report.
+type JSONReport struct {
+	SchemaVersion        string                      `json:"schemaVersion"`
+	Driver               string                      `json:"driver"`
+	MigrationType        string                      `json:"migrationType"`
+	DryRun               bool                        `json:"dryRun"`
+	Summary              JSONReportSummary           `json:"summary"`
+	IgnoredStatements    []string                    `json:"ignoredStatements"`
+	Tables               []JSONTableReport           `json:"tables"`
+	UnexpectedConditions map[string]int64            `json:"unexpectedConditions"`
+	UniqueViolations     map[string]int64            `json:"uniqueViolations"`  // Rows rejected by unique indexes, keyed by Spanner index name.
+	FkViolations         map[string]int64            `json:"fkViolations"`      // Rows violating foreign keys that weren't created, keyed by Spanner foreign key name.
+	DuplicateKeys        map[string][]DuplicateKey   `json:"duplicateKeys"`     // Keys held by more than one row of unique indexes that weren't created, keyed by Spanner index name.
+	OversizedValues      map[string]int64            `json:"oversizedValues"`   // Values larger than Spanner's limit, keyed by Spanner table name.
+	UnsignedOverflows    map[string]int64            `json:"unsignedOverflows"` // Unsigned values larger than the maximum INT64 value, keyed by source table name.
+	InvalidDates         map[string]map[string]int64 `json:"invalidDates"`      // Invalid dates and datetimes, keyed by source table and column name.
+	MaterializedViews    []JSONMaterializedView      `json:"materializedViews"` // Materialized views of the source database, sorted by name.
+	Streaming            *JSONStreamingReport        `json:"streaming,omitempty"`
+	WritePerformance     []JSONWritePerformance      `json:"writePerformance,omitempty"` // Performance of the Spanner writes of each table, sorted by Spanner table name.
+}
+
+// JSONMaterializedView describes a materialized view of the source database.
+type JSONMaterializedView struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+	SpTable    string `json:"spTable,omitempty"` // Set if the view was converted into a table.
+}
+
+// JSONReportSummary contains totals over all tables.
+type JSONReportSummary struct {
+	Text        string           `json:"text"` // Same summary as in the text report.
+	Tables      int              `json:"tables"`
+	Cols        int64            `json:"cols"`
+	Warnings    int64            `json:"warnings"`
+	Rows        int64            `json:"rows"`
+	BadRows     int64            `json:"badRows"`
+	IssueCounts map[string]int64 `json:"issueCounts"` // Number of occurrences of each schema issue, keyed by issue name.
+}
+
+// JSONTableReport describes the conversion of a single table.
+type JSONTableReport struct {
+	SrcTable            string              `json:"srcTable"`
+	SpTable             string              `json:"spTable"`
+	Cols                int64               `json:"cols"`
+	Warnings            int64               `json:"warnings"`
+	SyntheticPrimaryKey string              `json:"syntheticPrimaryKey,omitempty"`
+	Columns             []JSONColumnMapping `json:"columns"`
+	Issues              []JSONIssue         `json:"issues"`
+	Rows                int64               `json:"rows"`
+	BadConversionRows   int64               `json:"badConversionRows"`
+	BadWriteRows        int64               `json:"badWriteRows"`
+}
+
+// JSONColumnMapping describes how a source column is mapped to Spanner.
+type JSONColumnMapping struct {
+	SrcCol  string `json:"srcCol"`
+	SrcType string `json:"srcType"`
+	SpCol   string `json:"spCol"`
+	SpType  string `json:"spType"`
+}
+
+// JSONIssue is a schema conversion issue for a column.
+type JSONIssue struct {
+	Column      string `json:"column"`
+	Name        string `json:"name"`     // Stable issue name e.g. NoGoodType.
+	Severity    string `json:"severity"` // One of warning, note, suggestion and error.
+	Description string `json:"description"`
+}
+
+// JSONStreamingReport contains the stats of a streaming migration, broken down
+// by table and then by record type.
+type JSONStreamingReport struct {
+	TotalRecords      map[string]map[string]int64 `json:"totalRecords"`
+	BadRecords        map[string]map[string]int64 `json:"badRecords"`
+	DroppedRecords    map[string]map[string]int64 `json:"droppedRecords"`
+	StaleRecords      map[string]map[string]int64 `json:"staleRecords"`
+	UnknownAttributes map[string]map[string]int64 `json:"unknownAttributes"`
+	AddedColumns      map[string][]string         `json:"addedColumns"`
+}
+
+// JSONWritePerformance is the throughput and commit latency of the Spanner
+// writes of a table. Latencies are in milliseconds.
+type JSONWritePerformance struct {
+	SpTable       string  `json:"spTable"`
+	Rows          int64   `json:"rows"`
+	Bytes         int64   `json:"bytes"`
+	Writes        int64   `json:"writes"`
+	Retries       int64   `json:"retries"`
+	Aborted       int64   `json:"aborted"`
+	Failed        int64   `json:"failed"`
+	Seconds       float64 `json:"seconds"`
+	RowsPerSecond float64 `json:"rowsPerSecond"`
+	LatencyP50    float64 `json:"latencyP50Ms"`
+	LatencyP95    float64 `json:"latencyP95Ms"`
+	LatencyP99    float64 `json:"latencyP99Ms"`
+}
+
+var severityNames = map[severity]string{
+	warning:    "warning",
+	note:       "note",
+	suggestion: "suggestion",
+	errors:     "error",
+}
+
+// BuildJSONReport builds the machine-readable version of the report.
+func BuildJSONReport(driverName string, conv *Conv, badWrites map[string]int64) JSONReport {
+	reports := AnalyzeTables(conv, badWrites)
+	r := JSONReport{
+		SchemaVersion:        JSONReportSchemaVersion,
+		Driver:               driverName,
+		MigrationType:        conv.Audit.MigrationType.String(),
+		DryRun:               conv.Audit.DryRun,
+		IgnoredStatements:    IgnoredStatements(conv),
+		Tables:               []JSONTableReport{},
+		UnexpectedConditions: make(map[string]int64),
+		UniqueViolations:     make(map[string]int64),
+		FkViolations:         make(map[string]int64),
+		DuplicateKeys:        make(map[string][]DuplicateKey),
+		OversizedValues:      make(map[string]int64),
+		UnsignedOverflows:    make(map[string]int64),
+		InvalidDates:         make(map[string]map[string]int64),
+		MaterializedViews:    []JSONMaterializedView{},
+		Summary: JSONReportSummary{
+			Text:        GenerateSummary(conv, reports, badWrites),
+			Tables:      len(reports),
+			IssueCounts: make(map[string]int64),
+		},
+	}
+	if r.IgnoredStatements == nil {
+		r.IgnoredStatements = []string{}
+	}
+	for _, t := range reports {
+		tr := buildJSONTableReport(conv, t, badWrites)
+		for _, i := range tr.Issues {
+			r.Summary.IssueCounts[i.Name]++
+		}
+		r.Summary.Cols += tr.Cols
+		r.Summary.Warnings += tr.Warnings
+		r.Summary.Rows += tr.Rows
+		r.Summary.BadRows += tr.BadConversionRows + tr.BadWriteRows
+		r.Tables = append(r.Tables, tr)
+	}
+	for s, n := range conv.Stats.Unexpected {
+		r.UnexpectedConditions[s] = n
+	}
+	for idx, n := range conv.Stats.UniqueViolations {
+		r.UniqueViolations[idx] = n
+	}
+	for fk, n := range conv.Stats.FkViolations {
+		r.FkViolations[fk] = n
+	}
+	for idx, keys := range conv.Stats.DuplicateKeys {
+		r.DuplicateKeys[idx] = keys
+	}
+	for t, n := range conv.Stats.Oversized {
+		r.OversizedValues[t] = n
+	}
+	for t, n := range conv.Stats.UnsignedOverflow {
+		r.UnsignedOverflows[t] = n
+	}
+	for t, cols := range conv.Stats.InvalidDates {
+		r.InvalidDates[t] = make(map[string]int64)
+		for c, n := range cols {
+			r.InvalidDates[t][c] = n
+		}
+	}
+	for _, v := range conv.MaterializedViews {
+		mv := JSONMaterializedView{Name: v.Name, Definition: v.Definition}
+		if v.Table {
+			mv.SpTable = conv.ToSpanner[v.Name].Name
+		}
+		r.MaterializedViews = append(r.MaterializedViews, mv)
+	}
+	sort.Slice(r.MaterializedViews, func(i, j int) bool { return r.MaterializedViews[i].Name < r.MaterializedViews[j].Name })
+	if stats := conv.Audit.StreamingStats; stats.Streaming {
+		r.Streaming = &JSONStreamingReport{
+			TotalRecords:      stats.TotalRecords,
+			BadRecords:        stats.BadRecords,
+			DroppedRecords:    stats.DroppedRecords,
+			StaleRecords:      stats.StaleRecords,
+			UnknownAttributes: stats.UnknownAttributes,
+			AddedColumns:      stats.AddedColumns,
+		}
+	}
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	for _, p := range conv.WriteStats.Tables() {
+		r.WritePerformance = append(r.WritePerformance, JSONWritePerformance{
+			SpTable:       p.Table,
+			Rows:          p.Rows,
+			Bytes:         p.Bytes,
+			Writes:        p.Writes,
+			Retries:       p.Retries,
+			Aborted:       p.Aborted,
+			Failed:        p.Failed,
+			Seconds:       p.Duration.Seconds(),
+			RowsPerSecond: p.RowsPerSecond,
+			LatencyP50:    ms(p.LatencyP50),
+			LatencyP95:    ms(p.LatencyP95),
+			LatencyP99:    ms(p.LatencyP99),
+		})
+	}
+	return r
+}
+
+func buildJSONTableReport(conv *Conv, t tableReport, badWrites map[string]int64) JSONTableReport {
+	tr := JSONTableReport{
+		SrcTable:            t.SrcTable,
+		SpTable:             t.SpTable,
+		Cols:                t.Cols,
+		Warnings:            t.Warnings,
+		SyntheticPrimaryKey: t.SyntheticPKey,
+		Columns:             []JSONColumnMapping{},
+		Issues:              []JSONIssue{},
+		Rows:                t.rows,
+		BadConversionRows:   conv.Stats.BadRows[t.SrcTable],
+		BadWriteRows:        badWrites[t.SrcTable],
+	}
+	srcSchema := conv.SrcSchema[t.SrcTable]
+	spSchema := conv.SpSchema[t.SpTable]
+	for _, srcCol := range srcSchema.ColNames {
+		m := JSONColumnMapping{SrcCol: srcCol, SrcType: srcSchema.ColDefs[srcCol].Type.Print()}
+		if spCol, err := GetSpannerCol(conv, t.SrcTable, srcCol, true); err == nil {
+			if cd, ok := spSchema.ColDefs[spCol]; ok {
+				m.SpCol = spCol
+				m.SpType = strings.ToLower(cd.T.PrintColumnDefType())
+			}
+		}
+		tr.Columns = append(tr.Columns, m)
+	}
+	issues := conv.activeIssues(t.SrcTable)
+	var cols []string
+	for c := range issues {
+		cols = append(cols, c)
+	}
+	sort.Strings(cols)
+	for _, c := range cols {
+		for _, i := range issues[c] {
+			tr.Issues = append(tr.Issues, JSONIssue{
+				Column:      c,
+				Name:        i.Name(),
+				Severity:    severityNames[IssueDB[i].severity],
+				Description: IssueDB[i].Brief,
+			})
+		}
+	}
+	return tr
+}
+
+// GenerateJSONReport writes the machine-readable version of the report to w
+// as indented JSON.
+func GenerateJSONReport(driverName string, conv *Conv, w io.Writer, badWrites map[string]int64) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(BuildJSONReport(driverName, conv, badWrites))
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateJSONReport(t *testing.T) {
+	conv := buildReportConv()
+	buf := new(bytes.Buffer)
+	assert.Nil(t, GenerateJSONReport("mysql", conv, buf, map[string]int64{"t1": 5}))
+
+	var r JSONReport
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &r))
+	assert.Equal(t, JSONReportSchemaVersion, r.SchemaVersion)
+	assert.Equal(t, "mysql", r.Driver)
+	assert.Equal(t, "SCHEMA_AND_DATA", r.MigrationType)
+	assert.Equal(t, map[string]int64{"NoGoodType": 1}, r.Summary.IssueCounts)
+	assert.Equal(t, int64(100), r.Summary.Rows)
+	assert.Equal(t, int64(15), r.Summary.BadRows)
+	assert.Equal(t, map[string]int64{"<unexpected>": 2}, r.UnexpectedConditions)
+	assert.Nil(t, r.Streaming)
+	assert.Equal(t, []JSONTableReport{{
+		SrcTable: "t1",
+		SpTable:  "t1",
+		Cols:     2,
+		Warnings: 1,
+		Columns: []JSONColumnMapping{
+			{SrcCol: "a", SrcType: "bigint", SpCol: "a", SpType: "int64"},
+			{SrcCol: "b", SrcType: "float", SpCol: "b", SpType: "string(max)"},
+		},
+		Issues: []JSONIssue{
+			{Column: "b", Name: "NoGoodType", Severity: "warning", Description: IssueDB[NoGoodType].Brief},
+		},
+		Rows:              100,
+		BadConversionRows: 10,
+		BadWriteRows:      5,
+	}}, r.Tables)
+}
+
+func TestSchemaIssueName(t *testing.T) {
+	for i := range IssueDB {
+		assert.NotContains(t, i.Name(), "SchemaIssue(")
+	}
+	assert.Equal(t, "MissingPrimaryKey", MissingPrimaryKey.Name())
+	assert.Equal(t, "SchemaIssue(1000)", SchemaIssue(1000).Name())
+}
@@ -0,0 +1,206 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+func TestWriteUniqueViolations(t *testing.T) {
+	conv := MakeConv()
+	conv.Stats.UniqueViolations["idx_b"] = 2
+	conv.Stats.UniqueViolations["idx_a"] = 10
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeUniqueViolations(conv, w)
+	w.Flush()
+	expected := `----------------------------
+Unique Index Violations
+----------------------------
+The following unique indexes rejected rows during data migration. Since the
+source database enforces the same uniqueness constraint, this indicates that
+distinct source values were converted to equal Spanner values, for example
+because of loss of precision in numeric or timestamp conversions, or collations
+that ignore trailing spaces. Rejected rows are reported as bad rows in the bad
+data file.
+
+  --------------------------------------
+    rows  index
+  --------------------------------------
+      10  idx_a
+       2  idx_b
+
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteShards(t *testing.T) {
+	conv := MakeConv()
+	conv.Shards = NewShards([]string{"shard_b", "shard_a"}, "")
+	conv.SetShardStats("shard_a", ShardStats{Rows: 12, BadRows: 1})
+	conv.SetShardStats("shard_b", ShardStats{Rows: 3})
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeShards(conv, w)
+	w.Flush()
+	expected := `----------------------------
+Source Databases
+----------------------------
+The following source databases were merged into the Spanner database. The rows of
+each database are identified by the value of the shard_id column, which is the
+first primary key column of every table.
+
+  --------------------------------------
+      rows  bad rows  database
+  --------------------------------------
+         3         0  shard_b
+        12         1  shard_a
+
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteDuplicateKeys(t *testing.T) {
+	conv := MakeConv()
+	conv.Stats.DuplicateKeys["idx_b"] = []DuplicateKey{{Key: `"x"`, Rows: 2}}
+	conv.Stats.DuplicateKeys["idx_a"] = []DuplicateKey{{Key: `1, "a"`, Rows: 3}, {Key: `2, NULL`, Rows: 2}}
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeDuplicateKeys(conv, w)
+	w.Flush()
+	expected := `----------------------------
+Duplicate Unique Index Keys
+----------------------------
+The following unique indexes were not created because some keys are held by more
+than one row of the migrated data. This typically happens when distinct source
+values were converted to equal Spanner values, or when the source database
+doesn't enforce the constraint. Fix the data and add the indexes using the
+statements in the schema file.
+
+  idx_a
+  --------------------------------------
+    rows  key
+  --------------------------------------
+       3  1, "a"
+       2  2, NULL
+
+  idx_b
+  --------------------------------------
+    rows  key
+  --------------------------------------
+       2  "x"
+
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteFkViolations(t *testing.T) {
+	conv := MakeConv()
+	conv.Stats.FkViolations["fk_b"] = 3
+	conv.Stats.FkViolations["fk_a"] = 12
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeFkViolations(conv, w)
+	w.Flush()
+	expected := `----------------------------
+Foreign Key Violations
+----------------------------
+The following foreign keys were not created because some rows reference rows that
+don't exist in the referenced table. This typically happens when the source
+database doesn't enforce the constraint. Fix the data and add the constraints
+using the statements in the schema file.
+
+  --------------------------------------
+    rows  foreign key
+  --------------------------------------
+      12  fk_a
+       3  fk_b
+
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteAttributeCensus(t *testing.T) {
+	conv := MakeConv()
+	conv.SrcSchema["orders"] = schema.Table{Name: "orders", ColDefs: map[string]schema.Column{"id": {Name: "id"}, "note": {Name: "note"}}}
+	conv.AddAttributeCensus("orders", AttributeCensus{Items: 200, Full: true, Attributes: map[string]int64{"id": 200, "note": 50, "gift": 1},
+		Numbers: map[string]NumberPrecision{"id": {Values: 200, IntegerDigits: 31, Scale: 2, Exceeding: 3}, "gift": {Values: 1, IntegerDigits: 4}}})
+	conv.AddAttributeCensus("users", AttributeCensus{Items: 10, Attributes: map[string]int64{"id": 10}})
+	conv.SrcSchema["visits"] = schema.Table{Name: "visits", ColDefs: map[string]schema.Column{"id": {Name: "id"}, "other_attributes": {Name: "other_attributes"}}}
+	conv.AddAttributeCensus("visits", AttributeCensus{Items: 100, Attributes: map[string]int64{"id": 100, "ref": 2}, CatchAll: "other_attributes"})
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeAttributeCensus(conv, w)
+	w.Flush()
+	expected := `----------------------------
+Attribute Frequency
+----------------------------
+Schema inference read the following items of each table, and found these
+attributes. Attributes missing from a sample don't get a column, so read all the
+items to find rare attributes. Sparse attributes may be better kept in a
+catch-all JSON column than as columns of their own. Numbers with more than 29
+integer or 9 fractional digits exceed NUMERIC, and are better mapped to FLOAT64
+(losing precision) or STRING.
+
+  Table orders: 200 items read (all items)
+    id                             100.00%
+      numbers: up to 31 integer and 2 fractional digits, 3 of 200 values exceed NUMERIC
+    note                            25.00%
+    gift                             0.50% (no column)
+      numbers: up to 4 integer and 0 fractional digits
+  Table users: 10 items read (sample)
+    id                             100.00% (no column)
+  Table visits: 100 items read (sample)
+    id                             100.00%
+    ref                              2.00% (in other_attributes)
+
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteMaterializedViews(t *testing.T) {
+	conv := MakeConv()
+	conv.AddMaterializedView("totals", " SELECT product,\n    sum(qty) AS total\n   FROM orders\n  GROUP BY product;", true)
+	conv.AddMaterializedView("recent", " SELECT * FROM orders;", false)
+	conv.ToSpanner["totals"] = NameAndCols{Name: "totals"}
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeMaterializedViews(conv, w)
+	w.Flush()
+	expected := `----------------------------
+Materialized Views
+----------------------------
+Spanner doesn't support materialized views. Views converted into tables are
+populated once during data migration, and must be refreshed by the application
+e.g. by re-running the view's query and writing its results. Other views were not
+migrated.
+
+  recent: not migrated
+    SELECT * FROM orders;
+  totals: converted into table totals
+    SELECT product,
+        sum(qty) AS total
+       FROM orders
+      GROUP BY product;
+
+`
+	assert.Equal(t, expected, buf.String())
+}
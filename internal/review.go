@@ -0,0 +1,146 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Review states of a schema issue. An issue without a review is unreviewed.
+const (
+	// ReviewResolved means the schema has been changed to address the issue,
+	// or the issue doesn't apply to the data.
+	ReviewResolved = "resolved"
+	// ReviewAcknowledged means the consequences of the issue are accepted.
+	ReviewAcknowledged = "acknowledged"
+	// ReviewOverridden means the issue is deliberately ignored, for a reason
+	// recorded in the review comment.
+	ReviewOverridden = "overridden"
+)
+
+// IssueReview is the review of a schema issue of a source column, or of a
+// source table for issues such as MissingPrimaryKey.
+type IssueReview struct {
+	State   string // One of ReviewResolved, ReviewAcknowledged and ReviewOverridden.
+	Comment string
+}
+
+// ReviewItem is a schema issue in the review queue.
+type ReviewItem struct {
+	Table    string // Source table name.
+	Column   string // Source column name, empty for table issues.
+	SpTable  string // Spanner table name.
+	SpColumn string // Spanner column name, empty for table issues.
+	Issue    string // Issue name, as returned by SchemaIssue.Name.
+	Severity string // One of SeverityError, SeverityWarning and SeverityInfo.
+	Brief    string // Description of the issue.
+	IssueReview
+}
+
+// ReviewKey returns the key of the review of issue of source column
+// table.column in Conv.IssueReviews.
+func ReviewKey(table, column, issue string) string {
+	return fmt.Sprintf("%s.%s.%s", table, column, issue)
+}
+
+// ReviewQueue returns every outstanding schema issue of conv along with its
+// review, ordered by Spanner table and column. Suppressed issues (see
+// IssueSuppression) aren't outstanding.
+func (conv *Conv) ReviewQueue() []ReviewItem {
+	var l []ReviewItem
+	var tables []string
+	for t := range conv.SpSchema {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, spTable := range tables {
+		srcTable := conv.ToSource[spTable].Name
+		if pk, ok := conv.SyntheticPKeys[spTable]; ok && !conv.IsSuppressed(srcTable, "", MissingPrimaryKey) {
+			brief := fmt.Sprintf("Column '%s' was added because this table didn't have a primary key", pk.Col)
+			l = append(l, conv.reviewItem(srcTable, "", spTable, "", MissingPrimaryKey.Name(), SeverityWarning, brief))
+		}
+		for _, spCol := range conv.SpSchema[spTable].ColNames {
+			srcCol, ok := conv.ToSource[spTable].Cols[spCol]
+			if !ok {
+				continue
+			}
+			for _, i := range conv.Issues[srcTable][srcCol] {
+				if conv.IsSuppressed(srcTable, srcCol, i) {
+					continue
+				}
+				l = append(l, conv.reviewItem(srcTable, srcCol, spTable, spCol, i.Name(), i.Severity(), IssueDB[i].Brief))
+			}
+		}
+	}
+	return l
+}
+
+func (conv *Conv) reviewItem(table, column, spTable, spColumn, issue, severity, brief string) ReviewItem {
+	return ReviewItem{
+		Table:       table,
+		Column:      column,
+		SpTable:     spTable,
+		SpColumn:    spColumn,
+		Issue:       issue,
+		Severity:    severity,
+		Brief:       brief,
+		IssueReview: conv.IssueReviews[ReviewKey(table, column, issue)],
+	}
+}
+
+// ReviewIssue records the review of issue of source column table.column (or
+// of source table table, if column is empty). An empty state removes the
+// review. It returns an error if the issue isn't in the review queue.
+func (conv *Conv) ReviewIssue(table, column, issue, state, comment string) error {
+	switch state {
+	case "", ReviewResolved, ReviewAcknowledged, ReviewOverridden:
+	default:
+		return fmt.Errorf("unknown review state '%s': available choices(%s, %s, %s)", state, ReviewResolved, ReviewAcknowledged, ReviewOverridden)
+	}
+	found := false
+	for _, item := range conv.ReviewQueue() {
+		if item.Table == table && item.Column == column && item.Issue == issue {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("issue %s not found for table %s column %s", issue, table, column)
+	}
+	k := ReviewKey(table, column, issue)
+	if state == "" {
+		delete(conv.IssueReviews, k)
+		return nil
+	}
+	if conv.IssueReviews == nil {
+		conv.IssueReviews = make(map[string]IssueReview)
+	}
+	conv.IssueReviews[k] = IssueReview{State: state, Comment: comment}
+	return nil
+}
+
+// UnreviewedIssues returns the issues of the review queue that must be
+// reviewed before the schema is applied: errors and warnings without a
+// review. Informational issues don't need a review.
+func (conv *Conv) UnreviewedIssues() []ReviewItem {
+	var l []ReviewItem
+	for _, item := range conv.ReviewQueue() {
+		if item.State == "" && item.Severity != SeverityInfo {
+			l = append(l, item)
+		}
+	}
+	return l
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviewQueue(t *testing.T) {
+	conv := MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{Name: "t1", ColNames: []string{"a", "b", "synth_id"}}
+	conv.SpSchema["t2"] = ddl.CreateTable{Name: "t2", ColNames: []string{"c"}}
+	conv.ToSource["t1"] = NameAndCols{Name: "T1", Cols: map[string]string{"a": "A", "b": "B"}}
+	conv.ToSource["t2"] = NameAndCols{Name: "t2", Cols: map[string]string{"c": "c"}}
+	conv.SyntheticPKeys["t1"] = SyntheticPKey{Col: "synth_id"}
+	conv.Issues["T1"] = map[string][]SchemaIssue{"A": {NoGoodType}, "B": {Widened}}
+	conv.Issues["t2"] = map[string][]SchemaIssue{"c": {Numeric}}
+	conv.SetIssueSuppressions([]IssueSuppression{{Table: "t2"}})
+
+	q := conv.ReviewQueue()
+	assert.Equal(t, 3, len(q))
+	assert.Equal(t, ReviewItem{Table: "T1", SpTable: "t1", Issue: "MissingPrimaryKey", Severity: SeverityWarning, Brief: "Column 'synth_id' was added because this table didn't have a primary key"}, q[0])
+	assert.Equal(t, ReviewItem{Table: "T1", Column: "A", SpTable: "t1", SpColumn: "a", Issue: "NoGoodType", Severity: SeverityWarning, Brief: IssueDB[NoGoodType].Brief}, q[1])
+	assert.Equal(t, "Widened", q[2].Issue)
+	assert.Equal(t, SeverityInfo, q[2].Severity)
+	assert.Equal(t, 2, len(conv.UnreviewedIssues()))
+
+	assert.Nil(t, conv.ReviewIssue("T1", "A", "NoGoodType", ReviewOverridden, "data is ASCII"))
+	assert.Nil(t, conv.ReviewIssue("T1", "", "MissingPrimaryKey", ReviewAcknowledged, ""))
+	assert.Equal(t, IssueReview{State: ReviewOverridden, Comment: "data is ASCII"}, conv.ReviewQueue()[1].IssueReview)
+	assert.Equal(t, 0, len(conv.UnreviewedIssues()))
+
+	// Reviews can be removed.
+	assert.Nil(t, conv.ReviewIssue("T1", "A", "NoGoodType", "", ""))
+	assert.Equal(t, 1, len(conv.UnreviewedIssues()))
+
+	assert.NotNil(t, conv.ReviewIssue("T1", "A", "NoGoodType", "ignored", ""))
+	assert.NotNil(t, conv.ReviewIssue("T1", "A", "Widened", ReviewResolved, ""))
+	assert.NotNil(t, conv.ReviewIssue("t2", "c", "Numeric", ReviewResolved, ""))
+}
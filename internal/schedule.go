@@ -0,0 +1,204 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Window is a daily time window of a migration schedule, from Start to End
+// (offsets from midnight). A window ending before it starts spans midnight.
+type Window struct {
+	Start, End time.Duration
+}
+
+// contains returns true if the time of day d is in w.
+func (w Window) contains(d time.Duration) bool {
+	if w.Start <= w.End {
+		return d >= w.Start && d < w.End
+	}
+	return d >= w.Start || d < w.End
+}
+
+func (w Window) String() string {
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// clock formats the time of day d as HH:MM.
+func clock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// Schedule restricts data migration to daily time windows, in the time zone
+// of the source database, for sources that can't tolerate the read load of
+// a migration during the day. Outside the windows, data migration pauses at
+// its next checkpoint (the start of a table, or of a chunk of rows for
+// sources that read tables in chunks) and resumes from there once a window
+// opens.
+type Schedule struct {
+	Windows  []Window
+	Location *time.Location
+	out      io.Writer                                       // Where pauses and resumptions are printed.
+	now      func() time.Time                                // Returns the current time.
+	sleep    func(ctx context.Context, d time.Duration) bool // Pauses for d, returns false if ctx is done first.
+	mu       sync.Mutex
+	paused   bool
+}
+
+// ParseSchedule parses a comma separated list of daily windows of the form
+// HH:MM-HH:MM, e.g. "00:00-06:00,22:00-23:30", in the time zone tz (an IANA
+// time zone name, or "" for the local time zone). An empty list of windows
+// is no schedule, returned as nil.
+func ParseSchedule(windows, tz string) (*Schedule, error) {
+	if strings.TrimSpace(windows) == "" {
+		return nil, nil
+	}
+	loc := time.Local
+	if tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid time zone %s: %v", tz, err)
+		}
+	}
+	s := &Schedule{Location: loc}
+	for _, w := range strings.Split(windows, ",") {
+		bounds := strings.Split(strings.TrimSpace(w), "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid window %q, expected HH:MM-HH:MM", w)
+		}
+		start, err := parseClock(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %v", w, err)
+		}
+		end, err := parseClock(bounds[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %v", w, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("invalid window %q: it's empty", w)
+		}
+		s.Windows = append(s.Windows, Window{Start: start, End: end})
+	}
+	return s, nil
+}
+
+// parseClock parses a time of day of the form HH:MM, with 24:00 for the
+// end of the day.
+func parseClock(s string) (time.Duration, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &h, &m); err != nil || n != 2 || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func (s *Schedule) String() string {
+	var l []string
+	for _, w := range s.Windows {
+		l = append(l, w.String())
+	}
+	return strings.Join(l, ", ") + " " + s.Location.String()
+}
+
+// Open returns true if t is in a window of s. Otherwise, it also returns when
+// the next window opens.
+func (s *Schedule) Open(t time.Time) (bool, time.Time) {
+	t = t.In(s.Location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.Location)
+	for _, w := range s.Windows {
+		if w.contains(t.Sub(midnight)) {
+			return true, time.Time{}
+		}
+	}
+	var next time.Time
+	for day := 0; day <= 1; day++ {
+		for _, w := range s.Windows {
+			// Days aren't always 24 hours long.
+			opens := time.Date(t.Year(), t.Month(), t.Day()+day, 0, 0, 0, 0, s.Location).Add(w.Start)
+			if opens.After(t) && (next.IsZero() || opens.Before(next)) {
+				next = opens
+			}
+		}
+	}
+	return false, next
+}
+
+// Wait pauses until a window of s is open, or ctx is done. It returns
+// immediately if s is nil.
+func (s *Schedule) Wait(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	for {
+		open, next := s.Open(s.clock())
+		s.mu.Lock()
+		if open != !s.paused {
+			s.paused = !open
+			if open {
+				fmt.Fprintf(s.output(), "Migration window open (%s): resuming data migration.\n", s)
+			} else {
+				fmt.Fprintf(s.output(), "Outside migration windows (%s): pausing data migration until %s.\n", s, next.Format(time.RFC1123))
+			}
+		}
+		s.mu.Unlock()
+		if open {
+			return nil
+		}
+		d := next.Sub(s.clock())
+		// Recheck at least every minute, in case the system clock changes.
+		if d > time.Minute {
+			d = time.Minute
+		}
+		sleep := sleepCtx
+		if s.sleep != nil {
+			sleep = s.sleep
+		}
+		if !sleep(ctx, d) {
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Schedule) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func (s *Schedule) output() io.Writer {
+	if s.out != nil {
+		return s.out
+	}
+	return os.Stdout
+}
+
+// sleepCtx pauses for d, and returns false if ctx is done first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
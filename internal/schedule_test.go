@@ -0,0 +1,97 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	s, err := ParseSchedule("", "")
+	assert.Nil(t, err)
+	assert.Nil(t, s)
+
+	s, err = ParseSchedule("00:00-06:00, 22:30-24:00", "America/New_York")
+	assert.Nil(t, err)
+	assert.Equal(t, []Window{{0, 6 * time.Hour}, {22*time.Hour + 30*time.Minute, 24 * time.Hour}}, s.Windows)
+	assert.Equal(t, "00:00-06:00, 22:30-24:00 America/New_York", s.String())
+
+	for _, bad := range []string{"00:00", "0-6", "06:00-06:00", "25:00-06:00", "00:60-06:00", "24:30-06:00"} {
+		_, err = ParseSchedule(bad, "")
+		assert.NotNil(t, err, bad)
+	}
+	_, err = ParseSchedule("00:00-06:00", "Mars/Olympus_Mons")
+	assert.NotNil(t, err)
+}
+
+func TestScheduleOpen(t *testing.T) {
+	s, err := ParseSchedule("22:00-02:00,12:00-13:00", "UTC")
+	assert.Nil(t, err)
+	at := func(h, m int) time.Time { return time.Date(2022, 5, 1, h, m, 0, 0, time.UTC) }
+
+	for _, tc := range []struct {
+		t    time.Time
+		open bool
+		next time.Time
+	}{
+		{at(23, 0), true, time.Time{}},
+		{at(1, 59), true, time.Time{}},
+		{at(12, 30), true, time.Time{}},
+		{at(2, 0), false, at(12, 0)},
+		{at(13, 0), false, at(22, 0)},
+		// Times are converted to the time zone of the schedule.
+		{time.Date(2022, 5, 1, 8, 0, 0, 0, time.FixedZone("UTC-4", -4*3600)), true, time.Time{}},
+	} {
+		open, next := s.Open(tc.t)
+		assert.Equal(t, tc.open, open, tc.t)
+		assert.True(t, tc.next.Equal(next), tc.t)
+	}
+
+	s, _ = ParseSchedule("01:00-05:00", "UTC")
+	_, next := s.Open(at(6, 0))
+	assert.True(t, time.Date(2022, 5, 2, 1, 0, 0, 0, time.UTC).Equal(next))
+}
+
+func TestScheduleWait(t *testing.T) {
+	assert.Nil(t, (*Schedule)(nil).Wait(context.Background()))
+
+	s, _ := ParseSchedule("01:00-05:00", "UTC")
+	now := time.Date(2022, 5, 1, 0, 58, 30, 0, time.UTC)
+	var out bytes.Buffer
+	var slept []time.Duration
+	s.out, s.now = &out, func() time.Time { return now }
+	s.sleep = func(ctx context.Context, d time.Duration) bool {
+		slept = append(slept, d)
+		now = now.Add(d)
+		return true
+	}
+	assert.Nil(t, s.Wait(context.Background()))
+	assert.Equal(t, []time.Duration{time.Minute, 30 * time.Second}, slept)
+	assert.Equal(t, "Outside migration windows (01:00-05:00 UTC): pausing data migration until Sun, 01 May 2022 01:00:00 UTC.\n"+
+		"Migration window open (01:00-05:00 UTC): resuming data migration.\n", out.String())
+
+	// Waits are interrupted once ctx is done.
+	now = time.Date(2022, 5, 1, 6, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.sleep = sleepCtx
+	assert.Equal(t, context.Canceled, s.Wait(ctx))
+}
@@ -0,0 +1,43 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// ToSpannerSequence returns the bit-reversed Spanner sequence that replaces
+// source sequence seq of column spCol of Spanner table spTable. The sequence
+// never generates values up to the last value of seq, so that inserts after
+// cutover don't collide with migrated rows, and its counter starts beyond it.
+func ToSpannerSequence(conv *Conv, spTable, spCol string, seq schema.Sequence) *ddl.Sequence {
+	sq := &ddl.Sequence{Name: getSpannerID(conv, spTable+"_"+spCol+"_seq"), StartWithCounter: seq.LastValue + 1}
+	if seq.LastValue > 0 {
+		sq.SkipRangeMin, sq.SkipRangeMax = 1, seq.LastValue
+	}
+	return sq
+}
+
+// hasSourceSequence returns true if Spanner column spCol of spTable maps to
+// a source column whose values are generated by a source sequence.
+func (conv *Conv) hasSourceSequence(spTable, spCol string) bool {
+	srcTable, err := GetSourceTable(conv, spTable)
+	if err != nil {
+		return false
+	}
+	srcCol, ok := conv.ToSource[spTable].Cols[spCol]
+	return ok && conv.SrcSchema[srcTable].ColDefs[srcCol].Sequence != nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// Ways of handling tables with the same name in several source databases
+// (shards) that are merged into one Spanner database.
+const (
+	// TableCollisionMerge migrates tables with the same name to a single
+	// Spanner table. The tables must have the same columns. This is the
+	// default, and is the usual choice for a sharded database.
+	TableCollisionMerge = "merge"
+	// TableCollisionPrefix migrates tables with the same name to separate
+	// Spanner tables, whose names are prefixed with the shard id.
+	TableCollisionPrefix = "prefix"
+	// TableCollisionFail fails the migration.
+	TableCollisionFail = "fail"
+)
+
+// DefaultShardIdColumn is the default name of the column recording the shard
+// of each row.
+const DefaultShardIdColumn = "shard_id"
+
+// Shards describes the source databases (shards) merged into one Spanner
+// database. Source tables migrated from a single shard whose name collides
+// with a table of another shard are named "<shard id>.<table>" in
+// conv.SrcSchema (see TableCollisionPrefix).
+type Shards struct {
+	Ids      []string              // Ids of the shards, in the order they are migrated.
+	IdColumn string                // Name of the column added to every Spanner table to record the shard of each row.
+	Tables   map[string][]string   // Maps source table name to the ids of the shards it is migrated from.
+	Stats    map[string]ShardStats // Maps shard id to its data conversion stats.
+	Names    map[string]string     // Maps shard id to the pattern for Spanner names of its tables, indexes and foreign keys (see ValidNamePattern).
+	current  string                // Id of the shard whose data is being migrated.
+}
+
+// ShardStats contains the data conversion stats of a shard.
+type ShardStats struct {
+	Rows    int64 // Count of rows of the shard.
+	BadRows int64 // Count of rows of the shard where conversion failed.
+}
+
+// NewShards returns a Shards for the shards ids, using the column idColumn to
+// record the shard of each row.
+func NewShards(ids []string, idColumn string) *Shards {
+	if idColumn == "" {
+		idColumn = DefaultShardIdColumn
+	}
+	return &Shards{Ids: ids, IdColumn: idColumn, Tables: make(map[string][]string), Stats: make(map[string]ShardStats)}
+}
+
+// ValidNamePattern returns an error if pattern isn't a valid pattern for
+// the Spanner names of the tables, indexes and foreign keys of a shard. A
+// pattern contains a single '*', which is replaced by the source name e.g.
+// "billing_*" maps table invoices to billing_invoices, and "*_eu" maps it to
+// invoices_eu.
+func ValidNamePattern(pattern string) error {
+	if strings.Count(pattern, "*") != 1 {
+		return fmt.Errorf("name pattern '%s' must contain exactly one '*'", pattern)
+	}
+	if fixed, _ := FixName(strings.Replace(pattern, "*", "t", 1)); fixed != strings.Replace(pattern, "*", "t", 1) {
+		return fmt.Errorf("name pattern '%s' doesn't produce valid Spanner names", pattern)
+	}
+	return nil
+}
+
+// SetShard configures conv to migrate the data of shard id.
+func (conv *Conv) SetShard(id string) {
+	conv.Shards.current = id
+}
+
+// ShardTable returns the name in conv.SrcSchema of the table name of the
+// shard being migrated.
+func (conv *Conv) ShardTable(name string) string {
+	if conv.Shards == nil {
+		return name
+	}
+	if q := conv.Shards.current + "." + name; len(conv.Shards.Tables[q]) > 0 {
+		return q
+	}
+	return name
+}
+
+// ShardTableName returns the name of source table srcTable in the database
+// of the shard being migrated. It is the inverse of ShardTable.
+func (conv *Conv) ShardTableName(srcTable string) string {
+	if conv.Shards == nil {
+		return srcTable
+	}
+	if name := strings.TrimPrefix(srcTable, conv.Shards.current+"."); name != srcTable && conv.ShardTable(name) == srcTable {
+		return name
+	}
+	return srcTable
+}
+
+// InShard returns true if source table srcTable is migrated from the shard
+// being migrated. It is always true if conv isn't merging shards.
+func (conv *Conv) InShard(srcTable string) bool {
+	if conv.Shards == nil {
+		return true
+	}
+	for _, id := range conv.Shards.Tables[srcTable] {
+		if id == conv.Shards.current {
+			return true
+		}
+	}
+	return false
+}
+
+// NamedShard returns the id of the shard whose name pattern applies to the
+// Spanner names of source table srcTable, and "" if there is none. Tables
+// migrated from several shards don't have a name pattern.
+func (conv *Conv) NamedShard(srcTable string) string {
+	if conv.Shards == nil {
+		return ""
+	}
+	if ids := conv.Shards.Tables[srcTable]; len(ids) == 1 && conv.Shards.Names[ids[0]] != "" {
+		return ids[0]
+	}
+	return ""
+}
+
+// ShardSpannerName applies the name pattern of the shard of source table
+// srcTable, if any, to name, the source name of the table or of one of its
+// indexes or foreign keys. Qualified table names, such as "shard.orders",
+// are unqualified first. Since patterns are applied before names are made
+// unique, the tables, indexes and foreign keys of a shard are named
+// consistently.
+func (conv *Conv) ShardSpannerName(srcTable, name string) string {
+	id := conv.NamedShard(srcTable)
+	if id == "" || name == "" {
+		return name
+	}
+	return strings.Replace(conv.Shards.Names[id], "*", strings.TrimPrefix(name, id+"."), 1)
+}
+
+// AddShardIdColumn adds the shard id column to every Spanner table as the
+// first primary key column, so that rows with the same key in different
+// shards don't collide. The column is also added to unique indexes and to
+// both sides of foreign keys, since uniqueness and references only hold
+// within a shard.
+func (conv *Conv) AddShardIdColumn() error {
+	col := conv.Shards.IdColumn
+	if fixed, _ := FixName(col); fixed != col {
+		return fmt.Errorf("'%s' is not a valid Spanner column name", col)
+	}
+	for t, ct := range conv.SpSchema {
+		for c := range ct.ColDefs {
+			if strings.EqualFold(c, col) {
+				return fmt.Errorf("table %s already has a column named %s", t, c)
+			}
+		}
+	}
+	for t, ct := range conv.SpSchema {
+		ct.ColNames = append([]string{col}, ct.ColNames...)
+		ct.ColDefs[col] = ddl.ColumnDef{Name: col, T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, NotNull: true}
+		ct.Pks = append([]ddl.IndexKey{{Col: col}}, ct.Pks...)
+		for i, idx := range ct.Indexes {
+			if idx.Unique {
+				ct.Indexes[i].Keys = append([]ddl.IndexKey{{Col: col}}, idx.Keys...)
+			}
+		}
+		for i, fk := range ct.Fks {
+			ct.Fks[i].Columns = append([]string{col}, fk.Columns...)
+			ct.Fks[i].ReferColumns = append([]string{col}, fk.ReferColumns...)
+		}
+		conv.SpSchema[t] = ct
+	}
+	return nil
+}
+
+// ShardConv returns a copy of conv for migrating the data of shard id in
+// parallel with other shards. Data conversion state, such as stats and
+// synthetic key sequences, is separate from conv, and is added back to conv
+// by MergeShardConv. Schema state is shared and must not be modified.
+func (conv *Conv) ShardConv(id string) *Conv {
+	sc := conv.dataConv()
+	shards := *conv.Shards
+	shards.current = id
+	sc.Shards = &shards
+	return sc
+}
+
+// MergeShardConv adds the data conversion state of sc, returned by
+// ShardConv, to conv, and records the stats of its shard.
+func (conv *Conv) MergeShardConv(sc *Conv) {
+	add := func(to, from map[string]int64) {
+		for k, n := range from {
+			to[k] += n
+		}
+	}
+	add(conv.Stats.Rows, sc.Stats.Rows)
+	add(conv.Stats.GoodRows, sc.Stats.GoodRows)
+	add(conv.Stats.BadRows, sc.Stats.BadRows)
+	add(conv.Stats.Unexpected, sc.Stats.Unexpected)
+	add(conv.Stats.UniqueViolations, sc.Stats.UniqueViolations)
+	add(conv.Stats.Oversized, sc.Stats.Oversized)
+	add(conv.Stats.UnsignedOverflow, sc.Stats.UnsignedOverflow)
+	for t, cols := range sc.Stats.InvalidDates {
+		if conv.Stats.InvalidDates[t] == nil {
+			conv.Stats.InvalidDates[t] = make(map[string]int64)
+		}
+		add(conv.Stats.InvalidDates[t], cols)
+	}
+	for k, x := range sc.Stats.Statement {
+		y := conv.getStatementStat(k)
+		y.Schema += x.Schema
+		y.Data += x.Data
+		y.Skip += x.Skip
+		y.Error += x.Error
+	}
+	conv.Stats.Reparsed += sc.Stats.Reparsed
+	for _, r := range sc.sampleBadRows.rows {
+		bytes := byteSize(r)
+		if len(conv.sampleBadRows.rows) == 0 || bytes+conv.sampleBadRows.bytes < conv.sampleBadRows.bytesLimit {
+			conv.sampleBadRows.rows = append(conv.sampleBadRows.rows, r)
+			conv.sampleBadRows.bytes += bytes
+		}
+	}
+	id := sc.Shards.current
+	st := conv.Shards.Stats[id]
+	st.BadRows += sc.BadRows()
+	conv.SetShardStats(id, st)
+}
+
+// SetShardStats sets the data conversion stats of shard id.
+func (conv *Conv) SetShardStats(id string, st ShardStats) {
+	if conv.Shards.Stats == nil {
+		conv.Shards.Stats = make(map[string]ShardStats)
+	}
+	conv.Shards.Stats[id] = st
+}
+
+// shardRow adds the shard id column to a row of the shard being migrated.
+func (conv *Conv) shardRow(spCols []string, spVals []interface{}) ([]string, []interface{}) {
+	if conv.Shards == nil {
+		return spCols, spVals
+	}
+	cols := append([]string{conv.Shards.IdColumn}, spCols...)
+	vals := append([]interface{}{conv.Shards.current}, spVals...)
+	return cols, vals
+}
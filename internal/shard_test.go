@@ -0,0 +1,147 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddShardIdColumn(t *testing.T) {
+	conv := MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"a", "b"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a": {Name: "a", T: ddl.Type{Name: ddl.Int64}},
+			"b": {Name: "b", T: ddl.Type{Name: ddl.Int64}},
+		},
+		Pks:     []ddl.IndexKey{{Col: "a"}},
+		Fks:     []ddl.Foreignkey{{Name: "fk", Columns: []string{"b"}, ReferTable: "t2", ReferColumns: []string{"c"}}},
+		Indexes: []ddl.CreateIndex{{Name: "u", Table: "t1", Unique: true, Keys: []ddl.IndexKey{{Col: "b"}}}, {Name: "i", Table: "t1", Keys: []ddl.IndexKey{{Col: "b"}}}},
+	}
+	conv.SpSchema["t2"] = ddl.CreateTable{
+		Name:     "t2",
+		ColNames: []string{"c"},
+		ColDefs:  map[string]ddl.ColumnDef{"c": {Name: "c", T: ddl.Type{Name: ddl.Int64}}},
+		Pks:      []ddl.IndexKey{{Col: "c"}},
+	}
+	conv.Shards = NewShards([]string{"s1", "s2"}, "A")
+	assert.NotNil(t, conv.AddShardIdColumn())
+	conv.Shards = NewShards([]string{"s1", "s2"}, "")
+	assert.Nil(t, conv.AddShardIdColumn())
+
+	t1 := conv.SpSchema["t1"]
+	assert.Equal(t, []string{"shard_id", "a", "b"}, t1.ColNames)
+	assert.Equal(t, ddl.ColumnDef{Name: "shard_id", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, NotNull: true}, t1.ColDefs["shard_id"])
+	assert.Equal(t, []ddl.IndexKey{{Col: "shard_id"}, {Col: "a"}}, t1.Pks)
+	assert.Equal(t, []string{"shard_id", "b"}, t1.Fks[0].Columns)
+	assert.Equal(t, []string{"shard_id", "c"}, t1.Fks[0].ReferColumns)
+	assert.Equal(t, []ddl.IndexKey{{Col: "shard_id"}, {Col: "b"}}, t1.Indexes[0].Keys)
+	assert.Equal(t, []ddl.IndexKey{{Col: "b"}}, t1.Indexes[1].Keys)
+	assert.Equal(t, []ddl.IndexKey{{Col: "shard_id"}, {Col: "c"}}, conv.SpSchema["t2"].Pks)
+}
+
+func TestShardTables(t *testing.T) {
+	conv := MakeConv()
+	assert.Equal(t, "t1", conv.ShardTable("t1"))
+	assert.Equal(t, "t1", conv.ShardTableName("t1"))
+	assert.True(t, conv.InShard("t1"))
+
+	conv.Shards = NewShards([]string{"s1", "s2"}, "")
+	conv.Shards.Tables = map[string][]string{"t1": {"s1", "s2"}, "s1.t2": {"s1"}, "s2.t2": {"s2"}, "t3": {"s2"}}
+	conv.SetShard("s1")
+	assert.Equal(t, "t1", conv.ShardTable("t1"))
+	assert.Equal(t, "s1.t2", conv.ShardTable("t2"))
+	assert.Equal(t, "t2", conv.ShardTableName("s1.t2"))
+	assert.Equal(t, "t1", conv.ShardTableName("t1"))
+	assert.True(t, conv.InShard("t1"))
+	assert.True(t, conv.InShard("s1.t2"))
+	assert.False(t, conv.InShard("s2.t2"))
+	assert.False(t, conv.InShard("t3"))
+
+	var rows [][]interface{}
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		assert.Equal(t, []string{"shard_id", "a"}, cols)
+		rows = append(rows, vals)
+	})
+	conv.SetDataMode()
+	conv.WriteRow("t1", "t1", []string{"a"}, []interface{}{int64(1)})
+	conv.SetShard("s2")
+	conv.WriteRow("t1", "t1", []string{"a"}, []interface{}{int64(1)})
+	assert.Equal(t, [][]interface{}{{"s1", int64(1)}, {"s2", int64(1)}}, rows)
+}
+
+func TestShardConv(t *testing.T) {
+	conv := MakeConv()
+	conv.Shards = NewShards([]string{"s1", "s2"}, "")
+	conv.Shards.Tables = map[string][]string{"t1": {"s1", "s2"}}
+	conv.SyntheticPKeys["t1"] = SyntheticPKey{Col: "synth_id", Sequence: 5}
+	conv.Stats.Rows["t1"] = 7
+	conv.SetShardStats("s1", ShardStats{Rows: 4})
+	conv.SetShardStats("s2", ShardStats{Rows: 3})
+	conv.SetDataMode()
+
+	s1, s2 := conv.ShardConv("s1"), conv.ShardConv("s2")
+	var rows [][]interface{}
+	for _, sc := range []*Conv{s1, s2} {
+		sc.SetDataSink(func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, vals)
+		})
+	}
+	s1.WriteRow("t1", "t1", []string{"a"}, []interface{}{int64(1)})
+	s2.WriteRow("t1", "t1", []string{"a"}, []interface{}{int64(2)})
+	s2.Stats.BadRows["t1"]++
+	s2.CollectBadRow("t1", []string{"a"}, []string{"x"})
+	assert.Equal(t, [][]interface{}{{"s1", int64(1)}, {"s2", int64(2)}}, rows)
+	assert.Equal(t, int64(5), s2.SyntheticPKeys["t1"].Sequence)
+
+	// Shard convs don't change conv until they are merged.
+	assert.Equal(t, int64(0), conv.Stats.GoodRows["t1"])
+	conv.MergeShardConv(s1)
+	conv.MergeShardConv(s2)
+	assert.Equal(t, int64(7), conv.Stats.Rows["t1"])
+	assert.Equal(t, int64(2), conv.Stats.GoodRows["t1"])
+	assert.Equal(t, int64(1), conv.Stats.BadRows["t1"])
+	assert.Equal(t, 1, len(conv.SampleBadRows(10)))
+	assert.Equal(t, map[string]ShardStats{"s1": {Rows: 4}, "s2": {Rows: 3, BadRows: 1}}, conv.Shards.Stats)
+}
+
+func TestShardSpannerName(t *testing.T) {
+	assert.Nil(t, ValidNamePattern("billing_*"))
+	assert.Nil(t, ValidNamePattern("*_eu"))
+	assert.NotNil(t, ValidNamePattern("billing"))
+	assert.NotNil(t, ValidNamePattern("*_*"))
+	assert.NotNil(t, ValidNamePattern("billing-*"))
+
+	conv := MakeConv()
+	assert.Equal(t, "t1", conv.ShardSpannerName("t1", "t1"))
+	conv.Shards = NewShards([]string{"s1", "s2"}, "")
+	conv.Shards.Tables = map[string][]string{"t1": {"s1", "s2"}, "s1.t2": {"s1"}, "s2.t2": {"s2"}, "t3": {"s2"}}
+	conv.Shards.Names = map[string]string{"s2": "*_eu"}
+	assert.Equal(t, "t1", conv.ShardSpannerName("t1", "t1"))
+	assert.Equal(t, "s1.t2", conv.ShardSpannerName("s1.t2", "s1.t2"))
+	assert.Equal(t, "t2_eu", conv.ShardSpannerName("s2.t2", "s2.t2"))
+	assert.Equal(t, "idx_eu", conv.ShardSpannerName("s2.t2", "idx"))
+	assert.Equal(t, "t3_eu", conv.ShardSpannerName("t3", "t3"))
+	assert.Equal(t, "", conv.ShardSpannerName("t3", ""))
+
+	sp, _ := GetSpannerTable(conv, "s2.t2")
+	assert.Equal(t, "t2_eu", sp)
+	src, _ := GetSourceTable(conv, "t2_eu")
+	assert.Equal(t, "s2.t2", src)
+}
@@ -0,0 +1,413 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// TableSplit is a vertical split of a Spanner table into several tables
+// sharing its primary key, e.g. to move large, rarely read columns (blobs,
+// documents) out of a table whose other columns are read often. The columns
+// of each part are moved to a table of their own, and the table keeps its
+// other columns. Parts are interleaved in the table, or reference it with a
+// foreign key on the primary key.
+//
+// conv.SpSchema keeps the table with all its columns, which data conversion
+// reads: the split is applied to the schema created in Spanner (see
+// TargetSchema), and to the rows written (see WriteRow), each row of the
+// table being written to the table and to every part.
+type TableSplit struct {
+	Parts      []SplitPart
+	Interleave bool // If true, parts are interleaved in the table rather than referencing it with a foreign key.
+}
+
+// SplitPart is a table that a split table is split into.
+type SplitPart struct {
+	Table string   // Name of the Spanner table of the part.
+	Cols  []string // Non-key columns of the split table moved to the part.
+	Fk    string   // Name of the foreign key of the part referencing the split table, if not interleaved.
+}
+
+// SplitTable splits Spanner table spTable into the tables of parts, moving
+// the columns of each part to its table. Any existing split of the table is
+// replaced. Part tables need legal, unused names, and columns can only be
+// moved to one part. The secondary indexes and foreign keys of the table,
+// and the foreign keys referencing it, must not use columns of different
+// parts (the columns left in the table being a part of their own), except
+// for primary key columns.
+func (conv *Conv) SplitTable(spTable string, parts []SplitPart, interleave bool) error {
+	ct, ok := conv.SpSchema[spTable]
+	if !ok {
+		return fmt.Errorf("table %s not found", spTable)
+	}
+	if _, ok := conv.DdlEdits[spTable]; ok {
+		return fmt.Errorf("table %s has manual DDL edits: remove them before splitting it", spTable)
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("please specify the parts table %s is split into", spTable)
+	}
+	if len(conv.NestedTables[spTable]) > 0 {
+		return fmt.Errorf("table %s has nested tables: remove them before splitting it", spTable)
+	}
+	old, hadSplit := conv.TableSplits[spTable]
+	conv.ClearTableSplit(spTable)
+	split, err := conv.newTableSplit(ct, parts, interleave)
+	if err == nil {
+		err = conv.checkTableSplit(spTable, split)
+	}
+	if err != nil {
+		if hadSplit {
+			conv.addTableSplit(spTable, old)
+		}
+		return err
+	}
+	conv.addTableSplit(spTable, split)
+	return nil
+}
+
+func (conv *Conv) newTableSplit(ct ddl.CreateTable, parts []SplitPart, interleave bool) (TableSplit, error) {
+	keys := make(map[string]bool)
+	for _, pk := range ct.Pks {
+		keys[pk.Col] = true
+	}
+	names := make(map[string]bool)
+	moved := make(map[string]string)
+	split := TableSplit{Interleave: interleave}
+	for _, p := range parts {
+		if fixed, _ := FixName(p.Table); fixed != p.Table {
+			return TableSplit{}, fmt.Errorf("invalid name %s for a part of table %s: expected letters, digits and '_', starting with a letter", p.Table, ct.Name)
+		}
+		name := strings.ToLower(p.Table)
+		if _, ok := conv.SpSchema[p.Table]; ok || conv.UsedNames[name] || names[name] {
+			return TableSplit{}, fmt.Errorf("name %s of a part of table %s is already used", p.Table, ct.Name)
+		}
+		names[name] = true
+		if len(p.Cols) == 0 {
+			return TableSplit{}, fmt.Errorf("part %s of table %s has no columns", p.Table, ct.Name)
+		}
+		for _, c := range p.Cols {
+			if _, ok := ct.ColDefs[c]; !ok {
+				return TableSplit{}, fmt.Errorf("column %s not found in table %s", c, ct.Name)
+			}
+			if keys[c] {
+				return TableSplit{}, fmt.Errorf("column %s is part of the primary key of table %s, which all parts share", c, ct.Name)
+			}
+			if other, ok := moved[c]; ok {
+				return TableSplit{}, fmt.Errorf("column %s of table %s is moved to both %s and %s", c, ct.Name, other, p.Table)
+			}
+			moved[c] = p.Table
+		}
+		split.Parts = append(split.Parts, SplitPart{Table: p.Table, Cols: append([]string{}, p.Cols...)})
+	}
+	return split, nil
+}
+
+// addTableSplit records split as the split of spTable, reserving the names
+// of its tables and foreign keys.
+func (conv *Conv) addTableSplit(spTable string, split TableSplit) {
+	for i, p := range split.Parts {
+		conv.UsedNames[strings.ToLower(p.Table)] = true
+		if split.Interleave {
+			split.Parts[i].Fk = ""
+		} else if p.Fk == "" {
+			split.Parts[i].Fk = getSpannerID(conv, p.Table+"_"+spTable+"_fk")
+		} else {
+			conv.UsedNames[strings.ToLower(p.Fk)] = true
+		}
+	}
+	if conv.TableSplits == nil {
+		conv.TableSplits = make(map[string]TableSplit)
+	}
+	conv.TableSplits[spTable] = split
+}
+
+// ClearTableSplit removes the split of Spanner table spTable (if any),
+// moving the columns of its parts back to the table.
+func (conv *Conv) ClearTableSplit(spTable string) {
+	split, ok := conv.TableSplits[spTable]
+	if !ok {
+		return
+	}
+	for _, p := range split.Parts {
+		delete(conv.UsedNames, strings.ToLower(p.Table))
+		if p.Fk != "" {
+			delete(conv.UsedNames, strings.ToLower(p.Fk))
+		}
+	}
+	delete(conv.TableSplits, spTable)
+}
+
+// SplitOf returns the table that part table spTable was split from, if it
+// is the table of a part of a split.
+func (conv *Conv) SplitOf(spTable string) (string, bool) {
+	for t, split := range conv.TableSplits {
+		for _, p := range split.Parts {
+			if p.Table == spTable {
+				return t, true
+			}
+		}
+	}
+	return "", false
+}
+
+// owners maps the columns of table ct moved by split to the index of their
+// part in split.Parts.
+func (split TableSplit) owners(ct ddl.CreateTable) map[string]int {
+	m := make(map[string]int)
+	for i, p := range split.Parts {
+		for _, c := range p.Cols {
+			if _, ok := ct.ColDefs[c]; ok {
+				m[c] = i
+			}
+		}
+	}
+	return m
+}
+
+// partOfCols returns the index of the part of split that columns cols of
+// table ct are in, or -1 if they are all in the table itself (primary key
+// columns are in all parts). It fails if cols are in several parts.
+func (split TableSplit) partOfCols(ct ddl.CreateTable, owners map[string]int, cols []string) (int, error) {
+	keys := make(map[string]bool)
+	for _, pk := range ct.Pks {
+		keys[pk.Col] = true
+	}
+	part, found := -1, false
+	for _, c := range cols {
+		if keys[c] {
+			continue
+		}
+		i, ok := owners[c]
+		if !ok {
+			i = -1
+		}
+		if found && i != part {
+			return 0, fmt.Errorf("columns %s are in different parts", strings.Join(cols, ", "))
+		}
+		part, found = i, true
+	}
+	return part, nil
+}
+
+// checkTableSplit checks that the indexes and foreign keys of spTable, and
+// the foreign keys referencing it, don't use columns of different parts of
+// split.
+func (conv *Conv) checkTableSplit(spTable string, split TableSplit) error {
+	ct := conv.SpSchema[spTable]
+	owners := split.owners(ct)
+	for _, index := range ct.Indexes {
+		cols := append([]string{}, index.StoredColumns...)
+		for _, k := range index.Keys {
+			cols = append(cols, k.Col)
+		}
+		if _, err := split.partOfCols(ct, owners, cols); err != nil {
+			return fmt.Errorf("index %s of table %s can't be split: %v", index.Name, spTable, err)
+		}
+	}
+	for _, fk := range ct.Fks {
+		if _, err := split.partOfCols(ct, owners, fk.Columns); err != nil {
+			return fmt.Errorf("foreign key %s of table %s can't be split: %v", fk.Name, spTable, err)
+		}
+	}
+	for t, other := range conv.SpSchema {
+		for _, fk := range other.Fks {
+			if fk.ReferTable != spTable {
+				continue
+			}
+			if _, err := split.partOfCols(ct, owners, fk.ReferColumns); err != nil {
+				return fmt.Errorf("foreign key %s of table %s referencing table %s can't be split: %v", fk.Name, t, spTable, err)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckTableSplits checks that the splits of conv still apply to the
+// Spanner schema, which may have changed since the tables were split (see
+// SplitTable).
+func (conv *Conv) CheckTableSplits() error {
+	for t, split := range conv.TableSplits {
+		if _, ok := conv.SpSchema[t]; !ok {
+			return fmt.Errorf("split table %s not found", t)
+		}
+		if err := conv.checkTableSplit(t, split); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TargetSchema returns the Spanner schema of the database created in Spanner:
+// conv.SpSchema, with the split tables of conv.TableSplits replaced by the
+// tables of their parts, and the nested tables of conv.NestedTables (see
+// NestedTable). Secondary indexes and foreign keys follow the columns they
+// use, and foreign keys referencing moved columns reference the tables of
+// their parts. conv.SpSchema is returned as is if no table is split or has
+// nested tables.
+func (conv *Conv) TargetSchema() ddl.Schema {
+	if len(conv.TableSplits) == 0 && len(conv.NestedTables) == 0 {
+		return conv.SpSchema
+	}
+	s := make(ddl.Schema, len(conv.SpSchema))
+	for t, ct := range conv.SpSchema {
+		s[t] = ct
+	}
+	for t, split := range conv.TableSplits {
+		ct, ok := conv.SpSchema[t]
+		if !ok {
+			continue
+		}
+		owners := split.owners(ct)
+		partOf := func(cols []string) int {
+			i, _ := split.partOfCols(ct, owners, cols)
+			return i
+		}
+		tables := make([]ddl.CreateTable, len(split.Parts))
+		var pkCols []string
+		for _, pk := range ct.Pks {
+			pkCols = append(pkCols, pk.Col)
+		}
+		for i, p := range split.Parts {
+			pt := ddl.CreateTable{Name: p.Table, ColDefs: make(map[string]ddl.ColumnDef), Pks: append([]ddl.IndexKey{}, ct.Pks...), Comment: fmt.Sprintf("Part of table %s", t)}
+			if split.Interleave {
+				pt.Parent = t
+			} else {
+				pt.Fks = []ddl.Foreignkey{{Name: p.Fk, Columns: pkCols, ReferTable: t, ReferColumns: pkCols}}
+			}
+			tables[i] = pt
+		}
+		trimmed := ct
+		trimmed.ColNames, trimmed.ColDefs, trimmed.Indexes, trimmed.Fks = nil, make(map[string]ddl.ColumnDef), nil, nil
+		isKey := make(map[string]bool)
+		for _, c := range pkCols {
+			isKey[c] = true
+		}
+		for _, c := range ct.ColNames {
+			cd := ct.ColDefs[c]
+			if i, ok := owners[c]; ok {
+				tables[i].ColNames = append(tables[i].ColNames, c)
+				tables[i].ColDefs[c] = cd
+				continue
+			}
+			trimmed.ColNames = append(trimmed.ColNames, c)
+			trimmed.ColDefs[c] = cd
+			if isKey[c] {
+				// Key values of parts are those of the table.
+				cd.Sequence = nil
+				for i := range tables {
+					tables[i].ColNames = append(tables[i].ColNames, c)
+					tables[i].ColDefs[c] = cd
+				}
+			}
+		}
+		for _, index := range ct.Indexes {
+			cols := append([]string{}, index.StoredColumns...)
+			for _, k := range index.Keys {
+				cols = append(cols, k.Col)
+			}
+			if i := partOf(cols); i >= 0 {
+				index.Table = tables[i].Name
+				tables[i].Indexes = append(tables[i].Indexes, index)
+			} else {
+				trimmed.Indexes = append(trimmed.Indexes, index)
+			}
+		}
+		for _, fk := range ct.Fks {
+			if i := partOf(fk.Columns); i >= 0 {
+				tables[i].Fks = append(tables[i].Fks, fk)
+			} else {
+				trimmed.Fks = append(trimmed.Fks, fk)
+			}
+		}
+		s[t] = trimmed
+		for _, pt := range tables {
+			// Key columns come first, in the order of the table.
+			var keys, others []string
+			for _, c := range pt.ColNames {
+				if isKey[c] {
+					keys = append(keys, c)
+				} else {
+					others = append(others, c)
+				}
+			}
+			pt.ColNames = append(keys, others...)
+			s[pt.Name] = pt
+		}
+	}
+	// Foreign keys referencing moved columns reference their parts.
+	for t, ct := range s {
+		changed := false
+		fks := make([]ddl.Foreignkey, len(ct.Fks))
+		for i, fk := range ct.Fks {
+			fks[i] = fk
+			split, ok := conv.TableSplits[fk.ReferTable]
+			if !ok {
+				continue
+			}
+			refTable := conv.SpSchema[fk.ReferTable]
+			if p, err := split.partOfCols(refTable, split.owners(refTable), fk.ReferColumns); err == nil && p >= 0 {
+				fks[i].ReferTable, changed = split.Parts[p].Table, true
+			}
+		}
+		if changed {
+			ct.Fks = fks
+			s[t] = ct
+		}
+	}
+	conv.addNestedTablesTo(s)
+	return s
+}
+
+// splitRow splits a row of Spanner table spTable, which has split split,
+// into the rows of the table and of its parts, which all have the primary key
+// columns of the row. It calls write for each of them.
+func (conv *Conv) splitRow(spTable string, split TableSplit, cols []string, vals []interface{}, write func(table string, cols []string, vals []interface{})) {
+	ct := conv.SpSchema[spTable]
+	owners := split.owners(ct)
+	isKey := make(map[string]bool)
+	for _, pk := range ct.Pks {
+		isKey[pk.Col] = true
+	}
+	if conv.Shards != nil {
+		isKey[conv.Shards.IdColumn] = true
+	}
+	partCols := make([][]string, len(split.Parts))
+	partVals := make([][]interface{}, len(split.Parts))
+	var tableCols []string
+	var tableVals []interface{}
+	for i, c := range cols {
+		if p, ok := owners[c]; ok {
+			partCols[p] = append(partCols[p], c)
+			partVals[p] = append(partVals[p], vals[i])
+			continue
+		}
+		tableCols = append(tableCols, c)
+		tableVals = append(tableVals, vals[i])
+		if isKey[c] {
+			for p := range split.Parts {
+				partCols[p] = append(partCols[p], c)
+				partVals[p] = append(partVals[p], vals[i])
+			}
+		}
+	}
+	write(spTable, tableCols, tableVals)
+	for p, part := range split.Parts {
+		write(part.Table, partCols[p], partVals[p])
+	}
+}
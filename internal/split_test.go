@@ -0,0 +1,159 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildSplitConv() *Conv {
+	conv := MakeConv()
+	conv.SpSchema = map[string]ddl.CreateTable{
+		"docs": {
+			Name:     "docs",
+			ColNames: []string{"id", "title", "author", "body"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":     {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"title":  {Name: "title", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"author": {Name: "author", T: ddl.Type{Name: ddl.Int64}},
+				"body":   {Name: "body", T: ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}},
+			},
+			Pks:     []ddl.IndexKey{{Col: "id"}},
+			Indexes: []ddl.CreateIndex{{Name: "docs_title", Table: "docs", Keys: []ddl.IndexKey{{Col: "title"}}}},
+		},
+		"notes": {
+			Name:     "notes",
+			ColNames: []string{"id", "doc_id"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":     {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"doc_id": {Name: "doc_id", T: ddl.Type{Name: ddl.Int64}},
+			},
+			Pks: []ddl.IndexKey{{Col: "id"}},
+			Fks: []ddl.Foreignkey{{Name: "notes_fk", Columns: []string{"doc_id"}, ReferTable: "docs", ReferColumns: []string{"id"}}},
+		},
+	}
+	conv.UsedNames = map[string]bool{"docs": true, "notes": true, "docs_title": true, "notes_fk": true}
+	return conv
+}
+
+func TestSplitTable(t *testing.T) {
+	conv := buildSplitConv()
+	assert.NotNil(t, conv.SplitTable("missing", []SplitPart{{Table: "p", Cols: []string{"body"}}}, false))
+	assert.NotNil(t, conv.SplitTable("docs", nil, false))
+	assert.NotNil(t, conv.SplitTable("docs", []SplitPart{{Table: "notes", Cols: []string{"body"}}}, false))
+	assert.NotNil(t, conv.SplitTable("docs", []SplitPart{{Table: "1docs", Cols: []string{"body"}}}, false))
+	assert.NotNil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_body", Cols: []string{"id"}}}, false))
+	assert.NotNil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_body", Cols: []string{"missing"}}}, false))
+	assert.NotNil(t, conv.SplitTable("docs", []SplitPart{{Table: "p1", Cols: []string{"body"}}, {Table: "p2", Cols: []string{"body"}}}, false))
+	assert.Empty(t, conv.TableSplits)
+
+	// Indexes can't use columns of different parts.
+	ct := conv.SpSchema["docs"]
+	ct.Indexes = append(ct.Indexes, ddl.CreateIndex{Name: "docs_title_body", Table: "docs", Keys: []ddl.IndexKey{{Col: "title"}, {Col: "body"}}})
+	conv.SpSchema["docs"] = ct
+	assert.NotNil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_body", Cols: []string{"body"}}}, false))
+	conv = buildSplitConv()
+
+	assert.Nil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_body", Cols: []string{"body"}}}, false))
+	assert.Equal(t, TableSplit{Parts: []SplitPart{{Table: "docs_body", Cols: []string{"body"}, Fk: "docs_body_docs_fk"}}}, conv.TableSplits["docs"])
+	assert.True(t, conv.UsedNames["docs_body"])
+	assert.True(t, conv.UsedNames["docs_body_docs_fk"])
+	conv.ToSource["docs"] = NameAndCols{Name: "src_docs"}
+	srcTable, err := GetSourceTable(conv, "docs_body")
+	assert.Nil(t, err)
+	assert.Equal(t, "src_docs", srcTable)
+
+	// A failed split keeps the previous one.
+	assert.NotNil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_body", Cols: []string{"id"}}}, true))
+	assert.Equal(t, "docs_body", conv.TableSplits["docs"].Parts[0].Table)
+	assert.True(t, conv.UsedNames["docs_body"])
+
+	// A new split replaces the previous one.
+	assert.Nil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_content", Cols: []string{"body", "author"}}}, true))
+	assert.Equal(t, TableSplit{Parts: []SplitPart{{Table: "docs_content", Cols: []string{"body", "author"}}}, Interleave: true}, conv.TableSplits["docs"])
+	assert.False(t, conv.UsedNames["docs_body"])
+	assert.False(t, conv.UsedNames["docs_body_docs_fk"])
+
+	conv.ClearTableSplit("docs")
+	assert.Empty(t, conv.TableSplits)
+	assert.False(t, conv.UsedNames["docs_content"])
+	assert.Equal(t, buildSplitConv().SpSchema, conv.SpSchema)
+}
+
+func TestTargetSchema(t *testing.T) {
+	conv := buildSplitConv()
+	assert.Equal(t, conv.SpSchema, conv.TargetSchema())
+
+	// Make notes reference a moved column.
+	notes := conv.SpSchema["notes"]
+	notes.Fks = []ddl.Foreignkey{{Name: "notes_fk", Columns: []string{"doc_id"}, ReferTable: "docs", ReferColumns: []string{"author"}}}
+	conv.SpSchema["notes"] = notes
+	ct := conv.SpSchema["docs"]
+	ct.Indexes = append(ct.Indexes, ddl.CreateIndex{Name: "docs_author", Table: "docs", Keys: []ddl.IndexKey{{Col: "author"}}, StoredColumns: []string{"body"}})
+	conv.SpSchema["docs"] = ct
+	assert.Nil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_content", Cols: []string{"body", "author"}}}, false))
+
+	s := conv.TargetSchema()
+	assert.Equal(t, []string{"id", "title"}, s["docs"].ColNames)
+	assert.Equal(t, []string{"id", "title"}, []string{s["docs"].ColDefs["id"].Name, s["docs"].ColDefs["title"].Name})
+	assert.Len(t, s["docs"].ColDefs, 2)
+	assert.Equal(t, []ddl.CreateIndex{{Name: "docs_title", Table: "docs", Keys: []ddl.IndexKey{{Col: "title"}}}}, s["docs"].Indexes)
+	part := s["docs_content"]
+	assert.Equal(t, []string{"id", "author", "body"}, part.ColNames)
+	assert.Equal(t, []ddl.IndexKey{{Col: "id"}}, part.Pks)
+	assert.Equal(t, "", part.Parent)
+	assert.Equal(t, []ddl.Foreignkey{{Name: "docs_content_docs_fk", Columns: []string{"id"}, ReferTable: "docs", ReferColumns: []string{"id"}}}, part.Fks)
+	assert.Equal(t, []ddl.CreateIndex{{Name: "docs_author", Table: "docs_content", Keys: []ddl.IndexKey{{Col: "author"}}, StoredColumns: []string{"body"}}}, part.Indexes)
+	assert.Equal(t, "docs_content", s["notes"].Fks[0].ReferTable)
+	// conv.SpSchema is unchanged.
+	assert.Len(t, conv.SpSchema["docs"].ColNames, 4)
+	assert.Equal(t, "docs", conv.SpSchema["notes"].Fks[0].ReferTable)
+	assert.Equal(t, "docs", conv.SpSchema["docs"].Indexes[1].Table)
+
+	assert.Nil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_content", Cols: []string{"body", "author"}}}, true))
+	s = conv.TargetSchema()
+	assert.Equal(t, "docs", s["docs_content"].Parent)
+	assert.Empty(t, s["docs_content"].Fks)
+	assert.Equal(t, []string{"docs", "docs_content", "notes"}, ddl.OrderTables(s))
+}
+
+func TestWriteRowSplit(t *testing.T) {
+	conv := buildSplitConv()
+	assert.Nil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_body", Cols: []string{"body"}}}, true))
+	cols := make(map[string][]string)
+	vals := make(map[string][]interface{})
+	conv.SetDataSink(func(table string, c []string, v []interface{}) {
+		cols[table], vals[table] = c, v
+	})
+	conv.SetDataMode()
+	conv.WriteRow("docs", "docs", []string{"id", "title", "author", "body"}, []interface{}{int64(1), "t", int64(2), []byte("b")})
+	assert.Equal(t, map[string][]string{"docs": {"id", "title", "author"}, "docs_body": {"id", "body"}}, cols)
+	assert.Equal(t, map[string][]interface{}{"docs": {int64(1), "t", int64(2)}, "docs_body": {int64(1), []byte("b")}}, vals)
+	assert.Equal(t, int64(1), conv.Stats.GoodRows["docs"])
+}
+
+func TestRenameSplitTable(t *testing.T) {
+	conv := buildSplitConv()
+	assert.Nil(t, conv.SplitTable("docs", []SplitPart{{Table: "docs_body", Cols: []string{"body"}}}, true))
+	assert.NotNil(t, conv.RenameTable("notes", "docs_body"))
+	assert.Nil(t, conv.RenameColumn("docs", "body", "content"))
+	assert.Nil(t, conv.RenameTable("docs", "documents"))
+	assert.Equal(t, TableSplit{Parts: []SplitPart{{Table: "docs_body", Cols: []string{"content"}}}, Interleave: true}, conv.TableSplits["documents"])
+	assert.Nil(t, conv.CheckTableSplits())
+	assert.Equal(t, "documents", conv.TargetSchema()["docs_body"].Parent)
+}
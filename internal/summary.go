@@ -0,0 +1,89 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package internal
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// RunSummarySchemaVersion is the version of the run summary schema, bumped
+// on the same terms as JSONReportSchemaVersion.
+const RunSummarySchemaVersion = "1.0"
+
+// Statuses of a run, in RunSummary.Status.
+const (
+	RunSucceeded        = "succeeded"
+	RunSchemaIssues     = "schema_issues"     // The schema has warnings, with -fail-on-schema-issues.
+	RunDataErrors       = "data_errors"       // Too many rows couldn't be migrated, with -max-bad-row-rate or an error budget.
+	RunValidationFailed = "validation_failed" // Row counts of Spanner tables don't match.
+	RunFailed           = "failed"            // A fatal error stopped the run.
+)
+
+// RunSummary is a small machine-readable summary of a run of a subcommand,
+// so that automation can take decisions without parsing the full report.
+type RunSummary struct {
+	SchemaVersion      string   `json:"schemaVersion"`
+	Command            string   `json:"command"`
+	Status             string   `json:"status"`
+	ExitCode           int      `json:"exitCode"`
+	Error              string   `json:"error,omitempty"`
+	Driver             string   `json:"driver,omitempty"`
+	Database           string   `json:"database,omitempty"`
+	DryRun             bool     `json:"dryRun"`
+	DurationSeconds    float64  `json:"durationSeconds"`
+	Tables             int      `json:"tables"`
+	SchemaWarnings     int64    `json:"schemaWarnings"`
+	Rows               int64    `json:"rows"`
+	BadRows            int64    `json:"badRows"` // Rows that couldn't be converted or written.
+	Validated          bool     `json:"validated"`
+	RowCountMismatches []string `json:"rowCountMismatches"` // Spanner tables whose row count doesn't match.
+	BudgetViolations   []string `json:"budgetViolations"`   // Limits of -max-bad-rows and -max-dropped-rows exceeded.
+	Report             string   `json:"report,omitempty"`   // Name of the text report file.
+}
+
+// NewRunSummary returns the summary of a run of command that hasn't
+// finished yet.
+func NewRunSummary(command string) *RunSummary {
+	return &RunSummary{SchemaVersion: RunSummarySchemaVersion, Command: command, RowCountMismatches: []string{}, BudgetViolations: []string{}}
+}
+
+// AddStats sets the table, schema warning and row counts of s from conv,
+// with badWrites the rows that couldn't be written, as for reports.
+func (s *RunSummary) AddStats(conv *Conv, badWrites map[string]int64) {
+	s.Tables, s.SchemaWarnings, s.Rows, s.BadRows = 0, 0, 0, 0
+	for _, t := range AnalyzeTables(conv, badWrites) {
+		s.Tables++
+		s.SchemaWarnings += t.Warnings
+		s.Rows += t.rows
+		s.BadRows += t.badRows
+	}
+}
+
+// BadRowRate returns the fraction of rows that couldn't be migrated.
+func (s *RunSummary) BadRowRate() float64 {
+	if s.Rows == 0 {
+		return 0
+	}
+	return float64(s.BadRows) / float64(s.Rows)
+}
+
+// WriteRunSummary writes s to w as indented JSON.
+func WriteRunSummary(s *RunSummary, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunSummary(t *testing.T) {
+	conv := buildReportConv()
+	s := NewRunSummary("data")
+	s.AddStats(conv, map[string]int64{"t1": 5})
+	assert.Equal(t, 1, s.Tables)
+	assert.Equal(t, int64(1), s.SchemaWarnings)
+	assert.Equal(t, int64(100), s.Rows)
+	assert.Equal(t, int64(15), s.BadRows)
+	assert.Equal(t, 0.15, s.BadRowRate())
+	assert.Equal(t, float64(0), NewRunSummary("schema").BadRowRate())
+
+	s.Status, s.ExitCode = RunDataErrors, 4
+	buf := new(bytes.Buffer)
+	assert.Nil(t, WriteRunSummary(s, buf))
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, RunSummarySchemaVersion, m["schemaVersion"])
+	assert.Equal(t, "data", m["command"])
+	assert.Equal(t, "data_errors", m["status"])
+	assert.Equal(t, float64(4), m["exitCode"])
+	assert.Equal(t, []interface{}{}, m["rowCountMismatches"])
+	assert.NotContains(t, m, "error")
+}
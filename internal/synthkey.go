@@ -0,0 +1,148 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/google/uuid"
+)
+
+// Types of synthetic primary keys.
+const (
+	// SyntheticKeySequence is an INT64 key generated by bit-reversing a
+	// per-table counter. This is the default.
+	SyntheticKeySequence = "sequence"
+	// SyntheticKeyUUID is a random (version 4) UUID stored as STRING(36).
+	SyntheticKeyUUID = "uuid"
+	// SyntheticKeyULID is a ULID stored as STRING(26). ULIDs start with a
+	// timestamp, so they sort by creation time.
+	SyntheticKeyULID = "ulid"
+)
+
+// crockford is the base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// SetSyntheticKey changes the synthetic primary key of Spanner table spTable
+// to use column name col (if not empty) and key type keyType (if not empty).
+// Returns an error if the table has no synthetic primary key.
+func (conv *Conv) SetSyntheticKey(spTable, col, keyType string) error {
+	pk, ok := conv.SyntheticPKeys[spTable]
+	if !ok {
+		return fmt.Errorf("table %s does not have a synthetic primary key", spTable)
+	}
+	var t ddl.Type
+	switch keyType {
+	case "":
+		keyType = pk.Type
+	case SyntheticKeySequence, SyntheticKeyUUID, SyntheticKeyULID:
+	default:
+		return fmt.Errorf("unknown synthetic key type '%s': must be one of %s, %s or %s", keyType, SyntheticKeySequence, SyntheticKeyUUID, SyntheticKeyULID)
+	}
+	switch keyType {
+	case SyntheticKeyUUID:
+		t = ddl.Type{Name: ddl.String, Len: 36}
+	case SyntheticKeyULID:
+		t = ddl.Type{Name: ddl.String, Len: 26}
+	default:
+		t = ddl.Type{Name: ddl.Int64}
+	}
+	ct := conv.SpSchema[spTable]
+	if col == "" {
+		col = pk.Col
+	}
+	if col != pk.Col {
+		if fixed, _ := FixName(col); fixed != col {
+			return fmt.Errorf("'%s' is not a valid Spanner column name", col)
+		}
+		for c := range ct.ColDefs {
+			if strings.EqualFold(c, col) {
+				return fmt.Errorf("table %s already has a column named %s", spTable, c)
+			}
+		}
+	}
+	for i, c := range ct.ColNames {
+		if c == pk.Col {
+			ct.ColNames[i] = col
+		}
+	}
+	for i, k := range ct.Pks {
+		if k.Col == pk.Col {
+			ct.Pks[i].Col = col
+		}
+	}
+	cd := ct.ColDefs[pk.Col]
+	delete(ct.ColDefs, pk.Col)
+	cd.Name = col
+	cd.T = t
+	ct.ColDefs[col] = cd
+	conv.SpSchema[spTable] = ct
+	pk.Col = col
+	pk.Type = keyType
+	conv.SyntheticPKeys[spTable] = pk
+	return nil
+}
+
+// NextSyntheticKey returns the synthetic primary key value for the next row
+// of Spanner table spTable.
+func (conv *Conv) NextSyntheticKey(spTable string) interface{} {
+	pk := conv.SyntheticPKeys[spTable]
+	defer func() {
+		pk.Sequence++
+		conv.SyntheticPKeys[spTable] = pk
+	}()
+	switch pk.Type {
+	case SyntheticKeyUUID:
+		return uuid.New().String()
+	case SyntheticKeyULID:
+		return newULID(time.Now())
+	}
+	return int64(bits.Reverse64(uint64(pk.Sequence)))
+}
+
+// newULID returns a ULID for time t: a 48 bit millisecond timestamp followed
+// by 80 random bits, encoded as 26 characters of Crockford's base32.
+func newULID(t time.Time) string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(t.UnixNano()/int64(time.Millisecond))<<16)
+	rand.Read(b[6:])
+	// 128 bits are encoded as 26 groups of 5 bits, with the first group only
+	// using the top 3 bits of the timestamp.
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
+
+// SetSyntheticKeys applies SetSyntheticKey to every table with a synthetic
+// primary key.
+func (conv *Conv) SetSyntheticKeys(col, keyType string) error {
+	for t := range conv.SyntheticPKeys {
+		if err := conv.SetSyntheticKey(t, col, keyType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
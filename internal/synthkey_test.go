@@ -0,0 +1,67 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSyntheticKey(t *testing.T) {
+	conv := MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"a", "synth_id"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a":        {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"synth_id": {Name: "synth_id", T: ddl.Type{Name: ddl.Int64}},
+		},
+		Pks: []ddl.IndexKey{{Col: "synth_id"}},
+	}
+	conv.SyntheticPKeys["t1"] = SyntheticPKey{Col: "synth_id"}
+
+	assert.NotNil(t, conv.SetSyntheticKey("t2", "", SyntheticKeyUUID))
+	assert.NotNil(t, conv.SetSyntheticKey("t1", "", "serial"))
+	assert.NotNil(t, conv.SetSyntheticKey("t1", "A", ""))
+	assert.NotNil(t, conv.SetSyntheticKey("t1", "bad name", ""))
+
+	assert.Equal(t, int64(0), conv.NextSyntheticKey("t1"))
+	assert.Equal(t, int64(bits.Reverse64(1)), conv.NextSyntheticKey("t1"))
+
+	assert.Nil(t, conv.SetSyntheticKeys("row_id", SyntheticKeyUUID))
+	assert.Equal(t, []string{"a", "row_id"}, conv.SpSchema["t1"].ColNames)
+	assert.Equal(t, []ddl.IndexKey{{Col: "row_id"}}, conv.SpSchema["t1"].Pks)
+	assert.Equal(t, ddl.ColumnDef{Name: "row_id", T: ddl.Type{Name: ddl.String, Len: 36}}, conv.SpSchema["t1"].ColDefs["row_id"])
+	assert.Len(t, conv.NextSyntheticKey("t1"), 36)
+
+	// An empty name keeps the current column.
+	assert.Nil(t, conv.SetSyntheticKey("t1", "", SyntheticKeyULID))
+	assert.Equal(t, SyntheticPKey{Col: "row_id", Sequence: 3, Type: SyntheticKeyULID}, conv.SyntheticPKeys["t1"])
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 26}, conv.SpSchema["t1"].ColDefs["row_id"].T)
+}
+
+func TestNewULID(t *testing.T) {
+	ts := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	u1, u2 := newULID(ts), newULID(ts.Add(time.Millisecond))
+	assert.Len(t, u1, 26)
+	// The first 10 characters encode the timestamp.
+	assert.Equal(t, "01FRCBX048", u1[:10])
+	assert.Less(t, u1[:10], u2[:10])
+	assert.NotEqual(t, u1, newULID(ts))
+}
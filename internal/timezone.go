@@ -0,0 +1,109 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DatetimeZones are the time zones that source datetimes without time zone,
+// such as MySQL DATETIME, are assumed to be in when they are converted to
+// Spanner TIMESTAMP, which is always UTC.
+type DatetimeZones struct {
+	Default string                       // IANA time zone name, e.g. "America/New_York". UTC if empty.
+	Columns map[string]map[string]string // Overrides of Default, keyed by source table and column name.
+}
+
+// locations caches the time zones loaded by name, since datetimes are
+// converted concurrently and time.LoadLocation reads the zone database.
+var locations sync.Map
+
+func loadLocation(zone string) (*time.Location, error) {
+	if loc, ok := locations.Load(zone); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %s: %v", zone, err)
+	}
+	locations.Store(zone, loc)
+	return loc, nil
+}
+
+// SetDatetimeZone sets the time zone of the datetimes of column srcCol of
+// source table srcTable to zone, an IANA time zone name. If srcTable is
+// empty, it sets the default time zone instead. An empty zone removes a
+// column's override, or resets the default to UTC.
+func (conv *Conv) SetDatetimeZone(srcTable, srcCol, zone string) error {
+	if zone != "" {
+		if _, err := loadLocation(zone); err != nil {
+			return err
+		}
+	}
+	if srcTable == "" {
+		conv.DatetimeZones.Default = zone
+		return nil
+	}
+	st, ok := conv.SrcSchema[srcTable]
+	if !ok {
+		return fmt.Errorf("table %s not found", srcTable)
+	}
+	if _, ok := st.ColDefs[srcCol]; !ok {
+		return fmt.Errorf("column %s not found in table %s", srcCol, srcTable)
+	}
+	if zone == "" {
+		delete(conv.DatetimeZones.Columns[srcTable], srcCol)
+		if len(conv.DatetimeZones.Columns[srcTable]) == 0 {
+			delete(conv.DatetimeZones.Columns, srcTable)
+		}
+		return nil
+	}
+	if conv.DatetimeZones.Columns == nil {
+		conv.DatetimeZones.Columns = make(map[string]map[string]string)
+	}
+	if conv.DatetimeZones.Columns[srcTable] == nil {
+		conv.DatetimeZones.Columns[srcTable] = make(map[string]string)
+	}
+	conv.DatetimeZones.Columns[srcTable][srcCol] = zone
+	return nil
+}
+
+// DatetimeZone returns the name of the time zone of the datetimes of column
+// srcCol of source table srcTable.
+func (conv *Conv) DatetimeZone(srcTable, srcCol string) string {
+	if zone, ok := conv.DatetimeZones.Columns[srcTable][srcCol]; ok {
+		return zone
+	}
+	if conv.DatetimeZones.Default != "" {
+		return conv.DatetimeZones.Default
+	}
+	return "UTC"
+}
+
+// DatetimeLocation returns the time zone of the datetimes of column srcCol
+// of source table srcTable. Unknown time zones, e.g. from a session written
+// on a machine with a more recent zone database, are reported and treated
+// as UTC.
+func (conv *Conv) DatetimeLocation(srcTable, srcCol string) *time.Location {
+	zone := conv.DatetimeZone(srcTable, srcCol)
+	loc, err := loadLocation(zone)
+	if err != nil {
+		conv.Unexpected(err.Error())
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+func TestSetDatetimeZone(t *testing.T) {
+	conv := MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Name:     "t1",
+		ColNames: []string{"a", "b"},
+		ColDefs: map[string]schema.Column{
+			"a": {Name: "a", Type: schema.Type{Name: "datetime"}},
+			"b": {Name: "b", Type: schema.Type{Name: "datetime"}},
+		},
+	}
+	assert.Equal(t, "UTC", conv.DatetimeZone("t1", "a"))
+	assert.Equal(t, time.UTC, conv.DatetimeLocation("t1", "a"))
+
+	assert.NotNil(t, conv.SetDatetimeZone("", "", "Mars/Olympus_Mons"))
+	assert.NotNil(t, conv.SetDatetimeZone("t2", "a", "Asia/Kolkata"))
+	assert.NotNil(t, conv.SetDatetimeZone("t1", "c", "Asia/Kolkata"))
+
+	assert.Nil(t, conv.SetDatetimeZone("", "", "America/New_York"))
+	assert.Nil(t, conv.SetDatetimeZone("t1", "b", "Asia/Kolkata"))
+	assert.Equal(t, "America/New_York", conv.DatetimeZone("t1", "a"))
+	assert.Equal(t, "Asia/Kolkata", conv.DatetimeZone("t1", "b"))
+	assert.Equal(t, "Asia/Kolkata", conv.DatetimeLocation("t1", "b").String())
+
+	assert.Nil(t, conv.SetDatetimeZone("t1", "b", ""))
+	assert.Equal(t, "America/New_York", conv.DatetimeZone("t1", "b"))
+	assert.Empty(t, conv.DatetimeZones.Columns)
+
+	// Unknown zones, e.g. from an edited session, are treated as UTC.
+	conv.DatetimeZones.Default = "Mars/Olympus_Mons"
+	assert.Equal(t, time.UTC, conv.DatetimeLocation("t1", "a"))
+	assert.Equal(t, int64(1), conv.Unexpecteds())
+}
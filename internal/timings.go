@@ -0,0 +1,148 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stages of a migration timed by Timings, in pipeline order.
+const (
+	StageSchemaFetch      = "schema fetch"      // Reading the source schema.
+	StageSchemaConversion = "schema conversion" // Building the Spanner schema.
+	StageDdlApply         = "DDL apply"         // Creating or updating the Spanner database.
+	StageRead             = "read"              // Reading the rows of a table (timed per table).
+	StageConvert          = "convert"           // Converting the rows of a table (timed per table).
+	StageWriteWait        = "write wait"        // Waiting to hand rows of a table to Spanner writes (timed per table).
+	StageIndexBuild       = "index build"       // Creating deferred secondary indexes.
+	StageFkApply          = "foreign key apply" // Creating foreign keys.
+)
+
+// stages are the stages of the whole migration, in pipeline order.
+var stages = []string{StageSchemaFetch, StageSchemaConversion, StageDdlApply, StageIndexBuild, StageFkApply}
+
+// Timings records the time spent in each stage of a migration, so that the
+// report shows its bottlenecks. Data migration stages are timed per source
+// table: the time of a table's StageRead is that of its whole data
+// migration, which includes the time of its StageConvert and
+// StageWriteWait, and TableStages subtracts them. Timings is safe for
+// concurrent use, and a nil Timings records nothing.
+type Timings struct {
+	lock   sync.Mutex
+	stages map[string]time.Duration
+	tables map[string]map[string]time.Duration
+}
+
+// TableTimings is the time spent in the data migration stages of a table.
+type TableTimings struct {
+	Table     string // Source table.
+	Read      time.Duration
+	Convert   time.Duration
+	WriteWait time.Duration
+}
+
+// StageTiming is the time spent in a stage of the whole migration.
+type StageTiming struct {
+	Stage    string
+	Duration time.Duration
+}
+
+// NewTimings returns a Timings with no time recorded.
+func NewTimings() *Timings {
+	return &Timings{stages: make(map[string]time.Duration), tables: make(map[string]map[string]time.Duration)}
+}
+
+// Add adds d to the time of stage.
+func (t *Timings) Add(stage string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.stages[stage] += d
+}
+
+// AddTable adds d to the time of stage of source table 'table'.
+func (t *Timings) AddTable(stage, table string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.tables[table] == nil {
+		t.tables[table] = make(map[string]time.Duration)
+	}
+	t.tables[table][stage] += d
+}
+
+// Time adds the time since start to stage, e.g.
+// defer conv.Timings.Time(StageDdlApply, time.Now()).
+func (t *Timings) Time(stage string, start time.Time) {
+	t.Add(stage, time.Since(start))
+}
+
+// TimeTable adds the time since start to stage of source table 'table'.
+func (t *Timings) TimeTable(stage, table string, start time.Time) {
+	t.AddTable(stage, table, time.Since(start))
+}
+
+// Stages returns the time of the stages of the whole migration that were
+// timed, in pipeline order.
+func (t *Timings) Stages() []StageTiming {
+	if t == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var l []StageTiming
+	for _, s := range stages {
+		if d, ok := t.stages[s]; ok {
+			l = append(l, StageTiming{Stage: s, Duration: d})
+		}
+	}
+	return l
+}
+
+// TableStages returns the time of the data migration stages of each table,
+// sorted by table name. Rows of a table read in parallel (e.g. by several
+// DynamoDB scan segments) may take more time to convert than the table
+// takes to read: its read time is then 0. Tables of dump files aren't read
+// one at a time, and have no read time.
+func (t *Timings) TableStages() []TableTimings {
+	if t == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var l []TableTimings
+	for table, st := range t.tables {
+		nonNegative := func(d time.Duration) time.Duration {
+			if d < 0 {
+				return 0
+			}
+			return d
+		}
+		l = append(l, TableTimings{
+			Table:     table,
+			Read:      nonNegative(st[StageRead] - st[StageConvert] - st[StageWriteWait]),
+			Convert:   st[StageConvert],
+			WriteWait: st[StageWriteWait],
+		})
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Table < l[j].Table })
+	return l
+}
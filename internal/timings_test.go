@@ -0,0 +1,87 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimings(t *testing.T) {
+	tm := NewTimings()
+	tm.Add(StageFkApply, 3*time.Second)
+	tm.Add(StageSchemaFetch, time.Second)
+	tm.Add(StageSchemaFetch, time.Second)
+	tm.AddTable(StageRead, "users", 10*time.Second)
+	tm.AddTable(StageConvert, "users", 2*time.Second)
+	tm.AddTable(StageWriteWait, "users", 5*time.Second)
+	// Rows read in parallel.
+	tm.AddTable(StageRead, "events", time.Second)
+	tm.AddTable(StageConvert, "events", 3*time.Second)
+	assert.Equal(t, []StageTiming{{StageSchemaFetch, 2 * time.Second}, {StageFkApply, 3 * time.Second}}, tm.Stages())
+	assert.Equal(t, []TableTimings{
+		{Table: "events", Convert: 3 * time.Second},
+		{Table: "users", Read: 3 * time.Second, Convert: 2 * time.Second, WriteWait: 5 * time.Second},
+	}, tm.TableStages())
+
+	var nilTimings *Timings
+	nilTimings.Time(StageDdlApply, time.Now())
+	nilTimings.TimeTable(StageRead, "users", time.Now())
+	assert.Nil(t, nilTimings.Stages())
+	assert.Nil(t, nilTimings.TableStages())
+}
+
+func TestWriteStageTimings(t *testing.T) {
+	conv := MakeConv()
+	conv.ToSpanner["Users"] = NameAndCols{Name: "users"}
+	conv.Timings.Add(StageSchemaFetch, 1500*time.Millisecond)
+	conv.Timings.Add(StageDdlApply, 42*time.Second)
+	conv.Timings.AddTable(StageRead, "Users", 2*time.Minute)
+	conv.Timings.AddTable(StageConvert, "Users", 20*time.Second)
+	conv.Timings.AddTable(StageWriteWait, "Users", 90*time.Second)
+	conv.WriteStats = NewWriteStats()
+	start := time.Now()
+	conv.WriteStats.Record("users", WriteSample{Rows: 1, Start: start, Latency: 100 * time.Second})
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeStageTimings(conv, w)
+	w.Flush()
+	expected := `----------------------------
+Timing by Stage
+----------------------------
+Time spent in each stage of the migration. For each table, read is the time spent
+reading rows from the source, convert the time spent converting them, and write
+wait the time spent waiting for Spanner writes to take more rows, while write
+spans the table's Spanner writes. A long write wait means that Spanner writes are
+the bottleneck, and a long read that the source is.
+
+  --------------------------------
+    duration  stage
+  --------------------------------
+        1.5s  schema fetch
+         42s  DDL apply
+
+  ------------------------------------------------------------
+        read     convert  write wait       write  table
+  ------------------------------------------------------------
+         10s         20s       1m30s       1m40s  Users
+
+`
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// UnsignedBigint is the source type name of 64 bit unsigned integer columns,
+// such as MySQL's BIGINT UNSIGNED. Their values can be larger than the
+// maximum INT64 value.
+const UnsignedBigint = "bigint unsigned"
+
+// Strategies for unsigned integer values larger than the maximum INT64 value.
+const (
+	UnsignedFail    = "fail"    // Map to INT64, and drop rows with such values as bad rows. This is the default.
+	UnsignedNumeric = "numeric" // Map to NUMERIC, which holds all unsigned values.
+	UnsignedString  = "string"  // Map to STRING(MAX).
+)
+
+// SetUnsignedOverflow maps all UnsignedBigint source columns to the Spanner
+// type of unsigned overflow strategy strategy.
+func (conv *Conv) SetUnsignedOverflow(strategy string) error {
+	var t ddl.Type
+	var issue []SchemaIssue
+	switch strategy {
+	case UnsignedFail:
+		t, issue = ddl.Type{Name: ddl.Int64}, []SchemaIssue{UnsignedOverflow}
+	case UnsignedNumeric:
+		t = ddl.Type{Name: ddl.Numeric}
+	case UnsignedString:
+		t, issue = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []SchemaIssue{Widened}
+	default:
+		return fmt.Errorf("invalid unsigned overflow strategy %s, accepted values are: fail, numeric, string", strategy)
+	}
+	for srcTable, st := range conv.SrcSchema {
+		spTable, err := GetSpannerTable(conv, srcTable)
+		if err != nil {
+			continue
+		}
+		ct, ok := conv.SpSchema[spTable]
+		if !ok {
+			continue
+		}
+		for srcCol, sc := range st.ColDefs {
+			if sc.Type.Name != UnsignedBigint || len(sc.Type.ArrayBounds) > 0 {
+				continue
+			}
+			spCol, err := GetSpannerCol(conv, srcTable, srcCol, true)
+			if err != nil {
+				continue
+			}
+			cd := ct.ColDefs[spCol]
+			cd.T = t
+			ct.ColDefs[spCol] = cd
+			var issues []SchemaIssue
+			for _, i := range conv.Issues[srcTable][srcCol] {
+				if i != UnsignedOverflow && i != Widened {
+					issues = append(issues, i)
+				}
+			}
+			if conv.Issues[srcTable] == nil {
+				conv.Issues[srcTable] = make(map[string][]SchemaIssue)
+			}
+			conv.Issues[srcTable][srcCol] = append(issues, issue...)
+		}
+		conv.SpSchema[spTable] = ct
+	}
+	return nil
+}
+
+// StatsAddUnsignedOverflow counts an unsigned integer value of source table
+// srcTable that is larger than the maximum INT64 value.
+func (conv *Conv) StatsAddUnsignedOverflow(srcTable string) {
+	if conv.Stats.UnsignedOverflow == nil {
+		conv.Stats.UnsignedOverflow = make(map[string]int64)
+	}
+	conv.Stats.UnsignedOverflow[srcTable]++
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUnsignedOverflow(t *testing.T) {
+	conv := MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Name:     "t1",
+		ColNames: []string{"a", "b"},
+		ColDefs: map[string]schema.Column{
+			"a": {Name: "a", Type: schema.Type{Name: UnsignedBigint}},
+			"b": {Name: "b", Type: schema.Type{Name: "bigint"}},
+		},
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"a", "b"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a": {Name: "a", T: ddl.Type{Name: ddl.Int64}},
+			"b": {Name: "b", T: ddl.Type{Name: ddl.Int64}},
+		},
+	}
+	conv.ToSpanner["t1"] = NameAndCols{Name: "t1", Cols: map[string]string{"a": "a", "b": "b"}}
+	conv.ToSource["t1"] = NameAndCols{Name: "t1", Cols: map[string]string{"a": "a", "b": "b"}}
+	conv.Issues["t1"] = map[string][]SchemaIssue{"a": {UnsignedOverflow}}
+
+	assert.NotNil(t, conv.SetUnsignedOverflow("wrap"))
+
+	assert.Nil(t, conv.SetUnsignedOverflow(UnsignedString))
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, conv.SpSchema["t1"].ColDefs["a"].T)
+	assert.Equal(t, []SchemaIssue{Widened}, conv.Issues["t1"]["a"])
+
+	assert.Nil(t, conv.SetUnsignedOverflow(UnsignedNumeric))
+	assert.Equal(t, ddl.Type{Name: ddl.Numeric}, conv.SpSchema["t1"].ColDefs["a"].T)
+	assert.Empty(t, conv.Issues["t1"]["a"])
+
+	assert.Nil(t, conv.SetUnsignedOverflow(UnsignedFail))
+	assert.Equal(t, ddl.Type{Name: ddl.Int64}, conv.SpSchema["t1"].ColDefs["a"].T)
+	assert.Equal(t, []SchemaIssue{UnsignedOverflow}, conv.Issues["t1"]["a"])
+	assert.Equal(t, ddl.Type{Name: ddl.Int64}, conv.SpSchema["t1"].ColDefs["b"].T)
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxLatencySamples bounds the commit latencies kept per table: beyond it,
+// latencies are sampled (reservoir sampling), which keeps percentiles
+// accurate with bounded memory.
+const maxLatencySamples = 100000
+
+// WriteStats records the performance of the Spanner writes of bulk data
+// migration, broken down by Spanner table, so that the report can guide the
+// tuning of the parallelism of later runs. It is safe for concurrent use.
+type WriteStats struct {
+	lock   sync.Mutex
+	tables map[string]*tableWriteStats
+	rand   *rand.Rand
+}
+
+type tableWriteStats struct {
+	rows      int64           // Rows written.
+	bytes     int64           // Estimate of the bytes of the rows written.
+	writes    int64           // Writes, including failed ones and retries.
+	retries   int64           // Writes of parts of failed writes.
+	aborted   int64           // Writes aborted by Spanner.
+	failed    int64           // Writes that failed, including aborted ones.
+	commits   int64           // Writes that committed.
+	latencies []time.Duration // Commit latencies, sampled if there are more than maxLatencySamples.
+	start     time.Time       // Start of the first write.
+	end       time.Time       // End of the last write.
+}
+
+// WriteSample describes a write of rows of a table to Spanner.
+type WriteSample struct {
+	Rows    int64
+	Bytes   int64
+	Start   time.Time
+	Latency time.Duration
+	Retry   bool // If true, the write is a retry of part of a failed write.
+	Aborted bool // If true, Spanner aborted the write, e.g. because of lock contention.
+	Failed  bool
+}
+
+// TableWritePerformance summarizes the Spanner writes of the rows of a
+// table.
+type TableWritePerformance struct {
+	Table         string
+	Rows          int64
+	Bytes         int64
+	Writes        int64
+	Retries       int64
+	Aborted       int64
+	Failed        int64
+	Duration      time.Duration // From the start of the first write to the end of the last.
+	RowsPerSecond float64
+	LatencyP50    time.Duration // Percentiles of the latency of committed writes.
+	LatencyP95    time.Duration
+	LatencyP99    time.Duration
+}
+
+// NewWriteStats returns an empty WriteStats.
+func NewWriteStats() *WriteStats {
+	return &WriteStats{tables: make(map[string]*tableWriteStats), rand: rand.New(rand.NewSource(1))}
+}
+
+// Record records write w of the rows of Spanner table 'table'.
+func (ws *WriteStats) Record(table string, w WriteSample) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	t, ok := ws.tables[table]
+	if !ok {
+		t = &tableWriteStats{start: w.Start}
+		ws.tables[table] = t
+	}
+	t.writes++
+	if w.Retry {
+		t.retries++
+	}
+	if w.Start.Before(t.start) {
+		t.start = w.Start
+	}
+	if end := w.Start.Add(w.Latency); end.After(t.end) {
+		t.end = end
+	}
+	if w.Failed {
+		t.failed++
+		if w.Aborted {
+			t.aborted++
+		}
+		return
+	}
+	t.rows += w.Rows
+	t.bytes += w.Bytes
+	t.commits++
+	if len(t.latencies) < maxLatencySamples {
+		t.latencies = append(t.latencies, w.Latency)
+	} else if i := ws.rand.Int63n(t.commits); i < maxLatencySamples {
+		t.latencies[i] = w.Latency
+	}
+}
+
+// Tables returns the write performance of each table, sorted by table name.
+func (ws *WriteStats) Tables() []TableWritePerformance {
+	if ws == nil {
+		return nil
+	}
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	var l []TableWritePerformance
+	for name, t := range ws.tables {
+		p := TableWritePerformance{
+			Table:    name,
+			Rows:     t.rows,
+			Bytes:    t.bytes,
+			Writes:   t.writes,
+			Retries:  t.retries,
+			Aborted:  t.aborted,
+			Failed:   t.failed,
+			Duration: t.end.Sub(t.start),
+		}
+		if p.Duration > 0 {
+			p.RowsPerSecond = float64(t.rows) / p.Duration.Seconds()
+		}
+		latencies := append([]time.Duration(nil), t.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		p.LatencyP50 = percentile(latencies, 50)
+		p.LatencyP95 = percentile(latencies, 95)
+		p.LatencyP99 = percentile(latencies, 99)
+		l = append(l, p)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Table < l[j].Table })
+	return l
+}
+
+// percentile returns the p-th percentile of sorted, by the nearest-rank
+// method, or 0 if sorted is empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
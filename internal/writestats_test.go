@@ -0,0 +1,97 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteStats(t *testing.T) {
+	ws := NewWriteStats()
+	start := time.Date(2022, 1, 8, 10, 0, 0, 0, time.UTC)
+	for i := 1; i <= 100; i++ {
+		ws.Record("orders", WriteSample{Rows: 10, Bytes: 100, Start: start.Add(time.Duration(i) * 10 * time.Millisecond), Latency: time.Duration(i) * time.Millisecond})
+	}
+	ws.Record("orders", WriteSample{Rows: 5, Start: start, Latency: time.Second, Failed: true, Aborted: true})
+	ws.Record("orders", WriteSample{Rows: 1, Bytes: 10, Start: start.Add(time.Second), Latency: time.Second, Retry: true})
+	ws.Record("items", WriteSample{Rows: 3, Start: start, Latency: 500 * time.Millisecond, Failed: true})
+	perf := ws.Tables()
+	assert.Equal(t, []TableWritePerformance{
+		{Table: "items", Writes: 1, Failed: 1, Duration: 500 * time.Millisecond},
+		{
+			Table:         "orders",
+			Rows:          1001,
+			Bytes:         10010,
+			Writes:        102,
+			Retries:       1,
+			Aborted:       1,
+			Failed:        1,
+			Duration:      2 * time.Second,
+			RowsPerSecond: 500.5,
+			LatencyP50:    51 * time.Millisecond,
+			LatencyP95:    96 * time.Millisecond,
+			LatencyP99:    100 * time.Millisecond,
+		},
+	}, perf)
+	var nilStats *WriteStats
+	assert.Nil(t, nilStats.Tables())
+}
+
+func TestWriteStats_Sampled(t *testing.T) {
+	ws := NewWriteStats()
+	for i := 0; i < 2*maxLatencySamples; i++ {
+		ws.Record("t", WriteSample{Rows: 1, Latency: time.Duration(i%100+1) * time.Millisecond})
+	}
+	assert.Equal(t, maxLatencySamples, len(ws.tables["t"].latencies))
+	p := ws.Tables()[0]
+	assert.Equal(t, int64(2*maxLatencySamples), p.Rows)
+	assert.InDelta(t, 50*time.Millisecond, p.LatencyP50, float64(2*time.Millisecond))
+	assert.InDelta(t, 99*time.Millisecond, p.LatencyP99, float64(time.Millisecond))
+}
+
+func TestWriteWritePerformance(t *testing.T) {
+	conv := MakeConv()
+	conv.WriteStats = NewWriteStats()
+	start := time.Date(2022, 1, 8, 10, 0, 0, 0, time.UTC)
+	conv.WriteStats.Record("orders", WriteSample{Rows: 1000, Start: start, Latency: 250 * time.Millisecond})
+	conv.WriteStats.Record("orders", WriteSample{Rows: 1000, Start: start.Add(250 * time.Millisecond), Latency: 1500 * time.Microsecond, Failed: true, Aborted: true})
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	writeWritePerformance(conv.WriteStats.Tables(), w)
+	w.Flush()
+	expected := `----------------------------
+Appendix: Write Performance
+----------------------------
+Throughput and commit latency percentiles of the Spanner writes of each table.
+Tables with high p99 latencies, retries or aborted writes are contended: give
+them fewer parallel writes (writeLimit in the -write-tuning file) or smaller
+writes (batchRows). Tables with low latencies may take more parallel writes
+(-write-limit).
+
+  ----------------------------------------------------------------------------
+      rows/s    writes       p50       p95       p99  retries  aborted  table
+  ----------------------------------------------------------------------------
+        3976         2   250.0ms   250.0ms   250.0ms        0        1  orders
+
+`
+	assert.Equal(t, expected, buf.String())
+	r := BuildJSONReport("mysql", conv, nil)
+	assert.Equal(t, []JSONWritePerformance{{SpTable: "orders", Rows: 1000, Writes: 2, Aborted: 1, Failed: 1, Seconds: 0.2515, RowsPerSecond: 1000 / 0.2515, LatencyP50: 250, LatencyP95: 250, LatencyP99: 250}}, r.WritePerformance)
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// WriteTuning tunes the parallelism of the writes of bulk data migration. It
+// is read from a JSON file, e.g.
+//
+//	{"autoTune": true, "tables": {"orders": {"writeLimit": 4, "batchRows": 500}}}
+type WriteTuning struct {
+	// AutoTune adjusts the number of parallel writes during the migration,
+	// based on the commit latencies and aborted transactions observed.
+	AutoTune bool `json:"autoTune"`
+	// Tables maps Spanner table names to the tuning of the writes of their
+	// rows.
+	Tables map[string]TableWriteTuning `json:"tables"`
+	// MaxBatchBytes limits the bytes of each write, and of the rows buffered
+	// for writing, to bound memory use when rows are very wide (no limit
+	// beyond Spanner's if 0).
+	MaxBatchBytes int64 `json:"maxBatchBytes"`
+}
+
+// TableWriteTuning tunes the writes of the rows of a table.
+type TableWriteTuning struct {
+	WriteLimit int64 `json:"writeLimit"` // Limit on parallel writes of the table's rows, within the overall write limit (no limit if 0).
+	BatchRows  int64 `json:"batchRows"`  // Limit on rows per write (if 0, only Spanner's limits apply).
+}
+
+// ReadWriteTuning reads the WriteTuning in JSON file 'name'.
+func ReadWriteTuning(name string) (WriteTuning, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return WriteTuning{}, fmt.Errorf("can't read write tuning file %s: %v", name, err)
+	}
+	var wt WriteTuning
+	if err := json.Unmarshal(b, &wt); err != nil {
+		return WriteTuning{}, fmt.Errorf("can't parse write tuning file %s: %v", name, err)
+	}
+	if wt.MaxBatchBytes < 0 {
+		return WriteTuning{}, fmt.Errorf("invalid maxBatchBytes in write tuning file %s: can't be negative", name)
+	}
+	for t, tt := range wt.Tables {
+		if tt.WriteLimit < 0 || tt.BatchRows < 0 {
+			return WriteTuning{}, fmt.Errorf("invalid tuning of table %s in write tuning file %s: writeLimit and batchRows can't be negative", t, name)
+		}
+	}
+	return wt, nil
+}
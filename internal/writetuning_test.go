@@ -0,0 +1,51 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadWriteTuning(t *testing.T) {
+	dir := t.TempDir()
+	write := func(s string) string {
+		name := filepath.Join(dir, "tuning.json")
+		assert.Nil(t, os.WriteFile(name, []byte(s), 0644))
+		return name
+	}
+	wt, err := ReadWriteTuning(write(`{"autoTune": true, "tables": {"orders": {"writeLimit": 4, "batchRows": 500}, "users": {"batchRows": 100}}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, WriteTuning{AutoTune: true, Tables: map[string]TableWriteTuning{
+		"orders": {WriteLimit: 4, BatchRows: 500},
+		"users":  {BatchRows: 100},
+	}}, wt)
+
+	wt, err = ReadWriteTuning(write(`{"maxBatchBytes": 1048576}`))
+	assert.Nil(t, err)
+	assert.Equal(t, WriteTuning{MaxBatchBytes: 1 << 20}, wt)
+
+	_, err = ReadWriteTuning(write(`{"tables": {"orders": {"writeLimit": -1}}}`))
+	assert.NotNil(t, err)
+	_, err = ReadWriteTuning(write(`{"maxBatchBytes": -1}`))
+	assert.NotNil(t, err)
+	_, err = ReadWriteTuning(write(`{"autoTune": "yes"}`))
+	assert.NotNil(t, err)
+	_, err = ReadWriteTuning(filepath.Join(dir, "missing.json"))
+	assert.NotNil(t, err)
+}
@@ -23,6 +23,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path"
 	"strings"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
 	"github.com/cloudspannerecosystem/harbourbridge/web"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2"
 	"github.com/google/subcommands"
@@ -65,7 +67,7 @@ func setupGlobalFlags() {
 	flag.StringVar(&dbNameOverride, "dbname", "", "dbname: name to use for Spanner DB")
 	flag.StringVar(&instanceOverride, "instance", "", "instance: Spanner instance to use")
 	flag.StringVar(&filePrefix, "prefix", "", "prefix: file prefix for generated files")
-	flag.StringVar(&driverName, "driver", constants.PGDUMP, "driver name: flag for accessing source DB or dump files (accepted values are \"pg_dump\", \"postgres\", \"mysqldump\", and \"mysql\")")
+	flag.StringVar(&driverName, "driver", constants.PGDUMP, fmt.Sprintf("driver name: flag for accessing source DB or dump files (accepted values are %s)", strings.Join(registry.LegacyModeDrivers(), ", ")))
 	flag.Int64Var(&schemaSampleSize, "schema-sample-size", int64(100000), "schema-sample-size: the number of rows to use for inferring schema (only for DynamoDB)")
 	flag.BoolVar(&verbose, "v", false, "verbose: print additional output")
 	flag.BoolVar(&verbose, "verbose", false, "verbose: print additional output")
@@ -99,6 +101,48 @@ Sample usage:
 `, os.Args[0], os.Args[0])
 }
 
+// equivalentSubcommand returns the subcommand doing what the global command
+// line flags do, to ease moving to subcommands.
+func equivalentSubcommand() string {
+	name := "schema-and-data"
+	if schemaOnly {
+		name = "schema"
+	} else if dataOnly {
+		name = "data"
+	}
+	source := driverName
+	if d, ok := registry.Get(driverName); ok {
+		source = d.Info().Source
+	}
+	args := []string{path.Base(os.Args[0]), name, "-source=" + source}
+	if dumpFilePath != "" {
+		args = append(args, fmt.Sprintf("-source-profile=%q", "file="+dumpFilePath))
+	}
+	if sessionJSON != "" && !schemaOnly {
+		args = append(args, "-session="+sessionJSON)
+	}
+	var target []string
+	if instanceOverride != "" {
+		target = append(target, "instance="+instanceOverride)
+	}
+	if dbNameOverride != "" {
+		target = append(target, "dbName="+dbNameOverride)
+	}
+	if targetDb == constants.TargetExperimentalPostgres {
+		target = append(target, "dialect=postgresql")
+	}
+	if len(target) > 0 {
+		args = append(args, fmt.Sprintf("-target-profile=%q", strings.Join(target, ",")))
+	}
+	if filePrefix != "" {
+		args = append(args, "-prefix="+filePrefix)
+	}
+	if skipForeignKeys && !schemaOnly {
+		args = append(args, "-skip-foreign-keys")
+	}
+	return strings.Join(args, " ")
+}
+
 func main() {
 	ctx := context.Background()
 	lf, err := utils.SetupLogFile()
@@ -113,11 +157,23 @@ func main() {
 	// top-level flags in subcommand then.
 	if len(os.Args) > 1 && os.Args[1] != "" && !strings.HasPrefix(os.Args[1], "-") {
 		// Using HB CLI in subcommand mode.
+		// Each subcommand has its own flags, listed by "help <subcommand>".
 		subcommands.Register(subcommands.HelpCommand(), "")
 		subcommands.Register(subcommands.CommandsCommand(), "")
-		subcommands.Register(&cmd.SchemaCmd{}, "")
-		subcommands.Register(&cmd.DataCmd{}, "")
-		subcommands.Register(&cmd.SchemaAndDataCmd{}, "")
+		subcommands.Register(&cmd.CompletionCmd{}, "")
+		subcommands.Register(&cmd.WizardCmd{}, "")
+		subcommands.Register(&cmd.AssessCmd{}, "evaluation")
+		subcommands.Register(&cmd.BenchmarkCmd{}, "evaluation")
+		subcommands.Register(&cmd.GenerateDataCmd{}, "evaluation")
+		subcommands.Register(&cmd.SchemaCmd{}, "migration")
+		subcommands.Register(&cmd.DataCmd{}, "migration")
+		subcommands.Register(&cmd.SchemaAndDataCmd{}, "migration")
+		subcommands.Register(&cmd.ManifestCmd{}, "migration")
+		subcommands.Register(&cmd.PreflightCmd{}, "migration")
+		subcommands.Register(&cmd.ValidateCmd{}, "migration")
+		subcommands.Register(&cmd.MinimalDowntimeCmd{}, "migration")
+		// Keeps the former name of the stream subcommand working.
+		subcommands.Register(subcommands.Alias("minimal-downtime", &cmd.MinimalDowntimeCmd{}), "migration")
 		flag.Parse()
 		os.Exit(int(subcommands.Execute(ctx)))
 	}
@@ -152,6 +208,8 @@ func main() {
 	}
 
 	internal.VerboseInit(verbose)
+	// Printed on stderr, so that it doesn't mix with output e.g. of dry runs.
+	fmt.Fprintf(os.Stderr, "Warning: global command line flags are deprecated, use the equivalent subcommand instead: %s\n\n", equivalentSubcommand())
 	if schemaOnly && dataOnly {
 		panic(fmt.Errorf("can't use both schema-only and data-only modes at once"))
 	}
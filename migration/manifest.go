@@ -0,0 +1,257 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+)
+
+// DefaultManifestConcurrency is the default number of migrations of a
+// manifest run at the same time.
+const DefaultManifestConcurrency = 4
+
+// Manifest lists the migrations of several source databases to several
+// Spanner databases, run in one invocation by RunManifest, e.g. to migrate
+// many small services at once. It is read from a JSON file, e.g.
+//
+//	{
+//	  "concurrency": 2,
+//	  "migrations": [
+//	    {"name": "orders", "source": "mysql", "sourceProfile": "host=orders-db,user=hb,dbName=orders", "targetProfile": "instance=my-instance,dbName=orders"},
+//	    {"name": "users", "source": "postgres", "sourceProfile": "host=users-db,user=hb,dbName=users", "targetProfile": "instance=my-instance,dbName=users", "schemaOnly": true}
+//	  ]
+//	}
+type Manifest struct {
+	// Concurrency is the number of migrations run at the same time. It
+	// defaults to DefaultManifestConcurrency.
+	Concurrency int             `json:"concurrency"`
+	Migrations  []ManifestEntry `json:"migrations"`
+}
+
+// ManifestEntry is a migration of a manifest. Its fields are those of Config.
+// TargetProfile must name the database, which can't be the target of another
+// migration of the manifest.
+type ManifestEntry struct {
+	// Name identifies the migration in the report, and names its log file.
+	Name            string `json:"name"`
+	Source          string `json:"source"`
+	SourceProfile   string `json:"sourceProfile"`
+	TargetProfile   string `json:"targetProfile"`
+	WriteLimit      int64  `json:"writeLimit"`
+	SchemaOnly      bool   `json:"schemaOnly"` // Only convert the schema and create the database.
+	SkipForeignKeys bool   `json:"skipForeignKeys"`
+}
+
+var manifestNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ReadManifest reads the Manifest in JSON file 'name' and validates it.
+func ReadManifest(name string) (Manifest, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("can't read manifest %s: %v", name, err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Manifest{}, fmt.Errorf("can't parse manifest %s: %v", name, err)
+	}
+	if err := m.Validate(); err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest %s: %v", name, err)
+	}
+	return m, nil
+}
+
+// Validate checks that the migrations of m have unique names and distinct
+// target databases, named in their target profiles, which must be valid.
+func (m Manifest) Validate() error {
+	if len(m.Migrations) == 0 {
+		return fmt.Errorf("no migrations")
+	}
+	if m.Concurrency < 0 {
+		return fmt.Errorf("concurrency can't be negative")
+	}
+	names := make(map[string]bool)
+	targets := make(map[string]string)
+	for i, e := range m.Migrations {
+		if !manifestNameRegexp.MatchString(e.Name) {
+			return fmt.Errorf("migration %d: invalid name %q, expected letters, digits, '_', '.' and '-'", i+1, e.Name)
+		}
+		if names[e.Name] {
+			return fmt.Errorf("migration %s: duplicate name", e.Name)
+		}
+		names[e.Name] = true
+		if e.Source == "" {
+			return fmt.Errorf("migration %s: please specify the source", e.Name)
+		}
+		tp, err := profiles.NewTargetProfile(e.TargetProfile)
+		if err != nil {
+			return fmt.Errorf("migration %s: invalid target profile: %v", e.Name, err)
+		}
+		sp := tp.Conn.Sp
+		if sp.Dbname == "" {
+			return fmt.Errorf("migration %s: please specify the target database with dbName in the target profile", e.Name)
+		}
+		target := sp.Project + "/" + sp.Instance + "/" + sp.Dbname
+		if other, ok := targets[target]; ok {
+			return fmt.Errorf("migrations %s and %s have the same target database %s", other, e.Name, sp.Dbname)
+		}
+		targets[target] = e.Name
+	}
+	return nil
+}
+
+// ManifestResult is the result of a migration of a manifest.
+type ManifestResult struct {
+	Name     string
+	Source   string
+	Database string // URI of the Spanner database, empty for dry runs.
+	Tables   int
+	Rows     int64 // Source rows read.
+	BadRows  int64 // Rows that couldn't be converted or written.
+	Duration time.Duration
+	Err      error
+}
+
+// Status returns "failed" if r failed, "succeeded" otherwise.
+func (r ManifestResult) Status() string {
+	if r.Err != nil {
+		return "failed"
+	}
+	return "succeeded"
+}
+
+// ManifestReport is the consolidated report of the migrations of a manifest,
+// in the order of the manifest.
+type ManifestReport struct {
+	Results  []ManifestResult
+	Duration time.Duration
+}
+
+// Failed returns the number of migrations that failed.
+func (r ManifestReport) Failed() int {
+	n := 0
+	for _, x := range r.Results {
+		if x.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Write writes r to w as a table of the migrations, followed by the errors
+// of those that failed.
+func (r ManifestReport) Write(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Migration\tSource\tDatabase\tStatus\tTables\tRows\tBad rows\tDuration")
+	for _, x := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%v\n", x.Name, x.Source, x.Database, x.Status(), x.Tables, x.Rows, x.BadRows, x.Duration.Round(time.Second))
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d migration(s) succeeded, %d failed, in %v.\n", len(r.Results)-r.Failed(), r.Failed(), r.Duration.Round(time.Second))
+	for _, x := range r.Results {
+		if x.Err != nil {
+			fmt.Fprintf(w, "Migration %s failed: %v\n", x.Name, x.Err)
+		}
+	}
+}
+
+// RunManifest runs the migrations of m, at most m.Concurrency at the same
+// time: each converts the schema of its source database, creates or updates
+// its Spanner database and, unless it is schema only, migrates the data.
+// cfg holds the settings shared by all migrations (Target, DryRun, WriteLimit,
+// Force and Out). If logDir isn't empty, the output of each migration is
+// written to file <name>.log in logDir instead of cfg.Out. A migration that
+// fails doesn't stop the others: errors are reported in the results.
+func RunManifest(ctx context.Context, m Manifest, cfg Config, logDir string) (ManifestReport, error) {
+	if err := m.Validate(); err != nil {
+		return ManifestReport{}, fmt.Errorf("invalid manifest: %v", err)
+	}
+	concurrency := m.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultManifestConcurrency
+	}
+	if logDir != "" {
+		if err := os.MkdirAll(logDir, 0755); err != nil {
+			return ManifestReport{}, fmt.Errorf("can't create log directory %s: %v", logDir, err)
+		}
+	}
+	start := time.Now()
+	results := make([]ManifestResult, len(m.Migrations))
+	workers := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, e := range m.Migrations {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(i int, e ManifestEntry) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			results[i] = runManifestEntry(ctx, e, cfg, logDir)
+		}(i, e)
+	}
+	wg.Wait()
+	return ManifestReport{Results: results, Duration: time.Since(start)}, nil
+}
+
+// runManifestEntry runs migration e of a manifest with the settings of cfg.
+func runManifestEntry(ctx context.Context, e ManifestEntry, cfg Config, logDir string) (r ManifestResult) {
+	start := time.Now()
+	r = ManifestResult{Name: e.Name, Source: e.Source}
+	defer func() { r.Duration = time.Since(start) }()
+	cfg.Source, cfg.SourceProfile, cfg.TargetProfile = e.Source, e.SourceProfile, e.TargetProfile
+	cfg.SkipForeignKeys = e.SkipForeignKeys
+	if e.WriteLimit > 0 {
+		cfg.WriteLimit = e.WriteLimit
+	}
+	if logDir != "" {
+		f, err := os.Create(filepath.Join(logDir, e.Name+".log"))
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		defer f.Close()
+		cfg.Out = f
+	}
+	s, err := ConvertSchema(ctx, cfg)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Database, r.Tables = s.Database, len(s.Conv.SpSchema)
+	if e.SchemaOnly {
+		return r
+	}
+	d, err := MigrateData(ctx, cfg, s.Conv)
+	r.Rows, r.BadRows = s.Conv.Rows(), s.Conv.BadRows()
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	for _, n := range d.DroppedRows {
+		r.BadRows += n
+	}
+	return r
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestValidate(t *testing.T) {
+	entry := func(name, targetProfile string) ManifestEntry {
+		return ManifestEntry{Name: name, Source: "mysql", SourceProfile: "file=../test_data/mysqldump.test.out", TargetProfile: targetProfile}
+	}
+	tc := []struct {
+		name string
+		m    Manifest
+		ok   bool
+	}{
+		{"Valid", Manifest{Migrations: []ManifestEntry{entry("a", "instance=i,dbName=a"), entry("b", "instance=i,dbName=b")}}, true},
+		{"Same database on other instances", Manifest{Migrations: []ManifestEntry{entry("a", "instance=i,dbName=a"), entry("b", "instance=j,dbName=a")}}, true},
+		{"Empty", Manifest{}, false},
+		{"Negative concurrency", Manifest{Concurrency: -1, Migrations: []ManifestEntry{entry("a", "dbName=a")}}, false},
+		{"Invalid name", Manifest{Migrations: []ManifestEntry{entry("../a", "dbName=a")}}, false},
+		{"Duplicate name", Manifest{Migrations: []ManifestEntry{entry("a", "dbName=a"), entry("a", "dbName=b")}}, false},
+		{"Missing source", Manifest{Migrations: []ManifestEntry{{Name: "a", TargetProfile: "dbName=a"}}}, false},
+		{"Missing dbName", Manifest{Migrations: []ManifestEntry{entry("a", "instance=i")}}, false},
+		{"Same target", Manifest{Migrations: []ManifestEntry{entry("a", "instance=i,dbName=a"), entry("b", "instance=i,dbName=a")}}, false},
+	}
+	for _, tc := range tc {
+		assert.Equal(t, tc.ok, tc.m.Validate() == nil, tc.name)
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "manifest.json")
+	assert.Nil(t, os.WriteFile(name, []byte(`{"concurrency": 2, "migrations": [{"name": "shop", "source": "mysql", "sourceProfile": "file=shop.sql", "targetProfile": "dbName=shop", "schemaOnly": true}]}`), 0644))
+	m, err := ReadManifest(name)
+	assert.Nil(t, err)
+	assert.Equal(t, Manifest{Concurrency: 2, Migrations: []ManifestEntry{{Name: "shop", Source: "mysql", SourceProfile: "file=shop.sql", TargetProfile: "dbName=shop", SchemaOnly: true}}}, m)
+
+	assert.Nil(t, os.WriteFile(name, []byte(`{"migrations": []}`), 0644))
+	_, err = ReadManifest(name)
+	assert.NotNil(t, err)
+	_, err = ReadManifest(filepath.Join(dir, "missing.json"))
+	assert.NotNil(t, err)
+}
+
+func TestRunManifestDryRun(t *testing.T) {
+	cfg := dryRunConfig(t)
+	m := Manifest{Concurrency: 2, Migrations: []ManifestEntry{
+		{Name: "cart", Source: "mysql", SourceProfile: cfg.SourceProfile, TargetProfile: "dbName=cart"},
+		{Name: "schema", Source: "mysql", SourceProfile: cfg.SourceProfile, TargetProfile: "dbName=schema", SchemaOnly: true},
+		{Name: "missing", Source: "mysql", SourceProfile: "file=no_such_file.sql", TargetProfile: "dbName=missing"},
+	}}
+	logDir := t.TempDir()
+	r, err := RunManifest(context.Background(), m, cfg, logDir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, r.Failed())
+	assert.Equal(t, []string{"cart", "schema", "missing"}, []string{r.Results[0].Name, r.Results[1].Name, r.Results[2].Name})
+	assert.Nil(t, r.Results[0].Err)
+	assert.Equal(t, r.Results[0].Tables, r.Results[1].Tables)
+	assert.True(t, r.Results[0].Rows > 0)
+	assert.Equal(t, int64(0), r.Results[1].Rows)
+	assert.NotNil(t, r.Results[2].Err)
+	_, err = os.Stat(filepath.Join(logDir, "cart.log"))
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	r.Write(&b)
+	assert.Contains(t, b.String(), "2 migration(s) succeeded, 1 failed")
+	assert.Contains(t, b.String(), "Migration missing failed:")
+
+	_, err = RunManifest(context.Background(), Manifest{}, cfg, "")
+	assert.NotNil(t, err)
+}
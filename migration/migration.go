@@ -0,0 +1,327 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migration is the Go API of HarbourBridge, for services that embed
+// migrations instead of running the harbourbridge binary. It runs the steps
+// of the schema, data and stream subcommands, without writing their
+// report, session and bad data files:
+//
+//	cfg := migration.Config{
+//		Source:        "mysql",
+//		SourceProfile: "host=localhost,user=root,dbName=shop",
+//		TargetProfile: "instance=my-instance,dbName=shop",
+//	}
+//	s, err := migration.ConvertSchema(ctx, cfg)
+//	if err != nil {
+//		return err
+//	}
+//	d, err := migration.MigrateData(ctx, cfg, s.Conv)
+//
+// Source drivers are registered by importing this package. Errors can be
+// classified with package errs.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	migrationpb "github.com/cloudspannerecosystem/harbourbridge/proto/migration"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/writer"
+)
+
+// Conv is the state of a conversion: the source and Spanner schemas, the
+// mapping between them, and statistics. It is returned by ConvertSchema
+// and passed to the data migration.
+type Conv = internal.Conv
+
+// DefaultWriteLimit is the default number of parallel writes to Spanner.
+const DefaultWriteLimit = 40
+
+// Config configures a migration. Its fields are the flags of the
+// harbourbridge subcommands.
+type Config struct {
+	// Source is the source database, as the -source flag, e.g. "mysql".
+	Source string
+	// SourceProfile and TargetProfile are as the -source-profile and
+	// -target-profile flags. When TargetProfile doesn't name a database,
+	// ConvertSchema creates one with a generated name: pass the name in
+	// TargetProfile to migrate data to it.
+	SourceProfile string
+	TargetProfile string
+	// Target is TargetSpanner (the default) or TargetEmulator.
+	Target string
+	// WriteLimit is the number of parallel writes to Spanner. It defaults
+	// to DefaultWriteLimit.
+	WriteLimit int64
+	// DryRun converts the schema and data without writing to Spanner.
+	DryRun bool
+	// SkipForeignKeys doesn't add foreign keys after migrating data.
+	SkipForeignKeys bool
+	// Force takes over the leases that other migrations hold on the tables
+	// being migrated, as the -force flag. Without it, migrating data to
+	// tables leased by another running migration fails with an error of
+	// kind errs.ErrMigrationLocked.
+	Force bool
+	// Out is where progress and messages are written. It defaults to
+	// os.Stdout.
+	Out *os.File
+}
+
+// SchemaResult is the result of a schema conversion.
+type SchemaResult struct {
+	Conv *Conv
+	// Database is the URI of the Spanner database created or updated. It is
+	// empty for dry runs.
+	Database string
+}
+
+// DataResult is the result of a data migration.
+type DataResult struct {
+	// Database is the URI of the Spanner database. It is empty for dry runs.
+	Database string
+	// DroppedRows counts the rows that couldn't be written, by Spanner table.
+	DroppedRows map[string]int64
+	// WriteErrors counts the errors of writes to Spanner, by error message.
+	WriteErrors map[string]int64
+	// Mismatches lists the tables whose row count doesn't match the rows
+	// migrated. It is only set by StartStreaming.
+	Mismatches []conversion.RowCountMismatch
+}
+
+// setup holds the profiles and input of a migration.
+type setup struct {
+	cfg           Config
+	sourceProfile profiles.SourceProfile
+	targetProfile profiles.TargetProfile
+	io            utils.IOStreams
+	closeTunnels  func()
+}
+
+// prepare parses the profiles of cfg and opens its input.
+func prepare(cfg Config) (*setup, error) {
+	cfg, sourceProfile, targetProfile, err := parseConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	closeTunnels, err := sourceProfile.OpenTunnels()
+	if err != nil {
+		return nil, err
+	}
+	io, err := openIOStreams(sourceProfile.Driver, dumpFile(sourceProfile), cfg.Out)
+	if err != nil {
+		closeTunnels()
+		return nil, err
+	}
+	return &setup{cfg: cfg, sourceProfile: sourceProfile, targetProfile: targetProfile, io: io, closeTunnels: closeTunnels}, nil
+}
+
+// parseConfig sets the defaults of cfg and parses its profiles.
+func parseConfig(cfg Config) (Config, profiles.SourceProfile, profiles.TargetProfile, error) {
+	if logger.Log == nil {
+		logger.Log = zap.NewNop()
+	}
+	if cfg.Out == nil {
+		cfg.Out = os.Stdout
+	}
+	if cfg.WriteLimit <= 0 {
+		cfg.WriteLimit = DefaultWriteLimit
+	}
+	if cfg.Target == "" {
+		cfg.Target = TargetSpanner
+	}
+	sourceProfile, err := profiles.NewSourceProfile(cfg.SourceProfile, cfg.Source)
+	if err != nil {
+		return cfg, sourceProfile, profiles.TargetProfile{}, fmt.Errorf("invalid source profile: %v", err)
+	}
+	sourceProfile.Driver, err = SourceDriver(sourceProfile, cfg.Source)
+	if err != nil {
+		return cfg, sourceProfile, profiles.TargetProfile{}, err
+	}
+	targetProfile, err := profiles.NewTargetProfile(cfg.TargetProfile)
+	if err != nil {
+		return cfg, sourceProfile, targetProfile, fmt.Errorf("invalid target profile: %v", err)
+	}
+	targetProfile.TargetDb = targetProfile.ToLegacyTargetDb()
+	err = ConfigureTarget(cfg.Target, &targetProfile)
+	return cfg, sourceProfile, targetProfile, err
+}
+
+// close closes the input of s and the tunnels to its source databases.
+func (s *setup) close() {
+	if s.io.In != os.Stdin {
+		s.io.In.Close()
+	}
+	s.closeTunnels()
+}
+
+// ConvertSchema converts the schema of the source database of cfg to a
+// Spanner schema and, unless cfg.DryRun is set, creates the Spanner database
+// with this schema, or updates it if it already exists.
+func ConvertSchema(ctx context.Context, cfg Config) (*SchemaResult, error) {
+	s, err := prepare(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+	conv, err := conversion.SchemaConv(s.sourceProfile, s.targetProfile, &s.io)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert schema: %v", err)
+	}
+	conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
+	conv.Audit.MigrationType = migrationpb.MigrationData_SCHEMA_ONLY.Enum()
+	if s.cfg.DryRun {
+		conv.Audit.DryRun = true
+		return &SchemaResult{Conv: conv}, nil
+	}
+	adminClient, client, dbURI, err := CreateDatabaseClient(ctx, s.targetProfile, s.sourceProfile.Driver, s.cfg.Out)
+	if err != nil {
+		return nil, fmt.Errorf("can't create database client: %v", err)
+	}
+	defer adminClient.Close()
+	defer client.Close()
+	if err = conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, s.sourceProfile.Driver, s.targetProfile.TargetDb, conv, s.cfg.Out); err != nil {
+		return nil, fmt.Errorf("can't create/update database: %v", err)
+	}
+	return &SchemaResult{Conv: conv, Database: dbURI}, nil
+}
+
+// MigrateData migrates the data of the source database of cfg to the Spanner
+// database of cfg, whose schema must be that of conv, runs the fix-up
+// statements of conv (see Conv.AddFixup), and then adds foreign keys unless
+// cfg.SkipForeignKeys is set. conv is typically returned by
+// ConvertSchema, and can be nil for sources that use the schema of the
+// Spanner database, e.g. CSV files. If streaming is enabled in the source
+// profile, changes made during the migration are streamed too.
+func MigrateData(ctx context.Context, cfg Config, conv *Conv) (*DataResult, error) {
+	s, err := prepare(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+	if conv == nil {
+		if !s.sourceProfile.UseTargetSchema() {
+			return nil, fmt.Errorf("a conv is required to migrate data from source %s", s.sourceProfile.Driver)
+		}
+		conv = internal.MakeConv()
+	}
+	conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
+	conv.Audit.MigrationType = migrationpb.MigrationData_DATA_ONLY.Enum()
+	conv.Audit.DryRun = s.cfg.DryRun
+	conv.ForceLock = s.cfg.Force
+	if s.cfg.DryRun {
+		bw, err := conversion.DataConv(ctx, s.sourceProfile, s.targetProfile, &s.io, nil, conv, true, s.cfg.WriteLimit)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert data: %v", err)
+		}
+		return dataResult("", bw), nil
+	}
+	adminClient, client, dbURI, err := CreateDatabaseClient(ctx, s.targetProfile, s.sourceProfile.Driver, s.cfg.Out)
+	if err != nil {
+		return nil, fmt.Errorf("can't create database client: %v", err)
+	}
+	defer adminClient.Close()
+	defer client.Close()
+	if !s.sourceProfile.UseTargetSchema() {
+		if err = ValidateDatabase(ctx, conv.TargetDb, dbURI, adminClient, client, conv); err != nil {
+			return nil, fmt.Errorf("error while validating existing database: %v", err)
+		}
+	}
+	bw, err := conversion.DataConv(ctx, s.sourceProfile, s.targetProfile, &s.io, client, conv, true, s.cfg.WriteLimit)
+	if err != nil {
+		return nil, fmt.Errorf("can't finish data conversion for db %s: %v", dbURI, err)
+	}
+	conversion.RunFixups(ctx, client, conv, s.cfg.Out)
+	if !s.cfg.SkipForeignKeys {
+		if err = conversion.UpdateDDLForeignKeys(ctx, adminClient, dbURI, conv, s.cfg.Out); err != nil {
+			return nil, fmt.Errorf("can't perform update schema on db %s with foreign keys: %v", dbURI, err)
+		}
+	}
+	return dataResult(dbURI, bw), nil
+}
+
+// StartStreaming migrates the data of the source database of cfg with
+// minimal downtime, to the Spanner database of cfg whose schema must be that
+// of conv: it enables change data capture, bulk loads the data, validates
+// row counts, and then streams the changes made since change data capture
+// was enabled. For DynamoDB, streaming runs until ctx is done or the process
+// is interrupted; other sources stream with a Dataflow job that keeps
+// running after StartStreaming returns. Foreign keys are added once
+// streaming stops, unless cfg.SkipForeignKeys is set.
+func StartStreaming(ctx context.Context, cfg Config, conv *Conv) (*DataResult, error) {
+	s, err := prepare(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+	if conv == nil {
+		return nil, fmt.Errorf("a conv is required to stream data")
+	}
+	if s.cfg.DryRun {
+		return nil, fmt.Errorf("streaming doesn't support dry runs")
+	}
+	conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
+	conv.Audit.MigrationType = migrationpb.MigrationData_SCHEMA_AND_DATA.Enum()
+	conv.WriteOptions = s.targetProfile.WriteOptions()
+	conv.ForceLock = s.cfg.Force
+	infoSchema, err := conversion.GetInfoSchema(s.sourceProfile, s.targetProfile)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to source database: %v", err)
+	}
+	adminClient, client, dbURI, err := CreateDatabaseClient(ctx, s.targetProfile, s.sourceProfile.Driver, s.cfg.Out)
+	if err != nil {
+		return nil, fmt.Errorf("can't create database client: %v", err)
+	}
+	defer adminClient.Close()
+	defer client.Close()
+	// The tables stay leased while changes are streamed.
+	release, err := conversion.AcquireMigrationLock(ctx, client, conv, s.cfg.Out)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	streamInfo, err := infoSchema.StartChangeDataCapture(ctx, conv)
+	if err != nil {
+		return nil, fmt.Errorf("can't enable change data capture: %v", err)
+	}
+	bw, err := conversion.SnapshotMigration(ctx, conv, client, infoSchema, s.cfg.WriteLimit)
+	if err != nil {
+		return nil, fmt.Errorf("can't finish bulk load for db %s: %v", dbURI, err)
+	}
+	r := dataResult(dbURI, bw)
+	if r.Mismatches, err = conversion.ValidateRowCounts(ctx, client, conv, bw.DroppedRowsByTable(), s.cfg.Out); err != nil {
+		return nil, fmt.Errorf("can't validate db %s: %v", dbURI, err)
+	}
+	if err = infoSchema.StartStreamingMigration(ctx, client, conv, streamInfo); err != nil {
+		return nil, fmt.Errorf("can't stream changes to db %s: %v", dbURI, err)
+	}
+	if !s.cfg.SkipForeignKeys {
+		if err = conversion.UpdateDDLForeignKeys(ctx, adminClient, dbURI, conv, s.cfg.Out); err != nil {
+			return nil, fmt.Errorf("can't perform update schema on db %s with foreign keys: %v", dbURI, err)
+		}
+	}
+	return r, nil
+}
+
+func dataResult(dbURI string, bw *writer.BatchWriter) *DataResult {
+	return &DataResult{Database: dbURI, DroppedRows: bw.DroppedRowsByTable(), WriteErrors: bw.Errors()}
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+)
+
+func dryRunConfig(t *testing.T) Config {
+	out, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { out.Close() })
+	return Config{
+		Source:        "mysql",
+		SourceProfile: "file=../test_data/mysqldump.test.out",
+		TargetProfile: "dbName=test",
+		DryRun:        true,
+		Out:           out,
+	}
+}
+
+func TestConvertSchemaDryRun(t *testing.T) {
+	r, err := ConvertSchema(context.Background(), dryRunConfig(t))
+	assert.Nil(t, err)
+	assert.Equal(t, "", r.Database)
+	assert.True(t, r.Conv.Audit.DryRun)
+	for _, table := range []string{"cart", "customers"} {
+		_, found := r.Conv.SpSchema[table]
+		assert.True(t, found, table)
+	}
+}
+
+func TestMigrateDataDryRun(t *testing.T) {
+	cfg := dryRunConfig(t)
+	s, err := ConvertSchema(context.Background(), cfg)
+	assert.Nil(t, err)
+	r, err := MigrateData(context.Background(), cfg, s.Conv)
+	assert.Nil(t, err)
+	assert.Equal(t, "", r.Database)
+	assert.Equal(t, int64(3), s.Conv.Stats.Rows["cart"])
+	assert.Empty(t, r.DroppedRows)
+
+	_, err = MigrateData(context.Background(), cfg, nil)
+	assert.NotNil(t, err)
+}
+
+func TestPrepare(t *testing.T) {
+	tc := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{"Dump file", Config{Source: "mysql", SourceProfile: "file=../test_data/mysqldump.test.out"}, true},
+		{"Missing dump file", Config{Source: "mysql", SourceProfile: "file=no_such_file.sql"}, false},
+		{"Unknown source", Config{Source: "db2", SourceProfile: "file=../test_data/mysqldump.test.out"}, false},
+		{"Invalid target", Config{Source: "mysql", SourceProfile: "file=../test_data/mysqldump.test.out", Target: "bigtable"}, false},
+		{"Invalid target profile", Config{Source: "mysql", SourceProfile: "file=../test_data/mysqldump.test.out", TargetProfile: "instance"}, false},
+	}
+	for _, tc := range tc {
+		s, err := prepare(tc.cfg)
+		assert.Equal(t, tc.ok, err == nil, tc.name)
+		if err == nil {
+			assert.Equal(t, constants.MYSQLDUMP, s.sourceProfile.Driver, tc.name)
+			assert.Equal(t, int64(DefaultWriteLimit), s.cfg.WriteLimit, tc.name)
+			s.close()
+		}
+	}
+}
+
+func TestSourceDriver(t *testing.T) {
+	dump := profiles.SourceProfile{Ty: profiles.SourceProfileTypeFile}
+	conn := profiles.SourceProfile{Ty: profiles.SourceProfileTypeConnection}
+	d, err := SourceDriver(dump, "PostgreSQL")
+	assert.Nil(t, err)
+	assert.Equal(t, constants.PGDUMP, d)
+	d, err = SourceDriver(conn, "mysql")
+	assert.Nil(t, err)
+	assert.Equal(t, constants.MYSQL, d)
+	_, err = SourceDriver(dump, "dynamodb")
+	assert.NotNil(t, err)
+}
+
+func TestLeaderRegionWarning(t *testing.T) {
+	assert.Equal(t, "", leaderRegionWarning("us-central1", "us-central1"))
+	assert.Equal(t, "", leaderRegionWarning("US-Central1", "us-central1"))
+	w := leaderRegionWarning("europe-west1", "us-central1")
+	assert.Contains(t, w, "region europe-west1")
+	assert.Contains(t, w, "leader region of the database is us-central1")
+}
@@ -0,0 +1,209 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	iampb "google.golang.org/genproto/googleapis/iam/v1"
+	databasepb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+)
+
+// spannerPermissions are the IAM permissions a migration needs on its Spanner
+// database. Migrating to a new database also needs spanner.databases.create
+// on the instance.
+var spannerPermissions = []string{
+	"spanner.databases.beginOrRollbackReadWriteTransaction",
+	"spanner.databases.beginReadOnlyTransaction",
+	"spanner.databases.get",
+	"spanner.databases.getDdl",
+	"spanner.databases.read",
+	"spanner.databases.select",
+	"spanner.databases.updateDdl",
+	"spanner.databases.write",
+	"spanner.sessions.create",
+	"spanner.sessions.delete",
+	"spanner.sessions.get",
+}
+
+// Access lists the Google Cloud resources a migration uses besides its
+// Spanner database, whose IAM permissions are checked by CheckPermissions.
+type Access struct {
+	ReadGCS           []string // GCS locations read, e.g. dump files (gs://bucket/path).
+	WriteGCS          []string // GCS locations written, e.g. the spill location of oversized values.
+	MonitoringProject string   // Project Cloud Monitoring metrics are written to, if any.
+}
+
+// CheckPermissions tests the IAM permissions that the migration to the
+// Spanner database of targetProfile needs on the database (or on its
+// instance, if the database doesn't exist yet), and on the resources of
+// access, and returns the missing ones. Spanner permissions aren't tested on
+// the emulator. Resource ids missing from targetProfile are filled in, as by
+// CreateDatabaseClient.
+func CheckPermissions(ctx context.Context, targetProfile *profiles.TargetProfile, driver string, access Access) ([]utils.MissingPermission, error) {
+	var missing []utils.MissingPermission
+	if !utils.UsingEmulator() {
+		project, instanceID, dbName, err := targetProfile.GetResourceIds(ctx, time.Now(), driver, nil)
+		if err != nil {
+			return nil, err
+		}
+		instanceURI := fmt.Sprintf("projects/%s/instances/%s", project, instanceID)
+		dbURI := fmt.Sprintf("%s/databases/%s", instanceURI, dbName)
+		adminClient, err := utils.NewDatabaseAdminClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer adminClient.Close()
+		instanceClient, err := utils.NewInstanceAdminClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer instanceClient.Close()
+		_, err = adminClient.GetDatabase(ctx, &databasepb.GetDatabaseRequest{Name: dbURI})
+		exists := status.Code(err) != codes.NotFound
+		perms, _, err := testSpannerPermissions(ctx, adminClient, instanceClient, instanceURI, dbURI, exists)
+		if err != nil {
+			return nil, utils.AnalyzeError(err, dbURI)
+		}
+		resource := instanceURI
+		if exists {
+			resource = dbURI
+		}
+		for _, p := range perms {
+			missing = append(missing, utils.MissingPermission{Permission: p, Resource: resource})
+		}
+	}
+	gcs := map[string][]string{}
+	for _, l := range access.ReadGCS {
+		gcs[l] = append(gcs[l], "storage.objects.get")
+	}
+	for _, l := range access.WriteGCS {
+		gcs[l] = append(gcs[l], "storage.objects.create")
+	}
+	if len(gcs) > 0 {
+		m, err := testGCSPermissions(ctx, gcs)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, m...)
+	}
+	if access.MonitoringProject != "" {
+		m, err := testProjectPermissions(ctx, access.MonitoringProject, []string{"monitoring.timeSeries.create"})
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, m...)
+	}
+	return missing, nil
+}
+
+// testSpannerPermissions tests the IAM permissions of a migration on
+// database dbURI if it exists, or on instance instanceURI if the database
+// must be created. It returns the missing permissions, sorted, and the
+// number of permissions tested.
+func testSpannerPermissions(ctx context.Context, adminClient *database.DatabaseAdminClient, instanceClient *instance.InstanceAdminClient, instanceURI, dbURI string, exists bool) ([]string, int, error) {
+	var (
+		resp *iampb.TestIamPermissionsResponse
+		err  error
+	)
+	want := spannerPermissions
+	if exists {
+		resp, err = adminClient.TestIamPermissions(ctx, &iampb.TestIamPermissionsRequest{Resource: dbURI, Permissions: want})
+	} else {
+		want = append([]string{"spanner.databases.create"}, want...)
+		resp, err = instanceClient.TestIamPermissions(ctx, &iampb.TestIamPermissionsRequest{Resource: instanceURI, Permissions: want})
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return missingPermissions(want, resp.Permissions), len(want), nil
+}
+
+// testGCSPermissions tests the IAM permissions of gcs, keyed by GCS location,
+// on the buckets of the locations.
+func testGCSPermissions(ctx context.Context, gcs map[string][]string) ([]utils.MissingPermission, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't create GCS client: %v", err)
+	}
+	defer client.Close()
+	var locations []string
+	for l := range gcs {
+		locations = append(locations, l)
+	}
+	sort.Strings(locations)
+	var missing []utils.MissingPermission
+	for _, l := range locations {
+		u, err := url.Parse(l)
+		if err != nil || u.Scheme != "gs" || u.Host == "" {
+			return nil, fmt.Errorf("invalid GCS location %s, expected gs://bucket/path", l)
+		}
+		have, err := client.Bucket(u.Host).IAM().TestPermissions(ctx, gcs[l])
+		if err != nil {
+			return nil, fmt.Errorf("can't test permissions on gs://%s: %v", u.Host, err)
+		}
+		for _, p := range missingPermissions(gcs[l], have) {
+			missing = append(missing, utils.MissingPermission{Permission: p, Resource: "gs://" + u.Host})
+		}
+	}
+	return missing, nil
+}
+
+// testProjectPermissions tests IAM permissions want on project.
+func testProjectPermissions(ctx context.Context, project string, want []string) ([]utils.MissingPermission, error) {
+	service, err := cloudresourcemanager.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't create Resource Manager client: %v", err)
+	}
+	resp, err := service.Projects.TestIamPermissions(project, &cloudresourcemanager.TestIamPermissionsRequest{Permissions: want}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("can't test permissions on project %s: %v", project, err)
+	}
+	var missing []utils.MissingPermission
+	for _, p := range missingPermissions(want, resp.Permissions) {
+		missing = append(missing, utils.MissingPermission{Permission: p, Resource: "projects/" + project})
+	}
+	return missing, nil
+}
+
+// missingPermissions returns the permissions of want that aren't in have,
+// sorted.
+func missingPermissions(want, have []string) []string {
+	granted := map[string]bool{}
+	for _, p := range have {
+		granted[p] = true
+	}
+	var missing []string
+	for _, p := range want {
+		if !granted[p] {
+			missing = append(missing, p)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
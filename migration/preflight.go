@@ -0,0 +1,398 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	databasepb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	instancepb "google.golang.org/genproto/googleapis/spanner/admin/instance/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// CheckStatus is the outcome of a preflight check.
+type CheckStatus string
+
+// Outcomes of preflight checks. Only failed checks prevent a migration from
+// running: warnings point at what may slow it down or fail it later.
+const (
+	CheckPass CheckStatus = "PASS"
+	CheckWarn CheckStatus = "WARN"
+	CheckFail CheckStatus = "FAIL"
+	CheckSkip CheckStatus = "SKIP"
+)
+
+// Check is the result of a preflight check.
+type Check struct {
+	Name   string
+	Status CheckStatus
+	Detail string
+}
+
+// Checklist is the result of the preflight checks of a migration, in the
+// order they ran.
+type Checklist []Check
+
+// Passed returns true if no check of c failed.
+func (c Checklist) Passed() bool {
+	for _, check := range c {
+		if check.Status == CheckFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Write writes c to w, one check per line, followed by a count of the checks
+// by outcome.
+func (c Checklist) Write(w io.Writer) {
+	fmt.Fprintln(w, "Preflight checks:")
+	counts := map[CheckStatus]int{}
+	for _, check := range c {
+		fmt.Fprintf(w, "  [%s] %s: %s\n", check.Status, check.Name, check.Detail)
+		counts[check.Status]++
+	}
+	fmt.Fprintf(w, "%d passed, %d warning(s), %d failed, %d skipped.\n", counts[CheckPass], counts[CheckWarn], counts[CheckFail], counts[CheckSkip])
+}
+
+// maxDatabasesPerInstance is the number of databases a Spanner instance can
+// hold.
+const maxDatabasesPerInstance = 100
+
+// minFreeSpace is the free disk space below which HarbourBridge may not be
+// able to write its reports and bad data files.
+const minFreeSpace = 1 << 30
+
+// Preflight checks, without writing to the source or to Spanner, that the
+// migration of cfg can run: that the source database can be reached and its
+// tables read (and streamed, for streaming migrations), that there's enough
+// disk space, and that the Spanner API is enabled, the instance is ready,
+// the caller has the IAM permissions the migration needs and the instance
+// has room for a new database. It only returns an error if cfg is invalid.
+func Preflight(ctx context.Context, cfg Config) (Checklist, error) {
+	cfg, sourceProfile, targetProfile, err := parseConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var c Checklist
+	var need uint64
+	dir := "."
+	switch sourceProfile.Ty {
+	case profiles.SourceProfileTypeFile:
+		var check Check
+		check, need = checkDumpFile(ctx, sourceProfile.File.Path)
+		c = append(c, check)
+		if strings.HasPrefix(sourceProfile.File.Path, "gs://") {
+			// Dumps on GCS are downloaded before being read.
+			dir = os.TempDir()
+		}
+	case profiles.SourceProfileTypeConnection:
+		closeTunnels, err := sourceProfile.OpenTunnels()
+		if err != nil {
+			c = append(c, Check{"Source connectivity", CheckFail, err.Error()})
+			break
+		}
+		c = append(c, checkSourceDatabase(sourceProfile, targetProfile)...)
+		closeTunnels()
+	}
+	c = append(c, checkDiskSpace(dir, need))
+	return append(c, checkSpanner(ctx, targetProfile, sourceProfile.Driver)...), nil
+}
+
+// checkDumpFile checks that dump file path can be read, and returns its size
+// if it's on GCS, i.e. the disk space needed to download it.
+func checkDumpFile(ctx context.Context, path string) (Check, uint64) {
+	name := "Dump file"
+	if path == "" {
+		return Check{name, CheckSkip, "the dump is read from stdin"}, 0
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return Check{name, CheckFail, fmt.Sprintf("can't parse dump file path %s: %v", path, err)}, 0
+	}
+	if u.Scheme != "gs" {
+		f, err := os.Open(path)
+		if err != nil {
+			return Check{name, CheckFail, fmt.Sprintf("can't read %s: %v", path, err)}, 0
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return Check{name, CheckFail, fmt.Sprintf("can't read %s: %v", path, err)}, 0
+		}
+		return Check{name, CheckPass, fmt.Sprintf("%s (%s)", path, formatSize(uint64(fi.Size())))}, 0
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return Check{name, CheckFail, fmt.Sprintf("can't create GCS client: %v", err)}, 0
+	}
+	defer client.Close()
+	attrs, err := client.Bucket(u.Host).Object(strings.TrimPrefix(u.Path, "/")).Attrs(ctx)
+	if err != nil {
+		if serviceDisabled(err) {
+			return Check{name, CheckFail, fmt.Sprintf("the Cloud Storage API is not enabled: %v", err)}, 0
+		}
+		return Check{name, CheckFail, fmt.Sprintf("can't read %s: %v", path, err)}, 0
+	}
+	return Check{name, CheckPass, fmt.Sprintf("%s (%s)", path, formatSize(uint64(attrs.Size)))}, uint64(attrs.Size)
+}
+
+// checkSourceDatabase checks that the source database of sourceProfile can be
+// reached, that its tables can be read and, for streaming migrations, that
+// their changes can be streamed.
+func checkSourceDatabase(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) []Check {
+	infoSchema, err := conversion.GetInfoSchema(sourceProfile, targetProfile)
+	if err != nil {
+		return []Check{{"Source connectivity", CheckFail, fmt.Sprintf("can't connect to %s: %v", sourceProfile.Driver, err)}}
+	}
+	tables, err := infoSchema.GetTables()
+	if err != nil {
+		return []Check{
+			{"Source connectivity", CheckPass, fmt.Sprintf("connected to %s", sourceProfile.Driver)},
+			{"Source privileges", CheckFail, fmt.Sprintf("can't list tables: %v", err)},
+		}
+	}
+	c := []Check{{"Source connectivity", CheckPass, fmt.Sprintf("connected to %s, %d table(s)", sourceProfile.Driver, len(tables))}}
+	if ac, ok := infoSchema.(common.AccessChecker); ok {
+		c = append(c, checkTables("Source privileges", "can't read", infoSchema, tables, ac.CheckReadAccess))
+	} else {
+		c = append(c, Check{"Source privileges", CheckSkip, fmt.Sprintf("not checked for %s", sourceProfile.Driver)})
+	}
+	if !sourceProfile.Conn.Streaming {
+		return c
+	}
+	if sc, ok := infoSchema.(common.StreamChecker); ok {
+		c = append(c, checkTables("Change streams", "can't stream", infoSchema, tables, sc.CheckStream))
+	} else {
+		c = append(c, Check{"Change streams", CheckSkip, fmt.Sprintf("not checked for %s", sourceProfile.Driver)})
+	}
+	return c
+}
+
+// checkTables runs check on each table of tables, and fails naming the first
+// tables it fails for.
+func checkTables(name, verb string, infoSchema common.InfoSchema, tables []common.SchemaAndName, check func(common.SchemaAndName) error) Check {
+	const maxListed = 5
+	var failed []string
+	var firstErr error
+	for _, t := range tables {
+		if err := check(t); err != nil {
+			failed = append(failed, infoSchema.GetTableName(t.Schema, t.Name))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return Check{name, CheckPass, fmt.Sprintf("all %d table(s) checked", len(tables))}
+	}
+	listed := failed
+	if len(listed) > maxListed {
+		listed = append(listed[:maxListed:maxListed], fmt.Sprintf("and %d more", len(failed)-maxListed))
+	}
+	return Check{name, CheckFail, fmt.Sprintf("%s %d table(s) (%s): %v", verb, len(failed), strings.Join(listed, ", "), firstErr)}
+}
+
+// checkDiskSpace checks that dir has need bytes of free disk space, plus room
+// for the reports and bad data files of the migration.
+func checkDiskSpace(dir string, need uint64) Check {
+	name := "Disk space"
+	abs, err := filepath.Abs(dir)
+	if err == nil {
+		dir = abs
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		return Check{name, CheckSkip, fmt.Sprintf("can't get free space of %s: %v", dir, err)}
+	}
+	detail := fmt.Sprintf("%s free in %s", formatSize(free), dir)
+	if need > 0 {
+		detail += fmt.Sprintf(", %s needed to download the dump", formatSize(need))
+	}
+	switch {
+	case free < need:
+		return Check{name, CheckFail, detail}
+	case free < need+minFreeSpace:
+		return Check{name, CheckWarn, detail}
+	}
+	return Check{name, CheckPass, detail}
+}
+
+// formatSize formats n bytes for humans.
+func formatSize(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// checkSpanner checks that the Spanner API is enabled, that the instance of
+// targetProfile is ready, that the caller has the IAM permissions of the
+// migration, that the instance has room for a new database and that
+// HarbourBridge runs close to the leader region of the database.
+func checkSpanner(ctx context.Context, targetProfile profiles.TargetProfile, driver string) []Check {
+	if utils.UsingEmulator() {
+		return []Check{{"Spanner", CheckSkip, fmt.Sprintf("using the Cloud Spanner emulator at %s", os.Getenv("SPANNER_EMULATOR_HOST"))}}
+	}
+	project, instance, dbName, err := targetProfile.GetResourceIds(ctx, time.Now(), driver, nil)
+	if err != nil {
+		if serviceDisabled(err) {
+			return []Check{spannerAPICheck(project, err)}
+		}
+		return []Check{{"Spanner instance", CheckFail, err.Error()}}
+	}
+	instanceURI := fmt.Sprintf("projects/%s/instances/%s", project, instance)
+	dbURI := fmt.Sprintf("%s/databases/%s", instanceURI, dbName)
+	instanceClient, err := utils.NewInstanceAdminClient(ctx)
+	if err != nil {
+		return []Check{{"Spanner instance", CheckFail, utils.AnalyzeError(err, dbURI).Error()}}
+	}
+	defer instanceClient.Close()
+	inst, err := instanceClient.GetInstance(ctx, &instancepb.GetInstanceRequest{Name: instanceURI})
+	if err != nil && serviceDisabled(err) {
+		return []Check{spannerAPICheck(project, err)}
+	}
+	c := []Check{spannerAPICheck(project, nil)}
+	switch {
+	case err != nil:
+		return append(c, Check{"Spanner instance", CheckFail, utils.AnalyzeError(err, dbURI).Error()})
+	case inst.State != instancepb.Instance_READY:
+		c = append(c, Check{"Spanner instance", CheckFail, fmt.Sprintf("instance %s is %s", instance, inst.State)})
+	default:
+		c = append(c, Check{"Spanner instance", CheckPass, fmt.Sprintf("instance %s is ready, with %d processing units", instance, inst.ProcessingUnits)})
+	}
+	adminClient, err := utils.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return append(c, Check{"Spanner database", CheckFail, utils.AnalyzeError(err, dbURI).Error()})
+	}
+	defer adminClient.Close()
+	_, err = adminClient.GetDatabase(ctx, &databasepb.GetDatabaseRequest{Name: dbURI})
+	exists := status.Code(err) != codes.NotFound
+	c = append(c, checkPermissions(ctx, adminClient, instanceClient, instanceURI, dbURI, exists))
+	if exists {
+		c = append(c, Check{"Spanner quota", CheckPass, fmt.Sprintf("migrating to existing database %s", dbName)})
+	} else {
+		c = append(c, checkDatabaseQuota(ctx, adminClient, instanceURI))
+	}
+	return append(c, checkLeaderRegion(ctx, targetProfile, dbURI))
+}
+
+// spannerAPICheck returns the check that the Spanner API is enabled in
+// project, given the error of a call to it.
+func spannerAPICheck(project string, err error) Check {
+	if err != nil {
+		return Check{"Spanner API", CheckFail, fmt.Sprintf("the Cloud Spanner API is not enabled in project %s, enable it with: gcloud services enable spanner.googleapis.com --project=%s", project, project)}
+	}
+	return Check{"Spanner API", CheckPass, fmt.Sprintf("enabled in project %s", project)}
+}
+
+// serviceDisabled returns true if err is the error of a call to a Google
+// Cloud API that isn't enabled in the project.
+func serviceDisabled(err error) bool {
+	e := err.Error()
+	return strings.Contains(e, "SERVICE_DISABLED") || strings.Contains(e, "has not been used in project") || strings.Contains(e, "it is disabled")
+}
+
+// checkPermissions checks that the caller has the IAM permissions of a data
+// migration on database dbURI if it exists, or on instance instanceURI if
+// the database must be created.
+func checkPermissions(ctx context.Context, adminClient *database.DatabaseAdminClient, instanceClient *instance.InstanceAdminClient, instanceURI, dbURI string, exists bool) Check {
+	name := "Spanner IAM permissions"
+	missing, tested, err := testSpannerPermissions(ctx, adminClient, instanceClient, instanceURI, dbURI, exists)
+	if err != nil {
+		return Check{name, CheckFail, fmt.Sprintf("can't test permissions: %v", err)}
+	}
+	if len(missing) > 0 {
+		var l []string
+		for _, p := range missing {
+			l = append(l, fmt.Sprintf("%s (granted by %s)", p, utils.LeastPrivilegeRole(p)))
+		}
+		return Check{name, CheckFail, fmt.Sprintf("missing %s, see -check-permissions of the migration subcommands for how to grant them", strings.Join(l, ", "))}
+	}
+	return Check{name, CheckPass, fmt.Sprintf("all %d permissions granted", tested)}
+}
+
+// checkDatabaseQuota checks that instance instanceURI has room for a new
+// database.
+func checkDatabaseQuota(ctx context.Context, adminClient *database.DatabaseAdminClient, instanceURI string) Check {
+	name := "Spanner quota"
+	n := 0
+	it := adminClient.ListDatabases(ctx, &databasepb.ListDatabasesRequest{Parent: instanceURI})
+	for {
+		_, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return Check{name, CheckSkip, fmt.Sprintf("can't list databases: %v", err)}
+		}
+		n++
+	}
+	return databaseQuotaCheck(n)
+}
+
+// databaseQuotaCheck returns the check that an instance with n databases has
+// room for a new one.
+func databaseQuotaCheck(n int) Check {
+	detail := fmt.Sprintf("%d of %d databases of the instance used", n, maxDatabasesPerInstance)
+	switch {
+	case n >= maxDatabasesPerInstance:
+		return Check{"Spanner quota", CheckFail, detail + ", delete a database to create a new one"}
+	case n >= maxDatabasesPerInstance*9/10:
+		return Check{"Spanner quota", CheckWarn, detail}
+	}
+	return Check{"Spanner quota", CheckPass, detail}
+}
+
+// checkLeaderRegion checks that HarbourBridge runs in the leader region of
+// database dbURI, like CheckLeaderRegion.
+func checkLeaderRegion(ctx context.Context, targetProfile profiles.TargetProfile, dbURI string) Check {
+	name := "Leader region"
+	worker := targetProfile.Conn.Sp.WorkerRegion
+	if worker == "" {
+		worker = utils.WorkerRegion()
+	}
+	if worker == "" {
+		return Check{name, CheckSkip, "can't detect the region HarbourBridge runs in, set workerRegion in the target profile"}
+	}
+	leader, err := utils.LeaderRegion(ctx, dbURI)
+	if err != nil || leader == "" {
+		return Check{name, CheckSkip, fmt.Sprintf("can't get the leader region of the database: %v", err)}
+	}
+	if w := leaderRegionWarning(worker, leader); w != "" {
+		return Check{name, CheckWarn, strings.TrimPrefix(w, "Warning: ")}
+	}
+	return Check{name, CheckPass, fmt.Sprintf("HarbourBridge runs in leader region %s", leader)}
+}
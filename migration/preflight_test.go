@@ -0,0 +1,112 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+func TestChecklist(t *testing.T) {
+	c := Checklist{
+		{"Dump file", CheckPass, "dump.sql (10 bytes)"},
+		{"Disk space", CheckWarn, "512.0 MiB free in /tmp"},
+		{"Leader region", CheckSkip, "unknown"},
+	}
+	assert.True(t, c.Passed())
+	var b bytes.Buffer
+	c.Write(&b)
+	assert.Equal(t, `Preflight checks:
+  [PASS] Dump file: dump.sql (10 bytes)
+  [WARN] Disk space: 512.0 MiB free in /tmp
+  [SKIP] Leader region: unknown
+1 passed, 1 warning(s), 0 failed, 1 skipped.
+`, b.String())
+	c = append(c, Check{"Spanner API", CheckFail, "not enabled"})
+	assert.False(t, c.Passed())
+}
+
+func TestCheckDumpFile(t *testing.T) {
+	ctx := context.Background()
+	c, need := checkDumpFile(ctx, "../test_data/mysqldump.test.out")
+	assert.Equal(t, CheckPass, c.Status)
+	assert.Equal(t, uint64(0), need)
+	c, _ = checkDumpFile(ctx, "../test_data/no_such_dump.out")
+	assert.Equal(t, CheckFail, c.Status)
+	c, _ = checkDumpFile(ctx, "")
+	assert.Equal(t, CheckSkip, c.Status)
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	assert.NotEqual(t, CheckFail, checkDiskSpace(dir, 0).Status)
+	c := checkDiskSpace(dir, 1<<62)
+	assert.Equal(t, CheckFail, c.Status)
+	assert.Contains(t, c.Detail, "needed to download the dump")
+}
+
+func TestCheckTables(t *testing.T) {
+	isi := fakeInfoSchema{}
+	var tables []common.SchemaAndName
+	for i := 0; i < 8; i++ {
+		tables = append(tables, common.SchemaAndName{Name: fmt.Sprintf("t%d", i)})
+	}
+	allowed := func(common.SchemaAndName) error { return nil }
+	assert.Equal(t, Check{"Source privileges", CheckPass, "all 8 table(s) checked"}, checkTables("Source privileges", "can't read", isi, tables, allowed))
+	denied := func(t common.SchemaAndName) error {
+		if t.Name == "t0" {
+			return nil
+		}
+		return fmt.Errorf("SELECT command denied")
+	}
+	c := checkTables("Source privileges", "can't read", isi, tables, denied)
+	assert.Equal(t, CheckFail, c.Status)
+	assert.Equal(t, "can't read 7 table(s) (t1, t2, t3, t4, t5, and 2 more): SELECT command denied", c.Detail)
+}
+
+// fakeInfoSchema is an InfoSchema whose table names are their names.
+type fakeInfoSchema struct {
+	common.InfoSchema
+}
+
+func (fakeInfoSchema) GetTableName(schema string, tableName string) string {
+	return tableName
+}
+
+func TestMissingPermissions(t *testing.T) {
+	want := []string{"spanner.databases.write", "spanner.databases.create", "spanner.sessions.create"}
+	assert.Equal(t, []string{"spanner.databases.create", "spanner.databases.write"}, missingPermissions(want, []string{"spanner.sessions.create"}))
+	assert.Empty(t, missingPermissions(want, want))
+}
+
+func TestDatabaseQuotaCheck(t *testing.T) {
+	assert.Equal(t, CheckPass, databaseQuotaCheck(3).Status)
+	assert.Equal(t, CheckWarn, databaseQuotaCheck(95).Status)
+	assert.Equal(t, CheckFail, databaseQuotaCheck(100).Status)
+}
+
+func TestServiceDisabled(t *testing.T) {
+	assert.True(t, serviceDisabled(fmt.Errorf("rpc error: code = PermissionDenied desc = Cloud Spanner API has not been used in project 123 before or it is disabled")))
+	assert.False(t, serviceDisabled(fmt.Errorf("rpc error: code = PermissionDenied desc = Caller is missing IAM permission spanner.instances.get")))
+	assert.False(t, strings.Contains(spannerAPICheck("p", nil).Detail, "gcloud"))
+	assert.Contains(t, spannerAPICheck("p", fmt.Errorf("SERVICE_DISABLED")).Detail, "gcloud services enable spanner.googleapis.com --project=p")
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+)
+
+// Accepted values of the target flag.
+const (
+	TargetSpanner  = "spanner"
+	TargetEmulator = "emulator"
+)
+
+// SourceDriver returns the name of the registered driver for value source of
+// the -source flag: its dump driver if the source profile specifies a file,
+// and its database driver if it specifies a connection.
+func SourceDriver(sourceProfile profiles.SourceProfile, source string) (string, error) {
+	if sourceProfile.Ty != profiles.SourceProfileTypeFile && sourceProfile.Ty != profiles.SourceProfileTypeConnection {
+		return sourceProfile.ToLegacyDriver(source)
+	}
+	dump := sourceProfile.Ty == profiles.SourceProfileTypeFile
+	if d, found := registry.ForSource(source, dump); found {
+		return d.Info().Name, nil
+	}
+	if _, found := registry.ForSource(source, !dump); found {
+		if dump {
+			return "", fmt.Errorf("dump files are not supported with source %s", source)
+		}
+		return "", fmt.Errorf("direct connection is not supported with source %s: please specify a dump file", source)
+	}
+	return "", fmt.Errorf("please specify a valid source database using -source flag (one of %s), received source = %v", strings.Join(registry.Sources(), ", "), source)
+}
+
+// ConfigureTarget checks that target is a valid value for the target flag.
+// For the emulator target, Spanner clients are configured to connect to the
+// Cloud Spanner emulator, and the project and instance of targetProfile
+// default to ones that are created on the emulator if needed.
+func ConfigureTarget(target string, targetProfile *profiles.TargetProfile) error {
+	switch strings.ToLower(target) {
+	case TargetSpanner:
+		return nil
+	case TargetEmulator:
+		utils.ConfigureEmulator()
+		if targetProfile.Conn.Sp.Project == "" && os.Getenv("GCLOUD_PROJECT") == "" {
+			targetProfile.Conn.Sp.Project = utils.DefaultEmulatorProject
+		}
+		if targetProfile.Conn.Sp.Instance == "" {
+			targetProfile.Conn.Sp.Instance = utils.DefaultEmulatorInstance
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid target %s, accepted values are: Spanner, emulator", target)
+}
+
+// CreateDatabaseClient creates new database client and admin client for the
+// database of targetProfile, generating a database name for driver if the
+// profile doesn't specify one. It returns the URI of the database.
+func CreateDatabaseClient(ctx context.Context, targetProfile profiles.TargetProfile, driver string, out *os.File) (*database.DatabaseAdminClient, *sp.Client, string, error) {
+	project, instance, dbName, err := targetProfile.GetResourceIds(ctx, time.Now(), driver, out)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	fmt.Fprintln(out, "Using Google Cloud project:", project)
+	fmt.Fprintln(out, "Using Cloud Spanner instance:", instance)
+	if utils.UsingEmulator() {
+		fmt.Fprintln(out, "Using Cloud Spanner emulator:", os.Getenv("SPANNER_EMULATOR_HOST"))
+		if err := utils.CreateEmulatorInstance(ctx, project, instance); err != nil {
+			return nil, nil, "", err
+		}
+	} else {
+		utils.PrintPermissionsWarning(driver, out)
+	}
+
+	dbURI := fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, dbName)
+	if !utils.UsingEmulator() {
+		CheckLeaderRegion(ctx, targetProfile, dbURI, out)
+	}
+	adminClient, err := utils.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		err = fmt.Errorf("can't create admin client: %v", utils.AnalyzeError(err, dbURI))
+		return nil, nil, dbURI, err
+	}
+	client, err := utils.NewSpannerClientWithConfig(ctx, dbURI, targetProfile.ClientConfig())
+	if err != nil {
+		err = fmt.Errorf("can't create client for db %s: %v", dbURI, err)
+		return adminClient, nil, dbURI, err
+	}
+	return adminClient, client, dbURI, nil
+}
+
+// CheckLeaderRegion warns on out if HarbourBridge runs in a region other than
+// the leader region of database dbURI, where Spanner commits the writes of
+// the migration: each write then adds a round-trip between regions. The
+// region HarbourBridge runs in is the workerRegion of targetProfile, or is
+// detected on Google Cloud. Nothing is reported if either region is unknown.
+func CheckLeaderRegion(ctx context.Context, targetProfile profiles.TargetProfile, dbURI string, out *os.File) {
+	worker := targetProfile.Conn.Sp.WorkerRegion
+	if worker == "" {
+		worker = utils.WorkerRegion()
+	}
+	if worker == "" {
+		return
+	}
+	leader, err := utils.LeaderRegion(ctx, dbURI)
+	if err != nil || leader == "" {
+		return
+	}
+	fmt.Fprintf(out, "Cloud Spanner leader region: %s\n", leader)
+	if w := leaderRegionWarning(worker, leader); w != "" {
+		fmt.Fprintln(out, w)
+	}
+}
+
+// leaderRegionWarning returns the warning that HarbourBridge runs in region
+// worker, away from leader region leader, or "" if they are the same.
+func leaderRegionWarning(worker, leader string) string {
+	if strings.EqualFold(worker, leader) {
+		return ""
+	}
+	return fmt.Sprintf("Warning: HarbourBridge runs in region %s, but the leader region of the database is %s: "+
+		"every write of the migration adds a round-trip between the regions. "+
+		"Run the migration in %s to migrate faster, or set routeToLeader=true in the target profile to send requests straight to it.",
+		worker, leader, leader)
+}
+
+// ValidateDatabase validates that the existing Spanner database dbURI has the
+// schema of conv, for migrating data to it.
+func ValidateDatabase(ctx context.Context, targetDb, dbURI string, adminClient *database.DatabaseAdminClient, client *sp.Client, conv *internal.Conv) error {
+	dbExists, err := conversion.CheckExistingDb(ctx, adminClient, dbURI)
+	if err != nil {
+		err = fmt.Errorf("can't verify target database: %v", err)
+		return err
+	}
+	if !dbExists {
+		err = fmt.Errorf("target database doesn't exist")
+		return err
+	}
+	err = conversion.ValidateTables(ctx, client, targetDb)
+	if err != nil {
+		err = fmt.Errorf("error validating the tables: %v", err)
+		return err
+	}
+	spannerConv := internal.MakeConv()
+	spannerConv.TargetDb = targetDb
+	err = utils.ReadSpannerSchema(ctx, spannerConv, client)
+	if err != nil {
+		err = fmt.Errorf("can't read spanner schema: %v", err)
+		return err
+	}
+	err = utils.CompareSchema(conv, spannerConv)
+	if err != nil {
+		err = fmt.Errorf("error while comparing the schema from session file and existing spanner schema: %v", err)
+		return err
+	}
+	return nil
+}
+
+// dumpFile returns the path of the dump file of sourceProfile, if it reads
+// one.
+func dumpFile(sourceProfile profiles.SourceProfile) string {
+	if sourceProfile.Ty == profiles.SourceProfileTypeFile && (sourceProfile.File.Format == "" || sourceProfile.File.Format == "dump") {
+		return sourceProfile.File.Path
+	}
+	return ""
+}
+
+// openIOStreams is like utils.NewIOStreams, but returns an error instead of
+// exiting when the dump file can't be read.
+func openIOStreams(driver, dumpFile string, out *os.File) (utils.IOStreams, error) {
+	io := utils.IOStreams{In: os.Stdin, Out: out}
+	if dumpFile == "" || (driver != constants.PGDUMP && driver != constants.MYSQLDUMP && driver != constants.MARIADBDUMP) {
+		return io, nil
+	}
+	u, err := url.Parse(dumpFile)
+	if err != nil {
+		return io, fmt.Errorf("can't parse dump file path %s: %v", dumpFile, err)
+	}
+	var f *os.File
+	if u.Scheme == "gs" {
+		f, err = utils.DownloadFromGCS(u.Host, strings.TrimPrefix(u.Path, "/"), "harbourbridge.gcs.data")
+	} else {
+		f, err = os.Open(dumpFile)
+	}
+	if err != nil {
+		return io, fmt.Errorf("can't read dump file %s: %v", dumpFile, err)
+	}
+	io.In = f
+	return io, nil
+}
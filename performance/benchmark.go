@@ -0,0 +1,206 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package performance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// insertRows is the number of rows per INSERT statement of generated dumps.
+const insertRows = 100
+
+// Shape describes the schema of a synthetic benchmark dataset: its tables,
+// in an order compatible with their foreign keys, and a generator of the
+// values of their rows.
+type Shape struct {
+	Description string
+	Tables      []ShapeTable
+}
+
+// ShapeTable is a table of a Shape. RowFactor scales the number of rows of
+// the table relative to the dataset size, e.g. 3 order items per order.
+type ShapeTable struct {
+	Name      string
+	DDL       string
+	RowFactor int64
+	Row       func(i, rows int64) []string // SQL literals of the values of row i of rows.
+}
+
+// Shapes are the schema shapes of benchmark datasets, by name.
+var Shapes = map[string]Shape{
+	"single": {
+		Description: "a single table with columns of common types",
+		Tables: []ShapeTable{{
+			Name: "employee",
+			DDL: "CREATE TABLE `employee` (`employee_id` varchar(50) NOT NULL, `first_name` varchar(50) NOT NULL, " +
+				"`last_name` varchar(50), `address` varchar(100), `dob` date NOT NULL, `is_manager` bool NOT NULL, " +
+				"`height_in_cm` float NOT NULL, `salary` int NOT NULL, `last_updated_time` timestamp NOT NULL, " +
+				"PRIMARY KEY (`employee_id`));",
+			RowFactor: 1,
+			Row: func(i, _ int64) []string {
+				return []string{
+					quote(fmt.Sprintf("%s-%d", RandomString(5), i)), quote(RandomString(10)), quote(RandomString(10)),
+					quote(RandomString(50)), quote(RandomDate()), boolLiteral(RandomBool()),
+					strconv.FormatFloat(RandomFloat(150, 200), 'f', 1, 64), strconv.FormatInt(RandomInt(1000, 100000), 10),
+					quote(CurrentTimestamp()),
+				}
+			},
+		}},
+	},
+	"multi": {
+		Description: "customers, their orders and order items, related by foreign keys",
+		Tables: []ShapeTable{
+			{
+				Name: "customers",
+				DDL: "CREATE TABLE `customers` (`customer_id` bigint NOT NULL, `name` varchar(100) NOT NULL, " +
+					"`email` varchar(100), `created` datetime NOT NULL, PRIMARY KEY (`customer_id`));",
+				RowFactor: 1,
+				Row: func(i, _ int64) []string {
+					return []string{strconv.FormatInt(i, 10), quote(RandomString(20)), quote(RandomString(10) + "@example.com"), quote(CurrentTimestamp())}
+				},
+			},
+			{
+				Name: "orders",
+				DDL: "CREATE TABLE `orders` (`order_id` bigint NOT NULL, `customer_id` bigint NOT NULL, `ordered` date NOT NULL, " +
+					"`total` decimal(10,2) NOT NULL, PRIMARY KEY (`order_id`), " +
+					"CONSTRAINT `fk_orders_customers` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`customer_id`));",
+				RowFactor: 2,
+				Row: func(i, rows int64) []string {
+					return []string{strconv.FormatInt(i, 10), strconv.FormatInt(i%(rows/2), 10), quote(RandomDate()), strconv.FormatFloat(RandomFloat(1, 1000), 'f', 2, 64)}
+				},
+			},
+			{
+				Name: "order_items",
+				DDL: "CREATE TABLE `order_items` (`order_id` bigint NOT NULL, `line` int NOT NULL, `sku` varchar(20) NOT NULL, " +
+					"`quantity` int NOT NULL, PRIMARY KEY (`order_id`, `line`), " +
+					"CONSTRAINT `fk_order_items_orders` FOREIGN KEY (`order_id`) REFERENCES `orders` (`order_id`));",
+				RowFactor: 6,
+				Row: func(i, rows int64) []string {
+					return []string{strconv.FormatInt(i%(rows/3), 10), strconv.FormatInt(i/(rows/3), 10), quote(RandomString(12)), strconv.FormatInt(RandomInt(1, 10), 10)}
+				},
+			},
+		},
+	},
+	"wide": {
+		Description: "a table with wide text columns",
+		Tables: []ShapeTable{{
+			Name: "documents",
+			DDL: "CREATE TABLE `documents` (`doc_id` bigint NOT NULL, `title` varchar(200) NOT NULL, " +
+				"`summary` text, `body` longtext, PRIMARY KEY (`doc_id`));",
+			RowFactor: 1,
+			Row: func(i, _ int64) []string {
+				return []string{strconv.FormatInt(i, 10), quote(RandomString(100)), quote(RandomString(2000)), quote(RandomString(50000))}
+			},
+		}},
+	},
+}
+
+// ShapeNames returns the names of Shapes, sorted.
+func ShapeNames() []string {
+	var names []string
+	for name := range Shapes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func boolLiteral(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func quote(s string) string {
+	return "'" + s + "'"
+}
+
+// WriteDump writes a mysqldump-format dump of a dataset of shape to w, with
+// the given number of rows per unit of RowFactor of its tables.
+func WriteDump(w io.Writer, shape string, rows int64) error {
+	s, ok := Shapes[shape]
+	if !ok {
+		return fmt.Errorf("unknown shape %q (valid shapes: %s)", shape, strings.Join(ShapeNames(), ", "))
+	}
+	if rows < 1 {
+		return fmt.Errorf("dataset size must be positive, found %d", rows)
+	}
+	b := bufio.NewWriter(w)
+	for _, t := range s.Tables {
+		fmt.Fprintln(b, t.DDL)
+	}
+	for _, t := range s.Tables {
+		n := rows * t.RowFactor
+		for i := int64(0); i < n; i++ {
+			if i%insertRows == 0 {
+				fmt.Fprintf(b, "INSERT INTO `%s` VALUES ", t.Name)
+			} else {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(b, "(%s)", strings.Join(t.Row(i, n), ","))
+			if i%insertRows == insertRows-1 || i == n-1 {
+				b.WriteString(";\n")
+			}
+		}
+	}
+	return b.Flush()
+}
+
+// Result is the outcome of a benchmark run: the migration of a dataset with
+// a given write parallelism.
+type Result struct {
+	Shape      string
+	Size       int64 // Dataset size, as passed to WriteDump.
+	WriteLimit int64
+	Rows       int64         // Rows migrated.
+	Mutations  int64         // Column values written, which Spanner counts as mutations.
+	Duration   time.Duration // Wall-clock time of the data migration.
+	CPU        time.Duration // CPU time (user and system) of the data migration.
+	Err        error
+}
+
+// WriteComparison writes a table comparing results to w.
+func WriteComparison(w io.Writer, results []Result) {
+	fmt.Fprintf(w, "%-8s %10s %11s %10s %10s %12s %15s %8s\n", "Shape", "Size", "Write limit", "Rows", "Seconds", "Rows/sec", "Mutations/sec", "CPU %")
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "%-8s %10d %11d failed: %v\n", r.Shape, r.Size, r.WriteLimit, r.Err)
+			continue
+		}
+		secs := r.Duration.Seconds()
+		if secs <= 0 {
+			secs = 1e-9
+		}
+		fmt.Fprintf(w, "%-8s %10d %11d %10d %10.2f %12.0f %15.0f %8.0f\n", r.Shape, r.Size, r.WriteLimit, r.Rows, r.Duration.Seconds(),
+			float64(r.Rows)/secs, float64(r.Mutations)/secs, 100*r.CPU.Seconds()/secs)
+	}
+}
+
+// CPUTime returns the CPU time (user and system) used by this process so far.
+func CPUTime() time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
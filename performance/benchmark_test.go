@@ -0,0 +1,82 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package performance
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+)
+
+func init() {
+	logger.Log = zap.NewNop()
+}
+
+func TestWriteDump(t *testing.T) {
+	for _, shape := range ShapeNames() {
+		var b bytes.Buffer
+		assert.Nil(t, WriteDump(&b, shape, 150), shape)
+
+		conv := internal.MakeConv()
+		conv.SetSchemaMode()
+		assert.Nil(t, conversion.ProcessDump("mysqldump", conv, internal.NewReader(bufio.NewReader(bytes.NewReader(b.Bytes())), nil)), shape)
+		assert.Equal(t, len(Shapes[shape].Tables), len(conv.SpSchema), shape)
+
+		rows := make(map[string]int64)
+		keys := make(map[string]bool)
+		conv.SetDataMode()
+		conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+			rows[table]++
+			key := table
+			for _, pk := range conv.SpSchema[table].Pks {
+				for i, c := range cols {
+					if c == pk.Col {
+						key += "/" + fmt.Sprint(vals[i])
+					}
+				}
+			}
+			assert.False(t, keys[key], "duplicate key %s", key)
+			keys[key] = true
+		})
+		assert.Nil(t, conversion.ProcessDump("mysqldump", conv, internal.NewReader(bufio.NewReader(bytes.NewReader(b.Bytes())), nil)), shape)
+		for _, st := range Shapes[shape].Tables {
+			assert.Equal(t, 150*st.RowFactor, rows[st.Name], st.Name)
+		}
+		assert.Equal(t, int64(0), conv.BadRows(), shape)
+	}
+	assert.NotNil(t, WriteDump(&bytes.Buffer{}, "unknown", 10))
+	assert.NotNil(t, WriteDump(&bytes.Buffer{}, "single", 0))
+}
+
+func TestWriteComparison(t *testing.T) {
+	var b bytes.Buffer
+	WriteComparison(&b, []Result{
+		{Shape: "single", Size: 1000, WriteLimit: 20, Rows: 1000, Mutations: 9000, Duration: 2 * time.Second, CPU: time.Second},
+		{Shape: "single", Size: 1000, WriteLimit: 40, Err: assert.AnError},
+	})
+	assert.Equal(t, ""+
+		"Shape          Size Write limit       Rows    Seconds     Rows/sec   Mutations/sec    CPU %\n"+
+		"single         1000          20       1000       2.00          500            4500       50\n"+
+		"single         1000          40 failed: "+assert.AnError.Error()+"\n", b.String())
+}
@@ -0,0 +1,406 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package performance
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sp "cloud.google.com/go/spanner"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// nullFraction is the fraction of NULL values of nullable columns that aren't
+// keys.
+const nullFraction = 0.1
+
+// Generator generates synthetic data for the tables of a converted Spanner
+// schema, for load testing the schema before migrating real data. Rows
+// respect the schema's constraints:
+//   - Primary keys, and columns of unique indexes or referenced by foreign
+//     keys, are derived from the row number, so they are unique (and spread
+//     across the key space, to avoid hotspots).
+//   - Foreign keys and interleaving reference rows of the parent tables,
+//     which are generated first. Foreign keys on cycles reference rows that
+//     may not have been generated yet, and are NULL when nullable.
+//   - Other columns get random values of their type, e.g. strings of up to
+//     their maximum length.
+type Generator struct {
+	conv   *internal.Conv
+	rows   map[string]int64 // Number of rows of each table.
+	rand   *rand.Rand
+	loaded map[string]bool // Tables whose rows have been generated.
+}
+
+// NewGenerator returns a Generator of rows rows for each table of
+// conv.SpSchema, except for the tables in tableRows, which get the given
+// number of rows. The random values generated are determined by seed.
+func NewGenerator(conv *internal.Conv, rows int64, tableRows map[string]int64, seed int64) (*Generator, error) {
+	g := &Generator{conv: conv, rows: make(map[string]int64), rand: rand.New(rand.NewSource(seed)), loaded: make(map[string]bool)}
+	for t := range tableRows {
+		if _, ok := conv.SpSchema[t]; !ok {
+			return nil, fmt.Errorf("table %s not found in the schema", t)
+		}
+	}
+	for t := range conv.SpSchema {
+		g.rows[t] = rows
+		if n, ok := tableRows[t]; ok {
+			g.rows[t] = n
+		}
+	}
+	return g, nil
+}
+
+// Total returns the number of rows g generates.
+func (g *Generator) Total() int64 {
+	var n int64
+	for _, r := range g.rows {
+		n += r
+	}
+	return n
+}
+
+// Generate generates the rows of all tables, in load order, and writes them
+// with conv.WriteRow.
+func (g *Generator) Generate() {
+	for _, level := range ddl.LoadOrder(g.conv.SpSchema) {
+		for _, t := range level {
+			for i := int64(0); i < g.rows[t]; i++ {
+				cols, vals := g.Row(t, i)
+				g.conv.WriteRow(t, t, cols, vals)
+			}
+			g.loaded[t] = true
+		}
+	}
+}
+
+// Row returns the columns and values of row i of table t.
+func (g *Generator) Row(t string, i int64) ([]string, []interface{}) {
+	ct := g.conv.SpSchema[t]
+	keyed := g.keyedColumns(t)
+	vals := make(map[string]interface{})
+	// Values referencing other tables' rows, which take precedence over
+	// values derived from i: the interleaving parent first, then foreign keys.
+	refs := make(map[string]int64) // Referenced row, by referenced table.
+	if p := ct.Parent; p != "" && g.rows[p] > 0 {
+		var prefix []string
+		for k := range g.conv.SpSchema[p].Pks {
+			if k < len(ct.Pks) {
+				prefix = append(prefix, ct.Pks[k].Col)
+			}
+		}
+		j := g.referencedRow(t, p, i, prefix)
+		refs[p] = j
+		for k, pk := range g.conv.SpSchema[p].Pks {
+			if k < len(ct.Pks) {
+				vals[ct.Pks[k].Col] = keyValue(g.conv, g.conv.SpSchema[p].ColDefs[pk.Col].T, j)
+			}
+		}
+	}
+	for _, fk := range ct.Fks {
+		if fk.AppEnforced {
+			continue
+		}
+		rt, ok := g.conv.SpSchema[fk.ReferTable]
+		if !ok || g.rows[fk.ReferTable] == 0 {
+			continue
+		}
+		if !g.loaded[fk.ReferTable] && fk.ReferTable != t && !g.allNotNull(ct, fk.Columns) {
+			// The referenced table is on a cycle with t: its rows don't exist yet.
+			continue
+		}
+		j, ok := refs[fk.ReferTable]
+		if !ok {
+			j = g.referencedRow(t, fk.ReferTable, i, fk.Columns)
+			refs[fk.ReferTable] = j
+		}
+		if fk.ReferTable == t && j >= i {
+			// Self references can only reference earlier rows.
+			continue
+		}
+		for k, c := range fk.Columns {
+			if _, ok := vals[c]; !ok && k < len(fk.ReferColumns) {
+				vals[c] = keyValue(g.conv, rt.ColDefs[fk.ReferColumns[k]].T, j)
+			}
+		}
+	}
+	var cols []string
+	var l []interface{}
+	for _, c := range ct.ColNames {
+		cd := ct.ColDefs[c]
+		v, ok := vals[c]
+		switch {
+		case ok:
+		case cd.AllowCommitTimestamp:
+			v = sp.CommitTimestamp
+		case keyed[c]:
+			v = keyValue(g.conv, cd.T, i)
+		case g.isForeignKeyColumn(ct, c):
+			v = nil
+		case !cd.NotNull && g.rand.Float64() < nullFraction:
+			v = nil
+		default:
+			v = g.randomValue(cd.T)
+		}
+		cols = append(cols, c)
+		l = append(l, v)
+	}
+	return cols, l
+}
+
+// referencedRow returns the row of table ref that row i of table t
+// references with columns cols. If cols cover t's primary key, rows are
+// referenced in turn, so that they keep the primary key unique; otherwise a
+// random row is referenced.
+func (g *Generator) referencedRow(t, ref string, i int64, cols []string) int64 {
+	n := g.rows[ref]
+	covered := make(map[string]bool)
+	for _, c := range cols {
+		covered[c] = true
+	}
+	for _, pk := range g.conv.SpSchema[t].Pks {
+		if !covered[pk.Col] {
+			if ref == t {
+				n = i
+			}
+			if n <= 0 {
+				return 0
+			}
+			return g.rand.Int63n(n)
+		}
+	}
+	return i % n
+}
+
+// keyedColumns returns the columns of table t whose values are derived from
+// the row number: primary key columns, columns of unique indexes, and
+// columns referenced by foreign keys.
+func (g *Generator) keyedColumns(t string) map[string]bool {
+	keyed := make(map[string]bool)
+	ct := g.conv.SpSchema[t]
+	for _, pk := range ct.Pks {
+		keyed[pk.Col] = true
+	}
+	for _, idx := range ct.Indexes {
+		if idx.Unique {
+			for _, k := range idx.Keys {
+				keyed[k.Col] = true
+			}
+		}
+	}
+	for _, other := range g.conv.SpSchema {
+		for _, fk := range other.Fks {
+			if fk.ReferTable == t {
+				for _, c := range fk.ReferColumns {
+					keyed[c] = true
+				}
+			}
+		}
+	}
+	return keyed
+}
+
+func (g *Generator) isForeignKeyColumn(ct ddl.CreateTable, col string) bool {
+	for _, fk := range ct.Fks {
+		for _, c := range fk.Columns {
+			if c == col && !ct.ColDefs[col].NotNull {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (g *Generator) allNotNull(ct ddl.CreateTable, cols []string) bool {
+	for _, c := range cols {
+		if !ct.ColDefs[c].NotNull {
+			return false
+		}
+	}
+	return true
+}
+
+// keyInt maps row numbers to distinct non-negative integers spread across
+// the range of INT64, by reversing their bits.
+func keyInt(i int64) int64 {
+	return int64(bits.Reverse64(uint64(i)) >> 1)
+}
+
+// keyValue returns the value of type t derived from row number i. Distinct
+// row numbers get distinct values, except for types with few values (e.g.
+// BOOL) and strings too short to hold them.
+func keyValue(conv *internal.Conv, t ddl.Type, i int64) interface{} {
+	var v interface{}
+	switch t.Name {
+	case ddl.Bool:
+		v = i%2 == 1
+	case ddl.Int64:
+		v = keyInt(i)
+	case ddl.Float64:
+		v = float64(i)
+	case ddl.Numeric:
+		v = numeric(conv, big.NewRat(i, 1))
+	case ddl.String:
+		s := strconv.FormatInt(keyInt(i), 36)
+		if t.Len != ddl.MaxLength && int64(len(s)) > t.Len {
+			s = strconv.FormatInt(i, 36)
+		}
+		v = s
+	case ddl.Bytes:
+		v = []byte(strconv.FormatInt(keyInt(i), 36))
+	case ddl.Date:
+		v = civil.DateOf(time.Unix(0, 0).UTC()).AddDays(int(i % (3000 * 365)))
+	case ddl.Timestamp:
+		v = time.Unix(946684800+i, 0).UTC()
+	case ddl.JSON:
+		v = fmt.Sprintf(`{"id": %d}`, i)
+	default:
+		v = strconv.FormatInt(i, 10)
+	}
+	if t.IsArray {
+		return arrayOf(conv, t, []interface{}{v})
+	}
+	return v
+}
+
+// randomValue returns a random value of type t.
+func (g *Generator) randomValue(t ddl.Type) interface{} {
+	if t.IsArray {
+		var l []interface{}
+		for k := g.rand.Intn(4); k > 0; k-- {
+			l = append(l, g.randomScalar(t))
+		}
+		return arrayOf(g.conv, t, l)
+	}
+	return g.randomScalar(t)
+}
+
+func (g *Generator) randomScalar(t ddl.Type) interface{} {
+	r := g.rand
+	switch t.Name {
+	case ddl.Bool:
+		return r.Intn(2) == 1
+	case ddl.Int64:
+		return r.Int63n(1000000)
+	case ddl.Float64:
+		return r.NormFloat64()*100 + 500
+	case ddl.Numeric:
+		return numeric(g.conv, big.NewRat(r.Int63n(10000000), 100))
+	case ddl.String:
+		n := int64(30)
+		if t.Len != ddl.MaxLength && t.Len < n {
+			n = t.Len
+		}
+		return g.randomString(1 + r.Int63n(n))
+	case ddl.Bytes:
+		b := make([]byte, 1+r.Intn(32))
+		r.Read(b)
+		return b
+	case ddl.Date:
+		return civil.DateOf(time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)).AddDays(r.Intn(60 * 365))
+	case ddl.Timestamp:
+		return time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(r.Int63n(int64(5 * 365 * 24 * time.Hour))))
+	case ddl.JSON:
+		return fmt.Sprintf(`{"value": %d, "label": %q}`, r.Intn(1000), g.randomString(8))
+	default:
+		return g.randomString(10)
+	}
+}
+
+func (g *Generator) randomString(n int64) string {
+	var sb strings.Builder
+	for k := int64(0); k < n; k++ {
+		sb.WriteByte(alphabet[g.rand.Intn(len(alphabet))])
+	}
+	return sb.String()
+}
+
+// numeric returns r as a value of a NUMERIC column of conv's dialect.
+func numeric(conv *internal.Conv, r *big.Rat) interface{} {
+	if conv.TargetDb == constants.TargetExperimentalPostgres {
+		return sp.PGNumeric{Numeric: r.FloatString(2), Valid: true}
+	}
+	return r
+}
+
+// arrayOf returns the elements l as an array value of type t.
+func arrayOf(conv *internal.Conv, t ddl.Type, l []interface{}) interface{} {
+	switch t.Name {
+	case ddl.Bool:
+		a := []bool{}
+		for _, v := range l {
+			a = append(a, v.(bool))
+		}
+		return a
+	case ddl.Int64:
+		a := []int64{}
+		for _, v := range l {
+			a = append(a, v.(int64))
+		}
+		return a
+	case ddl.Float64:
+		a := []float64{}
+		for _, v := range l {
+			a = append(a, v.(float64))
+		}
+		return a
+	case ddl.Bytes:
+		a := [][]byte{}
+		for _, v := range l {
+			a = append(a, v.([]byte))
+		}
+		return a
+	case ddl.Date:
+		a := []civil.Date{}
+		for _, v := range l {
+			a = append(a, v.(civil.Date))
+		}
+		return a
+	case ddl.Timestamp:
+		a := []time.Time{}
+		for _, v := range l {
+			a = append(a, v.(time.Time))
+		}
+		return a
+	case ddl.Numeric:
+		if conv.TargetDb == constants.TargetExperimentalPostgres {
+			a := []sp.PGNumeric{}
+			for _, v := range l {
+				a = append(a, v.(sp.PGNumeric))
+			}
+			return a
+		}
+		a := []big.Rat{}
+		for _, v := range l {
+			a = append(a, *v.(*big.Rat))
+		}
+		return a
+	default:
+		a := []string{}
+		for _, v := range l {
+			a = append(a, v.(string))
+		}
+		return a
+	}
+}
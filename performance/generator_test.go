@@ -0,0 +1,162 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package performance
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	sp "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func generatorConv() *internal.Conv {
+	conv := internal.MakeConv()
+	conv.SpSchema = ddl.Schema{
+		"customers": {
+			Name:     "customers",
+			ColNames: []string{"id", "email", "name", "balance", "tags", "updated"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":      {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"email":   {Name: "email", T: ddl.Type{Name: ddl.String, Len: 100}, NotNull: true},
+				"name":    {Name: "name", T: ddl.Type{Name: ddl.String, Len: 5}},
+				"balance": {Name: "balance", T: ddl.Type{Name: ddl.Numeric}, NotNull: true},
+				"tags":    {Name: "tags", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}},
+				"updated": {Name: "updated", T: ddl.Type{Name: ddl.Timestamp}, NotNull: true, AllowCommitTimestamp: true},
+			},
+			Pks:     []ddl.IndexKey{{Col: "id"}},
+			Indexes: []ddl.CreateIndex{{Name: "idx_email", Table: "customers", Unique: true, Keys: []ddl.IndexKey{{Col: "email"}}}},
+		},
+		"orders": {
+			Name:     "orders",
+			ColNames: []string{"customer_id", "order_id", "ordered", "referrer"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"customer_id": {Name: "customer_id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"order_id":    {Name: "order_id", T: ddl.Type{Name: ddl.String, Len: 36}, NotNull: true},
+				"ordered":     {Name: "ordered", T: ddl.Type{Name: ddl.Date}},
+				"referrer":    {Name: "referrer", T: ddl.Type{Name: ddl.String, Len: 100}},
+			},
+			Pks:    []ddl.IndexKey{{Col: "customer_id"}, {Col: "order_id"}},
+			Parent: "customers",
+			Fks:    []ddl.Foreignkey{{Name: "fk_referrer", Columns: []string{"referrer"}, ReferTable: "customers", ReferColumns: []string{"email"}}},
+		},
+		"profiles": {
+			Name:     "profiles",
+			ColNames: []string{"customer_id", "bio"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"customer_id": {Name: "customer_id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"bio":         {Name: "bio", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			Pks: []ddl.IndexKey{{Col: "customer_id"}},
+			Fks: []ddl.Foreignkey{{Name: "fk_customer", Columns: []string{"customer_id"}, ReferTable: "customers", ReferColumns: []string{"id"}}},
+		},
+	}
+	return conv
+}
+
+func TestGenerator(t *testing.T) {
+	conv := generatorConv()
+	g, err := NewGenerator(conv, 50, map[string]int64{"orders": 200, "profiles": 40}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(290), g.Total())
+
+	rows := make(map[string][]map[string]interface{})
+	conv.SetDataMode()
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		row := make(map[string]interface{})
+		for i, c := range cols {
+			row[c] = vals[i]
+		}
+		rows[table] = append(rows[table], row)
+	})
+	g.Generate()
+	assert.Equal(t, 50, len(rows["customers"]))
+	assert.Equal(t, 200, len(rows["orders"]))
+	assert.Equal(t, 40, len(rows["profiles"]))
+
+	unique := func(table string, cols ...string) map[string]bool {
+		keys := make(map[string]bool)
+		for _, r := range rows[table] {
+			key := ""
+			for _, c := range cols {
+				key += fmt.Sprintf("%v/", r[c])
+			}
+			assert.False(t, keys[key], "duplicate key %s of %s", key, table)
+			keys[key] = true
+		}
+		return keys
+	}
+	ids := unique("customers", "id")
+	emails := unique("customers", "email")
+	unique("orders", "customer_id", "order_id")
+	unique("profiles", "customer_id")
+	for _, r := range rows["orders"] {
+		assert.True(t, ids[fmt.Sprintf("%v/", r["customer_id"])], "order of unknown customer %v", r["customer_id"])
+		if r["referrer"] != nil {
+			assert.True(t, emails[fmt.Sprintf("%v/", r["referrer"])], "unknown referrer %v", r["referrer"])
+		}
+	}
+	for _, r := range rows["profiles"] {
+		assert.True(t, ids[fmt.Sprintf("%v/", r["customer_id"])], "profile of unknown customer %v", r["customer_id"])
+	}
+	for _, r := range rows["customers"] {
+		assert.Equal(t, sp.CommitTimestamp, r["updated"])
+		assert.IsType(t, &big.Rat{}, r["balance"])
+		if r["tags"] != nil {
+			assert.IsType(t, []string{}, r["tags"])
+		}
+		if r["name"] != nil {
+			assert.LessOrEqual(t, len(r["name"].(string)), 5)
+		}
+	}
+
+	_, err = NewGenerator(conv, 10, map[string]int64{"unknown": 1}, 1)
+	assert.NotNil(t, err)
+}
+
+func TestGenerator_Deterministic(t *testing.T) {
+	generate := func() [][]interface{} {
+		conv := generatorConv()
+		g, _ := NewGenerator(conv, 5, nil, 42)
+		var l [][]interface{}
+		conv.SetDataMode()
+		conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+			l = append(l, vals)
+		})
+		g.Generate()
+		return l
+	}
+	assert.Equal(t, generate(), generate())
+}
+
+func TestKeyValue(t *testing.T) {
+	conv := internal.MakeConv()
+	assert.Equal(t, int64(0), keyValue(conv, ddl.Type{Name: ddl.Int64}, 0))
+	assert.Equal(t, int64(1<<62), keyValue(conv, ddl.Type{Name: ddl.Int64}, 1))
+	assert.Equal(t, "5", keyValue(conv, ddl.Type{Name: ddl.String, Len: 1}, 5))
+	assert.Equal(t, civil.Date{Year: 1970, Month: 1, Day: 3}, keyValue(conv, ddl.Type{Name: ddl.Date}, 2))
+	assert.Equal(t, time.Unix(946684802, 0).UTC(), keyValue(conv, ddl.Type{Name: ddl.Timestamp}, 2))
+	assert.Equal(t, []int64{1 << 62}, keyValue(conv, ddl.Type{Name: ddl.Int64, IsArray: true}, 1))
+	conv.TargetDb = constants.TargetExperimentalPostgres
+	assert.Equal(t, sp.PGNumeric{Numeric: "3.00", Valid: true}, keyValue(conv, ddl.Type{Name: ddl.Numeric}, 3))
+}
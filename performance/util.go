@@ -12,12 +12,11 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Utils.go contains common helper functions used across multiple other
-// packages under performance folder.
+// Utils.go contains helper functions generating the random values of
+// benchmark datasets.
 package performance
 
 import (
-	"fmt"
 	"math/rand"
 	"strings"
 	"time"
@@ -66,8 +65,3 @@ func RandomBool() bool {
 func CurrentTimestamp() string {
 	return time.Now().Format("2006-01-02 15:04:05")
 }
-
-// Generate MYSQL connection string with server, port, user, password, database specified
-func GetMYSQLConnectionStr(server, port, user, password, dbName string) string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", user, password, server, port, dbName)
-}
@@ -15,13 +15,18 @@
 package profiles
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/tunnel"
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
 )
 
 type SourceProfileType int
@@ -63,6 +68,8 @@ const (
 	SourceProfileConnectionTypeDynamoDB
 	SourceProfileConnectionTypeSqlServer
 	SourceProfileConnectionTypeOracle
+	SourceProfileConnectionTypeBigQuery
+	SourceProfileConnectionTypeFiles
 )
 
 type SourceProfileConnectionMySQL struct {
@@ -72,6 +79,11 @@ type SourceProfileConnectionMySQL struct {
 	Db              string // Same as MYSQLDATABASE environment variable
 	Pwd             string // Same as MYSQLPWD environment variable
 	StreamingConfig string
+	Grants          bool     // Whether grants of privileges on tables are migrated as database roles.
+	FetchSize       int      // Rows read per query when reading tables in chunks, or 0 to read each table with a single query.
+	Readers         int      // Number of connections reading each table in parallel.
+	Replicas        []string // host:port of read replicas preferred for reads, in order of preference.
+	MaxReplicaLag   int      // Maximum lag in seconds of the replicas read from, or 0 for no limit.
 }
 
 func NewSourceProfileConnectionMySQL(params map[string]string) (SourceProfileConnectionMySQL, error) {
@@ -88,6 +100,23 @@ func NewSourceProfileConnectionMySQL(params map[string]string) (SourceProfileCon
 		return mysql, fmt.Errorf("specify a non-empty streaming config file path")
 	}
 	mysql.StreamingConfig = streamingConfig
+	grants, err := parseGrants(params)
+	if err != nil {
+		return mysql, err
+	}
+	mysql.Grants = grants
+	if mysql.FetchSize, err = parseCount(params, "fetchSize", 0); err != nil {
+		return mysql, err
+	}
+	if mysql.Readers, err = parseCount(params, "readers", 1); err != nil {
+		return mysql, err
+	}
+	if mysql.Readers == 0 {
+		return mysql, fmt.Errorf("please specify a positive number of readers")
+	}
+	if mysql.Replicas, mysql.MaxReplicaLag, err = parseReplicas(params, "3306"); err != nil {
+		return mysql, err
+	}
 
 	// We don't users to mix and match params from source-profile and environment variables.
 	// We either try to get all params from the source-profile and if none are set, we read from the env variables.
@@ -133,12 +162,249 @@ func NewSourceProfileConnectionMySQL(params map[string]string) (SourceProfileCon
 	return mysql, nil
 }
 
+// SourceProfileShard is one of several source databases (shards) merged into
+// one Spanner database.
+type SourceProfileShard struct {
+	Id          string // Value of the shard id column for rows of this shard.
+	Mysql       SourceProfileConnectionMySQL
+	NamePattern string // Pattern for the Spanner names of the shard's tables, indexes and foreign keys (see internal.ValidNamePattern).
+}
+
+// SourceProfileShards describes the source databases merged into one Spanner
+// database. It is empty unless several databases are specified.
+type SourceProfileShards struct {
+	Shards         []SourceProfileShard
+	TableCollision string // How tables with the same name in several shards are handled (see internal.TableCollisionMerge and friends).
+	IdColumn       string // Name of the column recording the shard of each row.
+	Parallelism    int    // Maximum number of shards whose data is migrated at the same time.
+}
+
+// DefaultShardParallelism is the default maximum number of shards whose data
+// is migrated at the same time.
+const DefaultShardParallelism = 4
+
+// NewSourceProfileShards returns the shards specified by a MySQL source
+// profile whose dbName is a comma separated list of databases, e.g.
+// "dbName=shard_001,shard_002". Each database is a shard, identified by its
+// name, using the connection parameters of mysql.
+func NewSourceProfileShards(mysql SourceProfileConnectionMySQL, params map[string]string) (SourceProfileShards, error) {
+	shards := SourceProfileShards{}
+	dbs := strings.Split(mysql.Db, ",")
+	if len(dbs) < 2 {
+		return shards, nil
+	}
+	for _, db := range dbs {
+		db = strings.TrimSpace(db)
+		if db == "" {
+			return shards, fmt.Errorf("found empty database name in dbName list '%s'", mysql.Db)
+		}
+		conn := mysql
+		conn.Db = db
+		shards.Shards = append(shards.Shards, SourceProfileShard{Id: db, Mysql: conn})
+	}
+	shards.TableCollision = params["tableCollision"]
+	shards.IdColumn = params["shardIdColumn"]
+	if p, ok := params["shardParallelism"]; ok {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 {
+			return shards, fmt.Errorf("shardParallelism must be a positive integer, found '%s'", p)
+		}
+		shards.Parallelism = n
+	}
+	return shards, shards.setDefaults()
+}
+
+// ShardConfig is the format of a shard config file, which lists the source
+// databases (shards) merged into one Spanner database, e.g.
+//
+//	{
+//	  "TableCollision": "merge",
+//	  "ShardIdColumn": "shard_id",
+//	  "Parallelism": 4,
+//	  "Shards": [
+//	    {"Id": "eu", "Host": "10.0.0.1", "User": "root", "Password": "...", "DbName": "shop"},
+//	    {"Id": "us", "Host": "10.0.0.2", "Port": "3307", "User": "root", "DbName": "shop"},
+//	    {"Id": "billing", "Host": "10.0.0.3", "User": "root", "DbName": "billing", "NamePattern": "billing_*"}
+//	  ]
+//	}
+//
+// Only Shards is required; the other fields have the same defaults as the
+// corresponding source profile params.
+type ShardConfig struct {
+	TableCollision string
+	ShardIdColumn  string
+	Parallelism    int
+	Shards         []ShardConfigShard
+}
+
+// ShardConfigShard describes a shard of a ShardConfig.
+type ShardConfigShard struct {
+	Id       string // Defaults to DbName.
+	Host     string
+	Port     string // Defaults to 3306.
+	User     string
+	Password string // Prompted for if empty.
+	DbName   string
+	// NamePattern, if set, is the pattern for the Spanner names of the
+	// shard's tables, indexes and foreign keys, e.g. "billing_*" (see
+	// internal.ValidNamePattern). The shard's tables are never merged with
+	// tables of other shards.
+	NamePattern string
+}
+
+// ReadShardConfig returns the shards described by the shard config file
+// (see ShardConfig).
+func ReadShardConfig(file string) (SourceProfileShards, error) {
+	shards := SourceProfileShards{}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return shards, fmt.Errorf("can't read shard config file due to: %v", err)
+	}
+	cfg := ShardConfig{}
+	if err = json.Unmarshal(b, &cfg); err != nil {
+		return shards, fmt.Errorf("unable to unmarshall json due to: %v", err)
+	}
+	if len(cfg.Shards) == 0 {
+		return shards, fmt.Errorf("shard config file %s doesn't list any shards", file)
+	}
+	ids := make(map[string]bool)
+	pwd := ""
+	for i, s := range cfg.Shards {
+		if s.Host == "" || s.User == "" || s.DbName == "" {
+			return shards, fmt.Errorf("found empty string for Host/User/DbName of shard %d in shard config file %s", i+1, file)
+		}
+		if s.Id == "" {
+			s.Id = s.DbName
+		}
+		if ids[s.Id] {
+			return shards, fmt.Errorf("found several shards with id %s in shard config file %s: please specify a unique Id for each shard", s.Id, file)
+		}
+		ids[s.Id] = true
+		if s.Port == "" {
+			s.Port = "3306"
+		}
+		if s.Password == "" {
+			// Shards usually share credentials, so we only ask once.
+			if pwd == "" {
+				pwd = utils.GetPassword()
+			}
+			s.Password = pwd
+		}
+		if s.NamePattern != "" {
+			if err := internal.ValidNamePattern(s.NamePattern); err != nil {
+				return shards, fmt.Errorf("shard %s in shard config file %s: %v", s.Id, file, err)
+			}
+		}
+		conn := SourceProfileConnectionMySQL{Host: s.Host, Port: s.Port, User: s.User, Db: s.DbName, Pwd: s.Password}
+		shards.Shards = append(shards.Shards, SourceProfileShard{Id: s.Id, Mysql: conn, NamePattern: s.NamePattern})
+	}
+	if cfg.Parallelism < 0 {
+		return shards, fmt.Errorf("Parallelism must be a positive integer, found %d", cfg.Parallelism)
+	}
+	shards.TableCollision, shards.IdColumn, shards.Parallelism = cfg.TableCollision, cfg.ShardIdColumn, cfg.Parallelism
+	return shards, shards.setDefaults()
+}
+
+// setDefaults validates the options of shards, and sets defaults for
+// options that aren't specified.
+func (shards *SourceProfileShards) setDefaults() error {
+	switch shards.TableCollision {
+	case "":
+		shards.TableCollision = internal.TableCollisionMerge
+	case internal.TableCollisionMerge, internal.TableCollisionPrefix, internal.TableCollisionFail:
+	default:
+		return fmt.Errorf("please specify a valid choice for tableCollision: available choices(%s, %s, %s)", internal.TableCollisionMerge, internal.TableCollisionPrefix, internal.TableCollisionFail)
+	}
+	if shards.IdColumn == "" {
+		shards.IdColumn = internal.DefaultShardIdColumn
+	}
+	if shards.Parallelism == 0 {
+		shards.Parallelism = DefaultShardParallelism
+	}
+	return nil
+}
+
 type SourceProfileConnectionPostgreSQL struct {
-	Host string // Same as PGHOST environment variable
-	Port string // Same as PGPORT environment variable
-	User string // Same as PGUSER environment variable
-	Db   string // Same as PGDATABASE environment variable
-	Pwd  string // Same as PGPASSWORD environment variable
+	Host              string   // Same as PGHOST environment variable
+	Port              string   // Same as PGPORT environment variable
+	User              string   // Same as PGUSER environment variable
+	Db                string   // Same as PGDATABASE environment variable
+	Pwd               string   // Same as PGPASSWORD environment variable
+	MaterializedViews string   // Handling of materialized views, see internal.MaterializedViewSkip and friends.
+	Grants            bool     // Whether grants of privileges on tables are migrated as database roles.
+	Copy              bool     // Whether data is read with COPY rather than SELECT, where possible.
+	Replicas          []string // host:port of read replicas preferred for reads, in order of preference.
+	MaxReplicaLag     int      // Maximum lag in seconds of the replicas read from, or 0 for no limit.
+}
+
+// parseCopy returns the value of source profile param copy, which selects
+// whether data is read with COPY (the default) or with SELECT.
+func parseCopy(params map[string]string) (bool, error) {
+	switch params["copy"] {
+	case "", "yes", "true":
+		return true, nil
+	case "no", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("please specify a valid choice for copy: available choices(yes, no, true, false)")
+}
+
+// parseCount returns the value of source profile param name, a non-negative
+// integer, or def if the param isn't specified.
+func parseCount(params map[string]string, name string, def int) (int, error) {
+	v, ok := params[name]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("please specify a non-negative integer for %s, found '%s'", name, v)
+	}
+	return n, nil
+}
+
+// DefaultMaxReplicaLag is the default maximum lag in seconds of the read
+// replicas of a source database read from.
+const DefaultMaxReplicaLag = 60
+
+// parseReplicas returns the values of source profile params replicas, a
+// comma separated list of the hosts (host or host:port, with defaultPort by
+// default) of read replicas of the source database, e.g.
+// "replicas=\"replica-1,replica-2:3307\"", and maxReplicaLag, in seconds.
+func parseReplicas(params map[string]string, defaultPort string) ([]string, int, error) {
+	maxLag, err := parseCount(params, "maxReplicaLag", DefaultMaxReplicaLag)
+	if err != nil {
+		return nil, 0, err
+	}
+	if params["replicas"] == "" {
+		return nil, maxLag, nil
+	}
+	var replicas []string
+	for _, r := range strings.Split(params["replicas"], ",") {
+		r = strings.TrimSpace(r)
+		host, port, err := net.SplitHostPort(r)
+		if err != nil && !strings.Contains(r, ":") {
+			host, port, err = r, defaultPort, nil
+		}
+		if err != nil || host == "" || port == "" {
+			return nil, 0, fmt.Errorf("found invalid replica '%s' in replicas '%s', expected host or host:port", r, params["replicas"])
+		}
+		replicas = append(replicas, net.JoinHostPort(host, port))
+	}
+	return replicas, maxLag, nil
+}
+
+// parseGrants returns the value of source profile param grants, which
+// enables the migration of grants of privileges on tables as Spanner
+// database roles.
+func parseGrants(params map[string]string) (bool, error) {
+	switch params["grants"] {
+	case "", "no", "false":
+		return false, nil
+	case "yes", "true":
+		return true, nil
+	}
+	return false, fmt.Errorf("please specify a valid choice for grants: available choices(yes, no, true, false)")
 }
 
 func NewSourceProfileConnectionPostgreSQL(params map[string]string) (SourceProfileConnectionPostgreSQL, error) {
@@ -148,6 +414,21 @@ func NewSourceProfileConnectionPostgreSQL(params map[string]string) (SourceProfi
 	db, dbOk := params["dbName"]
 	port, portOk := params["port"]
 	pwd, pwdOk := params["password"]
+	pg.MaterializedViews = params["materializedViews"]
+	if err := internal.ValidateMaterializedViews(pg.MaterializedViews); err != nil {
+		return pg, err
+	}
+	grants, err := parseGrants(params)
+	if err != nil {
+		return pg, err
+	}
+	pg.Grants = grants
+	if pg.Copy, err = parseCopy(params); err != nil {
+		return pg, err
+	}
+	if pg.Replicas, pg.MaxReplicaLag, err = parseReplicas(params, "5432"); err != nil {
+		return pg, err
+	}
 	// We don't users to mix and match params from source-profile and environment variables.
 	// We either try to get all params from the source-profile and if none are set, we read from the env variables.
 	if !(hostOk || userOk || dbOk || portOk || pwdOk) {
@@ -187,11 +468,13 @@ func NewSourceProfileConnectionPostgreSQL(params map[string]string) (SourceProfi
 }
 
 type SourceProfileConnectionSqlServer struct {
-	Host string
-	Port string
-	User string
-	Db   string
-	Pwd  string
+	Host      string
+	Port      string
+	User      string
+	Db        string
+	Pwd       string
+	FetchSize int // Rows read per query when reading tables in chunks, or 0 to read each table with a single query.
+	Readers   int // Number of connections reading each table in parallel.
 }
 
 func NewSourceProfileConnectionSqlServer(params map[string]string) (SourceProfileConnectionSqlServer, error) {
@@ -201,6 +484,16 @@ func NewSourceProfileConnectionSqlServer(params map[string]string) (SourceProfil
 	db, dbOk := params["dbName"]
 	port, portOk := params["port"]
 	pwd, pwdOk := params["password"]
+	var err error
+	if ss.FetchSize, err = parseCount(params, "fetchSize", 0); err != nil {
+		return ss, err
+	}
+	if ss.Readers, err = parseCount(params, "readers", 1); err != nil {
+		return ss, err
+	}
+	if ss.Readers == 0 {
+		return ss, fmt.Errorf("please specify a positive number of readers")
+	}
 
 	// We don't allow users to mix and match params from source-profile and environment variables.
 	// We either try to get all params from the source-profile and if none are set, we read from the env variables.
@@ -256,22 +549,33 @@ type SourceProfileConnectionDynamoDB struct {
 	// These connection params are not used currently because the SDK reads directly from the env variables.
 	// These are still kept around as reference when we refactor passing
 	// SourceProfile instead of sqlConnectionStr around.
-	AwsAccessKeyID     string // Same as AWS_ACCESS_KEY_ID environment variable
-	AwsSecretAccessKey string // Same as AWS_SECRET_ACCESS_KEY environment variable
-	AwsRegion          string // Same as AWS_REGION environment variable
-	DydbEndpoint       string // Same as DYNAMODB_ENDPOINT_OVERRIDE environment variable
-	SchemaSampleSize   int64  // Number of rows to use for inferring schema (default 100,000)
-	enableStreaming    string // Used for confirming streaming migration (valid options: `yes`,`no`,`true`,`false`)
+	AwsAccessKeyID     string           // Same as AWS_ACCESS_KEY_ID environment variable
+	AwsSecretAccessKey string           // Same as AWS_SECRET_ACCESS_KEY environment variable
+	AwsRegion          string           // Same as AWS_REGION environment variable
+	DydbEndpoint       string           // Same as DYNAMODB_ENDPOINT_OVERRIDE environment variable
+	SchemaSampleSize   int64            // Number of rows to use for inferring schema (default 100,000), SchemaSampleAll to read all rows
+	enableStreaming    string           // Used for confirming streaming migration (valid options: `yes`,`no`,`true`,`false`)
+	ExactlyOnce        bool             // If true, records from DynamoDB Streams are applied to Spanner exactly once.
+	AutoAddColumns     bool             // If true, attributes found in DynamoDB Streams but missing from the schema are added as nullable Spanner columns.
+	ExportURI          string           // If set, S3 URI (s3://bucket/prefix) of table exports, in DynamoDB JSON or Amazon Ion format, to bulk-load instead of scanning the tables.
+	ScanSegments       int64            // Number of segments each table is scanned with in parallel during bulk load (default 1).
+	TableScanSegments  map[string]int64 // Overrides of ScanSegments, keyed by table name.
+	RCUBudget          float64          // If positive, maximum read capacity units per second consumed by scans and stream reads.
+	CatchAllThreshold  float64          // If positive, attributes found in fewer than this percentage of sampled items are kept in a catch-all JSON column.
 }
 
 func NewSourceProfileConnectionDynamoDB(params map[string]string) (SourceProfileConnectionDynamoDB, error) {
 	dydb := SourceProfileConnectionDynamoDB{}
 	if schemaSampleSize, ok := params["schema-sample-size"]; ok {
-		schemaSampleSizeInt, err := strconv.Atoi(schemaSampleSize)
-		if err != nil {
-			return dydb, fmt.Errorf("could not parse schema-sample-size = %v as a valid int64", schemaSampleSize)
+		if schemaSampleSize == "all" {
+			dydb.SchemaSampleSize = SchemaSampleAll
+		} else {
+			schemaSampleSizeInt, err := strconv.Atoi(schemaSampleSize)
+			if err != nil || schemaSampleSizeInt <= 0 {
+				return dydb, fmt.Errorf("could not parse schema-sample-size = %v as a valid positive int64 or all", schemaSampleSize)
+			}
+			dydb.SchemaSampleSize = int64(schemaSampleSizeInt)
 		}
-		dydb.SchemaSampleSize = int64(schemaSampleSizeInt)
 	}
 	// For DynamoDB, the preferred way to provide connection params is through env variables.
 	// Unlike postgres and mysql, there may not be deprecation of env variables, hence it
@@ -299,16 +603,148 @@ func NewSourceProfileConnectionDynamoDB(params map[string]string) (SourceProfile
 			return dydb, fmt.Errorf("please specify a valid choice for enableStreaming: available choices(yes, no, true, false)")
 		}
 	}
+	if exactlyOnce, ok := params["exactlyOnce"]; ok {
+		switch exactlyOnce {
+		case "yes", "true":
+			dydb.ExactlyOnce = true
+		case "no", "false":
+			dydb.ExactlyOnce = false
+		default:
+			return dydb, fmt.Errorf("please specify a valid choice for exactlyOnce: available choices(yes, no, true, false)")
+		}
+	}
+	if autoAddColumns, ok := params["autoAddColumns"]; ok {
+		switch autoAddColumns {
+		case "yes", "true":
+			dydb.AutoAddColumns = true
+		case "no", "false":
+			dydb.AutoAddColumns = false
+		default:
+			return dydb, fmt.Errorf("please specify a valid choice for autoAddColumns: available choices(yes, no, true, false)")
+		}
+	}
+	if dydb.ExportURI, ok = params["exportUri"]; ok && !strings.HasPrefix(dydb.ExportURI, "s3://") {
+		return dydb, fmt.Errorf("please specify a valid S3 URI for exportUri, e.g. s3://bucket/prefix")
+	}
+	if scanSegments, ok := params["scanSegments"]; ok {
+		var err error
+		if dydb.ScanSegments, dydb.TableScanSegments, err = parseScanSegments(scanSegments); err != nil {
+			return dydb, err
+		}
+	}
+	if rcuBudget, ok := params["rcuBudget"]; ok {
+		budget, err := strconv.ParseFloat(rcuBudget, 64)
+		if err != nil || budget <= 0 {
+			return dydb, fmt.Errorf("could not parse rcuBudget = %v as a positive number of read capacity units per second", rcuBudget)
+		}
+		dydb.RCUBudget = budget
+	}
+	if catchAllThreshold, ok := params["catchAllThreshold"]; ok {
+		threshold, err := strconv.ParseFloat(catchAllThreshold, 64)
+		if err != nil || threshold <= 0 || threshold > 100 {
+			return dydb, fmt.Errorf("could not parse catchAllThreshold = %v as a percentage between 0 and 100", catchAllThreshold)
+		}
+		dydb.CatchAllThreshold = threshold
+	}
 	return dydb, nil
 }
 
+type SourceProfileConnectionBigQuery struct {
+	Project     string // Project of the dataset, which is also billed for reads.
+	Dataset     string
+	ReadStreams int64 // Maximum number of streams each table is read with in parallel (default 0, chosen by BigQuery).
+}
+
+func NewSourceProfileConnectionBigQuery(params map[string]string) (SourceProfileConnectionBigQuery, error) {
+	bq := SourceProfileConnectionBigQuery{Project: params["project"], Dataset: params["dataset"]}
+	if bq.Project == "" {
+		bq.Project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if bq.Project == "" || bq.Dataset == "" {
+		return bq, fmt.Errorf("please specify project and dataset in the source-profile")
+	}
+	if readStreams, ok := params["readStreams"]; ok {
+		n, err := strconv.ParseInt(readStreams, 10, 64)
+		if err != nil || n < 1 {
+			return bq, fmt.Errorf("could not parse readStreams = %v as a valid positive int64", readStreams)
+		}
+		bq.ReadStreams = n
+	}
+	return bq, nil
+}
+
+type SourceProfileConnectionFiles struct {
+	Manifest         string // JSON file listing the files and, optionally, the schema of each table.
+	Format           string // csv or parquet, by default from the extension of the files.
+	Delimiter        string // Delimiter of CSV files (default ",").
+	NullStr          string // Value of nulls in CSV files (default "").
+	SchemaSampleSize int64  // Number of rows of CSV files sampled to infer the type of their columns (default 10,000).
+}
+
+func NewSourceProfileConnectionFiles(params map[string]string) (SourceProfileConnectionFiles, error) {
+	files := SourceProfileConnectionFiles{Manifest: params["manifest"], Format: strings.ToLower(params["format"]), Delimiter: ",", NullStr: params["nullStr"]}
+	if files.Manifest == "" {
+		return files, fmt.Errorf("please specify the manifest listing the files of each table in the source-profile")
+	}
+	if files.Format != "" && files.Format != "csv" && files.Format != "parquet" {
+		return files, fmt.Errorf("invalid format = %v: expected csv or parquet", files.Format)
+	}
+	if delimiter, ok := params["delimiter"]; ok {
+		if len([]rune(delimiter)) != 1 {
+			return files, fmt.Errorf("invalid delimiter = %v: expected a single character", delimiter)
+		}
+		files.Delimiter = delimiter
+	}
+	if schemaSampleSize, ok := params["schema-sample-size"]; ok {
+		n, err := strconv.ParseInt(schemaSampleSize, 10, 64)
+		if err != nil || n <= 0 {
+			return files, fmt.Errorf("could not parse schema-sample-size = %v as a valid positive int64", schemaSampleSize)
+		}
+		files.SchemaSampleSize = n
+	}
+	return files, nil
+}
+
+// SchemaSampleAll is the SchemaSampleSize of schema inference reading all
+// the items of DynamoDB tables.
+const SchemaSampleAll = int64(-1)
+
+// maxScanSegments is the maximum number of segments of a DynamoDB parallel
+// Scan.
+const maxScanSegments = 1000000
+
+// parseScanSegments parses the scanSegments parameter, a list of segment
+// counts separated by semicolons: a bare count is the default for all tables,
+// and table:count entries override it for a table, e.g. "4;orders:32".
+func parseScanSegments(s string) (int64, map[string]int64, error) {
+	var segments int64
+	tableSegments := make(map[string]int64)
+	for _, entry := range strings.Split(s, ";") {
+		table, count := "", entry
+		if i := strings.LastIndex(entry, ":"); i >= 0 {
+			table, count = entry[:i], entry[i+1:]
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(count), 10, 64)
+		if err != nil || n < 1 || n > maxScanSegments {
+			return 0, nil, fmt.Errorf("invalid scanSegments entry %q: expected a count between 1 and %d, or table:count", entry, maxScanSegments)
+		}
+		if table == "" {
+			segments = n
+		} else {
+			tableSegments[strings.TrimSpace(table)] = n
+		}
+	}
+	return segments, tableSegments, nil
+}
+
 type SourceProfileConnectionOracle struct {
-	Host            string
-	Port            string
-	User            string
-	Db              string
-	Pwd             string
-	StreamingConfig string
+	Host              string
+	Port              string
+	User              string
+	Db                string
+	Pwd               string
+	StreamingConfig   string
+	MaterializedViews string // Handling of materialized views, see internal.MaterializedViewSkip and friends.
 }
 
 func NewSourceProfileConnectionOracle(params map[string]string) (SourceProfileConnectionOracle, error) {
@@ -324,6 +760,10 @@ func NewSourceProfileConnectionOracle(params map[string]string) (SourceProfileCo
 		return ss, fmt.Errorf("specify a non-empty streaming config file path")
 	}
 	ss.StreamingConfig = streamingConfig
+	ss.MaterializedViews = params["materializedViews"]
+	if err := internal.ValidateMaterializedViews(ss.MaterializedViews); err != nil {
+		return ss, err
+	}
 
 	if hostOk && userOk && dbOk {
 		// All connection params provided through source-profile. Port and password handled later.
@@ -354,17 +794,117 @@ type SourceProfileConnection struct {
 	Mysql     SourceProfileConnectionMySQL
 	Pg        SourceProfileConnectionPostgreSQL
 	Dydb      SourceProfileConnectionDynamoDB
+	Bq        SourceProfileConnectionBigQuery
+	Files     SourceProfileConnectionFiles
 	SqlServer SourceProfileConnectionSqlServer
 	Oracle    SourceProfileConnectionOracle
+	Shards    SourceProfileShards
+	Tables    []string             // Source tables to migrate, or nil for all tables.
+	Merges    []internal.MergeRule // Rules merging structurally identical source tables into one table.
+	Tunnel    tunnel.Config        // Route to the source databases, through an SSH tunnel and/or a proxy.
+}
+
+// parseTunnel returns the route to the source databases given by source
+// profile params sshHost, sshUser, sshKeyFile, sshPassword, sshKnownHosts and
+// proxy.
+func parseTunnel(params map[string]string) (tunnel.Config, error) {
+	c := tunnel.Config{
+		SSHHost:       params["sshHost"],
+		SSHUser:       params["sshUser"],
+		SSHKeyFile:    params["sshKeyFile"],
+		SSHPassword:   params["sshPassword"],
+		SSHKnownHosts: params["sshKnownHosts"],
+		Proxy:         params["proxy"],
+	}
+	if c.SSHHost == "" && (c.SSHUser != "" || c.SSHKeyFile != "" || c.SSHPassword != "" || c.SSHKnownHosts != "") {
+		return c, fmt.Errorf("please specify sshHost to connect through an SSH tunnel")
+	}
+	if c.SSHHost != "" && c.SSHUser == "" {
+		return c, fmt.Errorf("please specify sshUser to connect through an SSH tunnel")
+	}
+	return c, nil
+}
+
+// parseTables returns the value of source profile param tables, a comma
+// separated list of the source tables to migrate (the param must be quoted,
+// e.g. "tables=users,orders"), or nil if all tables are migrated.
+func parseTables(params map[string]string) ([]string, error) {
+	v, ok := params["tables"]
+	if !ok {
+		return nil, nil
+	}
+	var tables []string
+	for _, t := range strings.Split(v, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tables = append(tables, t)
+		}
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("please specify at least one table in tables")
+	}
+	return tables, nil
+}
+
+// parseMergeTables returns the rules of source profile param mergeTables, a
+// comma separated list of patterns of source tables to merge, each optionally
+// followed by ':' and the name of the column recording the source table of
+// each row (the param must be quoted, e.g. "mergeTables=t_*:tenant,logs_*").
+func parseMergeTables(params map[string]string) ([]internal.MergeRule, error) {
+	v, ok := params["mergeTables"]
+	if !ok {
+		return nil, nil
+	}
+	var rules []internal.MergeRule
+	for _, r := range strings.Split(v, ",") {
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+		pattern, column := r, ""
+		if i := strings.LastIndex(r, ":"); i >= 0 {
+			pattern, column = r[:i], r[i+1:]
+		}
+		rule, err := internal.NewMergeRule(pattern, column)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("please specify at least one table pattern in mergeTables")
+	}
+	return rules, nil
 }
 
 func NewSourceProfileConnection(source string, params map[string]string) (SourceProfileConnection, error) {
 	conn := SourceProfileConnection{}
 	var err error
+	if conn.Tables, err = parseTables(params); err != nil {
+		return conn, err
+	}
+	if conn.Merges, err = parseMergeTables(params); err != nil {
+		return conn, err
+	}
+	if conn.Tunnel, err = parseTunnel(params); err != nil {
+		return conn, err
+	}
 	switch strings.ToLower(source) {
-	case "mysql":
+	case "mysql", "mariadb":
 		{
 			conn.Ty = SourceProfileConnectionTypeMySQL
+			if file, ok := params["shardConfig"]; ok {
+				if file == "" {
+					return conn, fmt.Errorf("specify a non-empty shard config file path")
+				}
+				if conn.Merges != nil {
+					return conn, fmt.Errorf("mergeTables is not supported when migrating several databases")
+				}
+				conn.Shards, err = ReadShardConfig(file)
+				if err != nil {
+					return conn, err
+				}
+				conn.Mysql = conn.Shards.Shards[0].Mysql
+				return conn, nil
+			}
 			conn.Mysql, err = NewSourceProfileConnectionMySQL(params)
 			if err != nil {
 				return conn, err
@@ -372,6 +912,21 @@ func NewSourceProfileConnection(source string, params map[string]string) (Source
 			if conn.Mysql.StreamingConfig != "" {
 				conn.Streaming = true
 			}
+			conn.Shards, err = NewSourceProfileShards(conn.Mysql, params)
+			if err != nil {
+				return conn, err
+			}
+			if len(conn.Shards.Shards) > 0 {
+				// The first database is used wherever a single database is
+				// expected e.g. for the default Spanner database name.
+				conn.Mysql.Db = conn.Shards.Shards[0].Mysql.Db
+				if conn.Streaming {
+					return conn, fmt.Errorf("streaming migration is not supported when migrating several databases")
+				}
+				if conn.Merges != nil {
+					return conn, fmt.Errorf("mergeTables is not supported when migrating several databases")
+				}
+			}
 		}
 	case "postgresql", "postgres", "pg":
 		{
@@ -392,7 +947,22 @@ func NewSourceProfileConnection(source string, params map[string]string) (Source
 				conn.Streaming = true
 			}
 		}
-
+	case "bigquery":
+		{
+			conn.Ty = SourceProfileConnectionTypeBigQuery
+			conn.Bq, err = NewSourceProfileConnectionBigQuery(params)
+			if err != nil {
+				return conn, err
+			}
+		}
+	case "files":
+		{
+			conn.Ty = SourceProfileConnectionTypeFiles
+			conn.Files, err = NewSourceProfileConnectionFiles(params)
+			if err != nil {
+				return conn, err
+			}
+		}
 	case "sqlserver", "mssql":
 		{
 			conn.Ty = SourceProfileConnectionTypeSqlServer
@@ -415,6 +985,14 @@ func NewSourceProfileConnection(source string, params map[string]string) (Source
 	default:
 		return conn, fmt.Errorf("please specify a valid source database using -source flag, received source = %v", source)
 	}
+	if conn.Merges != nil {
+		if conn.Ty != SourceProfileConnectionTypeMySQL && conn.Ty != SourceProfileConnectionTypePostgreSQL {
+			return conn, fmt.Errorf("mergeTables is only supported for MySQL and PostgreSQL sources")
+		}
+		if conn.Streaming {
+			return conn, fmt.Errorf("mergeTables is not supported with streaming migration")
+		}
+	}
 	return conn, nil
 }
 
@@ -455,12 +1033,150 @@ type SourceProfile struct {
 	Csv    SourceProfileCsv
 }
 
+// OpenTunnels opens the tunnels to the source databases of src, if it is
+// configured to connect through an SSH tunnel or a proxy, and points its
+// hosts and ports to their local ends. The returned function closes them.
+func (src *SourceProfile) OpenTunnels() (func(), error) {
+	conn := &src.Conn
+	if src.Ty != SourceProfileTypeConnection || !conn.Tunnel.Enabled() {
+		return func() {}, nil
+	}
+	tunnels := map[string]*tunnel.Tunnel{}
+	closeAll := func() {
+		for _, t := range tunnels {
+			t.Close()
+		}
+	}
+	// route points host and port to the local end of the tunnel to them,
+	// shared by all the connections to the same database.
+	route := func(host, port *string) error {
+		target := net.JoinHostPort(*host, *port)
+		t, ok := tunnels[target]
+		if !ok {
+			var err error
+			if t, err = tunnel.Open(conn.Tunnel, target); err != nil {
+				return fmt.Errorf("can't reach %s: %v", target, err)
+			}
+			tunnels[target] = t
+		}
+		*host, *port = t.Addr()
+		return nil
+	}
+	routeReplicas := func(replicas []string) error {
+		for i := range replicas {
+			host, port, err := net.SplitHostPort(replicas[i])
+			if err != nil {
+				return err
+			}
+			if err := route(&host, &port); err != nil {
+				return err
+			}
+			replicas[i] = net.JoinHostPort(host, port)
+		}
+		return nil
+	}
+	var err error
+	switch conn.Ty {
+	case SourceProfileConnectionTypeMySQL:
+		err = route(&conn.Mysql.Host, &conn.Mysql.Port)
+		if err == nil {
+			err = routeReplicas(conn.Mysql.Replicas)
+		}
+		for i := range conn.Shards.Shards {
+			if err == nil {
+				err = route(&conn.Shards.Shards[i].Mysql.Host, &conn.Shards.Shards[i].Mysql.Port)
+			}
+		}
+	case SourceProfileConnectionTypePostgreSQL:
+		err = route(&conn.Pg.Host, &conn.Pg.Port)
+		if err == nil {
+			err = routeReplicas(conn.Pg.Replicas)
+		}
+	case SourceProfileConnectionTypeSqlServer:
+		err = route(&conn.SqlServer.Host, &conn.SqlServer.Port)
+	case SourceProfileConnectionTypeOracle:
+		err = route(&conn.Oracle.Host, &conn.Oracle.Port)
+	default:
+		err = fmt.Errorf("SSH tunnels and proxies are not supported for this source")
+	}
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return closeAll, nil
+}
+
 // UseTargetSchema returns true if the driver expects an existing schema
 // to use in the target database.
 func (src SourceProfile) UseTargetSchema() bool {
 	return (src.Driver == constants.CSV)
 }
 
+// ShardProfiles returns a source profile for each shard of a source profile
+// that specifies several source databases, and nil otherwise.
+func (src SourceProfile) ShardProfiles() []SourceProfile {
+	var l []SourceProfile
+	for _, shard := range src.Conn.Shards.Shards {
+		p := src
+		p.Conn.Mysql = shard.Mysql
+		p.Conn.Shards = SourceProfileShards{}
+		l = append(l, p)
+	}
+	return l
+}
+
+// Identifier returns a description of the source database of src, e.g. its
+// host, port and database name or its dump file, which doesn't include
+// credentials.
+func (src SourceProfile) Identifier() string {
+	switch src.Ty {
+	case SourceProfileTypeFile:
+		if src.File.Path == "" {
+			return "stdin"
+		}
+		return src.File.Path
+	case SourceProfileTypeConfig:
+		return src.Config.path
+	case SourceProfileTypeCsv:
+		return src.Csv.Manifest
+	case SourceProfileTypeConnection:
+		switch src.Conn.Ty {
+		case SourceProfileConnectionTypeMySQL:
+			var dbs []string
+			for _, shard := range src.Conn.Shards.Shards {
+				dbs = append(dbs, hostPortDb(shard.Mysql.Host, shard.Mysql.Port, shard.Mysql.Db))
+			}
+			if len(dbs) > 0 {
+				return strings.Join(dbs, ",")
+			}
+			return hostPortDb(src.Conn.Mysql.Host, src.Conn.Mysql.Port, src.Conn.Mysql.Db)
+		case SourceProfileConnectionTypePostgreSQL:
+			return hostPortDb(src.Conn.Pg.Host, src.Conn.Pg.Port, src.Conn.Pg.Db)
+		case SourceProfileConnectionTypeSqlServer:
+			return hostPortDb(src.Conn.SqlServer.Host, src.Conn.SqlServer.Port, src.Conn.SqlServer.Db)
+		case SourceProfileConnectionTypeOracle:
+			return hostPortDb(src.Conn.Oracle.Host, src.Conn.Oracle.Port, src.Conn.Oracle.Db)
+		case SourceProfileConnectionTypeDynamoDB:
+			if src.Conn.Dydb.DydbEndpoint != "" {
+				return src.Conn.Dydb.DydbEndpoint
+			}
+			return "dynamodb:" + src.Conn.Dydb.AwsRegion
+		case SourceProfileConnectionTypeBigQuery:
+			return "bigquery:" + src.Conn.Bq.Project + "." + src.Conn.Bq.Dataset
+		case SourceProfileConnectionTypeFiles:
+			return "files:" + src.Conn.Files.Manifest
+		}
+	}
+	return ""
+}
+
+func hostPortDb(host, port, db string) string {
+	if port != "" {
+		host += ":" + port
+	}
+	return host + "/" + db
+}
+
 // ToLegacyDriver converts source-profile to equivalent legacy global flags
 // e.g., -driver, -dump-file etc since the rest of the codebase still uses the
 // same. TODO: Deprecate this function and pass around SourceProfile across the
@@ -472,10 +1188,14 @@ func (src SourceProfile) ToLegacyDriver(source string) (string, error) {
 			switch strings.ToLower(source) {
 			case "mysql":
 				return constants.MYSQLDUMP, nil
+			case "mariadb":
+				return constants.MARIADBDUMP, nil
 			case "postgresql", "postgres", "pg":
 				return constants.PGDUMP, nil
 			case "dynamodb":
 				return "", fmt.Errorf("dump files are not supported with DynamoDB")
+			case "bigquery":
+				return "", fmt.Errorf("dump files are not supported with BigQuery")
 			default:
 				return "", fmt.Errorf("please specify a valid source database using -source flag, received source = %v", source)
 			}
@@ -486,10 +1206,16 @@ func (src SourceProfile) ToLegacyDriver(source string) (string, error) {
 			switch strings.ToLower(source) {
 			case "mysql":
 				return constants.MYSQL, nil
+			case "mariadb":
+				return constants.MARIADB, nil
 			case "postgresql", "postgres", "pg":
 				return constants.POSTGRES, nil
 			case "dynamodb":
 				return constants.DYNAMODB, nil
+			case "bigquery":
+				return constants.BIGQUERY, nil
+			case "files":
+				return constants.FILES, nil
 			case "sqlserver", "mssql":
 				return constants.SQLSERVER, nil
 			case "oracle":
@@ -535,6 +1261,12 @@ func NewSourceProfile(s string, source string) (SourceProfile, error) {
 	if strings.ToLower(source) == constants.CSV {
 		return SourceProfile{Ty: SourceProfileTypeCsv, Csv: NewSourceProfileCsv(params)}, nil
 	}
+	// Files are read like a database: their format param and stdin don't
+	// make them dump files.
+	if strings.ToLower(source) == constants.FILES {
+		conn, err := NewSourceProfileConnection(source, params)
+		return SourceProfile{Ty: SourceProfileTypeConnection, Conn: conn}, err
+	}
 
 	if _, ok := params["file"]; ok || filePipedToStdin() {
 		profile := NewSourceProfileFile(params)
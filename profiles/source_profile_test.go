@@ -15,8 +15,12 @@
 package profiles
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -120,6 +124,182 @@ func TestNewSourceProfileConnectionSQL(t *testing.T) {
 	}
 }
 
+func TestNewSourceProfileConnectionPostgreSQL_Copy(t *testing.T) {
+	params := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+	pg, err := NewSourceProfileConnectionPostgreSQL(params)
+	assert.Nil(t, err)
+	assert.True(t, pg.Copy)
+	params["copy"] = "no"
+	pg, err = NewSourceProfileConnectionPostgreSQL(params)
+	assert.Nil(t, err)
+	assert.False(t, pg.Copy)
+	params["copy"] = "maybe"
+	_, err = NewSourceProfileConnectionPostgreSQL(params)
+	assert.NotNil(t, err)
+}
+
+func TestNewSourceProfileConnectionMySQL_Streaming(t *testing.T) {
+	params := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+	mysql, err := NewSourceProfileConnectionMySQL(params)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, mysql.FetchSize)
+	assert.Equal(t, 1, mysql.Readers)
+	params["fetchSize"], params["readers"] = "10000", "4"
+	mysql, err = NewSourceProfileConnectionMySQL(params)
+	assert.Nil(t, err)
+	assert.Equal(t, 10000, mysql.FetchSize)
+	assert.Equal(t, 4, mysql.Readers)
+	for _, bad := range []map[string]string{{"fetchSize": "-1"}, {"fetchSize": "many"}, {"readers": "0"}} {
+		p := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+		for k, v := range bad {
+			p[k] = v
+		}
+		_, err = NewSourceProfileConnectionMySQL(p)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestNewSourceProfileConnection_Replicas(t *testing.T) {
+	params := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+	mysql, err := NewSourceProfileConnectionMySQL(params)
+	assert.Nil(t, err)
+	assert.Nil(t, mysql.Replicas)
+	assert.Equal(t, DefaultMaxReplicaLag, mysql.MaxReplicaLag)
+	params["replicas"], params["maxReplicaLag"] = "r1, r2:3307,[::1]:3308", "10"
+	mysql, err = NewSourceProfileConnectionMySQL(params)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"r1:3306", "r2:3307", "[::1]:3308"}, mysql.Replicas)
+	assert.Equal(t, 10, mysql.MaxReplicaLag)
+	params["maxReplicaLag"] = "0"
+	pg, err := NewSourceProfileConnectionPostgreSQL(params)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"r1:5432", "r2:3307", "[::1]:3308"}, pg.Replicas)
+	assert.Equal(t, 0, pg.MaxReplicaLag)
+	for _, bad := range []string{"r1,", "r1:3306:1", ":3306"} {
+		params["replicas"] = bad
+		_, err = NewSourceProfileConnectionMySQL(params)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestNewSourceProfileConnectionSqlServer_Parallel(t *testing.T) {
+	params := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e", "readers": "8"}
+	ss, err := NewSourceProfileConnectionSqlServer(params)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, ss.FetchSize)
+	assert.Equal(t, 8, ss.Readers)
+	params["readers"] = "0"
+	_, err = NewSourceProfileConnectionSqlServer(params)
+	assert.NotNil(t, err)
+}
+
+func TestNewSourceProfileConnection_Tables(t *testing.T) {
+	params := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+	conn, err := NewSourceProfileConnection("postgres", params)
+	assert.Nil(t, err)
+	assert.Nil(t, conn.Tables)
+	params["tables"] = "public.users, public.orders"
+	conn, err = NewSourceProfileConnection("postgres", params)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"public.users", "public.orders"}, conn.Tables)
+	params["tables"] = ""
+	_, err = NewSourceProfileConnection("postgres", params)
+	assert.NotNil(t, err)
+}
+
+func TestNewSourceProfileConnection_MergeTables(t *testing.T) {
+	params := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+	params["mergeTables"] = "t_*:tenant, logs_*"
+	conn, err := NewSourceProfileConnection("mysql", params)
+	assert.Nil(t, err)
+	assert.Equal(t, []internal.MergeRule{{Pattern: "t_*", Column: "tenant"}, {Pattern: "logs_*", Column: internal.DefaultMergeColumn}}, conn.Merges)
+	conn, err = NewSourceProfileConnection("postgres", params)
+	assert.Nil(t, err)
+	assert.Len(t, conn.Merges, 2)
+
+	for _, v := range []string{"", "t_0001", "t_*_*", "*", "t_*:1tenant"} {
+		params["mergeTables"] = v
+		_, err = NewSourceProfileConnection("mysql", params)
+		assert.NotNil(t, err, v)
+	}
+	params["mergeTables"] = "t_*"
+	_, err = NewSourceProfileConnection("sqlserver", params)
+	assert.NotNil(t, err)
+	params["dbName"] = "c,d"
+	_, err = NewSourceProfileConnection("mysql", params)
+	assert.NotNil(t, err)
+}
+
+func TestNewSourceProfileConnection_Tunnel(t *testing.T) {
+	params := map[string]string{"host": "a", "user": "b", "dbName": "c", "password": "e"}
+	conn, err := NewSourceProfileConnection("mysql", params)
+	assert.Nil(t, err)
+	assert.False(t, conn.Tunnel.Enabled())
+	src := SourceProfile{Ty: SourceProfileTypeConnection, Conn: conn}
+	closeTunnels, err := src.OpenTunnels()
+	assert.Nil(t, err)
+	closeTunnels()
+	assert.Equal(t, "a", src.Conn.Mysql.Host)
+
+	params["sshHost"], params["sshUser"], params["sshKeyFile"] = "bastion:2222", "me", "/home/me/.ssh/id_ed25519"
+	params["proxy"] = "http://127.0.0.1:1"
+	conn, err = NewSourceProfileConnection("mysql", params)
+	assert.Nil(t, err)
+	assert.Equal(t, "bastion:2222", conn.Tunnel.SSHHost)
+	assert.Equal(t, "me", conn.Tunnel.SSHUser)
+	assert.Equal(t, "/home/me/.ssh/id_ed25519", conn.Tunnel.SSHKeyFile)
+	assert.Equal(t, "http://127.0.0.1:1", conn.Tunnel.Proxy)
+	// Nothing listens on the proxy's port.
+	src = SourceProfile{Ty: SourceProfileTypeConnection, Conn: conn}
+	_, err = src.OpenTunnels()
+	assert.NotNil(t, err)
+
+	delete(params, "sshUser")
+	_, err = NewSourceProfileConnection("mysql", params)
+	assert.NotNil(t, err)
+	params["sshUser"], params["sshHost"] = "me", ""
+	_, err = NewSourceProfileConnection("mysql", params)
+	assert.NotNil(t, err)
+}
+
+func TestNewSourceProfileConnectionBigQuery(t *testing.T) {
+	params := map[string]string{"project": "p", "dataset": "d", "readStreams": "8"}
+	conn, err := NewSourceProfileConnection("bigquery", params)
+	assert.Nil(t, err)
+	assert.Equal(t, SourceProfileConnectionBigQuery{Project: "p", Dataset: "d", ReadStreams: 8}, conn.Bq)
+	src := SourceProfile{Ty: SourceProfileTypeConnection, Conn: conn}
+	driver, err := src.ToLegacyDriver("bigquery")
+	assert.Nil(t, err)
+	assert.Equal(t, constants.BIGQUERY, driver)
+	params["readStreams"] = "0"
+	_, err = NewSourceProfileConnectionBigQuery(params)
+	assert.NotNil(t, err)
+	delete(params, "readStreams")
+	delete(params, "dataset")
+	_, err = NewSourceProfileConnectionBigQuery(params)
+	assert.NotNil(t, err)
+}
+
+func TestNewSourceProfileConnectionFiles(t *testing.T) {
+	params := map[string]string{"manifest": "m.json", "format": "CSV", "delimiter": "\t", "nullStr": "NULL", "schema-sample-size": "100"}
+	conn, err := NewSourceProfileConnection("files", params)
+	assert.Nil(t, err)
+	assert.Equal(t, SourceProfileConnectionFiles{Manifest: "m.json", Format: "csv", Delimiter: "\t", NullStr: "NULL", SchemaSampleSize: 100}, conn.Files)
+	src := SourceProfile{Ty: SourceProfileTypeConnection, Conn: conn}
+	driver, err := src.ToLegacyDriver("files")
+	assert.Nil(t, err)
+	assert.Equal(t, constants.FILES, driver)
+	for _, invalid := range []map[string]string{
+		{},
+		{"manifest": "m.json", "format": "avro"},
+		{"manifest": "m.json", "delimiter": "||"},
+		{"manifest": "m.json", "schema-sample-size": "0"},
+	} {
+		_, err = NewSourceProfileConnectionFiles(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
 func TestNewSourceProfileConnectionDynamoDB(t *testing.T) {
 	// Avoid getting/settinng env variables in the unit tests.
 	testCases := []struct {
@@ -142,6 +322,76 @@ func TestNewSourceProfileConnectionDynamoDB(t *testing.T) {
 			params:        map[string]string{"schema-sample-size": "a"},
 			errorExpected: true,
 		},
+		{
+			name:          "full schema sample",
+			params:        map[string]string{"schema-sample-size": "all"},
+			errorExpected: false,
+		},
+		{
+			name:          "negative schema sample size",
+			params:        map[string]string{"schema-sample-size": "-5"},
+			errorExpected: true,
+		},
+		{
+			name:          "valid exactly once",
+			params:        map[string]string{"enableStreaming": "yes", "exactlyOnce": "true"},
+			errorExpected: false,
+		},
+		{
+			name:          "invalid exactly once",
+			params:        map[string]string{"exactlyOnce": "maybe"},
+			errorExpected: true,
+		},
+		{
+			name:          "valid auto add columns",
+			params:        map[string]string{"enableStreaming": "yes", "autoAddColumns": "yes"},
+			errorExpected: false,
+		},
+		{
+			name:          "invalid auto add columns",
+			params:        map[string]string{"autoAddColumns": "sometimes"},
+			errorExpected: true,
+		},
+		{
+			name:          "valid export uri",
+			params:        map[string]string{"exportUri": "s3://bucket/exports"},
+			errorExpected: false,
+		},
+		{
+			name:          "invalid export uri",
+			params:        map[string]string{"exportUri": "bucket/exports"},
+			errorExpected: true,
+		},
+		{
+			name:          "valid scan segments",
+			params:        map[string]string{"scanSegments": "4;orders:32"},
+			errorExpected: false,
+		},
+		{
+			name:          "invalid scan segments",
+			params:        map[string]string{"scanSegments": "orders:0"},
+			errorExpected: true,
+		},
+		{
+			name:          "valid rcu budget",
+			params:        map[string]string{"rcuBudget": "500"},
+			errorExpected: false,
+		},
+		{
+			name:          "invalid rcu budget",
+			params:        map[string]string{"rcuBudget": "-1"},
+			errorExpected: true,
+		},
+		{
+			name:          "valid catch-all threshold",
+			params:        map[string]string{"catchAllThreshold": "0.5"},
+			errorExpected: false,
+		},
+		{
+			name:          "invalid catch-all threshold",
+			params:        map[string]string{"catchAllThreshold": "150"},
+			errorExpected: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -149,3 +399,113 @@ func TestNewSourceProfileConnectionDynamoDB(t *testing.T) {
 		assert.Equal(t, tc.errorExpected, err != nil)
 	}
 }
+
+func TestParseScanSegments(t *testing.T) {
+	segments, tableSegments, err := parseScanSegments("4;orders:32; users : 2")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), segments)
+	assert.Equal(t, map[string]int64{"orders": 32, "users": 2}, tableSegments)
+
+	for _, s := range []string{"", "x", "orders:", "0", "1000001"} {
+		_, _, err := parseScanSegments(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestNewSourceProfileShards(t *testing.T) {
+	mysql := SourceProfileConnectionMySQL{Host: "a", User: "b", Db: "s1, s2", Pwd: "e"}
+	shards, err := NewSourceProfileShards(mysql, map[string]string{"tableCollision": "prefix", "shardIdColumn": "shard"})
+	assert.Nil(t, err)
+	assert.Equal(t, "prefix", shards.TableCollision)
+	assert.Equal(t, "shard", shards.IdColumn)
+	assert.Equal(t, 2, len(shards.Shards))
+	assert.Equal(t, "s2", shards.Shards[1].Id)
+	assert.Equal(t, SourceProfileConnectionMySQL{Host: "a", User: "b", Db: "s2", Pwd: "e"}, shards.Shards[1].Mysql)
+
+	shards, err = NewSourceProfileShards(mysql, map[string]string{})
+	assert.Nil(t, err)
+	assert.Equal(t, "merge", shards.TableCollision)
+	assert.Equal(t, "shard_id", shards.IdColumn)
+	assert.Equal(t, DefaultShardParallelism, shards.Parallelism)
+
+	// A single database isn't sharded.
+	shards, err = NewSourceProfileShards(SourceProfileConnectionMySQL{Db: "s1"}, map[string]string{})
+	assert.Nil(t, err)
+	assert.Equal(t, SourceProfileShards{}, shards)
+
+	_, err = NewSourceProfileShards(mysql, map[string]string{"tableCollision": "rename"})
+	assert.NotNil(t, err)
+	_, err = NewSourceProfileShards(SourceProfileConnectionMySQL{Db: "s1,"}, map[string]string{})
+	assert.NotNil(t, err)
+	_, err = NewSourceProfileShards(mysql, map[string]string{"shardParallelism": "0"})
+	assert.NotNil(t, err)
+
+	profile := SourceProfile{Ty: SourceProfileTypeConnection, Conn: SourceProfileConnection{Ty: SourceProfileConnectionTypeMySQL, Mysql: mysql}}
+	profile.Conn.Shards, _ = NewSourceProfileShards(mysql, map[string]string{})
+	l := profile.ShardProfiles()
+	assert.Equal(t, 2, len(l))
+	assert.Equal(t, "s1", l[0].Conn.Mysql.Db)
+	assert.Equal(t, SourceProfileShards{}, l[0].Conn.Shards)
+	assert.Equal(t, "a/s1,a/s2", profile.Identifier())
+	assert.Equal(t, "a/s1", l[0].Identifier())
+}
+
+func TestSourceProfileIdentifier(t *testing.T) {
+	assert.Equal(t, "stdin", SourceProfile{Ty: SourceProfileTypeFile}.Identifier())
+	assert.Equal(t, "dump.sql", SourceProfile{Ty: SourceProfileTypeFile, File: SourceProfileFile{Path: "dump.sql"}}.Identifier())
+	pg := SourceProfile{Ty: SourceProfileTypeConnection, Conn: SourceProfileConnection{Ty: SourceProfileConnectionTypePostgreSQL, Pg: SourceProfileConnectionPostgreSQL{Host: "h", Port: "5432", User: "u", Db: "shop", Pwd: "secret"}}}
+	assert.Equal(t, "h:5432/shop", pg.Identifier())
+	dydb := SourceProfile{Ty: SourceProfileTypeConnection, Conn: SourceProfileConnection{Ty: SourceProfileConnectionTypeDynamoDB, Dydb: SourceProfileConnectionDynamoDB{AwsRegion: "us-east-1"}}}
+	assert.Equal(t, "dynamodb:us-east-1", dydb.Identifier())
+}
+
+func TestReadShardConfig(t *testing.T) {
+	write := func(cfg string) string {
+		f, err := ioutil.TempFile("", "shards*.json")
+		assert.Nil(t, err)
+		defer f.Close()
+		f.WriteString(cfg)
+		return f.Name()
+	}
+	file := write(`{
+		"TableCollision": "prefix",
+		"Parallelism": 2,
+		"Shards": [
+			{"Id": "eu", "Host": "h1", "User": "u", "Password": "p1", "DbName": "shop"},
+			{"Host": "h2", "Port": "3307", "User": "u", "Password": "p2", "DbName": "shop_us", "NamePattern": "us_*"}
+		]
+	}`)
+	defer os.Remove(file)
+	shards, err := ReadShardConfig(file)
+	assert.Nil(t, err)
+	assert.Equal(t, SourceProfileShards{
+		Shards: []SourceProfileShard{
+			{Id: "eu", Mysql: SourceProfileConnectionMySQL{Host: "h1", Port: "3306", User: "u", Db: "shop", Pwd: "p1"}},
+			{Id: "shop_us", Mysql: SourceProfileConnectionMySQL{Host: "h2", Port: "3307", User: "u", Db: "shop_us", Pwd: "p2"}, NamePattern: "us_*"},
+		},
+		TableCollision: "prefix",
+		IdColumn:       "shard_id",
+		Parallelism:    2,
+	}, shards)
+
+	conn, err := NewSourceProfileConnection("mysql", map[string]string{"shardConfig": file})
+	assert.Nil(t, err)
+	assert.Equal(t, shards.Shards[0].Mysql, conn.Mysql)
+	assert.Equal(t, 2, len(conn.Shards.Shards))
+
+	for _, cfg := range []string{
+		`{"Shards": []}`,
+		`{"Shards": [{"Host": "h1", "User": "u", "Password": "p"}]}`,
+		`{"Shards": [{"Host": "h1", "User": "u", "Password": "p", "DbName": "a"}, {"Host": "h2", "User": "u", "Password": "p", "DbName": "a"}]}`,
+		`{"TableCollision": "rename", "Shards": [{"Host": "h1", "User": "u", "Password": "p", "DbName": "a"}]}`,
+		`{"Shards": [{"Host": "h1", "User": "u", "Password": "p", "DbName": "a", "NamePattern": "billing_"}]}`,
+		`not json`,
+	} {
+		file := write(cfg)
+		defer os.Remove(file)
+		_, err := ReadShardConfig(file)
+		assert.NotNil(t, err, cfg)
+	}
+	_, err = ReadShardConfig("/does/not/exist.json")
+	assert.NotNil(t, err)
+}
@@ -17,10 +17,13 @@ package profiles
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"golang.org/x/net/context"
 	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
 )
@@ -45,6 +48,17 @@ type TargetProfileConnectionSpanner struct {
 	Instance string
 	Dbname   string
 	Dialect  string
+	Priority string // Request priority of migration writes: low, medium or high.
+	Tag      string // Request and transaction tag of migration writes.
+	Mode     string // Write mode of bulk data migration: mutation or dml.
+
+	MinSessions uint64        // Sessions opened by the Spanner client when it's created.
+	MaxSessions uint64        // Limit on the sessions open in the Spanner client.
+	Channels    int           // Number of gRPC channels of the Spanner client.
+	Keepalive   time.Duration // Interval of keepalive pings on idle gRPC channels.
+
+	RouteToLeader bool   // Route requests to the leader region of the database.
+	WorkerRegion  string // Region HarbourBridge runs in, if not detected from the metadata server.
 }
 
 type TargetProfileConnection struct {
@@ -128,6 +142,19 @@ func (targetProfile *TargetProfile) GetResourceIds(ctx context.Context, now time
 	return project, instance, dbName, err
 }
 
+// WriteOptions returns the options of the Spanner writes of data migration
+// to the target.
+func (trg TargetProfile) WriteOptions() internal.WriteOptions {
+	return internal.WriteOptions{Priority: trg.Conn.Sp.Priority, Tag: trg.Conn.Sp.Tag, Mode: trg.Conn.Sp.Mode}
+}
+
+// ClientConfig returns the configuration of the Spanner client of the
+// migration to the target.
+func (trg TargetProfile) ClientConfig() utils.ClientConfig {
+	sp := trg.Conn.Sp
+	return utils.ClientConfig{MinSessions: sp.MinSessions, MaxSessions: sp.MaxSessions, Channels: sp.Channels, Keepalive: sp.Keepalive, RouteToLeader: sp.RouteToLeader}
+}
+
 // Target profile is passed as a list of key value pairs on the command line.
 // Today we support only direct connection as a valid target profile type, but
 // in future we can support writing to CSV or AVRO as valid targets.
@@ -144,8 +171,33 @@ func (targetProfile *TargetProfile) GetResourceIds(ctx context.Context, now time
 // If dbName is not specified, then HarbourBridge will autogenerate the same
 // and create a database with the same name.
 //
+// The data migration writes to Spanner can be given a lower priority than
+// serving traffic on a shared instance with priority=low or priority=medium,
+// and tagged with tag=<tag> to tell them apart in Spanner's introspection
+// tables.
+//
+// Bulk data migration writes rows with mutations, or with batched DML INSERT
+// statements with writeMode=dml, e.g. to get the row counts of the writes
+// from Spanner.
+//
+// The Spanner client can be tuned for high-latency links or a high write
+// parallelism (see -write-limit): minSessions and maxSessions bound its
+// session pool, grpcChannels sets its number of gRPC channels and keepalive
+// (a duration, e.g. 1m) the interval of keepalive pings on idle channels.
+// Writes wait for a session when maxSessions are in use.
+//
+// The Spanner API endpoint can be set with endpoint, like with the
+// SPANNER_API_ENDPOINT environment variable, and routeToLeader=true routes
+// requests to the leader region of the database. Before migrating, the region
+// HarbourBridge runs in (detected on Google Cloud, or set with workerRegion) is
+// checked against the leader region, since every write then crosses regions.
+//
 // Example: -target-profile="instance=my-instance1,dbName=my-new-db1"
 // Example: -target-profile="instance=my-instance1,dbName=my-new-db1,dialect=PostgreSQL"
+// Example: -target-profile="instance=my-instance1,dbName=my-new-db1,priority=low,tag=migration"
+// Example: -target-profile="instance=my-instance1,dbName=my-new-db1,writeMode=dml"
+// Example: -target-profile="instance=my-instance1,dbName=my-new-db1,maxSessions=1000,grpcChannels=8,keepalive=1m"
+// Example: -target-profile="instance=my-instance1,dbName=my-new-db1,routeToLeader=true,workerRegion=us-east4"
 //
 func NewTargetProfile(s string) (TargetProfile, error) {
 	params, err := parseProfile(s)
@@ -156,6 +208,7 @@ func NewTargetProfile(s string) (TargetProfile, error) {
 	sp := TargetProfileConnectionSpanner{}
 	if endpoint, ok := params["endpoint"]; ok {
 		sp.Endpoint = endpoint
+		os.Setenv("SPANNER_API_ENDPOINT", endpoint)
 	}
 	if project, ok := params["project"]; ok {
 		sp.Project = project
@@ -169,7 +222,74 @@ func NewTargetProfile(s string) (TargetProfile, error) {
 	if dialect, ok := params["dialect"]; ok {
 		sp.Dialect = dialect
 	}
+	if priority, ok := params["priority"]; ok {
+		sp.Priority = strings.ToLower(priority)
+		if !utils.ValidPriority(sp.Priority) {
+			return TargetProfile{}, fmt.Errorf("invalid priority %s, accepted values are: low, medium, high", priority)
+		}
+	}
+	if tag, ok := params["tag"]; ok {
+		if err := utils.ValidateTag(tag); err != nil {
+			return TargetProfile{}, err
+		}
+		sp.Tag = tag
+	}
+	if mode, ok := params["writeMode"]; ok {
+		sp.Mode = strings.ToLower(mode)
+		if sp.Mode != internal.WriteModeMutation && sp.Mode != internal.WriteModeDML {
+			return TargetProfile{}, fmt.Errorf("invalid writeMode %s, accepted values are: mutation, dml", mode)
+		}
+	}
+	if routeToLeader, ok := params["routeToLeader"]; ok {
+		switch routeToLeader {
+		case "yes", "true":
+			sp.RouteToLeader = true
+		case "no", "false":
+			sp.RouteToLeader = false
+		default:
+			return TargetProfile{}, fmt.Errorf("please specify a valid choice for routeToLeader: available choices(yes, no, true, false)")
+		}
+	}
+	if region, ok := params["workerRegion"]; ok {
+		sp.WorkerRegion = region
+	}
+	if err := parseClientConfig(params, &sp); err != nil {
+		return TargetProfile{}, err
+	}
 
 	conn := TargetProfileConnection{Ty: TargetProfileConnectionTypeSpanner, Sp: sp}
 	return TargetProfile{Ty: TargetProfileTypeConnection, Conn: conn}, nil
 }
+
+// minKeepalive is the shortest keepalive interval accepted: gRPC doesn't ping
+// more often.
+const minKeepalive = 10 * time.Second
+
+// parseClientConfig parses the Spanner client configuration in params into sp.
+func parseClientConfig(params map[string]string, sp *TargetProfileConnectionSpanner) error {
+	var err error
+	if v, ok := params["minSessions"]; ok {
+		if sp.MinSessions, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return fmt.Errorf("invalid minSessions %s, expected a number of sessions", v)
+		}
+	}
+	if v, ok := params["maxSessions"]; ok {
+		if sp.MaxSessions, err = strconv.ParseUint(v, 10, 64); err != nil || sp.MaxSessions == 0 {
+			return fmt.Errorf("invalid maxSessions %s, expected a positive number of sessions", v)
+		}
+	}
+	if sp.MaxSessions > 0 && sp.MinSessions > sp.MaxSessions {
+		return fmt.Errorf("minSessions %d is greater than maxSessions %d", sp.MinSessions, sp.MaxSessions)
+	}
+	if v, ok := params["grpcChannels"]; ok {
+		if sp.Channels, err = strconv.Atoi(v); err != nil || sp.Channels <= 0 {
+			return fmt.Errorf("invalid grpcChannels %s, expected a positive number of channels", v)
+		}
+	}
+	if v, ok := params["keepalive"]; ok {
+		if sp.Keepalive, err = time.ParseDuration(v); err != nil || sp.Keepalive < minKeepalive {
+			return fmt.Errorf("invalid keepalive %s, expected a duration of at least %v, e.g. 1m", v, minKeepalive)
+		}
+	}
+	return nil
+}
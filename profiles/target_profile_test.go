@@ -0,0 +1,155 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiles
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+func TestNewTargetProfileWriteOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		profile string
+		wantErr bool
+		want    internal.WriteOptions
+	}{
+		{
+			name:    "no write options",
+			profile: "instance=test-instance,dbName=test-db",
+			want:    internal.WriteOptions{},
+		},
+		{
+			name:    "low priority and tag",
+			profile: "instance=test-instance,priority=low,tag=harbourbridge-migration",
+			want:    internal.WriteOptions{Priority: "low", Tag: "harbourbridge-migration"},
+		},
+		{
+			name:    "priority is case insensitive",
+			profile: "priority=MEDIUM",
+			want:    internal.WriteOptions{Priority: "medium"},
+		},
+		{
+			name:    "invalid priority",
+			profile: "priority=lowest",
+			wantErr: true,
+		},
+		{
+			name:    "tag too long",
+			profile: "tag=" + strings.Repeat("a", 51),
+			wantErr: true,
+		},
+		{
+			name:    "tag not printable",
+			profile: "tag=migration\t1",
+			wantErr: true,
+		},
+		{
+			name:    "dml write mode",
+			profile: "writeMode=DML",
+			want:    internal.WriteOptions{Mode: internal.WriteModeDML},
+		},
+		{
+			name:    "invalid write mode",
+			profile: "writeMode=copy",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		targetProfile, err := NewTargetProfile(tc.profile)
+		assert.Equal(t, tc.wantErr, err != nil, tc.name)
+		if err == nil {
+			assert.Equal(t, tc.want, targetProfile.WriteOptions(), tc.name)
+		}
+	}
+}
+
+func TestNewTargetProfileClientConfig(t *testing.T) {
+	testCases := []struct {
+		name    string
+		profile string
+		wantErr bool
+		want    utils.ClientConfig
+	}{
+		{
+			name:    "default client",
+			profile: "instance=test-instance,dbName=test-db",
+			want:    utils.ClientConfig{},
+		},
+		{
+			name:    "sessions, channels and keepalive",
+			profile: "minSessions=200,maxSessions=1000,grpcChannels=10,keepalive=1m",
+			want:    utils.ClientConfig{MinSessions: 200, MaxSessions: 1000, Channels: 10, Keepalive: time.Minute},
+		},
+		{
+			name:    "route to leader",
+			profile: "routeToLeader=yes,workerRegion=us-east4",
+			want:    utils.ClientConfig{RouteToLeader: true},
+		},
+		{
+			name:    "invalid route to leader",
+			profile: "routeToLeader=leader",
+			wantErr: true,
+		},
+		{
+			name:    "max sessions only",
+			profile: "maxSessions=50",
+			want:    utils.ClientConfig{MaxSessions: 50},
+		},
+		{
+			name:    "min sessions above max sessions",
+			profile: "minSessions=500,maxSessions=100",
+			wantErr: true,
+		},
+		{
+			name:    "zero max sessions",
+			profile: "maxSessions=0",
+			wantErr: true,
+		},
+		{
+			name:    "negative min sessions",
+			profile: "minSessions=-1",
+			wantErr: true,
+		},
+		{
+			name:    "zero channels",
+			profile: "grpcChannels=0",
+			wantErr: true,
+		},
+		{
+			name:    "keepalive without unit",
+			profile: "keepalive=60",
+			wantErr: true,
+		},
+		{
+			name:    "keepalive too short",
+			profile: "keepalive=1s",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		targetProfile, err := NewTargetProfile(tc.profile)
+		assert.Equal(t, tc.wantErr, err != nil, tc.name)
+		if err == nil {
+			assert.Equal(t, tc.want, targetProfile.ClientConfig(), tc.name)
+		}
+	}
+}
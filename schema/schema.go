@@ -46,11 +46,21 @@ type Table struct {
 // Column represents a database column.
 // TODO: add support for foreign keys.
 type Column struct {
-	Name    string
-	Type    Type
-	NotNull bool
-	Ignored Ignored
-	Id      string
+	Name      string
+	Type      Type
+	NotNull   bool
+	Ignored   Ignored
+	Id        string
+	Sequence  *Sequence // Source sequence generating the column's default values, if any.
+	Collation string    // Collation of string columns, e.g. utf8mb4_0900_ai_ci, if known.
+}
+
+// Sequence represents a source sequence generating the values of a column,
+// e.g. of a PostgreSQL serial or identity column, or a SQL Server identity
+// column.
+type Sequence struct {
+	Name      string // Empty for identity columns that aren't backed by a named sequence.
+	LastValue int64  // Largest value generated by the sequence or stored in the column.
 }
 
 // ForeignKey represents a foreign key.
@@ -98,8 +108,12 @@ type Index struct {
 // Type represents the type of a column.
 type Type struct {
 	Name        string
-	Mods        []int64 // List of modifiers (aka type parameters e.g. varchar(8) or numeric(6, 4).
-	ArrayBounds []int64 // Empty for scalar types.
+	Mods        []int64  // List of modifiers (aka type parameters e.g. varchar(8) or numeric(6, 4).
+	ArrayBounds []int64  // Empty for scalar types.
+	Values      []string // Allowed values of enumeration types, e.g. MySQL ENUM and SET.
+	UserDefined string   // Name of the user-defined type, e.g. a PostgreSQL domain or composite type, if any.
+	Checks      []string // CHECK constraints of domains, as boolean expressions on VALUE. Name is the base type of the domain.
+	Fields      []string // Field names of composite types.
 }
 
 // Ignored represents column properties/constraints that are not
@@ -117,7 +131,9 @@ type Ignored struct {
 // Print converts ty to a string suitable for printing.
 func (ty Type) Print() string {
 	s := ty.Name
-	if len(ty.Mods) > 0 {
+	if ty.UserDefined != "" {
+		s = ty.UserDefined
+	} else if len(ty.Mods) > 0 {
 		var l []string
 		for _, x := range ty.Mods {
 			l = append(l, strconv.FormatInt(x, 10))
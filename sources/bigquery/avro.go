@@ -0,0 +1,255 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/linkedin/goavro/v2"
+)
+
+// avroSchema is the Avro schema of the rows returned by the BigQuery Storage
+// Read API, which encodes each row as an Avro record. Rows are decoded by
+// goavro, whose values are then converted as described by t.
+type avroSchema struct {
+	codec *goavro.Codec
+	t     *avroType
+}
+
+// avroType is an Avro type, with the attributes needed to convert the values
+// goavro decodes.
+type avroType struct {
+	kind     string      // Avro type e.g. long, record or union.
+	name     string      // Full name of named types.
+	logical  string      // Logical type e.g. timestamp-micros, if any.
+	sqlType  string      // BigQuery type of strings e.g. DATETIME or JSON, if any.
+	fields   []avroField // Fields of records.
+	items    *avroType   // Items of arrays and values of maps.
+	branches []*avroType // Branches of unions.
+}
+
+type avroField struct {
+	name string
+	t    *avroType
+}
+
+// parseAvroSchema parses the Avro schema s, in JSON, of a record.
+func parseAvroSchema(s string) (*avroSchema, error) {
+	codec, err := goavro.NewCodec(s)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse Avro schema: %w", err)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("can't parse Avro schema: %w", err)
+	}
+	t, err := parseAvroType(v, "", make(map[string]*avroType))
+	if err != nil {
+		return nil, err
+	}
+	if t.kind != "record" {
+		return nil, fmt.Errorf("expected a record, got %s", t.kind)
+	}
+	return &avroSchema{codec: codec, t: t}, nil
+}
+
+// parseAvroType parses Avro type v, in namespace ns, where named are the
+// named types defined so far.
+func parseAvroType(v interface{}, ns string, named map[string]*avroType) (*avroType, error) {
+	switch v := v.(type) {
+	case string:
+		switch v {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroType{kind: v}, nil
+		}
+		if t, ok := named[v]; ok {
+			return t, nil
+		}
+		if t, ok := named[ns+"."+v]; ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("unknown Avro type %s", v)
+	case []interface{}:
+		t := &avroType{kind: "union"}
+		for _, b := range v {
+			bt, err := parseAvroType(b, ns, named)
+			if err != nil {
+				return nil, err
+			}
+			t.branches = append(t.branches, bt)
+		}
+		return t, nil
+	case map[string]interface{}:
+		kind, _ := v["type"].(string)
+		t := &avroType{kind: kind}
+		t.logical, _ = v["logicalType"].(string)
+		t.sqlType, _ = v["sqlType"].(string)
+		if name, ok := v["name"].(string); ok {
+			if n, ok := v["namespace"].(string); ok {
+				ns = n
+			}
+			if ns != "" && !strings.Contains(name, ".") {
+				name = ns + "." + name
+			}
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				ns = name[:i]
+			}
+			t.name = name
+			named[name] = t
+		}
+		switch kind {
+		case "record":
+			fields, _ := v["fields"].([]interface{})
+			for _, f := range fields {
+				f, _ := f.(map[string]interface{})
+				name, _ := f["name"].(string)
+				ft, err := parseAvroType(f["type"], ns, named)
+				if err != nil {
+					return nil, err
+				}
+				t.fields = append(t.fields, avroField{name: name, t: ft})
+			}
+		case "array", "map":
+			key := "items"
+			if kind == "map" {
+				key = "values"
+			}
+			items, err := parseAvroType(v[key], ns, named)
+			if err != nil {
+				return nil, err
+			}
+			t.items = items
+		case "enum", "fixed":
+		default:
+			// A primitive type with attributes e.g. a logical type.
+			p, err := parseAvroType(kind, ns, named)
+			if err != nil {
+				return nil, err
+			}
+			t.kind = p.kind
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("invalid Avro type %v", v)
+}
+
+// decode decodes the row at the start of b, and returns it along with the
+// rest of b.
+func (s *avroSchema) decode(b []byte) (map[string]interface{}, []byte, error) {
+	v, rest, err := s.codec.NativeFromBinary(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	row, err := avroValue(s.t, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return row.(map[string]interface{}), rest, nil
+}
+
+// avroValue converts v, a value of type t decoded by goavro, to:
+//   - nil for nulls,
+//   - bool, int64, float64, []byte and string for primitive types,
+//   - civil.Date, time.Time, civil.Time and *big.Rat for the date,
+//     timestamp-micros, time-micros and decimal logical types,
+//   - json.RawMessage for BigQuery JSON strings,
+//   - map[string]interface{} for records and maps, and []interface{} for
+//     arrays.
+func avroValue(t *avroType, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch t.kind {
+	case "union":
+		// goavro decodes non-null values of unions as a map from the name of
+		// their branch to the value.
+		m, ok := v.(map[string]interface{})
+		if !ok || len(m) != 1 {
+			return nil, fmt.Errorf("invalid union value %v", v)
+		}
+		for name, bv := range m {
+			for _, b := range t.branches {
+				if b.name == name || b.name == "" && (b.kind == name || strings.HasPrefix(name, b.kind+".")) {
+					return avroValue(b, bv)
+				}
+			}
+			return nil, fmt.Errorf("unknown union branch %s", name)
+		}
+	case "record":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid record value %v", v)
+		}
+		row := make(map[string]interface{}, len(t.fields))
+		for _, f := range t.fields {
+			fv, err := avroValue(f.t, m[f.name])
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.name, err)
+			}
+			row[f.name] = fv
+		}
+		return row, nil
+	case "array":
+		l, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid array value %v", v)
+		}
+		vals := make([]interface{}, len(l))
+		for i, e := range l {
+			ev, err := avroValue(t.items, e)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = ev
+		}
+		return vals, nil
+	case "map":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid map value %v", v)
+		}
+		vals := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			ev, err := avroValue(t.items, e)
+			if err != nil {
+				return nil, err
+			}
+			vals[k] = ev
+		}
+		return vals, nil
+	}
+	switch v := v.(type) {
+	case int32:
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	case string:
+		if t.sqlType == "JSON" {
+			return json.RawMessage(v), nil
+		}
+	case time.Time:
+		if t.logical == "date" {
+			return civil.DateOf(v), nil
+		}
+		return v.UTC(), nil
+	case time.Duration:
+		return civil.TimeOf(time.Unix(0, 0).Add(v).UTC()), nil
+	}
+	return v, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAvroSchema is the Avro schema of the rows of table test, as returned
+// by the BigQuery Storage Read API.
+const testAvroSchema = `{
+  "type": "record",
+  "name": "__root__",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "name", "type": ["null", "string"]},
+    {"name": "amount", "type": ["null", {"type": "bytes", "logicalType": "decimal", "precision": 38, "scale": 9}]},
+    {"name": "created", "type": ["null", {"type": "long", "logicalType": "timestamp-micros"}]},
+    {"name": "day", "type": ["null", {"type": "int", "logicalType": "date"}]},
+    {"name": "dt", "type": ["null", {"type": "string", "logicalType": "datetime"}]},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "attrs", "type": ["null", {"type": "record", "name": "__attrs", "fields": [{"name": "k", "type": ["null", "string"]}, {"name": "n", "type": ["null", "double"]}]}]},
+    {"name": "doc", "type": ["null", {"type": "string", "sqlType": "JSON"}]}
+  ]
+}`
+
+// testAvroRow returns the Avro encoding of a row of table test: all its
+// nullable fields are null if null is true.
+func testAvroRow(id int64, null bool) []byte {
+	codec, err := goavro.NewCodec(testAvroSchema)
+	if err != nil {
+		panic(err)
+	}
+	row := map[string]interface{}{"id": id, "name": nil, "amount": nil, "created": nil, "day": nil, "dt": nil, "tags": []interface{}{}, "attrs": nil, "doc": nil}
+	if !null {
+		row["name"] = goavro.Union("string", "ab")
+		row["amount"] = goavro.Union("bytes.decimal", big.NewRat(1234, 100))
+		row["created"] = goavro.Union("long.timestamp-micros", time.Date(2022, 1, 8, 0, 0, 0, 123456000, time.UTC))
+		row["day"] = goavro.Union("int.date", time.Date(2022, 1, 8, 0, 0, 0, 0, time.UTC))
+		row["dt"] = goavro.Union("string", "2022-01-08T10:30:00")
+		row["tags"] = []interface{}{"x", "y"}
+		row["attrs"] = goavro.Union("__attrs", map[string]interface{}{"k": goavro.Union("string", "v"), "n": goavro.Union("double", 1.5)})
+		row["doc"] = goavro.Union("string", `{"a":[1,2]}`)
+	}
+	b, err := codec.BinaryFromNative(nil, row)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestAvroDecoder(t *testing.T) {
+	s, err := parseAvroSchema(testAvroSchema)
+	assert.Nil(t, err)
+	b := append(testAvroRow(-3, false), testAvroRow(70, true)...)
+	row, b, err := s.decode(b)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":      int64(-3),
+		"name":    "ab",
+		"amount":  big.NewRat(1234, 100),
+		"created": time.Date(2022, 1, 8, 0, 0, 0, 123456000, time.UTC),
+		"day":     civil.Date{Year: 2022, Month: 1, Day: 8},
+		"dt":      "2022-01-08T10:30:00",
+		"tags":    []interface{}{"x", "y"},
+		"attrs":   map[string]interface{}{"k": "v", "n": 1.5},
+		"doc":     json.RawMessage(`{"a":[1,2]}`),
+	}, row)
+	row, b, err = s.decode(b)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":      int64(70),
+		"name":    nil,
+		"amount":  nil,
+		"created": nil,
+		"day":     nil,
+		"dt":      nil,
+		"tags":    []interface{}{},
+		"attrs":   nil,
+		"doc":     nil,
+	}, row)
+	assert.Empty(t, b)
+	_, _, err = s.decode(b)
+	assert.NotNil(t, err)
+}
+
+func TestParseAvroSchema_Invalid(t *testing.T) {
+	_, err := parseAvroSchema(`{"type": "record", "fields": [{"name": "a", "type": "nosuchtype"}]}`)
+	assert.NotNil(t, err)
+	_, err = parseAvroSchema(`not json`)
+	assert.NotNil(t, err)
+	_, err = parseAvroSchema(`"long"`)
+	assert.NotNil(t, err)
+}
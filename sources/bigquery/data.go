@@ -0,0 +1,402 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// maxReadRetries is the number of times reading a stream is resumed after a
+// transient error.
+const maxReadRetries = 3
+
+// ProcessData reads the rows of srcTable with the BigQuery Storage Read API
+// and writes them to Spanner. The table is read in Avro format, with up to
+// ReadStreams streams in parallel.
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	session, err := isi.ReadClient.CreateReadSession(isi.withParams(ctx, "read_session.table", isi.tablePath(srcTable)), &storagepb.CreateReadSessionRequest{
+		Parent: "projects/" + isi.Project,
+		ReadSession: &storagepb.ReadSession{
+			Table:       isi.tablePath(srcTable),
+			DataFormat:  storagepb.DataFormat_AVRO,
+			ReadOptions: &storagepb.ReadSession_TableReadOptions{SelectedFields: srcSchema.ColNames},
+		},
+		MaxStreamCount: int32(isi.ReadStreams),
+	})
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't create read session for table %s : err = %s", srcTable, err))
+		return err
+	}
+	t, err := parseAvroSchema(session.GetAvroSchema().GetSchema())
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't parse Avro schema of table %s : err = %s", srcTable, err))
+		return err
+	}
+	// Rows of all streams are converted and written one at a time, as conv
+	// isn't safe for concurrent use.
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make([]error, len(session.GetStreams()))
+	for i, stream := range session.GetStreams() {
+		wg.Add(1)
+		go func(i int, stream string) {
+			defer wg.Done()
+			errs[i] = isi.readStream(ctx, stream, t, func(rows []map[string]interface{}) {
+				mu.Lock()
+				for _, row := range rows {
+					ProcessDataRow(row, conv, srcTable, srcSchema, spTable, spCols, spSchema)
+				}
+				mu.Unlock()
+				// Responses are checkpoints: pause before reading the next
+				// one outside the migration windows.
+				conv.Schedule.Wait(ctx)
+			})
+		}(i, stream.GetName())
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
+			return err
+		}
+	}
+	return nil
+}
+
+// readStream reads the rows of stream, whose Avro schema is t, calling f for
+// the rows of each response. Reading is resumed from the last row read after transient
+// errors.
+func (isi InfoSchemaImpl) readStream(ctx context.Context, stream string, t *avroSchema, f func(rows []map[string]interface{})) error {
+	var offset int64
+	for retries := 0; ; {
+		rows, err := isi.ReadClient.ReadRows(isi.withParams(ctx, "read_stream", stream), &storagepb.ReadRowsRequest{ReadStream: stream, Offset: offset})
+		for err == nil {
+			var resp *storagepb.ReadRowsResponse
+			if resp, err = rows.Recv(); err != nil {
+				break
+			}
+			b := resp.GetAvroRows().GetSerializedBinaryRows()
+			var batch []map[string]interface{}
+			for n := int64(0); n < resp.GetRowCount(); n++ {
+				var row map[string]interface{}
+				if row, b, err = t.decode(b); err != nil {
+					return fmt.Errorf("can't decode row %d of stream %s: %w", offset+n, stream, err)
+				}
+				batch = append(batch, row)
+			}
+			f(batch)
+			offset += int64(len(batch))
+			retries = 0
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if retries++; retries > maxReadRetries || !retryable(err) {
+			return err
+		}
+		time.Sleep(time.Duration(retries) * time.Second)
+	}
+}
+
+// retryable returns true for errors after which reading a stream can be
+// resumed.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Internal, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+func (isi InfoSchemaImpl) tablePath(table string) string {
+	return fmt.Sprintf("projects/%s/datasets/%s/tables/%s", isi.Project, isi.Dataset, table)
+}
+
+// withParams adds the routing header the Storage Read API requires to ctx.
+func (isi InfoSchemaImpl) withParams(ctx context.Context, key, value string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "x-goog-request-params", key+"="+value)
+}
+
+// ProcessDataRow converts the values of row, read from srcTable, and writes
+// them to spTable.
+func ProcessDataRow(row map[string]interface{}, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) {
+	start := time.Now()
+	spVals, badCols, srcStrVals := cvtRow(conv, row, srcSchema, spSchema, spCols)
+	conv.Timings.TimeTable(internal.StageConvert, srcTable, start)
+	if len(badCols) == 0 {
+		conv.WriteRow(srcTable, spTable, spCols, spVals)
+	} else {
+		conv.Unexpected(fmt.Sprintf("Data conversion error for table %s in column(s) %s\n", srcTable, badCols))
+		conv.StatsAddBadRow(srcTable, conv.DataMode())
+		conv.CollectBadRow(srcTable, srcSchema.ColNames, srcStrVals)
+	}
+}
+
+func cvtRow(conv *internal.Conv, row map[string]interface{}, srcSchema schema.Table, spSchema ddl.CreateTable, spCols []string) ([]interface{}, []string, []string) {
+	var srcStrVals []string
+	var spVals []interface{}
+	var badCols []string
+	for i, srcCol := range srcSchema.ColNames {
+		v := row[srcCol]
+		var spVal interface{}
+		var err error
+		if v != nil {
+			spType := spSchema.ColDefs[spCols[i]].T
+			if l, ok := v.([]interface{}); ok && spType.IsArray {
+				spVal, err = convArray(conv, l, spType.Name)
+			} else {
+				spVal, err = convScalar(conv, v, spType.Name)
+			}
+			if err != nil {
+				badCols = append(badCols, srcCol)
+			}
+		}
+		srcStrVals = append(srcStrVals, srcString(v))
+		spVals = append(spVals, spVal)
+	}
+	return spVals, badCols, srcStrVals
+}
+
+// convScalar converts v, as decoded from Avro, to a value of Spanner type
+// spType.
+func convScalar(conv *internal.Conv, v interface{}, spType string) (interface{}, error) {
+	switch spType {
+	case ddl.Bool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case ddl.Int64:
+		switch v := v.(type) {
+		case int64:
+			return v, nil
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+	case ddl.Float64:
+		switch v := v.(type) {
+		case float64:
+			return v, nil
+		case *big.Rat:
+			f, _ := v.Float64()
+			return f, nil
+		}
+	case ddl.Numeric:
+		var r *big.Rat
+		switch v := v.(type) {
+		case *big.Rat:
+			r = v
+		case int64:
+			r = new(big.Rat).SetInt64(v)
+		}
+		if r != nil {
+			if conv.TargetDb == constants.TargetExperimentalPostgres {
+				return spanner.PGNumeric{Numeric: decimalString(r), Valid: true}, nil
+			}
+			return r, nil
+		}
+	case ddl.Date:
+		if d, ok := v.(civil.Date); ok {
+			return d, nil
+		}
+	case ddl.Timestamp:
+		switch v := v.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			// DATETIME values have no time zone: they are interpreted in
+			// the time zone of the conversion.
+			dt, err := civil.ParseDateTime(v)
+			if err != nil {
+				return nil, fmt.Errorf("can't convert %q to DATETIME: %w", v, err)
+			}
+			return dt.In(conv.Location), nil
+		}
+	case ddl.Bytes:
+		switch v := v.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	case ddl.JSON:
+		return jsonString(v)
+	case ddl.String:
+		switch v.(type) {
+		case map[string]interface{}, []interface{}, json.RawMessage:
+			return jsonString(v)
+		}
+		return srcString(v), nil
+	}
+	return nil, fmt.Errorf("can't convert value %v of type %T to Spanner type %s", v, v, spType)
+}
+
+// convArray converts the elements of l, the value of a repeated field, to
+// an array of Spanner type spType.
+func convArray(conv *internal.Conv, l []interface{}, spType string) (interface{}, error) {
+	vals := make([]interface{}, len(l))
+	for i, v := range l {
+		var err error
+		if vals[i], err = convScalar(conv, v, spType); err != nil {
+			return nil, err
+		}
+	}
+	switch spType {
+	case ddl.Bool:
+		r := make([]spanner.NullBool, len(vals))
+		for i, v := range vals {
+			r[i] = spanner.NullBool{Bool: v.(bool), Valid: true}
+		}
+		return r, nil
+	case ddl.Int64:
+		r := make([]spanner.NullInt64, len(vals))
+		for i, v := range vals {
+			r[i] = spanner.NullInt64{Int64: v.(int64), Valid: true}
+		}
+		return r, nil
+	case ddl.Float64:
+		r := make([]spanner.NullFloat64, len(vals))
+		for i, v := range vals {
+			r[i] = spanner.NullFloat64{Float64: v.(float64), Valid: true}
+		}
+		return r, nil
+	case ddl.Numeric:
+		r := make([]spanner.NullNumeric, len(vals))
+		for i, v := range vals {
+			r[i] = spanner.NullNumeric{Numeric: *v.(*big.Rat), Valid: true}
+		}
+		return r, nil
+	case ddl.Date:
+		r := make([]spanner.NullDate, len(vals))
+		for i, v := range vals {
+			r[i] = spanner.NullDate{Date: v.(civil.Date), Valid: true}
+		}
+		return r, nil
+	case ddl.Timestamp:
+		r := make([]spanner.NullTime, len(vals))
+		for i, v := range vals {
+			r[i] = spanner.NullTime{Time: v.(time.Time), Valid: true}
+		}
+		return r, nil
+	case ddl.Bytes:
+		r := make([][]byte, len(vals))
+		for i, v := range vals {
+			r[i] = v.([]byte)
+		}
+		return r, nil
+	case ddl.String:
+		r := make([]spanner.NullString, len(vals))
+		for i, v := range vals {
+			r[i] = spanner.NullString{StringVal: v.(string), Valid: true}
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("can't convert array to Spanner type %s", spType)
+}
+
+// jsonString returns v as a JSON document. Numeric values keep their
+// precision.
+func jsonString(v interface{}) (string, error) {
+	if m, ok := v.(json.RawMessage); ok {
+		if !json.Valid(m) {
+			return "", fmt.Errorf("can't convert %q to json", m)
+		}
+		return string(m), nil
+	}
+	b, err := json.Marshal(jsonValue(v))
+	if err != nil {
+		return "", fmt.Errorf("can't convert %v to json: %w", v, err)
+	}
+	return string(b), nil
+}
+
+// jsonValue returns v with numeric, date and time values replaced by their
+// BigQuery JSON encoding.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = jsonValue(e)
+		}
+		return l
+	case *big.Rat:
+		return json.Number(decimalString(v))
+	case civil.Date, civil.Time, time.Time:
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+// decimalString formats r, a NUMERIC or BIGNUMERIC value, with as many
+// decimals as needed to represent it exactly.
+func decimalString(r *big.Rat) string {
+	p := big.NewInt(1)
+	ten := big.NewInt(10)
+	for scale := 0; scale < 76; scale++ {
+		if new(big.Int).Mod(p, r.Denom()).Sign() == 0 {
+			return r.FloatString(scale)
+		}
+		p.Mul(p, ten)
+	}
+	return r.FloatString(76)
+}
+
+// srcString returns v formatted as a source value, for reports of bad rows.
+func srcString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case *big.Rat:
+		return decimalString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case map[string]interface{}, []interface{}, json.RawMessage:
+		if s, err := jsonString(v); err == nil {
+			return s
+		}
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,226 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func init() {
+	logger.Log = zap.NewNop()
+}
+
+// fakeReadClient serves the rows of table test, one per response, from
+// streams s1 and s2. Reading s2 fails with a transient error once, after
+// its first row.
+type fakeReadClient struct {
+	storagepb.BigQueryReadClient
+	mu      sync.Mutex
+	rows    map[string][][]byte
+	failed  bool
+	session *storagepb.CreateReadSessionRequest
+}
+
+func (c *fakeReadClient) CreateReadSession(ctx context.Context, req *storagepb.CreateReadSessionRequest, opts ...grpc.CallOption) (*storagepb.ReadSession, error) {
+	c.session = req
+	return &storagepb.ReadSession{
+		Schema:  &storagepb.ReadSession_AvroSchema{AvroSchema: &storagepb.AvroSchema{Schema: testAvroSchema}},
+		Streams: []*storagepb.ReadStream{{Name: "s1"}, {Name: "s2"}},
+	}, nil
+}
+
+func (c *fakeReadClient) ReadRows(ctx context.Context, req *storagepb.ReadRowsRequest, opts ...grpc.CallOption) (storagepb.BigQueryRead_ReadRowsClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := &fakeRowsStream{rows: c.rows[req.ReadStream][req.Offset:]}
+	if req.ReadStream == "s2" && !c.failed {
+		c.failed = true
+		s.rows, s.err = s.rows[:1], status.Error(codes.Unavailable, "unavailable")
+	}
+	return s, nil
+}
+
+type fakeRowsStream struct {
+	grpc.ClientStream
+	rows [][]byte
+	err  error
+}
+
+func (s *fakeRowsStream) Recv() (*storagepb.ReadRowsResponse, error) {
+	if len(s.rows) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return &storagepb.ReadRowsResponse{
+		Rows:     &storagepb.ReadRowsResponse_AvroRows{AvroRows: &storagepb.AvroRows{SerializedBinaryRows: row}},
+		RowCount: 1,
+	}, nil
+}
+
+func testSchemas() (schema.Table, ddl.CreateTable) {
+	cols := []string{"id", "name", "amount", "created", "day", "dt", "tags", "attrs", "doc"}
+	srcSchema := schema.Table{
+		Name:     "test",
+		ColNames: cols,
+		ColDefs: map[string]schema.Column{
+			"id":      {Name: "id", Type: schema.Type{Name: "INTEGER"}, NotNull: true},
+			"name":    {Name: "name", Type: schema.Type{Name: "STRING"}},
+			"amount":  {Name: "amount", Type: schema.Type{Name: "NUMERIC"}},
+			"created": {Name: "created", Type: schema.Type{Name: "TIMESTAMP"}},
+			"day":     {Name: "day", Type: schema.Type{Name: "DATE"}},
+			"dt":      {Name: "dt", Type: schema.Type{Name: "DATETIME"}},
+			"tags":    {Name: "tags", Type: schema.Type{Name: "STRING", ArrayBounds: []int64{-1}}},
+			"attrs":   {Name: "attrs", Type: schema.Type{Name: "RECORD"}},
+			"doc":     {Name: "doc", Type: schema.Type{Name: "JSON"}},
+		},
+	}
+	spSchema := ddl.CreateTable{
+		Name:     "test",
+		ColNames: cols,
+		ColDefs: map[string]ddl.ColumnDef{
+			"id":      {Name: "id", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+			"name":    {Name: "name", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"amount":  {Name: "amount", T: ddl.Type{Name: ddl.Numeric}},
+			"created": {Name: "created", T: ddl.Type{Name: ddl.Timestamp}},
+			"day":     {Name: "day", T: ddl.Type{Name: ddl.Date}},
+			"dt":      {Name: "dt", T: ddl.Type{Name: ddl.Timestamp}},
+			"tags":    {Name: "tags", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}},
+			"attrs":   {Name: "attrs", T: ddl.Type{Name: ddl.JSON}},
+			"doc":     {Name: "doc", T: ddl.Type{Name: ddl.JSON}},
+		},
+	}
+	return srcSchema, spSchema
+}
+
+func TestProcessData(t *testing.T) {
+	srcSchema, spSchema := testSchemas()
+	conv := internal.MakeConv()
+	conv.SetDataMode()
+	conv.SetLocation(time.UTC)
+	conv.SrcSchema["test"] = srcSchema
+	conv.SpSchema["test"] = spSchema
+	var ids []int64
+	var rows [][]interface{}
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		ids = append(ids, vals[0].(int64))
+		rows = append(rows, vals)
+	})
+	client := &fakeReadClient{rows: map[string][][]byte{
+		"s1": {testAvroRow(1, false), testAvroRow(2, true)},
+		"s2": {testAvroRow(3, true), testAvroRow(4, true), testAvroRow(5, true)},
+	}}
+	isi := InfoSchemaImpl{ReadClient: client, Project: "p", Dataset: "d", ReadStreams: 4}
+	assert.Nil(t, isi.ProcessData(context.Background(), conv, "test", srcSchema, "test", srcSchema.ColNames, spSchema))
+
+	assert.Equal(t, "projects/p", client.session.Parent)
+	assert.Equal(t, "projects/p/datasets/d/tables/test", client.session.ReadSession.Table)
+	assert.Equal(t, srcSchema.ColNames, client.session.ReadSession.ReadOptions.SelectedFields)
+	assert.Equal(t, int32(4), client.session.MaxStreamCount)
+	// Reading s2 was resumed after its first row.
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, ids)
+	for _, row := range rows {
+		if row[0] == int64(1) {
+			assert.Equal(t, []interface{}{
+				int64(1),
+				"ab",
+				big.NewRat(1234, 100),
+				time.Date(2022, 1, 8, 0, 0, 0, 123456000, time.UTC),
+				civil.Date{Year: 2022, Month: 1, Day: 8},
+				time.Date(2022, 1, 8, 10, 30, 0, 0, time.UTC),
+				[]spanner.NullString{{StringVal: "x", Valid: true}, {StringVal: "y", Valid: true}},
+				`{"k":"v","n":1.5}`,
+				`{"a":[1,2]}`,
+			}, row)
+		}
+		if row[0] == int64(2) {
+			assert.Equal(t, []interface{}{int64(2), nil, nil, nil, nil, nil, []spanner.NullString{}, nil, nil}, row)
+		}
+	}
+	assert.Equal(t, int64(0), conv.BadRows())
+}
+
+func TestConvScalar(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SetLocation(time.UTC)
+	testCases := []struct {
+		v        interface{}
+		spType   string
+		expected interface{}
+	}{
+		{true, ddl.Bool, true},
+		{true, ddl.Int64, int64(1)},
+		{int64(7), ddl.Numeric, big.NewRat(7, 1)},
+		{big.NewRat(5, 2), ddl.Float64, 2.5},
+		{new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(38), nil)), ddl.String, "0.00000000000000000000000000000000000001"},
+		{big.NewRat(-123, 1000), ddl.String, "-0.123"},
+		{int64(7), ddl.String, "7"},
+		{1.5, ddl.String, "1.5"},
+		{civil.Time{Hour: 10, Minute: 30}, ddl.String, "10:30:00"},
+		{"POINT(1 2)", ddl.String, "POINT(1 2)"},
+		{"ab", ddl.Bytes, []byte("ab")},
+		{"2022-01-08T10:30:00.5", ddl.Timestamp, time.Date(2022, 1, 8, 10, 30, 0, 500000000, time.UTC)},
+		{[]interface{}{int64(1), big.NewRat(1, 4)}, ddl.JSON, `[1,0.25]`},
+		{[]interface{}{int64(1), int64(2)}, ddl.String, `[1,2]`},
+		{map[string]interface{}{"d": civil.Date{Year: 2022, Month: 1, Day: 8}}, ddl.JSON, `{"d":"2022-01-08"}`},
+	}
+	for _, tc := range testCases {
+		v, err := convScalar(conv, tc.v, tc.spType)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, v)
+	}
+	_, err := convScalar(conv, "ab", ddl.Int64)
+	assert.NotNil(t, err)
+	_, err = convScalar(conv, "not a datetime", ddl.Timestamp)
+	assert.NotNil(t, err)
+}
+
+func TestProcessDataRow_BadRow(t *testing.T) {
+	srcSchema, spSchema := testSchemas()
+	conv := internal.MakeConv()
+	conv.SetDataMode()
+	conv.SrcSchema["test"] = srcSchema
+	conv.SpSchema["test"] = spSchema
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		t.Errorf("unexpected row %v", vals)
+	})
+	row := map[string]interface{}{"id": int64(1), "dt": "not a datetime"}
+	ProcessDataRow(row, conv, "test", srcSchema, "test", srcSchema.ColNames, spSchema)
+	assert.Equal(t, int64(1), conv.BadRows())
+}
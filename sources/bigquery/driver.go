@@ -0,0 +1,76 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"fmt"
+
+	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+	gtransport "google.golang.org/api/transport/grpc"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+)
+
+func init() {
+	registry.Register(dbDriver{})
+}
+
+// storageEndpoint is the endpoint of the BigQuery Storage Read API.
+const storageEndpoint = "bigquerystorage.googleapis.com:443"
+
+// dbDriver reads the schema and data of the tables of BigQuery datasets.
+type dbDriver struct{}
+
+func (dbDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.BIGQUERY,
+		Source:      "bigquery",
+		Description: "BigQuery dataset",
+	}
+}
+
+func (dbDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (dbDriver) TypeMap() common.TypeMap {
+	return TypeMap
+}
+
+// InfoSchema connects to BigQuery with the application default credentials.
+func (dbDriver) InfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) (common.InfoSchema, error) {
+	ctx := context.Background()
+	service, err := bq.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect to BigQuery: %w", err)
+	}
+	conn, err := gtransport.Dial(ctx, option.WithEndpoint(storageEndpoint), option.WithScopes(bq.CloudPlatformScope))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect to the BigQuery Storage Read API: %w", err)
+	}
+	return InfoSchemaImpl{
+		Service:     service,
+		ReadClient:  storagepb.NewBigQueryReadClient(conn),
+		Project:     sourceProfile.Conn.Bq.Project,
+		Dataset:     sourceProfile.Conn.Bq.Dataset,
+		ReadStreams: sourceProfile.Conn.Bq.ReadStreams,
+	}, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"fmt"
+
+	sp "cloud.google.com/go/spanner"
+	bq "google.golang.org/api/bigquery/v2"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// InfoSchemaImpl reads the schema of the tables of a BigQuery dataset with
+// the BigQuery API, and their data with the BigQuery Storage Read API.
+type InfoSchemaImpl struct {
+	Service     *bq.Service
+	ReadClient  storagepb.BigQueryReadClient
+	Project     string // Project of the dataset, also billed for reads.
+	Dataset     string
+	ReadStreams int64 // Maximum number of streams each table is read with in parallel.
+}
+
+func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
+	return tableName
+}
+
+// GetTables returns the tables of the dataset. Views, materialized views
+// and external tables aren't migrated.
+func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
+	var tables []common.SchemaAndName
+	err := isi.Service.Tables.List(isi.Project, isi.Dataset).Pages(context.Background(), func(l *bq.TableList) error {
+		for _, t := range l.Tables {
+			if t.Type == "TABLE" {
+				tables = append(tables, common.SchemaAndName{Name: t.TableReference.TableId})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list tables of dataset %s.%s: %w", isi.Project, isi.Dataset, err)
+	}
+	return tables, nil
+}
+
+func (isi InfoSchemaImpl) getTable(table string) (*bq.Table, error) {
+	t, err := isi.Service.Tables.Get(isi.Project, isi.Dataset, table).Context(context.Background()).Do()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get table %s.%s.%s: %w", isi.Project, isi.Dataset, table, err)
+	}
+	return t, nil
+}
+
+// GetColumns returns the top-level fields of table as columns. Nested
+// fields are migrated as part of the JSON value of their record.
+func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
+	t, err := isi.getTable(table.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	colDefs := make(map[string]schema.Column)
+	var colNames []string
+	if t.Schema == nil {
+		return colDefs, colNames, nil
+	}
+	for _, f := range t.Schema.Fields {
+		c := schema.Column{
+			Name:    f.Name,
+			Type:    toType(f),
+			NotNull: f.Mode == "REQUIRED",
+		}
+		colDefs[f.Name] = c
+		colNames = append(colNames, f.Name)
+	}
+	return colDefs, colNames, nil
+}
+
+// toType returns the source type of field f.
+func toType(f *bq.TableFieldSchema) schema.Type {
+	ty := schema.Type{Name: f.Type}
+	switch {
+	case f.MaxLength > 0:
+		ty.Mods = []int64{f.MaxLength}
+	case f.Precision > 0:
+		ty.Mods = []int64{f.Precision, f.Scale}
+	}
+	if f.Mode == "REPEATED" {
+		ty.ArrayBounds = []int64{-1}
+	}
+	return ty
+}
+
+// GetRowsFromTable isn't used: data is read by ProcessData.
+func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
+	return nil, nil
+}
+
+// GetRowCount returns the number of rows of table, from its metadata.
+func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
+	t, err := isi.getTable(table.Name)
+	if err != nil {
+		return 0, err
+	}
+	return int64(t.NumRows), nil
+}
+
+// GetConstraints returns no primary keys: BigQuery tables have none, and are
+// migrated with a synthetic primary key.
+func (isi InfoSchemaImpl) GetConstraints(conv *internal.Conv, table common.SchemaAndName) ([]string, map[string][]string, error) {
+	return nil, nil, nil
+}
+
+// GetForeignKeys returns no foreign keys: BigQuery tables have none.
+func (isi InfoSchemaImpl) GetForeignKeys(conv *internal.Conv, table common.SchemaAndName) ([]schema.ForeignKey, error) {
+	return nil, nil
+}
+
+// GetIndexes returns no indexes: BigQuery tables have none.
+func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAndName) ([]schema.Index, error) {
+	return nil, nil
+}
+
+// StartChangeDataCapture is not supported: BigQuery is only migrated in bulk.
+func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// StartStreamingMigration is not supported: BigQuery is only migrated in
+// bulk.
+func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamingInfo map[string]interface{}) error {
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// newTestInfoSchema returns an InfoSchemaImpl reading dataset p.d from a
+// fake BigQuery API serving responses, keyed by request path.
+func newTestInfoSchema(t *testing.T, responses map[string]string) InfoSchemaImpl {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, resp)
+	}))
+	t.Cleanup(server.Close)
+	service, err := bq.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	assert.Nil(t, err)
+	return InfoSchemaImpl{Service: service, Project: "p", Dataset: "d"}
+}
+
+func TestInfoSchemaImpl_GetTables(t *testing.T) {
+	isi := newTestInfoSchema(t, map[string]string{
+		"/projects/p/datasets/d/tables": `{"tables": [
+			{"tableReference": {"projectId": "p", "datasetId": "d", "tableId": "orders"}, "type": "TABLE"},
+			{"tableReference": {"projectId": "p", "datasetId": "d", "tableId": "orders_view"}, "type": "VIEW"},
+			{"tableReference": {"projectId": "p", "datasetId": "d", "tableId": "users"}, "type": "TABLE"}
+		]}`,
+	})
+	tables, err := isi.GetTables()
+	assert.Nil(t, err)
+	assert.Equal(t, []common.SchemaAndName{{Name: "orders"}, {Name: "users"}}, tables)
+}
+
+func TestInfoSchemaImpl_GetColumns(t *testing.T) {
+	isi := newTestInfoSchema(t, map[string]string{
+		"/projects/p/datasets/d/tables/orders": `{"numRows": "42", "schema": {"fields": [
+			{"name": "id", "type": "INTEGER", "mode": "REQUIRED"},
+			{"name": "code", "type": "STRING", "maxLength": "10"},
+			{"name": "amount", "type": "NUMERIC", "precision": "10", "scale": "2"},
+			{"name": "tags", "type": "STRING", "mode": "REPEATED"},
+			{"name": "items", "type": "RECORD", "mode": "REPEATED", "fields": [{"name": "sku", "type": "STRING"}]}
+		]}}`,
+	})
+	table := common.SchemaAndName{Name: "orders"}
+	colDefs, colNames, err := isi.GetColumns(internal.MakeConv(), table, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "code", "amount", "tags", "items"}, colNames)
+	assert.Equal(t, map[string]schema.Column{
+		"id":     {Name: "id", Type: schema.Type{Name: "INTEGER"}, NotNull: true},
+		"code":   {Name: "code", Type: schema.Type{Name: "STRING", Mods: []int64{10}}},
+		"amount": {Name: "amount", Type: schema.Type{Name: "NUMERIC", Mods: []int64{10, 2}}},
+		"tags":   {Name: "tags", Type: schema.Type{Name: "STRING", ArrayBounds: []int64{-1}}},
+		"items":  {Name: "items", Type: schema.Type{Name: "RECORD", ArrayBounds: []int64{-1}}},
+	}, colDefs)
+	n, err := isi.GetRowCount(table)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), n)
+	_, err = isi.GetRowCount(common.SchemaAndName{Name: "missing"})
+	assert.NotNil(t, err)
+}
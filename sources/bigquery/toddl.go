@@ -0,0 +1,113 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bigquery handles schema and data migrations from BigQuery.
+package bigquery
+
+import (
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// ToDdlImpl BigQuery specific implementation for ToDdl.
+type ToDdlImpl struct {
+}
+
+// ToSpannerType maps a BigQuery type (defined by its name and, for
+// parameterized types, its length or precision) into a Spanner type.
+// Repeated fields are mapped to arrays, except repeated records, which are
+// mapped to JSON arrays.
+func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
+	ty, issues := TypeMap.ToSpannerType(columnType.Name, "", columnType.Mods)
+	ty.IsArray = len(columnType.ArrayBounds) == 1 && ty.Name != ddl.JSON
+	if conv.TargetDb == constants.TargetExperimentalPostgres && (ty.IsArray || ty.Name == ddl.JSON) {
+		ty = common.MaxString
+	}
+	return ty, issues
+}
+
+// TypeMap declares the mapping of BigQuery types to Spanner types. The
+// legacy SQL names of types, which the BigQuery API returns, are mapped as
+// their standard SQL names.
+var TypeMap = common.NewTypeMap(
+	common.TypeGroup{SrcTypes: []string{"BOOL", "BOOLEAN"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Bool}),
+		Options: map[string]common.MapFunc{
+			ddl.String: common.To(common.MaxString, internal.Widened),
+			ddl.Int64:  common.To(ddl.Type{Name: ddl.Int64}, internal.Widened),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"INT64", "INTEGER"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}),
+		Options: map[string]common.MapFunc{
+			ddl.String:  common.To(common.MaxString, internal.Widened),
+			ddl.Numeric: common.To(ddl.Type{Name: ddl.Numeric}, internal.Widened),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"FLOAT64", "FLOAT"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Float64}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"NUMERIC", "DECIMAL"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Numeric}),
+		Options: map[string]common.MapFunc{
+			ddl.String:  common.To(common.MaxString, internal.Widened),
+			ddl.Float64: common.To(ddl.Type{Name: ddl.Float64}, internal.Numeric),
+		},
+	}},
+	// BIGNUMERIC has a precision of 76 digits and a scale of 38, which
+	// exceed those of Spanner's NUMERIC.
+	common.TypeGroup{SrcTypes: []string{"BIGNUMERIC", "BIGDECIMAL"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString, internal.Numeric),
+		Options: map[string]common.MapFunc{
+			ddl.Numeric: common.To(ddl.Type{Name: ddl.Numeric}, internal.Numeric),
+			ddl.Float64: common.To(ddl.Type{Name: ddl.Float64}, internal.Numeric),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"STRING"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.String, ddl.MaxLength),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.ToSized(ddl.Bytes, ddl.MaxLength)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"BYTES"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.Bytes, ddl.MaxLength),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"DATE"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Date}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"DATETIME"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Timestamp}, internal.Datetime),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"TIMESTAMP"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Timestamp}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"TIME"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString, internal.Time),
+	}},
+	// GEOGRAPHY values are migrated in WKT format, and INTERVAL values in
+	// canonical format e.g. 0-0 10 -12:30:00.
+	common.TypeGroup{SrcTypes: []string{"GEOGRAPHY", "INTERVAL"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+	}},
+	common.TypeGroup{SrcTypes: []string{"JSON", "RECORD", "STRUCT"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.JSON}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+)
@@ -0,0 +1,69 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToSpannerType(t *testing.T) {
+	conv := internal.MakeConv()
+	testCases := []struct {
+		srcType  schema.Type
+		expected ddl.Type
+		issues   []internal.SchemaIssue
+	}{
+		{schema.Type{Name: "BOOLEAN"}, ddl.Type{Name: ddl.Bool}, nil},
+		{schema.Type{Name: "INTEGER"}, ddl.Type{Name: ddl.Int64}, nil},
+		{schema.Type{Name: "INT64", ArrayBounds: []int64{-1}}, ddl.Type{Name: ddl.Int64, IsArray: true}, nil},
+		{schema.Type{Name: "FLOAT"}, ddl.Type{Name: ddl.Float64}, nil},
+		{schema.Type{Name: "NUMERIC", Mods: []int64{10, 2}}, ddl.Type{Name: ddl.Numeric}, nil},
+		{schema.Type{Name: "BIGNUMERIC"}, common.MaxString, []internal.SchemaIssue{internal.Numeric}},
+		{schema.Type{Name: "STRING"}, common.MaxString, nil},
+		{schema.Type{Name: "STRING", Mods: []int64{10}}, ddl.Type{Name: ddl.String, Len: 10}, nil},
+		{schema.Type{Name: "STRING", ArrayBounds: []int64{-1}}, ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}, nil},
+		{schema.Type{Name: "BYTES"}, common.MaxBytes, nil},
+		{schema.Type{Name: "DATE"}, ddl.Type{Name: ddl.Date}, nil},
+		{schema.Type{Name: "DATETIME"}, ddl.Type{Name: ddl.Timestamp}, []internal.SchemaIssue{internal.Datetime}},
+		{schema.Type{Name: "TIMESTAMP"}, ddl.Type{Name: ddl.Timestamp}, nil},
+		{schema.Type{Name: "TIME"}, common.MaxString, []internal.SchemaIssue{internal.Time}},
+		{schema.Type{Name: "GEOGRAPHY"}, common.MaxString, nil},
+		{schema.Type{Name: "JSON"}, ddl.Type{Name: ddl.JSON}, nil},
+		{schema.Type{Name: "RECORD"}, ddl.Type{Name: ddl.JSON}, nil},
+		// Repeated records are migrated as JSON arrays.
+		{schema.Type{Name: "RECORD", ArrayBounds: []int64{-1}}, ddl.Type{Name: ddl.JSON}, nil},
+	}
+	for _, tc := range testCases {
+		ty, issues := ToDdlImpl{}.ToSpannerType(conv, tc.srcType)
+		assert.Equal(t, tc.expected, ty, tc.srcType.Print())
+		assert.Equal(t, tc.issues, issues, tc.srcType.Print())
+	}
+}
+
+func TestToSpannerType_Postgres(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.TargetDb = constants.TargetExperimentalPostgres
+	ty, _ := ToDdlImpl{}.ToSpannerType(conv, schema.Type{Name: "INT64", ArrayBounds: []int64{-1}})
+	assert.Equal(t, common.MaxString, ty)
+	ty, _ = ToDdlImpl{}.ToSpannerType(conv, schema.Type{Name: "RECORD"})
+	assert.Equal(t, common.MaxString, ty)
+}
@@ -17,6 +17,8 @@ package common
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"time"
 
 	sp "cloud.google.com/go/spanner"
 
@@ -36,11 +38,83 @@ type InfoSchema interface {
 	GetConstraints(conv *internal.Conv, table SchemaAndName) ([]string, map[string][]string, error)
 	GetForeignKeys(conv *internal.Conv, table SchemaAndName) (foreignKeys []schema.ForeignKey, err error)
 	GetIndexes(conv *internal.Conv, table SchemaAndName) ([]schema.Index, error)
-	ProcessData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error
+	ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error
 	StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error)
 	StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamInfo map[string]interface{}) error
 }
 
+// DataPreviewer is implemented by the InfoSchemas of sources that can
+// preview the data conversion of a table (see PreviewData).
+type DataPreviewer interface {
+	// PreviewData converts at most n rows of source table srcTable.
+	PreviewData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, n int) ([]PreviewRow, error)
+}
+
+// PreviewRow is a row of a source table and its conversion to Spanner.
+type PreviewRow struct {
+	SrcVals []string      // Values of the source columns, as strings.
+	SpCols  []string      // Spanner columns of SpVals. Columns with NULL values are omitted.
+	SpVals  []interface{} // Converted values.
+	Err     error         // Set if the row can't be converted, in which case SpCols and SpVals are empty.
+}
+
+// MaterializedViewReader is implemented by the InfoSchemas of sources with
+// materialized views. Views that are converted into tables (see
+// internal.MaterializedViewTable) must also be returned by GetTables.
+type MaterializedViewReader interface {
+	// GetMaterializedViews returns the materialized views of the source
+	// database and their definitions.
+	GetMaterializedViews() ([]MaterializedView, error)
+}
+
+// MaterializedView is a materialized view of the source database.
+type MaterializedView struct {
+	SchemaAndName
+	Definition string
+}
+
+// GrantReader is implemented by the InfoSchemas of sources whose grants of
+// privileges on tables can be migrated as Spanner database roles.
+type GrantReader interface {
+	// GetGrants returns the grants of privileges on the tables of the
+	// source database.
+	GetGrants() ([]Grant, error)
+}
+
+// Grant is a grant of privileges on a table of the source database. Grants
+// with an empty table name are on all the tables of the schema.
+type Grant struct {
+	Table      SchemaAndName
+	Columns    []string // Columns the privileges are limited to, or all columns if empty.
+	Grantee    string
+	Privileges []string
+}
+
+// AccessChecker is implemented by the InfoSchemas of sources that can check,
+// before a migration, that the tables of the source database can be read.
+type AccessChecker interface {
+	// CheckReadAccess returns an error if the rows of table can't be read.
+	CheckReadAccess(table SchemaAndName) error
+}
+
+// StreamChecker is implemented by the InfoSchemas of sources that can check,
+// before a streaming migration, that the changes of tables can be streamed.
+type StreamChecker interface {
+	// CheckStream returns an error if the changes of table can't be
+	// streamed.
+	CheckStream(table SchemaAndName) error
+}
+
+// StreamVersionReader is implemented by the InfoSchemas of sources whose
+// streaming migration records in Spanner the items it writes, so that bulk
+// data migration doesn't overwrite them with older data.
+type StreamVersionReader interface {
+	// ReadStreamVersions reads the items written by earlier streaming
+	// migrations to the Spanner database of client, which ProcessData then
+	// skips.
+	ReadStreamVersions(ctx context.Context, client *sp.Client, conv *internal.Conv) error
+}
+
 // SchemaAndName contains the schema and name for a table
 type SchemaAndName struct {
 	Schema string
@@ -58,18 +132,241 @@ type FkConstraint struct {
 // ProcessSchema performs schema conversion for source database
 // 'db'. Information schema tables are a broadly supported ANSI standard,
 // and we use them to obtain source database's schema information.
+// If conv.SelectedTables is set, only the tables it holds are converted.
 func ProcessSchema(conv *internal.Conv, infoSchema InfoSchema) error {
+	start := time.Now()
 	tables, err := infoSchema.GetTables()
 	if err != nil {
 		return err
 	}
+	if conv.SelectedTables != nil {
+		var selected []SchemaAndName
+		for _, t := range tables {
+			if conv.SelectedTables[infoSchema.GetTableName(t.Schema, t.Name)] {
+				selected = append(selected, t)
+			}
+		}
+		tables = selected
+	}
+	if mvr, ok := infoSchema.(MaterializedViewReader); ok {
+		views, err := mvr.GetMaterializedViews()
+		if err != nil {
+			return fmt.Errorf("couldn't get materialized views: %w", err)
+		}
+		for _, v := range views {
+			table := false
+			for _, t := range tables {
+				table = table || t == v.SchemaAndName
+			}
+			conv.AddMaterializedView(infoSchema.GetTableName(v.Schema, v.Name), v.Definition, table)
+		}
+	}
 	for _, t := range tables {
 		if err := processTable(conv, t, infoSchema); err != nil {
 			return err
 		}
 	}
+	if err := mergeTables(conv, tables, infoSchema); err != nil {
+		return err
+	}
+	if gr, ok := infoSchema.(GrantReader); ok {
+		grants, err := gr.GetGrants()
+		if err != nil {
+			return fmt.Errorf("couldn't get grants: %w", err)
+		}
+		for _, g := range grants {
+			for _, t := range tables {
+				if t == g.Table || (g.Table.Name == "" && t.Schema == g.Table.Schema) {
+					// Merged tables get the grants of the first table merged.
+					name := infoSchema.GetTableName(t.Schema, t.Name)
+					if merged := conv.MergedTable(name); merged != name {
+						if conv.TableMerges[merged].Members[0].Name != name {
+							continue
+						}
+						name = merged
+					}
+					conv.AddGrant(name, g.Grantee, g.Privileges, g.Columns)
+				}
+			}
+		}
+	}
+	conv.Timings.Time(internal.StageSchemaFetch, start)
+	start = time.Now()
+	if conv.SelectedTables != nil {
+		dropForeignKeysToSkippedTables(conv)
+	}
 	SchemaToSpannerDDL(conv, infoSchema.GetToDdl())
 	conv.AddPrimaryKeys()
+	if err := conv.AddMergeColumns(); err != nil {
+		return err
+	}
+	conv.Timings.Time(internal.StageSchemaConversion, start)
+	return nil
+}
+
+// mergeTables merges the source tables matching conv.MergeRules (see
+// internal.MergeRule) into single tables of conv.SrcSchema. Tables merged
+// must have the same columns and primary key. Foreign keys referencing them
+// reference the tables they are merged into.
+func mergeTables(conv *internal.Conv, tables []SchemaAndName, infoSchema InfoSchema) error {
+	for _, t := range tables {
+		name := infoSchema.GetTableName(t.Schema, t.Name)
+		if r, key, ok := conv.MergeTable(name); ok {
+			conv.AddMergeMember(r.Table(), r, internal.MergeMember{Name: name, Schema: t.Schema, Table: t.Name, Key: key})
+		}
+	}
+	for table, tm := range conv.TableMerges {
+		if _, ok := conv.SrcSchema[table]; ok {
+			return fmt.Errorf("can't merge tables matching %s into table %s, which already exists", tm.Pattern, table)
+		}
+		merged := conv.SrcSchema[tm.Members[0].Name]
+		for _, m := range tm.Members[1:] {
+			if err := sameColumns(merged, conv.SrcSchema[m.Name]); err != nil {
+				return fmt.Errorf("can't merge tables %s and %s into table %s: %s", tm.Members[0].Name, m.Name, table, err)
+			}
+		}
+		for _, m := range tm.Members {
+			delete(conv.SrcSchema, m.Name)
+		}
+		merged.Name = table
+		conv.SrcSchema[table] = merged
+	}
+	if len(conv.TableMerges) == 0 {
+		return nil
+	}
+	for name, t := range conv.SrcSchema {
+		for i, fk := range t.ForeignKeys {
+			t.ForeignKeys[i].ReferTable = conv.MergedTable(fk.ReferTable)
+		}
+		conv.SrcSchema[name] = t
+	}
+	return nil
+}
+
+// dropForeignKeysToSkippedTables drops the foreign keys of source tables
+// that reference tables which aren't migrated.
+func dropForeignKeysToSkippedTables(conv *internal.Conv) {
+	for name, t := range conv.SrcSchema {
+		var fks []schema.ForeignKey
+		for _, fk := range t.ForeignKeys {
+			if _, ok := conv.SrcSchema[fk.ReferTable]; ok {
+				fks = append(fks, fk)
+				continue
+			}
+			internal.VerbosePrintf("Dropping foreign key %s of table %s: referenced table %s isn't migrated\n", fk.Name, name, fk.ReferTable)
+		}
+		t.ForeignKeys = fks
+		conv.SrcSchema[name] = t
+	}
+}
+
+// Shard is one of several source databases merged into one Spanner database.
+type Shard struct {
+	Id         string
+	InfoSchema InfoSchema
+}
+
+// ProcessShardSchemas performs schema conversion for several source
+// databases (shards) that are merged into one Spanner database, and adds the
+// shard id column to every Spanner table. Tables with the same name in
+// different shards are handled according to collision (see
+// internal.TableCollisionMerge and friends). Tables of shards with a name
+// pattern (see internal.ValidNamePattern) are never merged, and don't
+// collide with tables of other shards. conv.Shards must be set.
+func ProcessShardSchemas(conv *internal.Conv, shards []Shard, collision string) error {
+	if len(shards) == 0 {
+		return fmt.Errorf("no source databases to migrate")
+	}
+	// Read all tables first, since handling of a table depends on whether
+	// its name is used by another shard.
+	tables := make([][]schema.Table, len(shards))
+	found := make(map[string][]int)   // Shards with each table name.
+	unnamed := make(map[string][]int) // Shards without a name pattern with each table name.
+	for i, shard := range shards {
+		names, err := shard.InfoSchema.GetTables()
+		if err != nil {
+			return fmt.Errorf("couldn't get tables of source database %s: %s", shard.Id, err)
+		}
+		for _, name := range names {
+			t, err := readTable(conv, name, shard.InfoSchema)
+			if err != nil {
+				return fmt.Errorf("source database %s: %s", shard.Id, err)
+			}
+			tables[i] = append(tables[i], t)
+			found[t.Name] = append(found[t.Name], i)
+			if conv.Shards.Names[shard.Id] == "" {
+				unnamed[t.Name] = append(unnamed[t.Name], i)
+			}
+		}
+	}
+	for i, shard := range shards {
+		// Tables whose names collide are either merged, or qualified with
+		// the shard id.
+		collides := func(name string) bool { return len(unnamed[name]) > 1 }
+		qualify := collision == internal.TableCollisionPrefix
+		if conv.Shards.Names[shard.Id] != "" {
+			collides = func(name string) bool { return len(found[name]) > 1 }
+			qualify = true
+		}
+		for _, t := range tables[i] {
+			if qualify {
+				// References to qualified tables are qualified too.
+				var fks []schema.ForeignKey
+				for _, fk := range t.ForeignKeys {
+					if collides(fk.ReferTable) {
+						fk.ReferTable = shard.Id + "." + fk.ReferTable
+					}
+					fks = append(fks, fk)
+				}
+				t.ForeignKeys = fks
+			}
+			if !collides(t.Name) {
+				conv.SrcSchema[t.Name] = t
+				conv.Shards.Tables[t.Name] = []string{shard.Id}
+				continue
+			}
+			switch {
+			case qualify:
+				t.Name = shard.Id + "." + t.Name
+				conv.SrcSchema[t.Name] = t
+				conv.Shards.Tables[t.Name] = []string{shard.Id}
+			case collision == internal.TableCollisionMerge || collision == "":
+				if prev, ok := conv.SrcSchema[t.Name]; ok {
+					if err := sameColumns(prev, t); err != nil {
+						return fmt.Errorf("can't merge table %s of source databases %s and %s: %s", t.Name, conv.Shards.Tables[t.Name][0], shard.Id, err)
+					}
+				} else {
+					conv.SrcSchema[t.Name] = t
+				}
+				conv.Shards.Tables[t.Name] = append(conv.Shards.Tables[t.Name], shard.Id)
+			default:
+				return fmt.Errorf("table %s exists in source databases %s and %s", t.Name, shards[unnamed[t.Name][0]].Id, shards[unnamed[t.Name][1]].Id)
+			}
+		}
+	}
+	SchemaToSpannerDDL(conv, shards[0].InfoSchema.GetToDdl())
+	conv.AddPrimaryKeys()
+	return conv.AddShardIdColumn()
+}
+
+// sameColumns returns an error if tables t1 and t2 don't have the same
+// columns and primary key.
+func sameColumns(t1, t2 schema.Table) error {
+	if len(t1.ColNames) != len(t2.ColNames) {
+		return fmt.Errorf("tables have %d and %d columns", len(t1.ColNames), len(t2.ColNames))
+	}
+	for _, c := range t1.ColNames {
+		c2, ok := t2.ColDefs[c]
+		if !ok {
+			return fmt.Errorf("column %s is missing", c)
+		}
+		if c1 := t1.ColDefs[c]; !reflect.DeepEqual(c1.Type, c2.Type) {
+			return fmt.Errorf("column %s has types %s and %s", c, c1.Type.Print(), c2.Type.Print())
+		}
+	}
+	if !reflect.DeepEqual(t1.PrimaryKeys, t2.PrimaryKeys) {
+		return fmt.Errorf("primary keys differ")
+	}
 	return nil
 }
 
@@ -77,32 +374,109 @@ func ProcessSchema(conv *internal.Conv, infoSchema InfoSchema) error {
 // 'db'. For each table, we extract and convert the data to Spanner data
 // (based on the source and Spanner schemas), and write it to Spanner.
 // If we can't get/process data for a table, we skip that table and process
-// the remaining tables.
-func ProcessData(conv *internal.Conv, infoSchema InfoSchema) {
-	// Tables are ordered in alphabetical order with one exception: interleaved
-	// tables appear after the population of their parent table.
-	orderTableNames := ddl.OrderTables(conv.SpSchema)
-
-	for _, spannerTable := range orderTableNames {
-		srcTable, _ := internal.GetSourceTable(conv, spannerTable)
-		srcSchema := conv.SrcSchema[srcTable]
-		spTable, err1 := internal.GetSpannerTable(conv, srcTable)
-		spCols, err2 := internal.GetSpannerCols(conv, srcTable, srcSchema.ColNames)
-		spSchema, ok := conv.SpSchema[spTable]
-		if err1 != nil || err2 != nil || !ok {
-			conv.Stats.BadRows[srcTable] += conv.Stats.Rows[srcTable]
-			conv.Unexpected(fmt.Sprintf("Can't get cols and schemas for table %s: err1=%s, err2=%s, ok=%t",
-				srcTable, err1, err2, ok))
-			continue
-		}
-		err := infoSchema.ProcessData(conv, srcTable, srcSchema, spTable, spCols, spSchema)
-		if err != nil {
-			return
+// the remaining tables. ctx is passed to the InfoSchema, whose pauses
+// outside the migration windows (see internal.Schedule) end once it is done.
+func ProcessData(ctx context.Context, conv *internal.Conv, infoSchema InfoSchema) {
+	ProcessDataWithProgress(ctx, conv, infoSchema, nil)
+}
+
+// TableProgress is called when the data migration of Spanner table spTable
+// starts (done is false), and when it ends (done is true). err is set if the
+// migration of the table failed. The migration stops if it returns an error,
+// e.g. because it was cancelled.
+type TableProgress func(spTable string, done bool, err error) error
+
+// ProcessDataWithProgress is like ProcessData, and calls progress (if set)
+// as the migration of each table starts and ends. Tables end when the rows
+// of their level are flushed. It stops at the first table that fails.
+func ProcessDataWithProgress(ctx context.Context, conv *internal.Conv, infoSchema InfoSchema, progress TableProgress) {
+	if progress == nil {
+		progress = func(string, bool, error) error { return nil }
+	}
+	// Tables are loaded in dependency order: parents of interleaved tables
+	// and tables referenced by foreign keys are loaded first (see
+	// ddl.LoadOrder). Writes for tables in the same level are independent
+	// and proceed in parallel; we only wait for them to complete before
+	// moving on to the next level.
+	for _, level := range ddl.LoadOrder(conv.SpSchema) {
+		var started []string
+		for _, spannerTable := range level {
+			srcTable, _ := internal.GetSourceTable(conv, spannerTable)
+			if !conv.InShard(srcTable) {
+				continue
+			}
+			if progress(spannerTable, false, nil) != nil {
+				return
+			}
+			srcSchema := conv.SrcSchema[srcTable]
+			spTable, err1 := internal.GetSpannerTable(conv, srcTable)
+			spCols, err2 := internal.GetSpannerCols(conv, srcTable, srcSchema.ColNames)
+			spSchema, ok := conv.SpSchema[spTable]
+			if err1 != nil || err2 != nil || !ok {
+				conv.Stats.BadRows[srcTable] += conv.Stats.Rows[srcTable]
+				conv.Unexpected(fmt.Sprintf("Can't get cols and schemas for table %s: err1=%s, err2=%s, ok=%t",
+					srcTable, err1, err2, ok))
+				if progress(spannerTable, true, fmt.Errorf("can't get cols and schemas for table %s", srcTable)) != nil {
+					return
+				}
+				continue
+			}
+			start := time.Now()
+			var err error
+			if tm, ok := conv.TableMerges[srcTable]; ok {
+				// The tables merged into srcTable are migrated in turn.
+				for i := range tm.Members {
+					conv.SetMergeMember(srcTable, i)
+					if err = infoSchema.ProcessData(ctx, conv, srcTable, srcSchema, spTable, spCols, spSchema); err != nil {
+						break
+					}
+				}
+			} else {
+				err = infoSchema.ProcessData(ctx, conv, srcTable, srcSchema, spTable, spCols, spSchema)
+			}
+			conv.Timings.TimeTable(internal.StageRead, srcTable, start)
+			if err != nil {
+				progress(spannerTable, true, err)
+				return
+			}
+			started = append(started, spannerTable)
 		}
 		if conv.DataFlush != nil {
 			conv.DataFlush()
 		}
+		stop := false
+		for _, t := range started {
+			if progress(t, true, nil) != nil {
+				stop = true
+			}
+		}
+		if stop {
+			return
+		}
+	}
+}
+
+// PreviewData converts at most n rows of Spanner table spTable from the
+// source, so that type mappings can be checked on real data before
+// migrating. conv isn't changed, e.g. synthetic key sequences aren't
+// advanced.
+func PreviewData(conv *internal.Conv, infoSchema InfoSchema, spTable string, n int) ([]PreviewRow, error) {
+	dp, ok := infoSchema.(DataPreviewer)
+	if !ok {
+		return nil, fmt.Errorf("data preview isn't supported for this source")
+	}
+	srcTable, err := internal.GetSourceTable(conv, spTable)
+	if err != nil {
+		return nil, err
 	}
+	srcSchema := conv.SrcSchema[srcTable]
+	spCols, err := internal.GetSpannerCols(conv, srcTable, srcSchema.ColNames)
+	if err != nil {
+		return nil, err
+	}
+	sc := conv.SampleConv()
+	sc.SetDataMode()
+	return dp.PreviewData(sc, srcTable, srcSchema, spTable, spCols, conv.SpSchema[spTable], n)
 }
 
 // SetRowStats populates conv with the number of rows in each table.
@@ -113,7 +487,7 @@ func SetRowStats(conv *internal.Conv, infoSchema InfoSchema) {
 		return
 	}
 	for _, t := range tables {
-		tableName := infoSchema.GetTableName(t.Schema, t.Name)
+		tableName := conv.ShardTable(conv.MergedTable(infoSchema.GetTableName(t.Schema, t.Name)))
 		count, err := infoSchema.GetRowCount(t)
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Couldn't get number of rows for table %s", tableName))
@@ -124,34 +498,42 @@ func SetRowStats(conv *internal.Conv, infoSchema InfoSchema) {
 }
 
 func processTable(conv *internal.Conv, table SchemaAndName, infoSchema InfoSchema) error {
+	t, err := readTable(conv, table, infoSchema)
+	if err != nil {
+		return err
+	}
+	conv.SrcSchema[t.Name] = t
+	return nil
+}
+
+func readTable(conv *internal.Conv, table SchemaAndName, infoSchema InfoSchema) (schema.Table, error) {
 	primaryKeys, constraints, err := infoSchema.GetConstraints(conv, table)
 	if err != nil {
-		return fmt.Errorf("couldn't get constraints for table %s.%s: %s", table.Schema, table.Name, err)
+		return schema.Table{}, fmt.Errorf("couldn't get constraints for table %s.%s: %s", table.Schema, table.Name, err)
 	}
 	foreignKeys, err := infoSchema.GetForeignKeys(conv, table)
 	if err != nil {
-		return fmt.Errorf("couldn't get foreign key constraints for table %s.%s: %s", table.Schema, table.Name, err)
+		return schema.Table{}, fmt.Errorf("couldn't get foreign key constraints for table %s.%s: %s", table.Schema, table.Name, err)
 	}
 	indexes, err := infoSchema.GetIndexes(conv, table)
 	if err != nil {
-		return fmt.Errorf("couldn't get indexes for table %s.%s: %s", table.Schema, table.Name, err)
+		return schema.Table{}, fmt.Errorf("couldn't get indexes for table %s.%s: %s", table.Schema, table.Name, err)
 	}
 	colDefs, colNames, err := infoSchema.GetColumns(conv, table, constraints, primaryKeys)
 	if err != nil {
-		return fmt.Errorf("couldn't get schema for table %s.%s: %s", table.Schema, table.Name, err)
+		return schema.Table{}, fmt.Errorf("couldn't get schema for table %s.%s: %s", table.Schema, table.Name, err)
 	}
 	name := infoSchema.GetTableName(table.Schema, table.Name)
 	var schemaPKeys []schema.Key
 	for _, k := range primaryKeys {
 		schemaPKeys = append(schemaPKeys, schema.Key{Column: k})
 	}
-	conv.SrcSchema[name] = schema.Table{
+	return schema.Table{
 		Name:        name,
 		Schema:      table.Schema,
 		ColNames:    colNames,
 		ColDefs:     colDefs,
 		PrimaryKeys: schemaPKeys,
 		Indexes:     indexes,
-		ForeignKeys: foreignKeys}
-	return nil
+		ForeignKeys: foreignKeys}, nil
 }
@@ -0,0 +1,303 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// fakeToDdl maps every source type to INT64.
+type fakeToDdl struct{}
+
+func (fakeToDdl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
+	return ddl.Type{Name: ddl.Int64}, nil
+}
+
+// fakeInfoSchema is an InfoSchema for a database with the tables in
+// tables. Each table has the primary key column id, and a foreign key for
+// each entry of fks.
+type fakeInfoSchema struct {
+	tables map[string][]string // Maps table name to column names.
+	fks    map[string]string   // Maps table name to referenced table.
+	rows   map[string]int64    // Maps table name to row count.
+}
+
+func (isi fakeInfoSchema) GetToDdl() ToDdl { return fakeToDdl{} }
+
+func (isi fakeInfoSchema) GetTableName(schema string, tableName string) string { return tableName }
+
+func (isi fakeInfoSchema) GetTables() ([]SchemaAndName, error) {
+	var l []SchemaAndName
+	for t := range isi.tables {
+		l = append(l, SchemaAndName{Schema: "db", Name: t})
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+	return l, nil
+}
+
+func (isi fakeInfoSchema) GetColumns(conv *internal.Conv, table SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
+	colDefs := make(map[string]schema.Column)
+	for _, c := range isi.tables[table.Name] {
+		colDefs[c] = schema.Column{Name: c, Type: schema.Type{Name: "int"}}
+	}
+	return colDefs, isi.tables[table.Name], nil
+}
+
+func (isi fakeInfoSchema) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
+	return nil, nil
+}
+
+func (isi fakeInfoSchema) GetRowCount(table SchemaAndName) (int64, error) {
+	return isi.rows[table.Name], nil
+}
+
+func (isi fakeInfoSchema) GetConstraints(conv *internal.Conv, table SchemaAndName) ([]string, map[string][]string, error) {
+	return []string{"id"}, nil, nil
+}
+
+func (isi fakeInfoSchema) GetForeignKeys(conv *internal.Conv, table SchemaAndName) ([]schema.ForeignKey, error) {
+	if r, ok := isi.fks[table.Name]; ok {
+		return []schema.ForeignKey{{Name: "fk_" + table.Name, Columns: []string{r + "_id"}, ReferTable: r, ReferColumns: []string{"id"}}}, nil
+	}
+	return nil, nil
+}
+
+func (isi fakeInfoSchema) GetIndexes(conv *internal.Conv, table SchemaAndName) ([]schema.Index, error) {
+	return nil, nil
+}
+
+func (isi fakeInfoSchema) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	return nil
+}
+
+func (isi fakeInfoSchema) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (isi fakeInfoSchema) StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamInfo map[string]interface{}) error {
+	return nil
+}
+
+func TestProcessShardSchemas(t *testing.T) {
+	shard := func(id string, tables map[string][]string) Shard {
+		return Shard{Id: id, InfoSchema: fakeInfoSchema{
+			tables: tables,
+			fks:    map[string]string{"orders": "users"},
+			rows:   map[string]int64{"users": 2, "orders": 3, "extra": 4},
+		}}
+	}
+	s1 := shard("s1", map[string][]string{"users": {"id"}, "orders": {"id", "users_id"}})
+	s2 := shard("s2", map[string][]string{"users": {"id"}, "orders": {"id", "users_id"}, "extra": {"id"}})
+	s3 := shard("s3", map[string][]string{"users": {"id", "name"}})
+
+	newConv := func() *internal.Conv {
+		conv := internal.MakeConv()
+		conv.Shards = internal.NewShards([]string{"s1", "s2", "s3"}, "")
+		return conv
+	}
+
+	// Tables with the same name are merged.
+	conv := newConv()
+	assert.Nil(t, ProcessShardSchemas(conv, []Shard{s1, s2}, internal.TableCollisionMerge))
+	assert.Equal(t, map[string][]string{"users": {"s1", "s2"}, "orders": {"s1", "s2"}, "extra": {"s2"}}, conv.Shards.Tables)
+	assert.Equal(t, []string{"shard_id", "id"}, conv.SpSchema["users"].ColNames)
+	assert.Equal(t, []string{"shard_id", "users_id"}, conv.SpSchema["orders"].Fks[0].Columns)
+	conv.SetShard("s1")
+	SetRowStats(conv, s1.InfoSchema)
+	conv.SetShard("s2")
+	SetRowStats(conv, s2.InfoSchema)
+	assert.Equal(t, map[string]int64{"users": 4, "orders": 6, "extra": 4}, conv.Stats.Rows)
+
+	// Tables with different columns can't be merged.
+	assert.NotNil(t, ProcessShardSchemas(newConv(), []Shard{s1, s3}, internal.TableCollisionMerge))
+	assert.NotNil(t, ProcessShardSchemas(newConv(), []Shard{s1, s2}, internal.TableCollisionFail))
+
+	// Tables with the same name, and references to them, are prefixed.
+	conv = newConv()
+	assert.Nil(t, ProcessShardSchemas(conv, []Shard{s1, s2}, internal.TableCollisionPrefix))
+	assert.Equal(t, map[string][]string{"s1.users": {"s1"}, "s1.orders": {"s1"}, "s2.users": {"s2"}, "s2.orders": {"s2"}, "extra": {"s2"}}, conv.Shards.Tables)
+	assert.Equal(t, []string{"extra", "s1_orders", "s1_users", "s2_orders", "s2_users"}, ddl.OrderTables(conv.SpSchema))
+	assert.Equal(t, "s2_users", conv.SpSchema["s2_orders"].Fks[0].ReferTable)
+	conv.SetShard("s2")
+	SetRowStats(conv, s2.InfoSchema)
+	assert.Equal(t, map[string]int64{"s2.users": 2, "s2.orders": 3, "extra": 4}, conv.Stats.Rows)
+
+	// Tables of shards with a name pattern aren't merged, and the pattern is
+	// applied to their tables and foreign keys.
+	for _, collision := range []string{internal.TableCollisionMerge, internal.TableCollisionFail} {
+		conv = newConv()
+		conv.Shards.Names = map[string]string{"s3": "billing_*"}
+		assert.Nil(t, ProcessShardSchemas(conv, []Shard{s2, s3}, collision))
+		assert.Equal(t, map[string][]string{"users": {"s2"}, "s3.users": {"s3"}, "orders": {"s2"}, "extra": {"s2"}}, conv.Shards.Tables)
+		assert.Equal(t, []string{"billing_users", "extra", "orders", "users"}, ddl.OrderTables(conv.SpSchema))
+		assert.Equal(t, []string{"shard_id", "id", "name"}, conv.SpSchema["billing_users"].ColNames)
+		assert.Equal(t, "fk_orders", conv.SpSchema["orders"].Fks[0].Name)
+	}
+	conv = newConv()
+	conv.Shards.Names = map[string]string{"s2": "billing_*"}
+	assert.Nil(t, ProcessShardSchemas(conv, []Shard{s1, s2}, internal.TableCollisionMerge))
+	assert.Equal(t, []string{"billing_extra", "billing_orders", "billing_users", "orders", "users"}, ddl.OrderTables(conv.SpSchema))
+	assert.Equal(t, ddl.Foreignkey{Name: "billing_fk_orders", Columns: []string{"shard_id", "users_id"}, ReferTable: "billing_users", ReferColumns: []string{"shard_id", "id"}}, conv.SpSchema["billing_orders"].Fks[0])
+	assert.Equal(t, "users", conv.SpSchema["orders"].Fks[0].ReferTable)
+}
+
+func TestProcessDataWithProgress(t *testing.T) {
+	isi := fakeInfoSchema{
+		tables: map[string][]string{"users": {"id"}, "orders": {"id", "users_id"}},
+		fks:    map[string]string{"orders": "users"},
+	}
+	conv := internal.MakeConv()
+	assert.Nil(t, ProcessSchema(conv, isi))
+	var events []string
+	flushes := 0
+	conv.DataFlush = func() {
+		flushes++
+		events = append(events, "flush")
+	}
+	progress := func(spTable string, done bool, err error) error {
+		assert.Nil(t, err)
+		if done {
+			events = append(events, spTable+" done")
+		} else {
+			events = append(events, spTable+" started")
+		}
+		return nil
+	}
+	ProcessDataWithProgress(context.Background(), conv, isi, progress)
+	// Tables that orders references are done before orders starts.
+	assert.Equal(t, []string{"users started", "flush", "users done", "orders started", "flush", "orders done"}, events)
+	assert.Equal(t, 2, flushes)
+
+	// The migration stops when progress returns an error.
+	events = nil
+	ProcessDataWithProgress(context.Background(), conv, isi, func(spTable string, done bool, err error) error {
+		progress(spTable, done, err)
+		if done {
+			return fmt.Errorf("cancelled")
+		}
+		return nil
+	})
+	assert.Equal(t, []string{"users started", "flush", "users done"}, events)
+}
+
+// fakeGrantInfoSchema is a fakeInfoSchema whose tables have grants.
+type fakeGrantInfoSchema struct {
+	fakeInfoSchema
+	grants []Grant
+}
+
+func (isi fakeGrantInfoSchema) GetGrants() ([]Grant, error) {
+	return isi.grants, nil
+}
+
+func TestProcessSchema_Grants(t *testing.T) {
+	isi := fakeGrantInfoSchema{
+		fakeInfoSchema: fakeInfoSchema{tables: map[string][]string{"users": {"id"}, "orders": {"id", "users_id"}}},
+		grants: []Grant{
+			{Table: SchemaAndName{Schema: "db"}, Grantee: "'reader'@'%'", Privileges: []string{"SELECT"}},
+			{Table: SchemaAndName{Schema: "db", Name: "orders"}, Columns: []string{"users_id"}, Grantee: "'app'@'%'", Privileges: []string{"UPDATE"}},
+			{Table: SchemaAndName{Schema: "db", Name: "gone"}, Grantee: "'app'@'%'", Privileges: []string{"SELECT"}},
+		},
+	}
+	conv := internal.MakeConv()
+	assert.Nil(t, ProcessSchema(conv, isi))
+	assert.Equal(t, []internal.Grant{
+		{Table: "orders", Grantee: "'reader'@'%'", Privileges: []string{"SELECT"}},
+		{Table: "users", Grantee: "'reader'@'%'", Privileges: []string{"SELECT"}},
+		{Table: "orders", Columns: []string{"users_id"}, Grantee: "'app'@'%'", Privileges: []string{"UPDATE"}},
+	}, conv.Grants)
+}
+
+func TestProcessSchema_SelectedTables(t *testing.T) {
+	isi := fakeInfoSchema{
+		tables: map[string][]string{"users": {"id"}, "orders": {"id", "users_id"}, "audit": {"id", "orders_id"}},
+		fks:    map[string]string{"orders": "users", "audit": "orders"},
+	}
+	conv := internal.MakeConv()
+	conv.SelectedTables = map[string]bool{"users": true, "audit": true}
+	assert.Nil(t, ProcessSchema(conv, isi))
+	var tables []string
+	for t := range conv.SrcSchema {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	assert.Equal(t, []string{"audit", "users"}, tables)
+	// The foreign key of audit references orders, which isn't migrated.
+	assert.Empty(t, conv.SrcSchema["audit"].ForeignKeys)
+}
+
+// fakeMergeInfoSchema is a fakeInfoSchema whose ProcessData writes a row
+// with the id of each table read.
+type fakeMergeInfoSchema struct {
+	fakeInfoSchema
+	read *[]string
+}
+
+func (isi fakeMergeInfoSchema) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	table := srcTable
+	if m, ok := conv.MergeMember(srcTable); ok {
+		table = m.Table
+	}
+	*isi.read = append(*isi.read, table)
+	conv.WriteRow(srcTable, spTable, []string{"id"}, []interface{}{int64(len(*isi.read))})
+	return nil
+}
+
+func TestProcessSchema_MergeTables(t *testing.T) {
+	isi := fakeMergeInfoSchema{
+		fakeInfoSchema: fakeInfoSchema{
+			tables: map[string][]string{"t_0001": {"id"}, "t_0002": {"id"}, "orders": {"id", "t_0001_id"}},
+			fks:    map[string]string{"orders": "t_0001"},
+			rows:   map[string]int64{"t_0001": 2, "t_0002": 3, "orders": 4},
+		},
+		read: &[]string{},
+	}
+	conv := internal.MakeConv()
+	conv.MergeRules = []internal.MergeRule{{Pattern: "t_*", Column: "tenant"}}
+	assert.Nil(t, ProcessSchema(conv, isi))
+	assert.Equal(t, []string{"orders", "t"}, ddl.OrderTables(conv.SpSchema))
+	assert.Equal(t, []string{"tenant", "id"}, conv.SpSchema["t"].ColNames)
+	assert.Equal(t, []ddl.IndexKey{{Col: "tenant"}, {Col: "id"}}, conv.SpSchema["t"].Pks)
+	assert.Equal(t, "t", conv.SpSchema["orders"].Fks[0].ReferTable)
+	assert.Equal(t, []internal.MergeMember{{Name: "t_0001", Schema: "db", Table: "t_0001", Key: "0001"}, {Name: "t_0002", Schema: "db", Table: "t_0002", Key: "0002"}}, conv.TableMerges["t"].Members)
+	SetRowStats(conv, isi)
+	assert.Equal(t, map[string]int64{"t": 5, "orders": 4}, conv.Stats.Rows)
+
+	written := make(map[string][][]interface{})
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		written[table] = append(written[table], append([]interface{}{cols}, vals...))
+	})
+	conv.SetDataMode()
+	ProcessData(context.Background(), conv, isi)
+	assert.Equal(t, []string{"t_0001", "t_0002", "orders"}, *isi.read)
+	assert.Equal(t, [][]interface{}{{[]string{"tenant", "id"}, "0001", int64(1)}, {[]string{"tenant", "id"}, "0002", int64(2)}}, written["t"])
+	assert.Equal(t, [][]interface{}{{[]string{"id"}, int64(3)}}, written["orders"])
+
+	// Tables with different columns can't be merged.
+	isi.tables["t_0003"] = []string{"id", "name"}
+	conv = internal.MakeConv()
+	conv.MergeRules = []internal.MergeRule{{Pattern: "t_*", Column: "tenant"}}
+	assert.NotNil(t, ProcessSchema(conv, isi))
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HistogramBucket is a bucket of a histogram of the keys of a table, built
+// from the statistics of the source database: about Rows rows have a key
+// greater than the upper bound of the previous bucket and at most UpperBound.
+type HistogramBucket struct {
+	UpperBound int64
+	Rows       float64
+}
+
+// PlanKeyRanges splits the keys from lo to hi into at most n contiguous
+// ranges holding roughly the same number of rows, according to histogram
+// buckets, which must be sorted by upper bound. Keys are assumed to be spread
+// evenly within a bucket. It also returns the estimated number of rows of
+// each range. Without buckets, it falls back to ranges of similar size, with
+// no estimates.
+func PlanKeyRanges(lo, hi int64, buckets []HistogramBucket, n int) ([][2]int64, []int64) {
+	var total float64
+	for _, b := range buckets {
+		total += b.Rows
+	}
+	if n <= 1 || total <= 0 {
+		return KeyRanges(lo, hi, n), nil
+	}
+	var ranges [][2]int64
+	var rows []int64
+	start, from := lo, float64(lo)
+	var cum, last float64
+	for _, b := range buckets {
+		upper := b.UpperBound
+		if upper < lo {
+			upper = lo
+		} else if upper > hi {
+			upper = hi
+		}
+		for len(ranges) < n-1 && b.Rows > 0 {
+			target := total * float64(len(ranges)+1) / float64(n)
+			if cum+b.Rows < target {
+				break
+			}
+			split := int64(from + (target-cum)/b.Rows*(float64(upper)-from))
+			if split < start {
+				split = start
+			}
+			if split >= hi {
+				break
+			}
+			ranges = append(ranges, [2]int64{start, split})
+			rows = append(rows, int64(target-last+0.5))
+			start, last = split+1, target
+		}
+		cum += b.Rows
+		from = float64(upper)
+	}
+	ranges = append(ranges, [2]int64{start, hi})
+	rows = append(rows, int64(total-last+0.5))
+	return ranges, rows
+}
+
+// DescribeKeyRanges returns a description of the plan for reading table
+// srcTable in ranges of keys, with the estimated number of rows of each range
+// if known.
+func DescribeKeyRanges(srcTable string, ranges [][2]int64, rows []int64) string {
+	var l []string
+	for i, r := range ranges {
+		if rows != nil {
+			l = append(l, fmt.Sprintf("[%d, %d] (~%d rows)", r[0], r[1], rows[i]))
+		} else {
+			l = append(l, fmt.Sprintf("[%d, %d]", r[0], r[1]))
+		}
+	}
+	source := "from statistics"
+	if rows == nil {
+		source = "by key (no statistics)"
+	}
+	return fmt.Sprintf("Reading table %s in %d key range(s) planned %s: %s", srcTable, len(ranges), source, strings.Join(l, ", "))
+}
@@ -0,0 +1,246 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package common
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Endpoint is a server of a source database: its primary or a read replica.
+type Endpoint struct {
+	Name    string // host:port, for messages.
+	DSN     string // Data source name of the database on the server.
+	Replica bool
+}
+
+// ReplicaMonitorInterval is the interval between checks of the health and
+// lag of read replicas. Connections are recycled at the same interval, so
+// that reads move to the preferred endpoint.
+var ReplicaMonitorInterval = 30 * time.Second
+
+// ReplicaLag returns the replication lag of the server db is connected to.
+type ReplicaLag func(ctx context.Context, db *sql.DB) (time.Duration, error)
+
+// Replicas is a database/sql connector that prefers connecting to read
+// replicas of a source database, to spare its primary the load of bulk
+// reads. Replicas that can't be reached, or lag behind the primary by more
+// than MaxLag, are skipped: connections fail over to the next replica, and
+// to the primary as a last resort. Replicas are checked every
+// ReplicaMonitorInterval, and used again once they recover.
+type Replicas struct {
+	MaxLag    time.Duration
+	driver    driver.Driver
+	endpoints []*endpoint // Replicas in order of preference, then the primary.
+	lag       ReplicaLag
+	out       io.Writer // Where failovers are printed.
+	mu        sync.Mutex
+	current   *endpoint // Endpoint of the last connection, if any.
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+type endpoint struct {
+	Endpoint
+	db      *sql.DB // For health checks.
+	healthy bool
+	reason  string // Why the endpoint isn't healthy.
+}
+
+// OpenWithReplicas opens a database of driver driverName whose connections
+// are made to replicas, in order of preference, rather than primary, as long
+// as they are healthy. lag measures the lag of a replica; maxLag is the
+// maximum lag of replicas used. The replicas are checked before it returns,
+// and then monitored until the database is closed.
+func OpenWithReplicas(driverName string, primary Endpoint, replicas []Endpoint, maxLag time.Duration, lag ReplicaLag) (*sql.DB, error) {
+	r, err := newReplicas(driverName, primary, replicas, maxLag, lag)
+	if err != nil {
+		return nil, err
+	}
+	r.check(context.Background())
+	go r.monitor()
+	db := sql.OpenDB(r)
+	db.SetConnMaxLifetime(ReplicaMonitorInterval)
+	return db, nil
+}
+
+func newReplicas(driverName string, primary Endpoint, replicas []Endpoint, maxLag time.Duration, lag ReplicaLag) (*Replicas, error) {
+	// database/sql doesn't expose registered drivers other than through a
+	// database.
+	db, err := sql.Open(driverName, primary.DSN)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replicas{MaxLag: maxLag, driver: db.Driver(), lag: lag, out: os.Stdout, stop: make(chan struct{})}
+	db.Close()
+	for _, e := range append(replicas, primary) {
+		db, err := sql.Open(driverName, e.DSN)
+		if err != nil {
+			return nil, err
+		}
+		// Each check makes a new connection, to check that one can be made.
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(0)
+		r.endpoints = append(r.endpoints, &endpoint{Endpoint: e, db: db, healthy: true})
+	}
+	return r, nil
+}
+
+// Connect connects to the first healthy replica, failing over to the next
+// ones, and to the primary, if it can't. It implements driver.Connector.
+func (r *Replicas) Connect(ctx context.Context) (driver.Conn, error) {
+	var firstErr error
+	for _, e := range r.endpoints {
+		r.mu.Lock()
+		healthy := e.healthy || !e.Replica
+		r.mu.Unlock()
+		if !healthy {
+			continue
+		}
+		conn, err := r.open(ctx, e.DSN)
+		if err == nil {
+			r.use(e)
+			return conn, nil
+		}
+		if !e.Replica {
+			return nil, err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		r.setHealth(e, false, fmt.Sprintf("can't connect: %v", err))
+	}
+	return nil, firstErr
+}
+
+// open opens a connection of the driver of r to the database dsn.
+func (r *Replicas) open(ctx context.Context, dsn string) (driver.Conn, error) {
+	if dc, ok := r.driver.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return c.Connect(ctx)
+	}
+	return r.driver.Open(dsn)
+}
+
+// Driver returns the driver of r. It implements driver.Connector.
+func (r *Replicas) Driver() driver.Driver {
+	return r.driver
+}
+
+// Close stops monitoring the replicas. database/sql calls it when the
+// database is closed.
+func (r *Replicas) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	for _, e := range r.endpoints {
+		e.db.Close()
+	}
+	return nil
+}
+
+// Current returns the endpoint of the last connection, or nil if there is
+// none.
+func (r *Replicas) Current() *Endpoint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current == nil {
+		return nil
+	}
+	return &r.current.Endpoint
+}
+
+// use records that e is the endpoint of the last connection, and reports
+// changes of endpoint.
+func (r *Replicas) use(e *endpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current == e {
+		return
+	}
+	switch {
+	case r.current == nil && e.Replica:
+		fmt.Fprintf(r.out, "Reading from source replica %s.\n", e.Name)
+	case r.current == nil:
+		fmt.Fprintf(r.out, "Reading from source primary %s: no replica is available.\n", e.Name)
+	default:
+		what := "replica"
+		if !e.Replica {
+			what = "primary"
+		}
+		fmt.Fprintf(r.out, "Source reads moved from %s to %s %s.\n", r.current.Name, what, e.Name)
+	}
+	r.current = e
+}
+
+// setHealth records whether replica e is healthy, and why not, reporting
+// changes.
+func (r *Replicas) setHealth(e *endpoint, healthy bool, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e.healthy == healthy {
+		e.reason = reason
+		return
+	}
+	e.healthy, e.reason = healthy, reason
+	if healthy {
+		fmt.Fprintf(r.out, "Source replica %s is healthy again.\n", e.Name)
+	} else {
+		fmt.Fprintf(r.out, "Source replica %s is skipped (%s): failing over.\n", e.Name, reason)
+	}
+}
+
+// check checks the health and lag of each replica.
+func (r *Replicas) check(ctx context.Context) {
+	for _, e := range r.endpoints {
+		if !e.Replica {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, ReplicaMonitorInterval)
+		lag, err := r.lag(ctx, e.db)
+		cancel()
+		switch {
+		case err != nil:
+			r.setHealth(e, false, fmt.Sprintf("can't get its lag: %v", err))
+		case r.MaxLag > 0 && lag > r.MaxLag:
+			r.setHealth(e, false, fmt.Sprintf("it lags %s behind the primary, more than %s", lag.Round(time.Second), r.MaxLag))
+		default:
+			r.setHealth(e, true, "")
+		}
+	}
+}
+
+// monitor checks the replicas every ReplicaMonitorInterval until r is
+// closed.
+func (r *Replicas) monitor() {
+	t := time.NewTicker(ReplicaMonitorInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			r.check(context.Background())
+		case <-r.stop:
+			return
+		}
+	}
+}
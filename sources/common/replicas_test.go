@@ -0,0 +1,117 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver connects to any data source name that isn't down.
+type fakeDriver struct {
+	mu   sync.Mutex
+	down map[string]bool
+}
+
+type fakeConn struct{ dsn string }
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down[dsn] {
+		return nil, errors.New("connection refused")
+	}
+	return fakeConn{dsn: dsn}, nil
+}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+var replicasDriver = &fakeDriver{down: make(map[string]bool)}
+
+func init() {
+	sql.Register("replicas-test", replicasDriver)
+}
+
+func TestReplicas(t *testing.T) {
+	lags := make(map[*sql.DB]time.Duration)
+	lag := func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+		if err := db.PingContext(ctx); err != nil {
+			return 0, err
+		}
+		return lags[db], nil
+	}
+	r, err := newReplicas("replicas-test", Endpoint{Name: "primary", DSN: "p"},
+		[]Endpoint{{Name: "r1", DSN: "r1", Replica: true}, {Name: "r2", DSN: "r2", Replica: true}}, time.Minute, lag)
+	assert.Nil(t, err)
+	defer r.Close()
+	var out bytes.Buffer
+	r.out = &out
+	ctx := context.Background()
+	connect := func() string {
+		c, err := r.Connect(ctx)
+		assert.Nil(t, err)
+		return c.(fakeConn).dsn
+	}
+
+	r.check(ctx)
+	assert.Equal(t, "r1", connect())
+	assert.Equal(t, "r1", r.Current().Name)
+
+	// An unavailable replica fails over to the next one, even before it's
+	// checked.
+	replicasDriver.mu.Lock()
+	replicasDriver.down["r1"] = true
+	replicasDriver.mu.Unlock()
+	assert.Equal(t, "r2", connect())
+
+	// A lagging replica fails over to the primary.
+	lags[r.endpoints[1].db] = 5 * time.Minute
+	r.check(ctx)
+	assert.Equal(t, "p", connect())
+
+	// Replicas are used again once they recover.
+	replicasDriver.mu.Lock()
+	delete(replicasDriver.down, "r1")
+	replicasDriver.mu.Unlock()
+	r.check(ctx)
+	assert.Equal(t, "r1", connect())
+
+	assert.Equal(t, "Reading from source replica r1.\n"+
+		"Source replica r1 is skipped (can't connect: connection refused): failing over.\n"+
+		"Source reads moved from r1 to replica r2.\n"+
+		"Source replica r2 is skipped (it lags 5m0s behind the primary, more than 1m0s): failing over.\n"+
+		"Source reads moved from r2 to primary primary.\n"+
+		"Source replica r1 is healthy again.\n"+
+		"Source reads moved from primary to replica r1.\n", out.String())
+}
+
+func TestOpenWithReplicas(t *testing.T) {
+	lag := func(ctx context.Context, db *sql.DB) (time.Duration, error) { return 0, nil }
+	db, err := OpenWithReplicas("replicas-test", Endpoint{Name: "primary", DSN: "p"}, []Endpoint{{Name: "r3", DSN: "r3", Replica: true}}, 0, lag)
+	assert.Nil(t, err)
+	assert.Nil(t, db.Ping())
+	assert.Nil(t, db.Close())
+}
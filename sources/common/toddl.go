@@ -32,6 +32,7 @@ package common
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"unicode"
 
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
@@ -83,14 +84,25 @@ func SchemaToSpannerDDL(conv *internal.Conv, toddl ToDdl) error {
 			if srcCol.Ignored.AutoIncrement { //TODO(adibh) - check why this is not there in postgres
 				issues = append(issues, internal.AutoIncrement)
 			}
+			check, ok := cvtChecks(srcCol.Type.Checks)
+			if !ok {
+				issues = append(issues, internal.DomainCheck)
+			}
+			var seq *ddl.Sequence
+			if srcCol.Sequence != nil && ty.Name == ddl.Int64 && !ty.IsArray {
+				seq = internal.ToSpannerSequence(conv, spTableName, colName, *srcCol.Sequence)
+				issues = append(issues, internal.SourceSequence)
+			}
 			if len(issues) > 0 {
 				conv.Issues[srcTable.Name][srcCol.Name] = issues
 			}
 			spColDef[colName] = ddl.ColumnDef{
-				Name:    colName,
-				T:       ty,
-				NotNull: srcCol.NotNull,
-				Comment: "From: " + quoteIfNeeded(srcCol.Name) + " " + srcCol.Type.Print(),
+				Name:     colName,
+				T:        ty,
+				NotNull:  srcCol.NotNull,
+				Comment:  "From: " + quoteIfNeeded(srcCol.Name) + " " + srcCol.Type.Print(),
+				Sequence: seq,
+				Check:    check,
 			}
 		}
 		comment := "Spanner schema for source table " + quoteIfNeeded(srcTable.Name)
@@ -100,13 +112,31 @@ func SchemaToSpannerDDL(conv *internal.Conv, toddl ToDdl) error {
 			ColDefs:  spColDef,
 			Pks:      cvtPrimaryKeys(conv, srcTable.Name, srcTable.PrimaryKeys),
 			Fks:      cvtForeignKeys(conv, spTableName, srcTable.Name, srcTable.ForeignKeys),
-			Indexes:  cvtIndexes(conv, spTableName, srcTable.Name, srcTable.Indexes),
+			Indexes:  cvtIndexes(conv, spTableName, srcTable.Name, srcTable.Indexes, spColDef),
 			Comment:  comment}
 	}
 	internal.ResolveRefs(conv)
+	internal.AddCollationIssues(conv, internal.DetectCollationImpacts(conv))
 	return nil
 }
 
+// cvtChecks converts the CHECK constraints of a domain, boolean expressions on
+// VALUE, to a single Spanner CHECK expression. Constraints that use source
+// specific syntax, such as PostgreSQL casts, arrays and regular expression
+// operators, are dropped, and cvtChecks returns false.
+func cvtChecks(checks []string) (string, bool) {
+	var l []string
+	ok := true
+	for _, c := range checks {
+		if strings.Contains(c, "::") || strings.Contains(c, "~") || strings.Contains(c, "ARRAY[") {
+			ok = false
+			continue
+		}
+		l = append(l, c)
+	}
+	return strings.Join(l, " AND "), ok
+}
+
 func quoteIfNeeded(s string) string {
 	for _, r := range s {
 		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsPunct(r) {
@@ -153,7 +183,7 @@ func cvtForeignKeys(conv *internal.Conv, spTableName string, srcTable string, sr
 			spCols = append(spCols, spCol)
 			spReferCols = append(spReferCols, spReferCol)
 		}
-		spKeyName := internal.ToSpannerForeignKey(conv, key.Name)
+		spKeyName := internal.ToSpannerForeignKey(conv, conv.ShardSpannerName(srcTable, key.Name))
 
 		spKey := ddl.Foreignkey{
 			Name:         spKeyName,
@@ -168,10 +198,15 @@ func cvtForeignKeys(conv *internal.Conv, spTableName string, srcTable string, sr
 	return spKeys
 }
 
-func cvtIndexes(conv *internal.Conv, spTableName string, srcTable string, srcIndexes []schema.Index) []ddl.CreateIndex {
+// cvtIndexes converts source indexes to Spanner indexes. Unique indexes with a
+// nullable key column are null filtered: source databases typically allow
+// multiple rows with NULL values in a UNIQUE column, whereas Spanner's unique
+// indexes treat NULLs as equal.
+func cvtIndexes(conv *internal.Conv, spTableName string, srcTable string, srcIndexes []schema.Index, spColDef map[string]ddl.ColumnDef) []ddl.CreateIndex {
 	var spIndexes []ddl.CreateIndex
 	for _, srcIndex := range srcIndexes {
 		var spKeys []ddl.IndexKey
+		nullable := false
 
 		for _, k := range srcIndex.Keys {
 			spCol, err := internal.GetSpannerCol(conv, srcTable, k.Column, true)
@@ -180,18 +215,22 @@ func cvtIndexes(conv *internal.Conv, spTableName string, srcTable string, srcInd
 				continue
 			}
 			spKeys = append(spKeys, ddl.IndexKey{Col: spCol, Desc: k.Desc})
+			if !spColDef[spCol].NotNull {
+				nullable = true
+			}
 		}
 		if srcIndex.Name == "" {
 			// Generate a name if index name is empty in MySQL.
 			// Collision of index name will be handled by ToSpannerIndexName.
 			srcIndex.Name = fmt.Sprintf("Index_%s", srcTable)
 		}
-		spIndexName := internal.ToSpannerIndexName(conv, srcIndex.Name)
+		spIndexName := internal.ToSpannerIndexName(conv, conv.ShardSpannerName(srcTable, srcIndex.Name))
 		spIndex := ddl.CreateIndex{
-			Name:   spIndexName,
-			Table:  spTableName,
-			Unique: srcIndex.Unique,
-			Keys:   spKeys,
+			Name:         spIndexName,
+			Table:        spTableName,
+			Unique:       srcIndex.Unique,
+			NullFiltered: srcIndex.Unique && nullable,
+			Keys:         spKeys,
 		}
 		spIndexes = append(spIndexes, spIndex)
 		conv.Audit.ToSpannerFkIdx[srcTable].Index[srcIndex.Name] = spIndexName
@@ -0,0 +1,163 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// MapFunc maps a source type with modifiers mods (e.g. the length of a
+// varchar) to a Spanner type, and returns the issues of the conversion.
+type MapFunc func(mods []int64) (ddl.Type, []internal.SchemaIssue)
+
+// To returns a MapFunc that maps any modifiers to t, with issues.
+func To(t ddl.Type, issues ...internal.SchemaIssue) MapFunc {
+	return func(mods []int64) (ddl.Type, []internal.SchemaIssue) {
+		return t, append([]internal.SchemaIssue(nil), issues...)
+	}
+}
+
+// ToSized returns a MapFunc that maps to Spanner type name, with the length
+// given by the first modifier, or length if there is none.
+func ToSized(name string, length int64, issues ...internal.SchemaIssue) MapFunc {
+	return func(mods []int64) (ddl.Type, []internal.SchemaIssue) {
+		ty := ddl.Type{Name: name, Len: length}
+		if len(mods) > 0 && mods[0] > 0 {
+			ty.Len = mods[0]
+		}
+		return ty, append([]internal.SchemaIssue(nil), issues...)
+	}
+}
+
+// Commonly used Spanner types.
+var (
+	MaxString = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
+	MaxBytes  = ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}
+)
+
+// TypeMapping declares how a source type maps to Spanner types.
+type TypeMapping struct {
+	// Default maps the source type to its default Spanner type.
+	Default MapFunc
+	// Options maps the names of the other Spanner types that the source type
+	// can be mapped to.
+	Options map[string]MapFunc
+	// Conditional describes the Spanner types that the source type can only
+	// be mapped to for some modifiers, e.g. MySQL's tinyint(1) to BOOL.
+	Conditional map[string]string
+}
+
+// TypeMap declares the mapping of the types of a source to Spanner types.
+// It is shared by schema conversion, which uses the default Spanner types,
+// and the web apps, which let users choose between the Spanner types each
+// source type can be mapped to.
+type TypeMap struct {
+	Types map[string]TypeMapping
+	// Match optionally returns the key in Types of source types that
+	// aren't in Types, e.g. for source types that include their modifiers.
+	Match func(srcType string) (string, bool)
+}
+
+// TypeOption is a Spanner type that a source type can be mapped to.
+type TypeOption struct {
+	Name   string
+	Issues []internal.SchemaIssue
+	// Condition is set for Spanner types that the source type can only be
+	// mapped to for some modifiers, and describes them.
+	Condition string
+}
+
+// SpannerTypeNames lists the Spanner types in the order in which type
+// options are listed.
+var SpannerTypeNames = []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric, ddl.JSON}
+
+func (m TypeMap) mapping(srcType string) (TypeMapping, bool) {
+	if tm, found := m.Types[srcType]; found {
+		return tm, true
+	}
+	if m.Match != nil {
+		if key, found := m.Match(srcType); found {
+			tm, found := m.Types[key]
+			return tm, found
+		}
+	}
+	return TypeMapping{}, false
+}
+
+// ToSpannerType maps srcType with modifiers mods to Spanner type spType if
+// srcType can be mapped to it, and otherwise to the default Spanner type of
+// srcType. Source types without mapping are mapped to STRING.
+func (m TypeMap) ToSpannerType(srcType, spType string, mods []int64) (ddl.Type, []internal.SchemaIssue) {
+	tm, found := m.mapping(srcType)
+	if !found {
+		return MaxString, []internal.SchemaIssue{internal.NoGoodType}
+	}
+	if f, found := tm.Options[spType]; found {
+		return f(mods)
+	}
+	return tm.Default(mods)
+}
+
+// TypeOptions returns the Spanner types that srcType (without modifiers)
+// can be mapped to, ordered as in SpannerTypeNames, followed by the
+// conditional ones.
+func (m TypeMap) TypeOptions(srcType string) []TypeOption {
+	var l []TypeOption
+	for _, name := range SpannerTypeNames {
+		if ty, issues := m.ToSpannerType(srcType, name, []int64{}); ty.Name == name {
+			l = append(l, TypeOption{Name: name, Issues: issues})
+		}
+	}
+	tm, _ := m.mapping(srcType)
+	for _, name := range SpannerTypeNames {
+		if c, found := tm.Conditional[name]; found {
+			l = append(l, TypeOption{Name: name, Condition: c})
+		}
+	}
+	return l
+}
+
+// TypeGroup declares a mapping shared by several source types.
+type TypeGroup struct {
+	SrcTypes []string
+	TypeMapping
+}
+
+// NewTypeMap returns a TypeMap that maps the source types of each group
+// with the mapping of the group.
+func NewTypeMap(groups ...TypeGroup) TypeMap {
+	m := TypeMap{Types: make(map[string]TypeMapping)}
+	for _, g := range groups {
+		for _, srcType := range g.SrcTypes {
+			m.Types[srcType] = g.TypeMapping
+		}
+	}
+	return m
+}
+
+// With returns a TypeMap that maps source types as m does, and the source
+// types of each group with the mapping of the group, e.g. for a source that
+// extends the types of another one.
+func (m TypeMap) With(groups ...TypeGroup) TypeMap {
+	w := NewTypeMap(groups...)
+	for srcType, tm := range m.Types {
+		if _, found := w.Types[srcType]; !found {
+			w.Types[srcType] = tm
+		}
+	}
+	w.Match = m.Match
+	return w
+}
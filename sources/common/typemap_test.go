@@ -0,0 +1,100 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func TestTypeMap(t *testing.T) {
+	m := NewTypeMap(
+		TypeGroup{SrcTypes: []string{"int", "integer"}, TypeMapping: TypeMapping{
+			Default: To(ddl.Type{Name: ddl.Int64}, internal.Widened),
+			Options: map[string]MapFunc{ddl.String: To(MaxString, internal.Widened)},
+		}},
+		TypeGroup{SrcTypes: []string{"varchar"}, TypeMapping: TypeMapping{
+			Default: ToSized(ddl.String, ddl.MaxLength),
+			Options: map[string]MapFunc{ddl.Bytes: ToSized(ddl.Bytes, ddl.MaxLength)},
+		}},
+		TypeGroup{SrcTypes: []string{"tinyint"}, TypeMapping: TypeMapping{
+			Default:     To(ddl.Type{Name: ddl.Int64}),
+			Conditional: map[string]string{ddl.Bool: "Only tinyint(1)"},
+		}},
+	)
+	m.Match = func(srcType string) (string, bool) {
+		if strings.HasPrefix(srcType, "varchar") {
+			return "varchar", true
+		}
+		return "", false
+	}
+	tc := []struct {
+		name     string
+		srcType  string
+		spType   string
+		mods     []int64
+		expected ddl.Type
+		issues   []internal.SchemaIssue
+	}{
+		{"Test default", "integer", "", nil, ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}},
+		{"Test option", "int", ddl.String, nil, MaxString, []internal.SchemaIssue{internal.Widened}},
+		{"Test type not offered", "int", ddl.Date, nil, ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}},
+		{"Test sized", "varchar", "", []int64{42}, ddl.Type{Name: ddl.String, Len: 42}, nil},
+		{"Test sized without mods", "varchar", ddl.Bytes, nil, MaxBytes, nil},
+		{"Test match", "varchar2", "", []int64{7}, ddl.Type{Name: ddl.String, Len: 7}, nil},
+		{"Test unknown type", "geometry", ddl.Int64, nil, MaxString, []internal.SchemaIssue{internal.NoGoodType}},
+	}
+	for _, tc := range tc {
+		ty, issues := m.ToSpannerType(tc.srcType, tc.spType, tc.mods)
+		assert.Equal(t, tc.expected, ty, tc.name)
+		assert.Equal(t, tc.issues, issues, tc.name)
+	}
+
+	assert.Equal(t, []TypeOption{
+		{Name: ddl.Int64, Issues: []internal.SchemaIssue{internal.Widened}},
+		{Name: ddl.String, Issues: []internal.SchemaIssue{internal.Widened}},
+	}, m.TypeOptions("int"))
+	assert.Equal(t, []TypeOption{{Name: ddl.Bytes}, {Name: ddl.String}}, m.TypeOptions("varchar(10)"))
+	assert.Equal(t, []TypeOption{{Name: ddl.Int64}, {Name: ddl.Bool, Condition: "Only tinyint(1)"}}, m.TypeOptions("tinyint"))
+	assert.Equal(t, []TypeOption{{Name: ddl.String, Issues: []internal.SchemaIssue{internal.NoGoodType}}}, m.TypeOptions("geometry"))
+}
+
+func TestTypeMapWith(t *testing.T) {
+	m := NewTypeMap(
+		TypeGroup{SrcTypes: []string{"int"}, TypeMapping: TypeMapping{Default: To(ddl.Type{Name: ddl.Int64})}},
+		TypeGroup{SrcTypes: []string{"text"}, TypeMapping: TypeMapping{Default: To(MaxString)}},
+	)
+	w := m.With(
+		TypeGroup{SrcTypes: []string{"uuid"}, TypeMapping: TypeMapping{Default: ToSized(ddl.String, 36)}},
+		TypeGroup{SrcTypes: []string{"text"}, TypeMapping: TypeMapping{Default: To(MaxBytes)}},
+	)
+	ty, _ := w.ToSpannerType("int", "", nil)
+	assert.Equal(t, ddl.Type{Name: ddl.Int64}, ty)
+	ty, _ = w.ToSpannerType("uuid", "", nil)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 36}, ty)
+	ty, _ = w.ToSpannerType("text", "", nil)
+	assert.Equal(t, MaxBytes, ty)
+	// m is unchanged.
+	ty, issues := m.ToSpannerType("uuid", "", nil)
+	assert.Equal(t, MaxString, ty)
+	assert.Equal(t, []internal.SchemaIssue{internal.NoGoodType}, issues)
+	ty, _ = m.ToSpannerType("text", "", nil)
+	assert.Equal(t, MaxString, ty)
+}
@@ -46,3 +46,22 @@ func GetColsAndSchemas(conv *internal.Conv, srcTable string) (schema.Table, stri
 	}
 	return srcSchema, spTable, spCols, spSchema, err
 }
+
+// KeyRanges splits the keys from lo to hi into at most n contiguous ranges of
+// similar size, so that the rows of a table can be read by several readers in
+// parallel.
+func KeyRanges(lo, hi int64, n int) [][2]int64 {
+	if n <= 1 {
+		return [][2]int64{{lo, hi}}
+	}
+	span := uint64(hi) - uint64(lo)
+	step := span/uint64(n) + 1
+	var ranges [][2]int64
+	for off := uint64(0); ; off += step {
+		start := int64(uint64(lo) + off)
+		if span-off < step {
+			return append(ranges, [2]int64{start, hi})
+		}
+		ranges = append(ranges, [2]int64{start, int64(uint64(lo) + off + step - 1)})
+	}
+}
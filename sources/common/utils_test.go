@@ -15,6 +15,7 @@
 package common
 
 import (
+	"math"
 	"reflect"
 	"testing"
 
@@ -121,3 +122,33 @@ func TestGetColsAndSchemas(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyRanges(t *testing.T) {
+	assert.Equal(t, [][2]int64{{1, 4}, {5, 8}, {9, 10}}, KeyRanges(1, 10, 3))
+	assert.Equal(t, [][2]int64{{1, 10}}, KeyRanges(1, 10, 1))
+	assert.Equal(t, [][2]int64{{7, 7}}, KeyRanges(7, 7, 4))
+	assert.Equal(t, [][2]int64{{math.MinInt64, -1}, {0, math.MaxInt64}}, KeyRanges(math.MinInt64, math.MaxInt64, 2))
+}
+
+func TestPlanKeyRanges(t *testing.T) {
+	buckets := []HistogramBucket{{UpperBound: 10, Rows: 100}, {UpperBound: 20, Rows: 100}, {UpperBound: 1000, Rows: 100}, {UpperBound: 2000, Rows: 100}}
+	ranges, rows := PlanKeyRanges(1, 2000, buckets, 4)
+	assert.Equal(t, [][2]int64{{1, 10}, {11, 20}, {21, 1000}, {1001, 2000}}, ranges)
+	assert.Equal(t, []int64{100, 100, 100, 100}, rows)
+	assert.Equal(t, "Reading table t in 4 key range(s) planned from statistics: [1, 10] (~100 rows), [11, 20] (~100 rows), [21, 1000] (~100 rows), [1001, 2000] (~100 rows)", DescribeKeyRanges("t", ranges, rows))
+
+	// Splits interpolate within a bucket.
+	ranges, rows = PlanKeyRanges(0, 100, []HistogramBucket{{UpperBound: 100, Rows: 1000}}, 2)
+	assert.Equal(t, [][2]int64{{0, 50}, {51, 100}}, ranges)
+	assert.Equal(t, []int64{500, 500}, rows)
+
+	// A single hot key doesn't yield empty ranges.
+	ranges, _ = PlanKeyRanges(1, 3, []HistogramBucket{{UpperBound: 1, Rows: 1000}, {UpperBound: 3, Rows: 1}}, 4)
+	assert.Equal(t, [][2]int64{{1, 1}, {2, 2}, {3, 3}}, ranges)
+
+	// Without statistics, ranges have similar sizes.
+	ranges, rows = PlanKeyRanges(1, 10, nil, 3)
+	assert.Equal(t, KeyRanges(1, 10, 3), ranges)
+	assert.Nil(t, rows)
+	assert.Equal(t, "Reading table t in 3 key range(s) planned by key (no statistics): [1, 4], [5, 8], [9, 10]", DescribeKeyRanges("t", ranges, rows))
+}
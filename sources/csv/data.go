@@ -175,16 +175,30 @@ func getCSVDataRowCount(r *csvReader.Reader, colNames []string) (int64, error) {
 // ProcessCSV writes data across the tables provided in the manifest file. Each table's data can be provided
 // across multiple CSV files hence, the manifest accepts a list of file paths in the input.
 func ProcessCSV(conv *internal.Conv, tables []utils.ManifestTable, nullStr string, delimiter rune) error {
-	orderedTableNames := ddl.OrderTables(conv.SpSchema)
 	nameToFiles := map[string][]string{}
 	for _, table := range tables {
 		nameToFiles[table.Table_name] = table.File_patterns
 	}
-	orderedTables := []utils.ManifestTable{}
-	for _, name := range orderedTableNames {
-		orderedTables = append(orderedTables, utils.ManifestTable{name, nameToFiles[name]})
+	// Tables are loaded in dependency order (see ddl.LoadOrder), waiting for
+	// writes to complete only between levels.
+	for _, level := range ddl.LoadOrder(conv.SpSchema) {
+		var orderedTables []utils.ManifestTable
+		for _, name := range level {
+			orderedTables = append(orderedTables, utils.ManifestTable{name, nameToFiles[name]})
+		}
+		if err := processCSVTables(conv, orderedTables, nullStr, delimiter); err != nil {
+			return err
+		}
+		if conv.DataFlush != nil {
+			conv.DataFlush()
+		}
 	}
+	return nil
+}
 
+// processCSVTables writes the data of tables, which must not depend on each
+// other.
+func processCSVTables(conv *internal.Conv, orderedTables []utils.ManifestTable, nullStr string, delimiter rune) error {
 	for _, table := range orderedTables {
 		for _, filePath := range table.File_patterns {
 			csvFile, err := os.Open(filePath)
@@ -223,9 +237,6 @@ func ProcessCSV(conv *internal.Conv, tables []utils.ManifestTable, nullStr strin
 				processDataRow(conv, nullStr, table.Table_name, colNames, values)
 			}
 		}
-		if conv.DataFlush != nil {
-			conv.DataFlush()
-		}
 	}
 	return nil
 }
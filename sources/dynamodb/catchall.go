@@ -0,0 +1,95 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+// typeCatchAll is the type of the catch-all column of a table, which holds
+// the attributes without a column of their own as a JSON object.
+const typeCatchAll = "CatchAll"
+
+// catchAllColumn is the name of the catch-all column of a table, unless an
+// attribute has that name already.
+const catchAllColumn = "other_attributes"
+
+// applyCatchAll replaces the columns of the attributes whose frequency in
+// census is below threshold percent by a catch-all column. Key columns, i.e.
+// the attributes of the table's and its indexes' keys, are kept. It returns
+// the updated column definitions and names, and the name of the catch-all
+// column, or "" if no attribute is sparse.
+func applyCatchAll(colDefs map[string]schema.Column, colNames []string, census internal.AttributeCensus, threshold float64, keys map[string]bool) (map[string]schema.Column, []string, string) {
+	var kept []string
+	var sparse bool
+	for _, col := range colNames {
+		if !keys[col] && 100*census.Frequency(col) < threshold {
+			delete(colDefs, col)
+			sparse = true
+			continue
+		}
+		kept = append(kept, col)
+	}
+	if !sparse {
+		return colDefs, colNames, ""
+	}
+	name := catchAllColumn
+	for census.Attributes[name] > 0 || colDefs[name].Name != "" {
+		name += "_"
+	}
+	colDefs[name] = schema.Column{Name: name, Type: schema.Type{Name: typeCatchAll}}
+	return colDefs, append(kept, name), name
+}
+
+// hasCatchAll returns true if srcSchema has a catch-all column.
+func hasCatchAll(srcSchema schema.Table) bool {
+	for _, cd := range srcSchema.ColDefs {
+		if cd.Type.Name == typeCatchAll {
+			return true
+		}
+	}
+	return false
+}
+
+// catchAllValue returns the value of the catch-all column of srcSchema for
+// item attrsMap: a JSON object of the attributes without a column of their
+// own, or nil if there are none.
+func catchAllValue(attrsMap map[string]*dynamodb.AttributeValue, srcSchema schema.Table) (interface{}, error) {
+	obj := make(map[string]interface{})
+	for name, attr := range attrsMap {
+		if _, ok := srcSchema.ColDefs[name]; ok || attr == nil || attr.NULL != nil {
+			continue
+		}
+		val, err := stripNull(attr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %v to a go struct", attr.GoString())
+		}
+		obj[name] = val
+	}
+	if len(obj) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %v to a json string", obj)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func TestApplyCatchAll(t *testing.T) {
+	colDefs := func() map[string]schema.Column {
+		return map[string]schema.Column{
+			"a": {Name: "a", Type: schema.Type{Name: typeString}},
+			"b": {Name: "b", Type: schema.Type{Name: typeNumber}},
+			"c": {Name: "c", Type: schema.Type{Name: typeString}},
+			"d": {Name: "d", Type: schema.Type{Name: typeString}},
+		}
+	}
+	census := internal.AttributeCensus{Items: 1000, Attributes: map[string]int64{"a": 1000, "b": 900, "c": 5, "d": 1}}
+	keys := map[string]bool{"a": true, "d": true}
+
+	cols, names, catchAll := applyCatchAll(colDefs(), []string{"a", "b", "c", "d"}, census, 1, keys)
+	assert.Equal(t, catchAllColumn, catchAll)
+	assert.Equal(t, []string{"a", "b", "d", catchAllColumn}, names)
+	assert.Equal(t, schema.Column{Name: catchAllColumn, Type: schema.Type{Name: typeCatchAll}}, cols[catchAllColumn])
+	_, ok := cols["c"]
+	assert.False(t, ok)
+
+	// No sparse attributes.
+	cols, names, catchAll = applyCatchAll(colDefs(), []string{"a", "b", "c", "d"}, census, 0.1, keys)
+	assert.Equal(t, "", catchAll)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, names)
+	assert.Equal(t, colDefs(), cols)
+
+	// An attribute has the catch-all column's name.
+	census.Attributes[catchAllColumn] = 1
+	_, names, catchAll = applyCatchAll(colDefs(), []string{"a", "b", "c", "d"}, census, 1, keys)
+	assert.Equal(t, catchAllColumn+"_", catchAll)
+	assert.Equal(t, []string{"a", "b", "d", catchAllColumn + "_"}, names)
+}
+
+func catchAllSchemas() (schema.Table, ddl.CreateTable) {
+	srcSchema := schema.Table{
+		Name:     "testtable",
+		ColNames: []string{"a", catchAllColumn},
+		ColDefs: map[string]schema.Column{
+			"a":            {Name: "a", Type: schema.Type{Name: typeString}},
+			catchAllColumn: {Name: catchAllColumn, Type: schema.Type{Name: typeCatchAll}},
+		},
+		PrimaryKeys: []schema.Key{{Column: "a"}},
+	}
+	spSchema := ddl.CreateTable{
+		Name:     "testtable",
+		ColNames: []string{"a", catchAllColumn},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a":            {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			catchAllColumn: {Name: catchAllColumn, T: ddl.Type{Name: ddl.JSON}},
+		},
+		Pks: []ddl.IndexKey{{Col: "a"}},
+	}
+	return srcSchema, spSchema
+}
+
+func TestCvtRow_CatchAll(t *testing.T) {
+	srcSchema, spSchema := catchAllSchemas()
+	spVals, badCols, _ := cvtRow(map[string]*dynamodb.AttributeValue{
+		"a": {S: aws.String("key")},
+		"x": {N: aws.String("1.5")},
+		"y": {M: map[string]*dynamodb.AttributeValue{"k": {S: aws.String("v")}}},
+		"z": {NULL: aws.Bool(true)},
+	}, srcSchema, spSchema, spSchema.ColNames)
+	assert.Empty(t, badCols)
+	assert.Equal(t, []interface{}{"key", `{"x":"1.5","y":{"k":"v"}}`}, spVals)
+
+	spVals, badCols, _ = cvtRow(map[string]*dynamodb.AttributeValue{"a": {S: aws.String("key")}}, srcSchema, spSchema, spSchema.ColNames)
+	assert.Empty(t, badCols)
+	assert.Equal(t, []interface{}{"key", nil}, spVals)
+}
+
+func TestUnknownAttributes_CatchAll(t *testing.T) {
+	srcSchema, spSchema := catchAllSchemas()
+	conv := buildConv(spSchema, srcSchema)
+	streamInfo := MakeStreamingInfo()
+	image := map[string]*dynamodb.AttributeValue{"a": {S: aws.String("key")}, "x": {N: aws.String("1")}}
+	assert.Nil(t, unknownAttributes(conv, streamInfo, "testtable", image))
+}
+
+func TestToSpannerType_CatchAll(t *testing.T) {
+	conv := internal.MakeConv()
+	ty, _ := ToDdlImpl{}.ToSpannerType(conv, schema.Type{Name: typeCatchAll})
+	assert.Equal(t, ddl.Type{Name: ddl.JSON}, ty)
+	conv.TargetDb = constants.TargetExperimentalPostgres
+	ty, _ = ToDdlImpl{}.ToSpannerType(conv, schema.Type{Name: typeCatchAll})
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, ty)
+}
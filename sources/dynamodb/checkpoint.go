@@ -0,0 +1,72 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/errs"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+)
+
+// checkpointTable is the Spanner side table used to store the sequence numbers of
+// DynamoDB Streams records already applied to Cloud Spanner. It is only created
+// when exactly-once processing of DynamoDB Streams is enabled.
+const checkpointTable = "hb_dynamodb_stream_checkpoints"
+
+var checkpointCols = []string{"SourceTable", "SequenceNumber", "AppliedAt"}
+
+// checkpointTableDDL returns the CREATE TABLE statement for the checkpoint table.
+func checkpointTableDDL(targetDb string) string {
+	if targetDb == constants.TargetExperimentalPostgres {
+		return fmt.Sprintf(`CREATE TABLE "%s" (
+	"SourceTable" VARCHAR(2621440) NOT NULL,
+	"SequenceNumber" VARCHAR(2621440) NOT NULL,
+	"AppliedAt" SPANNER.COMMIT_TIMESTAMP NOT NULL,
+	PRIMARY KEY ("SourceTable", "SequenceNumber")
+)`, checkpointTable)
+	}
+	return fmt.Sprintf("CREATE TABLE `%s` (\n"+
+		"\t`SourceTable` STRING(MAX) NOT NULL,\n"+
+		"\t`SequenceNumber` STRING(MAX) NOT NULL,\n"+
+		"\t`AppliedAt` TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true),\n"+
+		") PRIMARY KEY (`SourceTable`, `SequenceNumber`)", checkpointTable)
+}
+
+// createSideTable creates the side table named table with statement stmt in the
+// Spanner database dbURI. An already existing side table (e.g. from a previous run
+// that was interrupted) is reused as is, so that its contents are taken into account.
+func createSideTable(ctx context.Context, dbURI, table, stmt string) error {
+	adminClient, err := utils.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return fmt.Errorf("can't create admin client: %w", utils.AnalyzeError(err, dbURI))
+	}
+	defer adminClient.Close()
+	op, err := adminClient.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+		Database:   dbURI,
+		Statements: []string{stmt},
+	})
+	if err == nil {
+		err = op.Wait(ctx)
+	}
+	if err != nil && !errors.Is(errs.Spanner(err), errs.ErrAlreadyExists) {
+		return fmt.Errorf("can't create table %s: %w", table, utils.AnalyzeError(err, dbURI))
+	}
+	return nil
+}
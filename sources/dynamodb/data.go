@@ -18,6 +18,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
@@ -26,7 +28,9 @@ import (
 )
 
 func ProcessDataRow(m map[string]*dynamodb.AttributeValue, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) {
+	start := time.Now()
 	spVals, badCols, srcStrVals := cvtRow(m, srcSchema, spSchema, spCols)
+	conv.Timings.TimeTable(internal.StageConvert, srcTable, start)
 	if len(badCols) == 0 {
 		conv.WriteRow(srcTable, spTable, spCols, spVals)
 	} else {
@@ -44,7 +48,13 @@ func cvtRow(attrsMap map[string]*dynamodb.AttributeValue, srcSchema schema.Table
 	for i, srcCol := range srcSchema.ColNames {
 		var spVal interface{}
 		var srcStrVal string
-		if attrsMap[srcCol] == nil {
+		if srcSchema.ColDefs[srcCol].Type.Name == typeCatchAll {
+			spVal, err = catchAllValue(attrsMap, srcSchema)
+			if err != nil {
+				badCols = append(badCols, srcCol)
+			}
+			srcStrVal = fmt.Sprintf("%v", spVal)
+		} else if attrsMap[srcCol] == nil {
 			spVal = nil
 			srcStrVal = "null"
 		} else {
@@ -96,7 +106,7 @@ func convArray(attrVal *dynamodb.AttributeValue, srcType string, spType string)
 			var numArr []big.Rat
 			for _, s := range attrVal.NS {
 				val, ok := (&big.Rat{}).SetString(*s)
-				if !ok {
+				if !ok || !numericParsable(*s) {
 					return nil, fmt.Errorf("failed to convert '%v' to an NUMERIC array", attrVal.NS)
 				}
 				numArr = append(numArr, *val)
@@ -123,7 +133,7 @@ func convScalar(attrVal *dynamodb.AttributeValue, srcType string, spType string)
 		switch srcType {
 		case typeString:
 			return *attrVal.S, nil
-		case typeNumberString:
+		case typeNumber, typeNumberString:
 			return *attrVal.N, nil
 		case typeMap, typeList, typeStringSet, typeNumberStringSet, typeNumberSet, typeBinarySet:
 			// For typeMap and typeList, attrVal is a very verbose data
@@ -146,14 +156,23 @@ func convScalar(attrVal *dynamodb.AttributeValue, srcType string, spType string)
 		}
 	case ddl.Numeric:
 		switch srcType {
-		case typeNumber:
+		case typeNumber, typeNumberString:
 			s := *attrVal.N
 			val, ok := (&big.Rat{}).SetString(s)
-			if !ok {
+			if !ok || !numericParsable(s) {
 				return nil, fmt.Errorf("failed to convert '%v' to an NUMERIC type", s)
 			}
 			return *val, nil
 		}
+	case ddl.Float64:
+		switch srcType {
+		case typeNumber, typeNumberString:
+			val, err := strconv.ParseFloat(*attrVal.N, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert '%v' to a FLOAT64 type", *attrVal.N)
+			}
+			return val, nil
+		}
 	}
 	return nil, fmt.Errorf("can't convert value of type %s to Spanner type %s", attrVal.GoString(), spType)
 }
@@ -20,6 +20,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
@@ -186,6 +187,10 @@ func TestConvScalar(t *testing.T) {
 		{"number string set", typeNumberStringSet, ddl.String, &dynamodb.AttributeValue{NS: []*string{&numStr}}, "[\"1234.56789\"]"},
 		{"number", typeNumber, ddl.Numeric, &dynamodb.AttributeValue{N: &numStr}, *numVal},
 		{"number set", typeNumberSet, ddl.String, &dynamodb.AttributeValue{NS: []*string{&numStr}}, "[\"1234.56789\"]"},
+		{"number to string", typeNumber, ddl.String, &dynamodb.AttributeValue{N: &numStr}, numStr},
+		{"number to float64", typeNumber, ddl.Float64, &dynamodb.AttributeValue{N: &numStr}, 1234.56789},
+		{"number string to float64", typeNumberString, ddl.Float64, &dynamodb.AttributeValue{N: aws.String("1.5e100")}, 1.5e100},
+		{"number string to numeric", typeNumberString, ddl.Numeric, &dynamodb.AttributeValue{N: &numStr}, *numVal},
 	}
 
 	for _, tc := range testcases {
@@ -193,6 +198,14 @@ func TestConvScalar(t *testing.T) {
 		assert.Nil(t, err, fmt.Sprintf("Failed to convert %v from %s to %s", tc.in, typeString, ddl.String))
 		assert.Equal(t, tc.want, cvtVal, tc.name)
 	}
+
+	// Numbers that exceed NUMERIC.
+	for _, srcType := range []string{typeNumber, typeNumberString} {
+		_, err := convScalar(&dynamodb.AttributeValue{N: aws.String("1.5e100")}, srcType, ddl.Numeric)
+		assert.NotNil(t, err)
+	}
+	_, err := convArray(&dynamodb.AttributeValue{NS: []*string{&numStr, aws.String("0.0000000001")}}, typeNumberSet, ddl.Numeric)
+	assert.NotNil(t, err)
 }
 
 func TestStripNull(t *testing.T) {
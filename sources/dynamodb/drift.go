@@ -0,0 +1,174 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// setSchemaUpdater initializes the function used to apply schema updates to the
+// Spanner database dbURI while streaming.
+func setSchemaUpdater(streamInfo *StreamingInfo, dbURI string) {
+	streamInfo.updateSchema = func(ctx context.Context, stmt string) error {
+		adminClient, err := utils.NewDatabaseAdminClient(ctx)
+		if err != nil {
+			return fmt.Errorf("can't create admin client: %w", utils.AnalyzeError(err, dbURI))
+		}
+		defer adminClient.Close()
+		op, err := adminClient.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+			Database:   dbURI,
+			Statements: []string{stmt},
+		})
+		if err != nil {
+			return utils.AnalyzeError(err, dbURI)
+		}
+		if err := op.Wait(ctx); err != nil {
+			return utils.AnalyzeError(err, dbURI)
+		}
+		return nil
+	}
+}
+
+// handleSchemaDrift detects attributes of a stream record which are not part of
+// the schema of srcTable, e.g. attributes added to items after the schema was
+// inferred. Data for such attributes can't be migrated, so they are counted per
+// attribute and reported. If a schema updater is configured, a nullable column
+// is added to the Spanner table for each of them and subsequent records are
+// migrated along with the new attribute.
+func handleSchemaDrift(ctx context.Context, conv *internal.Conv, streamInfo *StreamingInfo, srcTable string, image map[string]*dynamodb.AttributeValue) {
+	unknown := unknownAttributes(conv, streamInfo, srcTable, image)
+	if len(unknown) == 0 {
+		return
+	}
+	if streamInfo.updateSchema == nil {
+		for _, attrName := range unknown {
+			streamInfo.StatsAddUnknownAttribute(srcTable, attrName)
+		}
+		return
+	}
+
+	streamInfo.schemaLock.Lock()
+	defer streamInfo.schemaLock.Unlock()
+	for _, attrName := range unknown {
+		if _, ok := conv.SrcSchema[srcTable].ColDefs[attrName]; ok {
+			// Column was already added while processing another shard.
+			continue
+		}
+		if streamInfo.addColumnFailed[srcTable][attrName] {
+			streamInfo.StatsAddUnknownAttribute(srcTable, attrName)
+			continue
+		}
+		if err := addColumn(ctx, conv, streamInfo, srcTable, attrName, image[attrName]); err != nil {
+			if streamInfo.addColumnFailed[srcTable] == nil {
+				streamInfo.addColumnFailed[srcTable] = make(map[string]bool)
+			}
+			streamInfo.addColumnFailed[srcTable][attrName] = true
+			streamInfo.StatsAddUnknownAttribute(srcTable, attrName)
+			streamInfo.Unexpected(fmt.Sprintf("Can't add column for attribute %s of table %s: %v", attrName, srcTable, err))
+			continue
+		}
+		streamInfo.StatsAddAddedColumn(srcTable, attrName)
+	}
+}
+
+// unknownAttributes returns the sorted names of attributes in image which are
+// not present in the source schema of srcTable. Attributes with a NULL value are
+// ignored since they carry no data, and so are all attributes of tables with a
+// catch-all column.
+func unknownAttributes(conv *internal.Conv, streamInfo *StreamingInfo, srcTable string, image map[string]*dynamodb.AttributeValue) []string {
+	streamInfo.schemaLock.RLock()
+	defer streamInfo.schemaLock.RUnlock()
+	if hasCatchAll(conv.SrcSchema[srcTable]) {
+		// Attributes without a column are kept in the catch-all column.
+		return nil
+	}
+	colDefs := conv.SrcSchema[srcTable].ColDefs
+	var attrs []string
+	for attrName, attr := range image {
+		if _, ok := colDefs[attrName]; ok || attr == nil || attr.NULL != nil {
+			continue
+		}
+		attrs = append(attrs, attrName)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// addColumn adds a nullable column for attribute attrName to the Spanner table
+// mapped to srcTable, based on the type of the attribute value attr. The source
+// and Spanner schemas in conv are only updated once the schema update has been
+// applied to Cloud Spanner. The tables in conv are replaced rather than modified
+// in place, since other goroutines may still be using the previous versions.
+func addColumn(ctx context.Context, conv *internal.Conv, streamInfo *StreamingInfo, srcTable, attrName string, attr *dynamodb.AttributeValue) error {
+	typeCount := make(map[string]int64)
+	incTypeCount(attrName, attr, typeCount)
+	if len(typeCount) != 1 {
+		return fmt.Errorf("can't infer type of attribute")
+	}
+	var srcType string
+	for t := range typeCount {
+		srcType = t
+	}
+	srcCol := schema.Column{Name: attrName, Type: schema.Type{Name: srcType}}
+
+	spTable, err := internal.GetSpannerTable(conv, srcTable)
+	if err != nil {
+		return err
+	}
+	spCol, err := internal.GetSpannerCol(conv, srcTable, attrName, false)
+	if err != nil {
+		return err
+	}
+	ty, _ := ToDdlImpl{}.ToSpannerType(conv, srcCol.Type)
+	spColDef := ddl.ColumnDef{
+		Name:    spCol,
+		T:       ty,
+		Comment: "From: " + attrName + " " + srcCol.Type.Print(),
+	}
+	stmt := spColDef.PrintAddColumn(ddl.Config{ProtectIds: true, TargetDb: conv.TargetDb}, spTable)
+	if err := streamInfo.updateSchema(ctx, stmt); err != nil {
+		return err
+	}
+
+	srcSchema := conv.SrcSchema[srcTable]
+	srcColDefs := make(map[string]schema.Column)
+	for k, v := range srcSchema.ColDefs {
+		srcColDefs[k] = v
+	}
+	srcColDefs[attrName] = srcCol
+	srcSchema.ColDefs = srcColDefs
+	srcSchema.ColNames = append(append([]string{}, srcSchema.ColNames...), attrName)
+	conv.SrcSchema[srcTable] = srcSchema
+
+	spSchema := conv.SpSchema[spTable]
+	spColDefs := make(map[string]ddl.ColumnDef)
+	for k, v := range spSchema.ColDefs {
+		spColDefs[k] = v
+	}
+	spColDefs[spCol] = spColDef
+	spSchema.ColDefs = spColDefs
+	spSchema.ColNames = append(append([]string{}, spSchema.ColNames...), spCol)
+	conv.SpSchema[spTable] = spSchema
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func buildDriftConv() *internal.Conv {
+	return buildConv(
+		ddl.CreateTable{
+			Name:     "testtable",
+			ColNames: []string{"a"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"a": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			Pks: []ddl.IndexKey{{Col: "a"}},
+		},
+		schema.Table{
+			Name:     "testtable",
+			ColNames: []string{"a"},
+			ColDefs: map[string]schema.Column{
+				"a": {Name: "a", Type: schema.Type{Name: typeString}},
+			},
+			PrimaryKeys: []schema.Key{{Column: "a"}},
+		},
+	)
+}
+
+func TestHandleSchemaDrift(t *testing.T) {
+	image := map[string]*dynamodb.AttributeValue{
+		"a":     {S: aws.String("strA")},
+		"new-b": {N: aws.String("10")},
+		"c":     {NULL: aws.Bool(true)},
+	}
+
+	t.Run("report only", func(t *testing.T) {
+		conv := buildDriftConv()
+		streamInfo := MakeStreamingInfo()
+		streamInfo.makeRecordMaps("testtable")
+		handleSchemaDrift(context.Background(), conv, streamInfo, "testtable", image)
+		handleSchemaDrift(context.Background(), conv, streamInfo, "testtable", image)
+		assert.Equal(t, map[string]int64{"new-b": 2}, streamInfo.UnknownAttributes["testtable"])
+		assert.Equal(t, []string{"a"}, conv.SrcSchema["testtable"].ColNames)
+	})
+
+	t.Run("auto add columns", func(t *testing.T) {
+		conv := buildDriftConv()
+		streamInfo := MakeStreamingInfo()
+		streamInfo.makeRecordMaps("testtable")
+		var stmts []string
+		streamInfo.updateSchema = func(ctx context.Context, stmt string) error {
+			stmts = append(stmts, stmt)
+			return nil
+		}
+		handleSchemaDrift(context.Background(), conv, streamInfo, "testtable", image)
+		handleSchemaDrift(context.Background(), conv, streamInfo, "testtable", image)
+		assert.Equal(t, []string{"ALTER TABLE `testtable` ADD COLUMN `new_b` NUMERIC"}, stmts)
+		assert.Equal(t, []string{"a", "new-b"}, conv.SrcSchema["testtable"].ColNames)
+		assert.Equal(t, []string{"a", "new_b"}, conv.SpSchema["testtable"].ColNames)
+		assert.Equal(t, ddl.Type{Name: ddl.Numeric}, conv.SpSchema["testtable"].ColDefs["new_b"].T)
+		assert.Equal(t, []string{"new-b"}, streamInfo.AddedColumns["testtable"])
+		assert.Equal(t, 0, len(streamInfo.UnknownAttributes["testtable"]))
+	})
+
+	t.Run("schema update failure", func(t *testing.T) {
+		conv := buildDriftConv()
+		streamInfo := MakeStreamingInfo()
+		streamInfo.makeRecordMaps("testtable")
+		updates := 0
+		streamInfo.updateSchema = func(ctx context.Context, stmt string) error {
+			updates++
+			return errors.New("ddl failed")
+		}
+		handleSchemaDrift(context.Background(), conv, streamInfo, "testtable", image)
+		handleSchemaDrift(context.Background(), conv, streamInfo, "testtable", image)
+		assert.Equal(t, 1, updates)
+		assert.Equal(t, map[string]int64{"new-b": 2}, streamInfo.UnknownAttributes["testtable"])
+		assert.Equal(t, []string{"a"}, conv.SrcSchema["testtable"].ColNames)
+		assert.Equal(t, int64(1), streamInfo.TotalUnexpecteds())
+	})
+}
+
+func TestProcessRecord_SchemaDrift(t *testing.T) {
+	conv := buildDriftConv()
+	streamInfo := MakeStreamingInfo()
+	streamInfo.makeRecordMaps("testtable")
+	streamInfo.updateSchema = func(ctx context.Context, stmt string) error { return nil }
+	var written []*sp.Mutation
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
+		written = append(written, ms...)
+		return nil
+	}
+	record := &dynamodbstreams.Record{
+		Dynamodb: &dynamodbstreams.StreamRecord{
+			NewImage: map[string]*dynamodb.AttributeValue{
+				"a":     {S: aws.String("strA")},
+				"new-b": {BOOL: aws.Bool(true)},
+			},
+		},
+		EventName: aws.String("INSERT"),
+	}
+	ProcessRecord(context.Background(), conv, streamInfo, record, "testtable", "testShardId")
+	assert.Equal(t, []*sp.Mutation{sp.Insert("testtable", []string{"a", "new_b"}, []interface{}{"strA", true})}, written)
+}
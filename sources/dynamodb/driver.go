@@ -0,0 +1,93 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	dydb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/chaos"
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+)
+
+func init() {
+	registry.Register(dbDriver{})
+}
+
+// dbDriver reads the schema and data of DynamoDB tables.
+type dbDriver struct{}
+
+func (dbDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.DYNAMODB,
+		Source:      "dynamodb",
+		Description: "AWS DynamoDB",
+		Streaming:   true,
+		LegacyMode:  true,
+	}
+}
+
+func (dbDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (dbDriver) TypeMap() common.TypeMap {
+	return TypeMap
+}
+
+// InfoSchema connects to DynamoDB with the AWS configuration of the
+// environment, for both the legacy and the source profile modes.
+func (dbDriver) InfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) (common.InfoSchema, error) {
+	cfg := aws.Config{}
+	if endpointOverride := os.Getenv("DYNAMODB_ENDPOINT_OVERRIDE"); endpointOverride != "" {
+		cfg.Endpoint = aws.String(endpointOverride)
+	}
+	dydbClient := dydb.New(session.Must(session.NewSession()), &cfg)
+	chaos.InstrumentAWS(&dydbClient.Handlers)
+	var dydbStreamsClient *dynamodbstreams.DynamoDBStreams
+	var versions *streamVersions
+	if sourceProfile.Conn.Streaming {
+		dydbStreamsClient = dynamodbstreams.New(session.Must(session.NewSession()), &cfg)
+		chaos.InstrumentAWS(&dydbStreamsClient.Handlers)
+		versions = newStreamVersions()
+	}
+	var s3Client *s3.S3
+	if sourceProfile.Conn.Dydb.ExportURI != "" {
+		s3Client = s3.New(session.Must(session.NewSession()))
+		chaos.InstrumentAWS(&s3Client.Handlers)
+	}
+	return InfoSchemaImpl{
+		DynamoClient:        dydbClient,
+		SampleSize:          profiles.GetSchemaSampleSize(sourceProfile),
+		DynamoStreamsClient: dydbStreamsClient,
+		ExactlyOnce:         sourceProfile.Conn.Dydb.ExactlyOnce,
+		AutoAddColumns:      sourceProfile.Conn.Dydb.AutoAddColumns,
+		S3Client:            s3Client,
+		ExportURI:           sourceProfile.Conn.Dydb.ExportURI,
+		ScanSegments:        sourceProfile.Conn.Dydb.ScanSegments,
+		TableScanSegments:   sourceProfile.Conn.Dydb.TableScanSegments,
+		governor:            newReadGovernor(sourceProfile.Conn.Dydb.RCUBudget),
+		CatchAllThreshold:   sourceProfile.Conn.Dydb.CatchAllThreshold,
+		versions:            versions,
+	}, nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Output formats of DynamoDB table exports to S3.
+const (
+	exportFormatJSON = "DYNAMODB_JSON"
+	exportFormatIon  = "ION"
+)
+
+// exportSummaryFile is the name of the object describing an export, see
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/S3DataExport.Output.html
+const exportSummaryFile = "manifest-summary.json"
+
+// tableExport is a full export of a DynamoDB table to S3.
+type tableExport struct {
+	Bucket             string
+	TableArn           string    `json:"tableArn"`
+	ExportTime         time.Time `json:"exportTime"`
+	ExportType         string    `json:"exportType"` // FULL_EXPORT or INCREMENTAL_EXPORT, empty for older exports.
+	OutputFormat       string    `json:"outputFormat"`
+	ManifestFilesS3Key string    `json:"manifestFilesS3Key"`
+}
+
+// tableName returns the name of the exported table, the last part of its ARN.
+func (e tableExport) tableName() string {
+	return e.TableArn[strings.LastIndex(e.TableArn, "/")+1:]
+}
+
+// exports returns the latest export of each table in isi.ExportURI, or nil if
+// it isn't set.
+func (isi InfoSchemaImpl) exports() (map[string]tableExport, error) {
+	if isi.ExportURI == "" {
+		return nil, nil
+	}
+	return findExports(isi.S3Client, isi.ExportURI)
+}
+
+// streamCreationTime returns the creation time of a DynamoDB Stream, which
+// is the label at the end of its ARN, e.g.
+// arn:aws:dynamodb:us-east-1:123456789012:table/T/stream/2015-05-11T21:21:33.291
+func streamCreationTime(streamArn string) (time.Time, error) {
+	label := streamArn[strings.LastIndex(streamArn, "/")+1:]
+	return time.Parse("2006-01-02T15:04:05.000", label)
+}
+
+// parseS3URI splits uri, of the form s3://bucket/prefix, into its bucket and
+// key prefix.
+func parseS3URI(uri string) (bucket, prefix string, err error) {
+	if !strings.HasPrefix(uri, "s3://") {
+		return "", "", fmt.Errorf("invalid S3 URI %s, expected s3://bucket/prefix", uri)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(uri, "s3://"), "/", 2)
+	if parts[0] == "" {
+		return "", "", fmt.Errorf("invalid S3 URI %s, expected s3://bucket/prefix", uri)
+	}
+	if len(parts) == 2 {
+		prefix = parts[1]
+	}
+	return parts[0], prefix, nil
+}
+
+// findExports finds the full DynamoDB table exports under uri, an S3 URI
+// which can be the export prefix or the location of a single export. It
+// returns the latest export of each table, keyed by table name.
+func findExports(client s3iface.S3API, uri string) (map[string]tableExport, error) {
+	bucket, prefix, err := parseS3URI(uri)
+	if err != nil {
+		return nil, err
+	}
+	var summaryKeys []string
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucket), Prefix: aws.String(prefix)}
+	err = client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range page.Contents {
+			if strings.HasSuffix(aws.StringValue(o.Key), "/"+exportSummaryFile) {
+				summaryKeys = append(summaryKeys, aws.StringValue(o.Key))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't list exports in %s: %v", uri, err)
+	}
+	exports := make(map[string]tableExport)
+	for _, key := range summaryKeys {
+		body, err := getObject(client, bucket, key)
+		if err != nil {
+			return nil, err
+		}
+		export := tableExport{Bucket: bucket}
+		err = json.NewDecoder(body).Decode(&export)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("can't parse export summary s3://%s/%s: %v", bucket, key, err)
+		}
+		if export.ExportType == "INCREMENTAL_EXPORT" {
+			continue
+		}
+		if prev, ok := exports[export.tableName()]; !ok || export.ExportTime.After(prev.ExportTime) {
+			exports[export.tableName()] = export
+		}
+	}
+	return exports, nil
+}
+
+// getObject returns the content of object key of bucket, which the caller
+// must close.
+func getObject(client s3iface.S3API, bucket, key string) (io.ReadCloser, error) {
+	out, err := client.GetObject(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, fmt.Errorf("can't read s3://%s/%s: %v", bucket, key, err)
+	}
+	return out.Body, nil
+}
+
+// readExport calls f for each item of export, reading its data files one at
+// a time so that the table doesn't need to fit in memory.
+func readExport(client s3iface.S3API, export tableExport, f func(item map[string]*dynamodb.AttributeValue)) error {
+	if export.OutputFormat != exportFormatJSON && export.OutputFormat != exportFormatIon {
+		return fmt.Errorf("unsupported export format %s", export.OutputFormat)
+	}
+	body, err := getObject(client, export.Bucket, export.ManifestFilesS3Key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	dec := json.NewDecoder(body)
+	for {
+		var file struct {
+			DataFileS3Key string `json:"dataFileS3Key"`
+		}
+		if err := dec.Decode(&file); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("can't parse export manifest s3://%s/%s: %v", export.Bucket, export.ManifestFilesS3Key, err)
+		}
+		if err := readDataFile(client, export, file.DataFileS3Key, f); err != nil {
+			return err
+		}
+	}
+}
+
+// readDataFile calls f for each item of the gzipped data file key of export.
+func readDataFile(client s3iface.S3API, export tableExport, key string, f func(item map[string]*dynamodb.AttributeValue)) error {
+	body, err := getObject(client, export.Bucket, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("can't decompress s3://%s/%s: %v", export.Bucket, key, err)
+	}
+	defer gz.Close()
+	if export.OutputFormat == exportFormatIon {
+		err = readIonItems(bufio.NewReader(gz), f)
+	} else {
+		err = readJSONItems(gz, f)
+	}
+	if err != nil {
+		return fmt.Errorf("can't read s3://%s/%s: %v", export.Bucket, key, err)
+	}
+	return nil
+}
+
+// readJSONItems calls f for each item of r, a data file in DynamoDB JSON
+// format, i.e. one {"Item": {...}} object per line. The attribute values of
+// DynamoDB JSON have the same fields as dynamodb.AttributeValue, with binary
+// values encoded in base64, so they are decoded directly.
+func readJSONItems(r io.Reader, f func(item map[string]*dynamodb.AttributeValue)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var line struct {
+			Item map[string]*dynamodb.AttributeValue
+		}
+		if err := dec.Decode(&line); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		f(line.Item)
+	}
+}
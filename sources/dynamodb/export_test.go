@@ -0,0 +1,223 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// mockS3Client serves the objects of a single bucket from memory.
+type mockS3Client struct {
+	objects map[string][]byte
+	s3iface.S3API
+}
+
+func (m *mockS3Client) ListObjectsV2Pages(input *s3.ListObjectsV2Input, f func(*s3.ListObjectsV2Output, bool) bool) error {
+	var keys []string
+	for k := range m.objects {
+		if strings.HasPrefix(k, aws.StringValue(input.Prefix)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	out := &s3.ListObjectsV2Output{}
+	for _, k := range keys {
+		out.Contents = append(out.Contents, &s3.Object{Key: aws.String(k)})
+	}
+	f(out, true)
+	return nil
+}
+
+func (m *mockS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	data, ok := m.objects[aws.StringValue(input.Key)]
+	if !ok {
+		return nil, fmt.Errorf("unexpected call to GetObject: %v", input)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func gzipped(s string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte(s))
+	w.Close()
+	return b.Bytes()
+}
+
+// addExport adds an export of table to the objects of m, with one data file
+// of the given format and content.
+func (m *mockS3Client) addExport(id, table, exportType, format, exportTime, data string) {
+	dir := "exports/AWSDynamoDB/" + id
+	m.objects[dir+"/manifest-summary.json"] = []byte(fmt.Sprintf(
+		`{"tableArn":"arn:aws:dynamodb:us-east-1:123456789012:table/%s","exportTime":"%s","exportType":"%s","outputFormat":"%s","manifestFilesS3Key":"%s/manifest-files.json"}`,
+		table, exportTime, exportType, format, dir))
+	m.objects[dir+"/manifest-files.json"] = []byte(fmt.Sprintf(`{"itemCount":1,"dataFileS3Key":"%s/data/file.json.gz"}`+"\n", dir))
+	m.objects[dir+"/data/file.json.gz"] = gzipped(data)
+}
+
+func TestFindExports(t *testing.T) {
+	client := &mockS3Client{objects: make(map[string][]byte)}
+	client.addExport("01", "t1", "FULL_EXPORT", exportFormatJSON, "2022-06-01T10:00:00Z", "")
+	client.addExport("02", "t1", "FULL_EXPORT", exportFormatJSON, "2022-06-02T10:00:00Z", "")
+	client.addExport("03", "t1", "INCREMENTAL_EXPORT", exportFormatJSON, "2022-06-03T10:00:00Z", "")
+	client.addExport("04", "t2", "", exportFormatIon, "2022-06-01T10:00:00Z", "")
+	client.objects["other/AWSDynamoDB/05/manifest-summary.json"] = []byte(`{}`)
+
+	exports, err := findExports(client, "s3://bucket/exports")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"t1", "t2"}, []string{exports["t1"].tableName(), exports["t2"].tableName()})
+	assert.Equal(t, "exports/AWSDynamoDB/02/manifest-files.json", exports["t1"].ManifestFilesS3Key)
+	assert.Equal(t, "bucket", exports["t1"].Bucket)
+	assert.Equal(t, exportFormatIon, exports["t2"].OutputFormat)
+	assert.Equal(t, 2, len(exports))
+
+	_, err = findExports(client, "bucket/exports")
+	assert.NotNil(t, err)
+}
+
+func TestReadIonItems(t *testing.T) {
+	data := `$ion_1_0 {Item:{'a':"str-1",b:12.,c:1.5d-3,d:true,e:null,f:{{aGk=}},g:$dynamodb_SS::["x","y"],h:$dynamodb_NS::[1,2.5],i:{j:[1.,"ké"]}}}
+$ion_1_0 {Item:{'a':"str-2" /* comment */ , "b":-7e2}}
+`
+	var items []map[string]*dynamodb.AttributeValue
+	err := readIonItems(bufio.NewReader(strings.NewReader(data)), func(item map[string]*dynamodb.AttributeValue) {
+		items = append(items, item)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]*dynamodb.AttributeValue{
+		{
+			"a": {S: aws.String("str-1")},
+			"b": {N: aws.String("12")},
+			"c": {N: aws.String("1.5e-3")},
+			"d": {BOOL: aws.Bool(true)},
+			"e": {NULL: aws.Bool(true)},
+			"f": {B: []byte("hi")},
+			"g": {SS: []*string{aws.String("x"), aws.String("y")}},
+			"h": {NS: []*string{aws.String("1"), aws.String("2.5")}},
+			"i": {M: map[string]*dynamodb.AttributeValue{"j": {L: []*dynamodb.AttributeValue{{N: aws.String("1")}, {S: aws.String("ké")}}}}},
+		},
+		{
+			"a": {S: aws.String("str-2")},
+			"b": {N: aws.String("-700")},
+		},
+	}, items)
+
+	for _, bad := range []string{`{Item:{a:"x"`, `{Item:{a:"x" b:1}}`, `{Other:{}}`, `{Item:{a:$dynamodb_SS::[1]}}`, `{Item:{a:1.2.3x}}`} {
+		err := readIonItems(bufio.NewReader(strings.NewReader(bad)), func(map[string]*dynamodb.AttributeValue) {})
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestInfoSchemaImpl_ProcessData_Export(t *testing.T) {
+	client := &mockS3Client{objects: make(map[string][]byte)}
+	client.addExport("01", "testtable", "FULL_EXPORT", exportFormatJSON, "2022-06-01T10:00:00Z",
+		`{"Item":{"a":{"S":"str-1"},"b":{"N":"10.1"},"c":{"B":"aGk="}}}`+"\n"+
+			`{"Item":{"a":{"S":"str-2"}}}`+"\n")
+	isi := InfoSchemaImpl{DynamoClient: &mockDynamoClient{}, S3Client: client, ExportURI: "s3://bucket/exports"}
+
+	tableName := "testtable"
+	cols := []string{"a", "b", "c"}
+	spSchema := ddl.CreateTable{
+		Name:     tableName,
+		ColNames: cols,
+		ColDefs: map[string]ddl.ColumnDef{
+			"a": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"b": {Name: "b", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c": {Name: "c", T: ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}},
+		},
+		Pks: []ddl.IndexKey{{Col: "a"}},
+	}
+	conv := buildConv(
+		spSchema,
+		schema.Table{
+			Name:     tableName,
+			ColNames: cols,
+			ColDefs: map[string]schema.Column{
+				"a": {Name: "a", Type: schema.Type{Name: typeString}},
+				"b": {Name: "b", Type: schema.Type{Name: typeNumberString}},
+				"c": {Name: "c", Type: schema.Type{Name: typeBinary}},
+			},
+			PrimaryKeys: []schema.Key{{Column: "a"}},
+		},
+	)
+
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+	err := isi.ProcessData(context.Background(), conv, tableName, conv.SrcSchema[tableName], tableName, cols, spSchema)
+	assert.Nil(t, err)
+	assert.Equal(t,
+		[]spannerData{
+			{table: tableName, cols: cols, vals: []interface{}{"str-1", "10.1", []byte("hi")}},
+			{table: tableName, cols: cols, vals: []interface{}{"str-2", nil, nil}},
+		},
+		rows,
+	)
+}
+
+func TestProcessRecord_Export(t *testing.T) {
+	conv := buildDriftConv()
+	streamInfo := MakeStreamingInfo()
+	streamInfo.makeRecordMaps("testtable")
+	streamInfo.startTimes["testtable"] = time.Date(2022, 6, 1, 10, 0, 0, 500000000, time.UTC)
+	var written []*sp.Mutation
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
+		written = append(written, ms...)
+		return nil
+	}
+	for _, created := range []time.Time{
+		time.Date(2022, 6, 1, 9, 59, 59, 0, time.UTC),
+		time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC),
+	} {
+		record := &dynamodbstreams.Record{
+			Dynamodb: &dynamodbstreams.StreamRecord{
+				ApproximateCreationDateTime: aws.Time(created),
+				NewImage:                    map[string]*dynamodb.AttributeValue{"a": {S: aws.String(created.Format(time.RFC3339))}},
+			},
+			EventName: aws.String("INSERT"),
+		}
+		ProcessRecord(context.Background(), conv, streamInfo, record, "testtable", "testShardId")
+	}
+	assert.Equal(t, []*sp.Mutation{sp.Insert("testtable", []string{"a"}, []interface{}{"2022-06-01T10:00:00Z"})}, written)
+	assert.Equal(t, int64(1), streamInfo.StaleRecords["testtable"]["INSERT"])
+}
+
+func TestStreamCreationTime(t *testing.T) {
+	created, err := streamCreationTime("arn:aws:dynamodb:us-east-1:123456789012:table/T/stream/2015-05-11T21:21:33.291")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2015, 5, 11, 21, 21, 33, 291000000, time.UTC), created)
+}
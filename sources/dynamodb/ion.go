@@ -0,0 +1,213 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+// This file reads DynamoDB table exports in Amazon Ion text format, see
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/S3DataExport.Output.html
+// Items are structs whose sets are lists annotated $dynamodb_SS, $dynamodb_NS
+// and $dynamodb_BS. For example:
+//
+//   $ion_1_0 {Item:{id:"a",n:12.5,tags:$dynamodb_SS::["x","y"]}}
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// readIonItems calls f for each item of r, a data file in Amazon Ion text
+// format, i.e. a stream of {Item:{...}} structs.
+func readIonItems(r io.Reader, f func(item map[string]*dynamodb.AttributeValue)) error {
+	ir := ion.NewReader(r)
+	for ir.Next() {
+		if ir.Type() == ion.SymbolType {
+			// The reader returns Ion version markers e.g. $ion_1_0 as symbols.
+			if s, err := ir.SymbolValue(); err == nil && s.Text != nil && strings.HasPrefix(*s.Text, "$ion_") {
+				continue
+			}
+		}
+		if ir.Type() != ion.StructType || ir.IsNull() {
+			return fmt.Errorf("expected an {Item:{...}} struct")
+		}
+		if err := ir.StepIn(); err != nil {
+			return err
+		}
+		var item *dynamodb.AttributeValue
+		for ir.Next() {
+			name, err := ir.FieldName()
+			if err != nil {
+				return err
+			}
+			if name == nil || name.Text == nil || *name.Text != "Item" {
+				continue
+			}
+			if item, err = ionAttributeValue(ir); err != nil {
+				return err
+			}
+		}
+		if err := ir.Err(); err != nil {
+			return err
+		}
+		if err := ir.StepOut(); err != nil {
+			return err
+		}
+		if item == nil || item.M == nil {
+			return fmt.Errorf("expected an {Item:{...}} struct")
+		}
+		f(item.M)
+	}
+	return ir.Err()
+}
+
+// ionAttributeValue converts the current value of r to a DynamoDB attribute
+// value.
+func ionAttributeValue(r ion.Reader) (*dynamodb.AttributeValue, error) {
+	annotations, err := r.Annotations()
+	if err != nil {
+		return nil, err
+	}
+	set := ""
+	if len(annotations) > 0 && annotations[0].Text != nil {
+		set = *annotations[0].Text
+	}
+	if set != "" && r.Type() != ion.ListType {
+		return nil, fmt.Errorf("expected a list for %s", set)
+	}
+	if r.IsNull() {
+		return &dynamodb.AttributeValue{NULL: aws.Bool(true)}, nil
+	}
+	switch r.Type() {
+	case ion.StringType:
+		s, err := r.StringValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{S: s}, nil
+	case ion.SymbolType:
+		s, err := r.SymbolValue()
+		if err != nil {
+			return nil, err
+		}
+		if s.Text == nil {
+			return nil, fmt.Errorf("symbol without text")
+		}
+		return &dynamodb.AttributeValue{S: s.Text}, nil
+	case ion.IntType:
+		n, err := r.BigIntValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{N: aws.String(n.String())}, nil
+	case ion.DecimalType:
+		d, err := r.DecimalValue()
+		if err != nil {
+			return nil, err
+		}
+		// Ion decimals are written e.g. 12. or 1.5d-3.
+		n := strings.Replace(strings.TrimSuffix(d.String(), "."), "d", "e", 1)
+		return &dynamodb.AttributeValue{N: aws.String(n)}, nil
+	case ion.FloatType:
+		f, err := r.FloatValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(*f, 'g', -1, 64))}, nil
+	case ion.BlobType, ion.ClobType:
+		b, err := r.ByteValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{B: b}, nil
+	case ion.BoolType:
+		b, err := r.BoolValue()
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{BOOL: b}, nil
+	case ion.StructType:
+		av := &dynamodb.AttributeValue{M: make(map[string]*dynamodb.AttributeValue)}
+		err := ionContainer(r, func() error {
+			name, err := r.FieldName()
+			if err != nil {
+				return err
+			}
+			if name == nil || name.Text == nil {
+				return fmt.Errorf("field without name")
+			}
+			fv, err := ionAttributeValue(r)
+			if err != nil {
+				return err
+			}
+			av.M[*name.Text] = fv
+			return nil
+		})
+		return av, err
+	case ion.ListType, ion.SexpType:
+		av := &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}
+		err := ionContainer(r, func() error {
+			ev, err := ionAttributeValue(r)
+			if err != nil {
+				return err
+			}
+			av.L = append(av.L, ev)
+			return nil
+		})
+		if err != nil || set == "" {
+			return av, err
+		}
+		return ionSet(set, av.L)
+	}
+	return nil, fmt.Errorf("unsupported Ion type %s", r.Type())
+}
+
+// ionContainer calls value for each value of the container r is on.
+func ionContainer(r ion.Reader, value func() error) error {
+	if err := r.StepIn(); err != nil {
+		return err
+	}
+	for r.Next() {
+		if err := value(); err != nil {
+			return err
+		}
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	return r.StepOut()
+}
+
+// ionSet returns the set of DynamoDB type set, e.g. $dynamodb_SS, with
+// elements l.
+func ionSet(set string, l []*dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	av := &dynamodb.AttributeValue{}
+	for _, e := range l {
+		switch {
+		case set == "$dynamodb_SS" && e.S != nil:
+			av.SS = append(av.SS, e.S)
+		case set == "$dynamodb_NS" && e.N != nil:
+			av.NS = append(av.NS, e.N)
+		case set == "$dynamodb_BS" && e.B != nil:
+			av.BS = append(av.BS, e.B)
+		default:
+			return nil, fmt.Errorf("unexpected element in %s", set)
+		}
+	}
+	return av, nil
+}
@@ -20,13 +20,18 @@ import (
 	"log"
 	"math/big"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	sp "cloud.google.com/go/spanner"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
@@ -55,6 +60,15 @@ type InfoSchemaImpl struct {
 	DynamoClient        dynamodbiface.DynamoDBAPI
 	DynamoStreamsClient dynamodbstreamsiface.DynamoDBStreamsAPI
 	SampleSize          int64
+	ExactlyOnce         bool // If true, stream records and their checkpoints are written in a single transaction.
+	AutoAddColumns      bool // If true, nullable columns are added for attributes found in stream records but missing from the schema.
+	S3Client            s3iface.S3API
+	ExportURI           string           // If set, S3 URI of table exports which are bulk-loaded instead of scanning the tables.
+	ScanSegments        int64            // Number of segments each table is scanned with in parallel, 1 if unset.
+	TableScanSegments   map[string]int64 // Overrides of ScanSegments, keyed by table name.
+	governor            *readGovernor    // If set, limits the rate of reads to a budget of read capacity units.
+	versions            *streamVersions  // If set, keys written by earlier streaming migrations, which the bulk load skips (see ReadStreamVersions).
+	CatchAllThreshold   float64          // If positive, attributes found in fewer than this percentage of sampled items are kept in a catch-all JSON column.
 }
 
 func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
@@ -85,19 +99,172 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 }
 
 func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
-	stats, count, err := scanSampleData(isi.DynamoClient, isi.SampleSize, table.Name)
+	stats, numbers, count, full, err := isi.sampleTable(table.Name)
 	if err != nil {
 		return nil, nil, err
 	}
-	return inferDataTypes(stats, count, primaryKeys)
+	census := internal.AttributeCensus{Items: count, Full: full, Attributes: make(map[string]int64)}
+	if len(numbers) > 0 {
+		census.Numbers = numbers
+	}
+	for attr, types := range stats {
+		for _, n := range types {
+			census.Attributes[attr] += n
+		}
+	}
+	colDefs, colNames, err := inferDataTypes(stats, count, primaryKeys)
+	if err != nil || isi.CatchAllThreshold <= 0 {
+		conv.AddAttributeCensus(table.Name, census)
+		return colDefs, colNames, err
+	}
+	keys := make(map[string]bool)
+	for _, k := range primaryKeys {
+		keys[k] = true
+	}
+	indexes, err := isi.GetIndexes(conv, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, idx := range indexes {
+		for _, k := range idx.Keys {
+			keys[k.Column] = true
+		}
+	}
+	colDefs, colNames, census.CatchAll = applyCatchAll(colDefs, colNames, census, isi.CatchAllThreshold, keys)
+	conv.AddAttributeCensus(table.Name, census)
+	return colDefs, colNames, nil
+}
+
+// sampleTable reads up to isi.SampleSize items of table, or all of them if
+// SampleSize isn't positive, and returns the count of each data type of each
+// attribute, the precision of the number values of each attribute, the number
+// of items read, and whether they are all the items of the table. Tables with several scan segments are sampled from every
+// segment in parallel, so that the sample spans the whole table rather than
+// its first partitions.
+func (isi InfoSchemaImpl) sampleTable(table string) (map[string]map[string]int64, map[string]internal.NumberPrecision, int64, bool, error) {
+	stats := make(map[string]map[string]int64)
+	numbers := make(map[string]internal.NumberPrecision)
+	var count int64
+	full := true
+	var mu sync.Mutex // Guards stats, numbers, count and full.
+	err := isi.forEachSegment(table, func(segment, totalSegments int64) error {
+		quota := int64(0)
+		if isi.SampleSize > 0 {
+			quota = isi.SampleSize / totalSegments
+			if segment < isi.SampleSize%totalSegments {
+				quota++
+			}
+			if quota == 0 {
+				mu.Lock()
+				full = false
+				mu.Unlock()
+				return nil
+			}
+		}
+		segStats, segNumbers, segCount, segFull, err := scanSampleData(isi.DynamoClient, isi.governor, quota, table, segment, totalSegments)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for attr, types := range segStats {
+			if _, ok := stats[attr]; !ok {
+				stats[attr] = make(map[string]int64)
+			}
+			for t, n := range types {
+				stats[attr][t] += n
+			}
+		}
+		for attr, p := range segNumbers {
+			numbers[attr] = numbers[attr].Merge(p)
+		}
+		count += segCount
+		full = full && segFull
+		return nil
+	})
+	if err != nil {
+		return nil, nil, 0, false, err
+	}
+	return stats, numbers, count, full, nil
 }
 
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	err := isi.scanTable(srcTable, func(page []map[string]*dynamodb.AttributeValue) {
+		items = append(items, page...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// scanSegments returns the number of segments srcTable is scanned with.
+func (isi InfoSchemaImpl) scanSegments(srcTable string) int64 {
+	if n, ok := isi.TableScanSegments[srcTable]; ok {
+		return n
+	}
+	if isi.ScanSegments > 1 {
+		return isi.ScanSegments
+	}
+	return 1
+}
+
+// scanTable reads all items of srcTable, and calls f for each page of items.
+// Tables with more than one scan segment are read with a parallel Scan, one
+// goroutine per segment, and the calls to f are serialized.
+func (isi InfoSchemaImpl) scanTable(srcTable string, f func(items []map[string]*dynamodb.AttributeValue)) error {
+	var mu sync.Mutex // Serializes calls to f.
+	return isi.forEachSegment(srcTable, func(segment, totalSegments int64) error {
+		return isi.scanSegment(srcTable, segment, totalSegments, func(items []map[string]*dynamodb.AttributeValue) {
+			mu.Lock()
+			defer mu.Unlock()
+			f(items)
+		})
+	})
+}
+
+// forEachSegment calls f for each scan segment of srcTable, concurrently if
+// there are several, and returns the first error.
+func (isi InfoSchemaImpl) forEachSegment(srcTable string, f func(segment, totalSegments int64) error) error {
+	segments := isi.scanSegments(srcTable)
+	if segments == 1 {
+		return f(0, 1)
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex // Guards firstErr.
+		firstErr error
+	)
+	for i := int64(0); i < segments; i++ {
+		wg.Add(1)
+		go func(segment int64) {
+			defer wg.Done()
+			if err := f(segment, segments); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// scanSegment reads the items of segment segment of srcTable, scanned with
+// totalSegments segments, and calls f for each page of items.
+func (isi InfoSchemaImpl) scanSegment(srcTable string, segment, totalSegments int64, f func(items []map[string]*dynamodb.AttributeValue)) error {
 	var lastEvaluatedKey map[string]*dynamodb.AttributeValue
 	for {
 		// Build the query input parameters.
 		params := &dynamodb.ScanInput{
-			TableName: aws.String(srcTable),
+			TableName:              aws.String(srcTable),
+			ReturnConsumedCapacity: isi.governor.returnConsumedCapacity(),
+		}
+		if totalSegments > 1 {
+			params.Segment, params.TotalSegments = aws.Int64(segment), aws.Int64(totalSegments)
 		}
 		if lastEvaluatedKey != nil {
 			params.ExclusiveStartKey = lastEvaluatedKey
@@ -106,11 +273,13 @@ func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string)
 		// Make the DynamoDB Query API call.
 		result, err := isi.DynamoClient.Scan(params)
 		if err != nil {
-			return nil, fmt.Errorf("failed to make Query API call for table %v: %v", srcTable, err)
+			return fmt.Errorf("failed to make Query API call for table %v: %v", srcTable, err)
 		}
+		isi.governor.consumeCapacity(result.ConsumedCapacity)
+		f(result.Items)
 
 		if result.LastEvaluatedKey == nil {
-			return result.Items, nil
+			return nil
 		}
 		// If there are more rows, then continue.
 		lastEvaluatedKey = result.LastEvaluatedKey
@@ -128,6 +297,13 @@ func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error)
 	return *result.Table.ItemCount, err
 }
 
+// CheckReadAccess implements the common.AccessChecker interface: it scans at
+// most one item of table.
+func (isi InfoSchemaImpl) CheckReadAccess(table common.SchemaAndName) error {
+	_, err := isi.DynamoClient.Scan(&dynamodb.ScanInput{TableName: aws.String(table.Name), Limit: aws.Int64(1)})
+	return err
+}
+
 func (isi InfoSchemaImpl) GetConstraints(conv *internal.Conv, table common.SchemaAndName) (primaryKeys []string, constraints map[string][]string, err error) {
 	input := &dynamodb.DescribeTableInput{
 		TableName: aws.String(table.Name),
@@ -176,20 +352,54 @@ func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAnd
 }
 
 // ProcessData performs data conversion for DynamoDB database. For each table,
-// we extract data using Scan requests, convert the data to Spanner data (based
-// on the source and Spanner schemas), and write it to Spanner. If we can't
-// get/process data for a table, we skip that table and process the remaining
-// tables.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
-	rows, err := isi.GetRowsFromTable(conv, srcTable)
+// we extract data using (parallel) Scan requests, or from the table's export to S3 if
+// ExportURI is set, convert the data to Spanner data (based on the source and
+// Spanner schemas), and write it to Spanner. If we can't get/process data for
+// a table, we skip that table and process the remaining tables. Items whose keys were
+// already written by streaming migration are skipped (see ReadStreamVersions).
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	streamed := isi.versions.tableKeys(srcTable)
+	var skipped int64
+	defer func() {
+		if skipped > 0 {
+			log.Printf("Skipped %d items of table %s already written by streaming migration\n", skipped, srcTable)
+		}
+	}()
+	processItem := func(attrsMap map[string]*dynamodb.AttributeValue) {
+		if streamed[recordKey(attrsMap, srcSchema.PrimaryKeys)] {
+			atomic.AddInt64(&skipped, 1)
+			return
+		}
+		ProcessDataRow(attrsMap, conv, srcTable, srcSchema, spTable, spCols, spSchema)
+	}
+	if isi.ExportURI != "" {
+		exports, err := isi.exports()
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't find exports for table %s : err = %s", srcTable, err))
+			return err
+		}
+		if export, ok := exports[srcTable]; ok {
+			err = readExport(isi.S3Client, export, processItem)
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s from its export : err = %s", srcTable, err))
+			}
+			return err
+		}
+		log.Printf("No export found for table %s in %s, scanning the table instead\n", srcTable, isi.ExportURI)
+	}
+	err := isi.scanTable(srcTable, func(items []map[string]*dynamodb.AttributeValue) {
+		// Iterate the items returned.
+		for _, attrsMap := range items {
+			processItem(attrsMap)
+		}
+		// Pages are checkpoints: pause before scanning the next page outside
+		// the migration windows.
+		conv.Schedule.Wait(ctx)
+	})
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
 		return err
 	}
-	// Iterate the items returned.
-	for _, attrsMap := range rows.([]map[string]*dynamodb.AttributeValue) {
-		ProcessDataRow(attrsMap, conv, srcTable, srcSchema, spTable, spCols, spSchema)
-	}
 	return nil
 }
 
@@ -200,6 +410,10 @@ func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *inte
 
 	latestStreamArn := make(map[string]interface{})
 	orderTableNames := ddl.OrderTables(conv.SpSchema)
+	exports, err := isi.exports()
+	if err != nil {
+		return nil, err
+	}
 
 	for _, spannerTable := range orderTableNames {
 		srcTable, _ := internal.GetSourceTable(conv, spannerTable)
@@ -209,6 +423,13 @@ func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *inte
 			continue
 		}
 		latestStreamArn[srcTable] = streamArn
+		// Streaming catches up from the export time, so the stream must
+		// already have been enabled then.
+		if export, ok := exports[srcTable]; ok {
+			if created, err := streamCreationTime(streamArn); err == nil && created.After(export.ExportTime) {
+				conv.Unexpected(fmt.Sprintf("DynamoDB Stream for table %s was enabled after the table's export at %s, changes made in between are missing: export the table again", srcTable, export.ExportTime.Format(time.RFC3339)))
+			}
+		}
 	}
 
 	fmt.Println("DynamoDB Streams initialized successfully.")
@@ -217,25 +438,49 @@ func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *inte
 
 // StartStreamingMigration starts the streaming migration process by creating a seperate
 // worker thread/goroutine for each table's DynamoDB Stream. It catches Ctrl+C signal if
-// customer wants to stop the process.
+// customer wants to stop the process, and stops immediately once ctx is done.
 func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, latestStreamArn map[string]interface{}) error {
 	fmt.Println("Processing of DynamoDB Streams started...")
 	fmt.Println("Use Ctrl+C to stop the process.")
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	streamInfo := MakeStreamingInfo()
+	streamInfo.sampleSize = conv.BadDataSampleSize()
+	streamInfo.badData = conv.BadDataWriter()
+	streamInfo.governor = isi.governor
 	setWriter(streamInfo, client, conv)
+	if err := createSideTable(ctx, client.DatabaseName(), versionTable, versionTableDDL(conv.TargetDb)); err != nil {
+		return fmt.Errorf("can't create version table for streaming: %v", err)
+	}
+	if isi.ExactlyOnce {
+		if err := createSideTable(ctx, client.DatabaseName(), checkpointTable, checkpointTableDDL(conv.TargetDb)); err != nil {
+			return fmt.Errorf("can't create checkpoint table for exactly-once streaming: %v", err)
+		}
+	}
+	setVersionedWriter(streamInfo, client, conv, isi.ExactlyOnce)
+	if isi.AutoAddColumns {
+		setSchemaUpdater(streamInfo, client.DatabaseName())
+	}
 
 	wg := &sync.WaitGroup{}
 
 	wg.Add(2)
-	go catchCtrlC(wg, streamInfo)
-	go cutoverHelper(wg, streamInfo)
+	go catchCtrlC(ctx, cancel, wg, streamInfo)
+	go cutoverHelper(ctx, wg, streamInfo)
 
+	exports, err := isi.exports()
+	if err != nil {
+		return err
+	}
 	for srcTable, streamArn := range latestStreamArn {
 		streamInfo.makeRecordMaps(srcTable)
+		if export, ok := exports[srcTable]; ok {
+			streamInfo.startTimes[srcTable] = export.ExportTime
+		}
 
 		wg.Add(1)
-		go ProcessStream(wg, isi.DynamoStreamsClient, streamInfo, conv, streamArn.(string), srcTable)
+		go ProcessStream(ctx, wg, isi.DynamoStreamsClient, streamInfo, conv, streamArn.(string), srcTable)
 	}
 	wg.Wait()
 
@@ -253,21 +498,32 @@ func getSchemaIndexStruct(indexName string, keySchema []*dynamodb.KeySchemaEleme
 	return schema.Index{Name: indexName, Keys: keys}
 }
 
-func scanSampleData(client dynamodbiface.DynamoDBAPI, sampleSize int64, table string) (map[string]map[string]int64, int64, error) {
+// scanSampleData reads up to sampleSize items, or all of them if sampleSize
+// isn't positive, of segment segment of table scanned with totalSegments
+// segments. It returns the count of each data type of each attribute, the
+// precision of the number values of each attribute, the number of items
+// read, and whether the segment was read to its end.
+func scanSampleData(client dynamodbiface.DynamoDBAPI, governor *readGovernor, sampleSize int64, table string, segment, totalSegments int64) (map[string]map[string]int64, map[string]internal.NumberPrecision, int64, bool, error) {
 	// A map from column name to a count map of possible data types.
 	stats := make(map[string]map[string]int64)
+	numbers := make(map[string]internal.NumberPrecision)
 	var count int64
 	// Build the query input parameters.
 	params := &dynamodb.ScanInput{
-		TableName: aws.String(table),
+		TableName:              aws.String(table),
+		ReturnConsumedCapacity: governor.returnConsumedCapacity(),
+	}
+	if totalSegments > 1 {
+		params.Segment, params.TotalSegments = aws.Int64(segment), aws.Int64(totalSegments)
 	}
 
 	for {
 		// Make the DynamoDB Query API call.
 		result, err := client.Scan(params)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to make Query API call for table %v: %v", table, err)
+			return nil, nil, 0, false, fmt.Errorf("failed to make Query API call for table %v: %v", table, err)
 		}
+		governor.consumeCapacity(result.ConsumedCapacity)
 
 		// Iterate the items returned.
 		for _, attrsMap := range result.Items {
@@ -276,11 +532,14 @@ func scanSampleData(client dynamodbiface.DynamoDBAPI, sampleSize int64, table st
 					stats[attrName] = make(map[string]int64)
 				}
 				incTypeCount(attrName, attr, stats[attrName])
+				if p, ok := numberPrecision(attr); ok {
+					numbers[attrName] = numbers[attrName].Merge(p)
+				}
 			}
 
 			count++
-			if count >= sampleSize {
-				return stats, count, nil
+			if sampleSize > 0 && count >= sampleSize {
+				return stats, numbers, count, false, nil
 			}
 		}
 		if result.LastEvaluatedKey == nil {
@@ -289,7 +548,7 @@ func scanSampleData(client dynamodbiface.DynamoDBAPI, sampleSize int64, table st
 		// If there are more rows, then continue.
 		params.ExclusiveStartKey = result.LastEvaluatedKey
 	}
-	return stats, count, nil
+	return stats, numbers, count, true, nil
 }
 
 func incTypeCount(attrName string, attr *dynamodb.AttributeValue, s map[string]int64) {
@@ -389,6 +648,7 @@ func inferDataTypes(stats map[string]map[string]int64, rows int64, primaryKeys [
 				candidates = append(candidates, si)
 			}
 		}
+		candidates = foldNumberTypes(candidates)
 
 		colNames = append(colNames, col)
 		if len(candidates) == 1 {
@@ -406,6 +666,87 @@ func inferDataTypes(stats map[string]map[string]int64, rows int64, primaryKeys [
 	return colDefs, colNames, nil
 }
 
+// foldNumberTypes merges candidate numbers that fit NUMERIC into candidate
+// numbers that don't, so that an attribute with both gets a column that
+// holds all of them rather than a conflict.
+func foldNumberTypes(statItems []statItem) []statItem {
+	for _, pair := range [][2]string{{typeNumber, typeNumberString}, {typeNumberSet, typeNumberStringSet}} {
+		from, to := -1, -1
+		for i, si := range statItems {
+			switch si.Type {
+			case pair[0]:
+				from = i
+			case pair[1]:
+				to = i
+			}
+		}
+		if from < 0 || to < 0 {
+			continue
+		}
+		statItems[to].Count += statItems[from].Count
+		statItems = append(statItems[:from], statItems[from+1:]...)
+	}
+	return statItems
+}
+
+// numberPrecision returns the precision of the number or number set attr,
+// and false if attr isn't one.
+func numberPrecision(attr *dynamodb.AttributeValue) (internal.NumberPrecision, bool) {
+	var ns []*string
+	switch {
+	case attr.N != nil:
+		ns = []*string{attr.N}
+	case len(attr.NS) != 0:
+		ns = attr.NS
+	default:
+		return internal.NumberPrecision{}, false
+	}
+	var p internal.NumberPrecision
+	for _, n := range ns {
+		integerDigits, scale := numberDigits(*n)
+		p = p.Merge(internal.NumberPrecision{Values: 1, IntegerDigits: integerDigits, Scale: scale})
+		if !numericParsable(*n) {
+			p.Exceeding++
+		}
+	}
+	return p, true
+}
+
+// numberDigits returns the number of significant digits before and after
+// the decimal point of number n, e.g. 3 and 2 for "-123.45" and 0 and 3 for
+// "1E-3". DynamoDB numbers have up to 38 significant digits, and exponents
+// from -130 to 125.
+func numberDigits(n string) (int, int) {
+	n = strings.TrimLeft(n, "+-")
+	exp := 0
+	if i := strings.IndexAny(n, "eE"); i >= 0 {
+		exp, _ = strconv.Atoi(n[i+1:])
+		n = n[:i]
+	}
+	point := strings.IndexByte(n, '.')
+	if point < 0 {
+		point = len(n)
+	} else {
+		n = n[:point] + n[point+1:]
+	}
+	point += exp
+	// Leading and trailing zeros aren't significant.
+	trimmed := strings.TrimLeft(n, "0")
+	point -= len(n) - len(trimmed)
+	digits := strings.TrimRight(trimmed, "0")
+	if digits == "" {
+		return 0, 0
+	}
+	integerDigits, scale := point, len(digits)-point
+	if integerDigits < 0 {
+		integerDigits = 0
+	}
+	if scale < 0 {
+		scale = 0
+	}
+	return integerDigits, scale
+}
+
 // numericParsable determines whether its argument is a valid Spanner numeric
 // values. This is based on the definition of the NUMERIC type in Cloud Spanner:
 // a NUMERIC type with 38 digits of precision and 9 digits of scale. It can
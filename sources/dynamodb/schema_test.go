@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -188,7 +190,7 @@ func TestProcessSchema(t *testing.T) {
 	sampleSize := int64(10000)
 
 	conv := internal.MakeConv()
-	err := common.ProcessSchema(conv, InfoSchemaImpl{client, nil, sampleSize})
+	err := common.ProcessSchema(conv, InfoSchemaImpl{DynamoClient: client, SampleSize: sampleSize})
 
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
@@ -291,7 +293,7 @@ func TestProcessSchema_FullDataTypes(t *testing.T) {
 	sampleSize := int64(10000)
 
 	conv := internal.MakeConv()
-	err := common.ProcessSchema(conv, InfoSchemaImpl{client, nil, sampleSize})
+	err := common.ProcessSchema(conv, InfoSchemaImpl{DynamoClient: client, SampleSize: sampleSize})
 
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
@@ -374,7 +376,7 @@ func TestProcessData(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessData(conv, InfoSchemaImpl{client, nil, 10})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{DynamoClient: client, SampleSize: 10})
 	assert.Equal(t,
 		[]spannerData{
 			{
@@ -494,7 +496,8 @@ func TestScanSampleData(t *testing.T) {
 		scanOutputs: scanOutputs,
 	}
 
-	stats, _, err := scanSampleData(client, 3, "test")
+	stats, _, _, full, err := scanSampleData(client, nil, 3, "test", 0, 1)
+	assert.False(t, full)
 	assert.Nil(t, err)
 
 	expectedStats := map[string]map[string]int64{
@@ -553,7 +556,7 @@ func TestInfoSchemaImpl_GetIndexes(t *testing.T) {
 
 	dySchema := common.SchemaAndName{Name: "test"}
 	conv := internal.MakeConv()
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	indexes, err := isi.GetIndexes(conv, dySchema)
 	assert.Nil(t, err)
 
@@ -608,7 +611,7 @@ func TestInfoSchemaImpl_GetConstraints(t *testing.T) {
 
 	dySchema := common.SchemaAndName{Name: "test"}
 	conv := internal.MakeConv()
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	primaryKeys, constraints, err := isi.GetConstraints(conv, dySchema)
 	assert.Nil(t, err)
 
@@ -629,7 +632,7 @@ func TestInfoSchemaImpl_GetTables(t *testing.T) {
 	client := &mockDynamoClient{
 		listTableOutputs: listTableOutputs,
 	}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	tables, err := isi.GetTables()
 	assert.Nil(t, err)
 	assert.Equal(t, []common.SchemaAndName{{"", "table-a"}, {"", "table-b"}}, tables)
@@ -639,7 +642,7 @@ func TestInfoSchemaImpl_GetTableName(t *testing.T) {
 	tableNameA := "table-a"
 
 	client := &mockDynamoClient{}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	table := isi.GetTableName("", tableNameA)
 	assert.Equal(t, tableNameA, table)
 }
@@ -683,7 +686,7 @@ func TestInfoSchemaImpl_GetColumns(t *testing.T) {
 	}
 	dySchema := common.SchemaAndName{Name: "test"}
 
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 
 	colDefs, colNames, err := isi.GetColumns(conv, dySchema, nil, nil)
 	assert.Nil(t, err)
@@ -701,7 +704,7 @@ func TestInfoSchemaImpl_GetForeignKeys(t *testing.T) {
 	dySchema := common.SchemaAndName{Name: "test"}
 	conv := internal.MakeConv()
 	client := &mockDynamoClient{}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	fk, err := isi.GetForeignKeys(conv, dySchema)
 	assert.Nil(t, err)
 	assert.Nil(t, fk)
@@ -724,7 +727,7 @@ func TestInfoSchemaImpl_GetRowCount(t *testing.T) {
 		describeTableOutputs: describeTableOutputs,
 	}
 
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 	dySchema := common.SchemaAndName{Name: tableNameA}
 
 	rowCount, err := isi.GetRowCount(dySchema)
@@ -756,7 +759,7 @@ func TestInfoSchemaImpl_GetRowsFromTable(t *testing.T) {
 		scanOutputs: scanOutputs,
 	}
 	tableName := "testtable"
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 
 	rows, err := isi.GetRowsFromTable(conv, tableName)
 	assert.Nil(t, err)
@@ -769,6 +772,86 @@ func TestInfoSchemaImpl_GetRowsFromTable(t *testing.T) {
 	)
 }
 
+// mockSegmentedScanClient serves parallel Scans: each segment returns two
+// pages of one item, whose attribute "a" is "<segment>-<page>".
+type mockSegmentedScanClient struct {
+	lock          sync.Mutex
+	totalSegments []int64
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m *mockSegmentedScanClient) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	m.lock.Lock()
+	m.totalSegments = append(m.totalSegments, aws.Int64Value(input.TotalSegments))
+	m.lock.Unlock()
+	segment := aws.Int64Value(input.Segment)
+	if input.ExclusiveStartKey == nil {
+		return &dynamodb.ScanOutput{
+			Items:            []map[string]*dynamodb.AttributeValue{{"a": {S: aws.String(fmt.Sprintf("%d-0", segment))}}},
+			LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"a": {S: aws.String("next")}},
+		}, nil
+	}
+	return &dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{{"a": {S: aws.String(fmt.Sprintf("%d-1", segment))}}},
+	}, nil
+}
+
+func TestInfoSchemaImpl_ProcessData_ParallelScan(t *testing.T) {
+	tableName := "testtable"
+	for _, tc := range []struct {
+		isi      InfoSchemaImpl
+		segments int64
+	}{
+		{InfoSchemaImpl{}, 1},
+		{InfoSchemaImpl{ScanSegments: 3}, 3},
+		{InfoSchemaImpl{ScanSegments: 3, TableScanSegments: map[string]int64{tableName: 2}}, 2},
+		{InfoSchemaImpl{ScanSegments: 3, TableScanSegments: map[string]int64{"other": 2}}, 3},
+	} {
+		client := &mockSegmentedScanClient{}
+		isi := tc.isi
+		isi.DynamoClient = client
+		conv := buildDriftConv()
+		var rows []string
+		conv.SetDataSink(
+			func(table string, cols []string, vals []interface{}) {
+				rows = append(rows, vals[0].(string))
+			})
+		err := isi.ProcessData(context.Background(), conv, tableName, conv.SrcSchema[tableName], tableName, []string{"a"}, conv.SpSchema[tableName])
+		assert.Nil(t, err)
+		var expected []string
+		for i := int64(0); i < tc.segments; i++ {
+			expected = append(expected, fmt.Sprintf("%d-0", i), fmt.Sprintf("%d-1", i))
+		}
+		sort.Strings(rows)
+		assert.Equal(t, expected, rows)
+		assert.Equal(t, int(2*tc.segments), len(client.totalSegments))
+		if tc.segments > 1 {
+			assert.Equal(t, tc.segments, client.totalSegments[0])
+		} else {
+			assert.Equal(t, int64(0), client.totalSegments[0])
+		}
+	}
+}
+
+func TestInfoSchemaImpl_GetColumns_SegmentedSample(t *testing.T) {
+	for _, tc := range []struct {
+		sampleSize int64
+		items      int64
+		full       bool
+	}{
+		{sampleSize: 3, items: 3, full: false},
+		{sampleSize: 1, items: 1, full: false},
+		{sampleSize: 0, items: 4, full: true},
+	} {
+		isi := InfoSchemaImpl{DynamoClient: &mockSegmentedScanClient{}, SampleSize: tc.sampleSize, ScanSegments: 2}
+		conv := internal.MakeConv()
+		_, cols, err := isi.GetColumns(conv, common.SchemaAndName{Name: "test"}, nil, []string{"a"})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a"}, cols)
+		assert.Equal(t, internal.AttributeCensus{Items: tc.items, Full: tc.full, Attributes: map[string]int64{"a": tc.items}}, conv.AttributeCensus["test"])
+	}
+}
+
 func TestInfoSchemaImpl_ProcessData(t *testing.T) {
 	strA := "str-1"
 	numStr1 := "10.1"
@@ -792,7 +875,7 @@ func TestInfoSchemaImpl_ProcessData(t *testing.T) {
 	client := &mockDynamoClient{
 		scanOutputs: scanOutputs,
 	}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 10}
 
 	tableName := "testtable"
 	cols := []string{"a", "b", "c", "d"}
@@ -827,7 +910,7 @@ func TestInfoSchemaImpl_ProcessData(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	err := isi.ProcessData(conv, tableName, conv.SrcSchema[tableName], tableName,
+	err := isi.ProcessData(context.Background(), conv, tableName, conv.SrcSchema[tableName], tableName,
 		cols, spSchema)
 	assert.Nil(t, err)
 	assert.Equal(t,
@@ -897,6 +980,74 @@ func TestIncTypeCount_NumericArray(t *testing.T) {
 	}
 }
 
+func TestNumberDigits(t *testing.T) {
+	for _, test := range []struct {
+		in                   string
+		integerDigits, scale int
+	}{
+		{"0", 0, 0},
+		{"-123.45", 3, 2},
+		{"1200", 4, 0},
+		{"0.00123", 0, 5},
+		{"1.50", 1, 1},
+		{"1E-3", 0, 3},
+		{"-1.5e100", 101, 0},
+		{"12.345e1", 3, 2},
+		{"199999999999999999999999999999.999999999", 30, 9},
+	} {
+		integerDigits, scale := numberDigits(test.in)
+		assert.Equal(t, test.integerDigits, integerDigits, test.in)
+		assert.Equal(t, test.scale, scale, test.in)
+	}
+}
+
+func TestNumberPrecision(t *testing.T) {
+	p, ok := numberPrecision(&dynamodb.AttributeValue{N: aws.String("123.45")})
+	assert.True(t, ok)
+	assert.Equal(t, internal.NumberPrecision{Values: 1, IntegerDigits: 3, Scale: 2}, p)
+
+	p, ok = numberPrecision(&dynamodb.AttributeValue{NS: []*string{aws.String("1.5"), aws.String("0.0000000001"), aws.String("1e40")}})
+	assert.True(t, ok)
+	assert.Equal(t, internal.NumberPrecision{Values: 3, IntegerDigits: 41, Scale: 10, Exceeding: 2}, p)
+
+	_, ok = numberPrecision(&dynamodb.AttributeValue{S: aws.String("1")})
+	assert.False(t, ok)
+}
+
+func TestInferDataTypes_NumberPrecision(t *testing.T) {
+	stats := map[string]map[string]int64{
+		"mostly_fits":  {typeNumber: 90, typeNumberString: 10},
+		"rarely_fits":  {typeNumber: 10, typeNumberString: 90},
+		"set":          {typeNumberSet: 50, typeNumberStringSet: 50},
+		"with_strings": {typeNumber: 50, typeNumberString: 10, typeString: 40},
+	}
+	colDefs, _, err := inferDataTypes(stats, 100, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, typeNumberString, colDefs["mostly_fits"].Type.Name)
+	assert.Equal(t, typeNumberString, colDefs["rarely_fits"].Type.Name)
+	assert.Equal(t, typeNumberStringSet, colDefs["set"].Type.Name)
+	assert.Equal(t, typeString, colDefs["with_strings"].Type.Name)
+}
+
+func TestInfoSchemaImpl_GetColumns_NumberPrecision(t *testing.T) {
+	client := &mockDynamoClient{
+		scanOutputs: []dynamodb.ScanOutput{
+			{
+				Items: []map[string]*dynamodb.AttributeValue{
+					{"a": {S: aws.String("1")}, "n": {N: aws.String("12.5")}},
+					{"a": {S: aws.String("2")}, "n": {N: aws.String("1e40")}},
+				},
+			},
+		},
+	}
+	isi := InfoSchemaImpl{DynamoClient: client}
+	conv := internal.MakeConv()
+	colDefs, _, err := isi.GetColumns(conv, common.SchemaAndName{Name: "test"}, nil, []string{"a"})
+	assert.Nil(t, err)
+	assert.Equal(t, typeNumberString, colDefs["n"].Type.Name)
+	assert.Equal(t, map[string]internal.NumberPrecision{"n": {Values: 2, IntegerDigits: 41, Scale: 1, Exceeding: 1}}, conv.AttributeCensus["test"].Numbers)
+}
+
 func TestSetRowStats(t *testing.T) {
 	tableNameA := "test_a"
 	tableNameB := "test_b"
@@ -927,7 +1078,7 @@ func TestSetRowStats(t *testing.T) {
 		describeTableOutputs: describeTableOutputs,
 	}
 
-	common.SetRowStats(conv, InfoSchemaImpl{client, nil, 10})
+	common.SetRowStats(conv, InfoSchemaImpl{DynamoClient: client, SampleSize: 10})
 
 	assert.Equal(t, tableItemCountA, conv.Stats.Rows[tableNameA])
 	assert.Equal(t, tableItemCountB, conv.Stats.Rows[tableNameB])
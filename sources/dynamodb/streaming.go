@@ -16,6 +16,7 @@ package dynamodb
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -27,6 +28,8 @@ import (
 
 	sp "cloud.google.com/go/spanner"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
@@ -35,10 +38,15 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/errs"
 	"github.com/cloudspannerecosystem/harbourbridge/common/metrics"
+	"github.com/cloudspannerecosystem/harbourbridge/common/monitoring"
+	"github.com/cloudspannerecosystem/harbourbridge/common/notify"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
 const (
@@ -46,6 +54,10 @@ const (
 	retryLimit = 100
 )
 
+// parentRowRetryDelay is the delay before retrying writes that failed because
+// their parent row is missing.
+var parentRowRetryDelay = 4 * time.Second
+
 // NewDynamoDBStream initializes a new DynamoDB Stream for a table with NEW_AND_OLD_IMAGES
 // StreamViewType. If there exists a stream for a given table then it must be of type
 // NEW_IMAGE or NEW_AND_OLD_IMAGES otherwise streaming changes for this table won't be captured.
@@ -59,14 +71,10 @@ func NewDynamoDBStream(client dynamodbiface.DynamoDBAPI, srcTable string) (strin
 		return "", fmt.Errorf("unexpected call to DescribeTable: %v", err)
 	}
 	if result.Table.StreamSpecification != nil {
-		switch *result.Table.StreamSpecification.StreamViewType {
-		case dynamodb.StreamViewTypeKeysOnly:
-			return "", fmt.Errorf("error! there exists a stream with KEYS_ONLY StreamViewType")
-		case dynamodb.StreamViewTypeOldImage:
-			return "", fmt.Errorf("error! there exists a stream with OLD_IMAGE StreamViewType")
-		default:
-			return *result.Table.LatestStreamArn, nil
+		if err := checkStreamViewType(result.Table.StreamSpecification); err != nil {
+			return "", err
 		}
+		return *result.Table.LatestStreamArn, nil
 	} else {
 		streamSpecification := &dynamodb.StreamSpecification{
 			StreamEnabled:  aws.Bool(true),
@@ -84,17 +92,75 @@ func NewDynamoDBStream(client dynamodbiface.DynamoDBAPI, srcTable string) (strin
 	}
 }
 
-// catchCtrlC catches the Ctrl+C signal if customer wants to exit.
-func catchCtrlC(wg *sync.WaitGroup, streamInfo *StreamingInfo) {
+// checkStreamViewType returns an error if the changes of a table can't be
+// migrated from its existing stream of specification spec.
+func checkStreamViewType(spec *dynamodb.StreamSpecification) error {
+	switch *spec.StreamViewType {
+	case dynamodb.StreamViewTypeKeysOnly:
+		return fmt.Errorf("error! there exists a stream with KEYS_ONLY StreamViewType")
+	case dynamodb.StreamViewTypeOldImage:
+		return fmt.Errorf("error! there exists a stream with OLD_IMAGE StreamViewType")
+	}
+	return nil
+}
+
+// CheckStream implements the common.StreamChecker interface: an existing
+// stream of table must be of type NEW_IMAGE or NEW_AND_OLD_IMAGES (otherwise
+// a stream is created when migrating), and streams must be readable.
+func (isi InfoSchemaImpl) CheckStream(table common.SchemaAndName) error {
+	result, err := isi.DynamoClient.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table.Name)})
+	if err != nil {
+		return err
+	}
+	if spec := result.Table.StreamSpecification; spec != nil {
+		if err := checkStreamViewType(spec); err != nil {
+			return err
+		}
+	}
+	if isi.DynamoStreamsClient == nil {
+		return nil
+	}
+	_, err = isi.DynamoStreamsClient.ListStreams(&dynamodbstreams.ListStreamsInput{TableName: aws.String(table.Name), Limit: aws.Int64(1)})
+	return err
+}
+
+// catchCtrlC catches the Ctrl+C signal if customer wants to exit. The first
+// Ctrl+C stops processing once the records already in the streams are
+// processed, a second one cancels ctx to stop immediately.
+func catchCtrlC(ctx context.Context, cancel context.CancelFunc, wg *sync.WaitGroup, streamInfo *StreamingInfo) {
 	defer wg.Done()
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-c
-		streamInfo.UserExit = true
+		defer signal.Stop(c)
+		select {
+		case <-c:
+			streamInfo.Exit()
+			fmt.Println("Processing remaining records. Use Ctrl+C again to stop immediately.")
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-c:
+			cancel()
+		case <-ctx.Done():
+		}
 	}()
 }
 
+// sleep pauses for duration d. It returns false if ctx is done before d
+// elapses.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // clear erases the last printed line on the output file.
 var clear = fmt.Sprintf("%c[%dA%c[2K", ESC, 1, ESC)
 
@@ -110,7 +176,7 @@ func updateProgress(optimumCondition, firstCall bool, totalRecordsProcessed int6
 
 // cutoverHelper analyzes the records processed and makes a decision if current moment is
 // optimum for switching to Cloud Spanner or not.
-func cutoverHelper(wg *sync.WaitGroup, streamInfo *StreamingInfo) {
+func cutoverHelper(ctx context.Context, wg *sync.WaitGroup, streamInfo *StreamingInfo) {
 	defer wg.Done()
 
 	updateProgress(false, true, streamInfo.recordsProcessed)
@@ -120,10 +186,10 @@ func cutoverHelper(wg *sync.WaitGroup, streamInfo *StreamingInfo) {
 	lastFiveMin := int64(0)
 	tillLastMin := int64(0)
 	arr := [5]int64{0, 0, 0, 0, 0}
+	caughtUp, recommended := false, false
 
 	for {
-		time.Sleep(60 * time.Second)
-		if streamInfo.UserExit {
+		if !streamInfo.sleep(ctx, 60*time.Second) {
 			break
 		}
 		counter := timer % 5
@@ -142,24 +208,35 @@ func cutoverHelper(wg *sync.WaitGroup, streamInfo *StreamingInfo) {
 		lastMin := arr[counter]
 		optimumCondition := ((lastFiveMin*100 <= 5*firstFiveMin) || (lastMin == 0))
 		updateProgress(optimumCondition, false, tillLastMin)
+		monitoring.ReportReplication(ctx, streamInfo.ReplicationLag(time.Now(), 60*time.Second), optimumCondition)
+		if !caughtUp && timer >= 5 && lastFiveMin*100 <= 5*firstFiveMin {
+			caughtUp = true
+			notify.Sendf(notify.StreamingCaughtUp, "streaming has caught up: %d records processed in the last 5 minutes, %d in the first 5 minutes.", lastFiveMin, firstFiveMin)
+		}
+		if !recommended && optimumCondition {
+			recommended = true
+			notify.Sendf(notify.CutoverRecommended, "now is a good time to switch to Cloud Spanner: %d records processed in the last minute, %d in total.", lastMin, tillLastMin)
+		}
 		timer++
 	}
 }
 
 // ProcessStream processes the latest enabled DynamoDB Stream for a table. It searches
 // for shards within stream and for each shard it creates a seperate working thread to
-// process records within it.
-func ProcessStream(wgStream *sync.WaitGroup, streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, streamInfo *StreamingInfo, conv *internal.Conv, streamArn, srcTable string) {
+// process records within it. Processing stops as soon as ctx is done.
+func ProcessStream(ctx context.Context, wgStream *sync.WaitGroup, streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, streamInfo *StreamingInfo, conv *internal.Conv, streamArn, srcTable string) {
 	defer wgStream.Done()
 	wgShard := &sync.WaitGroup{}
 
 	processingStarted := make(map[string]bool)
 
 	passAfterUserExit := false
-	for {
-		shards, err := scanShards(streamClient, streamArn)
+	for ctx.Err() == nil {
+		shards, err := scanShards(ctx, streamClient, streamArn)
 		if err != nil {
-			streamInfo.Unexpected(fmt.Sprintf("Couldn't scan shards for table %s: %s", srcTable, err))
+			if ctx.Err() == nil {
+				streamInfo.Unexpected(fmt.Sprintf("Couldn't scan shards for table %s: %s", srcTable, err))
+			}
 			break
 		}
 		for _, shard := range shards {
@@ -175,32 +252,32 @@ func ProcessStream(wgStream *sync.WaitGroup, streamClient dynamodbstreamsiface.D
 				processingStarted[shardId] = true
 
 				wgShard.Add(1)
-				go ProcessShard(wgShard, streamInfo, conv, streamClient, shard, streamArn, srcTable)
+				go ProcessShard(ctx, wgShard, streamInfo, conv, streamClient, shard, streamArn, srcTable)
 			}
 		}
 
 		if passAfterUserExit {
 			break
-		} else if streamInfo.UserExit {
+		} else if streamInfo.exited() {
 			passAfterUserExit = true
 		} else {
-			time.Sleep(20 * time.Second)
+			streamInfo.sleep(ctx, 20*time.Second)
 		}
 	}
 	wgShard.Wait()
 }
 
 // scanShards fetches all the shards from a given DynamoDB Stream.
-func scanShards(streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, streamArn string) ([]*dynamodbstreams.Shard, error) {
+func scanShards(ctx context.Context, streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, streamArn string) ([]*dynamodbstreams.Shard, error) {
 	describeStreamInput := &dynamodbstreams.DescribeStreamInput{
 		ExclusiveStartShardId: nil,
 		StreamArn:             &streamArn,
 	}
 	var scanResult []*dynamodbstreams.Shard
 	for {
-		result, err := streamClient.DescribeStream(describeStreamInput)
+		result, err := streamClient.DescribeStreamWithContext(ctx, describeStreamInput)
 		if err != nil {
-			return nil, fmt.Errorf("unexpected call to DescribeStream: %v", err)
+			return nil, fmt.Errorf("unexpected call to DescribeStream: %w", streamsError(err))
 		}
 		scanResult = append(scanResult, result.StreamDescription.Shards...)
 
@@ -213,29 +290,43 @@ func scanShards(streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, streamArn
 	return scanResult, nil
 }
 
-// checkTrimmedDataError checks if the error is an TrimmedDataAccessException.
-func checkTrimmedDataError(err error) bool {
-	return strings.Contains(err.Error(), "TrimmedDataAccessException")
+// streamsError classifies err returned by DynamoDB Streams with the kinds of
+// package errs.
+func streamsError(err error) error {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		case dynamodbstreams.ErrCodeTrimmedDataAccessException:
+			return errs.Wrap(errs.ErrTrimmedData, err)
+		case request.CanceledErrorCode:
+			return errs.Wrap(errs.ErrCancelled, err)
+		}
+	}
+	return err
 }
 
 // ProcessShard processes records within a shard starting from the first unexpired record. It
 // doesn't start processing unless parent shard is processed. For closed shards this process is
 // completed after processing all records but for open shards it keeps searching for new records
-// until shards gets closed or customer calls for a exit.
-func ProcessShard(wgShard *sync.WaitGroup, streamInfo *StreamingInfo, conv *internal.Conv, streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, shard *dynamodbstreams.Shard, streamArn, srcTable string) {
+// until shards gets closed or customer calls for a exit. Processing stops as soon as ctx
+// is done.
+func ProcessShard(ctx context.Context, wgShard *sync.WaitGroup, streamInfo *StreamingInfo, conv *internal.Conv, streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, shard *dynamodbstreams.Shard, streamArn, srcTable string) {
 	defer wgShard.Done()
 
-	waitForParentShard(streamInfo, shard.ParentShardId)
+	waitForParentShard(ctx, streamInfo, shard.ParentShardId)
 
 	shardId := *shard.ShardId
 
 	var lastEvaluatedSequenceNumber *string = nil
 	passAfterUserExit := false
 	retryCount := 0
-	for {
-		shardIterator, err := getShardIterator(streamClient, lastEvaluatedSequenceNumber, shardId, streamArn)
+	for ctx.Err() == nil {
+		shardIterator, err := getShardIterator(ctx, streamClient, lastEvaluatedSequenceNumber, shardId, streamArn)
+		if ctx.Err() != nil {
+			break
+		}
 		if err != nil {
-			if checkTrimmedDataError(err) {
+			if errors.Is(err, errs.ErrTrimmedData) {
 				lastEvaluatedSequenceNumber = nil
 				continue
 			} else {
@@ -244,13 +335,16 @@ func ProcessShard(wgShard *sync.WaitGroup, streamInfo *StreamingInfo, conv *inte
 			}
 		}
 
-		getRecordsOutput, err := getRecords(streamClient, shardIterator)
+		getRecordsOutput, err := getRecords(ctx, streamClient, shardIterator)
+		if ctx.Err() != nil {
+			break
+		}
 		if err != nil {
 			// In case of closed shards, after all data records get expired it still returns a non-nil
 			// shardIterator for GetShardIterator query. Using this shardIterator for GetRecords
 			// API call results in TrimmedDataAccessException. This will result in same steps being
 			// followed again and again. To handle this a retry limit of 5 is set.
-			if checkTrimmedDataError(err) && retryCount < 5 {
+			if errors.Is(err, errs.ErrTrimmedData) && retryCount < 5 {
 				lastEvaluatedSequenceNumber = nil
 				retryCount++
 				continue
@@ -263,35 +357,40 @@ func ProcessShard(wgShard *sync.WaitGroup, streamInfo *StreamingInfo, conv *inte
 		}
 
 		records := getRecordsOutput.Records
+		streamInfo.governor.consumeRecords(records)
 		for _, record := range records {
-			ProcessRecord(conv, streamInfo, record, srcTable)
+			if ctx.Err() != nil {
+				break
+			}
+			ProcessRecord(ctx, conv, streamInfo, record, srcTable, shardId)
 			lastEvaluatedSequenceNumber = record.Dynamodb.SequenceNumber
 		}
 
 		if getRecordsOutput.NextShardIterator == nil || passAfterUserExit {
 			break
 		}
-		if streamInfo.UserExit {
+		if streamInfo.exited() {
 			passAfterUserExit = true
 		} else if len(records) == 0 {
-			time.Sleep(5 * time.Second)
+			streamInfo.sleep(ctx, 5*time.Second)
 		}
 	}
-	streamInfo.SetShardStatus(shardId, true)
+	// A cancelled shard isn't completely processed.
+	if ctx.Err() == nil {
+		streamInfo.SetShardStatus(shardId, true)
+	}
 }
 
 // waitForParentShard checks every 6 seconds if parentShard is processed or
-// not and waits as long as parent shard is not processed.
-func waitForParentShard(streamInfo *StreamingInfo, parentShard *string) {
+// not and waits as long as parent shard is not processed, or until ctx is done.
+func waitForParentShard(ctx context.Context, streamInfo *StreamingInfo, parentShard *string) {
 	if parentShard != nil {
 		for {
 			streamInfo.lock.Lock()
 			done, ok := streamInfo.ShardProcessed[*parentShard]
 			streamInfo.lock.Unlock()
-			if !ok || done {
+			if !ok || done || !sleep(ctx, 6*time.Second) {
 				return
-			} else {
-				time.Sleep(6 * time.Second)
 			}
 		}
 	}
@@ -301,7 +400,7 @@ func waitForParentShard(streamInfo *StreamingInfo, parentShard *string) {
 // If lastEvaluatedSequenceNumber is nil then it uses TrimHorizon as shardIterator type to point to first
 // non-expired record otherwise it finds the first unprocessed record after lastEvaluatedSequence number using
 // AfterSequenceNumber shardIterator type.
-func getShardIterator(streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, lastEvaluatedSequenceNumber *string, shardId, streamArn string) (*string, error) {
+func getShardIterator(ctx context.Context, streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, lastEvaluatedSequenceNumber *string, shardId, streamArn string) (*string, error) {
 	var getShardIteratorInput *dynamodbstreams.GetShardIteratorInput
 	if lastEvaluatedSequenceNumber == nil {
 		getShardIteratorInput = &dynamodbstreams.GetShardIteratorInput{
@@ -317,22 +416,22 @@ func getShardIterator(streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, last
 			StreamArn:         &streamArn,
 		}
 	}
-	result, err := streamClient.GetShardIterator(getShardIteratorInput)
+	result, err := streamClient.GetShardIteratorWithContext(ctx, getShardIteratorInput)
 	if err != nil {
-		err = fmt.Errorf("unexpected call to GetShardIterator: %v", err)
+		err = fmt.Errorf("unexpected call to GetShardIterator: %w", streamsError(err))
 		return nil, err
 	}
 	return result.ShardIterator, nil
 }
 
 // getRecords fetches the records from DynamoDB Streams by using the shardIterator.
-func getRecords(streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, shardIterator *string) (*dynamodbstreams.GetRecordsOutput, error) {
+func getRecords(ctx context.Context, streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, shardIterator *string) (*dynamodbstreams.GetRecordsOutput, error) {
 	getRecordsInput := &dynamodbstreams.GetRecordsInput{
 		ShardIterator: shardIterator,
 	}
-	result, err := streamClient.GetRecords(getRecordsInput)
+	result, err := streamClient.GetRecordsWithContext(ctx, getRecordsInput)
 	if err != nil {
-		err = fmt.Errorf("unexpected call to GetRecords: %v", err)
+		err = fmt.Errorf("unexpected call to GetRecords: %w", streamsError(err))
 		return result, err
 	}
 	return result, nil
@@ -341,13 +440,19 @@ func getRecords(streamClient dynamodbstreamsiface.DynamoDBStreamsAPI, shardItera
 // ProcessRecord processes records retrieved from shards. It first converts the data
 // to Spanner data (based on the source and Spanner schemas), and then writes that data
 // to Cloud Spanner.
-func ProcessRecord(conv *internal.Conv, streamInfo *StreamingInfo, record *dynamodbstreams.Record, srcTable string) {
+func ProcessRecord(ctx context.Context, conv *internal.Conv, streamInfo *StreamingInfo, record *dynamodbstreams.Record, srcTable, shardId string) {
 	eventName := *record.EventName
 	streamInfo.StatsAddRecord(srcTable, eventName)
+	if record.Dynamodb.ApproximateCreationDateTime != nil {
+		streamInfo.SetLatestRecordTime(*record.Dynamodb.ApproximateCreationDateTime, time.Now())
+	}
 
-	srcSchema, spTable, spCols, spSchema, err := common.GetColsAndSchemas(conv, srcTable)
-	if err != nil {
-		streamInfo.Unexpected(fmt.Sprintf("Can't get cols and schemas for table %s: %v", srcTable, err))
+	// Records made before the export the table was bulk-loaded from are
+	// already reflected in it. Creation times are rounded down to the second.
+	if start, ok := streamInfo.startTimes[srcTable]; ok && record.Dynamodb.ApproximateCreationDateTime != nil &&
+		record.Dynamodb.ApproximateCreationDateTime.Before(start.Truncate(time.Second)) {
+		streamInfo.StatsAddStaleRecord(srcTable, eventName)
+		streamInfo.StatsAddRecordProcessed()
 		return
 	}
 
@@ -356,11 +461,39 @@ func ProcessRecord(conv *internal.Conv, streamInfo *StreamingInfo, record *dynam
 		srcImage = record.Dynamodb.Keys
 	} else {
 		srcImage = record.Dynamodb.NewImage
+		handleSchemaDrift(ctx, conv, streamInfo, srcTable, srcImage)
+	}
+
+	streamInfo.schemaLock.RLock()
+	srcSchema, spTable, spCols, spSchema, err := common.GetColsAndSchemas(conv, srcTable)
+	streamInfo.schemaLock.RUnlock()
+	if err != nil {
+		streamInfo.Unexpected(fmt.Sprintf("Can't get cols and schemas for table %s: %v", srcTable, err))
+		return
 	}
 
 	spVals, badCols, srcStrVals := cvtRow(srcImage, srcSchema, spSchema, spCols)
+	var nested []internal.NestedRows
 	if len(badCols) == 0 {
-		writeRecord(streamInfo, srcTable, spTable, eventName, spCols, spVals, srcSchema)
+		// The elements of nested columns are written to their nested tables.
+		streamInfo.schemaLock.RLock()
+		spCols, spVals, nested, err = conv.NestRow(spTable, spCols, spVals)
+		streamInfo.schemaLock.RUnlock()
+		if err != nil {
+			streamInfo.Unexpected(err.Error())
+		}
+	}
+	if len(badCols) == 0 && err == nil {
+		// Records for a key can be replayed or arrive after a newer record for the same
+		// key has already been written (e.g. on retries after a shard iterator expires).
+		// Skip such records so that a row in Cloud Spanner doesn't regress to an older version.
+		key := recordKey(srcImage, srcSchema.PrimaryKeys)
+		seqNum := aws.StringValue(record.Dynamodb.SequenceNumber)
+		if seqNum != "" && streamInfo.IsStale(shardId, key, seqNum) {
+			streamInfo.StatsAddStaleRecord(srcTable, eventName)
+		} else if writeRecord(ctx, streamInfo, srcTable, spTable, eventName, spCols, spVals, nested, srcSchema, spSchema, key, seqNum) && seqNum != "" {
+			streamInfo.SetLatestSequence(shardId, key, seqNum)
+		}
 	} else {
 		streamInfo.StatsAddBadRecord(srcTable, eventName)
 		streamInfo.CollectBadRecord(eventName, srcTable, srcSchema.ColNames, srcStrVals)
@@ -368,92 +501,154 @@ func ProcessRecord(conv *internal.Conv, streamInfo *StreamingInfo, record *dynam
 	streamInfo.StatsAddRecordProcessed()
 }
 
+// recordKey builds a string which uniquely identifies the primary key of a record.
+func recordKey(image map[string]*dynamodb.AttributeValue, primaryKeys []schema.Key) string {
+	var vals []string
+	for _, pk := range primaryKeys {
+		if v, ok := image[pk.Column]; ok && v != nil {
+			vals = append(vals, v.String())
+		} else {
+			vals = append(vals, "null")
+		}
+	}
+	return strings.Join(vals, "|")
+}
+
 // writeRecord handles creation and processing of mutation from the converted data to Cloud Spanner.
-// If the writer which writes mutations to Cloud Spanner is not configured then it treats the record
-// as a bad record.
-func writeRecord(streamInfo *StreamingInfo, srcTable, spTable, eventName string, spCols []string, spVals []interface{}, srcSchema schema.Table) {
+// The rows of the nested tables of the record (see internal.NestedTable) are written along with
+// it, in the same transaction. If the writer which writes mutations to Cloud Spanner is not
+// configured then it treats the record as a bad record. It returns true if the record is reflected
+// in Cloud Spanner, either because it was written successfully now or because it or a newer record
+// for the same primary key had already been applied earlier.
+func writeRecord(ctx context.Context, streamInfo *StreamingInfo, srcTable, spTable, eventName string, spCols []string, spVals []interface{}, nested []internal.NestedRows, srcSchema schema.Table, spSchema ddl.CreateTable, key, seqNum string) bool {
 	if streamInfo.write == nil {
 		msg := "Internal error: writeRecord called but writer not configured"
 		streamInfo.StatsAddBadRecord(srcTable, eventName)
 		streamInfo.Unexpected(msg)
-	} else {
-		m := getMutation(eventName, srcTable, spTable, spCols, spVals, srcSchema)
-		err := writeMutation(m, streamInfo)
-		if err != nil {
-			streamInfo.StatsAddDroppedRecord(srcTable, eventName)
-			streamInfo.CollectDroppedRecord(eventName, spTable, spCols, spVals, err)
-		}
+		return false
+	}
+	m, err := getMutation(eventName, srcTable, spTable, spCols, spVals, srcSchema, spSchema)
+	if err != nil {
+		streamInfo.StatsAddBadRecord(srcTable, eventName)
+		streamInfo.Unexpected(fmt.Sprintf("Can't create mutation for table %s: %v", srcTable, err))
+		return false
+	}
+	// Rows of nested tables are written after the row of their table, and
+	// deleted before it.
+	ms := append([]*sp.Mutation{m}, nestedMutations(eventName, nested)...)
+	if eventName == "REMOVE" {
+		ms = append(nestedMutations(eventName, nested), m)
+	}
+	applied, err := writeMutations(ctx, ms, streamInfo, srcTable, key, seqNum, eventName == "REMOVE")
+	if err != nil {
+		streamInfo.StatsAddDroppedRecord(srcTable, eventName)
+		streamInfo.CollectDroppedRecord(eventName, spTable, spCols, spVals, err)
+		return false
+	}
+	if applied {
+		streamInfo.StatsAddStaleRecord(srcTable, eventName)
 	}
+	return true
 }
 
 // getMutation creates a mutation for writing to Cloud Spanner from the converted data.
-func getMutation(eventName, srcTable, spTable string, spCols []string, spVals []interface{}, srcSchema schema.Table) (m *sp.Mutation) {
-	if eventName == "INSERT" {
-		m = sp.Insert(spTable, spCols, spVals)
-	} else if eventName == "MODIFY" {
-		m = sp.InsertOrUpdate(spTable, spCols, spVals)
-	} else {
-		m = removeMutation(srcSchema, spTable, srcTable, spVals)
+func getMutation(eventName, srcTable, spTable string, spCols []string, spVals []interface{}, srcSchema schema.Table, spSchema ddl.CreateTable) (*sp.Mutation, error) {
+	if eventName == "INSERT" || eventName == "MODIFY" {
+		cols, vals := utils.CommitTimestamps(spSchema, spCols, spVals)
+		if eventName == "INSERT" {
+			return sp.Insert(spTable, cols, vals), nil
+		}
+		return sp.InsertOrUpdate(spTable, cols, vals), nil
 	}
-	return m
+	return removeMutation(srcSchema, spSchema, spTable, spCols, spVals)
 }
 
-// removeMutation create a mutation from converted data for records of type 'REMOVE'.
-// It ensures that when keyset is created the order for primary keys passed is same
-// as the original database i.e. HASH Key, Partition Key.
-func removeMutation(srcSchema schema.Table, spTable, srcTable string, spVals []interface{}) (m *sp.Mutation) {
-	var srcKeys []string
-	var reqSpVals []interface{}
-	for i := 0; i < len(spVals); i++ {
-		if spVals[i] == nil {
+// nestedMutations creates the mutations writing the rows of the nested tables
+// of a record, which replace the rows written for earlier records of the same
+// key. Records of type 'REMOVE' only delete rows.
+func nestedMutations(eventName string, nested []internal.NestedRows) []*sp.Mutation {
+	var ms []*sp.Mutation
+	for _, n := range nested {
+		ms = append(ms, sp.Delete(n.Table, sp.Key(n.Key).AsPrefix()))
+		if eventName == "REMOVE" {
 			continue
 		}
-		srcKeys = append(srcKeys, srcSchema.ColNames[i])
-		reqSpVals = append(reqSpVals, spVals[i])
-	}
-	primaryKeys := srcSchema.PrimaryKeys
-	if primaryKeys[0].Column != srcKeys[0] {
-		reqSpVals[0], reqSpVals[1] = reqSpVals[1], reqSpVals[0]
-	}
-	if len(reqSpVals) == 1 {
-		m = sp.Delete(spTable, sp.Key{reqSpVals[0]})
-	} else {
-		m = sp.Delete(spTable, sp.Key{reqSpVals[0], reqSpVals[1]})
+		for _, vals := range n.Vals {
+			ms = append(ms, sp.Insert(n.Table, n.Cols, vals))
+		}
 	}
-	return m
+	return ms
 }
 
-// parentDataMissingError is used to track errors where insertions fail because of missing parent data.
-//
-// Note: If error code and description for parent row missing error is changed in future, then this
-// function is subject to change.
-func parentDataMissingError(err error) bool {
-	return strings.Contains(err.Error(), "NotFound") && strings.Contains(err.Error(), "Parent row") && strings.Contains(err.Error(), "is missing")
+// removeMutation create a mutation from converted data for records of type 'REMOVE'.
+// The key is built in the primary key order of the Spanner table, which for interleaved
+// child tables starts with the key columns of the parent. If the Spanner schema has
+// no primary key (e.g. in tests) the declared order of the source primary key is used
+// i.e. HASH Key, Partition Key. spVals is indexed in the same order as srcSchema.ColNames
+// and spCols.
+func removeMutation(srcSchema schema.Table, spSchema ddl.CreateTable, spTable string, spCols []string, spVals []interface{}) (*sp.Mutation, error) {
+	var keyCols []string
+	colIndex := make(map[string]int)
+	if len(spSchema.Pks) > 0 {
+		for _, pk := range spSchema.Pks {
+			keyCols = append(keyCols, pk.Col)
+		}
+		for i, col := range spCols {
+			colIndex[col] = i
+		}
+	} else {
+		for _, pk := range srcSchema.PrimaryKeys {
+			keyCols = append(keyCols, pk.Column)
+		}
+		for i, col := range srcSchema.ColNames {
+			colIndex[col] = i
+		}
+	}
+	if len(keyCols) == 0 {
+		return nil, fmt.Errorf("no primary key found for table %s", spTable)
+	}
+	var key sp.Key
+	for _, col := range keyCols {
+		i, ok := colIndex[col]
+		if !ok || i >= len(spVals) || spVals[i] == nil {
+			return nil, fmt.Errorf("value for primary key column %s missing", col)
+		}
+		key = append(key, spVals[i])
+	}
+	return sp.Delete(spTable, key), nil
 }
 
-// writeMutation handles writing of a mutation to Cloud Spanner. To handle insertions failing
-// because of missing parent data, a retryLimit is set.
-func writeMutation(m *sp.Mutation, streamInfo *StreamingInfo) error {
+// writeMutations handles writing of the mutations of a record to Cloud Spanner. To handle
+// insertions failing because of missing parent data, a retryLimit is set. If versioned writes
+// are configured, the mutations are written along with seqNum as the version of the primary key
+// key (deleted if the record removed the item), and writeMutations returns true if the record or
+// a newer record for key had already been applied earlier. Retries stop once ctx is done.
+func writeMutations(ctx context.Context, ms []*sp.Mutation, streamInfo *StreamingInfo, srcTable, key, seqNum string, deleted bool) (bool, error) {
 	var err error
+	applied := false
 	tryNum := 0
 	for tryNum < retryLimit {
-		err = streamInfo.write(m)
-		if err == nil || !parentDataMissingError(err) {
+		if streamInfo.writeVersioned != nil && seqNum != "" {
+			applied, err = streamInfo.writeVersioned(ctx, ms, srcTable, key, seqNum, deleted)
+		} else {
+			err = streamInfo.write(ctx, ms)
+		}
+		err = errs.Spanner(err)
+		if err == nil || !errors.Is(err, errs.ErrParentRowMissing) || !sleep(ctx, parentRowRetryDelay) {
 			break
 		}
-		time.Sleep(4 * time.Second)
 		tryNum++
 	}
-	return err
+	return applied, err
 }
 
 // setWriter initializes the write function used to write mutations to Cloud Spanner.
 func setWriter(streamInfo *StreamingInfo, client *sp.Client, conv *internal.Conv) {
-	streamInfo.write = func(m *sp.Mutation) error {
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
 		migrationData := metrics.GetMigrationData(conv, "", "", constants.DataConv)
 		serializedMigrationData, _ := proto.Marshal(migrationData)
 		migrationMetadataValue := base64.StdEncoding.EncodeToString(serializedMigrationData)
-		_, err := client.Apply(metadata.AppendToOutgoingContext(context.Background(), constants.MigrationMetadataKey, migrationMetadataValue), []*sp.Mutation{m})
+		_, err := client.Apply(metadata.AppendToOutgoingContext(ctx, constants.MigrationMetadataKey, migrationMetadataValue), ms, utils.ApplyOptions(conv.WriteOptions)...)
 		return err
 	}
 }
@@ -474,6 +669,9 @@ func fillConvWithStreamingStats(streamInfo *StreamingInfo, conv *internal.Conv)
 	conv.Audit.StreamingStats.TotalRecords = streamInfo.Records
 	conv.Audit.StreamingStats.BadRecords = streamInfo.BadRecords
 	conv.Audit.StreamingStats.DroppedRecords = streamInfo.DroppedRecords
+	conv.Audit.StreamingStats.StaleRecords = streamInfo.StaleRecords
+	conv.Audit.StreamingStats.UnknownAttributes = streamInfo.UnknownAttributes
+	conv.Audit.StreamingStats.AddedColumns = streamInfo.AddedColumns
 
 	// Pass badRecords and droppedRecords
 	conv.Audit.StreamingStats.SampleBadRecords = streamInfo.SampleBadRecords
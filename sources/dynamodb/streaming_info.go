@@ -14,8 +14,11 @@
 package dynamodb
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	sp "cloud.google.com/go/spanner"
 
@@ -24,29 +27,87 @@ import (
 
 // StreamingInfo contains information related to processing of DynamoDB Streams.
 type StreamingInfo struct {
-	Records          map[string]map[string]int64 // Tablewise count of records received from DynamoDB Streams, broken down by record type i.e. INSERT, MODIFY & REMOVE.
-	BadRecords       map[string]map[string]int64 // Tablewise count of records not converted successfully, broken down by record type.
-	DroppedRecords   map[string]map[string]int64 // Tablewise count of records successfully converted but failed to written on Spanner, broken down by record type.
-	recordsProcessed int64                       // Count of total records processed to Cloud Spanner(includes records which generated error as well).
-	ShardProcessed   map[string]bool             // Processing status of a shard, (default false i.e. unprocessed).
-	UserExit         bool                        // Flag confirming if customer wants to exit or not, (false until user presses Ctrl+C).
-	Unexpecteds      map[string]int64            // Count of unexpected conditions, broken down by condition description.
-	write            func(m *sp.Mutation) error  // Writes a given mutation to Cloud Spanner.
-	SampleBadRecords []string                    // Records that generated errors during conversion.
-	SampleBadWrites  []string                    // Records that faced errors while writing to Cloud Spanner.
-	lock             sync.Mutex
+	Records           map[string]map[string]int64                                                                            // Tablewise count of records received from DynamoDB Streams, broken down by record type i.e. INSERT, MODIFY & REMOVE.
+	BadRecords        map[string]map[string]int64                                                                            // Tablewise count of records not converted successfully, broken down by record type.
+	DroppedRecords    map[string]map[string]int64                                                                            // Tablewise count of records successfully converted but failed to written on Spanner, broken down by record type.
+	StaleRecords      map[string]map[string]int64                                                                            // Tablewise count of records skipped because the record or a newer record for the same key was already written, broken down by record type.
+	UnknownAttributes map[string]map[string]int64                                                                            // Tablewise count of records containing attributes missing from the schema, broken down by attribute name.
+	AddedColumns      map[string][]string                                                                                    // Tablewise list of attributes for which a nullable column was added to the schema during streaming.
+	latestSequence    map[string]map[string]string                                                                           // Shardwise sequence number of the latest record written to Cloud Spanner, broken down by primary key.
+	startTimes        map[string]time.Time                                                                                   // Tablewise time of the export the table was bulk-loaded from: older records are already reflected in it.
+	governor          *readGovernor                                                                                          // If set, limits the rate of stream reads to a budget of read capacity units.
+	recordsProcessed  int64                                                                                                  // Count of total records processed to Cloud Spanner(includes records which generated error as well).
+	latestRecordTime  time.Time                                                                                              // Approximate creation time of the latest record processed.
+	lastProcessedTime time.Time                                                                                              // Time a record was last processed.
+	ShardProcessed    map[string]bool                                                                                        // Processing status of a shard, (default false i.e. unprocessed).
+	Unexpecteds       map[string]int64                                                                                       // Count of unexpected conditions, broken down by condition description.
+	write             func(ctx context.Context, ms []*sp.Mutation) error                                                     // Writes the mutations of a record to Cloud Spanner.
+	updateSchema      func(ctx context.Context, stmt string) error                                                           // Applies a schema update DDL statement to Cloud Spanner.
+	writeVersioned    func(ctx context.Context, ms []*sp.Mutation, srcTable, key, seqNum string, deleted bool) (bool, error) // Writes the mutations of a record along with the version of its key, returns true if the record or a newer one was already applied.
+	SampleBadRecords  []string                                                                                               // Records that generated errors during conversion.
+	SampleBadWrites   []string                                                                                               // Records that faced errors while writing to Cloud Spanner.
+	sampleSize        int                                                                                                    // Maximum number of records kept in SampleBadRecords and SampleBadWrites.
+	badData           *internal.BadDataWriter                                                                                // If set, all bad and dropped records are written to it.
+	lock              sync.Mutex
+	addColumnFailed   map[string]map[string]bool // Tablewise set of attributes for which adding a column failed, so that it isn't retried.
+	schemaLock        sync.RWMutex               // Guards schema and name mappings in conv, which can be changed while streaming.
+	exit              chan struct{}              // Closed when customer wants to exit, to interrupt waits.
+	exitOnce          sync.Once
 }
 
 func MakeStreamingInfo() *StreamingInfo {
 	return &StreamingInfo{
-		Records:          make(map[string]map[string]int64),
-		BadRecords:       make(map[string]map[string]int64),
-		DroppedRecords:   make(map[string]map[string]int64),
-		recordsProcessed: int64(0),
-		ShardProcessed:   make(map[string]bool),
-		Unexpecteds:      make(map[string]int64),
-		UserExit:         false,
-		lock:             sync.Mutex{},
+		Records:           make(map[string]map[string]int64),
+		BadRecords:        make(map[string]map[string]int64),
+		DroppedRecords:    make(map[string]map[string]int64),
+		StaleRecords:      make(map[string]map[string]int64),
+		UnknownAttributes: make(map[string]map[string]int64),
+		AddedColumns:      make(map[string][]string),
+		addColumnFailed:   make(map[string]map[string]bool),
+		latestSequence:    make(map[string]map[string]string),
+		startTimes:        make(map[string]time.Time),
+		recordsProcessed:  int64(0),
+		ShardProcessed:    make(map[string]bool),
+		Unexpecteds:       make(map[string]int64),
+		exit:              make(chan struct{}),
+		sampleSize:        internal.DefaultBadDataSampleSize,
+		lock:              sync.Mutex{},
+	}
+}
+
+// Exit records that the customer wants to exit: processing stops once the
+// records already in the streams are processed.
+func (info *StreamingInfo) Exit() {
+	info.exitOnce.Do(func() {
+		close(info.exit)
+	})
+}
+
+// exited reports whether the customer wants to exit.
+func (info *StreamingInfo) exited() bool {
+	select {
+	case <-info.exit:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep pauses for duration d. It returns false if ctx is done or the
+// customer wants to exit before d elapses.
+func (info *StreamingInfo) sleep(ctx context.Context, d time.Duration) bool {
+	if info.exited() {
+		return false
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-info.exit:
+		return false
+	case <-ctx.Done():
+		return false
 	}
 }
 
@@ -56,14 +117,21 @@ func (info *StreamingInfo) makeRecordMaps(srcTable string) {
 	info.Records[srcTable] = make(map[string]int64)
 	info.BadRecords[srcTable] = make(map[string]int64)
 	info.DroppedRecords[srcTable] = make(map[string]int64)
+	info.StaleRecords[srcTable] = make(map[string]int64)
+	info.UnknownAttributes[srcTable] = make(map[string]int64)
 }
 
 // SetShardStatus changes the processing status of a shard.
 //
-// true -> shard processed and vice versa.
+// true -> shard processed and vice versa. The sequence numbers tracked for a
+// processed shard are dropped: its records are never read again, and later
+// records for the same keys are in its child shards.
 func (info *StreamingInfo) SetShardStatus(shardId string, status bool) {
 	info.lock.Lock()
 	info.ShardProcessed[shardId] = status
+	if status {
+		delete(info.latestSequence, shardId)
+	}
 	info.lock.Unlock()
 }
 
@@ -91,6 +159,70 @@ func (info *StreamingInfo) StatsAddDroppedRecord(srcTable, recordType string) {
 	info.lock.Unlock()
 }
 
+// StatsAddStaleRecord increases the count of records which were skipped because the record
+// or a newer record for the same primary key was already written to Cloud Spanner.
+func (info *StreamingInfo) StatsAddStaleRecord(srcTable, recordType string) {
+	info.lock.Lock()
+	info.StaleRecords[srcTable][recordType]++
+	info.lock.Unlock()
+}
+
+// StatsAddUnknownAttribute increases the count of records which contain an attribute
+// that is not present in the schema of the given table.
+func (info *StreamingInfo) StatsAddUnknownAttribute(srcTable, attrName string) {
+	info.lock.Lock()
+	info.UnknownAttributes[srcTable][attrName]++
+	info.lock.Unlock()
+}
+
+// StatsAddAddedColumn records that a column was added to the schema for the
+// given attribute during streaming.
+func (info *StreamingInfo) StatsAddAddedColumn(srcTable, attrName string) {
+	info.lock.Lock()
+	info.AddedColumns[srcTable] = append(info.AddedColumns[srcTable], attrName)
+	info.lock.Unlock()
+}
+
+// IsStale checks if a record of shard shardId with sequence number seqNum for the given
+// primary key is older than the latest record of the shard already written to Cloud
+// Spanner for the same key. All records for a key are in the same shard until it is
+// closed, and child shards are only processed after their parent shard.
+func (info *StreamingInfo) IsStale(shardId, key, seqNum string) bool {
+	info.lock.Lock()
+	defer info.lock.Unlock()
+	latest, ok := info.latestSequence[shardId][key]
+	return ok && compareSequenceNumbers(seqNum, latest) <= 0
+}
+
+// SetLatestSequence stores seqNum as the sequence number of the latest record written
+// to Cloud Spanner from shard shardId for the given primary key.
+func (info *StreamingInfo) SetLatestSequence(shardId, key, seqNum string) {
+	info.lock.Lock()
+	defer info.lock.Unlock()
+	if _, ok := info.latestSequence[shardId]; !ok {
+		info.latestSequence[shardId] = make(map[string]string)
+	}
+	if latest, ok := info.latestSequence[shardId][key]; !ok || compareSequenceNumbers(seqNum, latest) > 0 {
+		info.latestSequence[shardId][key] = seqNum
+	}
+}
+
+// compareSequenceNumbers compares two DynamoDB Streams sequence numbers. Sequence
+// numbers are decimal strings which can exceed the range of int64, so they are
+// compared by length first and then lexicographically. It returns -1, 0 or 1 if
+// a is less than, equal to or greater than b respectively.
+func compareSequenceNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
 // StatsAddRecordProcessed increases the count of total records processed to Cloud Spanner.
 func (info *StreamingInfo) StatsAddRecordProcessed() {
 	info.lock.Lock()
@@ -98,6 +230,29 @@ func (info *StreamingInfo) StatsAddRecordProcessed() {
 	info.lock.Unlock()
 }
 
+// SetLatestRecordTime records that a record created at approximately t is
+// being processed at now.
+func (info *StreamingInfo) SetLatestRecordTime(t, now time.Time) {
+	info.lock.Lock()
+	if t.After(info.latestRecordTime) {
+		info.latestRecordTime = t
+	}
+	info.lastProcessedTime = now
+	info.lock.Unlock()
+}
+
+// ReplicationLag returns the lag at now of the replication of changes to
+// Cloud Spanner: the age of the latest record processed, or 0 if no record
+// was processed in the last interval, as the streams are caught up.
+func (info *StreamingInfo) ReplicationLag(now time.Time, interval time.Duration) time.Duration {
+	info.lock.Lock()
+	defer info.lock.Unlock()
+	if info.lastProcessedTime.IsZero() || now.Sub(info.lastProcessedTime) > interval {
+		return 0
+	}
+	return now.Sub(info.latestRecordTime)
+}
+
 // Unexpected records stats about corner-cases and conditions
 // that were not expected.
 func (info *StreamingInfo) Unexpected(u string) {
@@ -122,10 +277,17 @@ func (info *StreamingInfo) TotalUnexpecteds() int64 {
 func (info *StreamingInfo) CollectBadRecord(recordType, srcTable string, srcCols []string, vals []string) {
 	info.lock.Lock()
 	badRecord := fmt.Sprintf("type=%s table=%s cols=%v data=%v", recordType, srcTable, srcCols, vals)
-	// Cap storage used by sampleBadRecords. Keep at least one bad record and at max 100.
-	if len(info.SampleBadRecords) < 100 {
+	// Cap storage used by sampleBadRecords. Keep at least one bad record and at max sampleSize.
+	if len(info.SampleBadRecords) < info.sampleSize || len(info.SampleBadRecords) == 0 {
 		info.SampleBadRecords = append(info.SampleBadRecords, badRecord)
 	}
+	if info.badData != nil {
+		var l []interface{}
+		for _, v := range vals {
+			l = append(l, v)
+		}
+		info.badData.Write(internal.ConversionError, internal.BadDataRecord{Table: srcTable, RecordType: recordType, Cols: srcCols, Vals: l})
+	}
 	info.lock.Unlock()
 }
 
@@ -133,9 +295,12 @@ func (info *StreamingInfo) CollectBadRecord(recordType, srcTable string, srcCols
 func (info *StreamingInfo) CollectDroppedRecord(recordType, spTable string, spCols []string, spVals []interface{}, err error) {
 	info.lock.Lock()
 	droppedRecord := fmt.Sprintf("type=%s table=%s cols=%v data=%v error=%v", recordType, spTable, spCols, spVals, err)
-	// Cap storage used by sampleBadWrites. Keep at least one dropped record and at max 100.
-	if len(info.SampleBadWrites) < 100 {
+	// Cap storage used by sampleBadWrites. Keep at least one dropped record and at max sampleSize.
+	if len(info.SampleBadWrites) < info.sampleSize || len(info.SampleBadWrites) == 0 {
 		info.SampleBadWrites = append(info.SampleBadWrites, droppedRecord)
 	}
+	if info.badData != nil {
+		info.badData.Write(internal.WriteError, internal.BadDataRecord{Table: spTable, RecordType: recordType, Cols: spCols, Vals: spVals, Error: err.Error()})
+	}
 	info.lock.Unlock()
 }
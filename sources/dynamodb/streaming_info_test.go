@@ -17,6 +17,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -31,8 +32,14 @@ func TestInfo_TotalUnexpecteds(t *testing.T) {
 func TestStreamingInfo_SetShardStatus(t *testing.T) {
 	streamInfo := MakeStreamingInfo()
 	shardId := "testShardId"
+	streamInfo.SetLatestSequence(shardId, "key1", "200")
+	streamInfo.SetShardStatus(shardId, false)
+	assert.Equal(t, true, streamInfo.IsStale(shardId, "key1", "200"))
+
+	// The sequence numbers of a processed shard are no longer needed.
 	streamInfo.SetShardStatus(shardId, true)
 	assert.Equal(t, true, streamInfo.ShardProcessed[shardId])
+	assert.Equal(t, 0, len(streamInfo.latestSequence))
 }
 
 func sumNestedMapValues(mp map[string]map[string]int64) int64 {
@@ -88,6 +95,18 @@ func TestStreamingInfo_StatsAddRecordProcessed(t *testing.T) {
 	assert.Equal(t, int64(3), streamInfo.recordsProcessed)
 }
 
+func TestStreamingInfo_ReplicationLag(t *testing.T) {
+	streamInfo := MakeStreamingInfo()
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Duration(0), streamInfo.ReplicationLag(now, time.Minute))
+
+	streamInfo.SetLatestRecordTime(now.Add(-5*time.Minute), now.Add(-30*time.Second))
+	streamInfo.SetLatestRecordTime(now.Add(-7*time.Minute), now.Add(-20*time.Second))
+	assert.Equal(t, 5*time.Minute, streamInfo.ReplicationLag(now, time.Minute))
+	// No record processed in the last interval: the streams are caught up.
+	assert.Equal(t, time.Duration(0), streamInfo.ReplicationLag(now.Add(2*time.Minute), time.Minute))
+}
+
 func TestStreamingInfo_makeRecordMaps(t *testing.T) {
 	streamInfo := MakeStreamingInfo()
 	table := "testtable"
@@ -126,3 +145,46 @@ func TestInfo_CollectDroppedRecord(t *testing.T) {
 
 	assert.Equal(t, expectedDroppedRecord, actualDroppedRecord)
 }
+
+func TestStreamingInfo_StatsAddStaleRecord(t *testing.T) {
+	streamInfo := MakeStreamingInfo()
+	tableName := "testtable"
+	streamInfo.makeRecordMaps(tableName)
+
+	streamInfo.StatsAddStaleRecord(tableName, "MODIFY")
+	streamInfo.StatsAddStaleRecord(tableName, "REMOVE")
+
+	assert.Equal(t, int64(2), sumNestedMapValues(streamInfo.StaleRecords))
+}
+
+func TestStreamingInfo_IsStale(t *testing.T) {
+	streamInfo := MakeStreamingInfo()
+	shardId := "testShardId"
+
+	assert.Equal(t, false, streamInfo.IsStale(shardId, "key1", "200"))
+	streamInfo.SetLatestSequence(shardId, "key1", "200")
+	assert.Equal(t, true, streamInfo.IsStale(shardId, "key1", "200"))
+	assert.Equal(t, true, streamInfo.IsStale(shardId, "key1", "99"))
+	assert.Equal(t, false, streamInfo.IsStale(shardId, "key1", "1000"))
+	assert.Equal(t, false, streamInfo.IsStale(shardId, "key2", "99"))
+
+	// An older sequence number never replaces a newer one.
+	streamInfo.SetLatestSequence(shardId, "key1", "150")
+	assert.Equal(t, true, streamInfo.IsStale(shardId, "key1", "199"))
+}
+
+func Test_compareSequenceNumbers(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"100", "100", 0},
+		{"99", "100", -1},
+		{"100", "99", 1},
+		{"000100", "100", 0},
+		{"400000000000000000000000000000000000001", "400000000000000000000000000000000000002", -1},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, compareSequenceNumbers(tc.a, tc.b), fmt.Sprintf("compare %s with %s", tc.a, tc.b))
+	}
+}
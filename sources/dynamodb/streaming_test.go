@@ -14,6 +14,7 @@
 package dynamodb
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
@@ -24,12 +25,19 @@ import (
 
 	sp "cloud.google.com/go/spanner"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
 	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/cloudspannerecosystem/harbourbridge/common/errs"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
@@ -87,9 +95,21 @@ func (m *mockDynamoStreamsClient) GetRecords(input *dynamodbstreams.GetRecordsIn
 	return &m.getRecordsOutputs[m.getRecordsCallCount-1], nil
 }
 
+func (m *mockDynamoStreamsClient) DescribeStreamWithContext(ctx aws.Context, input *dynamodbstreams.DescribeStreamInput, opts ...request.Option) (*dynamodbstreams.DescribeStreamOutput, error) {
+	return m.DescribeStream(input)
+}
+
+func (m *mockDynamoStreamsClient) GetShardIteratorWithContext(ctx aws.Context, input *dynamodbstreams.GetShardIteratorInput, opts ...request.Option) (*dynamodbstreams.GetShardIteratorOutput, error) {
+	return m.GetShardIterator(input)
+}
+
+func (m *mockDynamoStreamsClient) GetRecordsWithContext(ctx aws.Context, input *dynamodbstreams.GetRecordsInput, opts ...request.Option) (*dynamodbstreams.GetRecordsOutput, error) {
+	return m.GetRecords(input)
+}
+
 func TestProcessStream(t *testing.T) {
 	streamInfo := MakeStreamingInfo()
-	streamInfo.UserExit = true
+	streamInfo.Exit()
 	wgStream := &sync.WaitGroup{}
 
 	streamArn := [2]string{"streamArn1", "streamArn2"}
@@ -124,7 +144,7 @@ func TestProcessStream(t *testing.T) {
 
 	wgStream.Add(2)
 	for i := 0; i < 2; i++ {
-		ProcessStream(wgStream, streamsClient, streamInfo, nil, streamArn[i], srcTableName[i])
+		ProcessStream(context.Background(), wgStream, streamsClient, streamInfo, nil, streamArn[i], srcTableName[i])
 	}
 	wgStream.Wait()
 	assert.Equal(t, int64(1), streamInfo.TotalUnexpecteds())
@@ -210,7 +230,7 @@ func Test_scanShards(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := scanShards(tt.args.streamClient, tt.args.streamArn)
+			got, err := scanShards(context.Background(), tt.args.streamClient, tt.args.streamArn)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("scanShards() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -240,15 +260,15 @@ func Test_getShardIterator(t *testing.T) {
 		},
 	}
 
-	shardIterator, err := getShardIterator(mockStreamsClient, nil, "", "")
+	shardIterator, err := getShardIterator(context.Background(), mockStreamsClient, nil, "", "")
 	assert.Nil(t, err)
 	assert.Equal(t, shardIteratorTrimHorizon, *shardIterator)
 
-	shardIterator, err = getShardIterator(mockStreamsClient, &lastEvaluatedSequenceNumber, "", "")
+	shardIterator, err = getShardIterator(context.Background(), mockStreamsClient, &lastEvaluatedSequenceNumber, "", "")
 	assert.Nil(t, err)
 	assert.Equal(t, shardIteratorSeqNum, *shardIterator)
 
-	_, err = getShardIterator(mockStreamsClient, nil, "", "")
+	_, err = getShardIterator(context.Background(), mockStreamsClient, nil, "", "")
 	assert.NotNil(t, err)
 }
 
@@ -275,7 +295,7 @@ func Test_getRecords(t *testing.T) {
 		},
 	}
 	shardIterator := "testShardIterator"
-	result, err := getRecords(mockStreamsClient, &shardIterator)
+	result, err := getRecords(context.Background(), mockStreamsClient, &shardIterator)
 	assert.Nil(t, err)
 	assert.Equal(t, int(4), len(result.Records))
 	mp := make(map[string]int)
@@ -286,14 +306,14 @@ func Test_getRecords(t *testing.T) {
 	assert.Equal(t, int(2), mp["INSERT"])
 	assert.Equal(t, int(1), mp["MODIFY"])
 
-	_, err = getRecords(mockStreamsClient, &shardIterator)
+	_, err = getRecords(context.Background(), mockStreamsClient, &shardIterator)
 	assert.NotNil(t, err)
 }
 
 func TestProcessShard(t *testing.T) {
 	wgShard := &sync.WaitGroup{}
 	streamInfo := MakeStreamingInfo()
-	streamInfo.UserExit = true
+	streamInfo.Exit()
 	shardIterator_TrimHorizon := "testShardIteratorTrimHorizon"
 
 	mockStreamClient := &mockDynamoStreamsClient{
@@ -324,15 +344,46 @@ func TestProcessShard(t *testing.T) {
 	srcTable := "testSrcTable"
 
 	wgShard.Add(1)
-	ProcessShard(wgShard, streamInfo, nil, mockStreamClient, shard, streamArn, srcTable)
+	ProcessShard(context.Background(), wgShard, streamInfo, nil, mockStreamClient, shard, streamArn, srcTable)
 	assert.Equal(t, true, streamInfo.ShardProcessed[*shard.ShardId])
 
 	wgShard.Add(1)
-	ProcessShard(wgShard, streamInfo, nil, mockStreamClient, shard, streamArn, srcTable)
+	ProcessShard(context.Background(), wgShard, streamInfo, nil, mockStreamClient, shard, streamArn, srcTable)
 	assert.Equal(t, int64(1), streamInfo.TotalUnexpecteds())
 	assert.Equal(t, true, streamInfo.ShardProcessed[*shard.ShardId])
 }
 
+func TestProcessShardCancelled(t *testing.T) {
+	wgShard := &sync.WaitGroup{}
+	streamInfo := MakeStreamingInfo()
+	shardId := "testShardId"
+	parentShardId := "testParentShardId"
+	// The parent shard is never processed, so that ProcessShard waits for it until
+	// the context is cancelled.
+	streamInfo.SetShardStatus(parentShardId, false)
+	shard := &dynamodbstreams.Shard{ShardId: &shardId, ParentShardId: &parentShardId}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	wgShard.Add(1)
+	ProcessShard(ctx, wgShard, streamInfo, nil, &mockDynamoStreamsClient{}, shard, "testStreamArn", "testSrcTable")
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	assert.Equal(t, int64(0), streamInfo.TotalUnexpecteds())
+	assert.Equal(t, false, streamInfo.ShardProcessed[shardId])
+}
+
+func TestStreamingInfoSleep(t *testing.T) {
+	streamInfo := MakeStreamingInfo()
+	assert.True(t, streamInfo.sleep(context.Background(), time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, streamInfo.sleep(ctx, time.Minute))
+	time.AfterFunc(10*time.Millisecond, streamInfo.Exit)
+	assert.False(t, streamInfo.sleep(context.Background(), time.Minute))
+	assert.True(t, streamInfo.exited())
+}
+
 func TestProcessRecord(t *testing.T) {
 	valA := "strA"
 	numStr := "10.1"
@@ -375,17 +426,271 @@ func TestProcessRecord(t *testing.T) {
 	streamInfo := MakeStreamingInfo()
 	streamInfo.Records[tableName] = make(map[string]int64)
 	writes := 0
-	streamInfo.write = func(m *sp.Mutation) error {
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
 		writes++
-		assert.Equal(t, m, sp.Insert(tableName, []string{"a", "b"}, []interface{}{valA, *numVal}))
+		assert.Equal(t, ms, []*sp.Mutation{sp.Insert(tableName, []string{"a", "b"}, []interface{}{valA, *numVal})})
 		return nil
 	}
-	ProcessRecord(conv, streamInfo, record, tableName)
+	ProcessRecord(context.Background(), conv, streamInfo, record, tableName, "testShardId")
 
 	// Check if call was successful.
 	assert.Equal(t, 1, writes)
 }
 
+func TestProcessRecord_StaleRecord(t *testing.T) {
+	valA := "strA"
+	tableName := "testtable"
+	cols := []string{"a"}
+	conv := buildConv(
+		ddl.CreateTable{
+			Name:     tableName,
+			ColNames: cols,
+			ColDefs: map[string]ddl.ColumnDef{
+				"a": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			Pks: []ddl.IndexKey{{Col: "a"}},
+		},
+		schema.Table{
+			Name:     tableName,
+			ColNames: cols,
+			ColDefs: map[string]schema.Column{
+				"a": {Name: "a", Type: schema.Type{Name: typeString}},
+			},
+			PrimaryKeys: []schema.Key{{Column: "a"}},
+		},
+	)
+	newRecord := func(seqNum string) *dynamodbstreams.Record {
+		return &dynamodbstreams.Record{
+			Dynamodb: &dynamodbstreams.StreamRecord{
+				NewImage:       map[string]*dynamodb.AttributeValue{"a": {S: &valA}},
+				SequenceNumber: aws.String(seqNum),
+			},
+			EventName: aws.String("MODIFY"),
+		}
+	}
+
+	streamInfo := MakeStreamingInfo()
+	streamInfo.makeRecordMaps(tableName)
+	writes := 0
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
+		writes++
+		return nil
+	}
+	ProcessRecord(context.Background(), conv, streamInfo, newRecord("300"), tableName, "testShardId")
+	ProcessRecord(context.Background(), conv, streamInfo, newRecord("200"), tableName, "testShardId")
+	ProcessRecord(context.Background(), conv, streamInfo, newRecord("400"), tableName, "testShardId")
+
+	assert.Equal(t, 2, writes)
+	assert.Equal(t, int64(1), streamInfo.StaleRecords[tableName]["MODIFY"])
+}
+
+func TestProcessData_RemovedByStreaming(t *testing.T) {
+	tableName := "testtable"
+	cols := []string{"a"}
+	conv := buildConv(
+		ddl.CreateTable{
+			Name:     tableName,
+			ColNames: cols,
+			ColDefs: map[string]ddl.ColumnDef{
+				"a": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			Pks: []ddl.IndexKey{{Col: "a"}},
+		},
+		schema.Table{
+			Name:     tableName,
+			ColNames: cols,
+			ColDefs: map[string]schema.Column{
+				"a": {Name: "a", Type: schema.Type{Name: typeString}},
+			},
+			PrimaryKeys: []schema.Key{{Column: "a"}},
+		},
+	)
+
+	// A streaming migration removes item k1, and is then restarted.
+	type version struct {
+		seqNum  string
+		deleted bool
+	}
+	stored := map[string]map[string]version{}
+	streamInfo := MakeStreamingInfo()
+	streamInfo.makeRecordMaps(tableName)
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error { return nil }
+	streamInfo.writeVersioned = func(ctx context.Context, ms []*sp.Mutation, srcTable, key, seqNum string, deleted bool) (bool, error) {
+		if _, ok := stored[srcTable]; !ok {
+			stored[srcTable] = map[string]version{}
+		}
+		stored[srcTable][key] = version{seqNum: seqNum, deleted: deleted}
+		return false, nil
+	}
+	record := &dynamodbstreams.Record{
+		Dynamodb: &dynamodbstreams.StreamRecord{
+			Keys:           map[string]*dynamodb.AttributeValue{"a": {S: aws.String("k1")}},
+			SequenceNumber: aws.String("100"),
+		},
+		EventName: aws.String("REMOVE"),
+	}
+	ProcessRecord(context.Background(), conv, streamInfo, record, tableName, "testShardId")
+	assert.Equal(t, 1, len(stored[tableName]))
+
+	// The bulk load of the restarted migration reads k1 from a scan (or an
+	// export) made before it was removed: it must not bring it back.
+	versions := newStreamVersions()
+	for key, v := range stored[tableName] {
+		assert.True(t, v.deleted)
+		versions.add(tableName, key)
+	}
+	client := &mockDynamoClient{
+		scanOutputs: []dynamodb.ScanOutput{
+			{
+				Items: []map[string]*dynamodb.AttributeValue{
+					{"a": {S: aws.String("k1")}},
+					{"a": {S: aws.String("k2")}},
+				},
+			},
+		},
+	}
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{DynamoClient: client, SampleSize: 10, versions: versions})
+	assert.Equal(t, []spannerData{{table: tableName, cols: cols, vals: []interface{}{"k2"}}}, rows)
+}
+
+func TestProcessRecord_NestedTables(t *testing.T) {
+	tableName := "testtable"
+	cols := []string{"a", "b"}
+	conv := buildConv(
+		ddl.CreateTable{
+			Name:     tableName,
+			ColNames: cols,
+			ColDefs: map[string]ddl.ColumnDef{
+				"a": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"b": {Name: "b", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			Pks: []ddl.IndexKey{{Col: "a"}},
+		},
+		schema.Table{
+			Name:     tableName,
+			ColNames: cols,
+			ColDefs: map[string]schema.Column{
+				"a": {Name: "a", Type: schema.Type{Name: typeString}},
+				"b": {Name: "b", Type: schema.Type{Name: typeList}},
+			},
+			PrimaryKeys: []schema.Key{{Column: "a"}},
+		},
+	)
+	assert.Nil(t, conv.NestColumns(tableName, []internal.NestedTable{{Table: "testtable_b", Col: "b"}}))
+
+	streamInfo := MakeStreamingInfo()
+	streamInfo.makeRecordMaps(tableName)
+	var written [][]*sp.Mutation
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
+		written = append(written, ms)
+		return nil
+	}
+	valA := "strA"
+	list := &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{{S: aws.String("x")}, {N: aws.String("2")}}}
+	for _, eventName := range []string{"INSERT", "MODIFY"} {
+		record := &dynamodbstreams.Record{
+			Dynamodb:  &dynamodbstreams.StreamRecord{NewImage: map[string]*dynamodb.AttributeValue{"a": {S: &valA}, "b": list}},
+			EventName: aws.String(eventName),
+		}
+		ProcessRecord(context.Background(), conv, streamInfo, record, tableName, "testShardId")
+	}
+	record := &dynamodbstreams.Record{
+		Dynamodb:  &dynamodbstreams.StreamRecord{Keys: map[string]*dynamodb.AttributeValue{"a": {S: &valA}}},
+		EventName: aws.String("REMOVE"),
+	}
+	ProcessRecord(context.Background(), conv, streamInfo, record, tableName, "testShardId")
+
+	nestedCols := []string{"a", "key", "value"}
+	children := []*sp.Mutation{
+		sp.Delete("testtable_b", sp.Key{valA}.AsPrefix()),
+		sp.Insert("testtable_b", nestedCols, []interface{}{valA, int64(0), `"x"`}),
+		sp.Insert("testtable_b", nestedCols, []interface{}{valA, int64(1), `"2"`}),
+	}
+	assert.Equal(t, [][]*sp.Mutation{
+		append([]*sp.Mutation{sp.Insert(tableName, []string{"a"}, []interface{}{valA})}, children...),
+		append([]*sp.Mutation{sp.InsertOrUpdate(tableName, []string{"a"}, []interface{}{valA})}, children...),
+		{sp.Delete("testtable_b", sp.Key{valA}.AsPrefix()), sp.Delete(tableName, sp.Key{valA})},
+	}, written)
+	assert.Empty(t, streamInfo.BadRecords[tableName])
+}
+
+func Test_streamsError(t *testing.T) {
+	trimmed := awserr.New(dynamodbstreams.ErrCodeTrimmedDataAccessException, "records trimmed", nil)
+	assert.True(t, errors.Is(streamsError(trimmed), errs.ErrTrimmedData))
+	cancelled := awserr.New(request.CanceledErrorCode, "request canceled", context.Canceled)
+	assert.True(t, errors.Is(streamsError(cancelled), errs.ErrCancelled))
+	other := awserr.New(dynamodbstreams.ErrCodeResourceNotFoundException, "stream not found", nil)
+	assert.Equal(t, other, streamsError(other))
+}
+
+func Test_writeMutations(t *testing.T) {
+	defer func(d time.Duration) { parentRowRetryDelay = d }(parentRowRetryDelay)
+	parentRowRetryDelay = time.Millisecond
+	parentRowMissing := sp.ToSpannerError(status.Error(codes.NotFound, "Parent row for row [1] in table child is missing. Row cannot be written."))
+	streamInfo := MakeStreamingInfo()
+	writes := 0
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
+		writes++
+		if writes < 3 {
+			return parentRowMissing
+		}
+		return nil
+	}
+	_, err := writeMutations(context.Background(), []*sp.Mutation{sp.Insert("child", []string{"a"}, []interface{}{1})}, streamInfo, "child", "", "", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, writes)
+
+	// Other errors aren't retried.
+	writes = 0
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
+		writes++
+		return sp.ToSpannerError(status.Error(codes.NotFound, "Table not found: child"))
+	}
+	_, err = writeMutations(context.Background(), []*sp.Mutation{sp.Insert("child", []string{"a"}, []interface{}{1})}, streamInfo, "child", "", "", false)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, writes)
+}
+
+func Test_writeRecordVersioned(t *testing.T) {
+	table := "testTable"
+	streamInfo := MakeStreamingInfo()
+	streamInfo.makeRecordMaps(table)
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
+		t.Fatalf("unversioned writer used for record with sequence number")
+		return nil
+	}
+	versions := map[string]string{}
+	var mutationsWritten []*sp.Mutation
+	streamInfo.writeVersioned = func(ctx context.Context, ms []*sp.Mutation, srcTable, key, seqNum string, deleted bool) (bool, error) {
+		if latest, ok := versions[srcTable+"/"+key]; ok && compareSequenceNumbers(seqNum, latest) <= 0 {
+			return true, nil
+		}
+		versions[srcTable+"/"+key] = seqNum
+		mutationsWritten = append(mutationsWritten, ms...)
+		return false, nil
+	}
+
+	spCols := []string{"a", "b"}
+	assert.True(t, writeRecord(context.Background(), streamInfo, table, table, "INSERT", spCols, []interface{}{1, "x"}, nil, schema.Table{}, ddl.CreateTable{}, "1", "100"))
+	assert.True(t, writeRecord(context.Background(), streamInfo, table, table, "INSERT", spCols, []interface{}{1, "x"}, nil, schema.Table{}, ddl.CreateTable{}, "1", "100"))
+	assert.True(t, writeRecord(context.Background(), streamInfo, table, table, "MODIFY", spCols, []interface{}{1, "y"}, nil, schema.Table{}, ddl.CreateTable{}, "1", "300"))
+	// An older record for the same key, e.g. from a shard read again after a restart.
+	assert.True(t, writeRecord(context.Background(), streamInfo, table, table, "MODIFY", spCols, []interface{}{1, "z"}, nil, schema.Table{}, ddl.CreateTable{}, "1", "200"))
+
+	assert.Equal(t, []*sp.Mutation{
+		sp.Insert(table, spCols, []interface{}{1, "x"}),
+		sp.InsertOrUpdate(table, spCols, []interface{}{1, "y"}),
+	}, mutationsWritten)
+	assert.Equal(t, int64(1), streamInfo.StaleRecords[table]["INSERT"])
+	assert.Equal(t, int64(1), streamInfo.StaleRecords[table]["MODIFY"])
+}
+
 func Test_getMutation(t *testing.T) {
 	srcTable := "testtable_src"
 	spTable := "testtable_sp"
@@ -401,6 +706,22 @@ func Test_getMutation(t *testing.T) {
 		},
 		PrimaryKeys: []schema.Key{schema.Key{Column: "d"}, schema.Key{Column: "b"}},
 	}
+	compositeSrcSchema := schema.Table{
+		Name:     srcTable,
+		ColNames: spCols,
+		ColDefs:  srcSchema.ColDefs,
+		PrimaryKeys: []schema.Key{
+			schema.Key{Column: "c"}, schema.Key{Column: "a"}, schema.Key{Column: "d"}, schema.Key{Column: "b"},
+		},
+	}
+	spCompositeCols := []string{"a_sp", "b_sp", "c_sp", "d_sp"}
+	// Interleaved child table whose Spanner primary key starts with the parent key "b_sp".
+	childSpSchema := ddl.CreateTable{
+		Name:     spTable,
+		ColNames: spCompositeCols,
+		Pks:      []ddl.IndexKey{{Col: "b_sp"}, {Col: "d_sp"}, {Col: "a_sp"}},
+		Parent:   "parent",
+	}
 
 	type args struct {
 		eventName string
@@ -409,11 +730,13 @@ func Test_getMutation(t *testing.T) {
 		spCols    []string
 		spVals    []interface{}
 		srcSchema schema.Table
+		spSchema  ddl.CreateTable
 	}
 	tests := []struct {
-		name  string
-		args  args
-		wantM *sp.Mutation
+		name    string
+		args    args
+		wantM   *sp.Mutation
+		wantErr bool
 	}{
 		{
 			name: "test for checking insert/update mutations",
@@ -427,6 +750,25 @@ func Test_getMutation(t *testing.T) {
 			},
 			wantM: sp.Insert(spTable, spCols, []interface{}{25, "key1", true, "key2", 3}),
 		},
+		{
+			name: "test for update mutations of table with commit timestamp column",
+			args: args{
+				eventName: "MODIFY",
+				srcTable:  srcTable,
+				spTable:   spTable,
+				spCols:    spCols,
+				spVals:    []interface{}{25, "key1", true, "key2"},
+				srcSchema: srcSchema,
+				spSchema: ddl.CreateTable{
+					Name:     spTable,
+					ColNames: []string{"a", "b", "c", "d", "updated"},
+					ColDefs: map[string]ddl.ColumnDef{
+						"updated": {Name: "updated", T: ddl.Type{Name: ddl.Timestamp}, AllowCommitTimestamp: true},
+					},
+				},
+			},
+			wantM: sp.InsertOrUpdate(spTable, []string{"a", "b", "c", "d", "updated"}, []interface{}{25, "key1", true, "key2", sp.CommitTimestamp}),
+		},
 		{
 			name: "test for checking delete mutations",
 			args: args{
@@ -439,10 +781,50 @@ func Test_getMutation(t *testing.T) {
 			},
 			wantM: sp.Delete(spTable, sp.Key{"key2", "key1"}),
 		},
+		{
+			name: "test for delete mutations with composite key of 4 columns",
+			args: args{
+				eventName: "REMOVE",
+				srcTable:  srcTable,
+				spTable:   spTable,
+				spCols:    spCols,
+				spVals:    []interface{}{int64(7), "key1", true, "key2"},
+				srcSchema: compositeSrcSchema,
+			},
+			wantM: sp.Delete(spTable, sp.Key{true, int64(7), "key2", "key1"}),
+		},
+		{
+			name: "test for delete mutations in interleaved child table",
+			args: args{
+				eventName: "REMOVE",
+				srcTable:  srcTable,
+				spTable:   spTable,
+				spCols:    spCompositeCols,
+				spVals:    []interface{}{int64(7), "parentKey", nil, "key2"},
+				srcSchema: compositeSrcSchema,
+				spSchema:  childSpSchema,
+			},
+			wantM: sp.Delete(spTable, sp.Key{"parentKey", "key2", int64(7)}),
+		},
+		{
+			name: "test for delete mutations with missing key value",
+			args: args{
+				eventName: "REMOVE",
+				srcTable:  srcTable,
+				spTable:   spTable,
+				spCols:    spCompositeCols,
+				spVals:    []interface{}{int64(7), nil, nil, "key2"},
+				srcSchema: compositeSrcSchema,
+				spSchema:  childSpSchema,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if gotM := getMutation(tt.args.eventName, tt.args.srcTable, tt.args.spTable, tt.args.spCols, tt.args.spVals, tt.args.srcSchema); !reflect.DeepEqual(gotM, tt.wantM) {
+			gotM, err := getMutation(tt.args.eventName, tt.args.srcTable, tt.args.spTable, tt.args.spCols, tt.args.spVals, tt.args.srcSchema, tt.args.spSchema)
+			assert.Equal(t, tt.wantErr, err != nil)
+			if !reflect.DeepEqual(gotM, tt.wantM) {
 				t.Errorf("CreateMutation() = %v, want %v", gotM, tt.wantM)
 			}
 		})
@@ -534,9 +916,10 @@ func Test_writeRecord(t *testing.T) {
 	var mutationsWritten []*sp.Mutation
 	var mutationsFailed []*sp.Mutation
 
-	streamInfo.write = func(m *sp.Mutation) error {
+	streamInfo.write = func(ctx context.Context, ms []*sp.Mutation) error {
 		var err error
 		writeCount++
+		m := ms[0]
 		if intersect(m, badMutations) {
 			err = errors.New("record not processed")
 			mutationsFailed = append(mutationsFailed, m)
@@ -548,7 +931,7 @@ func Test_writeRecord(t *testing.T) {
 	}
 
 	for _, data := range tests {
-		writeRecord(streamInfo, data.srcTable, data.spTable, data.eventName, data.spCols, data.spVals, data.srcSchema)
+		writeRecord(context.Background(), streamInfo, data.srcTable, data.spTable, data.eventName, data.spCols, data.spVals, nil, data.srcSchema, ddl.CreateTable{}, "", "")
 	}
 
 	// Check data written.
@@ -575,3 +958,28 @@ func intersect(m *sp.Mutation, mutationSet []*sp.Mutation) bool {
 	}
 	return false
 }
+
+func TestCheckStream(t *testing.T) {
+	streamArn := "arn:aws:dynamodb:us-east-1:123456789012:table/test/stream/2022-01-01T00:00:00.000"
+	testCases := []struct {
+		name     string
+		spec     *dynamodb.StreamSpecification
+		expectOk bool
+	}{
+		{"no stream", nil, true},
+		{"new and old images", &dynamodb.StreamSpecification{StreamEnabled: aws.Bool(true), StreamViewType: aws.String(dynamodb.StreamViewTypeNewAndOldImages)}, true},
+		{"new image", &dynamodb.StreamSpecification{StreamEnabled: aws.Bool(true), StreamViewType: aws.String(dynamodb.StreamViewTypeNewImage)}, true},
+		{"keys only", &dynamodb.StreamSpecification{StreamEnabled: aws.Bool(true), StreamViewType: aws.String(dynamodb.StreamViewTypeKeysOnly)}, false},
+		{"old image", &dynamodb.StreamSpecification{StreamEnabled: aws.Bool(true), StreamViewType: aws.String(dynamodb.StreamViewTypeOldImage)}, false},
+	}
+	for _, tc := range testCases {
+		client := &mockDynamoClient{
+			describeTableOutputs: []dynamodb.DescribeTableOutput{
+				{Table: &dynamodb.TableDescription{StreamSpecification: tc.spec, LatestStreamArn: aws.String(streamArn)}},
+			},
+		}
+		isi := InfoSchemaImpl{DynamoClient: client}
+		err := isi.CheckStream(common.SchemaAndName{Name: "test"})
+		assert.Equal(t, tc.expectOk, err == nil, tc.name)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+)
+
+// readGovernor limits the rate of DynamoDB reads to a budget of read
+// capacity units (RCUs) per second, shared by all the tables and goroutines
+// reading. The cost of a read is only known from its response, so reads are
+// made first and the capacity they consumed is then paid for: consume waits
+// until reading again stays within the budget.
+type readGovernor struct {
+	rcuPerSecond float64
+	lock         sync.Mutex
+	next         time.Time // Time from which reading stays within the budget.
+	consumed     float64   // Total RCUs consumed.
+	now          func() time.Time
+	sleep        func(time.Duration)
+}
+
+// newReadGovernor returns a governor for a budget of rcuPerSecond RCUs per
+// second, or nil (no limit) if rcuPerSecond isn't positive.
+func newReadGovernor(rcuPerSecond float64) *readGovernor {
+	if rcuPerSecond <= 0 {
+		return nil
+	}
+	return &readGovernor{rcuPerSecond: rcuPerSecond, now: time.Now, sleep: time.Sleep}
+}
+
+// consume records that a read consumed units RCUs, and waits until reading
+// again stays within the budget. Budget left unused in the past isn't saved
+// up, so reads never burst above it.
+func (g *readGovernor) consume(units float64) {
+	if g == nil || units <= 0 {
+		return
+	}
+	g.lock.Lock()
+	now := g.now()
+	if g.next.Before(now) {
+		g.next = now
+	}
+	g.next = g.next.Add(time.Duration(units / g.rcuPerSecond * float64(time.Second)))
+	g.consumed += units
+	wait := g.next.Sub(now)
+	g.lock.Unlock()
+	if wait > 0 {
+		g.sleep(wait)
+	}
+}
+
+// returnConsumedCapacity returns the ReturnConsumedCapacity parameter of
+// reads: the consumed capacity is only requested when reads are governed.
+func (g *readGovernor) returnConsumedCapacity() *string {
+	if g == nil {
+		return nil
+	}
+	return aws.String(dynamodb.ReturnConsumedCapacityTotal)
+}
+
+// consumeCapacity records the capacity consumed by a read, from its
+// response.
+func (g *readGovernor) consumeCapacity(cc *dynamodb.ConsumedCapacity) {
+	if cc != nil {
+		g.consume(aws.Float64Value(cc.CapacityUnits))
+	}
+}
+
+// consumeRecords records the capacity consumed by reading stream records.
+// Reads of DynamoDB Streams don't report consumed capacity, so it is
+// estimated as the capacity of eventually consistent reads of items of the
+// records' size: half an RCU per 4 KB.
+func (g *readGovernor) consumeRecords(records []*dynamodbstreams.Record) {
+	var units float64
+	for _, r := range records {
+		if r.Dynamodb != nil {
+			units += math.Max(1, math.Ceil(float64(aws.Int64Value(r.Dynamodb.SizeBytes))/4096)) / 2
+		}
+	}
+	g.consume(units)
+}
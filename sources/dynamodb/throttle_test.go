@@ -0,0 +1,91 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+// fakeClockGovernor returns a governor whose clock only advances when it
+// sleeps, along with the list of its sleeps.
+func fakeClockGovernor(rcuPerSecond float64) (*readGovernor, *[]time.Duration) {
+	g := newReadGovernor(rcuPerSecond)
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+	g.now = func() time.Time { return now }
+	g.sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+	}
+	return g, &sleeps
+}
+
+func TestReadGovernor(t *testing.T) {
+	assert.Nil(t, newReadGovernor(0))
+	var unlimited *readGovernor
+	unlimited.consume(100)
+	assert.Nil(t, unlimited.returnConsumedCapacity())
+
+	g, sleeps := fakeClockGovernor(100)
+	g.consume(50)
+	g.consume(100)
+	g.consume(0)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, *sleeps)
+	assert.Equal(t, float64(150), g.consumed)
+
+	// Unused budget isn't saved up.
+	g.now = func() time.Time { return time.Date(2022, 6, 1, 1, 0, 0, 0, time.UTC) }
+	g.consume(10)
+	assert.Equal(t, 100*time.Millisecond, (*sleeps)[2])
+
+	g, sleeps = fakeClockGovernor(1)
+	g.consumeRecords([]*dynamodbstreams.Record{
+		{Dynamodb: &dynamodbstreams.StreamRecord{SizeBytes: aws.Int64(100)}},
+		{Dynamodb: &dynamodbstreams.StreamRecord{SizeBytes: aws.Int64(5000)}},
+	})
+	assert.Equal(t, float64(1.5), g.consumed)
+	assert.Equal(t, []time.Duration{1500 * time.Millisecond}, *sleeps)
+}
+
+func TestGetRowsFromTable_Governed(t *testing.T) {
+	client := &mockDynamoClient{
+		scanOutputs: []dynamodb.ScanOutput{
+			{
+				Items:            []map[string]*dynamodb.AttributeValue{{"a": {S: aws.String("1")}}},
+				ConsumedCapacity: &dynamodb.ConsumedCapacity{CapacityUnits: aws.Float64(20)},
+				LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"a": {S: aws.String("1")}},
+			},
+			{
+				Items:            []map[string]*dynamodb.AttributeValue{{"a": {S: aws.String("2")}}},
+				ConsumedCapacity: &dynamodb.ConsumedCapacity{CapacityUnits: aws.Float64(5)},
+			},
+		},
+	}
+	g, sleeps := fakeClockGovernor(10)
+	isi := InfoSchemaImpl{DynamoClient: client, governor: g}
+	rows, err := isi.GetRowsFromTable(internal.MakeConv(), "testtable")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rows.([]map[string]*dynamodb.AttributeValue)))
+	assert.Equal(t, float64(25), g.consumed)
+	assert.Equal(t, []time.Duration{2 * time.Second, 500 * time.Millisecond}, *sleeps)
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
@@ -32,37 +33,57 @@ type ToDdlImpl struct {
 // mapping.  toSpannerType returns the Spanner type and a list of type
 // conversion issues encountered.
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
-	ty, issues := toSpannerTypeInternal(conv, columnType.Name)
+	ty, issues := TypeMap.ToSpannerType(columnType.Name, "", nil)
 	if conv.TargetDb == constants.TargetExperimentalPostgres {
 		ty = overrideExperimentalType(ty)
 	}
 	return ty, issues
 }
 
-func toSpannerTypeInternal(conv *internal.Conv, id string) (ddl.Type, []internal.SchemaIssue) {
-	switch id {
-	case typeNumber:
-		return ddl.Type{Name: ddl.Numeric}, nil
-	case typeNumberString, typeString, typeList, typeMap:
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case typeBool:
-		return ddl.Type{Name: ddl.Bool}, nil
-	case typeBinary:
-		return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
-	case typeStringSet, typeNumberStringSet:
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}, nil
-	case typeNumberSet:
-		return ddl.Type{Name: ddl.Numeric, IsArray: true}, nil
-	case typeBinarySet:
-		return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength, IsArray: true}, nil
-	default:
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
-	}
-}
+// TypeMap declares the mapping of DynamoDB types to Spanner types.
+var TypeMap = common.NewTypeMap(
+	common.TypeGroup{SrcTypes: []string{typeNumber}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Numeric}),
+		Options: map[string]common.MapFunc{
+			ddl.Float64: common.To(ddl.Type{Name: ddl.Float64}, internal.Numeric),
+			ddl.String:  common.To(common.MaxString, internal.Widened),
+		},
+	}},
+	// Numbers that exceed NUMERIC. Mapping them to NUMERIC makes the rows
+	// with such numbers bad rows.
+	common.TypeGroup{SrcTypes: []string{typeNumberString}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+		Options: map[string]common.MapFunc{
+			ddl.Float64: common.To(ddl.Type{Name: ddl.Float64}, internal.Numeric),
+			ddl.Numeric: common.To(ddl.Type{Name: ddl.Numeric}, internal.Numeric),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{typeString, typeList, typeMap}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+	}},
+	common.TypeGroup{SrcTypes: []string{typeBool}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Bool}),
+	}},
+	common.TypeGroup{SrcTypes: []string{typeBinary}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxBytes),
+	}},
+	common.TypeGroup{SrcTypes: []string{typeStringSet, typeNumberStringSet}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}),
+	}},
+	common.TypeGroup{SrcTypes: []string{typeNumberSet}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Numeric, IsArray: true}),
+	}},
+	common.TypeGroup{SrcTypes: []string{typeBinarySet}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength, IsArray: true}),
+	}},
+	common.TypeGroup{SrcTypes: []string{typeCatchAll}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.JSON}),
+	}},
+)
 
 // Override the types to map to experimental postgres types.
 func overrideExperimentalType(originalType ddl.Type) ddl.Type {
-	if originalType.IsArray {
+	if originalType.IsArray || originalType.Name == ddl.JSON {
 		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
 	}
 	return originalType
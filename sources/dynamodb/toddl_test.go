@@ -144,3 +144,16 @@ func dropComments(t *ddl.CreateTable) {
 		t.ColDefs[c] = cd
 	}
 }
+
+func TestTypeMap_NumberOptions(t *testing.T) {
+	assert.Equal(t, []common.TypeOption{
+		{Name: ddl.Float64, Issues: []internal.SchemaIssue{internal.Numeric}},
+		{Name: ddl.String, Issues: []internal.SchemaIssue{internal.Widened}},
+		{Name: ddl.Numeric},
+	}, TypeMap.TypeOptions(typeNumber))
+	assert.Equal(t, []common.TypeOption{
+		{Name: ddl.Float64, Issues: []internal.SchemaIssue{internal.Numeric}},
+		{Name: ddl.String},
+		{Name: ddl.Numeric, Issues: []internal.SchemaIssue{internal.Numeric}},
+	}, TypeMap.TypeOptions(typeNumberString))
+}
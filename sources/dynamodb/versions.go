@@ -0,0 +1,164 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+
+	sp "cloud.google.com/go/spanner"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/metrics"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+// versionTable is the Spanner side table used to store, for each primary key written
+// by streaming migration, the sequence number of the latest record applied to it and
+// whether that record removed the item. Unlike the sequence numbers tracked in memory,
+// versions survive restarts of HarbourBridge and splits of shards, and they are read
+// by the bulk load so that it doesn't bring back older versions of these items.
+const versionTable = "hb_dynamodb_stream_versions"
+
+var versionCols = []string{"SourceTable", "ItemKey", "SequenceNumber", "Deleted", "AppliedAt"}
+
+// versionTableDDL returns the CREATE TABLE statement for the version table.
+func versionTableDDL(targetDb string) string {
+	if targetDb == constants.TargetExperimentalPostgres {
+		return fmt.Sprintf(`CREATE TABLE "%s" (
+	"SourceTable" VARCHAR(2621440) NOT NULL,
+	"ItemKey" VARCHAR(2621440) NOT NULL,
+	"SequenceNumber" VARCHAR(2621440) NOT NULL,
+	"Deleted" BOOLEAN NOT NULL,
+	"AppliedAt" SPANNER.COMMIT_TIMESTAMP NOT NULL,
+	PRIMARY KEY ("SourceTable", "ItemKey")
+)`, versionTable)
+	}
+	return fmt.Sprintf("CREATE TABLE `%s` (\n"+
+		"\t`SourceTable` STRING(MAX) NOT NULL,\n"+
+		"\t`ItemKey` STRING(MAX) NOT NULL,\n"+
+		"\t`SequenceNumber` STRING(MAX) NOT NULL,\n"+
+		"\t`Deleted` BOOL NOT NULL,\n"+
+		"\t`AppliedAt` TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true),\n"+
+		") PRIMARY KEY (`SourceTable`, `ItemKey`)", versionTable)
+}
+
+// streamVersions holds the primary keys written by earlier streaming migrations,
+// which the bulk load skips.
+type streamVersions struct {
+	lock sync.Mutex
+	keys map[string]map[string]bool // Tablewise set of primary keys (see recordKey).
+}
+
+func newStreamVersions() *streamVersions {
+	return &streamVersions{keys: make(map[string]map[string]bool)}
+}
+
+// add records that key of srcTable was written by streaming migration.
+func (v *streamVersions) add(srcTable, key string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if _, ok := v.keys[srcTable]; !ok {
+		v.keys[srcTable] = make(map[string]bool)
+	}
+	v.keys[srcTable][key] = true
+}
+
+// tableKeys returns the keys of srcTable written by streaming migration.
+// It is nil-safe, so that InfoSchemaImpls created without streaming skip nothing.
+func (v *streamVersions) tableKeys(srcTable string) map[string]bool {
+	if v == nil {
+		return nil
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.keys[srcTable]
+}
+
+// ReadStreamVersions reads the primary keys written by earlier streaming migrations
+// to the Spanner database of client, creating the version table if it doesn't
+// exist yet. Streaming migration applies every change made to these items since,
+// so their rows in Spanner are at least as recent as the bulk load (and the items
+// may even have been removed), and the bulk load skips them.
+func (isi InfoSchemaImpl) ReadStreamVersions(ctx context.Context, client *sp.Client, conv *internal.Conv) error {
+	if isi.versions == nil {
+		return nil
+	}
+	if err := createSideTable(ctx, client.DatabaseName(), versionTable, versionTableDDL(conv.TargetDb)); err != nil {
+		return err
+	}
+	iter := client.Single().ReadWithOptions(ctx, versionTable, sp.AllKeys(), []string{"SourceTable", "ItemKey"}, utils.ReadOptions(conv.WriteOptions))
+	return iter.Do(func(row *sp.Row) error {
+		var srcTable, key string
+		if err := row.Columns(&srcTable, &key); err != nil {
+			return err
+		}
+		isi.versions.add(srcTable, key)
+		return nil
+	})
+}
+
+// setVersionedWriter initializes the function used to write the mutations of a record
+// to Cloud Spanner along with the version of its key. The mutations and the version
+// are written in a single read-write transaction, which first checks that the version
+// stored for the key is older than the record, so that a row never regresses to an
+// older version, even across restarts. If exactlyOnce is set, the record's checkpoint
+// is also checked and written in the transaction.
+func setVersionedWriter(streamInfo *StreamingInfo, client *sp.Client, conv *internal.Conv, exactlyOnce bool) {
+	streamInfo.writeVersioned = func(ctx context.Context, ms []*sp.Mutation, srcTable, key, seqNum string, deleted bool) (bool, error) {
+		migrationData := metrics.GetMigrationData(conv, "", "", constants.DataConv)
+		serializedMigrationData, _ := proto.Marshal(migrationData)
+		migrationMetadataValue := base64.StdEncoding.EncodeToString(serializedMigrationData)
+		ctx = metadata.AppendToOutgoingContext(ctx, constants.MigrationMetadataKey, migrationMetadataValue)
+
+		applied := false
+		_, err := client.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, txn *sp.ReadWriteTransaction) error {
+			applied = false
+			writes := append([]*sp.Mutation{}, ms...)
+			if exactlyOnce {
+				_, err := txn.ReadRowWithOptions(ctx, checkpointTable, sp.Key{srcTable, seqNum}, []string{"SequenceNumber"}, utils.ReadOptions(conv.WriteOptions))
+				if err == nil {
+					applied = true
+					return nil
+				}
+				if sp.ErrCode(err) != codes.NotFound {
+					return err
+				}
+				writes = append(writes, sp.Insert(checkpointTable, checkpointCols, []interface{}{srcTable, seqNum, sp.CommitTimestamp}))
+			}
+			row, err := txn.ReadRowWithOptions(ctx, versionTable, sp.Key{srcTable, key}, []string{"SequenceNumber"}, utils.ReadOptions(conv.WriteOptions))
+			if err == nil {
+				var latest string
+				if err := row.Columns(&latest); err != nil {
+					return err
+				}
+				if compareSequenceNumbers(seqNum, latest) <= 0 {
+					applied = true
+					return nil
+				}
+			} else if sp.ErrCode(err) != codes.NotFound {
+				return err
+			}
+			writes = append(writes, sp.InsertOrUpdate(versionTable, versionCols, []interface{}{srcTable, key, seqNum, deleted, sp.CommitTimestamp}))
+			return txn.BufferWrite(writes)
+		}, utils.TransactionOptions(conv.WriteOptions))
+		return applied, err
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"context"
+	csvReader "encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// timestampLayouts are the accepted layouts of timestamps in CSV files.
+// Timestamps without a time zone are in the time zone of the conversion.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+func parseTimestamp(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can't convert %q to timestamp", s)
+}
+
+// ProcessData reads the rows of the files of srcTable and writes them to
+// spTable. The start of each file, and of each row group of Parquet files,
+// is a checkpoint of the migration windows.
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	t, ok := isi.tables[srcTable]
+	if !ok {
+		return fmt.Errorf("table %s isn't in the manifest", srcTable)
+	}
+	row := func(row map[string]interface{}) {
+		ProcessDataRow(row, conv, srcTable, srcSchema, spTable, spCols, spSchema)
+	}
+	for _, file := range t.files {
+		if err := conv.Schedule.Wait(ctx); err != nil {
+			return err
+		}
+		var err error
+		if t.format == formatParquet {
+			err = readParquet(file, row, func() { conv.Schedule.Wait(ctx) })
+		} else {
+			err = readCSV(file, isi.Delimiter, isi.NullStr, t.colNames, row)
+		}
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
+			return err
+		}
+	}
+	return nil
+}
+
+// readCSV calls f with the rows of the CSV file, whose columns are colNames
+// unless its first row is a header, as maps from column names to values.
+func readCSV(file string, delimiter rune, nullStr string, colNames []string, f func(map[string]interface{})) error {
+	csvFile, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("can't read csv file: %v", err)
+	}
+	defer csvFile.Close()
+	r := csvReader.NewReader(csvFile)
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	first := true
+	for {
+		values, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("can't read row of %s: %v", file, err)
+		}
+		if first {
+			first = false
+			if isHeader(values, colNames) {
+				colNames = values
+				continue
+			}
+		}
+		row := make(map[string]interface{})
+		for i, v := range values {
+			if i < len(colNames) && v != nullStr {
+				row[colNames[i]] = v
+			}
+		}
+		f(row)
+	}
+}
+
+// isHeader returns true if values are distinct names of columns colNames.
+func isHeader(values []string, colNames []string) bool {
+	names := make(map[string]bool)
+	for _, c := range colNames {
+		names[c] = true
+	}
+	for _, v := range values {
+		if !names[v] {
+			return false
+		}
+		delete(names, v)
+	}
+	return true
+}
+
+// readParquet calls f with the rows of the Parquet file, as maps from
+// column names to values, and rowGroup before each row group.
+func readParquet(file string, f func(map[string]interface{}), rowGroup func()) error {
+	p, closeFile, err := openParquetFile(file)
+	if err != nil {
+		return err
+	}
+	defer closeFile()
+	for i := range p.rowGroups {
+		rowGroup()
+		cols, err := p.readRowGroup(i)
+		if err != nil {
+			return fmt.Errorf("can't read parquet file %s: %v", file, err)
+		}
+		for r := 0; len(cols) > 0 && r < len(cols[0]); r++ {
+			row := make(map[string]interface{})
+			for j, c := range p.columns {
+				if v := cols[j][r]; v != nil {
+					row[c.name] = v
+				}
+			}
+			f(row)
+		}
+	}
+	return nil
+}
+
+// ProcessDataRow converts the values of row, read from srcTable, and writes
+// them to spTable.
+func ProcessDataRow(row map[string]interface{}, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) {
+	start := time.Now()
+	spVals, badCols, srcStrVals := cvtRow(conv, row, srcSchema, spSchema, spCols)
+	conv.Timings.TimeTable(internal.StageConvert, srcTable, start)
+	if len(badCols) == 0 {
+		conv.WriteRow(srcTable, spTable, spCols, spVals)
+	} else {
+		conv.Unexpected(fmt.Sprintf("Data conversion error for table %s in column(s) %s\n", srcTable, badCols))
+		conv.StatsAddBadRow(srcTable, conv.DataMode())
+		conv.CollectBadRow(srcTable, srcSchema.ColNames, srcStrVals)
+	}
+}
+
+func cvtRow(conv *internal.Conv, row map[string]interface{}, srcSchema schema.Table, spSchema ddl.CreateTable, spCols []string) ([]interface{}, []string, []string) {
+	var srcStrVals []string
+	var spVals []interface{}
+	var badCols []string
+	for i, srcCol := range srcSchema.ColNames {
+		v := row[srcCol]
+		var spVal interface{}
+		if v != nil {
+			var err error
+			if spVal, err = convScalar(conv, v, spSchema.ColDefs[spCols[i]].T.Name); err != nil {
+				badCols = append(badCols, srcCol)
+			}
+		}
+		srcStrVals = append(srcStrVals, srcString(v))
+		spVals = append(spVals, spVal)
+	}
+	return spVals, badCols, srcStrVals
+}
+
+// convScalar converts v, a string read from a CSV file or a value read from
+// a Parquet file, to a value of Spanner type spType.
+func convScalar(conv *internal.Conv, v interface{}, spType string) (interface{}, error) {
+	if s, ok := v.(string); ok && spType != ddl.String && spType != ddl.Bytes {
+		return convString(conv, s, spType)
+	}
+	switch spType {
+	case ddl.Bool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case ddl.Int64:
+		switch v := v.(type) {
+		case int64:
+			return v, nil
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+	case ddl.Float64:
+		switch v := v.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case *big.Rat:
+			f, _ := v.Float64()
+			return f, nil
+		}
+	case ddl.Numeric:
+		var r *big.Rat
+		switch v := v.(type) {
+		case *big.Rat:
+			r = v
+		case int64:
+			r = new(big.Rat).SetInt64(v)
+		}
+		if r != nil {
+			if conv.TargetDb == constants.TargetExperimentalPostgres {
+				return spanner.PGNumeric{Numeric: srcString(r), Valid: true}, nil
+			}
+			return r, nil
+		}
+	case ddl.Date:
+		switch v := v.(type) {
+		case civil.Date:
+			return v, nil
+		case time.Time:
+			return civil.DateOf(v.In(conv.Location)), nil
+		}
+	case ddl.Timestamp:
+		if t, ok := v.(time.Time); ok {
+			return t, nil
+		}
+	case ddl.Bytes:
+		switch v := v.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	case ddl.String:
+		return srcString(v), nil
+	}
+	return nil, fmt.Errorf("can't convert value %v of type %T to Spanner type %s", v, v, spType)
+}
+
+// convString converts s to a value of Spanner type spType.
+func convString(conv *internal.Conv, s string, spType string) (interface{}, error) {
+	switch spType {
+	case ddl.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert to bool: %w", err)
+		}
+		return b, nil
+	case ddl.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert to int64: %w", err)
+		}
+		return i, nil
+	case ddl.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert to float64: %w", err)
+		}
+		return f, nil
+	case ddl.Numeric:
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("can't convert %q to big.Rat", s)
+		}
+		if conv.TargetDb == constants.TargetExperimentalPostgres {
+			return spanner.PGNumeric{Numeric: s, Valid: true}, nil
+		}
+		return r, nil
+	case ddl.Date:
+		d, err := civil.ParseDate(s)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert to date: %w", err)
+		}
+		return d, nil
+	case ddl.Timestamp:
+		return parseTimestamp(s, conv.Location)
+	case ddl.JSON:
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("can't convert %q to json", s)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("data conversion not implemented for type %v", spType)
+}
+
+// srcString returns v formatted as a source value.
+func srcString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case *big.Rat:
+		// Decimals of Parquet files have a scale of at most 38.
+		s := v.FloatString(38)
+		if strings.Contains(s, ".") {
+			s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+		}
+		return s
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
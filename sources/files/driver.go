@@ -0,0 +1,52 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+)
+
+func init() {
+	registry.Register(dbDriver{})
+}
+
+// dbDriver reads the schema and data of tables from CSV and Parquet files.
+type dbDriver struct{}
+
+func (dbDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.FILES,
+		Source:      "files",
+		Description: "CSV or Parquet files",
+	}
+}
+
+func (dbDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (dbDriver) TypeMap() common.TypeMap {
+	return TypeMap
+}
+
+// InfoSchema reads the manifest of the source profile and the schema of its
+// tables.
+func (dbDriver) InfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) (common.InfoSchema, error) {
+	f := sourceProfile.Conn.Files
+	return newInfoSchema(f.Manifest, f.Format, []rune(f.Delimiter)[0], f.NullStr, f.SchemaSampleSize)
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	csvReader "encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/xitongsys/parquet-go-source/local"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+// inferredTypes are the types CSV columns are inferred as, in order of
+// preference: a column gets the first type all its sampled values have.
+// Columns of no other type are strings.
+var inferredTypes = []struct {
+	name  string
+	match func(s string) bool
+}{
+	{"BOOL", func(s string) bool {
+		s = strings.ToLower(s)
+		return s == "true" || s == "false"
+	}},
+	{"INT64", func(s string) bool {
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err == nil && !leadingZeros(s)
+	}},
+	{"FLOAT64", func(s string) bool {
+		_, err := strconv.ParseFloat(s, 64)
+		return err == nil && !leadingZeros(s)
+	}},
+	{"DATE", func(s string) bool {
+		_, err := civil.ParseDate(s)
+		return err == nil
+	}},
+	{"TIMESTAMP", func(s string) bool {
+		_, err := parseTimestamp(s, time.UTC)
+		return err == nil
+	}},
+	{"JSON", func(s string) bool {
+		return (strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")) && json.Valid([]byte(s))
+	}},
+}
+
+// leadingZeros returns true if number s has leading zeros, which are kept
+// e.g. in zip codes by inferring strings.
+func leadingZeros(s string) bool {
+	digits := strings.TrimPrefix(s, "-")
+	return len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9'
+}
+
+// inferCSV infers the columns of the CSV file with the given delimiter and
+// null value from its header and its first sampleSize rows.
+func inferCSV(file string, delimiter rune, nullStr string, sampleSize int64) ([]string, map[string]schema.Column, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read csv file: %v", err)
+	}
+	defer f.Close()
+	r := csvReader.NewReader(f)
+	r.Comma = delimiter
+	colNames, err := r.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("can't infer the columns of %s: file is empty", file)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read csv headers of %s: %v", file, err)
+	}
+	seen := make(map[string]bool)
+	for _, c := range colNames {
+		if c == "" || seen[c] {
+			return nil, nil, fmt.Errorf("can't infer the columns of %s: its first row must be a header of distinct column names", file)
+		}
+		seen[c] = true
+	}
+	// candidates[i][j] is true while all values of column i have type j.
+	candidates := make([][]bool, len(colNames))
+	for i := range candidates {
+		candidates[i] = make([]bool, len(inferredTypes))
+		for j := range inferredTypes {
+			candidates[i][j] = true
+		}
+	}
+	for n := int64(0); n < sampleSize; n++ {
+		values, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't read row of %s: %v", file, err)
+		}
+		for i, v := range values {
+			if i >= len(colNames) || v == nullStr {
+				continue
+			}
+			for j, t := range inferredTypes {
+				if candidates[i][j] && !t.match(v) {
+					candidates[i][j] = false
+				}
+			}
+		}
+	}
+	colDefs := make(map[string]schema.Column)
+	for i, c := range colNames {
+		ty := "STRING"
+		for j, t := range inferredTypes {
+			if candidates[i][j] {
+				ty = t.name
+				break
+			}
+		}
+		colDefs[c] = schema.Column{Name: c, Type: schema.Type{Name: ty}}
+	}
+	return colNames, colDefs, nil
+}
+
+// inferParquet returns the columns of the Parquet file.
+func inferParquet(file string) ([]string, map[string]schema.Column, error) {
+	p, closeFile, err := openParquetFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeFile()
+	var colNames []string
+	colDefs := make(map[string]schema.Column)
+	for _, c := range p.columns {
+		colNames = append(colNames, c.name)
+		colDefs[c.name] = schema.Column{Name: c.name, Type: c.srcType(), NotNull: !c.optional}
+	}
+	return colNames, colDefs, nil
+}
+
+// openParquetFile opens the Parquet file and reads its metadata. The
+// returned function closes it.
+func openParquetFile(file string) (*parquetFile, func(), error) {
+	f, err := local.NewLocalFileReader(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read parquet file: %v", err)
+	}
+	p, err := openParquet(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("can't read parquet file %s: %v", file, err)
+	}
+	return p, func() {
+		p.r.ReadStop()
+		f.Close()
+	}, nil
+}
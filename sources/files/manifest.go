@@ -0,0 +1,134 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+// Formats of files.
+const (
+	formatCSV     = "csv"
+	formatParquet = "parquet"
+)
+
+// manifestTable is an entry of the manifest: the files of a table and,
+// optionally, the definition of its schema. Columns of tables without a
+// definition are inferred from their files.
+type manifestTable struct {
+	TableName    string           `json:"table_name"`
+	FilePatterns []string         `json:"file_patterns"`
+	Format       string           `json:"format,omitempty"` // csv or parquet, by default from the extension of the files.
+	Columns      []manifestColumn `json:"columns,omitempty"`
+	PrimaryKeys  []string         `json:"primary_keys,omitempty"`
+}
+
+type manifestColumn struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"` // e.g. INT64 or STRING(50).
+	NotNull bool   `json:"not_null,omitempty"`
+}
+
+// table is a table of the manifest, with its files and schema.
+type table struct {
+	name        string
+	files       []string
+	format      string
+	colNames    []string
+	colDefs     map[string]schema.Column
+	primaryKeys []string
+}
+
+// loadManifest reads the manifest in file and returns its tables.
+func loadManifest(file string) ([]manifestTable, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("can't read manifest file: %v", err)
+	}
+	var tables []manifestTable
+	if err := json.Unmarshal(b, &tables); err != nil {
+		return nil, fmt.Errorf("can't parse manifest file %s: %v", file, err)
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no tables found in manifest file %s", file)
+	}
+	return tables, nil
+}
+
+// expandFiles returns the files matching patterns, downloading files in
+// Google Cloud Storage. Local patterns may contain wildcards, e.g.
+// orders/part-*.parquet.
+func expandFiles(tableName string, patterns []string) ([]string, error) {
+	var files []string
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "gs://") || !strings.ContainsAny(p, "*?[") {
+			files = append(files, p)
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %s for table %s: %v", p, tableName, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %s for table %s", p, tableName)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file path provided for table %s", tableName)
+	}
+	downloaded, err := utils.PreloadGCSFiles([]utils.ManifestTable{{Table_name: tableName, File_patterns: files}})
+	if err != nil {
+		return nil, fmt.Errorf("gcs file download error: %v", err)
+	}
+	return downloaded[0].File_patterns, nil
+}
+
+// fileFormat returns the format of file, from its extension.
+func fileFormat(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".parquet", ".parq":
+		return formatParquet
+	}
+	return formatCSV
+}
+
+// defineColumns sets the columns of t from their definition in the
+// manifest.
+func (t *table) defineColumns(cols []manifestColumn) error {
+	t.colDefs = make(map[string]schema.Column)
+	for _, c := range cols {
+		if c.Name == "" {
+			return fmt.Errorf("a column of table %s has no name", t.name)
+		}
+		if _, ok := t.colDefs[c.Name]; ok {
+			return fmt.Errorf("column %s of table %s is defined twice", c.Name, t.name)
+		}
+		ty, err := parseType(c.Type)
+		if err != nil {
+			return fmt.Errorf("column %s of table %s: %v", c.Name, t.name, err)
+		}
+		t.colNames = append(t.colNames, c.Name)
+		t.colDefs[c.Name] = schema.Column{Name: c.Name, Type: ty, NotNull: c.NotNull}
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+// parquetMagic starts and ends Parquet files.
+const parquetMagic = "PAR1"
+
+// parquetFile is a Parquet file with a flat schema: nested and repeated
+// fields aren't supported.
+type parquetFile struct {
+	r         *reader.ParquetReader
+	columns   []parquetColumn
+	numRows   int64
+	rowGroups []int64 // Number of rows of each row group.
+}
+
+// parquetColumn is a column of a Parquet file.
+type parquetColumn struct {
+	name      string
+	physical  parquet.Type
+	optional  bool
+	kind      string // Kind of the column's values e.g. string, decimal or timestamp-micros.
+	precision int64
+	scale     int
+}
+
+// openParquet reads the metadata of Parquet file f.
+func openParquet(f source.ParquetFile) (*parquetFile, error) {
+	// The reader doesn't check that f is a Parquet file before decoding
+	// its footer.
+	size, err := f.Seek(-8, io.SeekEnd)
+	if err != nil || size < 4 {
+		return nil, fmt.Errorf("not a Parquet file")
+	}
+	tail := make([]byte, 8)
+	if _, err := io.ReadFull(f, tail); err != nil {
+		return nil, err
+	}
+	if string(tail[4:]) != parquetMagic || int64(binary.LittleEndian.Uint32(tail)) > size-4 {
+		return nil, fmt.Errorf("not a Parquet file")
+	}
+	r, err := reader.NewParquetColumnReader(f, 1)
+	if err != nil {
+		return nil, fmt.Errorf("can't read Parquet metadata: %w", err)
+	}
+	elems := r.Footer.GetSchema()
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("Parquet file has no schema")
+	}
+	p := &parquetFile{r: r, numRows: r.GetNumRows()}
+	for i, e := range elems[1:] {
+		// The reader renames the schema elements to Go identifiers, and keeps
+		// their names in the file as external names.
+		c := parquetColumn{name: r.SchemaHandler.Infos[i+1].ExName, physical: e.GetType(), optional: e.GetRepetitionType() == parquet.FieldRepetitionType_OPTIONAL}
+		switch {
+		case e.GetNumChildren() > 0:
+			return nil, fmt.Errorf("column %s is nested: only flat schemas are supported", c.name)
+		case e.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED:
+			return nil, fmt.Errorf("column %s is repeated: only flat schemas are supported", c.name)
+		}
+		c.kind, c.precision, c.scale = columnKind(e)
+		p.columns = append(p.columns, c)
+	}
+	for _, rg := range r.Footer.GetRowGroups() {
+		p.rowGroups = append(p.rowGroups, rg.GetNumRows())
+	}
+	return p, nil
+}
+
+// columnKind returns the kind of the values of the column defined by schema
+// element e, from its logical type or, for files written by older writers,
+// its converted type. Decimals also have a precision and a scale.
+func columnKind(e *parquet.SchemaElement) (string, int64, int) {
+	unit := func(u *parquet.TimeUnit) string {
+		switch {
+		case u != nil && u.IsSetMILLIS():
+			return "millis"
+		case u != nil && u.IsSetNANOS():
+			return "nanos"
+		}
+		return "micros"
+	}
+	if l := e.GetLogicalType(); l != nil {
+		switch {
+		case l.IsSetSTRING(), l.IsSetENUM():
+			return "string", 0, 0
+		case l.IsSetDECIMAL():
+			return "decimal", int64(l.DECIMAL.Precision), int(l.DECIMAL.Scale)
+		case l.IsSetDATE():
+			return "date", 0, 0
+		case l.IsSetTIME():
+			return "time-" + unit(l.TIME.Unit), 0, 0
+		case l.IsSetTIMESTAMP():
+			return "timestamp-" + unit(l.TIMESTAMP.Unit), 0, 0
+		case l.IsSetINTEGER():
+			if !l.INTEGER.IsSigned && l.INTEGER.BitWidth == 64 {
+				return "uint", 0, 0
+			}
+		case l.IsSetJSON():
+			return "json", 0, 0
+		case l.IsSetUUID():
+			return "uuid", 0, 0
+		}
+	} else if e.IsSetConvertedType() {
+		switch e.GetConvertedType() {
+		case parquet.ConvertedType_UTF8, parquet.ConvertedType_ENUM:
+			return "string", 0, 0
+		case parquet.ConvertedType_DECIMAL:
+			return "decimal", int64(e.GetPrecision()), int(e.GetScale())
+		case parquet.ConvertedType_DATE:
+			return "date", 0, 0
+		case parquet.ConvertedType_TIME_MILLIS:
+			return "time-millis", 0, 0
+		case parquet.ConvertedType_TIME_MICROS:
+			return "time-micros", 0, 0
+		case parquet.ConvertedType_TIMESTAMP_MILLIS:
+			return "timestamp-millis", 0, 0
+		case parquet.ConvertedType_TIMESTAMP_MICROS:
+			return "timestamp-micros", 0, 0
+		case parquet.ConvertedType_UINT_64:
+			return "uint", 0, 0
+		case parquet.ConvertedType_JSON:
+			return "json", 0, 0
+		}
+	}
+	switch e.GetType() {
+	case parquet.Type_BOOLEAN:
+		return "bool", 0, 0
+	case parquet.Type_FLOAT, parquet.Type_DOUBLE:
+		return "float", 0, 0
+	case parquet.Type_INT96:
+		return "timestamp-int96", 0, 0
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		return "bytes", 0, 0
+	}
+	return "int", 0, 0
+}
+
+// srcType returns the source type of c.
+func (c parquetColumn) srcType() schema.Type {
+	switch c.kind {
+	case "bool":
+		return schema.Type{Name: "BOOL"}
+	case "int":
+		return schema.Type{Name: "INT64"}
+	case "float":
+		return schema.Type{Name: "FLOAT64"}
+	case "uint":
+		return schema.Type{Name: "NUMERIC"}
+	case "decimal":
+		return schema.Type{Name: "NUMERIC", Mods: []int64{c.precision, int64(c.scale)}}
+	case "date":
+		return schema.Type{Name: "DATE"}
+	case "json":
+		return schema.Type{Name: "JSON"}
+	case "bytes":
+		return schema.Type{Name: "BYTES"}
+	case "string", "uuid", "time-millis", "time-micros", "time-nanos":
+		return schema.Type{Name: "STRING"}
+	}
+	return schema.Type{Name: "TIMESTAMP"}
+}
+
+// readRowGroup returns the values of the columns of row group i, nil for
+// nulls. Row groups must be read in order.
+func (p *parquetFile) readRowGroup(i int) ([][]interface{}, error) {
+	n := p.rowGroups[i]
+	var cols [][]interface{}
+	for j, c := range p.columns {
+		var vals []interface{}
+		if n > 0 {
+			raw, _, _, err := p.r.ReadColumnByIndex(int64(j), n)
+			if err != nil {
+				return nil, fmt.Errorf("column %s of row group %d: %w", c.name, i, err)
+			}
+			// The reader stops at pages it can't decode, without returning
+			// an error.
+			if int64(len(raw)) != n {
+				return nil, fmt.Errorf("column %s of row group %d has %d values, expected %d", c.name, i, len(raw), n)
+			}
+			for _, v := range raw {
+				vals = append(vals, c.value(v))
+			}
+		}
+		cols = append(cols, vals)
+	}
+	return cols, nil
+}
+
+// value converts v, a value of c as returned by the Parquet reader (nil,
+// bool, int32, int64, float32, float64, or string for INT96 and byte
+// arrays), to nil, a bool, int64, float64, *big.Rat, civil.Date, civil.Time,
+// time.Time, string or []byte.
+func (c parquetColumn) value(v interface{}) interface{} {
+	var n int64
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		return v
+	case int32:
+		n = int64(v)
+	case int64:
+		n = v
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		b := []byte(v)
+		if c.physical == parquet.Type_INT96 && len(b) == 12 {
+			// Nanoseconds of the day, and Julian day.
+			nanos := int64(binary.LittleEndian.Uint64(b))
+			days := int64(binary.LittleEndian.Uint32(b[8:])) - 2440588
+			return time.Unix(days*24*60*60, nanos).UTC()
+		}
+		switch c.kind {
+		case "string", "json":
+			return v
+		case "decimal":
+			return decimal(new(big.Int).SetBytes(b), b, c.scale)
+		case "uuid":
+			if len(b) != 16 {
+				break
+			}
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+		}
+		return b
+	default:
+		return v
+	}
+	switch c.kind {
+	case "uint":
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(uint64(n)))
+	case "decimal":
+		return new(big.Rat).SetFrac(big.NewInt(n), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.scale)), nil))
+	case "date":
+		return civil.DateOf(time.Unix(n*24*60*60, 0).UTC())
+	case "timestamp-millis":
+		return time.Unix(0, 0).Add(time.Duration(n) * time.Millisecond).UTC()
+	case "timestamp-micros":
+		return time.Unix(0, 0).Add(time.Duration(n) * time.Microsecond).UTC()
+	case "timestamp-nanos":
+		return time.Unix(0, n).UTC()
+	case "time-millis":
+		return civil.TimeOf(time.Unix(0, 0).Add(time.Duration(n) * time.Millisecond).UTC())
+	case "time-micros":
+		return civil.TimeOf(time.Unix(0, 0).Add(time.Duration(n) * time.Microsecond).UTC())
+	case "time-nanos":
+		return civil.TimeOf(time.Unix(0, n).UTC())
+	}
+	return n
+}
+
+// decimal returns the decimal whose unscaled value is u, big-endian two's
+// complement b, and whose scale is scale.
+func decimal(u *big.Int, b []byte, scale int) *big.Rat {
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		u.Sub(u, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return new(big.Rat).SetFrac(u, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+// testRow is a row of the Parquet files written by writeParquet.
+type testRow struct {
+	ID     int64   `parquet:"name=id, type=INT64"`
+	Name   *string `parquet:"name=name, type=UTF8, encoding=PLAIN_DICTIONARY, repetitiontype=OPTIONAL"`
+	Amount *int32  `parquet:"name=amount, type=DECIMAL, basetype=INT32, scale=2, precision=9, repetitiontype=OPTIONAL"`
+	TS     int64   `parquet:"name=ts, type=TIMESTAMP_MICROS"`
+	Flag   bool    `parquet:"name=flag, type=BOOLEAN"`
+}
+
+// writeParquet writes a Parquet file of rows, pointers to testRow or another
+// struct with parquet tags, compressed with codec, and returns its name.
+func writeParquet(t *testing.T, codec parquet.CompressionCodec, rows ...interface{}) string {
+	file := filepath.Join(t.TempDir(), "test.parquet")
+	f, err := local.NewLocalFileWriter(file)
+	assert.Nil(t, err)
+	w, err := writer.NewParquetWriter(f, rows[0], 1)
+	assert.Nil(t, err)
+	w.CompressionType = codec
+	for _, row := range rows {
+		assert.Nil(t, w.Write(row))
+	}
+	assert.Nil(t, w.WriteStop())
+	assert.Nil(t, f.Close())
+	return file
+}
+
+// testParquetFile writes a Snappy compressed Parquet file of the rows of
+// testParquetRows.
+func testParquetFile(t *testing.T) string {
+	return writeParquet(t, parquet.CompressionCodec_SNAPPY, testParquetRows()...)
+}
+
+// testParquetRows returns 3 rows of columns id, name, amount, ts and flag.
+func testParquetRows() []interface{} {
+	a, b := "a", "b"
+	amount1, amount2 := int32(1234), int32(-5)
+	return []interface{}{
+		&testRow{ID: 1, Name: &b, Amount: &amount1, TS: 0, Flag: true},
+		&testRow{ID: 2, Amount: &amount2, TS: 1641600000123456, Flag: false},
+		&testRow{ID: 3, Name: &a, TS: -1, Flag: true},
+	}
+}
+
+func TestReadParquet(t *testing.T) {
+	for _, codec := range []parquet.CompressionCodec{parquet.CompressionCodec_UNCOMPRESSED, parquet.CompressionCodec_SNAPPY, parquet.CompressionCodec_GZIP} {
+		t.Run(codec.String(), func(t *testing.T) { testReadParquet(t, writeParquet(t, codec, testParquetRows()...)) })
+	}
+}
+
+func testReadParquet(t *testing.T, file string) {
+	colNames, colDefs, err := inferParquet(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "name", "amount", "ts", "flag"}, colNames)
+	assert.Equal(t, map[string]schema.Column{
+		"id":     {Name: "id", Type: schema.Type{Name: "INT64"}, NotNull: true},
+		"name":   {Name: "name", Type: schema.Type{Name: "STRING"}},
+		"amount": {Name: "amount", Type: schema.Type{Name: "NUMERIC", Mods: []int64{9, 2}}},
+		"ts":     {Name: "ts", Type: schema.Type{Name: "TIMESTAMP"}, NotNull: true},
+		"flag":   {Name: "flag", Type: schema.Type{Name: "BOOL"}, NotNull: true},
+	}, colDefs)
+
+	var rows []map[string]interface{}
+	rowGroups := 0
+	err = readParquet(file, func(row map[string]interface{}) { rows = append(rows, row) }, func() { rowGroups++ })
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rowGroups)
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "name": "b", "amount": big.NewRat(1234, 100), "ts": time.Unix(0, 0).UTC(), "flag": true},
+		{"id": int64(2), "amount": big.NewRat(-5, 100), "ts": time.Date(2022, 1, 8, 0, 0, 0, 123456000, time.UTC), "flag": false},
+		{"id": int64(3), "name": "a", "ts": time.Date(1969, 12, 31, 23, 59, 59, 999999000, time.UTC), "flag": true},
+	}, rows)
+}
+
+func TestOpenParquet_Invalid(t *testing.T) {
+	f, err := buffer.NewBufferFile([]byte("id,name\n1,a\n"))
+	assert.Nil(t, err)
+	_, err = openParquet(f)
+	assert.NotNil(t, err)
+	// A nested column.
+	type address struct {
+		City string `parquet:"name=city, type=UTF8"`
+	}
+	file := writeParquet(t, parquet.CompressionCodec_UNCOMPRESSED, &struct {
+		Address address `parquet:"name=address"`
+	}{})
+	_, _, err = inferParquet(file)
+	assert.NotNil(t, err)
+}
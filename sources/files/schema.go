@@ -0,0 +1,214 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"context"
+	csvReader "encoding/csv"
+	"fmt"
+	"io"
+	"os"
+
+	sp "cloud.google.com/go/spanner"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// defaultSchemaSampleSize is the number of rows of CSV files sampled to
+// infer the type of their columns, by default.
+const defaultSchemaSampleSize = 10000
+
+// InfoSchemaImpl reads the tables of a manifest from their files.
+type InfoSchemaImpl struct {
+	tables     map[string]*table
+	tableNames []string // Names of tables, in the order of the manifest.
+	Delimiter  rune     // Delimiter of CSV files.
+	NullStr    string   // Value of nulls in CSV files.
+}
+
+// newInfoSchema returns the InfoSchemaImpl of the tables of manifest file:
+// files are listed, and columns without a definition inferred from the first
+// file of their table (sampling up to sampleSize rows of CSV files).
+func newInfoSchema(file, format string, delimiter rune, nullStr string, sampleSize int64) (InfoSchemaImpl, error) {
+	isi := InfoSchemaImpl{tables: make(map[string]*table), Delimiter: delimiter, NullStr: nullStr}
+	manifest, err := loadManifest(file)
+	if err != nil {
+		return isi, err
+	}
+	if sampleSize <= 0 {
+		sampleSize = defaultSchemaSampleSize
+	}
+	for i, m := range manifest {
+		if m.TableName == "" {
+			return isi, fmt.Errorf("table number %d (0-indexed) does not have a name", i)
+		}
+		if _, ok := isi.tables[m.TableName]; ok {
+			return isi, fmt.Errorf("table %s is listed twice in the manifest", m.TableName)
+		}
+		t := &table{name: m.TableName, primaryKeys: m.PrimaryKeys}
+		if t.files, err = expandFiles(m.TableName, m.FilePatterns); err != nil {
+			return isi, err
+		}
+		for _, f := range []string{m.Format, format, fileFormat(t.files[0])} {
+			if f != "" {
+				t.format = f
+				break
+			}
+		}
+		switch {
+		case t.format != formatCSV && t.format != formatParquet:
+			return isi, fmt.Errorf("invalid format %s for table %s: expected csv or parquet", t.format, t.name)
+		case len(m.Columns) > 0:
+			err = t.defineColumns(m.Columns)
+		case t.format == formatParquet:
+			t.colNames, t.colDefs, err = inferParquet(t.files[0])
+		default:
+			t.colNames, t.colDefs, err = inferCSV(t.files[0], delimiter, nullStr, sampleSize)
+		}
+		if err != nil {
+			return isi, err
+		}
+		for _, k := range t.primaryKeys {
+			if _, ok := t.colDefs[k]; !ok {
+				return isi, fmt.Errorf("primary key column %s of table %s isn't a column of the table", k, t.name)
+			}
+		}
+		isi.tables[t.name] = t
+		isi.tableNames = append(isi.tableNames, t.name)
+	}
+	return isi, nil
+}
+
+func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
+	return tableName
+}
+
+// GetTables returns the tables of the manifest.
+func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
+	var tables []common.SchemaAndName
+	for _, name := range isi.tableNames {
+		tables = append(tables, common.SchemaAndName{Name: name})
+	}
+	return tables, nil
+}
+
+// GetColumns returns the columns of table, as defined in the manifest or
+// inferred from its files.
+func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
+	t, ok := isi.tables[table.Name]
+	if !ok {
+		return nil, nil, fmt.Errorf("table %s isn't in the manifest", table.Name)
+	}
+	colDefs := make(map[string]schema.Column)
+	for name, c := range t.colDefs {
+		colDefs[name] = c
+	}
+	return colDefs, append([]string(nil), t.colNames...), nil
+}
+
+// GetRowsFromTable isn't used: data is read by ProcessData.
+func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
+	return nil, nil
+}
+
+// GetRowCount returns the number of rows of table: the number of rows in the
+// metadata of Parquet files, or of records of CSV files, less headers.
+func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
+	t, ok := isi.tables[table.Name]
+	if !ok {
+		return 0, fmt.Errorf("table %s isn't in the manifest", table.Name)
+	}
+	var count int64
+	for _, file := range t.files {
+		if t.format == formatParquet {
+			p, closeFile, err := openParquetFile(file)
+			if err != nil {
+				return 0, err
+			}
+			count += p.numRows
+			closeFile()
+			continue
+		}
+		n, err := countCSVRows(file, isi.Delimiter, t.colNames)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// countCSVRows returns the number of rows of the CSV file, without its
+// header if any.
+func countCSVRows(file string, delimiter rune, colNames []string) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, fmt.Errorf("can't read csv file: %v", err)
+	}
+	defer f.Close()
+	r := csvReader.NewReader(f)
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	var count int64
+	for {
+		values, err := r.Read()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("can't read row of %s: %v", file, err)
+		}
+		if count > 0 || !isHeader(values, colNames) {
+			count++
+		}
+	}
+}
+
+// GetConstraints returns the primary key of table, if defined in the
+// manifest. Tables without one are migrated with a synthetic primary key.
+func (isi InfoSchemaImpl) GetConstraints(conv *internal.Conv, table common.SchemaAndName) ([]string, map[string][]string, error) {
+	t, ok := isi.tables[table.Name]
+	if !ok {
+		return nil, nil, fmt.Errorf("table %s isn't in the manifest", table.Name)
+	}
+	return t.primaryKeys, nil, nil
+}
+
+// GetForeignKeys returns no foreign keys: files have none.
+func (isi InfoSchemaImpl) GetForeignKeys(conv *internal.Conv, table common.SchemaAndName) ([]schema.ForeignKey, error) {
+	return nil, nil
+}
+
+// GetIndexes returns no indexes: files have none.
+func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAndName) ([]schema.Index, error) {
+	return nil, nil
+}
+
+// StartChangeDataCapture is not supported: files are only migrated in bulk.
+func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// StartStreamingMigration is not supported: files are only migrated in bulk.
+func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *sp.Client, conv *internal.Conv, streamingInfo map[string]interface{}) error {
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package files
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func init() {
+	logger.Log = zap.NewNop()
+}
+
+// writeFiles writes files, keyed by name, to a temporary directory and
+// returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestInferCSV(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"users.csv": `id,zip,score,active,born,seen,prefs,name,empty,code
+1,02134,1.5,true,1990-01-02,2022-01-08 10:30:00,"{""a"": 1}",ann,,7
+-2,90210,2,FALSE,1985-12-31,2022-01-08T10:30:00Z,[],bob,,x7
+`})
+	colNames, colDefs, err := inferCSV(filepath.Join(dir, "users.csv"), ',', "", 100)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "zip", "score", "active", "born", "seen", "prefs", "name", "empty", "code"}, colNames)
+	types := make(map[string]string)
+	for name, c := range colDefs {
+		types[name] = c.Type.Name
+	}
+	assert.Equal(t, map[string]string{
+		"id":     "INT64",
+		"zip":    "STRING", // Leading zeros are kept.
+		"score":  "FLOAT64",
+		"active": "BOOL",
+		"born":   "DATE",
+		"seen":   "TIMESTAMP",
+		"prefs":  "JSON",
+		"name":   "STRING",
+		"empty":  "BOOL", // All values are null.
+		"code":   "STRING",
+	}, types)
+	// Only the first row is sampled.
+	_, colDefs, err = inferCSV(filepath.Join(dir, "users.csv"), ',', "", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "INT64", colDefs["code"].Type.Name)
+}
+
+func TestProcessData(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"users-1.csv": "name,id\nann,1\nbob,NULL\n",
+		"users-2.csv": "3,carl\n",
+		"events.txt":  "2,2022-01-08 10:30:00,\"{\"\"k\"\": 1}\"\n3,not a time,{}\n",
+	})
+	parquet := testParquetFile(t)
+	manifest := fmt.Sprintf(`[
+		{"table_name": "users", "file_patterns": [%q], "primary_keys": ["id"], "columns": [
+			{"name": "id", "type": "INT64", "not_null": true},
+			{"name": "name", "type": "STRING(20)"}
+		]},
+		{"table_name": "events", "file_patterns": [%q], "format": "csv", "columns": [
+			{"name": "user_id", "type": "int64"},
+			{"name": "at", "type": "TIMESTAMP"},
+			{"name": "payload", "type": "JSON"}
+		]},
+		{"table_name": "payments", "file_patterns": [%q], "primary_keys": ["id"]}
+	]`, filepath.Join(dir, "users-*.csv"), filepath.Join(dir, "events.txt"), parquet)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0644))
+	isi, err := newInfoSchema(filepath.Join(dir, "manifest.json"), "", ',', "NULL", 0)
+	assert.Nil(t, err)
+
+	tables, err := isi.GetTables()
+	assert.Nil(t, err)
+	assert.Equal(t, []common.SchemaAndName{{Name: "users"}, {Name: "events"}, {Name: "payments"}}, tables)
+	colDefs, colNames, err := isi.GetColumns(nil, common.SchemaAndName{Name: "users"}, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "name"}, colNames)
+	assert.Equal(t, schema.Column{Name: "name", Type: schema.Type{Name: "STRING", Mods: []int64{20}}}, colDefs["name"])
+	pks, _, err := isi.GetConstraints(nil, common.SchemaAndName{Name: "payments"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id"}, pks)
+	for table, count := range map[string]int64{"users": 3, "events": 2, "payments": 3} {
+		n, err := isi.GetRowCount(common.SchemaAndName{Name: table})
+		assert.Nil(t, err)
+		assert.Equal(t, count, n, table)
+	}
+
+	conv := internal.MakeConv()
+	conv.SetLocation(time.UTC)
+	rows := make(map[string][][]interface{})
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		rows[table] = append(rows[table], vals)
+	})
+	for _, table := range tables {
+		colDefs, colNames, err := isi.GetColumns(conv, table, nil, nil)
+		assert.Nil(t, err)
+		srcSchema := schema.Table{Name: table.Name, ColNames: colNames, ColDefs: colDefs}
+		spSchema := ddl.CreateTable{Name: table.Name, ColNames: colNames, ColDefs: make(map[string]ddl.ColumnDef)}
+		for _, c := range colNames {
+			ty, _ := ToDdlImpl{}.ToSpannerType(conv, colDefs[c].Type)
+			spSchema.ColDefs[c] = ddl.ColumnDef{Name: c, T: ty}
+		}
+		conv.SrcSchema[table.Name] = srcSchema
+		conv.SpSchema[table.Name] = spSchema
+		conv.SetDataMode()
+		assert.Nil(t, isi.ProcessData(context.Background(), conv, table.Name, srcSchema, table.Name, colNames, spSchema))
+	}
+	assert.Equal(t, [][]interface{}{{int64(1), "ann"}, {nil, "bob"}, {int64(3), "carl"}}, rows["users"])
+	assert.Equal(t, [][]interface{}{{int64(2), time.Date(2022, 1, 8, 10, 30, 0, 0, time.UTC), `{"k": 1}`}}, rows["events"])
+	assert.Equal(t, int64(1), conv.BadRows())
+	assert.Equal(t, []interface{}{int64(1), "b", big.NewRat(1234, 100), time.Unix(0, 0).UTC(), true}, rows["payments"][0])
+}
+
+func TestProcessData_Cancelled(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.csv": "1\n"})
+	manifest := fmt.Sprintf(`[{"table_name": "a", "file_patterns": [%q], "columns": [{"name": "x", "type": "INT64"}]}]`, filepath.Join(dir, "a.csv"))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0644))
+	isi, err := newInfoSchema(filepath.Join(dir, "manifest.json"), "", ',', "", 0)
+	assert.Nil(t, err)
+
+	conv := internal.MakeConv()
+	// The only migration window opens in an hour: the migration pauses until
+	// it is cancelled.
+	start := time.Now().UTC().Add(time.Hour)
+	conv.Schedule, err = internal.ParseSchedule(fmt.Sprintf("%s-%s", start.Format("15:04"), start.Add(time.Minute).Format("15:04")), "UTC")
+	assert.Nil(t, err)
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		t.Errorf("unexpected row %v", vals)
+	})
+	conv.SetDataMode()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	srcSchema := schema.Table{Name: "a", ColNames: []string{"x"}, ColDefs: map[string]schema.Column{"x": {Name: "x", Type: schema.Type{Name: "INT64"}}}}
+	spSchema := ddl.CreateTable{Name: "a", ColNames: []string{"x"}, ColDefs: map[string]ddl.ColumnDef{"x": {Name: "x", T: ddl.Type{Name: ddl.Int64}}}}
+	assert.Equal(t, context.Canceled, isi.ProcessData(ctx, conv, "a", srcSchema, "a", []string{"x"}, spSchema))
+}
+
+func TestNewInfoSchema_Invalid(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.csv": "x\n1\n"})
+	for _, manifest := range []string{
+		`[]`,
+		`[{"table_name": "a", "file_patterns": []}]`,
+		`[{"table_name": "a", "file_patterns": ["%[1]s/missing-*.csv"]}]`,
+		`[{"table_name": "a", "file_patterns": ["%[1]s/a.csv"], "format": "avro"}]`,
+		`[{"table_name": "a", "file_patterns": ["%[1]s/a.csv"], "columns": [{"name": "x", "type": "INT32"}]}]`,
+		`[{"table_name": "a", "file_patterns": ["%[1]s/a.csv"], "primary_keys": ["y"]}]`,
+		`[{"table_name": "a", "file_patterns": ["%[1]s/a.csv"]}, {"table_name": "a", "file_patterns": ["%[1]s/a.csv"]}]`,
+	} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte(fmt.Sprintf(manifest, dir)), 0644))
+		_, err := newInfoSchema(filepath.Join(dir, "manifest.json"), "", ',', "", 0)
+		assert.NotNil(t, err, manifest)
+	}
+}
+
+func TestConvScalar(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SetLocation(time.UTC)
+	testCases := []struct {
+		v        interface{}
+		spType   string
+		expected interface{}
+	}{
+		{"true", ddl.Bool, true},
+		{"12", ddl.Numeric, big.NewRat(12, 1)},
+		{"2022-01-08", ddl.Date, civil.Date{Year: 2022, Month: 1, Day: 8}},
+		{"ab", ddl.Bytes, []byte("ab")},
+		{int64(7), ddl.Float64, 7.0},
+		{int64(7), ddl.String, "7"},
+		{big.NewRat(-5, 100), ddl.String, "-0.05"},
+		{civil.Time{Hour: 10, Minute: 30}, ddl.String, "10:30:00"},
+		{time.Date(2022, 1, 8, 10, 30, 0, 0, time.UTC), ddl.Date, civil.Date{Year: 2022, Month: 1, Day: 8}},
+	}
+	for _, tc := range testCases {
+		v, err := convScalar(conv, tc.v, tc.spType)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, v)
+	}
+	_, err := convScalar(conv, "[1", ddl.JSON)
+	assert.NotNil(t, err)
+	_, err = convScalar(conv, 1.5, ddl.Int64)
+	assert.NotNil(t, err)
+}
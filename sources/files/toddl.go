@@ -0,0 +1,111 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package files handles schema and data migrations from CSV and Parquet
+// files, whose schema is either defined in their manifest or inferred from
+// the files.
+package files
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// ToDdlImpl files specific implementation for ToDdl.
+type ToDdlImpl struct {
+}
+
+// ToSpannerType maps a column type into a Spanner type. Column types are
+// named after Spanner types, so that schema definitions read like Spanner
+// DDL.
+func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
+	return TypeMap.ToSpannerType(columnType.Name, "", columnType.Mods)
+}
+
+// TypeMap declares the mapping of column types to Spanner types.
+var TypeMap = common.NewTypeMap(
+	common.TypeGroup{SrcTypes: []string{"BOOL"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Bool}),
+		Options: map[string]common.MapFunc{
+			ddl.String: common.To(common.MaxString, internal.Widened),
+			ddl.Int64:  common.To(ddl.Type{Name: ddl.Int64}, internal.Widened),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"INT64"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}),
+		Options: map[string]common.MapFunc{
+			ddl.String:  common.To(common.MaxString, internal.Widened),
+			ddl.Numeric: common.To(ddl.Type{Name: ddl.Numeric}, internal.Widened),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"FLOAT64"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Float64}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"NUMERIC"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Numeric}),
+		Options: map[string]common.MapFunc{
+			ddl.String:  common.To(common.MaxString, internal.Widened),
+			ddl.Float64: common.To(ddl.Type{Name: ddl.Float64}, internal.Numeric),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"STRING"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.String, ddl.MaxLength),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.ToSized(ddl.Bytes, ddl.MaxLength)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"BYTES"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.Bytes, ddl.MaxLength),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"DATE"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Date}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"TIMESTAMP"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Timestamp}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"JSON"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.JSON}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+)
+
+var typeRegexp = regexp.MustCompile(`^([A-Z0-9]+)\s*(?:\(\s*(MAX|\d+)\s*(?:,\s*(\d+)\s*)?\))?$`)
+
+// parseType parses column type s of a schema definition e.g. STRING(50) or
+// NUMERIC(10, 2).
+func parseType(s string) (schema.Type, error) {
+	m := typeRegexp.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(s)))
+	if m == nil {
+		return schema.Type{}, fmt.Errorf("invalid column type %q", s)
+	}
+	ty := schema.Type{Name: m[1]}
+	if _, ok := TypeMap.Types[ty.Name]; !ok {
+		return schema.Type{}, fmt.Errorf("unsupported column type %q: expected one of BOOL, INT64, FLOAT64, NUMERIC, STRING, BYTES, DATE, TIMESTAMP or JSON", s)
+	}
+	for _, mod := range m[2:] {
+		if n, err := strconv.ParseInt(mod, 10, 64); err == nil {
+			ty.Mods = append(ty.Mods, n)
+		}
+	}
+	return ty, nil
+}
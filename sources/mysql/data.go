@@ -16,8 +16,8 @@ package mysql
 
 import (
 	"fmt"
+	"math"
 	"math/big"
-	"math/bits"
 	"strconv"
 	"strings"
 	"time"
@@ -35,7 +35,9 @@ import (
 // and vals contains string data to be converted to appropriate types
 // to send to Spanner. ProcessDataRow is only called in DataMode.
 func ProcessDataRow(conv *internal.Conv, srcTable string, srcCols []string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, vals []string) {
+	start := time.Now()
 	spTable, cvtCols, cvtVals, err := ConvertData(conv, srcTable, srcCols, srcSchema, spTable, spCols, spSchema, vals)
+	conv.Timings.TimeTable(internal.StageConvert, srcTable, start)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(srcTable, conv.DataMode())
@@ -69,12 +71,26 @@ func ConvertData(conv *internal.Conv, srcTable string, srcCols []string, srcSche
 		if !ok1 || !ok2 {
 			return "", []string{}, []interface{}{}, fmt.Errorf("can't find Spanner and source-db schema for col %s", spCol)
 		}
+		if srcColDef.Type.Name == internal.UnsignedBigint && !spColDef.T.IsArray {
+			if u, err := strconv.ParseUint(vals[i], 10, 64); err == nil && u > math.MaxInt64 {
+				conv.StatsAddUnsignedOverflow(srcTable)
+				if spColDef.T.Name == ddl.Int64 {
+					return "", []string{}, []interface{}{}, fmt.Errorf("value %s of unsigned column %s is larger than the maximum INT64 value", vals[i], srcCol)
+				}
+			}
+		}
 		var x interface{}
 		var err error
 		if spColDef.T.IsArray {
 			x, err = convArray(spColDef.T, srcColDef.Type.Name, vals[i])
 		} else {
-			x, err = convScalar(conv, spColDef.T, srcColDef.Type.Name, conv.TimezoneOffset, vals[i])
+			x, err = convScalar(conv, spColDef.T, srcColDef.Type.Name, conv.TimezoneOffset, conv.DatetimeLocation(srcTable, srcCol), vals[i])
+			if isDateType(srcColDef.Type.Name) && (err != nil || !internal.DateInRange(x)) {
+				x, err = conv.SanitizeInvalidDate(srcTable, srcCol, spColDef.T.Name, vals[i])
+				if err == nil && x == nil {
+					continue
+				}
+			}
 		}
 		if err != nil {
 			return "", []string{}, []interface{}{}, err
@@ -84,18 +100,26 @@ func ConvertData(conv *internal.Conv, srcTable string, srcCols []string, srcSche
 	}
 	if aux, ok := conv.SyntheticPKeys[spTable]; ok {
 		c = append(c, aux.Col)
-		v = append(v, int64(bits.Reverse64(uint64(aux.Sequence))))
-		aux.Sequence++
-		conv.SyntheticPKeys[spTable] = aux
+		v = append(v, conv.NextSyntheticKey(spTable))
 	}
 	return spTable, c, v, nil
 }
 
+// isDateType returns true for MySQL types whose values can be invalid dates,
+// such as the zero date '0000-00-00'.
+func isDateType(srcTypeName string) bool {
+	switch srcTypeName {
+	case "date", "datetime", "timestamp":
+		return true
+	}
+	return false
+}
+
 // convScalar converts a source database string value to an
 // appropriate Spanner value. It is the caller's responsibility to
 // detect and handle NULL values: convScalar will return error if a
 // NULL value is passed.
-func convScalar(conv *internal.Conv, spannerType ddl.Type, srcTypeName string, TimezoneOffset string, val string) (interface{}, error) {
+func convScalar(conv *internal.Conv, spannerType ddl.Type, srcTypeName string, TimezoneOffset string, datetimeLoc *time.Location, val string) (interface{}, error) {
 	// Whitespace within the val string is considered part of the data value.
 	// Note that many of the underlying conversions functions we use (like
 	// strconv.ParseFloat and strconv.ParseInt) return "invalid syntax"
@@ -117,7 +141,7 @@ func convScalar(conv *internal.Conv, spannerType ddl.Type, srcTypeName string, T
 	case ddl.String:
 		return val, nil
 	case ddl.Timestamp:
-		return convTimestamp(srcTypeName, TimezoneOffset, val)
+		return convTimestamp(srcTypeName, TimezoneOffset, datetimeLoc, val)
 	case ddl.JSON:
 		return val, nil
 	default:
@@ -190,8 +214,9 @@ func convNumeric(conv *internal.Conv, val string) (interface{}, error) {
 }
 
 // convTimestamp maps a source DB timestamp into a go Time Spanner timestamp
-// It handles both datetime and timestamp conversions.
-func convTimestamp(srcTypeName string, TimezoneOffset string, val string) (t time.Time, err error) {
+// It handles both datetime and timestamp conversions. Datetimes are
+// interpreted in datetimeLoc.
+func convTimestamp(srcTypeName string, TimezoneOffset string, datetimeLoc *time.Location, val string) (t time.Time, err error) {
 	// mysqldump outputs timestamps as ISO 8601, except
 	// it uses space instead of T.
 	if srcTypeName == "timestamp" {
@@ -207,10 +232,10 @@ func convTimestamp(srcTypeName string, TimezoneOffset string, val string) (t tim
 		timeJoined = timeJoined + TimezoneOffset
 		t, err = time.Parse(time.RFC3339, timeJoined)
 	} else {
-		// datetime: data should just consist of date and time.
-		// timestamp conversion should ignore timezone. We mimic this using Parse
-		// i.e. treat it as UTC, so it will be stored 'as-is' in Spanner.
-		t, err = time.Parse("2006-01-02 15:04:05", val)
+		// datetime: data should just consist of date and time, which we
+		// interpret in the time zone configured for the column (UTC by
+		// default, so it will be stored 'as-is' in Spanner).
+		t, err = time.ParseInLocation("2006-01-02 15:04:05", val, datetimeLoc)
 	}
 	if err != nil {
 		return t, fmt.Errorf("can't convert to timestamp (mysql type: %s)", srcTypeName)
@@ -16,6 +16,7 @@ package mysql
 
 import (
 	"fmt"
+	"math/big"
 	"math/bits"
 	"testing"
 	"time"
@@ -156,6 +157,32 @@ func TestConvertTimestampData(t *testing.T) {
 	}
 }
 
+func TestConvertDatetimeZone(t *testing.T) {
+	tableName := "testtable"
+	cols := []string{"a", "b"}
+	conv := buildConv(
+		ddl.CreateTable{
+			Name:     tableName,
+			ColNames: cols,
+			ColDefs: map[string]ddl.ColumnDef{
+				"a": ddl.ColumnDef{Name: "a", T: ddl.Type{Name: ddl.Timestamp}},
+				"b": ddl.ColumnDef{Name: "b", T: ddl.Type{Name: ddl.Timestamp}},
+			}},
+		schema.Table{
+			Name:     tableName,
+			ColNames: cols,
+			ColDefs: map[string]schema.Column{
+				"a": schema.Column{Name: "a", Type: schema.Type{Name: "datetime"}},
+				"b": schema.Column{Name: "b", Type: schema.Type{Name: "datetime"}},
+			}})
+	assert.Nil(t, conv.SetDatetimeZone("", "", "Asia/Kolkata"))
+	assert.Nil(t, conv.SetDatetimeZone(tableName, "b", "America/New_York"))
+	_, _, av, err := ConvertData(conv, tableName, cols, conv.SrcSchema[tableName], tableName, cols, conv.SpSchema[tableName], []string{"2019-10-29 05:30:00", "2019-10-29 05:30:00"})
+	assert.Nil(t, err)
+	assert.True(t, getTime(t, "2019-10-29T00:00:00Z").Equal(av[0].(time.Time)))
+	assert.True(t, getTime(t, "2019-10-29T09:30:00Z").Equal(av[1].(time.Time)))
+}
+
 func TestConvertMultiColData(t *testing.T) {
 	multiColTests := []struct {
 		name  string
@@ -272,6 +299,92 @@ func TestConvertError(t *testing.T) {
 	}
 }
 
+func TestConvertUnsignedBigint(t *testing.T) {
+	max := "18446744073709551615"
+	maxRat, _ := new(big.Rat).SetString(max)
+	unsignedTests := []struct {
+		name     string
+		ty       ddl.Type
+		in       string
+		e        interface{} // Expected result, nil if conversion fails.
+		overflow int64
+	}{
+		{"int64", ddl.Type{Name: ddl.Int64}, "42", int64(42), 0},
+		{"int64 overflow", ddl.Type{Name: ddl.Int64}, max, nil, 1},
+		{"numeric overflow", ddl.Type{Name: ddl.Numeric}, max, maxRat, 1},
+		{"string overflow", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, max, max, 1},
+	}
+	tableName := "testtable"
+	col := "a"
+	for _, tc := range unsignedTests {
+		t.Run(tc.name, func(t *testing.T) {
+			conv := buildConv(
+				ddl.CreateTable{
+					Name:     tableName,
+					ColNames: []string{col},
+					ColDefs:  map[string]ddl.ColumnDef{col: ddl.ColumnDef{Name: col, T: tc.ty}}},
+				schema.Table{Name: tableName, ColNames: []string{col}, ColDefs: map[string]schema.Column{col: schema.Column{Type: schema.Type{Name: internal.UnsignedBigint}}}})
+			at, ac, av, err := ConvertData(conv, tableName, []string{col}, conv.SrcSchema[tableName], tableName, []string{col}, conv.SpSchema[tableName], []string{tc.in})
+			if tc.e == nil {
+				assert.NotNil(t, err, tc.name)
+			} else {
+				checkResults(t, at, ac, av, err, tableName, []string{col}, []interface{}{tc.e}, tc.name)
+			}
+			assert.Equal(t, tc.overflow, conv.Stats.UnsignedOverflow[tableName], tc.name)
+		})
+	}
+}
+
+func TestConvertInvalidDate(t *testing.T) {
+	invalidDateTests := []struct {
+		name   string
+		policy string
+		ty     ddl.Type
+		srcTy  string
+		in     string
+		cols   []string      // Expected columns, nil if conversion fails.
+		e      []interface{} // Expected values.
+	}{
+		{"valid date", internal.InvalidDateDrop, ddl.Type{Name: ddl.Date}, "date", "2019-10-29", []string{"id", "a"}, []interface{}{int64(1), getDate("2019-10-29")}},
+		{"zero date drop", internal.InvalidDateDrop, ddl.Type{Name: ddl.Date}, "date", "0000-00-00", nil, nil},
+		{"zero date null", internal.InvalidDateNull, ddl.Type{Name: ddl.Date}, "date", "0000-00-00", []string{"id"}, []interface{}{int64(1)}},
+		{"zero day sentinel", internal.InvalidDateSentinel, ddl.Type{Name: ddl.Date}, "date", "2019-10-00", []string{"id", "a"}, []interface{}{int64(1), getDate("0001-01-01")}},
+		{"zero datetime sentinel", internal.InvalidDateSentinel, ddl.Type{Name: ddl.Timestamp}, "datetime", "0000-00-00 00:00:00", []string{"id", "a"}, []interface{}{int64(1), time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{"year zero datetime null", internal.InvalidDateNull, ddl.Type{Name: ddl.Timestamp}, "datetime", "0000-01-01 00:00:00", []string{"id"}, []interface{}{int64(1)}},
+	}
+	tableName := "testtable"
+	cols := []string{"id", "a"}
+	for _, tc := range invalidDateTests {
+		t.Run(tc.name, func(t *testing.T) {
+			conv := buildConv(
+				ddl.CreateTable{
+					Name:     tableName,
+					ColNames: cols,
+					ColDefs: map[string]ddl.ColumnDef{
+						"id": ddl.ColumnDef{Name: "id", T: ddl.Type{Name: ddl.Int64}},
+						"a":  ddl.ColumnDef{Name: "a", T: tc.ty},
+					}},
+				schema.Table{
+					Name:     tableName,
+					ColNames: cols,
+					ColDefs: map[string]schema.Column{
+						"id": schema.Column{Type: schema.Type{Name: "bigint"}},
+						"a":  schema.Column{Type: schema.Type{Name: tc.srcTy}},
+					}})
+			assert.Nil(t, conv.SetInvalidDatePolicy(tc.policy, ""))
+			at, ac, av, err := ConvertData(conv, tableName, cols, conv.SrcSchema[tableName], tableName, cols, conv.SpSchema[tableName], []string{"1", tc.in})
+			if tc.cols == nil {
+				assert.NotNil(t, err, tc.name)
+			} else {
+				checkResults(t, at, ac, av, err, tableName, tc.cols, tc.e, tc.name)
+			}
+			if tc.name != "valid date" {
+				assert.Equal(t, map[string]map[string]int64{tableName: {"a": 1}}, conv.Stats.InvalidDates, tc.name)
+			}
+		})
+	}
+}
+
 func TestConvertsyntheticPKey(t *testing.T) {
 	syntheticPKeyTests := []struct {
 		name  string
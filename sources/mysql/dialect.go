@@ -0,0 +1,63 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+var (
+	// auroraMarkerRegexp matches the host names of Aurora clusters in
+	// mysqldump headers, and the Aurora-specific statements and procedures
+	// that give away Aurora MySQL dumps.
+	auroraMarkerRegexp = regexp.MustCompile(`(?i)(\.cluster-(ro-)?[a-z0-9]+\.[a-z0-9-]+\.rds\.amazonaws\.com|\bmysql\.rds_\w+|\bFROM\s+S3\b|\bOUTFILE\s+S3\b|\baurora_version\s*\()`)
+	// auroraStmtRegexp matches Aurora MySQL statements the parser doesn't
+	// parse, skipped since they don't define schema or data: data loads and
+	// unloads through S3, and fault injection queries.
+	auroraStmtRegexp = regexp.MustCompile(`(?is)^(\s*(--|#)[^\n]*\n)*\s*(LOAD\s+(DATA|XML)\s+(LOCAL\s+)?FROM\s+S3\b|SELECT\b.*\bINTO\s+OUTFILE\s+S3\b|ALTER\s+SYSTEM\s+(CRASH|SIMULATE)\b)`)
+)
+
+// maxDialectProbe is the size of the largest chunks checked for dialect
+// markers: larger ones hold data.
+const maxDialectProbe = 64 * 1024
+
+// detectDialect detects Aurora MySQL dumps from chunk.
+func detectDialect(conv *internal.Conv, chunk string) {
+	if conv.Dialect == "" && len(chunk) <= maxDialectProbe && auroraMarkerRegexp.MatchString(chunk) {
+		conv.DetectDialect(internal.DialectAuroraMySQL)
+	}
+}
+
+// skipAurora returns true if chunk is an Aurora MySQL statement that the
+// parser doesn't parse and that is skipped.
+func skipAurora(conv *internal.Conv, chunk string) bool {
+	if conv.Dialect != internal.DialectAuroraMySQL || !strings.HasSuffix(strings.TrimSpace(chunk), ";") {
+		return false
+	}
+	m := auroraStmtRegexp.FindStringSubmatch(chunk)
+	if m == nil {
+		return false
+	}
+	stmt := strings.Join(strings.Fields(m[3]), " ")
+	if strings.HasPrefix(strings.ToUpper(stmt), "SELECT") {
+		stmt = "SELECT ... INTO OUTFILE S3"
+	}
+	conv.DialectWarning(fmt.Sprintf("skipped statement %s...", stmt))
+	return true
+}
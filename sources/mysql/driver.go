@@ -0,0 +1,167 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+)
+
+func init() {
+	registry.Register(dbDriver{})
+	registry.Register(dumpDriver{})
+}
+
+// dbDriver reads the schema and data of a MySQL database.
+type dbDriver struct{}
+
+func (dbDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.MYSQL,
+		Source:      "mysql",
+		Description: "MySQL database",
+		Streaming:   true,
+		LegacyMode:  true,
+	}
+}
+
+func (dbDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (dbDriver) TypeMap() common.TypeMap {
+	return TypeMap
+}
+
+func (dbDriver) InfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) (common.InfoSchema, error) {
+	// If the connection parameters are empty, this is called as part of the
+	// legacy mode with global CLI flags, and they are read from environment
+	// variables.
+	var dsn string
+	var err error
+	conn := sourceProfile.Conn.Mysql
+	if conn.Host != "" && conn.User != "" && conn.Db != "" {
+		dsn = profiles.GetSQLConnectionStr(sourceProfile)
+	} else if dsn, err = profiles.GenerateMYSQLConnectionStr(); err != nil {
+		return nil, err
+	}
+	db, err := dbDriver{}.open(conn, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return InfoSchemaImpl{
+		DbName:        strings.Split(dsn, ")/")[1],
+		Db:            db,
+		SourceProfile: sourceProfile,
+		TargetProfile: targetProfile,
+	}, nil
+}
+
+// open opens database dsn of conn. If conn has read replicas, connections
+// are made to them rather than to the primary while they are healthy.
+func (d dbDriver) open(conn profiles.SourceProfileConnectionMySQL, dsn string) (*sql.DB, error) {
+	if len(conn.Replicas) == 0 {
+		return sql.Open(constants.MYSQL, dsn)
+	}
+	var replicas []common.Endpoint
+	for _, r := range conn.Replicas {
+		host, port, _ := net.SplitHostPort(r)
+		replicas = append(replicas, common.Endpoint{Name: r, DSN: d.DataSourceName(host, port, conn.User, conn.Pwd, conn.Db), Replica: true})
+	}
+	primary := common.Endpoint{Name: net.JoinHostPort(conn.Host, conn.Port), DSN: dsn}
+	return common.OpenWithReplicas(constants.MYSQL, primary, replicas, time.Duration(conn.MaxReplicaLag)*time.Second, replicaLag)
+}
+
+// replicaLag returns how far behind its source the MySQL server db is
+// connected to is. Servers that don't replicate with binary logs, such as
+// Aurora replicas, have no lag.
+func replicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// Before MySQL 8.0.22.
+		if rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS"); err != nil {
+			return 0, err
+		}
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	vals := make([]sql.NullString, len(cols))
+	scanArgs := make([]interface{}, len(cols))
+	for i := range vals {
+		scanArgs[i] = &vals[i]
+	}
+	if err := rows.Scan(scanArgs...); err != nil {
+		return 0, err
+	}
+	for i, c := range cols {
+		if c == "Seconds_Behind_Source" || c == "Seconds_Behind_Master" {
+			if !vals[i].Valid {
+				return 0, fmt.Errorf("replication isn't running")
+			}
+			s, err := strconv.ParseInt(vals[i].String, 10, 64)
+			return time.Duration(s) * time.Second, err
+		}
+	}
+	return 0, fmt.Errorf("no replication lag in replica status")
+}
+
+func (dbDriver) DataSourceName(host, port, user, password, dbName string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", user, password, host, port, dbName)
+}
+
+func (dbDriver) WebInfoSchema(db *sql.DB, dbName string) common.InfoSchema {
+	return InfoSchemaImpl{DbName: dbName, Db: db}
+}
+
+// dumpDriver reads the schema and data of a mysqldump file.
+type dumpDriver struct{}
+
+func (dumpDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.MYSQLDUMP,
+		Source:      "mysql",
+		Description: "mysqldump file",
+		Dump:        true,
+		LegacyMode:  true,
+	}
+}
+
+func (dumpDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (dumpDriver) TypeMap() common.TypeMap {
+	return TypeMap
+}
+
+func (dumpDriver) DbDump() common.DbDump {
+	return DbDumpImpl{}
+}
@@ -39,11 +39,12 @@ type InfoSchemaImpl struct {
 	Db            *sql.DB
 	SourceProfile profiles.SourceProfile
 	TargetProfile profiles.TargetProfile
+	MariaDB       bool // Whether the database is a MariaDB database.
 }
 
 // GetToDdl implement the common.InfoSchema interface.
 func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
-	return ToDdlImpl{}
+	return ToDdlImpl{MariaDB: isi.MariaDB}
 }
 
 // GetTableName returns table name.
@@ -51,6 +52,16 @@ func (isi InfoSchemaImpl) GetTableName(dbName string, tableName string) string {
 	return tableName
 }
 
+// sourceTable returns the name in the source database of source table
+// srcTable, or of the table being migrated if tables are merged into
+// srcTable (see internal.MergeRule).
+func sourceTable(conv *internal.Conv, srcTable string) string {
+	if m, ok := conv.MergeMember(srcTable); ok {
+		return m.Table
+	}
+	return conv.ShardTableName(srcTable)
+}
+
 // GetRowsFromTable returns a sql Rows object for a table.
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
 	srcSchema := conv.SrcSchema[srcTable]
@@ -63,7 +74,7 @@ func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string)
 	// Ideally we would pass schema/name as a query parameter,
 	// but MySQL doesn't support this. So we quote it instead.
 	colNameList := buildColNameList(srcSchema, srcCols)
-	q := fmt.Sprintf("SELECT %s FROM `%s`.`%s`;", colNameList, conv.SrcSchema[srcTable].Schema, srcTable)
+	q := fmt.Sprintf("SELECT %s FROM `%s`.`%s`;", colNameList, isi.DbName, sourceTable(conv, srcTable))
 	rows, err := isi.Db.Query(q)
 	return rows, err
 }
@@ -89,7 +100,13 @@ func buildColNameList(srcSchema schema.Table, srcColName []string) string {
 }
 
 // ProcessData performs data conversion for source database.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	mysql := isi.SourceProfile.Conn.Mysql
+	if mysql.FetchSize > 0 || mysql.Readers > 1 {
+		if key, ok := streamKey(srcSchema); ok {
+			return isi.processDataStreaming(ctx, conv, srcTable, srcSchema, spTable, spCols, spSchema, key)
+		}
+	}
 	rowsInterface, err := isi.GetRowsFromTable(conv, srcTable)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
@@ -114,6 +131,28 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcS
 	return nil
 }
 
+// PreviewData converts at most n rows of source table srcTable.
+func (isi InfoSchemaImpl) PreviewData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, n int) ([]common.PreviewRow, error) {
+	q := fmt.Sprintf("SELECT %s FROM `%s`.`%s` LIMIT %d;", buildColNameList(srcSchema, srcSchema.ColNames), isi.DbName, sourceTable(conv, srcTable), n)
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	srcCols, _ := rows.Columns()
+	v, scanArgs := buildVals(len(srcCols))
+	var l []common.PreviewRow
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+		values := valsToStrings(v)
+		_, cvtCols, cvtVals, err := ConvertData(conv, srcTable, srcCols, srcSchema, spTable, spCols, spSchema, values)
+		l = append(l, common.PreviewRow{SrcVals: values, SpCols: cvtCols, SpVals: cvtVals, Err: err})
+	}
+	return l, rows.Err()
+}
+
 // GetRowCount with number of rows in each table.
 func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
 	// MySQL schema and name can be arbitrary strings.
@@ -133,6 +172,18 @@ func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error)
 	return 0, nil //Check if 0 is ok to return
 }
 
+// CheckReadAccess implements the common.AccessChecker interface: it queries
+// table without reading any row.
+func (isi InfoSchemaImpl) CheckReadAccess(table common.SchemaAndName) error {
+	// MySQL schema and name can be arbitrary strings, so we quote them.
+	q := fmt.Sprintf("SELECT 1 FROM `%s`.`%s` LIMIT 0;", table.Schema, table.Name)
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
 // GetTables return list of tables in the selected database.
 // Note that sql.DB already effectively has the dbName
 // embedded within it (dbName is part of the DSN passed to sql.Open),
@@ -140,6 +191,10 @@ func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error)
 func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 	// In MySQL, schema is the same as database name.
 	q := "SELECT table_name FROM information_schema.tables where table_type = 'BASE TABLE' and table_schema=?"
+	if isi.MariaDB {
+		// Only the current rows of system-versioned tables are migrated.
+		q = "SELECT table_name FROM information_schema.tables where table_type IN ('BASE TABLE', 'SYSTEM VERSIONED') and table_schema=?"
+	}
 	rows, err := isi.Db.Query(q, isi.DbName)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get tables: %w", err)
@@ -154,9 +209,39 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 	return tables, nil
 }
 
+// GetGrants returns the grants of privileges on the database (which apply to
+// all its tables), on its tables and on their columns, if the migration of
+// grants is enabled.
+func (isi InfoSchemaImpl) GetGrants() ([]common.Grant, error) {
+	if !isi.SourceProfile.Conn.Mysql.Grants {
+		return nil, nil
+	}
+	q := `SELECT '', '', grantee, privilege_type FROM information_schema.SCHEMA_PRIVILEGES WHERE table_schema = ?
+              UNION ALL SELECT table_name, '', grantee, privilege_type FROM information_schema.TABLE_PRIVILEGES WHERE table_schema = ?
+              UNION ALL SELECT table_name, column_name, grantee, privilege_type FROM information_schema.COLUMN_PRIVILEGES WHERE table_schema = ?`
+	rows, err := isi.Db.Query(q, isi.DbName, isi.DbName, isi.DbName)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get grants: %w", err)
+	}
+	defer rows.Close()
+	var grants []common.Grant
+	var table, col, grantee, privilege string
+	for rows.Next() {
+		if err := rows.Scan(&table, &col, &grantee, &privilege); err != nil {
+			return nil, err
+		}
+		g := common.Grant{Table: common.SchemaAndName{Schema: isi.DbName, Name: table}, Grantee: grantee, Privileges: []string{privilege}}
+		if col != "" {
+			g.Columns = []string{col}
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
 // GetColumns returns a list of Column objects and names// ProcessColumns
 func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
-	q := `SELECT c.column_name, c.data_type, c.column_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale, c.extra
+	q := `SELECT c.column_name, c.data_type, c.column_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale, c.extra, c.collation_name
               FROM information_schema.COLUMNS c
               where table_schema = ? and table_name = ? ORDER BY c.ordinal_position;`
 	cols, err := isi.Db.Query(q, table.Schema, table.Name)
@@ -166,10 +251,10 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 	colDefs := make(map[string]schema.Column)
 	var colNames []string
 	var colName, dataType, isNullable, columnType string
-	var colDefault, colExtra sql.NullString
+	var colDefault, colExtra, collation sql.NullString
 	var charMaxLen, numericPrecision, numericScale sql.NullInt64
 	for cols.Next() {
-		err := cols.Scan(&colName, &dataType, &columnType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &colExtra)
+		err := cols.Scan(&colName, &dataType, &columnType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &colExtra, &collation)
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
 			continue
@@ -190,17 +275,44 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 			ignored.AutoIncrement = true
 		}
 		c := schema.Column{
-			Name:    colName,
-			Type:    toType(dataType, columnType, charMaxLen, numericPrecision, numericScale),
-			NotNull: common.ToNotNull(conv, isNullable),
-			Ignored: ignored,
+			Name:      colName,
+			Type:      toType(dataType, columnType, charMaxLen, numericPrecision, numericScale),
+			NotNull:   common.ToNotNull(conv, isNullable),
+			Ignored:   ignored,
+			Collation: collation.String,
+		}
+		// MariaDB columns whose default is the next value of a sequence.
+		if m := nextvalRegexp.FindStringSubmatch(colDefault.String); isi.MariaDB && m != nil {
+			c.Sequence = &schema.Sequence{Name: m[1]}
+			c.Ignored.Default = false
 		}
 		colDefs[colName] = c
 		colNames = append(colNames, colName)
 	}
+	cols.Close()
+	for _, col := range colNames {
+		c := colDefs[col]
+		if c.Sequence == nil {
+			continue
+		}
+		if err := isi.getSequenceLastValue(table, col, c.Sequence); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't get last value of sequence %s of column %s: %v", c.Sequence.Name, col, err))
+			c.Sequence = nil
+			colDefs[col] = c
+		}
+	}
 	return colDefs, colNames, nil
 }
 
+// getSequenceLastValue sets the last value of MariaDB sequence seq, which
+// generates the values of column col of table, to the largest of the
+// values it may have generated (including those cached by sessions) and of
+// the values stored in the column.
+func (isi InfoSchemaImpl) getSequenceLastValue(table common.SchemaAndName, col string, seq *schema.Sequence) error {
+	q := fmt.Sprintf("SELECT GREATEST((SELECT next_not_cached_value - 1 FROM `%s`.`%s`), COALESCE(MAX(`%s`), 0)) FROM `%s`.`%s`", table.Schema, seq.Name, col, table.Schema, table.Name)
+	return isi.Db.QueryRow(q).Scan(&seq.LastValue)
+}
+
 // GetConstraints returns a list of primary keys and by-column map of
 // other constraints.  Note: we need to preserve ordinal order of
 // columns in primary key constraints.
@@ -360,7 +472,11 @@ func (isi InfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *s
 func toType(dataType string, columnType string, charLen sql.NullInt64, numericPrecision, numericScale sql.NullInt64) schema.Type {
 	switch {
 	case dataType == "set":
-		return schema.Type{Name: dataType, ArrayBounds: []int64{-1}}
+		return schema.Type{Name: dataType, ArrayBounds: []int64{-1}, Values: enumValues(columnType)}
+	case dataType == "enum":
+		return schema.Type{Name: dataType, Values: enumValues(columnType)}
+	case dataType == "bigint" && strings.Contains(columnType, "unsigned"):
+		return schema.Type{Name: internal.UnsignedBigint}
 	case charLen.Valid:
 		return schema.Type{Name: dataType, Mods: []int64{charLen.Int64}}
 	case dataType == "decimal" && numericPrecision.Valid && numericScale.Valid && numericScale.Int64 != 0:
@@ -372,6 +488,38 @@ func toType(dataType string, columnType string, charLen sql.NullInt64, numericPr
 	}
 }
 
+// enumValues returns the values of an enum or set column type, e.g. a and
+// b'c for enum('a','b''c').
+func enumValues(columnType string) []string {
+	start, end := strings.Index(columnType, "("), strings.LastIndex(columnType, ")")
+	if start < 0 || end < start {
+		return nil
+	}
+	var values []string
+	s := columnType[start+1 : end]
+	for len(s) > 0 && s[0] == '\'' {
+		var v strings.Builder
+		i := 1
+		for ; i < len(s); i++ {
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					v.WriteByte('\'')
+					i++
+					continue
+				}
+				break
+			}
+			v.WriteByte(s[i])
+		}
+		values = append(values, v.String())
+		if i >= len(s) {
+			break
+		}
+		s = strings.TrimPrefix(s[i+1:], ",")
+	}
+	return values
+}
+
 // buildVals constructs []sql.RawBytes value containers to scan row
 // results into.  Returns both the underlying containers (as a slice)
 // as well as an interface{} of pointers to containers to pass to
@@ -15,6 +15,7 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"testing"
@@ -70,11 +71,11 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "user"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "collation_name"},
 			rows: [][]driver.Value{
-				{"user_id", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"name", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"ref", "bigint", "bigint", "NO", nil, nil, nil, nil, nil}},
+				{"user_id", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"name", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"ref", "bigint", "bigint", "NO", nil, nil, nil, nil, nil, nil}},
 		},
 		{
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
@@ -103,11 +104,11 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "cart"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "collation_name"},
 			rows: [][]driver.Value{
-				{"productid", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"userid", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"quantity", "bigint", "bigint", "YES", nil, nil, 64, 0, nil}},
+				{"productid", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"userid", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"quantity", "bigint", "bigint", "YES", nil, nil, 64, 0, nil, nil}},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"test", "product"},
@@ -125,10 +126,10 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "product"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "collation_name"},
 			rows: [][]driver.Value{
-				{"product_id", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"product_name", "text", "text", "NO", nil, nil, nil, nil, nil}},
+				{"product_id", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"product_name", "text", "text", "NO", nil, nil, nil, nil, nil, nil}},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"test", "test"},
@@ -147,28 +148,28 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "test"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "collation_name"},
 			rows: [][]driver.Value{
-				{"id", "bigint", "bigint", "NO", nil, nil, 64, 0, nil},
-				{"s", "set", "set", "YES", nil, nil, nil, nil, nil},
-				{"txt", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"b", "boolean", "boolean", "YES", nil, nil, nil, nil, nil},
-				{"bs", "bigint", "bigint", "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0, nil},
-				{"bl", "blob", "blob", "YES", nil, nil, nil, nil, nil},
-				{"c", "char", "char(1)", "YES", nil, 1, nil, nil, nil},
-				{"c8", "char", "char(8)", "YES", nil, 8, nil, nil, nil},
-				{"d", "date", "date", "YES", nil, nil, nil, nil, nil},
-				{"dec", "decimal", "decimal(20,5)", "YES", nil, nil, 20, 5, nil},
-				{"f8", "double", "double", "YES", nil, nil, 53, nil, nil},
-				{"f4", "float", "float", "YES", nil, nil, 24, nil, nil},
-				{"i8", "bigint", "bigint", "YES", nil, nil, 64, 0, nil},
-				{"i4", "integer", "integer", "YES", nil, nil, 32, 0, "auto_increment"},
-				{"i2", "smallint", "smallint", "YES", nil, nil, 16, 0, nil},
-				{"si", "integer", "integer", "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0, nil},
-				{"ts", "datetime", "datetime", "YES", nil, nil, nil, nil, nil},
-				{"tz", "timestamp", "timestamp", "YES", nil, nil, nil, nil, nil},
-				{"vc", "varchar", "varchar", "YES", nil, nil, nil, nil, nil},
-				{"vc6", "varchar", "varchar(6)", "YES", nil, 6, nil, nil, nil}},
+				{"id", "bigint", "bigint", "NO", nil, nil, 64, 0, nil, nil},
+				{"s", "set", "set", "YES", nil, nil, nil, nil, nil, nil},
+				{"txt", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"b", "boolean", "boolean", "YES", nil, nil, nil, nil, nil, nil},
+				{"bs", "bigint", "bigint", "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0, nil, nil},
+				{"bl", "blob", "blob", "YES", nil, nil, nil, nil, nil, nil},
+				{"c", "char", "char(1)", "YES", nil, 1, nil, nil, nil, nil},
+				{"c8", "char", "char(8)", "YES", nil, 8, nil, nil, nil, nil},
+				{"d", "date", "date", "YES", nil, nil, nil, nil, nil, nil},
+				{"dec", "decimal", "decimal(20,5)", "YES", nil, nil, 20, 5, nil, nil},
+				{"f8", "double", "double", "YES", nil, nil, 53, nil, nil, nil},
+				{"f4", "float", "float", "YES", nil, nil, 24, nil, nil, nil},
+				{"i8", "bigint", "bigint", "YES", nil, nil, 64, 0, nil, nil},
+				{"i4", "integer", "integer", "YES", nil, nil, 32, 0, "auto_increment", nil},
+				{"i2", "smallint", "smallint", "YES", nil, nil, 16, 0, nil, nil},
+				{"si", "integer", "integer", "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0, nil, nil},
+				{"ts", "datetime", "datetime", "YES", nil, nil, nil, nil, nil, nil},
+				{"tz", "timestamp", "timestamp", "YES", nil, nil, nil, nil, nil, nil},
+				{"vc", "varchar", "varchar", "YES", nil, nil, nil, nil, nil, nil},
+				{"vc6", "varchar", "varchar(6)", "YES", nil, 6, nil, nil, nil, nil}},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"test", "test_ref"},
@@ -187,16 +188,16 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "test_ref"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "collation_name"},
 			rows: [][]driver.Value{
-				{"ref_id", "bigint", "bigint", "NO", nil, nil, 64, 0, nil},
-				{"ref_txt", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"abc", "text", "text", "NO", nil, nil, nil, nil, nil}},
+				{"ref_id", "bigint", "bigint", "NO", nil, nil, 64, 0, nil, nil},
+				{"ref_txt", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"abc", "text", "text", "NO", nil, nil, nil, nil, nil, nil}},
 		},
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
-	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}}
+	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}, false}
 	err := common.ProcessSchema(conv, isi)
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
@@ -320,8 +321,8 @@ func TestProcessData(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}}
-	common.ProcessData(conv, isi)
+	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}, false}
+	common.ProcessData(context.Background(), conv, isi)
 	assert.Equal(t,
 		[]spannerData{
 			spannerData{table: "te_st", cols: []string{"a_a", "Ab", "Ac_"}, vals: []interface{}{float64(42.3), int64(3), "cat"}},
@@ -333,6 +334,85 @@ func TestProcessData(t *testing.T) {
 	assert.Equal(t, int64(1), conv.Unexpecteds()) // Bad row generates an entry in unexpected.
 }
 
+func TestProcessData_Streaming(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT MIN\\(`id`\\), MAX\\(`id`\\) FROM `test`.`t`",
+			cols:  []string{"MIN(`id`)", "MAX(`id`)"},
+			rows:  [][]driver.Value{{1, 5}},
+		}, {
+			query: "SELECT (.+) FROM `test`.`t` WHERE `id` >= \\? AND `id` <= \\? ORDER BY `id` LIMIT 2",
+			args:  []driver.Value{1, 5},
+			cols:  []string{"id", "name"},
+			rows:  [][]driver.Value{{1, "cat"}, {2, "dog"}},
+		}, {
+			query: "SELECT (.+) FROM `test`.`t` WHERE `id` > \\? AND `id` <= \\? ORDER BY `id` LIMIT 2",
+			args:  []driver.Value{2, 5},
+			cols:  []string{"id", "name"},
+			rows:  [][]driver.Value{{4, "cow"}, {5, "ant"}},
+		}, {
+			query: "SELECT (.+) FROM `test`.`t` WHERE `id` > \\? AND `id` <= \\? ORDER BY `id` LIMIT 2",
+			args:  []driver.Value{5, 5},
+			cols:  []string{"id", "name"},
+			rows:  [][]driver.Value{},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := buildConv(
+		ddl.CreateTable{
+			Name:     "t",
+			ColNames: []string{"id", "name"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":   ddl.ColumnDef{Name: "id", T: ddl.Type{Name: ddl.Int64}},
+				"name": ddl.ColumnDef{Name: "name", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			}},
+		schema.Table{
+			Name:     "t",
+			Schema:   "test",
+			ColNames: []string{"id", "name"},
+			ColDefs: map[string]schema.Column{
+				"id":   schema.Column{Name: "id", Type: schema.Type{Name: "bigint"}},
+				"name": schema.Column{Name: "name", Type: schema.Type{Name: "text"}},
+			},
+			PrimaryKeys: []schema.Key{{Column: "id"}}})
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+	sourceProfile := profiles.SourceProfile{Conn: profiles.SourceProfileConnection{Mysql: profiles.SourceProfileConnectionMySQL{FetchSize: 2, Readers: 1}}}
+	isi := InfoSchemaImpl{"test", db, sourceProfile, profiles.TargetProfile{}, false}
+	common.ProcessData(context.Background(), conv, isi)
+	var names []interface{}
+	for _, r := range rows {
+		names = append(names, r.vals[1])
+	}
+	assert.Equal(t, []interface{}{"cat", "dog", "cow", "ant"}, names)
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestKeyHistogram(t *testing.T) {
+	explainCols := []string{"id", "select_type", "table", "partitions", "type", "possible_keys", "key", "key_len", "ref", "rows", "filtered", "Extra"}
+	ms := []mockSpec{
+		{
+			query: "EXPLAIN SELECT `id` FROM `test`.`t` WHERE `id` BETWEEN 1 AND 50",
+			cols:  explainCols,
+			rows:  [][]driver.Value{{1, "SIMPLE", "t", nil, "range", "PRIMARY", "PRIMARY", "8", nil, 900, 100.0, "Using where; Using index"}},
+		}, {
+			query: "EXPLAIN SELECT `id` FROM `test`.`t` WHERE `id` BETWEEN 51 AND 100",
+			cols:  explainCols,
+			rows:  [][]driver.Value{{1, "SIMPLE", nil, nil, nil, nil, nil, nil, nil, nil, nil, "Impossible WHERE"}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}, false}
+	buckets, err := isi.keyHistogram(conv, "t", "id", 1, 100, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []common.HistogramBucket{{UpperBound: 50, Rows: 900}, {UpperBound: 100, Rows: 0}}, buckets)
+}
+
 func TestProcessData_MultiCol(t *testing.T) {
 	// Tests multi-column behavior of ProcessSQLData (including
 	// handling of null columns and synthetic keys). Also tests
@@ -361,11 +441,11 @@ func TestProcessData_MultiCol(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "test"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "collation_name"},
 			rows: [][]driver.Value{
-				{"a", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"b", "double", "double", "YES", nil, nil, 53, nil, nil},
-				{"c", "bigint", "bigint", "YES", nil, nil, 64, 0, nil}},
+				{"a", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"b", "double", "double", "YES", nil, nil, 53, nil, nil, nil},
+				{"c", "bigint", "bigint", "YES", nil, nil, 64, 0, nil, nil}},
 		},
 		{
 			query: "SELECT (.+) FROM `test`.`test`",
@@ -377,7 +457,7 @@ func TestProcessData_MultiCol(t *testing.T) {
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
-	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}}
+	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}, false}
 	err := common.ProcessSchema(conv, isi)
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
@@ -402,7 +482,7 @@ func TestProcessData_MultiCol(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessData(conv, isi)
+	common.ProcessData(context.Background(), conv, isi)
 	assert.Equal(t, []spannerData{
 		{table: "test", cols: []string{"a", "b", "synth_id"}, vals: []interface{}{"cat", float64(42.3), int64(0)}},
 		{table: "test", cols: []string{"a", "c", "synth_id"}, vals: []interface{}{"dog", int64(22), int64(-9223372036854775808)}}},
@@ -410,6 +490,50 @@ func TestProcessData_MultiCol(t *testing.T) {
 	assert.Equal(t, int64(0), conv.Unexpecteds())
 }
 
+func TestPreviewData(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT `a`,`b` FROM `test`.`test` LIMIT 3",
+			cols:  []string{"a", "b"},
+			rows: [][]driver.Value{
+				{"cat", "42"},
+				{"dog", nil},
+				{"eel", "x"}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	conv.SrcSchema["test"] = schema.Table{
+		Name:     "test",
+		ColNames: []string{"a", "b"},
+		ColDefs: map[string]schema.Column{
+			"a": {Name: "a", Type: schema.Type{Name: "text"}},
+			"b": {Name: "b", Type: schema.Type{Name: "bigint"}},
+		},
+	}
+	conv.SpSchema["test"] = ddl.CreateTable{
+		Name:     "test",
+		ColNames: []string{"a", "b", "synth_id"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a":        {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"b":        {Name: "b", T: ddl.Type{Name: ddl.Int64}},
+			"synth_id": {Name: "synth_id", T: ddl.Type{Name: ddl.Int64}},
+		},
+	}
+	conv.ToSpanner["test"] = internal.NameAndCols{Name: "test", Cols: map[string]string{"a": "a", "b": "b"}}
+	conv.ToSource["test"] = internal.NameAndCols{Name: "test", Cols: map[string]string{"a": "a", "b": "b"}}
+	conv.SyntheticPKeys["test"] = internal.SyntheticPKey{Col: "synth_id"}
+	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}, false}
+	rows, err := common.PreviewData(conv, isi, "test", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, common.PreviewRow{SrcVals: []string{"cat", "42"}, SpCols: []string{"a", "b", "synth_id"}, SpVals: []interface{}{"cat", int64(42), int64(0)}}, rows[0])
+	assert.Equal(t, []string{"a", "synth_id"}, rows[1].SpCols)
+	assert.NotNil(t, rows[2].Err)
+	// conv isn't changed.
+	assert.Equal(t, int64(0), conv.SyntheticPKeys["test"].Sequence)
+}
+
 func TestSetRowStats(t *testing.T) {
 	ms := []mockSpec{
 		{
@@ -430,13 +554,43 @@ func TestSetRowStats(t *testing.T) {
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
 	conv.SetDataMode()
-	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}}
+	isi := InfoSchemaImpl{"test", db, profiles.SourceProfile{}, profiles.TargetProfile{}, false}
 	common.SetRowStats(conv, isi)
 	assert.Equal(t, int64(5), conv.Stats.Rows["test1"])
 	assert.Equal(t, int64(142), conv.Stats.Rows["test2"])
 	assert.Equal(t, int64(0), conv.Unexpecteds())
 }
 
+func TestGetGrants(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT (.+) FROM information_schema.SCHEMA_PRIVILEGES (.+) UNION ALL SELECT (.+) FROM information_schema.TABLE_PRIVILEGES (.+) UNION ALL SELECT (.+) FROM information_schema.COLUMN_PRIVILEGES (.+)",
+			args:  []driver.Value{"test", "test", "test"},
+			cols:  []string{"table_name", "column_name", "grantee", "privilege_type"},
+			rows: [][]driver.Value{
+				{"", "", "'reader'@'%'", "SELECT"},
+				{"cart", "", "'app'@'%'", "INSERT"},
+				{"cart", "qty", "'app'@'%'", "UPDATE"},
+			},
+		},
+	}
+	isi := InfoSchemaImpl{DbName: "test", Db: mkMockDB(t, ms)}
+	isi.SourceProfile.Conn.Mysql.Grants = true
+	grants, err := isi.GetGrants()
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Grant{
+		{Table: common.SchemaAndName{Schema: "test"}, Grantee: "'reader'@'%'", Privileges: []string{"SELECT"}},
+		{Table: common.SchemaAndName{Schema: "test", Name: "cart"}, Grantee: "'app'@'%'", Privileges: []string{"INSERT"}},
+		{Table: common.SchemaAndName{Schema: "test", Name: "cart"}, Columns: []string{"qty"}, Grantee: "'app'@'%'", Privileges: []string{"UPDATE"}},
+	}, grants)
+}
+
+func TestEnumValues(t *testing.T) {
+	assert.Equal(t, []string{"a", "b'c", ""}, enumValues("enum('a','b''c','')"))
+	assert.Equal(t, []string{"x,y", "z"}, enumValues("set('x,y','z')"))
+	assert.Nil(t, enumValues("enum"))
+}
+
 func mkMockDB(t *testing.T, ms []mockSpec) *sql.DB {
 	db, mock, err := sqlmock.New()
 	assert.Nil(t, err)
@@ -0,0 +1,289 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidb/parser/ast"
+	driver "github.com/pingcap/tidb/types/parser_driver"
+)
+
+func init() {
+	// MariaDB speaks the MySQL protocol: the web app opens MariaDB
+	// databases with the name of the driver.
+	sql.Register(constants.MARIADB, &mysqldriver.MySQLDriver{})
+	registry.Register(mariaDBDriver{})
+	registry.Register(mariaDBDumpDriver{})
+}
+
+// MariaDBTypeMap declares the mapping of MariaDB types to Spanner types:
+// MariaDB has the types of MySQL, and INET4, INET6 and UUID, which are
+// migrated as their text form.
+var MariaDBTypeMap = TypeMap.With(
+	common.TypeGroup{SrcTypes: []string{"inet4"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.String, 15),
+	}},
+	common.TypeGroup{SrcTypes: []string{"inet6"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.String, 45),
+	}},
+	common.TypeGroup{SrcTypes: []string{"uuid"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.String, 36),
+	}},
+)
+
+var (
+	// nextvalRegexp matches the defaults of MariaDB columns generated by a
+	// sequence, e.g. nextval(`shop`.`order_ids`).
+	nextvalRegexp = regexp.MustCompile("(?i)^nextval\\(\\s*(?:`?[^`.()]+`?\\.)?`?([^`.()]+)`?\\s*\\)$")
+	// mariaDBMarkerRegexp matches the headers of dumps made by mariadb-dump,
+	// or by mysqldump from a MariaDB server.
+	mariaDBMarkerRegexp = regexp.MustCompile(`(?i)(\bMariaDB dump\b|Server version:?\s+\S*-MariaDB)`)
+	// mariaDBTypeRegexp matches the definitions of columns with MariaDB
+	// types the parser doesn't parse.
+	mariaDBTypeRegexp = regexp.MustCompile("(?im)^(\\s*`([^`]+)`\\s+)(inet4|inet6|uuid)\\b")
+	// setvalRegexp matches the 3 argument form of SETVAL, with which
+	// mariadb-dump restores the state of sequences.
+	setvalRegexp = regexp.MustCompile(`(?is)\bSETVAL\s*\(\s*([^,()]+?)\s*,\s*(-?\d+)\s*,\s*\d+\s*(,\s*\d+\s*)?\)`)
+)
+
+// mariaDBClauses are the MariaDB clauses the parser doesn't parse, dropped
+// from statements since they have no Spanner equivalent.
+var mariaDBClauses = []struct {
+	re      *regexp.Regexp
+	warning string
+}{
+	{regexp.MustCompile(`(?i)\s+WITH(OUT)?\s+SYSTEM\s+VERSIONING\b`), "ignored system versioning: only the current rows of tables are migrated"},
+	{regexp.MustCompile(`(?i)\s+GENERATED\s+ALWAYS\s+AS\s+ROW\s+(START|END)\b`), "ignored row start and end columns of system-versioned tables"},
+	{regexp.MustCompile("(?i),\\s*PERIOD\\s+FOR\\s+`?\\w+`?\\s*\\([^)]*\\)"), "ignored PERIOD FOR clause"},
+	{regexp.MustCompile(`(?is)\s*(/\*!\d*\s*)?PARTITION\s+BY\s+SYSTEM_TIME\b[^;]*`), "ignored partitioning by SYSTEM_TIME"},
+	{regexp.MustCompile(`(?i)\s+INVISIBLE\b`), "ignored INVISIBLE: invisible columns are migrated as regular columns"},
+	{regexp.MustCompile(`(?i)\s+(PAGE_CHECKSUM|PAGE_COMPRESSED|PAGE_COMPRESSION_LEVEL|TRANSACTIONAL|IETF_QUOTES)\s*=?\s*'?\w+'?`), "ignored table option"},
+}
+
+// detectMariaDB detects MariaDB dumps from chunk, and returns true if it
+// does.
+func detectMariaDB(conv *internal.Conv, chunk string) bool {
+	if conv.Dialect == "" && len(chunk) <= maxDialectProbe && mariaDBMarkerRegexp.MatchString(chunk) {
+		conv.DetectDialect(internal.DialectMariaDB)
+		return true
+	}
+	return false
+}
+
+// mariaDBCreateTable is a CREATE TABLE statement whose columns of MariaDB
+// types the parser doesn't parse were rewritten as varchar columns.
+type mariaDBCreateTable struct {
+	*ast.CreateTableStmt
+	types map[string]string // MariaDB type of the rewritten columns, by column name.
+}
+
+// rewriteMariaDB rewrites chunk, a MariaDB statement the parser doesn't
+// parse, into one it does, and returns whether chunk was rewritten and the
+// MariaDB types of the columns whose type was rewritten.
+func rewriteMariaDB(conv *internal.Conv, chunk string) (string, bool, map[string]string) {
+	if conv.Dialect != internal.DialectMariaDB {
+		return chunk, false, nil
+	}
+	s := chunk
+	for _, c := range mariaDBClauses {
+		if c.re.MatchString(s) {
+			s = c.re.ReplaceAllString(s, "")
+			conv.DialectWarning(c.warning)
+		}
+	}
+	if setvalRegexp.MatchString(s) {
+		s = setvalRegexp.ReplaceAllString(s, "SETVAL($1, $2)")
+	}
+	var types map[string]string
+	for _, m := range mariaDBTypeRegexp.FindAllStringSubmatch(s, -1) {
+		if types == nil {
+			types = make(map[string]string)
+		}
+		types[m[2]] = strings.ToLower(m[3])
+	}
+	if types != nil {
+		s = mariaDBTypeRegexp.ReplaceAllStringFunc(s, func(def string) string {
+			m := mariaDBTypeRegexp.FindStringSubmatch(def)
+			ty, _ := MariaDBTypeMap.ToSpannerType(strings.ToLower(m[3]), "", nil)
+			return fmt.Sprintf("%svarchar(%d)", m[1], ty.Len)
+		})
+	}
+	return s, s != chunk, types
+}
+
+// processMariaDBCreateTable sets the MariaDB types of the columns of table
+// stmt in the source schema: those of the columns rewritten by
+// rewriteMariaDB, and JSON for the longtext columns MariaDB uses as JSON
+// columns (those checked with JSON_VALID).
+func processMariaDBCreateTable(conv *internal.Conv, stmt *ast.CreateTableStmt, types map[string]string) {
+	tableName, err := getTableName(stmt.Table)
+	if err != nil {
+		return
+	}
+	srcTable, ok := conv.SrcSchema[tableName]
+	if !ok {
+		return
+	}
+	for _, col := range stmt.Cols {
+		name := col.Name.Name.String()
+		cd, ok := srcTable.ColDefs[name]
+		if !ok {
+			continue
+		}
+		if ty, ok := types[name]; ok {
+			cd.Type = schema.Type{Name: ty}
+		} else if strings.HasPrefix(col.Tp.String(), "longtext") && isJSONValidCheck(col) {
+			cd.Type = schema.Type{Name: "json"}
+			cd.Ignored.Check = false
+		}
+		srcTable.ColDefs[name] = cd
+	}
+	conv.SrcSchema[tableName] = srcTable
+}
+
+// isJSONValidCheck returns true if column col is checked with JSON_VALID,
+// as MariaDB does for JSON columns.
+func isJSONValidCheck(col *ast.ColumnDef) bool {
+	for _, opt := range col.Options {
+		if f, ok := opt.Expr.(*ast.FuncCallExpr); ok && opt.Tp == ast.ColumnOptionCheck && f.FnName.L == "json_valid" {
+			return true
+		}
+	}
+	return false
+}
+
+// nextvalSequence returns the sequence of expr if it's the next value of a
+// MariaDB sequence, and "" otherwise.
+func nextvalSequence(expr ast.ExprNode) string {
+	f, ok := expr.(*ast.FuncCallExpr)
+	if !ok || f.FnName.L != "nextval" || len(f.Args) != 1 {
+		return ""
+	}
+	if t, ok := f.Args[0].(*ast.TableNameExpr); ok {
+		return t.Name.Name.String()
+	}
+	return ""
+}
+
+// setval returns the sequence and value set by stmt, if it's a SETVAL
+// statement with which mariadb-dump restores the state of sequences.
+func setval(stmt ast.StmtNode) (string, int64, bool) {
+	sel, ok := stmt.(*ast.SelectStmt)
+	if !ok || sel.Fields == nil || len(sel.Fields.Fields) != 1 {
+		return "", 0, false
+	}
+	f, ok := sel.Fields.Fields[0].Expr.(*ast.FuncCallExpr)
+	if !ok || f.FnName.L != "setval" || len(f.Args) < 2 {
+		return "", 0, false
+	}
+	seq, ok := f.Args[0].(*ast.TableNameExpr)
+	if !ok {
+		return "", 0, false
+	}
+	v, ok := f.Args[1].(*driver.ValueExpr)
+	if !ok {
+		return "", 0, false
+	}
+	return seq.Name.Name.String(), v.GetInt64(), true
+}
+
+// setSequenceLastValues sets the last values of the sequences of columns
+// to the values set by the SETVAL statements of the dump.
+func setSequenceLastValues(conv *internal.Conv, values map[string]int64) {
+	for _, t := range conv.SrcSchema {
+		for name, cd := range t.ColDefs {
+			if cd.Sequence == nil {
+				continue
+			}
+			if v, ok := values[cd.Sequence.Name]; ok && v > cd.Sequence.LastValue {
+				cd.Sequence.LastValue = v
+				t.ColDefs[name] = cd
+			}
+		}
+	}
+}
+
+// mariaDBDriver reads the schema and data of a MariaDB database.
+type mariaDBDriver struct{}
+
+func (mariaDBDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.MARIADB,
+		Source:      "mariadb",
+		Description: "MariaDB database",
+	}
+}
+
+func (mariaDBDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{MariaDB: true}
+}
+
+func (mariaDBDriver) TypeMap() common.TypeMap {
+	return MariaDBTypeMap
+}
+
+func (mariaDBDriver) InfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) (common.InfoSchema, error) {
+	is, err := dbDriver{}.InfoSchema(sourceProfile, targetProfile)
+	if err != nil {
+		return nil, err
+	}
+	isi := is.(InfoSchemaImpl)
+	isi.MariaDB = true
+	return isi, nil
+}
+
+func (mariaDBDriver) DataSourceName(host, port, user, password, dbName string) string {
+	return dbDriver{}.DataSourceName(host, port, user, password, dbName)
+}
+
+func (mariaDBDriver) WebInfoSchema(db *sql.DB, dbName string) common.InfoSchema {
+	return InfoSchemaImpl{DbName: dbName, Db: db, MariaDB: true}
+}
+
+// mariaDBDumpDriver reads the schema and data of a mariadb-dump file.
+type mariaDBDumpDriver struct{}
+
+func (mariaDBDumpDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.MARIADBDUMP,
+		Source:      "mariadb",
+		Description: "mariadb-dump file",
+		Dump:        true,
+	}
+}
+
+func (mariaDBDumpDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{MariaDB: true}
+}
+
+func (mariaDBDumpDriver) TypeMap() common.TypeMap {
+	return MariaDBTypeMap
+}
+
+func (mariaDBDumpDriver) DbDump() common.DbDump {
+	return DbDumpImpl{MariaDB: true}
+}
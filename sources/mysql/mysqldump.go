@@ -27,6 +27,7 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/parser/opcode"
 	"github.com/pingcap/tidb/types"
 	driver "github.com/pingcap/tidb/types/parser_driver"
@@ -49,15 +50,20 @@ var spatialIndexRegex = regexp.MustCompile("(?i)\\sSPATIAL\\s")
 var spatialSridRegex = regexp.MustCompile("(?i)\\sSRID\\s\\d*")
 
 // DbDumpImpl MySQL specific implementation for DdlDumpImpl.
-type DbDumpImpl struct{}
+type DbDumpImpl struct {
+	MariaDB bool // Whether the dump is a dump of a MariaDB database.
+}
 
 // GetToDdl function below implement the common.DbDump interface.
 func (ddi DbDumpImpl) GetToDdl() common.ToDdl {
-	return ToDdlImpl{}
+	return ToDdlImpl{MariaDB: ddi.MariaDB}
 }
 
 // ProcessDump processes the mysql dump.
 func (ddi DbDumpImpl) ProcessDump(conv *internal.Conv, r *internal.Reader) error {
+	if ddi.MariaDB && conv.Dialect == "" {
+		conv.Dialect = internal.DialectMariaDB
+	}
 	return processMySQLDump(conv, r)
 }
 
@@ -67,6 +73,7 @@ func (ddi DbDumpImpl) ProcessDump(conv *internal.Conv, r *internal.Reader) error
 // In data mode, ProcessMySQLDump uses this schema to convert MySQL data
 // and writes it to Spanner, using the data sink specified in conv.
 func processMySQLDump(conv *internal.Conv, r *internal.Reader) error {
+	sequences := make(map[string]int64) // Values set by MariaDB SETVAL statements, by sequence.
 	for {
 		startLine := r.LineNumber
 		startOffset := r.Offset
@@ -75,6 +82,9 @@ func processMySQLDump(conv *internal.Conv, r *internal.Reader) error {
 			return err
 		}
 		for _, stmt := range stmts {
+			if seq, v, ok := setval(stmt); ok {
+				sequences[seq] = v
+			}
 			isInsert := processStatement(conv, stmt)
 			internal.VerbosePrintf("Parsed SQL command at line=%d/fpos=%d: %d stmts (%d lines, %d bytes) Insert Statement=%v\n", startLine, startOffset, 1, r.LineNumber-startLine, len(b), isInsert)
 			logger.Log.Debug(fmt.Sprintf("Parsed SQL command at line=%d/fpos=%d: %d stmts (%d lines, %d bytes) Insert Statement=%v\n", startLine, startOffset, 1, r.LineNumber-startLine, len(b), isInsert))
@@ -83,6 +93,9 @@ func processMySQLDump(conv *internal.Conv, r *internal.Reader) error {
 			break
 		}
 	}
+	if conv.SchemaMode() && len(sequences) > 0 {
+		setSequenceLastValues(conv, sequences)
+	}
 	return nil
 }
 
@@ -117,6 +130,9 @@ func readAndParseChunk(conv *internal.Conv, r *internal.Reader) ([]byte, []ast.S
 				n += copy(s[n:], l[i])
 			}
 			chunk := string(s)
+			if !detectMariaDB(conv, chunk) {
+				detectDialect(conv, chunk)
+			}
 			matchStatus := regexExp.Match([]byte(chunk))
 			if matchStatus {
 				fmt.Printf("\nParsing skipped for: %s\n", chunk)
@@ -130,6 +146,19 @@ func readAndParseChunk(conv *internal.Conv, r *internal.Reader) ([]byte, []ast.S
 			if ok {
 				return s, newTree, nil
 			}
+			if skipAurora(conv, chunk) {
+				return s, nil, nil
+			}
+			if rewritten, ok, types := rewriteMariaDB(conv, chunk); ok {
+				if tree, _, err := parser.New().Parse(rewritten, "", ""); err == nil {
+					for i, stmt := range tree {
+						if ct, ok := stmt.(*ast.CreateTableStmt); ok && types != nil {
+							tree[i] = &mariaDBCreateTable{CreateTableStmt: ct, types: types}
+						}
+					}
+					return s, tree, nil
+				}
+			}
 			// Likely causes of failing to parse:
 			// a) complex statements with embedded semicolons e.g. 'CREATE FUNCTION'
 			// b) a semicolon embedded in a multi-line comment, or
@@ -151,6 +180,14 @@ func processStatement(conv *internal.Conv, stmt ast.StmtNode) bool {
 	case *ast.CreateTableStmt:
 		if conv.SchemaMode() {
 			processCreateTable(conv, s)
+			if conv.Dialect == internal.DialectMariaDB {
+				processMariaDBCreateTable(conv, s, nil)
+			}
+		}
+	case *mariaDBCreateTable:
+		if conv.SchemaMode() {
+			processCreateTable(conv, s.CreateTableStmt)
+			processMariaDBCreateTable(conv, s.CreateTableStmt, s.types)
 		}
 	case *ast.AlterTableStmt:
 		if conv.SchemaMode() {
@@ -263,6 +300,18 @@ func processCreateTable(conv *internal.Conv, stmt *ast.CreateTableStmt) {
 			index = append(index, schema.Index{Name: "", Unique: true, Keys: []schema.Key{schema.Key{Column: colname, Desc: false}}})
 		}
 	}
+	// String columns without a collation use the table's default collation.
+	for _, opt := range stmt.Options {
+		if opt.Tp != ast.TableOptionCollate {
+			continue
+		}
+		for n, c := range colDef {
+			if c.Collation == "" && stringTypes[c.Type.Name] {
+				c.Collation = opt.StrValue
+				colDef[n] = c
+			}
+		}
+	}
 	conv.SchemaStatement(NodeType(stmt))
 	conv.SrcSchema[tableName] = schema.Table{
 		Name:        tableName,
@@ -276,6 +325,11 @@ func processCreateTable(conv *internal.Conv, stmt *ast.CreateTableStmt) {
 	}
 }
 
+// stringTypes are the MySQL types whose values have a collation.
+var stringTypes = map[string]bool{
+	"char": true, "varchar": true, "tinytext": true, "text": true, "mediumtext": true, "longtext": true, "enum": true, "set": true,
+}
+
 func processConstraint(conv *internal.Conv, table string, constraint *ast.Constraint, stmtType string) {
 	st := conv.SrcSchema[table]
 	switch ct := constraint.Tp; ct {
@@ -440,10 +494,16 @@ func processColumn(conv *internal.Conv, tableName string, col *ast.ColumnDef) (s
 		return "", schema.Column{}, columnConstraint{}, fmt.Errorf("can't get column type for %s: %w", name, fmt.Errorf("found nil *ast.ColumnDef.Tp"))
 	}
 	tid, mods := getTypeModsAndID(conv, col.Tp.String())
+	if tid == "bigint" && mysql.HasUnsignedFlag(col.Tp.Flag) {
+		tid, mods = internal.UnsignedBigint, nil
+	}
 	ty := schema.Type{
 		Name:        tid,
 		Mods:        mods,
 		ArrayBounds: getArrayBounds(col.Tp.String(), col.Tp.Elems)}
+	if tid == "set" || tid == "enum" {
+		ty.Values = col.Tp.Elems
+	}
 	column := schema.Column{Name: name, Type: ty}
 	return name, column, updateColsByOption(conv, tableName, col, &column), nil
 }
@@ -476,13 +536,18 @@ func updateColsByOption(conv *internal.Conv, tableName string, col *ast.ColumnDe
 			// This case is ignored from issue reporting of 'Default' value.
 			v, ok := elem.Expr.(*driver.ValueExpr)
 			nullDefault := ok && v.GetValue() == nil
-			if !nullDefault {
+			if seq := nextvalSequence(elem.Expr); seq != "" {
+				// MariaDB column generated by a sequence.
+				column.Sequence = &schema.Sequence{Name: seq}
+			} else if !nullDefault {
 				column.Ignored.Default = true
 			}
 		case ast.ColumnOptionUniqKey:
 			cc.isUniqueKey = true
 		case ast.ColumnOptionCheck:
 			column.Ignored.Check = true
+		case ast.ColumnOptionCollate:
+			column.Collation = elem.StrValue
 		case ast.ColumnOptionReference:
 			column := col.Name.String()
 			referTable, err := getTableName(elem.Refer.Table)
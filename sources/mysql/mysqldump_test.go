@@ -25,6 +25,7 @@ import (
 
 	"cloud.google.com/go/spanner"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 	"github.com/stretchr/testify/assert"
@@ -70,6 +71,33 @@ func TestProcessMySQLDump_Scalar(t *testing.T) {
 	}
 }
 
+func TestProcessMySQLDump_UnsignedBigint(t *testing.T) {
+	conv, rows := runProcessMySQLDump("CREATE TABLE t (id bigint(20) unsigned NOT NULL, PRIMARY KEY (id));\n" +
+		"INSERT INTO t VALUES (1),(18446744073709551615);\n")
+	assert.Equal(t, schema.Type{Name: internal.UnsignedBigint}, conv.SrcSchema["t"].ColDefs["id"].Type)
+	assert.Equal(t, ddl.Type{Name: ddl.Int64}, conv.SpSchema["t"].ColDefs["id"].T)
+	assert.Equal(t, []internal.SchemaIssue{internal.UnsignedOverflow}, conv.Issues["t"]["id"])
+	assert.Equal(t, []spannerData{spannerData{table: "t", cols: []string{"id"}, vals: []interface{}{int64(1)}}}, rows)
+	assert.Equal(t, int64(1), conv.Stats.BadRows["t"])
+	assert.Equal(t, map[string]int64{"t": 1}, conv.Stats.UnsignedOverflow)
+}
+
+func TestProcessMySQLDump_EnumValues(t *testing.T) {
+	conv, _ := runProcessMySQLDump("CREATE TABLE t (e enum('a','b''c'), s set('x','y'));")
+	assert.Equal(t, []string{"a", "b'c"}, conv.SrcSchema["t"].ColDefs["e"].Type.Values)
+	assert.Equal(t, []string{"x", "y"}, conv.SrcSchema["t"].ColDefs["s"].Type.Values)
+}
+
+func TestProcessMySQLDump_Collation(t *testing.T) {
+	conv, _ := runProcessMySQLDump("CREATE TABLE t (id varchar(10) NOT NULL, code varchar(5) COLLATE utf8mb4_bin, name varchar(20), n int, PRIMARY KEY (id), UNIQUE KEY uk (code), KEY idx (name)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;")
+	assert.Equal(t, "utf8mb4_0900_ai_ci", conv.SrcSchema["t"].ColDefs["id"].Collation)
+	assert.Equal(t, "utf8mb4_bin", conv.SrcSchema["t"].ColDefs["code"].Collation)
+	assert.Equal(t, "", conv.SrcSchema["t"].ColDefs["n"].Collation)
+	assert.Equal(t, []internal.SchemaIssue{internal.CollationUnique}, conv.Issues["t"]["id"])
+	assert.Empty(t, conv.Issues["t"]["code"])
+	assert.Equal(t, []internal.SchemaIssue{internal.CollationOrder}, conv.Issues["t"]["name"])
+}
+
 func TestProcessMySQLDump_SingleCol(t *testing.T) {
 	// Test array types and not null.
 	singleColTests := []struct {
@@ -907,6 +935,93 @@ func TestProcessMySQLDump_AddPrimaryKeys(t *testing.T) {
 	}
 }
 
+func TestProcessMySQLDump_Aurora(t *testing.T) {
+	conv, rows := runProcessMySQLDump("-- MySQL dump 10.13  Distrib 8.0.28, for Linux (x86_64)\n" +
+		"--\n" +
+		"-- Host: shop.cluster-c9akciq32.us-east-1.rds.amazonaws.com    Database: shop\n" +
+		"-- ------------------------------------------------------\n" +
+		"/*!40101 SET NAMES utf8mb4 */;\n" +
+		"CREATE TABLE cart (productid varchar(20) NOT NULL, quantity bigint, PRIMARY KEY (productid));\n" +
+		"LOAD DATA FROM S3 's3://bucket/cart.csv' INTO TABLE cart FIELDS TERMINATED BY ',';\n" +
+		"SELECT * FROM cart INTO OUTFILE S3 's3://bucket/cart' FORMAT CSV;\n" +
+		"INSERT INTO cart VALUES ('p1', 2);\n")
+	assert.Equal(t, internal.DialectAuroraMySQL, conv.Dialect)
+	assert.Equal(t, []string{"productid", "quantity"}, conv.SpSchema["cart"].ColNames)
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, map[string]int64{
+		"Aurora MySQL: skipped statement LOAD DATA FROM S3...":          1,
+		"Aurora MySQL: skipped statement SELECT ... INTO OUTFILE S3...": 1,
+	}, conv.Stats.Unexpected)
+
+	// Aurora statements are not skipped in other dumps.
+	conv = internal.MakeConv()
+	conv.SetSchemaMode()
+	assert.False(t, skipAurora(conv, "LOAD DATA FROM S3 's3://bucket/cart.csv' INTO TABLE cart;\n"))
+}
+
+func TestProcessMySQLDump_MariaDB(t *testing.T) {
+	conv, rows := runProcessMySQLDump("-- MariaDB dump 10.19  Distrib 10.6.12-MariaDB, for debian-linux-gnu (x86_64)\n" +
+		"--\n" +
+		"-- Host: localhost    Database: shop\n" +
+		"-- ------------------------------------------------------\n" +
+		"-- Server version\t10.6.12-MariaDB-0ubuntu0.22.04.1\n" +
+		"CREATE SEQUENCE `order_ids` start with 1 minvalue 1 maxvalue 9223372036854775806 increment by 1 cache 1000 nocycle ENGINE=InnoDB;\n" +
+		"SELECT SETVAL(`order_ids`, 2001, 0);\n" +
+		"CREATE TABLE `orders` (\n" +
+		"  `id` bigint(20) NOT NULL DEFAULT nextval(`shop`.`order_ids`),\n" +
+		"  `customer` uuid NOT NULL,\n" +
+		"  `ip` inet6 DEFAULT NULL,\n" +
+		"  `details` longtext CHARACTER SET utf8mb4 COLLATE utf8mb4_bin DEFAULT NULL CHECK (json_valid(`details`)),\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 PAGE_CHECKSUM=1 WITH SYSTEM VERSIONING;\n" +
+		"INSERT INTO `orders` VALUES (1,'7e8a1c2e-5b1d-11ee-8c99-0242ac120002','::1','{\\\"a\\\": 1}');\n")
+	assert.Equal(t, internal.DialectMariaDB, conv.Dialect)
+	assert.Equal(t, []string{"id", "customer", "ip", "details"}, conv.SpSchema["orders"].ColNames)
+	cds := conv.SpSchema["orders"].ColDefs
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 36}, cds["customer"].T)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 45}, cds["ip"].T)
+	assert.Equal(t, ddl.Type{Name: ddl.JSON}, cds["details"].T)
+	assert.Equal(t, &ddl.Sequence{Name: "orders_id_seq", StartWithCounter: 2002, SkipRangeMin: 1, SkipRangeMax: 2001}, cds["id"].Sequence)
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, map[string]int64{
+		"MariaDB: ignored system versioning: only the current rows of tables are migrated": 1,
+		"MariaDB: ignored table option": 1,
+	}, conv.Stats.Unexpected)
+}
+
+func TestRewriteMariaDB(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SetSchemaMode()
+	conv.Dialect = internal.DialectMariaDB
+	s, ok, types := rewriteMariaDB(conv, "CREATE TABLE `t` (\n"+
+		"  `a` int NOT NULL,\n"+
+		"  `b` inet4 INVISIBLE,\n"+
+		"  `start` timestamp(6) GENERATED ALWAYS AS ROW START,\n"+
+		"  `end` timestamp(6) GENERATED ALWAYS AS ROW END,\n"+
+		"  PRIMARY KEY (`a`),\n"+
+		"  PERIOD FOR SYSTEM_TIME (`start`, `end`)\n"+
+		") WITH SYSTEM VERSIONING\n"+
+		" PARTITION BY SYSTEM_TIME INTERVAL 1 MONTH PARTITIONS 12;\n")
+	assert.True(t, ok)
+	assert.Equal(t, "CREATE TABLE `t` (\n"+
+		"  `a` int NOT NULL,\n"+
+		"  `b` varchar(15),\n"+
+		"  `start` timestamp(6),\n"+
+		"  `end` timestamp(6),\n"+
+		"  PRIMARY KEY (`a`)\n"+
+		");\n", s)
+	assert.Equal(t, map[string]string{"b": "inet4"}, types)
+
+	s, ok, _ = rewriteMariaDB(conv, "SELECT SETVAL(`s`, 1001, 0);\n")
+	assert.True(t, ok)
+	assert.Equal(t, "SELECT SETVAL(`s`, 1001);\n", s)
+
+	// Other dumps are not rewritten.
+	conv.Dialect = ""
+	_, ok, _ = rewriteMariaDB(conv, "CREATE TABLE t (a int) WITH SYSTEM VERSIONING;\n")
+	assert.False(t, ok)
+}
+
 func runProcessMySQLDump(s string) (*internal.Conv, []spannerData) {
 	conv := internal.MakeConv()
 	conv.SetLocation(time.UTC)
@@ -110,6 +110,15 @@ Name Changes in Migration
 -----------------------------------------------------------------------------------------------------
 
 
+----------------------------
+Schema Issues by Severity
+----------------------------
+Errors: none
+Warnings: 1
+  DefaultValue (1): default_value.b
+Info: 3
+  Widened (3): bad_schema.a, bad_schema.b, no_pk.b
+
 ----------------------------
 Table bad_schema
 ----------------------------
@@ -0,0 +1,241 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// streamRow is a row read by one of the readers of a table, or the error
+// that kept it from being read.
+type streamRow struct {
+	vals []string
+	err  error
+}
+
+// streamKey returns the column by which table srcSchema can be read in chunks
+// and key ranges: its primary key, if it is a single integer column.
+func streamKey(srcSchema schema.Table) (string, bool) {
+	if len(srcSchema.PrimaryKeys) != 1 {
+		return "", false
+	}
+	col := srcSchema.PrimaryKeys[0].Column
+	switch strings.ToLower(srcSchema.ColDefs[col].Type.Name) {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		return col, true
+	}
+	return "", false
+}
+
+// processDataStreaming converts the data of source table srcTable, reading it
+// by key, the single integer primary key column of the table: the keys of the
+// table are split into one range per reader of the source profile, and each
+// range is read in chunks of FetchSize rows ordered by key on its own
+// connection. Rows are converted one at a time as they are read.
+func (isi InfoSchemaImpl) processDataStreaming(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, key string) error {
+	var lo, hi sql.NullInt64
+	q := fmt.Sprintf("SELECT MIN(`%s`), MAX(`%s`) FROM `%s`.`%s`;", key, key, isi.DbName, sourceTable(conv, srcTable))
+	if err := isi.Db.QueryRow(q).Scan(&lo, &hi); err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't get key range of table %s : err = %s", srcTable, err))
+		return err
+	}
+	if !lo.Valid {
+		// The table is empty.
+		return nil
+	}
+	readers := isi.SourceProfile.Conn.Mysql.Readers
+	var buckets []common.HistogramBucket
+	if readers > 1 {
+		var err error
+		if buckets, err = isi.keyHistogram(conv, srcTable, key, lo.Int64, hi.Int64, readers*histogramProbesPerReader); err != nil {
+			internal.VerbosePrintf("Couldn't estimate the distribution of the keys of table %s: %s\n", srcTable, err)
+		}
+	}
+	ranges, estimates := common.PlanKeyRanges(lo.Int64, hi.Int64, buckets, readers)
+	internal.VerbosePrintln(common.DescribeKeyRanges(srcTable, ranges, estimates))
+	rows := make(chan streamRow, 1024)
+	errs := make(chan error, len(ranges))
+	var wg sync.WaitGroup
+	for _, r := range ranges {
+		wg.Add(1)
+		go func(r [2]int64) {
+			defer wg.Done()
+			errs <- isi.readKeyRange(ctx, conv, srcTable, srcSchema, key, r, rows)
+		}(r)
+	}
+	go func() {
+		wg.Wait()
+		close(rows)
+		close(errs)
+	}()
+	for row := range rows {
+		if row.err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", row.err))
+			// Scan failed, so we don't have any data to add to bad rows.
+			conv.StatsAddBadRow(srcTable, conv.DataMode())
+			continue
+		}
+		ProcessDataRow(conv, srcTable, srcSchema.ColNames, srcSchema, spTable, spCols, spSchema, row.vals)
+	}
+	for err := range errs {
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
+			return err
+		}
+	}
+	return nil
+}
+
+// histogramProbesPerReader is the number of ranges of keys per reader whose
+// row count is estimated to plan the ranges read by each reader.
+const histogramProbesPerReader = 16
+
+// keyHistogram estimates the distribution of the keys from lo to hi of table
+// srcTable by splitting them into n ranges of similar size and getting the
+// optimizer's estimate of the rows of each, which MySQL computes from dives
+// into the primary key index without reading the rows. MySQL doesn't keep
+// histograms of columns of unique indexes, such as primary keys.
+func (isi InfoSchemaImpl) keyHistogram(conv *internal.Conv, srcTable, key string, lo, hi int64, n int) ([]common.HistogramBucket, error) {
+	var buckets []common.HistogramBucket
+	for _, r := range common.KeyRanges(lo, hi, n) {
+		q := fmt.Sprintf("EXPLAIN SELECT `%s` FROM `%s`.`%s` WHERE `%s` BETWEEN %d AND %d;", key, isi.DbName, sourceTable(conv, srcTable), key, r[0], r[1])
+		rows, err := explainRows(isi.Db, q)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, common.HistogramBucket{UpperBound: r[1], Rows: rows})
+	}
+	return buckets, nil
+}
+
+// explainRows returns the estimated number of rows of the first step of the
+// plan of query q, which must be an EXPLAIN statement.
+func explainRows(db *sql.DB, q string) (float64, error) {
+	rows, err := db.Query(q)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	idx := -1
+	for i, c := range cols {
+		if strings.EqualFold(c, "rows") {
+			idx = i
+		}
+	}
+	if idx < 0 || !rows.Next() {
+		return 0, fmt.Errorf("no row estimate in plan of %s", q)
+	}
+	v, scanArgs := buildVals(len(cols))
+	if err := rows.Scan(scanArgs...); err != nil {
+		return 0, err
+	}
+	if v[idx] == nil {
+		// No rows match, e.g. "Impossible WHERE noticed after reading const tables".
+		return 0, nil
+	}
+	return strconv.ParseFloat(string(v[idx]), 64)
+}
+
+// readKeyRange reads the rows of source table srcTable whose key is in range
+// r, in chunks of FetchSize rows (or all at once if FetchSize is 0), and sends
+// them to rows. Each chunk starts after the last key of the previous one, so
+// that no query holds a long-running result set open.
+func (isi InfoSchemaImpl) readKeyRange(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, key string, r [2]int64, rows chan<- streamRow) error {
+	keyIdx := -1
+	for i, c := range srcSchema.ColNames {
+		if c == key {
+			keyIdx = i
+		}
+	}
+	fetchSize := isi.SourceProfile.Conn.Mysql.FetchSize
+	failovers := len(isi.SourceProfile.Conn.Mysql.Replicas)
+	start, op := r[0], ">="
+	for {
+		if op == ">" {
+			// Chunks are checkpoints: pause between them outside the migration windows.
+			if err := conv.Schedule.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		q := fmt.Sprintf("SELECT %s FROM `%s`.`%s` WHERE `%s` %s ? AND `%s` <= ? ORDER BY `%s`",
+			buildColNameList(srcSchema, srcSchema.ColNames), isi.DbName, sourceTable(conv, srcTable), key, op, key, key)
+		if fetchSize > 0 {
+			q += fmt.Sprintf(" LIMIT %d", fetchSize)
+		}
+		n, last, ok, err := isi.readChunk(q+";", start, r[1], keyIdx, len(srcSchema.ColNames), rows)
+		if err != nil && failovers > 0 && (n == 0 || ok) {
+			// The connection to a replica failed: resume after the last row
+			// read, on the endpoint reads fail over to.
+			failovers--
+			if n > 0 {
+				start, op = last, ">"
+			}
+			internal.VerbosePrintf("Resuming the read of table %s at key %s %d: %s\n", srcTable, op, start, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if fetchSize == 0 || n < fetchSize {
+			return nil
+		}
+		if !ok {
+			return fmt.Errorf("couldn't read the key of row %d of a chunk starting at key %d", n, start)
+		}
+		start, op = last, ">"
+	}
+}
+
+// readChunk runs query q with the bounds of a key range and sends the rows
+// read to rows. It returns the number of rows read and the key of the last
+// one, if that row could be read.
+func (isi InfoSchemaImpl) readChunk(q string, start, end int64, keyIdx, ncols int, rows chan<- streamRow) (int, int64, bool, error) {
+	rs, err := isi.Db.Query(q, start, end)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer rs.Close()
+	v, scanArgs := buildVals(ncols)
+	var n int
+	var last int64
+	var ok bool
+	for rs.Next() {
+		n++
+		if err := rs.Scan(scanArgs...); err != nil {
+			rows <- streamRow{err: err}
+			ok = false
+			continue
+		}
+		vals := valsToStrings(v)
+		last, err = strconv.ParseInt(vals[keyIdx], 10, 64)
+		ok = err == nil
+		rows <- streamRow{vals: vals}
+	}
+	return n, last, ok, rs.Err()
+}
@@ -19,11 +19,13 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
 // ToDdlImpl MySQL specific implementation for ToDdl.
 type ToDdlImpl struct {
+	MariaDB bool // Whether the source is MariaDB, whose types are mapped with MariaDBTypeMap.
 }
 
 // ToSpannerType maps a scalar source schema type (defined by id and
@@ -32,7 +34,11 @@ type ToDdlImpl struct {
 // conversion issues encountered.
 // Functions below implement the common.ToDdl interface
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
-	ty, issues := toSpannerTypeInternal(conv, columnType.Name, columnType.Mods)
+	typeMap := TypeMap
+	if tdi.MariaDB || conv.Dialect == internal.DialectMariaDB {
+		typeMap = MariaDBTypeMap
+	}
+	ty, issues := typeMap.ToSpannerType(columnType.Name, "", columnType.Mods)
 	if conv.TargetDb == constants.TargetExperimentalPostgres {
 		ty = overrideExperimentalType(columnType, ty)
 	} else {
@@ -45,61 +51,102 @@ func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type)
 	return ty, issues
 }
 
-func toSpannerTypeInternal(conv *internal.Conv, id string, mods []int64) (ddl.Type, []internal.SchemaIssue) {
-	switch id {
-	case "bool", "boolean":
-		return ddl.Type{Name: ddl.Bool}, nil
-	case "tinyint":
-		// tinyint(1) is a bool in MySQL
-		if len(mods) > 0 && mods[0] == 1 {
-			return ddl.Type{Name: ddl.Bool}, nil
-		}
-		return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}
-	case "double":
-		return ddl.Type{Name: ddl.Float64}, nil
-	case "float":
-		return ddl.Type{Name: ddl.Float64}, []internal.SchemaIssue{internal.Widened}
-	case "numeric", "decimal":
-		// MySQL's NUMERIC type can store up to 65 digits, with up to 30 after the
-		// the decimal point. Spanner's NUMERIC type can store up to 29 digits before the
-		// decimal point and up to 9 after the decimal point -- it is equivalent to
-		// MySQL's NUMERIC(38,9) type.
-		//
-		// TODO: Generate appropriate SchemaIssue to warn of different precision
-		// capabilities between MySQL and Spanner NUMERIC.
-		return ddl.Type{Name: ddl.Numeric}, nil
-	case "bigint":
-		return ddl.Type{Name: ddl.Int64}, nil
-	case "smallint", "mediumint", "integer", "int":
-		return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}
-	case "bit":
-		return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
-	case "varchar", "char":
-		if len(mods) > 0 {
-			return ddl.Type{Name: ddl.String, Len: mods[0]}, nil
-		}
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "text", "tinytext", "mediumtext", "longtext":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "set", "enum":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "json":
-		return ddl.Type{Name: ddl.JSON}, nil
-	case "binary", "varbinary":
-		return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
-	case "tinyblob", "mediumblob", "blob", "longblob":
-		return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
-	case "date":
-		return ddl.Type{Name: ddl.Date}, nil
-	case "datetime":
-		return ddl.Type{Name: ddl.Timestamp}, []internal.SchemaIssue{internal.Datetime}
-	case "timestamp":
-		return ddl.Type{Name: ddl.Timestamp}, nil
-	case "time", "year":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Time}
-	}
-	return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
-}
+// TypeMap declares the mapping of MySQL types to Spanner types.
+var TypeMap = common.NewTypeMap(
+	common.TypeGroup{SrcTypes: []string{"bool", "boolean"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Bool}),
+		Options: map[string]common.MapFunc{
+			ddl.String: common.To(common.MaxString, internal.Widened),
+			ddl.Int64:  common.To(ddl.Type{Name: ddl.Int64}, internal.Widened),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"tinyint"}, TypeMapping: common.TypeMapping{
+		Default: func(mods []int64) (ddl.Type, []internal.SchemaIssue) {
+			// tinyint(1) is a bool in MySQL
+			if len(mods) > 0 && mods[0] == 1 {
+				return ddl.Type{Name: ddl.Bool}, nil
+			}
+			return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}
+		},
+		Options: map[string]common.MapFunc{
+			ddl.String: common.To(common.MaxString, internal.Widened),
+			ddl.Int64:  common.To(ddl.Type{Name: ddl.Int64}, internal.Widened),
+		},
+		Conditional: map[string]string{ddl.Bool: "Only tinyint(1) can be converted to BOOL, for any other mods it will be converted to INT64"},
+	}},
+	common.TypeGroup{SrcTypes: []string{"double"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Float64}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"float"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Float64}, internal.Widened),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	// MySQL's NUMERIC type can store up to 65 digits, with up to 30 after the
+	// the decimal point. Spanner's NUMERIC type can store up to 29 digits before the
+	// decimal point and up to 9 after the decimal point -- it is equivalent to
+	// MySQL's NUMERIC(38,9) type.
+	//
+	// TODO: Generate appropriate SchemaIssue to warn of different precision
+	// capabilities between MySQL and Spanner NUMERIC.
+	common.TypeGroup{SrcTypes: []string{"numeric", "decimal"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Numeric}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"bigint"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	// Values of unsigned bigints can be larger than the maximum INT64 value.
+	common.TypeGroup{SrcTypes: []string{internal.UnsignedBigint}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}, internal.UnsignedOverflow),
+		Options: map[string]common.MapFunc{
+			ddl.Numeric: common.To(ddl.Type{Name: ddl.Numeric}),
+			ddl.String:  common.To(common.MaxString, internal.Widened),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"smallint", "mediumint", "integer", "int"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}, internal.Widened),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"bit", "binary", "varbinary", "tinyblob", "mediumblob", "blob", "longblob"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxBytes),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"varchar", "char"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.String, ddl.MaxLength),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.ToSized(ddl.Bytes, ddl.MaxLength)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"text", "tinytext", "mediumtext", "longtext"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.To(common.MaxBytes)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"set", "enum"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+	}},
+	common.TypeGroup{SrcTypes: []string{"json"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.JSON}),
+		Options: map[string]common.MapFunc{
+			ddl.Bytes:  common.To(common.MaxBytes),
+			ddl.String: common.To(common.MaxString),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"date"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Date}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"datetime"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Timestamp}, internal.Datetime),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"timestamp"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Timestamp}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"time", "year"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString, internal.Time),
+	}},
+)
 
 // Override the types to map to experimental postgres types.
 func overrideExperimentalType(columnType schema.Type, originalType ddl.Type) ddl.Type {
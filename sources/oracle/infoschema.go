@@ -99,7 +99,7 @@ func getSelectQuery(srcDb string, schemaName string, tableName string, colNames
 }
 
 // ProcessData performs data conversion for source database.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
 	rowsInterface, err := isi.GetRowsFromTable(conv, srcTable)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
@@ -124,6 +124,28 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcS
 	return nil
 }
 
+// PreviewData converts at most n rows of source table srcTable.
+func (isi InfoSchemaImpl) PreviewData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, n int) ([]common.PreviewRow, error) {
+	q := getSelectQuery(isi.DbName, srcSchema.Schema, srcSchema.Name, srcSchema.ColNames, srcSchema.ColDefs)
+	rows, err := isi.Db.Query(fmt.Sprintf("%s FETCH FIRST %d ROWS ONLY", q, n))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	srcCols, _ := rows.Columns()
+	v, scanArgs := buildVals(len(srcCols))
+	var l []common.PreviewRow
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+		values := valsToStrings(v)
+		_, cvtCols, cvtVals, err := convertData(conv, srcTable, srcCols, srcSchema, spTable, spCols, spSchema, values)
+		l = append(l, common.PreviewRow{SrcVals: values, SpCols: cvtCols, SpVals: cvtVals, Err: err})
+	}
+	return l, rows.Err()
+}
+
 // GetRowCount with number of rows in each table.
 func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
 	q := fmt.Sprintf(`SELECT count(*) FROM "%s"`, table.Name)
@@ -140,8 +162,24 @@ func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error)
 	return 0, nil
 }
 
+// CheckReadAccess implements the common.AccessChecker interface: it queries
+// table without reading any row.
+func (isi InfoSchemaImpl) CheckReadAccess(table common.SchemaAndName) error {
+	q := fmt.Sprintf(`SELECT 1 FROM "%s"."%s" WHERE 1 = 0`, table.Schema, table.Name)
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
 func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 	q := fmt.Sprintf("SELECT table_name FROM all_tables WHERE owner = '%s'", isi.DbName)
+	// The rows of materialized views are stored in tables with the same name
+	// as the view, which are only migrated if views are converted into tables.
+	if isi.SourceProfile.Conn.Oracle.MaterializedViews != internal.MaterializedViewTable {
+		q += fmt.Sprintf(" AND table_name NOT IN (SELECT mview_name FROM all_mviews WHERE owner = '%s')", isi.DbName)
+	}
 	rows, err := isi.Db.Query(q)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get tables: %w", err)
@@ -156,6 +194,25 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 	return tables, nil
 }
 
+// GetMaterializedViews returns the materialized views of the database.
+func (isi InfoSchemaImpl) GetMaterializedViews() ([]common.MaterializedView, error) {
+	q := fmt.Sprintf("SELECT mview_name, query FROM all_mviews WHERE owner = '%s' ORDER BY mview_name", isi.DbName)
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var views []common.MaterializedView
+	for rows.Next() {
+		v := common.MaterializedView{SchemaAndName: common.SchemaAndName{Schema: isi.DbName}}
+		if err := rows.Scan(&v.Name, &v.Definition); err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
 // GetColumns returns a list of Column objects and names
 func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
 	q := fmt.Sprintf(`
@@ -204,7 +261,7 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 				ignored.Check = true
 			// Oracle 21c introduces a JSON datatype, before that we used to store json as VARCHAR2, CLOB, and BLOB.
 			// If column has check constraints IS JSON(check for J in constraints array as per GetConstraints function) then update src datatype to JSON
-			// so toSpannerTypeInternal function map this datatype to spanner JSON.
+			// so TypeMap maps this datatype to spanner JSON.
 			case "J":
 				dataType = "JSON"
 				charMaxLen.Valid = false
@@ -46,6 +46,11 @@ func TestProcessSchemaOracle(t *testing.T) {
 				{"TEST"},
 				{"TEST2"}},
 		},
+		{
+			query: "SELECT mview_name, query FROM all_mviews (.+)",
+			args:  []driver.Value{},
+			cols:  []string{"mview_name", "query"},
+		},
 		// USER table
 		{
 			query: `SELECT (.+) FROM all_constraints (.+)`,
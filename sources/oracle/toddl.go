@@ -21,6 +21,7 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
@@ -38,8 +39,7 @@ type ToDdlImpl struct {
 // mapping.  toSpannerType returns the Spanner type and a list of type
 // conversion issues encountered.
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
-	// passing empty spType to execute default case.will get other spType from web pkg
-	ty, issues := toSpannerTypeInternal(conv, "", columnType.Name, columnType.Mods)
+	ty, issues := TypeMap.ToSpannerType(columnType.Name, "", columnType.Mods)
 	if conv.TargetDb == constants.TargetExperimentalPostgres {
 		ty = overrideExperimentalType(columnType, ty)
 	} else {
@@ -52,119 +52,87 @@ func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type)
 	return ty, issues
 }
 
-func ToSpannerTypeWeb(conv *internal.Conv, spType string, srcType string, mods []int64) (ddl.Type, []internal.SchemaIssue) {
-	return toSpannerTypeInternal(conv, spType, srcType, mods)
+// TypeMap declares the mapping of Oracle types to Spanner types.
+var TypeMap = common.TypeMap{
+	Types: common.NewTypeMap(
+		common.TypeGroup{SrcTypes: []string{"TIMESTAMP"}, TypeMapping: common.TypeMapping{
+			Default: common.To(ddl.Type{Name: ddl.Timestamp}),
+			Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+		}},
+		common.TypeGroup{SrcTypes: []string{"INTERVAL"}, TypeMapping: common.TypeMapping{
+			Default: func(mods []int64) (ddl.Type, []internal.SchemaIssue) {
+				if len(mods) > 0 {
+					return ddl.Type{Name: ddl.String, Len: 30}, nil
+				}
+				return common.MaxString, nil
+			},
+			Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+		}},
+		common.TypeGroup{SrcTypes: []string{"NUMBER"}, TypeMapping: common.TypeMapping{
+			Default: toSpannerNumber,
+			Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+		}},
+		common.TypeGroup{SrcTypes: []string{"BFILE", "BLOB", "RAW", "LONG RAW"}, TypeMapping: common.TypeMapping{
+			Default: common.To(common.MaxBytes),
+			Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+		}},
+		common.TypeGroup{SrcTypes: []string{"CHAR"}, TypeMapping: common.TypeMapping{
+			Default: common.ToSized(ddl.String, 0),
+		}},
+		common.TypeGroup{SrcTypes: []string{"NCHAR", "NVARCHAR2", "VARCHAR", "VARCHAR2", "UROWID"}, TypeMapping: common.TypeMapping{
+			Default: common.ToSized(ddl.String, ddl.MaxLength),
+		}},
+		common.TypeGroup{SrcTypes: []string{"CLOB", "LONG", "NCLOB", "ROWID", "XMLTYPE"}, TypeMapping: common.TypeMapping{
+			Default: common.To(common.MaxString),
+		}},
+		common.TypeGroup{SrcTypes: []string{"DATE"}, TypeMapping: common.TypeMapping{
+			Default: common.To(ddl.Type{Name: ddl.Date}),
+			Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+		}},
+		common.TypeGroup{SrcTypes: []string{"BINARY_DOUBLE", "BINARY_FLOAT", "FLOAT"}, TypeMapping: common.TypeMapping{
+			Default: common.To(ddl.Type{Name: ddl.Float64}),
+			Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+		}},
+		common.TypeGroup{SrcTypes: []string{"JSON", "OBJECT"}, TypeMapping: common.TypeMapping{
+			Default: common.To(ddl.Type{Name: ddl.JSON}),
+		}},
+	).Types,
+	Match: matchType,
 }
 
-func toSpannerTypeInternal(conv *internal.Conv, spType string, srcType string, mods []int64) (ddl.Type, []internal.SchemaIssue) {
-	// Oracle returns some datatype with the precision,
-	// So will get TIMESTAMP as TIMESTAMP(6),TIMESTAMP(6) WITH TIME ZONE,TIMESTAMP(6) WITH LOCAL TIME ZONE.
-	// To match this case timestampReg Regex defined.
+// matchType matches the source types that Oracle returns with their
+// precision: TIMESTAMP as TIMESTAMP(6), TIMESTAMP(6) WITH TIME ZONE and
+// TIMESTAMP(6) WITH LOCAL TIME ZONE, and INTERVAL as INTERVAL YEAR(2) TO
+// MONTH, INTERVAL DAY(2) TO SECOND(6), etc.
+func matchType(srcType string) (string, bool) {
 	if TimestampReg.MatchString(srcType) {
-		switch spType {
-		case ddl.String:
-			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-		default:
-			return ddl.Type{Name: ddl.Timestamp}, nil
-		}
+		return "TIMESTAMP", true
 	}
-
-	// Matching cases like INTERVAL YEAR(2) TO MONTH, INTERVAL DAY(2) TO SECOND(6),etc.
 	if IntervalReg.MatchString(srcType) {
-		switch spType {
-		case ddl.String:
-			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-		default:
-			if len(mods) > 0 {
-				return ddl.Type{Name: ddl.String, Len: 30}, nil
-			}
-			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-		}
+		return "INTERVAL", true
 	}
+	return "", false
+}
 
-	switch srcType {
-	case "NUMBER":
-		switch spType {
-		case ddl.String:
-			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-		default:
-			modsLen := len(mods)
-			if modsLen == 0 {
-				return ddl.Type{Name: ddl.Numeric}, nil
-			} else if modsLen == 1 { // Only precision is available.
-				if mods[0] > 29 {
-					// Max precision in Oracle is 38. String representation of the number should not have more than 50 characters
-					// https://docs.oracle.com/cd/B19306_01/server.102/b14237/limits001.htm#i287903
-					return ddl.Type{Name: ddl.String, Len: 50}, nil
-				}
-				return ddl.Type{Name: ddl.Int64}, nil
-			} else if mods[0] > 29 || mods[1] > 9 { // When both precision and scale are available and within limit
-				// Max precision in Oracle is 38. String representation of the number should not have more than 50 characters
-				// https://docs.oracle.com/cd/B19306_01/server.102/b14237/limits001.htm#i287903
-				return ddl.Type{Name: ddl.String, Len: 50}, nil
-			}
-
-			return ddl.Type{Name: ddl.Numeric}, nil
-		}
-
-	case "BFILE", "BLOB":
-		switch spType {
-		case ddl.String:
-			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-		default:
-			return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
-		}
-	case "CHAR":
-		if len(mods) > 0 {
-			return ddl.Type{Name: ddl.String, Len: mods[0]}, nil
-		}
-		return ddl.Type{Name: ddl.String}, nil
-	case "CLOB":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "DATE":
-		switch spType {
-		case ddl.String:
-			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-		default:
-			return ddl.Type{Name: ddl.Date}, nil
-		}
-	case "BINARY_DOUBLE", "BINARY_FLOAT", "FLOAT":
-		switch spType {
-		case ddl.String:
-			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-		default:
-			return ddl.Type{Name: ddl.Float64}, nil
-		}
-	case "LONG":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "RAW", "LONG RAW":
-		switch spType {
-		case ddl.String:
-			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-		default:
-			return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
-		}
-	case "NCHAR", "NVARCHAR2", "VARCHAR", "VARCHAR2":
-		if len(mods) > 0 {
-			return ddl.Type{Name: ddl.String, Len: mods[0]}, nil
-		}
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "NCLOB":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "ROWID":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "UROWID":
-		if len(mods) > 0 {
-			return ddl.Type{Name: ddl.String, Len: mods[0]}, nil
+// toSpannerNumber maps NUMBER to NUMERIC, or to INT64 or STRING depending on
+// its precision and scale.
+func toSpannerNumber(mods []int64) (ddl.Type, []internal.SchemaIssue) {
+	modsLen := len(mods)
+	if modsLen == 0 {
+		return ddl.Type{Name: ddl.Numeric}, nil
+	} else if modsLen == 1 { // Only precision is available.
+		if mods[0] > 29 {
+			// Max precision in Oracle is 38. String representation of the number should not have more than 50 characters
+			// https://docs.oracle.com/cd/B19306_01/server.102/b14237/limits001.htm#i287903
+			return ddl.Type{Name: ddl.String, Len: 50}, nil
 		}
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "XMLTYPE":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "JSON", "OBJECT":
-		return ddl.Type{Name: ddl.JSON}, nil
-	default:
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
+		return ddl.Type{Name: ddl.Int64}, nil
+	} else if mods[0] > 29 || mods[1] > 9 { // When both precision and scale are available and within limit
+		// Max precision in Oracle is 38. String representation of the number should not have more than 50 characters
+		// https://docs.oracle.com/cd/B19306_01/server.102/b14237/limits001.htm#i287903
+		return ddl.Type{Name: ddl.String, Len: 50}, nil
 	}
+	return ddl.Type{Name: ddl.Numeric}, nil
 }
 
 // Override the types to map to experimental postgres types.
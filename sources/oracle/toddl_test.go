@@ -96,7 +96,7 @@ func TestToSpannerType(t *testing.T) {
 		Pks: []ddl.IndexKey{{Col: "a"}},
 		Fks: []ddl.Foreignkey{{Name: "fk_test", Columns: []string{"d"}, ReferTable: "ref_table", ReferColumns: []string{"dref"}},
 			{Name: "fk_test2", Columns: []string{"a"}, ReferTable: "ref_table2", ReferColumns: []string{"aref"}}},
-		Indexes: []ddl.CreateIndex{{Name: "index1", Table: name, Unique: true, Keys: []ddl.IndexKey{{Col: "a", Desc: false}, {Col: "d", Desc: true}}},
+		Indexes: []ddl.CreateIndex{{Name: "index1", Table: name, Unique: true, Keys: []ddl.IndexKey{{Col: "a", Desc: false}, {Col: "d", Desc: true}}, NullFiltered: true},
 			{Name: "index_with_0_key", Table: name, Unique: true, Keys: nil}},
 	}
 	assert.Equal(t, expected, actual)
@@ -179,7 +179,7 @@ func TestToExperimentalSpannerType(t *testing.T) {
 		Pks: []ddl.IndexKey{{Col: "a"}},
 		Fks: []ddl.Foreignkey{{Name: "fk_test", Columns: []string{"d"}, ReferTable: "ref_table", ReferColumns: []string{"dref"}},
 			{Name: "fk_test2", Columns: []string{"a"}, ReferTable: "ref_table2", ReferColumns: []string{"aref"}}},
-		Indexes: []ddl.CreateIndex{{Name: "index1", Table: name, Unique: true, Keys: []ddl.IndexKey{{Col: "a", Desc: false}, {Col: "d", Desc: true}}},
+		Indexes: []ddl.CreateIndex{{Name: "index1", Table: name, Unique: true, Keys: []ddl.IndexKey{{Col: "a", Desc: false}, {Col: "d", Desc: true}}, NullFiltered: true},
 			{Name: "index_with_0_key", Table: name, Unique: true, Keys: nil}},
 	}
 	assert.Equal(t, expected, actual)
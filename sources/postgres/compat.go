@@ -0,0 +1,108 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// compat records the catalog features that the database being migrated
+// lacks. The driver reads any database that speaks the PostgreSQL wire
+// protocol (e.g. CockroachDB or AlloyDB Omni), whose catalogs implement
+// PostgreSQL's to varying degrees: queries relying on a missing feature fall
+// back to the information schema, or skip what can't be read. The zero value
+// is PostgreSQL, which has all of them.
+type compat struct {
+	flavor           string // Name of the database if it's not PostgreSQL e.g. CockroachDB, for messages.
+	noSerialSequence bool   // No pg_get_serial_sequence: sequences of serial columns aren't read.
+	noElementTypes   bool   // No information_schema.element_types: array element types come from udt_name.
+	noMatviews       bool   // No pg_matviews: there are no materialized views.
+	noPgIndex        bool   // Index keys can't be read from pg_index: they come from information_schema.statistics.
+	noPgConstraint   bool   // Foreign keys can't be read from pg_constraint: they come from the information schema.
+	hiddenColumns    bool   // information_schema.columns lists hidden columns (e.g. CockroachDB's rowid), flagged by is_hidden.
+}
+
+// Queries probing the features of compat, which fail if the feature is
+// missing.
+const (
+	serialSequenceProbe = `SELECT pg_get_serial_sequence('pg_catalog.pg_class', 'relname')`
+	elementTypesProbe   = `SELECT collection_type_identifier FROM information_schema.element_types LIMIT 0`
+	matviewsProbe       = `SELECT schemaname, matviewname, definition FROM pg_matviews LIMIT 0`
+	pgIndexProbe        = `SELECT 1 + array_position(i.indkey, a.attnum), o.option & 1
+		FROM pg_index AS i
+		CROSS JOIN LATERAL UNNEST (i.indkey) WITH ordinality AS c (colnum, ordinality)
+		LEFT JOIN LATERAL UNNEST (i.indoption) WITH ordinality AS o (option, ordinality) ON c.ordinality = o.ordinality
+		JOIN pg_attribute AS a ON i.indrelid = a.attrelid AND a.attnum = c.colnum LIMIT 0`
+	pgConstraintProbe  = `SELECT UNNEST(conkey), UNNEST(confkey) FROM pg_constraint WHERE contype = 'f' LIMIT 0`
+	hiddenColumnsProbe = `SELECT is_hidden FROM information_schema.columns LIMIT 0`
+)
+
+// detectCompat detects the catalog features that db lacks, and prints those
+// of databases other than PostgreSQL.
+func detectCompat(db *sql.DB) compat {
+	var c compat
+	var version string
+	if err := db.QueryRow("SELECT version()").Scan(&version); err == nil && strings.Contains(version, "CockroachDB") {
+		c.flavor = "CockroachDB"
+	}
+	probe := func(q string) bool {
+		rows, err := db.Query(q)
+		if err != nil {
+			return false
+		}
+		rows.Close()
+		return true
+	}
+	c.noSerialSequence = !probe(serialSequenceProbe)
+	c.noElementTypes = !probe(elementTypesProbe)
+	c.noMatviews = !probe(matviewsProbe)
+	c.noPgIndex = !probe(pgIndexProbe)
+	c.noPgConstraint = !probe(pgConstraintProbe)
+	c.hiddenColumns = probe(hiddenColumnsProbe)
+	if missing := c.missing(); c.flavor != "" || len(missing) > 0 {
+		name := c.flavor
+		if name == "" {
+			name = "PostgreSQL-compatible"
+		}
+		fmt.Printf("Detected a %s database", name)
+		if len(missing) > 0 {
+			fmt.Printf(" lacking %s: the schema is read from the information schema where possible", strings.Join(missing, ", "))
+		}
+		fmt.Println(".")
+	}
+	return c
+}
+
+// missing returns the catalog features that c lacks.
+func (c compat) missing() []string {
+	var l []string
+	for _, f := range []struct {
+		missing bool
+		name    string
+	}{
+		{c.noSerialSequence, "pg_get_serial_sequence"},
+		{c.noElementTypes, "information_schema.element_types"},
+		{c.noMatviews, "pg_matviews"},
+		{c.noPgIndex, "pg_index"},
+		{c.noPgConstraint, "pg_constraint"},
+	} {
+		if f.missing {
+			l = append(l, f.name)
+		}
+	}
+	return l
+}
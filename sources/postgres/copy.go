@@ -0,0 +1,249 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+)
+
+// The database/sql driver for PostgreSQL doesn't support COPY TO, so data is
+// extracted with COPY ... TO STDOUT in text format over a pgconn connection.
+// Rows are decoded into the values the database/sql driver would have
+// returned for them, so that they are converted exactly like the rows of
+// SELECT queries.
+
+// errCopyUnavailable is the kind of errors of COPY that happen before any
+// row was read, after which data can still be read with SELECT.
+var errCopyUnavailable = errors.New("COPY is unavailable")
+
+// newCopyConfig returns the COPY connection parameters of dsn, a connection
+// string of key=value pairs, or nil if pgconn can't parse it.
+func newCopyConfig(dsn string) *pgconn.Config {
+	cfg, err := pgconn.ParseConfig(dsn)
+	if err != nil {
+		return nil
+	}
+	cfg.RuntimeParams["client_encoding"] = "UTF8"
+	cfg.RuntimeParams["datestyle"] = "ISO, MDY"
+	cfg.RuntimeParams["bytea_output"] = "hex"
+	return cfg
+}
+
+// copySupported returns true if the values of all columns of srcSchema can
+// be read with COPY. Times without date are decoded by the database/sql
+// driver in ways that COPY doesn't replicate, so tables with such columns
+// are read with SELECT.
+func copySupported(srcSchema schema.Table) bool {
+	for _, col := range srcSchema.ColNames {
+		switch srcSchema.ColDefs[col].Type.Name {
+		case "time without time zone", "time with time zone":
+			return false
+		}
+	}
+	return true
+}
+
+// copyQuery returns the COPY statement reading columns cols of table
+// schemaName.table.
+func copyQuery(schemaName, table string, cols []string) string {
+	var quoted []string
+	for _, c := range cols {
+		quoted = append(quoted, quoteIdent(c))
+	}
+	return fmt.Sprintf("COPY (SELECT %s FROM %s.%s) TO STDOUT", strings.Join(quoted, ", "), quoteIdent(schemaName), quoteIdent(table))
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// copyOut runs COPY statement query over a connection with the parameters
+// of cfg, calling row with each row of the output, in text format, and the
+// time zone of the session, used for timestamps with time zone. The copy
+// stops at the first error returned by row.
+func copyOut(cfg *pgconn.Config, query string, row func(row []byte, loc *time.Location) error) error {
+	ctx := context.Background()
+	conn, err := pgconn.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	loc, err := time.LoadLocation(conn.ParameterStatus("TimeZone"))
+	if err != nil {
+		loc = time.UTC
+	}
+	_, err = conn.CopyTo(ctx, &copyRowWriter{row: func(b []byte) error { return row(b, loc) }}, query)
+	return err
+}
+
+// copyRowWriter is an io.Writer that splits COPY output into rows. pgconn
+// writes each CopyData message separately; servers send a row per message,
+// but rows split across messages are reassembled.
+type copyRowWriter struct {
+	row     func([]byte) error
+	partial []byte
+}
+
+func (w *copyRowWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, b...)
+			break
+		}
+		// pgconn reuses the buffer of b, so rows are copied.
+		line := append(w.partial, b[:i]...)
+		w.partial = nil
+		if err := w.row(line); err != nil {
+			return 0, err
+		}
+		b = b[i+1:]
+	}
+	return n, nil
+}
+
+// splitCopyRow splits row, a row of COPY output in text format, into its
+// fields, with nil for NULL.
+func splitCopyRow(row []byte) [][]byte {
+	var fields [][]byte
+	for _, f := range bytes.Split(row, []byte("\t")) {
+		if string(f) == `\N` {
+			fields = append(fields, nil)
+			continue
+		}
+		fields = append(fields, unescapeCopyField(f))
+	}
+	return fields
+}
+
+// copyFieldVals returns fields, split by splitCopyRow, as values: nil for
+// NULL, and their text otherwise.
+func copyFieldVals(fields [][]byte) []interface{} {
+	var vals []interface{}
+	for _, f := range fields {
+		if f == nil {
+			vals = append(vals, nil)
+			continue
+		}
+		vals = append(vals, string(f))
+	}
+	return vals
+}
+
+// unescapeCopyField decodes the backslash escapes of field f of COPY output
+// in text format.
+func unescapeCopyField(f []byte) []byte {
+	if bytes.IndexByte(f, '\\') < 0 {
+		return f
+	}
+	out := make([]byte, 0, len(f))
+	for i := 0; i < len(f); i++ {
+		if f[i] != '\\' || i+1 == len(f) {
+			out = append(out, f[i])
+			continue
+		}
+		i++
+		switch c := f[i]; c {
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'v':
+			out = append(out, '\v')
+		case 'x':
+			j := i + 1
+			for j < len(f) && j < i+3 && isHexDigit(f[j]) {
+				j++
+			}
+			if j == i+1 {
+				out = append(out, c)
+				continue
+			}
+			n, _ := strconv.ParseUint(string(f[i+1:j]), 16, 8)
+			out = append(out, byte(n))
+			i = j - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(f) && j < i+3 && f[j] >= '0' && f[j] <= '7' {
+				j++
+			}
+			n, _ := strconv.ParseUint(string(f[i:j]), 8, 8)
+			out = append(out, byte(n))
+			i = j - 1
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// decodeCopyValue returns the value of field f of COPY output in text format,
+// for a column of type ty, decoded like the database/sql driver decodes the
+// values of SELECT queries.
+func decodeCopyValue(f []byte, ty schema.Type, loc *time.Location) (interface{}, error) {
+	if f == nil {
+		return nil, nil
+	}
+	if len(ty.ArrayBounds) > 0 {
+		return f, nil
+	}
+	switch ty.Name {
+	case "boolean":
+		return len(f) > 0 && f[0] == 't', nil
+	case "smallint", "integer", "bigint":
+		return strconv.ParseInt(string(f), 10, 64)
+	case "real":
+		return strconv.ParseFloat(string(f), 32)
+	case "double precision":
+		return strconv.ParseFloat(string(f), 64)
+	case "bytea":
+		if !bytes.HasPrefix(f, []byte(`\x`)) {
+			return nil, fmt.Errorf("invalid bytea value %q", f)
+		}
+		b := make([]byte, hex.DecodedLen(len(f)-2))
+		if _, err := hex.Decode(b, f[2:]); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "date", "timestamp without time zone":
+		return pq.ParseTimestamp(nil, string(f))
+	case "timestamp with time zone":
+		return pq.ParseTimestamp(loc, string(f))
+	}
+	return f, nil
+}
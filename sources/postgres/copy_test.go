@@ -0,0 +1,201 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// fakeBackend serves a COPY connection on c: it requests MD5 password
+// authentication, checks the password, and answers the COPY query with rows,
+// or with an error if rows is nil. It returns the query it received.
+func fakeBackend(c net.Conn, rows []string) <-chan string {
+	queries := make(chan string, 1)
+	go func() {
+		defer c.Close()
+		be := pgproto3.NewBackend(pgproto3.NewChunkReader(c), c)
+		if _, err := be.ReceiveStartupMessage(); err != nil {
+			return
+		}
+		be.Send(&pgproto3.AuthenticationMD5Password{Salt: [4]byte{'s', 'a', 'l', 't'}})
+		msg, err := be.Receive()
+		if pwd, ok := msg.(*pgproto3.PasswordMessage); err != nil || !ok || pwd.Password != "md5"+md5Hex(md5Hex("secretu")+"salt") {
+			be.Send(&pgproto3.ErrorResponse{Severity: "FATAL", Code: "28P01", Message: "password authentication failed"})
+			return
+		}
+		be.Send(&pgproto3.AuthenticationOk{})
+		be.Send(&pgproto3.ParameterStatus{Name: "TimeZone", Value: "UTC"})
+		be.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		msg, err = be.Receive()
+		q, ok := msg.(*pgproto3.Query)
+		if err != nil || !ok {
+			return
+		}
+		queries <- q.String
+		if rows == nil {
+			be.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "42501", Message: "permission denied"})
+		} else {
+			be.Send(&pgproto3.CopyOutResponse{})
+			for _, row := range rows {
+				be.Send(&pgproto3.CopyData{Data: []byte(row + "\n")})
+			}
+			be.Send(&pgproto3.CopyDone{})
+			be.Send(&pgproto3.CommandComplete{CommandTag: []byte("COPY 2")})
+		}
+		be.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		be.Receive()
+	}()
+	return queries
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func fakeCopyConfig(password string, rows []string) (*pgconn.Config, <-chan string) {
+	client, server := net.Pipe()
+	queries := fakeBackend(server, rows)
+	cfg := newCopyConfig("host=127.0.0.1 port=5432 user=u password=" + password + " dbname=d sslmode=disable")
+	cfg.DialFunc = func(ctx context.Context, network, address string) (net.Conn, error) { return client, nil }
+	return cfg, queries
+}
+
+func TestNewCopyConfig(t *testing.T) {
+	cfg := newCopyConfig("host=h port=5433 user=u password=p dbname=d sslmode=disable")
+	assert.Equal(t, uint16(5433), cfg.Port)
+	assert.Equal(t, "d", cfg.Database)
+	assert.Nil(t, cfg.TLSConfig)
+	assert.Equal(t, "hex", cfg.RuntimeParams["bytea_output"])
+	cfg = newCopyConfig("host=h port=5432 user=u password=p dbname=d sslmode=require")
+	assert.NotNil(t, cfg.TLSConfig)
+	assert.Nil(t, newCopyConfig("host=h port=x"))
+}
+
+func TestCopyRowWriter(t *testing.T) {
+	var rows []string
+	w := &copyRowWriter{row: func(b []byte) error {
+		rows = append(rows, string(b))
+		return nil
+	}}
+	for _, b := range []string{"a\tb\n", "c", "d\ne\n"} {
+		n, err := w.Write([]byte(b))
+		assert.Nil(t, err)
+		assert.Equal(t, len(b), n)
+	}
+	assert.Equal(t, []string{"a\tb", "cd", "e"}, rows)
+}
+
+func TestSplitCopyRow(t *testing.T) {
+	assert.Equal(t, [][]byte{[]byte("a\tb\nc\\"), nil, []byte(""), []byte("\x01A")}, splitCopyRow([]byte(`a\tb\nc\\`+"\t"+`\N`+"\t\t"+`\001\x41`)))
+}
+
+func TestDecodeCopyValue(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		ty   schema.Type
+		want interface{}
+	}{
+		{"t", schema.Type{Name: "boolean"}, true},
+		{"-7", schema.Type{Name: "bigint"}, int64(-7)},
+		{"1.5", schema.Type{Name: "double precision"}, 1.5},
+		{`\x0aff`, schema.Type{Name: "bytea"}, []byte{10, 255}},
+		{"12.50", schema.Type{Name: "numeric"}, []byte("12.50")},
+		{"{1,2}", schema.Type{Name: "integer", ArrayBounds: []int64{-1}}, []byte("{1,2}")},
+		{"2022-06-01", schema.Type{Name: "date"}, time.Date(2022, 6, 1, 0, 0, 0, 0, time.FixedZone("", 0))},
+	} {
+		v, err := decodeCopyValue([]byte(tc.in), tc.ty, time.UTC)
+		assert.Nil(t, err, tc.in)
+		assert.Equal(t, tc.want, v, tc.in)
+	}
+	v, err := decodeCopyValue(nil, schema.Type{Name: "text"}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+	v, err = decodeCopyValue([]byte("2022-06-01 10:00:00+02"), schema.Type{Name: "timestamp with time zone"}, time.UTC)
+	assert.Nil(t, err)
+	assert.True(t, time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC).Equal(v.(time.Time)))
+	assert.False(t, copySupported(schema.Table{ColNames: []string{"a"}, ColDefs: map[string]schema.Column{"a": {Type: schema.Type{Name: "time without time zone"}}}}))
+}
+
+func copyTestConv() (ddl.CreateTable, schema.Table) {
+	return ddl.CreateTable{
+			Name:     "orders",
+			ColNames: []string{"id", "day", "note"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":   {Name: "id", T: ddl.Type{Name: ddl.Int64}},
+				"day":  {Name: "day", T: ddl.Type{Name: ddl.Date}},
+				"note": {Name: "note", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			}},
+		schema.Table{
+			Name:     "orders",
+			Schema:   "public",
+			ColNames: []string{"id", "day", "note"},
+			ColDefs: map[string]schema.Column{
+				"id":   {Name: "id", Type: schema.Type{Name: "bigint"}},
+				"day":  {Name: "day", Type: schema.Type{Name: "date"}},
+				"note": {Name: "note", Type: schema.Type{Name: "text"}},
+			}}
+}
+
+func TestProcessData_Copy(t *testing.T) {
+	conv := buildConv(copyTestConv())
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+	cfg, queries := fakeCopyConfig("secret", []string{"1\t2022-06-01\ta\\tb", "2\t\\N\t\\N", "x\t2022-06-01\tc"})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{copy: cfg})
+	assert.Equal(t, `COPY (SELECT "id", "day", "note" FROM "public"."orders") TO STDOUT`, <-queries)
+	assert.Equal(t, []spannerData{
+		{table: "orders", cols: []string{"id", "day", "note"}, vals: []interface{}{int64(1), civil.Date{Year: 2022, Month: 6, Day: 1}, "a\tb"}},
+		{table: "orders", cols: []string{"id"}, vals: []interface{}{int64(2)}},
+	}, rows)
+	assert.Equal(t, int64(1), conv.BadRows())
+	assert.Equal(t, []string{"table=orders cols=[id day note] data=[x 2022-06-01 c]\n"}, conv.SampleBadRows(10))
+}
+
+func TestProcessDataCopy_Unavailable(t *testing.T) {
+	spSchema, srcSchema := copyTestConv()
+	conv := buildConv(spSchema, srcSchema)
+	conv.SetDataMode()
+
+	// Wrong password.
+	cfg, _ := fakeCopyConfig("wrong", []string{})
+	err := InfoSchemaImpl{copy: cfg}.processDataCopy(conv, "orders", srcSchema, "orders", spSchema.ColNames, spSchema)
+	assert.ErrorIs(t, err, errCopyUnavailable)
+	assert.Contains(t, err.Error(), "password authentication failed")
+
+	// COPY fails before any row is read.
+	cfg, _ = fakeCopyConfig("secret", nil)
+	err = InfoSchemaImpl{copy: cfg}.processDataCopy(conv, "orders", srcSchema, "orders", spSchema.ColNames, spSchema)
+	assert.ErrorIs(t, err, errCopyUnavailable)
+	assert.Contains(t, err.Error(), "permission denied")
+}
@@ -16,9 +16,9 @@ package postgres
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/big"
-	"math/bits"
 	"reflect"
 	"strconv"
 	"strings"
@@ -36,7 +36,9 @@ import (
 // and vals contains string data to be converted to appropriate types
 // to send to Spanner.  ProcessDataRow is only called in DataMode.
 func ProcessDataRow(conv *internal.Conv, srcTable string, srcCols, vals []string) {
+	start := time.Now()
 	spTable, spCols, spVals, err := ConvertData(conv, srcTable, srcCols, vals)
+	conv.Timings.TimeTable(internal.StageConvert, srcTable, start)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(srcTable, conv.DataMode())
@@ -102,9 +104,7 @@ func ConvertData(conv *internal.Conv, srcTable string, srcCols []string, vals []
 	}
 	if aux, ok := conv.SyntheticPKeys[spTable]; ok {
 		c = append(c, aux.Col)
-		v = append(v, int64(bits.Reverse64(uint64(aux.Sequence))))
-		aux.Sequence++
-		conv.SyntheticPKeys[spTable] = aux
+		v = append(v, conv.NextSyntheticKey(spTable))
 	}
 	return spTable, c, v, nil
 }
@@ -257,7 +257,10 @@ func convArray(spannerType ddl.Type, srcTypeName string, location *time.Location
 	if v[0] != '{' || v[len(v)-1] != '}' {
 		return []interface{}{}, fmt.Errorf("unrecognized data format for array: expected {v1, v2, ...}")
 	}
-	a := strings.Split(v[1:len(v)-1], ",")
+	a, err := splitArray(v[1 : len(v)-1])
+	if err != nil {
+		return []interface{}{}, err
+	}
 
 	// The Spanner client for go does not accept []interface{} for arrays.
 	// Instead it only accepts slices of a specific type e.g. []int64, []string.
@@ -353,6 +356,41 @@ func convArray(spannerType ddl.Type, srcTypeName string, location *time.Location
 			r = append(r, spanner.NullInt64{Int64: i, Valid: true})
 		}
 		return r, nil
+	case ddl.Numeric:
+		var r []spanner.NullNumeric
+		for _, s := range a {
+			if s == "NULL" {
+				r = append(r, spanner.NullNumeric{Valid: false})
+				continue
+			}
+			s, err := processQuote(s)
+			if err != nil {
+				return []spanner.NullNumeric{}, err
+			}
+			n := new(big.Rat)
+			if _, ok := n.SetString(s); !ok {
+				return []spanner.NullNumeric{}, fmt.Errorf("can't convert %q to big.Rat", s)
+			}
+			r = append(r, spanner.NullNumeric{Numeric: *n, Valid: true})
+		}
+		return r, nil
+	case ddl.JSON:
+		var r []spanner.NullJSON
+		for _, s := range a {
+			if s == "NULL" {
+				r = append(r, spanner.NullJSON{Valid: false})
+				continue
+			}
+			s, err := processQuote(s)
+			if err != nil {
+				return []spanner.NullJSON{}, err
+			}
+			if !json.Valid([]byte(s)) {
+				return []spanner.NullJSON{}, fmt.Errorf("can't convert %q to json", s)
+			}
+			r = append(r, spanner.NullJSON{Value: json.RawMessage(s), Valid: true})
+		}
+		return r, nil
 	case ddl.String:
 		var r []spanner.NullString
 		for _, s := range a {
@@ -389,6 +427,90 @@ func convArray(spannerType ddl.Type, srcTypeName string, location *time.Location
 	return []interface{}{}, fmt.Errorf("array type conversion not implemented for type %v", reflect.TypeOf(spannerType))
 }
 
+// convComposite converts v, the text representation of a value of a
+// composite type with fields fields e.g. (1,"Main St",), to a JSON object
+// keyed by field name e.g. {"id": "1", "street": "Main St", "zip": null}.
+// Field values are JSON strings, since their types aren't tracked, and
+// unquoted empty values are NULL.
+func convComposite(fields []string, v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if len(v) < 2 || v[0] != '(' || v[len(v)-1] != ')' {
+		return "", fmt.Errorf("unrecognized data format for composite type: expected (v1,v2,...)")
+	}
+	var vals []*string
+	var b strings.Builder
+	quoted, empty := false, true
+	for i := 1; i < len(v)-1; i++ {
+		c := v[i]
+		switch {
+		case quoted && c == '"' && i+1 < len(v)-1 && v[i+1] == '"':
+			b.WriteByte('"') // Doubled quote within quoted value.
+			i++
+		case c == '"':
+			quoted, empty = !quoted, false
+		case quoted && c == '\\' && i+1 < len(v)-1:
+			b.WriteByte(v[i+1])
+			i++
+		case !quoted && c == ',':
+			vals = append(vals, compositeVal(b.String(), empty))
+			b.Reset()
+			empty = true
+		default:
+			b.WriteByte(c)
+			empty = false
+		}
+	}
+	if quoted {
+		return "", fmt.Errorf("unrecognized data format for composite type: unterminated quoted value")
+	}
+	vals = append(vals, compositeVal(b.String(), empty))
+	if len(vals) != len(fields) {
+		return "", fmt.Errorf("composite value has %d fields, expected %d", len(vals), len(fields))
+	}
+	var l []string
+	for i, f := range fields {
+		k, _ := json.Marshal(f)
+		x, _ := json.Marshal(vals[i])
+		l = append(l, string(k)+": "+string(x))
+	}
+	return "{" + strings.Join(l, ", ") + "}", nil
+}
+
+func compositeVal(s string, null bool) *string {
+	if null {
+		return nil
+	}
+	return &s
+}
+
+// splitArray splits v, the elements of a PostgreSQL array value without
+// its enclosing curly braces, at the commas that delimit its elements.
+// Elements keep their double quotes (see processQuote), so that quoted
+// "NULL" can be told apart from NULL. Nested curly braces, i.e.
+// multi-dimensional arrays, are reported as an error: they are mapped to
+// STRING (see MultiDimensionalArray) and never reach convArray.
+func splitArray(v string) ([]string, error) {
+	var a []string
+	start, quoted := 0, false
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case c == '\\' && quoted:
+			i++ // Skip escaped character.
+		case c == '"':
+			quoted = !quoted
+		case (c == '{' || c == '}') && !quoted:
+			return nil, fmt.Errorf("multi-dimensional arrays are not supported")
+		case c == ',' && !quoted:
+			a = append(a, v[start:i])
+			start = i + 1
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unrecognized data format for array: unterminated quoted element")
+	}
+	return append(a, v[start:]), nil
+}
+
 // processQuote returns the unquoted version of s.
 // Note: The element values of PostgreSQL arrays may have double
 // quotes around them.  The array output routine will put double
@@ -398,8 +520,19 @@ func convArray(spannerType ddl.Type, srcTypeName string, location *time.Location
 // embedded in element values will be backslash-escaped.  See section
 // 8.14.6.of www.postgresql.org/docs/9.1/arrays.html.
 func processQuote(s string) (string, error) {
-	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
-		return strconv.Unquote(s)
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s, nil
+	}
+	var b strings.Builder
+	s = s[1 : len(s)-1]
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			if i == len(s) {
+				return "", fmt.Errorf("unrecognized data format for array element: trailing backslash")
+			}
+		}
+		b.WriteByte(s[i])
 	}
-	return s, nil
+	return b.String(), nil
 }
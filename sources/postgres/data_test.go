@@ -15,7 +15,9 @@
 package postgres
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"math/bits"
 	"testing"
 	"time"
@@ -107,6 +109,18 @@ func TestConvertData(t *testing.T) {
 			spanner.NullTime{Time: getTime(t, "2019-10-29T05:30:00+10:00"), Valid: true},
 			spanner.NullTime{Valid: false}}},
 		{"empty array", ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}, "", "{}", []spanner.NullString{}},
+		{"numeric array", ddl.Type{Name: ddl.Numeric, IsArray: true}, "", "{1.5,NULL}", []spanner.NullNumeric{
+			spanner.NullNumeric{Numeric: *big.NewRat(3, 2), Valid: true},
+			spanner.NullNumeric{Valid: false}}},
+		{"json array", ddl.Type{Name: ddl.JSON, IsArray: true}, "", `{"{\"a\": 1}",NULL}`, []spanner.NullJSON{
+			spanner.NullJSON{Value: json.RawMessage(`{"a": 1}`), Valid: true},
+			spanner.NullJSON{Valid: false}}},
+		{"quoted string array", ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}, "", `{"a,b","say \"hi\"","back\\slash","",x}`, []spanner.NullString{
+			spanner.NullString{StringVal: "a,b", Valid: true},
+			spanner.NullString{StringVal: `say "hi"`, Valid: true},
+			spanner.NullString{StringVal: `back\slash`, Valid: true},
+			spanner.NullString{StringVal: "", Valid: true},
+			spanner.NullString{StringVal: "x", Valid: true}}},
 	}
 	tableName := "testtable"
 	for _, tc := range singleColTests {
@@ -246,6 +260,21 @@ func TestConvertData(t *testing.T) {
 		assert.NotNil(t, err, tc.name)
 	}
 
+	arrayErrorTests := []struct {
+		name string
+		ty   ddl.Type
+		in   string
+	}{
+		{"multi-dimensional array", ddl.Type{Name: ddl.Int64, IsArray: true}, "{{1,2},{3,4}}"},
+		{"unterminated quote", ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}, `{"a,b}`},
+		{"bad numeric", ddl.Type{Name: ddl.Numeric, IsArray: true}, "{1.5,x}"},
+		{"bad json", ddl.Type{Name: ddl.JSON, IsArray: true}, `{"{a"}`},
+	}
+	for _, tc := range arrayErrorTests {
+		_, err := convArray(tc.ty, "", time.UTC, tc.in)
+		assert.NotNil(t, err, tc.name)
+	}
+
 	syntheticPKeyTests := []struct {
 		name  string
 		cols  []string      // Input columns.
@@ -302,3 +331,16 @@ func getDate(s string) civil.Date {
 	d, _ := civil.ParseDate(s)
 	return d
 }
+
+func TestConvComposite(t *testing.T) {
+	fields := []string{"a", "b", "c", "d"}
+	s, err := convComposite(fields, `("x ""y""","c\\d",,"")`)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a": "x \"y\"", "b": "c\\d", "c": null, "d": ""}`, s)
+	_, err = convComposite(fields, `(1,2)`)
+	assert.NotNil(t, err)
+	_, err = convComposite(fields, `("x,,,)`)
+	assert.NotNil(t, err)
+	_, err = convComposite(fields, `x,y,z,w`)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+var (
+	// tableStmtRegexp matches chunks holding CREATE TABLE or ALTER TABLE
+	// statements, the only ones whose Redshift clauses are rewritten.
+	tableStmtRegexp = regexp.MustCompile(`(?im)^\s*(CREATE|ALTER)\s+(\w+\s+)*TABLE\b`)
+	// redshiftMarkerRegexp matches the clauses of Redshift CREATE TABLE
+	// statements that give away Redshift dumps.
+	redshiftMarkerRegexp = regexp.MustCompile(`(?i)\b(DISTSTYLE\s+\w+|DISTKEY|SORTKEY|ENCODE\s+(AUTO|RAW|AZ64|BYTEDICT|DELTA|DELTA32K|LZO|MOSTLY8|MOSTLY16|MOSTLY32|RUNLENGTH|TEXT255|TEXT32K|ZSTD))\b`)
+	// auroraMarkerRegexp matches the roles and extensions of Aurora
+	// PostgreSQL that show up in its dumps.
+	auroraMarkerRegexp = regexp.MustCompile(`\b(rdsadmin|rds_superuser|aws_commons|aws_s3|aws_lambda|apg_plan_mgmt)\b`)
+	// redshiftStmtRegexp matches Redshift statements with no PostgreSQL
+	// equivalent, skipped since they don't define schema or data: data
+	// loads and unloads through S3, external schemas and tables, datashares,
+	// libraries, maintenance, and changes of distribution, sort keys and
+	// encodings.
+	redshiftStmtRegexp = regexp.MustCompile(`(?is)^(UNLOAD\b|COPY\s+\S+.*\bFROM\s+'s3://|(CREATE|ALTER|DROP)\s+(EXTERNAL|DATASHARE|LIBRARY)\b|ANALYZE\s+COMPRESSION\b|VACUUM\b|ALTER\s+TABLE\s+\S+\s+ALTER\s+(DISTKEY|DISTSTYLE|(COMPOUND\s+|INTERLEAVED\s+)?SORTKEY|ENCODE|COLUMN\s+\S+\s+ENCODE)\b)`)
+)
+
+// redshiftClauses are the Redshift clauses of CREATE TABLE and ALTER TABLE
+// statements that PostgreSQL doesn't parse. Distribution styles and keys,
+// sort keys, compression encodings and backup settings have no Spanner
+// equivalent and are dropped. Redshift identity columns are migrated as
+// plain columns, and VARCHAR(MAX) as an unbounded varchar.
+var redshiftClauses = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"DISTSTYLE", regexp.MustCompile(`(?i)\bDISTSTYLE\s+(EVEN|KEY|ALL|AUTO)\b`)},
+	{"DISTKEY", regexp.MustCompile(`(?i)\bDISTKEY\s*\([^)]*\)`)},
+	{"SORTKEY", regexp.MustCompile(`(?i)\b((COMPOUND|INTERLEAVED)\s+)?SORTKEY\s*(\([^)]*\)|AUTO\b)`)},
+	// Column attributes, once table clauses are removed.
+	{"DISTKEY", regexp.MustCompile(`(?i)\bDISTKEY\b`)},
+	{"SORTKEY", regexp.MustCompile(`(?i)\bSORTKEY\b`)},
+	{"ENCODE", regexp.MustCompile(`(?i)\bENCODE\s+\w+`)},
+	{"BACKUP", regexp.MustCompile(`(?i)\bBACKUP\s+(YES|NO)\b`)},
+	{"IDENTITY", regexp.MustCompile(`(?i)\b(GENERATED\s+BY\s+DEFAULT\s+AS\s+)?IDENTITY\s*\(\s*-?\d+\s*,\s*-?\d+\s*\)`)},
+	{"VARCHAR(MAX) length", regexp.MustCompile(`(?i)\(\s*MAX\s*\)`)},
+}
+
+// detectDialect detects Redshift and Aurora PostgreSQL dumps from chunk.
+func detectDialect(conv *internal.Conv, chunk string) {
+	if conv.Dialect != "" || len(chunk) > maxDialectProbe {
+		return
+	}
+	switch {
+	case tableStmtRegexp.MatchString(chunk) && redshiftMarkerRegexp.MatchString(chunk):
+		conv.DetectDialect(internal.DialectRedshift)
+	case auroraMarkerRegexp.MatchString(chunk):
+		conv.DetectDialect(internal.DialectAuroraPostgres)
+	}
+}
+
+// maxDialectProbe is the size of the largest chunks checked for dialect
+// markers: larger ones hold data.
+const maxDialectProbe = 64 * 1024
+
+// rewriteRedshift returns chunk without the Redshift clauses PostgreSQL
+// doesn't parse, and whether it is a Redshift statement with no PostgreSQL
+// equivalent, to be skipped. It is only called for chunks that don't parse.
+func rewriteRedshift(conv *internal.Conv, chunk string) (string, bool) {
+	stmt := stripComments(chunk)
+	if m := redshiftStmtRegexp.FindString(stmt); m != "" && strings.HasSuffix(strings.TrimSpace(chunk), ";") {
+		conv.DialectWarning(fmt.Sprintf("skipped statement %s...", strings.Join(strings.Fields(m), " ")))
+		return "", true
+	}
+	if !tableStmtRegexp.MatchString(chunk) {
+		return chunk, false
+	}
+	for _, c := range redshiftClauses {
+		if c.re.MatchString(chunk) {
+			conv.DialectWarning(fmt.Sprintf("ignored %s clause", c.name))
+			chunk = c.re.ReplaceAllString(chunk, "")
+		}
+	}
+	return chunk, false
+}
+
+// stripComments returns chunk without its leading comment lines.
+func stripComments(chunk string) string {
+	lines := strings.Split(chunk, "\n")
+	for len(lines) > 0 && (strings.HasPrefix(strings.TrimSpace(lines[0]), "--") || strings.TrimSpace(lines[0]) == "") {
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+)
+
+func init() {
+	registry.Register(dbDriver{})
+	registry.Register(dumpDriver{})
+}
+
+// dbDriver reads the schema and data of a PostgreSQL database.
+type dbDriver struct{}
+
+func (dbDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.POSTGRES,
+		Source:      "postgres",
+		Aliases:     []string{"postgresql", "pg"},
+		Description: "PostgreSQL database",
+		LegacyMode:  true,
+	}
+}
+
+func (dbDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (dbDriver) TypeMap() common.TypeMap {
+	return TypeMap
+}
+
+func (dbDriver) InfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) (common.InfoSchema, error) {
+	// If the connection parameters are empty, this is called as part of the
+	// legacy mode with global CLI flags, and they are read from environment
+	// variables.
+	var dsn string
+	var err error
+	conn := sourceProfile.Conn.Pg
+	if conn.Host != "" && conn.User != "" && conn.Db != "" {
+		dsn = profiles.GetSQLConnectionStr(sourceProfile)
+	} else if dsn, err = profiles.GeneratePGSQLConnectionStr(); err != nil {
+		return nil, err
+	}
+	db, err := dbDriver{}.open(conn, dsn)
+	if err != nil {
+		return nil, err
+	}
+	isi := InfoSchemaImpl{Db: db, MaterializedViews: conn.MaterializedViews, Grants: conn.Grants, compat: detectCompat(db)}
+	// COPY connects to the primary directly, so with replicas all reads use
+	// SELECT, whose connections fail over.
+	if conn.Copy && len(conn.Replicas) == 0 {
+		isi.copy = newCopyConfig(dsn)
+	}
+	return isi, nil
+}
+
+// open opens database dsn of conn. If conn has read replicas, connections
+// are made to them rather than to the primary while they are healthy.
+func (d dbDriver) open(conn profiles.SourceProfileConnectionPostgreSQL, dsn string) (*sql.DB, error) {
+	if len(conn.Replicas) == 0 {
+		return sql.Open(constants.POSTGRES, dsn)
+	}
+	var replicas []common.Endpoint
+	for _, r := range conn.Replicas {
+		host, port, _ := net.SplitHostPort(r)
+		replicas = append(replicas, common.Endpoint{Name: r, DSN: d.DataSourceName(host, port, conn.User, conn.Pwd, conn.Db), Replica: true})
+	}
+	primary := common.Endpoint{Name: net.JoinHostPort(conn.Host, conn.Port), DSN: dsn}
+	return common.OpenWithReplicas(constants.POSTGRES, primary, replicas, time.Duration(conn.MaxReplicaLag)*time.Second, replicaLag)
+}
+
+// replicaLag returns how far behind its primary the PostgreSQL server db is
+// connected to is: the age of the last transaction replayed, unless all WAL
+// received has been replayed.
+func replicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	var s float64
+	err := db.QueryRowContext(ctx, `SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`).Scan(&s)
+	return time.Duration(s * float64(time.Second)), err
+}
+
+func (dbDriver) DataSourceName(host, port, user, password, dbName string) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbName)
+}
+
+func (dbDriver) WebInfoSchema(db *sql.DB, dbName string) common.InfoSchema {
+	return InfoSchemaImpl{Db: db, compat: detectCompat(db)}
+}
+
+// dumpDriver reads the schema and data of a pg_dump file.
+type dumpDriver struct{}
+
+func (dumpDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.PGDUMP,
+		Source:      "postgres",
+		Aliases:     []string{"postgresql", "pg"},
+		Description: "pg_dump file",
+		Dump:        true,
+		LegacyMode:  true,
+	}
+}
+
+func (dumpDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (dumpDriver) TypeMap() common.TypeMap {
+	return TypeMap
+}
+
+func (dumpDriver) DbDump() common.DbDump {
+	return DbDumpImpl{}
+}
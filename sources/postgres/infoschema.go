@@ -17,15 +17,17 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"math/bits"
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/civil"
 	sp "cloud.google.com/go/spanner"
+	"github.com/jackc/pgconn"
 	_ "github.com/lib/pq" // we will use database/sql package instead of using this package directly
 
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
@@ -36,7 +38,11 @@ import (
 
 // InfoSchemaImpl postgres specific implementation for InfoSchema.
 type InfoSchemaImpl struct {
-	Db *sql.DB
+	Db                *sql.DB
+	MaterializedViews string         // Handling of materialized views, see internal.MaterializedViewSkip and friends.
+	Grants            bool           // Whether grants of privileges on tables are migrated as database roles.
+	copy              *pgconn.Config // If set, data is read with COPY rather than SELECT, where possible.
+	compat            compat         // Catalog features the database lacks, if it's not PostgreSQL.
 }
 
 // We leave the 2 functions below empty to be able to pass this as an infoSchema interface. We don't need these for now.
@@ -61,12 +67,23 @@ func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
 	return fmt.Sprintf("%s.%s", schema, tableName)
 }
 
+// sourceTable returns the schema and name in the source database of source
+// table srcTable, or of the table being migrated if tables are merged into
+// srcTable (see internal.MergeRule).
+func sourceTable(conv *internal.Conv, srcTable string) (string, string) {
+	if m, ok := conv.MergeMember(srcTable); ok {
+		return m.Schema, m.Table
+	}
+	return conv.SrcSchema[srcTable].Schema, srcTable
+}
+
 // GetRowsFromTable returns a sql Rows object for a table.
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
 	// PostgreSQL schema and name can be arbitrary strings.
 	// Ideally we would pass schema/name as a query parameter,
 	// but PostgreSQL doesn't support this. So we quote it instead.
-	q := fmt.Sprintf(`SELECT * FROM "%s"."%s";`, conv.SrcSchema[srcTable].Schema, srcTable)
+	schemaName, table := sourceTable(conv, srcTable)
+	q := fmt.Sprintf(`SELECT * FROM "%s"."%s";`, schemaName, table)
 	rows, err := isi.Db.Query(q)
 	if err != nil {
 		return nil, err
@@ -93,7 +110,14 @@ func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string)
 // We choose to do all type conversions explicitly ourselves so that
 // we can generate more targeted error messages: hence we pass
 // *interface{} parameters to row.Scan.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	if isi.copy != nil && copySupported(srcSchema) {
+		err := isi.processDataCopy(conv, srcTable, srcSchema, spTable, spCols, spSchema)
+		if !errors.Is(err, errCopyUnavailable) {
+			return err
+		}
+		conv.Unexpected(fmt.Sprintf("Reading table %s with SELECT: %s", srcTable, err))
+	}
 	rowsInterface, err := isi.GetRowsFromTable(conv, srcTable)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
@@ -123,6 +147,72 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcS
 	return nil
 }
 
+// processDataCopy is like ProcessData, but extracts data with COPY, which
+// streams rows much faster than a SELECT query. It fails with an error of
+// kind errCopyUnavailable if COPY failed before reading any row, in which
+// case the table can still be read with SELECT.
+func (isi InfoSchemaImpl) processDataCopy(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	srcCols := srcSchema.ColNames
+	read := false
+	schemaName, table := sourceTable(conv, srcTable)
+	err := copyOut(isi.copy, copyQuery(schemaName, table, srcCols), func(row []byte, loc *time.Location) error {
+		read = true
+		fields := splitCopyRow(row)
+		if len(fields) != len(srcCols) {
+			return fmt.Errorf("COPY returned %d values for %d columns", len(fields), len(srcCols))
+		}
+		v := make([]interface{}, len(srcCols))
+		for i, f := range fields {
+			val, err := decodeCopyValue(f, srcSchema.ColDefs[srcCols[i]].Type, loc)
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
+				conv.StatsAddBadRow(srcTable, conv.DataMode())
+				conv.CollectBadRow(srcTable, srcCols, valsToStrings(copyFieldVals(fields)))
+				return nil
+			}
+			v[i] = val
+		}
+		cvtCols, cvtVals, err := convertSQLRow(conv, srcTable, srcCols, srcSchema, spTable, spCols, spSchema, v)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
+			conv.StatsAddBadRow(srcTable, conv.DataMode())
+			conv.CollectBadRow(srcTable, srcCols, valsToStrings(v))
+			return nil
+		}
+		conv.WriteRow(srcTable, spTable, cvtCols, cvtVals)
+		return nil
+	})
+	if err != nil && !read {
+		return fmt.Errorf("%w: %v", errCopyUnavailable, err)
+	}
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
+	}
+	return err
+}
+
+// PreviewData converts at most n rows of source table srcTable.
+func (isi InfoSchemaImpl) PreviewData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, n int) ([]common.PreviewRow, error) {
+	schemaName, table := sourceTable(conv, srcTable)
+	q := fmt.Sprintf(`SELECT * FROM "%s"."%s" LIMIT %d;`, schemaName, table, n)
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	srcCols, _ := rows.Columns()
+	v, iv := buildVals(len(srcCols))
+	var l []common.PreviewRow
+	for rows.Next() {
+		if err := rows.Scan(iv...); err != nil {
+			return nil, err
+		}
+		cvtCols, cvtVals, err := convertSQLRow(conv, srcTable, srcCols, srcSchema, spTable, spCols, spSchema, v)
+		l = append(l, common.PreviewRow{SrcVals: valsToStrings(v), SpCols: cvtCols, SpVals: cvtVals, Err: err})
+	}
+	return l, rows.Err()
+}
+
 // ConvertSQLRow performs data conversion for a single row of data
 // returned from a 'SELECT *' query. ConvertSQLRow assumes that
 // srcCols, spCols and srcVals all have the same length. Note that
@@ -130,6 +220,7 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcS
 // because cols can change when we add a column (synthetic primary
 // key) or because we drop columns (handling of NULL values).
 func convertSQLRow(conv *internal.Conv, srcTable string, srcCols []string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, srcVals []interface{}) ([]string, []interface{}, error) {
+	defer conv.Timings.TimeTable(internal.StageConvert, srcTable, time.Now())
 	var vs []interface{}
 	var cs []string
 	for i := range srcCols {
@@ -156,9 +247,7 @@ func convertSQLRow(conv *internal.Conv, srcTable string, srcCols []string, srcSc
 	}
 	if aux, ok := conv.SyntheticPKeys[spTable]; ok {
 		cs = append(cs, aux.Col)
-		vs = append(vs, int64(bits.Reverse64(uint64(aux.Sequence))))
-		aux.Sequence++
-		conv.SyntheticPKeys[spTable] = aux
+		vs = append(vs, conv.NextSyntheticKey(spTable))
 	}
 	return cs, vs, nil
 }
@@ -182,6 +271,18 @@ func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error)
 	return 0, nil //Check if 0 is ok to return
 }
 
+// CheckReadAccess implements the common.AccessChecker interface: it queries
+// table without reading any row.
+func (isi InfoSchemaImpl) CheckReadAccess(table common.SchemaAndName) error {
+	// PostgreSQL schema and name can be arbitrary strings, so we quote them.
+	q := fmt.Sprintf(`SELECT 1 FROM "%s"."%s" LIMIT 0;`, table.Schema, table.Name)
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
 // GetTables return list of tables in the selected database.
 // TODO: All of the queries to get tables and table data should be in
 // a single transaction to ensure we obtain a consistent snapshot of
@@ -207,16 +308,105 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 			tables = append(tables, common.SchemaAndName{Schema: tableSchema, Name: tableName})
 		}
 	}
+	if isi.MaterializedViews == internal.MaterializedViewTable {
+		views, err := isi.GetMaterializedViews()
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range views {
+			tables = append(tables, v.SchemaAndName)
+		}
+	}
 	return tables, nil
 }
 
+// GetMaterializedViews returns the materialized views of the database, which
+// the information schema doesn't include.
+func (isi InfoSchemaImpl) GetMaterializedViews() ([]common.MaterializedView, error) {
+	if isi.compat.noMatviews {
+		return nil, nil
+	}
+	q := `SELECT schemaname, matviewname, definition FROM pg_matviews
+              WHERE schemaname NOT IN ('information_schema', 'pg_catalog') ORDER BY schemaname, matviewname;`
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get materialized views: %w", err)
+	}
+	defer rows.Close()
+	var views []common.MaterializedView
+	var v common.MaterializedView
+	for rows.Next() {
+		if err := rows.Scan(&v.Schema, &v.Name, &v.Definition); err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+// GetGrants returns the grants of privileges on tables and on their columns
+// to roles other than the tables' owners and PUBLIC, if the migration of
+// grants is enabled. The information schema also lists the privileges on
+// columns implied by privileges on their tables, which are skipped.
+func (isi InfoSchemaImpl) GetGrants() ([]common.Grant, error) {
+	if !isi.Grants {
+		return nil, nil
+	}
+	q := `SELECT table_schema, table_name, '', grantee, privilege_type FROM information_schema.table_privileges
+              WHERE grantor <> grantee AND grantee <> 'PUBLIC'
+              UNION ALL SELECT c.table_schema, c.table_name, c.column_name, c.grantee, c.privilege_type FROM information_schema.column_privileges c
+              WHERE c.grantor <> c.grantee AND c.grantee <> 'PUBLIC' AND NOT EXISTS (
+                  SELECT 1 FROM information_schema.table_privileges t
+                  WHERE t.table_schema = c.table_schema AND t.table_name = c.table_name AND t.grantee = c.grantee AND t.privilege_type = c.privilege_type);`
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get grants: %w", err)
+	}
+	defer rows.Close()
+	var grants []common.Grant
+	var g common.Grant
+	var col, privilege string
+	for rows.Next() {
+		if err := rows.Scan(&g.Table.Schema, &g.Table.Name, &col, &g.Grantee, &privilege); err != nil {
+			return nil, err
+		}
+		g.Privileges = []string{privilege}
+		g.Columns = nil
+		if col != "" {
+			g.Columns = []string{col}
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
 // GetColumns returns a list of Column objects and names
 func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
-	q := `SELECT c.column_name, c.data_type, e.data_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale
-              FROM information_schema.COLUMNS c LEFT JOIN information_schema.element_types e
+	elementType := "e.data_type"
+	join := `LEFT JOIN information_schema.element_types e
                  ON ((c.table_catalog, c.table_schema, c.table_name, 'TABLE', c.dtd_identifier)
-                     = (e.object_catalog, e.object_schema, e.object_name, e.object_type, e.collection_type_identifier))
-              where table_schema = $1 and table_name = $2 ORDER BY c.ordinal_position;`
+                     = (e.object_catalog, e.object_schema, e.object_name, e.object_type, e.collection_type_identifier))`
+	if isi.compat.noElementTypes {
+		// The udt_name of arrays is the internal name of their element
+		// type prefixed by an underscore e.g. _int4, which the type map
+		// also accepts.
+		elementType, join = "CASE WHEN c.data_type = 'ARRAY' THEN substr(c.udt_name, 2) END", ""
+	}
+	serialSequence := "pg_get_serial_sequence(quote_ident(c.table_schema) || '.' || quote_ident(c.table_name), c.column_name)"
+	if isi.compat.noSerialSequence {
+		serialSequence = "NULL"
+	}
+	var hidden string
+	if isi.compat.hiddenColumns {
+		// Hidden columns, such as the rowid of CockroachDB tables without
+		// a primary key, aren't returned by SELECT *.
+		hidden = "and c.is_hidden = 'NO' "
+	}
+	q := fmt.Sprintf(`SELECT c.column_name, c.data_type, %s, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale,
+                     c.udt_schema, c.udt_name, c.domain_schema, c.domain_name,
+                     %s
+              FROM information_schema.COLUMNS c %s
+              where table_schema = $1 and table_name = $2 %sORDER BY c.ordinal_position;`, elementType, serialSequence, join, hidden)
 	cols, err := isi.Db.Query(q, table.Schema, table.Name)
 	if err != nil {
 		return nil, nil, fmt.Errorf("couldn't get schema for table %s.%s: %s", table.Schema, table.Name, err)
@@ -224,10 +414,12 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 	colDefs := make(map[string]schema.Column)
 	var colNames []string
 	var colName, dataType, isNullable string
-	var colDefault, elementDataType sql.NullString
+	var colDefault, elementDataType, udtSchema, udtName, domainSchema, domainName, seqName sql.NullString
 	var charMaxLen, numericPrecision, numericScale sql.NullInt64
+	var userDefined []udtColumn
+	var sequences []string
 	for cols.Next() {
-		err := cols.Scan(&colName, &dataType, &elementDataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale)
+		err := cols.Scan(&colName, &dataType, &elementDataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &udtSchema, &udtName, &domainSchema, &domainName, &seqName)
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
 			continue
@@ -251,12 +443,174 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 			NotNull: common.ToNotNull(conv, isNullable),
 			Ignored: ignored,
 		}
+		// Serial and identity columns are backed by a sequence, which
+		// serial columns use as default value.
+		if seqName.Valid {
+			c.Sequence = &schema.Sequence{Name: seqName.String}
+			c.Ignored.Default = colDefault.Valid && !strings.HasPrefix(colDefault.String, "nextval(")
+			sequences = append(sequences, colName)
+		}
+		// Domains are reported with data type set to their base type, and
+		// composite types (as well as enums) as USER-DEFINED.
+		switch {
+		case domainName.Valid:
+			c.Type.UserDefined = domainSchema.String + "." + domainName.String
+			userDefined = append(userDefined, udtColumn{colName, domainSchema.String, domainName.String})
+		case dataType == "USER-DEFINED" && udtName.Valid:
+			c.Type.UserDefined = udtSchema.String + "." + udtName.String
+			userDefined = append(userDefined, udtColumn{colName, udtSchema.String, udtName.String})
+		}
 		colDefs[colName] = c
 		colNames = append(colNames, colName)
 	}
+	cols.Close()
+	if len(colNames) == 0 && isi.MaterializedViews == internal.MaterializedViewTable {
+		// The information schema doesn't include the columns of
+		// materialized views.
+		return isi.getMaterializedViewColumns(conv, table)
+	}
+	for _, col := range sequences {
+		c := colDefs[col]
+		lastValue, err := isi.getSequenceLastValue(table, col, c.Sequence.Name)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't get last value of sequence %s of column %s: %v", c.Sequence.Name, col, err))
+			c.Sequence = nil
+		} else {
+			c.Sequence.LastValue = lastValue
+		}
+		colDefs[col] = c
+	}
+	for _, u := range userDefined {
+		c := colDefs[u.col]
+		var err error
+		if c.Type.Name == "USER-DEFINED" {
+			c.Type, err = isi.getCompositeType(c.Type, u.schema, u.name)
+		} else {
+			c.Type.Checks, err = isi.getDomainChecks(u.schema, u.name)
+		}
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't get user-defined type %s of column %s: %v", c.Type.UserDefined, c.Name, err))
+			continue
+		}
+		colDefs[u.col] = c
+	}
 	return colDefs, colNames, nil
 }
 
+// getMaterializedViewColumns returns the columns of materialized view table,
+// read from the system catalog. Types are given by their internal names e.g.
+// int4 and varchar, which the type map also accepts.
+func (isi InfoSchemaImpl) getMaterializedViewColumns(conv *internal.Conv, table common.SchemaAndName) (map[string]schema.Column, []string, error) {
+	q := `SELECT a.attname, CASE WHEN e.oid IS NOT NULL THEN 'ARRAY' ELSE t.typname END, e.typname, a.attnotnull, a.atttypmod
+              FROM pg_attribute a
+                INNER JOIN pg_class c ON a.attrelid = c.oid
+                INNER JOIN pg_namespace n ON c.relnamespace = n.oid
+                INNER JOIN pg_type t ON a.atttypid = t.oid
+                LEFT JOIN pg_type e ON t.typelem = e.oid AND t.typlen = -1
+              WHERE n.nspname = $1 AND c.relname = $2 AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum;`
+	rows, err := isi.Db.Query(q, table.Schema, table.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get schema for materialized view %s.%s: %s", table.Schema, table.Name, err)
+	}
+	defer rows.Close()
+	colDefs := make(map[string]schema.Column)
+	var colNames []string
+	var colName, dataType string
+	var elementDataType sql.NullString
+	var notNull bool
+	var typmod int64
+	for rows.Next() {
+		if err := rows.Scan(&colName, &dataType, &elementDataType, &notNull, &typmod); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		// The type modifier of varchar(n) and bpchar(n) is n+4, and the
+		// one of numeric(p, s) is (p << 16 | s) + 4; -1 if unspecified.
+		var charLen, numericPrecision, numericScale sql.NullInt64
+		switch {
+		case typmod < 4:
+		case dataType == "varchar" || dataType == "bpchar":
+			charLen = sql.NullInt64{Int64: typmod - 4, Valid: true}
+		case dataType == "numeric":
+			numericPrecision = sql.NullInt64{Int64: (typmod - 4) >> 16, Valid: true}
+			numericScale = sql.NullInt64{Int64: (typmod - 4) & 0xffff, Valid: true}
+		}
+		colDefs[colName] = schema.Column{
+			Name:    colName,
+			Type:    toType(dataType, elementDataType, charLen, numericPrecision, numericScale),
+			NotNull: notNull,
+		}
+		colNames = append(colNames, colName)
+	}
+	return colDefs, colNames, rows.Err()
+}
+
+// getSequenceLastValue returns the largest value generated by sequence seq,
+// the (quoted) sequence name returned by pg_get_serial_sequence, or stored in
+// column col of table, which may have been inserted explicitly.
+func (isi InfoSchemaImpl) getSequenceLastValue(table common.SchemaAndName, col, seq string) (int64, error) {
+	q := fmt.Sprintf(`SELECT GREATEST((SELECT last_value FROM %s), (SELECT max("%s") FROM "%s"."%s"))`, seq, col, table.Schema, table.Name)
+	var lastValue sql.NullInt64
+	if err := isi.Db.QueryRow(q).Scan(&lastValue); err != nil {
+		return 0, err
+	}
+	return lastValue.Int64, nil
+}
+
+// udtColumn is a column of user-defined type schema.name.
+type udtColumn struct {
+	col, schema, name string
+}
+
+// getCompositeType returns ty, of user-defined type udtSchema.udtName, as a
+// composite type with the names of its fields, or unchanged if the
+// user-defined type isn't a composite type (e.g. an enum).
+func (isi InfoSchemaImpl) getCompositeType(ty schema.Type, udtSchema, udtName string) (schema.Type, error) {
+	q := `SELECT attribute_name FROM information_schema.attributes
+              WHERE udt_schema = $1 AND udt_name = $2 ORDER BY ordinal_position;`
+	rows, err := isi.Db.Query(q, udtSchema, udtName)
+	if err != nil {
+		return ty, err
+	}
+	defer rows.Close()
+	var fields []string
+	var field string
+	for rows.Next() {
+		if err := rows.Scan(&field); err != nil {
+			return ty, err
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return ty, rows.Err()
+	}
+	ty.Name, ty.Fields = compositeType, fields
+	return ty, rows.Err()
+}
+
+// getDomainChecks returns the CHECK constraints of domain
+// domainSchema.domainName, as boolean expressions on VALUE e.g. "(VALUE > 0)".
+func (isi InfoSchemaImpl) getDomainChecks(domainSchema, domainName string) ([]string, error) {
+	q := `SELECT cc.check_clause FROM information_schema.domain_constraints d
+                INNER JOIN information_schema.check_constraints cc
+                  ON d.constraint_schema = cc.constraint_schema AND d.constraint_name = cc.constraint_name
+              WHERE d.domain_schema = $1 AND d.domain_name = $2 ORDER BY d.constraint_name;`
+	rows, err := isi.Db.Query(q, domainSchema, domainName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var checks []string
+	var check string
+	for rows.Next() {
+		if err := rows.Scan(&check); err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
+
 // GetConstraints returns a list of primary keys and by-column map of
 // other constraints.  Note: we need to preserve ordinal order of
 // columns in primary key constraints.
@@ -297,30 +651,10 @@ func (isi InfoSchemaImpl) GetConstraints(conv *internal.Conv, table common.Schem
 
 // GetForeignKeys returns a list of all the foreign key constraints.
 func (isi InfoSchemaImpl) GetForeignKeys(conv *internal.Conv, table common.SchemaAndName) (foreignKeys []schema.ForeignKey, err error) {
-	q := `SELECT 
-		schema_name AS "TABLE_SCHEMA", 
-		cl.relname AS "TABLE_NAME", 
-		att2.attname AS "COLUMN_NAME", 
-		att.attname AS "REF_COLUMN_NAME", 
-		conname AS "CONSTRAINT_NAME"
-		FROM (SELECT 
-			UNNEST(con1.conkey) AS "parent", 
-			UNNEST(con1.confkey) AS "child", 
-			con1.confrelid, 
-			con1.conrelid, 
-			con1.conname, 
-			ns.nspname AS schema_name
-    		FROM PG_CLASS cl
-        		JOIN PG_NAMESPACE ns ON cl.relnamespace = ns.oid
-        		JOIN PG_CONSTRAINT con1 ON con1.conrelid = cl.oid
-    			WHERE ns.nspname = $1 AND cl.relname = $2 AND con1.contype = 'f') con
-   		JOIN PG_ATTRIBUTE att ON
-       		att.attrelid = con.confrelid AND att.attnum = con.child
-   		JOIN PG_CLASS cl ON
-       		cl.oid = con.confrelid
-   		JOIN PG_ATTRIBUTE att2 ON
-       		att2.attrelid = con.conrelid AND att2.attnum = con.parent;`
-
+	q := foreignKeysQuery
+	if isi.compat.noPgConstraint {
+		q = foreignKeysInfoSchemaQuery
+	}
 	rows, err := isi.Db.Query(q, table.Schema, table.Name)
 	if err != nil {
 		return nil, err
@@ -360,6 +694,45 @@ func (isi InfoSchemaImpl) GetForeignKeys(conv *internal.Conv, table common.Schem
 	return foreignKeys, nil
 }
 
+// foreignKeysQuery returns the columns of the foreign keys of a table, and
+// those they reference, from the system catalog.
+const foreignKeysQuery = `SELECT 
+		schema_name AS "TABLE_SCHEMA", 
+		cl.relname AS "TABLE_NAME", 
+		att2.attname AS "COLUMN_NAME", 
+		att.attname AS "REF_COLUMN_NAME", 
+		conname AS "CONSTRAINT_NAME"
+		FROM (SELECT 
+			UNNEST(con1.conkey) AS "parent", 
+			UNNEST(con1.confkey) AS "child", 
+			con1.confrelid, 
+			con1.conrelid, 
+			con1.conname, 
+			ns.nspname AS schema_name
+    		FROM PG_CLASS cl
+        		JOIN PG_NAMESPACE ns ON cl.relnamespace = ns.oid
+        		JOIN PG_CONSTRAINT con1 ON con1.conrelid = cl.oid
+    			WHERE ns.nspname = $1 AND cl.relname = $2 AND con1.contype = 'f') con
+   		JOIN PG_ATTRIBUTE att ON
+       		att.attrelid = con.confrelid AND att.attnum = con.child
+   		JOIN PG_CLASS cl ON
+       		cl.oid = con.confrelid
+   		JOIN PG_ATTRIBUTE att2 ON
+       		att2.attrelid = con.conrelid AND att2.attnum = con.parent;`
+
+// foreignKeysInfoSchemaQuery returns the same as foreignKeysQuery from the
+// information schema, matching the columns of foreign keys with those of the
+// unique constraints they reference by position.
+const foreignKeysInfoSchemaQuery = `SELECT rk.table_schema, rk.table_name, k.column_name, rk.column_name, k.constraint_name
+	FROM information_schema.referential_constraints r
+	JOIN information_schema.key_column_usage k
+		ON k.constraint_schema = r.constraint_schema AND k.constraint_name = r.constraint_name
+	JOIN information_schema.key_column_usage rk
+		ON rk.constraint_schema = r.unique_constraint_schema AND rk.constraint_name = r.unique_constraint_name
+			AND rk.ordinal_position = k.position_in_unique_constraint
+	WHERE k.table_schema = $1 AND k.table_name = $2
+	ORDER BY k.constraint_name, k.ordinal_position;`
+
 // GetIndexes return a list of all indexes for the specified table.
 // Note: Extracting index definitions from PostgreSQL information schema tables is complex.
 // See https://stackoverflow.com/questions/6777456/list-all-index-names-column-names-and-its-table-name-of-a-postgresql-database/44460269#44460269
@@ -394,6 +767,16 @@ func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAnd
            		array_position(i.indkey, a.attnum),
            		o.OPTION,i.indisunique
 		ORDER BY irel.relname, array_position(i.indkey, a.attnum);`
+	if isi.compat.noPgIndex {
+		// information_schema.statistics, as in MySQL, is a common extension
+		// of pg-wire databases e.g. CockroachDB.
+		q = `SELECT s.index_name, s.column_name, s.seq_in_index, s.non_unique = 'NO', s.direction
+			FROM information_schema.statistics AS s
+			WHERE s.table_schema = $1 AND s.table_name = $2 AND s.storing = 'NO' AND s.implicit = 'NO'
+				AND s.index_name NOT IN (SELECT constraint_name FROM information_schema.table_constraints
+					WHERE table_schema = $1 AND table_name = $2 AND constraint_type = 'PRIMARY KEY')
+			ORDER BY s.index_name, s.seq_in_index;`
+	}
 	rows, err := isi.Db.Query(q, table.Schema, table.Name)
 	if err != nil {
 		return nil, err
@@ -462,6 +845,14 @@ func cvtSQLArray(conv *internal.Conv, srcCd schema.Column, spCd ddl.ColumnDef, v
 //    string
 //    time.Time
 func cvtSQLScalar(conv *internal.Conv, srcCd schema.Column, spCd ddl.ColumnDef, val interface{}) (interface{}, error) {
+	if srcCd.Type.Name == compositeType {
+		switch v := val.(type) {
+		case []byte:
+			return convComposite(srcCd.Type.Fields, string(v))
+		case string:
+			return convComposite(srcCd.Type.Fields, v)
+		}
+	}
 	switch spCd.T.Name {
 	case ddl.Bool:
 		switch v := val.(type) {
@@ -15,9 +15,12 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
+	"fmt"
 	"math/big"
+	"regexp"
 	"testing"
 	"time"
 
@@ -54,6 +57,9 @@ func TestProcessSchema(t *testing.T) {
 				{"public", "product"},
 				{"public", "test"},
 				{"public", "test_ref"}},
+		}, {
+			query: "SELECT (.+) FROM pg_matviews (.+)",
+			cols:  []string{"schemaname", "matviewname", "definition"},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"public", "user"},
@@ -75,11 +81,11 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "user"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
 			rows: [][]driver.Value{
-				{"user_id", "text", nil, "NO", nil, nil, nil, nil},
-				{"name", "text", nil, "NO", nil, nil, nil, nil},
-				{"ref", "bigint", nil, "YES", nil, nil, nil, nil}},
+				{"user_id", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"name", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"ref", "bigint", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil}},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"public", "cart"},
@@ -107,11 +113,11 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "cart"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
 			rows: [][]driver.Value{
-				{"productid", "text", nil, "NO", nil, nil, nil, nil},
-				{"userid", "text", nil, "NO", nil, nil, nil, nil},
-				{"quantity", "bigint", nil, "YES", nil, nil, 64, 0}},
+				{"productid", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"userid", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"quantity", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil, nil, nil, nil}},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"public", "product"},
@@ -129,10 +135,10 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "product"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
 			rows: [][]driver.Value{
-				{"product_id", "text", nil, "NO", nil, nil, nil, nil},
-				{"product_name", "text", nil, "NO", nil, nil, nil, nil}},
+				{"product_id", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"product_name", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil}},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"public", "test"},
@@ -151,29 +157,29 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "test"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
 			rows: [][]driver.Value{
-				{"id", "bigint", nil, "NO", nil, nil, 64, 0},
-				{"aint", "ARRAY", "integer", "YES", nil, nil, nil, nil},
-				{"atext", "ARRAY", "text", "YES", nil, nil, nil, nil},
-				{"b", "boolean", nil, "YES", nil, nil, nil, nil},
-				{"bs", "bigint", nil, "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0},
-				{"by", "bytea", nil, "YES", nil, nil, nil, nil},
-				{"c", "character", nil, "YES", nil, 1, nil, nil},
-				{"c8", "character", nil, "YES", nil, 8, nil, nil},
-				{"d", "date", nil, "YES", nil, nil, nil, nil},
-				{"f8", "double precision", nil, "YES", nil, nil, 53, nil},
-				{"f4", "real", nil, "YES", nil, nil, 24, nil},
-				{"i8", "bigint", nil, "YES", nil, nil, 64, 0},
-				{"i4", "integer", nil, "YES", nil, nil, 32, 0},
-				{"i2", "smallint", nil, "YES", nil, nil, 16, 0},
-				{"num", "numeric", nil, "YES", nil, nil, nil, nil},
-				{"s", "integer", nil, "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0},
-				{"ts", "timestamp without time zone", nil, "YES", nil, nil, nil, nil},
-				{"tz", "timestamp with time zone", nil, "YES", nil, nil, nil, nil},
-				{"txt", "text", nil, "NO", nil, nil, nil, nil},
-				{"vc", "character varying", nil, "YES", nil, nil, nil, nil},
-				{"vc6", "character varying", nil, "YES", nil, 6, nil, nil}},
+				{"id", "bigint", nil, "NO", nil, nil, 64, 0, nil, nil, nil, nil, nil},
+				{"aint", "ARRAY", "integer", "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"atext", "ARRAY", "text", "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"b", "boolean", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"bs", "bigint", nil, "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0, nil, nil, nil, nil, nil},
+				{"by", "bytea", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"c", "character", nil, "YES", nil, 1, nil, nil, nil, nil, nil, nil, nil},
+				{"c8", "character", nil, "YES", nil, 8, nil, nil, nil, nil, nil, nil, nil},
+				{"d", "date", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"f8", "double precision", nil, "YES", nil, nil, 53, nil, nil, nil, nil, nil, nil},
+				{"f4", "real", nil, "YES", nil, nil, 24, nil, nil, nil, nil, nil, nil},
+				{"i8", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil, nil, nil, nil},
+				{"i4", "integer", nil, "YES", nil, nil, 32, 0, nil, nil, nil, nil, nil},
+				{"i2", "smallint", nil, "YES", nil, nil, 16, 0, nil, nil, nil, nil, nil},
+				{"num", "numeric", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"s", "integer", nil, "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0, nil, nil, nil, nil, nil},
+				{"ts", "timestamp without time zone", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"tz", "timestamp with time zone", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"txt", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"vc", "character varying", nil, "YES", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"vc6", "character varying", nil, "YES", nil, 6, nil, nil, nil, nil, nil, nil, nil}},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"public", "test_ref"},
@@ -192,16 +198,16 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "test_ref"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
 			rows: [][]driver.Value{
-				{"ref_id", "bigint", nil, "NO", nil, nil, 64, 0},
-				{"ref_txt", "text", nil, "NO", nil, nil, nil, nil},
-				{"abc", "text", nil, "NO", nil, nil, nil, nil}},
+				{"ref_id", "bigint", nil, "NO", nil, nil, 64, 0, nil, nil, nil, nil, nil},
+				{"ref_txt", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"abc", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil}},
 		},
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
-	err := common.ProcessSchema(conv, InfoSchemaImpl{db})
+	err := common.ProcessSchema(conv, InfoSchemaImpl{Db: db})
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
 		"user": ddl.CreateTable{
@@ -329,7 +335,7 @@ func TestProcessData(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessData(conv, InfoSchemaImpl{db})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{Db: db})
 
 	assert.Equal(t,
 		[]spannerData{
@@ -424,6 +430,9 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 			query: "SELECT table_schema, table_name FROM information_schema.tables where table_type = 'BASE TABLE'",
 			cols:  []string{"table_schema", "table_name"},
 			rows:  [][]driver.Value{{"public", "test"}},
+		}, {
+			query: "SELECT (.+) FROM pg_matviews (.+)",
+			cols:  []string{"schemaname", "matviewname", "definition"},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"public", "test"},
@@ -442,11 +451,11 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "test"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
 			rows: [][]driver.Value{
-				{"a", "text", nil, "NO", nil, nil, nil, nil},
-				{"b", "double precision", nil, "YES", nil, nil, 53, nil},
-				{"c", "bigint", nil, "YES", nil, nil, 64, 0}},
+				{"a", "text", nil, "NO", nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"b", "double precision", nil, "YES", nil, nil, 53, nil, nil, nil, nil, nil, nil},
+				{"c", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil, nil, nil, nil}},
 		}, {
 			query: `SELECT [*] FROM "public"."test"`, // query is a regexp!
 			cols:  []string{"a", "b", "c"},
@@ -457,7 +466,7 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
-	err := common.ProcessSchema(conv, InfoSchemaImpl{db})
+	err := common.ProcessSchema(conv, InfoSchemaImpl{Db: db})
 	assert.Nil(t, err)
 	conv.SetDataMode()
 	var rows []spannerData
@@ -465,7 +474,7 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 		func(table string, cols []string, vals []interface{}) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
-	common.ProcessData(conv, InfoSchemaImpl{db})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{Db: db})
 	assert.Equal(t, []spannerData{
 		{table: "test", cols: []string{"a", "b", "synth_id"}, vals: []interface{}{"cat", float64(42.3), int64(0)}},
 		{table: "test", cols: []string{"a", "c", "synth_id"}, vals: []interface{}{"dog", int64(22), int64(-9223372036854775808)}}},
@@ -473,6 +482,285 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 	assert.Equal(t, int64(0), conv.Unexpecteds())
 }
 
+func TestProcessSchema_UserDefinedTypes(t *testing.T) {
+	// Tests domains, which map to their base type and CHECK constraints,
+	// and composite types, which map to JSON.
+	ms := []mockSpec{
+		{
+			query: "SELECT table_schema, table_name FROM information_schema.tables where table_type = 'BASE TABLE'",
+			cols:  []string{"table_schema", "table_name"},
+			rows:  [][]driver.Value{{"public", "test"}},
+		}, {
+			query: "SELECT (.+) FROM pg_matviews (.+)",
+			cols:  []string{"schemaname", "matviewname", "definition"},
+		}, {
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"column_name", "constraint_type"},
+			rows:  [][]driver.Value{{"id", "PRIMARY KEY"}},
+		}, {
+			query: "SELECT (.+) FROM PG_CLASS (.+) JOIN PG_NAMESPACE (.+) JOIN PG_CONSTRAINT (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME", "REF_COLUMN_NAME", "CONSTRAINT_NAME"},
+		}, {
+			query: "SELECT (.+) FROM pg_index (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
+		}, {
+			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
+			rows: [][]driver.Value{
+				{"id", "bigint", nil, "NO", nil, nil, 64, 0, "pg_catalog", "int8", nil, nil, nil},
+				{"qty", "integer", nil, "YES", nil, nil, 32, 0, "pg_catalog", "int4", "public", "positive_int", nil},
+				{"code", "text", nil, "YES", nil, nil, nil, nil, "pg_catalog", "text", "public", "us_code", nil},
+				{"addr", "USER-DEFINED", nil, "YES", nil, nil, nil, nil, "public", "address", nil, nil, nil}},
+		}, {
+			query: "SELECT (.+) FROM information_schema.domain_constraints (.+)",
+			args:  []driver.Value{"public", "positive_int"},
+			cols:  []string{"check_clause"},
+			rows:  [][]driver.Value{{"(VALUE > 0)"}},
+		}, {
+			query: "SELECT (.+) FROM information_schema.domain_constraints (.+)",
+			args:  []driver.Value{"public", "us_code"},
+			cols:  []string{"check_clause"},
+			rows:  [][]driver.Value{{"(VALUE ~ '^[0-9]{5}$'::text)"}},
+		}, {
+			query: "SELECT (.+) FROM information_schema.attributes (.+)",
+			args:  []driver.Value{"public", "address"},
+			cols:  []string{"attribute_name"},
+			rows:  [][]driver.Value{{"street"}, {"zip"}},
+		}, {
+			query: `SELECT [*] FROM "public"."test"`, // query is a regexp!
+			cols:  []string{"id", "qty", "code", "addr"},
+			rows: [][]driver.Value{
+				{int64(1), int64(2), "12345", []byte(`("Main St, 1",)`)}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	err := common.ProcessSchema(conv, InfoSchemaImpl{Db: db})
+	assert.Nil(t, err)
+	assert.Equal(t, schema.Type{Name: "integer", UserDefined: "public.positive_int", Checks: []string{"(VALUE > 0)"}}, conv.SrcSchema["test"].ColDefs["qty"].Type)
+	assert.Equal(t, schema.Type{Name: compositeType, UserDefined: "public.address", Fields: []string{"street", "zip"}}, conv.SrcSchema["test"].ColDefs["addr"].Type)
+	assert.Equal(t, "(VALUE > 0)", conv.SpSchema["test"].ColDefs["qty"].Check)
+	assert.Equal(t, "", conv.SpSchema["test"].ColDefs["code"].Check)
+	assert.Equal(t, ddl.Type{Name: ddl.JSON}, conv.SpSchema["test"].ColDefs["addr"].T)
+	assert.Equal(t, map[string][]internal.SchemaIssue{
+		"qty":  {internal.Widened, internal.Domain},
+		"code": {internal.Domain, internal.DomainCheck},
+		"addr": {internal.Composite},
+	}, conv.Issues["test"])
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+	common.ProcessData(context.Background(), conv, InfoSchemaImpl{Db: db})
+	assert.Equal(t, []spannerData{
+		{table: "test", cols: []string{"id", "qty", "code", "addr"}, vals: []interface{}{int64(1), int64(2), "12345", `{"street": "Main St, 1", "zip": null}`}}},
+		rows)
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestProcessSchema_Sequences(t *testing.T) {
+	// Tests serial and identity columns, whose sequences are converted into
+	// Spanner sequences that skip the migrated values.
+	ms := []mockSpec{
+		{
+			query: "SELECT table_schema, table_name FROM information_schema.tables where table_type = 'BASE TABLE'",
+			cols:  []string{"table_schema", "table_name"},
+			rows:  [][]driver.Value{{"public", "test"}},
+		}, {
+			query: "SELECT (.+) FROM pg_matviews (.+)",
+			cols:  []string{"schemaname", "matviewname", "definition"},
+		}, {
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"column_name", "constraint_type"},
+			rows:  [][]driver.Value{{"id", "PRIMARY KEY"}},
+		}, {
+			query: "SELECT (.+) FROM PG_CLASS (.+) JOIN PG_NAMESPACE (.+) JOIN PG_CONSTRAINT (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME", "REF_COLUMN_NAME", "CONSTRAINT_NAME"},
+		}, {
+			query: "SELECT (.+) FROM pg_index (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
+		}, {
+			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
+			args:  []driver.Value{"public", "test"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
+			rows: [][]driver.Value{
+				{"id", "bigint", nil, "NO", "nextval('test_id_seq'::regclass)", nil, 64, 0, "pg_catalog", "int8", nil, nil, "public.test_id_seq"},
+				{"num", "integer", nil, "NO", nil, nil, 32, 0, "pg_catalog", "int4", nil, nil, "public.test_num_seq"},
+				{"qty", "integer", nil, "YES", "0", nil, 32, 0, "pg_catalog", "int4", nil, nil, nil}},
+		}, {
+			query: `SELECT GREATEST\(\(SELECT last_value FROM public.test_id_seq\), \(SELECT max\("id"\) FROM "public"."test"\)\)`,
+			cols:  []string{"greatest"},
+			rows:  [][]driver.Value{{int64(1042)}},
+		}, {
+			query: `SELECT GREATEST\(\(SELECT last_value FROM public.test_num_seq\), \(SELECT max\("num"\) FROM "public"."test"\)\)`,
+			cols:  []string{"greatest"},
+			rows:  [][]driver.Value{{nil}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	err := common.ProcessSchema(conv, InfoSchemaImpl{Db: db})
+	assert.Nil(t, err)
+	assert.Equal(t, &schema.Sequence{Name: "public.test_id_seq", LastValue: 1042}, conv.SrcSchema["test"].ColDefs["id"].Sequence)
+	assert.False(t, conv.SrcSchema["test"].ColDefs["id"].Ignored.Default)
+	assert.Equal(t, &ddl.Sequence{Name: "test_id_seq", SkipRangeMin: 1, SkipRangeMax: 1042, StartWithCounter: 1043}, conv.SpSchema["test"].ColDefs["id"].Sequence)
+	assert.Equal(t, &ddl.Sequence{Name: "test_num_seq", StartWithCounter: 1}, conv.SpSchema["test"].ColDefs["num"].Sequence)
+	assert.Nil(t, conv.SpSchema["test"].ColDefs["qty"].Sequence)
+	assert.Equal(t, map[string][]internal.SchemaIssue{
+		"id":  {internal.SourceSequence},
+		"num": {internal.Widened, internal.SourceSequence},
+		"qty": {internal.Widened, internal.DefaultValue},
+	}, conv.Issues["test"])
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestProcessSchema_Compat(t *testing.T) {
+	// Tests a CockroachDB database, which lacks some of the catalog of
+	// PostgreSQL: indexes and foreign keys are read from the information
+	// schema, and the hidden rowid column is skipped.
+	ms := []mockSpec{
+		{
+			query: "SELECT table_schema, table_name FROM information_schema.tables where table_type = 'BASE TABLE'",
+			cols:  []string{"table_schema", "table_name"},
+			rows:  [][]driver.Value{{"public", "orders"}},
+		}, {
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
+			args:  []driver.Value{"public", "orders"},
+			cols:  []string{"column_name", "constraint_type"},
+			rows:  [][]driver.Value{{"id", "PRIMARY KEY"}, {"parent_id", "FOREIGN KEY"}},
+		}, {
+			query: "SELECT (.+) FROM information_schema.referential_constraints (.+) JOIN information_schema.key_column_usage (.+)",
+			args:  []driver.Value{"public", "orders"},
+			cols:  []string{"table_schema", "table_name", "column_name", "column_name", "constraint_name"},
+			rows:  [][]driver.Value{{"public", "orders", "parent_id", "id", "fk_parent"}},
+		}, {
+			query: "SELECT (.+) FROM information_schema.statistics (.+)",
+			args:  []driver.Value{"public", "orders"},
+			cols:  []string{"index_name", "column_name", "seq_in_index", "non_unique", "direction"},
+			rows:  [][]driver.Value{{"orders_parent_idx", "parent_id", 1, false, "DESC"}},
+		}, {
+			query: "SELECT (.+) CASE WHEN c.data_type = 'ARRAY' THEN substr(.+) NULL FROM information_schema.COLUMNS c where (.+) and c.is_hidden = 'NO' ORDER BY (.+)",
+			args:  []driver.Value{"public", "orders"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
+			rows: [][]driver.Value{
+				{"id", "bigint", nil, "NO", "unique_rowid()", nil, 64, 0, "pg_catalog", "int8", nil, nil, nil},
+				{"parent_id", "bigint", nil, "YES", nil, nil, 64, 0, "pg_catalog", "int8", nil, nil, nil},
+				{"tags", "ARRAY", "text", "YES", nil, nil, nil, nil, "pg_catalog", "_text", nil, nil, nil}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	isi := InfoSchemaImpl{Db: db, compat: compat{flavor: "CockroachDB", noSerialSequence: true, noElementTypes: true, noMatviews: true, noPgIndex: true, noPgConstraint: true, hiddenColumns: true}}
+	err := common.ProcessSchema(conv, isi)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "parent_id", "tags"}, conv.SrcSchema["orders"].ColNames)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}, conv.SpSchema["orders"].ColDefs["tags"].T)
+	assert.Equal(t, []schema.ForeignKey{{Name: "fk_parent", Columns: []string{"parent_id"}, ReferTable: "orders", ReferColumns: []string{"id"}}}, conv.SrcSchema["orders"].ForeignKeys)
+	assert.Equal(t, []schema.Index{{Name: "orders_parent_idx", Keys: []schema.Key{{Column: "parent_id", Desc: true}}}}, conv.SrcSchema["orders"].Indexes)
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestDetectCompat(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version()")).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("CockroachDB CCL v23.1.11 (x86_64-pc-linux-gnu, built 2023/09/27 01:53:43, go1.19.10)"))
+	mock.ExpectQuery(regexp.QuoteMeta(serialSequenceProbe)).WillReturnRows(sqlmock.NewRows([]string{"pg_get_serial_sequence"}).AddRow(nil))
+	mock.ExpectQuery(regexp.QuoteMeta(elementTypesProbe)).WillReturnError(fmt.Errorf(`relation "information_schema.element_types" does not exist`))
+	mock.ExpectQuery(regexp.QuoteMeta(matviewsProbe)).WillReturnRows(sqlmock.NewRows([]string{"schemaname", "matviewname", "definition"}))
+	mock.ExpectQuery(regexp.QuoteMeta(pgIndexProbe)).WillReturnError(fmt.Errorf("unknown signature: array_position(int2vector, int2)"))
+	mock.ExpectQuery(regexp.QuoteMeta(pgConstraintProbe)).WillReturnRows(sqlmock.NewRows([]string{"unnest", "unnest"}))
+	mock.ExpectQuery(regexp.QuoteMeta(hiddenColumnsProbe)).WillReturnRows(sqlmock.NewRows([]string{"is_hidden"}))
+	assert.Equal(t, compat{flavor: "CockroachDB", noElementTypes: true, noPgIndex: true, hiddenColumns: true}, detectCompat(db))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessSchema_MaterializedViews(t *testing.T) {
+	// Tests a materialized view converted into a table, whose columns
+	// aren't in the information schema.
+	matviews := mockSpec{
+		query: "SELECT (.+) FROM pg_matviews (.+)",
+		cols:  []string{"schemaname", "matviewname", "definition"},
+		rows:  [][]driver.Value{{"public", "totals", " SELECT product, sum(qty) AS total FROM orders GROUP BY product;"}},
+	}
+	ms := []mockSpec{
+		{
+			query: "SELECT table_schema, table_name FROM information_schema.tables where table_type = 'BASE TABLE'",
+			cols:  []string{"table_schema", "table_name"},
+		},
+		matviews,
+		matviews,
+		{
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
+			args:  []driver.Value{"public", "totals"},
+			cols:  []string{"column_name", "constraint_type"},
+		}, {
+			query: "SELECT (.+) FROM PG_CLASS (.+) JOIN PG_NAMESPACE (.+) JOIN PG_CONSTRAINT (.+)",
+			args:  []driver.Value{"public", "totals"},
+			cols:  []string{"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME", "REF_COLUMN_NAME", "CONSTRAINT_NAME"},
+		}, {
+			query: "SELECT (.+) FROM pg_index (.+)",
+			args:  []driver.Value{"public", "totals"},
+			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
+		}, {
+			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
+			args:  []driver.Value{"public", "totals"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "udt_schema", "udt_name", "domain_schema", "domain_name", "pg_get_serial_sequence"},
+		}, {
+			query: "SELECT (.+) FROM pg_attribute (.+)",
+			args:  []driver.Value{"public", "totals"},
+			cols:  []string{"attname", "typname", "typname", "attnotnull", "atttypmod"},
+			rows: [][]driver.Value{
+				{"product", "varchar", nil, false, 24},
+				{"total", "numeric", nil, false, -1}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	err := common.ProcessSchema(conv, InfoSchemaImpl{Db: db, MaterializedViews: internal.MaterializedViewTable})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]internal.MaterializedView{
+		"totals": {Name: "totals", Definition: " SELECT product, sum(qty) AS total FROM orders GROUP BY product;", Table: true},
+	}, conv.MaterializedViews)
+	assert.Equal(t, []string{"product", "total"}, conv.SrcSchema["totals"].ColNames)
+	assert.Equal(t, schema.Type{Name: "varchar", Mods: []int64{20}}, conv.SrcSchema["totals"].ColDefs["product"].Type)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 20}, conv.SpSchema["totals"].ColDefs["product"].T)
+	assert.Equal(t, ddl.Type{Name: ddl.Numeric}, conv.SpSchema["totals"].ColDefs["total"].T)
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestGetGrants(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT (.+) FROM information_schema.table_privileges (.+) UNION ALL SELECT (.+) FROM information_schema.column_privileges (.+)",
+			cols:  []string{"table_schema", "table_name", "column_name", "grantee", "privilege_type"},
+			rows: [][]driver.Value{
+				{"public", "cart", "", "reader", "SELECT"},
+				{"public", "cart", "quantity", "reader", "UPDATE"},
+			},
+		},
+	}
+	grants, err := InfoSchemaImpl{Db: mkMockDB(t, ms), Grants: true}.GetGrants()
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Grant{
+		{Table: common.SchemaAndName{Schema: "public", Name: "cart"}, Grantee: "reader", Privileges: []string{"SELECT"}},
+		{Table: common.SchemaAndName{Schema: "public", Name: "cart"}, Columns: []string{"quantity"}, Grantee: "reader", Privileges: []string{"UPDATE"}},
+	}, grants)
+
+	// Grants aren't read unless enabled.
+	grants, err = InfoSchemaImpl{Db: mkMockDB(t, nil)}.GetGrants()
+	assert.Nil(t, err)
+	assert.Nil(t, grants)
+}
+
 func TestSetRowStats(t *testing.T) {
 	ms := []mockSpec{
 		{
@@ -492,7 +780,7 @@ func TestSetRowStats(t *testing.T) {
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
 	conv.SetDataMode()
-	common.SetRowStats(conv, InfoSchemaImpl{db})
+	common.SetRowStats(conv, InfoSchemaImpl{Db: db})
 	assert.Equal(t, int64(5), conv.Stats.Rows["test1"])
 	assert.Equal(t, int64(142), conv.Stats.Rows["test2"])
 	assert.Equal(t, int64(0), conv.Unexpecteds())
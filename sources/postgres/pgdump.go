@@ -114,10 +114,21 @@ func readAndParseChunk(conv *internal.Conv, r *internal.Reader) ([]byte, []*pg_q
 			for i := range l {
 				n += copy(s[n:], l[i])
 			}
-			tree, err := pg_query.Parse(string(s))
+			chunk := string(s)
+			detectDialect(conv, chunk)
+			tree, err := pg_query.Parse(chunk)
 			if err == nil {
 				return s, tree.Stmts, nil
 			}
+			if conv.Dialect == internal.DialectRedshift {
+				chunk, skip := rewriteRedshift(conv, chunk)
+				if skip {
+					return s, nil, nil
+				}
+				if tree, err = pg_query.Parse(chunk); err == nil {
+					return s, tree.Stmts, nil
+				}
+			}
 			// Likely causes of failing to parse:
 			// a) complex statements with embedded semicolons e.g. 'CREATE FUNCTION'
 			// b) a semicolon embedded in a multi-line comment, or
@@ -199,6 +210,10 @@ func processStatements(conv *internal.Conv, rawStmts []*pg_query.RawStmt) *copyO
 			if conv.SchemaMode() {
 				processIndexStmt(conv, n.IndexStmt)
 			}
+		case *pg_query.Node_GrantStmt:
+			if conv.SchemaMode() {
+				processGrantStmt(conv, n.GrantStmt)
+			}
 		default:
 			conv.SkipStatement(printNodeType(n))
 		}
@@ -229,6 +244,65 @@ func processIndexStmt(conv *internal.Conv, n *pg_query.IndexStmt) {
 	}
 }
 
+// processGrantStmt records grants of privileges on tables to roles, which
+// are migrated as Spanner database roles. Revocations, grants on other
+// objects and grants to PUBLIC are skipped.
+func processGrantStmt(conv *internal.Conv, n *pg_query.GrantStmt) {
+	if !n.IsGrant || n.Targtype != pg_query.GrantTargetType_ACL_TARGET_OBJECT || n.Objtype != pg_query.ObjectType_OBJECT_TABLE {
+		conv.SkipStatement(printNodeType(n))
+		return
+	}
+	// Privileges are nil for GRANT ALL.
+	privs := map[string][]string{"ALL": nil}
+	var order []string
+	if len(n.Privileges) > 0 {
+		privs = make(map[string][]string)
+		for _, p := range n.Privileges {
+			ap := p.GetAccessPriv()
+			if ap == nil {
+				continue
+			}
+			var cols []string
+			for _, c := range ap.Cols {
+				col, err := getString(c)
+				if err != nil {
+					logStmtError(conv, n, fmt.Errorf("can't get column name: %w", err))
+					return
+				}
+				cols = append(cols, col)
+			}
+			privs[ap.PrivName] = cols
+			order = append(order, ap.PrivName)
+		}
+	} else {
+		order = []string{"ALL"}
+	}
+	for _, o := range n.Objects {
+		rv := o.GetRangeVar()
+		if rv == nil {
+			continue
+		}
+		table, err := getTableName(conv, rv)
+		if err != nil {
+			logStmtError(conv, n, fmt.Errorf("can't get table name: %w", err))
+			return
+		}
+		if _, ok := conv.SrcSchema[table]; !ok {
+			conv.Unexpected(fmt.Sprintf("Table %s not found while processing grant statement", table))
+			continue
+		}
+		for _, g := range n.Grantees {
+			rs := g.GetRoleSpec()
+			if rs == nil || rs.Roletype != pg_query.RoleSpecType_ROLESPEC_CSTRING {
+				continue
+			}
+			for _, p := range order {
+				conv.AddGrant(table, rs.Rolename, []string{p}, privs[p])
+			}
+		}
+	}
+}
+
 func processAlterTableStmt(conv *internal.Conv, n *pg_query.AlterTableStmt) {
 	if n.Relation == nil {
 		logStmtError(conv, n, fmt.Errorf("relation is nil"))
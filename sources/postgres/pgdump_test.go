@@ -1406,6 +1406,27 @@ func TestProcessPgDump_GetPGDDL(t *testing.T) {
 	assert.Equal(t, expected, strings.Join(conv.SpSchema.GetDDL(c), " "))
 }
 
+func TestProcessPgDump_Grants(t *testing.T) {
+	conv, _ := runProcessPgDump("CREATE TABLE cart (productid text, userid text, quantity bigint);\n" +
+		"ALTER TABLE ONLY cart ADD CONSTRAINT cart_pkey PRIMARY KEY (productid, userid);\n" +
+		"REVOKE ALL ON TABLE public.cart FROM PUBLIC;\n" +
+		"GRANT SELECT, UPDATE (quantity) ON TABLE public.cart TO reader, PUBLIC;\n" +
+		"GRANT ALL ON TABLE public.cart TO admin;\n" +
+		"GRANT USAGE ON SCHEMA public TO reader;")
+	assert.Equal(t, []internal.Grant{
+		{Table: "cart", Grantee: "reader", Privileges: []string{"select"}},
+		{Table: "cart", Columns: []string{"quantity"}, Grantee: "reader", Privileges: []string{"update"}},
+		{Table: "cart", Grantee: "admin", Privileges: []string{"ALL"}},
+	}, conv.Grants)
+	assert.Equal(t, []string{
+		"CREATE ROLE admin",
+		"CREATE ROLE reader",
+		"GRANT SELECT, INSERT, UPDATE, DELETE ON TABLE cart TO ROLE admin",
+		"GRANT SELECT ON TABLE cart TO ROLE reader",
+		"GRANT UPDATE(quantity) ON TABLE cart TO ROLE reader",
+	}, conv.RoleDDL(ddl.Config{}))
+}
+
 func TestProcessPgDump_Rows(t *testing.T) {
 	conv, _ := runProcessPgDump("CREATE TABLE cart (a text, n bigint);\n" +
 		"INSERT INTO cart (a, n) VALUES ('a42', 2);")
@@ -1470,6 +1491,49 @@ func TestProcessPgDump_AddPrimaryKeys(t *testing.T) {
 	}
 }
 
+func TestProcessPgDump_Redshift(t *testing.T) {
+	conv, rows := runProcessPgDump("CREATE TABLE IF NOT EXISTS public.sales\n" +
+		"(\n" +
+		"	salesid INTEGER NOT NULL IDENTITY(1,1) ENCODE az64\n" +
+		"	,sellerid INTEGER NOT NULL ENCODE az64 DISTKEY\n" +
+		"	,notes VARCHAR(MAX) ENCODE lzo\n" +
+		"	,attrs SUPER\n" +
+		"	,saletime TIMESTAMP WITHOUT TIME ZONE ENCODE RAW SORTKEY\n" +
+		"	,PRIMARY KEY (salesid)\n" +
+		")\n" +
+		"DISTSTYLE KEY\n" +
+		" DISTKEY (sellerid)\n" +
+		" COMPOUND SORTKEY (saletime, sellerid)\n" +
+		";\n" +
+		"ALTER TABLE public.sales ALTER DISTSTYLE ALL;\n" +
+		"UNLOAD ('select * from public.sales') TO 's3://bucket/sales_' IAM_ROLE 'arn:aws:iam::0123456789:role/unload';\n" +
+		"INSERT INTO public.sales (salesid, sellerid, notes, attrs, saletime) VALUES (1, 2, 'n', '{\"a\": 1}', '2019-10-29 05:30:00');\n")
+	assert.Equal(t, internal.DialectRedshift, conv.Dialect)
+	expected := ddl.CreateTable{
+		Name:     "sales",
+		ColNames: []string{"salesid", "sellerid", "notes", "attrs", "saletime"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"salesid":  ddl.ColumnDef{Name: "salesid", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+			"sellerid": ddl.ColumnDef{Name: "sellerid", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+			"notes":    ddl.ColumnDef{Name: "notes", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"attrs":    ddl.ColumnDef{Name: "attrs", T: ddl.Type{Name: ddl.JSON}},
+			"saletime": ddl.ColumnDef{Name: "saletime", T: ddl.Type{Name: ddl.Timestamp}},
+		},
+		Pks: []ddl.IndexKey{ddl.IndexKey{Col: "salesid"}},
+	}
+	assert.Equal(t, map[string]ddl.CreateTable{"sales": expected}, stripSchemaComments(conv.SpSchema))
+	assert.Equal(t, 1, len(rows))
+	for _, w := range []string{"ignored IDENTITY clause", "ignored DISTSTYLE clause", "ignored SORTKEY clause", "ignored ENCODE clause", "ignored VARCHAR(MAX) length clause", "skipped statement UNLOAD...", "skipped statement ALTER TABLE public.sales ALTER DISTSTYLE..."} {
+		assert.Contains(t, conv.Stats.Unexpected, "Redshift: "+w)
+	}
+
+	// Aurora PostgreSQL dumps are detected from their roles and extensions.
+	conv, _ = runProcessPgDump("CREATE TABLE t (a bigint PRIMARY KEY, b text);\n" +
+		"ALTER TABLE public.t OWNER TO rdsadmin;\n")
+	assert.Equal(t, internal.DialectAuroraPostgres, conv.Dialect)
+	noIssues(conv, t, "Aurora PostgreSQL")
+}
+
 func TestProcessPgDump_WithUnparsableContent(t *testing.T) {
 	s := "This is unparsable content"
 	conv := internal.MakeConv()
@@ -105,6 +105,17 @@ Name Changes in Migration
 -----------------------------------------------------------------------------------------------------
 
 
+----------------------------
+Schema Issues by Severity
+----------------------------
+Errors: none
+Warnings: 3
+  DefaultValue (1): default_value.b
+  MultiDimensionalArray (1): bad_schema.c
+  NoGoodType (1): bad_schema.d
+Info: 3
+  Widened (3): bad_schema.b, bad_schema.c, no_pk.b
+
 ----------------------------
 Table bad_schema
 ----------------------------
@@ -19,6 +19,7 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
@@ -31,7 +32,10 @@ type ToDdlImpl struct {
 // mapping.  toSpannerType returns the Spanner type and a list of type
 // conversion issues encountered.
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
-	ty, issues := toSpannerTypeInternal(conv, columnType.Name, columnType.Mods)
+	ty, issues := TypeMap.ToSpannerType(columnType.Name, "", columnType.Mods)
+	if columnType.UserDefined != "" && columnType.Name != compositeType {
+		issues = append(issues, internal.Domain)
+	}
 	if conv.TargetDb == constants.TargetExperimentalPostgres {
 		ty = overrideExperimentalType(columnType, ty)
 	} else {
@@ -44,75 +48,120 @@ func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type)
 	return ty, issues
 }
 
-// toSpannerType maps a scalar source schema type (defined by id and
-// mods) into a Spanner type. This is the core source-to-Spanner type
-// mapping.  toSpannerType returns the Spanner type and a list of type
-// conversion issues encountered.
-func toSpannerTypeInternal(conv *internal.Conv, id string, mods []int64) (ddl.Type, []internal.SchemaIssue) {
-	switch id {
-	case "bool", "boolean":
-		return ddl.Type{Name: ddl.Bool}, nil
-	case "bigserial":
-		return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Serial}
-	case "bpchar", "character": // Note: Postgres internal name for char is bpchar (aka blank padded char).
-		if len(mods) > 0 {
-			return ddl.Type{Name: ddl.String, Len: mods[0]}, nil
-		}
-		// Note: bpchar without length specifier is equivalent to bpchar(1)
-		return ddl.Type{Name: ddl.String, Len: 1}, nil
-	case "bytea":
-		return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
-	case "date":
-		return ddl.Type{Name: ddl.Date}, nil
-	case "float8", "double precision":
-		return ddl.Type{Name: ddl.Float64}, nil
-	case "float4", "real":
-		return ddl.Type{Name: ddl.Float64}, []internal.SchemaIssue{internal.Widened}
-	case "int8", "bigint":
-		return ddl.Type{Name: ddl.Int64}, nil
-	case "int4", "integer":
-		return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}
-	case "int2", "smallint":
-		return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}
-	case "numeric":
-		// PostgreSQL's NUMERIC type can have a specified precision of up to 1000
-		// digits (and scale can be anything from 0 up to the value of 'precision').
-		// If precision and scale are not specified, then values of any precision
-		// or scale can be stored, up to the implementation's limits (can be up to
-		// 131072 digits before the decimal point and up to 16383 digits after
-		// the decimal point).
-		// Spanner's NUMERIC type can store up to 29 digits before the
-		// decimal point and up to 9 after the decimal point -- it is
-		// equivalent to PostgreSQL's NUMERIC(38,9) type.
-		//
-		// TODO: Generate appropriate SchemaIssue to warn of different precision
-		// capabilities between PostgreSQL and Spanner NUMERIC.
-		return ddl.Type{Name: ddl.Numeric}, nil
-	case "serial":
-		return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Serial}
-	case "text":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "timestamptz", "timestamp with time zone":
-		return ddl.Type{Name: ddl.Timestamp}, nil
-	case "timestamp", "timestamp without time zone":
-		// Map timestamp without timezone to Spanner timestamp.
-		return ddl.Type{Name: ddl.Timestamp}, []internal.SchemaIssue{internal.Timestamp}
-	case "varchar", "character varying":
-		if len(mods) > 0 {
-			return ddl.Type{Name: ddl.String, Len: mods[0]}, nil
-		}
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "json", "jsonb":
-		return ddl.Type{Name: ddl.JSON}, nil
-	}
-	return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
-}
+// compositeType is the source type name of columns of user-defined composite
+// types, whose values are mapped to JSON objects keyed by field name.
+const compositeType = "composite"
+
+// TypeMap declares the mapping of PostgreSQL types to Spanner types.
+var TypeMap = common.NewTypeMap(
+	common.TypeGroup{SrcTypes: []string{"bool", "boolean"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Bool}),
+		Options: map[string]common.MapFunc{
+			ddl.String: common.To(common.MaxString, internal.Widened),
+			ddl.Int64:  common.To(ddl.Type{Name: ddl.Int64}, internal.Widened),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{"bigserial", "serial"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}, internal.Serial),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened, internal.Serial)},
+	}},
+	// Note: Postgres internal name for char is bpchar (aka blank padded char),
+	// and bpchar without length specifier is equivalent to bpchar(1).
+	common.TypeGroup{SrcTypes: []string{"bpchar", "character"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.String, 1),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.ToSized(ddl.Bytes, 1)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"bytea"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxBytes),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"date"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Date}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"float8", "double precision"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Float64}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"float4", "real"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Float64}, internal.Widened),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"int8", "bigint"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"int4", "integer", "int2", "smallint"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}, internal.Widened),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	// PostgreSQL's NUMERIC type can have a specified precision of up to 1000
+	// digits (and scale can be anything from 0 up to the value of 'precision').
+	// If precision and scale are not specified, then values of any precision
+	// or scale can be stored, up to the implementation's limits (can be up to
+	// 131072 digits before the decimal point and up to 16383 digits after
+	// the decimal point).
+	// Spanner's NUMERIC type can store up to 29 digits before the
+	// decimal point and up to 9 after the decimal point -- it is
+	// equivalent to PostgreSQL's NUMERIC(38,9) type.
+	//
+	// TODO: Generate appropriate SchemaIssue to warn of different precision
+	// capabilities between PostgreSQL and Spanner NUMERIC.
+	common.TypeGroup{SrcTypes: []string{"numeric"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Numeric}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"text"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.To(common.MaxBytes)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"timestamptz", "timestamp with time zone"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Timestamp}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	// Map timestamp without timezone to Spanner timestamp.
+	common.TypeGroup{SrcTypes: []string{"timestamp", "timestamp without time zone"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Timestamp}, internal.Timestamp),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"varchar", "character varying"}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.String, ddl.MaxLength),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.ToSized(ddl.Bytes, ddl.MaxLength)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"json", "jsonb"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.JSON}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	// Redshift types: SUPER holds semi-structured data, VARBYTE binary data
+	// and HLLSKETCH HyperLogLog sketches, which are migrated as their text
+	// form.
+	common.TypeGroup{SrcTypes: []string{"super"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.JSON}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"varbyte", "varbinary"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxBytes),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"hllsketch"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+	}},
+	// Aurora PostgreSQL types of the aws_commons extension, used with
+	// aws_s3 and aws_lambda, migrated as their text form.
+	common.TypeGroup{SrcTypes: []string{"aws_commons._s3_uri_1", "aws_commons._aws_credentials_1"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+	}},
+	common.TypeGroup{SrcTypes: []string{compositeType}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.JSON}, internal.Composite),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Composite)},
+	}},
+)
 
 // Override the types to map to experimental postgres types.
 func overrideExperimentalType(columnType schema.Type, originalType ddl.Type) ddl.Type {
 	if len(columnType.ArrayBounds) > 0 {
 		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
-	} else if columnType.Name == "json" || columnType.Name == "jsonb" {
+	} else if columnType.Name == "json" || columnType.Name == "jsonb" || columnType.Name == compositeType {
 		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
 	}
 	return originalType
@@ -0,0 +1,217 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registry is the registry of the source drivers HarbourBridge can
+// migrate from. Each driver registers itself from an init function of its
+// package, e.g.
+//
+//	func init() {
+//		registry.Register(driver{})
+//	}
+//
+// so that adding a driver doesn't require changes to the conversion code, the
+// command line tool or the web app: they look drivers up by name here.
+package registry
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+)
+
+// Info describes a source driver.
+type Info struct {
+	// Name is the name of the driver, as used by the legacy -driver flag and
+	// stored in sessions, e.g. "mysqldump".
+	Name string
+	// Source is the value of the -source flag that selects the driver, e.g.
+	// "mysql". A database driver and a dump driver can share a source: the
+	// dump driver is used when the source profile specifies a file.
+	Source string
+	// Aliases are other accepted values of the -source flag.
+	Aliases     []string
+	Description string
+	// Dump is true for drivers that read dump files rather than connecting
+	// to a database.
+	Dump bool
+	// Streaming is true for drivers that support change data capture, which
+	// is required for minimal downtime migrations.
+	Streaming bool
+	// LegacyMode is true for drivers that can be used with the legacy
+	// -driver flag.
+	LegacyMode bool
+}
+
+// SourceDriver is a source that HarbourBridge can migrate from. A driver
+// also implements DatabaseDriver or DumpDriver.
+type SourceDriver interface {
+	Info() Info
+	// ToDdl returns the mapping of source types to Spanner types.
+	ToDdl() common.ToDdl
+	// TypeMap returns the Spanner types that source types can be mapped to,
+	// which the web apps let users choose from.
+	TypeMap() common.TypeMap
+}
+
+// DatabaseDriver is a SourceDriver that reads the schema and data of a
+// source database it connects to. The InfoSchema it returns captures
+// changes if the driver supports streaming.
+type DatabaseDriver interface {
+	SourceDriver
+	// InfoSchema connects to the source database of sourceProfile.
+	InfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) (common.InfoSchema, error)
+}
+
+// DumpDriver is a SourceDriver that reads the schema and data of a dump
+// file.
+type DumpDriver interface {
+	SourceDriver
+	DbDump() common.DbDump
+}
+
+// WebDriver is a DatabaseDriver that the web app can connect to with
+// database/sql, using the driver name as database/sql driver name.
+type WebDriver interface {
+	DatabaseDriver
+	// DataSourceName returns the data source name of a database.
+	DataSourceName(host, port, user, password, dbName string) string
+	// WebInfoSchema returns the InfoSchema of open database db named dbName.
+	WebInfoSchema(db *sql.DB, dbName string) common.InfoSchema
+}
+
+var (
+	mu      sync.RWMutex
+	drivers = make(map[string]SourceDriver)
+)
+
+// Register makes driver d available. It panics if a driver of the same
+// name is already registered, or if d is neither a DatabaseDriver nor a
+// DumpDriver.
+func Register(d SourceDriver) {
+	info := d.Info()
+	if info.Name == "" {
+		panic("registry: driver without name")
+	}
+	_, isDatabase := d.(DatabaseDriver)
+	_, isDump := d.(DumpDriver)
+	if !isDatabase && !isDump {
+		panic(fmt.Sprintf("registry: driver %s is neither a database driver nor a dump driver", info.Name))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, found := drivers[info.Name]; found {
+		panic(fmt.Sprintf("registry: driver %s registered twice", info.Name))
+	}
+	drivers[info.Name] = d
+}
+
+// Get returns the driver named name.
+func Get(name string) (SourceDriver, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	d, found := drivers[name]
+	return d, found
+}
+
+// Database returns the database driver named name.
+func Database(name string) (DatabaseDriver, error) {
+	d, found := Get(name)
+	if !found {
+		return nil, fmt.Errorf("driver %s not supported", name)
+	}
+	dd, ok := d.(DatabaseDriver)
+	if !ok {
+		return nil, fmt.Errorf("driver %s doesn't connect to a database", name)
+	}
+	return dd, nil
+}
+
+// Dump returns the dump driver named name.
+func Dump(name string) (DumpDriver, error) {
+	d, found := Get(name)
+	if !found {
+		return nil, fmt.Errorf("driver %s not supported", name)
+	}
+	dd, ok := d.(DumpDriver)
+	if !ok {
+		return nil, fmt.Errorf("driver %s doesn't read dump files", name)
+	}
+	return dd, nil
+}
+
+// ForSource returns the driver selected by value source of the -source flag,
+// case insensitively. dump selects between the dump driver and the database
+// driver of the source.
+func ForSource(source string, dump bool) (SourceDriver, bool) {
+	source = strings.ToLower(source)
+	for _, d := range List() {
+		info := d.Info()
+		if info.Dump != dump {
+			continue
+		}
+		if info.Source == source {
+			return d, true
+		}
+		for _, a := range info.Aliases {
+			if a == source {
+				return d, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// List returns the registered drivers, sorted by name.
+func List() []SourceDriver {
+	mu.RLock()
+	defer mu.RUnlock()
+	var l []SourceDriver
+	for _, d := range drivers {
+		l = append(l, d)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Info().Name < l[j].Info().Name })
+	return l
+}
+
+// Sources returns the sorted values of the -source flag that select a
+// registered driver, without aliases.
+func Sources() []string {
+	seen := make(map[string]bool)
+	var l []string
+	for _, d := range List() {
+		if s := d.Info().Source; s != "" && !seen[s] {
+			seen[s] = true
+			l = append(l, s)
+		}
+	}
+	sort.Strings(l)
+	return l
+}
+
+// LegacyModeDrivers returns the sorted names of the drivers that can be
+// used with the legacy -driver flag.
+func LegacyModeDrivers() []string {
+	var l []string
+	for _, d := range List() {
+		if d.Info().LegacyMode {
+			l = append(l, d.Info().Name)
+		}
+	}
+	return l
+}
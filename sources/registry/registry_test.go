@@ -0,0 +1,104 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+type testToDdl struct{}
+
+func (testToDdl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
+	return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
+}
+
+type testDriver struct{ info Info }
+
+func (d testDriver) Info() Info              { return d.info }
+func (d testDriver) ToDdl() common.ToDdl     { return testToDdl{} }
+func (d testDriver) TypeMap() common.TypeMap { return common.TypeMap{} }
+func (d testDriver) InfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) (common.InfoSchema, error) {
+	return nil, nil
+}
+
+type testDumpDriver struct{ info Info }
+
+func (d testDumpDriver) Info() Info              { return d.info }
+func (d testDumpDriver) ToDdl() common.ToDdl     { return testToDdl{} }
+func (d testDumpDriver) TypeMap() common.TypeMap { return common.TypeMap{} }
+func (d testDumpDriver) DbDump() common.DbDump   { return nil }
+
+type testInvalidDriver struct{}
+
+func (testInvalidDriver) Info() Info              { return Info{Name: "invalid"} }
+func (testInvalidDriver) ToDdl() common.ToDdl     { return testToDdl{} }
+func (testInvalidDriver) TypeMap() common.TypeMap { return common.TypeMap{} }
+
+func TestRegistry(t *testing.T) {
+	Register(testDriver{Info{Name: "testdb", Source: "testdb", Aliases: []string{"tdb"}, Streaming: true, LegacyMode: true}})
+	Register(testDumpDriver{Info{Name: "testdb_dump", Source: "testdb", Dump: true}})
+	Register(testDriver{Info{Name: "otherdb", Source: "otherdb"}})
+
+	d, found := Get("testdb")
+	assert.True(t, found)
+	assert.Equal(t, "testdb", d.Info().Name)
+	_, found = Get("nodb")
+	assert.False(t, found)
+
+	_, err := Database("testdb")
+	assert.Nil(t, err)
+	_, err = Database("testdb_dump")
+	assert.NotNil(t, err)
+	_, err = Dump("testdb_dump")
+	assert.Nil(t, err)
+	_, err = Dump("nodb")
+	assert.NotNil(t, err)
+
+	for _, tc := range []struct {
+		source string
+		dump   bool
+		driver string
+	}{
+		{"testdb", false, "testdb"},
+		{"TestDB", false, "testdb"},
+		{"tdb", false, "testdb"},
+		{"testdb", true, "testdb_dump"},
+		{"otherdb", true, ""},
+		{"nodb", false, ""},
+	} {
+		d, found := ForSource(tc.source, tc.dump)
+		if tc.driver == "" {
+			assert.False(t, found, tc.source)
+			continue
+		}
+		assert.True(t, found, tc.source)
+		assert.Equal(t, tc.driver, d.Info().Name, tc.source)
+	}
+
+	assert.Equal(t, []string{"otherdb", "testdb"}, Sources())
+	assert.Equal(t, []string{"testdb"}, LegacyModeDrivers())
+
+	assert.Panics(t, func() { Register(testDriver{Info{Name: "testdb"}}) })
+	assert.Panics(t, func() { Register(testDriver{Info{}}) })
+	assert.Panics(t, func() { Register(testInvalidDriver{}) })
+}
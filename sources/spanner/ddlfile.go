@@ -0,0 +1,227 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanner
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/spansql"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// DDLInfoSchemaImpl is an implementation of InfoSchema for Spanner DDL
+// statements (e.g. the contents of a .ddl file), rather than a Spanner
+// database. Only the GoogleSQL dialect is supported.
+type DDLInfoSchemaImpl struct {
+	tables      []*spansql.CreateTable
+	indexes     map[string][]*spansql.CreateIndex
+	foreignKeys map[string][]schema.ForeignKey
+	// Skipped lists the statements that don't describe tables, indexes or
+	// foreign keys (e.g. CREATE VIEW), and are ignored.
+	Skipped []string
+}
+
+// NewDDLInfoSchema parses Spanner DDL statements separated by semicolons.
+func NewDDLInfoSchema(text string) (DDLInfoSchemaImpl, error) {
+	parsed, err := spansql.ParseDDL("", text)
+	if err != nil {
+		return DDLInfoSchemaImpl{}, fmt.Errorf("can't parse Spanner DDL: %v", err)
+	}
+	isi := DDLInfoSchemaImpl{indexes: make(map[string][]*spansql.CreateIndex), foreignKeys: make(map[string][]schema.ForeignKey)}
+	for _, stmt := range parsed.List {
+		switch s := stmt.(type) {
+		case *spansql.CreateTable:
+			isi.tables = append(isi.tables, s)
+			for _, c := range s.Constraints {
+				isi.addConstraint(string(s.Name), c)
+			}
+		case *spansql.CreateIndex:
+			isi.indexes[string(s.Table)] = append(isi.indexes[string(s.Table)], s)
+		case *spansql.AlterTable:
+			if ac, ok := s.Alteration.(spansql.AddConstraint); ok {
+				isi.addConstraint(string(s.Name), ac.Constraint)
+			} else {
+				isi.Skipped = append(isi.Skipped, s.SQL())
+			}
+		default:
+			isi.Skipped = append(isi.Skipped, s.SQL())
+		}
+	}
+	return isi, nil
+}
+
+func (isi DDLInfoSchemaImpl) addConstraint(table string, c spansql.TableConstraint) {
+	fk, ok := c.Constraint.(spansql.ForeignKey)
+	if !ok {
+		// Check constraints aren't supported by HarbourBridge yet.
+		return
+	}
+	isi.foreignKeys[table] = append(isi.foreignKeys[table], schema.ForeignKey{
+		Name:         string(c.Name),
+		Columns:      idsToStrings(fk.Columns),
+		ReferTable:   string(fk.RefTable),
+		ReferColumns: idsToStrings(fk.RefColumns)})
+}
+
+// GetToDdl function below implement the common.InfoSchema interface.
+func (isi DDLInfoSchemaImpl) GetToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+// GetTableName returns table name.
+func (isi DDLInfoSchemaImpl) GetTableName(schema string, tableName string) string {
+	return tableName
+}
+
+// GetTables return list of tables, in the order of the DDL statements.
+func (isi DDLInfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
+	var tables []common.SchemaAndName
+	for _, t := range isi.tables {
+		tables = append(tables, common.SchemaAndName{Name: string(t.Name)})
+	}
+	return tables, nil
+}
+
+// GetColumns returns a list of Column objects and names
+func (isi DDLInfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
+	t := isi.table(table.Name)
+	if t == nil {
+		return nil, nil, fmt.Errorf("table %s not found", table.Name)
+	}
+	colDefs := make(map[string]schema.Column)
+	var colNames []string
+	for _, c := range t.Columns {
+		colDefs[string(c.Name)] = schema.Column{Name: string(c.Name), Type: toType(c.Type.SQL()), NotNull: c.NotNull}
+		colNames = append(colNames, string(c.Name))
+	}
+	return colDefs, colNames, nil
+}
+
+// GetConstraints returns the primary keys of the table. The other
+// constraints of Spanner DDL are foreign keys, which are handled in
+// GetForeignKeys, and check constraints, which are ignored.
+func (isi DDLInfoSchemaImpl) GetConstraints(conv *internal.Conv, table common.SchemaAndName) ([]string, map[string][]string, error) {
+	t := isi.table(table.Name)
+	if t == nil {
+		return nil, nil, fmt.Errorf("table %s not found", table.Name)
+	}
+	var primaryKeys []string
+	for _, k := range t.PrimaryKey {
+		primaryKeys = append(primaryKeys, string(k.Column))
+	}
+	return primaryKeys, map[string][]string{}, nil
+}
+
+// GetForeignKeys returns a list of all the foreign key constraints.
+func (isi DDLInfoSchemaImpl) GetForeignKeys(conv *internal.Conv, table common.SchemaAndName) (foreignKeys []schema.ForeignKey, err error) {
+	return isi.foreignKeys[table.Name], nil
+}
+
+// GetIndexes returns a list of Indexes per table.
+func (isi DDLInfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAndName) ([]schema.Index, error) {
+	var indexes []schema.Index
+	for _, ci := range isi.indexes[table.Name] {
+		index := schema.Index{Name: string(ci.Name), Unique: ci.Unique}
+		for _, k := range ci.Columns {
+			index.Keys = append(index.Keys, schema.Key{Column: string(k.Column), Desc: k.Desc})
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// GetInterleaveTables returns the parent of each interleaved table.
+func (isi DDLInfoSchemaImpl) GetInterleaveTables() (map[string]string, error) {
+	parentTables := map[string]string{}
+	for _, t := range isi.tables {
+		if t.Interleave != nil {
+			parentTables[string(t.Name)] = string(t.Interleave.Parent)
+		}
+	}
+	return parentTables, nil
+}
+
+// UpdateSpannerSchema sets the parts of conv.SpSchema that can't be
+// expressed with a source schema: descending primary keys, and null
+// filtered indexes and stored columns of indexes. It must be called after
+// schema conversion, while conv.SpSchema is still keyed by table name.
+func (isi DDLInfoSchemaImpl) UpdateSpannerSchema(conv *internal.Conv) {
+	for _, t := range isi.tables {
+		spTable, ok := conv.SpSchema[string(t.Name)]
+		if !ok {
+			continue
+		}
+		desc := make(map[string]bool)
+		for _, k := range t.PrimaryKey {
+			desc[string(k.Column)] = k.Desc
+		}
+		for i := range spTable.Pks {
+			spTable.Pks[i].Desc = desc[spTable.Pks[i].Col]
+		}
+		for _, ci := range isi.indexes[string(t.Name)] {
+			for i := range spTable.Indexes {
+				if spTable.Indexes[i].Name == string(ci.Name) {
+					spTable.Indexes[i].NullFiltered = ci.NullFiltered
+					spTable.Indexes[i].StoredColumns = idsToStrings(ci.Storing)
+				}
+			}
+		}
+		conv.SpSchema[string(t.Name)] = spTable
+	}
+}
+
+func (isi DDLInfoSchemaImpl) table(name string) *spansql.CreateTable {
+	for _, t := range isi.tables {
+		if string(t.Name) == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// We leave the functions below empty to be able to pass this as an infoSchema interface: DDL statements have no data.
+func (isi DDLInfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
+	return 0, nil
+}
+
+func (isi DDLInfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
+	return nil, nil
+}
+
+func (isi DDLInfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	return nil
+}
+
+func (isi DDLInfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (isi DDLInfoSchemaImpl) StartStreamingMigration(ctx context.Context, client *spanner.Client, conv *internal.Conv, streamingInfo map[string]interface{}) error {
+	return nil
+}
+
+func idsToStrings(ids []spansql.ID) []string {
+	var l []string
+	for _, id := range ids {
+		l = append(l, string(id))
+	}
+	return l
+}
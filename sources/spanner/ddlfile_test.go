@@ -0,0 +1,74 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+func TestDDLInfoSchema(t *testing.T) {
+	text := `
+CREATE TABLE users (
+	user_id STRING(36) NOT NULL,
+	name STRING(MAX),
+	tags ARRAY<STRING(20)>,
+) PRIMARY KEY (user_id);
+CREATE TABLE orders (
+	user_id STRING(36) NOT NULL,
+	order_id INT64 NOT NULL,
+	amount NUMERIC,
+	created TIMESTAMP NOT NULL,
+	CONSTRAINT fk_orders_users FOREIGN KEY (user_id) REFERENCES users (user_id),
+) PRIMARY KEY (user_id, order_id DESC),
+  INTERLEAVE IN PARENT users ON DELETE CASCADE;
+CREATE NULL_FILTERED INDEX orders_by_created ON orders (created DESC) STORING (amount);
+CREATE VIEW names SQL SECURITY INVOKER AS SELECT users.name FROM users;
+`
+	isi, err := NewDDLInfoSchema(text)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(isi.Skipped))
+	conv := internal.MakeConv()
+	assert.Nil(t, common.ProcessSchema(conv, isi))
+	isi.UpdateSpannerSchema(conv)
+
+	users := conv.SpSchema["users"]
+	assert.Equal(t, []string{"user_id", "name", "tags"}, users.ColNames)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 36}, users.ColDefs["user_id"].T)
+	assert.True(t, users.ColDefs["user_id"].NotNull)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 20, IsArray: true}, users.ColDefs["tags"].T)
+	assert.False(t, users.ColDefs["tags"].NotNull)
+
+	orders := conv.SpSchema["orders"]
+	assert.Equal(t, []ddl.IndexKey{{Col: "user_id"}, {Col: "order_id", Desc: true}}, orders.Pks)
+	assert.Equal(t, ddl.Type{Name: ddl.Numeric}, orders.ColDefs["amount"].T)
+	assert.Equal(t, []ddl.Foreignkey{{Name: "fk_orders_users", Columns: []string{"user_id"}, ReferTable: "users", ReferColumns: []string{"user_id"}}}, orders.Fks)
+	assert.Equal(t, 1, len(orders.Indexes))
+	assert.Equal(t, []ddl.IndexKey{{Col: "created", Desc: true}}, orders.Indexes[0].Keys)
+	assert.True(t, orders.Indexes[0].NullFiltered)
+	assert.Equal(t, []string{"amount"}, orders.Indexes[0].StoredColumns)
+
+	parents, err := isi.GetInterleaveTables()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"orders": "users"}, parents)
+
+	_, err = NewDDLInfoSchema("CREATE TABLE t (")
+	assert.NotNil(t, err)
+}
@@ -46,7 +46,7 @@ func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
 }
 
 // We leave the 5 functions below empty to be able to pass this as an infoSchema interface. We don't need these for now.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
 	return nil
 }
 
@@ -97,7 +97,8 @@ func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
 	return fmt.Sprintf("%s.%s", schema, tableName)
 }
 
-// GetTables return list of tables in the selected database.
+// GetTables return list of tables in the selected database, except the tables
+// of the migration catalog.
 func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 	q := `SELECT table_schema, table_name FROM information_schema.tables 
 	WHERE table_type = 'BASE TABLE' AND table_schema = ''`
@@ -123,6 +124,11 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 		if err != nil {
 			return nil, err
 		}
+		// The migration catalog records migrations to the database, and
+		// isn't part of its schema.
+		if internal.IsMigrationCatalogTable(tableName) {
+			continue
+		}
 		tables = append(tables, common.SchemaAndName{Schema: tableSchema, Name: tableName})
 	}
 	return tables, nil
@@ -18,6 +18,7 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
@@ -30,7 +31,7 @@ type ToDdlImpl struct {
 // conversion issues encountered.
 // Functions below implement the common.ToDdl interface
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
-	ty, issues := toSpannerTypeInternal(conv, columnType.Name, columnType.Mods)
+	ty, issues := TypeMap.ToSpannerType(columnType.Name, "", columnType.Mods)
 	if conv.TargetDb == constants.TargetExperimentalPostgres {
 		ty, issues = overrideExperimentalType(columnType, ty, issues)
 	} else {
@@ -39,33 +40,51 @@ func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type)
 	return ty, issues
 }
 
-// toSpannerType maps a scalar source schema type (defined by id and
-// mods) into a Spanner type. This is the core source-to-Spanner type
-// mapping.  toSpannerType returns the Spanner type and a list of type
-// conversion issues encountered.
-func toSpannerTypeInternal(conv *internal.Conv, id string, mods []int64) (ddl.Type, []internal.SchemaIssue) {
-	switch id {
-	case "BOOL":
-		return ddl.Type{Name: ddl.Bool}, nil
-	case "BYTES":
-		return ddl.Type{Name: ddl.Bytes, Len: mods[0]}, nil
-	case "DATE":
-		return ddl.Type{Name: ddl.Date}, nil
-	case "FLOAT64":
-		return ddl.Type{Name: ddl.Float64}, nil
-	case "INT64":
-		return ddl.Type{Name: ddl.Int64}, nil
-	case "JSON":
-		return ddl.Type{Name: ddl.JSON}, nil
-	case "NUMERIC":
-		return ddl.Type{Name: ddl.Numeric}, nil
-	case "STRING":
-		return ddl.Type{Name: ddl.String, Len: mods[0]}, nil
-	case "TIMESTAMP":
-		return ddl.Type{Name: ddl.Timestamp}, nil
-	}
-	return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
-}
+// TypeMap declares the mapping of the types of a Spanner schema loaded from
+// a Spanner database or Spanner DDL into Spanner types. Each type maps to
+// itself by default, and can be changed to the types it can be losslessly
+// converted to.
+var TypeMap = common.NewTypeMap(
+	common.TypeGroup{SrcTypes: []string{ddl.Bool}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Bool}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{ddl.Bytes}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.Bytes, ddl.MaxLength),
+		Options: map[string]common.MapFunc{ddl.String: common.ToSized(ddl.String, ddl.MaxLength)},
+	}},
+	common.TypeGroup{SrcTypes: []string{ddl.Int64}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}),
+		Options: map[string]common.MapFunc{
+			ddl.Numeric: common.To(ddl.Type{Name: ddl.Numeric}),
+			ddl.String:  common.To(common.MaxString),
+		},
+	}},
+	common.TypeGroup{SrcTypes: []string{ddl.String}, TypeMapping: common.TypeMapping{
+		Default: common.ToSized(ddl.String, ddl.MaxLength),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.ToSized(ddl.Bytes, ddl.MaxLength)},
+	}},
+	common.TypeGroup{SrcTypes: []string{ddl.Float64}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Float64}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{ddl.Numeric}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Numeric}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{ddl.Date}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Date}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{ddl.Timestamp}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Timestamp}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{ddl.JSON}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.JSON}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+)
 
 // Override the types to map to experimental postgres types.
 func overrideExperimentalType(columnType schema.Type, originalType ddl.Type, issues []internal.SchemaIssue) (ddl.Type, []internal.SchemaIssue) {
@@ -17,7 +17,6 @@ package sqlserver
 import (
 	"fmt"
 	"math/big"
-	"math/bits"
 	"strconv"
 	"strings"
 	"time"
@@ -35,7 +34,9 @@ import (
 // and vals contains string data to be converted to appropriate types
 // to send to Spanner.  ProcessDataRow is only called in DataMode.
 func ProcessDataRow(conv *internal.Conv, srcTable string, srcCols []string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, vals []string) {
+	start := time.Now()
 	spTable, cvtCols, cvtVals, err := ConvertData(conv, srcTable, srcCols, srcSchema, spTable, spCols, spSchema, vals)
+	conv.Timings.TimeTable(internal.StageConvert, srcTable, start)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(srcTable, conv.DataMode())
@@ -68,7 +69,7 @@ func ConvertData(conv *internal.Conv, srcTable string, srcCols []string, srcSche
 		}
 		var x interface{}
 		var err error
-		x, err = convScalar(conv, spColDef.T, srcColDef.Type.Name, conv.TimezoneOffset, vals[i])
+		x, err = convScalar(conv, spColDef.T, srcColDef.Type.Name, conv.TimezoneOffset, conv.DatetimeLocation(srcTable, srcCol), vals[i])
 		if err != nil {
 			return "", []string{}, []interface{}{}, err
 		}
@@ -77,9 +78,7 @@ func ConvertData(conv *internal.Conv, srcTable string, srcCols []string, srcSche
 	}
 	if aux, ok := conv.SyntheticPKeys[spTable]; ok {
 		c = append(c, aux.Col)
-		v = append(v, int64(bits.Reverse64(uint64(aux.Sequence))))
-		aux.Sequence++
-		conv.SyntheticPKeys[spTable] = aux
+		v = append(v, conv.NextSyntheticKey(spTable))
 	}
 	return spTable, c, v, nil
 }
@@ -88,7 +87,7 @@ func ConvertData(conv *internal.Conv, srcTable string, srcCols []string, srcSche
 // appropriate Spanner value. It is the caller's responsibility to
 // detect and handle NULL values: convScalar will return error if a
 // NULL value is passed.
-func convScalar(conv *internal.Conv, spannerType ddl.Type, srcTypeName string, timezoneOffset string, val string) (interface{}, error) {
+func convScalar(conv *internal.Conv, spannerType ddl.Type, srcTypeName string, timezoneOffset string, datetimeLoc *time.Location, val string) (interface{}, error) {
 	// Whitespace within the val string is considered part of the data value.
 	// Note that many of the underlying conversions functions we use (like
 	// strconv.ParseFloat and strconv.ParseInt) return "invalid syntax"
@@ -109,7 +108,7 @@ func convScalar(conv *internal.Conv, spannerType ddl.Type, srcTypeName string, t
 	case ddl.String:
 		return val, nil
 	case ddl.Timestamp:
-		return convTimestamp(srcTypeName, val)
+		return convTimestamp(srcTypeName, datetimeLoc, val)
 	default:
 		return val, fmt.Errorf("data conversion not implemented for type %v", spannerType.Name)
 	}
@@ -168,8 +167,9 @@ func convNumeric(conv *internal.Conv, val string) (interface{}, error) {
 	}
 }
 
-// convTimestamp maps a source DB datetime types to Spanner timestamp
-func convTimestamp(srcTypeName string, val string) (t time.Time, err error) {
+// convTimestamp maps a source DB datetime types to Spanner timestamp.
+// Datetimes without offset are interpreted in datetimeLoc.
+func convTimestamp(srcTypeName string, datetimeLoc *time.Location, val string) (t time.Time, err error) {
 	// the query returns the datetime in ISO8601
 	// e.g. 2021-12-15T07:39:52.943 			(datetime)
 	// e.g. 2021-12-15T07:39:52.9433333 		(datetime2)
@@ -179,7 +179,7 @@ func convTimestamp(srcTypeName string, val string) (t time.Time, err error) {
 	if srcTypeName == dateTimeOffsetType {
 		t, err = time.Parse(time.RFC3339, val)
 	} else {
-		t, err = time.Parse("2006-01-02T15:04:05", val)
+		t, err = time.ParseInLocation("2006-01-02T15:04:05", val, datetimeLoc)
 	}
 	if err != nil {
 		return t, fmt.Errorf("can't convert to timestamp (mssql type: %s)", srcTypeName)
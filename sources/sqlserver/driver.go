@@ -0,0 +1,69 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/profiles"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+)
+
+func init() {
+	registry.Register(dbDriver{})
+}
+
+// dbDriver reads the schema and data of a SQL Server database.
+type dbDriver struct{}
+
+func (dbDriver) Info() registry.Info {
+	return registry.Info{
+		Name:        constants.SQLSERVER,
+		Source:      "sqlserver",
+		Aliases:     []string{"mssql"},
+		Description: "SQL Server database",
+	}
+}
+
+func (dbDriver) ToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+func (dbDriver) TypeMap() common.TypeMap {
+	return TypeMap
+}
+
+func (dbDriver) InfoSchema(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile) (common.InfoSchema, error) {
+	dsn := profiles.GetSQLConnectionStr(sourceProfile)
+	db, err := sql.Open(constants.SQLSERVER, dsn)
+	if err != nil {
+		return nil, err
+	}
+	splts := strings.Split(dsn, "?database=")
+	conn := sourceProfile.Conn.SqlServer
+	return InfoSchemaImpl{DbName: splts[len(splts)-1], Db: db, FetchSize: conn.FetchSize, Readers: conn.Readers}, nil
+}
+
+func (dbDriver) DataSourceName(host, port, user, password, dbName string) string {
+	return fmt.Sprintf(`sqlserver://%s:%s@%s:%s?database=%s`, user, password, host, port, dbName)
+}
+
+func (dbDriver) WebInfoSchema(db *sql.DB, dbName string) common.InfoSchema {
+	return InfoSchemaImpl{DbName: dbName, Db: db}
+}
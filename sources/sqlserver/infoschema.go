@@ -18,6 +18,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -44,8 +45,10 @@ const (
 )
 
 type InfoSchemaImpl struct {
-	DbName string
-	Db     *sql.DB
+	DbName    string
+	Db        *sql.DB
+	FetchSize int // Rows read per query when reading tables in chunks, or 0 to read each table with a single query.
+	Readers   int // Number of connections reading each table in parallel.
 }
 
 // GetToDdl function below implement the common.InfoSchema interface.
@@ -84,7 +87,12 @@ func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
 // We choose to do all type conversions explicitly ourselves so that
 // we can generate more targeted error messages: hence we pass
 // *interface{} parameters to row.Scan.
-func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+func (isi InfoSchemaImpl) ProcessData(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable) error {
+	if isi.FetchSize > 0 || isi.Readers > 1 {
+		if key, ok := streamKey(srcSchema); ok {
+			return isi.processDataParallel(ctx, conv, srcTable, srcSchema, spTable, spCols, spSchema, key)
+		}
+	}
 	rowsInterface, err := isi.GetRowsFromTable(conv, srcTable)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
@@ -109,6 +117,30 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, srcTable string, srcS
 	return nil
 }
 
+// PreviewData converts at most n rows of source table srcTable.
+func (isi InfoSchemaImpl) PreviewData(conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, n int) ([]common.PreviewRow, error) {
+	tblName := strings.Replace(srcTable, srcSchema.Schema+".", "", 1)
+	q := getSelectQuery(isi.DbName, srcSchema.Schema, tblName, srcSchema.ColNames, srcSchema.ColDefs)
+	q = strings.Replace(q, "SELECT ", fmt.Sprintf("SELECT TOP %d ", n), 1)
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	srcCols, _ := rows.Columns()
+	v, scanArgs := buildVals(len(srcCols))
+	var l []common.PreviewRow
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+		values := valsToStrings(v)
+		_, cvtCols, cvtVals, err := ConvertData(conv, srcTable, srcCols, srcSchema, spTable, spCols, spSchema, values)
+		l = append(l, common.PreviewRow{SrcVals: values, SpCols: cvtCols, SpVals: cvtVals, Err: err})
+	}
+	return l, rows.Err()
+}
+
 // GetRowsFromTable returns a sql Rows object for a table.
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, srcTable string) (interface{}, error) {
 	tbl := conv.SrcSchema[srcTable]
@@ -180,6 +212,17 @@ func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error)
 	return 0, nil
 }
 
+// CheckReadAccess implements the common.AccessChecker interface: it queries
+// table without reading any row.
+func (isi InfoSchemaImpl) CheckReadAccess(table common.SchemaAndName) error {
+	q := fmt.Sprintf(`SELECT TOP 0 1 FROM [%s].[%s].[%s];`, isi.DbName, table.Schema, table.Name)
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
 // GetTables return list of tables in the selected database.
 func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 	q := `
@@ -216,7 +259,9 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 			column_default, 
 			character_maximum_length, 
 			numeric_precision, 
-			numeric_scale
+			numeric_scale,
+			COLUMNPROPERTY(OBJECT_ID(QUOTENAME(table_schema) + '.' + QUOTENAME(table_name)), column_name, 'IsIdentity'),
+			collation_name
 		FROM information_schema.COLUMNS 
 		WHERE table_schema = @p1 and table_name = @p2 
 		ORDER BY ordinal_position;
@@ -229,11 +274,12 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 	var colNames []string
 	var colName, dataType string
 	var isNullable string
-	var colDefault sql.NullString
+	var colDefault, collation sql.NullString
 	// elementDataType
-	var charMaxLen, numericPrecision, numericScale sql.NullInt64
+	var charMaxLen, numericPrecision, numericScale, isIdentity sql.NullInt64
+	var sequences []string
 	for cols.Next() {
-		err := cols.Scan(&colName, &dataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale)
+		err := cols.Scan(&colName, &dataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &isIdentity, &collation)
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
 			continue
@@ -252,17 +298,64 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 		}
 		ignored.Default = colDefault.Valid
 		c := schema.Column{
-			Name:    colName,
-			Type:    toType(dataType, charMaxLen, numericPrecision, numericScale),
-			NotNull: strings.ToUpper(isNullable) == "NO",
-			Ignored: ignored,
+			Name:      colName,
+			Type:      toType(dataType, charMaxLen, numericPrecision, numericScale),
+			NotNull:   strings.ToUpper(isNullable) == "NO",
+			Ignored:   ignored,
+			Collation: collation.String,
+		}
+		// Values of identity columns, and of columns with a NEXT VALUE
+		// FOR default, are generated by the identity or a sequence.
+		if isIdentity.Int64 == 1 {
+			c.Sequence = &schema.Sequence{}
+		} else if m := nextValueFor.FindStringSubmatch(colDefault.String); m != nil {
+			c.Sequence = &schema.Sequence{Name: m[1]}
+			c.Ignored.Default = false
+		}
+		if c.Sequence != nil {
+			sequences = append(sequences, colName)
 		}
 		colDefs[colName] = c
 		colNames = append(colNames, colName)
 	}
+	for _, col := range sequences {
+		c := colDefs[col]
+		lastValue, err := isi.getSequenceLastValue(table, col, c.Sequence.Name)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't get last value of identity or sequence of column %s: %v", col, err))
+			c.Sequence = nil
+		} else {
+			c.Sequence.LastValue = lastValue
+		}
+		colDefs[col] = c
+	}
 	return colDefs, colNames, nil
 }
 
+// nextValueFor matches the default of columns whose values are generated by
+// a sequence, e.g. (NEXT VALUE FOR [dbo].[order_seq]).
+var nextValueFor = regexp.MustCompile(`(?i)^\(*NEXT VALUE FOR ([^()]+?)\)*$`)
+
+// getSequenceLastValue returns the largest value generated by sequence seq,
+// or by the identity of table if seq is empty, or stored in column col of
+// table, which may have been inserted explicitly.
+func (isi InfoSchemaImpl) getSequenceLastValue(table common.SchemaAndName, col, seq string) (int64, error) {
+	current := "IDENT_CURRENT(@p1)"
+	arg := fmt.Sprintf("[%s].[%s]", table.Schema, table.Name)
+	if seq != "" {
+		current, arg = "(SELECT current_value FROM sys.sequences WHERE object_id = OBJECT_ID(@p1))", seq
+	}
+	q := fmt.Sprintf("SELECT CAST(%s AS BIGINT), (SELECT CAST(MAX([%s]) AS BIGINT) FROM [%s].[%s])", current, col, table.Schema, table.Name)
+	var lastValue, maxValue sql.NullInt64
+	if err := isi.Db.QueryRow(q, arg).Scan(&lastValue, &maxValue); err != nil {
+		return 0, err
+	}
+	if maxValue.Int64 > lastValue.Int64 {
+		return maxValue.Int64, nil
+	}
+	return lastValue.Int64, nil
+}
+
 // GetConstraints returns a list of primary keys and by-column map of
 // other constraints.  Note: we need to preserve ordinal order of
 // columns in primary key constraints.
@@ -15,6 +15,7 @@
 package sqlserver
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"testing"
@@ -22,6 +23,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 	"github.com/stretchr/testify/assert"
@@ -72,11 +74,11 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"dbo", "user"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_identity", "collation_name"},
 			rows: [][]driver.Value{
-				{"user_id", "text", "NO", nil, nil, nil, nil},
-				{"name", "text", "NO", nil, nil, nil, nil},
-				{"ref", "bigint", "YES", nil, nil, nil, nil}},
+				{"user_id", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"name", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"ref", "bigint", "YES", nil, nil, nil, nil, nil, nil}},
 		}, {
 			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
 			args:  []driver.Value{"dbo", "test"},
@@ -96,45 +98,45 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"dbo", "test"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_identity", "collation_name"},
 			rows: [][]driver.Value{
-				{"Id", "int", "NO", nil, nil, 10, 0},
-				{"BigInt", "bigint", "YES", nil, nil, 19, 0},
-				{"Binary", "binary", "YES", nil, 50, nil, nil},
-				{"Bit", "bit", "YES", nil, nil, nil, nil},
-				{"Char", "char", "YES", nil, 10, nil, nil},
-				{"Date", "date", "YES", nil, nil, nil, nil},
-				{"DateTime", "datetime", "YES", nil, nil, nil, nil},
-				{"DateTime2", "datetime2", "YES", nil, nil, nil, nil},
-				{"DateTimeOffset", "datetimeoffset", "YES", nil, nil, nil, nil},
-				{"Decimal", "decimal", "YES", nil, nil, 18, 9},
-				{"Float", "float", "YES", nil, nil, 53, nil},
-				{"Geography", "geography", "YES", nil, -1, nil, nil},
-				{"Geometry", "geometry", "YES", nil, -1, nil, nil},
-				{"HierarchyId", "hierarchyid", "YES", nil, 892, nil, nil},
-				{"Image", "image", "YES", nil, 2147483647, nil, nil},
-				{"Int", "int", "YES", nil, nil, 10, 0},
-				{"Money", "money", "YES", nil, nil, 19, 4},
-				{"NChar", "nchar", "YES", nil, 10, nil, nil},
-				{"NText", "ntext", "YES", nil, 1073741823, nil, nil},
-				{"Numeric", "numeric", "YES", nil, nil, 18, 17},
-				{"NVarChar", "nvarchar", "YES", nil, 50, nil, nil},
-				{"NVarCharMax", "nvarchar", "YES", nil, -1, nil, nil},
-				{"Real", "real", "YES", nil, nil, 24, nil},
-				{"SmallDateTime", "smalldatetime", "YES", nil, nil, nil, nil},
-				{"SmallInt", "smallint", "YES", nil, nil, 5, 0},
-				{"SmallMoney", "smallmoney", "YES", nil, nil, 10, 4},
-				{"SQLVariant", "sql_variant", "YES", nil, 0, nil, nil},
-				{"Text", "text", "YES", nil, 2147483647, nil, nil},
-				{"Time", "time", "YES", nil, nil, nil, nil},
-				{"TimeStamp", "timestamp", "YES", nil, nil, nil, nil},
-				{"TinyInt", "tinyint", "YES", nil, nil, 3, 0},
-				{"UniqueIdentifier", "uniqueidentifier", "YES", nil, nil, nil, nil},
-				{"VarBinary", "varbinary", "YES", nil, 50, nil, nil},
-				{"VarBinaryMax", "varbinary", "YES", nil, -1, nil, nil},
-				{"VarChar", "varchar", "YES", nil, 50, nil, nil},
-				{"VarCharMax", "varchar", "YES", nil, -1, nil, nil},
-				{"Xml", "xml", "YES", nil, -1, nil, nil},
+				{"Id", "int", "NO", nil, nil, 10, 0, nil, nil},
+				{"BigInt", "bigint", "YES", nil, nil, 19, 0, nil, nil},
+				{"Binary", "binary", "YES", nil, 50, nil, nil, nil, nil},
+				{"Bit", "bit", "YES", nil, nil, nil, nil, nil, nil},
+				{"Char", "char", "YES", nil, 10, nil, nil, nil, nil},
+				{"Date", "date", "YES", nil, nil, nil, nil, nil, nil},
+				{"DateTime", "datetime", "YES", nil, nil, nil, nil, nil, nil},
+				{"DateTime2", "datetime2", "YES", nil, nil, nil, nil, nil, nil},
+				{"DateTimeOffset", "datetimeoffset", "YES", nil, nil, nil, nil, nil, nil},
+				{"Decimal", "decimal", "YES", nil, nil, 18, 9, nil, nil},
+				{"Float", "float", "YES", nil, nil, 53, nil, nil, nil},
+				{"Geography", "geography", "YES", nil, -1, nil, nil, nil, nil},
+				{"Geometry", "geometry", "YES", nil, -1, nil, nil, nil, nil},
+				{"HierarchyId", "hierarchyid", "YES", nil, 892, nil, nil, nil, nil},
+				{"Image", "image", "YES", nil, 2147483647, nil, nil, nil, nil},
+				{"Int", "int", "YES", nil, nil, 10, 0, nil, nil},
+				{"Money", "money", "YES", nil, nil, 19, 4, nil, nil},
+				{"NChar", "nchar", "YES", nil, 10, nil, nil, nil, nil},
+				{"NText", "ntext", "YES", nil, 1073741823, nil, nil, nil, nil},
+				{"Numeric", "numeric", "YES", nil, nil, 18, 17, nil, nil},
+				{"NVarChar", "nvarchar", "YES", nil, 50, nil, nil, nil, nil},
+				{"NVarCharMax", "nvarchar", "YES", nil, -1, nil, nil, nil, nil},
+				{"Real", "real", "YES", nil, nil, 24, nil, nil, nil},
+				{"SmallDateTime", "smalldatetime", "YES", nil, nil, nil, nil, nil, nil},
+				{"SmallInt", "smallint", "YES", nil, nil, 5, 0, nil, nil},
+				{"SmallMoney", "smallmoney", "YES", nil, nil, 10, 4, nil, nil},
+				{"SQLVariant", "sql_variant", "YES", nil, 0, nil, nil, nil, nil},
+				{"Text", "text", "YES", nil, 2147483647, nil, nil, nil, nil},
+				{"Time", "time", "YES", nil, nil, nil, nil, nil, nil},
+				{"TimeStamp", "timestamp", "YES", nil, nil, nil, nil, nil, nil},
+				{"TinyInt", "tinyint", "YES", nil, nil, 3, 0, nil, nil},
+				{"UniqueIdentifier", "uniqueidentifier", "YES", nil, nil, nil, nil, nil, nil},
+				{"VarBinary", "varbinary", "YES", nil, 50, nil, nil, nil, nil},
+				{"VarBinaryMax", "varbinary", "YES", nil, -1, nil, nil, nil, nil},
+				{"VarChar", "varchar", "YES", nil, 50, nil, nil, nil, nil},
+				{"VarCharMax", "varchar", "YES", nil, -1, nil, nil, nil, nil},
+				{"Xml", "xml", "YES", nil, -1, nil, nil, nil, nil},
 			},
 		},
 
@@ -166,11 +168,11 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"dbo", "cart"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_identity", "collation_name"},
 			rows: [][]driver.Value{
-				{"productid", "text", "NO", nil, nil, nil, nil},
-				{"userid", "text", "NO", nil, nil, nil, nil},
-				{"quantity", "bigint", "YES", nil, nil, 64, 0}},
+				{"productid", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"userid", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"quantity", "bigint", "YES", nil, nil, 64, 0, nil, nil}},
 		},
 
 		{
@@ -191,10 +193,10 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"production", "product"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_identity", "collation_name"},
 			rows: [][]driver.Value{
-				{"product_id", "text", "NO", nil, nil, nil, nil},
-				{"product_name", "text", "NO", nil, nil, nil, nil},
+				{"product_id", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"product_name", "text", "NO", nil, nil, nil, nil, nil, nil},
 			},
 		},
 
@@ -217,17 +219,17 @@ func TestProcessSchema(t *testing.T) {
 		}, {
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"dbo", "test_ref"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_identity", "collation_name"},
 			rows: [][]driver.Value{
-				{"ref_id", "bigint", "NO", nil, nil, 64, 0},
-				{"ref_txt", "text", "NO", nil, nil, nil, nil},
-				{"abc", "text", "NO", nil, nil, nil, nil},
+				{"ref_id", "bigint", "NO", nil, nil, 64, 0, nil, nil},
+				{"ref_txt", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"abc", "text", "NO", nil, nil, nil, nil, nil, nil},
 			},
 		},
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
-	err := common.ProcessSchema(conv, InfoSchemaImpl{"test", db})
+	err := common.ProcessSchema(conv, InfoSchemaImpl{DbName: "test", Db: db})
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
 		"user": {
@@ -328,6 +330,120 @@ func TestProcessSchema(t *testing.T) {
 
 }
 
+func TestProcessSchema_Identity(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: `SELECT (.+) WHERE TBL.type = 'U' AND TBL.is_tracked_by_cdc = 0 AND TBL.is_ms_shipped = 0 AND TBL.name <> 'sysdiagrams'`,
+			cols:  []string{"table_schema", "table_name"},
+			rows:  [][]driver.Value{{"dbo", "orders"}},
+		}, {
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS (.+)",
+			args:  []driver.Value{"dbo", "orders"},
+			cols:  []string{"column_name", "constraint_type"},
+			rows:  [][]driver.Value{{"id", "PRIMARY KEY"}},
+		}, {
+			query: "SELECT (.+) FROM sys.foreign_keys AS FK (.+)",
+			args:  []driver.Value{"dbo.orders"},
+			cols:  []string{"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME", "REF_COLUMN_NAME", "CONSTRAINT_NAME"},
+		}, {
+			query: "SELECT (.+) FROM sys.indexes (.+)",
+			args:  []driver.Value{"orders", "dbo"},
+			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
+		}, {
+			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
+			args:  []driver.Value{"dbo", "orders"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_identity", "collation_name"},
+			rows: [][]driver.Value{
+				{"id", "bigint", "NO", nil, nil, 19, 0, 1, nil},
+				{"num", "int", "NO", "(NEXT VALUE FOR [dbo].[order_num_seq])", nil, 10, 0, 0, nil}},
+		}, {
+			query: `SELECT CAST\(IDENT_CURRENT\(@p1\) AS BIGINT\), \(SELECT CAST\(MAX\(\[id\]\) AS BIGINT\) FROM \[dbo\].\[orders\]\)`,
+			args:  []driver.Value{"[dbo].[orders]"},
+			cols:  []string{"current", "max"},
+			rows:  [][]driver.Value{{int64(500), int64(512)}},
+		}, {
+			query: `SELECT CAST\(\(SELECT current_value FROM sys.sequences (.+)\) AS BIGINT\), \(SELECT CAST\(MAX\(\[num\]\) AS BIGINT\) FROM \[dbo\].\[orders\]\)`,
+			args:  []driver.Value{"[dbo].[order_num_seq]"},
+			cols:  []string{"current", "max"},
+			rows:  [][]driver.Value{{int64(7), int64(3)}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	err := common.ProcessSchema(conv, InfoSchemaImpl{DbName: "test", Db: db})
+	assert.Nil(t, err)
+	assert.Equal(t, &schema.Sequence{LastValue: 512}, conv.SrcSchema["orders"].ColDefs["id"].Sequence)
+	assert.Equal(t, &schema.Sequence{Name: "[dbo].[order_num_seq]", LastValue: 7}, conv.SrcSchema["orders"].ColDefs["num"].Sequence)
+	assert.Equal(t, &ddl.Sequence{Name: "orders_id_seq", SkipRangeMin: 1, SkipRangeMax: 512, StartWithCounter: 513}, conv.SpSchema["orders"].ColDefs["id"].Sequence)
+	assert.Equal(t, &ddl.Sequence{Name: "orders_num_seq", SkipRangeMin: 1, SkipRangeMax: 7, StartWithCounter: 8}, conv.SpSchema["orders"].ColDefs["num"].Sequence)
+	assert.Equal(t, []internal.SchemaIssue{internal.SourceSequence}, conv.Issues["orders"]["id"])
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestProcessData_Parallel(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT MIN\\(\\[id\\]\\), MAX\\(\\[id\\]\\) FROM \\[test\\].\\[dbo\\].\\[t\\]",
+			cols:  []string{"", ""},
+			rows:  [][]driver.Value{{1, 5}},
+		}, {
+			query: "SELECT TOP 2 \\[id\\], \\[name\\] FROM \\[test\\].\\[dbo\\].\\[t\\] WHERE \\[id\\] >= @p1 AND \\[id\\] <= @p2 ORDER BY \\[id\\]",
+			args:  []driver.Value{1, 5},
+			cols:  []string{"id", "name"},
+			rows:  [][]driver.Value{{1, "cat"}, {2, "dog"}},
+		}, {
+			query: "SELECT TOP 2 (.+) WHERE \\[id\\] > @p1 AND \\[id\\] <= @p2 ORDER BY \\[id\\]",
+			args:  []driver.Value{2, 5},
+			cols:  []string{"id", "name"},
+			rows:  [][]driver.Value{{5, "ant"}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	spSchema := ddl.CreateTable{
+		Name:     "t",
+		ColNames: []string{"id", "name"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"id":   {Name: "id", T: ddl.Type{Name: ddl.Int64}},
+			"name": {Name: "name", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		}}
+	srcSchema := schema.Table{
+		Name:     "dbo.t",
+		Schema:   "dbo",
+		ColNames: []string{"id", "name"},
+		ColDefs: map[string]schema.Column{
+			"id":   {Name: "id", Type: schema.Type{Name: "int"}},
+			"name": {Name: "name", Type: schema.Type{Name: "varchar"}},
+		},
+		PrimaryKeys: []schema.Key{{Column: "id"}}}
+	conv := buildConv(spSchema, srcSchema)
+	conv.SetDataMode()
+	var names []interface{}
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			names = append(names, vals[1])
+		})
+	isi := InfoSchemaImpl{DbName: "test", Db: db, FetchSize: 2, Readers: 1}
+	assert.Nil(t, isi.ProcessData(context.Background(), conv, "dbo.t", srcSchema, "t", spSchema.ColNames, spSchema))
+	assert.Equal(t, []interface{}{"cat", "dog", "ant"}, names)
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+}
+
+func TestKeyHistogram(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "sys.dm_db_stats_histogram",
+			args:  []driver.Value{"[test].[dbo].[t]", "id"},
+			cols:  []string{"stats_id", "range_high_key", "rows"},
+			rows:  [][]driver.Value{{1, 1, 1.0}, {1, 500, 2000.0}, {1, 900, 10.0}, {2, 300, 900.0}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	isi := InfoSchemaImpl{DbName: "test", Db: db}
+	buckets, err := isi.keyHistogram("dbo", "t", "id")
+	assert.Nil(t, err)
+	assert.Equal(t, []common.HistogramBucket{{UpperBound: 1, Rows: 1}, {UpperBound: 500, Rows: 2000}, {UpperBound: 900, Rows: 10}}, buckets)
+}
+
 func mkMockDB(t *testing.T, ms []mockSpec) *sql.DB {
 	db, mock, err := sqlmock.New()
 	assert.Nil(t, err)
@@ -0,0 +1,208 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+)
+
+// streamRow is a row read by one of the readers of a table, or the error
+// that kept it from being read.
+type streamRow struct {
+	vals []string
+	err  error
+}
+
+// streamKey returns the column by which table srcSchema can be read in chunks
+// and key ranges: its primary key, if it is a single integer column.
+func streamKey(srcSchema schema.Table) (string, bool) {
+	if len(srcSchema.PrimaryKeys) != 1 {
+		return "", false
+	}
+	col := srcSchema.PrimaryKeys[0].Column
+	switch strings.ToLower(srcSchema.ColDefs[col].Type.Name) {
+	case "tinyint", "smallint", "int", "bigint":
+		return col, true
+	}
+	return "", false
+}
+
+// processDataParallel converts the data of source table srcTable, reading it
+// by key, the single integer primary key column of the table: the keys of the
+// table are split into isi.Readers ranges, each read on its own connection in
+// chunks of isi.FetchSize rows ordered by key. Rows are converted one at a
+// time as they are read.
+func (isi InfoSchemaImpl) processDataParallel(ctx context.Context, conv *internal.Conv, srcTable string, srcSchema schema.Table, spTable string, spCols []string, spSchema ddl.CreateTable, key string) error {
+	tblName := strings.Replace(srcTable, srcSchema.Schema+".", "", 1)
+	var lo, hi sql.NullInt64
+	q := fmt.Sprintf("SELECT MIN([%s]), MAX([%s]) FROM [%s].[%s].[%s];", key, key, isi.DbName, srcSchema.Schema, tblName)
+	if err := isi.Db.QueryRow(q).Scan(&lo, &hi); err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't get key range of table %s : err = %s", srcTable, err))
+		return err
+	}
+	if !lo.Valid {
+		// The table is empty.
+		return nil
+	}
+	var buckets []common.HistogramBucket
+	if isi.Readers > 1 {
+		var err error
+		if buckets, err = isi.keyHistogram(srcSchema.Schema, tblName, key); err != nil {
+			internal.VerbosePrintf("Couldn't read the statistics of the keys of table %s: %s\n", srcTable, err)
+		}
+	}
+	ranges, estimates := common.PlanKeyRanges(lo.Int64, hi.Int64, buckets, isi.Readers)
+	internal.VerbosePrintln(common.DescribeKeyRanges(srcTable, ranges, estimates))
+	rows := make(chan streamRow, 1024)
+	errs := make(chan error, len(ranges))
+	var wg sync.WaitGroup
+	for _, r := range ranges {
+		wg.Add(1)
+		go func(r [2]int64) {
+			defer wg.Done()
+			errs <- isi.readKeyRange(ctx, conv, srcSchema, tblName, key, r, rows)
+		}(r)
+	}
+	go func() {
+		wg.Wait()
+		close(rows)
+		close(errs)
+	}()
+	for row := range rows {
+		if row.err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", row.err))
+			// Scan failed, so we don't have any data to add to bad rows.
+			conv.StatsAddBadRow(srcTable, conv.DataMode())
+			continue
+		}
+		ProcessDataRow(conv, srcTable, srcSchema.ColNames, srcSchema, spTable, spCols, spSchema, row.vals)
+	}
+	for err := range errs {
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTable, err))
+			return err
+		}
+	}
+	return nil
+}
+
+// keyHistogram returns the histogram of column key of table
+// schemaName.tblName, from the first statistics object (usually that of the
+// primary key index) whose leading column is key. It requires SQL Server 2016
+// SP1 CU2 or later, for sys.dm_db_stats_histogram.
+func (isi InfoSchemaImpl) keyHistogram(schemaName, tblName, key string) ([]common.HistogramBucket, error) {
+	q := `SELECT s.stats_id, CAST(h.range_high_key AS BIGINT), h.range_rows + h.equal_rows
+		FROM sys.stats s
+		JOIN sys.stats_columns sc ON sc.object_id = s.object_id AND sc.stats_id = s.stats_id AND sc.stats_column_id = 1
+		JOIN sys.columns c ON c.object_id = sc.object_id AND c.column_id = sc.column_id
+		CROSS APPLY sys.dm_db_stats_histogram(s.object_id, s.stats_id) h
+		WHERE s.object_id = OBJECT_ID(@p1) AND c.name = @p2
+		ORDER BY s.stats_id, h.step_number;`
+	rows, err := isi.Db.Query(q, fmt.Sprintf("[%s].[%s].[%s]", isi.DbName, schemaName, tblName), key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var buckets []common.HistogramBucket
+	var first int64
+	for rows.Next() {
+		var statsId int64
+		var b common.HistogramBucket
+		if err := rows.Scan(&statsId, &b.UpperBound, &b.Rows); err != nil {
+			return nil, err
+		}
+		if len(buckets) == 0 {
+			first = statsId
+		} else if statsId != first {
+			break
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// readKeyRange reads the rows of table tblName whose key is in range r, in
+// chunks of isi.FetchSize rows (or all at once if FetchSize is 0), and sends
+// them to rows. Each chunk starts after the last key of the previous one.
+func (isi InfoSchemaImpl) readKeyRange(ctx context.Context, conv *internal.Conv, srcSchema schema.Table, tblName, key string, r [2]int64, rows chan<- streamRow) error {
+	keyIdx := -1
+	for i, c := range srcSchema.ColNames {
+		if c == key {
+			keyIdx = i
+		}
+	}
+	q := getSelectQuery(isi.DbName, srcSchema.Schema, tblName, srcSchema.ColNames, srcSchema.ColDefs)
+	if isi.FetchSize > 0 {
+		q = strings.Replace(q, "SELECT ", fmt.Sprintf("SELECT TOP %d ", isi.FetchSize), 1)
+	}
+	start, op := r[0], ">="
+	for {
+		if op == ">" {
+			// Chunks are checkpoints: pause between them outside the migration windows.
+			if err := conv.Schedule.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		n, last, ok, err := isi.readChunk(fmt.Sprintf("%s WHERE [%s] %s @p1 AND [%s] <= @p2 ORDER BY [%s]", q, key, op, key, key), start, r[1], keyIdx, len(srcSchema.ColNames), rows)
+		if err != nil {
+			return err
+		}
+		if isi.FetchSize == 0 || n < isi.FetchSize {
+			return nil
+		}
+		if !ok {
+			return fmt.Errorf("couldn't read the key of row %d of a chunk starting at key %d", n, start)
+		}
+		start, op = last, ">"
+	}
+}
+
+// readChunk runs query q with the bounds of a key range and sends the rows
+// read to rows. It returns the number of rows read and the key of the last
+// one, if that row could be read.
+func (isi InfoSchemaImpl) readChunk(q string, start, end int64, keyIdx, ncols int, rows chan<- streamRow) (int, int64, bool, error) {
+	rs, err := isi.Db.Query(q, start, end)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer rs.Close()
+	v, scanArgs := buildVals(ncols)
+	var n int
+	var last int64
+	var ok bool
+	for rs.Next() {
+		n++
+		if err := rs.Scan(scanArgs...); err != nil {
+			rows <- streamRow{err: err}
+			ok = false
+			continue
+		}
+		vals := valsToStrings(v)
+		last, err = strconv.ParseInt(vals[keyIdx], 10, 64)
+		ok = err == nil
+		rows <- streamRow{vals: vals}
+	}
+	return n, last, ok, rs.Err()
+}
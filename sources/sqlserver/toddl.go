@@ -18,6 +18,7 @@ package sqlserver
 import (
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 )
 
@@ -30,54 +31,78 @@ type ToDdlImpl struct {
 // mapping.  toSpannerType returns the Spanner type and a list of type
 // conversion issues encountered.
 func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, columnType schema.Type) (ddl.Type, []internal.SchemaIssue) {
-	ty, issues := toSpannerTypeInternal(columnType.Name, columnType.Mods)
-	return ty, issues
+	return TypeMap.ToSpannerType(columnType.Name, "", columnType.Mods)
 }
 
-// toSpannerType maps a scalar source schema type (defined by id and
-// mods) into a Spanner type. This is the core source-to-Spanner type
-// mapping.  toSpannerType returns the Spanner type and a list of type
-// conversion issues encountered.
-func toSpannerTypeInternal(srcType string, mods []int64) (ddl.Type, []internal.SchemaIssue) {
-	switch srcType {
-	case "bit":
-		return ddl.Type{Name: ddl.Bool}, nil
-	case "uniqueidentifier":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "binary", "varbinary", "image":
-		return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
-	case "date":
-		return ddl.Type{Name: ddl.Date}, nil
-	case "float", "real":
-		return ddl.Type{Name: ddl.Float64}, []internal.SchemaIssue{internal.Widened}
-	case "bigint":
-		return ddl.Type{Name: ddl.Int64}, nil
-	case "tinyint", "smallint", "int":
-		return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}
-	case "numeric", "money", "smallmoney", "decimal":
-		return ddl.Type{Name: ddl.Numeric}, nil
-	case "ntext", "text", "xml":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "smalldatetime", "datetimeoffset", "datetime2", "datetime":
-		return ddl.Type{Name: ddl.Timestamp}, []internal.SchemaIssue{internal.Timestamp}
-	case "time":
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Time}
-	case "varchar", "char", "nvarchar", "nchar":
-		// Sets the source length only if it falls within the allowed length range in Spanner.
-		if len(mods) > 0 && mods[0] > 0 && mods[0] <= ddl.StringMaxLength {
-			return ddl.Type{Name: ddl.String, Len: mods[0]}, nil
-		}
-		// Raises warning and sets length to MAX when -
-		// Source length is greater than maximum allowed length
-		// -OR-
-		// Source length is "-1" which represents MAX in SQL Server
-		if len(mods) > 0 && (mods[0] > ddl.StringMaxLength || mods[0] < 0) {
-			return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.StringOverflow}
-		}
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
-	case "timestamp":
-		return ddl.Type{Name: ddl.Int64}, nil
-	default:
-		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
+// TypeMap declares the mapping of SQL Server types to Spanner types.
+var TypeMap = common.NewTypeMap(
+	common.TypeGroup{SrcTypes: []string{"bit"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Bool}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"uniqueidentifier"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.To(common.MaxBytes)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"binary", "varbinary", "image"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxBytes),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"date"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Date}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"float", "real"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Float64}, internal.Widened),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"bigint"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"tinyint", "smallint", "int"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}, internal.Widened),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	// TODO: check mod[0] and mod[1] and generate a warning
+	// if this numeric won't fit in Spanner's NUMERIC.
+	common.TypeGroup{SrcTypes: []string{"numeric", "money", "smallmoney", "decimal"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Numeric}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"ntext", "text", "xml"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString),
+		Options: map[string]common.MapFunc{ddl.Bytes: common.To(common.MaxBytes)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"smalldatetime", "datetimeoffset", "datetime2", "datetime"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Timestamp}, internal.Timestamp),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"time"}, TypeMapping: common.TypeMapping{
+		Default: common.To(common.MaxString, internal.Time),
+	}},
+	common.TypeGroup{SrcTypes: []string{"varchar", "char", "nvarchar", "nchar"}, TypeMapping: common.TypeMapping{
+		Default: toSpannerString,
+		Options: map[string]common.MapFunc{ddl.Bytes: common.ToSized(ddl.Bytes, ddl.MaxLength)},
+	}},
+	common.TypeGroup{SrcTypes: []string{"timestamp"}, TypeMapping: common.TypeMapping{
+		Default: common.To(ddl.Type{Name: ddl.Int64}),
+		Options: map[string]common.MapFunc{ddl.String: common.To(common.MaxString, internal.Widened)},
+	}},
+)
+
+// toSpannerString maps character types to STRING, with the source length
+// if it falls within the allowed length range in Spanner.
+func toSpannerString(mods []int64) (ddl.Type, []internal.SchemaIssue) {
+	if len(mods) > 0 && mods[0] > 0 && mods[0] <= ddl.StringMaxLength {
+		return ddl.Type{Name: ddl.String, Len: mods[0]}, nil
+	}
+	// Raises warning and sets length to MAX when -
+	// Source length is greater than maximum allowed length
+	// -OR-
+	// Source length is "-1" which represents MAX in SQL Server
+	if len(mods) > 0 && (mods[0] > ddl.StringMaxLength || mods[0] < 0) {
+		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.StringOverflow}
 	}
+	return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
 }
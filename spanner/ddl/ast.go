@@ -69,6 +69,11 @@ const (
 	PGTimestamptz string = "TIMESTAMPTZ"
 	// PGMaxLength represents sentinel for Type's Len field in PG.
 	PGMaxLength = 2621440
+
+	// MaxIndexKeyColumns is the maximum number of key columns in an index.
+	MaxIndexKeyColumns = 16
+	// MaxIndexesPerTable is the maximum number of secondary indexes on a table.
+	MaxIndexesPerTable = 128
 )
 
 // Type represents the type of a column.
@@ -148,6 +153,53 @@ type ColumnDef struct {
 	NotNull bool
 	Comment string
 	Id      string
+	// Sequence, if set, is a bit-reversed sequence that provides the default
+	// value of the column.
+	Sequence *Sequence
+	// AllowCommitTimestamp is set for TIMESTAMP columns whose value is the
+	// commit timestamp of the write, instead of the source value.
+	AllowCommitTimestamp bool
+	// AllowedValues, if set, are the only values of the column, which are
+	// enforced by a CHECK constraint of its table.
+	AllowedValues []string
+	// Check, if set, is a boolean expression on VALUE, the value of the
+	// column, e.g. converted from the CHECK constraints of a PostgreSQL
+	// domain, which is enforced by the same CHECK constraint.
+	Check string
+}
+
+// Sequence encodes the following DDL definition:
+//     create_sequence:
+//       CREATE SEQUENCE sequence_name OPTIONS ( sequence_kind = "bit_reversed_positive" [, skip_range_min = n, skip_range_max = n] [, start_with_counter = n] )
+// Values in the skip range are never generated by the sequence, which is used
+// to avoid collisions with key values migrated from the source database.
+type Sequence struct {
+	Name             string
+	SkipRangeMin     int64
+	SkipRangeMax     int64
+	StartWithCounter int64 // First value of the sequence's internal counter, before bit reversal. Spanner's default if zero.
+}
+
+// PrintCreateSequence unparses a CREATE SEQUENCE statement.
+func (sq Sequence) PrintCreateSequence(c Config) string {
+	if c.TargetDb == constants.TargetExperimentalPostgres {
+		s := fmt.Sprintf("CREATE SEQUENCE %s BIT_REVERSED_POSITIVE", c.quote(sq.Name))
+		if sq.SkipRangeMax > 0 {
+			s += fmt.Sprintf(" SKIP RANGE %d %d", sq.SkipRangeMin, sq.SkipRangeMax)
+		}
+		if sq.StartWithCounter > 0 {
+			s += fmt.Sprintf(" START COUNTER WITH %d", sq.StartWithCounter)
+		}
+		return s
+	}
+	opts := []string{`sequence_kind = "bit_reversed_positive"`}
+	if sq.SkipRangeMax > 0 {
+		opts = append(opts, fmt.Sprintf("skip_range_min = %d", sq.SkipRangeMin), fmt.Sprintf("skip_range_max = %d", sq.SkipRangeMax))
+	}
+	if sq.StartWithCounter > 0 {
+		opts = append(opts, fmt.Sprintf("start_with_counter = %d", sq.StartWithCounter))
+	}
+	return fmt.Sprintf("CREATE SEQUENCE %s OPTIONS (%s)", c.quote(sq.Name), strings.Join(opts, ", "))
 }
 
 // Config controls how AST nodes are printed (aka unparsed).
@@ -156,6 +208,7 @@ type Config struct {
 	ProtectIds  bool // If true, table and col names are quoted using backticks (avoids reserved-word issue).
 	Tables      bool // If true, print tables
 	ForeignKeys bool // If true, print foreign key constraints.
+	SkipIndexes bool // If true, don't print secondary indexes along with tables.
 	TargetDb    string
 }
 
@@ -170,22 +223,88 @@ func (c Config) quote(s string) string {
 	return s
 }
 
+// quoteString returns s as a string literal.
+func (c Config) quoteString(s string) string {
+	if c.TargetDb == constants.TargetExperimentalPostgres {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`).Replace(s) + "'"
+}
+
 // PrintColumnDef unparses ColumnDef and returns it as well as any ColumnDef
 // comment. These are returned as separate strings to support formatting
 // needs of PrintCreateTable.
 func (cd ColumnDef) PrintColumnDef(c Config) (string, string) {
 	var s string
-	if c.TargetDb == constants.TargetExperimentalPostgres {
+	switch {
+	case c.TargetDb == constants.TargetExperimentalPostgres && cd.AllowCommitTimestamp:
+		// PostgreSQL databases have a dedicated type for commit timestamps.
+		s = fmt.Sprintf("%s SPANNER.COMMIT_TIMESTAMP", c.quote(cd.Name))
+	case c.TargetDb == constants.TargetExperimentalPostgres:
 		s = fmt.Sprintf("%s %s", c.quote(cd.Name), cd.T.PGPrintColumnDefType())
-	} else {
+	default:
 		s = fmt.Sprintf("%s %s", c.quote(cd.Name), cd.T.PrintColumnDefType())
 	}
 	if cd.NotNull {
 		s += " NOT NULL"
 	}
+	if cd.Sequence != nil {
+		if c.TargetDb == constants.TargetExperimentalPostgres {
+			s += fmt.Sprintf(" DEFAULT nextval('%s')", cd.Sequence.Name)
+		} else {
+			s += fmt.Sprintf(" DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE %s))", c.quote(cd.Sequence.Name))
+		}
+	}
+	if cd.AllowCommitTimestamp && c.TargetDb != constants.TargetExperimentalPostgres {
+		s += " OPTIONS (allow_commit_timestamp=true)"
+	}
 	return s, cd.Comment
 }
 
+// PrintCheckConstraint unparses the CHECK constraint that limits column cd of
+// table table to cd.AllowedValues and cd.Check.
+func (cd ColumnDef) PrintCheckConstraint(table string, c Config) string {
+	var conds []string
+	if len(cd.AllowedValues) > 0 {
+		var l []string
+		for _, v := range cd.AllowedValues {
+			l = append(l, c.quoteString(v))
+		}
+		conds = append(conds, fmt.Sprintf("%s IN (%s)", c.quote(cd.Name), strings.Join(l, ", ")))
+	}
+	if cd.Check != "" {
+		conds = append(conds, replaceValue(cd.Check, c.quote(cd.Name)))
+	}
+	return fmt.Sprintf("CONSTRAINT %s CHECK (%s)", c.quote("ck_"+table+"_"+cd.Name), strings.Join(conds, " AND "))
+}
+
+// replaceValue replaces the VALUE keyword in expression expr, outside of
+// string literals, with col.
+func replaceValue(expr, col string) string {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(expr); i++ {
+		switch {
+		case expr[i] == '\'':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(expr[i:], "VALUE") && !isIdentChar(expr, i-1) && !isIdentChar(expr, i+len("VALUE")):
+			b.WriteString(col)
+			i += len("VALUE") - 1
+			continue
+		}
+		b.WriteByte(expr[i])
+	}
+	return b.String()
+}
+
+func isIdentChar(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
 // IndexKey encodes the following DDL definition:
 //     primary_key:
 //       PRIMARY KEY ( [key_part, ...] )
@@ -216,6 +335,8 @@ type Foreignkey struct {
 	ReferTable   string
 	ReferColumns []string
 	Id           string
+	AppEnforced  bool // If true, the constraint is enforced by the application and isn't created in Spanner.
+	Deferred     bool // If true, the constraint is ignored when ordering data loads (used to break foreign key cycles).
 }
 
 // PrintForeignKey unparses the foreign keys.
@@ -267,6 +388,11 @@ func (ct CreateTable) PrintCreateTable(config Config) string {
 		}
 		cols += "\n"
 	}
+	for _, cn := range ct.ColNames {
+		if cd := ct.ColDefs[cn]; len(cd.AllowedValues) > 0 || cd.Check != "" {
+			cols += "\t" + cd.PrintCheckConstraint(ct.Name, config) + ",\n"
+		}
+	}
 
 	for _, p := range ct.Pks {
 		keys = append(keys, p.PrintIndexKey(config))
@@ -295,14 +421,22 @@ func (ct CreateTable) PrintCreateTable(config Config) string {
 
 // CreateIndex encodes the following DDL definition:
 //     create index: CREATE [UNIQUE] [NULL_FILTERED] INDEX index_name ON table_name ( key_part [, ...] ) [ storing_clause ] [ , interleave_clause ]
+//     storing_clause: STORING ( column_name [, ...] )
 type CreateIndex struct {
 	Name   string
 	Table  string
 	Unique bool
 	Keys   []IndexKey
 	Id     string
-	// We have no requirements for null-filtered option and
-	// storing/interleaving clauses yet, so we omit them for now.
+	// NullFiltered excludes rows with a NULL in any key column from the index.
+	// For unique indexes, this allows multiple rows with NULL keys, matching the
+	// semantics of UNIQUE constraints in most source databases.
+	NullFiltered bool
+	// StoredColumns are non-key columns stored in the index, so that queries
+	// reading them can be served from the index alone.
+	StoredColumns []string
+	// We have no requirements for interleaving clauses yet, so we omit them
+	// for now.
 }
 
 // PrintCreateIndex unparses a CREATE INDEX statement.
@@ -311,11 +445,71 @@ func (ci CreateIndex) PrintCreateIndex(c Config) string {
 	for _, p := range ci.Keys {
 		keys = append(keys, p.PrintIndexKey(c))
 	}
-	var unique string
+	var unique, nullFiltered, storing, where string
 	if ci.Unique {
 		unique = "UNIQUE "
 	}
-	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, c.quote(ci.Name), c.quote(ci.Table), strings.Join(keys, ", "))
+	if ci.NullFiltered {
+		if c.TargetDb == constants.TargetExperimentalPostgres {
+			// PostgreSQL dialect has no NULL_FILTERED option, and instead
+			// supports null filtering via a WHERE clause.
+			var conds []string
+			for _, p := range ci.Keys {
+				conds = append(conds, c.quote(p.Col)+" IS NOT NULL")
+			}
+			where = " WHERE " + strings.Join(conds, " AND ")
+		} else {
+			nullFiltered = "NULL_FILTERED "
+		}
+	}
+	if len(ci.StoredColumns) > 0 {
+		var cols []string
+		for _, col := range ci.StoredColumns {
+			cols = append(cols, c.quote(col))
+		}
+		if c.TargetDb == constants.TargetExperimentalPostgres {
+			storing = fmt.Sprintf(" INCLUDE (%s)", strings.Join(cols, ", "))
+		} else {
+			storing = fmt.Sprintf(" STORING (%s)", strings.Join(cols, ", "))
+		}
+	}
+	return fmt.Sprintf("CREATE %s%sINDEX %s ON %s (%s)%s%s", unique, nullFiltered, c.quote(ci.Name), c.quote(ci.Table), strings.Join(keys, ", "), storing, where)
+}
+
+// Grant encodes the following DDL definition, which gives a database role
+// privileges on a table for fine-grained access control:
+//     grant: GRANT privilege [( column_name [, ...] )] [, ...] ON TABLE table_name TO ROLE role_name
+// The PostgreSQL dialect omits the ROLE keyword.
+type Grant struct {
+	Privileges []string // SELECT, INSERT, UPDATE or DELETE.
+	Columns    []string // Columns the privileges are limited to, or all columns if empty. DELETE can't be limited to columns.
+	Table      string
+	Role       string
+}
+
+// PrintGrant unparses a GRANT statement.
+func (g Grant) PrintGrant(c Config) string {
+	var cols []string
+	for _, col := range g.Columns {
+		cols = append(cols, c.quote(col))
+	}
+	var privs []string
+	for _, p := range g.Privileges {
+		if len(cols) > 0 {
+			p = fmt.Sprintf("%s(%s)", p, strings.Join(cols, ", "))
+		}
+		privs = append(privs, p)
+	}
+	role := "ROLE " + c.quote(g.Role)
+	if c.TargetDb == constants.TargetExperimentalPostgres {
+		role = c.quote(g.Role)
+	}
+	return fmt.Sprintf("GRANT %s ON TABLE %s TO %s", strings.Join(privs, ", "), c.quote(g.Table), role)
+}
+
+// PrintCreateRole unparses a CREATE ROLE statement for database role role.
+func PrintCreateRole(role string, c Config) string {
+	return "CREATE ROLE " + c.quote(role)
 }
 
 // PrintForeignKeyAlterTable unparses the foreign keys using ALTER TABLE.
@@ -332,6 +526,50 @@ func (k Foreignkey) PrintForeignKeyAlterTable(c Config, tableName string) string
 	return fmt.Sprintf("ALTER TABLE %s ADD %sFOREIGN KEY (%s) REFERENCES %s (%s)", c.quote(tableName), s, strings.Join(cols, ", "), c.quote(k.ReferTable), strings.Join(referCols, ", "))
 }
 
+// PrintOrphanCountQuery returns a query that counts the rows of tableName
+// that violate foreign key k, i.e. rows whose foreign key columns are all
+// non-NULL but have no matching row in the referenced table. Rows with a
+// NULL in any foreign key column are not checked by Spanner.
+func (k Foreignkey) PrintOrphanCountQuery(c Config, tableName string) string {
+	var notNull, match []string
+	for i, col := range k.Columns {
+		notNull = append(notNull, fmt.Sprintf("t.%s IS NOT NULL", c.quote(col)))
+		match = append(match, fmt.Sprintf("r.%s = t.%s", c.quote(k.ReferColumns[i]), c.quote(col)))
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s AS t WHERE %s AND NOT EXISTS (SELECT 1 FROM %s AS r WHERE %s)",
+		c.quote(tableName), strings.Join(notNull, " AND "), c.quote(k.ReferTable), strings.Join(match, " AND "))
+}
+
+// PrintDuplicateKeysQuery returns a query that finds the keys of unique index
+// ci held by more than one row of its table, along with their row counts,
+// most frequent first and at most limit of them. Keys with a NULL in any key
+// column are skipped if the index is null-filtered, since Spanner doesn't
+// index them.
+func (ci CreateIndex) PrintDuplicateKeysQuery(c Config, limit int) string {
+	var keys, notNull []string
+	for _, p := range ci.Keys {
+		keys = append(keys, c.quote(p.Col))
+		notNull = append(notNull, c.quote(p.Col)+" IS NOT NULL")
+	}
+	var where string
+	if ci.NullFiltered {
+		where = " WHERE " + strings.Join(notNull, " AND ")
+	}
+	return fmt.Sprintf("SELECT %s, COUNT(*) FROM %s%s GROUP BY %s HAVING COUNT(*) > 1 ORDER BY COUNT(*) DESC LIMIT %d",
+		strings.Join(keys, ", "), c.quote(ci.Table), where, strings.Join(keys, ", "), limit)
+}
+
+// PrintRowCountQuery returns a query that counts the rows of the table.
+func (ct CreateTable) PrintRowCountQuery(c Config) string {
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s", c.quote(ct.Name))
+}
+
+// PrintAddColumn unparses the column definition as an ALTER TABLE ... ADD COLUMN statement.
+func (cd ColumnDef) PrintAddColumn(c Config, tableName string) string {
+	col, _ := cd.PrintColumnDef(c)
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", c.quote(tableName), col)
+}
+
 // Schema stores a map of table names and Tables.
 type Schema map[string]CreateTable
 
@@ -376,6 +614,69 @@ func OrderTables(s Schema) []string {
 	return sortedTableNames
 }
 
+// Dependencies returns the tables that table t depends on when loading data:
+// its parent (if interleaved) and the tables referenced by its foreign keys,
+// in alphabetical order. Self references, tables not in s, and foreign keys
+// that are deferred or enforced by the application are ignored.
+func (s Schema) Dependencies(t string) []string {
+	deps := make(map[string]bool)
+	if p := s[t].Parent; p != "" {
+		deps[p] = true
+	}
+	for _, fk := range s[t].Fks {
+		if !fk.AppEnforced && !fk.Deferred {
+			deps[fk.ReferTable] = true
+		}
+	}
+	var l []string
+	for d := range deps {
+		if _, ok := s[d]; ok && d != t {
+			l = append(l, d)
+		}
+	}
+	sort.Strings(l)
+	return l
+}
+
+// LoadOrder groups the tables of s into levels for loading data. Every table
+// appears after the tables it depends on (see Dependencies), and tables within
+// a level don't depend on each other, so they can be loaded in parallel.
+// Levels (and tables within a level) are ordered as in OrderTables. Foreign
+// keys that form a cycle can't be respected: tables on a cycle are placed in
+// a final level, ordered only by interleaving.
+func LoadOrder(s Schema) [][]string {
+	order := OrderTables(s)
+	level := make(map[string]int)
+	var levels [][]string
+	remaining := order
+	for len(remaining) > 0 {
+		var next, current []string
+		for _, t := range remaining {
+			ready := true
+			for _, d := range s.Dependencies(t) {
+				if l, ok := level[d]; !ok || l == len(levels) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level[t] = len(levels)
+				current = append(current, t)
+			} else {
+				next = append(next, t)
+			}
+		}
+		if len(current) == 0 {
+			// Only tables on (or depending on) foreign key cycles are left.
+			levels = append(levels, next)
+			break
+		}
+		levels = append(levels, current)
+		remaining = next
+	}
+	return levels
+}
+
 // GetDDL returns the string representation of Spanner schema represented by Schema struct.
 // Tables are printed in alphabetical order with one exception: interleaved
 // tables are potentially out of order since they must appear after the
@@ -385,10 +686,20 @@ func (s Schema) GetDDL(c Config) []string {
 	sortedTableNames := OrderTables(s)
 
 	if c.Tables {
+		// Sequences must be created before the tables that use them.
+		for _, tableName := range sortedTableNames {
+			for _, cn := range s[tableName].ColNames {
+				if sq := s[tableName].ColDefs[cn].Sequence; sq != nil {
+					ddl = append(ddl, sq.PrintCreateSequence(c))
+				}
+			}
+		}
 		for _, tableName := range sortedTableNames {
 			ddl = append(ddl, s[tableName].PrintCreateTable(c))
-			for _, index := range s[tableName].Indexes {
-				ddl = append(ddl, index.PrintCreateIndex(c))
+			if !c.SkipIndexes {
+				for _, index := range s[tableName].Indexes {
+					ddl = append(ddl, index.PrintCreateIndex(c))
+				}
 			}
 		}
 	}
@@ -401,6 +712,14 @@ func (s Schema) GetDDL(c Config) []string {
 	if c.ForeignKeys {
 		for _, t := range sortedTableNames {
 			for _, fk := range s[t].Fks {
+				// Foreign keys enforced by the application are only
+				// printed (as comments) for human readers.
+				if fk.AppEnforced {
+					if c.Comments {
+						ddl = append(ddl, "-- Enforced by the application: "+fk.PrintForeignKeyAlterTable(c, t))
+					}
+					continue
+				}
 				ddl = append(ddl, fk.PrintForeignKeyAlterTable(c, t))
 			}
 		}
@@ -408,6 +727,19 @@ func (s Schema) GetDDL(c Config) []string {
 	return ddl
 }
 
+// GetIndexDDL returns the CREATE INDEX statements for all secondary indexes
+// in the schema, in the same table order as GetDDL. It is used to create
+// indexes separately from their tables, e.g. after data has been loaded.
+func (s Schema) GetIndexDDL(c Config) []string {
+	var ddl []string
+	for _, tableName := range OrderTables(s) {
+		for _, index := range s[tableName].Indexes {
+			ddl = append(ddl, index.PrintCreateIndex(c))
+		}
+	}
+	return ddl
+}
+
 // CheckInterleaved checks if schema contains interleaved tables.
 func (s Schema) CheckInterleaved() bool {
 	for _, table := range s {
@@ -71,6 +71,8 @@ func TestPrintColumnDef(t *testing.T) {
 		{in: ColumnDef{Name: "col1", T: Type{Name: Int64}, NotNull: true}, expected: "col1 INT64 NOT NULL"},
 		{in: ColumnDef{Name: "col1", T: Type{Name: Int64, IsArray: true}, NotNull: true}, expected: "col1 ARRAY<INT64> NOT NULL"},
 		{in: ColumnDef{Name: "col1", T: Type{Name: Int64}}, protectIds: true, expected: "`col1` INT64"},
+		{in: ColumnDef{Name: "col1", T: Type{Name: Int64}, NotNull: true, Sequence: &Sequence{Name: "seq1"}}, expected: "col1 INT64 NOT NULL DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE seq1))"},
+		{in: ColumnDef{Name: "col1", T: Type{Name: Timestamp}, NotNull: true, AllowCommitTimestamp: true}, protectIds: true, expected: "`col1` TIMESTAMP NOT NULL OPTIONS (allow_commit_timestamp=true)"},
 	}
 	for _, tc := range tests {
 		s, _ := tc.in.PrintColumnDef(Config{ProtectIds: tc.protectIds})
@@ -89,6 +91,8 @@ func TestPrintColumnDefPG(t *testing.T) {
 		{in: ColumnDef{Name: "col1", T: Type{Name: Int64}, NotNull: true}, expected: "col1 INT8 NOT NULL"},
 		{in: ColumnDef{Name: "col1", T: Type{Name: Int64, IsArray: true}, NotNull: true}, expected: "col1 VARCHAR(2621440) NOT NULL"},
 		{in: ColumnDef{Name: "col1", T: Type{Name: Int64}}, protectIds: true, expected: "\"col1\" INT8"},
+		{in: ColumnDef{Name: "col1", T: Type{Name: Int64}, Sequence: &Sequence{Name: "seq1"}}, expected: "col1 INT8 DEFAULT nextval('seq1')"},
+		{in: ColumnDef{Name: "col1", T: Type{Name: Timestamp}, NotNull: true, AllowCommitTimestamp: true}, expected: "col1 SPANNER.COMMIT_TIMESTAMP NOT NULL"},
 	}
 	for _, tc := range tests {
 		s, _ := tc.in.PrintColumnDef(Config{ProtectIds: tc.protectIds, TargetDb: constants.TargetExperimentalPostgres})
@@ -96,6 +100,17 @@ func TestPrintColumnDefPG(t *testing.T) {
 	}
 }
 
+func TestPrintCreateSequence(t *testing.T) {
+	sq := Sequence{Name: "seq1", SkipRangeMin: 1, SkipRangeMax: 1000}
+	assert.Equal(t, "CREATE SEQUENCE seq1 OPTIONS (sequence_kind = \"bit_reversed_positive\", skip_range_min = 1, skip_range_max = 1000)", sq.PrintCreateSequence(Config{}))
+	assert.Equal(t, "CREATE SEQUENCE seq1 BIT_REVERSED_POSITIVE SKIP RANGE 1 1000", sq.PrintCreateSequence(Config{TargetDb: constants.TargetExperimentalPostgres}))
+	sq = Sequence{Name: "seq1", SkipRangeMin: 1, SkipRangeMax: 1000, StartWithCounter: 1001}
+	assert.Equal(t, "CREATE SEQUENCE seq1 OPTIONS (sequence_kind = \"bit_reversed_positive\", skip_range_min = 1, skip_range_max = 1000, start_with_counter = 1001)", sq.PrintCreateSequence(Config{}))
+	assert.Equal(t, "CREATE SEQUENCE seq1 BIT_REVERSED_POSITIVE SKIP RANGE 1 1000 START COUNTER WITH 1001", sq.PrintCreateSequence(Config{TargetDb: constants.TargetExperimentalPostgres}))
+	sq = Sequence{Name: "seq1"}
+	assert.Equal(t, "CREATE SEQUENCE `seq1` OPTIONS (sequence_kind = \"bit_reversed_positive\")", sq.PrintCreateSequence(Config{ProtectIds: true}))
+}
+
 func TestPrintIndexKey(t *testing.T) {
 	tests := []struct {
 		in         IndexKey
@@ -255,6 +270,49 @@ func TestPrintCreateTablePG(t *testing.T) {
 	}
 }
 
+func TestPrintCreateTableCheckConstraint(t *testing.T) {
+	ct := CreateTable{
+		Name:     "orders",
+		ColNames: []string{"id", "status"},
+		ColDefs: map[string]ColumnDef{
+			"id":     {Name: "id", T: Type{Name: Int64}, NotNull: true},
+			"status": {Name: "status", T: Type{Name: String, Len: MaxLength}, AllowedValues: []string{"new", "shipped", "won't ship"}},
+		},
+		Pks: []IndexKey{{Col: "id"}},
+	}
+	assert.Equal(t, "CREATE TABLE `orders` (\n"+
+		"	`id` INT64 NOT NULL,\n"+
+		"	`status` STRING(MAX),\n"+
+		"	CONSTRAINT `ck_orders_status` CHECK (`status` IN ('new', 'shipped', 'won\\'t ship')),\n"+
+		") PRIMARY KEY (`id`)", ct.PrintCreateTable(Config{ProtectIds: true}))
+	assert.Equal(t, "CREATE TABLE orders (\n"+
+		"	id INT8 NOT NULL,\n"+
+		"	status VARCHAR(2621440),\n"+
+		"	CONSTRAINT ck_orders_status CHECK (status IN ('new', 'shipped', 'won''t ship')),\n"+
+		"	PRIMARY KEY (id)\n"+
+		")", ct.PrintCreateTable(Config{TargetDb: constants.TargetExperimentalPostgres}))
+}
+
+func TestPrintCreateTableDomainCheck(t *testing.T) {
+	ct := CreateTable{
+		Name:     "orders",
+		ColNames: []string{"id", "qty", "code"},
+		ColDefs: map[string]ColumnDef{
+			"id":   {Name: "id", T: Type{Name: Int64}, NotNull: true},
+			"qty":  {Name: "qty", T: Type{Name: Int64}, Check: "(VALUE > 0) AND (VALUE < 100)"},
+			"code": {Name: "code", T: Type{Name: String, Len: MaxLength}, AllowedValues: []string{"a", "b"}, Check: "(VALUE <> 'VALUE')"},
+		},
+		Pks: []IndexKey{{Col: "id"}},
+	}
+	assert.Equal(t, "CREATE TABLE `orders` (\n"+
+		"	`id` INT64 NOT NULL,\n"+
+		"	`qty` INT64,\n"+
+		"	`code` STRING(MAX),\n"+
+		"	CONSTRAINT `ck_orders_qty` CHECK ((`qty` > 0) AND (`qty` < 100)),\n"+
+		"	CONSTRAINT `ck_orders_code` CHECK (`code` IN ('a', 'b') AND (`code` <> 'VALUE')),\n"+
+		") PRIMARY KEY (`id`)", ct.PrintCreateTable(Config{ProtectIds: true}))
+}
+
 func TestPrintCreateIndex(t *testing.T) {
 	ci := []CreateIndex{
 		{
@@ -263,6 +321,8 @@ func TestPrintCreateIndex(t *testing.T) {
 			/*Unique =*/ false,
 			[]IndexKey{{Col: "col1", Desc: true}, {Col: "col2"}},
 			"1",
+			/*NullFiltered =*/ false,
+			nil,
 		},
 		{
 			"myindex2",
@@ -270,6 +330,24 @@ func TestPrintCreateIndex(t *testing.T) {
 			/*Unique =*/ true,
 			[]IndexKey{{Col: "col1", Desc: true}, {Col: "col2"}},
 			"1",
+			/*NullFiltered =*/ false,
+			nil,
+		},
+		{
+			"myindex3",
+			"mytable",
+			/*Unique =*/ true,
+			[]IndexKey{{Col: "col1", Desc: true}, {Col: "col2"}},
+			"1",
+			/*NullFiltered =*/ true,
+			nil,
+		},
+		{
+			Name:          "myindex4",
+			Table:         "mytable",
+			Keys:          []IndexKey{{Col: "col1"}},
+			NullFiltered:  true,
+			StoredColumns: []string{"col2", "col3"},
 		}}
 	tests := []struct {
 		name       string
@@ -283,6 +361,10 @@ func TestPrintCreateIndex(t *testing.T) {
 		{"unique key", true, "", ci[1], "CREATE UNIQUE INDEX `myindex2` ON `mytable` (`col1` DESC, `col2`)"},
 		{"quote non unique PG", true, constants.TargetExperimentalPostgres, ci[0], "CREATE INDEX \"myindex\" ON \"mytable\" (\"col1\" DESC, \"col2\")"},
 		{"unique key PG", true, constants.TargetExperimentalPostgres, ci[1], "CREATE UNIQUE INDEX \"myindex2\" ON \"mytable\" (\"col1\" DESC, \"col2\")"},
+		{"null filtered unique key", true, "", ci[2], "CREATE UNIQUE NULL_FILTERED INDEX `myindex3` ON `mytable` (`col1` DESC, `col2`)"},
+		{"null filtered unique key PG", true, constants.TargetExperimentalPostgres, ci[2], "CREATE UNIQUE INDEX \"myindex3\" ON \"mytable\" (\"col1\" DESC, \"col2\") WHERE \"col1\" IS NOT NULL AND \"col2\" IS NOT NULL"},
+		{"storing", true, "", ci[3], "CREATE NULL_FILTERED INDEX `myindex4` ON `mytable` (`col1`) STORING (`col2`, `col3`)"},
+		{"storing PG", true, constants.TargetExperimentalPostgres, ci[3], "CREATE INDEX \"myindex4\" ON \"mytable\" (\"col1\") INCLUDE (\"col2\", \"col3\") WHERE \"col1\" IS NOT NULL"},
 	}
 	for _, tc := range tests {
 		assert.Equal(t, tc.expected, tc.index.PrintCreateIndex(Config{ProtectIds: tc.protectIds, TargetDb: tc.targetDb}))
@@ -297,6 +379,8 @@ func TestPrintForeignKey(t *testing.T) {
 			"ref_table",
 			[]string{"ref_c1", "ref_c2"},
 			"1",
+			false,
+			false,
 		},
 		{
 			"",
@@ -304,6 +388,8 @@ func TestPrintForeignKey(t *testing.T) {
 			"ref_table",
 			[]string{"ref_c1"},
 			"1",
+			false,
+			false,
 		},
 	}
 	tests := []struct {
@@ -331,6 +417,8 @@ func TestPrintForeignKeyAlterTable(t *testing.T) {
 			"ref_table",
 			[]string{"ref_c1", "ref_c2"},
 			"1",
+			false,
+			false,
 		},
 		{
 			"",
@@ -338,6 +426,8 @@ func TestPrintForeignKeyAlterTable(t *testing.T) {
 			"ref_table",
 			[]string{"ref_c1"},
 			"1",
+			false,
+			false,
 		},
 	}
 	tests := []struct {
@@ -358,6 +448,58 @@ func TestPrintForeignKeyAlterTable(t *testing.T) {
 	}
 }
 
+func TestPrintOrphanCountQuery(t *testing.T) {
+	fk := Foreignkey{Name: "fk_test", Columns: []string{"c1", "c2"}, ReferTable: "ref_table", ReferColumns: []string{"ref_c1", "ref_c2"}}
+	assert.Equal(t, "SELECT COUNT(*) FROM table1 AS t WHERE t.c1 IS NOT NULL AND t.c2 IS NOT NULL AND NOT EXISTS (SELECT 1 FROM ref_table AS r WHERE r.ref_c1 = t.c1 AND r.ref_c2 = t.c2)",
+		fk.PrintOrphanCountQuery(Config{}, "table1"))
+	assert.Equal(t, "SELECT COUNT(*) FROM \"table1\" AS t WHERE t.\"c1\" IS NOT NULL AND t.\"c2\" IS NOT NULL AND NOT EXISTS (SELECT 1 FROM \"ref_table\" AS r WHERE r.\"ref_c1\" = t.\"c1\" AND r.\"ref_c2\" = t.\"c2\")",
+		fk.PrintOrphanCountQuery(Config{ProtectIds: true, TargetDb: constants.TargetExperimentalPostgres}, "table1"))
+}
+
+func TestPrintDuplicateKeysQuery(t *testing.T) {
+	ci := CreateIndex{Name: "idx", Table: "table1", Unique: true, Keys: []IndexKey{{Col: "c1"}, {Col: "c2", Desc: true}}}
+	assert.Equal(t, "SELECT c1, c2, COUNT(*) FROM table1 GROUP BY c1, c2 HAVING COUNT(*) > 1 ORDER BY COUNT(*) DESC LIMIT 10",
+		ci.PrintDuplicateKeysQuery(Config{}, 10))
+	ci.NullFiltered = true
+	assert.Equal(t, "SELECT `c1`, `c2`, COUNT(*) FROM `table1` WHERE `c1` IS NOT NULL AND `c2` IS NOT NULL GROUP BY `c1`, `c2` HAVING COUNT(*) > 1 ORDER BY COUNT(*) DESC LIMIT 10",
+		ci.PrintDuplicateKeysQuery(Config{ProtectIds: true}, 10))
+	assert.Equal(t, "SELECT \"c1\", \"c2\", COUNT(*) FROM \"table1\" WHERE \"c1\" IS NOT NULL AND \"c2\" IS NOT NULL GROUP BY \"c1\", \"c2\" HAVING COUNT(*) > 1 ORDER BY COUNT(*) DESC LIMIT 5",
+		ci.PrintDuplicateKeysQuery(Config{ProtectIds: true, TargetDb: constants.TargetExperimentalPostgres}, 5))
+}
+
+func TestPrintRowCountQuery(t *testing.T) {
+	ct := CreateTable{Name: "table1"}
+	assert.Equal(t, "SELECT COUNT(*) FROM table1", ct.PrintRowCountQuery(Config{}))
+	assert.Equal(t, "SELECT COUNT(*) FROM `table1`", ct.PrintRowCountQuery(Config{ProtectIds: true}))
+	assert.Equal(t, "SELECT COUNT(*) FROM \"table1\"", ct.PrintRowCountQuery(Config{ProtectIds: true, TargetDb: constants.TargetExperimentalPostgres}))
+}
+
+func TestPrintAddColumn(t *testing.T) {
+	cd := ColumnDef{Name: "col1", T: Type{Name: String, Len: MaxLength}}
+	tests := []struct {
+		name       string
+		protectIds bool
+		targetDb   string
+		expected   string
+	}{
+		{"no quote", false, "", "ALTER TABLE table1 ADD COLUMN col1 STRING(MAX)"},
+		{"quote", true, "", "ALTER TABLE `table1` ADD COLUMN `col1` STRING(MAX)"},
+		{"quote PG", true, constants.TargetExperimentalPostgres, "ALTER TABLE \"table1\" ADD COLUMN \"col1\" VARCHAR(2621440)"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, cd.PrintAddColumn(Config{ProtectIds: tc.protectIds, TargetDb: tc.targetDb}, "table1"))
+	}
+}
+
+func TestPrintGrant(t *testing.T) {
+	g := Grant{Privileges: []string{"SELECT", "DELETE"}, Table: "table1", Role: "analyst"}
+	assert.Equal(t, "GRANT SELECT, DELETE ON TABLE table1 TO ROLE analyst", g.PrintGrant(Config{}))
+	assert.Equal(t, "GRANT SELECT, DELETE ON TABLE \"table1\" TO \"analyst\"", g.PrintGrant(Config{ProtectIds: true, TargetDb: constants.TargetExperimentalPostgres}))
+	g = Grant{Privileges: []string{"SELECT", "UPDATE"}, Columns: []string{"c1", "c2"}, Table: "table1", Role: "analyst"}
+	assert.Equal(t, "GRANT SELECT(`c1`, `c2`), UPDATE(`c1`, `c2`) ON TABLE `table1` TO ROLE `analyst`", g.PrintGrant(Config{ProtectIds: true}))
+	assert.Equal(t, "CREATE ROLE `analyst`", PrintCreateRole("analyst", Config{ProtectIds: true}))
+}
+
 func TestGetDDL(t *testing.T) {
 	s := NewSchema()
 	s["table1"] = CreateTable{
@@ -449,6 +591,41 @@ func TestGetDDL(t *testing.T) {
 		"ALTER TABLE table3 ADD CONSTRAINT fk3 FOREIGN KEY (c) REFERENCES ref_table3 (ref_c)",
 	}
 	assert.ElementsMatch(t, e3, tablesAndFks)
+
+	tablesWithoutIndexes := s.GetDDL(Config{Tables: true, SkipIndexes: true})
+	e4 := []string{
+		"CREATE TABLE table1 (\n" +
+			"	a INT64,\n" +
+			"	b INT64,\n" +
+			") PRIMARY KEY (a)",
+		"CREATE TABLE table2 (\n" +
+			"	a INT64,\n" +
+			"	b INT64,\n" +
+			"	c INT64,\n" +
+			") PRIMARY KEY (a)",
+		"CREATE TABLE table3 (\n" +
+			"	a INT64,\n" +
+			"	b INT64,\n" +
+			"	c INT64,\n" +
+			") PRIMARY KEY (a, b),\n" +
+			"INTERLEAVE IN PARENT table1",
+	}
+	assert.ElementsMatch(t, e4, tablesWithoutIndexes)
+
+	indexesOnly := s.GetIndexDDL(Config{})
+	e5 := []string{
+		"CREATE INDEX index1 ON table1 (b)",
+		"CREATE UNIQUE INDEX index2 ON table2 (b DESC, c)",
+	}
+	assert.Equal(t, e5, indexesOnly)
+
+	t1 := s["table1"]
+	t1.Fks[0].AppEnforced = true
+	s["table1"] = t1
+	fksWithoutAppEnforced := s.GetDDL(Config{ForeignKeys: true})
+	assert.ElementsMatch(t, e2[1:], fksWithoutAppEnforced)
+	fksWithComments := s.GetDDL(Config{Comments: true, ForeignKeys: true})
+	assert.ElementsMatch(t, append([]string{"-- Enforced by the application: " + e2[0]}, e2[1:]...), fksWithComments)
 }
 
 func TestGetPGDDL(t *testing.T) {
@@ -547,3 +724,26 @@ func TestGetPGDDL(t *testing.T) {
 	}
 	assert.ElementsMatch(t, e3, tablesAndFks)
 }
+
+func TestLoadOrder(t *testing.T) {
+	s := NewSchema()
+	s["a"] = CreateTable{Name: "a"}
+	s["b"] = CreateTable{Name: "b", Fks: []Foreignkey{{Name: "fk1", ReferTable: "c"}}}
+	s["c"] = CreateTable{Name: "c"}
+	s["d"] = CreateTable{Name: "d", Parent: "b"}
+	s["e"] = CreateTable{Name: "e", Fks: []Foreignkey{{Name: "fk2", ReferTable: "e"}, {Name: "fk3", ReferTable: "missing"}}}
+	assert.Equal(t, []string{"c"}, s.Dependencies("b"))
+	assert.Equal(t, []string{"b"}, s.Dependencies("d"))
+	assert.Nil(t, s.Dependencies("e"))
+	assert.Equal(t, [][]string{{"a", "c", "e"}, {"b"}, {"d"}}, LoadOrder(s))
+
+	// Tables on a foreign key cycle are loaded last.
+	s["f"] = CreateTable{Name: "f", Fks: []Foreignkey{{Name: "fk4", ReferTable: "g"}}}
+	s["g"] = CreateTable{Name: "g", Fks: []Foreignkey{{Name: "fk5", ReferTable: "f"}}}
+	s["h"] = CreateTable{Name: "h", Parent: "g"}
+	assert.Equal(t, [][]string{{"a", "c", "e"}, {"b"}, {"d"}, {"f", "g", "h"}}, LoadOrder(s))
+
+	// Deferring one of the foreign keys breaks the cycle.
+	s["g"] = CreateTable{Name: "g", Fks: []Foreignkey{{Name: "fk5", ReferTable: "f", Deferred: true}}}
+	assert.Equal(t, [][]string{{"a", "c", "e", "g"}, {"b", "f", "h"}, {"d"}}, LoadOrder(s))
+}
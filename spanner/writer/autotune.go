@@ -0,0 +1,100 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"sync"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	"google.golang.org/grpc/codes"
+)
+
+// Parameters of auto-tuning. The write limit is adjusted after every
+// tuneWindow writes: it is cut by a quarter if more than
+// congestionThreshold of the writes were aborted or rejected by an
+// overloaded Spanner, or if their mean latency was more than twice the
+// lowest mean latency seen, and it is increased by one otherwise.
+const (
+	tuneWindow          = 20
+	congestionThreshold = 0.05
+)
+
+// autoTuner adjusts the limit on in-progress writes of a BatchWriter to the
+// load Spanner can take: additive increase while writes commit quickly,
+// multiplicative decrease once they slow down or abort. A nil autoTuner
+// keeps the configured limit.
+type autoTuner struct {
+	lock      sync.Mutex
+	max       int64         // Configured limit on in-progress writes.
+	limit     int64         // Current limit on in-progress writes.
+	writes    int64         // Writes observed in the current window.
+	congested int64         // Congested writes observed in the current window.
+	latency   time.Duration // Total latency of the writes of the current window.
+	baseline  time.Duration // Lowest mean latency of a window.
+}
+
+// newAutoTuner returns an autoTuner for a configured limit of max
+// in-progress writes. It starts at a quarter of max, and never exceeds it.
+func newAutoTuner(max int64) *autoTuner {
+	limit := max / 4
+	if limit < 1 {
+		limit = 1
+	}
+	return &autoTuner{max: max, limit: limit}
+}
+
+// writeLimit returns the current limit on in-progress writes, or
+// configured if a is nil.
+func (a *autoTuner) writeLimit(configured int64) int64 {
+	if a == nil {
+		return configured
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.limit
+}
+
+// observe records a write that took latency and returned err, and adjusts
+// the limit at the end of each window.
+func (a *autoTuner) observe(latency time.Duration, err error) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.writes++
+	a.latency += latency
+	switch sp.ErrCode(err) {
+	case codes.Aborted, codes.ResourceExhausted, codes.DeadlineExceeded:
+		a.congested++
+	}
+	if a.writes < tuneWindow {
+		return
+	}
+	mean := a.latency / time.Duration(a.writes)
+	if a.baseline == 0 || mean < a.baseline {
+		a.baseline = mean
+	}
+	if float64(a.congested)/float64(a.writes) > congestionThreshold || mean > 2*a.baseline {
+		a.limit -= (a.limit + 3) / 4
+		if a.limit < 1 {
+			a.limit = 1
+		}
+	} else if a.limit < a.max {
+		a.limit++
+	}
+	a.writes, a.congested, a.latency = 0, 0, 0
+}
@@ -18,13 +18,16 @@
 package writer
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
 
 	sp "cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/harbourbridge/common/errs"
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
 )
 
@@ -38,6 +41,14 @@ const (
 	byteThreshold  = 20 * 1 << 20 // Spanner per-operation limit is 100MB.
 )
 
+// parentRowRetryDelay is how long a write whose rows miss their parent rows
+// waits before it is retried (see BatchWriterConfig.ParentRowRetries).
+var parentRowRetryDelay = time.Second
+
+// errRowNotInserted is the error of rows whose INSERT statement was executed
+// by a DML write without inserting them, according to its row count.
+var errRowNotInserted = errors.New("INSERT statement didn't insert the row")
+
 // BatchWriter accumulates rows of data (via AddRow) and assembles them
 // into batches that it asynchronously writes to Spanner.  Rows are
 // written to Spanner using insert semantics i.e. if a row already exists
@@ -49,18 +60,46 @@ const (
 // in-progress writes, amount of data buffered and retry behavior.
 // BatchWriter is not threadsafe: only one call to AddRow or Flush should
 // be active at any time.  See ExampleBatchWriter (batchwriter_test.go)
-// for sample usage code.
+// for sample usage code. Rows are written with mutations, unless the
+// BatchWriter is configured with WriteDML, in which case they are written
+// with batches of DML INSERT statements.
 type BatchWriter struct {
-	rows       []*row                     // Buffered rows.
-	rBytes     int64                      // Estimate of bytes for buffered rows.
-	rCount     int64                      // Mutation count for buffered rows.
-	write      func([]*sp.Mutation) error // Typically a closure that calls client.Apply, but structured this way for testing.
-	wg         sync.WaitGroup             // Tracks in-progress writes.
-	writeLimit int64                      // Limit on number of in-progress writes.
-	bytesLimit int64                      // Limit on bytes buffered. AddRow blocks if rBytes exceeded this value.
-	retryLimit int64                      // Limit on retries.
-	verbose    bool                       // If true, print out messages about each write batch.
-	async      asyncState
+	rows          []*row                                                           // Buffered rows.
+	rBytes        int64                                                            // Estimate of bytes for buffered rows.
+	rCount        int64                                                            // Mutation count for buffered rows.
+	write         func([]*sp.Mutation) error                                       // Typically a closure that calls client.Apply, but structured this way for testing.
+	writeDML      func([]sp.Statement) ([]int64, error)                            // Typically a closure that calls BatchUpdate in a read-write transaction. If set, write isn't used.
+	postgreSQL    bool                                                             // If true, DML statements are in the PostgreSQL dialect.
+	wg            sync.WaitGroup                                                   // Tracks in-progress writes.
+	writeLimit    int64                                                            // Limit on number of in-progress writes.
+	bytesLimit    int64                                                            // Limit on bytes buffered. AddRow blocks if rBytes exceeded this value.
+	batchBytes    int64                                                            // Limit on bytes per write.
+	retryLimit    int64                                                            // Limit on retries.
+	parentRetries int64                                                            // Retries of writes whose rows miss their parent rows.
+	verbose       bool                                                             // If true, print out messages about each write batch.
+	droppedRow    func(table string, cols []string, vals []interface{}, err error) // If set, called for each dropped row.
+	tables        map[string]TableConfig                                           // Tuning of the writes of the rows of some tables.
+	tuner         *autoTuner                                                       // If set, adjusts the limit on in-progress writes.
+	observe       func(WriteResult)                                                // If set, called after each write.
+	async         asyncState
+}
+
+// TableConfig tunes the writes of the rows of a table. Batches of rows of
+// tables with a TableConfig don't include rows of other tables.
+type TableConfig struct {
+	WriteLimit int64 // Limit on in-progress writes of the table's rows, within the overall limit (no limit if 0).
+	BatchRows  int64 // Limit on rows per write (if 0, only the mutation count and byte thresholds apply).
+}
+
+// WriteResult describes a write of rows to Spanner.
+type WriteResult struct {
+	Table   string        // Table of the first row of the write.
+	Rows    int64         // Number of rows written.
+	Bytes   int64         // Estimate of the bytes of the rows.
+	Start   time.Time     // Start of the write.
+	Latency time.Duration // Time the write took to commit or fail.
+	Retry   bool          // If true, the write is a retry of part of a failed write.
+	Err     error         // Error of the write, classified by errs.Spanner.
 }
 
 type row struct {
@@ -84,28 +123,54 @@ type asyncState struct {
 	sampleBadRows      []*row           // A sample of rows that generated errors; protected by lock.
 	sampleBadRowsBytes int64            // Estimate of bytes for sampleBadRows; protected by lock.
 	droppedRows        map[string]int64 // Count of dropped rows, broken down by table.
+	tableWrites        map[string]int64 // Number of in-progress writes, broken down by table of their first row; protected by lock.
 }
 
 // BatchWriterConfig specifies parameters for configuring BatchWriter.
 type BatchWriterConfig struct {
-	WriteLimit int64                      // Limit on number of in-progress writes.
-	BytesLimit int64                      // Limit on bytes buffered.
-	RetryLimit int64                      // Limit on retries.
-	Write      func([]*sp.Mutation) error // Function to call to write to Spanner (typically a closure that calls client.Apply).
-	Verbose    bool                       // If true, print out messages about each write batch.
+	WriteLimit       int64                                                            // Limit on number of in-progress writes.
+	BytesLimit       int64                                                            // Limit on bytes buffered.
+	BatchBytes       int64                                                            // Limit on bytes per write (if 0, a conservative fraction of Spanner's limit). A row bigger than this is written on its own.
+	RetryLimit       int64                                                            // Limit on retries.
+	Write            func([]*sp.Mutation) error                                       // Function to call to write to Spanner (typically a closure that calls client.Apply).
+	WriteDML         func([]sp.Statement) ([]int64, error)                            // If set, function to call to write to Spanner with DML instead of Write (typically a closure that calls BatchUpdate), returning the row count of each statement.
+	PostgreSQL       bool                                                             // If true, DML statements are written in the PostgreSQL dialect.
+	Verbose          bool                                                             // If true, print out messages about each write batch.
+	DroppedRow       func(table string, cols []string, vals []interface{}, err error) // If set, called for each row that is dropped, along with the error for its batch, classified by errs.Spanner.
+	Tables           map[string]TableConfig                                           // Tuning of the writes of the rows of some tables, by table name.
+	AutoTune         bool                                                             // If true, the limit on in-progress writes is adjusted to observed commit latencies and aborts, up to WriteLimit.
+	Observe          func(WriteResult)                                                // If set, called after each write, e.g. to record write performance. Must be thread-safe.
+	ParentRowRetries int64                                                            // Number of times a write that failed because rows of interleaved tables miss their parent rows is retried, after a delay, before its batch is split. Useful when parent and child rows are written concurrently.
 }
 
 // NewBatchWriter returns a new BatchWriter with parameters defined by config.
 func NewBatchWriter(config BatchWriterConfig) *BatchWriter {
+	batchBytes := int64(byteThreshold)
+	if config.BatchBytes > 0 && config.BatchBytes < batchBytes {
+		batchBytes = config.BatchBytes
+	}
+	var tuner *autoTuner
+	if config.AutoTune {
+		tuner = newAutoTuner(config.WriteLimit)
+	}
 	return &BatchWriter{
-		write:      config.Write,
-		writeLimit: config.WriteLimit,
-		bytesLimit: config.BytesLimit,
-		retryLimit: config.RetryLimit,
-		verbose:    config.Verbose,
+		write:         config.Write,
+		writeDML:      config.WriteDML,
+		postgreSQL:    config.PostgreSQL,
+		writeLimit:    config.WriteLimit,
+		bytesLimit:    config.BytesLimit,
+		batchBytes:    batchBytes,
+		retryLimit:    config.RetryLimit,
+		parentRetries: config.ParentRowRetries,
+		verbose:       config.Verbose,
+		droppedRow:    config.DroppedRow,
+		tables:        config.Tables,
+		tuner:         tuner,
+		observe:       config.Observe,
 		async: asyncState{
 			errors:      make(map[string]int64),
 			droppedRows: make(map[string]int64),
+			tableWrites: make(map[string]int64),
 		},
 	}
 }
@@ -126,7 +191,7 @@ func (bw *BatchWriter) AddRow(table string, cols []string, vals []interface{}) {
 // for them to complete.
 func (bw *BatchWriter) Flush() {
 	for len(bw.rows) > 0 {
-		if atomic.LoadInt64(&bw.async.writes) < bw.writeLimit {
+		if bw.canStartWrite() {
 			m, count, bytes := bw.getBatch()
 			if bw.verbose {
 				fmt.Printf("Starting write of %d rows to Spanner (%d bytes, %d mutations) [%d in progress]\n",
@@ -192,8 +257,28 @@ func (bw *BatchWriter) getBadRowsForTest() []*row {
 	return bw.async.sampleBadRows
 }
 
+// canStartWrite returns true if a write of the rows at the front of bw.rows
+// can start without exceeding the limits on in-progress writes.
+func (bw *BatchWriter) canStartWrite() bool {
+	if atomic.LoadInt64(&bw.async.writes) >= bw.tuner.writeLimit(bw.writeLimit) {
+		return false
+	}
+	if len(bw.rows) == 0 {
+		return true
+	}
+	table := bw.rows[0].table
+	limit := bw.tables[table].WriteLimit
+	if limit <= 0 {
+		return true
+	}
+	bw.async.lock.Lock()
+	defer bw.async.lock.Unlock()
+	return bw.async.tableWrites[table] < limit
+}
+
 // getBatch returns a slice of data from the front of bw.rows.  The slice
-// returned is the largest one not exceeding countThreshold and byteThreshold.
+// returned is the largest one not exceeding countThreshold and bw.batchBytes,
+// nor the batch size of the table of its rows, if configured.
 func (bw *BatchWriter) getBatch() (rows []*row, count int64, bytes int64) {
 	for i := range bw.rows {
 		c := count + int64(len(bw.rows[i].cols))
@@ -202,7 +287,7 @@ func (bw *BatchWriter) getBatch() (rows []*row, count int64, bytes int64) {
 		// we have at least one row. If a single row puts us over the
 		// thresholds, there's not much we can do: we just try sending it to Spanner
 		// (it might succeed, since our thresholds are conservative).
-		if (c >= countThreshold || b >= byteThreshold) && len(rows) >= 1 {
+		if (c >= countThreshold || b >= bw.batchBytes || bw.endsBatch(rows, bw.rows[i])) && len(rows) >= 1 {
 			bw.rCount -= count
 			bw.rBytes -= bytes
 			bw.rows = bw.rows[i:]
@@ -218,6 +303,22 @@ func (bw *BatchWriter) getBatch() (rows []*row, count int64, bytes int64) {
 	return rows, count, bytes
 }
 
+// endsBatch returns true if r can't be added to batch rows because of the
+// tuning of the tables of their rows.
+func (bw *BatchWriter) endsBatch(rows []*row, r *row) bool {
+	if len(rows) == 0 || len(bw.tables) == 0 {
+		return false
+	}
+	table := rows[0].table
+	_, configured := bw.tables[r.table]
+	if r.table != table {
+		_, ok := bw.tables[table]
+		return ok || configured
+	}
+	n := bw.tables[table].BatchRows
+	return n > 0 && int64(len(rows)) >= n
+}
+
 func (bw *BatchWriter) errorStats(rows []*row, err error, retry bool) {
 	if bw.verbose {
 		fmt.Printf("Error while writing %d rows to Spanner: %v\n", len(rows), err)
@@ -243,20 +344,31 @@ func (bw *BatchWriter) errorStats(rows []*row, err error, retry bool) {
 	}
 	for _, x := range rows {
 		bw.async.droppedRows[x.table]++
+		if bw.droppedRow != nil {
+			bw.droppedRow(x.table, x.cols, x.vals, err)
+		}
 	}
 	return
 }
 
 // Note: doWriteAndHandleErrors must be thread-safe because it is run
-// inside a go routine.
-func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row) {
-	var m []*sp.Mutation
-	for _, x := range rows {
-		m = append(m, sp.Insert(x.table, x.cols, x.vals))
+// inside a go routine. retry is true for the pieces of a failed write.
+func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row, retry bool) {
+	notInserted, err := bw.writeRows(rows, retry)
+	for i := int64(0); i < bw.parentRetries && errors.Is(err, errs.ErrParentRowMissing); i++ {
+		// The parent rows may be in a write that is still in progress.
+		time.Sleep(parentRowRetryDelay)
+		notInserted, err = bw.writeRows(rows, true)
+	}
+	// The rest of the write committed, so rows it didn't insert are dropped
+	// rather than retried.
+	for _, r := range notInserted {
+		bw.errorStats([]*row{r}, errRowNotInserted, false)
 	}
-	if err := bw.write(m); err != nil {
+	if err != nil {
 		hitRetryLimit := atomic.LoadInt64(&bw.async.retries) >= bw.retryLimit
-		retry := len(rows) > 1 && !hitRetryLimit
+		// Retrying the pieces of a cancelled write would only fail again.
+		retry := len(rows) > 1 && !hitRetryLimit && !errors.Is(err, errs.ErrCancelled)
 		bw.errorStats(rows, err, retry)
 		if !retry {
 			if hitRetryLimit && bw.verbose {
@@ -281,23 +393,101 @@ func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row) {
 		}
 		for i := 0; i < len(rows); i += k {
 			atomic.AddInt64(&bw.async.retries, 1)
-			bw.doWriteAndHandleErrors(rows[i:min(i+k, len(rows))])
+			bw.doWriteAndHandleErrors(rows[i:min(i+k, len(rows))], true)
 		}
 	}
 }
 
+// writeRows writes rows to Spanner with mutations, or with DML statements if
+// bw has a writeDML function, and returns the rows that a successful DML
+// write didn't insert, and the error classified by errs.Spanner.
+func (bw *BatchWriter) writeRows(rows []*row, retry bool) ([]*row, error) {
+	start := time.Now()
+	notInserted, err := bw.writeRowsWith(rows)
+	latency := time.Since(start)
+	bw.tuner.observe(latency, err)
+	err = errs.Spanner(err)
+	if bw.observe != nil {
+		var bytes int64
+		for _, r := range rows {
+			bytes += byteSize(r)
+		}
+		bw.observe(WriteResult{Table: rows[0].table, Rows: int64(len(rows) - len(notInserted)), Bytes: bytes, Start: start, Latency: latency, Retry: retry, Err: err})
+	}
+	return notInserted, err
+}
+
+func (bw *BatchWriter) writeRowsWith(rows []*row) ([]*row, error) {
+	if bw.writeDML != nil {
+		var stmts []sp.Statement
+		for _, x := range rows {
+			stmts = append(stmts, insertStatement(x, bw.postgreSQL))
+		}
+		counts, err := bw.writeDML(stmts)
+		if err != nil {
+			return nil, err
+		}
+		// Each statement inserts a single row: those whose count isn't 1,
+		// or that have no count, weren't inserted.
+		var notInserted []*row
+		for i, x := range rows {
+			if i >= len(counts) || counts[i] != 1 {
+				notInserted = append(notInserted, x)
+			}
+		}
+		return notInserted, nil
+	}
+	var m []*sp.Mutation
+	for _, x := range rows {
+		m = append(m, sp.Insert(x.table, x.cols, x.vals))
+	}
+	return nil, bw.write(m)
+}
+
+// insertStatement returns a DML statement that inserts r. Values that are
+// spanner.CommitTimestamp are written as the commit timestamp of the
+// transaction, as they are by mutations.
+func insertStatement(r *row, postgreSQL bool) sp.Statement {
+	quote, param, commitTimestamp := "`", "@p%d", "PENDING_COMMIT_TIMESTAMP()"
+	if postgreSQL {
+		quote, param, commitTimestamp = `"`, "$%d", "SPANNER.PENDING_COMMIT_TIMESTAMP()"
+	}
+	stmt := sp.Statement{Params: make(map[string]interface{})}
+	var cols, vals []string
+	for i, c := range r.cols {
+		cols = append(cols, quote+c+quote)
+		if r.vals[i] == sp.CommitTimestamp {
+			vals = append(vals, commitTimestamp)
+			continue
+		}
+		n := len(stmt.Params) + 1
+		vals = append(vals, fmt.Sprintf(param, n))
+		stmt.Params[fmt.Sprintf("p%d", n)] = r.vals[i]
+	}
+	stmt.SQL = fmt.Sprintf("INSERT INTO %s%s%s (%s) VALUES (%s)", quote, r.table, quote, strings.Join(cols, ", "), strings.Join(vals, ", "))
+	return stmt
+}
+
 // Note: backgroundWrite must be thread-safe because it is run as
 // a go routine.
 func (bw *BatchWriter) backgroundWrite(rows []*row) {
 	defer bw.wg.Done()
 	defer atomic.AddInt64(&bw.async.writes, -1)
-	bw.doWriteAndHandleErrors(rows)
+	defer func() {
+		bw.async.lock.Lock()
+		bw.async.tableWrites[rows[0].table]--
+		bw.async.lock.Unlock()
+	}()
+	bw.doWriteAndHandleErrors(rows, false)
 }
 
 // startWrite initiates an asynchronous write of rows to Spanner.
 func (bw *BatchWriter) startWrite(rows []*row) {
 	bw.wg.Add(1)
 	atomic.AddInt64(&bw.async.writes, 1)
+	bw.async.lock.Lock()
+	bw.async.tableWrites[rows[0].table]++
+	bw.async.lock.Unlock()
 	go bw.backgroundWrite(rows)
 }
 
@@ -306,8 +496,8 @@ func (bw *BatchWriter) startWrite(rows []*row) {
 // b) we've hit writeLimit and we're under bytesLimit.
 // It will block and re-try till either (a) or (b) holds.
 func (bw *BatchWriter) writeData() {
-	for bw.rCount > countThreshold || bw.rBytes > byteThreshold {
-		if atomic.LoadInt64(&bw.async.writes) < bw.writeLimit {
+	for bw.rCount > countThreshold || bw.rBytes > bw.batchBytes {
+		if bw.canStartWrite() {
 			m, count, bytes := bw.getBatch()
 			if bw.verbose {
 				fmt.Printf("Starting write of %d rows to Spanner (%d bytes, %d mutations) [%d in progress]\n",
@@ -325,18 +515,54 @@ func (bw *BatchWriter) writeData() {
 	}
 }
 
+// byteSize returns an estimate of the bytes of r. Variable-length values,
+// e.g. the strings and bytes of wide TEXT and BLOB columns, and arrays are
+// counted by their length, so that batches and the buffer of rows stay
+// within their byte limits regardless of the width of rows.
 func byteSize(r *row) int64 {
 	n := int64(len(r.table))
 	for _, c := range r.cols {
 		n += int64(len(c))
 	}
 	for _, v := range r.vals {
-		switch x := v.(type) {
-		case string:
-			n += int64(len(x))
-		default:
-			n += int64(unsafe.Sizeof(v))
-		}
+		n += valueSize(v)
 	}
 	return n
 }
+
+func valueSize(v interface{}) int64 {
+	switch x := v.(type) {
+	case string:
+		return int64(len(x))
+	case []byte:
+		return int64(len(x))
+	case sp.NullString:
+		return int64(len(x.StringVal))
+	case []string:
+		n := int64(0)
+		for _, s := range x {
+			n += int64(len(s))
+		}
+		return n
+	case []sp.NullString:
+		n := int64(0)
+		for _, s := range x {
+			n += int64(len(s.StringVal))
+		}
+		return n
+	case [][]byte:
+		n := int64(0)
+		for _, b := range x {
+			n += int64(len(b))
+		}
+		return n
+	case []interface{}:
+		n := int64(0)
+		for _, e := range x {
+			n += valueSize(e)
+		}
+		return n
+	default:
+		return int64(unsafe.Sizeof(v))
+	}
+}
@@ -15,12 +15,14 @@
 package writer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -28,6 +30,8 @@ import (
 	"github.com/cloudspannerecosystem/harbourbridge/logger"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func init() {
@@ -123,6 +127,114 @@ func TestSampleBadRows(t *testing.T) {
 	assert.Equal(t, l, []string{"table=test cols=[col1 col2] data=[a 42]"})
 }
 
+func TestDroppedRow(t *testing.T) {
+	var dropped []string
+	bw := NewBatchWriter(BatchWriterConfig{
+		WriteLimit: 1,
+		BytesLimit: 1000,
+		RetryLimit: 0,
+		Write: func(m []*sp.Mutation) error {
+			return fmt.Errorf("write failed")
+		},
+		DroppedRow: func(table string, cols []string, vals []interface{}, err error) {
+			dropped = append(dropped, fmt.Sprintf("%s %v %v %v", table, cols, vals, err))
+		},
+	})
+	bw.AddRow("test", []string{"col1"}, []interface{}{"a"})
+	bw.AddRow("test", []string{"col1"}, []interface{}{"b"})
+	bw.Flush()
+	sort.Strings(dropped)
+	assert.Equal(t, []string{"test [col1] [a] write failed", "test [col1] [b] write failed"}, dropped)
+	assert.Equal(t, int64(2), bw.DroppedRowsByTable()["test"])
+}
+
+func TestParentRowRetries(t *testing.T) {
+	defer func(d time.Duration) { parentRowRetryDelay = d }(parentRowRetryDelay)
+	parentRowRetryDelay = time.Millisecond
+	parentMissing := sp.ToSpannerError(status.Error(codes.NotFound, "Parent row for row [1] in table child is missing. Row cannot be written."))
+	for _, retries := range []int64{0, 2} {
+		var calls int64
+		bw := NewBatchWriter(BatchWriterConfig{
+			WriteLimit:       1,
+			BytesLimit:       1000,
+			RetryLimit:       0,
+			ParentRowRetries: retries,
+			Write: func(m []*sp.Mutation) error {
+				// The parent row is written by the time of the second call.
+				if atomic.AddInt64(&calls, 1) == 1 {
+					return parentMissing
+				}
+				return nil
+			},
+		})
+		bw.AddRow("child", []string{"id"}, []interface{}{int64(1)})
+		bw.Flush()
+		if retries == 0 {
+			assert.Equal(t, int64(1), calls)
+			assert.Equal(t, int64(1), bw.DroppedRowsByTable()["child"])
+		} else {
+			assert.Equal(t, int64(2), calls)
+			assert.Empty(t, bw.DroppedRowsByTable())
+		}
+	}
+}
+
+func TestCancelledWrite(t *testing.T) {
+	var calls int64
+	bw := NewBatchWriter(BatchWriterConfig{
+		WriteLimit: 1,
+		BytesLimit: 1000,
+		RetryLimit: 1000,
+		Write: func(m []*sp.Mutation) error {
+			atomic.AddInt64(&calls, 1)
+			return fmt.Errorf("can't write: %w", context.Canceled)
+		},
+	})
+	for _, v := range []string{"a", "b", "c"} {
+		bw.AddRow("test", []string{"col1"}, []interface{}{v})
+	}
+	bw.Flush()
+	// The batch isn't split and retried.
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+	assert.Equal(t, int64(3), bw.DroppedRowsByTable()["test"])
+}
+
+func TestObserve(t *testing.T) {
+	var lock sync.Mutex
+	var results []WriteResult
+	bw := NewBatchWriter(BatchWriterConfig{
+		WriteLimit: 1,
+		BytesLimit: 1000,
+		RetryLimit: 1000,
+		Write: func(m []*sp.Mutation) error {
+			if len(m) > 1 {
+				return status.Error(codes.Aborted, "aborted")
+			}
+			return nil
+		},
+		Observe: func(r WriteResult) {
+			lock.Lock()
+			defer lock.Unlock()
+			results = append(results, r)
+		},
+	})
+	bw.AddRow("test", []string{"col1"}, []interface{}{"a"})
+	bw.AddRow("test", []string{"col1"}, []interface{}{"b"})
+	bw.Flush()
+	// The batch of both rows is aborted, then each row is retried on its own.
+	assert.Equal(t, 3, len(results))
+	assert.Equal(t, int64(2), results[0].Rows)
+	assert.Equal(t, codes.Aborted, sp.ErrCode(results[0].Err))
+	assert.False(t, results[0].Retry)
+	for _, r := range results[1:] {
+		assert.Equal(t, "test", r.Table)
+		assert.Equal(t, int64(1), r.Rows)
+		assert.Equal(t, byteSize(&row{"test", []string{"col1"}, []interface{}{"a"}}), r.Bytes)
+		assert.True(t, r.Retry)
+		assert.Nil(t, r.Err)
+	}
+}
+
 func TestErrors(t *testing.T) {
 	bw := NewBatchWriter(BatchWriterConfig{})
 	bw.async.lock.Lock()
@@ -238,3 +350,188 @@ func partitionRows(badRowIndex map[int]bool, data []*row) (goodRows []*row, badR
 	}
 	return goodRows, badRows
 }
+
+func TestFlush_DML(t *testing.T) {
+	var stmts []sp.Statement
+	var calls int
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		WriteLimit: 1,
+		RetryLimit: 1000,
+		Write: func(m []*sp.Mutation) error {
+			t.Fatal("unexpected mutation write")
+			return nil
+		},
+		WriteDML: func(s []sp.Statement) ([]int64, error) {
+			calls++
+			for _, x := range s {
+				if x.Params["p1"] == "bad" {
+					return nil, errors.New("bad data")
+				}
+			}
+			stmts = append(stmts, s...)
+			counts := make([]int64, len(s))
+			for i := range counts {
+				counts[i] = 1
+			}
+			return counts, nil
+		},
+	})
+	bw.AddRow("t1", []string{"a", "b"}, []interface{}{"x", int64(1)})
+	bw.AddRow("t1", []string{"a", "b"}, []interface{}{"bad", int64(2)})
+	bw.AddRow("t2", []string{"c", "ts"}, []interface{}{"y", sp.CommitTimestamp})
+	bw.Flush()
+	assert.Equal(t, []sp.Statement{
+		{SQL: "INSERT INTO `t1` (`a`, `b`) VALUES (@p1, @p2)", Params: map[string]interface{}{"p1": "x", "p2": int64(1)}},
+		{SQL: "INSERT INTO `t2` (`c`, `ts`) VALUES (@p1, PENDING_COMMIT_TIMESTAMP())", Params: map[string]interface{}{"p1": "y"}},
+	}, stmts)
+	// The failed batch is split to isolate the bad row.
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, map[string]int64{"t1": 1}, bw.DroppedRowsByTable())
+}
+
+func TestFlush_DMLRowCounts(t *testing.T) {
+	var calls int
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		WriteLimit: 1,
+		RetryLimit: 1000,
+		WriteDML: func(s []sp.Statement) ([]int64, error) {
+			calls++
+			// The second statement inserts no row, and the last one has no
+			// count.
+			return []int64{1, 0, 1}, nil
+		},
+	})
+	for i := int64(1); i <= 4; i++ {
+		bw.AddRow("t1", []string{"a"}, []interface{}{i})
+	}
+	bw.Flush()
+	// Rows that weren't inserted are dropped, and the batch isn't retried.
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, map[string]int64{"t1": 2}, bw.DroppedRowsByTable())
+	assert.Equal(t, map[string]int64{errRowNotInserted.Error(): 2}, bw.Errors())
+	assert.Equal(t, 2, len(bw.SampleBadRows(10)))
+}
+
+func TestInsertStatement_PostgreSQL(t *testing.T) {
+	stmt := insertStatement(&row{"t", []string{"a", "ts", "b"}, []interface{}{"x", sp.CommitTimestamp, int64(1)}}, true)
+	assert.Equal(t, sp.Statement{
+		SQL:    `INSERT INTO "t" ("a", "ts", "b") VALUES ($1, SPANNER.PENDING_COMMIT_TIMESTAMP(), $2)`,
+		Params: map[string]interface{}{"p1": "x", "p2": int64(1)},
+	}, stmt)
+}
+
+func TestFlush_TableConfig(t *testing.T) {
+	var mutex sync.Mutex
+	batches := make(map[string][]int)
+	var t1InProgress, maxT1InProgress int
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		WriteLimit: 40,
+		RetryLimit: 1000,
+		Tables:     map[string]TableConfig{"t1": {WriteLimit: 1, BatchRows: 3}},
+		// Statements tell the table of their rows apart, unlike mutations.
+		WriteDML: func(stmts []sp.Statement) ([]int64, error) {
+			table := strings.Fields(stmts[0].SQL)[2]
+			mutex.Lock()
+			for _, stmt := range stmts {
+				assert.Equal(t, table, strings.Fields(stmt.SQL)[2])
+			}
+			batches[table] = append(batches[table], len(stmts))
+			if table == "`t1`" {
+				t1InProgress++
+				if t1InProgress > maxT1InProgress {
+					maxT1InProgress = t1InProgress
+				}
+			}
+			mutex.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mutex.Lock()
+			if table == "`t1`" {
+				t1InProgress--
+			}
+			mutex.Unlock()
+			return nil, nil
+		},
+	})
+	for i := 0; i < 7; i++ {
+		bw.AddRow("t1", []string{"a"}, []interface{}{int64(i)})
+	}
+	bw.AddRow("t2", []string{"a"}, []interface{}{int64(7)})
+	bw.AddRow("t2", []string{"a"}, []interface{}{int64(8)})
+	bw.Flush()
+	assert.Equal(t, map[string][]int{"`t1`": {3, 3, 1}, "`t2`": {2}}, batches)
+	// Writes of t1 rows are serialized.
+	assert.Equal(t, 1, maxT1InProgress)
+}
+
+func TestFlush_BatchBytes(t *testing.T) {
+	var mutex sync.Mutex
+	var batches []int
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit: 2500,
+		BatchBytes: 2500,
+		WriteLimit: 2,
+		RetryLimit: 1000,
+		Write: func(m []*sp.Mutation) error {
+			mutex.Lock()
+			batches = append(batches, len(m))
+			mutex.Unlock()
+			return nil
+		},
+	})
+	blob := make([]byte, 1000)
+	for i := 0; i < 5; i++ {
+		bw.AddRow("t", []string{"a", "b"}, []interface{}{int64(i), blob})
+		// Buffered rows never take much more than a batch.
+		assert.LessOrEqual(t, bw.rBytes, int64(2*2500))
+	}
+	// A row wider than the limit is written on its own.
+	bw.AddRow("t", []string{"a", "b"}, []interface{}{int64(5), make([]byte, 5000)})
+	bw.Flush()
+	sort.Ints(batches)
+	assert.Equal(t, []int{1, 1, 2, 2}, batches)
+}
+
+func TestByteSize(t *testing.T) {
+	r := &row{"t", []string{"a", "b", "c", "d"}, []interface{}{
+		"xyz",
+		[]byte("0123456789"),
+		[]sp.NullString{{StringVal: "ab", Valid: true}, {}},
+		[][]byte{[]byte("abcd"), nil},
+	}}
+	assert.Equal(t, int64(1+4+3+10+2+4), byteSize(r))
+}
+
+func TestAutoTuner(t *testing.T) {
+	var tuner *autoTuner
+	assert.Equal(t, int64(40), tuner.writeLimit(40))
+	tuner.observe(time.Second, nil)
+
+	tuner = newAutoTuner(6)
+	assert.Equal(t, int64(1), tuner.writeLimit(6))
+	window := func(latency time.Duration, err error) {
+		for i := 0; i < tuneWindow; i++ {
+			tuner.observe(latency, err)
+		}
+	}
+	// Fast writes increase the limit, up to the configured one.
+	for i := 0; i < 10; i++ {
+		window(100*time.Millisecond, nil)
+	}
+	assert.Equal(t, int64(6), tuner.writeLimit(6))
+	// Slow writes decrease it.
+	window(300*time.Millisecond, nil)
+	assert.Equal(t, int64(4), tuner.writeLimit(6))
+	window(120*time.Millisecond, nil)
+	assert.Equal(t, int64(5), tuner.writeLimit(6))
+	// So do aborted writes.
+	aborted := status.Error(codes.Aborted, "transaction aborted")
+	window(100*time.Millisecond, aborted)
+	assert.Equal(t, int64(3), tuner.writeLimit(6))
+	for i := 0; i < 5; i++ {
+		window(100*time.Millisecond, aborted)
+	}
+	assert.Equal(t, int64(1), tuner.writeLimit(6))
+}
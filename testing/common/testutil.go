@@ -16,9 +16,13 @@ package common
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"testing"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
 )
 
 func RunCommand(args string, projectID string) error {
@@ -58,3 +62,21 @@ func RestoreEnvVariables(params map[string]string) {
 		os.Setenv(k, v)
 	}
 }
+
+// OnlyRunForEmulatorTest skips t unless SPANNER_EMULATOR_HOST is set.
+func OnlyRunForEmulatorTest(t *testing.T) {
+	if !utils.UsingEmulator() {
+		t.Skip("Skipping tests only running against the emulator.")
+	}
+}
+
+// PrepareEmulatorInstance creates instanceID in projectID on the emulator
+// (if it doesn't already exist) and returns the URI of database dbName in it.
+// Use it to rehearse a migration with -target=emulator.
+func PrepareEmulatorInstance(t *testing.T, projectID, instanceID, dbName string) string {
+	OnlyRunForEmulatorTest(t)
+	if err := utils.CreateEmulatorInstance(context.Background(), projectID, instanceID); err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("projects/%s/instances/%s/databases/%s", projectID, instanceID, dbName)
+}
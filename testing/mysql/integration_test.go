@@ -323,6 +323,26 @@ func TestIntegration_MySQLDUMP_SchemaAndDataSubcommand(t *testing.T) {
 	checkResults(t, dbURI, false)
 }
 
+func TestIntegration_MySQLDUMP_EmulatorTarget(t *testing.T) {
+	onlyRunForEmulatorTest(t)
+	tmpdir := prepareIntegrationTest(t)
+	defer os.RemoveAll(tmpdir)
+
+	// The emulator target creates the instance if it doesn't exist.
+	rehearsalInstance := instanceID + "-rehearsal"
+	dbName := "test-emulator-target"
+	dumpFilePath := "../../test_data/mysqldump.test.out"
+	filePrefix := filepath.Join(tmpdir, dbName+".")
+
+	args := fmt.Sprintf("schema-and-data -source=mysql -target=emulator -prefix %s -target-profile='instance=%s,dbName=%s' < %s", filePrefix, rehearsalInstance, dbName, dumpFilePath)
+	if err := common.RunCommand(args, projectID); err != nil {
+		t.Fatal(err)
+	}
+	dbURI := common.PrepareEmulatorInstance(t, projectID, rehearsalInstance, dbName)
+	defer dropDatabase(t, dbURI)
+	checkResults(t, dbURI, false)
+}
+
 func checkResults(t *testing.T, dbURI string, skipJson bool) {
 	// Make a query to check results.
 	client, err := spanner.NewClient(ctx, dbURI)
@@ -9,13 +9,17 @@ export enum StorageKeys {
   Type = 'inputType',
   Config = 'config',
   SourceDbName = 'sourceDbName',
+  // Google ID token sent to the backend when it authenticates users with OIDC.
+  IdToken = 'idToken',
 }
 
 export enum SourceDbNames {
   MySQL = 'MySQL',
+  MariaDB = 'MariaDB',
   Postgres = 'Postgres',
   SQLServer = 'SQL Server',
   Oracle = 'Oracle',
+  Spanner = 'Spanner',
 }
 
 export enum ObjectExplorerNodeType {
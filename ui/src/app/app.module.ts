@@ -32,6 +32,13 @@ import { SidenavViewAssessmentComponent } from './components/sidenav-view-assess
 import { SidenavSaveSessionComponent } from './components/sidenav-save-session/sidenav-save-session.component'
 import { DropIndexDialogComponent } from './components/drop-index-dialog/drop-index-dialog.component'
 import { DatabaseLoaderComponent } from './components/database-loader/database-loader.component'
+import { ErDiagramComponent } from './components/er-diagram/er-diagram.component'
+import { SchemaSearchComponent } from './components/schema-search/schema-search.component'
+import { ReviewQueueComponent } from './components/review-queue/review-queue.component'
+import { DdlEditorComponent } from './components/ddl-editor/ddl-editor.component'
+import { DataPreviewComponent } from './components/data-preview/data-preview.component'
+import { MigrateComponent } from './components/migrate/migrate.component'
+import { JobLogComponent } from './components/job-log/job-log.component'
 
 @NgModule({
   declarations: [
@@ -60,6 +67,13 @@ import { DatabaseLoaderComponent } from './components/database-loader/database-l
     SidenavSaveSessionComponent,
     DropIndexDialogComponent,
     DatabaseLoaderComponent,
+    ErDiagramComponent,
+    SchemaSearchComponent,
+    ReviewQueueComponent,
+    DdlEditorComponent,
+    DataPreviewComponent,
+    MigrateComponent,
+    JobLogComponent,
   ],
   imports: [
     BrowserModule,
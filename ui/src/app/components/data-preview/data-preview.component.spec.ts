@@ -0,0 +1,41 @@
+import { ComponentFixture, TestBed } from '@angular/core/testing'
+import { HttpClientModule } from '@angular/common/http'
+import { MatSnackBar } from '@angular/material/snack-bar'
+
+import { DataPreviewComponent } from './data-preview.component'
+
+describe('DataPreviewComponent', () => {
+  let component: DataPreviewComponent
+  let fixture: ComponentFixture<DataPreviewComponent>
+
+  beforeEach(async () => {
+    await TestBed.configureTestingModule({
+      declarations: [DataPreviewComponent],
+      providers: [MatSnackBar],
+      imports: [HttpClientModule],
+    }).compileComponents()
+  })
+
+  beforeEach(() => {
+    fixture = TestBed.createComponent(DataPreviewComponent)
+    component = fixture.componentInstance
+    fixture.detectChanges()
+  })
+
+  it('should create', () => {
+    expect(component).toBeTruthy()
+  })
+
+  it('should count rows that can not be converted', () => {
+    component.preview = {
+      Columns: [{ SrcCol: 'id', SrcType: 'bigint', SpCol: 'id', SpType: 'INT64' }],
+      Rows: [
+        { SrcVals: ['1'], SpVals: ['1'], Error: '' },
+        { SrcVals: ['x'], SpVals: [], Error: "can't convert to int64" },
+      ],
+    }
+    expect(component.errorCount()).toEqual(1)
+    expect(component.isChanged('1.5', '1.500000000')).toBeTrue()
+    expect(component.isChanged('1', '1')).toBeFalse()
+  })
+})
@@ -0,0 +1,52 @@
+import { Component, Input, OnChanges } from '@angular/core'
+import IDataPreview from 'src/app/model/data-preview'
+import { FetchService } from 'src/app/services/fetch/fetch.service'
+import { SnackbarService } from 'src/app/services/snackbar/snackbar.service'
+
+@Component({
+  selector: 'app-data-preview',
+  templateUrl: './data-preview.component.html',
+  styleUrls: ['./data-preview.component.scss'],
+})
+export class DataPreviewComponent implements OnChanges {
+  // Spanner table to preview.
+  @Input() table: string = ''
+  rows: number = 10
+  rowChoices: number[] = [10, 25, 50, 100]
+  preview: IDataPreview | null = null
+  loading: boolean = false
+
+  constructor(private fetch: FetchService, private snackbar: SnackbarService) {}
+
+  ngOnChanges(): void {
+    this.preview = null
+    this.load()
+  }
+
+  load() {
+    if (this.table === '') {
+      return
+    }
+    this.loading = true
+    this.fetch.getDataPreview(this.table, this.rows).subscribe({
+      next: (preview: IDataPreview) => {
+        this.preview = preview
+        this.loading = false
+      },
+      error: (err: any) => {
+        this.loading = false
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+
+  // isChanged is true if conversion changed how a value is written, e.g.
+  // a rounded decimal or a timestamp converted to UTC.
+  isChanged(srcVal: string, spVal: string): boolean {
+    return spVal !== undefined && srcVal !== spVal
+  }
+
+  errorCount(): number {
+    return this.preview ? this.preview.Rows.filter((r) => r.Error !== '').length : 0
+  }
+}
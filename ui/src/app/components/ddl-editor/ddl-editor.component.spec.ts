@@ -0,0 +1,41 @@
+import { ComponentFixture, TestBed } from '@angular/core/testing'
+import { HttpClientModule } from '@angular/common/http'
+import { MatSnackBar } from '@angular/material/snack-bar'
+
+import { DdlEditorComponent } from './ddl-editor.component'
+
+describe('DdlEditorComponent', () => {
+  let component: DdlEditorComponent
+  let fixture: ComponentFixture<DdlEditorComponent>
+
+  beforeEach(async () => {
+    await TestBed.configureTestingModule({
+      declarations: [DdlEditorComponent],
+      providers: [MatSnackBar],
+      imports: [HttpClientModule],
+    }).compileComponents()
+  })
+
+  beforeEach(() => {
+    fixture = TestBed.createComponent(DdlEditorComponent)
+    component = fixture.componentInstance
+    fixture.detectChanges()
+  })
+
+  it('should create', () => {
+    expect(component).toBeTruthy()
+  })
+
+  it('should detect changed DDL', () => {
+    const table = {
+      Table: 'users',
+      Generated: ['CREATE TABLE users (id INT64) PRIMARY KEY (id)'],
+      Edited: null,
+      Stale: false,
+    }
+    component.text['users'] = component.toText(table.Generated)
+    expect(component.isChanged(table)).toBeFalse()
+    component.text['users'] = 'CREATE TABLE users (id STRING(36)) PRIMARY KEY (id);'
+    expect(component.isChanged(table)).toBeTrue()
+  })
+})
@@ -0,0 +1,89 @@
+import { Component, OnInit } from '@angular/core'
+import IDdlPreview, { IDdlValidation, ITableDdl } from 'src/app/model/ddl-preview'
+import { DataService } from 'src/app/services/data/data.service'
+import { FetchService } from 'src/app/services/fetch/fetch.service'
+import { SnackbarService } from 'src/app/services/snackbar/snackbar.service'
+
+@Component({
+  selector: 'app-ddl-editor',
+  templateUrl: './ddl-editor.component.html',
+  styleUrls: ['./ddl-editor.component.scss'],
+})
+export class DdlEditorComponent implements OnInit {
+  preview: IDdlPreview = { Tables: [], ForeignKeys: [] }
+  // Text being edited, keyed by table name.
+  text: Record<string, string> = {}
+  validation: IDdlValidation | null = null
+  validating: boolean = false
+
+  constructor(
+    private data: DataService,
+    private fetch: FetchService,
+    private snackbar: SnackbarService
+  ) {}
+
+  ngOnInit(): void {
+    this.load()
+  }
+
+  load() {
+    this.fetch.getDdlPreview().subscribe({
+      next: (preview: IDdlPreview) => {
+        this.preview = preview
+        this.text = {}
+        preview.Tables.forEach((t) => (this.text[t.Table] = this.toText(this.statements(t))))
+      },
+      error: (err: any) => {
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+
+  // statements returns the statements applied for a table.
+  statements(table: ITableDdl): string[] {
+    return table.Edited ? table.Edited : table.Generated
+  }
+
+  toText(stmts: string[]): string {
+    return stmts.map((s) => s + ';').join('\n\n')
+  }
+
+  isChanged(table: ITableDdl): boolean {
+    return this.text[table.Table] !== this.toText(this.statements(table))
+  }
+
+  save(table: ITableDdl) {
+    this.edit(table.Table, this.text[table.Table])
+  }
+
+  revert(table: ITableDdl) {
+    this.edit(table.Table, '')
+  }
+
+  edit(table: string, text: string) {
+    this.data.editDdl(table, text).subscribe({
+      next: (err: string) => {
+        if (err === '') {
+          this.validation = null
+          this.load()
+        } else {
+          this.snackbar.openSnackBar(err, 'Close')
+        }
+      },
+    })
+  }
+
+  validate() {
+    this.validating = true
+    this.fetch.validateDdl().subscribe({
+      next: (res: IDdlValidation) => {
+        this.validation = res
+        this.validating = false
+      },
+      error: (err: any) => {
+        this.validating = false
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+}
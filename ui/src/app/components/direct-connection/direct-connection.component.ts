@@ -23,10 +23,16 @@ export class DirectConnectionComponent implements OnInit {
     userName: new FormControl('', [Validators.required]),
     password: new FormControl(''),
     dbName: new FormControl('', [Validators.required]),
+    sshHost: new FormControl(''),
+    sshUser: new FormControl(''),
+    sshPassword: new FormControl(''),
+    sshKeyFile: new FormControl(''),
+    proxy: new FormControl(''),
   })
 
   dbEngineList = [
     { value: 'mysql', displayName: 'MYSQL' },
+    { value: 'mariadb', displayName: 'MariaDB' },
     { value: 'sqlserver', displayName: 'SQL Server' },
     { value: 'oracle', displayName: 'ORACLE' },
     { value: 'postgres', displayName: 'PostgreSQL' },
@@ -48,7 +54,20 @@ export class DirectConnectionComponent implements OnInit {
     window.scroll(0, 0)
     this.data.resetStore()
     const { dbEngine, hostName, port, userName, password, dbName } = this.connectForm.value
-    const config: IDbConfig = { dbEngine, hostName, port, userName, password, dbName }
+    const { sshHost, sshUser, sshPassword, sshKeyFile, proxy } = this.connectForm.value
+    const config: IDbConfig = {
+      dbEngine,
+      hostName,
+      port,
+      userName,
+      password,
+      dbName,
+      sshHost,
+      sshUser,
+      sshPassword,
+      sshKeyFile,
+      proxy,
+    }
     this.fetch.connectTodb(config).subscribe({
       next: () => {
         this.data.getSchemaConversionFromDb()
@@ -1,7 +1,9 @@
 import { Component, Input, OnInit, Output, EventEmitter } from '@angular/core'
 import { FormBuilder, FormGroup, Validators } from '@angular/forms'
 import { DataService } from 'src/app/services/data/data.service'
+import { FetchService } from 'src/app/services/fetch/fetch.service'
 import { SidenavService } from 'src/app/services/sidenav/sidenav.service'
+import ITypeMapPreset from 'src/app/model/typemap-preset'
 
 interface IConvSourceType {
   T: string
@@ -20,7 +22,14 @@ export class EditGlobalDatatypeFormComponent implements OnInit {
   conversionType: Record<string, IConvSourceType[]> = {}
   sourceType: string[] = []
   destinationType: string[] = []
-  constructor(private fb: FormBuilder, private data: DataService, private sidenav: SidenavService) {
+  presets: ITypeMapPreset[] = []
+  selectedPreset: string = ''
+  constructor(
+    private fb: FormBuilder,
+    private data: DataService,
+    private fetch: FetchService,
+    private sidenav: SidenavService
+  ) {
     this.addGlobalDataTypeForm = this.fb.group({
       objectType: ['column', Validators.required],
       table: ['allTable', Validators.required],
@@ -37,6 +46,17 @@ export class EditGlobalDatatypeFormComponent implements OnInit {
         this.sourceType = Object.keys(this.conversionType)
       },
     })
+    this.fetch.getTypeMapPresets().subscribe({
+      next: (res: ITypeMapPreset[]) => {
+        this.presets = res
+      },
+    })
+  }
+
+  applyPreset(): void {
+    this.data.applyTypeMapPreset(this.selectedPreset)
+    this.resetRuleType.emit('')
+    this.sidenav.closeSidenav()
   }
   formSubmit(): void {
     const ruleValue = this.addGlobalDataTypeForm.value
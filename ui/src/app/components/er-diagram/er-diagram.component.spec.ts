@@ -0,0 +1,48 @@
+import { ComponentFixture, TestBed } from '@angular/core/testing'
+import { HttpClientModule } from '@angular/common/http'
+
+import { ErDiagramComponent } from './er-diagram.component'
+
+describe('ErDiagramComponent', () => {
+  let component: ErDiagramComponent
+  let fixture: ComponentFixture<ErDiagramComponent>
+
+  beforeEach(async () => {
+    await TestBed.configureTestingModule({
+      declarations: [ErDiagramComponent],
+      imports: [HttpClientModule],
+    }).compileComponents()
+  })
+
+  beforeEach(() => {
+    fixture = TestBed.createComponent(ErDiagramComponent)
+    component = fixture.componentInstance
+    fixture.detectChanges()
+  })
+
+  it('should create', () => {
+    expect(component).toBeTruthy()
+  })
+
+  it('should place tables in columns by level', () => {
+    component.layout({
+      Nodes: [
+        { Table: 'users', SrcTable: 'users', Columns: 1, Issues: 0, Level: 0 },
+        { Table: 'products', SrcTable: 'products', Columns: 1, Issues: 0, Level: 0 },
+        { Table: 'orders', SrcTable: 'orders', Columns: 2, Issues: 1, Level: 1 },
+      ],
+      Edges: [
+        { From: 'orders', To: 'users', Kind: 'foreignKey', Name: 'fk_users' },
+        { From: 'orders', To: 'gone', Kind: 'foreignKey', Name: 'fk_gone' },
+      ],
+    })
+    expect(component.nodes.map((n) => [n.Table, n.x, n.y])).toEqual([
+      ['users', 0, 0],
+      ['products', 0, 60],
+      ['orders', 280, 0],
+    ])
+    expect(component.edges.length).toEqual(1)
+    expect(component.width).toEqual(560)
+    expect(component.height).toEqual(120)
+  })
+})
@@ -0,0 +1,99 @@
+import { Component, EventEmitter, OnDestroy, OnInit, Output } from '@angular/core'
+import { Subscription } from 'rxjs/internal/Subscription'
+import ISchemaGraph, { ISchemaGraphEdge, ISchemaGraphNode } from 'src/app/model/schema-graph'
+import { DataService } from 'src/app/services/data/data.service'
+import { FetchService } from 'src/app/services/fetch/fetch.service'
+
+export interface IDiagramNode extends ISchemaGraphNode {
+  x: number
+  y: number
+}
+
+export interface IDiagramEdge extends ISchemaGraphEdge {
+  path: string
+}
+
+@Component({
+  selector: 'app-er-diagram',
+  templateUrl: './er-diagram.component.html',
+  styleUrls: ['./er-diagram.component.scss'],
+})
+export class ErDiagramComponent implements OnInit, OnDestroy {
+  @Output() selectTable = new EventEmitter<string>()
+  readonly nodeWidth: number = 180
+  readonly nodeHeight: number = 44
+  readonly columnGap: number = 100
+  readonly rowGap: number = 16
+  nodes: IDiagramNode[] = []
+  edges: IDiagramEdge[] = []
+  width: number = 0
+  height: number = 0
+  zoom: number = 1
+  highlighted: string = ''
+  convObj!: Subscription
+
+  constructor(private data: DataService, private fetch: FetchService) {}
+
+  ngOnInit(): void {
+    // The graph is fetched again whenever the schema changes.
+    this.convObj = this.data.conv.subscribe(() => {
+      this.fetch.getSchemaGraph().subscribe({
+        next: (graph: ISchemaGraph) => this.layout(graph),
+      })
+    })
+  }
+
+  ngOnDestroy(): void {
+    this.convObj.unsubscribe()
+  }
+
+  // layout places tables in columns by load order level, so that edges go
+  // from right to left, from child tables to the tables they depend on.
+  layout(graph: ISchemaGraph) {
+    const rows: number[] = []
+    const byTable: Record<string, IDiagramNode> = {}
+    this.nodes = graph.Nodes.map((node: ISchemaGraphNode) => {
+      const row = rows[node.Level] || 0
+      rows[node.Level] = row + 1
+      const n = {
+        ...node,
+        x: node.Level * (this.nodeWidth + this.columnGap),
+        y: row * (this.nodeHeight + this.rowGap),
+      }
+      byTable[node.Table] = n
+      return n
+    })
+    this.edges = graph.Edges.filter((edge) => byTable[edge.From] && byTable[edge.To]).map(
+      (edge: ISchemaGraphEdge) => {
+        return { ...edge, path: this.edgePath(byTable[edge.From], byTable[edge.To]) }
+      }
+    )
+    this.width = rows.length * (this.nodeWidth + this.columnGap)
+    this.height = Math.max(...rows, 0) * (this.nodeHeight + this.rowGap)
+  }
+
+  edgePath(from: IDiagramNode, to: IDiagramNode): string {
+    const x1 = from.x
+    const y1 = from.y + this.nodeHeight / 2
+    const x2 = to.x + this.nodeWidth
+    const y2 = to.y + this.nodeHeight / 2
+    const mid = (x1 + x2) / 2
+    return `M ${x1} ${y1} C ${mid} ${y1}, ${mid} ${y2}, ${x2} ${y2}`
+  }
+
+  isConnected(edge: IDiagramEdge): boolean {
+    return edge.From === this.highlighted || edge.To === this.highlighted
+  }
+
+  zoomIn() {
+    this.zoom = Math.min(this.zoom * 1.25, 4)
+  }
+
+  zoomOut() {
+    this.zoom = Math.max(this.zoom / 1.25, 0.1)
+  }
+
+  openTable(table: string) {
+    this.selectTable.emit(table)
+  }
+}
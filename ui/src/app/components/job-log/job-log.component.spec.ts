@@ -0,0 +1,35 @@
+import { ComponentFixture, TestBed } from '@angular/core/testing'
+import { HttpClientModule } from '@angular/common/http'
+import { MatSnackBar } from '@angular/material/snack-bar'
+
+import { JobLogComponent } from './job-log.component'
+
+describe('JobLogComponent', () => {
+  let component: JobLogComponent
+  let fixture: ComponentFixture<JobLogComponent>
+
+  beforeEach(async () => {
+    await TestBed.configureTestingModule({
+      declarations: [JobLogComponent],
+      providers: [MatSnackBar],
+      imports: [HttpClientModule],
+    }).compileComponents()
+  })
+
+  beforeEach(() => {
+    fixture = TestBed.createComponent(JobLogComponent)
+    component = fixture.componentInstance
+    fixture.detectChanges()
+  })
+
+  it('should create', () => {
+    expect(component).toBeTruthy()
+  })
+
+  it('should not stream without a job', () => {
+    component.jobId = ''
+    component.ngOnChanges()
+    expect(component.source).toBeNull()
+    expect(component.lines).toEqual([])
+  })
+})
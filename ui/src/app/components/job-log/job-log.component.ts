@@ -0,0 +1,63 @@
+import { Component, Input, NgZone, OnChanges, OnDestroy } from '@angular/core'
+import { FetchService } from 'src/app/services/fetch/fetch.service'
+import { SnackbarService } from 'src/app/services/snackbar/snackbar.service'
+
+@Component({
+  selector: 'app-job-log',
+  templateUrl: './job-log.component.html',
+  styleUrls: ['./job-log.component.scss'],
+})
+export class JobLogComponent implements OnChanges, OnDestroy {
+  // Background job whose log is streamed.
+  @Input() jobId: string = ''
+  lines: string[] = []
+  // state is the state of the job once it has ended, empty while it runs.
+  state: string = ''
+  source: EventSource | null = null
+
+  constructor(
+    private fetch: FetchService,
+    private snackbar: SnackbarService,
+    private zone: NgZone
+  ) {}
+
+  ngOnChanges(): void {
+    this.close()
+    this.lines = []
+    this.state = ''
+    if (this.jobId === '') {
+      return
+    }
+    this.source = this.fetch.jobLogs(this.jobId)
+    // EventSource callbacks run outside of Angular.
+    this.source.onmessage = (e: MessageEvent) => {
+      this.zone.run(() => this.lines.push(e.data))
+    }
+    this.source.addEventListener('end', (e: Event) => {
+      this.zone.run(() => (this.state = (e as MessageEvent).data))
+      this.close()
+    })
+    this.source.onerror = () => {
+      this.close()
+    }
+  }
+
+  ngOnDestroy(): void {
+    this.close()
+  }
+
+  close() {
+    if (this.source !== null) {
+      this.source.close()
+      this.source = null
+    }
+  }
+
+  cancel() {
+    this.fetch.cancelJob(this.jobId).subscribe({
+      error: (err: any) => {
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+}
@@ -24,7 +24,9 @@ export class LoadDumpComponent implements OnInit {
   })
   dbEngineList = [
     { value: 'mysqldump', displayName: 'MYSQL' },
+    { value: 'mariadbdump', displayName: 'MariaDB' },
     { value: 'pg_dump', displayName: 'PostgreSQL' },
+    { value: 'spanner', displayName: 'Spanner DDL' },
   ]
 
   ngOnInit(): void {}
@@ -26,6 +26,7 @@ export class LoadSessionComponent implements OnInit {
 
   dbEngineList = [
     { value: 'mysql', displayName: 'MYSQL' },
+    { value: 'mariadb', displayName: 'MariaDB' },
     { value: 'sqlserver', displayName: 'SQL Server' },
     { value: 'oracle', displayName: 'ORACLE' },
     { value: 'postgres', displayName: 'PostgreSQL' },
@@ -50,4 +51,19 @@ export class LoadSessionComponent implements OnInit {
       this.router.navigate(['/workspace'])
     })
   }
+
+  importSessionArchive(event: Event) {
+    const files = (event.target as HTMLInputElement).files
+    if (!files || files.length === 0) {
+      return
+    }
+    this.clickEvent.openDatabaseLoader('session', '')
+    this.data.resetStore()
+    this.data.importSession(files[0])
+    this.data.conv.subscribe((res) => {
+      localStorage.setItem(StorageKeys.SourceDbName, extractSourceDbName(res.DatabaseType))
+      this.clickEvent.closeDatabaseLoader()
+      this.router.navigate(['/workspace'])
+    })
+  }
 }
@@ -0,0 +1,46 @@
+import { ComponentFixture, TestBed } from '@angular/core/testing'
+import { HttpClientModule } from '@angular/common/http'
+import { MatSnackBar } from '@angular/material/snack-bar'
+
+import { MigrateComponent } from './migrate.component'
+
+describe('MigrateComponent', () => {
+  let component: MigrateComponent
+  let fixture: ComponentFixture<MigrateComponent>
+
+  beforeEach(async () => {
+    await TestBed.configureTestingModule({
+      declarations: [MigrateComponent],
+      providers: [MatSnackBar],
+      imports: [HttpClientModule],
+    }).compileComponents()
+  })
+
+  beforeEach(() => {
+    fixture = TestBed.createComponent(MigrateComponent)
+    component = fixture.componentInstance
+    fixture.detectChanges()
+  })
+
+  it('should create', () => {
+    expect(component).toBeTruthy()
+  })
+
+  it('should report table progress', () => {
+    component.status = {
+      State: 'running',
+      JobId: '',
+      Database: 'projects/p/instances/i/databases/d',
+      Mode: 'schema-and-data',
+      Error: '',
+      Tables: [
+        { Table: 'users', State: 'done', Rows: 0, GoodRows: 0, BadRows: 0, Error: '' },
+        { Table: 'orders', State: 'running', Rows: 10, GoodRows: 4, BadRows: 1, Error: '' },
+      ],
+    }
+    expect(component.isRunning()).toBeTrue()
+    expect(component.countTables('done')).toEqual(1)
+    expect(component.percentDone(component.status.Tables[0])).toEqual(100)
+    expect(component.percentDone(component.status.Tables[1])).toEqual(50)
+  })
+})
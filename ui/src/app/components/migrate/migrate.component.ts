@@ -0,0 +1,87 @@
+import { Component, OnDestroy, OnInit } from '@angular/core'
+import { interval, Subscription } from 'rxjs'
+import IMigrationStatus, { ITableStatus } from 'src/app/model/migration'
+import { FetchService } from 'src/app/services/fetch/fetch.service'
+import { SnackbarService } from 'src/app/services/snackbar/snackbar.service'
+
+@Component({
+  selector: 'app-migrate',
+  templateUrl: './migrate.component.html',
+  styleUrls: ['./migrate.component.scss'],
+})
+export class MigrateComponent implements OnInit, OnDestroy {
+  database: string = ''
+  mode: string = 'schema'
+  status: IMigrationStatus | null = null
+  // poll refreshes the status while a migration is running.
+  poll: Subscription | null = null
+
+  constructor(private fetch: FetchService, private snackbar: SnackbarService) {}
+
+  ngOnInit(): void {
+    this.refresh()
+  }
+
+  ngOnDestroy(): void {
+    this.stopPolling()
+  }
+
+  start() {
+    this.fetch.migrate({ Database: this.database, Mode: this.mode }).subscribe({
+      next: (status: IMigrationStatus) => {
+        this.setStatus(status)
+      },
+      error: (err: any) => {
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+
+  refresh() {
+    this.fetch.getMigrationStatus().subscribe({
+      next: (status: IMigrationStatus) => {
+        this.setStatus(status)
+      },
+      error: (err: any) => {
+        this.stopPolling()
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+
+  setStatus(status: IMigrationStatus) {
+    this.status = status
+    if (status.State === 'running' && this.poll === null) {
+      this.poll = interval(2000).subscribe(() => this.refresh())
+    } else if (status.State !== 'running') {
+      this.stopPolling()
+    }
+  }
+
+  stopPolling() {
+    if (this.poll !== null) {
+      this.poll.unsubscribe()
+      this.poll = null
+    }
+  }
+
+  isRunning(): boolean {
+    return this.status !== null && this.status.State === 'running'
+  }
+
+  countTables(state: string): number {
+    return this.status ? this.status.Tables.filter((t) => t.State === state).length : 0
+  }
+
+  // percentDone is the percentage of the source rows of a table that were
+  // processed, whether they were converted or not.
+  percentDone(table: ITableStatus): number {
+    if (table.State === 'done') {
+      return 100
+    }
+    if (table.Rows === 0) {
+      return 0
+    }
+    return Math.min(100, Math.round(((table.GoodRows + table.BadRows) * 100) / table.Rows))
+  }
+}
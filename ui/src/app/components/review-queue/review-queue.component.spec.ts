@@ -0,0 +1,48 @@
+import { ComponentFixture, TestBed } from '@angular/core/testing'
+import { HttpClientModule } from '@angular/common/http'
+import { MatSnackBar } from '@angular/material/snack-bar'
+
+import { ReviewQueueComponent } from './review-queue.component'
+
+describe('ReviewQueueComponent', () => {
+  let component: ReviewQueueComponent
+  let fixture: ComponentFixture<ReviewQueueComponent>
+
+  beforeEach(async () => {
+    await TestBed.configureTestingModule({
+      declarations: [ReviewQueueComponent],
+      providers: [MatSnackBar],
+      imports: [HttpClientModule],
+    }).compileComponents()
+  })
+
+  beforeEach(() => {
+    fixture = TestBed.createComponent(ReviewQueueComponent)
+    component = fixture.componentInstance
+    fixture.detectChanges()
+    const item = {
+      Table: 'users',
+      Column: 'id',
+      SpTable: 'users',
+      SpColumn: 'id',
+      Brief: '',
+      State: '',
+      Comment: '',
+    }
+    component.items = [
+      { ...item, Issue: 'NoGoodType', Severity: 'warning' },
+      { ...item, Issue: 'Widened', Severity: 'info' },
+      { ...item, Issue: 'Decimal', Severity: 'warning', State: 'acknowledged' },
+    ]
+  })
+
+  it('should create', () => {
+    expect(component).toBeTruthy()
+  })
+
+  it('should show only unreviewed warnings', () => {
+    expect(component.visibleItems().length).toEqual(3)
+    component.onlyUnreviewed = true
+    expect(component.visibleItems().map((item) => item.Issue)).toEqual(['NoGoodType'])
+  })
+})
@@ -0,0 +1,84 @@
+import { Component, EventEmitter, OnInit, Output } from '@angular/core'
+import IReviewQueue, { IReviewItem } from 'src/app/model/review'
+import { DataService } from 'src/app/services/data/data.service'
+import { FetchService } from 'src/app/services/fetch/fetch.service'
+import { SnackbarService } from 'src/app/services/snackbar/snackbar.service'
+
+@Component({
+  selector: 'app-review-queue',
+  templateUrl: './review-queue.component.html',
+  styleUrls: ['./review-queue.component.scss'],
+})
+export class ReviewQueueComponent implements OnInit {
+  @Output() selectTable = new EventEmitter<string>()
+  items: IReviewItem[] = []
+  unreviewed: number = 0
+  // Shows only the issues that block applying the schema.
+  onlyUnreviewed: boolean = false
+  states: string[] = ['resolved', 'acknowledged', 'overridden']
+  actions: Record<string, string> = {
+    resolved: 'RESOLVE',
+    acknowledged: 'ACKNOWLEDGE',
+    overridden: 'OVERRIDE',
+  }
+
+  constructor(
+    private data: DataService,
+    private fetch: FetchService,
+    private snackbar: SnackbarService
+  ) {}
+
+  ngOnInit(): void {
+    this.load()
+  }
+
+  load() {
+    this.fetch.getReviewQueue().subscribe({
+      next: (queue: IReviewQueue) => {
+        this.items = queue.Items
+        this.unreviewed = queue.Unreviewed
+      },
+      error: (err: any) => {
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+
+  needsReview(item: IReviewItem): boolean {
+    return item.State === '' && item.Severity !== 'info'
+  }
+
+  visibleItems(): IReviewItem[] {
+    return this.onlyUnreviewed ? this.items.filter((item) => this.needsReview(item)) : this.items
+  }
+
+  // review sets the review state of an issue, or removes its review if
+  // state is empty. Overriding an issue requires a comment.
+  review(item: IReviewItem, state: string) {
+    if (state === 'overridden' && item.Comment.trim() === '') {
+      this.snackbar.openSnackBar('Add a comment explaining why the issue is overridden', 'Close')
+      return
+    }
+    this.data
+      .reviewIssue({
+        Table: item.Table,
+        Column: item.Column,
+        Issue: item.Issue,
+        State: state,
+        Comment: state === '' ? '' : item.Comment,
+      })
+      .subscribe({
+        next: (err: string) => {
+          if (err === '') {
+            this.load()
+          } else {
+            this.snackbar.openSnackBar(err, 'Close')
+          }
+        },
+      })
+  }
+
+  openTable(table: string) {
+    this.selectTable.emit(table)
+  }
+}
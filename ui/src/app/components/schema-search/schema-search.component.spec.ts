@@ -0,0 +1,61 @@
+import { ComponentFixture, TestBed } from '@angular/core/testing'
+import { HttpClientModule } from '@angular/common/http'
+import { MatSnackBar } from '@angular/material/snack-bar'
+
+import { SchemaSearchComponent } from './schema-search.component'
+
+describe('SchemaSearchComponent', () => {
+  let component: SchemaSearchComponent
+  let fixture: ComponentFixture<SchemaSearchComponent>
+
+  beforeEach(async () => {
+    await TestBed.configureTestingModule({
+      declarations: [SchemaSearchComponent],
+      providers: [MatSnackBar],
+      imports: [HttpClientModule],
+    }).compileComponents()
+  })
+
+  beforeEach(() => {
+    fixture = TestBed.createComponent(SchemaSearchComponent)
+    component = fixture.componentInstance
+    fixture.detectChanges()
+    component.results = [
+      {
+        Table: 'users',
+        SrcTable: 'users',
+        Columns: [
+          { Col: 'id', SrcCol: 'id', SpType: 'INT64', SrcType: 'bigint', Issues: [] },
+          { Col: 'name', SrcCol: 'name', SpType: 'STRING', SrcType: 'varchar', Issues: [] },
+        ],
+      },
+      {
+        Table: 'orders',
+        SrcTable: 'orders',
+        Columns: [{ Col: 'note', SrcCol: 'note', SpType: 'STRING', SrcType: 'text', Issues: [] }],
+      },
+    ]
+  })
+
+  it('should create', () => {
+    expect(component).toBeTruthy()
+  })
+
+  it('should select all columns of a table', () => {
+    component.toggleTable(component.results[0])
+    expect(component.isTableSelected(component.results[0])).toBeTrue()
+    expect(component.selectedColumns()).toEqual([
+      { Table: 'users', Col: 'id' },
+      { Table: 'users', Col: 'name' },
+    ])
+    component.toggle('users', 'id')
+    expect(component.isTableSelected(component.results[0])).toBeFalse()
+  })
+
+  it('should select and clear all columns', () => {
+    component.selectAll(true)
+    expect(component.selected.size).toEqual(3)
+    component.selectAll(false)
+    expect(component.selectedColumns()).toEqual([])
+  })
+})
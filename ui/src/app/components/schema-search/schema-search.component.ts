@@ -0,0 +1,142 @@
+import { Component, EventEmitter, OnInit, Output } from '@angular/core'
+import ISearchRequest, { ISearchResult, ITableColumn } from 'src/app/model/search'
+import { DataService } from 'src/app/services/data/data.service'
+import { FetchService } from 'src/app/services/fetch/fetch.service'
+import { SnackbarService } from 'src/app/services/snackbar/snackbar.service'
+
+@Component({
+  selector: 'app-schema-search',
+  templateUrl: './schema-search.component.html',
+  styleUrls: ['./schema-search.component.scss'],
+})
+export class SchemaSearchComponent implements OnInit {
+  @Output() selectTable = new EventEmitter<string>()
+  request: ISearchRequest = { Text: '', SrcType: '', SpType: '', Issue: '' }
+  results: ISearchResult[] = []
+  // Selected columns, keyed by `${table}.${column}`.
+  selected: Set<string> = new Set<string>()
+  toType: string = ''
+  spannerTypes: string[] = [
+    'BOOL',
+    'BYTES',
+    'DATE',
+    'FLOAT64',
+    'INT64',
+    'JSON',
+    'NUMERIC',
+    'STRING',
+    'TIMESTAMP',
+  ]
+  issues: string[] = [
+    'NoGoodType',
+    'Widened',
+    'Numeric',
+    'Decimal',
+    'Timestamp',
+    'Datetime',
+    'Time',
+    'Serial',
+    'AutoIncrement',
+    'DefaultValue',
+    'ForeignKey',
+    'MissingPrimaryKey',
+    'MultiDimensionalArray',
+    'StringOverflow',
+    'HotspotTimestamp',
+    'HotspotAutoIncrement',
+    'IllegalName',
+  ]
+
+  constructor(
+    private data: DataService,
+    private fetch: FetchService,
+    private snackbar: SnackbarService
+  ) {}
+
+  ngOnInit(): void {}
+
+  search() {
+    this.fetch.search(this.request).subscribe({
+      next: (results: ISearchResult[]) => {
+        this.results = results
+        this.selected.clear()
+      },
+      error: (err: any) => {
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+
+  columnCount(): number {
+    return this.results.reduce((n, r) => n + r.Columns.length, 0)
+  }
+
+  key(table: string, col: string): string {
+    return `${table}.${col}`
+  }
+
+  toggle(table: string, col: string) {
+    const k = this.key(table, col)
+    if (this.selected.has(k)) {
+      this.selected.delete(k)
+    } else {
+      this.selected.add(k)
+    }
+  }
+
+  isTableSelected(result: ISearchResult): boolean {
+    return result.Columns.every((c) => this.selected.has(this.key(result.Table, c.Col)))
+  }
+
+  toggleTable(result: ISearchResult) {
+    const select = !this.isTableSelected(result)
+    result.Columns.forEach((c) => {
+      const k = this.key(result.Table, c.Col)
+      select ? this.selected.add(k) : this.selected.delete(k)
+    })
+  }
+
+  selectAll(select: boolean) {
+    this.selected.clear()
+    if (select) {
+      this.results.forEach((r) =>
+        r.Columns.forEach((c) => this.selected.add(this.key(r.Table, c.Col)))
+      )
+    }
+  }
+
+  selectedColumns(): ITableColumn[] {
+    const columns: ITableColumn[] = []
+    this.results.forEach((r) =>
+      r.Columns.forEach((c) => {
+        if (this.selected.has(this.key(r.Table, c.Col))) {
+          columns.push({ Table: r.Table, Col: c.Col })
+        }
+      })
+    )
+    return columns
+  }
+
+  // changeType applies the selected Spanner type to all selected columns,
+  // and refreshes the results since they may no longer match.
+  changeType() {
+    this.data.updateColumnsType(this.selectedColumns(), this.toType).subscribe({
+      next: (err: string) => {
+        if (err === '') {
+          this.snackbar.openSnackBar(
+            `Type of ${this.selected.size} columns updated successfully`,
+            'Close',
+            5
+          )
+          this.search()
+        } else {
+          this.snackbar.openSnackBar(err, 'Close')
+        }
+      },
+    })
+  }
+
+  openTable(table: string) {
+    this.selectTable.emit(table)
+  }
+}
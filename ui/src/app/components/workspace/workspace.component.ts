@@ -11,6 +11,9 @@ import ISchemaObjectNode, { FlatNode } from 'src/app/model/schema-object-node'
 import { ObjectExplorerNodeType, StorageKeys } from 'src/app/app.constants'
 import { IUpdateTableArgument } from 'src/app/model/update-table'
 import ConversionRate from 'src/app/model/conversion-rate'
+import { FetchService } from 'src/app/services/fetch/fetch.service'
+import IUser from 'src/app/model/user'
+import { SnackbarService } from 'src/app/services/snackbar/snackbar.service'
 
 @Component({
   selector: 'app-workspace',
@@ -32,8 +35,14 @@ export class WorkspaceComponent implements OnInit, OnDestroy {
   ddlObj!: Subscription
   isLeftColumnCollapse: boolean = false
   isRightColumnCollapse: boolean = true
+  // middleColumnView is what the middle column shows: the details of the
+  // current object, the ER diagram, the schema search, the review queue, the
+  // DDL editor, the data preview or the migration to Spanner.
+  middleColumnView: string = 'detail'
   ddlStmts: any
   isOfflineStatus: boolean = false
+  // isOperator is true if the user may apply the schema to Spanner.
+  isOperator: boolean = false
   spannerTree: ISchemaObjectNode[] = []
   srcTree: ISchemaObjectNode[] = []
   issuesAndSuggestionsLabel: string = 'ISSUES AND SUGGESTIONS'
@@ -44,7 +53,9 @@ export class WorkspaceComponent implements OnInit, OnDestroy {
     private data: DataService,
     private conversion: ConversionService,
     private dialog: MatDialog,
-    private sidenav: SidenavService
+    private sidenav: SidenavService,
+    private fetch: FetchService,
+    private snackbar: SnackbarService
   ) {
     this.currentObject = null
   }
@@ -109,6 +120,12 @@ export class WorkspaceComponent implements OnInit, OnDestroy {
         this.isOfflineStatus = res
       },
     })
+
+    this.fetch.whoAmI().subscribe({
+      next: (user: IUser) => {
+        this.isOperator = user.Role === 'operator'
+      },
+    })
   }
 
   ngOnDestroy(): void {
@@ -167,6 +184,34 @@ export class WorkspaceComponent implements OnInit, OnDestroy {
     }
   }
 
+  toggleMiddleColumnView(view: string) {
+    this.middleColumnView = this.middleColumnView === view ? 'detail' : view
+  }
+
+  // previewTable returns the Spanner table whose data is previewed: the
+  // current table, if any.
+  previewTable(): string {
+    return this.currentObject && this.currentObject.type === ObjectExplorerNodeType.Table
+      ? this.currentObject.name
+      : ''
+  }
+
+  // openTable opens the edit panel of a table clicked in the ER diagram, the
+  // search results or the review queue.
+  openTable(tableName: string) {
+    this.middleColumnView = 'detail'
+    this.changeCurrentObject({
+      expandable: true,
+      name: tableName,
+      status: this.conversionRates[tableName],
+      type: ObjectExplorerNodeType.Table,
+      parent: '',
+      pos: -1,
+      level: 1,
+      isSpannerNode: true,
+    })
+  }
+
   updateIssuesLabel(count: number) {
     setTimeout(() => {
       this.issuesAndSuggestionsLabel = `ISSUES AND SUGGESTIONS (${count})`
@@ -200,6 +245,33 @@ export class WorkspaceComponent implements OnInit, OnDestroy {
     a.click()
   }
 
+  // exportSession downloads the session as an archive, including its DDL
+  // edits and history, which can be imported on another HarbourBridge instance.
+  exportSession() {
+    this.fetch.exportSession().subscribe((archive: Blob) => {
+      var a = document.createElement('a')
+      a.href = URL.createObjectURL(archive)
+      a.download = `${this.conv.SessionName}_${this.conv.DatabaseName}.hbsession.zip`
+      a.click()
+    })
+  }
+
+  // takeSnapshot saves a snapshot of the session, in addition to the
+  // automatic ones, which can be resumed from the session list.
+  takeSnapshot() {
+    this.fetch.takeSnapshot().subscribe({
+      next: (res) => {
+        this.snackbar.openSnackBar(
+          res.status === 204 ? 'No changes since the last snapshot' : 'Snapshot saved',
+          'Close'
+        )
+      },
+      error: (err: any) => {
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+
   updateSpannerTable(data: IUpdateTableArgument) {
     this.spannerTree = this.conversion.createTreeNode(
       this.conv,
@@ -0,0 +1,17 @@
+export default interface IDataPreview {
+  Columns: IPreviewColumn[]
+  Rows: IPreviewRow[]
+}
+
+export interface IPreviewColumn {
+  SrcCol: string
+  SrcType: string
+  SpCol: string
+  SpType: string
+}
+
+export interface IPreviewRow {
+  SrcVals: string[]
+  SpVals: string[]
+  Error: string
+}
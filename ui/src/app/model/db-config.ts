@@ -5,4 +5,9 @@ export default interface IDbConfig {
   userName: string
   password: string
   dbName: string
+  sshHost?: string
+  sshUser?: string
+  sshPassword?: string
+  sshKeyFile?: string
+  proxy?: string
 }
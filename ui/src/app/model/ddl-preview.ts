@@ -0,0 +1,16 @@
+export default interface IDdlPreview {
+  Tables: ITableDdl[]
+  ForeignKeys: string[]
+}
+
+export interface ITableDdl {
+  Table: string
+  Generated: string[]
+  Edited: string[] | null
+  Stale: boolean
+}
+
+export interface IDdlValidation {
+  Valid: boolean
+  Error: string
+}
@@ -1,4 +1,10 @@
 export default interface IDumpConfig {
   Driver: string
   Path: string
+  Preset?: string
+}
+
+export interface ISpannerDdlConfig {
+  Text?: string
+  FilePath?: string
 }
@@ -0,0 +1,9 @@
+export default interface IJob {
+  Id: string
+  Kind: string
+  State: string
+  Error: string
+  Created: string
+  Ended: string
+  Log: string[]
+}
@@ -0,0 +1,22 @@
+export default interface IMigrationStatus {
+  State: string
+  JobId: string
+  Database: string
+  Mode: string
+  Error: string
+  Tables: ITableStatus[]
+}
+
+export interface ITableStatus {
+  Table: string
+  State: string
+  Rows: number
+  GoodRows: number
+  BadRows: number
+  Error: string
+}
+
+export interface IMigrationRequest {
+  Database: string
+  Mode: string
+}
@@ -0,0 +1,24 @@
+export default interface IReviewQueue {
+  Items: IReviewItem[]
+  Unreviewed: number
+}
+
+export interface IReviewItem {
+  Table: string
+  Column: string
+  SpTable: string
+  SpColumn: string
+  Issue: string
+  Severity: string
+  Brief: string
+  State: string
+  Comment: string
+}
+
+export interface IReviewIssue {
+  Table: string
+  Column: string
+  Issue: string
+  State: string
+  Comment: string
+}
@@ -0,0 +1,19 @@
+export default interface ISchemaGraph {
+  Nodes: ISchemaGraphNode[]
+  Edges: ISchemaGraphEdge[]
+}
+
+export interface ISchemaGraphNode {
+  Table: string
+  SrcTable: string
+  Columns: number
+  Issues: number
+  Level: number
+}
+
+export interface ISchemaGraphEdge {
+  From: string
+  To: string
+  Kind: string // 'foreignKey' or 'interleave'
+  Name: string
+}
@@ -0,0 +1,25 @@
+export default interface ISearchRequest {
+  Text: string
+  SrcType: string
+  SpType: string
+  Issue: string
+}
+
+export interface ISearchResult {
+  Table: string
+  SrcTable: string
+  Columns: ISearchColumn[]
+}
+
+export interface ISearchColumn {
+  Col: string
+  SrcCol: string
+  SpType: string
+  SrcType: string
+  Issues: string[]
+}
+
+export interface ITableColumn {
+  Table: string
+  Col: string
+}
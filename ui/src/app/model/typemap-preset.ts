@@ -0,0 +1,9 @@
+export default interface ITypeMapPreset {
+  Name: string
+  Description: string
+  BuiltIn: boolean
+  Driver: string
+  Mappings: Record<string, string> | null
+  SpannerTypes: Record<string, string> | null
+  AvoidIssues: boolean
+}
@@ -0,0 +1,6 @@
+export default interface IUser {
+  Email: string
+  Role: string
+  // Enabled is false if the backend doesn't authenticate users.
+  Enabled: boolean
+}
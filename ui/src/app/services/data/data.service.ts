@@ -13,6 +13,8 @@ import ISpannerConfig from '../../model/spanner-config'
 import { SnackbarService } from '../snackbar/snackbar.service'
 import ISummary from 'src/app/model/summary'
 import { ClickEventService } from '../click-event/click-event.service'
+import { ITableColumn } from 'src/app/model/search'
+import { IReviewIssue } from 'src/app/model/review'
 
 @Injectable({
   providedIn: 'root',
@@ -118,7 +120,12 @@ export class DataService {
   }
 
   getSchemaConversionFromDump(payload: IDumpConfig) {
-    this.fetch.getSchemaConversionFromDump(payload).subscribe({
+    // Spanner DDL files aren't dumps of a source database, and have their own API.
+    const conversion =
+      payload.Driver === 'spanner'
+        ? this.fetch.getSchemaConversionFromSpannerDdl({ FilePath: payload.Path })
+        : this.fetch.getSchemaConversionFromDump(payload)
+    conversion.subscribe({
       next: (res: IConv) => {
         this.convSubject.next(res)
       },
@@ -129,6 +136,18 @@ export class DataService {
     })
   }
 
+  importSession(archive: Blob) {
+    this.fetch.importSession(archive).subscribe({
+      next: (res: IConv) => {
+        this.convSubject.next(res)
+      },
+      error: (err: any) => {
+        this.snackbar.openSnackBar(err.error, 'Close')
+        this.clickEvent.closeDatabaseLoader()
+      },
+    })
+  }
+
   getSchemaConversionFromSession(payload: ISessionConfig) {
     this.fetch.getSchemaConversionFromSessionFile(payload).subscribe({
       next: (res: IConv) => {
@@ -279,6 +298,70 @@ export class DataService {
     })
   }
 
+  applyTypeMapPreset(name: string): void {
+    this.fetch.applyTypeMapPreset(name).subscribe({
+      next: (data: any) => {
+        this.convSubject.next(data)
+        this.snackbar.openSnackBar(`Preset ${name} applied successfully`, 'Close', 5)
+        this.getSummary()
+        this.getDdl()
+      },
+      error: (err: any) => {
+        this.snackbar.openSnackBar(err.error, 'Close')
+      },
+    })
+  }
+
+  updateColumnsType(columns: ITableColumn[], toType: string): Observable<string> {
+    return this.fetch.updateColumnsType(columns, toType).pipe(
+      catchError((e: any) => {
+        return of({ error: e.error })
+      }),
+      map((data: any) => {
+        if (data.error) {
+          return data.error
+        } else {
+          this.convSubject.next(data)
+          this.getSummary()
+          this.getDdl()
+          return ''
+        }
+      })
+    )
+  }
+
+  reviewIssue(payload: IReviewIssue): Observable<string> {
+    return this.fetch.reviewIssue(payload).pipe(
+      catchError((e: any) => {
+        return of({ error: e.error })
+      }),
+      map((data: any) => {
+        if (data.error) {
+          return data.error
+        } else {
+          this.convSubject.next(data)
+          return ''
+        }
+      })
+    )
+  }
+
+  editDdl(table: string, text: string): Observable<string> {
+    return this.fetch.editDdl(table, text).pipe(
+      catchError((e: any) => {
+        return of({ error: e.error })
+      }),
+      map((data: any) => {
+        if (data.error) {
+          return data.error
+        } else {
+          this.convSubject.next(data)
+          return ''
+        }
+      })
+    )
+  }
+
   addRule(nextData: IRuleContent): void {
     this.ruleMapSub.next(nextData)
   }
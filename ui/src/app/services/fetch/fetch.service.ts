@@ -4,9 +4,18 @@ import IDbConfig from 'src/app/model/db-config'
 import ISession, { ISaveSessionPayload } from '../../model/session'
 import IUpdateTable from '../../model/update-table'
 import IConv, { ICreateIndex, IInterleaveStatus, IPrimaryKey } from '../../model/conv'
-import IDumpConfig from '../../model/dump-config'
+import IDumpConfig, { ISpannerDdlConfig } from '../../model/dump-config'
 import ISessionConfig from '../../model/session-config'
 import ISpannerConfig from '../../model/spanner-config'
+import ISchemaGraph from '../../model/schema-graph'
+import ISearchRequest, { ISearchResult, ITableColumn } from '../../model/search'
+import IReviewQueue, { IReviewIssue } from '../../model/review'
+import IDdlPreview, { IDdlValidation } from '../../model/ddl-preview'
+import IDataPreview from '../../model/data-preview'
+import IMigrationStatus, { IMigrationRequest } from '../../model/migration'
+import IJob from '../../model/job'
+import IUser from '../../model/user'
+import ITypeMapPreset from '../../model/typemap-preset'
 
 @Injectable({
   providedIn: 'root',
@@ -16,7 +25,19 @@ export class FetchService {
   constructor(private http: HttpClient) {}
 
   connectTodb(payload: IDbConfig) {
-    const { dbEngine, hostName, port, dbName, userName, password } = payload
+    const {
+      dbEngine,
+      hostName,
+      port,
+      dbName,
+      userName,
+      password,
+      sshHost,
+      sshUser,
+      sshPassword,
+      sshKeyFile,
+      proxy,
+    } = payload
     return this.http.post<HttpResponse<null>>(
       `${this.url}/connect`,
       {
@@ -26,6 +47,11 @@ export class FetchService {
         Database: dbName,
         User: userName,
         Password: password,
+        SSHHost: sshHost,
+        SSHUser: sshUser,
+        SSHPassword: sshPassword,
+        SSHKeyFile: sshKeyFile,
+        Proxy: proxy,
       },
       { observe: 'response' }
     )
@@ -39,6 +65,10 @@ export class FetchService {
     return this.http.post<IConv>(`${this.url}/convert/dump`, payload)
   }
 
+  getSchemaConversionFromSpannerDdl(payload: ISpannerDdlConfig) {
+    return this.http.post<IConv>(`${this.url}/convert/spanner/ddl`, payload)
+  }
+
   getSchemaConversionFromSessionFile(payload: ISessionConfig) {
     return this.http.post<IConv>(`${this.url}/convert/session`, payload)
   }
@@ -136,4 +166,106 @@ export class FetchService {
   setInterleave(tableName: string) {
     return this.http.get(`${this.url}/setparent?table=${tableName}&update=true`)
   }
+
+  getSchemaGraph() {
+    return this.http.get<ISchemaGraph>(`${this.url}/graph`)
+  }
+
+  search(payload: ISearchRequest) {
+    return this.http.post<ISearchResult[]>(`${this.url}/search`, payload)
+  }
+
+  updateColumnsType(columns: ITableColumn[], toType: string) {
+    return this.http.post<IConv>(`${this.url}/typemap/columns`, {
+      Columns: columns,
+      ToType: toType,
+    })
+  }
+
+  getReviewQueue() {
+    return this.http.get<IReviewQueue>(`${this.url}/review`)
+  }
+
+  reviewIssue(payload: IReviewIssue) {
+    return this.http.post<IConv>(`${this.url}/review`, payload)
+  }
+
+  getDdlPreview() {
+    return this.http.get<IDdlPreview>(`${this.url}/ddl/preview`)
+  }
+
+  editDdl(table: string, text: string) {
+    return this.http.post<IConv>(`${this.url}/ddl/edit`, { Table: table, Text: text })
+  }
+
+  validateDdl() {
+    return this.http.post<IDdlValidation>(`${this.url}/ddl/validate`, {})
+  }
+
+  getDataPreview(table: string, rows: number) {
+    return this.http.get<IDataPreview>(`${this.url}/preview?table=${table}&rows=${rows}`)
+  }
+
+  migrate(payload: IMigrationRequest) {
+    return this.http.post<IMigrationStatus>(`${this.url}/migrate`, payload)
+  }
+
+  getMigrationStatus() {
+    return this.http.get<IMigrationStatus>(`${this.url}/migrate/status`)
+  }
+
+  getJobs() {
+    return this.http.get<IJob[]>(`${this.url}/jobs`)
+  }
+
+  cancelJob(id: string) {
+    return this.http.post<IJob>(`${this.url}/jobs/${id}/cancel`, {})
+  }
+
+  // jobLogs streams the log of a job as server-sent events.
+  jobLogs(id: string): EventSource {
+    return new EventSource(`${this.url}/jobs/${id}/logs`)
+  }
+
+  whoAmI() {
+    return this.http.get<IUser>(`${this.url}/auth/whoami`)
+  }
+
+  exportSession() {
+    return this.http.get(`${this.url}/session/export`, { responseType: 'blob' })
+  }
+
+  importSession(archive: Blob) {
+    return this.http.post<IConv>(`${this.url}/session/import`, archive, {
+      headers: { 'Content-Type': 'application/zip' },
+    })
+  }
+
+  getSnapshots() {
+    return this.http.get<ISession[]>(`${this.url}/session/snapshots`)
+  }
+
+  takeSnapshot() {
+    return this.http.post<ISession>(`${this.url}/session/snapshots`, {}, { observe: 'response' })
+  }
+
+  getTypeMapPresets() {
+    return this.http.get<ITypeMapPreset[]>(`${this.url}/typemap/presets`)
+  }
+
+  saveTypeMapPreset(preset: ITypeMapPreset) {
+    return this.http.post<ITypeMapPreset>(`${this.url}/typemap/presets`, preset)
+  }
+
+  deleteTypeMapPreset(name: string) {
+    return this.http.delete<ITypeMapPreset[]>(`${this.url}/typemap/presets/${name}`)
+  }
+
+  applyTypeMapPreset(name: string) {
+    return this.http.post<IConv>(`${this.url}/typemap/presets/${name}/apply`, {})
+  }
+
+  renameTable(tableId: string, name: string) {
+    return this.http.post<IConv>(`${this.url}/rename/table?tableId=${tableId}`, { Name: name })
+  }
 }
@@ -2,6 +2,7 @@ import { HttpEvent, HttpHandler, HttpInterceptor, HttpRequest } from '@angular/c
 import { Injectable } from '@angular/core'
 import { finalize, Observable } from 'rxjs'
 import { LoaderService } from '../loader/loader.service'
+import { StorageKeys } from 'src/app/app.constants'
 
 @Injectable({
   providedIn: 'root',
@@ -12,6 +13,10 @@ export class InterceptorService implements HttpInterceptor {
   intercept(req: HttpRequest<any>, next: HttpHandler): Observable<HttpEvent<any>> {
     this.loader.startLoader()
     this.count++
+    const idToken = localStorage.getItem(StorageKeys.IdToken)
+    if (idToken) {
+      req = req.clone({ setHeaders: { Authorization: `Bearer ${idToken}` } })
+    }
     return next.handle(req).pipe(
       finalize(() => {
         this.count--
@@ -4,6 +4,9 @@ export function extractSourceDbName(srcDbName: string) {
   if (srcDbName == 'mysql' || srcDbName == 'mysqldump') {
     return SourceDbNames.MySQL
   }
+  if (srcDbName === 'mariadb' || srcDbName === 'mariadbdump') {
+    return SourceDbNames.MariaDB
+  }
   if (srcDbName === 'postgres' || srcDbName === 'pgdump') {
     return SourceDbNames.Postgres
   }
@@ -13,5 +16,8 @@ export function extractSourceDbName(srcDbName: string) {
   if (srcDbName === 'sqlserver') {
     return SourceDbNames.SQLServer
   }
+  if (srcDbName === 'spanner') {
+    return SourceDbNames.Spanner
+  }
   return srcDbName
 }
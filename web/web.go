@@ -59,10 +59,6 @@ import (
 // 6) Update schema conv after setting global datatypes and return conv. (setTypeMap)
 // 7) Add rateConversion() in schema conversion, ddl and report APIs.
 // 8) Add an overview in summary report API
-var mysqlTypeMap = make(map[string][]typeIssue)
-var postgresTypeMap = make(map[string][]typeIssue)
-var sqlserverTypeMap = make(map[string][]typeIssue)
-var oracleTypeMap = make(map[string][]typeIssue)
 
 // TODO:(searce) organize this file according to go style guidelines: generally
 // have public constants and public type definitions first, then public
@@ -264,17 +260,8 @@ func getTypeMap(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
 		return
 	}
-	var typeMap map[string][]typeIssue
-	switch sessionState.driver {
-	case constants.MYSQL, constants.MYSQLDUMP:
-		typeMap = mysqlTypeMap
-	case constants.POSTGRES, constants.PGDUMP:
-		typeMap = postgresTypeMap
-	case constants.SQLSERVER:
-		typeMap = sqlserverTypeMap
-	case constants.ORACLE:
-		typeMap = oracleTypeMap
-	default:
+	typeMap, found := driverTypeMap(sessionState.driver)
+	if !found {
 		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", sessionState.driver), http.StatusBadRequest)
 		return
 	}
@@ -285,19 +272,7 @@ func getTypeMap(w http.ResponseWriter, r *http.Request) {
 			if _, ok := filteredTypeMap[colDef.Type.Name]; ok {
 				continue
 			}
-			// Timestamp and interval types do not have exact key in typemap.
-			// Typemap for  TIMESTAMP(6), TIMESTAMP(6) WITH LOCAL TIMEZONE,TIMESTAMP(6) WITH TIMEZONE is stored into TIMESTAMP key.
-			// Same goes with interval types like INTERVAL YEAR(2) TO MONTH, INTERVAL DAY(2) TO SECOND(6) etc.
-			// If exact key not found then check with regex.
-			if _, ok := typeMap[colDef.Type.Name]; !ok {
-				if oracle.TimestampReg.MatchString(colDef.Type.Name) {
-					filteredTypeMap[colDef.Type.Name] = typeMap["TIMESTAMP"]
-				} else if oracle.IntervalReg.MatchString(colDef.Type.Name) {
-					filteredTypeMap[colDef.Type.Name] = typeMap["INTERVAL"]
-				}
-				continue
-			}
-			filteredTypeMap[colDef.Type.Name] = typeMap[colDef.Type.Name]
+			filteredTypeMap[colDef.Type.Name] = toTypeIssues(typeMap.TypeOptions(colDef.Type.Name))
 		}
 	}
 	w.WriteHeader(http.StatusOK)
@@ -1067,20 +1042,11 @@ func getType(newType, table, colName string, srcTableName string) (ddl.CreateTab
 	sp := sessionState.conv.SpSchema[table]
 	srcColName := sessionState.conv.ToSource[table].Cols[colName]
 	srcCol := sessionState.conv.SrcSchema[srcTableName].ColDefs[srcColName]
-	var ty ddl.Type
-	var issues []internal.SchemaIssue
-	switch sessionState.driver {
-	case constants.MYSQL, constants.MYSQLDUMP:
-		ty, issues = toSpannerTypeMySQL(srcCol.Type.Name, newType, srcCol.Type.Mods)
-	case constants.PGDUMP, constants.POSTGRES:
-		ty, issues = toSpannerTypePostgres(srcCol.Type.Name, newType, srcCol.Type.Mods)
-	case constants.SQLSERVER:
-		ty, issues = toSpannerTypeSQLserver(srcCol.Type.Name, newType, srcCol.Type.Mods)
-	case constants.ORACLE:
-		ty, issues = oracle.ToSpannerTypeWeb(sessionState.conv, newType, srcCol.Type.Name, srcCol.Type.Mods)
-	default:
-		return sp, ty, fmt.Errorf("driver : '%s' is not supported", sessionState.driver)
+	typeMap, found := driverTypeMap(sessionState.driver)
+	if !found {
+		return sp, ddl.Type{}, fmt.Errorf("driver : '%s' is not supported", sessionState.driver)
 	}
+	ty, issues := typeMap.ToSpannerType(srcCol.Type.Name, newType, srcCol.Type.Mods)
 	if len(srcCol.Type.ArrayBounds) > 1 {
 		ty = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
 		issues = append(issues, internal.MultiDimensionalArray)
@@ -1169,63 +1135,41 @@ type typeIssue struct {
 	Brief string
 }
 
-func addTypeToList(convertedType string, spType string, issues []internal.SchemaIssue, l []typeIssue) []typeIssue {
-	if convertedType == spType {
-		if len(issues) > 0 {
-			var briefs []string
-			for _, issue := range issues {
-				briefs = append(briefs, internal.IssueDB[issue].Brief)
-			}
-			l = append(l, typeIssue{T: spType, Brief: fmt.Sprintf(strings.Join(briefs, ", "))})
-		} else {
-			l = append(l, typeIssue{T: spType})
+func toTypeIssues(options []common.TypeOption) []typeIssue {
+	var l []typeIssue
+	for _, o := range options {
+		if o.Condition != "" {
+			l = append(l, typeIssue{T: o.Name, Brief: o.Condition})
+			continue
 		}
+		var briefs []string
+		for _, issue := range o.Issues {
+			briefs = append(briefs, internal.IssueDB[issue].Brief)
+		}
+		l = append(l, typeIssue{T: o.Name, Brief: strings.Join(briefs, ", ")})
 	}
 	return l
 }
-func init() {
-	// Initialize mysqlTypeMap.
-	for _, srcType := range []string{"bool", "boolean", "varchar", "char", "text", "tinytext", "mediumtext", "longtext", "set", "enum", "json", "bit", "binary", "varbinary", "blob", "tinyblob", "mediumblob", "longblob", "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "double", "float", "numeric", "decimal", "date", "datetime", "timestamp", "time", "year", "geometrycollection", "multipoint", "multilinestring", "multipolygon", "point", "linestring", "polygon", "geometry"} {
-		var l []typeIssue
-		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric} {
-			ty, issues := toSpannerTypeMySQL(srcType, spType, []int64{})
-			l = addTypeToList(ty.Name, spType, issues, l)
-		}
-		if srcType == "tinyint" {
-			l = append(l, typeIssue{T: ddl.Bool, Brief: "Only tinyint(1) can be converted to BOOL, for any other mods it will be converted to INT64"})
-		}
-		mysqlTypeMap[srcType] = l
-	}
-	// Initialize postgresTypeMap.
-	for _, srcType := range []string{"bool", "boolean", "bigserial", "bpchar", "character", "bytea", "date", "float8", "double precision", "float4", "real", "int8", "bigint", "int4", "integer", "int2", "smallint", "numeric", "serial", "text", "timestamptz", "timestamp with time zone", "timestamp", "timestamp without time zone", "varchar", "character varying"} {
-		var l []typeIssue
-		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric} {
-			ty, issues := toSpannerTypePostgres(srcType, spType, []int64{})
-			l = addTypeToList(ty.Name, spType, issues, l)
-		}
-		postgresTypeMap[srcType] = l
-	}
-
-	// Initialize sqlserverTypeMap.
-	for _, srcType := range []string{"int", "tinyint", "smallint", "bigint", "bit", "float", "real", "numeric", "decimal", "money", "smallmoney", "char", "nchar", "varchar", "nvarchar", "text", "ntext", "date", "datetime", "datetime2", "smalldatetime", "datetimeoffset", "time", "timestamp", "rowversion", "binary", "varbinary", "image", "xml", "geography", "geometry", "uniqueidentifier", "sql_variant", "hierarchyid"} {
-		var l []typeIssue
-		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric} {
-			ty, issues := toSpannerTypeSQLserver(srcType, spType, []int64{})
-			l = addTypeToList(ty.Name, spType, issues, l)
-		}
-		sqlserverTypeMap[srcType] = l
-	}
 
-	// Initialize oracleTypeMap.
-	for _, srcType := range []string{"NUMBER", "BFILE", "BLOB", "CHAR", "CLOB", "DATE", "BINARY_DOUBLE", "BINARY_FLOAT", "FLOAT", "LONG", "RAW", "LONG RAW", "NCHAR", "NVARCHAR2", "VARCHAR", "VARCHAR2", "NCLOB", "ROWID", "UROWID", "XMLTYPE", "TIMESTAMP", "INTERVAL", "SDO_GEOMETRY"} {
-		var l []typeIssue
-		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric} {
-			ty, issues := oracle.ToSpannerTypeWeb(sessionState.conv, spType, srcType, []int64{})
-			l = addTypeToList(ty.Name, spType, issues, l)
-		}
-		oracleTypeMap[srcType] = l
+// driverTypeMap returns the typemap that driver declares next to its toddl
+// implementation.
+func driverTypeMap(driver string) (common.TypeMap, bool) {
+	switch driver {
+	case constants.MYSQL, constants.MYSQLDUMP:
+		return mysql.TypeMap, true
+	case constants.MARIADB, constants.MARIADBDUMP:
+		return mysql.MariaDBTypeMap, true
+	case constants.POSTGRES, constants.PGDUMP:
+		return postgres.TypeMap, true
+	case constants.SQLSERVER:
+		return sqlserver.TypeMap, true
+	case constants.ORACLE:
+		return oracle.TypeMap, true
 	}
+	return common.TypeMap{}, false
+}
 
+func init() {
 	sessionState.conv = internal.MakeConv()
 }
 
@@ -18,12 +18,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
@@ -31,6 +34,19 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// TestMain writes the files generated by the handlers (session files,
+// reports and schemas) to a temporary directory rather than the source tree.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "harbour_bridge_output")
+	if err != nil {
+		panic(err)
+	}
+	conversion.OutputDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestGetTypeMapNoDriver(t *testing.T) {
 	req, err := http.NewRequest("GET", "/typemap", nil)
 	if err != nil {
@@ -864,7 +880,8 @@ func TestGetTypeMapMySQL(t *testing.T) {
 			{T: ddl.String}},
 		"json": {
 			{T: ddl.Bytes},
-			{T: ddl.String}},
+			{T: ddl.String},
+			{T: ddl.JSON}},
 		"binary": {
 			{T: ddl.Bytes},
 			{T: ddl.String}},
@@ -2461,7 +2478,7 @@ func buildConvMySQL(conv *internal.Conv) {
 			"a": {internal.Widened},
 		},
 	}
-	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{"synth_id", 0}
+	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{Col: "synth_id", Sequence: 0}
 	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_AND_DATA.Enum()
 }
 
@@ -2570,6 +2587,6 @@ func buildConvPostgres(conv *internal.Conv) {
 			"b": {internal.Widened},
 		},
 	}
-	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{"synth_id", 0}
+	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{Col: "synth_id", Sequence: 0}
 	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_AND_DATA.Enum()
 }
@@ -0,0 +1,320 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth implements optional authentication and role-based access for
+// the web app, so that a shared deployment can restrict who may modify
+// schemas or launch migrations. Users are identified by the Google-signed ID
+// token of Identity-Aware Proxy (IAP) or of a Google OIDC sign-in, and get
+// the role assigned to their email address.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/api/idtoken"
+)
+
+// Authentication modes.
+const (
+	// ModeNone disables authentication: everyone is an operator.
+	ModeNone = ""
+	// ModeIAP validates the JWT added by Identity-Aware Proxy to the
+	// X-Goog-IAP-JWT-Assertion header of requests.
+	ModeIAP = "iap"
+	// ModeOIDC validates a Google ID token sent as a bearer token in the
+	// Authorization header of requests.
+	ModeOIDC = "oidc"
+)
+
+// Roles, in increasing order of privilege. Each role can do everything the
+// previous roles can.
+const (
+	// RoleViewer can view sessions, schemas, reports and migrations.
+	RoleViewer = "viewer"
+	// RoleEditor can also connect to source databases, and convert and
+	// modify schemas.
+	RoleEditor = "editor"
+	// RoleOperator can also change the Spanner config, validate DDL in
+	// Spanner, and launch and cancel migrations.
+	RoleOperator = "operator"
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOperator: 3}
+
+// routeRoles are the roles needed for the routes of the web app, keyed by
+// "<method> <path template>". Routes aren't classified by method since some
+// GET routes e.g. /convert/infoschema and /setparent change the session.
+var routeRoles = map[string]string{
+	"GET /drivers":                       RoleViewer,
+	"POST /connect":                      RoleEditor,
+	"GET /convert/infoschema":            RoleEditor,
+	"POST /convert/dump":                 RoleEditor,
+	"POST /convert/session":              RoleEditor,
+	"POST /convert/spanner/ddl":          RoleEditor,
+	"POST /convert/spanner/database":     RoleEditor,
+	"GET /ddl":                           RoleViewer,
+	"GET /ddl/preview":                   RoleViewer,
+	"POST /ddl/edit":                     RoleEditor,
+	"POST /ddl/validate":                 RoleOperator,
+	"GET /overview":                      RoleViewer,
+	"GET /conversion":                    RoleViewer,
+	"GET /typemap":                       RoleViewer,
+	"GET /report":                        RoleViewer,
+	"GET /schema":                        RoleViewer,
+	"POST /typemap/global":               RoleEditor,
+	"POST /typemap/table":                RoleEditor,
+	"POST /typemap/columns":              RoleEditor,
+	"GET /typemap/presets":               RoleViewer,
+	"POST /typemap/presets":              RoleEditor,
+	"DELETE /typemap/presets/{name}":     RoleEditor,
+	"POST /typemap/presets/{name}/apply": RoleEditor,
+	"POST /search":                       RoleViewer,
+	"GET /setparent":                     RoleEditor,
+	"GET /graph":                         RoleViewer,
+	"GET /preview":                       RoleViewer,
+	"GET /review":                        RoleViewer,
+	"POST /review":                       RoleEditor,
+	"POST /migrate":                      RoleOperator,
+	"GET /migrate/status":                RoleViewer,
+	"GET /jobs":                          RoleViewer,
+	"GET /jobs/{id}":                     RoleViewer,
+	"POST /jobs/{id}/cancel":             RoleOperator,
+	"GET /jobs/{id}/logs":                RoleViewer,
+	"POST /drop/fk":                      RoleEditor,
+	"POST /fk/enforcement":               RoleEditor,
+	"GET /fk/cycles":                     RoleViewer,
+	"POST /fk/cycle":                     RoleEditor,
+	"POST /drop/secondaryindex":          RoleEditor,
+	"POST /rename/fks":                   RoleEditor,
+	"POST /rename/indexes":               RoleEditor,
+	"POST /rename/table":                 RoleEditor,
+	"POST /add/indexes":                  RoleEditor,
+	"POST /update/indexes":               RoleEditor,
+	"POST /keystrategy":                  RoleEditor,
+	"POST /nested":                       RoleEditor,
+	"POST /split":                        RoleEditor,
+	"POST /synthetickey":                 RoleEditor,
+	"POST /timezone":                     RoleEditor,
+	"GET /IsOffline":                     RoleViewer,
+	"POST /InitiateSession":              RoleEditor,
+	"GET /GetSessions":                   RoleViewer,
+	"GET /GetSession/{versionId}":        RoleViewer,
+	"POST /SaveRemoteSession":            RoleOperator,
+	"POST /ResumeSession/{versionId}":    RoleEditor,
+	"GET /session/export":                RoleViewer,
+	"POST /session/import":               RoleEditor,
+	"GET /session/snapshots":             RoleViewer,
+	"POST /session/snapshots":            RoleEditor,
+	"POST /primaryKey":                   RoleEditor,
+	"GET /summary":                       RoleViewer,
+	"GET /GetConfig":                     RoleViewer,
+	"POST /SetSpannerConfig":             RoleOperator,
+	"GET /auth/whoami":                   RoleViewer,
+}
+
+// Config configures authentication.
+type Config struct {
+	Mode string // One of ModeNone, ModeIAP and ModeOIDC.
+	// Audience of ID tokens: the "/projects/<number>/global/backendServices/<id>"
+	// of the IAP backend service for ModeIAP, or the OAuth client id for
+	// ModeOIDC.
+	Audience string
+	// Roles maps email addresses to roles. A key "@<domain>" applies to every
+	// address of the domain, and a key "*" applies to everyone else. Users
+	// without a role are denied access.
+	Roles map[string]string
+}
+
+// ConfigFromEnv returns the Config set with environment variables
+// HB_AUTH_MODE, HB_AUTH_AUDIENCE and HB_AUTH_ROLES, since the web app
+// doesn't use command line flags. HB_AUTH_ROLES is a comma-separated list of
+// "<email>=<role>" e.g. "alice@example.com=operator,@example.com=editor,*=viewer".
+func ConfigFromEnv() (Config, error) {
+	c := Config{Mode: os.Getenv("HB_AUTH_MODE"), Audience: os.Getenv("HB_AUTH_AUDIENCE"), Roles: make(map[string]string)}
+	switch c.Mode {
+	case ModeNone:
+		return c, nil
+	case ModeIAP, ModeOIDC:
+	default:
+		return c, fmt.Errorf("unknown authentication mode '%s': available choices(%s, %s)", c.Mode, ModeIAP, ModeOIDC)
+	}
+	if c.Audience == "" {
+		return c, fmt.Errorf("HB_AUTH_AUDIENCE must be set when HB_AUTH_MODE is %s", c.Mode)
+	}
+	roles, err := ParseRoles(os.Getenv("HB_AUTH_ROLES"))
+	if err != nil {
+		return c, err
+	}
+	c.Roles = roles
+	return c, nil
+}
+
+// ParseRoles parses a comma-separated list of "<email>=<role>".
+func ParseRoles(s string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("can't parse role '%s': expected <email>=<role>", entry)
+		}
+		role := strings.TrimSpace(kv[1])
+		if _, ok := roleRank[role]; !ok {
+			return nil, fmt.Errorf("unknown role '%s': available choices(%s, %s, %s)", role, RoleViewer, RoleEditor, RoleOperator)
+		}
+		roles[strings.ToLower(strings.TrimSpace(kv[0]))] = role
+	}
+	return roles, nil
+}
+
+// User is an authenticated user.
+type User struct {
+	Email string
+	Role  string
+}
+
+// Role returns the role of email address email, and "" if it has none.
+func (c Config) Role(email string) string {
+	email = strings.ToLower(email)
+	if r, ok := c.Roles[email]; ok {
+		return r
+	}
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		if r, ok := c.Roles[email[i:]]; ok {
+			return r
+		}
+	}
+	return c.Roles["*"]
+}
+
+// RouteRole returns the role needed for the route with the given method and
+// path template, and false if the route has no role in routeRoles.
+func RouteRole(method, tpl string) (string, bool) {
+	role, ok := routeRoles[method+" "+tpl]
+	return role, ok
+}
+
+// RequiredRole returns the role needed for request r. Requests of routes
+// without a role in routeRoles need RoleOperator, so that new routes are
+// restricted until they're given one.
+func RequiredRole(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			if role, ok := RouteRole(r.Method, tpl); ok {
+				return role
+			}
+		}
+	}
+	return RoleOperator
+}
+
+// HasRole returns true if role grants at least the privileges of required.
+func HasRole(role, required string) bool {
+	return roleRank[role] >= roleRank[required]
+}
+
+// Authenticator authenticates requests.
+type Authenticator struct {
+	config Config
+	// validate validates ID tokens. It is idtoken.Validate, except in tests.
+	validate func(ctx context.Context, token, audience string) (*idtoken.Payload, error)
+}
+
+// NewAuthenticator returns an Authenticator for c.
+func NewAuthenticator(c Config) *Authenticator {
+	return &Authenticator{config: c, validate: idtoken.Validate}
+}
+
+type userKey struct{}
+
+// UserFromContext returns the user of a request authenticated by
+// Middleware, and false if authentication is disabled.
+func UserFromContext(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(userKey{}).(User)
+	return u, ok
+}
+
+// Authenticate returns the user who sent r.
+func (a *Authenticator) Authenticate(r *http.Request) (User, error) {
+	var token string
+	switch a.config.Mode {
+	case ModeIAP:
+		token = r.Header.Get("X-Goog-IAP-JWT-Assertion")
+	case ModeOIDC:
+		if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+			token = strings.TrimPrefix(h, "Bearer ")
+		}
+	}
+	if token == "" {
+		return User{}, fmt.Errorf("missing ID token")
+	}
+	p, err := a.validate(r.Context(), token, a.config.Audience)
+	if err != nil {
+		return User{}, fmt.Errorf("invalid ID token: %v", err)
+	}
+	email, _ := p.Claims["email"].(string)
+	if email == "" {
+		return User{}, fmt.Errorf("ID token has no email")
+	}
+	// IAP only signs verified addresses, and doesn't set email_verified.
+	if verified, ok := p.Claims["email_verified"].(bool); ok && !verified {
+		return User{}, fmt.Errorf("email %s isn't verified", email)
+	}
+	return User{Email: email, Role: a.config.Role(email)}, nil
+}
+
+// Middleware authenticates requests, and denies those whose user doesn't
+// have the role they need (see RequiredRole).
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.config.Mode == ModeNone || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		u, err := a.Authenticate(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Authentication error : %v", err), http.StatusUnauthorized)
+			return
+		}
+		if required := RequiredRole(r); !HasRole(u.Role, required) {
+			http.Error(w, fmt.Sprintf("User %s needs the %s role", u.Email, required), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, u)))
+	})
+}
+
+// WhoAmI returns the user of the request, so that the UI can hide the
+// actions the user isn't allowed to do. Everyone is an operator if
+// authentication is disabled.
+func WhoAmI(w http.ResponseWriter, r *http.Request) {
+	u, ok := UserFromContext(r.Context())
+	if !ok {
+		u = User{Role: RoleOperator}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		User
+		Enabled bool
+	}{u, ok})
+}
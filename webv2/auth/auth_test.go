@@ -0,0 +1,132 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/idtoken"
+)
+
+func TestParseRoles(t *testing.T) {
+	roles, err := ParseRoles("Alice@example.com=operator, @example.com=editor,*=viewer")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"alice@example.com": RoleOperator, "@example.com": RoleEditor, "*": RoleViewer}, roles)
+	c := Config{Roles: roles}
+	assert.Equal(t, RoleOperator, c.Role("alice@example.com"))
+	assert.Equal(t, RoleEditor, c.Role("bob@example.com"))
+	assert.Equal(t, RoleViewer, c.Role("carol@other.com"))
+	assert.Equal(t, "", Config{}.Role("carol@other.com"))
+
+	_, err = ParseRoles("alice@example.com=admin")
+	assert.NotNil(t, err)
+	_, err = ParseRoles("alice@example.com")
+	assert.NotNil(t, err)
+}
+
+func TestMiddleware(t *testing.T) {
+	a := NewAuthenticator(Config{
+		Mode:     ModeOIDC,
+		Audience: "client-id",
+		Roles:    map[string]string{"op@example.com": RoleOperator, "@example.com": RoleEditor, "*": RoleViewer},
+	})
+	// Tokens are the email addresses of their users.
+	a.validate = func(ctx context.Context, token, audience string) (*idtoken.Payload, error) {
+		if audience != "client-id" || token == "bad" {
+			return nil, fmt.Errorf("invalid token")
+		}
+		return &idtoken.Payload{Claims: map[string]interface{}{"email": token, "email_verified": true}}, nil
+	}
+	router := mux.NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.HandleFunc("/ddl", ok).Methods("GET")
+	router.HandleFunc("/typemap/table", ok).Methods("POST")
+	router.HandleFunc("/migrate", ok).Methods("POST")
+	router.HandleFunc("/setparent", ok).Methods("GET")
+	router.HandleFunc("/convert/infoschema", ok).Methods("GET")
+	router.HandleFunc("/unknown", ok).Methods("GET")
+	router.HandleFunc("/auth/whoami", WhoAmI).Methods("GET")
+	router.Use(a.Middleware)
+
+	tc := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		statusCode int
+	}{
+		{"Test missing token", "GET", "/ddl", "", http.StatusUnauthorized},
+		{"Test invalid token", "GET", "/ddl", "bad", http.StatusUnauthorized},
+		{"Test viewer reads", "GET", "/ddl", "viewer@other.com", http.StatusOK},
+		{"Test viewer can't edit", "POST", "/typemap/table", "viewer@other.com", http.StatusForbidden},
+		{"Test editor edits", "POST", "/typemap/table", "editor@example.com", http.StatusOK},
+		{"Test editor can't migrate", "POST", "/migrate", "editor@example.com", http.StatusForbidden},
+		{"Test operator migrates", "POST", "/migrate", "op@example.com", http.StatusOK},
+		{"Test viewer can't set parent", "GET", "/setparent", "viewer@other.com", http.StatusForbidden},
+		{"Test editor sets parent", "GET", "/setparent", "editor@example.com", http.StatusOK},
+		{"Test viewer can't convert", "GET", "/convert/infoschema", "viewer@other.com", http.StatusForbidden},
+		{"Test editor converts", "GET", "/convert/infoschema", "editor@example.com", http.StatusOK},
+		{"Test editor can't use unknown route", "GET", "/unknown", "editor@example.com", http.StatusForbidden},
+		{"Test operator uses unknown route", "GET", "/unknown", "op@example.com", http.StatusOK},
+	}
+	for _, tc := range tc {
+		req, err := http.NewRequest(tc.method, tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+	}
+
+	req, _ := http.NewRequest("GET", "/auth/whoami", nil)
+	req.Header.Set("Authorization", "Bearer editor@example.com")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var res struct {
+		User
+		Enabled bool
+	}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.Equal(t, User{Email: "editor@example.com", Role: RoleEditor}, res.User)
+	assert.True(t, res.Enabled)
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/auth/whoami", WhoAmI).Methods("GET")
+	router.Use(NewAuthenticator(Config{}).Middleware)
+	req, _ := http.NewRequest("GET", "/auth/whoami", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var res struct {
+		User
+		Enabled bool
+	}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.Equal(t, RoleOperator, res.Role)
+	assert.False(t, res.Enabled)
+}
@@ -0,0 +1,289 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jobs runs long operations of the web app, such as schema
+// conversion of large databases, data migration and DDL validation, in the
+// background. Each job has an id, can be cancelled, and keeps a log which
+// can be streamed to the browser. The state of jobs is persisted to a file,
+// so that it survives restarts of the web app.
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// States of a job.
+const (
+	Running   = "running"
+	Failed    = "failed"
+	Cancelled = "cancelled"
+	Done      = "done"
+)
+
+// maxLogLines is the number of log lines kept for each job. Older lines
+// are dropped.
+const maxLogLines = 1000
+
+// Job is a long operation running in the background.
+type Job struct {
+	Id      string
+	Kind    string // What the job does e.g. "migration".
+	State   string // One of Running, Failed, Cancelled and Done.
+	Error   string // Why the job failed.
+	Created time.Time
+	Ended   time.Time
+	Log     []string
+	// Result is the result of a done job, as returned by its function. It
+	// isn't persisted.
+	Result interface{} `json:"-"`
+
+	cancel    context.CancelFunc
+	listeners []chan string
+}
+
+// Manager runs jobs and keeps their state.
+type Manager struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+	path string // File where the state of jobs is persisted. Empty if it isn't persisted.
+}
+
+// NewManager returns a Manager which persists the state of jobs to path,
+// unless path is empty. Jobs persisted by a previous Manager are loaded;
+// those that were running were interrupted, and are marked as failed.
+func NewManager(path string) *Manager {
+	m := &Manager{jobs: make(map[string]*Job), path: path}
+	if path == "" {
+		return m
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Can't read jobs file %s: %v\n", path, err)
+		}
+		return m
+	}
+	var l []*Job
+	if err = json.Unmarshal(b, &l); err != nil {
+		log.Printf("Can't parse jobs file %s: %v\n", path, err)
+		return m
+	}
+	for _, j := range l {
+		if j.State == Running {
+			j.State = Failed
+			j.Error = "interrupted by a restart of the web app"
+		}
+		m.jobs[j.Id] = j
+	}
+	return m
+}
+
+// Start runs f in the background as a job of kind kind, and returns a copy
+// of the job. f must stop when ctx is cancelled. The job is done when f
+// returns without an error, and its result is the value returned by f.
+func (m *Manager) Start(kind string, f func(ctx context.Context, j *Job) (interface{}, error)) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &Job{Id: uuid.New().String(), Kind: kind, State: Running, Created: time.Now(), cancel: cancel}
+	m.mu.Lock()
+	m.jobs[j.Id] = j
+	c := j.copy()
+	m.persist()
+	m.mu.Unlock()
+	go func() {
+		defer cancel()
+		res, err := f(ctx, j)
+		m.finish(j, res, err, ctx.Err() != nil)
+	}()
+	return c
+}
+
+func (m *Manager) finish(j *Job, res interface{}, err error, cancelled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.Ended = time.Now()
+	switch {
+	case cancelled:
+		j.State = Cancelled
+	case err != nil:
+		j.State = Failed
+		j.Error = err.Error()
+	default:
+		j.State = Done
+		j.Result = res
+	}
+	for _, l := range j.listeners {
+		close(l)
+	}
+	j.listeners = nil
+	m.persist()
+}
+
+// Get returns a copy of job id.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return j.copy(), true
+}
+
+// List returns copies of the jobs, most recent first, without their logs.
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := []Job{}
+	for _, j := range m.jobs {
+		c := j.copy()
+		c.Log = nil
+		l = append(l, c)
+	}
+	sort.Slice(l, func(i, k int) bool { return l[i].Created.After(l[k].Created) })
+	return l
+}
+
+// Cancel cancels job id. It returns an error if the job doesn't exist or
+// isn't running.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return fmt.Errorf("job %s not found", id)
+	}
+	if j.State != Running {
+		return fmt.Errorf("job %s is %s", id, j.State)
+	}
+	j.cancel()
+	return nil
+}
+
+// Logf adds a line to the log of job j, and sends it to the listeners of
+// the job.
+func (m *Manager) Logf(j *Job, format string, a ...interface{}) {
+	line := fmt.Sprintf(format, a...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.Log = append(j.Log, line)
+	if len(j.Log) > maxLogLines {
+		j.Log = j.Log[len(j.Log)-maxLogLines:]
+	}
+	for _, l := range j.listeners {
+		select {
+		case l <- line:
+		default:
+			// Slow listeners miss lines rather than blocking the job.
+		}
+	}
+}
+
+// Output returns a file whose lines are added to the log of job j, for
+// functions that write their progress to an *os.File. The returned function
+// must be called once the file isn't used anymore.
+func (m *Manager) Output(j *Job) (*os.File, func(), error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			m.Logf(j, "%s", s.Text())
+		}
+		r.Close()
+	}()
+	return w, func() {
+		w.Close()
+		wg.Wait()
+	}, nil
+}
+
+// Listen returns the log of job id, and a channel which receives the lines
+// added to it until the job ends, if the job is running. The channel must be
+// released with Unlisten.
+func (m *Manager) Listen(id string) ([]string, chan string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("job %s not found", id)
+	}
+	lines := append([]string{}, j.Log...)
+	if j.State != Running {
+		return lines, nil, nil
+	}
+	l := make(chan string, 100)
+	j.listeners = append(j.listeners, l)
+	return lines, l, nil
+}
+
+// Unlisten stops sending the log of job id to l.
+func (m *Manager) Unlisten(id string, l chan string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return
+	}
+	for i, x := range j.listeners {
+		if x == l {
+			j.listeners = append(j.listeners[:i], j.listeners[i+1:]...)
+			close(l)
+			return
+		}
+	}
+}
+
+func (j *Job) copy() Job {
+	c := *j
+	c.Log = append([]string{}, j.Log...)
+	c.cancel = nil
+	c.listeners = nil
+	return c
+}
+
+// persist writes the state of jobs to m.path. m.mu must be held.
+func (m *Manager) persist() {
+	if m.path == "" {
+		return
+	}
+	var l []*Job
+	for _, j := range m.jobs {
+		l = append(l, j)
+	}
+	sort.Slice(l, func(i, k int) bool { return l[i].Created.Before(l[k].Created) })
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		log.Printf("Can't encode jobs: %v\n", err)
+		return
+	}
+	if err = ioutil.WriteFile(m.path, b, 0644); err != nil {
+		log.Printf("Can't write jobs file %s: %v\n", m.path, err)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// ListJobs returns the jobs, most recent first, without their logs.
+func (m *Manager) ListJobs(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(m.List())
+}
+
+// GetJob returns job {id}, including its log and its result if it is done.
+func (m *Manager) GetJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := m.Get(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, fmt.Sprintf("Job %s not found", mux.Vars(r)["id"]), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Job
+		Result interface{}
+	}{j, j.Result})
+}
+
+// CancelJob cancels running job {id}.
+func (m *Manager) CancelJob(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := m.Cancel(id); err != nil {
+		if _, ok := m.Get(id); !ok {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusConflict)
+		}
+		return
+	}
+	j, _ := m.Get(id)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(j)
+}
+
+// StreamLog streams the log of job {id} as server-sent events, one event
+// per line, until the job ends or the client goes away. An "end" event with
+// the state of the job is sent when the job ends.
+func (m *Manager) StreamLog(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	lines, l, err := m.Listen(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if l != nil {
+		defer m.Unlisten(id, l)
+	}
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	send := func(event, data string) {
+		if event != "" {
+			fmt.Fprintf(w, "event: %s\n", event)
+		}
+		for _, s := range strings.Split(data, "\n") {
+			fmt.Fprintf(w, "data: %s\n", s)
+		}
+		fmt.Fprint(w, "\n")
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	for _, line := range lines {
+		send("", line)
+	}
+	if l != nil {
+	loop:
+		for {
+			select {
+			case line, ok := <-l:
+				if !ok {
+					break loop
+				}
+				send("", line)
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+	j, _ := m.Get(id)
+	send("end", j.State)
+}
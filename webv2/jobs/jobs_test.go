@@ -0,0 +1,138 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// wait returns job id once it isn't running anymore.
+func wait(t *testing.T, m *Manager, id string) Job {
+	for i := 0; i < 500; i++ {
+		if j, _ := m.Get(id); j.State != Running {
+			return j
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s didn't end", id)
+	return Job{}
+}
+
+func TestJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	m := NewManager(path)
+
+	j := m.Start("conversion", func(ctx context.Context, j *Job) (interface{}, error) {
+		m.Logf(j, "converting %d tables", 2)
+		return 2, nil
+	})
+	assert.Equal(t, Running, j.State)
+	j = wait(t, m, j.Id)
+	assert.Equal(t, Done, j.State)
+	assert.Equal(t, 2, j.Result)
+	assert.Equal(t, []string{"converting 2 tables"}, j.Log)
+	assert.NotNil(t, m.Cancel(j.Id))
+
+	failed := m.Start("validation", func(ctx context.Context, j *Job) (interface{}, error) {
+		return nil, fmt.Errorf("invalid DDL")
+	})
+	failed = wait(t, m, failed.Id)
+	assert.Equal(t, Failed, failed.State)
+	assert.Equal(t, "invalid DDL", failed.Error)
+
+	started := make(chan bool)
+	running := m.Start("migration", func(ctx context.Context, j *Job) (interface{}, error) {
+		out, closeOut, err := m.Output(j)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(out, "Creating database")
+		closeOut()
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+	assert.Equal(t, 3, len(m.List()))
+	assert.Equal(t, running.Id, m.List()[0].Id)
+	assert.Nil(t, m.Cancel(running.Id))
+	running = wait(t, m, running.Id)
+	assert.Equal(t, Cancelled, running.State)
+	assert.Equal(t, []string{"Creating database"}, running.Log)
+
+	// Jobs are persisted without their result, and jobs that were running
+	// are failed when they are loaded.
+	interrupted := m.Start("migration", func(ctx context.Context, j *Job) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	m2 := NewManager(path)
+	assert.Equal(t, 4, len(m2.List()))
+	j2, ok := m2.Get(j.Id)
+	assert.True(t, ok)
+	assert.Equal(t, Done, j2.State)
+	assert.Nil(t, j2.Result)
+	j2, _ = m2.Get(interrupted.Id)
+	assert.Equal(t, Failed, j2.State)
+	assert.Nil(t, m.Cancel(interrupted.Id))
+	wait(t, m, interrupted.Id)
+}
+
+func TestStreamLog(t *testing.T) {
+	m := NewManager("")
+	proceed := make(chan bool)
+	j := m.Start("migration", func(ctx context.Context, j *Job) (interface{}, error) {
+		m.Logf(j, "Creating database")
+		<-proceed
+		m.Logf(j, "Migrating table users")
+		return nil, nil
+	})
+	for {
+		if j, _ := m.Get(j.Id); len(j.Log) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/jobs/{id}/logs", m.StreamLog)
+	req, err := http.NewRequest("GET", "/jobs/"+j.Id+"/logs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	go func() {
+		// Let the handler send the existing log before the job proceeds.
+		time.Sleep(50 * time.Millisecond)
+		close(proceed)
+	}()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "data: Creating database\n\ndata: Migrating table users\n\nevent: end\ndata: done\n\n", rr.Body.String())
+
+	req, _ = http.NewRequest("GET", "/jobs/unknown/logs", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,328 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webv2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/config"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/jobs"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+)
+
+// Migration modes of MigrationRequest.
+const (
+	migrateSchema        = "schema"
+	migrateSchemaAndData = "schema-and-data"
+)
+
+// States of a migration and of its tables.
+const (
+	migrationQueued    = "queued"
+	migrationRunning   = "running"
+	migrationFailed    = "failed"
+	migrationCancelled = "cancelled"
+	migrationDone      = "done"
+)
+
+// migrationWriteLimit is the number of parallel writers to Spanner used by
+// data migrations, as for the command line tool.
+const migrationWriteLimit = 40
+
+// spannerDbName matches valid Spanner database names.
+var spannerDbName = regexp.MustCompile("^[a-z][a-z0-9_-]{0,28}[a-z0-9]$")
+
+// MigrationRequest is the payload of migrate.
+type MigrationRequest struct {
+	Database string `json:"Database"` // Name of the Spanner database, which is created if it doesn't exist.
+	Mode     string `json:"Mode"`     // One of migrateSchema and migrateSchemaAndData.
+	Force    bool   `json:"Force"`    // If true, take over the leases other migrations hold on the tables being migrated.
+}
+
+// MigrationStatus is the status of the migration started by migrate.
+type MigrationStatus struct {
+	State    string        // One of migrationRunning, migrationFailed, migrationCancelled and migrationDone. Empty if no migration was started.
+	JobId    string        // Id of the job running the migration (see jobs.Manager).
+	Database string        // URI of the Spanner database.
+	Mode     string        // Mode of the migration.
+	Error    string        // Why the migration failed.
+	Tables   []TableStatus // Status of each Spanner table, in load order.
+}
+
+// TableStatus is the status of the migration of a Spanner table.
+type TableStatus struct {
+	Table    string
+	State    string // One of migrationQueued, migrationRunning, migrationFailed, migrationCancelled and migrationDone.
+	Rows     int64  // Count of source rows.
+	GoodRows int64  // Count of rows converted successfully.
+	BadRows  int64  // Count of rows that couldn't be converted or written.
+	Error    string
+}
+
+// migrationJob is the migration started by migrate. There is at most one
+// running migration at a time.
+var migrationJob struct {
+	sync.Mutex
+	status MigrationStatus
+}
+
+// migrate applies the schema of the session to a Spanner database, and
+// optionally migrates the data of the source database, in a background job.
+// The schema can't be applied until every schema issue of the review queue
+// is reviewed. Progress is reported by getMigrationStatus, and the job's log.
+func migrate(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req MigrationRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Mode != migrateSchema && req.Mode != migrateSchemaAndData {
+		http.Error(w, fmt.Sprintf("unknown migration mode '%s': available choices(%s, %s)", req.Mode, migrateSchema, migrateSchemaAndData), http.StatusBadRequest)
+		return
+	}
+	if !spannerDbName.MatchString(req.Database) {
+		http.Error(w, fmt.Sprintf("'%s' is not a valid Spanner database name", req.Database), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	if n := len(sessionState.Conv.UnreviewedIssues()); n > 0 {
+		http.Error(w, fmt.Sprintf("%d schema issues must be reviewed before the schema is applied", n), http.StatusPreconditionFailed)
+		return
+	}
+	var infoSchema common.InfoSchema
+	if req.Mode == migrateSchemaAndData {
+		if sessionState.SourceDB == nil {
+			http.Error(w, "Data can only be migrated from a direct connection to the source database", http.StatusBadRequest)
+			return
+		}
+		if infoSchema, err = getInfoSchema(sessionState); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	spConfig, err := config.GetSpannerConfig()
+	if err != nil || spConfig.GCPProjectID == "" || spConfig.SpannerInstanceID == "" {
+		http.Error(w, "Spanner project and instance are not configured", http.StatusBadRequest)
+		return
+	}
+	// The migration works on a copy of the session's conv, so that the schema
+	// can be edited while data is migrated.
+	conv, err := copyConv(sessionState.Conv)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't copy session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// Each migration takes its own leases on the tables it migrates.
+	conv.Audit.MigrationRequestId = "HB-" + uuid.New().String()
+	conv.ForceLock = req.Force
+	dbURI := fmt.Sprintf("projects/%s/instances/%s/databases/%s", spConfig.GCPProjectID, spConfig.SpannerInstanceID, req.Database)
+
+	migrationJob.Lock()
+	if migrationJob.status.State == migrationRunning {
+		migrationJob.Unlock()
+		http.Error(w, fmt.Sprintf("A migration to %s is already running", migrationJob.status.Database), http.StatusConflict)
+		return
+	}
+	migrationJob.status = MigrationStatus{State: migrationRunning, Database: dbURI, Mode: req.Mode}
+	for _, level := range ddl.LoadOrder(conv.SpSchema) {
+		for _, t := range level {
+			migrationJob.status.Tables = append(migrationJob.status.Tables, TableStatus{Table: t, State: migrationQueued})
+		}
+	}
+	j := jobManager.Start("migration", func(ctx context.Context, j *jobs.Job) (interface{}, error) {
+		err := runMigration(ctx, j, conv, sessionState.Driver, dbURI, infoSchema)
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		finishMigration(err)
+		return nil, err
+	})
+	migrationJob.status.JobId = j.Id
+	status := getMigrationJobStatus()
+	migrationJob.Unlock()
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// getMigrationStatus returns the status of the last migration started by
+// migrate.
+func getMigrationStatus(w http.ResponseWriter, r *http.Request) {
+	migrationJob.Lock()
+	status := getMigrationJobStatus()
+	migrationJob.Unlock()
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// getMigrationJobStatus returns a copy of the status of migrationJob, which
+// must be locked.
+func getMigrationJobStatus() MigrationStatus {
+	status := migrationJob.status
+	status.Tables = append([]TableStatus{}, status.Tables...)
+	return status
+}
+
+// updateTableStatus applies f to the status of Spanner table spTable.
+func updateTableStatus(spTable string, f func(ts *TableStatus)) {
+	migrationJob.Lock()
+	defer migrationJob.Unlock()
+	for i := range migrationJob.status.Tables {
+		if migrationJob.status.Tables[i].Table == spTable {
+			f(&migrationJob.status.Tables[i])
+		}
+	}
+}
+
+// finishMigration records the end of the migration. If err is set, tables
+// that aren't done are marked as failed, or as cancelled if the migration
+// was cancelled.
+func finishMigration(err error) {
+	migrationJob.Lock()
+	defer migrationJob.Unlock()
+	if err == nil {
+		migrationJob.status.State = migrationDone
+		return
+	}
+	state := migrationFailed
+	if err == context.Canceled {
+		state = migrationCancelled
+	}
+	migrationJob.status.State = state
+	migrationJob.status.Error = err.Error()
+	for i := range migrationJob.status.Tables {
+		if migrationJob.status.Tables[i].State != migrationDone {
+			migrationJob.status.Tables[i].State = state
+		}
+	}
+}
+
+// runMigration creates or updates Spanner database dbURI with the schema of
+// conv, migrates the data of infoSchema (if set), and then adds foreign keys.
+// Progress is written to the log of job j.
+func runMigration(ctx context.Context, j *jobs.Job, conv *internal.Conv, driver, dbURI string, infoSchema common.InfoSchema) error {
+	out, closeOut, err := jobManager.Output(j)
+	if err != nil {
+		return err
+	}
+	defer closeOut()
+	adminClient, err := utils.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return fmt.Errorf("can't create admin client: %v", err)
+	}
+	defer adminClient.Close()
+	jobManager.Logf(j, "Applying schema to %s", dbURI)
+	if err = conversion.CreateOrUpdateDatabase(ctx, adminClient, dbURI, driver, conv.TargetDb, conv, out); err != nil {
+		return err
+	}
+	if infoSchema == nil {
+		for _, t := range ddl.OrderTables(conv.SpSchema) {
+			updateTableStatus(t, func(ts *TableStatus) { ts.State = migrationDone })
+		}
+	} else if err = migrateData(ctx, j, conv, dbURI, infoSchema); err != nil {
+		return err
+	}
+	jobManager.Logf(j, "Adding foreign keys")
+	if err = conversion.UpdateDDLForeignKeys(ctx, adminClient, dbURI, conv, out); err != nil {
+		return fmt.Errorf("can't add foreign keys: %v", err)
+	}
+	return nil
+}
+
+// migrateData migrates the data of infoSchema to Spanner database dbURI,
+// updating the status of each table as it is migrated. The migration stops
+// before the next table when ctx is cancelled.
+func migrateData(ctx context.Context, j *jobs.Job, conv *internal.Conv, dbURI string, infoSchema common.InfoSchema) error {
+	client, err := utils.GetClient(ctx, dbURI)
+	if err != nil {
+		return fmt.Errorf("can't create client for db %s: %v", dbURI, err)
+	}
+	defer client.Close()
+	conv.SetDataMode()
+	var tableErr error
+	bw, err := conversion.SnapshotMigrationWithProgress(ctx, conv, client, infoSchema, migrationWriteLimit, func(spTable string, done bool, err error) error {
+		srcTable, _ := internal.GetSourceTable(conv, spTable)
+		rows, goodRows, badRows := conv.Stats.Rows[srcTable], conv.Stats.GoodRows[srcTable], conv.Stats.BadRows[srcTable]
+		updateTableStatus(spTable, func(ts *TableStatus) {
+			ts.Rows, ts.GoodRows, ts.BadRows = rows, goodRows, badRows
+			switch {
+			case !done:
+				ts.State = migrationRunning
+			case err != nil:
+				ts.State = migrationFailed
+				ts.Error = err.Error()
+			default:
+				ts.State = migrationDone
+			}
+		})
+		switch {
+		case !done:
+			jobManager.Logf(j, "Migrating table %s", spTable)
+		case err != nil:
+			jobManager.Logf(j, "Can't migrate table %s: %v", spTable, err)
+		default:
+			jobManager.Logf(j, "Migrated table %s: %d rows, %d bad rows", spTable, goodRows, badRows)
+		}
+		if err != nil && tableErr == nil {
+			tableErr = fmt.Errorf("can't migrate table %s: %v", spTable, err)
+		}
+		return nil
+	})
+	if bw == nil {
+		return err
+	}
+	for t, n := range bw.DroppedRowsByTable() {
+		updateTableStatus(t, func(ts *TableStatus) { ts.BadRows += n })
+	}
+	if tableErr != nil {
+		return tableErr
+	}
+	return err
+}
+
+// copyConv returns a deep copy of conv, without its data conversion state.
+func copyConv(conv *internal.Conv) (*internal.Conv, error) {
+	b, err := json.Marshal(conv)
+	if err != nil {
+		return nil, err
+	}
+	c := internal.MakeConv()
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c.SampleConv(), nil
+}
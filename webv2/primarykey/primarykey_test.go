@@ -17,10 +17,13 @@ package primarykey
 import (
 	"bytes"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
@@ -28,6 +31,19 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// TestMain writes the files generated by the handlers (session files,
+// reports and schemas) to a temporary directory rather than the source tree.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "harbour_bridge_output")
+	if err != nil {
+		panic(err)
+	}
+	conversion.OutputDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestUpdatePrimaryKey(t *testing.T) {
 
 	sessionState := session.GetSessionState()
@@ -15,6 +15,7 @@
 package webv2
 
 import (
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/auth"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/config"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/primarykey"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
@@ -25,11 +26,17 @@ import (
 
 func getRoutes() *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/drivers", getSourceDrivers).Methods("GET")
 	router.HandleFunc("/connect", databaseConnection).Methods("POST")
 	router.HandleFunc("/convert/infoschema", convertSchemaSQL).Methods("GET")
 	router.HandleFunc("/convert/dump", convertSchemaDump).Methods("POST")
 	router.HandleFunc("/convert/session", loadSession).Methods("POST")
+	router.HandleFunc("/convert/spanner/ddl", convertSpannerDDL).Methods("POST")
+	router.HandleFunc("/convert/spanner/database", convertSpannerDatabase).Methods("POST")
 	router.HandleFunc("/ddl", getDDL).Methods("GET")
+	router.HandleFunc("/ddl/preview", getDDLPreview).Methods("GET")
+	router.HandleFunc("/ddl/edit", editDDL).Methods("POST")
+	router.HandleFunc("/ddl/validate", validateDDL).Methods("POST")
 	router.HandleFunc("/overview", getOverview).Methods("GET")
 	router.HandleFunc("/conversion", getConversionRate).Methods("GET")
 	router.HandleFunc("/typemap", getTypeMap).Methods("GET")
@@ -37,18 +44,45 @@ func getRoutes() *mux.Router {
 	router.HandleFunc("/schema", getSchemaFile).Methods("GET")
 	router.HandleFunc("/typemap/global", setTypeMapGlobal).Methods("POST")
 	router.HandleFunc("/typemap/table", updateTableSchema).Methods("POST")
+	router.HandleFunc("/typemap/columns", setColumnsType).Methods("POST")
+	router.HandleFunc("/typemap/presets", getTypeMapPresets).Methods("GET")
+	router.HandleFunc("/typemap/presets", saveTypeMapPreset).Methods("POST")
+	router.HandleFunc("/typemap/presets/{name}", deleteTypeMapPreset).Methods("DELETE")
+	router.HandleFunc("/typemap/presets/{name}/apply", applyTypeMapPreset).Methods("POST")
+	router.HandleFunc("/search", search).Methods("POST")
 	router.HandleFunc("/setparent", setParentTable).Methods("GET")
+	router.HandleFunc("/graph", getSchemaGraph).Methods("GET")
+	router.HandleFunc("/preview", getDataPreview).Methods("GET")
+	router.HandleFunc("/review", getReviewQueue).Methods("GET")
+	router.HandleFunc("/review", reviewIssue).Methods("POST")
+	router.HandleFunc("/migrate", migrate).Methods("POST")
+	router.HandleFunc("/migrate/status", getMigrationStatus).Methods("GET")
+
+	// Background jobs
+	router.HandleFunc("/jobs", jobManager.ListJobs).Methods("GET")
+	router.HandleFunc("/jobs/{id}", jobManager.GetJob).Methods("GET")
+	router.HandleFunc("/jobs/{id}/cancel", jobManager.CancelJob).Methods("POST")
+	router.HandleFunc("/jobs/{id}/logs", jobManager.StreamLog).Methods("GET")
 
 	// TODO:(searce) take constraint names themselves which are guaranteed to be unique for Spanner.
 	router.HandleFunc("/drop/fk", dropForeignKey).Methods("POST")
+	router.HandleFunc("/fk/enforcement", setForeignKeyEnforcement).Methods("POST")
+	router.HandleFunc("/fk/cycles", getFkCycles).Methods("GET")
+	router.HandleFunc("/fk/cycle", resolveFkCycle).Methods("POST")
 
 	// TODO:(searce) take constraint names themselves which are guaranteed to be unique for Spanner.
 	router.HandleFunc("/drop/secondaryindex", dropSecondaryIndex).Methods("POST")
 
 	router.HandleFunc("/rename/fks", renameForeignKeys).Methods("POST")
 	router.HandleFunc("/rename/indexes", renameIndexes).Methods("POST")
+	router.HandleFunc("/rename/table", renameTable).Methods("POST")
 	router.HandleFunc("/add/indexes", addIndexes).Methods("POST")
 	router.HandleFunc("/update/indexes", updateIndexes).Methods("POST")
+	router.HandleFunc("/keystrategy", setKeyStrategy).Methods("POST")
+	router.HandleFunc("/split", splitTable).Methods("POST")
+	router.HandleFunc("/nested", nestColumns).Methods("POST")
+	router.HandleFunc("/synthetickey", setSyntheticKey).Methods("POST")
+	router.HandleFunc("/timezone", setDatetimeZone).Methods("POST")
 
 	// Session Management
 	router.HandleFunc("/IsOffline", session.IsOfflineSession).Methods("GET")
@@ -57,6 +91,10 @@ func getRoutes() *mux.Router {
 	router.HandleFunc("/GetSession/{versionId}", session.GetConv).Methods("GET")
 	router.HandleFunc("/SaveRemoteSession", session.SaveRemoteSession).Methods("POST")
 	router.HandleFunc("/ResumeSession/{versionId}", session.ResumeSession).Methods("POST")
+	router.HandleFunc("/session/export", exportSession).Methods("GET")
+	router.HandleFunc("/session/import", importSession).Methods("POST")
+	router.HandleFunc("/session/snapshots", snapshotter.ListSnapshots).Methods("GET")
+	router.HandleFunc("/session/snapshots", snapshotter.TakeSnapshot).Methods("POST")
 
 	// primarykey
 	router.HandleFunc("/primaryKey", primarykey.PrimaryKey).Methods("POST")
@@ -68,5 +106,8 @@ func getRoutes() *mux.Router {
 	router.HandleFunc("/GetConfig", config.GetConfig).Methods("GET")
 	router.HandleFunc("/SetSpannerConfig", config.SetSpannerConfig).Methods("POST")
 
+	// Authentication
+	router.HandleFunc("/auth/whoami", auth.WhoAmI).Methods("GET")
+
 	return router
 }
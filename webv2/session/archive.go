@@ -0,0 +1,185 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+)
+
+// ArchiveVersion is the version of the format of session archives. It is
+// incremented when the format changes in a way that older versions of
+// HarbourBridge can't read.
+const ArchiveVersion = 1
+
+// Files of a session archive.
+const (
+	archiveManifest = "manifest.json"
+	archiveConv     = "conv.json"
+	archiveHistory  = "history.json"
+	// archiveDdlEdits holds the manual edits of the DDL as a unified diff,
+	// for reading. Edits are restored from the conv.
+	archiveDdlEdits = "ddl_edits.diff"
+)
+
+// Archive is an in-progress conversion session, exported as a single zip
+// file so that it can be handed off to another HarbourBridge instance.
+type Archive struct {
+	Version int
+	Created time.Time
+	Driver  string
+	SessionMetadata
+	Conv *internal.Conv `json:"-"`
+	// History lists the saved versions of the session, oldest first, without
+	// their conv.
+	History []SchemaConversionSession `json:"-"`
+}
+
+// WriteArchive writes a to w as a zip file.
+func WriteArchive(w io.Writer, a Archive) error {
+	zw := zip.NewWriter(w)
+	add := func(name string, v interface{}) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if s, ok := v.(string); ok {
+			_, err = io.WriteString(f, s)
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	history := []SchemaConversionSession{}
+	for _, s := range a.History {
+		s.SchemaConversionObject = ""
+		history = append(history, s)
+	}
+	if err := add(archiveManifest, a); err != nil {
+		return fmt.Errorf("can't write archive manifest: %v", err)
+	}
+	if err := add(archiveConv, a.Conv); err != nil {
+		return fmt.Errorf("can't write archive conv: %v", err)
+	}
+	if err := add(archiveHistory, history); err != nil {
+		return fmt.Errorf("can't write archive history: %v", err)
+	}
+	if err := add(archiveDdlEdits, ddlEditsDiff(a.Conv)); err != nil {
+		return fmt.Errorf("can't write archive DDL edits: %v", err)
+	}
+	return zw.Close()
+}
+
+// ReadArchive reads an archive written by WriteArchive.
+func ReadArchive(b []byte) (Archive, error) {
+	var a Archive
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return a, fmt.Errorf("not a session archive: %v", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	read := func(name string, v interface{}) error {
+		f, ok := files[name]
+		if !ok {
+			return fmt.Errorf("session archive has no %s", name)
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(b, v); err != nil {
+			return fmt.Errorf("can't parse %s of session archive: %v", name, err)
+		}
+		return nil
+	}
+	if err = read(archiveManifest, &a); err != nil {
+		return a, err
+	}
+	if a.Version > ArchiveVersion {
+		return a, fmt.Errorf("session archive version %d is newer than the supported version %d", a.Version, ArchiveVersion)
+	}
+	a.Conv = internal.MakeConv()
+	if err = read(archiveConv, a.Conv); err != nil {
+		return a, err
+	}
+	if err = read(archiveHistory, &a.History); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// GetSessionHistory returns the saved versions of session sessionName,
+// oldest first.
+func GetSessionHistory(sessionName string) ([]SchemaConversionSession, error) {
+	var sessions []SchemaConversionSession
+	var err error
+	if GetSessionState().IsOffline {
+		sessions, err = getLocalSessions()
+	} else {
+		sessions, err = getRemoteSessions()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []SchemaConversionSession
+	for _, s := range sessions {
+		if s.SessionName == sessionName {
+			history = append(history, s)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].CreateTimestamp.Before(history[j].CreateTimestamp) })
+	return history, nil
+}
+
+// ddlEditsDiff returns the manual edits of the DDL of conv as a unified
+// diff, with one file per edited table.
+func ddlEditsDiff(conv *internal.Conv) string {
+	var tables []string
+	for t := range conv.DdlEdits {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	var b strings.Builder
+	for _, t := range tables {
+		from := strings.Split(strings.Join(conv.DdlEdits[t].Generated, ";\n")+";", "\n")
+		to := strings.Split(strings.Join(conv.DdlEdits[t].Edited, ";\n")+";", "\n")
+		fmt.Fprintf(&b, "--- generated/%s.ddl\n+++ edited/%s.ddl\n@@ -1,%d +1,%d @@\n", t, t, len(from), len(to))
+		for _, l := range from {
+			fmt.Fprintf(&b, "-%s\n", l)
+		}
+		for _, l := range to {
+			fmt.Fprintf(&b, "+%s\n", l)
+		}
+	}
+	return b.String()
+}
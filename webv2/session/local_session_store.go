@@ -18,9 +18,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-)
 
-const hbOutputDirPath string = "harbour_bridge_output"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
+)
 
 type localStore struct {
 	sessions []SchemaConversionSession
@@ -85,6 +85,16 @@ func (st *localStore) IsSessionNameUnique(ctx context.Context, scs SchemaConvers
 	return true, nil
 }
 
+func (st *localStore) DeleteSession(ctx context.Context, versionId string) error {
+	for i, s := range st.sessions {
+		if s.VersionId == versionId {
+			st.sessions = append(st.sessions[:i], st.sessions[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("No session found in local")
+}
+
 func getSessionFilePath(dbName string) string {
-	return fmt.Sprintf("%s/%s/%s.session.json", hbOutputDirPath, dbName, dbName)
+	return fmt.Sprintf("%s/%s/%s.session.json", conversion.OutputDir, dbName, dbName)
 }
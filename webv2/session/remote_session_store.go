@@ -166,3 +166,8 @@ func (st *spannerStore) IsSessionNameUnique(ctx context.Context, scs SchemaConve
 	}
 	return false, err
 }
+
+func (st *spannerStore) DeleteSession(ctx context.Context, versionId string) error {
+	_, err := st.spannerClient.Apply(ctx, []*spanner.Mutation{spanner.Delete("SchemaConversionSession", spanner.Key{versionId})})
+	return err
+}
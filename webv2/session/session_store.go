@@ -7,4 +7,5 @@ type SessionStore interface {
 	GetConvWithMetadata(ctx context.Context, versionId string) (ConvWithMetadata, error)
 	SaveSession(ctx context.Context, scs SchemaConversionSession) error
 	IsSessionNameUnique(ctx context.Context, scs SchemaConversionSession) (bool, error)
+	DeleteSession(ctx context.Context, versionId string) error
 }
@@ -1,14 +1,21 @@
 package session_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+	"github.com/stretchr/testify/assert"
 )
 
 func getTestData() []session.SchemaConversionSession {
@@ -168,3 +175,140 @@ func TestSaveSession(t *testing.T) {
 		t.Errorf("Expected: %d, got: %d", expect, got)
 	}
 }
+
+func TestArchive(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.TargetDb = "spanner"
+	conv.DdlEdits = map[string]internal.DdlEdit{
+		"users": {Generated: []string{"CREATE TABLE users (\n  id INT64\n) PRIMARY KEY (id)"}, Edited: []string{"CREATE TABLE users (\n  id INT64 NOT NULL\n) PRIMARY KEY (id)"}},
+	}
+	history := getTestData()[:1]
+	a := session.Archive{
+		Version:         session.ArchiveVersion,
+		Created:         time.Now(),
+		Driver:          "mysql",
+		SessionMetadata: session.SessionMetadata{SessionName: "session-1", DatabaseName: "BikeStore"},
+		Conv:            conv,
+		History:         history,
+	}
+	var b bytes.Buffer
+	if err := session.WriteArchive(&b, a); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := session.ReadArchive(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "mysql", got.Driver)
+	assert.Equal(t, a.SessionMetadata, got.SessionMetadata)
+	assert.Equal(t, conv.DdlEdits, got.Conv.DdlEdits)
+	assert.Equal(t, 1, len(got.History))
+	assert.Equal(t, "v1", got.History[0].VersionId)
+	assert.Equal(t, "", got.History[0].SchemaConversionObject)
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "ddl_edits.diff" {
+			continue
+		}
+		r, _ := f.Open()
+		diff, _ := ioutil.ReadAll(r)
+		assert.Equal(t, `--- generated/users.ddl
++++ edited/users.ddl
+@@ -1,3 +1,3 @@
+-CREATE TABLE users (
+-  id INT64
+-) PRIMARY KEY (id);
++CREATE TABLE users (
++  id INT64 NOT NULL
++) PRIMARY KEY (id);
+`, string(diff))
+	}
+
+	_, err = session.ReadArchive([]byte("not a zip file"))
+	assert.NotNil(t, err)
+	a.Version = session.ArchiveVersion + 1
+	b.Reset()
+	session.WriteArchive(&b, a)
+	_, err = session.ReadArchive(b.Bytes())
+	assert.NotNil(t, err)
+}
+
+func TestGetSessionHistory(t *testing.T) {
+	session.GetSessionState().IsOffline = true
+	history, err := session.GetSessionHistory("session-2")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(history))
+	assert.Equal(t, "v2", history[0].VersionId)
+}
+
+func TestSnapshots(t *testing.T) {
+	sessionState := session.GetSessionState()
+	sessionState.IsOffline = true
+	sessionState.Driver = "mysql"
+	sessionState.SessionMetadata = session.SessionMetadata{SessionName: "snap-session", DatabaseName: "orders"}
+	sessionState.Conv = internal.MakeConv()
+	sessionState.Conv.SpSchema["users"] = ddl.CreateTable{Name: "users"}
+	s := session.NewSnapshotter(session.SnapshotPolicy{KeepLast: 2})
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		sessionState.Conv.SpSchema["users"] = ddl.CreateTable{Name: "users", Comment: fmt.Sprintf("edit %d", i)}
+		scs, saved, err := s.Snapshot(context.Background())
+		assert.Nil(t, err)
+		assert.True(t, saved)
+		assert.Equal(t, []string{session.SnapshotTag}, scs.Tags)
+		ids = append(ids, scs.VersionId)
+	}
+	// The session didn't change since the last snapshot.
+	_, saved, err := s.Snapshot(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, saved)
+
+	// Only the last 2 snapshots are kept.
+	st := session.NewLocalSessionStore()
+	var kept []string
+	sessions, _ := st.GetSessionsMetadata(nil)
+	for _, scs := range sessions {
+		if scs.SessionName == "snap-session" {
+			kept = append(kept, scs.VersionId)
+		}
+	}
+	assert.Equal(t, ids[1:], kept)
+	convm, err := st.GetConvWithMetadata(nil, ids[2])
+	assert.Nil(t, err)
+	assert.Equal(t, "edit 2", convm.Conv.SpSchema["users"].Comment)
+
+	sm := session.SessionMetadata{SessionName: "snap-session", DatabaseName: "orders", DatabaseType: "mysql"}
+	assert.Nil(t, session.PruneSnapshots(context.Background(), st, sm, session.SnapshotPolicy{MaxAge: time.Hour}, time.Now().Add(2*time.Hour)))
+	sessions, _ = st.GetSessionsMetadata(nil)
+	for _, scs := range sessions {
+		assert.NotEqual(t, "snap-session", scs.SessionName)
+	}
+}
+
+func TestSnapshotPolicyFromEnv(t *testing.T) {
+	p, err := session.SnapshotPolicyFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, session.DefaultSnapshotPolicy, p)
+
+	os.Setenv("HB_SNAPSHOT_INTERVAL", "1m")
+	os.Setenv("HB_SNAPSHOT_KEEP", "3")
+	os.Setenv("HB_SNAPSHOT_MAX_AGE", "24h")
+	defer func() {
+		os.Unsetenv("HB_SNAPSHOT_INTERVAL")
+		os.Unsetenv("HB_SNAPSHOT_KEEP")
+		os.Unsetenv("HB_SNAPSHOT_MAX_AGE")
+	}()
+	p, err = session.SnapshotPolicyFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, session.SnapshotPolicy{Interval: time.Minute, KeepLast: 3, MaxAge: 24 * time.Hour}, p)
+
+	os.Setenv("HB_SNAPSHOT_KEEP", "all")
+	_, err = session.SnapshotPolicyFromEnv()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,262 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/uuid"
+)
+
+// SnapshotTag tags the sessions saved as snapshots.
+const SnapshotTag = "snapshot"
+
+// SnapshotPolicy configures automatic snapshots of the session, which
+// protect manual schema edits from browser and server crashes.
+type SnapshotPolicy struct {
+	Interval time.Duration // Minimum time between automatic snapshots. Zero disables them.
+	KeepLast int           // Number of snapshots kept per session. Zero keeps all of them.
+	MaxAge   time.Duration // Snapshots older than MaxAge are deleted. Zero keeps them regardless of age.
+}
+
+// DefaultSnapshotPolicy snapshots the session every 5 minutes, and keeps
+// the last 10 snapshots.
+var DefaultSnapshotPolicy = SnapshotPolicy{Interval: 5 * time.Minute, KeepLast: 10}
+
+// SnapshotPolicyFromEnv returns DefaultSnapshotPolicy, overridden by
+// environment variables HB_SNAPSHOT_INTERVAL and HB_SNAPSHOT_MAX_AGE
+// (durations e.g. "10m" or "168h") and HB_SNAPSHOT_KEEP (a number), since
+// the web app doesn't use command line flags.
+func SnapshotPolicyFromEnv() (SnapshotPolicy, error) {
+	p := DefaultSnapshotPolicy
+	var err error
+	if s := os.Getenv("HB_SNAPSHOT_INTERVAL"); s != "" {
+		if p.Interval, err = time.ParseDuration(s); err != nil || p.Interval < 0 {
+			return p, fmt.Errorf("can't parse HB_SNAPSHOT_INTERVAL '%s': expected a duration e.g. 10m", s)
+		}
+	}
+	if s := os.Getenv("HB_SNAPSHOT_KEEP"); s != "" {
+		if p.KeepLast, err = strconv.Atoi(s); err != nil || p.KeepLast < 0 {
+			return p, fmt.Errorf("can't parse HB_SNAPSHOT_KEEP '%s': expected a number", s)
+		}
+	}
+	if s := os.Getenv("HB_SNAPSHOT_MAX_AGE"); s != "" {
+		if p.MaxAge, err = time.ParseDuration(s); err != nil || p.MaxAge < 0 {
+			return p, fmt.Errorf("can't parse HB_SNAPSHOT_MAX_AGE '%s': expected a duration e.g. 168h", s)
+		}
+	}
+	return p, nil
+}
+
+// Snapshotter saves snapshots of the session to the session store, and
+// prunes old snapshots according to its policy.
+type Snapshotter struct {
+	Policy SnapshotPolicy
+
+	mu       sync.Mutex
+	last     time.Time // Time of the last snapshot.
+	lastConv []byte    // Conv of the last snapshot, in JSON.
+	lastId   string    // VersionId of the last snapshot.
+}
+
+// NewSnapshotter returns a Snapshotter with policy p.
+func NewSnapshotter(p SnapshotPolicy) *Snapshotter {
+	return &Snapshotter{Policy: p}
+}
+
+// Snapshot saves a snapshot of the session, unless the conv didn't change
+// since the last snapshot. It returns the snapshot, and false if none was
+// saved.
+func (s *Snapshotter) Snapshot(ctx context.Context) (SchemaConversionSession, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessionState := GetSessionState()
+	if sessionState.Conv == nil || len(sessionState.Conv.SpSchema) == 0 {
+		return SchemaConversionSession{}, false, nil
+	}
+	conv, err := json.Marshal(sessionState.Conv)
+	if err != nil {
+		return SchemaConversionSession{}, false, fmt.Errorf("can't encode conv: %v", err)
+	}
+	if bytes.Equal(conv, s.lastConv) {
+		return SchemaConversionSession{}, false, nil
+	}
+	now := time.Now()
+	scs := SchemaConversionSession{
+		VersionId:              uuid.New().String(),
+		PreviousVersionId:      []string{},
+		SchemaConversionObject: string(conv),
+		CreateTimestamp:        now,
+		SessionMetadata: SessionMetadata{
+			SessionName:  sessionState.SessionMetadata.SessionName,
+			EditorName:   sessionState.SessionMetadata.EditorName,
+			DatabaseType: sessionState.Driver,
+			DatabaseName: sessionState.SessionMetadata.DatabaseName,
+			Notes:        []string{"Automatic snapshot"},
+			Tags:         []string{SnapshotTag},
+		},
+	}
+	if s.lastId != "" {
+		scs.PreviousVersionId = []string{s.lastId}
+	}
+	store, closeStore, err := getSessionStore(ctx)
+	if err != nil {
+		return SchemaConversionSession{}, false, err
+	}
+	defer closeStore()
+	if err = store.SaveSession(ctx, scs); err != nil {
+		return SchemaConversionSession{}, false, fmt.Errorf("can't save snapshot: %v", err)
+	}
+	s.last, s.lastConv, s.lastId = now, conv, scs.VersionId
+	if err = PruneSnapshots(ctx, store, scs.SessionMetadata, s.Policy, now); err != nil {
+		return scs, true, fmt.Errorf("can't prune snapshots: %v", err)
+	}
+	return scs, true, nil
+}
+
+// Middleware takes a snapshot after requests that change the session, at
+// most once per Policy.Interval. Snapshots are taken in the request rather
+// than by a timer, so that the conv isn't read while it is being changed,
+// and so that an idle session isn't snapshotted.
+func (s *Snapshotter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if s.Policy.Interval == 0 || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			return
+		}
+		s.mu.Lock()
+		due := time.Since(s.last) >= s.Policy.Interval
+		s.mu.Unlock()
+		if !due {
+			return
+		}
+		if _, _, err := s.Snapshot(context.Background()); err != nil {
+			log.Printf("Can't snapshot session: %v\n", err)
+		}
+	})
+}
+
+// PruneSnapshots deletes the snapshots of session sm that policy p doesn't
+// keep at time now.
+func PruneSnapshots(ctx context.Context, store SessionStore, sm SessionMetadata, p SnapshotPolicy, now time.Time) error {
+	snapshots, err := getSnapshots(ctx, store, sm)
+	if err != nil {
+		return err
+	}
+	for i, scs := range snapshots {
+		if (p.KeepLast > 0 && i >= p.KeepLast) || (p.MaxAge > 0 && now.Sub(scs.CreateTimestamp) > p.MaxAge) {
+			if err = store.DeleteSession(ctx, scs.VersionId); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListSnapshots returns the snapshots of the current session, most recent
+// first. A snapshot is restored with ResumeSession.
+func (s *Snapshotter) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	store, closeStore, err := getSessionStore(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer closeStore()
+	sessionState := GetSessionState()
+	sm := sessionState.SessionMetadata
+	sm.DatabaseType = sessionState.Driver
+	snapshots, err := getSnapshots(ctx, store, sm)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't read snapshots : %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(snapshots)
+}
+
+// TakeSnapshot takes a snapshot of the current session now.
+func (s *Snapshotter) TakeSnapshot(w http.ResponseWriter, r *http.Request) {
+	if GetSessionState().Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	scs, saved, err := s.Snapshot(context.Background())
+	if err != nil && !saved {
+		http.Error(w, fmt.Sprintf("Can't snapshot session : %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !saved {
+		// The session didn't change since the last snapshot.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	scs.SchemaConversionObject = ""
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scs)
+}
+
+// getSnapshots returns the snapshots of session sm, most recent first,
+// without their conv.
+func getSnapshots(ctx context.Context, store SessionStore, sm SessionMetadata) ([]SchemaConversionSession, error) {
+	sessions, err := store.GetSessionsMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []SchemaConversionSession{}
+	for _, scs := range sessions {
+		if scs.SessionName != sm.SessionName || scs.DatabaseName != sm.DatabaseName || scs.DatabaseType != sm.DatabaseType || !isSnapshot(scs) {
+			continue
+		}
+		scs.SchemaConversionObject = ""
+		snapshots = append(snapshots, scs)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].CreateTimestamp.After(snapshots[j].CreateTimestamp) })
+	return snapshots, nil
+}
+
+func isSnapshot(scs SchemaConversionSession) bool {
+	for _, t := range scs.Tags {
+		if t == SnapshotTag {
+			return true
+		}
+	}
+	return false
+}
+
+// getSessionStore returns the session store: the metadata database, or the
+// local store if the session is offline. The returned function must be
+// called once the store isn't used anymore.
+func getSessionStore(ctx context.Context) (SessionStore, func(), error) {
+	if GetSessionState().IsOffline {
+		return NewLocalSessionStore(), func() {}, nil
+	}
+	spannerClient, err := spanner.NewClient(ctx, getMetadataDbUri())
+	if err != nil {
+		return nil, nil, fmt.Errorf("Spanner Client error : %v", err)
+	}
+	return NewRemoteSessionStore(spannerClient), spannerClient.Close, nil
+}
@@ -41,10 +41,13 @@ type SessionState struct {
 	GCPProjectID      string
 	SpannerInstanceID string
 	SessionMetadata   SessionMetadata
-	Counter
+	ImportedHistory   []SchemaConversionSession // Saved versions of a session imported from an archive, oldest first
+	Counter           Counter                   // Counters of the ids of the objects of Conv
 }
 
 // Counter used to generate id for table, column, Foreignkey and indexes.
+// There is one counter per type of object, keyed by the prefix of its ids.
 type Counter struct {
-	ObjectId string
+	Conv *internal.Conv // Conv whose ids the counters were synced with.
+	Last map[string]int // Last id generated for each prefix.
 }
@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webv2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/primarykey"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/uniqueid"
+)
+
+// exportSession returns the current session as a zip archive, containing
+// the conv with its manual DDL edits and issue reviews, the session metadata
+// and the saved versions of the session, so that an in-progress conversion
+// can be handed off to another HarbourBridge instance.
+func exportSession(w http.ResponseWriter, r *http.Request) {
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	name := sessionState.SessionMetadata.SessionName
+	var history []session.SchemaConversionSession
+	if len(sessionState.ImportedHistory) > 0 && sessionState.ImportedHistory[0].SessionName == name {
+		history = append(history, sessionState.ImportedHistory...)
+	}
+	saved, err := session.GetSessionHistory(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't read session history : %v", err), http.StatusInternalServerError)
+		return
+	}
+	history = append(history, saved...)
+	a := session.Archive{
+		Version:         session.ArchiveVersion,
+		Created:         time.Now(),
+		Driver:          sessionState.Driver,
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            sessionState.Conv,
+		History:         history,
+	}
+	var b bytes.Buffer
+	if err = session.WriteArchive(&b, a); err != nil {
+		http.Error(w, fmt.Sprintf("Can't write session archive : %v", err), http.StatusInternalServerError)
+		return
+	}
+	fileName := name
+	if fileName == "" || fileName == "NewSession" {
+		fileName = sessionState.SessionMetadata.DatabaseName
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName+".hbsession.zip"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
+
+// importSession makes the session archive in the request body, as returned
+// by exportSession, the current session.
+func importSession(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	a, err := session.ReadArchive(reqBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read session archive : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	uniqueid.InitObjectId()
+
+	uniqueid.AssignUniqueId(a.Conv)
+	sessionState.Conv = a.Conv
+	primarykey.DetectHotspot()
+
+	sessionState.SessionMetadata = a.SessionMetadata
+	sessionState.Driver = a.Driver
+	sessionState.DbName = a.DatabaseName
+	sessionState.SessionFile = ""
+	sessionState.SourceDB = nil
+	sessionState.ImportedHistory = a.History
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: a.SessionMetadata,
+		Conv:            *a.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webv2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+
+	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/config"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/primarykey"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/uniqueid"
+)
+
+// SpannerDDLRequest is the payload of convertSpannerDDL. Exactly one of Text
+// and FilePath must be set.
+type SpannerDDLRequest struct {
+	Text     string `json:"Text"`     // Spanner DDL statements separated by semicolons.
+	FilePath string `json:"FilePath"` // Path of a .ddl file containing Spanner DDL statements.
+}
+
+// SpannerDatabaseRequest is the payload of convertSpannerDatabase.
+type SpannerDatabaseRequest struct {
+	Database string `json:"Database"` // Name of a database of the configured Spanner instance.
+}
+
+// convertSpannerDDL loads the schema described by Spanner DDL statements
+// into the session, so that a schema created elsewhere can be refined with
+// the editing, index and interleave tooling of the web app.
+func convertSpannerDDL(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req SpannerDDLRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	if (req.Text == "") == (req.FilePath == "") {
+		http.Error(w, "Exactly one of Text and FilePath must be set", http.StatusBadRequest)
+		return
+	}
+	text, dbName := req.Text, "spanner.ddl"
+	if req.FilePath != "" {
+		b, err := ioutil.ReadFile(req.FilePath)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to open DDL file : %v, no such file or directory", req.FilePath), http.StatusNotFound)
+			return
+		}
+		text, dbName = string(b), filepath.Base(req.FilePath)
+	}
+	conv := internal.MakeConv()
+	conv.TargetDb = constants.TargetSpanner
+	if err = utils.ReadSpannerDDL(conv, text); err != nil {
+		http.Error(w, fmt.Sprintf("Schema Conversion Error : %v", err), http.StatusBadRequest)
+		return
+	}
+	loadSpannerSchema(w, conv, dbName)
+}
+
+// convertSpannerDatabase loads the schema of an existing database of the
+// configured Spanner instance into the session.
+func convertSpannerDatabase(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req SpannerDatabaseRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	if !spannerDbName.MatchString(req.Database) {
+		http.Error(w, fmt.Sprintf("'%s' is not a valid Spanner database name", req.Database), http.StatusBadRequest)
+		return
+	}
+	spConfig, err := config.GetSpannerConfig()
+	if err != nil || spConfig.GCPProjectID == "" || spConfig.SpannerInstanceID == "" {
+		http.Error(w, "Spanner project and instance are not configured", http.StatusBadRequest)
+		return
+	}
+	dbURI := fmt.Sprintf("projects/%s/instances/%s/databases/%s", spConfig.GCPProjectID, spConfig.SpannerInstanceID, req.Database)
+	ctx := context.Background()
+	adminClient, err := utils.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't create admin client : %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer adminClient.Close()
+	db, err := adminClient.GetDatabase(ctx, &adminpb.GetDatabaseRequest{Name: dbURI})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't get database %s : %v", dbURI, err), http.StatusNotFound)
+		return
+	}
+	client, err := utils.GetClient(ctx, dbURI)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't create client for database %s : %v", dbURI, err), http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+	conv := internal.MakeConv()
+	conv.TargetDb = utils.DialectToTarget(db.DatabaseDialect.String())
+	if err = utils.ReadSpannerSchema(ctx, conv, client); err != nil {
+		http.Error(w, fmt.Sprintf("Schema Conversion Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	loadSpannerSchema(w, conv, req.Database)
+}
+
+// loadSpannerSchema makes conv, read from Spanner DDL or from Spanner
+// database dbName, the schema of a new session.
+func loadSpannerSchema(w http.ResponseWriter, conv *internal.Conv, dbName string) {
+	sessionMetadata := session.SessionMetadata{
+		SessionName:  "NewSession",
+		DatabaseType: constants.SPANNER,
+		DatabaseName: dbName,
+	}
+
+	sessionState := session.GetSessionState()
+	uniqueid.InitObjectId()
+
+	uniqueid.AssignUniqueId(conv)
+	sessionState.Conv = conv
+	primarykey.DetectHotspot()
+
+	sessionState.SessionMetadata = sessionMetadata
+	sessionState.Driver = constants.SPANNER
+	sessionState.DbName = ""
+	sessionState.SessionFile = ""
+	sessionState.SourceDB = nil
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionMetadata,
+		Conv:            *conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
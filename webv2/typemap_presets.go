@@ -0,0 +1,379 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/helpers"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+)
+
+// TypeMapPreset is a named type-mapping that can be applied to any
+// conversion, so that teams can reuse the type choices of earlier
+// migrations instead of redoing them table by table.
+type TypeMapPreset struct {
+	Name        string
+	Description string
+	BuiltIn     bool
+	// Driver restricts the preset to conversions of this driver. Empty
+	// means the preset applies to all drivers.
+	Driver string
+	// Mappings maps source types to Spanner types. The key "*" maps all
+	// source types that have no mapping of their own.
+	Mappings map[string]string
+	// SpannerTypes maps default Spanner types to other Spanner types, for
+	// source types that have no mapping in Mappings.
+	SpannerTypes map[string]string
+	// AvoidIssues maps source types whose Spanner type has conversion issues
+	// to a Spanner type without issues, when there is one.
+	AvoidIssues bool
+}
+
+// builtInPresets are available in every HarbourBridge instance, and can't
+// be changed or deleted.
+var builtInPresets = []TypeMapPreset{
+	{
+		Name:        "strict",
+		Description: "Use Spanner types that convert source values without widening or loss of precision",
+		BuiltIn:     true,
+		AvoidIssues: true,
+	},
+	{
+		Name:        "string-everything",
+		Description: "Convert all columns to STRING, e.g. for a staging copy of the source database",
+		BuiltIn:     true,
+		Mappings:    map[string]string{"*": ddl.String},
+	},
+	{
+		Name:         "pg-dialect-friendly",
+		Description:  "Avoid Spanner types that aren't supported by PostgreSQL-dialect databases",
+		BuiltIn:      true,
+		SpannerTypes: map[string]string{ddl.JSON: ddl.String},
+	},
+}
+
+var presetNameRegexp = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// presetStore persists the custom type-mapping presets to a json file, so
+// that they are shared across sessions.
+type presetStore struct {
+	mu   sync.Mutex
+	path string // Empty path keeps presets in memory.
+	// custom maps names to custom presets.
+	custom map[string]TypeMapPreset
+}
+
+// newPresetStore returns a presetStore that persists custom presets to
+// path, loading the presets saved there by earlier runs.
+func newPresetStore(path string) *presetStore {
+	s := &presetStore{path: path, custom: make(map[string]TypeMapPreset)}
+	if path == "" {
+		return s
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Can't read presets file %s: %v\n", path, err)
+		}
+		return s
+	}
+	var presets []TypeMapPreset
+	if err = json.Unmarshal(b, &presets); err != nil {
+		log.Printf("Can't parse presets file %s: %v\n", path, err)
+		return s
+	}
+	for _, p := range presets {
+		s.custom[p.Name] = p
+	}
+	return s
+}
+
+// list returns the built-in presets followed by the custom presets sorted
+// by name.
+func (s *presetStore) list() []TypeMapPreset {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	presets := append([]TypeMapPreset{}, builtInPresets...)
+	return append(presets, s.sortedCustom()...)
+}
+
+func (s *presetStore) get(name string) (TypeMapPreset, bool) {
+	for _, p := range builtInPresets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, found := s.custom[name]
+	return p, found
+}
+
+// save adds custom preset p, or replaces the custom preset of the same name.
+func (s *presetStore) save(p TypeMapPreset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, found := s.custom[p.Name]
+	s.custom[p.Name] = p
+	if err := s.write(); err != nil {
+		if found {
+			s.custom[p.Name] = old
+		} else {
+			delete(s.custom, p.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// delete deletes custom preset name. It returns false if there is none.
+func (s *presetStore) delete(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, found := s.custom[name]
+	if !found {
+		return false, nil
+	}
+	delete(s.custom, name)
+	if err := s.write(); err != nil {
+		s.custom[name] = p
+		return true, err
+	}
+	return true, nil
+}
+
+func (s *presetStore) sortedCustom() []TypeMapPreset {
+	presets := []TypeMapPreset{}
+	for _, p := range s.custom {
+		presets = append(presets, p)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets
+}
+
+func (s *presetStore) write() error {
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(s.sortedCustom(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, b, os.ModePerm)
+}
+
+// validatePreset checks that custom preset p can be saved.
+func validatePreset(p TypeMapPreset) error {
+	if !presetNameRegexp.MatchString(p.Name) {
+		return fmt.Errorf("preset name '%s' must only contain letters, digits, '_' and '-'", p.Name)
+	}
+	for _, b := range builtInPresets {
+		if b.Name == p.Name {
+			return fmt.Errorf("preset '%s' is built-in and can't be changed", p.Name)
+		}
+	}
+	if len(p.Mappings) == 0 && len(p.SpannerTypes) == 0 && !p.AvoidIssues {
+		return fmt.Errorf("preset '%s' doesn't change any type", p.Name)
+	}
+	for _, m := range []map[string]string{p.Mappings, p.SpannerTypes} {
+		for from, to := range m {
+			if !isSpannerTypeName(to) {
+				return fmt.Errorf("'%s' is mapped to '%s', which isn't a Spanner type", from, to)
+			}
+		}
+	}
+	return nil
+}
+
+func isSpannerTypeName(t string) bool {
+	for _, spType := range common.SpannerTypeNames {
+		if t == spType {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePreset returns the mapping from source type to Spanner type that
+// preset p makes for the source types of the session. Source types are only
+// mapped to the Spanner types offered for them by the typemap, and source
+// types that p doesn't change are left out.
+func resolvePreset(sessionState *session.SessionState, p TypeMapPreset) (map[string]string, error) {
+	if p.Driver != "" && p.Driver != sessionState.Driver {
+		return nil, fmt.Errorf("preset '%s' is for driver '%s', but the session uses driver '%s'", p.Name, p.Driver, sessionState.Driver)
+	}
+	typeMap, err := sessionTypeMap(sessionState)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make(map[string]string)
+	for srcType, options := range typeMap {
+		ty, _, err := toSpannerType(sessionState, srcType, "", []int64{})
+		if err != nil {
+			return nil, err
+		}
+		spType := ty.Name
+		if t, found := p.Mappings[srcType]; found {
+			spType = t
+		} else if t, found := p.Mappings["*"]; found {
+			spType = t
+		} else if t, found := p.SpannerTypes[spType]; found {
+			spType = t
+		}
+		if p.AvoidIssues {
+			spType = issueFreeType(spType, options)
+		}
+		if spType == ty.Name {
+			continue
+		}
+		for _, o := range options {
+			if o.T == spType {
+				resolved[srcType] = spType
+				break
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// issueFreeType returns spType if it converts without issues, and otherwise
+// an option without issues, preferring STRING. It returns spType if all
+// options have issues.
+func issueFreeType(spType string, options []typeIssue) string {
+	var alternatives []string
+	for _, o := range options {
+		if o.Brief != "" {
+			continue
+		}
+		if o.T == spType {
+			return spType
+		}
+		alternatives = append(alternatives, o.T)
+	}
+	for _, t := range alternatives {
+		if t == ddl.String {
+			return t
+		}
+	}
+	if len(alternatives) > 0 {
+		return alternatives[0]
+	}
+	return spType
+}
+
+// applyPreset applies preset name to the conv of the session.
+func applyPreset(sessionState *session.SessionState, name string) error {
+	p, found := typeMapPresets.get(name)
+	if !found {
+		return fmt.Errorf("no type-mapping preset named '%s'", name)
+	}
+	typeMap, err := resolvePreset(sessionState, p)
+	if err != nil {
+		return err
+	}
+	return applyTypeMap(sessionState, typeMap)
+}
+
+// getTypeMapPresets returns the built-in and custom type-mapping presets.
+func getTypeMapPresets(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(typeMapPresets.list())
+}
+
+// saveTypeMapPreset creates a custom type-mapping preset, or replaces the
+// custom preset of the same name.
+func saveTypeMapPreset(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var p TypeMapPreset
+	if err = json.Unmarshal(reqBody, &p); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	p.BuiltIn = false
+	if err = validatePreset(p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = typeMapPresets.save(p); err != nil {
+		http.Error(w, fmt.Sprintf("Can't save preset : %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(p)
+}
+
+// deleteTypeMapPreset deletes a custom type-mapping preset.
+func deleteTypeMapPreset(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if p, found := typeMapPresets.get(name); found && p.BuiltIn {
+		http.Error(w, fmt.Sprintf("Preset '%s' is built-in and can't be deleted", name), http.StatusBadRequest)
+		return
+	}
+	found, err := typeMapPresets.delete(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't delete preset : %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("No type-mapping preset named '%s'", name), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(typeMapPresets.list())
+}
+
+// applyTypeMapPreset applies a type-mapping preset to the current
+// conversion.
+func applyTypeMapPreset(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil || sessionState.Driver == "" {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	if _, found := typeMapPresets.get(name); !found {
+		http.Error(w, fmt.Sprintf("No type-mapping preset named '%s'", name), http.StatusNotFound)
+		return
+	}
+	if err := applyPreset(sessionState, name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
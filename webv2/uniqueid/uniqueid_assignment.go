@@ -15,7 +15,9 @@
 package uniqueid
 
 import (
+	"sort"
 	"strconv"
+	"sync"
 
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
@@ -26,98 +28,102 @@ import (
 // AssignUniqueId to handles  cascading effect in UI.
 // Its iterate over source and spanner schema
 // and assign id to table and column.
+// Ids already in conv (e.g. of a saved session) are kept, and new ids are
+// assigned in the order of table names, columns, foreign keys and indexes,
+// so that ids are stable across saves and HarbourBridge instances.
 func AssignUniqueId(conv *internal.Conv) {
+	mu.Lock()
+	defer mu.Unlock()
+	c := &session.GetSessionState().Counter
+	syncCounter(c, conv)
+	// seen holds the ids kept so far. An id that was already seen is
+	// replaced, so that a conv with duplicate ids is repaired.
+	seen := make(map[string]bool)
+	keep := func(sourceId, spannerId string) bool {
+		if sourceId == "" || sourceId != spannerId || seen[sourceId] {
+			return false
+		}
+		seen[sourceId] = true
+		return true
+	}
 
-	for sourcetablename, sourcetable := range conv.SrcSchema {
-
-		for spannertablename, spannertable := range conv.SpSchema {
-
-			if sourcetablename == spannertablename {
-
-				tableuniqueid := GenerateTableId()
-				sourcetable.Id = tableuniqueid
-				spannertable.Id = tableuniqueid
-
-				for spannercolumnname, spannercolumn := range spannertable.ColDefs {
+	var tableNames []string
+	for name := range conv.SpSchema {
+		tableNames = append(tableNames, name)
+	}
+	sort.Strings(tableNames)
+	for _, spannertablename := range tableNames {
+		spannertable := conv.SpSchema[spannertablename]
+		sourcetable, found := conv.SrcSchema[spannertablename]
+		if !found {
+			continue
+		}
+		if !keep(sourcetable.Id, spannertable.Id) {
+			tableuniqueid := nextId(c, tablePrefix)
+			sourcetable.Id = tableuniqueid
+			spannertable.Id = tableuniqueid
+		}
 
-					if spannercolumn.Name == "synth_id" {
+		if spannercolumn, found := spannertable.ColDefs["synth_id"]; found {
+			if !keep(spannercolumn.Id, spannercolumn.Id) {
+				spannercolumn.Id = nextId(c, columnPrefix)
+				spannertable.ColDefs["synth_id"] = spannercolumn
+			}
+		}
 
-						columnuniqueid := GenerateColumnId()
+		for _, sourcecolumnname := range sourcetable.ColNames {
+			sourcecolumn := sourcetable.ColDefs[sourcecolumnname]
+			for spannercolumnname, spannercolumn := range spannertable.ColDefs {
+				if sourcecolumn.Name == spannercolumn.Name {
+					if !keep(sourcecolumn.Id, spannercolumn.Id) {
+						columnuniqueid := nextId(c, columnPrefix)
+						sourcecolumn.Id = columnuniqueid
 						spannercolumn.Id = columnuniqueid
-						conv.SpSchema[spannertablename].ColDefs[spannercolumnname] = spannercolumn
 					}
-
+					sourcetable.ColDefs[sourcecolumnname] = sourcecolumn
+					spannertable.ColDefs[spannercolumnname] = spannercolumn
+					break
 				}
+			}
+		}
 
-				for sourcecolumnname, sourcecolumn := range sourcetable.ColDefs {
-
-					for spannercolumnname, spannercolumn := range spannertable.ColDefs {
-
-						if sourcecolumn.Name == spannercolumn.Name {
-
-							columnuniqueid := GenerateColumnId()
-							sourcecolumn.Id = columnuniqueid
-							spannercolumn.Id = columnuniqueid
-
-							conv.SrcSchema[sourcetablename].ColDefs[sourcecolumnname] = sourcecolumn
-							conv.SpSchema[spannertablename].ColDefs[spannercolumnname] = spannercolumn
-
-							break
-						}
-					}
-
-				}
-
-				for sourceforeignkeyindex, sourceforeignkey := range sourcetable.ForeignKeys {
-
-					for spannerforeignkeyindex, spannerforeignkey := range spannertable.Fks {
-
-						if sourceforeignkey.Name == spannerforeignkey.Name {
-
-							foreignkeyid := GenerateForeignkeyId()
-
-							sourceforeignkey.Id = foreignkeyid
-							spannerforeignkey.Id = foreignkeyid
-
-							conv.SrcSchema[sourcetable.Name].ForeignKeys[sourceforeignkeyindex] = sourceforeignkey
-							conv.SpSchema[spannertable.Name].Fks[spannerforeignkeyindex] = spannerforeignkey
-						}
-
+		for sourceforeignkeyindex, sourceforeignkey := range sourcetable.ForeignKeys {
+			for spannerforeignkeyindex, spannerforeignkey := range spannertable.Fks {
+				if sourceforeignkey.Name == spannerforeignkey.Name {
+					if !keep(sourceforeignkey.Id, spannerforeignkey.Id) {
+						foreignkeyid := nextId(c, foreignKeyPrefix)
+						sourceforeignkey.Id = foreignkeyid
+						spannerforeignkey.Id = foreignkeyid
 					}
+					sourcetable.ForeignKeys[sourceforeignkeyindex] = sourceforeignkey
+					spannertable.Fks[spannerforeignkeyindex] = spannerforeignkey
 				}
+			}
+		}
 
-				for sourcei, sourceindexes := range sourcetable.Indexes {
-
-					for spanneri, spannerindexes := range spannertable.Indexes {
-
-						if sourceindexes.Name == spannerindexes.Name {
-
-							indexesid := GenerateIndexesId()
-
-							sourceindexes.Id = indexesid
-							spannerindexes.Id = indexesid
-
-							conv.SrcSchema[sourcetable.Name].Indexes[sourcei] = sourceindexes
-							conv.SpSchema[spannertable.Name].Indexes[spanneri] = spannerindexes
-
-						}
-
+		for sourcei, sourceindexes := range sourcetable.Indexes {
+			for spanneri, spannerindexes := range spannertable.Indexes {
+				if sourceindexes.Name == spannerindexes.Name {
+					if !keep(sourceindexes.Id, spannerindexes.Id) {
+						indexesid := nextId(c, indexesPrefix)
+						sourceindexes.Id = indexesid
+						spannerindexes.Id = indexesid
 					}
+					sourcetable.Indexes[sourcei] = sourceindexes
+					spannertable.Indexes[spanneri] = spannerindexes
 				}
+			}
+		}
 
-				updateSpannerTableIndexKeyOrder(spannertable)
-				updateSourceTableIndexKeyOrder(sourcetable)
+		updateSpannerTableIndexKeyOrder(spannertable)
+		updateSourceTableIndexKeyOrder(sourcetable)
 
-				updateSpannerTableSecondaryIndexKeyOrder(spannertable)
-				updateSourceTableSecondaryIndexKeyOrder(sourcetable)
+		updateSpannerTableSecondaryIndexKeyOrder(spannertable)
+		updateSourceTableSecondaryIndexKeyOrder(sourcetable)
 
-				conv.SrcSchema[sourcetablename] = sourcetable
-				conv.SpSchema[spannertablename] = spannertable
-				break
-			}
-		}
+		conv.SrcSchema[spannertablename] = sourcetable
+		conv.SpSchema[spannertablename] = spannertable
 	}
-
 }
 
 // updateSpannerTableIndexKeyOrder Update Primary Key Order as columnId.
@@ -209,52 +215,103 @@ func getSourceColumnIndex(sourcetable schema.Table, columnName string) int {
 	return 0
 }
 
-func GenerateId() string {
-
-	sessionState := session.GetSessionState()
-
-	counter, _ := strconv.Atoi(sessionState.Counter.ObjectId)
+// Prefixes of the ids of each type of object.
+const (
+	tablePrefix      = "t"
+	columnPrefix     = "c"
+	foreignKeyPrefix = "f"
+	indexesPrefix    = "i"
+)
 
-	counter = counter + 1
+// mu guards the Counter of the session.
+var mu sync.Mutex
 
-	sessionState.Counter.ObjectId = strconv.Itoa(counter)
-	return sessionState.Counter.ObjectId
+// GenerateId returns a new id for an object of the conv of the session,
+// from the counter of the given prefix.
+func GenerateId(prefix string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	sessionState := session.GetSessionState()
+	c := &sessionState.Counter
+	if c.Conv != sessionState.Conv {
+		// The conv was replaced, e.g. by a session resumed from the session
+		// store, since the counters were last synced.
+		syncCounter(c, sessionState.Conv)
+	}
+	return nextId(c, prefix)
 }
 
 func GenerateTableId() string {
-	tablePrefix := "t"
-	id := GenerateId()
-	tableId := tablePrefix + id
-	return tableId
+	return GenerateId(tablePrefix)
 }
 
 func GenerateColumnId() string {
-
-	columnPrefix := "c"
-	id := GenerateId()
-	columnId := columnPrefix + id
-	return columnId
+	return GenerateId(columnPrefix)
 }
 
 func GenerateForeignkeyId() string {
-
-	foreignKeyPrefix := "f"
-	id := GenerateId()
-	foreignKeyId := foreignKeyPrefix + id
-	return foreignKeyId
+	return GenerateId(foreignKeyPrefix)
 }
 
 func GenerateIndexesId() string {
-
-	indexesPrefix := "i"
-	id := GenerateId()
-
-	indexesId := indexesPrefix + id
-	return indexesId
+	return GenerateId(indexesPrefix)
 }
 
+// InitObjectId resets the counters of the session. They are synced with the
+// ids of the conv of the session when the next id is generated.
 func InitObjectId() {
-
+	mu.Lock()
+	defer mu.Unlock()
 	sessionState := session.GetSessionState()
-	sessionState.Counter.ObjectId = "0"
+	sessionState.Counter = session.Counter{}
+}
+
+func nextId(c *session.Counter, prefix string) string {
+	c.Last[prefix]++
+	return prefix + strconv.Itoa(c.Last[prefix])
+}
+
+// syncCounter sets the counters of c to the largest ids of conv, so that
+// the ids generated next don't collide with the ids in conv. Since ids are
+// saved with the conv, counters are effectively persisted in the session
+// store.
+func syncCounter(c *session.Counter, conv *internal.Conv) {
+	c.Conv = conv
+	c.Last = make(map[string]int)
+	if conv == nil {
+		return
+	}
+	see := func(id string) {
+		if len(id) < 2 {
+			return
+		}
+		n, err := strconv.Atoi(id[1:])
+		if err == nil && n > c.Last[id[:1]] {
+			c.Last[id[:1]] = n
+		}
+	}
+	for _, t := range conv.SrcSchema {
+		see(t.Id)
+		for _, col := range t.ColDefs {
+			see(col.Id)
+		}
+		for _, fk := range t.ForeignKeys {
+			see(fk.Id)
+		}
+		for _, index := range t.Indexes {
+			see(index.Id)
+		}
+	}
+	for _, t := range conv.SpSchema {
+		see(t.Id)
+		for _, col := range t.ColDefs {
+			see(col.Id)
+		}
+		for _, fk := range t.Fks {
+			see(fk.Id)
+		}
+		for _, index := range t.Indexes {
+			see(index.Id)
+		}
+	}
 }
@@ -0,0 +1,100 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uniqueid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudspannerecosystem/harbourbridge/internal"
+	"github.com/cloudspannerecosystem/harbourbridge/schema"
+	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+)
+
+func testConv() *internal.Conv {
+	conv := internal.MakeConv()
+	for _, name := range []string{"users", "orders"} {
+		conv.SrcSchema[name] = schema.Table{
+			Name:     name,
+			ColNames: []string{"id", "name"},
+			ColDefs: map[string]schema.Column{
+				"id":   {Name: "id"},
+				"name": {Name: "name"},
+			},
+			Indexes: []schema.Index{{Name: name + "_by_name"}},
+		}
+		conv.SpSchema[name] = ddl.CreateTable{
+			Name:     name,
+			ColNames: []string{"id", "name"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":   {Name: "id"},
+				"name": {Name: "name"},
+			},
+			Indexes: []ddl.CreateIndex{{Name: name + "_by_name"}},
+		}
+	}
+	return conv
+}
+
+func TestAssignUniqueId(t *testing.T) {
+	sessionState := session.GetSessionState()
+
+	// Ids are assigned in the order of table names and columns.
+	InitObjectId()
+	conv := testConv()
+	AssignUniqueId(conv)
+	sessionState.Conv = conv
+	assert.Equal(t, "t1", conv.SpSchema["orders"].Id)
+	assert.Equal(t, "t1", conv.SrcSchema["orders"].Id)
+	assert.Equal(t, "c1", conv.SpSchema["orders"].ColDefs["id"].Id)
+	assert.Equal(t, "c2", conv.SrcSchema["orders"].ColDefs["name"].Id)
+	assert.Equal(t, "i1", conv.SpSchema["orders"].Indexes[0].Id)
+	assert.Equal(t, "t2", conv.SpSchema["users"].Id)
+	assert.Equal(t, "c4", conv.SpSchema["users"].ColDefs["name"].Id)
+
+	// Ids are kept when the conv is loaded again.
+	InitObjectId()
+	AssignUniqueId(conv)
+	assert.Equal(t, "t1", conv.SpSchema["orders"].Id)
+	assert.Equal(t, "c4", conv.SpSchema["users"].ColDefs["name"].Id)
+	assert.Equal(t, "c5", GenerateColumnId())
+
+	// Counters are synced with a conv resumed from the session store.
+	resumed := testConv()
+	sp := resumed.SpSchema["users"]
+	src := resumed.SrcSchema["users"]
+	sp.Id, src.Id = "t7", "t7"
+	sp.ColDefs["id"] = ddl.ColumnDef{Name: "id", Id: "c9"}
+	src.ColDefs["id"] = schema.Column{Name: "id", Id: "c9"}
+	resumed.SpSchema["users"], resumed.SrcSchema["users"] = sp, src
+	sessionState.Conv = resumed
+	assert.Equal(t, "c10", GenerateColumnId())
+	assert.Equal(t, "i1", GenerateIndexesId())
+
+	// Duplicate ids are replaced.
+	InitObjectId()
+	conv = testConv()
+	for _, name := range []string{"users", "orders"} {
+		sp, src := conv.SpSchema[name], conv.SrcSchema[name]
+		sp.Id, src.Id = "t1", "t1"
+		conv.SpSchema[name], conv.SrcSchema[name] = sp, src
+	}
+	AssignUniqueId(conv)
+	assert.Equal(t, "t1", conv.SpSchema["orders"].Id)
+	assert.Equal(t, "t2", conv.SpSchema["users"].Id)
+	assert.Equal(t, "t2", conv.SrcSchema["users"].Id)
+}
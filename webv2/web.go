@@ -20,12 +20,16 @@ package webv2
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"io/ioutil"
 	"log"
+	"math/big"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -36,18 +40,19 @@ import (
 	"time"
 
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/common/tunnel"
 	"github.com/cloudspannerecosystem/harbourbridge/common/utils"
 	"github.com/cloudspannerecosystem/harbourbridge/conversion"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/profiles"
 	"github.com/cloudspannerecosystem/harbourbridge/sources/common"
-	"github.com/cloudspannerecosystem/harbourbridge/sources/mysql"
-	"github.com/cloudspannerecosystem/harbourbridge/sources/oracle"
-	"github.com/cloudspannerecosystem/harbourbridge/sources/postgres"
-	"github.com/cloudspannerecosystem/harbourbridge/sources/sqlserver"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/registry"
+	"github.com/cloudspannerecosystem/harbourbridge/sources/spanner"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/auth"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/config"
 	helpers "github.com/cloudspannerecosystem/harbourbridge/webv2/helpers"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/jobs"
 	utilities "github.com/cloudspannerecosystem/harbourbridge/webv2/utilities"
 
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
@@ -57,8 +62,6 @@ import (
 	primarykey "github.com/cloudspannerecosystem/harbourbridge/webv2/primarykey"
 
 	uniqueid "github.com/cloudspannerecosystem/harbourbridge/webv2/uniqueid"
-
-	go_ora "github.com/sijms/go-ora/v2"
 )
 
 // TODO:(searce):
@@ -70,10 +73,6 @@ import (
 // 6) Update schema conv after setting global datatypes and return conv. (setTypeMap)
 // 7) Add rateConversion() in schema conversion, ddl and report APIs.
 // 8) Add an overview in summary report API
-var mysqlTypeMap = make(map[string][]typeIssue)
-var postgresTypeMap = make(map[string][]typeIssue)
-var sqlserverTypeMap = make(map[string][]typeIssue)
-var oracleTypeMap = make(map[string][]typeIssue)
 
 // TODO:(searce) organize this file according to go style guidelines: generally
 // have public constants and public type definitions first, then public
@@ -88,6 +87,35 @@ type driverConfig struct {
 	Database string `json:"Database"`
 	User     string `json:"User"`
 	Password string `json:"Password"`
+	// Optional route to the database, through an SSH tunnel and/or a proxy.
+	SSHHost     string `json:"SSHHost,omitempty"`
+	SSHUser     string `json:"SSHUser,omitempty"`
+	SSHPassword string `json:"SSHPassword,omitempty"`
+	SSHKeyFile  string `json:"SSHKeyFile,omitempty"`
+	Proxy       string `json:"Proxy,omitempty"`
+}
+
+// sourceTunnel is the tunnel to the source database connected to, if any.
+var sourceTunnel *tunnel.Tunnel
+
+// sourceDriver describes a source driver registered with the registry of
+// source drivers.
+type sourceDriver struct {
+	registry.Info
+	// Connect is true if the web app can connect to databases of the driver
+	// with /connect.
+	Connect bool
+}
+
+// getSourceDrivers returns the registered source drivers, sorted by name.
+func getSourceDrivers(w http.ResponseWriter, r *http.Request) {
+	drivers := []sourceDriver{}
+	for _, d := range registry.List() {
+		_, connect := d.(registry.WebDriver)
+		drivers = append(drivers, sourceDriver{Info: d.Info(), Connect: connect})
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(drivers)
 }
 
 // databaseConnection creates connection with database when using
@@ -104,21 +132,31 @@ func databaseConnection(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
 		return
 	}
-	var dataSourceName string
-	switch config.Driver {
-	case constants.POSTGRES:
-		dataSourceName = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", config.Host, config.Port, config.User, config.Password, config.Database)
-	case constants.MYSQL:
-		dataSourceName = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", config.User, config.Password, config.Host, config.Port, config.Database)
-	case constants.SQLSERVER:
-		dataSourceName = fmt.Sprintf(`sqlserver://%s:%s@%s:%s?database=%s`, config.User, config.Password, config.Host, config.Port, config.Database)
-	case constants.ORACLE:
-		portNumber, _ := strconv.Atoi(config.Port)
-		dataSourceName = go_ora.BuildUrl(config.Host, portNumber, config.Database, config.User, config.Password, nil)
-	default:
+	d, found := registry.Get(config.Driver)
+	webDriver, ok := d.(registry.WebDriver)
+	if !found || !ok {
 		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", config.Driver), http.StatusBadRequest)
 		return
 	}
+	host, port := config.Host, config.Port
+	route := tunnel.Config{SSHHost: config.SSHHost, SSHUser: config.SSHUser, SSHPassword: config.SSHPassword, SSHKeyFile: config.SSHKeyFile, Proxy: config.Proxy}
+	if sourceTunnel != nil {
+		sourceTunnel.Close()
+		sourceTunnel = nil
+	}
+	if route.Enabled() {
+		if route.SSHHost != "" && route.SSHUser == "" {
+			http.Error(w, "Please specify the SSH user to connect through an SSH tunnel.", http.StatusBadRequest)
+			return
+		}
+		sourceTunnel, err = tunnel.Open(route, net.JoinHostPort(host, port))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Can't reach the database through the SSH tunnel or proxy: %v", err), http.StatusInternalServerError)
+			return
+		}
+		host, port = sourceTunnel.Addr()
+	}
+	dataSourceName := webDriver.DataSourceName(host, port, config.User, config.Password, config.Database)
 	sourceDB, err := sql.Open(config.Driver, dataSourceName)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Database connection error, check connection properties."), http.StatusInternalServerError)
@@ -144,34 +182,60 @@ func databaseConnection(w http.ResponseWriter, r *http.Request) {
 }
 
 // convertSchemaSQL converts source database to Spanner when using
-// with postgres and mysql driver.
+// with postgres and mysql driver. With query parameter async=true, the
+// schema is converted in a background job, whose result is the converted
+// session.
 func convertSchemaSQL(w http.ResponseWriter, r *http.Request) {
 	sessionState := session.GetSessionState()
 	if sessionState.SourceDB == nil || sessionState.DbName == "" || sessionState.Driver == "" {
 		http.Error(w, fmt.Sprintf("Database is not configured or Database connection is lost. Please set configuration and connect to database."), http.StatusNotFound)
 		return
 	}
-	conv := internal.MakeConv()
-	// Setting target db to spanner by default.
-	conv.TargetDb = constants.TargetSpanner
-	var err error
-	switch sessionState.Driver {
-	case constants.MYSQL:
-		err = common.ProcessSchema(conv, mysql.InfoSchemaImpl{DbName: sessionState.DbName, Db: sessionState.SourceDB})
-	case constants.POSTGRES:
-		err = common.ProcessSchema(conv, postgres.InfoSchemaImpl{Db: sessionState.SourceDB})
-	case constants.SQLSERVER:
-		err = common.ProcessSchema(conv, sqlserver.InfoSchemaImpl{DbName: sessionState.DbName, Db: sessionState.SourceDB})
-	case constants.ORACLE:
-		err = common.ProcessSchema(conv, oracle.InfoSchemaImpl{DbName: strings.ToUpper(sessionState.DbName), Db: sessionState.SourceDB})
-	default:
-		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", sessionState.Driver), http.StatusBadRequest)
+	infoSchema, err := getInfoSchema(sessionState)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	preset := r.URL.Query().Get("preset")
+	if _, found := typeMapPresets.get(preset); preset != "" && !found {
+		http.Error(w, fmt.Sprintf("No type-mapping preset named '%s'", preset), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("async") == "true" {
+		j := jobManager.Start("schema-conversion", func(ctx context.Context, j *jobs.Job) (interface{}, error) {
+			jobManager.Logf(j, "Converting schema of %s database %s", sessionState.Driver, sessionState.DbName)
+			convm, err := convertSchema(ctx, sessionState, infoSchema, preset)
+			if err == nil {
+				jobManager.Logf(j, "Converted %d tables", len(convm.Conv.SpSchema))
+			}
+			return convm, err
+		})
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(j)
 		return
 	}
+	convm, err := convertSchema(context.Background(), sessionState, infoSchema, preset)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Schema Conversion Error : %v", err), http.StatusNotFound)
 		return
 	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// convertSchema converts the schema of infoSchema, applies type-mapping
+// preset (if not empty), and makes it the schema of the session. The
+// session isn't changed if ctx is cancelled.
+func convertSchema(ctx context.Context, sessionState *session.SessionState, infoSchema common.InfoSchema, preset string) (session.ConvWithMetadata, error) {
+	conv := internal.MakeConv()
+	// Setting target db to spanner by default.
+	conv.TargetDb = constants.TargetSpanner
+	if err := common.ProcessSchema(conv, infoSchema); err != nil {
+		return session.ConvWithMetadata{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return session.ConvWithMetadata{}, err
+	}
 
 	uniqueid.InitObjectId()
 
@@ -179,6 +243,11 @@ func convertSchemaSQL(w http.ResponseWriter, r *http.Request) {
 	sessionState.Conv = conv
 
 	primarykey.DetectHotspot()
+	if preset != "" {
+		if err := applyPreset(sessionState, preset); err != nil {
+			return session.ConvWithMetadata{}, err
+		}
+	}
 
 	sessionMetadata := session.SessionMetadata{
 		SessionName:  "NewSession",
@@ -192,8 +261,18 @@ func convertSchemaSQL(w http.ResponseWriter, r *http.Request) {
 	}
 	sessionState.Conv = conv
 	sessionState.SessionMetadata = sessionMetadata
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(convm)
+	return convm, nil
+}
+
+// getInfoSchema returns the InfoSchema of the source database of the
+// session.
+func getInfoSchema(sessionState *session.SessionState) (common.InfoSchema, error) {
+	d, _ := registry.Get(sessionState.Driver)
+	webDriver, ok := d.(registry.WebDriver)
+	if !ok {
+		return nil, fmt.Errorf("Driver : '%s' is not supported", sessionState.Driver)
+	}
+	return webDriver.WebInfoSchema(sessionState.SourceDB, sessionState.DbName), nil
 }
 
 // dumpConfig contains the parameters needed to run the tool using dump approach. It is
@@ -201,6 +280,7 @@ func convertSchemaSQL(w http.ResponseWriter, r *http.Request) {
 type dumpConfig struct {
 	Driver   string `json:"Driver"`
 	FilePath string `json:"Path"`
+	Preset   string `json:"Preset"` // Optional type-mapping preset applied to the converted schema.
 }
 
 // convertSchemaDump converts schema from dump file to Spanner schema for
@@ -217,6 +297,10 @@ func convertSchemaDump(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
 		return
 	}
+	if _, found := typeMapPresets.get(dc.Preset); dc.Preset != "" && !found {
+		http.Error(w, fmt.Sprintf("No type-mapping preset named '%s'", dc.Preset), http.StatusBadRequest)
+		return
+	}
 	f, err := os.Open(dc.FilePath)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to open dump file : %v, no such file or directory", dc.FilePath), http.StatusNotFound)
@@ -251,6 +335,12 @@ func convertSchemaDump(w http.ResponseWriter, r *http.Request) {
 	sessionState.DbName = ""
 	sessionState.SessionFile = ""
 	sessionState.SourceDB = nil
+	if dc.Preset != "" {
+		if err = applyPreset(sessionState, dc.Preset); err != nil {
+			http.Error(w, fmt.Sprintf("Can't apply preset : %v", err), http.StatusBadRequest)
+			return
+		}
+	}
 
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionMetadata,
@@ -336,6 +426,175 @@ func getDDL(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(ddl)
 }
 
+// DdlPreview is the final DDL that is applied to Spanner, including manual
+// edits.
+type DdlPreview struct {
+	Tables      []TableDdl // In the order the tables are created.
+	ForeignKeys []string   // ALTER TABLE statements adding foreign keys, which are applied after data migration.
+}
+
+// TableDdl is the DDL of a Spanner table.
+type TableDdl struct {
+	Table     string
+	Generated []string // Statements generated from the schema.
+	Edited    []string // Manually edited statements that replace the generated ones, if any.
+	Stale     bool     // True if the schema of the table changed after its DDL was edited.
+}
+
+// DdlValidation is the result of validating the DDL.
+type DdlValidation struct {
+	Valid bool
+	Error string
+}
+
+// applyDDLConfig is the DDL config of the statements applied to Spanner.
+func applyDDLConfig(conv *internal.Conv) ddl.Config {
+	return ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: true, TargetDb: conv.TargetDb}
+}
+
+// getDDLPreview returns the final DDL applied to Spanner, table by table.
+func getDDLPreview(w http.ResponseWriter, r *http.Request) {
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	conv := sessionState.Conv
+	stale := make(map[string]bool)
+	for _, t := range conv.StaleDdlEdits() {
+		stale[t] = true
+	}
+	preview := DdlPreview{Tables: []TableDdl{}}
+	schema := conv.TargetSchema()
+	for _, t := range ddl.OrderTables(schema) {
+		preview.Tables = append(preview.Tables, TableDdl{Table: t, Generated: conv.TableDDL(t), Edited: conv.DdlEdits[t].Edited, Stale: stale[t]})
+	}
+	c := applyDDLConfig(conv)
+	c.Tables = false
+	preview.ForeignKeys = schema.GetDDL(c)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(preview)
+}
+
+// editDDLRequest is the payload of editDDL.
+type editDDLRequest struct {
+	Table string `json:"Table"`
+	Text  string `json:"Text"` // Edited statements separated by ';'. Empty to revert to the generated statements.
+}
+
+// editDDL replaces the generated DDL statements of a table with manually
+// edited statements, which are saved in the session.
+func editDDL(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req editDDLRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	if err := sessionState.Conv.EditTableDDL(req.Table, splitDDL(req.Text)); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// validateDDL checks that Spanner accepts the final DDL, including manual
+// edits, using the Spanner instance of the Spanner config. With query
+// parameter async=true, the DDL is validated in a background job, whose
+// result is the DdlValidation.
+func validateDDL(w http.ResponseWriter, r *http.Request) {
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	spConfig, err := config.GetSpannerConfig()
+	if err != nil || spConfig.GCPProjectID == "" || spConfig.SpannerInstanceID == "" {
+		http.Error(w, "Spanner project and instance are not configured", http.StatusBadRequest)
+		return
+	}
+	stmts := sessionState.Conv.SchemaDDL(applyDDLConfig(sessionState.Conv))
+	targetDb := sessionState.Conv.TargetDb
+	if r.URL.Query().Get("async") == "true" {
+		j := jobManager.Start("ddl-validation", func(ctx context.Context, j *jobs.Job) (interface{}, error) {
+			jobManager.Logf(j, "Validating %d DDL statements in instance %s", len(stmts), spConfig.SpannerInstanceID)
+			res, err := validateDDLStatements(ctx, spConfig, targetDb, stmts)
+			if err == nil {
+				jobManager.Logf(j, "Valid: %t %s", res.Valid, res.Error)
+			}
+			return res, err
+		})
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(j)
+		return
+	}
+	res, err := validateDDLStatements(context.Background(), spConfig, targetDb, stmts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// validateDDLStatements checks that Spanner accepts stmts. It returns an
+// error if stmts can't be checked.
+func validateDDLStatements(ctx context.Context, spConfig config.Config, targetDb string, stmts []string) (DdlValidation, error) {
+	adminClient, err := utils.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return DdlValidation{}, fmt.Errorf("Can't create admin client: %v", err)
+	}
+	defer adminClient.Close()
+	if err := conversion.ValidateDDLStatements(ctx, adminClient, spConfig.GCPProjectID, spConfig.SpannerInstanceID, targetDb, stmts); err != nil {
+		return DdlValidation{Valid: false, Error: err.Error()}, nil
+	}
+	return DdlValidation{Valid: true}, nil
+}
+
+// splitDDL splits text into DDL statements separated by ';', ignoring
+// separators in quoted strings and names.
+func splitDDL(text string) []string {
+	var stmts []string
+	var quote rune
+	start := 0
+	add := func(stmt string) {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	for i, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ';':
+			add(text[start:i])
+			start = i + 1
+		}
+	}
+	add(text[start:])
+	return stmts
+}
+
 // getOverview returns the overview of conversion.
 func getOverview(w http.ResponseWriter, r *http.Request) {
 	var buf bytes.Buffer
@@ -357,20 +616,22 @@ func getTypeMap(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
 		return
 	}
-	var typeMap map[string][]typeIssue
-	switch sessionState.Driver {
-	case constants.MYSQL, constants.MYSQLDUMP:
-		typeMap = mysqlTypeMap
-	case constants.POSTGRES, constants.PGDUMP:
-		typeMap = postgresTypeMap
-	case constants.SQLSERVER:
-		typeMap = sqlserverTypeMap
-	case constants.ORACLE:
-		typeMap = oracleTypeMap
-	default:
-		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", sessionState.Driver), http.StatusBadRequest)
+	filteredTypeMap, err := sessionTypeMap(sessionState)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(filteredTypeMap)
+}
+
+// sessionTypeMap returns the source to Spanner typemap of the driver of the
+// session, only for the source types used in current conversion.
+func sessionTypeMap(sessionState *session.SessionState) (map[string][]typeIssue, error) {
+	typeMap, err := driverTypeMap(sessionState.Driver)
+	if err != nil {
+		return nil, err
+	}
 	// Filter typeMap so it contains just the types SrcSchema uses.
 	filteredTypeMap := make(map[string][]typeIssue)
 	for _, srcTable := range sessionState.Conv.SrcSchema {
@@ -378,23 +639,10 @@ func getTypeMap(w http.ResponseWriter, r *http.Request) {
 			if _, ok := filteredTypeMap[colDef.Type.Name]; ok {
 				continue
 			}
-			// Timestamp and interval types do not have exact key in typemap.
-			// Typemap for  TIMESTAMP(6), TIMESTAMP(6) WITH LOCAL TIMEZONE,TIMESTAMP(6) WITH TIMEZONE is stored into TIMESTAMP key.
-			// Same goes with interval types like INTERVAL YEAR(2) TO MONTH, INTERVAL DAY(2) TO SECOND(6) etc.
-			// If exact key not found then check with regex.
-			if _, ok := typeMap[colDef.Type.Name]; !ok {
-				if oracle.TimestampReg.MatchString(colDef.Type.Name) {
-					filteredTypeMap[colDef.Type.Name] = typeMap["TIMESTAMP"]
-				} else if oracle.IntervalReg.MatchString(colDef.Type.Name) {
-					filteredTypeMap[colDef.Type.Name] = typeMap["INTERVAL"]
-				}
-				continue
-			}
-			filteredTypeMap[colDef.Type.Name] = typeMap[colDef.Type.Name]
+			filteredTypeMap[colDef.Type.Name] = toTypeIssues(typeMap.TypeOptions(colDef.Type.Name))
 		}
 	}
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(filteredTypeMap)
+	return filteredTypeMap, nil
 }
 
 // setTypeMapGlobal allows to change Spanner type globally.
@@ -415,6 +663,23 @@ func setTypeMapGlobal(w http.ResponseWriter, r *http.Request) {
 
 	sessionState := session.GetSessionState()
 
+	if err = applyTypeMap(sessionState, typeMap); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// applyTypeMap changes the Spanner type of the columns of the session whose
+// source type is in typeMap (a map from source type to Spanner type).
+func applyTypeMap(sessionState *session.SessionState, typeMap map[string]string) error {
 	// Redo source-to-Spanner typeMap using t (the mapping specified in the http request).
 	// We drive this process by iterating over the Spanner schema because we want to preserve all
 	// other customizations that have been performed via the UI (dropping columns, renaming columns
@@ -430,18 +695,17 @@ func setTypeMapGlobal(w http.ResponseWriter, r *http.Request) {
 			// column as is. Note that per-column type overrides could be lost in
 			// this process -- the mapping in typeMap always takes precendence.
 			if _, found := typeMap[srcColDef.Type.Name]; found {
-				updateType(typeMap[srcColDef.Type.Name], t, col, srcTable, w)
+				sp, ty, err := getType(typeMap[srcColDef.Type.Name], t, col, srcTable)
+				if err != nil {
+					return err
+				}
+				colDef := sp.ColDefs[col]
+				colDef.T = ty
+				sp.ColDefs[col] = colDef
 			}
 		}
 	}
-	helpers.UpdateSessionFile()
-
-	convm := session.ConvWithMetadata{
-		SessionMetadata: sessionState.SessionMetadata,
-		Conv:            *sessionState.Conv,
-	}
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(convm)
+	return nil
 }
 
 // Actions to be performed on a column.
@@ -450,12 +714,16 @@ func setTypeMapGlobal(w http.ResponseWriter, r *http.Request) {
 // (3) PK: "ADDED", "REMOVED" or ""
 // (4) NotNull: "ADDED", "REMOVED" or ""
 // (5) ToType: New type or empty string
+// (6) CommitTimestamp: "ADDED", "REMOVED" or ""
+// (7) EnumConversion: "string", "check", "array" or ""
 type updateCol struct {
-	Removed bool   `json:"Removed"`
-	Rename  string `json:"Rename"`
-	PK      string `json:"PK"`
-	NotNull string `json:"NotNull"`
-	ToType  string `json:"ToType"`
+	Removed         bool   `json:"Removed"`
+	Rename          string `json:"Rename"`
+	PK              string `json:"PK"`
+	NotNull         string `json:"NotNull"`
+	ToType          string `json:"ToType"`
+	CommitTimestamp string `json:"CommitTimestamp"`
+	EnumConversion  string `json:"EnumConversion"`
 }
 
 type updateTable struct {
@@ -469,6 +737,9 @@ type updateTable struct {
 // (3) Add or Remove Primary Key
 // (4) Add or Remove NotNull constraint
 // (5) Update Spanner type
+// (6) Allow or disallow commit timestamps in a TIMESTAMP column
+// (7) Convert an enumeration column (e.g. MySQL ENUM or SET) to STRING, to
+// STRING with a CHECK constraint, or to ARRAY<STRING>
 func updateTableSchema(w http.ResponseWriter, r *http.Request) {
 	reqBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -478,6 +749,12 @@ func updateTableSchema(w http.ResponseWriter, r *http.Request) {
 	var t updateTable
 
 	table := r.FormValue("table")
+	if r.FormValue("tableId") != "" {
+		if table, err = getTableName(session.GetSessionState().Conv, r); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
 
 	err = json.Unmarshal(reqBody, &t)
 	if err != nil {
@@ -497,11 +774,14 @@ func updateTableSchema(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		if v.Rename != "" && v.Rename != colName {
-			if status, err := canRenameOrChangeType(colName, table); err != nil {
+			if status, err := canRenameColumn(colName, table); err != nil {
 				http.Error(w, fmt.Sprintf("%v", err), status)
 				return
 			}
-			renameColumn(v.Rename, table, colName, srcTableName)
+			if err := sessionState.Conv.RenameColumn(table, colName, v.Rename); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 			colName = v.Rename
 		}
 		if v.PK != "" {
@@ -527,6 +807,18 @@ func updateTableSchema(w http.ResponseWriter, r *http.Request) {
 		if v.NotNull != "" {
 			updateNotNull(v.NotNull, table, colName)
 		}
+		if v.CommitTimestamp != "" {
+			if err := updateCommitTimestamp(v.CommitTimestamp, table, colName); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if v.EnumConversion != "" {
+			if err := sessionState.Conv.SetEnumConversion(table, colName, v.EnumConversion); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 	}
 	helpers.UpdateSessionFile()
 	convm := session.ConvWithMetadata{
@@ -775,6 +1067,522 @@ func dropForeignKey(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(convm)
 }
 
+type FkEnforcement struct {
+	Name        string `json:"Name"`
+	AppEnforced bool   `json:"AppEnforced"`
+}
+
+// setForeignKeyEnforcement marks a foreign key as enforced by the application
+// (or not). Foreign keys enforced by the application remain part of the
+// session, but aren't created in Spanner.
+func setForeignKeyEnforcement(w http.ResponseWriter, r *http.Request) {
+	table := r.FormValue("table")
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+	}
+
+	var fkEnforcement FkEnforcement
+	if err = json.Unmarshal(reqBody, &fkEnforcement); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil || sessionState.Driver == "" {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	sp := sessionState.Conv.SpSchema[table]
+	position := -1
+	for i, fk := range sp.Fks {
+		if fkEnforcement.Name == fk.Name {
+			position = i
+			break
+		}
+	}
+	if table == "" || fkEnforcement.Name == "" || position < 0 {
+		http.Error(w, fmt.Sprintf("No foreign key %s found in table %s", fkEnforcement.Name, table), http.StatusBadRequest)
+		return
+	}
+	sp.Fks[position].AppEnforced = fkEnforcement.AppEnforced
+	sessionState.Conv.SpSchema[table] = sp
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// getFkCycles returns the foreign key cycles of the Spanner schema, along
+// with the resolution of each foreign key on a cycle.
+func getFkCycles(w http.ResponseWriter, r *http.Request) {
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	cycles := internal.FkCycles(sessionState.Conv)
+	if cycles == nil {
+		cycles = []internal.FkCycle{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cycles)
+}
+
+type FkCycleResolution struct {
+	Name       string `json:"Name"`
+	Resolution string `json:"Resolution"`
+}
+
+// resolveFkCycle applies a resolution (drop, defer or application) to a
+// foreign key that is part of a foreign key cycle.
+func resolveFkCycle(w http.ResponseWriter, r *http.Request) {
+	table := r.FormValue("table")
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+	}
+
+	var resolution FkCycleResolution
+	if err = json.Unmarshal(reqBody, &resolution); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil || sessionState.Driver == "" {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	if err := sessionState.Conv.ResolveFkCycle(table, resolution.Name, resolution.Resolution); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// SchemaGraph is the Spanner schema as a graph, for rendering an ER diagram.
+type SchemaGraph struct {
+	Nodes []SchemaGraphNode
+	Edges []SchemaGraphEdge
+}
+
+// SchemaGraphNode is a Spanner table.
+type SchemaGraphNode struct {
+	Table    string // Spanner table name.
+	SrcTable string // Source table name.
+	Columns  int    // Number of columns.
+	Issues   int    // Number of schema conversion issues.
+	Level    int    // Load order level (see ddl.LoadOrder): a table only depends on tables with lower levels.
+}
+
+// SchemaGraphEdge is a foreign key or an interleaving relationship between
+// two Spanner tables. Edges go from the child table to the parent or
+// referenced table.
+type SchemaGraphEdge struct {
+	From string
+	To   string
+	Kind string // "foreignKey" or "interleave".
+	Name string // Name of the foreign key, empty for interleave edges.
+}
+
+// buildSchemaGraph returns the graph of the Spanner schema of conv. Nodes
+// are in load order, and foreign keys to tables that don't exist are left
+// out.
+func buildSchemaGraph(conv *internal.Conv) SchemaGraph {
+	g := SchemaGraph{Nodes: []SchemaGraphNode{}, Edges: []SchemaGraphEdge{}}
+	for level, tables := range ddl.LoadOrder(conv.SpSchema) {
+		for _, t := range tables {
+			ct := conv.SpSchema[t]
+			srcTable, _ := internal.GetSourceTable(conv, t)
+			issues := 0
+			for _, l := range conv.Issues[srcTable] {
+				issues += len(l)
+			}
+			g.Nodes = append(g.Nodes, SchemaGraphNode{Table: t, SrcTable: srcTable, Columns: len(ct.ColNames), Issues: issues, Level: level})
+			if ct.Parent != "" {
+				g.Edges = append(g.Edges, SchemaGraphEdge{From: t, To: ct.Parent, Kind: "interleave"})
+			}
+			for _, fk := range ct.Fks {
+				if _, ok := conv.SpSchema[fk.ReferTable]; ok {
+					g.Edges = append(g.Edges, SchemaGraphEdge{From: t, To: fk.ReferTable, Kind: "foreignKey", Name: fk.Name})
+				}
+			}
+		}
+	}
+	return g
+}
+
+// getSchemaGraph returns the Spanner schema as a graph of tables, with foreign
+// key and interleave edges.
+func getSchemaGraph(w http.ResponseWriter, r *http.Request) {
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(buildSchemaGraph(sessionState.Conv))
+}
+
+// DataPreview is a sample of the rows of a table, and their conversion to
+// Spanner.
+type DataPreview struct {
+	Columns []PreviewColumn
+	Rows    []PreviewRow
+}
+
+// PreviewColumn is a source column and the Spanner column it is converted
+// to.
+type PreviewColumn struct {
+	SrcCol  string
+	SrcType string
+	SpCol   string
+	SpType  string
+}
+
+// PreviewRow is a source row and its conversion to Spanner. Values are in
+// the order of DataPreview.Columns.
+type PreviewRow struct {
+	SrcVals []string
+	SpVals  []string // Empty if the row can't be converted.
+	Error   string   // Set if the row can't be converted.
+}
+
+// defaultPreviewRows is the default number of rows of a data preview.
+const defaultPreviewRows = 10
+
+// maxPreviewRows is the maximum number of rows of a data preview.
+const maxPreviewRows = 100
+
+// getDataPreview fetches sample rows of a Spanner table from the source
+// database, and converts them using the conversion functions of the driver,
+// so that type mappings can be checked on real data before migrating.
+func getDataPreview(w http.ResponseWriter, r *http.Request) {
+	table := r.FormValue("table")
+	n := defaultPreviewRows
+	if rows := r.FormValue("rows"); rows != "" {
+		var err error
+		if n, err = strconv.Atoi(rows); err != nil || n <= 0 || n > maxPreviewRows {
+			http.Error(w, fmt.Sprintf("rows must be a number between 1 and %d", maxPreviewRows), http.StatusBadRequest)
+			return
+		}
+	}
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	if sessionState.SourceDB == nil {
+		http.Error(w, "Data preview needs a direct connection to the source database", http.StatusBadRequest)
+		return
+	}
+	if _, ok := sessionState.Conv.SpSchema[table]; !ok {
+		http.Error(w, fmt.Sprintf("Table %s not found", table), http.StatusNotFound)
+		return
+	}
+	infoSchema, err := getInfoSchema(sessionState)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := common.PreviewData(sessionState.Conv, infoSchema, table, n)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't preview data of table %s: %v", table, err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(buildDataPreview(sessionState.Conv, table, rows))
+}
+
+// buildDataPreview lines up the source and converted values of rows, sample
+// rows of Spanner table spTable.
+func buildDataPreview(conv *internal.Conv, spTable string, rows []common.PreviewRow) DataPreview {
+	srcTable := conv.ToSource[spTable].Name
+	srcSchema := conv.SrcSchema[srcTable]
+	preview := DataPreview{Columns: []PreviewColumn{}, Rows: []PreviewRow{}}
+	for _, srcCol := range srcSchema.ColNames {
+		spCol := conv.ToSpanner[srcTable].Cols[srcCol]
+		preview.Columns = append(preview.Columns, PreviewColumn{
+			SrcCol:  srcCol,
+			SrcType: srcSchema.ColDefs[srcCol].Type.Print(),
+			SpCol:   spCol,
+			SpType:  conv.SpSchema[spTable].ColDefs[spCol].T.PrintColumnDefType(),
+		})
+	}
+	for _, row := range rows {
+		pr := PreviewRow{SrcVals: row.SrcVals, SpVals: []string{}}
+		if row.Err != nil {
+			pr.Error = row.Err.Error()
+			preview.Rows = append(preview.Rows, pr)
+			continue
+		}
+		vals := make(map[string]interface{})
+		for i, c := range row.SpCols {
+			vals[c] = row.SpVals[i]
+		}
+		for _, c := range preview.Columns {
+			v, ok := vals[c.SpCol]
+			if !ok {
+				pr.SpVals = append(pr.SpVals, "NULL")
+				continue
+			}
+			pr.SpVals = append(pr.SpVals, formatSpannerValue(v))
+		}
+		preview.Rows = append(preview.Rows, pr)
+	}
+	return preview
+}
+
+// formatSpannerValue returns the string representation of v, a value
+// converted for Spanner.
+func formatSpannerValue(v interface{}) string {
+	switch x := v.(type) {
+	case big.Rat:
+		return x.FloatString(9)
+	case *big.Rat:
+		return x.FloatString(9)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case string:
+		return x
+	default:
+		return fmt.Sprintf("%v", x)
+	}
+}
+
+// SearchRequest is the payload of searchSchema. Empty fields match
+// anything.
+type SearchRequest struct {
+	Text    string `json:"Text"`    // Case-insensitive substring of a Spanner or source table or column name.
+	SrcType string `json:"SrcType"` // Source type name, e.g. "varchar".
+	SpType  string `json:"SpType"`  // Spanner type name, e.g. "STRING".
+	Issue   string `json:"Issue"`   // Name of an outstanding schema issue, e.g. "Widened" (see internal.SchemaIssue.Name).
+}
+
+// SearchResult is a table with columns that match a SearchRequest.
+type SearchResult struct {
+	Table    string
+	SrcTable string
+	Columns  []SearchColumn
+}
+
+// SearchColumn is a column that matches a SearchRequest.
+type SearchColumn struct {
+	Col     string
+	SrcCol  string
+	SpType  string
+	SrcType string
+	Issues  []string // Names of outstanding schema issues.
+}
+
+// searchSchema returns the columns of the Spanner schema of conv that match
+// req, grouped by table. A column matches Text if its name or its table's
+// name does, so a search for a table name returns all of its columns.
+// Suppressed issues aren't outstanding, so they don't match Issue.
+func searchSchema(conv *internal.Conv, req SearchRequest) []SearchResult {
+	results := []SearchResult{}
+	text := strings.ToLower(req.Text)
+	contains := func(names ...string) bool {
+		for _, n := range names {
+			if strings.Contains(strings.ToLower(n), text) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, t := range ddl.OrderTables(conv.SpSchema) {
+		sp := conv.SpSchema[t]
+		srcTable := conv.ToSource[t].Name
+		res := SearchResult{Table: t, SrcTable: srcTable}
+		for _, col := range sp.ColNames {
+			srcCol := conv.ToSource[t].Cols[col]
+			c := SearchColumn{Col: col, SrcCol: srcCol, SpType: sp.ColDefs[col].T.Name, SrcType: conv.SrcSchema[srcTable].ColDefs[srcCol].Type.Name, Issues: []string{}}
+			for _, i := range conv.Issues[srcTable][srcCol] {
+				if !conv.IsSuppressed(srcTable, srcCol, i) {
+					c.Issues = append(c.Issues, i.Name())
+				}
+			}
+			if !contains(t, srcTable, col, srcCol) ||
+				(req.SrcType != "" && !strings.EqualFold(req.SrcType, c.SrcType)) ||
+				(req.SpType != "" && !strings.EqualFold(req.SpType, c.SpType)) {
+				continue
+			}
+			if req.Issue != "" {
+				found := false
+				for _, i := range c.Issues {
+					found = found || strings.EqualFold(req.Issue, i)
+				}
+				if !found {
+					continue
+				}
+			}
+			res.Columns = append(res.Columns, c)
+		}
+		if len(res.Columns) > 0 {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// search filters the tables and columns of the Spanner schema by name, source
+// type, Spanner type or outstanding schema issue.
+func search(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req SearchRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(searchSchema(sessionState.Conv, req))
+}
+
+// TableColumn identifies a column of a Spanner table.
+type TableColumn struct {
+	Table string `json:"Table"`
+	Col   string `json:"Col"`
+}
+
+// columnsTypeRequest is the payload of setColumnsType.
+type columnsTypeRequest struct {
+	Columns []TableColumn `json:"Columns"`
+	ToType  string        `json:"ToType"`
+}
+
+// setColumnsType changes the Spanner type of a set of columns, typically
+// selected from search results. The change is only made if the type of every
+// column can be changed.
+func setColumnsType(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req columnsTypeRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	var changed []TableColumn
+	for _, c := range req.Columns {
+		if _, ok := sessionState.Conv.SpSchema[c.Table].ColDefs[c.Col]; !ok {
+			http.Error(w, fmt.Sprintf("column : '%s' not found in table : '%s'", c.Col, c.Table), http.StatusNotFound)
+			return
+		}
+		srcTableName := sessionState.Conv.ToSource[c.Table].Name
+		typeChange, err := isTypeChanged(req.ToType, c.Table, c.Col, srcTableName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !typeChange {
+			continue
+		}
+		if status, err := canRenameOrChangeType(c.Col, c.Table); err != nil {
+			http.Error(w, fmt.Sprintf("%v", err), status)
+			return
+		}
+		changed = append(changed, c)
+	}
+	for _, c := range changed {
+		updateType(req.ToType, c.Table, c.Col, sessionState.Conv.ToSource[c.Table].Name, w)
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// ReviewQueue is the response of getReviewQueue.
+type ReviewQueue struct {
+	Items      []internal.ReviewItem
+	Unreviewed int // Count of errors and warnings without a review, which block applying the schema.
+}
+
+// getReviewQueue returns every outstanding schema issue along with its review.
+func getReviewQueue(w http.ResponseWriter, r *http.Request) {
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	items := sessionState.Conv.ReviewQueue()
+	if items == nil {
+		items = []internal.ReviewItem{}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ReviewQueue{Items: items, Unreviewed: len(sessionState.Conv.UnreviewedIssues())})
+}
+
+// reviewIssueRequest is the payload of reviewIssue.
+type reviewIssueRequest struct {
+	Table   string `json:"Table"`  // Source table name.
+	Column  string `json:"Column"` // Source column name, empty for table issues.
+	Issue   string `json:"Issue"`
+	State   string `json:"State"` // Empty to remove the review.
+	Comment string `json:"Comment"`
+}
+
+// reviewIssue records the review of a schema issue in the session.
+func reviewIssue(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req reviewIssueRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	if err := sessionState.Conv.ReviewIssue(req.Table, req.Column, req.Issue, req.State, req.Comment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
 // renameForeignKeys checks the new names for spanner name validity, ensures the new names are already not used by existing tables
 // secondary indexes or foreign key constraints. If above checks passed then foreignKey renaming reflected in the schema else appropriate
 // error thrown.
@@ -830,9 +1638,62 @@ func renameForeignKeys(w http.ResponseWriter, r *http.Request) {
 		}
 		newFKs = append(newFKs, foreignKey)
 	}
-	sp.Fks = newFKs
-
-	sessionState.Conv.SpSchema[table] = sp
+	sp.Fks = newFKs
+
+	sessionState.Conv.SpSchema[table] = sp
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// renameTableRequest is the payload of renameTable.
+type renameTableRequest struct {
+	Name string `json:"Name"`
+}
+
+// renameTable renames a Spanner table, given by query parameter table or
+// tableId. The mappings to the source table, and the indexes, foreign keys
+// and interleaving that refer to the table, are updated with the new name.
+func renameTable(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil || sessionState.Driver == "" {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	var req renameTableRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	table, err := getTableName(sessionState.Conv, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if ok, invalidNames := checkSpannerNamesValidity([]string{req.Name}); !ok || req.Name == "" {
+		http.Error(w, fmt.Sprintf("Following names are not valid Spanner identifiers: %s", strings.Join(invalidNames, ",")), http.StatusBadRequest)
+		return
+	}
+	if req.Name != table {
+		if ok, err := canRename([]string{req.Name}, table); !ok {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err = sessionState.Conv.RenameTable(table, req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	helpers.UpdateSessionFile()
 
 	convm := session.ConvWithMetadata{
@@ -843,6 +1704,24 @@ func renameForeignKeys(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(convm)
 }
 
+// getTableName returns the Spanner table given by query parameter tableId,
+// which doesn't change when the table is renamed, or else by query
+// parameter table.
+func getTableName(conv *internal.Conv, r *http.Request) (string, error) {
+	if id := r.FormValue("tableId"); id != "" {
+		table, found := conv.SpTableName(id)
+		if !found {
+			return "", fmt.Errorf("Table with id %s not found", id)
+		}
+		return table, nil
+	}
+	table := r.FormValue("table")
+	if _, found := conv.SpSchema[table]; !found {
+		return "", fmt.Errorf("Table %s not found", table)
+	}
+	return table, nil
+}
+
 // renameIndexes checks the new names for spanner name validity, ensures the new names are already not used by existing tables
 // secondary indexes or foreign key constraints. If above checks passed then index renaming reflected in the schema else appropriate
 // error thrown.
@@ -946,6 +1825,17 @@ func addIndexes(w http.ResponseWriter, r *http.Request) {
 
 	sp := sessionState.Conv.SpSchema[table]
 
+	if len(sp.Indexes)+len(newIndexes) > ddl.MaxIndexesPerTable {
+		http.Error(w, fmt.Sprintf("Spanner supports at most %d indexes per table", ddl.MaxIndexesPerTable), http.StatusBadRequest)
+		return
+	}
+	for _, index := range newIndexes {
+		if err := validateIndex(sp, index); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	for i := 0; i < len(newIndexes); i++ {
 		newIndexes[i].Id = uniqueid.GenerateIndexesId()
 	}
@@ -963,6 +1853,229 @@ func addIndexes(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(convm)
 }
 
+// keyStrategyRequest is the payload of setKeyStrategy.
+type keyStrategyRequest struct {
+	Strategy string `json:"Strategy"`
+	Shards   int64  `json:"Shards"`
+}
+
+// setKeyStrategy sets the strategy used to replace the auto-increment primary
+// key of a table: a bit-reversed sequence, UUIDs or a hash-based shard prefix.
+// An empty strategy restores the original primary key.
+func setKeyStrategy(w http.ResponseWriter, r *http.Request) {
+	table := r.FormValue("table")
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req keyStrategyRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	if _, ok := sessionState.Conv.SpSchema[table]; !ok {
+		http.Error(w, fmt.Sprintf("Table %s not found", table), http.StatusNotFound)
+		return
+	}
+	if req.Strategy == "" {
+		sessionState.Conv.ClearKeyStrategy(table)
+	} else if err = sessionState.Conv.SetKeyStrategy(table, req.Strategy, req.Shards); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Columns added by the key strategy need ids for the web UI.
+	sp := sessionState.Conv.SpSchema[table]
+	for name, cd := range sp.ColDefs {
+		if cd.Id == "" {
+			cd.Id = uniqueid.GenerateColumnId()
+			sp.ColDefs[name] = cd
+		}
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// splitTableRequest is the payload of splitTable.
+type splitTableRequest struct {
+	Parts      []internal.SplitPart `json:"Parts"`
+	Interleave bool                 `json:"Interleave"`
+}
+
+// splitTable splits a table vertically into several tables sharing its
+// primary key, e.g. to move blob columns out of a table of hot columns. The
+// parts are interleaved in the table, or reference it with a foreign key.
+// Empty parts remove the split.
+func splitTable(w http.ResponseWriter, r *http.Request) {
+	table := r.FormValue("table")
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req splitTableRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	if _, ok := sessionState.Conv.SpSchema[table]; !ok {
+		http.Error(w, fmt.Sprintf("Table %s not found", table), http.StatusNotFound)
+		return
+	}
+	if len(req.Parts) == 0 {
+		sessionState.Conv.ClearTableSplit(table)
+	} else if err = sessionState.Conv.SplitTable(table, req.Parts, req.Interleave); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// nestColumnsRequest is the payload of nestColumns.
+type nestColumnsRequest struct {
+	Tables []internal.NestedTable `json:"Tables"`
+}
+
+// nestColumns moves the elements of List and Map columns of a DynamoDB table
+// to child tables interleaved in its table, with a row per element. Empty
+// tables move the elements back to their columns.
+func nestColumns(w http.ResponseWriter, r *http.Request) {
+	table := r.FormValue("table")
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req nestColumnsRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	if _, ok := sessionState.Conv.SpSchema[table]; !ok {
+		http.Error(w, fmt.Sprintf("Table %s not found", table), http.StatusNotFound)
+		return
+	}
+	if err = sessionState.Conv.NestColumns(table, req.Tables); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// syntheticKeyRequest is the payload of setSyntheticKey.
+type syntheticKeyRequest struct {
+	Col  string `json:"Col"`
+	Type string `json:"Type"`
+}
+
+// setSyntheticKey sets the column name and type (sequence, uuid or ulid) of
+// the synthetic primary key of a table. If no table is specified, it applies
+// to all tables with synthetic primary keys.
+func setSyntheticKey(w http.ResponseWriter, r *http.Request) {
+	table := r.FormValue("table")
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req syntheticKeyRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	if table == "" {
+		err = sessionState.Conv.SetSyntheticKeys(req.Col, req.Type)
+	} else {
+		err = sessionState.Conv.SetSyntheticKey(table, req.Col, req.Type)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// datetimeZoneRequest is the payload of setDatetimeZone.
+type datetimeZoneRequest struct {
+	Column   string `json:"Column"`
+	TimeZone string `json:"TimeZone"`
+}
+
+// setDatetimeZone sets the time zone (an IANA name, e.g. America/New_York)
+// that the datetimes of a column, which have no time zone, are assumed to be
+// in when converted to Spanner timestamps. If no table is specified, it sets
+// the default for all columns. An empty time zone removes the column's
+// override, or resets the default to UTC.
+func setDatetimeZone(w http.ResponseWriter, r *http.Request) {
+	table := r.FormValue("table")
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Body Read Error : %v", err), http.StatusInternalServerError)
+		return
+	}
+	var req datetimeZoneRequest
+	if err = json.Unmarshal(reqBody, &req); err != nil {
+		http.Error(w, fmt.Sprintf("Request Body parse error : %v", err), http.StatusBadRequest)
+		return
+	}
+	sessionState := session.GetSessionState()
+	var srcTable, srcCol string
+	if table != "" {
+		if srcTable, err = internal.GetSourceTable(sessionState.Conv, table); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		var ok bool
+		if srcCol, ok = sessionState.Conv.ToSource[table].Cols[req.Column]; !ok {
+			http.Error(w, fmt.Sprintf("column %s not found in table %s", req.Column, table), http.StatusNotFound)
+			return
+		}
+	}
+	if err = sessionState.Conv.SetDatetimeZone(srcTable, srcCol, req.TimeZone); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	helpers.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
 func updateIndexes(w http.ResponseWriter, r *http.Request) {
 	table := r.FormValue("table")
 	reqBody, err := ioutil.ReadAll(r.Body)
@@ -981,6 +2094,15 @@ func updateIndexes(w http.ResponseWriter, r *http.Request) {
 
 	st := sessionState.Conv.SrcSchema[table]
 
+	if len(newIndexes) == 0 {
+		http.Error(w, "No index to update", http.StatusBadRequest)
+		return
+	}
+	if err := validateIndex(sp, newIndexes[0]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	for i, index := range sp.Indexes {
 
 		if index.Table == newIndexes[0].Table && index.Name == newIndexes[0].Name {
@@ -989,6 +2111,8 @@ func updateIndexes(w http.ResponseWriter, r *http.Request) {
 			sp.Indexes[i].Name = newIndexes[0].Name
 			sp.Indexes[i].Table = newIndexes[0].Table
 			sp.Indexes[i].Unique = newIndexes[0].Unique
+			sp.Indexes[i].NullFiltered = newIndexes[0].NullFiltered
+			sp.Indexes[i].StoredColumns = newIndexes[0].StoredColumns
 
 			break
 		}
@@ -1027,13 +2151,54 @@ func updateIndexes(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(convm)
 }
 
+// validateIndex checks that index is a valid secondary index of Spanner table
+// sp: key and stored columns must not be repeated, stored columns must be
+// existing non-key columns, and the number of key columns must be within
+// Spanner's limits.
+func validateIndex(sp ddl.CreateTable, index ddl.CreateIndex) error {
+	if len(index.Keys) == 0 {
+		return fmt.Errorf("index %s has no key columns", index.Name)
+	}
+	if len(index.Keys) > ddl.MaxIndexKeyColumns {
+		return fmt.Errorf("index %s has %d key columns, but Spanner supports at most %d", index.Name, len(index.Keys), ddl.MaxIndexKeyColumns)
+	}
+	keys := make(map[string]bool)
+	for _, k := range index.Keys {
+		if keys[k.Col] {
+			return fmt.Errorf("column %s appears more than once in the keys of index %s", k.Col, index.Name)
+		}
+		keys[k.Col] = true
+		if cd, ok := sp.ColDefs[k.Col]; ok && cd.T.IsArray {
+			return fmt.Errorf("array column %s can't be a key of index %s", k.Col, index.Name)
+		}
+	}
+	pks := make(map[string]bool)
+	for _, pk := range sp.Pks {
+		pks[pk.Col] = true
+	}
+	stored := make(map[string]bool)
+	for _, c := range index.StoredColumns {
+		if _, ok := sp.ColDefs[c]; !ok {
+			return fmt.Errorf("stored column %s of index %s is not a column of table %s", c, index.Name, sp.Name)
+		}
+		if keys[c] || pks[c] {
+			return fmt.Errorf("column %s of index %s is a key column, and is always stored", c, index.Name)
+		}
+		if stored[c] {
+			return fmt.Errorf("column %s appears more than once in the stored columns of index %s", c, index.Name)
+		}
+		stored[c] = true
+	}
+	return nil
+}
+
 func checkSpannerNamesValidity(input []string) (bool, []string) {
 	status := true
 	var invalidNewNames []string
-	for _, changed := range input {
-		if _, status := internal.FixName(changed); status {
+	for _, name := range input {
+		if _, changed := internal.FixName(name); changed {
 			status = false
-			invalidNewNames = append(invalidNewNames, changed)
+			invalidNewNames = append(invalidNewNames, name)
 		}
 	}
 	return status, invalidNewNames
@@ -1165,6 +2330,11 @@ func isPartOfSecondaryIndex(col, table string) (bool, string) {
 				return true, index.Name
 			}
 		}
+		for _, c := range index.StoredColumns {
+			if c == col {
+				return true, index.Name
+			}
+		}
 	}
 	return false, ""
 }
@@ -1219,7 +2389,11 @@ func canRemoveColumn(colName, table string) (int, error) {
 	return http.StatusOK, nil
 }
 
-func canRenameOrChangeType(colName, table string) (int, error) {
+// canRenameColumn checks that column colName of table can be renamed.
+// Renames update the indexes and foreign keys using the column, but the
+// primary key columns of interleaved tables must keep the names of the
+// primary key columns of their parent.
+func canRenameColumn(colName, table string) (int, error) {
 	sessionState := session.GetSessionState()
 
 	isPartOfPK := isPartOfPK(colName, table)
@@ -1228,6 +2402,13 @@ func canRenameOrChangeType(colName, table string) (int, error) {
 	if isPartOfPK && (isParent || isChild) {
 		return http.StatusBadRequest, fmt.Errorf("column : '%s' in table : '%s' is part of parent-child relation with schema : '%s'", colName, table, childSchema)
 	}
+	return http.StatusOK, nil
+}
+
+func canRenameOrChangeType(colName, table string) (int, error) {
+	if status, err := canRenameColumn(colName, table); err != nil {
+		return status, err
+	}
 	if isPartOfSecondaryIndex, indexName := isPartOfSecondaryIndex(colName, table); isPartOfSecondaryIndex {
 		return http.StatusPreconditionFailed, fmt.Errorf("column : '%s' in table : '%s' is part of secondary index : '%s', remove secondary index before making the update",
 			colName, table, indexName)
@@ -1411,39 +2592,6 @@ func removeColumn(table string, colName string, srcTableName string) {
 	sessionState.Conv.SpSchema[table] = sp
 }
 
-func renameColumn(newName, table, colName, srcTableName string) {
-	sessionState := session.GetSessionState()
-
-	sp := sessionState.Conv.SpSchema[table]
-	for i, col := range sp.ColNames {
-		if col == colName {
-			sp.ColNames[i] = newName
-			break
-		}
-	}
-	if _, found := sp.ColDefs[colName]; found {
-		sp.ColDefs[newName] = ddl.ColumnDef{
-			Name:    newName,
-			T:       sp.ColDefs[colName].T,
-			NotNull: sp.ColDefs[colName].NotNull,
-			Comment: sp.ColDefs[colName].Comment,
-			Id:      sp.ColDefs[colName].Id,
-		}
-		delete(sp.ColDefs, colName)
-	}
-	for i, pk := range sp.Pks {
-		if pk.Col == colName {
-			sp.Pks[i].Col = newName
-			break
-		}
-	}
-	srcColName := sessionState.Conv.ToSource[table].Cols[colName]
-	sessionState.Conv.ToSpanner[srcTableName].Cols[srcColName] = newName
-	sessionState.Conv.ToSource[table].Cols[newName] = srcColName
-	delete(sessionState.Conv.ToSource[table].Cols, colName)
-	sessionState.Conv.SpSchema[table] = sp
-}
-
 func updateType(newType, table, colName, srcTableName string, w http.ResponseWriter) {
 	sp, ty, err := getType(newType, table, colName, srcTableName)
 	if err != nil {
@@ -1451,7 +2599,21 @@ func updateType(newType, table, colName, srcTableName string, w http.ResponseWri
 		return
 	}
 	colDef := sp.ColDefs[colName]
+	// CHECK expressions, e.g. of domains, are written for the previous type.
+	if colDef.T.Name != ty.Name || colDef.T.IsArray != ty.IsArray {
+		colDef.Check = ""
+	}
 	colDef.T = ty
+	// Only TIMESTAMP columns can hold commit timestamps.
+	colDef.AllowCommitTimestamp = colDef.AllowCommitTimestamp && ty.Name == ddl.Timestamp && !ty.IsArray
+	// CHECK constraints enumerate STRING values.
+	if ty.Name != ddl.String {
+		colDef.AllowedValues = nil
+	}
+	// Sequences generate INT64 values.
+	if ty.Name != ddl.Int64 || ty.IsArray {
+		colDef.Sequence = nil
+	}
 	sp.ColDefs[colName] = colDef
 }
 
@@ -1470,23 +2632,18 @@ func getType(newType, table, colName string, srcTableName string) (ddl.CreateTab
 	sp := sessionState.Conv.SpSchema[table]
 	srcColName := sessionState.Conv.ToSource[table].Cols[colName]
 	srcCol := sessionState.Conv.SrcSchema[srcTableName].ColDefs[srcColName]
-	var ty ddl.Type
-	var issues []internal.SchemaIssue
-	switch sessionState.Driver {
-	case constants.MYSQL, constants.MYSQLDUMP:
-		ty, issues = toSpannerTypeMySQL(srcCol.Type.Name, newType, srcCol.Type.Mods)
-	case constants.PGDUMP, constants.POSTGRES:
-		ty, issues = toSpannerTypePostgres(srcCol.Type.Name, newType, srcCol.Type.Mods)
-	case constants.SQLSERVER:
-		ty, issues = toSpannerTypeSQLserver(srcCol.Type.Name, newType, srcCol.Type.Mods)
-	case constants.ORACLE:
-		ty, issues = oracle.ToSpannerTypeWeb(sessionState.Conv, newType, srcCol.Type.Name, srcCol.Type.Mods)
-	default:
-		return sp, ty, fmt.Errorf("driver : '%s' is not supported", sessionState.Driver)
+	ty, issues, err := toSpannerType(sessionState, srcCol.Type.Name, newType, srcCol.Type.Mods)
+	if err != nil {
+		return sp, ty, err
 	}
-	if len(srcCol.Type.ArrayBounds) > 1 {
+	switch {
+	case len(srcCol.Type.ArrayBounds) > 1:
 		ty = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
 		issues = append(issues, internal.MultiDimensionalArray)
+	case len(srcCol.Type.ArrayBounds) == 1 && sessionState.Conv.TargetDb == constants.TargetExperimentalPostgres:
+		// PostgreSQL dialect databases don't support arrays, so array values
+		// are written as strings, e.g. '{1,2,3}'.
+		ty = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
 	}
 	if srcCol.Ignored.Default {
 		issues = append(issues, internal.DefaultValue)
@@ -1497,10 +2654,39 @@ func getType(newType, table, colName string, srcTableName string) (ddl.CreateTab
 	if sessionState.Conv.Issues != nil && len(issues) > 0 {
 		sessionState.Conv.Issues[srcTableName][srcCol.Name] = issues
 	}
-	ty.IsArray = len(srcCol.Type.ArrayBounds) == 1
+	ty.IsArray = len(srcCol.Type.ArrayBounds) == 1 && sessionState.Conv.TargetDb != constants.TargetExperimentalPostgres
+	// Sets converted to STRING (see SetEnumConversion) stay scalar.
+	if len(srcCol.Type.Values) > 0 && !sp.ColDefs[colName].T.IsArray {
+		ty.IsArray = false
+	}
 	return sp, ty, nil
 }
 
+// toSpannerType maps srcType of the driver of the session to spType. If
+// spType is empty, srcType is mapped to its default Spanner type.
+func toSpannerType(sessionState *session.SessionState, srcType, spType string, mods []int64) (ddl.Type, []internal.SchemaIssue, error) {
+	typeMap, err := driverTypeMap(sessionState.Driver)
+	if err != nil {
+		return ddl.Type{}, nil, err
+	}
+	ty, issues := typeMap.ToSpannerType(srcType, spType, mods)
+	return ty, issues, nil
+}
+
+// driverTypeMap returns the typemap of driver, which is declared by the
+// driver next to its toddl implementation, so that the web app offers the
+// same default Spanner types as schema conversion.
+func driverTypeMap(driver string) (common.TypeMap, error) {
+	if driver == constants.SPANNER {
+		return spanner.TypeMap, nil
+	}
+	d, found := registry.Get(driver)
+	if !found {
+		return common.TypeMap{}, fmt.Errorf("Driver : '%s' is not supported", driver)
+	}
+	return d.TypeMap(), nil
+}
+
 func updateNotNull(notNullChange, table, colName string) {
 	sessionState := session.GetSessionState()
 
@@ -1517,6 +2703,29 @@ func updateNotNull(notNullChange, table, colName string) {
 	}
 }
 
+// updateCommitTimestamp allows or disallows commit timestamps in column
+// colName of table. Columns that allow them are set to the commit timestamp
+// of the write by data migration, instead of their source value.
+func updateCommitTimestamp(commitTimestampChange, table, colName string) error {
+	sessionState := session.GetSessionState()
+
+	sp := sessionState.Conv.SpSchema[table]
+	spColDef := sp.ColDefs[colName]
+	switch commitTimestampChange {
+	case "ADDED":
+		if spColDef.T.Name != ddl.Timestamp || spColDef.T.IsArray {
+			return fmt.Errorf("column %s of table %s must be of type %s to hold commit timestamps", colName, table, ddl.Timestamp)
+		}
+		spColDef.AllowCommitTimestamp = true
+	case "REMOVED":
+		spColDef.AllowCommitTimestamp = false
+	default:
+		return fmt.Errorf("invalid commit timestamp change %s, accepted values are: ADDED, REMOVED", commitTimestampChange)
+	}
+	sp.ColDefs[colName] = spColDef
+	return nil
+}
+
 func rateSchema(cols, warnings int64, missingPKey bool) string {
 	good := func(total, badCount int64) bool { return badCount < total/20 }
 	ok := func(total, badCount int64) bool { return badCount < total/3 }
@@ -1571,17 +2780,18 @@ type typeIssue struct {
 	Brief string
 }
 
-func addTypeToList(convertedType string, spType string, issues []internal.SchemaIssue, l []typeIssue) []typeIssue {
-	if convertedType == spType {
-		if len(issues) > 0 {
-			var briefs []string
-			for _, issue := range issues {
-				briefs = append(briefs, internal.IssueDB[issue].Brief)
-			}
-			l = append(l, typeIssue{T: spType, Brief: fmt.Sprintf(strings.Join(briefs, ", "))})
-		} else {
-			l = append(l, typeIssue{T: spType})
+func toTypeIssues(options []common.TypeOption) []typeIssue {
+	var l []typeIssue
+	for _, o := range options {
+		if o.Condition != "" {
+			l = append(l, typeIssue{T: o.Name, Brief: o.Condition})
+			continue
 		}
+		var briefs []string
+		for _, issue := range o.Issues {
+			briefs = append(briefs, internal.IssueDB[issue].Brief)
+		}
+		l = append(l, typeIssue{T: o.Name, Brief: strings.Join(briefs, ", ")})
 	}
 	return l
 }
@@ -1591,57 +2801,46 @@ func init() {
 
 	uniqueid.InitObjectId()
 
-	// Initialize mysqlTypeMap.
-	for _, srcType := range []string{"bool", "boolean", "varchar", "char", "text", "tinytext", "mediumtext", "longtext", "set", "enum", "json", "bit", "binary", "varbinary", "blob", "tinyblob", "mediumblob", "longblob", "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "double", "float", "numeric", "decimal", "date", "datetime", "timestamp", "time", "year", "geometrycollection", "multipoint", "multilinestring", "multipolygon", "point", "linestring", "polygon", "geometry"} {
-		var l []typeIssue
-		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric} {
-			ty, issues := toSpannerTypeMySQL(srcType, spType, []int64{})
-			l = addTypeToList(ty.Name, spType, issues, l)
-		}
-		if srcType == "tinyint" {
-			l = append(l, typeIssue{T: ddl.Bool, Brief: "Only tinyint(1) can be converted to BOOL, for any other mods it will be converted to INT64"})
-		}
-		mysqlTypeMap[srcType] = l
-	}
-	// Initialize postgresTypeMap.
-	for _, srcType := range []string{"bool", "boolean", "bigserial", "bpchar", "character", "bytea", "date", "float8", "double precision", "float4", "real", "int8", "bigint", "int4", "integer", "int2", "smallint", "numeric", "serial", "text", "timestamptz", "timestamp with time zone", "timestamp", "timestamp without time zone", "varchar", "character varying"} {
-		var l []typeIssue
-		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric} {
-			ty, issues := toSpannerTypePostgres(srcType, spType, []int64{})
-			l = addTypeToList(ty.Name, spType, issues, l)
-		}
-		postgresTypeMap[srcType] = l
-	}
-
-	// Initialize sqlserverTypeMap.
-	for _, srcType := range []string{"int", "tinyint", "smallint", "bigint", "bit", "float", "real", "numeric", "decimal", "money", "smallmoney", "char", "nchar", "varchar", "nvarchar", "text", "ntext", "date", "datetime", "datetime2", "smalldatetime", "datetimeoffset", "time", "timestamp", "rowversion", "binary", "varbinary", "image", "xml", "geography", "geometry", "uniqueidentifier", "sql_variant", "hierarchyid"} {
-		var l []typeIssue
-		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric} {
-			ty, issues := toSpannerTypeSQLserver(srcType, spType, []int64{})
-			l = addTypeToList(ty.Name, spType, issues, l)
-		}
-		sqlserverTypeMap[srcType] = l
-	}
-
-	// Initialize oracleTypeMap.
-	for _, srcType := range []string{"NUMBER", "BFILE", "BLOB", "CHAR", "CLOB", "DATE", "BINARY_DOUBLE", "BINARY_FLOAT", "FLOAT", "LONG", "RAW", "LONG RAW", "NCHAR", "NVARCHAR2", "VARCHAR", "VARCHAR2", "NCLOB", "ROWID", "UROWID", "XMLTYPE", "TIMESTAMP", "INTERVAL", "SDO_GEOMETRY"} {
-		var l []typeIssue
-		for _, spType := range []string{ddl.Bool, ddl.Bytes, ddl.Date, ddl.Float64, ddl.Int64, ddl.String, ddl.Timestamp, ddl.Numeric} {
-			ty, issues := oracle.ToSpannerTypeWeb(sessionState.Conv, spType, srcType, []int64{})
-			l = addTypeToList(ty.Name, spType, issues, l)
-		}
-		oracleTypeMap[srcType] = l
-	}
-
 	sessionState.Conv = internal.MakeConv()
 	config := config.TryInitializeSpannerConfig()
 	session.SetSessionStorageConnectionState(config.GCPProjectID, config.SpannerInstanceID)
 }
 
+// jobManager runs the background jobs of the web app. Jobs aren't persisted
+// until App starts.
+var jobManager = jobs.NewManager("")
+
+// snapshotter takes snapshots of the session. Automatic snapshots are
+// disabled until App starts.
+var snapshotter = session.NewSnapshotter(session.SnapshotPolicy{})
+
+// typeMapPresets stores the custom type-mapping presets. They aren't
+// persisted until App starts.
+var typeMapPresets = newPresetStore("")
+
 // App connects to the web app v2.
 func App() {
 	addr := ":8080"
+	if err := os.MkdirAll(conversion.OutputDir, os.ModePerm); err != nil {
+		log.Printf("Can't create output directory: %v\n", err)
+	}
+	jobManager = jobs.NewManager(filepath.Join(conversion.OutputDir, "jobs.json"))
+	typeMapPresets = newPresetStore(filepath.Join(conversion.OutputDir, "typemap_presets.json"))
+	authConfig, err := auth.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	snapshotPolicy, err := session.SnapshotPolicyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	snapshotter.Policy = snapshotPolicy
 	router := getRoutes()
+	router.Use(auth.NewAuthenticator(authConfig).Middleware)
+	router.Use(snapshotter.Middleware)
+	if authConfig.Mode != auth.ModeNone {
+		log.Printf("Authenticating users with %s\n", authConfig.Mode)
+	}
 	log.Printf("Starting server at port 8080\n")
-	log.Fatal(http.ListenAndServe(addr, handlers.CORS(handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"}), handlers.AllowedMethods([]string{"GET", "POST", "PUT", "HEAD", "OPTIONS"}), handlers.AllowedOrigins([]string{"*"}))(router)))
+	log.Fatal(http.ListenAndServe(addr, handlers.CORS(handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"}), handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS"}), handlers.AllowedOrigins([]string{"*"}))(router)))
 }
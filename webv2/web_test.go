@@ -18,20 +18,42 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/cloudspannerecosystem/harbourbridge/common/constants"
+	"github.com/cloudspannerecosystem/harbourbridge/conversion"
 	"github.com/cloudspannerecosystem/harbourbridge/internal"
 	"github.com/cloudspannerecosystem/harbourbridge/proto/migration"
 	"github.com/cloudspannerecosystem/harbourbridge/schema"
 	"github.com/cloudspannerecosystem/harbourbridge/spanner/ddl"
+	"github.com/cloudspannerecosystem/harbourbridge/webv2/auth"
 	"github.com/cloudspannerecosystem/harbourbridge/webv2/session"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
 
+// TestMain writes the files generated by the handlers (session files,
+// reports and schemas) to a temporary directory rather than the source tree.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "harbour_bridge_output")
+	if err != nil {
+		panic(err)
+	}
+	conversion.OutputDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestGetTypeMapNoDriver(t *testing.T) {
 	req, err := http.NewRequest("GET", "/typemap", nil)
 	if err != nil {
@@ -373,7 +395,8 @@ func TestUpdateTableSchema(t *testing.T) {
 			},
 		},
 		{
-			name:  "Test rename fail column part of secondary index",
+			// Renames update the secondary indexes and foreign keys using the column.
+			name:  "Test rename column part of secondary index",
 			table: "t1",
 			payload: `
 		{
@@ -381,7 +404,7 @@ func TestUpdateTableSchema(t *testing.T) {
 			"b": { "Rename": "bb" }
 		}
 		}`,
-			statusCode: http.StatusPreconditionFailed,
+			statusCode: http.StatusOK,
 			conv: &internal.Conv{
 				SpSchema: map[string]ddl.CreateTable{
 					"t1": {
@@ -397,10 +420,29 @@ func TestUpdateTableSchema(t *testing.T) {
 				ToSource: map[string]internal.NameAndCols{
 					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "b": "b"}},
 				},
+				Audit: internal.Audit{
+					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+				},
+			},
+			expectedConv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Name:     "t1",
+						ColNames: []string{"a", "bb"},
+						ColDefs: map[string]ddl.ColumnDef{
+							"a":  {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+							"bb": {Name: "bb", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+						},
+						Pks:     []ddl.IndexKey{{Col: "a"}},
+						Indexes: []ddl.CreateIndex{{Name: "idx", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "bb", Desc: false}}}},
+					}},
+				ToSource: map[string]internal.NameAndCols{
+					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "bb": "b"}},
+				},
 			},
 		},
 		{
-			name:  "Test rename fail column part of FK",
+			name:  "Test rename column part of FK",
 			table: "t1",
 			payload: `
 		{
@@ -408,7 +450,7 @@ func TestUpdateTableSchema(t *testing.T) {
 			"b": { "Rename": "bb" }
 		}
 		}`,
-			statusCode: http.StatusPreconditionFailed,
+			statusCode: http.StatusOK,
 			conv: &internal.Conv{
 				SpSchema: map[string]ddl.CreateTable{
 					"t1": {
@@ -424,10 +466,29 @@ func TestUpdateTableSchema(t *testing.T) {
 				ToSource: map[string]internal.NameAndCols{
 					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "b": "b"}},
 				},
+				Audit: internal.Audit{
+					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+				},
+			},
+			expectedConv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Name:     "t1",
+						ColNames: []string{"a", "bb"},
+						ColDefs: map[string]ddl.ColumnDef{
+							"a":  {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+							"bb": {Name: "bb", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+						},
+						Pks: []ddl.IndexKey{{Col: "a"}},
+						Fks: []ddl.Foreignkey{{Name: "fk1", Columns: []string{"bb"}, ReferTable: "t2", ReferColumns: []string{"b"}}},
+					}},
+				ToSource: map[string]internal.NameAndCols{
+					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "bb": "b"}},
+				},
 			},
 		},
 		{
-			name:  "Test rename fail column referenced by FK",
+			name:  "Test rename column referenced by FK",
 			table: "t1",
 			payload: `
 		{
@@ -435,7 +496,7 @@ func TestUpdateTableSchema(t *testing.T) {
 			"b": { "Rename": "bb" }
 		}
 		}`,
-			statusCode: http.StatusPreconditionFailed,
+			statusCode: http.StatusOK,
 			conv: &internal.Conv{
 				SpSchema: map[string]ddl.CreateTable{
 					"t1": {
@@ -461,6 +522,61 @@ func TestUpdateTableSchema(t *testing.T) {
 				ToSource: map[string]internal.NameAndCols{
 					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "b": "b"}},
 				},
+				Audit: internal.Audit{
+					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+				},
+			},
+			expectedConv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Name:     "t1",
+						ColNames: []string{"a", "bb"},
+						ColDefs: map[string]ddl.ColumnDef{
+							"a":  {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+							"bb": {Name: "bb", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+						},
+						Pks: []ddl.IndexKey{{Col: "a"}},
+					},
+					"t2": {
+						Name:     "t2",
+						ColNames: []string{"aa", "bb"},
+						ColDefs: map[string]ddl.ColumnDef{
+							"aa": {Name: "aa", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+							"bb": {Name: "bb", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+						},
+						Pks: []ddl.IndexKey{{Col: "aa"}},
+						Fks: []ddl.Foreignkey{{Name: "fk1", Columns: []string{"bb"}, ReferTable: "t1", ReferColumns: []string{"bb"}}},
+					},
+				},
+				ToSource: map[string]internal.NameAndCols{
+					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "bb": "b"}},
+				},
+			},
+		},
+		{
+			name:  "Test rename fail name used by another column",
+			table: "t1",
+			payload: `
+		{
+		  "UpdateCols":{
+			"b": { "Rename": "a" }
+		}
+		}`,
+			statusCode: http.StatusBadRequest,
+			conv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Name:     "t1",
+						ColNames: []string{"a", "b"},
+						ColDefs: map[string]ddl.ColumnDef{
+							"a": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+							"b": {Name: "b", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+						},
+						Pks: []ddl.IndexKey{{Col: "a"}},
+					}},
+				ToSource: map[string]internal.NameAndCols{
+					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "b": "b"}},
+				},
 			},
 		},
 		{
@@ -641,6 +757,79 @@ func TestUpdateTableSchema(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "Test allow or disallow commit timestamp",
+			table: "t1",
+			payload: `
+    {
+      "UpdateCols":{
+		"b": { "CommitTimestamp": "ADDED" },
+		"c": { "CommitTimestamp": "REMOVED" }
+	}
+    }`,
+			statusCode: http.StatusOK,
+			conv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Name:     "t1",
+						ColNames: []string{"a", "b", "c"},
+						ColDefs: map[string]ddl.ColumnDef{
+							"a": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+							"b": {Name: "b", T: ddl.Type{Name: ddl.Timestamp}},
+							"c": {Name: "c", T: ddl.Type{Name: ddl.Timestamp}, AllowCommitTimestamp: true},
+						},
+						Pks: []ddl.IndexKey{{Col: "a"}},
+					}},
+				ToSource: map[string]internal.NameAndCols{
+					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "b": "b", "c": "c"}},
+				},
+				Audit: internal.Audit{
+					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+				},
+			},
+			expectedConv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Name:     "t1",
+						ColNames: []string{"a", "b", "c"},
+						ColDefs: map[string]ddl.ColumnDef{
+							"a": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+							"b": {Name: "b", T: ddl.Type{Name: ddl.Timestamp}, AllowCommitTimestamp: true},
+							"c": {Name: "c", T: ddl.Type{Name: ddl.Timestamp}},
+						},
+						Pks: []ddl.IndexKey{{Col: "a"}},
+					}},
+				ToSource: map[string]internal.NameAndCols{
+					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "b": "b", "c": "c"}},
+				},
+			},
+		},
+		{
+			name:  "Test allow commit timestamp fail for non timestamp column",
+			table: "t1",
+			payload: `
+    {
+      "UpdateCols":{
+		"b": { "CommitTimestamp": "ADDED" }
+	}
+    }`,
+			statusCode: http.StatusBadRequest,
+			conv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Name:     "t1",
+						ColNames: []string{"a", "b"},
+						ColDefs: map[string]ddl.ColumnDef{
+							"a": {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+							"b": {Name: "b", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+						},
+						Pks: []ddl.IndexKey{{Col: "a"}},
+					}},
+				ToSource: map[string]internal.NameAndCols{
+					"t1": {Name: "t1", Cols: map[string]string{"a": "a", "b": "b"}},
+				},
+			},
+		},
 	}
 	for _, tc := range tc {
 		sessionState := session.GetSessionState()
@@ -877,7 +1066,8 @@ func TestGetTypeMapMySQL(t *testing.T) {
 			{T: ddl.String}},
 		"json": {
 			{T: ddl.Bytes},
-			{T: ddl.String}},
+			{T: ddl.String},
+			{T: ddl.JSON}},
 		"binary": {
 			{T: ddl.Bytes},
 			{T: ddl.String}},
@@ -1488,26 +1678,25 @@ func TestDropForeignKey(t *testing.T) {
 	}
 }
 
-func TestRenameIndexes(t *testing.T) {
+func TestSetForeignKeyEnforcement(t *testing.T) {
 	tc := []struct {
 		name         string
 		table        string
-		input        interface{}
+		payload      string
 		statusCode   int64
 		conv         *internal.Conv
 		expectedConv *internal.Conv
 	}{
 		{
-			name:  "Test rename indexes",
-			table: "t1",
-			input: map[string]string{
-				"idx": "idx_new",
-			},
+			name:       "Test mark FK as enforced by the application",
+			table:      "t1",
+			payload:    `{"Name":"fk2", "AppEnforced":true}`,
 			statusCode: http.StatusOK,
 			conv: &internal.Conv{
 				SpSchema: map[string]ddl.CreateTable{
 					"t1": {
-						Indexes: []ddl.CreateIndex{{Name: "idx", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+						Fks: []ddl.Foreignkey{{Name: "fk1", Columns: []string{"b"}, ReferTable: "reft1", ReferColumns: []string{"ref_b"}},
+							{Name: "fk2", Columns: []string{"c", "d"}, ReferTable: "reft2", ReferColumns: []string{"ref_c", "ref_d"}}},
 					}},
 				Audit: internal.Audit{
 					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
@@ -1516,23 +1705,20 @@ func TestRenameIndexes(t *testing.T) {
 			expectedConv: &internal.Conv{
 				SpSchema: map[string]ddl.CreateTable{
 					"t1": {
-						Indexes: []ddl.CreateIndex{{Name: "idx_new", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+						Fks: []ddl.Foreignkey{{Name: "fk1", Columns: []string{"b"}, ReferTable: "reft1", ReferColumns: []string{"ref_b"}},
+							{Name: "fk2", Columns: []string{"c", "d"}, ReferTable: "reft2", ReferColumns: []string{"ref_c", "ref_d"}, AppEnforced: true}},
 					}},
 			},
 		},
 		{
-			name:  "Test rename multiple indexes",
-			table: "t1",
-			input: map[string]string{
-				"idx_1": "idx_new_1",
-				"idx_2": "idx_new_2",
-			},
+			name:       "Test unmark FK as enforced by the application",
+			table:      "t1",
+			payload:    `{"Name":"fk1", "AppEnforced":false}`,
 			statusCode: http.StatusOK,
 			conv: &internal.Conv{
 				SpSchema: map[string]ddl.CreateTable{
 					"t1": {
-						Indexes: []ddl.CreateIndex{{Name: "idx_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
-							{Name: "idx_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+						Fks: []ddl.Foreignkey{{Name: "fk1", Columns: []string{"b"}, ReferTable: "reft1", ReferColumns: []string{"ref_b"}, AppEnforced: true}},
 					}},
 				Audit: internal.Audit{
 					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
@@ -1541,69 +1727,607 @@ func TestRenameIndexes(t *testing.T) {
 			expectedConv: &internal.Conv{
 				SpSchema: map[string]ddl.CreateTable{
 					"t1": {
-						Indexes: []ddl.CreateIndex{{Name: "idx_new_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
-							{Name: "idx_new_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+						Fks: []ddl.Foreignkey{{Name: "fk1", Columns: []string{"b"}, ReferTable: "reft1", ReferColumns: []string{"ref_b"}}},
 					}},
 			},
 		},
 		{
-			name:  "New name conflicts with an existing table",
-			table: "t1",
-			input: map[string]string{
-				"idx_1": "t1",
-			},
+			name:       "Test unknown FK",
+			table:      "t1",
+			payload:    `{"Name":"AB", "AppEnforced":true}`,
 			statusCode: http.StatusBadRequest,
 			conv: &internal.Conv{
 				SpSchema: map[string]ddl.CreateTable{
 					"t1": {
-						Indexes: []ddl.CreateIndex{{Name: "idx_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
-							{Name: "idx_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+						Fks: []ddl.Foreignkey{{Name: "fk1", Columns: []string{"b"}, ReferTable: "reft1", ReferColumns: []string{"ref_b"}}},
 					}},
 				Audit: internal.Audit{
 					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
 				},
 			},
-			expectedConv: &internal.Conv{
-				SpSchema: map[string]ddl.CreateTable{
-					"t1": {
-						Indexes: []ddl.CreateIndex{{Name: "idx_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
-							{Name: "idx_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
-					}},
-			},
 		},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+
+		sessionState.Driver = constants.MYSQL
+		sessionState.Conv = tc.conv
+		payload := tc.payload
+		req, err := http.NewRequest("POST", "/fk/enforcement?table="+tc.table, strings.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(setForeignKeyEnforcement)
+		handler.ServeHTTP(rr, req)
+		var res *internal.Conv
+		json.Unmarshal(rr.Body.Bytes(), &res)
+		if status := rr.Code; int64(status) != tc.statusCode {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v",
+				tc.name, status, tc.statusCode)
+		}
+		if tc.statusCode == http.StatusOK {
+			assert.Equal(t, tc.expectedConv, res, tc.name)
+		}
+	}
+}
+
+func TestResolveFkCycle(t *testing.T) {
+	fk := func(name, refer string) []ddl.Foreignkey {
+		return []ddl.Foreignkey{{Name: name, Columns: []string{"x"}, ReferTable: refer, ReferColumns: []string{"x"}}}
+	}
+	tc := []struct {
+		name       string
+		table      string
+		payload    string
+		statusCode int64
+		expectedFk []ddl.Foreignkey
+	}{
 		{
-			name:  "New name conflicts with an existing index",
-			table: "t1",
-			input: map[string]string{
-				"idx_1": "idx_2",
-			},
+			name:       "Test defer FK on cycle",
+			table:      "a",
+			payload:    `{"Name":"fk_a", "Resolution":"defer"}`,
+			statusCode: http.StatusOK,
+			expectedFk: []ddl.Foreignkey{{Name: "fk_a", Columns: []string{"x"}, ReferTable: "b", ReferColumns: []string{"x"}, Deferred: true}},
+		},
+		{
+			name:       "Test drop FK on cycle",
+			table:      "a",
+			payload:    `{"Name":"fk_a", "Resolution":"drop"}`,
+			statusCode: http.StatusOK,
+			expectedFk: []ddl.Foreignkey{},
+		},
+		{
+			name:       "Test FK not on cycle",
+			table:      "c",
+			payload:    `{"Name":"fk_c", "Resolution":"defer"}`,
 			statusCode: http.StatusBadRequest,
-			conv: &internal.Conv{
-				SpSchema: map[string]ddl.CreateTable{
-					"t1": {
-						Indexes: []ddl.CreateIndex{{Name: "idx_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
-							{Name: "idx_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
-					}},
-				Audit: internal.Audit{
-					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
-				},
-			},
-			expectedConv: &internal.Conv{
-				SpSchema: map[string]ddl.CreateTable{
-					"t1": {
-						Indexes: []ddl.CreateIndex{{Name: "idx_new_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
-							{Name: "idx_new_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
-					}},
-			},
 		},
 		{
-			name:  "New name conflicts with an existing foreign key",
-			table: "t1",
-			input: map[string]string{
-				"idx_1": "fk1",
-			},
+			name:       "Test unknown resolution",
+			table:      "a",
+			payload:    `{"Name":"fk_a", "Resolution":"ignore"}`,
 			statusCode: http.StatusBadRequest,
-			conv: &internal.Conv{
+		},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.Conv = internal.MakeConv()
+		sessionState.Conv.SpSchema = ddl.Schema{
+			"a": {Name: "a", Fks: fk("fk_a", "b")},
+			"b": {Name: "b", Fks: fk("fk_b", "a")},
+			"c": {Name: "c", Fks: fk("fk_c", "a")},
+		}
+		req, err := http.NewRequest("POST", "/fk/cycle?table="+tc.table, strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(resolveFkCycle)
+		handler.ServeHTTP(rr, req)
+		if status := rr.Code; int64(status) != tc.statusCode {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v",
+				tc.name, status, tc.statusCode)
+		}
+		if tc.statusCode == http.StatusOK {
+			assert.Equal(t, tc.expectedFk, sessionState.Conv.SpSchema[tc.table].Fks, tc.name)
+		}
+	}
+}
+
+func TestGetSchemaGraph(t *testing.T) {
+	sessionState := session.GetSessionState()
+	sessionState.Driver = constants.MYSQL
+	sessionState.Conv = internal.MakeConv()
+	sessionState.Conv.SpSchema = ddl.Schema{
+		"users":  {Name: "users", ColNames: []string{"id"}},
+		"orders": {Name: "orders", ColNames: []string{"id", "user_id"}, Fks: []ddl.Foreignkey{{Name: "fk_users", Columns: []string{"user_id"}, ReferTable: "users", ReferColumns: []string{"id"}}, {Name: "fk_gone", Columns: []string{"x"}, ReferTable: "gone", ReferColumns: []string{"x"}}}},
+		"items":  {Name: "items", ColNames: []string{"id", "order_id", "n"}, Parent: "orders"},
+	}
+	sessionState.Conv.ToSource = map[string]internal.NameAndCols{"users": {Name: "Users"}, "orders": {Name: "orders"}, "items": {Name: "items"}}
+	sessionState.Conv.Issues = map[string]map[string][]internal.SchemaIssue{"Users": {"id": {internal.Widened, internal.Serial}}}
+	req, err := http.NewRequest("GET", "/graph", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getSchemaGraph)
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var g SchemaGraph
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &g))
+	assert.Equal(t, SchemaGraph{
+		Nodes: []SchemaGraphNode{
+			{Table: "users", SrcTable: "Users", Columns: 1, Issues: 2, Level: 0},
+			{Table: "orders", SrcTable: "orders", Columns: 2, Level: 1},
+			{Table: "items", SrcTable: "items", Columns: 3, Level: 2},
+		},
+		Edges: []SchemaGraphEdge{
+			{From: "orders", To: "users", Kind: "foreignKey", Name: "fk_users"},
+			{From: "items", To: "orders", Kind: "interleave"},
+		},
+	}, g)
+}
+
+func searchTestConv() *internal.Conv {
+	conv := internal.MakeConv()
+	conv.SpSchema = ddl.Schema{
+		"users": {
+			Name:     "users",
+			ColNames: []string{"id", "name"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":   {Name: "id", T: ddl.Type{Name: ddl.Int64}},
+				"name": {Name: "name", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			Pks: []ddl.IndexKey{{Col: "id"}},
+		},
+		"orders": {
+			Name:     "orders",
+			ColNames: []string{"id", "note", "code"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"id":   {Name: "id", T: ddl.Type{Name: ddl.Int64}},
+				"note": {Name: "note", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"code": {Name: "code", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			Pks:     []ddl.IndexKey{{Col: "id"}},
+			Indexes: []ddl.CreateIndex{{Name: "idx_code", Table: "orders", Keys: []ddl.IndexKey{{Col: "code"}}}},
+		},
+	}
+	conv.SrcSchema = map[string]schema.Table{
+		"users": {
+			Name:     "users",
+			ColNames: []string{"id", "name"},
+			ColDefs: map[string]schema.Column{
+				"id":   {Name: "id", Type: schema.Type{Name: "bigint"}},
+				"name": {Name: "name", Type: schema.Type{Name: "varchar"}},
+			},
+		},
+		"orders": {
+			Name:     "orders",
+			ColNames: []string{"id", "note", "code"},
+			ColDefs: map[string]schema.Column{
+				"id":   {Name: "id", Type: schema.Type{Name: "int"}},
+				"note": {Name: "note", Type: schema.Type{Name: "text"}},
+				"code": {Name: "code", Type: schema.Type{Name: "varchar"}},
+			},
+		},
+	}
+	conv.ToSource = map[string]internal.NameAndCols{
+		"users":  {Name: "users", Cols: map[string]string{"id": "id", "name": "name"}},
+		"orders": {Name: "orders", Cols: map[string]string{"id": "id", "note": "note", "code": "code"}},
+	}
+	conv.ToSpanner = conv.ToSource
+	conv.Issues = map[string]map[string][]internal.SchemaIssue{
+		"users":  {"id": {internal.Widened}},
+		"orders": {"id": {internal.Widened}},
+	}
+	return conv
+}
+
+func TestSearch(t *testing.T) {
+	tc := []struct {
+		name     string
+		payload  string
+		expected map[string][]string // Maps table to matching columns.
+	}{
+		{"Test table name", `{"Text": "USER"}`, map[string][]string{"users": {"id", "name"}}},
+		{"Test column name", `{"Text": "na"}`, map[string][]string{"users": {"name"}}},
+		{"Test source type", `{"SrcType": "varchar"}`, map[string][]string{"orders": {"code"}, "users": {"name"}}},
+		{"Test Spanner type and name", `{"Text": "orders", "SpType": "string"}`, map[string][]string{"orders": {"note", "code"}}},
+		{"Test issue", `{"Issue": "Widened"}`, map[string][]string{"orders": {"id"}, "users": {"id"}}},
+		{"Test no match", `{"Text": "zzz"}`, map[string][]string{}},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.Conv = searchTestConv()
+		// Suppressed issues aren't outstanding.
+		sessionState.Conv.SetIssueSuppressions([]internal.IssueSuppression{{Issue: "Widened", Table: "orders"}})
+		if tc.name == "Test issue" {
+			sessionState.Conv.SetIssueSuppressions(nil)
+		}
+		req, err := http.NewRequest("POST", "/search", strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(search)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, tc.name)
+		var res []SearchResult
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res), tc.name)
+		got := make(map[string][]string)
+		for _, r := range res {
+			for _, c := range r.Columns {
+				got[r.Table] = append(got[r.Table], c.Col)
+			}
+		}
+		assert.Equal(t, tc.expected, got, tc.name)
+	}
+}
+
+func TestSetColumnsType(t *testing.T) {
+	tc := []struct {
+		name       string
+		payload    string
+		statusCode int64
+		expected   map[string]string // Maps table.column to expected Spanner type.
+	}{
+		{
+			name:       "Test change type of several columns",
+			payload:    `{"Columns": [{"Table": "users", "Col": "name"}, {"Table": "orders", "Col": "note"}], "ToType": "BYTES"}`,
+			statusCode: http.StatusOK,
+			expected:   map[string]string{"users.name": ddl.Bytes, "orders.note": ddl.Bytes},
+		},
+		{
+			name:       "Test column in secondary index",
+			payload:    `{"Columns": [{"Table": "users", "Col": "name"}, {"Table": "orders", "Col": "code"}], "ToType": "BYTES"}`,
+			statusCode: http.StatusPreconditionFailed,
+			expected:   map[string]string{"users.name": ddl.String, "orders.code": ddl.String},
+		},
+		{
+			name:       "Test unknown column",
+			payload:    `{"Columns": [{"Table": "users", "Col": "age"}], "ToType": "BYTES"}`,
+			statusCode: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.Conv = searchTestConv()
+		req, err := http.NewRequest("POST", "/typemap/columns", strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(setColumnsType)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, int(tc.statusCode), rr.Code, tc.name)
+		for tableCol, ty := range tc.expected {
+			l := strings.Split(tableCol, ".")
+			assert.Equal(t, ty, sessionState.Conv.SpSchema[l[0]].ColDefs[l[1]].T.Name, tc.name)
+		}
+	}
+}
+
+func TestReviewQueue(t *testing.T) {
+	tc := []struct {
+		name       string
+		payload    string
+		statusCode int64
+		expected   internal.IssueReview
+		unreviewed int
+	}{
+		{
+			name:       "Test acknowledge issue",
+			payload:    `{"Table": "users", "Column": "id", "Issue": "Widened", "State": "acknowledged"}`,
+			statusCode: http.StatusOK,
+			expected:   internal.IssueReview{State: internal.ReviewAcknowledged},
+			unreviewed: 1,
+		},
+		{
+			name:       "Test override issue",
+			payload:    `{"Table": "users", "Column": "id", "Issue": "NoGoodType", "State": "overridden", "Comment": "ids are small"}`,
+			statusCode: http.StatusOK,
+			expected:   internal.IssueReview{State: internal.ReviewOverridden, Comment: "ids are small"},
+			unreviewed: 0,
+		},
+		{
+			name:       "Test unknown state",
+			payload:    `{"Table": "users", "Column": "id", "Issue": "NoGoodType", "State": "ignored"}`,
+			statusCode: http.StatusBadRequest,
+			unreviewed: 1,
+		},
+		{
+			name:       "Test unknown issue",
+			payload:    `{"Table": "users", "Column": "name", "Issue": "NoGoodType", "State": "resolved"}`,
+			statusCode: http.StatusBadRequest,
+			unreviewed: 1,
+		},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.Conv = searchTestConv()
+		sessionState.Conv.Issues["users"]["id"] = []internal.SchemaIssue{internal.Widened, internal.NoGoodType}
+		req, err := http.NewRequest("POST", "/review", strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(reviewIssue)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, int(tc.statusCode), rr.Code, tc.name)
+
+		req, err = http.NewRequest("GET", "/review", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr = httptest.NewRecorder()
+		handler = http.HandlerFunc(getReviewQueue)
+		handler.ServeHTTP(rr, req)
+		var res ReviewQueue
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res), tc.name)
+		assert.Equal(t, 3, len(res.Items), tc.name)
+		assert.Equal(t, tc.unreviewed, res.Unreviewed, tc.name)
+		if tc.statusCode == http.StatusOK {
+			var p reviewIssueRequest
+			json.Unmarshal([]byte(tc.payload), &p)
+			for _, item := range res.Items {
+				if item.Table == p.Table && item.Column == p.Column && item.Issue == p.Issue {
+					assert.Equal(t, tc.expected, item.IssueReview, tc.name)
+				}
+			}
+		}
+	}
+}
+
+func TestEditDDL(t *testing.T) {
+	tc := []struct {
+		name       string
+		payload    string
+		statusCode int64
+		expected   []string // Expected edited statements of table orders.
+	}{
+		{
+			name:       "Test edit statements",
+			payload:    "{\"Table\": \"orders\", \"Text\": \"CREATE TABLE orders (id INT64, note STRING(MAX)) PRIMARY KEY (id);\\n\\nCREATE INDEX idx_code ON orders (note, code);\\n\"}",
+			statusCode: http.StatusOK,
+			expected:   []string{"CREATE TABLE orders (id INT64, note STRING(MAX)) PRIMARY KEY (id)", "CREATE INDEX idx_code ON orders (note, code)"},
+		},
+		{
+			name:       "Test separator in string",
+			payload:    `{"Table": "orders", "Text": "CREATE TABLE orders (id INT64, note STRING(MAX) DEFAULT (';')) PRIMARY KEY (id)"}`,
+			statusCode: http.StatusOK,
+			expected:   []string{"CREATE TABLE orders (id INT64, note STRING(MAX) DEFAULT (';')) PRIMARY KEY (id)"},
+		},
+		{
+			name:       "Test revert edit",
+			payload:    `{"Table": "orders", "Text": ""}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Test unknown table",
+			payload:    `{"Table": "invoices", "Text": "CREATE TABLE invoices (id INT64) PRIMARY KEY (id)"}`,
+			statusCode: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.Conv = searchTestConv()
+		sessionState.Conv.DdlEdits = map[string]internal.DdlEdit{"orders": {Edited: []string{"CREATE TABLE orders (id INT64) PRIMARY KEY (id)"}}}
+		req, err := http.NewRequest("POST", "/ddl/edit", strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(editDDL)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, int(tc.statusCode), rr.Code, tc.name)
+		if tc.statusCode != http.StatusOK {
+			continue
+		}
+
+		req, err = http.NewRequest("GET", "/ddl/preview", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr = httptest.NewRecorder()
+		handler = http.HandlerFunc(getDDLPreview)
+		handler.ServeHTTP(rr, req)
+		var res DdlPreview
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res), tc.name)
+		assert.Equal(t, 2, len(res.Tables), tc.name)
+		assert.Equal(t, "orders", res.Tables[0].Table, tc.name)
+		assert.Equal(t, sessionState.Conv.TableDDL("orders"), res.Tables[0].Generated, tc.name)
+		assert.Equal(t, tc.expected, res.Tables[0].Edited, tc.name)
+		assert.False(t, res.Tables[0].Stale, tc.name)
+	}
+}
+
+func TestGetDataPreview(t *testing.T) {
+	tc := []struct {
+		name       string
+		query      string
+		statusCode int64
+		expected   DataPreview
+	}{
+		{
+			name:       "Test preview",
+			query:      "table=users&rows=3",
+			statusCode: http.StatusOK,
+			expected: DataPreview{
+				Columns: []PreviewColumn{{SrcCol: "id", SrcType: "bigint", SpCol: "id", SpType: "INT64"}, {SrcCol: "name", SrcType: "varchar", SpCol: "name", SpType: "STRING(MAX)"}},
+				Rows: []PreviewRow{
+					{SrcVals: []string{"1", "ann"}, SpVals: []string{"1", "ann"}},
+					{SrcVals: []string{"2", "NULL"}, SpVals: []string{"2", "NULL"}},
+					{SrcVals: []string{"x", "bob"}, SpVals: []string{}, Error: `can't convert to int64: strconv.ParseInt: parsing "x": invalid syntax`},
+				},
+			},
+		},
+		{
+			name:       "Test too many rows",
+			query:      "table=users&rows=1000",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Test unknown table",
+			query:      "table=invoices",
+			statusCode: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tc {
+		db, mock, err := sqlmock.New()
+		assert.Nil(t, err)
+		mock.ExpectQuery("SELECT `id`,`name` FROM `test`.`users` LIMIT 3").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "name"}).AddRow("1", "ann").AddRow("2", nil).AddRow("x", "bob"))
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.DbName = "test"
+		sessionState.SourceDB = db
+		sessionState.Conv = searchTestConv()
+		req, err := http.NewRequest("GET", "/preview?"+tc.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(getDataPreview)
+		handler.ServeHTTP(rr, req)
+		sessionState.SourceDB = nil
+		assert.Equal(t, int(tc.statusCode), rr.Code, tc.name)
+		if tc.statusCode != http.StatusOK {
+			continue
+		}
+		var res DataPreview
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res), tc.name)
+		assert.Equal(t, tc.expected, res, tc.name)
+	}
+}
+
+func TestRenameIndexes(t *testing.T) {
+	tc := []struct {
+		name         string
+		table        string
+		input        interface{}
+		statusCode   int64
+		conv         *internal.Conv
+		expectedConv *internal.Conv
+	}{
+		{
+			name:  "Test rename indexes",
+			table: "t1",
+			input: map[string]string{
+				"idx": "idx_new",
+			},
+			statusCode: http.StatusOK,
+			conv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Indexes: []ddl.CreateIndex{{Name: "idx", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+					}},
+				Audit: internal.Audit{
+					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+				},
+			},
+			expectedConv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Indexes: []ddl.CreateIndex{{Name: "idx_new", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+					}},
+			},
+		},
+		{
+			name:  "Test rename multiple indexes",
+			table: "t1",
+			input: map[string]string{
+				"idx_1": "idx_new_1",
+				"idx_2": "idx_new_2",
+			},
+			statusCode: http.StatusOK,
+			conv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Indexes: []ddl.CreateIndex{{Name: "idx_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
+							{Name: "idx_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+					}},
+				Audit: internal.Audit{
+					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+				},
+			},
+			expectedConv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Indexes: []ddl.CreateIndex{{Name: "idx_new_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
+							{Name: "idx_new_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+					}},
+			},
+		},
+		{
+			name:  "New name conflicts with an existing table",
+			table: "t1",
+			input: map[string]string{
+				"idx_1": "t1",
+			},
+			statusCode: http.StatusBadRequest,
+			conv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Indexes: []ddl.CreateIndex{{Name: "idx_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
+							{Name: "idx_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+					}},
+				Audit: internal.Audit{
+					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+				},
+			},
+			expectedConv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Indexes: []ddl.CreateIndex{{Name: "idx_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
+							{Name: "idx_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+					}},
+			},
+		},
+		{
+			name:  "New name conflicts with an existing index",
+			table: "t1",
+			input: map[string]string{
+				"idx_1": "idx_2",
+			},
+			statusCode: http.StatusBadRequest,
+			conv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Indexes: []ddl.CreateIndex{{Name: "idx_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
+							{Name: "idx_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+					}},
+				Audit: internal.Audit{
+					MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+				},
+			},
+			expectedConv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Indexes: []ddl.CreateIndex{{Name: "idx_new_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
+							{Name: "idx_new_2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}}},
+					}},
+			},
+		},
+		{
+			name:  "New name conflicts with an existing foreign key",
+			table: "t1",
+			input: map[string]string{
+				"idx_1": "fk1",
+			},
+			statusCode: http.StatusBadRequest,
+			conv: &internal.Conv{
 				SpSchema: map[string]ddl.CreateTable{
 					"t1": {
 						Indexes: []ddl.CreateIndex{{Name: "idx_1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
@@ -2130,8 +2854,8 @@ func TestAddIndexes(t *testing.T) {
 					"t1": {
 						Indexes: []ddl.CreateIndex{{Name: "idx1", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
 							{Name: "idx2", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "c", Desc: false}, {Col: "d", Desc: false}}},
-							{Id: "i2", Name: "idx3", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
-							{Id: "i3", Name: "idx4", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
+							{Id: "i1", Name: "idx3", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
+							{Id: "i2", Name: "idx4", Table: "t1", Unique: false, Keys: []ddl.IndexKey{{Col: "b", Desc: false}}},
 						},
 					}},
 			},
@@ -2300,6 +3024,43 @@ func TestAddIndexes(t *testing.T) {
 	}
 }
 
+func TestValidateIndex(t *testing.T) {
+	sp := ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"a", "b", "c", "d"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a": {Name: "a", T: ddl.Type{Name: ddl.Int64}},
+			"b": {Name: "b", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c": {Name: "c", T: ddl.Type{Name: ddl.Int64, IsArray: true}},
+			"d": {Name: "d", T: ddl.Type{Name: ddl.Float64}},
+		},
+		Pks: []ddl.IndexKey{{Col: "a"}},
+	}
+	var tooManyKeys []ddl.IndexKey
+	for i := 0; i <= ddl.MaxIndexKeyColumns; i++ {
+		tooManyKeys = append(tooManyKeys, ddl.IndexKey{Col: fmt.Sprintf("c%d", i)})
+	}
+	tc := []struct {
+		name  string
+		index ddl.CreateIndex
+		valid bool
+	}{
+		{"Valid", ddl.CreateIndex{Name: "idx", Keys: []ddl.IndexKey{{Col: "b", Desc: true}}, NullFiltered: true, StoredColumns: []string{"d"}}, true},
+		{"No keys", ddl.CreateIndex{Name: "idx"}, false},
+		{"Too many keys", ddl.CreateIndex{Name: "idx", Keys: tooManyKeys}, false},
+		{"Repeated key", ddl.CreateIndex{Name: "idx", Keys: []ddl.IndexKey{{Col: "b"}, {Col: "b", Desc: true}}}, false},
+		{"Array key", ddl.CreateIndex{Name: "idx", Keys: []ddl.IndexKey{{Col: "c"}}}, false},
+		{"Unknown stored column", ddl.CreateIndex{Name: "idx", Keys: []ddl.IndexKey{{Col: "b"}}, StoredColumns: []string{"e"}}, false},
+		{"Stored key column", ddl.CreateIndex{Name: "idx", Keys: []ddl.IndexKey{{Col: "b"}}, StoredColumns: []string{"b"}}, false},
+		{"Stored primary key column", ddl.CreateIndex{Name: "idx", Keys: []ddl.IndexKey{{Col: "b"}}, StoredColumns: []string{"a"}}, false},
+		{"Repeated stored column", ddl.CreateIndex{Name: "idx", Keys: []ddl.IndexKey{{Col: "b"}}, StoredColumns: []string{"d", "d"}}, false},
+	}
+	for _, tc := range tc {
+		err := validateIndex(sp, tc.index)
+		assert.Equal(t, tc.valid, err == nil, tc.name)
+	}
+}
+
 func TestDropSecondaryIndex(t *testing.T) {
 	tc := []struct {
 		name         string
@@ -2362,29 +3123,168 @@ func TestDropSecondaryIndex(t *testing.T) {
 			},
 		},
 	}
-	for _, tc := range tc {
-		sessionState := session.GetSessionState()
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+
+		sessionState.Driver = constants.MYSQL
+		sessionState.Conv = tc.conv
+		payload := tc.payload
+		req, err := http.NewRequest("POST", "/drop/secondaryindex?table="+tc.table, strings.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(dropSecondaryIndex)
+		handler.ServeHTTP(rr, req)
+		var res *internal.Conv
+		json.Unmarshal(rr.Body.Bytes(), &res)
+		if status := rr.Code; int64(status) != tc.statusCode {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, tc.statusCode)
+		}
+		if tc.statusCode == http.StatusOK {
+			assert.Equal(t, tc.expectedConv, res)
+		}
+	}
+}
+
+func TestSetKeyStrategy(t *testing.T) {
+	tc := []struct {
+		name       string
+		table      string
+		payload    string
+		statusCode int64
+		pks        []ddl.IndexKey
+	}{
+		{name: "Hash prefix", table: "t1", payload: `{"Strategy":"hash_prefix","Shards":8}`, statusCode: http.StatusOK, pks: []ddl.IndexKey{{Col: "a_shard", Order: 1}, {Col: "a", Order: 2}}},
+		{name: "Clear", table: "t1", payload: `{"Strategy":""}`, statusCode: http.StatusOK, pks: []ddl.IndexKey{{Col: "a", Order: 1}}},
+		{name: "Unknown strategy", table: "t1", payload: `{"Strategy":"serial"}`, statusCode: http.StatusBadRequest},
+		{name: "Unknown table", table: "t2", payload: `{"Strategy":"uuid"}`, statusCode: http.StatusNotFound},
+	}
+	sessionState := session.GetSessionState()
+	sessionState.Driver = constants.MYSQL
+	sessionState.Conv = internal.MakeConv()
+	sessionState.Conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"a"},
+		ColDefs:  map[string]ddl.ColumnDef{"a": {Name: "a", T: ddl.Type{Name: ddl.Int64}, NotNull: true, Id: "c1"}},
+		Pks:      []ddl.IndexKey{{Col: "a", Order: 1}},
+	}
+	for _, tc := range tc {
+		req, err := http.NewRequest("POST", "/keystrategy?table="+tc.table, strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(setKeyStrategy)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, int64(rr.Code), tc.name)
+		if tc.statusCode == http.StatusOK {
+			assert.Equal(t, tc.pks, sessionState.Conv.SpSchema["t1"].Pks, tc.name)
+			for _, cd := range sessionState.Conv.SpSchema["t1"].ColDefs {
+				assert.NotEmpty(t, cd.Id, tc.name)
+			}
+		}
+	}
+}
 
-		sessionState.Driver = constants.MYSQL
-		sessionState.Conv = tc.conv
-		payload := tc.payload
-		req, err := http.NewRequest("POST", "/drop/secondaryindex?table="+tc.table, strings.NewReader(payload))
+func TestSplitTable(t *testing.T) {
+	tc := []struct {
+		name       string
+		table      string
+		payload    string
+		statusCode int64
+		parts      []string
+	}{
+		{name: "Split", table: "t1", payload: `{"Parts":[{"Table":"t1_blobs","Cols":["c"]}],"Interleave":true}`, statusCode: http.StatusOK, parts: []string{"t1_blobs"}},
+		{name: "Key column", table: "t1", payload: `{"Parts":[{"Table":"t1_keys","Cols":["a"]}]}`, statusCode: http.StatusBadRequest, parts: []string{"t1_blobs"}},
+		{name: "Clear", table: "t1", payload: `{"Parts":[]}`, statusCode: http.StatusOK},
+		{name: "Unknown table", table: "t2", payload: `{"Parts":[{"Table":"t2_blobs","Cols":["c"]}]}`, statusCode: http.StatusNotFound},
+	}
+	sessionState := session.GetSessionState()
+	sessionState.Driver = constants.MYSQL
+	sessionState.Conv = internal.MakeConv()
+	sessionState.Conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"a", "b", "c"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a": {Name: "a", T: ddl.Type{Name: ddl.Int64}, NotNull: true, Id: "c1"},
+			"b": {Name: "b", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, Id: "c2"},
+			"c": {Name: "c", T: ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, Id: "c3"},
+		},
+		Pks: []ddl.IndexKey{{Col: "a", Order: 1}},
+	}
+	for _, tc := range tc {
+		req, err := http.NewRequest("POST", "/split?table="+tc.table, strings.NewReader(tc.payload))
 		if err != nil {
 			t.Fatal(err)
 		}
 		req.Header.Set("Content-Type", "application/json")
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(dropSecondaryIndex)
+		handler := http.HandlerFunc(splitTable)
 		handler.ServeHTTP(rr, req)
-		var res *internal.Conv
-		json.Unmarshal(rr.Body.Bytes(), &res)
-		if status := rr.Code; int64(status) != tc.statusCode {
-			t.Errorf("handler returned wrong status code: got %v want %v",
-				status, tc.statusCode)
+		assert.Equal(t, tc.statusCode, int64(rr.Code), tc.name)
+		var parts []string
+		for _, p := range sessionState.Conv.TableSplits["t1"].Parts {
+			parts = append(parts, p.Table)
 		}
-		if tc.statusCode == http.StatusOK {
-			assert.Equal(t, tc.expectedConv, res)
+		assert.Equal(t, tc.parts, parts, tc.name)
+	}
+}
+
+func TestNestColumns(t *testing.T) {
+	tc := []struct {
+		name       string
+		table      string
+		payload    string
+		statusCode int64
+		tables     []string
+	}{
+		{name: "Nest", table: "t1", payload: `{"Tables":[{"Table":"t1_items","Col":"items"}]}`, statusCode: http.StatusOK, tables: []string{"t1_items"}},
+		{name: "Not a List or Map", table: "t1", payload: `{"Tables":[{"Table":"t1_b","Col":"b"}]}`, statusCode: http.StatusBadRequest, tables: []string{"t1_items"}},
+		{name: "Clear", table: "t1", payload: `{"Tables":[]}`, statusCode: http.StatusOK},
+		{name: "Unknown table", table: "t2", payload: `{"Tables":[{"Table":"t2_items","Col":"items"}]}`, statusCode: http.StatusNotFound},
+	}
+	sessionState := session.GetSessionState()
+	sessionState.Driver = constants.DYNAMODB
+	sessionState.Conv = internal.MakeConv()
+	sessionState.Conv.SpSchema["t1"] = ddl.CreateTable{
+		Name:     "t1",
+		ColNames: []string{"a", "b", "items"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"a":     {Name: "a", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, NotNull: true, Id: "c1"},
+			"b":     {Name: "b", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, Id: "c2"},
+			"items": {Name: "items", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, Id: "c3"},
+		},
+		Pks: []ddl.IndexKey{{Col: "a", Order: 1}},
+	}
+	sessionState.Conv.SrcSchema["t1"] = schema.Table{
+		Name:     "t1",
+		ColNames: []string{"a", "b", "items"},
+		ColDefs: map[string]schema.Column{
+			"a":     {Name: "a", Type: schema.Type{Name: "String"}},
+			"b":     {Name: "b", Type: schema.Type{Name: "String"}},
+			"items": {Name: "items", Type: schema.Type{Name: "List"}},
+		},
+	}
+	sessionState.Conv.ToSource["t1"] = internal.NameAndCols{Name: "t1", Cols: map[string]string{"a": "a", "b": "b", "items": "items"}}
+	for _, tc := range tc {
+		req, err := http.NewRequest("POST", "/nested?table="+tc.table, strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(nestColumns)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, int64(rr.Code), tc.name)
+		var tables []string
+		for _, n := range sessionState.Conv.NestedTables["t1"] {
+			tables = append(tables, n.Table)
 		}
+		assert.Equal(t, tc.tables, tables, tc.name)
 	}
 }
 
@@ -2491,7 +3391,7 @@ func buildConvMySQL(conv *internal.Conv) {
 			"a": {internal.Widened},
 		},
 	}
-	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{"synth_id", 0}
+	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{Col: "synth_id", Sequence: 0}
 	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_AND_DATA.Enum()
 }
 
@@ -2600,6 +3500,561 @@ func buildConvPostgres(conv *internal.Conv) {
 			"b": {internal.Widened},
 		},
 	}
-	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{"synth_id", 0}
+	conv.SyntheticPKeys["t2"] = internal.SyntheticPKey{Col: "synth_id", Sequence: 0}
 	conv.Audit.MigrationType = migration.MigrationData_SCHEMA_AND_DATA.Enum()
 }
+
+func TestMigrate(t *testing.T) {
+	tc := []struct {
+		name       string
+		payload    string
+		unreviewed bool
+		statusCode int64
+	}{
+		{
+			name:       "Test unknown mode",
+			payload:    `{"Database": "orders-db", "Mode": "data"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Test invalid database name",
+			payload:    `{"Database": "Orders", "Mode": "schema"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Test unreviewed issues",
+			payload:    `{"Database": "orders-db", "Mode": "schema"}`,
+			unreviewed: true,
+			statusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "Test data without source connection",
+			payload:    `{"Database": "orders-db", "Mode": "schema-and-data"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Test Spanner not configured",
+			payload:    `{"Database": "orders-db", "Mode": "schema"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.SourceDB = nil
+		sessionState.Conv = searchTestConv()
+		if tc.unreviewed {
+			sessionState.Conv.Issues["users"]["id"] = []internal.SchemaIssue{internal.NoGoodType}
+		}
+		req, err := http.NewRequest("POST", "/migrate", strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(migrate)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, int(tc.statusCode), rr.Code, tc.name)
+	}
+}
+
+func TestGetMigrationStatus(t *testing.T) {
+	migrationJob.status = MigrationStatus{
+		State:    migrationRunning,
+		Database: "projects/p/instances/i/databases/orders-db",
+		Mode:     migrateSchemaAndData,
+		Tables:   []TableStatus{{Table: "users", State: migrationDone, Rows: 2, GoodRows: 2}, {Table: "orders", State: migrationRunning}},
+	}
+	defer func() { migrationJob.status = MigrationStatus{} }()
+	req, err := http.NewRequest("GET", "/migrate/status", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getMigrationStatus)
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var res MigrationStatus
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.Equal(t, migrationJob.status, res)
+
+	finishMigration(fmt.Errorf("can't add foreign keys"))
+	assert.Equal(t, migrationFailed, migrationJob.status.State)
+	assert.Equal(t, []TableStatus{{Table: "users", State: migrationDone, Rows: 2, GoodRows: 2}, {Table: "orders", State: migrationFailed}}, migrationJob.status.Tables)
+}
+
+func TestConvertSpannerDDL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.ddl")
+	if err := ioutil.WriteFile(path, []byte("CREATE TABLE orders (id INT64 NOT NULL) PRIMARY KEY (id)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	text := `CREATE TABLE users (id INT64 NOT NULL, name STRING(50)) PRIMARY KEY (id);
+CREATE TABLE orders (id INT64 NOT NULL, order_id INT64 NOT NULL) PRIMARY KEY (id, order_id), INTERLEAVE IN PARENT users;
+CREATE INDEX users_by_name ON users (name) STORING (id)`
+	payload, _ := json.Marshal(SpannerDDLRequest{Text: text})
+	tc := []struct {
+		name       string
+		payload    string
+		statusCode int
+		tables     int
+	}{
+		{
+			name:       "Test DDL text",
+			payload:    string(payload),
+			statusCode: http.StatusOK,
+			tables:     2,
+		},
+		{
+			name:       "Test DDL file",
+			payload:    fmt.Sprintf(`{"FilePath": "%s"}`, path),
+			statusCode: http.StatusOK,
+			tables:     1,
+		},
+		{
+			name:       "Test missing DDL file",
+			payload:    `{"FilePath": "missing.ddl"}`,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "Test neither text nor file",
+			payload:    `{}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Test invalid DDL",
+			payload:    `{"Text": "CREATE TABLE users ("}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tc {
+		req, err := http.NewRequest("POST", "/convert/spanner/ddl", strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(convertSpannerDDL)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+		if tc.statusCode != http.StatusOK {
+			continue
+		}
+		var res session.ConvWithMetadata
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res), tc.name)
+		assert.Equal(t, constants.SPANNER, res.DatabaseType, tc.name)
+		assert.Equal(t, tc.tables, len(res.SpSchema), tc.name)
+	}
+
+	// The session holds the schema of the DDL text.
+	req, _ := http.NewRequest("POST", "/convert/spanner/ddl", strings.NewReader(string(payload)))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(convertSpannerDDL).ServeHTTP(rr, req)
+	sessionState := session.GetSessionState()
+	assert.Equal(t, constants.SPANNER, sessionState.Driver)
+	for _, sp := range sessionState.Conv.SpSchema {
+		switch sp.Name {
+		case "orders":
+			assert.Equal(t, "users", sp.Parent)
+		case "users":
+			assert.Equal(t, 1, len(sp.Indexes))
+			assert.Equal(t, []string{"id"}, sp.Indexes[0].StoredColumns)
+		}
+	}
+
+	req, _ = http.NewRequest("GET", "/typemap", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(getTypeMap).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var typemap map[string][]typeIssue
+	json.Unmarshal(rr.Body.Bytes(), &typemap)
+	assert.Equal(t, map[string][]typeIssue{
+		ddl.Int64:  {{T: ddl.Int64}, {T: ddl.String}, {T: ddl.Numeric}},
+		ddl.String: {{T: ddl.Bytes}, {T: ddl.String}},
+	}, typemap)
+}
+
+func TestConvertSpannerDatabase(t *testing.T) {
+	tc := []struct {
+		name       string
+		payload    string
+		statusCode int
+	}{
+		{
+			name:       "Test invalid database name",
+			payload:    `{"Database": "Orders"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Test Spanner not configured",
+			payload:    `{"Database": "orders-db"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tc {
+		req, err := http.NewRequest("POST", "/convert/spanner/database", strings.NewReader(tc.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(convertSpannerDatabase)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+	}
+}
+
+func TestExportImportSession(t *testing.T) {
+	sessionState := session.GetSessionState()
+	sessionState.Conv = nil
+	req, _ := http.NewRequest("GET", "/session/export", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(exportSession).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	sessionState.Driver = constants.MYSQL
+	sessionState.SessionMetadata = session.SessionMetadata{SessionName: "orders.session.json", DatabaseType: constants.MYSQL, DatabaseName: "orders"}
+	sessionState.Conv = searchTestConv()
+	assert.Nil(t, sessionState.Conv.EditTableDDL("users", []string{"CREATE TABLE users (id INT64 NOT NULL) PRIMARY KEY (id)"}))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(exportSession).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="orders.session.json.hbsession.zip"`, rr.Header().Get("Content-Disposition"))
+	archive := rr.Body.Bytes()
+
+	sessionState.Conv = internal.MakeConv()
+	sessionState.Driver = constants.POSTGRES
+	req, _ = http.NewRequest("POST", "/session/import", bytes.NewReader(archive))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(importSession).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var res session.ConvWithMetadata
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.Equal(t, "orders", res.DatabaseName)
+	assert.Equal(t, constants.MYSQL, sessionState.Driver)
+	assert.Equal(t, 2, len(sessionState.Conv.SpSchema))
+	assert.Equal(t, []string{"CREATE TABLE users (id INT64 NOT NULL) PRIMARY KEY (id)"}, sessionState.Conv.DdlEdits["users"].Edited)
+
+	req, _ = http.NewRequest("POST", "/session/import", strings.NewReader("not an archive"))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(importSession).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestSessionSnapshots(t *testing.T) {
+	sessionState := session.GetSessionState()
+	sessionState.IsOffline = true
+	sessionState.Driver = constants.MYSQL
+	sessionState.SessionMetadata = session.SessionMetadata{SessionName: "snapshots.session.json", DatabaseName: "orders"}
+	sessionState.Conv = searchTestConv()
+	s := session.NewSnapshotter(session.SnapshotPolicy{Interval: time.Hour, KeepLast: 5})
+	router := mux.NewRouter()
+	router.HandleFunc("/session/snapshots", s.ListSnapshots).Methods("GET")
+	router.HandleFunc("/session/snapshots", s.TakeSnapshot).Methods("POST")
+	router.HandleFunc("/typemap/table", func(w http.ResponseWriter, r *http.Request) {
+		sessionState.Conv.SpSchema["users"] = ddl.CreateTable{Name: "users", Comment: "edited"}
+	}).Methods("POST")
+	router.Use(s.Middleware)
+
+	tc := []struct {
+		name       string
+		method     string
+		path       string
+		statusCode int
+		snapshots  int
+	}{
+		{"Test take snapshot", "POST", "/session/snapshots", http.StatusCreated, 1},
+		{"Test unchanged session", "POST", "/session/snapshots", http.StatusNoContent, 1},
+		// The middleware doesn't snapshot again before the interval elapsed.
+		{"Test edit", "POST", "/typemap/table", http.StatusOK, 1},
+		{"Test snapshot of edit", "POST", "/session/snapshots", http.StatusCreated, 2},
+	}
+	for _, tc := range tc {
+		req, _ := http.NewRequest(tc.method, tc.path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+
+		req, _ = http.NewRequest("GET", "/session/snapshots", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var snapshots []session.SchemaConversionSession
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &snapshots), tc.name)
+		assert.Equal(t, tc.snapshots, len(snapshots), tc.name)
+	}
+}
+
+// TestRouteRoles checks that every route has a role, since routes without one
+// are restricted to operators.
+func TestRouteRoles(t *testing.T) {
+	err := getRoutes().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, m := range methods {
+			_, ok := auth.RouteRole(m, tpl)
+			assert.True(t, ok, "%s %s has no role", m, tpl)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestTypeMapPresets(t *testing.T) {
+	sessionState := session.GetSessionState()
+	sessionState.Driver = constants.MYSQL
+	sessionState.DbName = ""
+	sessionState.Conv = searchTestConv()
+	path := filepath.Join(t.TempDir(), "typemap_presets.json")
+	typeMapPresets = newPresetStore(path)
+	defer func() { typeMapPresets = newPresetStore("") }()
+	router := getRoutes()
+
+	tc := []struct {
+		name       string
+		method     string
+		path       string
+		payload    string
+		statusCode int
+		presets    int                 // Number of presets listed after the request.
+		types      map[string][]string // Maps table to Spanner types of its columns, in ColNames order.
+	}{
+		{"Test list built-in presets", "GET", "/typemap/presets", "", http.StatusOK, 3, nil},
+		{"Test save preset", "POST", "/typemap/presets", `{"Name": "bigint-as-string", "Mappings": {"bigint": "STRING"}}`, http.StatusOK, 4, nil},
+		{"Test replace preset", "POST", "/typemap/presets", `{"Name": "bigint-as-string", "Description": "ids as strings", "Mappings": {"bigint": "STRING"}}`, http.StatusOK, 4, nil},
+		{"Test save built-in preset", "POST", "/typemap/presets", `{"Name": "strict", "Mappings": {"int": "STRING"}}`, http.StatusBadRequest, 4, nil},
+		{"Test save invalid type", "POST", "/typemap/presets", `{"Name": "bad", "Mappings": {"int": "INTEGER"}}`, http.StatusBadRequest, 4, nil},
+		{"Test save invalid name", "POST", "/typemap/presets", `{"Name": "a/b", "Mappings": {"int": "STRING"}}`, http.StatusBadRequest, 4, nil},
+		{"Test apply custom preset", "POST", "/typemap/presets/bigint-as-string/apply", "", http.StatusOK, 4,
+			map[string][]string{"users": {ddl.String, ddl.String}, "orders": {ddl.Int64, ddl.String, ddl.String}}},
+		{"Test apply string-everything", "POST", "/typemap/presets/string-everything/apply", "", http.StatusOK, 4,
+			map[string][]string{"users": {ddl.String, ddl.String}, "orders": {ddl.String, ddl.String, ddl.String}}},
+		{"Test apply unknown preset", "POST", "/typemap/presets/unknown/apply", "", http.StatusNotFound, 4, nil},
+		{"Test delete built-in preset", "DELETE", "/typemap/presets/strict", "", http.StatusBadRequest, 4, nil},
+		{"Test delete preset", "DELETE", "/typemap/presets/bigint-as-string", "", http.StatusOK, 3, nil},
+		{"Test delete unknown preset", "DELETE", "/typemap/presets/bigint-as-string", "", http.StatusNotFound, 3, nil},
+	}
+	for _, tc := range tc {
+		req, _ := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.payload))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+		assert.Equal(t, tc.presets, len(typeMapPresets.list()), tc.name)
+		// Custom presets are shared with later runs.
+		assert.Equal(t, tc.presets, len(newPresetStore(path).list()), tc.name)
+		for table, types := range tc.types {
+			sp := sessionState.Conv.SpSchema[table]
+			for i, col := range sp.ColNames {
+				assert.Equal(t, types[i], sp.ColDefs[col].T.Name, tc.name+": "+table+"."+col)
+			}
+		}
+	}
+}
+
+func TestResolvePreset(t *testing.T) {
+	sessionState := session.GetSessionState()
+	sessionState.Conv = searchTestConv()
+	sessionState.Conv.SrcSchema["users"].ColDefs["name"] = schema.Column{Name: "name", Type: schema.Type{Name: "json"}}
+	sessionState.Conv.SrcSchema["orders"].ColDefs["note"] = schema.Column{Name: "note", Type: schema.Type{Name: ddl.JSON}}
+	tc := []struct {
+		name     string
+		driver   string
+		preset   TypeMapPreset
+		expected map[string]string
+	}{
+		{"Test pg-dialect-friendly", constants.SPANNER, builtInPresets[2], map[string]string{ddl.JSON: ddl.String}},
+		{"Test strict", constants.POSTGRES, builtInPresets[0], map[string]string{}},
+		{"Test mapping not offered by typemap", constants.MYSQL, TypeMapPreset{Mappings: map[string]string{"text": ddl.Date}}, map[string]string{}},
+		{"Test mapping of all types", constants.MYSQL, TypeMapPreset{Mappings: map[string]string{"*": ddl.String, "varchar": ddl.Bytes}},
+			map[string]string{"bigint": ddl.String, "int": ddl.String, "json": ddl.String, "varchar": ddl.Bytes}},
+	}
+	for _, tc := range tc {
+		sessionState.Driver = tc.driver
+		resolved, err := resolvePreset(sessionState, tc.preset)
+		assert.Nil(t, err, tc.name)
+		assert.Equal(t, tc.expected, resolved, tc.name)
+	}
+	sessionState.Driver = constants.MYSQL
+	_, err := resolvePreset(sessionState, TypeMapPreset{Name: "pg", Driver: constants.POSTGRES, AvoidIssues: true})
+	assert.NotNil(t, err)
+}
+
+func TestRenameTable(t *testing.T) {
+	tc := []struct {
+		name       string
+		query      string
+		payload    string
+		statusCode int
+		tables     []string // Spanner tables after the request.
+	}{
+		{"Test rename", "table=orders", `{"Name": "purchases"}`, http.StatusOK, []string{"purchases", "users"}},
+		{"Test rename by id", "tableId=t1", `{"Name": "customers"}`, http.StatusOK, []string{"customers", "orders"}},
+		{"Test unknown table", "table=items", `{"Name": "purchases"}`, http.StatusNotFound, []string{"orders", "users"}},
+		{"Test unknown id", "tableId=t9", `{"Name": "purchases"}`, http.StatusNotFound, []string{"orders", "users"}},
+		{"Test name used by table", "table=orders", `{"Name": "users"}`, http.StatusBadRequest, []string{"orders", "users"}},
+		{"Test name used by index", "table=orders", `{"Name": "idx_code"}`, http.StatusBadRequest, []string{"orders", "users"}},
+		{"Test invalid name", "table=orders", `{"Name": "a b"}`, http.StatusBadRequest, []string{"orders", "users"}},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.DbName = ""
+		sessionState.Conv = searchTestConv()
+		users, orders := sessionState.Conv.SpSchema["users"], sessionState.Conv.SpSchema["orders"]
+		users.Id, orders.Id = "t1", "t2"
+		orders.Fks = []ddl.Foreignkey{{Name: "fk_users", Columns: []string{"id"}, ReferTable: "users", ReferColumns: []string{"id"}}}
+		sessionState.Conv.SpSchema["users"], sessionState.Conv.SpSchema["orders"] = users, orders
+		sessionState.Conv.Audit.MigrationType = migration.MigrationData_SCHEMA_ONLY.Enum()
+		// searchTestConv shares the map of ToSource and ToSpanner.
+		sessionState.Conv.ToSpanner = map[string]internal.NameAndCols{
+			"users":  {Name: "users", Cols: map[string]string{"id": "id", "name": "name"}},
+			"orders": {Name: "orders", Cols: map[string]string{"id": "id", "note": "note", "code": "code"}},
+		}
+
+		req, _ := http.NewRequest("POST", "/rename/table?"+tc.query, strings.NewReader(tc.payload))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(renameTable).ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+		var tables []string
+		for name := range sessionState.Conv.SpSchema {
+			tables = append(tables, name)
+		}
+		sort.Strings(tables)
+		assert.Equal(t, tc.tables, tables, tc.name)
+		if tc.statusCode != http.StatusOK {
+			continue
+		}
+		var res *internal.Conv
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res), tc.name)
+		switch tc.tables[0] {
+		case "purchases":
+			assert.Equal(t, "purchases", res.SpSchema["purchases"].Indexes[0].Table, tc.name)
+			assert.Equal(t, "orders", res.ToSource["purchases"].Name, tc.name)
+			assert.Equal(t, "purchases", res.ToSpanner["orders"].Name, tc.name)
+		case "customers":
+			assert.Equal(t, "customers", res.SpSchema["orders"].Fks[0].ReferTable, tc.name)
+			assert.Equal(t, "users", res.ToSource["customers"].Name, tc.name)
+		}
+	}
+}
+
+func TestSetDatetimeZone(t *testing.T) {
+	tc := []struct {
+		name       string
+		query      string
+		payload    string
+		statusCode int
+		zones      internal.DatetimeZones
+	}{
+		{"Test default", "", `{"TimeZone": "Asia/Kolkata"}`, http.StatusOK, internal.DatetimeZones{Default: "Asia/Kolkata"}},
+		{"Test column", "table=orders", `{"Column": "note", "TimeZone": "Asia/Kolkata"}`, http.StatusOK, internal.DatetimeZones{Columns: map[string]map[string]string{"orders": {"note": "Asia/Kolkata"}}}},
+		{"Test unknown column", "table=orders", `{"Column": "name", "TimeZone": "Asia/Kolkata"}`, http.StatusNotFound, internal.DatetimeZones{}},
+		{"Test unknown table", "table=items", `{"Column": "note", "TimeZone": "Asia/Kolkata"}`, http.StatusNotFound, internal.DatetimeZones{}},
+		{"Test unknown time zone", "", `{"TimeZone": "Asia/Atlantis"}`, http.StatusBadRequest, internal.DatetimeZones{}},
+		{"Test invalid payload", "", `{"TimeZone": 1}`, http.StatusBadRequest, internal.DatetimeZones{}},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.DbName = ""
+		sessionState.Conv = searchTestConv()
+		sessionState.Conv.Audit.MigrationType = migration.MigrationData_SCHEMA_ONLY.Enum()
+
+		req, _ := http.NewRequest("POST", "/timezone?"+tc.query, strings.NewReader(tc.payload))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(setDatetimeZone).ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+		assert.Equal(t, tc.zones, sessionState.Conv.DatetimeZones, tc.name)
+		if tc.statusCode == http.StatusOK {
+			var res *internal.Conv
+			assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &res), tc.name)
+			assert.Equal(t, tc.zones, res.DatetimeZones, tc.name)
+		}
+	}
+}
+
+func TestUpdateTableSchemaEnumConversion(t *testing.T) {
+	values := []string{"new", "paid"}
+	tc := []struct {
+		name          string
+		payload       string
+		statusCode    int
+		allowedValues []string
+	}{
+		{"Test check", `{"UpdateCols": {"code": {"EnumConversion": "check"}}}`, http.StatusOK, values},
+		{"Test string", `{"UpdateCols": {"code": {"EnumConversion": "string"}}}`, http.StatusOK, nil},
+		{"Test array of enum", `{"UpdateCols": {"code": {"EnumConversion": "array"}}}`, http.StatusBadRequest, nil},
+		{"Test non-enum column", `{"UpdateCols": {"note": {"EnumConversion": "check"}}}`, http.StatusBadRequest, nil},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.MYSQL
+		sessionState.DbName = ""
+		sessionState.Conv = searchTestConv()
+		sessionState.Conv.Audit.MigrationType = migration.MigrationData_SCHEMA_ONLY.Enum()
+		sessionState.Conv.SrcSchema["orders"].ColDefs["code"] = schema.Column{Name: "code", Type: schema.Type{Name: "enum", Values: values}}
+
+		req, _ := http.NewRequest("POST", "/typemap/table?table=orders", strings.NewReader(tc.payload))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(updateTableSchema).ServeHTTP(rr, req)
+		assert.Equal(t, tc.statusCode, rr.Code, tc.name)
+		assert.Equal(t, tc.allowedValues, sessionState.Conv.SpSchema["orders"].ColDefs["code"].AllowedValues, tc.name)
+	}
+}
+
+func TestUpdateTableSchemaArray(t *testing.T) {
+	tc := []struct {
+		name     string
+		targetDb string
+		expected ddl.Type
+	}{
+		{"Test Spanner", constants.TargetSpanner, ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}},
+		{"Test PostgreSQL dialect", constants.TargetExperimentalPostgres, ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+	}
+	for _, tc := range tc {
+		sessionState := session.GetSessionState()
+		sessionState.Driver = constants.POSTGRES
+		sessionState.DbName = ""
+		sessionState.Conv = searchTestConv()
+		sessionState.Conv.TargetDb = tc.targetDb
+		sessionState.Conv.Audit.MigrationType = migration.MigrationData_SCHEMA_ONLY.Enum()
+		sessionState.Conv.SrcSchema["orders"].ColDefs["note"] = schema.Column{Name: "note", Type: schema.Type{Name: "int4", ArrayBounds: []int64{-1}}}
+
+		req, _ := http.NewRequest("POST", "/typemap/table?table=orders", strings.NewReader(`{"UpdateCols": {"note": {"ToType": "STRING"}}}`))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(updateTableSchema).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, tc.name)
+		assert.Equal(t, tc.expected, sessionState.Conv.SpSchema["orders"].ColDefs["note"].T, tc.name)
+	}
+}
+
+func TestGetSourceDrivers(t *testing.T) {
+	req, err := http.NewRequest("GET", "/drivers", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getSourceDrivers)
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var drivers []sourceDriver
+	json.Unmarshal(rr.Body.Bytes(), &drivers)
+	byName := make(map[string]sourceDriver)
+	for _, d := range drivers {
+		byName[d.Name] = d
+	}
+	for _, name := range []string{constants.MYSQL, constants.MYSQLDUMP, constants.POSTGRES, constants.PGDUMP, constants.SQLSERVER, constants.ORACLE, constants.DYNAMODB} {
+		assert.Contains(t, byName, name)
+	}
+	assert.True(t, byName[constants.MYSQL].Connect)
+	assert.True(t, byName[constants.MYSQL].Streaming)
+	assert.True(t, byName[constants.MYSQLDUMP].Dump)
+	assert.False(t, byName[constants.MYSQLDUMP].Connect)
+	assert.False(t, byName[constants.DYNAMODB].Connect)
+}